@@ -0,0 +1,358 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+// The types of the shares the admins manage.
+const (
+	adminTypeShare  = "share"
+	adminTypePublic = "public"
+	adminTypeOCM    = "ocm"
+)
+
+// adminShare is a share, public link or OCM share of any user, as listed by
+// admin-share-list.
+type adminShare struct {
+	Type          string     `json:"type"`
+	ID            string     `json:"id"`
+	OwnerIdp      string     `json:"owner_idp"`
+	OwnerOpaqueID string     `json:"owner_opaque_id"`
+	ResourceID    string     `json:"resource_id"`
+	Grantee       string     `json:"grantee,omitempty"`
+	Token         string     `json:"token,omitempty"`
+	Expiration    *time.Time `json:"expiration,omitempty"`
+	Created       time.Time  `json:"created"`
+}
+
+func adminShareListCommand() *command {
+	cmd := newCommand("admin-share-list")
+	cmd.Description = func() string { return "list the shares, public links and OCM shares of all the users (admins only)" }
+	cmd.Usage = func() string { return "Usage: admin-share-list [-flags]" }
+	shareType := cmd.String("type", "", "list only the shares of this type (share, public or ocm)")
+	owner := cmd.String("owner", "", "filter by owner (opaque_id or idp:opaque_id)")
+	creator := cmd.String("creator", "", "filter by creator (opaque_id or idp:opaque_id)")
+	resID := cmd.String("by-resource-id", "", "filter by resource id (storage_id:opaque_id)")
+	expiresBefore := cmd.String("expires-before", "", "list only the shares expiring before this date (YYYY-MM-DD)")
+	expired := cmd.Bool("expired", false, "list only the expired shares")
+
+	cmd.ResetFlags = func() {
+		*shareType, *owner, *creator, *resID, *expiresBefore, *expired = "", "", "", "", "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		types, err := adminShareTypes(*shareType)
+		if err != nil {
+			return err
+		}
+
+		f := adminFilters{}
+		if *owner != "" {
+			f.owner = parseUserID(*owner)
+		}
+		if *creator != "" {
+			f.creator = parseUserID(*creator)
+		}
+		if *resID != "" {
+			tokens := strings.Split(*resID, ":")
+			if len(tokens) != 2 {
+				return fmt.Errorf("resource id invalid")
+			}
+			f.resourceID = &provider.ResourceId{StorageId: tokens[0], OpaqueId: tokens[1]}
+		}
+		if *expired {
+			f.expiresBefore = time.Now()
+		}
+		if *expiresBefore != "" {
+			t, err := time.ParseInLocation("2006-01-02", *expiresBefore, time.Local)
+			if err != nil {
+				return errors.Wrap(err, "invalid date, expected YYYY-MM-DD")
+			}
+			if f.expiresBefore.IsZero() || t.Before(f.expiresBefore) {
+				f.expiresBefore = t
+			}
+		}
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		shares := []*adminShare{}
+		for _, t := range types {
+			var list []*adminShare
+			switch t {
+			case adminTypeShare:
+				list, err = adminListShares(ctx, client, f)
+			case adminTypePublic:
+				list, err = adminListPublicShares(ctx, client, f)
+			case adminTypeOCM:
+				list, err = adminListOCMShares(ctx, client, f)
+			}
+			if err != nil {
+				return err
+			}
+			shares = append(shares, list...)
+		}
+
+		if jsonOutput() {
+			return printJSON(shares)
+		}
+
+		tw := table.NewWriter()
+		tw.SetOutputMirror(os.Stdout)
+		tw.AppendHeader(table.Row{"Type", "#", "Owner.Idp", "Owner.OpaqueId", "ResourceId", "Grantee", "Token", "Expiration", "Created"})
+		for _, s := range shares {
+			var exp string
+			if s.Expiration != nil {
+				exp = s.Expiration.String()
+			}
+			tw.AppendRows([]table.Row{
+				{s.Type, s.ID, s.OwnerIdp, s.OwnerOpaqueID, s.ResourceID, s.Grantee, s.Token, exp, s.Created},
+			})
+		}
+		tw.Render()
+		return nil
+	}
+	return cmd
+}
+
+// adminShareTypes returns the share types selected by the -type flag, all of
+// them if empty.
+func adminShareTypes(t string) ([]string, error) {
+	switch t {
+	case "":
+		return []string{adminTypeShare, adminTypePublic, adminTypeOCM}, nil
+	case adminTypeShare, adminTypePublic, adminTypeOCM:
+		return []string{t}, nil
+	default:
+		return nil, fmt.Errorf("invalid share type %q: expected share, public or ocm", t)
+	}
+}
+
+// parseUserID parses an opaque id, optionally prefixed by the idp and a colon.
+// The idp is split at the last colon as it is often an URL.
+func parseUserID(s string) *userpb.UserId {
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		return &userpb.UserId{Idp: s[:i], OpaqueId: s[i+1:]}
+	}
+	return &userpb.UserId{OpaqueId: s}
+}
+
+// adminFilters are the filters of admin-share-list. The providers filter by
+// owner, creator and resource, the expiration is checked here.
+type adminFilters struct {
+	owner, creator *userpb.UserId
+	resourceID     *provider.ResourceId
+	expiresBefore  time.Time
+}
+
+func (f adminFilters) matchExpiration(exp *time.Time) bool {
+	return f.expiresBefore.IsZero() || exp != nil && exp.Before(f.expiresBefore)
+}
+
+func timestampTime(ts *typespb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := time.Unix(int64(ts.Seconds), int64(ts.Nanos))
+	return &t
+}
+
+func granteeString(g *provider.Grantee) string {
+	switch g.GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		return "user:" + g.GetUserId().GetOpaqueId()
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		return "group:" + g.GetGroupId().GetOpaqueId()
+	default:
+		return g.GetType().String()
+	}
+}
+
+func adminListShares(ctx context.Context, client gateway.GatewayAPIClient, f adminFilters) ([]*adminShare, error) {
+	req := &collaboration.ListSharesRequest{Opaque: share.EncodeAdmin(nil)}
+	if f.owner != nil {
+		req.Filters = append(req.Filters, &collaboration.ListSharesRequest_Filter{
+			Type: collaboration.ListSharesRequest_Filter_TYPE_OWNER,
+			Term: &collaboration.ListSharesRequest_Filter_Owner{Owner: f.owner},
+		})
+	}
+	if f.creator != nil {
+		req.Filters = append(req.Filters, &collaboration.ListSharesRequest_Filter{
+			Type: collaboration.ListSharesRequest_Filter_TYPE_CREATOR,
+			Term: &collaboration.ListSharesRequest_Filter_Creator{Creator: f.creator},
+		})
+	}
+	if f.resourceID != nil {
+		req.Filters = append(req.Filters, &collaboration.ListSharesRequest_Filter{
+			Type: collaboration.ListSharesRequest_Filter_TYPE_RESOURCE_ID,
+			Term: &collaboration.ListSharesRequest_Filter_ResourceId{ResourceId: f.resourceID},
+		})
+	}
+
+	res, err := client.ListShares(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, formatError(res.Status)
+	}
+	exps, err := share.DecodeExpirations(res.Opaque)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := []*adminShare{}
+	for _, s := range res.Shares {
+		exp := timestampTime(exps[s.Id.OpaqueId])
+		if !f.matchExpiration(exp) {
+			continue
+		}
+		shares = append(shares, &adminShare{
+			Type:          adminTypeShare,
+			ID:            s.Id.OpaqueId,
+			OwnerIdp:      s.Owner.GetIdp(),
+			OwnerOpaqueID: s.Owner.GetOpaqueId(),
+			ResourceID:    s.ResourceId.String(),
+			Grantee:       granteeString(s.Grantee),
+			Expiration:    exp,
+			Created:       time.Unix(int64(s.Ctime.GetSeconds()), 0),
+		})
+	}
+	return shares, nil
+}
+
+func adminListPublicShares(ctx context.Context, client gateway.GatewayAPIClient, f adminFilters) ([]*adminShare, error) {
+	req := &link.ListPublicSharesRequest{Opaque: share.EncodeAdmin(nil)}
+	if f.owner != nil {
+		req.Filters = append(req.Filters, &link.ListPublicSharesRequest_Filter{
+			Type: link.ListPublicSharesRequest_Filter_TYPE_OWNER,
+			Term: &link.ListPublicSharesRequest_Filter_Owner{Owner: f.owner},
+		})
+	}
+	if f.creator != nil {
+		req.Filters = append(req.Filters, &link.ListPublicSharesRequest_Filter{
+			Type: link.ListPublicSharesRequest_Filter_TYPE_CREATOR,
+			Term: &link.ListPublicSharesRequest_Filter_Creator{Creator: f.creator},
+		})
+	}
+	if f.resourceID != nil {
+		req.Filters = append(req.Filters, &link.ListPublicSharesRequest_Filter{
+			Type: link.ListPublicSharesRequest_Filter_TYPE_RESOURCE_ID,
+			Term: &link.ListPublicSharesRequest_Filter_ResourceId{ResourceId: f.resourceID},
+		})
+	}
+
+	res, err := client.ListPublicShares(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, formatError(res.Status)
+	}
+
+	shares := []*adminShare{}
+	for _, s := range res.Share {
+		exp := timestampTime(s.Expiration)
+		if !f.matchExpiration(exp) {
+			continue
+		}
+		shares = append(shares, &adminShare{
+			Type:          adminTypePublic,
+			ID:            s.Id.OpaqueId,
+			OwnerIdp:      s.Owner.GetIdp(),
+			OwnerOpaqueID: s.Owner.GetOpaqueId(),
+			ResourceID:    s.ResourceId.String(),
+			Token:         s.Token,
+			Expiration:    exp,
+			Created:       time.Unix(int64(s.Ctime.GetSeconds()), 0),
+		})
+	}
+	return shares, nil
+}
+
+func adminListOCMShares(ctx context.Context, client gateway.GatewayAPIClient, f adminFilters) ([]*adminShare, error) {
+	// the OCM shares do not expire
+	if !f.expiresBefore.IsZero() {
+		return nil, nil
+	}
+
+	req := &ocm.ListOCMSharesRequest{Opaque: share.EncodeAdmin(nil)}
+	if f.owner != nil {
+		req.Filters = append(req.Filters, &ocm.ListOCMSharesRequest_Filter{
+			Type: ocm.ListOCMSharesRequest_Filter_TYPE_OWNER,
+			Term: &ocm.ListOCMSharesRequest_Filter_Owner{Owner: f.owner},
+		})
+	}
+	if f.creator != nil {
+		req.Filters = append(req.Filters, &ocm.ListOCMSharesRequest_Filter{
+			Type: ocm.ListOCMSharesRequest_Filter_TYPE_CREATOR,
+			Term: &ocm.ListOCMSharesRequest_Filter_Creator{Creator: f.creator},
+		})
+	}
+	if f.resourceID != nil {
+		req.Filters = append(req.Filters, &ocm.ListOCMSharesRequest_Filter{
+			Type: ocm.ListOCMSharesRequest_Filter_TYPE_RESOURCE_ID,
+			Term: &ocm.ListOCMSharesRequest_Filter_ResourceId{ResourceId: f.resourceID},
+		})
+	}
+
+	res, err := client.ListOCMShares(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, formatError(res.Status)
+	}
+
+	shares := []*adminShare{}
+	for _, s := range res.Shares {
+		shares = append(shares, &adminShare{
+			Type:          adminTypeOCM,
+			ID:            s.Id.OpaqueId,
+			OwnerIdp:      s.Owner.GetIdp(),
+			OwnerOpaqueID: s.Owner.GetOpaqueId(),
+			ResourceID:    s.ResourceId.String(),
+			Grantee:       granteeString(s.Grantee),
+			Created:       time.Unix(int64(s.Ctime.GetSeconds()), 0),
+		})
+	}
+	return shares, nil
+}
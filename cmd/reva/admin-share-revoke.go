@@ -0,0 +1,99 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/pkg/errors"
+)
+
+func adminShareRevokeCommand() *command {
+	cmd := newCommand("admin-share-revoke")
+	cmd.Description = func() string { return "revoke a share, public link or OCM share of any user (admins only)" }
+	cmd.Usage = func() string { return "Usage: admin-share-revoke [-flags] <share_id>" }
+	shareType := cmd.String("type", adminTypeShare, "the type of the share (share, public or ocm)")
+
+	cmd.ResetFlags = func() {
+		*shareType = adminTypeShare
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		id := cmd.Args()[0]
+
+		ctx := getAuthContext()
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		var st *rpc.Status
+		switch *shareType {
+		case adminTypeShare:
+			res, err := client.RemoveShare(ctx, &collaboration.RemoveShareRequest{
+				Opaque: share.EncodeAdmin(nil),
+				Ref: &collaboration.ShareReference{
+					Spec: &collaboration.ShareReference_Id{Id: &collaboration.ShareId{OpaqueId: id}},
+				},
+			})
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		case adminTypePublic:
+			res, err := client.RemovePublicShare(ctx, &link.RemovePublicShareRequest{
+				Opaque: share.EncodeAdmin(nil),
+				Ref: &link.PublicShareReference{
+					Spec: &link.PublicShareReference_Id{Id: &link.PublicShareId{OpaqueId: id}},
+				},
+			})
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		case adminTypeOCM:
+			res, err := client.RemoveOCMShare(ctx, &ocm.RemoveOCMShareRequest{
+				Opaque: share.EncodeAdmin(nil),
+				Ref: &ocm.ShareReference{
+					Spec: &ocm.ShareReference_Id{Id: &ocm.ShareId{OpaqueId: id}},
+				},
+			})
+			if err != nil {
+				return err
+			}
+			st = res.Status
+		default:
+			return errors.Errorf("invalid share type %q: expected share, public or ocm", *shareType)
+		}
+
+		if st.Code != rpc.Code_CODE_OK {
+			return formatError(st)
+		}
+		return printOK()
+	}
+	return cmd
+}
@@ -28,7 +28,6 @@ import (
 	"github.com/c-bata/go-prompt"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
-	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 )
 
 type argumentCompleter struct {
@@ -61,41 +60,8 @@ func (c *Completer) loginArgumentCompleter() []prompt.Suggest {
 	return suggests
 }
 
-func (c *Completer) lsArgumentCompleter(onlyDirs bool) []prompt.Suggest {
-	if onlyDirs {
-		if s, ok := checkCache(c.lsDirArguments); ok {
-			return s
-		}
-	} else {
-		if s, ok := checkCache(c.lsArguments); ok {
-			return s
-		}
-	}
-
-	info := []*provider.ResourceInfo{}
-	suggests := []prompt.Suggest{}
-
-	b, err := executeCommand(lsCommand(), "/home")
-	if err == nil {
-		dec := gob.NewDecoder(&b)
-		if err := dec.Decode(&info); err != nil {
-			return []prompt.Suggest{}
-		}
-
-		suggests = append(suggests, prompt.Suggest{Text: "/home"})
-		for _, r := range info {
-			if !onlyDirs || r.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
-				suggests = append(suggests, prompt.Suggest{Text: r.Path})
-			}
-		}
-	}
-
-	if onlyDirs {
-		cacheSuggestions(c.lsDirArguments, suggests)
-	} else {
-		cacheSuggestions(c.lsArguments, suggests)
-	}
-	return suggests
+func (c *Completer) pathArgumentCompleter(word string, onlyDirs bool) []prompt.Suggest {
+	return c.paths.complete(word, onlyDirs)
 }
 
 func (c *Completer) ocmShareArgumentCompleter() []prompt.Suggest {
@@ -22,6 +22,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	gouser "os/user"
 	"path"
 	"strings"
@@ -70,6 +71,9 @@ func writeConfig(c *config) error {
 }
 
 func getTokenFile() string {
+	if tokenFile != "" {
+		return tokenFile
+	}
 	user, err := gouser.Current()
 	if err != nil {
 		panic(err)
@@ -78,12 +82,17 @@ func getTokenFile() string {
 	return path.Join(user.HomeDir, ".reva-token")
 }
 
+// readToken returns the access token of the REVA_TOKEN variable, or the one
+// saved by login in the token file.
 func readToken() (string, error) {
+	if t := os.Getenv("REVA_TOKEN"); t != "" {
+		return t, nil
+	}
 	data, err := ioutil.ReadFile(getTokenFile())
 	if err != nil {
 		return "", err
 	}
-	return string(data), nil
+	return strings.TrimSpace(string(data)), nil
 }
 
 func writeToken(token string) {
@@ -30,8 +30,7 @@ type Completer struct {
 	Commands                  []*command
 	DisableArgPrompt          bool
 	loginArguments            *argumentCompleter
-	lsArguments               *argumentCompleter
-	lsDirArguments            *argumentCompleter
+	paths                     *pathCompleter
 	ocmShareArguments         *argumentCompleter
 	ocmShareReceivedArguments *argumentCompleter
 	shareArguments            *argumentCompleter
@@ -39,10 +38,16 @@ type Completer struct {
 }
 
 func (c *Completer) init() {
-	c.loginArguments, c.lsArguments = new(argumentCompleter), new(argumentCompleter)
+	c.loginArguments = new(argumentCompleter)
 	c.ocmShareArguments, c.ocmShareReceivedArguments = new(argumentCompleter), new(argumentCompleter)
 	c.shareArguments, c.shareReceivedArguments = new(argumentCompleter), new(argumentCompleter)
-	c.lsDirArguments = new(argumentCompleter)
+	c.paths = newPathCompleter()
+}
+
+// InvalidatePaths drops the cached listings of the remote paths, for the
+// completion to see the changes made by a command.
+func (c *Completer) InvalidatePaths() {
+	c.paths.invalidate()
 }
 
 // Complete provides completion to prompt
@@ -96,24 +101,24 @@ func (c *Completer) argumentCompleter(args ...string) []prompt.Suggest {
 
 	case "ls", "mkdir":
 		if len(args) == 2 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(true), args[1], true)
+			return c.pathArgumentCompleter(args[1], true)
 		}
 
 	case "mv":
 		if len(args) == 2 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[1], true)
+			return c.pathArgumentCompleter(args[1], false)
 		} else if len(args) == 3 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[2], true)
+			return c.pathArgumentCompleter(args[2], false)
 		}
 
 	case "rm", "stat", "share-create", "ocm-share-create", "public-share-create", "open-in-app", "open-file-in-app-provider", "download":
 		if len(args) == 2 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[1], true)
+			return c.pathArgumentCompleter(args[1], false)
 		}
 
-	case "upload":
+	case "upload", "mirror":
 		if len(args) == 3 {
-			return prompt.FilterHasPrefix(c.lsArgumentCompleter(false), args[2], true)
+			return c.pathArgumentCompleter(args[2], false)
 		}
 
 	case "ocm-share-remove", "ocm-share-update":
@@ -148,8 +153,22 @@ func (c *Completer) optionCompleter(args ...string) []prompt.Suggest {
 	var suggests []prompt.Suggest
 	for _, cmd := range commands {
 		if cmd.Name == args[0] {
+			// the flags already given are not suggested again
+			given := map[string]bool{}
+			for _, a := range args[1 : len(args)-1] {
+				if strings.HasPrefix(a, "-") {
+					given[strings.SplitN(strings.TrimLeft(a, "-"), "=", 2)[0]] = true
+				}
+			}
 			cmd.VisitAll(func(fl *flag.Flag) {
-				suggests = append(suggests, prompt.Suggest{Text: "-" + fl.Name, Description: fl.Usage})
+				if given[fl.Name] {
+					return
+				}
+				s := prompt.Suggest{Text: "-" + fl.Name, Description: fl.Usage}
+				if fl.DefValue != "" && fl.DefValue != "false" {
+					s.Description += " (default " + fl.DefValue + ")"
+				}
+				suggests = append(suggests, s)
 			})
 			return prompt.FilterContains(suggests, strings.TrimLeft(args[len(args)-1], "-"), true)
 		}
@@ -179,6 +198,9 @@ func (c *Completer) completeOptionArguments(d prompt.Document) ([]prompt.Suggest
 	case "-rol":
 		suggests = []prompt.Suggest{prompt.Suggest{Text: "viewer"}, prompt.Suggest{Text: "editor"}}
 		match = true
+	case "-role":
+		suggests = []prompt.Suggest{prompt.Suggest{Text: "viewer"}, prompt.Suggest{Text: "editor"}, prompt.Suggest{Text: "manager"}}
+		match = true
 	case "-state":
 		suggests = []prompt.Suggest{prompt.Suggest{Text: "pending"}, prompt.Suggest{Text: "accepted"}, prompt.Suggest{Text: "rejected"}}
 		match = true
@@ -30,7 +30,7 @@ var configureCommand = func() *command {
 	cmd.Description = func() string { return "configure the reva client" }
 	cmd.Action = func(w ...io.Writer) error {
 		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("host: ")
+		printInfo("host: ")
 		text, err := read(reader)
 		if err != nil {
 			return err
@@ -40,8 +40,9 @@ var configureCommand = func() *command {
 		if err := writeConfig(conf); err != nil {
 			return err
 		}
-		fmt.Println("config saved at ", getConfigFile())
-		return nil
+		return printResult(map[string]string{"config": getConfigFile()}, func() {
+			fmt.Println("config saved at ", getConfigFile())
+		})
 	}
 	return cmd
 }
@@ -19,7 +19,6 @@
 package main
 
 import (
-	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -92,7 +91,7 @@ func downloadCommand() *command {
 		}
 
 		// TODO(labkode): upload to data server
-		fmt.Printf("Downloading from: %s\n", p.DownloadEndpoint)
+		printInfo("Downloading from: %s\n", p.DownloadEndpoint)
 
 		content, err := checkDownloadWebdavRef(res.Protocols)
 		if err != nil {
@@ -134,6 +133,9 @@ func downloadCommand() *command {
 		}
 
 		bar := pb.New(int(info.Size)).SetUnits(pb.U_BYTES)
+		if jsonOutput() {
+			bar.Output = os.Stderr
+		}
 		bar.Start()
 		reader := bar.NewProxyReader(content)
 
@@ -145,7 +147,7 @@ func downloadCommand() *command {
 			return err
 		}
 		bar.Finish()
-		return nil
+		return printResult(map[string]interface{}{"path": absPath, "info": info}, func() {})
 	}
 	return cmd
 }
@@ -19,9 +19,10 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
-	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
@@ -31,14 +32,57 @@ import (
 // Executor provides exec command handler
 type Executor struct {
 	Timeout int
+	// OnChange is called after the commands changing the remote files.
+	OnChange func()
 }
 
-// Execute provides execute commands
+// mutatingCommands are the commands after which the cached listings of the
+// completer are stale.
+var mutatingCommands = map[string]bool{
+	"mkdir":           true,
+	"rm":              true,
+	"mv":              true,
+	"upload":          true,
+	"mirror":          true,
+	"recycle-restore": true,
+	"recycle-purge":   true,
+}
+
+// Execute provides execute commands, the ones of the interactive shell are
+// remembered in the history of the host.
 func (e *Executor) Execute(s string) {
+	s = strings.TrimSpace(s)
+	_ = e.Run(s)
+	if s != "" && conf != nil && conf.Host != "" {
+		appendHistory(conf.Host, s)
+	}
+	if e.OnChange != nil && mutatingCommands[strings.SplitN(s, " ", 2)[0]] {
+		e.OnChange()
+	}
+}
+
+// RunBatch executes the commands read from r, one per line, and stops at the
+// first failing one.
+func (e *Executor) RunBatch(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := e.Run(line); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// Run executes the command and returns its error, which is printed.
+func (e *Executor) Run(s string) error {
 	s = strings.TrimSpace(s)
 	switch s {
 	case "":
-		return
+		return nil
 	case "exit", "quit":
 		os.Exit(0)
 	}
@@ -49,8 +93,9 @@ func (e *Executor) Execute(s string) {
 	if conf == nil || conf.Host == "" {
 		c, err := readConfig()
 		if err != nil && args[0] != "configure" {
-			fmt.Println("reva is not configured, please pass the -host flag or run the configure command")
-			return
+			err := errors.New("reva is not configured, please pass the -host flag, set REVA_HOST or run the configure command")
+			printError(err)
+			return err
 		} else if args[0] != "configure" {
 			conf = c
 		}
@@ -60,8 +105,8 @@ func (e *Executor) Execute(s string) {
 	for _, v := range commands {
 		if v.Name == action {
 			if err := v.Parse(args[1:]); err != nil {
-				fmt.Println(err)
-				return
+				printError(err)
+				return err
 			}
 			defer v.ResetFlags()
 
@@ -92,14 +137,17 @@ func (e *Executor) Execute(s string) {
 				}
 			}()
 
-			if err := executeWithContext(ctx, v); err != nil {
-				fmt.Println(err.Error())
+			err := executeWithContext(ctx, v)
+			if err != nil {
+				printError(err)
 			}
-			return
+			return err
 		}
 	}
 
-	fmt.Println("Invalid command. Use \"help\" to list the available commands.")
+	err := errors.New("Invalid command. Use \"help\" to list the available commands.")
+	printError(err)
+	return err
 }
 
 func executeWithContext(ctx context.Context, cmd *command) error {
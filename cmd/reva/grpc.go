@@ -28,6 +28,7 @@ import (
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
 	"github.com/cs3org/reva/pkg/token"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -54,6 +55,15 @@ func getClient() (gateway.GatewayAPIClient, error) {
 	return gateway.NewGatewayAPIClient(conn), nil
 }
 
+// getSpacesClient returns a client of the spaces service served by the gateway.
+func getSpacesClient() (spacespb.SpacesServiceClient, error) {
+	conn, err := getConn()
+	if err != nil {
+		return nil, err
+	}
+	return spacespb.NewSpacesServiceClient(conn), nil
+}
+
 func getConn() (*grpc.ClientConn, error) {
 	if insecure {
 		return grpc.Dial(conf.Host, grpc.WithInsecure())
@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	gouser "os/user"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// maxHistory is the number of commands kept in the history of a host.
+const maxHistory = 1000
+
+var unsafeHostChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// getHistoryFile returns the file the history of the commands run against
+// the given host is kept in.
+func getHistoryFile(host string) string {
+	user, err := gouser.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	return path.Join(user.HomeDir, ".reva-history", unsafeHostChars.ReplaceAllString(host, "_"))
+}
+
+// readHistory returns the last commands run against the host, the oldest
+// first. The history file is truncated when it grew over maxHistory.
+func readHistory(host string) []string {
+	if host == "" {
+		return nil
+	}
+	f, err := os.Open(getHistoryFile(host))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if l := strings.TrimSpace(s.Text()); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		_ = ioutil.WriteFile(getHistoryFile(host), []byte(strings.Join(lines, "\n")+"\n"), 0600)
+	}
+	return lines
+}
+
+// appendHistory adds the command to the history of the host.
+func appendHistory(host, line string) {
+	file := getHistoryFile(host)
+	if err := os.MkdirAll(path.Dir(file), 0700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.WriteString(line + "\n")
+}
@@ -62,6 +62,10 @@ var loginCommand = func() *command {
 				return formatError(res.Status)
 			}
 
+			if len(w) == 0 && jsonOutput() {
+				return printJSON(res.Types)
+			}
+
 			if len(w) == 0 {
 				fmt.Println("Available login methods:")
 				for _, v := range res.Types {
@@ -81,15 +85,7 @@ var loginCommand = func() *command {
 		}
 
 		authType := cmd.Args()[0]
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("username: ")
-		username, err := read(reader)
-		if err != nil {
-			return err
-		}
-
-		fmt.Print("password: ")
-		password, err := readPassword(0)
+		username, password, err := readCredentials()
 		if err != nil {
 			return err
 		}
@@ -116,8 +112,31 @@ var loginCommand = func() *command {
 		}
 
 		writeToken(res.Token)
-		fmt.Println("OK")
-		return nil
+		return printOK()
 	}
 	return cmd
 }
+
+// readCredentials reads the username and password from the REVA_USERNAME and
+// REVA_PASSWORD environment variables, or prompts for the missing ones.
+func readCredentials() (string, string, error) {
+	username, password := os.Getenv("REVA_USERNAME"), os.Getenv("REVA_PASSWORD")
+
+	if username == "" {
+		printInfo("username: ")
+		var err error
+		if username, err = read(bufio.NewReader(os.Stdin)); err != nil {
+			return "", "", err
+		}
+	}
+
+	if password == "" {
+		printInfo("password: ")
+		var err error
+		if password, err = readPassword(0); err != nil {
+			return "", "", err
+		}
+	}
+
+	return username, password, nil
+}
@@ -35,6 +35,8 @@ var (
 	host                                   string
 	insecure, skipverify, disableargprompt bool
 	timeout                                int
+	outputFormat, tokenFile                string
+	jsonFlag, batch                        bool
 
 	helpCommandOutput string
 
@@ -48,10 +50,13 @@ var (
 		loginCommand(),
 		whoamiCommand(),
 		importCommand(),
+		adminShareListCommand(),
+		adminShareRevokeCommand(),
 		lsCommand(),
 		statCommand(),
 		uploadCommand(),
 		downloadCommand(),
+		mirrorCommand(),
 		rmCommand(),
 		moveCommand(),
 		mkdirCommand(),
@@ -79,6 +84,9 @@ var (
 		shareUpdateCommand(),
 		shareListReceivedCommand(),
 		shareUpdateReceivedCommand(),
+		spaceMemberListCommand(),
+		spaceMemberAddCommand(),
+		spaceMemberRemoveCommand(),
 		openInAppCommand(),
 		openFileInAppProviderCommand(),
 		transferCreateCommand(),
@@ -94,17 +102,32 @@ func init() {
 	flag.BoolVar(&skipverify, "skip-verify", false, "whether to skip verifying the server's certificate chain and host name")
 	flag.BoolVar(&disableargprompt, "disable-arg-prompt", false, "whether to disable prompts for command arguments")
 	flag.IntVar(&timeout, "timout", -1, "the timeout in seconds for executing the commands, -1 means no timeout")
+	flag.StringVar(&outputFormat, "format", formatText, "the output format of the commands, text or json")
+	flag.BoolVar(&jsonFlag, "json", false, "print the output of the commands as JSON, like -format json")
+	flag.StringVar(&tokenFile, "token-file", "", "the file the access token is read from and written to by login, overridden by the REVA_TOKEN variable (default ~/.reva-token)")
+	flag.BoolVar(&batch, "batch", false, "execute the commands read from the standard input, one per line, stopping at the first failing one")
 	flag.Parse()
+	if jsonFlag {
+		outputFormat = formatJSON
+	}
 }
 
 func main() {
 
+	if outputFormat != formatText && outputFormat != formatJSON {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", outputFormat)
+		os.Exit(2)
+	}
+
 	if host != "" {
 		conf = &config{host}
 		if err := writeConfig(conf); err != nil {
 			fmt.Println("error writing to config file")
 			os.Exit(1)
 		}
+	} else if h := os.Getenv("REVA_HOST"); h != "" {
+		// not persisted, e.g. for the jobs of a CI
+		conf = &config{h}
 	}
 
 	client = rhttp.GetHTTPClient(
@@ -115,23 +138,41 @@ func main() {
 	)
 
 	generateMainUsage()
-	executor := Executor{Timeout: timeout}
 	completer := Completer{DisableArgPrompt: disableargprompt}
 	completer.init()
+	executor := Executor{Timeout: timeout, OnChange: completer.InvalidatePaths}
 
+	// the exit code of the non interactive modes tells the scripts whether
+	// the commands succeeded
 	if len(flag.Args()) > 0 {
-		executor.Execute(strings.Join(flag.Args(), " "))
+		if err := executor.Run(strings.Join(flag.Args(), " ")); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if batch {
+		if err := executor.RunBatch(os.Stdin); err != nil {
+			os.Exit(1)
+		}
 		return
 	}
 
 	fmt.Printf("reva-cli %s (rev-%s)\n", version, gitCommit)
 	fmt.Println("Please use `exit` or `Ctrl-D` to exit this program.")
 
+	var history []string
+	if conf != nil {
+		history = readHistory(conf.Host)
+	} else if c, err := readConfig(); err == nil {
+		history = readHistory(c.Host)
+	}
+
 	p := prompt.New(
 		executor.Execute,
 		completer.Complete,
 		prompt.OptionTitle("reva-cli"),
 		prompt.OptionPrefix(">> "),
+		prompt.OptionHistory(history),
 	)
 	p.Run()
 }
@@ -0,0 +1,593 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func mirrorCommand() *command {
+	cmd := newCommand("mirror")
+	cmd.Description = func() string {
+		return "mirror a local folder to a remote folder, or the remote folder to the local one"
+	}
+	cmd.Usage = func() string { return "Usage: mirror [-flags] <local_folder> <remote_folder>" }
+	downloadFlag := cmd.Bool("download", false, "mirror the remote folder to the local folder instead")
+	parallelFlag := cmd.Int("parallel", 4, "the number of files transferred in parallel")
+	includeFlag := cmd.String("include", "", "comma-separated glob patterns of the files to transfer, all if empty")
+	excludeFlag := cmd.String("exclude", "", "comma-separated glob patterns of the files and folders to skip")
+	dryRunFlag := cmd.Bool("dry-run", false, "print the files to transfer without transferring them")
+
+	cmd.ResetFlags = func() {
+		*downloadFlag, *parallelFlag, *includeFlag, *excludeFlag, *dryRunFlag = false, 4, "", "", false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 2 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		if *parallelFlag < 1 {
+			return errors.New("Invalid arguments: parallel must be at least 1")
+		}
+
+		local, err := utils.ResolvePath(cmd.Args()[0])
+		if err != nil {
+			return err
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		m := &mirror{
+			ctx:      getAuthContext(),
+			client:   client,
+			local:    local,
+			remote:   path.Clean(cmd.Args()[1]),
+			include:  splitPatterns(*includeFlag),
+			exclude:  splitPatterns(*excludeFlag),
+			download: *downloadFlag,
+			dryRun:   *dryRunFlag,
+			result:   &mirrorResult{DryRun: *dryRunFlag, Transferred: []string{}, Skipped: []string{}},
+		}
+
+		var files []*mirrorFile
+		if m.download {
+			files, err = m.prepareDownload()
+		} else {
+			files, err = m.prepareUpload()
+		}
+		if err != nil {
+			return err
+		}
+		m.transfer(files, *parallelFlag)
+
+		res := m.result
+		if err := printResult(res, func() {
+			fmt.Printf("%d transferred, %d skipped, %d failed\n", len(res.Transferred), len(res.Skipped), len(res.Failed))
+		}); err != nil {
+			return err
+		}
+		if len(res.Failed) > 0 {
+			return fmt.Errorf("mirror: %d of the %d files failed to transfer", len(res.Failed), len(files))
+		}
+		return nil
+	}
+	return cmd
+}
+
+// mirrorResult reports the relative paths of the mirrored files.
+type mirrorResult struct {
+	DryRun      bool              `json:"dry_run"`
+	Transferred []string          `json:"transferred"`
+	Skipped     []string          `json:"skipped"`
+	Failed      map[string]string `json:"failed,omitempty"`
+}
+
+// mirrorFile is a file of the source folder, with its copy in the target
+// folder if there is one.
+type mirrorFile struct {
+	rel    string
+	size   uint64
+	remote *provider.ResourceInfo
+}
+
+type mirror struct {
+	ctx      context.Context
+	client   gateway.GatewayAPIClient
+	local    string
+	remote   string
+	include  []string
+	exclude  []string
+	download bool
+	dryRun   bool
+
+	mu     sync.Mutex
+	result *mirrorResult
+}
+
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// matchPatterns tells whether one of the glob patterns matches the path
+// relative to the mirrored folder, or its base name for the patterns without
+// a slash, like *.tmp.
+func matchPatterns(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		name := rel
+		if !strings.Contains(p, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mirror) included(rel string) bool {
+	return len(m.include) == 0 || matchPatterns(m.include, rel)
+}
+
+func (m *mirror) remotePath(rel string) string {
+	return path.Join(m.remote, rel)
+}
+
+func (m *mirror) localPath(rel string) string {
+	return filepath.Join(m.local, filepath.FromSlash(rel))
+}
+
+func (m *mirror) stat(p string) (*provider.ResourceInfo, error) {
+	res, err := m.client.Stat(m.ctx, &provider.StatRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Path{Path: p}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return res.Info, nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, nil
+	default:
+		return nil, formatError(res.Status)
+	}
+}
+
+// walkRemote lists the remote folder recursively, skipping the excluded
+// resources.
+func (m *mirror) walkRemote(root string, fn func(rel string, info *provider.ResourceInfo) error) error {
+	res, err := m.client.ListContainer(m.ctx, &provider.ListContainerRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Path{Path: root}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+
+	for _, info := range res.Infos {
+		p := path.Join(root, path.Base(info.Path))
+		rel := strings.TrimPrefix(p, m.remote+"/")
+		if matchPatterns(m.exclude, rel) {
+			continue
+		}
+		if err := fn(rel, info); err != nil {
+			return err
+		}
+		if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			if err := m.walkRemote(p, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// prepareUpload creates the remote folders and returns the local files to
+// upload.
+func (m *mirror) prepareUpload() ([]*mirrorFile, error) {
+	root, err := m.stat(m.remote)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := map[string]*provider.ResourceInfo{}
+	if root != nil {
+		if root.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			return nil, errors.New("mirror: " + m.remote + " is not a folder")
+		}
+		if err := m.walkRemote(m.remote, func(rel string, info *provider.ResourceInfo) error {
+			remote[rel] = info
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	} else if err := m.createContainer(""); err != nil {
+		return nil, err
+	}
+
+	var files []*mirrorFile
+	err = filepath.Walk(m.local, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == m.local {
+			if !fi.IsDir() {
+				return errors.New("mirror: " + m.local + " is not a folder")
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.local, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchPatterns(m.exclude, rel) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if fi.IsDir() {
+			if _, ok := remote[rel]; !ok {
+				return m.createContainer(rel)
+			}
+			return nil
+		}
+
+		if fi.Mode().IsRegular() && m.included(rel) {
+			files = append(files, &mirrorFile{rel: rel, size: uint64(fi.Size()), remote: remote[rel]})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// prepareDownload creates the local folders and returns the remote files to
+// download.
+func (m *mirror) prepareDownload() ([]*mirrorFile, error) {
+	root, err := m.stat(m.remote)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil || root.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return nil, errors.New("mirror: " + m.remote + " is not a folder")
+	}
+
+	var files []*mirrorFile
+	err = m.walkRemote(m.remote, func(rel string, info *provider.ResourceInfo) error {
+		switch info.Type {
+		case provider.ResourceType_RESOURCE_TYPE_CONTAINER:
+			if m.dryRun {
+				return nil
+			}
+			return os.MkdirAll(m.localPath(rel), 0755)
+		case provider.ResourceType_RESOURCE_TYPE_FILE:
+			if m.included(rel) {
+				files = append(files, &mirrorFile{rel: rel, size: info.Size, remote: info})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.dryRun {
+		if err := os.MkdirAll(m.local, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (m *mirror) createContainer(rel string) error {
+	if m.dryRun {
+		printInfo("would create folder %s\n", m.remotePath(rel))
+		return nil
+	}
+	res, err := m.client.CreateContainer(m.ctx, &provider.CreateContainerRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Path{Path: m.remotePath(rel)}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+	return nil
+}
+
+// transfer transfers the files with the given number of workers, skipping
+// the ones whose copy already has the same checksum.
+func (m *mirror) transfer(files []*mirrorFile, parallel int) {
+	todo := make(chan *mirrorFile)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range todo {
+				m.transferFile(f)
+			}
+		}()
+	}
+	for _, f := range files {
+		todo <- f
+	}
+	close(todo)
+	wg.Wait()
+}
+
+func (m *mirror) transferFile(f *mirrorFile) {
+	same, err := m.sameChecksum(f)
+	if err != nil {
+		m.done(f, err)
+		return
+	}
+	if same {
+		m.mu.Lock()
+		m.result.Skipped = append(m.result.Skipped, f.rel)
+		m.mu.Unlock()
+		return
+	}
+
+	if m.dryRun {
+		if m.download {
+			printInfo("would download %s\n", f.rel)
+		} else {
+			printInfo("would upload %s\n", f.rel)
+		}
+		m.done(f, nil)
+		return
+	}
+
+	if m.download {
+		err = m.downloadFile(f)
+	} else {
+		err = m.uploadFile(f)
+	}
+	m.done(f, err)
+}
+
+func (m *mirror) done(f *mirrorFile, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if m.result.Failed == nil {
+			m.result.Failed = map[string]string{}
+		}
+		m.result.Failed[f.rel] = err.Error()
+		printInfo("failed %s: %v\n", f.rel, err)
+		return
+	}
+	m.result.Transferred = append(m.result.Transferred, f.rel)
+	if !m.dryRun {
+		printInfo("transferred %s\n", f.rel)
+	}
+}
+
+// sameChecksum tells whether the local file and its remote copy have the
+// same content, comparing the checksum computed by the storage. The files
+// of the storages which don't compute checksums are always transferred.
+func (m *mirror) sameChecksum(f *mirrorFile) (bool, error) {
+	if f.remote == nil || f.remote.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		return false, nil
+	}
+	xs := f.remote.Checksum
+	if xs == nil || xs.Sum == "" || f.remote.Size != f.size {
+		return false, nil
+	}
+	switch xs.Type {
+	case provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32,
+		provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_MD5,
+		provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1:
+	default:
+		return false, nil
+	}
+
+	fd, err := os.Open(m.localPath(f.rel))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer fd.Close()
+
+	if m.download {
+		if fi, err := fd.Stat(); err != nil || !fi.Mode().IsRegular() || uint64(fi.Size()) != f.size {
+			return false, err
+		}
+	}
+
+	sum, err := computeXS(xs.Type, fd)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(sum, xs.Sum), nil
+}
+
+func (m *mirror) uploadFile(f *mirrorFile) error {
+	fd, err := os.Open(m.localPath(f.rel))
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	md, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+
+	res, err := m.client.InitiateFileUpload(m.ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Path{Path: m.remotePath(f.rel)}},
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatInt(md.Size(), 10)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+
+	if err = checkUploadWebdavRef(res.Protocols, md, fd); err == nil {
+		return nil
+	} else if _, ok := err.(errtypes.IsNotSupported); !ok {
+		return err
+	}
+
+	p, err := getUploadProtocolInfo(res.Protocols, "simple")
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := rhttp.NewRequest(m.ctx, "PUT", p.UploadEndpoint, fd)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, p.Token)
+
+	// let the storage verify the content when it supports a checksum
+	if xsType, err := guessXS("negotiate", p.AvailableChecksums); err == nil {
+		xs, err := computeXS(xsType, fd)
+		if err != nil {
+			return err
+		}
+		if _, err := fd.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		q := httpReq.URL.Query()
+		q.Add("xs", xs)
+		q.Add("xs_type", storageprovider.GRPC2PKGXS(xsType).String())
+		httpReq.URL.RawQuery = q.Encode()
+	}
+
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return errors.New("upload: PUT request returned " + httpRes.Status)
+	}
+	return nil
+}
+
+func (m *mirror) downloadFile(f *mirrorFile) error {
+	res, err := m.client.InitiateFileDownload(m.ctx, &provider.InitiateFileDownloadRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Path{Path: m.remotePath(f.rel)}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return formatError(res.Status)
+	}
+
+	content, err := checkDownloadWebdavRef(res.Protocols)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotSupported); !ok {
+			return err
+		}
+
+		p, err := getDownloadProtocolInfo(res.Protocols, "simple")
+		if err != nil {
+			return err
+		}
+		httpReq, err := rhttp.NewRequest(m.ctx, "GET", p.DownloadEndpoint, nil)
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set(datagateway.TokenTransportHeader, p.Token)
+
+		httpRes, err := client.Do(httpReq)
+		if err != nil {
+			return err
+		}
+		defer httpRes.Body.Close()
+		if httpRes.StatusCode != http.StatusOK {
+			return errors.New("download: GET request returned " + httpRes.Status)
+		}
+		content = httpRes.Body
+	}
+
+	// write a temporary file first, for an interrupted download not to
+	// replace the local copy
+	target := m.localPath(f.rel)
+	tmp, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".mirror-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -56,7 +56,7 @@ func mkdirCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printDone()
 	}
 	return cmd
 }
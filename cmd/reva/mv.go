@@ -60,7 +60,7 @@ func moveCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printDone()
 	}
 	return cmd
 }
@@ -20,7 +20,6 @@ package main
 
 import (
 	"errors"
-	"fmt"
 	"io"
 
 	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
@@ -80,8 +79,7 @@ func ocmInviteForwardCommand() *command {
 		if forwardToken.Status.Code != rpc.Code_CODE_OK {
 			return formatError(forwardToken.Status)
 		}
-		fmt.Println("OK")
-		return nil
+		return printOK()
 	}
 	return cmd
 }
@@ -45,8 +45,7 @@ func ocmInviteGenerateCommand() *command {
 		if inviteToken.Status.Code != rpc.Code_CODE_OK {
 			return formatError(inviteToken.Status)
 		}
-		fmt.Println(inviteToken)
-		return nil
+		return printResult(inviteToken, func() { fmt.Println(inviteToken) })
 	}
 	return cmd
 }
@@ -19,7 +19,6 @@
 package main
 
 import (
-	"fmt"
 	"io"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
@@ -63,8 +62,7 @@ func ocmShareRemoveCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printOK()
 	}
 	return cmd
 }
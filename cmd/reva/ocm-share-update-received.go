@@ -19,7 +19,6 @@
 package main
 
 import (
-	"fmt"
 	"io"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
@@ -81,8 +80,7 @@ func ocmShareUpdateReceivedCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printOK()
 	}
 	return cmd
 }
@@ -19,7 +19,6 @@
 package main
 
 import (
-	"fmt"
 	"io"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
@@ -83,8 +82,7 @@ func ocmShareUpdateCommand() *command {
 			return formatError(shareRes.Status)
 		}
 
-		fmt.Println("OK")
-		return nil
+		return printOK()
 	}
 	return cmd
 }
@@ -84,9 +84,9 @@ func openFileInAppProviderCommand() *command {
 			return formatError(openRes.Status)
 		}
 
-		fmt.Println("App provider url: " + openRes.AppProviderUrl)
-
-		return nil
+		return printResult(map[string]string{"app_provider_url": openRes.AppProviderUrl}, func() {
+			fmt.Println("App provider url: " + openRes.AppProviderUrl)
+		})
 	}
 	return cmd
 }
@@ -86,9 +86,7 @@ func openInAppCommand() *command {
 			return formatError(openRes.Status)
 		}
 
-		fmt.Println("App URL: " + openRes.AppUrl)
-
-		return nil
+		return printResult(map[string]string{"app_url": openRes.AppUrl}, func() { fmt.Println("App URL: " + openRes.AppUrl) })
 	}
 	return cmd
 }
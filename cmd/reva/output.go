@@ -0,0 +1,135 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/golang/protobuf/proto"
+)
+
+// The output formats of the commands.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// jsonOutput tells whether the commands print JSON, for scripts to parse.
+func jsonOutput() bool {
+	return outputFormat == formatJSON
+}
+
+// printResult prints the result of a command, v as JSON with the -json flag
+// or with the given function otherwise.
+func printResult(v interface{}, text func()) error {
+	if !jsonOutput() {
+		text()
+		return nil
+	}
+	return printJSON(v)
+}
+
+var statusOK = map[string]string{"status": "OK"}
+
+// printOK prints the result of the commands which return nothing.
+func printOK() error {
+	return printResult(statusOK, func() { fmt.Println("OK") })
+}
+
+// printDone prints the result of the commands which are silent on success,
+// which print their status only with the -json flag.
+func printDone() error {
+	return printResult(statusOK, func() {})
+}
+
+// printInfo prints the progress of a command, to stderr with the -json flag
+// for the output to stay parsable.
+func printInfo(format string, a ...interface{}) {
+	if jsonOutput() {
+		fmt.Fprintf(os.Stderr, format, a...)
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+// printError prints the error of a failed command.
+func printError(err error) {
+	if !jsonOutput() {
+		fmt.Println(err.Error())
+		return
+	}
+	if err := printJSON(map[string]string{"error": err.Error()}); err != nil {
+		fmt.Println(err.Error())
+	}
+}
+
+func printJSON(v interface{}) error {
+	data, err := toJSON(v)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// toJSON encodes the protobuf messages, also within slices and maps, with the
+// protobuf JSON mapping and the rest with encoding/json.
+func toJSON(v interface{}) (json.RawMessage, error) {
+	if m, ok := v.(proto.Message); ok {
+		if reflect.ValueOf(m).IsNil() {
+			return json.RawMessage("null"), nil
+		}
+		return utils.MarshalProtoV1ToJSON(m)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		items := make([]json.RawMessage, rv.Len())
+		for i := range items {
+			item, err := toJSON(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return json.Marshal(items)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		fields := make(map[string]json.RawMessage, rv.Len())
+		for _, k := range rv.MapKeys() {
+			field, err := toJSON(rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			fields[k.String()] = field
+		}
+		return json.Marshal(fields)
+	}
+	return json.Marshal(v)
+}
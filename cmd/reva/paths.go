@@ -0,0 +1,127 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"encoding/gob"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// pathCompleter completes the remote paths, listing the containers lazily as
+// the user types into them. The listings are cached, so that typing on in a
+// container doesn't list it again on every key.
+type pathCompleter struct {
+	mu       sync.Mutex
+	listings map[string]*argumentCompleter
+}
+
+func newPathCompleter() *pathCompleter {
+	return &pathCompleter{listings: map[string]*argumentCompleter{}}
+}
+
+// complete returns the paths completing the word, the ones of the folders only
+// when onlyDirs is set. Words that are not absolute paths are completed with
+// the entries of the home.
+func (p *pathCompleter) complete(word string, onlyDirs bool) []prompt.Suggest {
+	dir := "/home"
+	if strings.HasPrefix(word, "/") {
+		dir = word[:strings.LastIndex(word, "/")+1]
+		if dir != "/" {
+			dir = strings.TrimSuffix(dir, "/")
+		}
+	}
+
+	suggests := []prompt.Suggest{}
+	if dir == "/home" && !strings.HasPrefix(word, "/home/") {
+		suggests = append(suggests, prompt.Suggest{Text: "/home", Description: "folder"})
+	}
+	for _, s := range p.list(dir) {
+		if !onlyDirs || s.Description == "folder" {
+			suggests = append(suggests, s)
+		}
+	}
+	return prompt.FilterHasPrefix(suggests, word, false)
+}
+
+// list returns the entries of the container, from the cache if it was listed
+// recently. The failed listings are cached too, not to retry them on every key.
+func (p *pathCompleter) list(dir string) []prompt.Suggest {
+	key := dir
+	if conf != nil {
+		key = conf.Host + "!" + dir
+	}
+
+	p.mu.Lock()
+	a, ok := p.listings[key]
+	if !ok {
+		now := time.Now()
+		for k, l := range p.listings {
+			if expired(l, now) {
+				delete(p.listings, k)
+			}
+		}
+		a = new(argumentCompleter)
+		p.listings[key] = a
+	}
+	p.mu.Unlock()
+
+	if s, ok := checkCache(a); ok {
+		return s
+	}
+
+	suggests := []prompt.Suggest{}
+	b, err := executeCommand(lsCommand(), dir)
+	if err == nil {
+		info := []*provider.ResourceInfo{}
+		if err := gob.NewDecoder(&b).Decode(&info); err == nil {
+			for _, r := range info {
+				s := prompt.Suggest{Text: r.Path, Description: "file"}
+				if r.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+					s.Description = "folder"
+				}
+				if !strings.HasPrefix(s.Text, "/") {
+					s.Text = path.Join(dir, s.Text)
+				}
+				suggests = append(suggests, s)
+			}
+		}
+	}
+
+	cacheSuggestions(a, suggests)
+	return suggests
+}
+
+// invalidate drops the cached listings, e.g. once a command changed the files.
+func (p *pathCompleter) invalidate() {
+	p.mu.Lock()
+	p.listings = map[string]*argumentCompleter{}
+	p.mu.Unlock()
+}
+
+func expired(a *argumentCompleter, now time.Time) bool {
+	a.RLock()
+	defer a.RUnlock()
+	return !now.Before(a.expiration)
+}
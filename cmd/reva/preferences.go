@@ -67,6 +67,7 @@ var preferencesCommand = func() *command {
 			if res.Status.Code != rpc.Code_CODE_OK {
 				return formatError(res.Status)
 			}
+			return printDone()
 
 		case "get":
 			req := &preferences.GetKeyRequest{
@@ -82,12 +83,11 @@ var preferencesCommand = func() *command {
 				return formatError(res.Status)
 			}
 
-			fmt.Println(res.Val)
+			return printResult(map[string]string{"key": key, "value": res.Val}, func() { fmt.Println(res.Val) })
 
 		default:
 			return errors.New("Invalid arguments: " + cmd.Usage())
 		}
-		return nil
 	}
 	return cmd
 }
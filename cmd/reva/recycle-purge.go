@@ -61,7 +61,7 @@ func recyclePurgeCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printDone()
 	}
 	return cmd
 }
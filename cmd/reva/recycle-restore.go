@@ -68,7 +68,7 @@ func recycleRestoreCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printDone()
 	}
 	return cmd
 }
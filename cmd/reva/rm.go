@@ -55,7 +55,7 @@ func rmCommand() *command {
 			return formatError(res.Status)
 		}
 
-		return nil
+		return printDone()
 	}
 	return cmd
 }
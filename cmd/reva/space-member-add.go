@@ -0,0 +1,77 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/pkg/errors"
+)
+
+func spaceMemberAddCommand() *command {
+	cmd := newCommand("space-member-add")
+	cmd.Description = func() string { return "add a user or group to the members of a space, or change its role" }
+	cmd.Usage = func() string { return "Usage: space-member-add [-flags] <space_id>" }
+	grantType := cmd.String("type", "user", "grantee type (user or group)")
+	grantee := cmd.String("grantee", "", "the grantee")
+	idp := cmd.String("idp", "", "the idp of the grantee")
+	role := cmd.String("role", "viewer", "the role of the member (viewer, editor or manager)")
+
+	cmd.ResetFlags = func() {
+		*grantType, *grantee, *idp, *role = "user", "", "", "viewer"
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		if *grantee == "" {
+			return errors.New("Grantee cannot be empty: use -grantee flag\n" + cmd.Usage())
+		}
+		g, err := getGrantee(*grantType, *idp, *grantee)
+		if err != nil {
+			return err
+		}
+
+		ctx := getAuthContext()
+		client, err := getSpacesClient()
+		if err != nil {
+			return err
+		}
+
+		res, err := client.AddSpaceMember(ctx, &spacespb.AddSpaceMemberRequest{
+			Id:      &provider.StorageSpaceId{OpaqueId: cmd.Args()[0]},
+			Grantee: g,
+			Role:    *role,
+		})
+		if err != nil {
+			return err
+		}
+
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		return printOK()
+	}
+	return cmd
+}
@@ -0,0 +1,83 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+	"os"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+func spaceMemberListCommand() *command {
+	cmd := newCommand("space-member-list")
+	cmd.Description = func() string { return "list the members of a space" }
+	cmd.Usage = func() string { return "Usage: space-member-list <space_id>" }
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+
+		ctx := getAuthContext()
+		client, err := getSpacesClient()
+		if err != nil {
+			return err
+		}
+
+		res, err := client.ListSpaceMembers(ctx, &spacespb.ListSpaceMembersRequest{
+			Id: &provider.StorageSpaceId{OpaqueId: cmd.Args()[0]},
+		})
+		if err != nil {
+			return err
+		}
+
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		if jsonOutput() {
+			return printJSON(res.Members)
+		}
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Type", "Grantee.Idp", "Grantee.OpaqueId", "Role"})
+		for _, m := range res.Members {
+			var idp, opaque string
+			if m.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER {
+				idp, opaque = m.Grantee.GetUserId().Idp, m.Grantee.GetUserId().OpaqueId
+			} else if m.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
+				idp, opaque = m.Grantee.GetGroupId().Idp, m.Grantee.GetGroupId().OpaqueId
+			}
+			role := m.Role
+			if role == "" {
+				role = "custom"
+			}
+			t.AppendRow(table.Row{m.Grantee.Type.String(), idp, opaque, role})
+		}
+		t.Render()
+
+		return nil
+	}
+	return cmd
+}
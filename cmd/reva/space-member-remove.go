@@ -0,0 +1,93 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"io"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/pkg/errors"
+)
+
+func spaceMemberRemoveCommand() *command {
+	cmd := newCommand("space-member-remove")
+	cmd.Description = func() string { return "remove a user or group from the members of a space" }
+	cmd.Usage = func() string { return "Usage: space-member-remove [-flags] <space_id>" }
+	grantType := cmd.String("type", "user", "grantee type (user or group)")
+	grantee := cmd.String("grantee", "", "the grantee")
+	idp := cmd.String("idp", "", "the idp of the grantee")
+
+	cmd.ResetFlags = func() {
+		*grantType, *grantee, *idp = "user", "", ""
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		if *grantee == "" {
+			return errors.New("Grantee cannot be empty: use -grantee flag\n" + cmd.Usage())
+		}
+		g, err := getGrantee(*grantType, *idp, *grantee)
+		if err != nil {
+			return err
+		}
+
+		ctx := getAuthContext()
+		client, err := getSpacesClient()
+		if err != nil {
+			return err
+		}
+
+		res, err := client.RemoveSpaceMember(ctx, &spacespb.RemoveSpaceMemberRequest{
+			Id:      &provider.StorageSpaceId{OpaqueId: cmd.Args()[0]},
+			Grantee: g,
+		})
+		if err != nil {
+			return err
+		}
+
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		return printOK()
+	}
+	return cmd
+}
+
+func getGrantee(grantType, idp, id string) (*provider.Grantee, error) {
+	switch grantType {
+	case "user":
+		return &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: &userpb.UserId{Idp: idp, OpaqueId: id}},
+		}, nil
+	case "group":
+		return &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+			Id:   &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{Idp: idp, OpaqueId: id}},
+		}, nil
+	}
+	return nil, errors.New("Invalid grantee type argument: " + grantType)
+}
@@ -57,8 +57,7 @@ func statCommand() *command {
 			return formatError(res.Status)
 		}
 
-		fmt.Println(res.Info)
-		return nil
+		return printResult(res.Info, func() { fmt.Println(res.Info) })
 	}
 	return cmd
 }
@@ -44,7 +44,9 @@ func transferCancelCommand() *command {
 			return err
 		}
 
-		cancelRequest := &datatx.CancelTransferRequest{}
+		cancelRequest := &datatx.CancelTransferRequest{
+			TxId: &datatx.TxId{OpaqueId: *txID},
+		}
 
 		cancelResponse, err := client.CancelTransfer(ctx, cancelRequest)
 		if err != nil {
@@ -54,7 +56,7 @@ func transferCancelCommand() *command {
 			return formatError(cancelResponse.Status)
 		}
 
-		return nil
+		return printTransferInfo(cancelResponse.TxInfo, cancelResponse.Opaque)
 	}
 	return cmd
 }
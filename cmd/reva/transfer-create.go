@@ -153,6 +153,10 @@ func transferCreateCommand() *command {
 			return err
 		}
 
+		if jsonOutput() {
+			return printJSON(createShareResponse.Share)
+		}
+
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
 		t.AppendHeader(table.Row{"#", "Owner.Idp", "Owner.OpaqueId", "ResourceId", "Permissions", "Type", "Grantee.Idp", "Grantee.OpaqueId", "ShareType", "Created", "Updated"})
@@ -21,9 +21,13 @@ package main
 import (
 	"errors"
 	"io"
+	"os"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	datatxpkg "github.com/cs3org/reva/pkg/datatx"
+	"github.com/jedib0t/go-pretty/table"
 )
 
 func transferGetStatusCommand() *command {
@@ -44,7 +48,9 @@ func transferGetStatusCommand() *command {
 			return err
 		}
 
-		getStatusRequest := &datatx.GetTransferStatusRequest{}
+		getStatusRequest := &datatx.GetTransferStatusRequest{
+			TxId: &datatx.TxId{OpaqueId: *txID},
+		}
 
 		getStatusResponse, err := client.GetTransferStatus(ctx, getStatusRequest)
 		if err != nil {
@@ -54,7 +60,33 @@ func transferGetStatusCommand() *command {
 			return formatError(getStatusResponse.Status)
 		}
 
-		return nil
+		return printTransferInfo(getStatusResponse.TxInfo, getStatusResponse.Opaque)
 	}
 	return cmd
 }
+
+func printTransferInfo(info *datatx.TxInfo, progress *typespb.Opaque) error {
+	m := progress.GetMap()
+	get := func(k string) string {
+		if e, ok := m[k]; ok {
+			return string(e.Value)
+		}
+		return ""
+	}
+
+	if jsonOutput() {
+		p := make(map[string]string, len(m))
+		for k := range m {
+			p[k] = get(k)
+		}
+		return printJSON(map[string]interface{}{"info": info, "progress": p})
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"ID", "Status", "Bytes", "Total", "Files", "Attempts", "Error"})
+	t.AppendRow(table.Row{info.GetId().GetOpaqueId(), info.GetStatus().String(), get(datatxpkg.BytesOpaqueKey),
+		get(datatxpkg.TotalOpaqueKey), get(datatxpkg.FilesOpaqueKey), get(datatxpkg.AttemptsOpaqueKey), get(datatxpkg.ErrorOpaqueKey)})
+	t.Render()
+	return nil
+}
@@ -84,7 +84,7 @@ func uploadCommand() *command {
 			return err
 		}
 
-		fmt.Printf("Local file size: %d bytes\n", md.Size())
+		printInfo("Local file size: %d bytes\n", md.Size())
 
 		gwc, err := getClient()
 		if err != nil {
@@ -121,7 +121,8 @@ func uploadCommand() *command {
 				return err
 			}
 		} else {
-			return nil
+			printInfo("File uploaded\n")
+			return printDone()
 		}
 
 		p, err := getUploadProtocolInfo(res.Protocols, *protocolFlag)
@@ -129,21 +130,21 @@ func uploadCommand() *command {
 			return err
 		}
 
-		fmt.Printf("Data server: %s\n", p.UploadEndpoint)
-		fmt.Printf("Allowed checksums: %+v\n", p.AvailableChecksums)
+		printInfo("Data server: %s\n", p.UploadEndpoint)
+		printInfo("Allowed checksums: %+v\n", p.AvailableChecksums)
 
 		xsType, err := guessXS(*xsFlag, p.AvailableChecksums)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Checksum selected: %s\n", xsType)
+		printInfo("Checksum selected: %s\n", xsType)
 
 		xs, err := computeXS(xsType, fd)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Local XS: %s:%s\n", xsType, xs)
+		printInfo("Local XS: %s:%s\n", xsType, xs)
 		// seek back reader to 0
 		if _, err := fd.Seek(0, 0); err != nil {
 			return err
@@ -229,9 +230,9 @@ func uploadCommand() *command {
 
 		info := res2.Info
 
-		fmt.Printf("File uploaded: %s:%s %d %s\n", info.Id.StorageId, info.Id.OpaqueId, info.Size, info.Path)
-
-		return nil
+		return printResult(info, func() {
+			fmt.Printf("File uploaded: %s:%s %d %s\n", info.Id.StorageId, info.Id.OpaqueId, info.Size, info.Path)
+		})
 	}
 	return cmd
 }
@@ -287,7 +288,6 @@ func checkUploadWebdavRef(protocols []*gateway.FileUploadProtocol, md os.FileInf
 		return err
 	}
 
-	fmt.Println("File uploaded")
 	return nil
 }
 
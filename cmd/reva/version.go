@@ -32,8 +32,12 @@ var versionCommand = func() *command {
 		msg += "go_version=%s "
 		msg += "build_date=%s\n"
 
-		fmt.Printf(msg, version, gitCommit, goVersion, buildDate)
-		return nil
+		return printResult(map[string]string{
+			"version":    version,
+			"commit":     gitCommit,
+			"go_version": goVersion,
+			"build_date": buildDate,
+		}, func() { fmt.Printf(msg, version, gitCommit, goVersion, buildDate) })
 	}
 	return cmd
 }
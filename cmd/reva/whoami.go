@@ -47,8 +47,8 @@ func whoamiCommand() *command {
 			// read token from file
 			t, err := readToken()
 			if err != nil {
-				fmt.Println("the token file cannot be read from file ", getTokenFile())
-				fmt.Println("make sure you have logged in before with \"reva login\"")
+				printInfo("the token file cannot be read from file %s\n", getTokenFile())
+				printInfo("make sure you have logged in before with \"reva login\"\n")
 				return err
 			}
 			token = t
@@ -71,8 +71,7 @@ func whoamiCommand() *command {
 			return formatError(res.Status)
 		}
 
-		fmt.Println(res.User)
-		return nil
+		return printResult(res.User, func() { fmt.Println(res.User) })
 	}
 	return cmd
 }
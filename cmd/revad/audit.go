@@ -0,0 +1,62 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cs3org/reva/pkg/audit"
+)
+
+// handleVerifyAuditFlag verifies the hash chain of an audit log written by
+// the file sink and exits. The records are checked with the secret of the
+// audit interceptor of the first configuration enabling it.
+func handleVerifyAuditFlag(confs []map[string]interface{}) {
+	if *verifyAuditFlag == "" {
+		return
+	}
+
+	fd, err := os.Open(*verifyAuditFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	defer fd.Close()
+
+	n, err := audit.Verify(fd, []byte(getAuditSecret(confs)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit log %s is not valid after %d records: %v\n", *verifyAuditFlag, n, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "verified %d records of audit log %s\n", n, *verifyAuditFlag)
+	os.Exit(0)
+}
+
+func getAuditSecret(confs []map[string]interface{}) string {
+	for _, conf := range confs {
+		grpc, _ := conf["grpc"].(map[string]interface{})
+		interceptors, _ := grpc["interceptors"].(map[string]interface{})
+		if c, ok := interceptors["audit"].(map[string]interface{}); ok {
+			secret, _ := c["secret"].(string)
+			return secret
+		}
+	}
+	return ""
+}
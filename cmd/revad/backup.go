@@ -0,0 +1,150 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cs3org/reva/pkg/backup"
+	"github.com/cs3org/reva/pkg/errtypes"
+	inviteregistry "github.com/cs3org/reva/pkg/ocm/invite/manager/registry"
+	publicshareregistry "github.com/cs3org/reva/pkg/publicshare/manager/registry"
+	shareregistry "github.com/cs3org/reva/pkg/share/manager/registry"
+)
+
+// handleBackupFlags exports or imports the data of the managers configured
+// for the usershareprovider, publicshareprovider and ocminvitemanager
+// services and exits. Services that are not configured are skipped.
+func handleBackupFlags(confs []map[string]interface{}) {
+	if *exportFlag == "" && *importFlag == "" {
+		return
+	}
+	if *exportFlag != "" && *importFlag != "" {
+		fmt.Fprintf(os.Stderr, "the -export and -import flags cannot be used together\n")
+		os.Exit(1)
+	}
+
+	m, err := getBackupManagers(confs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating the managers: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	if *exportFlag != "" {
+		err = exportBackup(ctx, m, *exportFlag)
+	} else {
+		err = importBackup(ctx, m, *importFlag)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func exportBackup(ctx context.Context, m backup.Managers, file string) error {
+	a, err := backup.Export(ctx, m)
+	if err != nil {
+		return fmt.Errorf("error exporting: %w", err)
+	}
+	fd, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	if err := backup.Encode(fd, a); err != nil {
+		return fmt.Errorf("error writing %s: %w", file, err)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d shares, %d public shares and %d ocm invites to %s\n", len(a.Shares), len(a.PublicShares), len(a.Invites), file)
+	return nil
+}
+
+func importBackup(ctx context.Context, m backup.Managers, file string) error {
+	fd, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	a, err := backup.Decode(fd)
+	if err != nil {
+		return err
+	}
+	if err := backup.Import(ctx, m, a); err != nil {
+		return fmt.Errorf("error importing: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "imported %d shares, %d public shares and %d ocm invites from %s\n", len(a.Shares), len(a.PublicShares), len(a.Invites), file)
+	return nil
+}
+
+func getBackupManagers(confs []map[string]interface{}) (backup.Managers, error) {
+	m := backup.Managers{}
+
+	if driver, c, ok := getServiceDriver(confs, "usershareprovider"); ok {
+		f, ok := shareregistry.NewFuncs[driver]
+		if !ok {
+			return m, errtypes.NotFound("share driver not found: " + driver)
+		}
+		mgr, err := f(c)
+		if err != nil {
+			return m, err
+		}
+		m.Shares = mgr
+	}
+
+	if driver, c, ok := getServiceDriver(confs, "publicshareprovider"); ok {
+		f, ok := publicshareregistry.NewFuncs[driver]
+		if !ok {
+			return m, errtypes.NotFound("public share driver not found: " + driver)
+		}
+		mgr, err := f(c)
+		if err != nil {
+			return m, err
+		}
+		m.PublicShares = mgr
+	}
+
+	if driver, c, ok := getServiceDriver(confs, "ocminvitemanager"); ok {
+		f, ok := inviteregistry.NewFuncs[driver]
+		if !ok {
+			return m, errtypes.NotFound("invite driver not found: " + driver)
+		}
+		mgr, err := f(c)
+		if err != nil {
+			return m, err
+		}
+		m.Invites = mgr
+	}
+
+	return m, nil
+}
+
+// getServiceDriver returns the driver name and configuration of the first
+// grpc service with the given name found in the configurations.
+func getServiceDriver(confs []map[string]interface{}, service string) (string, map[string]interface{}, bool) {
+	svcs := getServices(confs, service)
+	if len(svcs) == 0 {
+		return "", nil, false
+	}
+	// same default as the services
+	driver, c := serviceDriver(svcs[0], "json")
+	return driver, c, true
+}
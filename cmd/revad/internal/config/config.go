@@ -26,7 +26,10 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Read reads the configuration from the reader.
+// Read reads the configuration from the reader. The references to the
+// environment variables, ${NAME} or ${NAME:-default}, in the string values
+// are replaced by their values, and the values starting with file:// by the
+// content of the file they point to, e.g. file:///run/secrets/db_password.
 func Read(r io.Reader) (map[string]interface{}, error) {
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -41,5 +44,9 @@ func Read(r io.Reader) (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	if err := interpolate(v); err != nil {
+		return nil, err
+	}
+
 	return v, nil
 }
@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "db_password")
+	if err := ioutil.WriteFile(secret, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("REVA_TEST_HOST", "db.example.org")
+	defer os.Unsetenv("REVA_TEST_HOST")
+
+	v, err := Read(strings.NewReader(`
+[shared]
+jwt_secret = "${REVA_TEST_JWT_SECRET:-changeme}"
+
+[grpc.services.usershareprovider.drivers.sql]
+db_host = "${REVA_TEST_HOST}:3306"
+db_password = "file://` + secret + `"
+comment = "costs $${REVA_TEST_HOST}"
+
+[[http.middlewares.cors.allowed_origins]]
+origin = "https://${REVA_TEST_HOST}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := v["shared"].(map[string]interface{})["jwt_secret"]; got != "changeme" {
+		t.Errorf("jwt_secret = %v, want the default", got)
+	}
+	sql := v["grpc"].(map[string]interface{})["services"].(map[string]interface{})["usershareprovider"].(map[string]interface{})["drivers"].(map[string]interface{})["sql"].(map[string]interface{})
+	for k, want := range map[string]string{
+		"db_host":     "db.example.org:3306",
+		"db_password": "s3cr3t",
+		"comment":     "costs ${REVA_TEST_HOST}",
+	} {
+		if sql[k] != want {
+			t.Errorf("%s = %v, want %s", k, sql[k], want)
+		}
+	}
+	origins := v["http"].(map[string]interface{})["middlewares"].(map[string]interface{})["cors"].(map[string]interface{})["allowed_origins"].([]map[string]interface{})
+	if origins[0]["origin"] != "https://db.example.org" {
+		t.Errorf("origin = %v", origins[0]["origin"])
+	}
+}
+
+func TestReadMissingSecrets(t *testing.T) {
+	_, err := Read(strings.NewReader(`
+[shared]
+jwt_secret = "${REVA_TEST_UNSET}"
+
+[grpc.services.authprovider]
+secret = "file:///nonexistent/reva/secret"
+`))
+	if err == nil {
+		t.Fatal("read a configuration with missing secrets")
+	}
+	for _, want := range []string{"shared.jwt_secret: environment variable REVA_TEST_UNSET not set", "grpc.services.authprovider.secret:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %q", err, want)
+		}
+	}
+}
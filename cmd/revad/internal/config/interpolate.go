@@ -0,0 +1,114 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// secretFilePrefix starts the string values replaced by the content of the
+// file they point to, e.g. file:///run/secrets/db_password.
+const secretFilePrefix = "file://"
+
+// envRef matches the references to the environment variables, ${NAME} or
+// ${NAME:-default}, and the escaped ones, $${NAME}, kept as ${NAME}.
+var envRef = regexp.MustCompile(`\$(\$)?\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// interpolate replaces, in the string values of the configuration, the
+// references to the environment variables and the values naming a secret
+// file by their values. All the references which can't be resolved, the
+// variables not set without a default and the files which can't be read,
+// are reported in the error so that revad refuses to start without its
+// secrets.
+func interpolate(v map[string]interface{}) error {
+	var missing []string
+	interpolateMap("", v, &missing)
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.New("config: error interpolating " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func interpolateMap(prefix string, m map[string]interface{}, missing *[]string) {
+	for k, v := range m {
+		m[k] = interpolateValue(join(prefix, k), v, missing)
+	}
+}
+
+func interpolateValue(key string, v interface{}, missing *[]string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return interpolateString(key, v, missing)
+	case map[string]interface{}:
+		interpolateMap(key, v, missing)
+	case []map[string]interface{}:
+		for i, m := range v {
+			interpolateMap(fmt.Sprintf("%s[%d]", key, i), m, missing)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = interpolateValue(fmt.Sprintf("%s[%d]", key, i), e, missing)
+		}
+	}
+	return v
+}
+
+func interpolateString(key, s string, missing *[]string) string {
+	if strings.HasPrefix(s, secretFilePrefix) {
+		file := strings.TrimPrefix(s, secretFilePrefix)
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			// the error tells why, e.g. the permissions, but not the content
+			*missing = append(*missing, fmt.Sprintf("%s: %v", key, err))
+			return s
+		}
+		// the files written by hand or by echo end with a new line
+		return strings.TrimRight(string(data), "\r\n")
+	}
+
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		groups := envRef.FindStringSubmatch(ref)
+		if groups[1] != "" {
+			return ref[1:]
+		}
+		if val, ok := os.LookupEnv(groups[2]); ok {
+			return val
+		}
+		if groups[3] != "" {
+			return strings.TrimPrefix(groups[3], ":-")
+		}
+		*missing = append(*missing, fmt.Sprintf("%s: environment variable %s not set", key, groups[2]))
+		return ref
+	})
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
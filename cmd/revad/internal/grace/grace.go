@@ -30,6 +30,8 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/cs3org/reva/pkg/plugin"
+	"github.com/cs3org/reva/pkg/tracing"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -44,6 +46,8 @@ type Watcher struct {
 	ss        map[string]Server
 	pidFile   string
 	childPIDs []int
+	reload    func()
+	shutdown  func()
 }
 
 // Option represent an option.
@@ -63,6 +67,22 @@ func WithPIDFile(fn string) Option {
 	}
 }
 
+// WithReload makes SIGHUP call the given function to reload the configuration
+// in place instead of forking a child process.
+func WithReload(f func()) Option {
+	return func(w *Watcher) {
+		w.reload = f
+	}
+}
+
+// WithShutdown makes the shutdowns call the given function before the servers
+// are stopped, e.g. to withdraw them from the service registry.
+func WithShutdown(f func()) Option {
+	return func(w *Watcher) {
+		w.shutdown = f
+	}
+}
+
 // NewWatcher creates a Watcher.
 func NewWatcher(opts ...Option) *Watcher {
 	w := &Watcher{
@@ -82,6 +102,10 @@ func NewWatcher(opts ...Option) *Watcher {
 // Exit exits the current process cleaning up
 // existing pid files.
 func (w *Watcher) Exit(errc int) {
+	// the plugin processes would outlive us
+	plugin.Cleanup()
+	// and the last spans would be lost
+	tracing.Flush()
 	err := w.clean()
 	if err != nil {
 		w.log.Warn().Err(err).Msg("error removing pid file")
@@ -260,13 +284,18 @@ type Server interface {
 // TrapSignals captures the OS signal.
 func (w *Watcher) TrapSignals() {
 	signalCh := make(chan os.Signal, 1024)
-	signal.Notify(signalCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT)
+	signal.Notify(signalCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
 	for {
 		s := <-signalCh
 		w.log.Info().Msgf("%v signal received", s)
 
 		switch s {
 		case syscall.SIGHUP:
+			if w.reload != nil {
+				w.log.Info().Msg("reloading the configuration in place")
+				w.reload()
+				continue
+			}
 			w.log.Info().Msg("preparing for a hot-reload, forking child process...")
 
 			// Fork a child process.
@@ -279,40 +308,13 @@ func (w *Watcher) TrapSignals() {
 				w.childPIDs = append(w.childPIDs, p.Pid)
 			}
 
-		case syscall.SIGQUIT:
-			w.log.Info().Msg("preparing for a graceful shutdown with deadline of 10 seconds")
-			go func() {
-				count := 10
-				ticker := time.NewTicker(time.Second)
-				for ; true; <-ticker.C {
-					w.log.Info().Msgf("shutting down in %d seconds", count-1)
-					count--
-					if count <= 0 {
-						w.log.Info().Msg("deadline reached before draining active conns, hard stopping ...")
-						for _, s := range w.ss {
-							err := s.Stop()
-							if err != nil {
-								w.log.Error().Err(err).Msg("error stopping server")
-							}
-							w.log.Info().Msgf("fd to %s:%s abruptly closed", s.Network(), s.Address())
-						}
-						w.Exit(1)
-					}
-				}
-			}()
-			for _, s := range w.ss {
-				w.log.Info().Msgf("fd to %s:%s gracefully closed ", s.Network(), s.Address())
-				err := s.GracefulStop()
-				if err != nil {
-					w.log.Error().Err(err).Msg("error stopping server")
-					w.log.Info().Msg("exit with error code 1")
-					w.Exit(1)
-				}
-			}
-			w.log.Info().Msg("exit with error code 0")
-			w.Exit(0)
-		case syscall.SIGINT, syscall.SIGTERM:
+		case syscall.SIGQUIT, syscall.SIGTERM:
+			w.Exit(w.gracefulStop())
+		case syscall.SIGINT:
 			w.log.Info().Msg("preparing for hard shutdown, aborting all conns")
+			if w.shutdown != nil {
+				w.shutdown()
+			}
 			for _, s := range w.ss {
 				w.log.Info().Msgf("fd to %s:%s abruptly closed", s.Network(), s.Address())
 				err := s.Stop()
@@ -325,6 +327,46 @@ func (w *Watcher) TrapSignals() {
 	}
 }
 
+// gracefulStop stops the servers from accepting new requests and waits for
+// them to drain the ones in progress, e.g. the transfers, until their shutdown
+// deadline, before their services are closed and the storage drivers shut
+// down. The servers are drained together. It returns the exit code.
+func (w *Watcher) gracefulStop() int {
+	w.log.Info().Msg("preparing for a graceful shutdown")
+	if w.shutdown != nil {
+		w.shutdown()
+	}
+
+	errs := make(chan error, len(w.ss))
+	for _, s := range w.ss {
+		go func(s Server) {
+			err := s.GracefulStop()
+			if err == nil {
+				w.log.Info().Msgf("fd to %s:%s gracefully closed", s.Network(), s.Address())
+			}
+			errs <- err
+		}(s)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	code := 0
+	for pending := len(w.ss); pending > 0; {
+		select {
+		case err := <-errs:
+			pending--
+			if err != nil {
+				w.log.Error().Err(err).Msg("error stopping server")
+				code = 1
+			}
+		case <-ticker.C:
+			w.log.Info().Msgf("waiting for %d server(s) to drain their active conns", pending)
+		}
+	}
+	w.log.Info().Msgf("exit with error code %d", code)
+	return code
+}
+
 func getListenerFile(ln net.Listener) (*os.File, error) {
 	switch t := ln.(type) {
 	case *net.TCPListener:
@@ -37,13 +37,17 @@ import (
 )
 
 var (
-	versionFlag = flag.Bool("version", false, "show version and exit")
-	testFlag    = flag.Bool("t", false, "test configuration and exit")
-	signalFlag  = flag.String("s", "", "send signal to a master process: stop, quit, reload")
-	configFlag  = flag.String("c", "/etc/revad/revad.toml", "set configuration file")
-	pidFlag     = flag.String("p", "", "pid file. If empty defaults to a random file in the OS temporary directory")
-	logFlag     = flag.String("log", "", "log messages with the given severity or above. One of: [trace, debug, info, warn, error, fatal, panic]")
-	dirFlag     = flag.String("dev-dir", "", "runs any toml file in the specified directory. Intended for development use only")
+	versionFlag     = flag.Bool("version", false, "show version and exit")
+	testFlag        = flag.Bool("t", false, "test configuration and exit")
+	signalFlag      = flag.String("s", "", "send signal to a master process: stop, quit, reload")
+	configFlag      = flag.String("c", "/etc/revad/revad.toml", "set configuration file")
+	pidFlag         = flag.String("p", "", "pid file. If empty defaults to a random file in the OS temporary directory")
+	logFlag         = flag.String("log", "", "log messages with the given severity or above. One of: [trace, debug, info, warn, error, fatal, panic]")
+	dirFlag         = flag.String("dev-dir", "", "runs any toml file in the specified directory. Intended for development use only")
+	exportFlag      = flag.String("export", "", "export the shares, public shares and ocm invites of the configured managers to a new file and exit")
+	importFlag      = flag.String("import", "", "import the shares, public shares and ocm invites from the given file into the configured managers and exit")
+	renameFlag      = flag.String("rename-user", "", "rename a user given as old:new in the configured user, storage and share managers and exit")
+	verifyAuditFlag = flag.String("verify-audit", "", "verify the hash chain of the given audit log with the secret of the configured audit interceptor and exit")
 
 	// Compile time variables initialized with gcc flags.
 	gitCommit, buildDate, version, goVersion string
@@ -80,6 +84,10 @@ func main() {
 		os.Exit(0)
 	}
 
+	handleBackupFlags(confs)
+	handleRenameFlag(confs)
+	handleVerifyAuditFlag(confs)
+
 	runConfigs(confs)
 }
 
@@ -108,7 +116,8 @@ func handleSignalFlag() {
 		case "quit":
 			signal = syscall.SIGQUIT
 		case "stop":
-			signal = syscall.SIGTERM
+			// SIGTERM drains the servers like SIGQUIT, as sent by the orchestrators
+			signal = syscall.SIGINT
 		default:
 			fmt.Fprintf(os.Stderr, "unknown signal %q\n", *signalFlag)
 			os.Exit(1)
@@ -211,7 +220,12 @@ func runSingle(conf map[string]interface{}) {
 		*pidFlag = getPidfile()
 	}
 
-	runtime.Run(conf, *pidFlag, *logFlag)
+	var opts []runtime.Option
+	// the configurations of a development directory are not reloaded
+	if *dirFlag == "" {
+		opts = append(opts, runtime.WithConfigFile(*configFlag))
+	}
+	runtime.Run(conf, *pidFlag, *logFlag, opts...)
 }
 
 func getPidfile() string {
@@ -0,0 +1,172 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	publicshareregistry "github.com/cs3org/reva/pkg/publicshare/manager/registry"
+	shareregistry "github.com/cs3org/reva/pkg/share/manager/registry"
+	cacheregistry "github.com/cs3org/reva/pkg/storage/cache/registry"
+	fsregistry "github.com/cs3org/reva/pkg/storage/fs/registry"
+	userregistry "github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/cs3org/reva/pkg/user/rename"
+)
+
+// handleRenameFlag renames a user in the managers configured for the
+// userprovider, storageprovider, usershareprovider and publicshareprovider
+// services and exits. The revad processes running these services should be
+// stopped while renaming, they keep the old user in their caches otherwise.
+func handleRenameFlag(confs []map[string]interface{}) {
+	if *renameFlag == "" {
+		return
+	}
+	parts := strings.SplitN(*renameFlag, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		fmt.Fprintf(os.Stderr, "the -rename-user flag expects old:new usernames\n")
+		os.Exit(1)
+	}
+
+	m, err := getRenameManagers(confs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating the managers: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	u, err := m.Users.GetUserByClaim(ctx, "username", parts[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error getting user %s: %v\n", parts[0], err)
+		os.Exit(1)
+	}
+	renamed, err := rename.Rename(ctx, m, u.Id, parts[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error renaming user %s: %v\n", parts[0], err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "renamed user %s to %s with id %s\n", parts[0], renamed.Username, renamed.Id.OpaqueId)
+	os.Exit(0)
+}
+
+func getRenameManagers(confs []map[string]interface{}) (rename.Managers, error) {
+	m := rename.Managers{}
+
+	driver, c, ok := getServiceDriver(confs, "userprovider")
+	if !ok {
+		return m, errtypes.NotFound("no userprovider service configured")
+	}
+	uf, ok := userregistry.NewFuncs[driver]
+	if !ok {
+		return m, errtypes.NotFound("user driver not found: " + driver)
+	}
+	um, err := uf(c)
+	if err != nil {
+		return m, err
+	}
+	m.Users = um
+
+	for _, svc := range getServices(confs, "storageprovider") {
+		driver, c := serviceDriver(svc, "localhome")
+		f, ok := fsregistry.NewFuncs[driver]
+		if !ok {
+			return m, errtypes.NotFound("storage driver not found: " + driver)
+		}
+		fs, err := f(c)
+		if err != nil {
+			return m, err
+		}
+		m.Storages = append(m.Storages, fs)
+
+		// the homes of storages without home support are visible below the mount path
+		if layout, _ := c["user_layout"].(string); layout != "" {
+			mountPath, _ := svc["mount_path"].(string)
+			m.CachePaths = append(m.CachePaths, path.Join("/", mountPath, layout))
+		}
+	}
+
+	if driver, c, ok := getServiceDriver(confs, "usershareprovider"); ok {
+		f, ok := shareregistry.NewFuncs[driver]
+		if !ok {
+			return m, errtypes.NotFound("share driver not found: " + driver)
+		}
+		if m.Shares, err = f(c); err != nil {
+			return m, err
+		}
+	}
+
+	if driver, c, ok := getServiceDriver(confs, "publicshareprovider"); ok {
+		f, ok := publicshareregistry.NewFuncs[driver]
+		if !ok {
+			return m, errtypes.NotFound("public share driver not found: " + driver)
+		}
+		if m.PublicShares, err = f(c); err != nil {
+			return m, err
+		}
+	}
+
+	// an in-memory stat cache lives in the gateway process, it cannot be reached from here
+	if gws := getServices(confs, "gateway"); len(gws) > 0 {
+		driver, _ := gws[0]["stat_cache_driver"].(string)
+		if driver != "" && driver != "memory" {
+			drivers, _ := gws[0]["stat_cache_drivers"].(map[string]interface{})
+			c, _ := drivers[driver].(map[string]interface{})
+			f, ok := cacheregistry.NewFuncs[driver]
+			if !ok {
+				return m, errtypes.NotFound("stat cache driver not found: " + driver)
+			}
+			if m.Cache, err = f(c); err != nil {
+				return m, err
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// getServices returns the configurations of all the grpc services with the given name.
+func getServices(confs []map[string]interface{}, service string) []map[string]interface{} {
+	var svcs []map[string]interface{}
+	for _, conf := range confs {
+		grpc, _ := conf["grpc"].(map[string]interface{})
+		services, _ := grpc["services"].(map[string]interface{})
+		if svc, ok := services[service].(map[string]interface{}); ok {
+			svcs = append(svcs, svc)
+		}
+	}
+	return svcs
+}
+
+// serviceDriver returns the driver name and configuration of a service.
+func serviceDriver(svc map[string]interface{}, defaultDriver string) (string, map[string]interface{}) {
+	driver, _ := svc["driver"].(string)
+	if driver == "" {
+		driver = defaultDriver
+	}
+	drivers, _ := svc["drivers"].(map[string]interface{})
+	c, _ := drivers[driver].(map[string]interface{})
+	if c == nil {
+		c = map[string]interface{}{}
+	}
+	return driver, c
+}
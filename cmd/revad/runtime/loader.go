@@ -27,20 +27,34 @@ import (
 	_ "github.com/cs3org/reva/internal/http/interceptors/auth/tokenwriter/loader"
 	_ "github.com/cs3org/reva/internal/http/interceptors/loader"
 	_ "github.com/cs3org/reva/internal/http/services/loader"
+	_ "github.com/cs3org/reva/pkg/antivirus/loader"
 	_ "github.com/cs3org/reva/pkg/appauth/manager/loader"
+	_ "github.com/cs3org/reva/pkg/audit/sink/loader"
 	_ "github.com/cs3org/reva/pkg/auth/manager/loader"
 	_ "github.com/cs3org/reva/pkg/auth/registry/loader"
 	_ "github.com/cs3org/reva/pkg/cbox/loader"
+	_ "github.com/cs3org/reva/pkg/datatx/manager/loader"
+	_ "github.com/cs3org/reva/pkg/events/loader"
+	_ "github.com/cs3org/reva/pkg/favorite/manager/loader"
 	_ "github.com/cs3org/reva/pkg/group/manager/loader"
+	_ "github.com/cs3org/reva/pkg/kms/loader"
 	_ "github.com/cs3org/reva/pkg/metrics/driver/loader"
 	_ "github.com/cs3org/reva/pkg/ocm/invite/manager/loader"
 	_ "github.com/cs3org/reva/pkg/ocm/provider/authorizer/loader"
 	_ "github.com/cs3org/reva/pkg/ocm/share/manager/loader"
 	_ "github.com/cs3org/reva/pkg/publicshare/manager/loader"
+	_ "github.com/cs3org/reva/pkg/registry/loader"
 	_ "github.com/cs3org/reva/pkg/rhttp/datatx/manager/loader"
+	_ "github.com/cs3org/reva/pkg/search/index/loader"
 	_ "github.com/cs3org/reva/pkg/share/manager/loader"
+	_ "github.com/cs3org/reva/pkg/storage/cache/loader"
+	_ "github.com/cs3org/reva/pkg/storage/changes/loader"
 	_ "github.com/cs3org/reva/pkg/storage/fs/loader"
 	_ "github.com/cs3org/reva/pkg/storage/registry/loader"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/loader"
+	_ "github.com/cs3org/reva/pkg/thumbnail/loader"
 	_ "github.com/cs3org/reva/pkg/token/manager/loader"
+	_ "github.com/cs3org/reva/pkg/token/revocation/loader"
+	_ "github.com/cs3org/reva/pkg/twofactor/loader"
 	_ "github.com/cs3org/reva/pkg/user/manager/loader"
 )
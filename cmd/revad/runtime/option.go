@@ -30,6 +30,8 @@ type Option func(o *Options)
 type Options struct {
 	Logger   *zerolog.Logger
 	Registry registry.Registry
+	// ConfigFile is the file the configuration is reloaded from.
+	ConfigFile string
 }
 
 // newOptions initializes the available default options.
@@ -56,3 +58,11 @@ func WithRegistry(r registry.Registry) Option {
 		o.Registry = r
 	}
 }
+
+// WithConfigFile provides a function to set the file the configuration is
+// reloaded from.
+func WithConfigFile(fn string) Option {
+	return func(o *Options) {
+		o.ConfigFile = fn
+	}
+}
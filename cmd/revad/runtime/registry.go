@@ -0,0 +1,106 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cs3org/reva/pkg/registry"
+	"github.com/cs3org/reva/pkg/registry/memory"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
+)
+
+type registryConf struct {
+	// Driver is the service registry the services of the process are registered in and
+	// the addresses named in the configuration are resolved with, e.g. etcd or consul.
+	// Without a driver only the static services are known.
+	Driver  string                            `mapstructure:"driver"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// Services are the static services, added to the memory registry without a driver.
+	Services map[string]interface{} `mapstructure:"services"`
+	// Names maps the names of the services of the process to the names they are registered
+	// under, e.g. storageprovider to storage-home, which default to the former.
+	Names map[string]string `mapstructure:"names"`
+	// Weight is the share of the calls to its services the process gets.
+	Weight int `mapstructure:"weight"`
+	// Interval is the time in seconds after which the services are registered again, along
+	// with the status of the readiness checks of the process.
+	Interval int `mapstructure:"interval"`
+	// CacheTTL is the time in seconds the services looked up are cached for.
+	CacheTTL int `mapstructure:"cache_ttl"`
+}
+
+func (c *registryConf) init() {
+	if c.Weight == 0 {
+		c.Weight = 1
+	}
+	if c.Interval == 0 {
+		c.Interval = 10
+	}
+	if c.CacheTTL == 0 {
+		c.CacheTTL = 5
+	}
+}
+
+// initRegistry sets up the global service registry. It returns the registrar
+// keeping the services of the process registered, nil without a driver.
+func initRegistry(v interface{}, options Options, log *zerolog.Logger) *registry.Registrar {
+	if options.Registry != nil {
+		utils.GlobalRegistry = options.Registry
+		return nil
+	}
+
+	c := &registryConf{}
+	if err := mapstructure.Decode(v, c); err != nil {
+		fmt.Fprintf(os.Stderr, "error decoding registry config: %s\n", err.Error())
+		os.Exit(1)
+	}
+	c.init()
+
+	if c.Driver == "" {
+		for _, services := range c.Services {
+			for sName, nodes := range services.(map[string]interface{}) {
+				for _, instance := range nodes.([]interface{}) {
+					if err := utils.GlobalRegistry.Add(memory.NewService(sName, instance.(map[string]interface{})["nodes"].([]interface{}))); err != nil {
+						panic(err)
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		log.Error().Msgf("registry driver %q not found", c.Driver)
+		os.Exit(1)
+	}
+	r, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		log.Error().Err(err).Msgf("error creating registry driver %q", c.Driver)
+		os.Exit(1)
+	}
+	log.Info().Msgf("services registered in the %s registry", c.Driver)
+	utils.GlobalRegistry = registry.NewCache(r, time.Duration(c.CacheTTL)*time.Second)
+	return registry.NewRegistrar(utils.GlobalRegistry, c.Weight, time.Duration(c.Interval)*time.Second, c.Names)
+}
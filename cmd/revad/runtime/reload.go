@@ -0,0 +1,124 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package runtime
+
+import (
+	"os"
+	"time"
+
+	"github.com/cs3org/reva/cmd/revad/internal/config"
+	"github.com/cs3org/reva/pkg/reload"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// reloader re-reads the configuration file and applies the sections that
+// can be swapped at runtime.
+type reloader struct {
+	file string
+	log  *zerolog.Logger
+}
+
+func (r *reloader) reload(trigger string) {
+	var s *reload.Status
+	conf, err := readConfig(r.file)
+	if err != nil {
+		s = reload.Fail(trigger, err)
+	} else {
+		s = reload.Apply(conf, trigger)
+	}
+
+	if s.Error != "" {
+		r.log.Error().Str("file", r.file).Str("trigger", trigger).Msgf("error reloading the configuration: %s", s.Error)
+		return
+	}
+	for section, res := range s.Sections {
+		if res.Status == reload.Failed {
+			r.log.Error().Str("section", section).Msgf("error reloading the configuration: %s", res.Error)
+		} else {
+			r.log.Info().Str("section", section).Msg("configuration reloaded")
+		}
+	}
+	if len(s.RestartRequired) > 0 {
+		r.log.Warn().Strs("keys", s.RestartRequired).Msg("configuration changes that only apply after a restart")
+	}
+}
+
+// watch reloads the configuration when the file changes.
+func (r *reloader) watch(interval time.Duration) {
+	last, _ := os.Stat(r.file)
+	for range time.Tick(interval) {
+		fi, err := os.Stat(r.file)
+		if err != nil {
+			// the file may be in the middle of being replaced
+			continue
+		}
+		if last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+			continue
+		}
+		last = fi
+		r.reload("watch")
+	}
+}
+
+func readConfig(file string) (map[string]interface{}, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return config.Read(fd)
+}
+
+// reloadLog returns the reload function of the log section. The logger is
+// created with the lowest level and the configured one is set as the global
+// level, which is what is reloaded. The output and the mode only change
+// after a restart, and the level given on the command line has priority.
+func reloadLog(started *logConf, flagLevel string) reload.Func {
+	return func(m map[string]interface{}) error {
+		c := &logConf{}
+		if err := mapstructure.Decode(m, c); err != nil {
+			return errors.Wrap(err, "error decoding log config")
+		}
+		if c.Mode == "" {
+			c.Mode = "console"
+		}
+		if c.Output != started.Output || c.Mode != started.Mode {
+			return errors.New("only the level of the logs can be reloaded")
+		}
+		if flagLevel != "" {
+			return nil
+		}
+		return setLogLevel(c.Level)
+	}
+}
+
+func setLogLevel(level string) error {
+	// same default as the logger
+	if level == "" {
+		level = zerolog.DebugLevel.String()
+	}
+	l, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	zerolog.SetGlobalLevel(l)
+	return nil
+}
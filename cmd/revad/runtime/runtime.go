@@ -27,17 +27,19 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-
-	"github.com/cs3org/reva/pkg/registry/memory"
-
-	"github.com/cs3org/reva/pkg/utils"
+	"time"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
 	"github.com/cs3org/reva/cmd/revad/internal/grace"
 	"github.com/cs3org/reva/pkg/logger"
+	"github.com/cs3org/reva/pkg/mtls"
+	"github.com/cs3org/reva/pkg/permissions"
+	"github.com/cs3org/reva/pkg/registry"
+	"github.com/cs3org/reva/pkg/reload"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/tracing"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -48,34 +50,27 @@ import (
 )
 
 // Run runs a reva server with the given config file and pid file.
-func Run(mainConf map[string]interface{}, pidFile, logLevel string) {
+func Run(mainConf map[string]interface{}, pidFile, logLevel string, opts ...Option) {
 	logConf := parseLogConfOrDie(mainConf["log"], logLevel)
-	logger := initLogger(logConf)
-	RunWithOptions(mainConf, pidFile, WithLogger(logger))
+	hotReload := newOptions(opts...).ConfigFile != "" && parseCoreConfOrDie(mainConf["core"]).HotReload
+	logger := initLogger(logConf, hotReload)
+	if hotReload {
+		reload.Register("log", reloadLog(logConf, logLevel))
+	}
+	RunWithOptions(mainConf, pidFile, append(opts, WithLogger(logger))...)
 }
 
 // RunWithOptions runs a reva server with the given config file, pid file and options.
 func RunWithOptions(mainConf map[string]interface{}, pidFile string, opts ...Option) {
 	options := newOptions(opts...)
 	parseSharedConfOrDie(mainConf["shared"])
+	parseRolesConfOrDie(mainConf["roles"])
+	parseMTLSConfOrDie(mainConf["mtls"])
 	coreConf := parseCoreConfOrDie(mainConf["core"])
 
-	// TODO: one can pass the options from the config file to registry.New() and initialize a registry based upon config files.
-	if options.Registry != nil {
-		utils.GlobalRegistry = options.Registry
-	} else if _, ok := mainConf["registry"]; ok {
-		for _, services := range mainConf["registry"].(map[string]interface{}) {
-			for sName, nodes := range services.(map[string]interface{}) {
-				for _, instance := range nodes.([]interface{}) {
-					if err := utils.GlobalRegistry.Add(memory.NewService(sName, instance.(map[string]interface{})["nodes"].([]interface{}))); err != nil {
-						panic(err)
-					}
-				}
-			}
-		}
-	}
+	registrar := initRegistry(mainConf["registry"], options, options.Logger)
 
-	run(mainConf, coreConf, options.Logger, pidFile)
+	run(mainConf, coreConf, options.Logger, pidFile, options.ConfigFile, registrar)
 }
 
 type coreConf struct {
@@ -84,22 +79,37 @@ type coreConf struct {
 	TracingEndpoint    string `mapstructure:"tracing_endpoint"`
 	TracingCollector   string `mapstructure:"tracing_collector"`
 	TracingServiceName string `mapstructure:"tracing_service_name"`
+	// TracingExporter is jaeger or otlp, which posts the spans to the
+	// tracing_collector of an OpenTelemetry collector with TracingHeaders.
+	TracingExporter   string            `mapstructure:"tracing_exporter"`
+	TracingHeaders    map[string]string `mapstructure:"tracing_headers"`
+	TracingSampleRate float64           `mapstructure:"tracing_sample_rate"`
+	// HotReload makes SIGHUP reload the sections of the configuration file
+	// that can be swapped at runtime instead of forking a new process.
+	HotReload bool `mapstructure:"hot_reload"`
+	// WatchConfig reloads them when the configuration file changes, which
+	// is checked every WatchInterval seconds.
+	WatchConfig   bool `mapstructure:"watch_config"`
+	WatchInterval int  `mapstructure:"watch_interval"`
 }
 
-func run(mainConf map[string]interface{}, coreConf *coreConf, logger *zerolog.Logger, filename string) {
+func run(mainConf map[string]interface{}, coreConf *coreConf, logger *zerolog.Logger, filename, configFile string, registrar *registry.Registrar) {
 	host, _ := os.Hostname()
 	logger.Info().Msgf("host info: %s", host)
 
-	// initRegistry()
 	initTracing(coreConf, logger)
 	initCPUCount(coreConf, logger)
+	reloadFunc := initReload(mainConf, coreConf, configFile, logger)
 
-	servers := initServers(mainConf, logger)
-	watcher, err := initWatcher(logger, filename)
+	servers := initServers(mainConf, logger, registrar)
+	watcher, err := initWatcher(logger, filename, reloadFunc, registrar)
 	if err != nil {
 		log.Panic(err)
 	}
 	listeners := initListeners(watcher, servers, logger)
+	if registrar != nil {
+		go registrar.Run()
+	}
 
 	start(mainConf, servers, listeners, logger, watcher)
 }
@@ -113,8 +123,8 @@ func initListeners(watcher *grace.Watcher, servers map[string]grace.Server, log
 	return listeners
 }
 
-func initWatcher(log *zerolog.Logger, filename string) (*grace.Watcher, error) {
-	watcher, err := handlePIDFlag(log, filename)
+func initWatcher(log *zerolog.Logger, filename string, reloadFunc func(), registrar *registry.Registrar) (*grace.Watcher, error) {
+	watcher, err := handlePIDFlag(log, filename, reloadFunc, registrar)
 	// TODO(labkode): maybe pidfile can be created later on? like once a server is going to be created?
 	if err != nil {
 		log.Error().Err(err).Msg("error creating grace watcher")
@@ -123,7 +133,7 @@ func initWatcher(log *zerolog.Logger, filename string) (*grace.Watcher, error) {
 	return watcher, err
 }
 
-func initServers(mainConf map[string]interface{}, log *zerolog.Logger) map[string]grace.Server {
+func initServers(mainConf map[string]interface{}, log *zerolog.Logger, registrar *registry.Registrar) map[string]grace.Server {
 	servers := map[string]grace.Server{}
 	if isEnabledHTTP(mainConf) {
 		s, err := getHTTPServer(mainConf["http"], log)
@@ -131,6 +141,9 @@ func initServers(mainConf map[string]interface{}, log *zerolog.Logger) map[strin
 			log.Error().Err(err).Msg("error creating http server")
 			os.Exit(1)
 		}
+		if registrar != nil {
+			s.SetRegistrar(registrar)
+		}
 		servers["http"] = s
 	}
 
@@ -140,6 +153,9 @@ func initServers(mainConf map[string]interface{}, log *zerolog.Logger) map[strin
 			log.Error().Err(err).Msg("error creating grpc server")
 			os.Exit(1)
 		}
+		if registrar != nil {
+			s.SetRegistrar(registrar)
+		}
 		servers["grpc"] = s
 	}
 
@@ -152,7 +168,7 @@ func initServers(mainConf map[string]interface{}, log *zerolog.Logger) map[strin
 }
 
 func initTracing(conf *coreConf, log *zerolog.Logger) {
-	if err := setupOpenCensus(conf); err != nil {
+	if err := setupOpenCensus(conf, log); err != nil {
 		log.Error().Err(err).Msg("error configuring open census stats and tracing")
 		os.Exit(1)
 	}
@@ -168,8 +184,31 @@ func initCPUCount(conf *coreConf, log *zerolog.Logger) {
 	log.Info().Msgf("running on %d cpus", ncpus)
 }
 
-func initLogger(conf *logConf) *zerolog.Logger {
-	log, err := newLogger(conf)
+// initReload returns the function reloading the configuration in place, nil
+// if hot reloads are disabled.
+func initReload(mainConf map[string]interface{}, conf *coreConf, configFile string, log *zerolog.Logger) func() {
+	if !conf.HotReload {
+		return nil
+	}
+	if configFile == "" {
+		log.Warn().Msg("hot reload enabled without a configuration file to reload from, SIGHUP forks a new process")
+		return nil
+	}
+
+	reload.Init(mainConf)
+	r := &reloader{file: configFile, log: log}
+	if conf.WatchConfig {
+		interval := conf.WatchInterval
+		if interval <= 0 {
+			interval = 5
+		}
+		go r.watch(time.Duration(interval) * time.Second)
+	}
+	return func() { r.reload("signal") }
+}
+
+func initLogger(conf *logConf, hotReload bool) *zerolog.Logger {
+	log, err := newLogger(conf, hotReload)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error creating logger, exiting ...")
 		os.Exit(1)
@@ -177,10 +216,17 @@ func initLogger(conf *logConf) *zerolog.Logger {
 	return log
 }
 
-func handlePIDFlag(l *zerolog.Logger, pidFile string) (*grace.Watcher, error) {
+func handlePIDFlag(l *zerolog.Logger, pidFile string, reloadFunc func(), registrar *registry.Registrar) (*grace.Watcher, error) {
 	var opts []grace.Option
 	opts = append(opts, grace.WithPIDFile(pidFile))
 	opts = append(opts, grace.WithLogger(l.With().Str("pkg", "grace").Logger()))
+	if reloadFunc != nil {
+		opts = append(opts, grace.WithReload(reloadFunc))
+	}
+	if registrar != nil {
+		// the services get no new calls while the servers are drained
+		opts = append(opts, grace.WithShutdown(registrar.Stop))
+	}
 	w := grace.NewWatcher(opts...)
 	err := w.WritePID()
 	if err != nil {
@@ -210,7 +256,7 @@ func start(mainConf map[string]interface{}, servers map[string]grace.Server, lis
 	watcher.TrapSignals()
 }
 
-func newLogger(conf *logConf) (*zerolog.Logger, error) {
+func newLogger(conf *logConf, hotReload bool) (*zerolog.Logger, error) {
 	// TODO(labkode): use debug level rather than info as default until reaching a stable version.
 	// Helps having smaller development files.
 	if conf.Level == "" {
@@ -218,7 +264,15 @@ func newLogger(conf *logConf) (*zerolog.Logger, error) {
 	}
 
 	var opts []logger.Option
-	opts = append(opts, logger.WithLevel(conf.Level))
+	if hotReload {
+		// the level of a logger is fixed, the reloadable one is the global level
+		if err := setLogLevel(conf.Level); err != nil {
+			return nil, err
+		}
+		opts = append(opts, logger.WithLevel(zerolog.TraceLevel.String()))
+	} else {
+		opts = append(opts, logger.WithLevel(conf.Level))
+	}
 
 	w, err := getWriter(conf.Output)
 	if err != nil {
@@ -270,7 +324,7 @@ func getHTTPServer(conf interface{}, l *zerolog.Logger) (*rhttp.Server, error) {
 	return s, nil
 }
 
-func setupOpenCensus(conf *coreConf) error {
+func setupOpenCensus(conf *coreConf, log *zerolog.Logger) error {
 	if err := view.Register(ochttp.DefaultServerViews...); err != nil {
 		return err
 	}
@@ -287,19 +341,38 @@ func setupOpenCensus(conf *coreConf) error {
 		conf.TracingServiceName = "revad"
 	}
 
-	je, err := jaeger.NewExporter(jaeger.Options{
-		AgentEndpoint:     conf.TracingEndpoint,
-		CollectorEndpoint: conf.TracingCollector,
-		ServiceName:       conf.TracingServiceName,
-	})
-
-	if err != nil {
-		return err
+	var exporter trace.Exporter
+	switch conf.TracingExporter {
+	case "", "jaeger":
+		je, err := jaeger.NewExporter(jaeger.Options{
+			AgentEndpoint:     conf.TracingEndpoint,
+			CollectorEndpoint: conf.TracingCollector,
+			ServiceName:       conf.TracingServiceName,
+		})
+		if err != nil {
+			return err
+		}
+		exporter = je
+	case "otlp":
+		oe, err := tracing.NewOTLPExporter(tracing.OTLPOptions{
+			Endpoint:    conf.TracingCollector,
+			ServiceName: conf.TracingServiceName,
+			Headers:     conf.TracingHeaders,
+			OnError: func(err error) {
+				log.Warn().Err(err).Msg("error exporting the spans")
+			},
+		})
+		if err != nil {
+			return err
+		}
+		exporter = oe
+	default:
+		return fmt.Errorf("unknown tracing exporter %q", conf.TracingExporter)
 	}
 
 	// register it as a trace exporter
-	trace.RegisterExporter(je)
-	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	tracing.RegisterExporter(exporter)
+	trace.ApplyConfig(trace.Config{DefaultSampler: tracing.Sampler(conf.TracingSampleRate)})
 	return nil
 }
 
@@ -359,6 +432,31 @@ func parseSharedConfOrDie(v interface{}) {
 	}
 }
 
+func parseMTLSConfOrDie(v interface{}) {
+	if v == nil {
+		return
+	}
+	c := &mtls.Config{}
+	if err := mapstructure.Decode(v, c); err != nil {
+		fmt.Fprintf(os.Stderr, "error decoding mtls config: %s\n", err.Error())
+		os.Exit(1)
+	}
+	s, err := mtls.New(c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading mtls certificate: %s\n", err.Error())
+		os.Exit(1)
+	}
+	mtls.Set(s)
+}
+
+func parseRolesConfOrDie(v interface{}) {
+	m, _ := v.(map[string]interface{})
+	if err := permissions.Configure(m); err != nil {
+		fmt.Fprintf(os.Stderr, "error decoding roles config: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
 func parseLogConfOrDie(v interface{}, logLevel string) *logConf {
 	c := &logConf{}
 	if err := mapstructure.Decode(v, c); err != nil {
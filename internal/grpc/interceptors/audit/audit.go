@@ -0,0 +1,293 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package audit provides an interceptor recording the permission relevant
+// calls, like creating shares and public links, changing grants, accessing
+// public links, impersonating users and purging the trash, in the hash
+// chained audit log. The grants are changed by the storage providers and the
+// other calls are served by the gateway, so the interceptor is enabled on
+// both to record all operations.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/audit"
+	"github.com/cs3org/reva/pkg/audit/sink/registry"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const defaultPriority = 200
+
+func init() {
+	rgrpc.RegisterUnaryInterceptor("audit", NewUnary)
+}
+
+type config struct {
+	Priority int `mapstructure:"priority"`
+	// Secret is the key chaining the records with HMAC-SHA256. Without it
+	// the records are chained with SHA-256, which detects accidental
+	// modifications but can be recomputed by anyone with write access.
+	Secret string `mapstructure:"secret"`
+	// ChainFile keeps the position of the chain across restarts.
+	ChainFile string                            `mapstructure:"chain_file"`
+	Sinks     map[string]map[string]interface{} `mapstructure:"sinks"`
+}
+
+func (c *config) init() {
+	if c.Priority == 0 {
+		c.Priority = defaultPriority
+	}
+	if c.ChainFile == "" {
+		c.ChainFile = "/var/log/reva/audit.chain"
+	}
+	if len(c.Sinks) == 0 {
+		c.Sinks = map[string]map[string]interface{}{"file": {}}
+	}
+}
+
+// NewUnary returns a new unary interceptor that records the permission
+// relevant calls in the audit log.
+func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, 0, errors.Wrap(err, "audit: error decoding conf")
+	}
+	c.init()
+
+	sinks, err := registry.NewSinks(c.Sinks)
+	if err != nil {
+		return nil, 0, err
+	}
+	l, err := audit.New([]byte(c.Secret), c.ChainFile, sinks...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		action, target := describe(req)
+		if action == "" {
+			return handler(ctx, req)
+		}
+
+		res, err := handler(ctx, req)
+
+		r := &audit.Record{
+			Action: action,
+			Method: info.FullMethod,
+			Target: target,
+			Status: code(res, err),
+		}
+		if u, ok := user.ContextGetUser(ctx); ok {
+			r.Actor = u.GetId().GetOpaqueId()
+			r.Username = u.GetUsername()
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			r.From = p.Addr.Network() + "://" + p.Addr.String()
+		}
+		describeResponse(res, target)
+
+		if err := l.Log(r); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("action", action).Msg("audit: error writing record")
+		}
+		return res, err
+	}
+	return interceptor, c.Priority, nil
+}
+
+// describe returns the audited action of the request and its target, or an
+// empty action for the requests that are not audited. Secrets, like the
+// passwords of public links, are never part of the target.
+func describe(req interface{}) (string, map[string]string) {
+	switch r := req.(type) {
+	case *provider.AddGrantRequest:
+		return audit.ActionGrantAdd, grant(r.GetRef(), r.GetGrant())
+	case *provider.UpdateGrantRequest:
+		return audit.ActionGrantUpdate, grant(r.GetRef(), r.GetGrant())
+	case *provider.RemoveGrantRequest:
+		return audit.ActionGrantRemove, grant(r.GetRef(), r.GetGrant())
+	case *collaboration.CreateShareRequest:
+		t := map[string]string{
+			"resource":    formatResourceID(r.GetResourceInfo().GetId()),
+			"path":        r.GetResourceInfo().GetPath(),
+			"permissions": formatPermissions(r.GetGrant().GetPermissions().GetPermissions()),
+		}
+		addGrantee(t, r.GetGrant().GetGrantee())
+		return audit.ActionShareCreate, t
+	case *collaboration.UpdateShareRequest:
+		return audit.ActionShareUpdate, map[string]string{
+			"share":       formatShareRef(r.GetRef()),
+			"permissions": formatPermissions(r.GetField().GetPermissions().GetPermissions()),
+		}
+	case *collaboration.RemoveShareRequest:
+		return audit.ActionShareRemove, map[string]string{"share": formatShareRef(r.GetRef())}
+	case *collaboration.UpdateReceivedShareRequest:
+		return audit.ActionShareState, map[string]string{
+			"share": formatShareRef(r.GetRef()),
+			"state": r.GetField().GetState().String(),
+		}
+	case *ocm.CreateOCMShareRequest:
+		t := map[string]string{
+			"resource":    formatResourceID(r.GetResourceId()),
+			"permissions": formatPermissions(r.GetGrant().GetPermissions().GetPermissions()),
+		}
+		addGrantee(t, r.GetGrant().GetGrantee())
+		return audit.ActionOCMShareCreate, t
+	case *link.CreatePublicShareRequest:
+		return audit.ActionPublicLinkCreate, map[string]string{
+			"resource":    formatResourceID(r.GetResourceInfo().GetId()),
+			"path":        r.GetResourceInfo().GetPath(),
+			"permissions": formatPermissions(r.GetGrant().GetPermissions().GetPermissions()),
+		}
+	case *link.UpdatePublicShareRequest:
+		return audit.ActionPublicLinkUpdate, map[string]string{
+			"share":  r.GetRef().GetId().GetOpaqueId(),
+			"token":  r.GetRef().GetToken(),
+			"update": r.GetUpdate().GetType().String(),
+		}
+	case *link.RemovePublicShareRequest:
+		return audit.ActionPublicLinkRemove, map[string]string{
+			"share": r.GetRef().GetId().GetOpaqueId(),
+			"token": r.GetRef().GetToken(),
+		}
+	case *link.GetPublicShareByTokenRequest:
+		return audit.ActionPublicLinkAccess, map[string]string{"token": r.GetToken()}
+	case *gateway.AuthenticateRequest:
+		switch r.GetType() {
+		case "publicshares":
+			return audit.ActionPublicLinkAccess, map[string]string{"token": r.GetClientId(), "auth_type": r.GetType()}
+		case "impersonator":
+			return audit.ActionImpersonate, map[string]string{"username": r.GetClientId(), "auth_type": r.GetType()}
+		}
+	case *gateway.PurgeRecycleRequest:
+		return audit.ActionTrashPurge, map[string]string{"ref": formatRef(r.GetRef())}
+	case *provider.PurgeRecycleRequest:
+		return audit.ActionTrashPurge, map[string]string{"ref": formatRef(r.GetRef())}
+	}
+	return "", nil
+}
+
+// describeResponse adds the identifiers only known after the call,
+// like the ids of new shares, to the target.
+func describeResponse(res interface{}, target map[string]string) {
+	switch r := res.(type) {
+	case *collaboration.CreateShareResponse:
+		if id := r.GetShare().GetId().GetOpaqueId(); id != "" {
+			target["share"] = id
+		}
+	case *ocm.CreateOCMShareResponse:
+		if id := r.GetShare().GetId().GetOpaqueId(); id != "" {
+			target["share"] = id
+		}
+	case *link.CreatePublicShareResponse:
+		if id := r.GetShare().GetId().GetOpaqueId(); id != "" {
+			target["share"] = id
+			target["token"] = r.GetShare().GetToken()
+		}
+	case *link.GetPublicShareByTokenResponse:
+		if id := r.GetShare().GetId().GetOpaqueId(); id != "" {
+			target["share"] = id
+		}
+	}
+}
+
+// code returns the status of the call, which the cs3 apis report
+// in the response for the errors of the services.
+func code(res interface{}, err error) string {
+	if err != nil {
+		return status.Code(err).String()
+	}
+	if r, ok := res.(interface{ GetStatus() *rpc.Status }); ok {
+		return r.GetStatus().GetCode().String()
+	}
+	return rpc.Code_CODE_OK.String()
+}
+
+func grant(ref *provider.Reference, g *provider.Grant) map[string]string {
+	t := map[string]string{"ref": formatRef(ref)}
+	if p := g.GetPermissions(); p != nil {
+		t["permissions"] = formatPermissions(p)
+	}
+	addGrantee(t, g.GetGrantee())
+	return t
+}
+
+func addGrantee(t map[string]string, g *provider.Grantee) {
+	if g == nil {
+		return
+	}
+	t["grantee_type"] = g.GetType().String()
+	if id := g.GetUserId(); id != nil {
+		t["grantee"] = id.GetOpaqueId()
+		t["grantee_idp"] = id.GetIdp()
+	} else if id := g.GetGroupId(); id != nil {
+		t["grantee"] = id.GetOpaqueId()
+		t["grantee_idp"] = id.GetIdp()
+	}
+}
+
+func formatRef(ref *provider.Reference) string {
+	if id := ref.GetId(); id != nil {
+		return formatResourceID(id)
+	}
+	return ref.GetPath()
+}
+
+func formatResourceID(id *provider.ResourceId) string {
+	if id == nil {
+		return ""
+	}
+	return id.GetStorageId() + ":" + id.GetOpaqueId()
+}
+
+func formatShareRef(ref *collaboration.ShareReference) string {
+	if id := ref.GetId(); id != nil {
+		return id.GetOpaqueId()
+	}
+	if k := ref.GetKey(); k != nil {
+		return formatResourceID(k.GetResourceId())
+	}
+	return ""
+}
+
+// formatPermissions encodes the permissions which are granted,
+// the others are omitted.
+func formatPermissions(p *provider.ResourcePermissions) string {
+	if p == nil {
+		return ""
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -230,6 +230,18 @@ func dismantleToken(ctx context.Context, tkn string, req interface{}, mgr token.
 		return u, nil
 	}
 
+	// Guests only have access to the resources shared with them.
+	if shareFolder, ok := scope.GetGuestShareFolder(tokenScope); ok {
+		ok, err := verifyGuestAccess(ctx, req, shareFolder, gatewayAddr)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errtypes.PermissionDenied("auth: access to the resource is not granted to the guest")
+		}
+		return u, nil
+	}
+
 	// Check if req is of type *provider.Reference_Path
 	// If yes, the request might be coming from a share where the accessor is
 	// trying to impersonate the owner, since the share manager doesn't know the
@@ -239,7 +251,7 @@ func dismantleToken(ctx context.Context, tkn string, req interface{}, mgr token.
 
 			// Try to extract the resource ID from the scope resource.
 			// Currently, we only check for public shares, but this will be extended
-			// for OCM shares, guest accounts, etc.
+			// for OCM shares, etc.
 			log.Info().Msgf("resolving path reference to ID to check token scope %+v", ref.GetPath())
 			var share link.PublicShare
 			var publicShareScope []byte
@@ -0,0 +1,145 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/pkg/errors"
+)
+
+// verifyGuestAccess checks that the resources referenced by a request of a
+// guest were shared with the guest, being referenced either by id or by a
+// path below the share root or the share folder of the guest's home.
+func verifyGuestAccess(ctx context.Context, req interface{}, shareFolder, gatewayAddr string) (bool, error) {
+	refs, readOnly := extractGuestRefs(req)
+	if len(refs) == 0 {
+		return false, nil
+	}
+
+	client, err := pool.GetGatewayServiceClient(gatewayAddr)
+	if err != nil {
+		return false, err
+	}
+
+	lsRes, err := client.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	if err != nil {
+		return false, err
+	}
+	if lsRes.Status.Code != rpc.Code_CODE_OK {
+		return false, errors.New("auth: error listing the shares of the guest: " + lsRes.Status.Message)
+	}
+
+	var home string
+	var roots []string
+	for _, ref := range refs {
+		if id := ref.GetId(); id != nil {
+			if !sharedWithGuest(lsRes.Shares, id) {
+				return false, nil
+			}
+			continue
+		}
+
+		p := path.Clean(ref.GetPath())
+		if home == "" {
+			homeRes, err := client.GetHome(ctx, &provider.GetHomeRequest{})
+			if err != nil {
+				return false, err
+			}
+			if homeRes.Status.Code != rpc.Code_CODE_OK {
+				return false, errors.New("auth: error getting the home of the guest: " + homeRes.Status.Message)
+			}
+			home = path.Clean(homeRes.Path)
+		}
+		// The guest may browse their home, which only holds the share folder.
+		if readOnly && p == home {
+			continue
+		}
+		if isBelow(p, path.Join(home, shareFolder)) {
+			continue
+		}
+
+		if roots == nil {
+			roots = []string{}
+			for _, s := range lsRes.Shares {
+				statRes, err := client.Stat(ctx, &provider.StatRequest{
+					Ref: &provider.Reference{
+						Spec: &provider.Reference_Id{Id: s.Share.ResourceId},
+					},
+				})
+				if err != nil || statRes.Status.Code != rpc.Code_CODE_OK {
+					continue
+				}
+				roots = append(roots, path.Clean(statRes.Info.Path))
+			}
+		}
+		allowed := false
+		for _, r := range roots {
+			if isBelow(p, r) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func sharedWithGuest(shares []*collaboration.ReceivedShare, id *provider.ResourceId) bool {
+	for _, s := range shares {
+		rid := s.GetShare().GetResourceId()
+		if rid.GetStorageId() == id.StorageId && rid.GetOpaqueId() == id.OpaqueId {
+			return true
+		}
+	}
+	return false
+}
+
+func isBelow(p, root string) bool {
+	return p == root || strings.HasPrefix(p, root+"/")
+}
+
+// extractGuestRefs returns the references of a request checked for guests and
+// whether the request only reads the referenced resources.
+func extractGuestRefs(req interface{}) ([]*provider.Reference, bool) {
+	switch v := req.(type) {
+	case *provider.MoveRequest:
+		return []*provider.Reference{v.GetSource(), v.GetDestination()}, false
+	case *provider.GetPathRequest:
+		return []*provider.Reference{{Spec: &provider.Reference_Id{Id: v.GetResourceId()}}}, true
+	case *provider.CreateReferenceRequest:
+		return []*provider.Reference{{Spec: &provider.Reference_Path{Path: v.GetPath()}}}, false
+	case *registry.GetStorageProvidersRequest, *provider.StatRequest, *provider.ListContainerRequest, *provider.InitiateFileDownloadRequest:
+		ref, _ := extractRef(req)
+		return []*provider.Reference{ref}, true
+	}
+	if ref, ok := extractRef(req); ok {
+		return []*provider.Reference{ref}, false
+	}
+	return nil, false
+}
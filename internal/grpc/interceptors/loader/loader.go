@@ -18,4 +18,9 @@
 
 package loader
 
-// Add your own.
+import (
+	// Load core grpc interceptors.
+	_ "github.com/cs3org/reva/internal/grpc/interceptors/audit"
+	_ "github.com/cs3org/reva/internal/grpc/interceptors/ratelimit"
+	// Add your own here.
+)
@@ -0,0 +1,67 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type window struct {
+	count int
+	end   time.Time
+}
+
+// memoryCounter counts the calls of a single process.
+type memoryCounter struct {
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+func newMemoryCounter() *memoryCounter {
+	return &memoryCounter{windows: map[string]*window{}}
+}
+
+func (c *memoryCounter) incr(key string, length time.Duration, now time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.windows[key]
+	if !ok || !now.Before(w.end) {
+		if !ok {
+			c.purge(now)
+		}
+		w = &window{end: now.Add(retryDelay(length, now))}
+		c.windows[key] = w
+	}
+	w.count++
+	return w.count, nil
+}
+
+// purge drops the windows that have ended, they carry no state.
+func (c *memoryCounter) purge(now time.Time) {
+	if len(c.windows) < 1024 {
+		return
+	}
+	for k, w := range c.windows {
+		if !now.Before(w.end) {
+			delete(c.windows, k)
+		}
+	}
+}
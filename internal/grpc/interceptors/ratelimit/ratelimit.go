@@ -0,0 +1,257 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package ratelimit provides an interceptor limiting the number of calls
+// clients can issue against a grpc service, so that abusive or buggy clients
+// can't starve the gateway. The calls are counted per method, per user or per
+// client address in fixed windows, in memory or in redis to share the limits
+// across the replicas of a service. Rejected calls fail with
+// RESOURCE_EXHAUSTED, carrying the violated limit and the delay after which
+// the call can be retried in the status details.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/reload"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	defaultPriority = 100
+	reloadSection   = "grpc.interceptors.ratelimit"
+)
+
+func init() {
+	rgrpc.RegisterUnaryInterceptor("ratelimit", NewUnary)
+}
+
+type config struct {
+	Priority int `mapstructure:"priority"`
+	// Counter is where the calls are counted, "memory" or "redis".
+	Counter       string  `mapstructure:"counter"`
+	RedisAddress  string  `mapstructure:"redis_address"`
+	RedisUsername string  `mapstructure:"redis_username"`
+	RedisPassword string  `mapstructure:"redis_password"`
+	Rules         []*rule `mapstructure:"rules"`
+}
+
+type rule struct {
+	// Methods are the full names of the methods the rule applies to, like
+	// "/cs3.gateway.v1beta1.GatewayAPI/ListContainer". A trailing "*"
+	// matches all the methods with the prefix, "*" alone all the methods.
+	Methods []string `mapstructure:"methods"`
+	// Key is what the calls are counted by: "method", "user" or "ip". The
+	// calls without a user in the context are counted by client address.
+	Key string `mapstructure:"key"`
+	// Limit is the number of calls granted in every window.
+	Limit int `mapstructure:"limit"`
+	// Window is the length of the window in seconds.
+	Window int `mapstructure:"window"`
+}
+
+func (c *config) init() {
+	if c.Priority == 0 {
+		c.Priority = defaultPriority
+	}
+	if c.Counter == "" {
+		c.Counter = "memory"
+	}
+	if c.RedisAddress == "" {
+		c.RedisAddress = "localhost:6379"
+	}
+	for _, r := range c.Rules {
+		if len(r.Methods) == 0 {
+			r.Methods = []string{"*"}
+		}
+		if r.Key == "" {
+			r.Key = "user"
+		}
+		if r.Window <= 0 {
+			r.Window = 1
+		}
+	}
+}
+
+func (c *config) validate() error {
+	for i, r := range c.Rules {
+		switch r.Key {
+		case "method", "user", "ip":
+		default:
+			return fmt.Errorf("ratelimit: rule %d: unknown key %q", i, r.Key)
+		}
+		if r.Limit <= 0 {
+			return fmt.Errorf("ratelimit: rule %d: limit must be positive", i)
+		}
+	}
+	return nil
+}
+
+// counter counts the calls in fixed windows.
+type counter interface {
+	// incr increments the count of the key in the current window and
+	// returns the new count.
+	incr(key string, window time.Duration, now time.Time) (int, error)
+}
+
+type limiter struct {
+	mu      sync.RWMutex
+	rules   []*rule
+	counter counter
+	now     func() time.Time
+}
+
+func parseConfig(m map[string]interface{}) (*config, counter, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, nil, errors.Wrap(err, "ratelimit: error decoding conf")
+	}
+	c.init()
+	if err := c.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	switch c.Counter {
+	case "memory":
+		return c, newMemoryCounter(), nil
+	case "redis":
+		return c, newRedisCounter(c.RedisAddress, c.RedisUsername, c.RedisPassword), nil
+	default:
+		return nil, nil, fmt.Errorf("ratelimit: unknown counter %q", c.Counter)
+	}
+}
+
+// NewUnary returns a new unary interceptor that rejects the calls exceeding
+// the configured limits.
+func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error) {
+	c, cnt, err := parseConfig(m)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	l := &limiter{rules: c.Rules, counter: cnt, now: time.Now}
+	reload.Register(reloadSection, l.reload)
+	return l.intercept, c.Priority, nil
+}
+
+// reload applies the new rules, starting the counts over. The priority of
+// the interceptor only changes after a restart.
+func (l *limiter) reload(m map[string]interface{}) error {
+	c, cnt, err := parseConfig(m)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules, l.counter = c.Rules, cnt
+	return nil
+}
+
+func (l *limiter) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	l.mu.RLock()
+	rules, cnt := l.rules, l.counter
+	l.mu.RUnlock()
+
+	now := l.now()
+	for i, r := range rules {
+		if !r.matches(info.FullMethod) {
+			continue
+		}
+		subject := r.subject(ctx, info.FullMethod)
+		window := time.Duration(r.Window) * time.Second
+		n, err := cnt.incr(fmt.Sprintf("%d:%s", i, subject), window, now)
+		if err != nil {
+			// an unavailable counter must not take the service down with it
+			appctx.GetLogger(ctx).Warn().Err(err).Str("subject", subject).Msg("ratelimit: error counting call, letting it through")
+			continue
+		}
+		if n > r.Limit {
+			return nil, exhausted(r, subject, retryDelay(window, now))
+		}
+	}
+	return handler(ctx, req)
+}
+
+func (r *rule) matches(method string) bool {
+	for _, m := range r.Methods {
+		if m == method || m == "*" || (strings.HasSuffix(m, "*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// subject returns what the call is counted by, prefixed with the key.
+func (r *rule) subject(ctx context.Context, method string) string {
+	switch r.Key {
+	case "method":
+		return "method:" + method
+	case "user":
+		if u, ok := user.ContextGetUser(ctx); ok && u.GetId() != nil {
+			return "user:" + u.Id.Idp + "!" + u.Id.OpaqueId
+		}
+	}
+	return "ip:" + clientIP(ctx)
+}
+
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// retryDelay returns the time left until the window containing now ends.
+func retryDelay(window time.Duration, now time.Time) time.Duration {
+	return window - time.Duration(now.UnixNano()%int64(window))
+}
+
+func exhausted(r *rule, subject string, delay time.Duration) error {
+	msg := fmt.Sprintf("ratelimit: more than %d calls in %ds", r.Limit, r.Window)
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(
+		&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: msg}},
+		},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)},
+	)
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return st.Err()
+}
@@ -0,0 +1,137 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/user"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestIntercept(t *testing.T) {
+	interceptor, _, err := NewUnary(map[string]interface{}{
+		"rules": []map[string]interface{}{
+			{"methods": []string{"/svc/List*"}, "key": "user", "limit": 2, "window": 60},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(ctx context.Context, method string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	einstein := user.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}})
+	marie := user.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "marie"}})
+
+	for i := 0; i < 2; i++ {
+		if err := call(einstein, "/svc/ListContainer"); err != nil {
+			t.Fatalf("call %d: unexpected error %v", i, err)
+		}
+	}
+	err = call(einstein, "/svc/ListContainer")
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("expected RESOURCE_EXHAUSTED, got %v", err)
+	}
+	var subject string
+	var delay time.Duration
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.QuotaFailure:
+			subject = d.Violations[0].Subject
+		case *errdetails.RetryInfo:
+			delay = d.RetryDelay.AsDuration()
+		}
+	}
+	if subject != "user:idp!einstein" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if delay <= 0 || delay > time.Minute {
+		t.Errorf("unexpected retry delay %v", delay)
+	}
+
+	if err := call(marie, "/svc/ListContainer"); err != nil {
+		t.Errorf("other users must not be limited, got %v", err)
+	}
+	if err := call(einstein, "/svc/Stat"); err != nil {
+		t.Errorf("other methods must not be limited, got %v", err)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4242}})
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"method", "method:/svc/Stat"},
+		{"ip", "ip:10.0.0.1"},
+		{"user", "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := &rule{Key: tt.key}
+		if got := r.subject(ctx, "/svc/Stat"); got != tt.expected {
+			t.Errorf("key %s: expected %q, got %q", tt.key, tt.expected, got)
+		}
+	}
+}
+
+func TestMemoryCounter(t *testing.T) {
+	c := newMemoryCounter()
+	now := time.Unix(100, 0)
+	for i := 1; i <= 3; i++ {
+		if n, _ := c.incr("k", 10*time.Second, now); n != i {
+			t.Errorf("expected %d, got %d", i, n)
+		}
+	}
+	if n, _ := c.incr("k", 10*time.Second, now.Add(10*time.Second)); n != 1 {
+		t.Errorf("expected the count to be reset in the next window, got %d", n)
+	}
+}
+
+func TestReload(t *testing.T) {
+	l := &limiter{counter: newMemoryCounter(), now: time.Now}
+	if err := l.reload(map[string]interface{}{"rules": []map[string]interface{}{{"key": "method", "limit": 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Stat"}
+	if _, err := l.intercept(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := l.intercept(context.Background(), nil, info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected RESOURCE_EXHAUSTED, got %v", err)
+	}
+
+	if err := l.reload(map[string]interface{}{"rules": []map[string]interface{}{{"key": "unknown", "limit": 1}}}); err == nil {
+		t.Error("expected an invalid rule to be rejected")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// redisCounter counts the calls in redis, sharing the limits between all the
+// processes using the same server.
+type redisCounter struct {
+	pool *redis.Pool
+}
+
+func newRedisCounter(address, username, password string) *redisCounter {
+	return &redisCounter{pool: initRedisPool(address, username, password)}
+}
+
+func initRedisPool(address, username, password string) *redis.Pool {
+	return &redis.Pool{
+
+		MaxIdle:     50,
+		MaxActive:   1000,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if username != "" {
+				opts = append(opts, redis.DialUsername(username))
+			}
+			if password != "" {
+				opts = append(opts, redis.DialPassword(password))
+			}
+			return redis.Dial("tcp", address, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
+
+func (c *redisCounter) incr(key string, length time.Duration, now time.Time) (int, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	// every window has its own key, expiring with the window
+	k := fmt.Sprintf("ratelimit:%s:%d", key, now.UnixNano()/int64(length))
+	n, err := redis.Int(conn.Do("INCR", k))
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		ms := (retryDelay(length, now) + time.Millisecond - 1) / time.Millisecond
+		if _, err := conn.Do("PEXPIRE", k, int64(ms)); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
@@ -19,27 +19,18 @@
 package appprovider
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/url"
-	"os"
-	"path"
-	"strings"
-	"time"
 
 	providerpb "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/app"
+	"github.com/cs3org/reva/pkg/app/provider/codimd"
 	"github.com/cs3org/reva/pkg/app/provider/demo"
+	"github.com/cs3org/reva/pkg/app/provider/wopi"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
-	"github.com/cs3org/reva/pkg/rhttp"
-	"github.com/cs3org/reva/pkg/user"
 	"github.com/mitchellh/mapstructure"
 	"google.golang.org/grpc"
 )
@@ -50,7 +41,6 @@ func init() {
 
 type service struct {
 	provider app.Provider
-	client   *http.Client
 	conf     *config
 }
 
@@ -60,6 +50,8 @@ type config struct {
 	IopSecret string                 `mapstructure:"iopsecret" docs:";The iopsecret used to connect to the wopiserver."`
 	WopiURL   string                 `mapstructure:"wopiurl" docs:";The wopiserver's URL."`
 	WopiBrURL string                 `mapstructure:"wopibridgeurl" docs:";The wopibridge's URL."`
+	Wopi      map[string]interface{} `mapstructure:"wopi" docs:";The configuration of the wopi driver, defaulting to the wopiserver settings above."`
+	CodiMD    map[string]interface{} `mapstructure:"codimd" docs:";The configuration of the codimd driver."`
 }
 
 // New creates a new AppProviderService
@@ -77,9 +69,6 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	service := &service{
 		conf:     c,
 		provider: provider,
-		client: rhttp.GetHTTPClient(
-			rhttp.Timeout(5 * time.Second),
-		),
 	}
 
 	return service, nil
@@ -108,179 +97,55 @@ func (s *service) Register(ss *grpc.Server) {
 func getProvider(c *config) (app.Provider, error) {
 	switch c.Driver {
 	case "demo":
+		// the deployments predating the wopi driver open the files
+		// through the wopiserver configured for the service
+		if c.WopiURL != "" {
+			return wopi.New(c.wopiConfig())
+		}
 		return demo.New(c.Demo)
+	case "wopi":
+		return wopi.New(c.wopiConfig())
+	case "codimd":
+		return codimd.New(c.CodiMD)
 	default:
 		return nil, errtypes.NotFound("driver not found: " + c.Driver)
 	}
 }
 
-func (s *service) getWopiAppEndpoints(ctx context.Context) (map[string]interface{}, error) {
-	// TODO this query will eventually be served by Reva.
-	// For the time being it is a remnant of the CERNBox-specific WOPI server, which justifies the /cbox path in the URL.
-	wopiurl, err := url.Parse(s.conf.WopiURL)
-	if err != nil {
-		return nil, err
-	}
-	wopiurl.Path = path.Join(wopiurl.Path, "/wopi/cbox/endpoints")
-	appsReq, err := rhttp.NewRequest(ctx, "GET", wopiurl.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	appsRes, err := s.client.Do(appsReq)
-	if err != nil {
-		return nil, err
-	}
-	defer appsRes.Body.Close()
-	if appsRes.StatusCode != http.StatusOK {
-		return nil, errtypes.InternalError(fmt.Sprintf("Request to WOPI server returned %d", appsRes.StatusCode))
-	}
-	appsBody, err := ioutil.ReadAll(appsRes.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	appsURLMap := make(map[string]interface{})
-	err = json.Unmarshal(appsBody, &appsURLMap)
-	if err != nil {
-		return nil, err
+// wopiConfig returns the configuration of the wopi driver, which defaults to
+// the wopiserver settings of the service.
+func (c *config) wopiConfig() map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range c.Wopi {
+		m[k] = v
+	}
+	defaults := map[string]string{
+		"iop_secret":      c.IopSecret,
+		"wopi_url":        c.WopiURL,
+		"wopi_bridge_url": c.WopiBrURL,
+	}
+	for k, v := range defaults {
+		if _, ok := m[k]; !ok && v != "" {
+			m[k] = v
+		}
 	}
-
-	log := appctx.GetLogger(ctx)
-	log.Info().Msg(fmt.Sprintf("Successfully retrieved %d WOPI app endpoints", len(appsURLMap)))
-	return appsURLMap, nil
+	return m
 }
 
 func (s *service) OpenInApp(ctx context.Context, req *providerpb.OpenInAppRequest) (*providerpb.OpenInAppResponse, error) {
-
 	log := appctx.GetLogger(ctx)
 
-	wopiurl, err := url.Parse(s.conf.WopiURL)
-	if err != nil {
-		return nil, err
-	}
-	wopiurl.Path = path.Join(wopiurl.Path, "/wopi/iop/open")
-	httpReq, err := rhttp.NewRequest(ctx, "GET", wopiurl.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	q := httpReq.URL.Query()
-	q.Add("fileid", req.ResourceInfo.GetId().OpaqueId)
-	q.Add("endpoint", req.ResourceInfo.GetId().StorageId)
-	q.Add("viewmode", req.ViewMode.String())
-	// TODO the folder URL should be resolved as e.g. `'https://cernbox.cern.ch/index.php/apps/files/?dir=' + filepath.Dir(req.Ref.GetPath())`
-	// or should be deprecated/removed altogether, needs discussion and decision.
-	q.Add("folderurl", "undefined")
-	u, ok := user.ContextGetUser(ctx)
-	if ok {
-		q.Add("username", u.Username)
-	}
-	// else defaults to "Anonymous Guest"
-
-	if s.conf.IopSecret == "" {
-		s.conf.IopSecret = os.Getenv("REVA_APPPROVIDER_IOPSECRET")
-	}
-
-	httpReq.Header.Set("Authorization", "Bearer "+s.conf.IopSecret)
-	httpReq.Header.Set("TokenHeader", req.AccessToken)
-
-	httpReq.URL.RawQuery = q.Encode()
-
-	openRes, err := s.client.Do(httpReq)
-
+	appURL, err := s.provider.GetAppURL(ctx, req.ResourceInfo, req.ViewMode, req.AccessToken, req.App)
 	if err != nil {
-		res := &providerpb.OpenInAppResponse{
-			Status: status.NewInternal(ctx, err, "appprovider: error performing open request to WOPI"),
-		}
-		return res, nil
-	}
-	defer openRes.Body.Close()
-
-	if openRes.StatusCode != http.StatusOK {
-		res := &providerpb.OpenInAppResponse{
-			Status: status.NewInvalid(ctx, fmt.Sprintf("appprovider: error performing open request to WOPI, status code: %d", openRes.StatusCode)),
-		}
-		return res, nil
-	}
-
-	buf := new(bytes.Buffer)
-	_, err = buf.ReadFrom(openRes.Body)
-	if err != nil {
-		return nil, err
-	}
-	openResBody := buf.String()
-
-	var viewmode string
-	if req.ViewMode == providerpb.OpenInAppRequest_VIEW_MODE_READ_WRITE {
-		viewmode = "edit"
-	} else {
-		viewmode = "view"
-	}
-
-	var appProviderURL string
-	if req.App == "" {
-		// Default behavior: work out the application URL to be used for this file
-		// TODO call this e.g. once a day or a week, and cache the content in a shared map protected by a multi-reader Lock
-		appsURLMap, err := s.getWopiAppEndpoints(ctx)
-		if err != nil {
-			res := &providerpb.OpenInAppResponse{
-				Status: status.NewInternal(ctx, err, "appprovider: getWopiAppEndpoints failed"),
-			}
-			return res, nil
-		}
-		viewOptions := appsURLMap[path.Ext(req.ResourceInfo.GetPath())]
-		viewOptionsMap, ok := viewOptions.(map[string]interface{})
-		if !ok {
-			res := &providerpb.OpenInAppResponse{
-				Status: status.NewInvalid(ctx, "Incorrect parsing of the App URLs map from the WOPI server"),
-			}
-			return res, nil
-		}
-
-		appProviderURL = fmt.Sprintf("%v", viewOptionsMap[viewmode])
-		if strings.Contains(appProviderURL, "?") {
-			appProviderURL += "&"
-		} else {
-			appProviderURL += "?"
-		}
-		appProviderURL = fmt.Sprintf("%sWOPISrc=%s", appProviderURL, openResBody)
-	} else {
-		// User specified the application to use, generate the URL out of that
-		// TODO map the given req.App to the URL via config. For now assume it's a URL!
-		appProviderURL = fmt.Sprintf("%sWOPISrc=%s", req.App, openResBody)
-	}
-
-	// In case of applications served by the WOPI bridge, resolve the URL and go to the app
-	// Note that URL matching is performed via string matching, not via IP resolution: may need to fix this
-	if len(s.conf.WopiBrURL) > 0 && strings.Contains(appProviderURL, s.conf.WopiBrURL) {
-		httpClient := rhttp.GetHTTPClient(
-			rhttp.Context(ctx),
-			rhttp.Timeout(time.Duration(5*int64(time.Second))),
-		)
-		httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			// do not follow a redirect
-			return http.ErrUseLastResponse
-		}
-
-		bridgeReq, err := rhttp.NewRequest(ctx, "GET", appProviderURL, nil)
-		if err != nil {
-			return nil, err
-		}
-		bridgeRes, err := httpClient.Do(bridgeReq)
-		if err != nil {
-			return nil, err
-		}
-		defer bridgeRes.Body.Close()
-		if bridgeRes.StatusCode != http.StatusFound {
-			return nil, errtypes.InternalError(fmt.Sprintf("Request to WOPI bridge returned %d", bridgeRes.StatusCode))
-		}
-		appProviderURL = bridgeRes.Header.Get("Location")
+		return &providerpb.OpenInAppResponse{
+			Status: status.NewStatusFromErrType(ctx, "appprovider: error getting the app URL", err),
+		}, nil
 	}
 
-	log.Info().Msg(fmt.Sprintf("Returning app provider URL %s", appProviderURL))
+	log.Info().Msg(fmt.Sprintf("Returning app provider URL %s", appURL))
 	return &providerpb.OpenInAppResponse{
 		Status: status.NewOK(ctx),
-		AppUrl: appProviderURL,
+		AppUrl: appURL,
 	}, nil
 }
 
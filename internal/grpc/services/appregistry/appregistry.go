@@ -24,6 +24,7 @@ import (
 	"google.golang.org/grpc"
 
 	registrypb "github.com/cs3org/go-cs3apis/cs3/app/registry/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/app"
 	"github.com/cs3org/reva/pkg/app/registry/static"
 	"github.com/cs3org/reva/pkg/errtypes"
@@ -95,17 +96,20 @@ func getRegistry(c *config) (app.Registry, error) {
 }
 
 func (s *svc) GetAppProviders(ctx context.Context, req *registrypb.GetAppProvidersRequest) (*registrypb.GetAppProvidersResponse, error) {
-	p, err := s.registry.FindProvider(ctx, req.ResourceInfo.MimeType)
+	pvds, err := s.registry.FindProviders(ctx, req.ResourceInfo.MimeType)
 	if err != nil {
 		return &registrypb.GetAppProvidersResponse{
 			Status: status.NewInternal(ctx, err, "error looking for the app provider"),
 		}, nil
 	}
 
-	provider := format(p)
+	providers := make([]*registrypb.ProviderInfo, 0, len(pvds))
+	for _, pvd := range pvds {
+		providers = append(providers, format(pvd))
+	}
 	res := &registrypb.GetAppProvidersResponse{
 		Status:    status.NewOK(ctx),
-		Providers: []*registrypb.ProviderInfo{provider},
+		Providers: providers,
 	}
 	return res, nil
 }
@@ -122,15 +126,37 @@ func (s *svc) ListAppProviders(ctx context.Context, req *registrypb.ListAppProvi
 		providers = append(providers, format(pvd))
 	}
 
+	defaultApps, err := s.registry.DefaultApps(ctx)
+	if err != nil {
+		return &registrypb.ListAppProvidersResponse{
+			Status: status.NewInternal(ctx, err, "error listing the default apps"),
+		}, nil
+	}
+	val, err := app.EncodeDefaultApps(defaultApps)
+	if err != nil {
+		return &registrypb.ListAppProvidersResponse{
+			Status: status.NewInternal(ctx, err, "error encoding the default apps"),
+		}, nil
+	}
+
 	res := &registrypb.ListAppProvidersResponse{
 		Status:    status.NewOK(ctx),
 		Providers: providers,
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				app.DefaultAppsKey: {Decoder: "json", Value: val},
+			},
+		},
 	}
 	return res, nil
 }
 
 func format(p *app.ProviderInfo) *registrypb.ProviderInfo {
-	return &registrypb.ProviderInfo{
-		Address: p.Location,
+	pi := &registrypb.ProviderInfo{
+		Address:     p.Location,
+		Description: p.Description,
+		MimeTypes:   p.MimeTypes,
 	}
+	app.EncodeProviderInfo(pi, p.Name, p.Icon)
+	return pi
 }
@@ -21,12 +21,14 @@ package authprovider
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/auth"
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/reload"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/mitchellh/mapstructure"
@@ -49,7 +51,10 @@ func (c *config) init() {
 	}
 }
 
+const reloadSection = "grpc.services.authprovider"
+
 type service struct {
+	mu      sync.RWMutex
 	authmgr auth.Manager
 	conf    *config
 }
@@ -87,11 +92,37 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	}
 
 	svc := &service{conf: c, authmgr: authManager}
+	reload.Register(reloadSection, svc.reload)
 
 	return svc, nil
 }
 
+// reload swaps the auth manager for one built from the new configuration,
+// the authentications in progress complete with the previous one.
+func (s *service) reload(m map[string]interface{}) error {
+	c, err := parseConfig(m)
+	if err != nil {
+		return err
+	}
+	authManager, err := getAuthManager(c.AuthManager, c.AuthManagers)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conf, s.authmgr = c, authManager
+	return nil
+}
+
+func (s *service) manager() auth.Manager {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.authmgr
+}
+
 func (s *service) Close() error {
+	reload.Unregister(reloadSection)
 	return nil
 }
 
@@ -108,7 +139,7 @@ func (s *service) Authenticate(ctx context.Context, req *provider.AuthenticateRe
 	username := req.ClientId
 	password := req.ClientSecret
 
-	u, scope, err := s.authmgr.Authenticate(ctx, username, password)
+	u, scope, err := s.manager().Authenticate(ctx, username, password)
 	switch v := err.(type) {
 	case nil:
 		log.Info().Msgf("user %s authenticated", u.String())
@@ -22,9 +22,13 @@ import (
 	"context"
 
 	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	txdriver "github.com/cs3org/reva/pkg/datatx"
+	"github.com/cs3org/reva/pkg/datatx/manager/registry"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -35,19 +39,32 @@ func init() {
 }
 
 type config struct {
+	TxDriver  string                            `mapstructure:"txdriver"`
+	TxDrivers map[string]map[string]interface{} `mapstructure:"txdrivers"`
 }
 
 type service struct {
 	conf *config
+	txm  txdriver.Manager
 }
 
 func (c *config) init() {
+	if c.TxDriver == "" {
+		c.TxDriver = "rclone"
+	}
 }
 
 func (s *service) Register(ss *grpc.Server) {
 	datatx.RegisterTxAPIServer(ss, s)
 }
 
+func getDatatxManager(c *config) (txdriver.Manager, error) {
+	if f, ok := registry.NewFuncs[c.TxDriver]; ok {
+		return f(c.TxDrivers[c.TxDriver])
+	}
+	return nil, errtypes.NotFound("driver not found: " + c.TxDriver)
+}
+
 func parseConfig(m map[string]interface{}) (*config, error) {
 	c := &config{}
 	if err := mapstructure.Decode(m, c); err != nil {
@@ -66,8 +83,14 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	}
 	c.init()
 
+	txm, err := getDatatxManager(c)
+	if err != nil {
+		return nil, err
+	}
+
 	service := &service{
 		conf: c,
+		txm:  txm,
 	}
 
 	return service, nil
@@ -81,20 +104,93 @@ func (s *service) UnprotectedEndpoints() []string {
 	return []string{}
 }
 
+// CreateTransfer starts the transfer described by the TransferOpaqueKey entry of the request opaque.
 func (s *service) CreateTransfer(ctx context.Context, req *datatx.CreateTransferRequest) (*datatx.CreateTransferResponse, error) {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok {
+		return &datatx.CreateTransferResponse{
+			Status: status.NewUnauthenticated(ctx, errtypes.UserRequired("datatx"), "user not found in context"),
+		}, nil
+	}
+
+	r, err := txdriver.DecodeRequest(req.GetOpaque())
+	if err != nil {
+		return &datatx.CreateTransferResponse{
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		}, nil
+	}
+
+	t, err := s.txm.StartTransfer(ctx, u.Id, r)
+	if err != nil {
+		return &datatx.CreateTransferResponse{
+			Status: status.NewStatusFromErrType(ctx, "error starting transfer", err),
+		}, nil
+	}
+
 	return &datatx.CreateTransferResponse{
-		Status: status.NewUnimplemented(ctx, errtypes.NotSupported("CreateTransfer not implemented"), "CreateTransfer not implemented"),
+		Status: status.NewOK(ctx),
+		TxInfo: t.Info,
+		Opaque: t.Progress,
 	}, nil
 }
 
-func (s *service) GetTransferStatus(ctx context.Context, in *datatx.GetTransferStatusRequest) (*datatx.GetTransferStatusResponse, error) {
+func (s *service) GetTransferStatus(ctx context.Context, req *datatx.GetTransferStatusRequest) (*datatx.GetTransferStatusResponse, error) {
+	id := req.GetTxId().GetOpaqueId()
+	if err := s.checkOwner(ctx, id); err != nil {
+		return &datatx.GetTransferStatusResponse{
+			Status: status.NewStatusFromErrType(ctx, "error getting transfer status", err),
+		}, nil
+	}
+
+	t, err := s.txm.GetTransferStatus(ctx, id)
+	if err != nil {
+		return &datatx.GetTransferStatusResponse{
+			Status: status.NewStatusFromErrType(ctx, "error getting transfer status", err),
+		}, nil
+	}
+
 	return &datatx.GetTransferStatusResponse{
-		Status: status.NewUnimplemented(ctx, errtypes.NotSupported("GetTransferStatus not implemented"), "GetTransferStatus not implemented"),
+		Status: status.NewOK(ctx),
+		TxInfo: t.Info,
+		Opaque: t.Progress,
 	}, nil
 }
 
-func (s *service) CancelTransfer(ctx context.Context, in *datatx.CancelTransferRequest) (*datatx.CancelTransferResponse, error) {
+func (s *service) CancelTransfer(ctx context.Context, req *datatx.CancelTransferRequest) (*datatx.CancelTransferResponse, error) {
+	id := req.GetTxId().GetOpaqueId()
+	if err := s.checkOwner(ctx, id); err != nil {
+		return &datatx.CancelTransferResponse{
+			Status: status.NewStatusFromErrType(ctx, "error cancelling transfer", err),
+		}, nil
+	}
+
+	t, err := s.txm.CancelTransfer(ctx, id)
+	if err != nil {
+		return &datatx.CancelTransferResponse{
+			Status: status.NewStatusFromErrType(ctx, "error cancelling transfer", err),
+		}, nil
+	}
+
 	return &datatx.CancelTransferResponse{
-		Status: status.NewUnimplemented(ctx, errtypes.NotSupported("CancelTransfer not implemented"), "CancelTransfer not implemented"),
+		Status: status.NewOK(ctx),
+		TxInfo: t.Info,
+		Opaque: t.Progress,
 	}, nil
 }
+
+// checkOwner makes sure that only the user that created a transfer can see or cancel it.
+// Transfers of other users are reported as not found.
+func (s *service) checkOwner(ctx context.Context, id string) error {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok {
+		return errtypes.UserRequired("datatx")
+	}
+	owner, err := s.txm.GetTransferOwner(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !utils.UserEqual(owner, u.Id) {
+		return errtypes.NotFound("transfer " + id)
+	}
+	return nil
+}
@@ -28,15 +28,18 @@ import (
 	registry "github.com/cs3org/go-cs3apis/cs3/app/registry/v1beta1"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	apppkg "github.com/cs3org/reva/pkg/app"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/token"
 	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ocgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
@@ -191,7 +194,7 @@ func (s *svc) openLocalResources(ctx context.Context, ri *storageprovider.Resour
 		}, nil
 	}
 
-	provider, err := s.findAppProvider(ctx, ri)
+	provider, err := s.findAppProvider(ctx, ri, app)
 	if err != nil {
 		err = errors.Wrap(err, "gateway: error calling findAppProvider")
 		var st *rpc.Status
@@ -215,7 +218,7 @@ func (s *svc) openLocalResources(ctx context.Context, ri *storageprovider.Resour
 
 	appProviderReq := &providerpb.OpenInAppRequest{
 		ResourceInfo: ri,
-		ViewMode:     providerpb.OpenInAppRequest_ViewMode(vm),
+		ViewMode:     providerpb.OpenInAppRequest_ViewMode(getViewMode(ri, vm)),
 		App:          app,
 		AccessToken:  accessToken,
 	}
@@ -228,7 +231,35 @@ func (s *svc) openLocalResources(ctx context.Context, ri *storageprovider.Resour
 	return res, nil
 }
 
-func (s *svc) findAppProvider(ctx context.Context, ri *storageprovider.ResourceInfo) (*registry.ProviderInfo, error) {
+// getViewMode limits the requested view mode to the one the permissions of the
+// user on the resource allow, which is also the view mode used when none is requested.
+// The storage drivers not reporting permissions get the requested view mode.
+func getViewMode(ri *storageprovider.ResourceInfo, vm gateway.OpenInAppRequest_ViewMode) gateway.OpenInAppRequest_ViewMode {
+	p := ri.GetPermissionSet()
+	if p == nil {
+		if vm == gateway.OpenInAppRequest_VIEW_MODE_INVALID {
+			return gateway.OpenInAppRequest_VIEW_MODE_READ_ONLY
+		}
+		return vm
+	}
+
+	allowed := gateway.OpenInAppRequest_VIEW_MODE_VIEW_ONLY
+	switch {
+	case p.InitiateFileDownload && p.InitiateFileUpload:
+		allowed = gateway.OpenInAppRequest_VIEW_MODE_READ_WRITE
+	case p.InitiateFileDownload:
+		allowed = gateway.OpenInAppRequest_VIEW_MODE_READ_ONLY
+	}
+	if vm == gateway.OpenInAppRequest_VIEW_MODE_INVALID || vm > allowed {
+		return allowed
+	}
+	return vm
+}
+
+// findAppProvider returns the provider of the requested app or, if none is
+// requested, the one of the default app of the user for the mime type of the
+// resource, falling back to the default app of the registry.
+func (s *svc) findAppProvider(ctx context.Context, ri *storageprovider.ResourceInfo, app string) (*registry.ProviderInfo, error) {
 	c, err := pool.GetAppRegistryClient(s.c.AppRegistryEndpoint)
 	if err != nil {
 		err = errors.Wrap(err, "gateway: error getting appregistry client")
@@ -243,9 +274,16 @@ func (s *svc) findAppProvider(ctx context.Context, ri *storageprovider.ResourceI
 		return nil, err
 	}
 
-	// TODO(labkode): when sending an Open to the proxy we need to choose one
-	// provider from the list of available as the client
 	if res.Status.Code == rpc.Code_CODE_OK {
+		if app == "" {
+			app = s.userDefaultApp(ctx, ri.MimeType)
+		}
+		for _, p := range res.Providers {
+			if app != "" && apppkg.ProviderName(p) == app {
+				return p, nil
+			}
+		}
+		// the requested app can also be an app url the provider resolves
 		return res.Providers[0], nil
 	}
 
@@ -256,6 +294,21 @@ func (s *svc) findAppProvider(ctx context.Context, ri *storageprovider.ResourceI
 	return nil, errtypes.InternalError("gateway: error finding a storage provider")
 }
 
+// userDefaultApp returns the app the user chose as default for a mime type, if any
+func (s *svc) userDefaultApp(ctx context.Context, mimeType string) string {
+	res, err := s.GetKey(ctx, &preferences.GetKeyRequest{Key: apppkg.DefaultAppsKey})
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		// no preferences, eg. the key isn't set
+		return ""
+	}
+	apps, err := apppkg.DecodeDefaultApps([]byte(res.Val))
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("gateway: error decoding the default apps of the user")
+		return ""
+	}
+	return apps[mimeType]
+}
+
 func getGRPCConfig(opaque *typespb.Opaque) (bool, bool) {
 	if opaque == nil {
 		return false, false
@@ -267,12 +320,12 @@ func getGRPCConfig(opaque *typespb.Opaque) (bool, bool) {
 
 func getConn(host string, insecure, skipverify bool) (*grpc.ClientConn, error) {
 	if insecure {
-		return grpc.Dial(host, grpc.WithInsecure())
+		return grpc.Dial(host, grpc.WithInsecure(), grpc.WithStatsHandler(&ocgrpc.ClientHandler{}))
 	}
 
 	// TODO(labkode): if in the future we want client-side certificate validation,
 	// we need to load the client cert here
 	tlsconf := &tls.Config{InsecureSkipVerify: skipverify}
 	creds := credentials.NewTLS(tlsconf)
-	return grpc.Dial(host, grpc.WithTransportCredentials(creds))
+	return grpc.Dial(host, grpc.WithTransportCredentials(creds), grpc.WithStatsHandler(&ocgrpc.ClientHandler{}))
 }
@@ -22,13 +22,14 @@ import (
 	"context"
 	"fmt"
 
-	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	registry "github.com/cs3org/go-cs3apis/cs3/auth/registry/v1beta1"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
@@ -38,9 +39,49 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+// isDisabled tells whether the user logging in was disabled, according to the
+// auth provider or, when configured, to the user provider.
+func (s *svc) isDisabled(ctx context.Context, u *userpb.User) (bool, error) {
+	if userpkg.IsDisabled(u) {
+		return true, nil
+	}
+	if !s.c.CheckDisabledUsers {
+		return false, nil
+	}
+
+	ctx, err := s.asGroupResolver(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	c, err := pool.GetUserProviderServiceClient(s.c.UserProviderEndpoint)
+	if err != nil {
+		return false, errors.Wrap(err, "gateway: error getting user provider client")
+	}
+	res, err := c.GetUser(ctx, &userpb.GetUserRequest{UserId: u.Id})
+	if err != nil {
+		return false, errors.Wrap(err, "gateway: error calling GetUser")
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return userpkg.IsDisabled(res.User), nil
+	case rpc.Code_CODE_NOT_FOUND:
+		// the users unknown to the user provider can't be disabled in it
+		return false, nil
+	}
+	return false, status.NewErrorFromCode(res.Status.Code, "gateway")
+}
+
 func (s *svc) Authenticate(ctx context.Context, req *gateway.AuthenticateRequest) (*gateway.AuthenticateResponse, error) {
 	log := appctx.GetLogger(ctx)
 
+	// the token can be restricted to a narrower scope than the one granted by the auth provider
+	restriction, err := scope.GetRestriction(req.Opaque)
+	if err != nil {
+		return &gateway.AuthenticateResponse{
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		}, nil
+	}
+
 	// find auth provider
 	c, err := s.findAuthProvider(ctx, req.Type)
 	if err != nil {
@@ -93,7 +134,36 @@ func (s *svc) Authenticate(ctx context.Context, req *gateway.AuthenticateRequest
 		}, nil
 	}
 
-	token, err := s.tokenmgr.MintToken(ctx, res.User, res.TokenScope)
+	disabled, err := s.isDisabled(ctx, res.User)
+	if err != nil {
+		return &gateway.AuthenticateResponse{
+			Status: status.NewInternal(ctx, err, "error checking whether the user is disabled"),
+		}, nil
+	}
+	if disabled {
+		err := errtypes.PermissionDenied("gateway: user is disabled: " + res.User.Username)
+		return &gateway.AuthenticateResponse{
+			Status: status.NewPermissionDenied(ctx, err, "user is disabled"),
+		}, nil
+	}
+
+	if s.c.ResolveNestedGroups {
+		s.resolveNestedGroups(ctx, res.User)
+	}
+
+	tokenScope := res.TokenScope
+	if restriction != nil {
+		// a restriction must not give access beyond the resources of the user
+		if !scope.IsOwnerScope(res.TokenScope) {
+			err := errtypes.PermissionDenied("gateway: only the tokens with the owner scope can be restricted")
+			return &gateway.AuthenticateResponse{
+				Status: status.NewPermissionDenied(ctx, err, "the token can't be restricted"),
+			}, nil
+		}
+		tokenScope = restriction
+	}
+
+	token, err := s.tokenmgr.MintToken(ctx, res.User, tokenScope)
 	if err != nil {
 		err = errors.Wrap(err, "authsvc: error in MintToken")
 		res := &gateway.AuthenticateResponse{
@@ -102,7 +172,7 @@ func (s *svc) Authenticate(ctx context.Context, req *gateway.AuthenticateRequest
 		return res, nil
 	}
 
-	if scope, ok := res.TokenScope["user"]; s.c.DisableHomeCreationOnLogin || !ok || scope.Role != authpb.Role_ROLE_OWNER {
+	if s.c.DisableHomeCreationOnLogin || !scope.IsOwnerScope(tokenScope) {
 		gwRes := &gateway.AuthenticateResponse{
 			Status: status.NewOK(ctx),
 			User:   res.User,
@@ -66,3 +66,20 @@ func (s *svc) ListAuthProviders(ctx context.Context, req *registry.ListAuthProvi
 
 	return gwRes, nil
 }
+
+// checkAuthRegistry tells whether the auth registry can be reached, the
+// logins fail while it can't.
+func (s *svc) checkAuthRegistry(ctx context.Context) error {
+	c, err := pool.GetAuthRegistryServiceClient(s.c.AuthRegistryEndpoint)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error getting auth registry client")
+	}
+	res, err := c.ListAuthProviders(ctx, &registry.ListAuthProvidersRequest{})
+	if err != nil {
+		return errors.Wrap(err, "gateway: error calling ListAuthProviders")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(res.Status.Code, "gateway")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"io"
+	"strings"
+
+	changespb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// Subscribe relays the changes streamed by the storage provider holding the
+// referenced resource.
+func (s *svc) Subscribe(req *changespb.SubscribeRequest, stream changespb.ChangeService_SubscribeServer) error {
+	ctx := stream.Context()
+	log := appctx.GetLogger(ctx)
+
+	providers, err := s.findProviders(ctx, req.Ref)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return gstatus.Error(codes.NotFound, "gateway: no storage provider found for "+req.Ref.String())
+		}
+		return gstatus.Error(codes.Internal, "gateway: error finding storage provider: "+err.Error())
+	}
+	// subscriptions spanning several providers, e.g. on the root of the
+	// namespace, would need the paths of each stream to be rebased
+	if resPath := req.Ref.GetPath(); len(providers) != 1 || (resPath != "" && !strings.HasPrefix(resPath, providers[0].ProviderPath)) {
+		return gstatus.Error(codes.InvalidArgument, "gateway: changes can only be subscribed to within a single storage provider")
+	}
+
+	c, err := pool.GetChangeServiceClient(providers[0].Address)
+	if err != nil {
+		return gstatus.Error(codes.Internal, "gateway: error getting change service client: "+err.Error())
+	}
+	changes, err := c.Subscribe(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := changes.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(n); err != nil {
+			log.Debug().Err(err).Msg("gateway: error relaying change, closing subscription")
+			return err
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// progressStep is the amount of bytes after which the progress of a copy is logged.
+const progressStep = 64 * 1024 * 1024
+
+func isCopy(req *provider.MoveRequest) bool {
+	return req.Opaque != nil && req.Opaque.Map != nil && req.Opaque.Map[storage.CopyOpaqueKey] != nil
+}
+
+// copyProgress keeps track of the files and bytes copied by the gateway.
+type copyProgress struct {
+	log        *zerolog.Logger
+	total      uint64
+	files      uint64
+	bytes      uint64
+	lastLogged uint64
+}
+
+func (p *copyProgress) add(n uint64) {
+	b := atomic.AddUint64(&p.bytes, n)
+	if b-atomic.LoadUint64(&p.lastLogged) >= progressStep {
+		atomic.StoreUint64(&p.lastLogged, b)
+		p.log.Info().Uint64("bytes", b).Uint64("total", p.total).Uint64("files", atomic.LoadUint64(&p.files)).Msg("gateway: copy in progress")
+	}
+}
+
+func (p *copyProgress) opaque() *types.Opaque {
+	return &types.Opaque{
+		Map: map[string]*types.OpaqueEntry{
+			"files": {
+				Decoder: "plain",
+				Value:   []byte(strconv.FormatUint(atomic.LoadUint64(&p.files), 10)),
+			},
+			"bytes": {
+				Decoder: "plain",
+				Value:   []byte(strconv.FormatUint(atomic.LoadUint64(&p.bytes), 10)),
+			},
+		},
+	}
+}
+
+// progressReader reports every read to the copy progress.
+type progressReader struct {
+	io.Reader
+	p *copyProgress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	r.p.add(uint64(n))
+	return n, err
+}
+
+// copy performs a server-side copy. If both references live on the same storage provider
+// the copy is delegated to it, otherwise, or if the driver can't copy, the data is streamed
+// through the data gateway without leaving the datacenter.
+func (s *svc) copy(ctx context.Context, req *provider.MoveRequest, srcP, dstP *registry.ProviderInfo) (*provider.MoveResponse, error) {
+	log := appctx.GetLogger(ctx)
+
+	if srcP.Address == dstP.Address {
+		c, err := s.getStorageProviderClient(ctx, srcP)
+		if err != nil {
+			return &provider.MoveResponse{
+				Status: status.NewInternal(ctx, err, "error connecting to storage provider="+srcP.Address),
+			}, nil
+		}
+		res, err := c.Move(ctx, req)
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway: error calling Move")
+		}
+		if res.Status.Code != rpc.Code_CODE_UNIMPLEMENTED {
+			return res, nil
+		}
+		log.Debug().Str("provider", srcP.Address).Msg("gateway: storage provider can't copy, falling back to streaming")
+	}
+
+	if req.Destination.GetPath() == "" {
+		return &provider.MoveResponse{
+			Status: status.NewInvalidArg(ctx, "copy destination must be a path"),
+		}, nil
+	}
+
+	statRes, err := s.stat(ctx, &provider.StatRequest{Ref: req.Source})
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error stating ref:"+req.Source.String()),
+		}, nil
+	}
+	if statRes.Status.Code != rpc.Code_CODE_OK {
+		return &provider.MoveResponse{
+			Status: statRes.Status,
+		}, nil
+	}
+
+	if statRes.Info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && strings.HasPrefix(path.Clean(req.Destination.GetPath())+"/", path.Clean(statRes.Info.Path)+"/") {
+		return &provider.MoveResponse{
+			Status: status.NewInvalidArg(ctx, "cannot copy a folder into itself"),
+		}, nil
+	}
+
+	sublog := log.With().Str("src", statRes.Info.Path).Str("dst", req.Destination.GetPath()).Logger()
+	progress := &copyProgress{
+		log:   &sublog,
+		total: statRes.Info.Size,
+	}
+	if err := s.streamCopy(ctx, statRes.Info, req.Destination.GetPath(), progress); err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewStatusFromErrType(ctx, "gateway: error copying "+statRes.Info.Path, err),
+		}, nil
+	}
+	sublog.Info().Uint64("bytes", atomic.LoadUint64(&progress.bytes)).Uint64("files", atomic.LoadUint64(&progress.files)).Msg("gateway: copy finished")
+
+	return &provider.MoveResponse{
+		Status: status.NewOK(ctx),
+		Opaque: progress.opaque(),
+	}, nil
+}
+
+func (s *svc) streamCopy(ctx context.Context, src *provider.ResourceInfo, dst string, progress *copyProgress) error {
+	if src.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		createRes, err := s.createContainer(ctx, &provider.CreateContainerRequest{
+			Ref: &provider.Reference{
+				Spec: &provider.Reference_Path{Path: dst},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if createRes.Status.Code != rpc.Code_CODE_OK && createRes.Status.Code != rpc.Code_CODE_ALREADY_EXISTS {
+			return status.NewErrorFromCode(createRes.Status.Code, "gateway")
+		}
+
+		listRes, err := s.listContainer(ctx, &provider.ListContainerRequest{
+			Ref: &provider.Reference{
+				Spec: &provider.Reference_Path{Path: src.Path},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if listRes.Status.Code != rpc.Code_CODE_OK {
+			return status.NewErrorFromCode(listRes.Status.Code, "gateway")
+		}
+
+		for _, child := range listRes.Infos {
+			if err := s.streamCopy(ctx, child, path.Join(dst, path.Base(child.Path)), progress); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	downloadRes, err := s.initiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: src.Path},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if downloadRes.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(downloadRes.Status.Code, "gateway")
+	}
+	downloadEP, downloadToken, err := getSimpleDownloadProtocol(downloadRes.Protocols)
+	if err != nil {
+		return err
+	}
+
+	uploadRes, err := s.initiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: dst},
+		},
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatUint(src.Size, 10)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if uploadRes.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(uploadRes.Status.Code, "gateway")
+	}
+	uploadEP, uploadToken, err := getSimpleUploadProtocol(uploadRes.Protocols)
+	if err != nil {
+		return err
+	}
+
+	httpDownloadReq, err := rhttp.NewRequest(ctx, http.MethodGet, downloadEP, nil)
+	if err != nil {
+		return err
+	}
+	httpDownloadReq.Header.Set(datagateway.TokenTransportHeader, downloadToken)
+
+	httpDownloadRes, err := s.httpClient.Do(httpDownloadReq)
+	if err != nil {
+		return err
+	}
+	defer httpDownloadRes.Body.Close()
+	if httpDownloadRes.StatusCode != http.StatusOK {
+		return errtypes.InternalError(fmt.Sprintf("gateway: error downloading %s: status code %d", src.Path, httpDownloadRes.StatusCode))
+	}
+
+	httpUploadReq, err := rhttp.NewRequest(ctx, http.MethodPut, uploadEP, &progressReader{Reader: httpDownloadRes.Body, p: progress})
+	if err != nil {
+		return err
+	}
+	httpUploadReq.Header.Set(datagateway.TokenTransportHeader, uploadToken)
+	httpUploadReq.ContentLength = int64(src.Size)
+
+	httpUploadRes, err := s.httpClient.Do(httpUploadReq)
+	if err != nil {
+		return err
+	}
+	defer httpUploadRes.Body.Close()
+	if httpUploadRes.StatusCode != http.StatusOK {
+		return errtypes.InternalError(fmt.Sprintf("gateway: error uploading %s: status code %d", dst, httpUploadRes.StatusCode))
+	}
+
+	atomic.AddUint64(&progress.files, 1)
+	return nil
+}
+
+func getSimpleDownloadProtocol(protocols []*gateway.FileDownloadProtocol) (string, string, error) {
+	for _, p := range protocols {
+		if p.Protocol == "simple" {
+			return p.DownloadEndpoint, p.Token, nil
+		}
+	}
+	return "", "", errtypes.NotSupported("gateway: simple download protocol not available")
+}
+
+func getSimpleUploadProtocol(protocols []*gateway.FileUploadProtocol) (string, string, error) {
+	for _, p := range protocols {
+		if p.Protocol == "simple" {
+			return p.UploadEndpoint, p.Token, nil
+		}
+	}
+	return "", "", errtypes.NotSupported("gateway: simple upload protocol not available")
+}
@@ -0,0 +1,381 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/storage"
+	"google.golang.org/grpc"
+)
+
+// memTree is the content of the storage providers of the copy tests, the
+// folders have a nil content. Its http server serves the data of the files.
+type memTree struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	data  *httptest.Server
+}
+
+func newMemTree(t *testing.T, files map[string]string) *memTree {
+	m := &memTree{files: map[string][]byte{}}
+	for p, c := range files {
+		if strings.HasSuffix(p, "/") {
+			m.files[path.Clean(p)] = nil
+		} else {
+			m.files[p] = []byte(c)
+		}
+	}
+	m.data = httptest.NewServer(m)
+	t.Cleanup(m.data.Close)
+	return m
+}
+
+func (m *memTree) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		c, ok := m.files[r.URL.Path]
+		if !ok || c == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(c)
+	case http.MethodPut:
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		m.files[r.URL.Path] = b
+	}
+}
+
+func (m *memTree) info(p string) (*provider.ResourceInfo, bool) {
+	c, ok := m.files[p]
+	if !ok {
+		return nil, false
+	}
+	info := &provider.ResourceInfo{
+		Id:   &provider.ResourceId{StorageId: "mem", OpaqueId: p},
+		Path: p,
+		Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+		Size: uint64(len(c)),
+	}
+	if c == nil {
+		info.Type = provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	}
+	return info, true
+}
+
+// content returns the files below the path with their content, relative to it.
+func (m *memTree) content(root string) map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := map[string]string{}
+	for p, c := range m.files {
+		if strings.HasPrefix(p, root+"/") {
+			rel := strings.TrimPrefix(p, root+"/")
+			if c == nil {
+				rel += "/"
+			}
+			files[rel] = string(c)
+		}
+	}
+	return files
+}
+
+// memProvider is a storage provider of a part of the memTree. It copies on
+// Move requests with the Copy opaque when native is set, it doesn't
+// implement the copies otherwise.
+type memProvider struct {
+	provider.ProviderAPIClient
+	tree   *memTree
+	native bool
+	moves  []*provider.MoveRequest
+}
+
+func (p *memProvider) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	p.tree.mu.Lock()
+	defer p.tree.mu.Unlock()
+	info, ok := p.tree.info(req.Ref.GetPath())
+	if !ok {
+		return &provider.StatResponse{Status: status.NewNotFound(ctx, req.Ref.GetPath())}, nil
+	}
+	return &provider.StatResponse{Status: status.NewOK(ctx), Info: info}, nil
+}
+
+func (p *memProvider) CreateContainer(ctx context.Context, req *provider.CreateContainerRequest, opts ...grpc.CallOption) (*provider.CreateContainerResponse, error) {
+	p.tree.mu.Lock()
+	defer p.tree.mu.Unlock()
+	fn := req.Ref.GetPath()
+	if _, ok := p.tree.files[fn]; ok {
+		return &provider.CreateContainerResponse{Status: status.NewAlreadyExists(ctx, nil, fn)}, nil
+	}
+	p.tree.files[fn] = nil
+	return &provider.CreateContainerResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (p *memProvider) ListContainer(ctx context.Context, req *provider.ListContainerRequest, opts ...grpc.CallOption) (*provider.ListContainerResponse, error) {
+	p.tree.mu.Lock()
+	defer p.tree.mu.Unlock()
+	var infos []*provider.ResourceInfo
+	for fn := range p.tree.files {
+		if path.Dir(fn) == req.Ref.GetPath() {
+			info, _ := p.tree.info(fn)
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return &provider.ListContainerResponse{Status: status.NewOK(ctx), Infos: infos}, nil
+}
+
+func (p *memProvider) InitiateFileDownload(ctx context.Context, req *provider.InitiateFileDownloadRequest, opts ...grpc.CallOption) (*provider.InitiateFileDownloadResponse, error) {
+	return &provider.InitiateFileDownloadResponse{
+		Status: status.NewOK(ctx),
+		Protocols: []*provider.FileDownloadProtocol{{
+			Protocol:         "simple",
+			DownloadEndpoint: p.tree.data.URL + req.Ref.GetPath(),
+			Expose:           true,
+		}},
+	}, nil
+}
+
+func (p *memProvider) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest, opts ...grpc.CallOption) (*provider.InitiateFileUploadResponse, error) {
+	return &provider.InitiateFileUploadResponse{
+		Status: status.NewOK(ctx),
+		Protocols: []*provider.FileUploadProtocol{{
+			Protocol:       "simple",
+			UploadEndpoint: p.tree.data.URL + req.Ref.GetPath(),
+			Expose:         true,
+		}},
+	}, nil
+}
+
+func (p *memProvider) Move(ctx context.Context, req *provider.MoveRequest, opts ...grpc.CallOption) (*provider.MoveResponse, error) {
+	p.tree.mu.Lock()
+	defer p.tree.mu.Unlock()
+	p.moves = append(p.moves, req)
+	if !isCopy(req) {
+		return &provider.MoveResponse{Status: status.NewUnimplemented(ctx, nil, "only copies")}, nil
+	}
+	if !p.native {
+		return &provider.MoveResponse{Status: status.NewUnimplemented(ctx, nil, "copy not supported")}, nil
+	}
+	src, dst := req.Source.GetPath(), req.Destination.GetPath()
+	for fn, c := range p.tree.files {
+		if fn == src || strings.HasPrefix(fn, src+"/") {
+			p.tree.files[dst+strings.TrimPrefix(fn, src)] = c
+		}
+	}
+	return &provider.MoveResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// memRegistry finds the provider by the first element of the path.
+type memRegistry struct {
+	registry.RegistryAPIClient
+}
+
+func (memRegistry) GetStorageProviders(ctx context.Context, req *registry.GetStorageProvidersRequest, opts ...grpc.CallOption) (*registry.GetStorageProvidersResponse, error) {
+	root := "/" + strings.SplitN(strings.TrimPrefix(req.Ref.GetPath(), "/"), "/", 2)[0]
+	return &registry.GetStorageProvidersResponse{
+		Status:    status.NewOK(ctx),
+		Providers: []*registry.ProviderInfo{{Address: root, ProviderPath: root}},
+	}, nil
+}
+
+// newCopyService returns a gateway with the providers of the roots.
+func newCopyService(providers map[string]*memProvider) *svc {
+	c := &config{}
+	c.init()
+	return &svc{
+		c:          c,
+		httpClient: http.DefaultClient,
+		providerClient: func(addr string) (provider.ProviderAPIClient, error) {
+			if p, ok := providers[addr]; ok {
+				return p, nil
+			}
+			return nil, errors.New("unknown provider " + addr)
+		},
+		registryClient: func(string) (registry.RegistryAPIClient, error) {
+			return memRegistry{}, nil
+		},
+	}
+}
+
+func copyRequest(src, dst string) *provider.MoveRequest {
+	return &provider.MoveRequest{
+		Source:      &provider.Reference{Spec: &provider.Reference_Path{Path: src}},
+		Destination: &provider.Reference{Spec: &provider.Reference_Path{Path: dst}},
+		Opaque: &types.Opaque{Map: map[string]*types.OpaqueEntry{
+			storage.CopyOpaqueKey: {Decoder: "plain", Value: []byte("true")},
+		}},
+	}
+}
+
+var copyTree = map[string]string{
+	"/a/":                 "",
+	"/a/docs/":            "",
+	"/a/docs/notes.txt":   "notes",
+	"/a/docs/sub/":        "",
+	"/a/docs/sub/big.bin": "0123456789",
+	"/a/docs/empty/":      "",
+	"/b/":                 "",
+	"/b/existing/":        "",
+	"/b/existing/old.txt": "old",
+	"/b/existing/sub/":    "",
+}
+
+func TestCopyStreamed(t *testing.T) {
+	tree := newMemTree(t, copyTree)
+	a, b := &memProvider{tree: tree}, &memProvider{tree: tree}
+	s := newCopyService(map[string]*memProvider{"/a": a, "/b": b})
+	ctx := context.Background()
+
+	res, err := s.Move(ctx, copyRequest("/a/docs", "/b/docs"))
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		t.Fatalf("copy across providers = %v, %v", res.GetStatus(), err)
+	}
+	want := map[string]string{"notes.txt": "notes", "sub/": "", "sub/big.bin": "0123456789", "empty/": ""}
+	if got := tree.content("/b/docs"); !equalContent(got, want) {
+		t.Errorf("the folder was copied as %v, wanted %v", got, want)
+	}
+	if got := tree.content("/a/docs"); !equalContent(got, want) {
+		t.Errorf("the source was changed to %v", got)
+	}
+	m := res.Opaque.GetMap()
+	if string(m["files"].GetValue()) != "2" || string(m["bytes"].GetValue()) != "15" {
+		t.Errorf("the copy reported %s files and %s bytes, wanted 2 and 15", m["files"].GetValue(), m["bytes"].GetValue())
+	}
+	if len(a.moves) != 0 || len(b.moves) != 0 {
+		t.Error("the copy across providers was sent to a provider")
+	}
+
+	// a plain move is not a copy
+	if isCopy(&provider.MoveRequest{}) || !isCopy(copyRequest("/a", "/b")) {
+		t.Error("the Copy opaque must turn a move into a copy")
+	}
+}
+
+func TestCopyOverwrite(t *testing.T) {
+	tree := newMemTree(t, copyTree)
+	s := newCopyService(map[string]*memProvider{"/a": {tree: tree}, "/b": {tree: tree}})
+	ctx := context.Background()
+
+	// the files of the destination are replaced, the others are kept
+	tree.files["/a/docs/old.txt"] = []byte("new")
+	res, err := s.Move(ctx, copyRequest("/a/docs", "/b/existing"))
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		t.Fatalf("copy onto a folder = %v, %v", res.GetStatus(), err)
+	}
+	want := map[string]string{"old.txt": "new", "notes.txt": "notes", "sub/": "", "sub/big.bin": "0123456789", "empty/": ""}
+	if got := tree.content("/b/existing"); !equalContent(got, want) {
+		t.Errorf("the folder was copied as %v, wanted %v", got, want)
+	}
+
+	res, err = s.Move(ctx, copyRequest("/a/docs/notes.txt", "/b/existing/old.txt"))
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		t.Fatalf("copy onto a file = %v, %v", res.GetStatus(), err)
+	}
+	if got := tree.content("/b/existing")["old.txt"]; got != "notes" {
+		t.Errorf("the file was overwritten with %q", got)
+	}
+}
+
+func TestCopyDelegated(t *testing.T) {
+	tree := newMemTree(t, copyTree)
+	native := &memProvider{tree: tree, native: true}
+	s := newCopyService(map[string]*memProvider{"/a": native})
+	ctx := context.Background()
+
+	req := copyRequest("/a/docs", "/a/copy")
+	res, err := s.Move(ctx, req)
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		t.Fatalf("copy on a provider = %v, %v", res.GetStatus(), err)
+	}
+	if len(native.moves) != 1 || !isCopy(native.moves[0]) {
+		t.Fatalf("the copy was not delegated with the Copy opaque: %v", native.moves)
+	}
+	if res.Opaque.GetMap()["files"] != nil {
+		t.Error("the delegated copy was streamed")
+	}
+	if got := tree.content("/a/copy"); got["sub/big.bin"] != "0123456789" {
+		t.Errorf("the folder was copied as %v", got)
+	}
+}
+
+func TestCopyFallback(t *testing.T) {
+	tree := newMemTree(t, copyTree)
+	a := &memProvider{tree: tree}
+	s := newCopyService(map[string]*memProvider{"/a": a})
+	ctx := context.Background()
+
+	// the providers which can't copy get the data streamed
+	res, err := s.Move(ctx, copyRequest("/a/docs", "/a/copy"))
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		t.Fatalf("copy on a provider without copies = %v, %v", res.GetStatus(), err)
+	}
+	if len(a.moves) != 1 {
+		t.Errorf("the copy was not tried on the provider first: %v", a.moves)
+	}
+	if got := tree.content("/a/copy"); got["sub/big.bin"] != "0123456789" || got["notes.txt"] != "notes" {
+		t.Errorf("the folder was copied as %v", got)
+	}
+
+	for _, dst := range []string{"/a/docs/sub/copy", "/a/docs"} {
+		res, err = s.Move(ctx, copyRequest("/a/docs", dst))
+		if err != nil || res.Status.Code != rpc.Code_CODE_INVALID_ARGUMENT {
+			t.Errorf("copy of a folder to %s = %v, %v, wanted invalid argument", dst, res.GetStatus(), err)
+		}
+	}
+	if _, ok := tree.files["/a/docs/sub/copy"]; ok {
+		t.Error("the folder was copied into itself")
+	}
+	// a sibling with the same prefix is not inside the folder
+	if res, err = s.Move(ctx, copyRequest("/a/docs", "/a/docs2")); err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		t.Errorf("copy of a folder to a sibling = %v, %v", res.GetStatus(), err)
+	}
+}
+
+func equalContent(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
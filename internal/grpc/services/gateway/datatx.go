@@ -45,6 +45,15 @@ func (s *svc) CreateTransfer(ctx context.Context, req *datatx.CreateTransferRequ
 }
 
 func (s *svc) GetTransferStatus(ctx context.Context, req *datatx.GetTransferStatusRequest) (*datatx.GetTransferStatusResponse, error) {
+	// cross storage moves are tracked by the gateway itself
+	if t, ok := s.transfers.get(req.GetTxId().GetOpaqueId()); ok {
+		return &datatx.GetTransferStatusResponse{
+			Status: status.NewOK(ctx),
+			TxInfo: t.info(req.GetTxId().GetOpaqueId()),
+			Opaque: t.progressOpaque(),
+		}, nil
+	}
+
 	c, err := pool.GetDataTxClient(s.c.DataTxEndpoint)
 	if err != nil {
 		err = errors.Wrap(err, "gateway: error calling GetOCMShareProviderClient")
@@ -62,6 +71,15 @@ func (s *svc) GetTransferStatus(ctx context.Context, req *datatx.GetTransferStat
 }
 
 func (s *svc) CancelTransfer(ctx context.Context, req *datatx.CancelTransferRequest) (*datatx.CancelTransferResponse, error) {
+	if t, ok := s.transfers.get(req.GetTxId().GetOpaqueId()); ok {
+		t.cancel()
+		return &datatx.CancelTransferResponse{
+			Status: status.NewOK(ctx),
+			TxInfo: t.info(req.GetTxId().GetOpaqueId()),
+			Opaque: t.progressOpaque(),
+		}, nil
+	}
+
 	c, err := pool.GetDataTxClient(s.c.DataTxEndpoint)
 	if err != nil {
 		err = errors.Wrap(err, "gateway: error calling GetOCMShareProviderClient")
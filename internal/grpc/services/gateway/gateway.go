@@ -19,17 +19,32 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/ReneKroon/ttlcache/v2"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	storageregistry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 
-	"github.com/ReneKroon/ttlcache/v2"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	changespb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/health"
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/ops"
 	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/storage/cache"
+	cacheregistry "github.com/cs3org/reva/pkg/storage/cache/registry"
 	"github.com/cs3org/reva/pkg/token"
 	"github.com/cs3org/reva/pkg/token/manager/registry"
 	"github.com/mitchellh/mapstructure"
@@ -57,6 +72,8 @@ type config struct {
 	GroupProviderEndpoint         string `mapstructure:"groupprovidersvc"`
 	DataTxEndpoint                string `mapstructure:"datatx"`
 	DataGatewayEndpoint           string `mapstructure:"datagateway"`
+	DataGatewayInsecure           bool   `mapstructure:"datagateway_insecure"`
+	DataGatewayMTLS               bool   `mapstructure:"datagateway_mtls"`
 	CommitShareToStorageGrant     bool   `mapstructure:"commit_share_to_storage_grant"`
 	CommitShareToStorageRef       bool   `mapstructure:"commit_share_to_storage_ref"`
 	DisableHomeCreationOnLogin    bool   `mapstructure:"disable_home_creation_on_login"`
@@ -69,6 +86,50 @@ type config struct {
 	HomeMapping         string                            `mapstructure:"home_mapping"`
 	TokenManagers       map[string]map[string]interface{} `mapstructure:"token_managers"`
 	EtagCacheTTL        int                               `mapstructure:"etag_cache_ttl"`
+	// StatCacheTTL is the time in seconds stat results are cached, 0 disables caching them.
+	StatCacheTTL int `mapstructure:"stat_cache_ttl"`
+	// ListCacheTTL is the time in seconds folder listings are cached, 0 disables caching them.
+	// A cached listing is only used while the folder keeps the etag it was listed with.
+	ListCacheTTL     int                               `mapstructure:"list_cache_ttl"`
+	StatCacheDriver  string                            `mapstructure:"stat_cache_driver"`
+	StatCacheDrivers map[string]map[string]interface{} `mapstructure:"stat_cache_drivers"`
+	// StatCacheEvents drops the cached stat results and listings of the paths changed
+	// according to the file events of the event stream, e.g. by uploads to the data providers.
+	StatCacheEvents bool `mapstructure:"stat_cache_events"`
+	// EventStream is the event stream the share and space events are published to, none disables them.
+	EventStream  string                            `mapstructure:"event_stream"`
+	EventStreams map[string]map[string]interface{} `mapstructure:"event_streams"`
+	// DisableShareExpirationCheck skips checking that the references in the share folder
+	// point to shares the user still receives, which hides the ones of expired shares.
+	DisableShareExpirationCheck bool `mapstructure:"disable_share_expiration_check"`
+	// ResolveNestedGroups adds to the groups of the users logging in the groups they are
+	// members of through other groups, looked up in the group provider.
+	ResolveNestedGroups bool `mapstructure:"resolve_nested_groups"`
+	// NestedGroupsCacheTTL is the time in seconds the parent groups of a group are cached.
+	NestedGroupsCacheTTL int `mapstructure:"nested_groups_cache_ttl"`
+	// ListConcurrency is the number of storage providers listed at the same time when a
+	// folder spans several of them.
+	ListConcurrency int `mapstructure:"list_concurrency"`
+	// ListProviderTimeout is the time in milliseconds a storage provider is given to list
+	// a folder spanning several providers, 0 waits for it. The providers failing the deadline
+	// are left out of the listing, which is then flagged as degraded.
+	ListProviderTimeout int `mapstructure:"list_provider_timeout"`
+	// CheckDisabledUsers refuses the logins of the users disabled in the user provider,
+	// looked up on every login. The users flagged by the auth provider are always refused.
+	CheckDisabledUsers bool `mapstructure:"check_disabled_users"`
+	// HedgeDelay is the time in milliseconds after which a Stat or ListContainer call to a
+	// storage provider that hasn't answered yet is sent to one of its replicas as well, the
+	// slower call being canceled. 0 disables hedging.
+	HedgeDelay int `mapstructure:"hedge_delay"`
+	// SpaceGracePeriod is the number of days the deleted storage spaces are kept disabled,
+	// hidden from the catalog and read-only, before being purged. They can be restored
+	// meanwhile. 0 deletes them at once.
+	SpaceGracePeriod int `mapstructure:"space_grace_period"`
+	// DisabledSpacesFile is the file the disabled spaces are kept in, to be shared by the
+	// gateways of a deployment.
+	DisabledSpacesFile string `mapstructure:"disabled_spaces_file"`
+	// SpacePurgeInterval is how often in seconds the spaces past their grace period are purged.
+	SpacePurgeInterval int `mapstructure:"space_purge_interval"`
 }
 
 // sets defaults
@@ -87,6 +148,26 @@ func (c *config) init() {
 		c.TokenManager = "jwt"
 	}
 
+	if c.StatCacheDriver == "" {
+		c.StatCacheDriver = "memory"
+	}
+
+	if c.NestedGroupsCacheTTL == 0 {
+		c.NestedGroupsCacheTTL = 300
+	}
+
+	if c.ListConcurrency <= 0 {
+		c.ListConcurrency = 10
+	}
+
+	if c.DisabledSpacesFile == "" {
+		c.DisabledSpacesFile = "/var/tmp/reva/disabled_spaces.json"
+	}
+
+	if c.SpacePurgeInterval == 0 {
+		c.SpacePurgeInterval = 3600
+	}
+
 	// if services address are not specified we used the shared conf
 	// for the gatewaysvc to have dev setups very quickly.
 	c.AuthRegistryEndpoint = sharedconf.GetGatewaySVC(c.AuthRegistryEndpoint)
@@ -116,10 +197,30 @@ func (c *config) init() {
 }
 
 type svc struct {
+	// the counters come first for the alignment of the atomic operations
+	statHits       uint64
+	statMisses     uint64
+	listHits       uint64
+	listMisses     uint64
+	groupHits      uint64
+	groupMisses    uint64
+	hedgedCalls    uint64
+	hedgeWins      uint64
 	c              *config
 	dataGatewayURL url.URL
 	tokenmgr       token.Manager
-	etagCache      *ttlcache.Cache `mapstructure:"etag_cache"`
+	statCache      cache.Cache
+	groupCache     *ttlcache.Cache
+	httpClient     *http.Client
+	transfers      *moveTransfers
+	events         *events.Emitter
+	stopEvents     context.CancelFunc
+	disabledSpaces *disabledSpaces
+	stopPurge      context.CancelFunc
+	// providerClient returns the client of the storage provider at an address
+	providerClient func(addr string) (provider.ProviderAPIClient, error)
+	// registryClient returns the client of the storage registry at an address
+	registryClient func(addr string) (storageregistry.RegistryAPIClient, error)
 }
 
 // New creates a new gateway svc that acts as a proxy for any grpc operation.
@@ -144,15 +245,64 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		return nil, err
 	}
 
-	etagCache := ttlcache.NewCache()
-	_ = etagCache.SetTTL(time.Duration(c.EtagCacheTTL) * time.Second)
-	etagCache.SkipTTLExtensionOnHit(true)
+	statCache, err := getStatCache(c)
+	if err != nil {
+		return nil, err
+	}
+
+	emitter, err := eventsregistry.NewEmitter(c.EventStream, c.EventStreams)
+	if err != nil {
+		return nil, err
+	}
 
 	s := &svc{
 		c:              c,
 		dataGatewayURL: *u,
 		tokenmgr:       tokenManager,
-		etagCache:      etagCache,
+		statCache:      statCache,
+		httpClient: rhttp.GetHTTPClient(
+			rhttp.Insecure(c.DataGatewayInsecure),
+			rhttp.MutualTLS(c.DataGatewayMTLS),
+		),
+		transfers:      newMoveTransfers(),
+		events:         emitter,
+		providerClient: pool.GetStorageProviderServiceClient,
+		registryClient: pool.GetStorageRegistryClient,
+	}
+
+	if c.StatCacheTTL > 0 {
+		ops.Register(ops.Caches, "gateway/stat", s.statCacheProbe)
+	}
+	if c.ListCacheTTL > 0 {
+		ops.Register(ops.Caches, "gateway/list", s.listCacheProbe)
+	}
+	if c.StatCacheEvents && (c.StatCacheTTL > 0 || c.ListCacheTTL > 0) {
+		if err := s.consumeFileEvents(); err != nil {
+			return nil, err
+		}
+	}
+	if c.ResolveNestedGroups {
+		s.groupCache = ttlcache.NewCache()
+		// bound how long group changes take to apply
+		s.groupCache.SkipTTLExtensionOnHit(true)
+		if err := s.groupCache.SetTTL(time.Duration(c.NestedGroupsCacheTTL) * time.Second); err != nil {
+			return nil, err
+		}
+		ops.Register(ops.Caches, "gateway/groups", s.groupCacheProbe)
+	}
+	if c.SpaceGracePeriod > 0 {
+		if s.disabledSpaces, err = loadDisabledSpaces(c.DisabledSpacesFile); err != nil {
+			return nil, err
+		}
+		var ctx context.Context
+		ctx, s.stopPurge = context.WithCancel(context.Background())
+		go s.purgeDisabledSpaces(ctx, time.Duration(c.SpacePurgeInterval)*time.Second)
+	}
+	ops.Register(ops.Transfers, "gateway/moves", s.transfers.probe)
+	health.Register("gateway/authregistry", s.checkAuthRegistry)
+	metrics.RegisterCollector("gateway/caches", s.collectCaches)
+	if c.HedgeDelay > 0 {
+		metrics.RegisterCollector("gateway/hedging", s.collectHedging)
 	}
 
 	return s, nil
@@ -160,11 +310,33 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 
 func (s *svc) Register(ss *grpc.Server) {
 	gateway.RegisterGatewayAPIServer(ss, s)
+	spacespb.RegisterSpacesServiceServer(ss, s)
+	changespb.RegisterChangeServiceServer(ss, s)
 }
 
 func (s *svc) Close() error {
-	s.etagCache.Close()
-	return nil
+	ops.Unregister(ops.Caches, "gateway/stat")
+	ops.Unregister(ops.Caches, "gateway/list")
+	if s.stopEvents != nil {
+		s.stopEvents()
+	}
+	if s.stopPurge != nil {
+		s.stopPurge()
+	}
+	ops.Unregister(ops.Caches, "gateway/groups")
+	ops.Unregister(ops.Transfers, "gateway/moves")
+	health.Unregister("gateway/authregistry")
+	metrics.UnregisterCollector("gateway/caches")
+	metrics.UnregisterCollector("gateway/hedging")
+	if s.groupCache != nil {
+		if err := s.groupCache.Close(); err != nil {
+			return err
+		}
+	}
+	if err := s.events.Close(); err != nil {
+		return err
+	}
+	return s.statCache.Close()
 }
 
 func (s *svc) UnprotectedEndpoints() []string {
@@ -187,3 +359,25 @@ func getTokenManager(manager string, m map[string]map[string]interface{}) (token
 
 	return nil, errtypes.NotFound(fmt.Sprintf("driver %s not found for token manager", manager))
 }
+
+func getStatCache(c *config) (cache.Cache, error) {
+	f, ok := cacheregistry.NewFuncs[c.StatCacheDriver]
+	if !ok {
+		return nil, errtypes.NotFound(fmt.Sprintf("driver %s not found for stat cache", c.StatCacheDriver))
+	}
+	m := map[string]interface{}{}
+	for k, v := range c.StatCacheDrivers[c.StatCacheDriver] {
+		m[k] = v
+	}
+	// the gateway wide ttls apply unless the driver is configured otherwise
+	if _, ok := m["stat_ttl"]; !ok {
+		m["stat_ttl"] = c.StatCacheTTL
+	}
+	if _, ok := m["list_ttl"]; !ok {
+		m["list_ttl"] = c.ListCacheTTL
+	}
+	if _, ok := m["etag_ttl"]; !ok {
+		m["etag_ttl"] = c.EtagCacheTTL
+	}
+	return f(m)
+}
@@ -0,0 +1,132 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// providerCall is an idempotent read sent to a storage provider.
+type providerCall func(ctx context.Context, c provider.ProviderAPIClient) (interface{}, error)
+
+// replicaAddresses returns the address of the provider followed by the ones
+// of its replicas announced by the storage registry.
+func replicaAddresses(p *registry.ProviderInfo) []string {
+	addrs := []string{p.Address}
+	if e, ok := p.GetOpaque().GetMap()[storage.ReplicasOpaqueKey]; ok && e.Decoder == "plain" {
+		for _, a := range strings.Split(string(e.Value), ",") {
+			if a = strings.TrimSpace(a); a != "" && a != p.Address {
+				addrs = append(addrs, a)
+			}
+		}
+	}
+	return addrs
+}
+
+type hedgeResult struct {
+	res    interface{}
+	err    error
+	hedged bool
+}
+
+// hedge sends the call to the provider and, when it hasn't answered within
+// the hedge delay or failed, to one of its replicas too. The first answer
+// wins and the other call is canceled. Only idempotent reads may be hedged.
+func (s *svc) hedge(ctx context.Context, p *registry.ProviderInfo, call providerCall) (interface{}, error) {
+	addrs := replicaAddresses(p)
+	if s.c.HedgeDelay <= 0 || len(addrs) < 2 {
+		c, err := s.providerClient(p.Address)
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway: error getting a storage provider client")
+		}
+		return call(ctx, c)
+	}
+
+	// the loser is canceled when the winner returns
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	send := func(addr string, hedged bool) {
+		c, err := s.providerClient(addr)
+		if err != nil {
+			results <- hedgeResult{err: errors.Wrap(err, "gateway: error getting a storage provider client"), hedged: hedged}
+			return
+		}
+		res, err := call(ctx, c)
+		results <- hedgeResult{res: res, err: err, hedged: hedged}
+	}
+	go send(addrs[0], false)
+
+	timer := time.NewTimer(time.Duration(s.c.HedgeDelay) * time.Millisecond)
+	defer timer.Stop()
+
+	pending, hedged := 1, false
+	sendHedge := func() {
+		hedged = true
+		pending++
+		atomic.AddUint64(&s.hedgedCalls, 1)
+		go send(addrs[1+rand.Intn(len(addrs)-1)], true)
+	}
+
+	var firstErr error
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if r.hedged {
+					atomic.AddUint64(&s.hedgeWins, 1)
+				}
+				return r.res, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if !hedged {
+				sendHedge()
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		case <-timer.C:
+			if !hedged {
+				sendHedge()
+			}
+		}
+	}
+}
+
+// collectHedging returns the number of hedged calls and of the ones the
+// replica answered first.
+func (s *svc) collectHedging() []metrics.Sample {
+	return []metrics.Sample{
+		{Name: "gateway_hedged_calls_total", Help: "The storage provider reads the gateway sent to a replica as well", Kind: metrics.Counter, Value: float64(atomic.LoadUint64(&s.hedgedCalls))},
+		{Name: "gateway_hedge_wins_total", Help: "The hedged storage provider reads answered first by the replica", Kind: metrics.Counter, Value: float64(atomic.LoadUint64(&s.hedgeWins))},
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+	"google.golang.org/grpc"
+)
+
+// fakeProvider answers a Stat after its delay, or fails with err.
+type fakeProvider struct {
+	provider.ProviderAPIClient
+	name     string
+	delay    time.Duration
+	err      error
+	calls    int32
+	canceled chan struct{}
+}
+
+func newFakeProvider(name string, delay time.Duration, err error) *fakeProvider {
+	return &fakeProvider{name: name, delay: delay, err: err, canceled: make(chan struct{})}
+}
+
+func (p *fakeProvider) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	atomic.AddInt32(&p.calls, 1)
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		close(p.canceled)
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.StatResponse{Info: &provider.ResourceInfo{Path: p.name}}, nil
+}
+
+func newHedgeService(delay int, providers ...*fakeProvider) *svc {
+	clients := map[string]*fakeProvider{}
+	for _, p := range providers {
+		clients[p.name] = p
+	}
+	return &svc{
+		c: &config{HedgeDelay: delay},
+		providerClient: func(addr string) (provider.ProviderAPIClient, error) {
+			if c, ok := clients[addr]; ok {
+				return c, nil
+			}
+			return nil, errors.New("unknown provider " + addr)
+		},
+	}
+}
+
+func statCall(ctx context.Context, c provider.ProviderAPIClient) (interface{}, error) {
+	return c.Stat(ctx, &provider.StatRequest{})
+}
+
+func TestHedge(t *testing.T) {
+	p := &registry.ProviderInfo{
+		Address: "primary",
+		Opaque: &types.Opaque{Map: map[string]*types.OpaqueEntry{
+			storage.ReplicasOpaqueKey: {Decoder: "plain", Value: []byte("primary, replica")},
+		}},
+	}
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name             string
+		primary, replica *fakeProvider
+		winner           string
+		err              error
+		hedged, wins     uint64
+		primaryCanceled  bool
+	}{
+		{
+			name:    "primary answers within the hedge delay",
+			primary: newFakeProvider("primary", 0, nil),
+			replica: newFakeProvider("replica", 0, nil),
+			winner:  "primary",
+		},
+		{
+			name:            "slow primary loses to the replica",
+			primary:         newFakeProvider("primary", time.Minute, nil),
+			replica:         newFakeProvider("replica", 0, nil),
+			winner:          "replica",
+			hedged:          1,
+			wins:            1,
+			primaryCanceled: true,
+		},
+		{
+			name:    "failed primary is hedged at once",
+			primary: newFakeProvider("primary", 0, errFailed),
+			replica: newFakeProvider("replica", 0, nil),
+			winner:  "replica",
+			hedged:  1,
+			wins:    1,
+		},
+		{
+			name:    "the first error is returned when both fail",
+			primary: newFakeProvider("primary", 0, errFailed),
+			replica: newFakeProvider("replica", 0, errors.New("replica failed")),
+			err:     errFailed,
+			hedged:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay := 50
+			if tt.primaryCanceled {
+				delay = 10
+			} else if tt.primary.err != nil {
+				// a failure must not wait for the hedge delay
+				delay = 60000
+			}
+			s := newHedgeService(delay, tt.primary, tt.replica)
+
+			res, err := s.hedge(context.Background(), p, statCall)
+			if err != tt.err {
+				t.Fatalf("got error %v, expected %v", err, tt.err)
+			}
+			if tt.err == nil {
+				if got := res.(*provider.StatResponse).Info.Path; got != tt.winner {
+					t.Errorf("got the answer of %s, expected %s", got, tt.winner)
+				}
+			}
+			if s.hedgedCalls != tt.hedged || s.hedgeWins != tt.wins {
+				t.Errorf("got %d hedged calls and %d wins, expected %d and %d", s.hedgedCalls, s.hedgeWins, tt.hedged, tt.wins)
+			}
+			if tt.hedged == 0 && atomic.LoadInt32(&tt.replica.calls) != 0 {
+				t.Error("the replica was called although the primary answered in time")
+			}
+			if tt.primaryCanceled {
+				select {
+				case <-tt.primary.canceled:
+				case <-time.After(time.Second):
+					t.Error("the call to the losing primary was not canceled")
+				}
+			}
+		})
+	}
+}
+
+func TestHedgeDisabled(t *testing.T) {
+	errFailed := errors.New("failed")
+	withReplica := &types.Opaque{Map: map[string]*types.OpaqueEntry{
+		storage.ReplicasOpaqueKey: {Decoder: "plain", Value: []byte("replica")},
+	}}
+
+	for name, tt := range map[string]struct {
+		delay  int
+		opaque *types.Opaque
+	}{
+		"without a hedge delay": {delay: 0, opaque: withReplica},
+		"without replicas":      {delay: 10},
+	} {
+		t.Run(name, func(t *testing.T) {
+			primary, replica := newFakeProvider("primary", 0, errFailed), newFakeProvider("replica", 0, nil)
+			s := newHedgeService(tt.delay, primary, replica)
+			p := &registry.ProviderInfo{Address: "primary", Opaque: tt.opaque}
+
+			if _, err := s.hedge(context.Background(), p, statCall); err != errFailed {
+				t.Errorf("got error %v, expected the one of the primary", err)
+			}
+			if atomic.LoadInt32(&replica.calls) != 0 || s.hedgedCalls != 0 {
+				t.Error("the call was hedged")
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/pkg/errors"
+)
+
+// shareMount is an entry of the share folder of the user, with the target of
+// the reference if it is one to a cs3 resource.
+type shareMount struct {
+	name   string
+	target string
+}
+
+// resourceTarget returns the opaque part of the cs3 reference targets to the
+// resource, with layout <storage_id>/<opaque_id>.
+func resourceTarget(id *provider.ResourceId) string {
+	return id.GetStorageId() + "/" + id.GetOpaqueId()
+}
+
+// listShareMounts lists the entries of the share folder of the user, which
+// doesn't exist until the first share is accepted.
+func (s *svc) listShareMounts(ctx context.Context) ([]shareMount, error) {
+	lcr, err := s.listContainer(ctx, &provider.ListContainerRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{
+				Path: s.getSharedFolder(ctx),
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error listing shared folder")
+	}
+	switch lcr.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, nil
+	default:
+		return nil, status.NewErrorFromCode(lcr.Status.Code, "gateway")
+	}
+
+	mounts := make([]shareMount, 0, len(lcr.Infos))
+	for _, info := range lcr.Infos {
+		m := shareMount{name: path.Base(info.Path)}
+		if info.Type == provider.ResourceType_RESOURCE_TYPE_REFERENCE {
+			if u, err := url.Parse(info.Target); err == nil && u.Scheme == "cs3" {
+				m.target = u.Opaque
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
+
+// mountPoint returns the name the resource is mounted with, or an empty name
+// if it isn't mounted.
+func mountPoint(mounts []shareMount, id *provider.ResourceId) string {
+	target := resourceTarget(id)
+	for _, m := range mounts {
+		if m.target == target {
+			return m.name
+		}
+	}
+	return ""
+}
+
+// uniqueMountName returns the name if no entry of the share folder has it
+// already, otherwise the first free name numbered like "name (2).ext".
+func uniqueMountName(name string, mounts []shareMount) string {
+	taken := make(map[string]bool, len(mounts))
+	for _, m := range mounts {
+		taken[m.name] = true
+	}
+	if !taken[name] {
+		return name
+	}
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if base == "" {
+		// hidden files like .bashrc have no extension
+		base, ext = name, ""
+	}
+	for i := 2; ; i++ {
+		n := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if !taken[n] {
+			return n
+		}
+	}
+}
+
+// unmountShare removes the references to the resource of a declined share
+// from the share folder, unless the user still accepts another share of it.
+func (s *svc) unmountShare(ctx context.Context, declined *collaboration.Share) *rpc.Status {
+	c, err := pool.GetUserShareProviderClient(s.c.UserShareProviderEndpoint)
+	if err != nil {
+		return status.NewInternal(ctx, err, "error getting share provider client")
+	}
+	lrs, err := c.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	if err != nil {
+		return status.NewInternal(ctx, err, "gateway: error calling ListReceivedShares")
+	}
+	if lrs.Status.Code != rpc.Code_CODE_OK {
+		return lrs.Status
+	}
+	target := resourceTarget(declined.ResourceId)
+	for _, rs := range lrs.Shares {
+		if rs.State == collaboration.ShareState_SHARE_STATE_ACCEPTED && rs.Share.Id.GetOpaqueId() != declined.Id.GetOpaqueId() &&
+			resourceTarget(rs.Share.ResourceId) == target {
+			return status.NewOK(ctx)
+		}
+	}
+
+	mounts, err := s.listShareMounts(ctx)
+	if err != nil {
+		return status.NewInternal(ctx, err, "error listing share mounts")
+	}
+	for _, m := range mounts {
+		if m.target != target {
+			continue
+		}
+		res, err := s.delete(ctx, &provider.DeleteRequest{
+			Ref: &provider.Reference{
+				Spec: &provider.Reference_Path{
+					Path: path.Join(s.getSharedFolder(ctx), m.name),
+				},
+			},
+		})
+		if err != nil {
+			return status.NewInternal(ctx, err, "error removing share mount")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+			return res.Status
+		}
+	}
+	return status.NewOK(ctx)
+}
+
+// mountPointsOpaque adds the mount points of the accepted shares to the opaque
+// of a received shares listing.
+func (s *svc) mountPointsOpaque(ctx context.Context, res *collaboration.ListReceivedSharesResponse) {
+	log := appctx.GetLogger(ctx)
+	mounts, err := s.listShareMounts(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("gateway: error listing share mounts")
+		return
+	}
+	names := map[string]string{}
+	for _, rs := range res.Shares {
+		if rs.State != collaboration.ShareState_SHARE_STATE_ACCEPTED {
+			continue
+		}
+		if name := mountPoint(mounts, rs.Share.ResourceId); name != "" {
+			names[rs.Share.Id.GetOpaqueId()] = name
+		}
+	}
+	o, err := share.EncodeMountPoints(res.Opaque, names)
+	if err != nil {
+		log.Error().Err(err).Msg("gateway: error encoding share mount points")
+		return
+	}
+	res.Opaque = o
+}
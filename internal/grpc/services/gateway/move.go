@@ -0,0 +1,256 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// TransferIDOpaqueKey is the opaque key under which clients can pass the id a cross storage
+// move should be tracked with, and under which the gateway returns it.
+const TransferIDOpaqueKey = "transfer_id"
+
+// transferRetention is how long a finished move stays queryable via GetTransferStatus.
+const transferRetention = 10 * time.Minute
+
+// moveTransfer is a cross storage move tracked by the gateway.
+type moveTransfer struct {
+	progress *copyProgress
+	cancel   context.CancelFunc
+	status   int32 // datatx.TxInfo_Status
+}
+
+func (t *moveTransfer) setStatus(s datatx.TxInfo_Status) {
+	atomic.StoreInt32(&t.status, int32(s))
+}
+
+func (t *moveTransfer) info(id string) *datatx.TxInfo {
+	return &datatx.TxInfo{
+		Id:     &datatx.TxId{OpaqueId: id},
+		Status: datatx.TxInfo_Status(atomic.LoadInt32(&t.status)),
+	}
+}
+
+// progressOpaque returns the progress of the move, for the status responses.
+func (t *moveTransfer) progressOpaque() *types.Opaque {
+	opaque := t.progress.opaque()
+	opaque.Map["total"] = &types.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(strconv.FormatUint(t.progress.total, 10)),
+	}
+	return opaque
+}
+
+// moveTransfers keeps the cross storage moves running in this gateway.
+type moveTransfers struct {
+	sync.RWMutex
+	m map[string]*moveTransfer
+}
+
+func newMoveTransfers() *moveTransfers {
+	return &moveTransfers{m: map[string]*moveTransfer{}}
+}
+
+func (ts *moveTransfers) add(id string, t *moveTransfer) bool {
+	ts.Lock()
+	defer ts.Unlock()
+	if _, ok := ts.m[id]; ok {
+		return false
+	}
+	ts.m[id] = t
+	return true
+}
+
+func (ts *moveTransfers) get(id string) (*moveTransfer, bool) {
+	ts.RLock()
+	defer ts.RUnlock()
+	t, ok := ts.m[id]
+	return t, ok
+}
+
+// probe reports the cross storage moves by status.
+func (ts *moveTransfers) probe() ops.State {
+	ts.RLock()
+	defer ts.RUnlock()
+	byStatus := map[string]int{}
+	for _, t := range ts.m {
+		byStatus[datatx.TxInfo_Status(atomic.LoadInt32(&t.status)).String()]++
+	}
+	return ops.State{
+		Health:  ops.Healthy,
+		Details: map[string]interface{}{"status": byStatus},
+	}
+}
+
+// expire forgets the transfer after the retention period.
+func (ts *moveTransfers) expire(id string) {
+	time.AfterFunc(transferRetention, func() {
+		ts.Lock()
+		delete(ts.m, id)
+		ts.Unlock()
+	})
+}
+
+// crossMove moves a resource between two storage providers by streaming a copy to the
+// destination and deleting the source afterwards. If any step fails the copy is removed
+// again so that the source is left untouched.
+func (s *svc) crossMove(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	log := appctx.GetLogger(ctx)
+
+	if req.Destination.GetPath() == "" {
+		return &provider.MoveResponse{
+			Status: status.NewInvalidArg(ctx, "cross storage move destination must be a path"),
+		}, nil
+	}
+
+	statRes, err := s.stat(ctx, &provider.StatRequest{Ref: req.Source})
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error stating ref:"+req.Source.String()),
+		}, nil
+	}
+	if statRes.Status.Code != rpc.Code_CODE_OK {
+		return &provider.MoveResponse{
+			Status: statRes.Status,
+		}, nil
+	}
+
+	dstRes, err := s.stat(ctx, &provider.StatRequest{Ref: req.Destination})
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error stating ref:"+req.Destination.String()),
+		}, nil
+	}
+	switch dstRes.Status.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+	case rpc.Code_CODE_OK:
+		return &provider.MoveResponse{
+			Status: status.NewAlreadyExists(ctx, nil, "gateway: move destination already exists: "+req.Destination.GetPath()),
+		}, nil
+	default:
+		return &provider.MoveResponse{
+			Status: dstRes.Status,
+		}, nil
+	}
+
+	id := uuid.New().String()
+	if e, ok := req.GetOpaque().GetMap()[TransferIDOpaqueKey]; ok && len(e.Value) > 0 {
+		id = string(e.Value)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sublog := log.With().Str("transfer", id).Str("src", statRes.Info.Path).Str("dst", req.Destination.GetPath()).Logger()
+	t := &moveTransfer{
+		progress: &copyProgress{
+			log:   &sublog,
+			total: statRes.Info.Size,
+		},
+		cancel: cancel,
+	}
+	t.setStatus(datatx.TxInfo_STATUS_TRANSFER_IN_PROGRESS)
+	if !s.transfers.add(id, t) {
+		return &provider.MoveResponse{
+			Status: status.NewAlreadyExists(ctx, nil, "gateway: transfer already exists: "+id),
+		}, nil
+	}
+	defer s.transfers.expire(id)
+
+	dstRef := &provider.Reference{
+		Spec: &provider.Reference_Path{Path: req.Destination.GetPath()},
+	}
+
+	if err := s.streamCopy(ctx, statRes.Info, req.Destination.GetPath(), t.progress); err != nil {
+		s.rollbackMove(parent, ctx, t, dstRef, &sublog)
+		return &provider.MoveResponse{
+			Status: status.NewStatusFromErrType(ctx, "gateway: error copying "+statRes.Info.Path, err),
+			Opaque: t.opaque(id),
+		}, nil
+	}
+
+	delRes, err := s.delete(ctx, &provider.DeleteRequest{Ref: req.Source})
+	if err != nil || delRes.Status.Code != rpc.Code_CODE_OK {
+		s.rollbackMove(parent, ctx, t, dstRef, &sublog)
+		if err != nil {
+			return &provider.MoveResponse{
+				Status: status.NewInternal(ctx, err, "gateway: error deleting move source "+statRes.Info.Path),
+				Opaque: t.opaque(id),
+			}, nil
+		}
+		return &provider.MoveResponse{
+			Status: delRes.Status,
+			Opaque: t.opaque(id),
+		}, nil
+	}
+
+	t.setStatus(datatx.TxInfo_STATUS_TRANSFER_COMPLETE)
+	sublog.Info().Uint64("bytes", t.progress.bytes).Uint64("files", t.progress.files).Msg("gateway: cross storage move finished")
+
+	return &provider.MoveResponse{
+		Status: status.NewOK(ctx),
+		Opaque: t.opaque(id),
+	}, nil
+}
+
+// rollbackMove removes whatever was already copied to the destination of a failed move.
+// The partial copy is cleaned up even if the move was canceled.
+func (s *svc) rollbackMove(parent, ctx context.Context, t *moveTransfer, dst *provider.Reference, log *zerolog.Logger) {
+	if ctx.Err() != nil {
+		t.setStatus(datatx.TxInfo_STATUS_TRANSFER_CANCELLED)
+	} else {
+		t.setStatus(datatx.TxInfo_STATUS_TRANSFER_FAILED)
+	}
+	if parent.Err() != nil {
+		parent = appctx.DetachContext(parent)
+	}
+	res, err := s.delete(parent, &provider.DeleteRequest{Ref: dst})
+	switch {
+	case err != nil:
+		log.Error().Err(err).Msg("gateway: error rolling back cross storage move")
+	case res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND:
+		log.Error().Interface("status", res.Status).Msg("gateway: error rolling back cross storage move")
+	default:
+		log.Info().Msg("gateway: rolled back cross storage move")
+	}
+}
+
+func (t *moveTransfer) opaque(id string) *types.Opaque {
+	opaque := t.progress.opaque()
+	opaque.Map[TransferIDOpaqueKey] = &types.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(id),
+	}
+	return opaque
+}
@@ -0,0 +1,152 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"sync/atomic"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/group"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	tokenpkg "github.com/cs3org/reva/pkg/token"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// resolveNestedGroups adds to the groups of the user the groups they are
+// members of through other groups, so that the shares with the outer groups
+// apply to them. Groups whose parents can't be resolved are kept as they are.
+func (s *svc) resolveNestedGroups(ctx context.Context, u *userpb.User) {
+	log := appctx.GetLogger(ctx)
+	ctx, err := s.asGroupResolver(ctx, u)
+	if err != nil {
+		log.Error().Err(err).Str("user", u.Username).Msg("gateway: error authenticating nested group resolution")
+		return
+	}
+
+	seen := make(map[string]bool, len(u.Groups))
+	for _, g := range u.Groups {
+		seen[g] = true
+	}
+	queue := append([]string{}, u.Groups...)
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+		parents, err := s.parentGroups(ctx, g)
+		if err != nil {
+			log.Warn().Err(err).Str("group", g).Msg("gateway: error resolving parent groups")
+			continue
+		}
+		for _, p := range parents {
+			// groups can be members of each other, visit them once
+			if !seen[p] {
+				seen[p] = true
+				u.Groups = append(u.Groups, p)
+				queue = append(queue, p)
+			}
+		}
+	}
+}
+
+// parentGroups returns the cached names of the groups the group is a direct
+// member of, looking them up in the group provider on a miss.
+func (s *svc) parentGroups(ctx context.Context, name string) ([]string, error) {
+	if v, err := s.groupCache.Get(name); err == nil {
+		atomic.AddUint64(&s.groupHits, 1)
+		return v.([]string), nil
+	}
+	atomic.AddUint64(&s.groupMisses, 1)
+
+	c, err := pool.GetGroupProviderServiceClient(s.c.GroupProviderEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error getting group provider client")
+	}
+	res, err := c.GetGroupByClaim(ctx, &grouppb.GetGroupByClaimRequest{
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				group.ParentGroupsOpaqueKey: {Decoder: "plain", Value: []byte("true")},
+			},
+		},
+		Claim: "group_name",
+		Value: name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling GetGroupByClaim")
+	}
+
+	var parents []string
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		if parents, _, err = group.DecodeParentGroups(res.Group.GetOpaque()); err != nil {
+			return nil, err
+		}
+	case rpc.Code_CODE_NOT_FOUND:
+		// groups unknown to the provider have no parents
+	default:
+		return nil, status.NewErrorFromCode(res.Status.Code, "gateway")
+	}
+	if parents == nil {
+		parents = []string{}
+	}
+	if err := s.groupCache.Set(name, parents); err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Str("group", name).Msg("gateway: error caching parent groups")
+	}
+	return parents, nil
+}
+
+// asGroupResolver returns a context authenticated as the user logging in,
+// who has no token yet, to look up the groups and the user with.
+func (s *svc) asGroupResolver(ctx context.Context, u *userpb.User) (context.Context, error) {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return nil, err
+	}
+	tkn, err := s.tokenmgr.MintToken(tokenpkg.ContextSetService(ctx, "gateway"), u, ownerScope)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tokenpkg.ContextSetToken(ctx, tkn)
+	return metadata.AppendToOutgoingContext(ctx, tokenpkg.TokenHeader, tkn), nil
+}
+
+// groupCacheProbe reports the hit rate of the parent groups cache.
+func (s *svc) groupCacheProbe() ops.State {
+	hits, misses := atomic.LoadUint64(&s.groupHits), atomic.LoadUint64(&s.groupMisses)
+	rate := 0.0
+	if hits+misses > 0 {
+		rate = float64(hits) / float64(hits+misses)
+	}
+	return ops.State{
+		Health: ops.Healthy,
+		Details: map[string]interface{}{
+			"groups":   s.groupCache.Count(),
+			"hits":     hits,
+			"misses":   misses,
+			"hit_rate": rate,
+		},
+	}
+}
@@ -26,15 +26,31 @@ import (
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	datatxpkg "github.com/cs3org/reva/pkg/datatx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	tokenpkg "github.com/cs3org/reva/pkg/token"
 	"github.com/pkg/errors"
 )
 
 // TODO(labkode): add multi-phase commit logic when commit share or commit ref is enabled.
 func (s *svc) CreateOCMShare(ctx context.Context, req *ocm.CreateOCMShareRequest) (*ocm.CreateOCMShareResponse, error) {
+	sp, st := s.getSharePolicyByID(ctx, req.ResourceId)
+	if st.Code != rpc.Code_CODE_OK {
+		return &ocm.CreateOCMShareResponse{
+			Status: st,
+		}, nil
+	}
+	if err := sp.CheckExternal(); err != nil {
+		return &ocm.CreateOCMShareResponse{
+			Status: status.NewPermissionDenied(ctx, err, err.Error()),
+		}, nil
+	}
+
 	c, err := pool.GetOCMShareProviderClient(s.c.OCMShareProviderEndpoint)
 	if err != nil {
 		return &ocm.CreateOCMShareResponse{
@@ -80,7 +96,8 @@ func (s *svc) RemoveOCMShare(ctx context.Context, req *ocm.RemoveOCMShareRequest
 	var share *ocm.Share
 	if s.c.CommitShareToStorageGrant {
 		getShareReq := &ocm.GetOCMShareRequest{
-			Ref: req.Ref,
+			Opaque: req.Opaque,
+			Ref:    req.Ref,
 		}
 		getShareRes, err := c.GetOCMShare(ctx, getShareReq)
 		if err != nil {
@@ -260,6 +277,10 @@ func (s *svc) UpdateReceivedOCMShare(ctx context.Context, req *ocm.UpdateReceive
 				panic("gateway: error updating a received share: the share is nil")
 			}
 
+			if share.Share.ShareType == ocm.Share_SHARE_TYPE_TRANSFER {
+				return s.startOCMTransfer(ctx, share.Share), nil
+			}
+
 			createRefStatus, err := s.createOCMReference(ctx, share.Share)
 			return &ocm.UpdateReceivedOCMShareResponse{
 				Status: createRefStatus,
@@ -314,34 +335,13 @@ func (s *svc) createOCMReference(ctx context.Context, share *ocm.Share) (*rpc.St
 		return status.NewInternal(ctx, err, "error updating received share"), nil
 	}
 
-	var refPath, targetURI string
-	if share.ShareType == ocm.Share_SHARE_TYPE_TRANSFER {
-		createTransferDir, err := s.CreateContainer(ctx, &provider.CreateContainerRequest{
-			Ref: &provider.Reference{
-				Spec: &provider.Reference_Path{
-					Path: path.Join(homeRes.Path, s.c.DataTransfersFolder),
-				},
-			},
-		})
-		if err != nil {
-			return status.NewInternal(ctx, err, "error creating transfers directory"), nil
-		}
-		if createTransferDir.Status.Code != rpc.Code_CODE_OK && createTransferDir.Status.Code != rpc.Code_CODE_ALREADY_EXISTS {
-			err := status.NewErrorFromCode(createTransferDir.Status.GetCode(), "gateway")
-			return status.NewInternal(ctx, err, "error creating transfers directory"), nil
-		}
-
-		refPath = path.Join(homeRes.Path, s.c.DataTransfersFolder, path.Base(share.Name))
-		targetURI = fmt.Sprintf("datatx://%s@%s?name=%s", token, share.Creator.Idp, share.Name)
-	} else {
-		// reference path is the home path + some name on the corresponding
-		// mesh provider (/home/MyShares/x)
-		// It is the responsibility of the gateway to resolve these references and merge the response back
-		// from the main request.
-		refPath = path.Join(homeRes.Path, s.c.ShareFolder, path.Base(share.Name))
-		// webdav is the scheme, token@host the opaque part and the share name the query of the URL.
-		targetURI = fmt.Sprintf("webdav://%s@%s?name=%s", token, share.Creator.Idp, share.Name)
-	}
+	// reference path is the home path + some name on the corresponding
+	// mesh provider (/home/MyShares/x)
+	// It is the responsibility of the gateway to resolve these references and merge the response back
+	// from the main request.
+	refPath := path.Join(homeRes.Path, s.c.ShareFolder, path.Base(share.Name))
+	// webdav is the scheme, token@host the opaque part and the share name the query of the URL.
+	targetURI := fmt.Sprintf("webdav://%s@%s?name=%s", token, share.Creator.Idp, share.Name)
 
 	log.Info().Msg("mount path will be:" + refPath)
 	createRefReq := &provider.CreateReferenceRequest{
@@ -372,3 +372,110 @@ func (s *svc) createOCMReference(ctx context.Context, share *ocm.Share) (*rpc.St
 
 	return status.NewOK(ctx), nil
 }
+
+// startOCMTransfer pulls the data of an accepted transfer share into the data transfers
+// folder of the user. The id of the transfer is returned in the opaque of the response
+// under TransferIDOpaqueKey.
+func (s *svc) startOCMTransfer(ctx context.Context, share *ocm.Share) *ocm.UpdateReceivedOCMShareResponse {
+	log := appctx.GetLogger(ctx)
+
+	tokenOpaque, ok := share.Grantee.Opaque.GetMap()["token"]
+	if !ok {
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewNotFound(ctx, "token not found"),
+		}
+	}
+	if tokenOpaque.Decoder != "plain" {
+		err := errtypes.NotSupported("opaque entry decoder not recognized: " + tokenOpaque.Decoder)
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewInternal(ctx, err, "invalid opaque entry decoder"),
+		}
+	}
+
+	// the destination is written with the token of the user accepting the share
+	userToken, ok := tokenpkg.ContextGetToken(ctx)
+	if !ok {
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewUnauthenticated(ctx, errtypes.InvalidCredentials("token missing"), "token not found in context"),
+		}
+	}
+
+	srcEndpoint, err := s.getWebdavEndpoint(ctx, share.Creator.Idp)
+	if err != nil {
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error getting the webdav endpoint of "+share.Creator.Idp, err),
+		}
+	}
+
+	homeRes, err := s.GetHome(ctx, &provider.GetHomeRequest{})
+	if err != nil {
+		err := errors.Wrap(err, "gateway: error calling GetHome")
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewInternal(ctx, err, "error updating received share"),
+		}
+	}
+
+	createTransferDir, err := s.CreateContainer(ctx, &provider.CreateContainerRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{
+				Path: path.Join(homeRes.Path, s.c.DataTransfersFolder),
+			},
+		},
+	})
+	if err != nil {
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewInternal(ctx, err, "error creating transfers directory"),
+		}
+	}
+	if createTransferDir.Status.Code != rpc.Code_CODE_OK && createTransferDir.Status.Code != rpc.Code_CODE_ALREADY_EXISTS {
+		err := status.NewErrorFromCode(createTransferDir.Status.GetCode(), "gateway")
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewInternal(ctx, err, "error creating transfers directory"),
+		}
+	}
+
+	// the destination path is relative to the webdav endpoint of this site, which is rooted at the home
+	opaque, err := datatxpkg.EncodeRequest(&datatxpkg.Request{
+		Src: datatxpkg.Endpoint{
+			URL:   srcEndpoint,
+			Path:  share.Name,
+			Token: string(tokenOpaque.Value),
+		},
+		Dest: datatxpkg.Endpoint{
+			Path:  path.Join(s.c.DataTransfersFolder, path.Base(share.Name)),
+			Token: userToken,
+		},
+		ShareID: share.Id.GetOpaqueId(),
+	})
+	if err != nil {
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewInternal(ctx, err, "error encoding transfer"),
+		}
+	}
+
+	res, err := s.CreateTransfer(ctx, &datatx.CreateTransferRequest{Opaque: opaque})
+	if err != nil {
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: status.NewInternal(ctx, err, "error creating transfer"),
+		}
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return &ocm.UpdateReceivedOCMShareResponse{
+			Status: res.Status,
+		}
+	}
+
+	id := res.TxInfo.GetId().GetOpaqueId()
+	log.Info().Str("transfer", id).Str("share", share.Id.GetOpaqueId()).Msg("gateway: started ocm transfer")
+	return &ocm.UpdateReceivedOCMShareResponse{
+		Status: status.NewOK(ctx),
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				TransferIDOpaqueKey: {
+					Decoder: "plain",
+					Value:   []byte(id),
+				},
+			},
+		},
+	}
+}
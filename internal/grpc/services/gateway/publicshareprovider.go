@@ -20,11 +20,16 @@ package gateway
 
 import (
 	"context"
+	"time"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/pkg/errors"
 )
@@ -37,6 +42,21 @@ func (s *svc) CreatePublicShare(ctx context.Context, req *link.CreatePublicShare
 	log := appctx.GetLogger(ctx)
 	log.Info().Msg("create public share")
 
+	sp, err := s.getSharePolicy(ctx, req.ResourceInfo.GetPath())
+	if err != nil {
+		return &link.CreatePublicShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error getting share policy", err),
+		}, nil
+	}
+	if req.Grant == nil {
+		req.Grant = &link.Grant{}
+	}
+	if err := sp.ApplyToLink(req.Grant, time.Now()); err != nil {
+		return &link.CreatePublicShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "share policy", err),
+		}, nil
+	}
+
 	c, err := pool.GetPublicShareProviderClient(s.c.PublicShareProviderEndpoint)
 	if err != nil {
 		return nil, err
@@ -47,9 +67,31 @@ func (s *svc) CreatePublicShare(ctx context.Context, req *link.CreatePublicShare
 		return nil, err
 	}
 
+	if res.Status.Code == rpc.Code_CODE_OK && res.Share != nil {
+		s.events.Emit(ctx, newPublicShareCreated(res.Share, res.Opaque))
+	}
 	return res, nil
 }
 
+// newPublicShareCreated builds the event for the share, o is the opaque of the
+// response of the share provider, carrying the upload options of the share.
+func newPublicShareCreated(share *link.PublicShare, o *types.Opaque) *events.PublicShareCreated {
+	e := &events.PublicShareCreated{
+		ShareID:     share.Id,
+		Token:       share.Token,
+		ItemID:      share.ResourceId,
+		Owner:       share.Owner,
+		Creator:     share.Creator,
+		Permissions: share.Permissions,
+		Expiration:  share.Expiration,
+		DisplayName: share.DisplayName,
+	}
+	if opts, _ := publicshare.DecodeUploadOptions(o); opts != nil {
+		e.NotifyUploads = &opts.NotifyUploads
+	}
+	return e
+}
+
 func (s *svc) RemovePublicShare(ctx context.Context, req *link.RemovePublicShareRequest) (*link.RemovePublicShareResponse, error) {
 	log := appctx.GetLogger(ctx)
 	log.Info().Msg("remove public share")
@@ -62,6 +104,12 @@ func (s *svc) RemovePublicShare(ctx context.Context, req *link.RemovePublicShare
 	if err != nil {
 		return nil, err
 	}
+	if res.Status.Code == rpc.Code_CODE_OK {
+		s.events.Emit(ctx, &events.PublicShareRemoved{
+			ShareID: req.GetRef().GetId(),
+			Token:   req.GetRef().GetToken(),
+		})
+	}
 	return res, nil
 }
 
@@ -139,5 +187,8 @@ func (s *svc) UpdatePublicShare(ctx context.Context, req *link.UpdatePublicShare
 	if err != nil {
 		return nil, errors.Wrap(err, "error updating share")
 	}
+	if res.Status.Code == rpc.Code_CODE_OK && res.Share != nil {
+		s.events.Emit(ctx, (*events.PublicShareUpdated)(newPublicShareCreated(res.Share, res.Opaque)))
+	}
 	return res, nil
 }
@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/pkg/errors"
+)
+
+type receivedSharesKey struct{}
+
+// withReceivedShares returns a context holding the resources the user receives
+// shares of, so that checking many references lists the shares only once.
+func (s *svc) withReceivedShares(ctx context.Context) (context.Context, error) {
+	if s.c.DisableShareExpirationCheck {
+		return ctx, nil
+	}
+	ids, err := s.receivedShareTargets(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, receivedSharesKey{}, ids), nil
+}
+
+// checkReceivedShare checks that the target of a cs3 reference, with layout
+// <storage_id>/<opaque_id>, is a resource the user still receives a share of.
+// The share providers don't return expired shares to their grantees, so the
+// references left behind by expired shares don't resolve anymore.
+func (s *svc) checkReceivedShare(ctx context.Context, target string) error {
+	if s.c.DisableShareExpirationCheck {
+		return nil
+	}
+	ids, ok := ctx.Value(receivedSharesKey{}).(map[string]struct{})
+	if !ok {
+		var err error
+		if ids, err = s.receivedShareTargets(ctx); err != nil {
+			return err
+		}
+	}
+	if _, ok := ids[target]; !ok {
+		return errtypes.NotFound("gateway: no valid share for reference target " + target)
+	}
+	return nil
+}
+
+func (s *svc) receivedShareTargets(ctx context.Context) (map[string]struct{}, error) {
+	c, err := pool.GetUserShareProviderClient(s.c.UserShareProviderEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error getting user share provider client")
+	}
+	res, err := c.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling ListReceivedShares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "gateway")
+	}
+	ids := make(map[string]struct{}, len(res.Shares))
+	for _, rs := range withoutDeniedShares(res.Shares) {
+		ids[resourceTarget(rs.GetShare().GetResourceId())] = struct{}{}
+	}
+	return ids, nil
+}
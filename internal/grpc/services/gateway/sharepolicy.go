@@ -0,0 +1,69 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"path"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/share/policy"
+)
+
+// getSharePolicy collects the share policies configured on the resource and
+// all its parents. Parents the user can't stat are skipped.
+func (s *svc) getSharePolicy(ctx context.Context, p string) (*policy.Policy, error) {
+	sp := policy.Default()
+	for p = path.Clean(p); p != "/" && p != "."; p = path.Dir(p) {
+		res, err := s.stat(ctx, &provider.StatRequest{
+			Ref:                   &provider.Reference{Spec: &provider.Reference_Path{Path: p}},
+			ArbitraryMetadataKeys: policy.Keys,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			continue
+		}
+		if err := sp.Merge(res.Info.GetArbitraryMetadata().GetMetadata()); err != nil {
+			return nil, err
+		}
+	}
+	return sp, nil
+}
+
+// getSharePolicyByID resolves the path of the resource before collecting its share policy.
+func (s *svc) getSharePolicyByID(ctx context.Context, id *provider.ResourceId) (*policy.Policy, *rpc.Status) {
+	res, err := s.stat(ctx, &provider.StatRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: id}},
+	})
+	if err != nil {
+		return nil, status.NewInternal(ctx, err, "gateway: error stating shared resource")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, res.Status
+	}
+	sp, err := s.getSharePolicy(ctx, res.Info.Path)
+	if err != nil {
+		return nil, status.NewStatusFromErrType(ctx, "error getting share policy", err)
+	}
+	return sp, status.NewOK(ctx)
+}
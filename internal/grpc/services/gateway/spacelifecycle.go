@@ -0,0 +1,409 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	tokenpkg "github.com/cs3org/reva/pkg/token"
+	userpkg "github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// disabledSpace is a deleted space kept until the end of its grace period.
+type disabledSpace struct {
+	// Space is the JSON encoded storage space.
+	Space json.RawMessage `json:"space"`
+	// Alias is the path the space is reached at.
+	Alias     string       `json:"alias"`
+	Disabled  time.Time    `json:"disabled"`
+	Executant *userpb.User `json:"executant"`
+	space     *provider.StorageSpace
+}
+
+// disabledSpaces holds the disabled spaces by id, kept in a file so that they
+// survive the restarts of the gateway.
+type disabledSpaces struct {
+	file string
+
+	mu     sync.Mutex
+	Spaces map[string]*disabledSpace `json:"spaces"`
+}
+
+func loadDisabledSpaces(file string) (*disabledSpaces, error) {
+	d := &disabledSpaces{file: file, Spaces: map[string]*disabledSpace{}}
+	b, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		return d, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "gateway: error reading disabled spaces")
+	}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, errors.Wrap(err, "gateway: error decoding disabled spaces")
+	}
+	if d.Spaces == nil {
+		d.Spaces = map[string]*disabledSpace{}
+	}
+	for id, ds := range d.Spaces {
+		ds.space = &provider.StorageSpace{}
+		if err := utils.UnmarshalJSONToProtoV1(ds.Space, ds.space); err != nil {
+			return nil, errors.Wrap(err, "gateway: error decoding disabled space "+id)
+		}
+	}
+	return d, nil
+}
+
+// save writes the disabled spaces, the caller holding the lock.
+func (d *disabledSpaces) save() error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error encoding disabled spaces")
+	}
+	if err := os.MkdirAll(filepath.Dir(d.file), 0700); err != nil {
+		return errors.Wrap(err, "gateway: error writing disabled spaces")
+	}
+	tmp := d.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "gateway: error writing disabled spaces")
+	}
+	return errors.Wrap(os.Rename(tmp, d.file), "gateway: error writing disabled spaces")
+}
+
+// get returns the disabled space with the given id, nil if the space isn't
+// disabled or the spaces are deleted without a grace period.
+func (d *disabledSpaces) get(id *provider.StorageSpaceId) *disabledSpace {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.Spaces[id.GetOpaqueId()]
+}
+
+func (d *disabledSpaces) add(space *provider.StorageSpace, alias string, executant *userpb.User, now time.Time) error {
+	b, err := utils.MarshalProtoV1ToJSON(space)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error encoding storage space")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Spaces[space.Id.OpaqueId] = &disabledSpace{
+		Space:     b,
+		Alias:     alias,
+		Disabled:  now,
+		Executant: executant,
+		space:     space,
+	}
+	return d.save()
+}
+
+// remove removes the space, reporting whether it was disabled.
+func (d *disabledSpaces) remove(id *provider.StorageSpaceId) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.Spaces[id.OpaqueId]; !ok {
+		return false, nil
+	}
+	delete(d.Spaces, id.OpaqueId)
+	return true, d.save()
+}
+
+// list returns the disabled spaces, those disabled before the given time when it isn't zero.
+func (d *disabledSpaces) list(before time.Time) []*disabledSpace {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	spaces := make([]*disabledSpace, 0, len(d.Spaces))
+	for _, ds := range d.Spaces {
+		if before.IsZero() || ds.Disabled.Before(before) {
+			spaces = append(spaces, ds)
+		}
+	}
+	return spaces
+}
+
+func (d *disabledSpaces) empty() bool {
+	if d == nil {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.Spaces) == 0
+}
+
+// disabledAt returns the disabled space the path is in, nil if there is none.
+func (d *disabledSpaces) disabledAt(p string) *disabledSpace {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ds := range d.Spaces {
+		if ds.Alias != "" && (p == ds.Alias || strings.HasPrefix(p, strings.TrimSuffix(ds.Alias, "/")+"/")) {
+			return ds
+		}
+	}
+	return nil
+}
+
+func (s *svc) gracePeriod() time.Duration {
+	return time.Duration(s.c.SpaceGracePeriod) * 24 * time.Hour
+}
+
+func (s *svc) purgeTime(ds *disabledSpace) *types.Timestamp {
+	return &types.Timestamp{Seconds: uint64(ds.Disabled.Add(s.gracePeriod()).Unix())}
+}
+
+// canManageSpace tells whether the permissions on the root of a space are the ones of its managers.
+func canManageSpace(p *provider.ResourcePermissions) bool {
+	return p.GetDelete() && p.GetAddGrant() && p.GetRemoveGrant()
+}
+
+// disableStorageSpace disables the space instead of deleting it: the space is
+// hidden from the catalog and can't be written to until it is restored or
+// purged at the end of the grace period.
+func (s *svc) disableStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) (*provider.DeleteStorageSpaceResponse, error) {
+	if req.Id.GetOpaqueId() == "" {
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewInvalidArg(ctx, "missing space id"),
+		}, nil
+	}
+	if s.disabledSpaces.get(req.Id) != nil {
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewNotFound(ctx, "space not found"),
+		}, nil
+	}
+	u, ok := userpkg.ContextGetUser(ctx)
+	if !ok {
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewUnauthenticated(ctx, errtypes.UserRequired("gateway: missing user"), "missing user"),
+		}, nil
+	}
+
+	entries, st := s.listCatalog(ctx, nil)
+	if st != nil {
+		return &provider.DeleteStorageSpaceResponse{Status: st}, nil
+	}
+	var entry *catalogEntry
+	for i := range entries {
+		if entries[i].space.Id.GetOpaqueId() == req.Id.OpaqueId {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewNotFound(ctx, "space not found"),
+		}, nil
+	}
+	if entry.space.Root.GetOpaqueId() != "" {
+		if st := s.checkSpacePermission(ctx, entry.space, canManageSpace); st != nil {
+			return &provider.DeleteStorageSpaceResponse{Status: st}, nil
+		}
+	}
+
+	if err := s.disabledSpaces.add(entry.space, entry.alias, u, time.Now()); err != nil {
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewInternal(ctx, err, "error disabling storage space"),
+		}, nil
+	}
+	ds := s.disabledSpaces.get(req.Id)
+	s.events.Emit(ctx, &events.SpaceDisabled{
+		ID:        entry.space.Id,
+		Executant: u.Id,
+		Name:      entry.space.Name,
+		SpaceType: entry.space.SpaceType,
+		Purge:     s.purgeTime(ds),
+	})
+	return &provider.DeleteStorageSpaceResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+func (s *svc) ListDisabledStorageSpaces(ctx context.Context, req *spacespb.ListDisabledStorageSpacesRequest) (*spacespb.ListDisabledStorageSpacesResponse, error) {
+	spaces := []*spacespb.DisabledStorageSpace{}
+	for _, ds := range s.disabledSpaces.list(time.Time{}) {
+		if ds.space.Root.GetOpaqueId() == "" || s.checkSpacePermission(ctx, ds.space, canManageSpace) != nil {
+			continue
+		}
+		spaces = append(spaces, &spacespb.DisabledStorageSpace{
+			StorageSpace: ds.space,
+			Disabled:     &types.Timestamp{Seconds: uint64(ds.Disabled.Unix())},
+			Purge:        s.purgeTime(ds),
+		})
+	}
+	return &spacespb.ListDisabledStorageSpacesResponse{
+		Status:        status.NewOK(ctx),
+		StorageSpaces: spaces,
+	}, nil
+}
+
+func (s *svc) RestoreStorageSpace(ctx context.Context, req *spacespb.RestoreStorageSpaceRequest) (*spacespb.RestoreStorageSpaceResponse, error) {
+	ds := s.disabledSpaces.get(req.Id)
+	if ds == nil {
+		return &spacespb.RestoreStorageSpaceResponse{
+			Status: status.NewNotFound(ctx, "disabled space not found"),
+		}, nil
+	}
+	if ds.space.Root.GetOpaqueId() == "" {
+		return &spacespb.RestoreStorageSpaceResponse{
+			Status: status.NewInvalidArg(ctx, "the space has no members"),
+		}, nil
+	}
+	if st := s.checkSpacePermission(ctx, ds.space, canManageSpace); st != nil {
+		return &spacespb.RestoreStorageSpaceResponse{Status: st}, nil
+	}
+
+	ok, err := s.disabledSpaces.remove(req.Id)
+	if err != nil {
+		return &spacespb.RestoreStorageSpaceResponse{
+			Status: status.NewInternal(ctx, err, "error restoring storage space"),
+		}, nil
+	}
+	if !ok {
+		// purged meanwhile
+		return &spacespb.RestoreStorageSpaceResponse{
+			Status: status.NewNotFound(ctx, "disabled space not found"),
+		}, nil
+	}
+	s.events.Emit(ctx, &events.SpaceRestored{
+		ID:        ds.space.Id,
+		Executant: executant(ctx),
+	})
+	return &spacespb.RestoreStorageSpaceResponse{
+		Status:       status.NewOK(ctx),
+		StorageSpace: ds.space,
+	}, nil
+}
+
+// purgeDisabledSpaces removes for good the spaces disabled for longer than
+// the grace period, every interval until ctx is done.
+func (s *svc) purgeDisabledSpaces(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.purgeExpiredSpaces(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *svc) purgeExpiredSpaces(ctx context.Context, now time.Time) {
+	log := appctx.GetLogger(ctx)
+	for _, ds := range s.disabledSpaces.list(now.Add(-s.gracePeriod())) {
+		if err := s.purgeSpace(ctx, ds); err != nil {
+			log.Error().Err(err).Str("space", ds.space.Id.GetOpaqueId()).Msg("gateway: error purging disabled storage space")
+		}
+	}
+}
+
+// purgeSpace deletes the disabled space from its storage provider on behalf
+// of the user who deleted it.
+func (s *svc) purgeSpace(ctx context.Context, ds *disabledSpace) error {
+	ctx, err := s.asExecutant(ctx, ds.Executant)
+	if err != nil {
+		return err
+	}
+	res, err := s.deleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{Id: ds.space.Id})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		return status.NewErrorFromCode(res.Status.Code, "gateway")
+	}
+	if _, err := s.disabledSpaces.remove(ds.space.Id); err != nil {
+		return err
+	}
+	appctx.GetLogger(ctx).Info().Str("space", ds.space.Id.GetOpaqueId()).Str("name", ds.space.Name).Msg("gateway: disabled storage space purged")
+	s.events.Emit(ctx, &events.SpaceDeleted{
+		ID:        ds.space.Id,
+		Executant: ds.Executant.GetId(),
+	})
+	return nil
+}
+
+// asExecutant returns a context authenticated as the given user.
+func (s *svc) asExecutant(ctx context.Context, u *userpb.User) (context.Context, error) {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return nil, err
+	}
+	tkn, err := s.tokenmgr.MintToken(tokenpkg.ContextSetService(ctx, "gateway"), u, ownerScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error minting token")
+	}
+	ctx = tokenpkg.ContextSetToken(ctx, tkn)
+	ctx = userpkg.ContextSetUser(ctx, u)
+	return metadata.AppendToOutgoingContext(ctx, tokenpkg.TokenHeader, tkn), nil
+}
+
+// checkWritable refuses the writes to the path when it is in a disabled space.
+func (s *svc) checkWritable(ctx context.Context, p string) *rpc.Status {
+	if ds := s.disabledSpaces.disabledAt(p); ds != nil {
+		err := errtypes.PermissionDenied("gateway: space " + ds.space.Id.GetOpaqueId() + " is disabled")
+		return status.NewPermissionDenied(ctx, err, "the space is disabled")
+	}
+	return nil
+}
+
+// checkRefWritable is checkWritable for a reference, which is only resolved
+// to a path when some spaces are disabled.
+func (s *svc) checkRefWritable(ctx context.Context, ref *provider.Reference) *rpc.Status {
+	if s.disabledSpaces.empty() {
+		return nil
+	}
+	p, st := s.getPath(ctx, ref)
+	if st.Code != rpc.Code_CODE_OK {
+		// left to the storage provider to report
+		return nil
+	}
+	return s.checkWritable(ctx, p)
+}
+
+func executant(ctx context.Context) *userpb.UserId {
+	if u, ok := userpkg.ContextGetUser(ctx); ok {
+		return u.Id
+	}
+	return nil
+}
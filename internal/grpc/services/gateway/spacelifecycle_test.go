@@ -0,0 +1,92 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestDisabledSpaces(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "disabled.json")
+	d, err := loadDisabledSpaces(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.empty() {
+		t.Fatal("expected no disabled spaces")
+	}
+
+	now := time.Now()
+	u := &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}}
+	for id, age := range map[string]time.Duration{"old": 48 * time.Hour, "new": time.Hour} {
+		space := &provider.StorageSpace{
+			Id:   &provider.StorageSpaceId{OpaqueId: id},
+			Root: &provider.ResourceId{StorageId: "storage", OpaqueId: id},
+			Name: id,
+		}
+		if err := d.add(space, "/projects/"+id, u, now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the spaces survive a restart
+	d, err = loadDisabledSpaces(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := d.get(&provider.StorageSpaceId{OpaqueId: "old"})
+	if ds == nil || ds.space.Name != "old" || ds.Executant.Id.OpaqueId != "einstein" {
+		t.Fatalf("unexpected disabled space %+v", ds)
+	}
+	if expired := d.list(now.Add(-24 * time.Hour)); len(expired) != 1 || expired[0].space.Name != "old" {
+		t.Errorf("expected old to be past the grace period, got %v", expired)
+	}
+
+	for p, disabled := range map[string]bool{
+		"/projects/new":         true,
+		"/projects/new/a/b.txt": true,
+		"/projects/newer":       false,
+		"/projects":             false,
+		"/home/new":             false,
+	} {
+		if got := d.disabledAt(p) != nil; got != disabled {
+			t.Errorf("disabledAt(%q): expected %t, got %t", p, disabled, got)
+		}
+	}
+
+	if ok, err := d.remove(&provider.StorageSpaceId{OpaqueId: "new"}); !ok || err != nil {
+		t.Fatalf("expected new to be removed, got %t %v", ok, err)
+	}
+	if ok, _ := d.remove(&provider.StorageSpaceId{OpaqueId: "new"}); ok {
+		t.Error("expected new to be gone")
+	}
+	if d.disabledAt("/projects/new") != nil {
+		t.Error("expected /projects/new to be writable again")
+	}
+
+	var none *disabledSpaces
+	if none.get(&provider.StorageSpaceId{OpaqueId: "old"}) != nil || none.disabledAt("/projects/old") != nil || !none.empty() {
+		t.Error("expected nothing disabled without a grace period")
+	}
+}
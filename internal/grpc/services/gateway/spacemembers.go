@@ -0,0 +1,257 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/permissions"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// spaceRoles are the roles the members of a space can be given.
+var spaceRoles = []string{permissions.RoleViewer, permissions.RoleEditor, permissions.RoleManager}
+
+func isSpaceRole(name string) bool {
+	for _, r := range spaceRoles {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getSpace returns the space of the catalog with the given id, along with a
+// client of the provider holding its root.
+func (s *svc) getSpace(ctx context.Context, id *provider.StorageSpaceId) (*provider.StorageSpace, provider.ProviderAPIClient, *rpc.Status) {
+	if id.GetOpaqueId() == "" {
+		return nil, nil, status.NewInvalidArg(ctx, "missing space id")
+	}
+	res, err := s.listCatalogSpaces(ctx, &provider.ListStorageSpacesRequest{})
+	if err != nil {
+		return nil, nil, status.NewInternal(ctx, err, "error listing storage spaces")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, nil, res.Status
+	}
+	for _, space := range res.StorageSpaces {
+		if space.Id.GetOpaqueId() != id.OpaqueId {
+			continue
+		}
+		if space.Root.GetOpaqueId() == "" {
+			return nil, nil, status.NewInvalidArg(ctx, "the space has no members")
+		}
+		c, err := s.findByID(ctx, space.Root)
+		if err != nil {
+			return nil, nil, status.NewStatusFromErrType(ctx, "error finding storage provider", err)
+		}
+		return space, c, nil
+	}
+	return nil, nil, status.NewNotFound(ctx, "space not found")
+}
+
+// checkSpacePermission makes sure the user may do what check tells on the root of the space.
+func (s *svc) checkSpacePermission(ctx context.Context, space *provider.StorageSpace, check func(*provider.ResourcePermissions) bool) *rpc.Status {
+	res, err := s.stat(ctx, &provider.StatRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: space.Root}},
+	})
+	if err != nil {
+		return status.NewInternal(ctx, err, "error stating space root")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return res.Status
+	}
+	if !check(res.Info.GetPermissionSet()) {
+		err := errtypes.PermissionDenied("gateway: not allowed to manage the members of space " + space.Id.GetOpaqueId())
+		return status.NewPermissionDenied(ctx, err, "permission denied")
+	}
+	return nil
+}
+
+func (s *svc) listSpaceGrants(ctx context.Context, c provider.ProviderAPIClient, space *provider.StorageSpace) ([]*provider.Grant, *rpc.Status) {
+	res, err := c.ListGrants(ctx, &provider.ListGrantsRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: space.Root}},
+	})
+	if err != nil {
+		return nil, status.NewInternal(ctx, err, "error calling ListGrants")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, res.Status
+	}
+	return res.Grants, nil
+}
+
+func spaceMember(g *provider.Grant) *spacespb.SpaceMember {
+	m := &spacespb.SpaceMember{Grantee: g.Grantee, Permissions: g.Permissions}
+	if r, ok := permissions.FromResourcePermissions(g.Permissions); ok {
+		m.Role = r.Name
+	}
+	return m
+}
+
+// keepsManager tells whether a manager is left among the grants once the one
+// of the grantee is gone.
+func keepsManager(grants []*provider.Grant, grantee *provider.Grantee) bool {
+	for _, g := range grants {
+		if utils.GranteeEqual(g.Grantee, grantee) {
+			continue
+		}
+		if r, ok := permissions.FromResourcePermissions(g.Permissions); ok && r.Name == permissions.RoleManager {
+			return true
+		}
+	}
+	return false
+}
+
+func findGrant(grants []*provider.Grant, grantee *provider.Grantee) *provider.Grant {
+	for _, g := range grants {
+		if utils.GranteeEqual(g.Grantee, grantee) {
+			return g
+		}
+	}
+	return nil
+}
+
+func (s *svc) ListSpaceMembers(ctx context.Context, req *spacespb.ListSpaceMembersRequest) (*spacespb.ListSpaceMembersResponse, error) {
+	space, c, st := s.getSpace(ctx, req.Id)
+	if st != nil {
+		return &spacespb.ListSpaceMembersResponse{Status: st}, nil
+	}
+	if st := s.checkSpacePermission(ctx, space, func(p *provider.ResourcePermissions) bool { return p.GetListGrants() }); st != nil {
+		return &spacespb.ListSpaceMembersResponse{Status: st}, nil
+	}
+	grants, st := s.listSpaceGrants(ctx, c, space)
+	if st != nil {
+		return &spacespb.ListSpaceMembersResponse{Status: st}, nil
+	}
+
+	members := make([]*spacespb.SpaceMember, 0, len(grants))
+	for _, g := range grants {
+		members = append(members, spaceMember(g))
+	}
+	return &spacespb.ListSpaceMembersResponse{
+		Status:  status.NewOK(ctx),
+		Members: members,
+	}, nil
+}
+
+func (s *svc) AddSpaceMember(ctx context.Context, req *spacespb.AddSpaceMemberRequest) (*spacespb.AddSpaceMemberResponse, error) {
+	if !isSpaceRole(req.Role) {
+		return &spacespb.AddSpaceMemberResponse{
+			Status: status.NewInvalidArg(ctx, "unknown space role "+req.Role),
+		}, nil
+	}
+	if u, g := utils.ExtractGranteeID(req.Grantee); u == nil && g == nil {
+		return &spacespb.AddSpaceMemberResponse{
+			Status: status.NewInvalidArg(ctx, "missing grantee"),
+		}, nil
+	}
+	role, ok := permissions.Get(req.Role)
+	if !ok {
+		return &spacespb.AddSpaceMemberResponse{
+			Status: status.NewInternal(ctx, errtypes.NotFound(req.Role), "space role not configured"),
+		}, nil
+	}
+
+	space, c, st := s.getSpace(ctx, req.Id)
+	if st != nil {
+		return &spacespb.AddSpaceMemberResponse{Status: st}, nil
+	}
+	grants, st := s.listSpaceGrants(ctx, c, space)
+	if st != nil {
+		return &spacespb.AddSpaceMemberResponse{Status: st}, nil
+	}
+	existing := findGrant(grants, req.Grantee)
+	check := func(p *provider.ResourcePermissions) bool { return p.GetAddGrant() }
+	if existing != nil {
+		check = func(p *provider.ResourcePermissions) bool { return p.GetUpdateGrant() }
+	}
+	if st := s.checkSpacePermission(ctx, space, check); st != nil {
+		return &spacespb.AddSpaceMemberResponse{Status: st}, nil
+	}
+	if existing != nil && role.Name != permissions.RoleManager && !keepsManager(grants, req.Grantee) {
+		return &spacespb.AddSpaceMemberResponse{
+			Status: status.NewInvalidArg(ctx, "the space needs another manager first"),
+		}, nil
+	}
+
+	grant := &provider.Grant{Grantee: req.Grantee, Permissions: role.Permissions}
+	ref := &provider.Reference{Spec: &provider.Reference_Id{Id: space.Root}}
+	var grantStatus *rpc.Status
+	if existing != nil {
+		res, err := c.UpdateGrant(ctx, &provider.UpdateGrantRequest{Ref: ref, Grant: grant})
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway: error calling UpdateGrant")
+		}
+		grantStatus = res.Status
+	} else {
+		res, err := c.AddGrant(ctx, &provider.AddGrantRequest{Ref: ref, Grant: grant})
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway: error calling AddGrant")
+		}
+		grantStatus = res.Status
+	}
+	if grantStatus.Code != rpc.Code_CODE_OK {
+		return &spacespb.AddSpaceMemberResponse{Status: grantStatus}, nil
+	}
+	return &spacespb.AddSpaceMemberResponse{
+		Status: status.NewOK(ctx),
+		Member: spaceMember(grant),
+	}, nil
+}
+
+func (s *svc) RemoveSpaceMember(ctx context.Context, req *spacespb.RemoveSpaceMemberRequest) (*spacespb.RemoveSpaceMemberResponse, error) {
+	space, c, st := s.getSpace(ctx, req.Id)
+	if st != nil {
+		return &spacespb.RemoveSpaceMemberResponse{Status: st}, nil
+	}
+	if st := s.checkSpacePermission(ctx, space, func(p *provider.ResourcePermissions) bool { return p.GetRemoveGrant() }); st != nil {
+		return &spacespb.RemoveSpaceMemberResponse{Status: st}, nil
+	}
+	grants, st := s.listSpaceGrants(ctx, c, space)
+	if st != nil {
+		return &spacespb.RemoveSpaceMemberResponse{Status: st}, nil
+	}
+	existing := findGrant(grants, req.Grantee)
+	if existing == nil {
+		return &spacespb.RemoveSpaceMemberResponse{
+			Status: status.NewNotFound(ctx, "not a member of the space"),
+		}, nil
+	}
+	if !keepsManager(grants, req.Grantee) {
+		return &spacespb.RemoveSpaceMemberResponse{
+			Status: status.NewInvalidArg(ctx, "the space needs another manager first"),
+		}, nil
+	}
+
+	res, err := c.RemoveGrant(ctx, &provider.RemoveGrantRequest{
+		Ref:   &provider.Reference{Spec: &provider.Reference_Id{Id: space.Root}},
+		Grant: existing,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling RemoveGrant")
+	}
+	return &spacespb.RemoveSpaceMemberResponse{Status: res.Status}, nil
+}
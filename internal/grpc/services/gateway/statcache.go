@@ -0,0 +1,246 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"path"
+	"sync/atomic"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/cache"
+	userpkg "github.com/cs3org/reva/pkg/user"
+)
+
+// stat returns the cached stat result of path references when the stat cache is enabled.
+// Results depend on the permissions of the user, so they are cached per user.
+func (s *svc) stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	userID, p, ok := s.statCacheKey(ctx, req)
+	if !ok {
+		return s.statProviders(ctx, req)
+	}
+
+	if info, err := s.statCache.GetStat(userID, p); err == nil {
+		atomic.AddUint64(&s.statHits, 1)
+		return &provider.StatResponse{
+			Status: status.NewOK(ctx),
+			Info:   info,
+		}, nil
+	}
+
+	atomic.AddUint64(&s.statMisses, 1)
+	res, err := s.statProviders(ctx, req)
+	if err == nil && res.Status.Code == rpc.Code_CODE_OK {
+		if err := s.statCache.SetStat(userID, p, res.Info); err != nil {
+			appctx.GetLogger(ctx).Warn().Err(err).Str("path", p).Msg("gateway: error caching stat result")
+		}
+	}
+	return res, err
+}
+
+func (s *svc) statCacheKey(ctx context.Context, req *provider.StatRequest) (string, string, bool) {
+	// arbitrary metadata keys change the result, don't cache them
+	if s.c.StatCacheTTL <= 0 || len(req.ArbitraryMetadataKeys) > 0 {
+		return "", "", false
+	}
+	return cacheKey(ctx, req.Ref)
+}
+
+// cacheKey returns the user and the path the results for a path reference are
+// cached with.
+func cacheKey(ctx context.Context, ref *provider.Reference) (string, string, bool) {
+	if ref.GetPath() == "" {
+		return "", "", false
+	}
+	u, ok := userpkg.ContextGetUser(ctx)
+	if !ok {
+		return "", "", false
+	}
+	return u.Id.Idp + "!" + u.Id.OpaqueId, path.Clean(ref.GetPath()), true
+}
+
+// listContainer returns the cached listing of path references when the list
+// cache is enabled. The folder is stated first, through the stat cache, and
+// its cached listing is only used while the folder has the same etag, so that
+// the changes made past the gateway are seen once the stat result expires.
+func (s *svc) listContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
+	userID, p, ok := cacheKey(ctx, req.Ref)
+	if s.c.ListCacheTTL <= 0 || !ok {
+		return s.listProviders(ctx, req)
+	}
+
+	statRes, err := s.stat(ctx, &provider.StatRequest{Ref: req.Ref})
+	if err != nil || statRes.Status.Code != rpc.Code_CODE_OK || statRes.Info.Etag == "" {
+		// the providers report the error, if any
+		return s.listProviders(ctx, req)
+	}
+	etag := statRes.Info.Etag
+
+	if infos, err := s.statCache.GetList(userID, p, etag); err == nil {
+		atomic.AddUint64(&s.listHits, 1)
+		return &provider.ListContainerResponse{
+			Status: status.NewOK(ctx),
+			Infos:  infos,
+		}, nil
+	}
+
+	atomic.AddUint64(&s.listMisses, 1)
+	res, err := s.listProviders(ctx, req)
+	// partial listings are not cached
+	if err == nil && res.Status.Code == rpc.Code_CODE_OK && !isPartial(res) {
+		if err := s.statCache.SetList(userID, p, etag, res.Infos); err != nil {
+			appctx.GetLogger(ctx).Warn().Err(err).Str("path", p).Msg("gateway: error caching listing")
+		}
+	}
+	return res, err
+}
+
+func isPartial(res *provider.ListContainerResponse) bool {
+	m := res.Opaque.GetMap()
+	return m[storage.TruncatedOpaqueKey] != nil || m[storage.DegradedOpaqueKey] != nil
+}
+
+// invalidateStat drops the cached stat results and listings of the referenced paths, of all
+// the paths below them and of all their parents. Id based references are not resolved, their
+// cached results expire after the ttls.
+func (s *svc) invalidateStat(ctx context.Context, refs ...*provider.Reference) {
+	var paths []string
+	for _, ref := range refs {
+		if p := ref.GetPath(); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	s.invalidatePaths(ctx, paths...)
+}
+
+func (s *svc) invalidatePaths(ctx context.Context, changed ...string) {
+	if s.c.StatCacheTTL <= 0 && s.c.ListCacheTTL <= 0 {
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+	// a deleted or moved folder takes the resources below it along
+	if err := s.statCache.InvalidateTree(changed...); err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Strs("paths", changed).Msg("gateway: error invalidating cached stat results")
+	}
+	var parents []string
+	for _, p := range changed {
+		parents = append(parents, cache.Ancestors(p)[1:]...)
+	}
+	if len(parents) == 0 {
+		return
+	}
+	if err := s.statCache.Invalidate(parents...); err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Strs("paths", parents).Msg("gateway: error invalidating cached stat results")
+	}
+}
+
+// consumeFileEvents invalidates the cached results of the paths of the file
+// events, which report the changes made past the gateway. The events carrying
+// only ids are not resolved, their cached results expire after the ttls.
+func (s *svc) consumeFileEvents() error {
+	f, ok := eventsregistry.NewFuncs[s.c.EventStream]
+	if !ok {
+		return errtypes.NotFound("gateway: event stream not found for stat cache events: " + s.c.EventStream)
+	}
+	stream, err := f(s.c.EventStreams[s.c.EventStream])
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// every gateway drops the results it cached itself, hence no consumer group
+	err = events.Consume(ctx, stream, "", func(ev events.Event) {
+		switch e := ev.(type) {
+		case *events.FileUploaded:
+			s.invalidatePaths(ctx, e.Path)
+		case *events.FileDeleted:
+			s.invalidatePaths(ctx, e.Path)
+		}
+	}, events.FileUploaded{}.Type(), events.FileDeleted{}.Type())
+	if err != nil {
+		cancel()
+		_ = stream.Close()
+		return err
+	}
+	s.stopEvents = func() {
+		cancel()
+		_ = stream.Close()
+	}
+	return nil
+}
+
+// statCacheProbe reports the hit rate of the stat cache.
+func (s *svc) statCacheProbe() ops.State {
+	return s.cacheProbe(atomic.LoadUint64(&s.statHits), atomic.LoadUint64(&s.statMisses))
+}
+
+// listCacheProbe reports the hit rate of the list cache.
+func (s *svc) listCacheProbe() ops.State {
+	return s.cacheProbe(atomic.LoadUint64(&s.listHits), atomic.LoadUint64(&s.listMisses))
+}
+
+func (s *svc) cacheProbe(hits, misses uint64) ops.State {
+	rate := 0.0
+	if hits+misses > 0 {
+		rate = float64(hits) / float64(hits+misses)
+	}
+	return ops.State{
+		Health: ops.Healthy,
+		Details: map[string]interface{}{
+			"driver":   s.c.StatCacheDriver,
+			"hits":     hits,
+			"misses":   misses,
+			"hit_rate": rate,
+		},
+	}
+}
+
+// collectCaches returns the hits and misses of the enabled caches, from which
+// their hit rates are computed.
+func (s *svc) collectCaches() []metrics.Sample {
+	var samples []metrics.Sample
+	add := func(cache string, hits, misses *uint64) {
+		labels := map[string]string{"cache": cache}
+		samples = append(samples,
+			metrics.Sample{Name: "gateway_cache_hits_total", Help: "The lookups answered by the caches of the gateway", Kind: metrics.Counter, Labels: labels, Value: float64(atomic.LoadUint64(hits))},
+			metrics.Sample{Name: "gateway_cache_misses_total", Help: "The lookups the caches of the gateway could not answer", Kind: metrics.Counter, Labels: labels, Value: float64(atomic.LoadUint64(misses))},
+		)
+	}
+	if s.c.StatCacheTTL > 0 {
+		add("stat", &s.statHits, &s.statMisses)
+	}
+	if s.c.ListCacheTTL > 0 {
+		add("list", &s.listHits, &s.listMisses)
+	}
+	if s.groupCache != nil {
+		add("groups", &s.groupHits, &s.groupMisses)
+	}
+	return samples
+}
@@ -0,0 +1,56 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage/cache/memory"
+)
+
+func TestInvalidateStat(t *testing.T) {
+	paths := []string{"/", "/home", "/home/docs", "/home/docs/a.txt", "/home/docs/sub/b.txt", "/home/docsets", "/home/other"}
+	c := memory.NewCache(time.Minute, time.Minute, 0)
+	for _, p := range paths {
+		_ = c.SetStat("einstein", p, &provider.ResourceInfo{Path: p})
+		_ = c.SetList("einstein", p, "e", nil)
+	}
+	s := &svc{c: &config{StatCacheTTL: 60, ListCacheTTL: 60}, statCache: c}
+
+	// moving or deleting a folder changes its parents and the resources below it
+	s.invalidateStat(context.Background(),
+		&provider.Reference{Spec: &provider.Reference_Path{Path: "/home/docs"}},
+		&provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{OpaqueId: "id"}}},
+	)
+
+	kept := map[string]bool{"/home/docsets": true, "/home/other": true}
+	for _, p := range paths {
+		_, statErr := c.GetStat("einstein", p)
+		_, listErr := c.GetList("einstein", p, "e")
+		if kept[p] && (statErr != nil || listErr != nil) {
+			t.Errorf("%s was invalidated", p)
+		}
+		if !kept[p] && (statErr == nil || listErr == nil) {
+			t.Errorf("%s was not invalidated", p)
+		}
+	}
+}
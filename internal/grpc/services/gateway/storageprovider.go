@@ -28,13 +28,16 @@ import (
 	"time"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
-	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/etag"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/dgrijalva/jwt-go"
@@ -110,6 +113,15 @@ func (s *svc) CreateStorageSpace(ctx context.Context, req *provider.CreateStorag
 			Status: status.NewInternal(ctx, err, "error calling CreateStorageSpace"),
 		}, nil
 	}
+	if res.Status.Code == rpc.Code_CODE_OK && res.StorageSpace != nil {
+		s.events.Emit(ctx, &events.SpaceCreated{
+			ID:        res.StorageSpace.Id,
+			Owner:     res.StorageSpace.GetOwner().GetId(),
+			Root:      res.StorageSpace.Root,
+			Name:      res.StorageSpace.Name,
+			SpaceType: res.StorageSpace.SpaceType,
+		})
+	}
 	return res, nil
 }
 
@@ -122,6 +134,15 @@ func (s *svc) ListStorageSpaces(ctx context.Context, req *provider.ListStorageSp
 			id = f.GetId()
 		}
 	}
+	if id == nil {
+		return s.listCatalogSpaces(ctx, req)
+	}
+	if s.disabledSpaces.get(id) != nil {
+		return &provider.ListStorageSpacesResponse{
+			Status:        status.NewOK(ctx),
+			StorageSpaces: []*provider.StorageSpace{},
+		}, nil
+	}
 	c, err := s.findByID(ctx, &provider.ResourceId{
 		OpaqueId: id.OpaqueId,
 	})
@@ -141,6 +162,96 @@ func (s *svc) ListStorageSpaces(ctx context.Context, req *provider.ListStorageSp
 	return res, nil
 }
 
+// listCatalogSpaces lists the spaces of the catalog kept by the storage registry,
+// filtered by type and owner. The disabled spaces are left out.
+func (s *svc) listCatalogSpaces(ctx context.Context, req *provider.ListStorageSpacesRequest) (*provider.ListStorageSpacesResponse, error) {
+	var types []string
+	var owners []*userpb.UserId
+	for _, f := range req.Filters {
+		switch f.Type {
+		case provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE:
+			types = append(types, f.GetSpaceType())
+		case provider.ListStorageSpacesRequest_Filter_TYPE_OWNER:
+			owners = append(owners, f.GetOwner())
+		}
+	}
+
+	entries, st := s.listCatalog(ctx, types)
+	if st != nil {
+		return &provider.ListStorageSpacesResponse{
+			Status: st,
+		}, nil
+	}
+	spaces := []*provider.StorageSpace{}
+	for _, e := range entries {
+		if len(owners) > 0 && !ownedByAny(e.space, owners) {
+			continue
+		}
+		if s.disabledSpaces.get(e.space.Id) != nil {
+			continue
+		}
+		spaces = append(spaces, e.space)
+	}
+	return &provider.ListStorageSpacesResponse{
+		Status:        status.NewOK(ctx),
+		StorageSpaces: spaces,
+	}, nil
+}
+
+// catalogEntry is a space of the catalog along with the path it is reached at.
+type catalogEntry struct {
+	space *provider.StorageSpace
+	alias string
+}
+
+// listCatalog lists the spaces of the catalog of the given types, all of them when none is given.
+func (s *svc) listCatalog(ctx context.Context, types []string) ([]catalogEntry, *rpc.Status) {
+	c, err := s.registryClient(s.c.StorageRegistryEndpoint)
+	if err != nil {
+		return nil, status.NewInternal(ctx, err, "error getting storage registry client")
+	}
+	res, err := c.ListStorageProviders(ctx, &registry.ListStorageProvidersRequest{
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				storage.SpaceTypesOpaqueKey: {
+					Decoder: "plain",
+					Value:   []byte(strings.Join(types, ",")),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, status.NewInternal(ctx, err, "error calling ListStorageProviders")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, res.Status
+	}
+
+	entries := []catalogEntry{}
+	for _, p := range res.Providers {
+		e, ok := p.Opaque.GetMap()[storage.SpaceOpaqueKey]
+		if !ok {
+			continue
+		}
+		space := &provider.StorageSpace{}
+		if err := utils.UnmarshalJSONToProtoV1(e.Value, space); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("provider", p.Address).Msg("gateway: error decoding storage space")
+			continue
+		}
+		entries = append(entries, catalogEntry{space: space, alias: p.ProviderPath})
+	}
+	return entries, nil
+}
+
+func ownedByAny(space *provider.StorageSpace, owners []*userpb.UserId) bool {
+	for _, o := range owners {
+		if utils.UserEqual(space.GetOwner().GetId(), o) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *svc) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
 	log := appctx.GetLogger(ctx)
 	// TODO: needs to be fixed
@@ -162,6 +273,24 @@ func (s *svc) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorag
 }
 
 func (s *svc) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) (*provider.DeleteStorageSpaceResponse, error) {
+	if s.disabledSpaces != nil {
+		return s.disableStorageSpace(ctx, req)
+	}
+	res, err := s.deleteStorageSpace(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code == rpc.Code_CODE_OK {
+		s.events.Emit(ctx, &events.SpaceDeleted{
+			ID:        req.Id,
+			Executant: executant(ctx),
+		})
+	}
+	return res, nil
+}
+
+// deleteStorageSpace removes the space from its storage provider.
+func (s *svc) deleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) (*provider.DeleteStorageSpaceResponse, error) {
 	log := appctx.GetLogger(ctx)
 	// TODO: needs to be fixed
 	c, err := s.findByID(ctx, &provider.ResourceId{
@@ -418,6 +547,7 @@ func (s *svc) initiateFileDownload(ctx context.Context, req *provider.InitiateFi
 }
 
 func (s *svc) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*gateway.InitiateFileUploadResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
 	log := appctx.GetLogger(ctx)
 	p, st := s.getPath(ctx, req.Ref)
 	if st.Code != rpc.Code_CODE_OK {
@@ -425,6 +555,11 @@ func (s *svc) InitiateFileUpload(ctx context.Context, req *provider.InitiateFile
 			Status: st,
 		}, nil
 	}
+	if st := s.checkWritable(ctx, p); st != nil {
+		return &gateway.InitiateFileUploadResponse{
+			Status: st,
+		}, nil
+	}
 
 	if !s.inSharedFolder(ctx, p) {
 		return s.initiateFileUpload(ctx, req)
@@ -577,6 +712,7 @@ func (s *svc) InitiateFileUpload(ctx context.Context, req *provider.InitiateFile
 }
 
 func (s *svc) initiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*gateway.InitiateFileUploadResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
 		return &gateway.InitiateFileUploadResponse{
@@ -661,6 +797,7 @@ func (s *svc) GetPath(ctx context.Context, req *provider.GetPathRequest) (*provi
 }
 
 func (s *svc) CreateContainer(ctx context.Context, req *provider.CreateContainerRequest) (*provider.CreateContainerResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
 	log := appctx.GetLogger(ctx)
 	p, st := s.getPath(ctx, req.Ref)
 	if st.Code != rpc.Code_CODE_OK {
@@ -668,6 +805,11 @@ func (s *svc) CreateContainer(ctx context.Context, req *provider.CreateContainer
 			Status: st,
 		}, nil
 	}
+	if st := s.checkWritable(ctx, p); st != nil {
+		return &provider.CreateContainerResponse{
+			Status: st,
+		}, nil
+	}
 
 	if !s.inSharedFolder(ctx, p) {
 		return s.createContainer(ctx, req)
@@ -740,6 +882,7 @@ func (s *svc) CreateContainer(ctx context.Context, req *provider.CreateContainer
 }
 
 func (s *svc) createContainer(ctx context.Context, req *provider.CreateContainerRequest) (*provider.CreateContainerResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
 		return &provider.CreateContainerResponse{
@@ -762,6 +905,7 @@ func (s *svc) inSharedFolder(ctx context.Context, p string) bool {
 }
 
 func (s *svc) Delete(ctx context.Context, req *provider.DeleteRequest) (*provider.DeleteResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
 	log := appctx.GetLogger(ctx)
 	p, st := s.getPath(ctx, req.Ref)
 	if st.Code != rpc.Code_CODE_OK {
@@ -769,6 +913,11 @@ func (s *svc) Delete(ctx context.Context, req *provider.DeleteRequest) (*provide
 			Status: st,
 		}, nil
 	}
+	if st := s.checkWritable(ctx, p); st != nil {
+		return &provider.DeleteResponse{
+			Status: st,
+		}, nil
+	}
 
 	if !s.inSharedFolder(ctx, p) {
 		return s.delete(ctx, req)
@@ -857,6 +1006,7 @@ func (s *svc) Delete(ctx context.Context, req *provider.DeleteRequest) (*provide
 }
 
 func (s *svc) delete(ctx context.Context, req *provider.DeleteRequest) (*provider.DeleteResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
 	// TODO(ishank011): enable deleting references spread across storage providers, eg. /eos
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
@@ -874,6 +1024,7 @@ func (s *svc) delete(ctx context.Context, req *provider.DeleteRequest) (*provide
 }
 
 func (s *svc) Move(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	defer s.invalidateStat(ctx, req.Source, req.Destination)
 	log := appctx.GetLogger(ctx)
 	p, st := s.getPath(ctx, req.Source)
 	if st.Code != rpc.Code_CODE_OK {
@@ -888,6 +1039,13 @@ func (s *svc) Move(ctx context.Context, req *provider.MoveRequest) (*provider.Mo
 			Status: st2,
 		}, nil
 	}
+	for _, p := range []string{p, dp} {
+		if st := s.checkWritable(ctx, p); st != nil {
+			return &provider.MoveResponse{
+				Status: st,
+			}, nil
+		}
+	}
 
 	if !s.inSharedFolder(ctx, p) && !s.inSharedFolder(ctx, dp) {
 		return s.move(ctx, req)
@@ -975,6 +1133,7 @@ func (s *svc) Move(ctx context.Context, req *provider.MoveRequest) (*provider.Mo
 }
 
 func (s *svc) move(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	defer s.invalidateStat(ctx, req.Source, req.Destination)
 	srcList, err := s.findProviders(ctx, req.Source)
 	if err != nil {
 		return &provider.MoveResponse{
@@ -990,12 +1149,13 @@ func (s *svc) move(ctx context.Context, req *provider.MoveRequest) (*provider.Mo
 	}
 	srcP, dstP := srcList[0], dstList[0]
 
-	// if providers are not the same we do not implement cross storage copy yet.
+	if isCopy(req) {
+		return s.copy(ctx, req, srcP, dstP)
+	}
+
+	// if providers are not the same the gateway moves the data itself.
 	if srcP.Address != dstP.Address {
-		res := &provider.MoveResponse{
-			Status: status.NewUnimplemented(ctx, nil, "gateway: cross storage copy not yet implemented"),
-		}
-		return res, nil
+		return s.crossMove(ctx, req)
 	}
 
 	c, err := s.getStorageProviderClient(ctx, srcP)
@@ -1009,6 +1169,22 @@ func (s *svc) move(ctx context.Context, req *provider.MoveRequest) (*provider.Mo
 }
 
 func (s *svc) SetArbitraryMetadata(ctx context.Context, req *provider.SetArbitraryMetadataRequest) (*provider.SetArbitraryMetadataResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
+	if st := s.checkRefWritable(ctx, req.Ref); st != nil {
+		return &provider.SetArbitraryMetadataResponse{
+			Status: st,
+		}, nil
+	}
+	var changes []*storage.MetadataChange
+	if ok, err := storage.DecodeMetadataBatch(req.Opaque, &changes); ok {
+		if err != nil {
+			return &provider.SetArbitraryMetadataResponse{
+				Status: status.NewInvalidArg(ctx, "gateway: error decoding metadata batch: "+err.Error()),
+			}, nil
+		}
+		return s.setArbitraryMetadataBatch(ctx, changes)
+	}
+
 	// TODO(ishank011): enable for references spread across storage providers, eg. /eos
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
@@ -1025,7 +1201,99 @@ func (s *svc) SetArbitraryMetadata(ctx context.Context, req *provider.SetArbitra
 	return res, nil
 }
 
+// setArbitraryMetadataBatch splits the batch by storage provider and sends one
+// request to each of them, the results are returned in the order of the changes.
+func (s *svc) setArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) (*provider.SetArbitraryMetadataResponse, error) {
+	refs := make([]*provider.Reference, len(changes))
+	for i := range changes {
+		refs[i] = changes[i].Reference()
+	}
+	defer s.invalidateStat(ctx, refs...)
+
+	results := make([]*storage.MetadataResult, len(changes))
+	batches := map[string][]int{}
+	providers := map[string]*registry.ProviderInfo{}
+	for i, c := range changes {
+		p, err := s.findProviders(ctx, c.Reference())
+		if err != nil {
+			st := status.NewStatusFromErrType(ctx, "SetArbitraryMetadata ref="+c.Reference().String(), err)
+			results[i] = &storage.MetadataResult{Code: st.Code, Message: st.Message}
+			continue
+		}
+		batches[p[0].Address] = append(batches[p[0].Address], i)
+		providers[p[0].Address] = p[0]
+	}
+
+	for addr, indexes := range batches {
+		batch := make([]*storage.MetadataChange, 0, len(indexes))
+		for _, i := range indexes {
+			batch = append(batch, changes[i])
+		}
+
+		providerResults, err := s.setArbitraryMetadataOnProvider(ctx, providers[addr], batch)
+		if err != nil {
+			st := status.NewInternal(ctx, err, "gateway: error setting arbitrary metadata on provider="+addr)
+			providerResults = make([]*storage.MetadataResult, len(indexes))
+			for j := range providerResults {
+				providerResults[j] = &storage.MetadataResult{Code: st.Code, Message: st.Message}
+			}
+		}
+		for j, i := range indexes {
+			results[i] = providerResults[j]
+		}
+	}
+
+	opaque, err := storage.EncodeMetadataBatch(results)
+	if err != nil {
+		return &provider.SetArbitraryMetadataResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error encoding metadata batch results"),
+		}, nil
+	}
+	return &provider.SetArbitraryMetadataResponse{
+		Status: status.NewOK(ctx),
+		Opaque: opaque,
+	}, nil
+}
+
+func (s *svc) setArbitraryMetadataOnProvider(ctx context.Context, p *registry.ProviderInfo, batch []*storage.MetadataChange) ([]*storage.MetadataResult, error) {
+	c, err := s.getStorageProviderClient(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	opaque, err := storage.EncodeMetadataBatch(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Opaque: opaque,
+		Ref:    batch[0].Reference(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error calling SetArbitraryMetadata")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "gateway")
+	}
+
+	var results []*storage.MetadataResult
+	if ok, err := storage.DecodeMetadataBatch(res.Opaque, &results); !ok || err != nil {
+		return nil, errtypes.InternalError("gateway: storage provider did not return the metadata batch results")
+	}
+	if len(results) != len(batch) {
+		return nil, errtypes.InternalError("gateway: storage provider returned an unexpected number of metadata batch results")
+	}
+	return results, nil
+}
+
 func (s *svc) UnsetArbitraryMetadata(ctx context.Context, req *provider.UnsetArbitraryMetadataRequest) (*provider.UnsetArbitraryMetadataResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
+	if st := s.checkRefWritable(ctx, req.Ref); st != nil {
+		return &provider.UnsetArbitraryMetadataResponse{
+			Status: st,
+		}, nil
+	}
 	// TODO(ishank011): enable for references spread across storage providers, eg. /eos
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
@@ -1079,17 +1347,16 @@ func (s *svc) statHome(ctx context.Context) (*provider.StatResponse, error) {
 		}, nil
 	}
 
-	if etagIface, err := s.etagCache.Get(statRes.Info.Owner.OpaqueId + ":" + statRes.Info.Path); err == nil {
+	if resEtag, resTS, err := s.statCache.GetEtag(statRes.Info.Owner.OpaqueId + ":" + statRes.Info.Path); err == nil {
 		resMtime := utils.TSToTime(statRes.Info.Mtime)
-		resEtag := etagIface.(etagWithTS)
 		// Use the updated etag if the home folder has been modified
-		if resMtime.Before(resEtag.Timestamp) {
-			statRes.Info.Etag = resEtag.Etag
+		if resMtime.Before(resTS) {
+			statRes.Info.Etag = resEtag
 		}
 	} else {
 		statRes.Info.Etag = etag.GenerateEtagFromResources(statRes.Info, []*provider.ResourceInfo{statSharedFolder.Info})
 		if s.c.EtagCacheTTL > 0 {
-			_ = s.etagCache.Set(statRes.Info.Owner.OpaqueId+":"+statRes.Info.Path, etagWithTS{statRes.Info.Etag, time.Now()})
+			_ = s.statCache.SetEtag(statRes.Info.Owner.OpaqueId+":"+statRes.Info.Path, statRes.Info.Etag, time.Now())
 		}
 	}
 
@@ -1128,24 +1395,23 @@ func (s *svc) statSharesFolder(ctx context.Context) (*provider.StatResponse, err
 		}, nil
 	}
 
-	if etagIface, err := s.etagCache.Get(statRes.Info.Owner.OpaqueId + ":" + statRes.Info.Path); err == nil {
+	if resEtag, resTS, err := s.statCache.GetEtag(statRes.Info.Owner.OpaqueId + ":" + statRes.Info.Path); err == nil {
 		resMtime := utils.TSToTime(statRes.Info.Mtime)
-		resEtag := etagIface.(etagWithTS)
 		// Use the updated etag if the shares folder has been modified, i.e., a new
 		// reference has been created.
-		if resMtime.Before(resEtag.Timestamp) {
-			statRes.Info.Etag = resEtag.Etag
+		if resMtime.Before(resTS) {
+			statRes.Info.Etag = resEtag
 		}
 	} else {
 		statRes.Info.Etag = etag.GenerateEtagFromResources(statRes.Info, lsRes.Infos)
 		if s.c.EtagCacheTTL > 0 {
-			_ = s.etagCache.Set(statRes.Info.Owner.OpaqueId+":"+statRes.Info.Path, etagWithTS{statRes.Info.Etag, time.Now()})
+			_ = s.statCache.SetEtag(statRes.Info.Owner.OpaqueId+":"+statRes.Info.Path, statRes.Info.Etag, time.Now())
 		}
 	}
 	return statRes, nil
 }
 
-func (s *svc) stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+func (s *svc) statProviders(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
 	providers, err := s.findProviders(ctx, req.Ref)
 	if err != nil {
 		return &provider.StatResponse{
@@ -1155,13 +1421,15 @@ func (s *svc) stat(ctx context.Context, req *provider.StatRequest) (*provider.St
 
 	resPath := req.Ref.GetPath()
 	if len(providers) == 1 && (resPath == "" || strings.HasPrefix(resPath, providers[0].ProviderPath)) {
-		c, err := s.getStorageProviderClient(ctx, providers[0])
+		res, err := s.hedge(ctx, providers[0], func(ctx context.Context, c provider.ProviderAPIClient) (interface{}, error) {
+			return c.Stat(ctx, req)
+		})
 		if err != nil {
 			return &provider.StatResponse{
-				Status: status.NewInternal(ctx, err, "error connecting to storage provider="+providers[0].Address),
+				Status: status.NewInternal(ctx, err, "error calling Stat on storage provider="+providers[0].Address),
 			}, nil
 		}
-		return c.Stat(ctx, req)
+		return res.(*provider.StatResponse), nil
 	}
 
 	infoFromProviders := make([]*provider.ResourceInfo, len(providers))
@@ -1382,6 +1650,9 @@ func (s *svc) checkRef(ctx context.Context, ri *provider.ResourceInfo) (*provide
 
 	switch uri.Scheme {
 	case "cs3":
+		if err := s.checkReceivedShare(ctx, uri.Opaque); err != nil {
+			return nil, "cs3", err
+		}
 		ref, err := s.handleCS3Ref(ctx, uri.Opaque)
 		return ref, "cs3", err
 	case "webdav":
@@ -1504,6 +1775,13 @@ func (s *svc) listSharesFolder(ctx context.Context) (*provider.ListContainerResp
 			Status: lcr.Status,
 		}, nil
 	}
+	// resolve the received shares once for all the references
+	ctx, err = s.withReceivedShares(ctx)
+	if err != nil {
+		return &provider.ListContainerResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error listing received shares"),
+		}, nil
+	}
 	checkedInfos := make([]*provider.ResourceInfo, 0)
 	for i := range lcr.Infos {
 		info, protocol, err := s.checkRef(ctx, lcr.Infos[i])
@@ -1532,7 +1810,7 @@ func (s *svc) listSharesFolder(ctx context.Context) (*provider.ListContainerResp
 	return lcr, nil
 }
 
-func (s *svc) listContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
+func (s *svc) listProviders(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
 	providers, err := s.findProviders(ctx, req.Ref)
 	if err != nil {
 		return &provider.ListContainerResponse{
@@ -1541,25 +1819,42 @@ func (s *svc) listContainer(ctx context.Context, req *provider.ListContainerRequ
 	}
 
 	resPath := path.Clean(req.Ref.GetPath())
-	infoFromProviders := make([][]*provider.ResourceInfo, len(providers))
-	errors := make([]error, len(providers))
+	listings := make([]providerListing, len(providers))
 	var wg sync.WaitGroup
-
+	// bound the number of providers listed at the same time
+	sem := make(chan struct{}, s.c.ListConcurrency)
 	for i, p := range providers {
 		wg.Add(1)
-		go s.listContainerOnProvider(ctx, req, &infoFromProviders[i], p, &errors[i], &wg)
+		go func(i int, p *registry.ProviderInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			listings[i] = s.listContainerOnProvider(ctx, req, p)
+		}(i, p)
 	}
 	wg.Wait()
 
 	infos := []*provider.ResourceInfo{}
 	indirects := make(map[string][]*provider.ResourceInfo)
-	for i := range providers {
-		if errors[i] != nil {
-			return &provider.ListContainerResponse{
-				Status: status.NewStatusFromErrType(ctx, "listContainer ref: "+req.Ref.String(), errors[i]),
-			}, nil
+	var isTruncated bool
+	var failed *providerListing
+	var degraded []string
+	listed := 0
+	for i, p := range providers {
+		l := &listings[i]
+		if l.err != nil || l.status.Code != rpc.Code_CODE_OK {
+			if failed == nil {
+				failed = l
+			}
+			// a path missing on some of the providers is not a failure
+			if l.err != nil || l.status.Code != rpc.Code_CODE_NOT_FOUND {
+				degraded = append(degraded, p.ProviderPath)
+			}
+			continue
 		}
-		for _, inf := range infoFromProviders[i] {
+		listed++
+		isTruncated = isTruncated || l.truncated
+		for _, inf := range l.infos {
 			if parent := path.Dir(inf.Path); resPath != "" && resPath != parent {
 				parts := strings.Split(strings.TrimPrefix(inf.Path, resPath), "/")
 				p := path.Join(resPath, parts[1])
@@ -1570,6 +1865,18 @@ func (s *svc) listContainer(ctx context.Context, req *provider.ListContainerRequ
 		}
 	}
 
+	// the result is only degraded when some of the providers could be listed
+	if listed == 0 && failed != nil {
+		if failed.err != nil {
+			return &provider.ListContainerResponse{
+				Status: status.NewStatusFromErrType(ctx, "listContainer ref: "+req.Ref.String(), failed.err),
+			}, nil
+		}
+		return &provider.ListContainerResponse{
+			Status: failed.status,
+		}, nil
+	}
+
 	for k, v := range indirects {
 		inf := &provider.ResourceInfo{
 			Id: &provider.ResourceId{
@@ -1584,18 +1891,43 @@ func (s *svc) listContainer(ctx context.Context, req *provider.ListContainerRequ
 		infos = append(infos, inf)
 	}
 
-	return &provider.ListContainerResponse{
+	res := &provider.ListContainerResponse{
 		Status: status.NewOK(ctx),
 		Infos:  infos,
-	}, nil
+	}
+	if isTruncated || len(degraded) > 0 {
+		res.Opaque = &typespb.Opaque{Map: map[string]*typespb.OpaqueEntry{}}
+	}
+	if isTruncated {
+		// at least one provider returned a partial listing
+		res.Opaque.Map[storage.TruncatedOpaqueKey] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte("true"),
+		}
+	}
+	if len(degraded) > 0 {
+		appctx.GetLogger(ctx).Warn().Str("ref", req.Ref.String()).Strs("providers", degraded).Msg("gateway: storage providers failed to list container, returning partial result")
+		res.Opaque.Map[storage.DegradedOpaqueKey] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(strings.Join(degraded, ",")),
+		}
+	}
+	return res, nil
 }
 
-func (s *svc) listContainerOnProvider(ctx context.Context, req *provider.ListContainerRequest, res *[]*provider.ResourceInfo, p *registry.ProviderInfo, e *error, wg *sync.WaitGroup) {
-	defer wg.Done()
-	c, err := s.getStorageProviderClient(ctx, p)
-	if err != nil {
-		*e = errors.Wrap(err, "error connecting to storage provider="+p.Address)
-		return
+// providerListing is the outcome of listing a container on one of the providers.
+type providerListing struct {
+	infos     []*provider.ResourceInfo
+	truncated bool
+	status    *rpc.Status
+	err       error
+}
+
+func (s *svc) listContainerOnProvider(ctx context.Context, req *provider.ListContainerRequest, p *registry.ProviderInfo) providerListing {
+	if s.c.ListProviderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.c.ListProviderTimeout)*time.Millisecond)
+		defer cancel()
 	}
 
 	resPath := path.Clean(req.Ref.GetPath())
@@ -1603,18 +1935,24 @@ func (s *svc) listContainerOnProvider(ctx context.Context, req *provider.ListCon
 	if resPath != "" && !strings.HasPrefix(resPath, p.ProviderPath) {
 		newPath = p.ProviderPath
 	}
-	r, err := c.ListContainer(ctx, &provider.ListContainerRequest{
-		Ref: &provider.Reference{
-			Spec: &provider.Reference_Path{
-				Path: newPath,
+	res, err := s.hedge(ctx, p, func(ctx context.Context, c provider.ProviderAPIClient) (interface{}, error) {
+		return c.ListContainer(ctx, &provider.ListContainerRequest{
+			Ref: &provider.Reference{
+				Spec: &provider.Reference_Path{
+					Path: newPath,
+				},
 			},
-		},
+		})
 	})
 	if err != nil {
-		*e = errors.Wrap(err, "gateway: error calling ListContainer")
-		return
+		return providerListing{err: errors.Wrap(err, "gateway: error calling ListContainer on storage provider="+p.Address)}
+	}
+	r := res.(*provider.ListContainerResponse)
+	return providerListing{
+		infos:     r.Infos,
+		truncated: r.Opaque.GetMap()[storage.TruncatedOpaqueKey] != nil,
+		status:    r.Status,
 	}
-	*res = r.Infos
 }
 
 func (s *svc) ListContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
@@ -1924,6 +2262,12 @@ func (s *svc) ListFileVersions(ctx context.Context, req *provider.ListFileVersio
 }
 
 func (s *svc) RestoreFileVersion(ctx context.Context, req *provider.RestoreFileVersionRequest) (*provider.RestoreFileVersionResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref)
+	if st := s.checkRefWritable(ctx, req.Ref); st != nil {
+		return &provider.RestoreFileVersionResponse{
+			Status: st,
+		}, nil
+	}
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
 		return &provider.RestoreFileVersionResponse{
@@ -1952,8 +2296,17 @@ func (s *svc) ListRecycle(ctx context.Context, req *gateway.ListRecycleRequest)
 		}, nil
 	}
 
+	opaque := req.Opaque
+	if id := req.Ref.GetId(); id != nil {
+		// the providers are not given the reference, only the root to list the trash of
+		opaque = &typespb.Opaque{Map: map[string]*typespb.OpaqueEntry{}}
+		for k, v := range req.Opaque.GetMap() {
+			opaque.Map[k] = v
+		}
+		opaque.Map[storage.RecycleRootOpaqueKey] = &typespb.OpaqueEntry{Decoder: "plain", Value: []byte(id.OpaqueId)}
+	}
 	res, err := c.ListRecycle(ctx, &provider.ListRecycleRequest{
-		Opaque: req.Opaque,
+		Opaque: opaque,
 		FromTs: req.FromTs,
 		ToTs:   req.ToTs,
 	})
@@ -1965,6 +2318,7 @@ func (s *svc) ListRecycle(ctx context.Context, req *gateway.ListRecycleRequest)
 }
 
 func (s *svc) RestoreRecycleItem(ctx context.Context, req *provider.RestoreRecycleItemRequest) (*provider.RestoreRecycleItemResponse, error) {
+	defer s.invalidateStat(ctx, req.Ref, &provider.Reference{Spec: &provider.Reference_Path{Path: req.RestorePath}})
 	c, err := s.find(ctx, req.Ref)
 	if err != nil {
 		return &provider.RestoreRecycleItemResponse{
@@ -2044,7 +2398,7 @@ func (s *svc) find(ctx context.Context, ref *provider.Reference) (provider.Provi
 }
 
 func (s *svc) getStorageProviderClient(_ context.Context, p *registry.ProviderInfo) (provider.ProviderAPIClient, error) {
-	c, err := pool.GetStorageProviderServiceClient(p.Address)
+	c, err := s.providerClient(p.Address)
 	if err != nil {
 		err = errors.Wrap(err, "gateway: error getting a storage provider client")
 		return nil, err
@@ -2054,7 +2408,7 @@ func (s *svc) getStorageProviderClient(_ context.Context, p *registry.ProviderIn
 }
 
 func (s *svc) findProviders(ctx context.Context, ref *provider.Reference) ([]*registry.ProviderInfo, error) {
-	c, err := pool.GetStorageRegistryClient(s.c.StorageRegistryEndpoint)
+	c, err := s.registryClient(s.c.StorageRegistryEndpoint)
 	if err != nil {
 		return nil, errors.Wrap(err, "gateway: error getting storage registry client")
 	}
@@ -2088,8 +2442,3 @@ func (s *svc) findProviders(ctx context.Context, ref *provider.Reference) ([]*re
 
 	return res.Providers, nil
 }
-
-type etagWithTS struct {
-	Etag      string
-	Timestamp time.Time
-}
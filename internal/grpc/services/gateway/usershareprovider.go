@@ -28,8 +28,12 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/permissions"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/storage/utils/grants"
 	"github.com/pkg/errors"
 )
 
@@ -40,6 +44,13 @@ func (s *svc) CreateShare(ctx context.Context, req *collaboration.CreateShareReq
 		return nil, errtypes.AlreadyExists("gateway: can't share the share folder itself")
 	}
 
+	// shares can be created with the name of a role instead of its permissions
+	if req.Grant != nil && len(permissions.Names(req.Grant.Permissions.GetPermissions())) == 0 {
+		if role, ok := permissions.RoleFromOpaque(req.Opaque); ok {
+			req.Grant.Permissions = &collaboration.SharePermissions{Permissions: role.Permissions}
+		}
+	}
+
 	c, err := pool.GetUserShareProviderClient(s.c.UserShareProviderEndpoint)
 	if err != nil {
 		return &collaboration.CreateShareResponse{
@@ -58,6 +69,7 @@ func (s *svc) CreateShare(ctx context.Context, req *collaboration.CreateShareReq
 
 	// if we don't need to commit we return earlier
 	if !s.c.CommitShareToStorageGrant && !s.c.CommitShareToStorageRef {
+		s.emitShareCreated(ctx, res.Share)
 		return res, nil
 	}
 
@@ -74,9 +86,25 @@ func (s *svc) CreateShare(ctx context.Context, req *collaboration.CreateShareReq
 		}
 	}
 
+	s.emitShareCreated(ctx, res.Share)
 	return res, nil
 }
 
+func (s *svc) emitShareCreated(ctx context.Context, share *collaboration.Share) {
+	if share == nil {
+		return
+	}
+	s.events.Emit(ctx, &events.ShareCreated{
+		ShareID:        share.Id,
+		Sharer:         share.Creator,
+		GranteeUserID:  share.GetGrantee().GetUserId(),
+		GranteeGroupID: share.GetGrantee().GetGroupId(),
+		ItemID:         share.ResourceId,
+		Permissions:    share.Permissions,
+		CTime:          share.Ctime,
+	})
+}
+
 func (s *svc) RemoveShare(ctx context.Context, req *collaboration.RemoveShareRequest) (*collaboration.RemoveShareResponse, error) {
 	c, err := pool.GetUserShareProviderClient(s.c.UserShareProviderEndpoint)
 	if err != nil {
@@ -85,6 +113,16 @@ func (s *svc) RemoveShare(ctx context.Context, req *collaboration.RemoveShareReq
 		}, nil
 	}
 
+	// the share provider removes the shares of the admin requests through the
+	// gateway on behalf of their creators, committing them to the storage.
+	if share.IsAdmin(req.Opaque) {
+		res, err := c.RemoveShare(ctx, req)
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway: error calling RemoveShare")
+		}
+		return res, nil
+	}
+
 	// if we need to commit the share, we need the resource it points to.
 	var share *collaboration.Share
 	if s.c.CommitShareToStorageGrant || s.c.CommitShareToStorageRef {
@@ -175,6 +213,16 @@ func (s *svc) ListShares(ctx context.Context, req *collaboration.ListSharesReque
 }
 
 func (s *svc) UpdateShare(ctx context.Context, req *collaboration.UpdateShareRequest) (*collaboration.UpdateShareResponse, error) {
+	if len(permissions.Names(req.GetField().GetPermissions().GetPermissions())) == 0 && req.GetField().GetDisplayName() == "" {
+		if role, ok := permissions.RoleFromOpaque(req.Opaque); ok {
+			req.Field = &collaboration.UpdateShareRequest_UpdateField{
+				Field: &collaboration.UpdateShareRequest_UpdateField_Permissions{
+					Permissions: &collaboration.SharePermissions{Permissions: role.Permissions},
+				},
+			}
+		}
+	}
+
 	c, err := pool.GetUserShareProviderClient(s.c.UserShareProviderEndpoint)
 	if err != nil {
 		err = errors.Wrap(err, "gateway: error calling GetUserShareProviderClient")
@@ -231,9 +279,37 @@ func (s *svc) ListReceivedShares(ctx context.Context, req *collaboration.ListRec
 	if err != nil {
 		return nil, errors.Wrap(err, "gateway: error calling ListReceivedShares")
 	}
+	if res.Status.Code == rpc.Code_CODE_OK {
+		res.Shares = withoutDeniedShares(res.Shares)
+	}
+	if s.c.CommitShareToStorageRef && res.Status.Code == rpc.Code_CODE_OK {
+		s.mountPointsOpaque(ctx, res)
+	}
 	return res, nil
 }
 
+// withoutDeniedShares resolves the deny shares among the received shares: a deny
+// share of a resource wins over the shares granting access to it, so neither
+// the deny share nor the shares of the denied resource are returned.
+func withoutDeniedShares(shares []*collaboration.ReceivedShare) []*collaboration.ReceivedShare {
+	denied := map[string]struct{}{}
+	for _, rs := range shares {
+		if grants.IsDeny(rs.GetShare().GetPermissions().GetPermissions()) {
+			denied[resourceTarget(rs.GetShare().GetResourceId())] = struct{}{}
+		}
+	}
+	if len(denied) == 0 {
+		return shares
+	}
+	filtered := make([]*collaboration.ReceivedShare, 0, len(shares))
+	for _, rs := range shares {
+		if _, ok := denied[resourceTarget(rs.GetShare().GetResourceId())]; !ok {
+			filtered = append(filtered, rs)
+		}
+	}
+	return filtered
+}
+
 func (s *svc) GetReceivedShare(ctx context.Context, req *collaboration.GetReceivedShareRequest) (*collaboration.GetReceivedShareResponse, error) {
 	c, err := pool.GetUserShareProviderClient(s.c.UserShareProviderEndpoint)
 	if err != nil {
@@ -248,6 +324,15 @@ func (s *svc) GetReceivedShare(ctx context.Context, req *collaboration.GetReceiv
 		return nil, errors.Wrap(err, "gateway: error calling GetReceivedShare")
 	}
 
+	if s.c.CommitShareToStorageRef && res.Status.Code == rpc.Code_CODE_OK && res.Share.GetState() == collaboration.ShareState_SHARE_STATE_ACCEPTED {
+		mounts, err := s.listShareMounts(ctx)
+		if err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Msg("gateway: error listing share mounts")
+		} else if name := mountPoint(mounts, res.Share.Share.GetResourceId()); name != "" {
+			res.Opaque = share.EncodeMountPoint(res.Opaque, name)
+		}
+	}
+
 	return res, nil
 }
 
@@ -295,20 +380,32 @@ func (s *svc) UpdateReceivedShare(ctx context.Context, req *collaboration.Update
 	// TODO(labkode): if update field is displayName we need to do a rename on the storage to align
 	// share display name and storage filename.
 	if req.Field.GetState() != collaboration.ShareState_SHARE_STATE_INVALID {
+		rs := res.Share
+		if rs == nil {
+			panic("gateway: error updating a received share: the share is nil")
+		}
 		if req.Field.GetState() == collaboration.ShareState_SHARE_STATE_ACCEPTED {
-			share := res.Share
-			if share == nil {
-				panic("gateway: error updating a received share: the share is nil")
+			mounts, err := s.listShareMounts(ctx)
+			if err != nil {
+				return &collaboration.UpdateReceivedShareResponse{
+					Status: status.NewInternal(ctx, err, "error listing share mounts"),
+				}, nil
 			}
-			createRefStatus := s.createReference(ctx, share.Share.ResourceId)
-			rsp := &collaboration.UpdateReceivedShareResponse{Status: createRefStatus}
-
-			if createRefStatus.Code == rpc.Code_CODE_OK {
-				rsp.Share = share
+			// accepting a share again keeps its mount point
+			name := mountPoint(mounts, rs.Share.ResourceId)
+			if name == "" {
+				var createRefStatus *rpc.Status
+				name, createRefStatus = s.createReference(ctx, rs.Share.ResourceId, mounts)
+				if createRefStatus.Code != rpc.Code_CODE_OK {
+					return &collaboration.UpdateReceivedShareResponse{Status: createRefStatus}, nil
+				}
 			}
-			return rsp, nil
+			res.Opaque = share.EncodeMountPoint(res.Opaque, name)
+			return res, nil
 		} else if req.Field.GetState() == collaboration.ShareState_SHARE_STATE_REJECTED {
-			// Nothing more to do, return the original result
+			if unmountStatus := s.unmountShare(ctx, rs.Share); unmountStatus.Code != rpc.Code_CODE_OK {
+				return &collaboration.UpdateReceivedShareResponse{Status: unmountStatus}, nil
+			}
 			return res, nil
 		}
 	}
@@ -320,7 +417,10 @@ func (s *svc) UpdateReceivedShare(ctx context.Context, req *collaboration.Update
 	}, nil
 }
 
-func (s *svc) createReference(ctx context.Context, resourceID *provider.ResourceId) *rpc.Status {
+// createReference mounts the resource in the share folder under the name of the
+// resource, numbered if an entry of the share folder has it already, and returns
+// the name it is mounted with.
+func (s *svc) createReference(ctx context.Context, resourceID *provider.ResourceId, mounts []shareMount) (string, *rpc.Status) {
 
 	log := appctx.GetLogger(ctx)
 
@@ -328,9 +428,9 @@ func (s *svc) createReference(ctx context.Context, resourceID *provider.Resource
 	c, err := s.findByID(ctx, resourceID)
 	if err != nil {
 		if _, ok := err.(errtypes.IsNotFound); ok {
-			return status.NewNotFound(ctx, "storage provider not found")
+			return "", status.NewNotFound(ctx, "storage provider not found")
 		}
-		return status.NewInternal(ctx, err, "error finding storage provider")
+		return "", status.NewInternal(ctx, err, "error finding storage provider")
 	}
 
 	statReq := &provider.StatRequest{
@@ -343,19 +443,19 @@ func (s *svc) createReference(ctx context.Context, resourceID *provider.Resource
 
 	statRes, err := c.Stat(ctx, statReq)
 	if err != nil {
-		return status.NewInternal(ctx, err, "gateway: error calling Stat for the share resource id: "+resourceID.String())
+		return "", status.NewInternal(ctx, err, "gateway: error calling Stat for the share resource id: "+resourceID.String())
 	}
 
 	if statRes.Status.Code != rpc.Code_CODE_OK {
 		err := status.NewErrorFromCode(statRes.Status.GetCode(), "gateway")
 		log.Err(err).Msg("gateway: Stat failed on the share resource id: " + resourceID.String())
-		return status.NewInternal(ctx, err, "error updating received share")
+		return "", status.NewInternal(ctx, err, "error updating received share")
 	}
 
 	homeRes, err := s.GetHome(ctx, &provider.GetHomeRequest{})
 	if err != nil {
 		err := errors.Wrap(err, "gateway: error calling GetHome")
-		return status.NewInternal(ctx, err, "error updating received share")
+		return "", status.NewInternal(ctx, err, "error updating received share")
 	}
 
 	// reference path is the home path + some name
@@ -367,8 +467,8 @@ func (s *svc) createReference(ctx context.Context, resourceID *provider.Resource
 	// CreateReference(dropbox://x/y/z)
 	// It is the responsibility of the gateway to resolve these references and merge the response back
 	// from the main request.
-	// TODO(labkode): the name of the share should be the filename it points to by default.
-	refPath := path.Join(homeRes.Path, s.c.ShareFolder, path.Base(statRes.Info.Path))
+	name := uniqueMountName(path.Base(statRes.Info.Path), mounts)
+	refPath := path.Join(homeRes.Path, s.c.ShareFolder, name)
 	log.Info().Msg("mount path will be:" + refPath)
 
 	createRefReq := &provider.CreateReferenceRequest{
@@ -380,25 +480,25 @@ func (s *svc) createReference(ctx context.Context, resourceID *provider.Resource
 	c, err = s.findByPath(ctx, refPath)
 	if err != nil {
 		if _, ok := err.(errtypes.IsNotFound); ok {
-			return status.NewNotFound(ctx, "storage provider not found")
+			return "", status.NewNotFound(ctx, "storage provider not found")
 		}
-		return status.NewInternal(ctx, err, "error finding storage provider")
+		return "", status.NewInternal(ctx, err, "error finding storage provider")
 	}
 
 	createRefRes, err := c.CreateReference(ctx, createRefReq)
 	if err != nil {
 		log.Err(err).Msg("gateway: error calling GetHome")
-		return &rpc.Status{
+		return "", &rpc.Status{
 			Code: rpc.Code_CODE_INTERNAL,
 		}
 	}
 
 	if createRefRes.Status.Code != rpc.Code_CODE_OK {
 		err := status.NewErrorFromCode(createRefRes.Status.GetCode(), "gateway")
-		return status.NewInternal(ctx, err, "error updating received share")
+		return "", status.NewInternal(ctx, err, "error updating received share")
 	}
 
-	return status.NewOK(ctx)
+	return name, status.NewOK(ctx)
 }
 
 func (s *svc) addGrant(ctx context.Context, id *provider.ResourceId, g *provider.Grantee, p *provider.ResourcePermissions) (*rpc.Status, error) {
@@ -23,11 +23,13 @@ import (
 	"fmt"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/group"
 	"github.com/cs3org/reva/pkg/group/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/golang/protobuf/proto"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -108,6 +110,13 @@ func (s *service) GetGroup(ctx context.Context, req *grouppb.GetGroupRequest) (*
 		}, nil
 	}
 
+	group, err = s.withParentGroups(ctx, req.Opaque, group)
+	if err != nil {
+		return &grouppb.GetGroupResponse{
+			Status: status.NewInternal(ctx, err, "error getting parent groups"),
+		}, nil
+	}
+
 	return &grouppb.GetGroupResponse{
 		Status: status.NewOK(ctx),
 		Group:  group,
@@ -123,12 +132,42 @@ func (s *service) GetGroupByClaim(ctx context.Context, req *grouppb.GetGroupByCl
 		}, nil
 	}
 
+	group, err = s.withParentGroups(ctx, req.Opaque, group)
+	if err != nil {
+		return &grouppb.GetGroupByClaimResponse{
+			Status: status.NewInternal(ctx, err, "error getting parent groups"),
+		}, nil
+	}
+
 	return &grouppb.GetGroupByClaimResponse{
 		Status: status.NewOK(ctx),
 		Group:  group,
 	}, nil
 }
 
+// withParentGroups adds the parent groups to the opaque of the group if the
+// request asks for them. Drivers without nested groups return none.
+func (s *service) withParentGroups(ctx context.Context, o *types.Opaque, g *grouppb.Group) (*grouppb.Group, error) {
+	if _, ok := o.GetMap()[group.ParentGroupsOpaqueKey]; !ok {
+		return g, nil
+	}
+	var parents []string
+	if m, ok := s.groupmgr.(group.NestedManager); ok {
+		var err error
+		if parents, err = m.GetParentGroups(ctx, g.GroupName); err != nil {
+			return nil, errors.Wrap(err, "groupprovidersvc: error getting parent groups")
+		}
+	}
+	// the drivers may hand out the groups they hold
+	g = proto.Clone(g).(*grouppb.Group)
+	opaque, err := group.EncodeParentGroups(g.Opaque, parents)
+	if err != nil {
+		return nil, err
+	}
+	g.Opaque = opaque
+	return g, nil
+}
+
 func (s *service) FindGroups(ctx context.Context, req *grouppb.FindGroupsRequest) (*grouppb.FindGroupsResponse, error) {
 	groups, err := s.groupmgr.FindGroups(ctx, req.Filter)
 	if err != nil {
@@ -0,0 +1,274 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package guests implements the service inviting the guests, the lightweight
+// users created when a resource is shared with an unknown mail address.
+package guests
+
+import (
+	"context"
+	"net/url"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	guestspb "github.com/cs3org/reva/internal/grpc/services/guests/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/guest"
+	"github.com/cs3org/reva/pkg/guest/manager/registry"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/passwordpolicy"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/smtpclient"
+	"github.com/cs3org/reva/pkg/token"
+	tokenregistry "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	// Load the guest manager drivers.
+	_ "github.com/cs3org/reva/pkg/guest/manager/loader"
+)
+
+func init() {
+	rgrpc.Register("guests", New)
+}
+
+type config struct {
+	Driver         string                            `mapstructure:"driver" docs:"json;The guest manager the guests are stored in."`
+	Drivers        map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/guest/manager/json/json.go;The configuration for the guest managers."`
+	GatewaySvc     string                            `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
+	InvitationURL  string                            `mapstructure:"invitation_url" docs:";The URL of the page the guests set their password at, the token of the invitation is added as query parameter."`
+	SMTP           *smtpclient.SMTPCredentials       `mapstructure:"smtp" docs:";The SMTP server the invitations are sent through."`
+	TemplatesDir   string                            `mapstructure:"templates_dir" docs:";The directory holding custom templates as <language>/<kind>.tmpl."`
+	Language       string                            `mapstructure:"language" docs:"en;The language of the invitations."`
+	TokenManager   string                            `mapstructure:"token_manager" docs:"jwt;The token manager minting the tokens the homes of the guests are created with."`
+	TokenManagers  map[string]map[string]interface{} `mapstructure:"token_managers" docs:"url:pkg/token/manager/jwt/jwt.go;The configuration for the token managers."`
+	PasswordPolicy map[string]interface{}            `mapstructure:"password_policy" docs:"url:pkg/passwordpolicy/passwordpolicy.go;The rules the passwords of the guests have to follow."`
+}
+
+func (c *config) init() {
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.Language == "" {
+		c.Language = "en"
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type service struct {
+	conf      *config
+	guests    guest.Manager
+	tokenmgr  token.Manager
+	templates *notification.Templates
+	smtp      *smtpclient.SMTPCredentials
+	passwords *passwordpolicy.Policy
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	c.init()
+	return c, nil
+}
+
+// New creates a new guests service.
+func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	if c.SMTP == nil {
+		return nil, errors.New("guests: smtp not configured")
+	}
+	if c.InvitationURL == "" {
+		return nil, errors.New("guests: invitation_url not configured")
+	}
+
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("guests: guest manager driver not found: " + c.Driver)
+	}
+	guests, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+
+	tf, ok := tokenregistry.NewFuncs[c.TokenManager]
+	if !ok {
+		return nil, errtypes.NotFound("guests: token manager not found: " + c.TokenManager)
+	}
+	tokenmgr, err := tf(c.TokenManagers[c.TokenManager])
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := notification.NewTemplates(c.TemplatesDir, c.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	passwords, err := passwordpolicy.New(c.PasswordPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service{
+		conf:      c,
+		guests:    guests,
+		tokenmgr:  tokenmgr,
+		templates: templates,
+		smtp:      smtpclient.NewSMTPCredentials(c.SMTP),
+		passwords: passwords,
+	}, nil
+}
+
+func (s *service) Register(ss *grpc.Server) {
+	guestspb.RegisterGuestServiceServer(ss, s)
+}
+
+func (s *service) Close() error {
+	return nil
+}
+
+func (s *service) UnprotectedEndpoints() []string {
+	return []string{"/revad.guests.GuestService/AcceptInvitation"}
+}
+
+func (s *service) InviteGuest(ctx context.Context, req *guestspb.InviteGuestRequest) (*guestspb.InviteGuestResponse, error) {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok || guest.IsGuest(u) {
+		err := errtypes.PermissionDenied("guests: guests can't invite guests")
+		return &guestspb.InviteGuestResponse{Status: status.NewPermissionDenied(ctx, err, "guests can't invite guests")}, nil
+	}
+	if !guest.IsMail(req.Mail) {
+		return &guestspb.InviteGuestResponse{Status: status.NewInvalidArg(ctx, "invalid mail address")}, nil
+	}
+
+	if g, err := s.guests.GetGuestByClaim(ctx, "mail", req.Mail); err == nil {
+		return &guestspb.InviteGuestResponse{Status: status.NewOK(ctx), User: g}, nil
+	}
+
+	g, tkn, err := s.guests.CreateGuest(ctx, req.Mail, u.Id)
+	if err != nil {
+		return &guestspb.InviteGuestResponse{Status: status.NewStatusFromErrType(ctx, "error creating guest", err)}, nil
+	}
+	if err := s.createHome(ctx, g); err != nil {
+		s.removeGuest(ctx, g)
+		return &guestspb.InviteGuestResponse{Status: status.NewInternal(ctx, err, "error creating the home of the guest")}, nil
+	}
+	if err := s.sendInvitation(u, g, tkn, req.ResourceName); err != nil {
+		s.removeGuest(ctx, g)
+		return &guestspb.InviteGuestResponse{Status: status.NewInternal(ctx, err, "error sending the invitation")}, nil
+	}
+	return &guestspb.InviteGuestResponse{Status: status.NewOK(ctx), User: g, Created: true}, nil
+}
+
+func (s *service) AcceptInvitation(ctx context.Context, req *guestspb.AcceptInvitationRequest) (*guestspb.AcceptInvitationResponse, error) {
+	if req.Token == "" || req.Password == "" {
+		return &guestspb.AcceptInvitationResponse{Status: status.NewInvalidArg(ctx, "token or password missing")}, nil
+	}
+	if err := s.passwords.Validate(ctx, "password", req.Password); err != nil {
+		o, eerr := passwordpolicy.EncodeViolations(nil, err.(*passwordpolicy.Error))
+		if eerr != nil {
+			return &guestspb.AcceptInvitationResponse{Status: status.NewInternal(ctx, eerr, "error encoding password policy violations")}, nil
+		}
+		return &guestspb.AcceptInvitationResponse{Status: status.NewInvalidArg(ctx, err.Error()), Opaque: o}, nil
+	}
+	g, err := s.guests.AcceptInvitation(ctx, req.Token, req.Password)
+	if err != nil {
+		return &guestspb.AcceptInvitationResponse{Status: status.NewStatusFromErrType(ctx, "error accepting invitation", err)}, nil
+	}
+	return &guestspb.AcceptInvitationResponse{Status: status.NewOK(ctx), User: g}, nil
+}
+
+// createHome creates the home of the guest, as the guest, where the shares
+// they accept are mounted.
+func (s *service) createHome(ctx context.Context, g *userpb.User) error {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return err
+	}
+	tkn, err := s.tokenmgr.MintToken(token.ContextSetService(ctx, "guests"), g, ownerScope)
+	if err != nil {
+		return errors.Wrap(err, "guests: error minting token")
+	}
+	ctx = token.ContextSetToken(ctx, tkn)
+	ctx = user.ContextSetUser(ctx, g)
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs(token.TokenHeader, tkn))
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return errors.Wrap(err, "guests: error getting gateway client")
+	}
+	res, err := client.CreateHome(ctx, &provider.CreateHomeRequest{})
+	if err != nil {
+		return errors.Wrap(err, "guests: error calling CreateHome")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_ALREADY_EXISTS {
+		return status.NewErrorFromCode(res.Status.Code, "guests")
+	}
+	return nil
+}
+
+func (s *service) sendInvitation(inviter, g *userpb.User, tkn, resourceName string) error {
+	u, err := url.Parse(s.conf.InvitationURL)
+	if err != nil {
+		return errors.Wrap(err, "guests: invalid invitation url")
+	}
+	q := u.Query()
+	q.Set("token", tkn)
+	u.RawQuery = q.Encode()
+
+	actor := inviter.DisplayName
+	if actor == "" {
+		actor = inviter.Username
+	}
+	msg, err := s.templates.Render(s.conf.Language, notification.KindGuestInvited, &notification.Data{
+		Recipient:    g.Mail,
+		Actor:        actor,
+		ResourceName: resourceName,
+		URL:          u.String(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.smtp.SendMail(g.Mail, msg.Subject, msg.Body)
+}
+
+// removeGuest deletes a guest whose invitation failed, the invitation can
+// be retried with the same mail address.
+func (s *service) removeGuest(ctx context.Context, g *userpb.User) {
+	if err := s.guests.DeleteGuest(ctx, g.Id); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("mail", g.Mail).Msg("guests: error removing guest after failed invitation")
+	}
+}
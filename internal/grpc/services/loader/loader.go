@@ -28,6 +28,7 @@ import (
 	_ "github.com/cs3org/reva/internal/grpc/services/datatx"
 	_ "github.com/cs3org/reva/internal/grpc/services/gateway"
 	_ "github.com/cs3org/reva/internal/grpc/services/groupprovider"
+	_ "github.com/cs3org/reva/internal/grpc/services/guests"
 	_ "github.com/cs3org/reva/internal/grpc/services/helloworld"
 	_ "github.com/cs3org/reva/internal/grpc/services/ocmcore"
 	_ "github.com/cs3org/reva/internal/grpc/services/ocminvitemanager"
@@ -36,8 +37,10 @@ import (
 	_ "github.com/cs3org/reva/internal/grpc/services/preferences"
 	_ "github.com/cs3org/reva/internal/grpc/services/publicshareprovider"
 	_ "github.com/cs3org/reva/internal/grpc/services/publicstorageprovider"
+	_ "github.com/cs3org/reva/internal/grpc/services/search"
 	_ "github.com/cs3org/reva/internal/grpc/services/storageprovider"
 	_ "github.com/cs3org/reva/internal/grpc/services/storageregistry"
+	_ "github.com/cs3org/reva/internal/grpc/services/useradmin"
 	_ "github.com/cs3org/reva/internal/grpc/services/userprovider"
 	_ "github.com/cs3org/reva/internal/grpc/services/usershareprovider"
 	// Add your own service here
@@ -20,13 +20,16 @@ package ocminvitemanager
 
 import (
 	"context"
+	"time"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/ocm/invite"
 	"github.com/cs3org/reva/pkg/ocm/invite/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/user"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -39,11 +42,22 @@ func init() {
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// OneTime makes the generated tokens one time tokens unless the request
+	// says otherwise.
+	OneTime bool `mapstructure:"one_time"`
+	// MaxExpiration is the longest validity users can ask for their tokens,
+	// e.g. 720h. There is no limit if empty.
+	MaxExpiration string `mapstructure:"max_expiration"`
+	// Admins are the usernames of the users allowed to list and revoke the
+	// tokens of all users.
+	Admins []string `mapstructure:"admins"`
 }
 
 type service struct {
-	conf *config
-	im   invite.Manager
+	conf          *config
+	im            invite.Manager
+	maxExpiration time.Duration
+	admins        map[string]bool
 }
 
 func (c *config) init() {
@@ -87,8 +101,20 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	}
 
 	service := &service{
-		conf: c,
-		im:   im,
+		conf:   c,
+		im:     im,
+		admins: map[string]bool{},
+	}
+	if c.MaxExpiration != "" {
+		if service.maxExpiration, err = time.ParseDuration(c.MaxExpiration); err != nil {
+			return nil, errors.Wrap(err, "error parsing max_expiration")
+		}
+	}
+	if _, ok := im.(invite.TokenManager); c.OneTime && !ok {
+		return nil, errtypes.NotSupported("invite manager driver does not support one time tokens: " + c.Driver)
+	}
+	for _, a := range c.Admins {
+		service.admins[a] = true
 	}
 	return service, nil
 }
@@ -101,7 +127,39 @@ func (s *service) UnprotectedEndpoints() []string {
 	return []string{"/cs3.ocm.invite.v1beta1.InviteAPI/AcceptInvite"}
 }
 
+// GenerateInviteToken generates, lists or revokes invite tokens depending on
+// the token request held in the opaque of the request.
 func (s *service) GenerateInviteToken(ctx context.Context, req *invitepb.GenerateInviteTokenRequest) (*invitepb.GenerateInviteTokenResponse, error) {
+	r, err := invite.DecodeTokenRequest(req.Opaque)
+	if err != nil {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewInvalidArg(ctx, "error decoding token request: "+err.Error()),
+		}, nil
+	}
+
+	tm, ok := s.im.(invite.TokenManager)
+	if !ok && (r.Action != invite.ActionGenerate || r.Expiration != "" || r.OneTime != nil) {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewUnimplemented(ctx, nil, "invite manager driver does not manage tokens: "+s.conf.Driver),
+		}, nil
+	}
+
+	switch r.Action {
+	case invite.ActionGenerate:
+		if !ok {
+			break
+		}
+		return s.generateToken(ctx, tm, r)
+	case invite.ActionList:
+		return s.listTokens(ctx, tm, r)
+	case invite.ActionRevoke:
+		return s.revokeToken(ctx, tm, r)
+	default:
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewInvalidArg(ctx, "unknown token action: "+r.Action),
+		}, nil
+	}
+
 	token, err := s.im.GenerateToken(ctx)
 	if err != nil {
 		return &invitepb.GenerateInviteTokenResponse{
@@ -115,6 +173,99 @@ func (s *service) GenerateInviteToken(ctx context.Context, req *invitepb.Generat
 	}, nil
 }
 
+func (s *service) generateToken(ctx context.Context, tm invite.TokenManager, r *invite.TokenRequest) (*invitepb.GenerateInviteTokenResponse, error) {
+	if r.Expiration != "" {
+		d, err := time.ParseDuration(r.Expiration)
+		if err != nil || d <= 0 {
+			return &invitepb.GenerateInviteTokenResponse{
+				Status: status.NewInvalidArg(ctx, "invalid expiration: "+r.Expiration),
+			}, nil
+		}
+		if s.maxExpiration > 0 && d > s.maxExpiration {
+			return &invitepb.GenerateInviteTokenResponse{
+				Status: status.NewInvalidArg(ctx, "expiration exceeds the maximum of "+s.conf.MaxExpiration),
+			}, nil
+		}
+	}
+	oneTime := s.conf.OneTime
+	if r.OneTime != nil {
+		oneTime = *r.OneTime
+	}
+
+	token, err := tm.GenerateTokenWithOptions(ctx, r.Expiration, oneTime)
+	if err != nil {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewInternal(ctx, err, "error generating invite token"),
+		}, nil
+	}
+	return &invitepb.GenerateInviteTokenResponse{
+		Status:      status.NewOK(ctx),
+		InviteToken: token,
+	}, nil
+}
+
+func (s *service) listTokens(ctx context.Context, tm invite.TokenManager, r *invite.TokenRequest) (*invitepb.GenerateInviteTokenResponse, error) {
+	owner, err := s.tokenOwner(ctx, r)
+	if err != nil {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewPermissionDenied(ctx, err, "error listing invite tokens"),
+		}, nil
+	}
+
+	tokens, err := tm.ListTokens(ctx, owner)
+	if err != nil {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewInternal(ctx, err, "error listing invite tokens"),
+		}, nil
+	}
+	opaque, err := invite.EncodeTokens(tokens)
+	if err != nil {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewInternal(ctx, err, "error encoding invite tokens"),
+		}, nil
+	}
+	return &invitepb.GenerateInviteTokenResponse{
+		Status: status.NewOK(ctx),
+		Opaque: opaque,
+	}, nil
+}
+
+func (s *service) revokeToken(ctx context.Context, tm invite.TokenManager, r *invite.TokenRequest) (*invitepb.GenerateInviteTokenResponse, error) {
+	if r.Token == "" {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewInvalidArg(ctx, "missing token to revoke"),
+		}, nil
+	}
+	owner, err := s.tokenOwner(ctx, r)
+	if err != nil {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewPermissionDenied(ctx, err, "error revoking invite token"),
+		}, nil
+	}
+
+	if err := tm.RevokeToken(ctx, owner, r.Token); err != nil {
+		return &invitepb.GenerateInviteTokenResponse{
+			Status: status.NewStatusFromErrType(ctx, "error revoking invite token", err),
+		}, nil
+	}
+	return &invitepb.GenerateInviteTokenResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+// tokenOwner returns the user whose tokens are managed, which is nil for the
+// admins asking for the tokens of everybody.
+func (s *service) tokenOwner(ctx context.Context, r *invite.TokenRequest) (*userpb.UserId, error) {
+	u := user.ContextMustGetUser(ctx)
+	if !r.All {
+		return u.GetId(), nil
+	}
+	if !s.admins[u.GetUsername()] {
+		return nil, errtypes.PermissionDenied("only admins can manage the tokens of all users")
+	}
+	return nil, nil
+}
+
 func (s *service) ForwardInvite(ctx context.Context, req *invitepb.ForwardInviteRequest) (*invitepb.ForwardInviteResponse, error) {
 	err := s.im.ForwardInvite(ctx, req.InviteToken, req.OriginSystemProvider)
 	if err != nil {
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocmshareprovider
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/share"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+// checkAdmin returns the status refusing the admin request of a user who is
+// not an admin, or nil.
+func (s *service) checkAdmin(ctx context.Context) *rpc.Status {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok || !s.admins[u.GetUsername()] {
+		err := errtypes.PermissionDenied("ocmshareprovider: only admins can manage the OCM shares of all the users")
+		return status.NewPermissionDenied(ctx, err, "only admins can manage the OCM shares of all the users")
+	}
+	return nil
+}
+
+// allShares returns the OCM shares of all the users.
+func (s *service) allShares(ctx context.Context) ([]*ocm.Share, error) {
+	d, ok := s.sm.(share.Dumper)
+	if !ok {
+		return nil, errtypes.NotSupported("listing the OCM shares of all the users not supported by driver " + s.conf.Driver)
+	}
+	return d.Dump(ctx)
+}
+
+func (s *service) adminListShares(ctx context.Context, req *ocm.ListOCMSharesRequest) *ocm.ListOCMSharesResponse {
+	if st := s.checkAdmin(ctx); st != nil {
+		return &ocm.ListOCMSharesResponse{Status: st}
+	}
+
+	all, err := s.allShares(ctx)
+	if err != nil {
+		return &ocm.ListOCMSharesResponse{Status: status.NewStatusFromErrType(ctx, "error listing shares", err)}
+	}
+
+	shares := []*ocm.Share{}
+	for _, sh := range all {
+		if share.MatchFilters(sh, req.Filters) {
+			shares = append(shares, sh)
+		}
+	}
+	return &ocm.ListOCMSharesResponse{Status: status.NewOK(ctx), Shares: shares}
+}
+
+// adminGetShare returns the OCM share of any user with the id of the reference.
+func (s *service) adminGetShare(ctx context.Context, ref *ocm.ShareReference) (*ocm.Share, *rpc.Status) {
+	if st := s.checkAdmin(ctx); st != nil {
+		return nil, st
+	}
+	if ref.GetId() == nil {
+		return nil, status.NewInvalidArg(ctx, "the admins get the shares by id")
+	}
+
+	all, err := s.allShares(ctx)
+	if err != nil {
+		return nil, status.NewStatusFromErrType(ctx, "error listing shares", err)
+	}
+	for _, sh := range all {
+		if sh.Id.GetOpaqueId() == ref.GetId().OpaqueId {
+			return sh, status.NewOK(ctx)
+		}
+	}
+	return nil, status.NewNotFound(ctx, "share not found")
+}
+
+// adminRemoveShare removes the OCM share of any user on behalf of its owner.
+func (s *service) adminRemoveShare(ctx context.Context, ref *ocm.ShareReference) *rpc.Status {
+	sh, st := s.adminGetShare(ctx, ref)
+	if sh == nil {
+		return st
+	}
+
+	if err := s.sm.Unshare(user.ContextSetUser(ctx, &userpb.User{Id: sh.Owner}), ref); err != nil {
+		return status.NewInternal(ctx, err, "error removing share")
+	}
+
+	appctx.GetLogger(ctx).Info().Str("share", sh.Id.GetOpaqueId()).Str("admin", user.ContextMustGetUser(ctx).Username).
+		Msg("ocmshareprovider: admin removed share")
+	return status.NewOK(ctx)
+}
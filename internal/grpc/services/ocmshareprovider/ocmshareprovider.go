@@ -27,6 +27,7 @@ import (
 	"github.com/cs3org/reva/pkg/ocm/share/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
+	usershare "github.com/cs3org/reva/pkg/share"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -39,11 +40,15 @@ func init() {
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// Admins are the usernames of the users allowed to list and remove the
+	// OCM shares of all the users.
+	Admins []string `mapstructure:"admins"`
 }
 
 type service struct {
-	conf *config
-	sm   share.Manager
+	conf   *config
+	sm     share.Manager
+	admins map[string]bool
 }
 
 func (c *config) init() {
@@ -87,8 +92,12 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	}
 
 	service := &service{
-		conf: c,
-		sm:   sm,
+		conf:   c,
+		sm:     sm,
+		admins: map[string]bool{},
+	}
+	for _, a := range c.Admins {
+		service.admins[a] = true
 	}
 
 	return service, nil
@@ -164,7 +173,7 @@ func (s *service) CreateOCMShare(ctx context.Context, req *ocm.CreateOCMShareReq
 	share, err := s.sm.Share(ctx, req.ResourceId, req.Grant, name, req.RecipientMeshProvider, permissions, nil, "", sharetype)
 	if err != nil {
 		return &ocm.CreateOCMShareResponse{
-			Status: status.NewInternal(ctx, err, "error creating share"),
+			Status: status.NewStatusFromErrType(ctx, "error creating share", err),
 		}, nil
 	}
 
@@ -176,6 +185,10 @@ func (s *service) CreateOCMShare(ctx context.Context, req *ocm.CreateOCMShareReq
 }
 
 func (s *service) RemoveOCMShare(ctx context.Context, req *ocm.RemoveOCMShareRequest) (*ocm.RemoveOCMShareResponse, error) {
+	if usershare.IsAdmin(req.Opaque) {
+		return &ocm.RemoveOCMShareResponse{Status: s.adminRemoveShare(ctx, req.Ref)}, nil
+	}
+
 	err := s.sm.Unshare(ctx, req.Ref)
 	if err != nil {
 		return &ocm.RemoveOCMShareResponse{
@@ -189,6 +202,11 @@ func (s *service) RemoveOCMShare(ctx context.Context, req *ocm.RemoveOCMShareReq
 }
 
 func (s *service) GetOCMShare(ctx context.Context, req *ocm.GetOCMShareRequest) (*ocm.GetOCMShareResponse, error) {
+	if usershare.IsAdmin(req.Opaque) {
+		share, st := s.adminGetShare(ctx, req.Ref)
+		return &ocm.GetOCMShareResponse{Status: st, Share: share}, nil
+	}
+
 	share, err := s.sm.GetShare(ctx, req.Ref)
 	if err != nil {
 		return &ocm.GetOCMShareResponse{
@@ -203,6 +221,10 @@ func (s *service) GetOCMShare(ctx context.Context, req *ocm.GetOCMShareRequest)
 }
 
 func (s *service) ListOCMShares(ctx context.Context, req *ocm.ListOCMSharesRequest) (*ocm.ListOCMSharesResponse, error) {
+	if usershare.IsAdmin(req.Opaque) {
+		return s.adminListShares(ctx, req), nil
+	}
+
 	shares, err := s.sm.ListShares(ctx, req.Filters) // TODO(labkode): add filter to share manager
 	if err != nil {
 		return &ocm.ListOCMSharesResponse{
@@ -0,0 +1,116 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshareprovider
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+// checkAdmin returns the status refusing the admin request of a user who is
+// not an admin, or nil.
+func (s *service) checkAdmin(ctx context.Context) *rpc.Status {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok || !s.admins[u.GetUsername()] {
+		err := errtypes.PermissionDenied("publicshareprovider: only admins can manage the public shares of all the users")
+		return status.NewPermissionDenied(ctx, err, "only admins can manage the public shares of all the users")
+	}
+	return nil
+}
+
+// allShares returns the public shares of all the users, without their
+// passwords.
+func (s *service) allShares(ctx context.Context) ([]*link.PublicShare, error) {
+	d, ok := s.sm.(publicshare.Dumper)
+	if !ok {
+		return nil, errtypes.NotSupported("listing the public shares of all the users not supported by driver " + s.conf.Driver)
+	}
+	dump, err := d.Dump(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*link.PublicShare, 0, len(dump))
+	for _, ps := range dump {
+		shares = append(shares, ps.PublicShare)
+	}
+	return shares, nil
+}
+
+func (s *service) adminListPublicShares(ctx context.Context, req *link.ListPublicSharesRequest) *link.ListPublicSharesResponse {
+	if st := s.checkAdmin(ctx); st != nil {
+		return &link.ListPublicSharesResponse{Status: st}
+	}
+
+	all, err := s.allShares(ctx)
+	if err != nil {
+		return &link.ListPublicSharesResponse{Status: status.NewStatusFromErrType(ctx, "error listing public shares", err)}
+	}
+
+	shares := []*link.PublicShare{}
+	for _, ps := range all {
+		if publicshare.MatchFilters(ps, req.Filters) {
+			shares = append(shares, ps)
+		}
+	}
+	return &link.ListPublicSharesResponse{
+		Status: status.NewOK(ctx),
+		Share:  shares,
+		Opaque: s.accessStatsOpaque(ctx, nil, true, shares...),
+	}
+}
+
+// adminRemovePublicShare revokes the public share of any user on behalf of
+// its owner.
+func (s *service) adminRemovePublicShare(ctx context.Context, ref *link.PublicShareReference) *rpc.Status {
+	if st := s.checkAdmin(ctx); st != nil {
+		return st
+	}
+
+	all, err := s.allShares(ctx)
+	if err != nil {
+		return status.NewStatusFromErrType(ctx, "error listing public shares", err)
+	}
+	var ps *link.PublicShare
+	for _, candidate := range all {
+		if (ref.GetId() != nil && candidate.Id.GetOpaqueId() == ref.GetId().OpaqueId) ||
+			(ref.GetToken() != "" && candidate.Token == ref.GetToken()) {
+			ps = candidate
+			break
+		}
+	}
+	if ps == nil {
+		return status.NewNotFound(ctx, "public share not found")
+	}
+
+	if err := s.sm.RevokePublicShare(ctx, &userpb.User{Id: ps.Owner}, ref); err != nil {
+		return status.NewInternal(ctx, err, "error deleting public share")
+	}
+
+	appctx.GetLogger(ctx).Info().Str("share", ps.Id.GetOpaqueId()).Str("admin", user.ContextMustGetUser(ctx).Username).
+		Msg("publicshareprovider: admin removed public share")
+	return status.NewOK(ctx)
+}
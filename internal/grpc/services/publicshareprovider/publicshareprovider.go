@@ -21,14 +21,18 @@ package publicshareprovider
 import (
 	"context"
 
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/passwordpolicy"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -42,6 +46,12 @@ func init() {
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// Admins are the usernames of the users allowed to list and remove the
+	// public shares of all the users.
+	Admins []string `mapstructure:"admins"`
+	// PasswordPolicy holds the rules the passwords of the links have to
+	// follow, see pkg/passwordpolicy.
+	PasswordPolicy map[string]interface{} `mapstructure:"password_policy"`
 }
 
 func (c *config) init() {
@@ -51,8 +61,10 @@ func (c *config) init() {
 }
 
 type service struct {
-	conf *config
-	sm   publicshare.Manager
+	conf      *config
+	sm        publicshare.Manager
+	admins    map[string]bool
+	passwords *passwordpolicy.Policy
 }
 
 func getShareManager(c *config) (publicshare.Manager, error) {
@@ -98,9 +110,19 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		return nil, err
 	}
 
+	passwords, err := passwordpolicy.New(c.PasswordPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	service := &service{
-		conf: c,
-		sm:   sm,
+		conf:      c,
+		sm:        sm,
+		admins:    map[string]bool{},
+		passwords: passwords,
+	}
+	for _, a := range c.Admins {
+		service.admins[a] = true
 	}
 
 	return service, nil
@@ -115,22 +137,116 @@ func (s *service) CreatePublicShare(ctx context.Context, req *link.CreatePublicS
 		log.Error().Msg("error getting user from context")
 	}
 
+	opts, err := s.checkUploadOptions(req.Opaque, req.Grant.GetPermissions().GetPermissions())
+	if err != nil {
+		return &link.CreatePublicShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error reading upload options", err),
+		}, nil
+	}
+	max, setMax, err := s.checkMaxDownloads(req.Opaque)
+	if err != nil {
+		return &link.CreatePublicShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error reading max downloads", err),
+		}, nil
+	}
+	if st, o := s.checkPassword(ctx, req.Grant.GetPassword()); st != nil {
+		return &link.CreatePublicShareResponse{Status: st, Opaque: o}, nil
+	}
+
 	share, err := s.sm.CreatePublicShare(ctx, u, req.ResourceInfo, req.Grant)
 	if err != nil {
 		log.Debug().Err(err).Str("createShare", "shares").Msg("error connecting to storage provider")
 	}
 
+	if share != nil && opts != nil {
+		if err := s.sm.(publicshare.UploadOptionsManager).SetUploadOptions(ctx, share.Id, opts); err != nil {
+			return &link.CreatePublicShareResponse{
+				Status: status.NewInternal(ctx, err, "error storing upload options"),
+			}, nil
+		}
+	}
+	if share != nil && setMax {
+		if err := s.sm.(publicshare.AccessStatsManager).SetMaxDownloads(ctx, share.Id, max); err != nil {
+			return &link.CreatePublicShareResponse{
+				Status: status.NewInternal(ctx, err, "error storing max downloads"),
+			}, nil
+		}
+	}
+
 	res := &link.CreatePublicShareResponse{
 		Status: status.NewOK(ctx),
 		Share:  share,
+		Opaque: s.accessStatsOpaque(ctx, s.uploadOptionsOpaque(ctx, share), false, share),
 	}
 	return res, nil
 }
 
+// checkPassword returns the status and opaque rejecting a password which
+// breaks the password policy, or nil. Empty passwords remove the protection
+// of the links and aren't checked.
+func (s *service) checkPassword(ctx context.Context, password string) (*rpc.Status, *types.Opaque) {
+	if password == "" {
+		return nil, nil
+	}
+	err := s.passwords.Validate(ctx, "password", password)
+	if err == nil {
+		return nil, nil
+	}
+	o, eerr := passwordpolicy.EncodeViolations(nil, err.(*passwordpolicy.Error))
+	if eerr != nil {
+		return status.NewInternal(ctx, eerr, "error encoding password policy violations"), nil
+	}
+	return status.NewInvalidArg(ctx, err.Error()), o
+}
+
+// checkUploadOptions reads the upload options carried by a request, which
+// can only be set on upload only links and if the driver can store them.
+func (s *service) checkUploadOptions(o *types.Opaque, perms *provider.ResourcePermissions) (*publicshare.UploadOptions, error) {
+	opts, err := publicshare.DecodeUploadOptions(o)
+	if err != nil || opts == nil {
+		return nil, err
+	}
+	if _, ok := s.sm.(publicshare.UploadOptionsManager); !ok {
+		return nil, errtypes.NotSupported("publicshareprovider: driver " + s.conf.Driver + " does not support upload options")
+	}
+	if !publicshare.IsUploadOnly(perms) {
+		return nil, errtypes.BadRequest("publicshareprovider: upload options can only be set on upload only links")
+	}
+	return opts, nil
+}
+
+// uploadOptionsOpaque returns an opaque carrying the upload options of the
+// share, or nil if it has none.
+func (s *service) uploadOptionsOpaque(ctx context.Context, share *link.PublicShare) *types.Opaque {
+	m, ok := s.sm.(publicshare.UploadOptionsManager)
+	if !ok || share == nil || !publicshare.IsUploadOnly(share.GetPermissions().GetPermissions()) {
+		return nil
+	}
+	log := appctx.GetLogger(ctx)
+	opts, err := m.GetUploadOptions(ctx, share.Id)
+	if err != nil {
+		log.Error().Err(err).Str("share", share.Id.GetOpaqueId()).Msg("error getting upload options")
+		return nil
+	}
+	if opts == nil {
+		return nil
+	}
+	o, err := publicshare.EncodeUploadOptions(nil, opts)
+	if err != nil {
+		log.Error().Err(err).Str("share", share.Id.GetOpaqueId()).Msg("error encoding upload options")
+		return nil
+	}
+	return o
+}
+
 func (s *service) RemovePublicShare(ctx context.Context, req *link.RemovePublicShareRequest) (*link.RemovePublicShareResponse, error) {
 	log := appctx.GetLogger(ctx)
 	log.Info().Str("publicshareprovider", "remove").Msg("remove public share")
 
+	if share.IsAdmin(req.Opaque) {
+		return &link.RemovePublicShareResponse{Status: s.adminRemovePublicShare(ctx, req.Ref)}, nil
+	}
+
 	user := user.ContextMustGetUser(ctx)
 	err := s.sm.RevokePublicShare(ctx, user, req.Ref)
 	if err != nil {
@@ -149,11 +265,15 @@ func (s *service) GetPublicShareByToken(ctx context.Context, req *link.GetPublic
 
 	// there are 2 passes here, and the second request has no password
 	found, err := s.sm.GetPublicShareByToken(ctx, req.GetToken(), req.GetAuthentication(), req.GetSign())
+	if err == nil {
+		err = s.checkAccess(ctx, found, nil)
+	}
 	switch v := err.(type) {
 	case nil:
 		return &link.GetPublicShareByTokenResponse{
 			Status: status.NewOK(ctx),
 			Share:  found,
+			Opaque: s.uploadOptionsOpaque(ctx, found),
 		}, nil
 	case errtypes.InvalidCredentials:
 		return &link.GetPublicShareByTokenResponse{
@@ -183,16 +303,29 @@ func (s *service) GetPublicShare(ctx context.Context, req *link.GetPublicShareRe
 	if err != nil {
 		return nil, err
 	}
+	if req.Ref.GetToken() != "" {
+		if err := s.checkAccess(ctx, found, req.Opaque); err != nil {
+			return &link.GetPublicShareResponse{
+				Status: status.NewStatusFromErrType(ctx, "error accessing public share", err),
+			}, nil
+		}
+	}
 
 	return &link.GetPublicShareResponse{
 		Status: status.NewOK(ctx),
 		Share:  found,
+		Opaque: s.accessStatsOpaque(ctx, s.uploadOptionsOpaque(ctx, found), false, found),
 	}, nil
 }
 
 func (s *service) ListPublicShares(ctx context.Context, req *link.ListPublicSharesRequest) (*link.ListPublicSharesResponse, error) {
 	log := appctx.GetLogger(ctx)
 	log.Info().Str("publicshareprovider", "list").Msg("list public share")
+
+	if share.IsAdmin(req.Opaque) {
+		return s.adminListPublicShares(ctx, req), nil
+	}
+
 	user, _ := user.ContextGetUser(ctx)
 
 	shares, err := s.sm.ListPublicShares(ctx, user, req.Filters, &provider.ResourceInfo{}, req.GetSign())
@@ -206,6 +339,7 @@ func (s *service) ListPublicShares(ctx context.Context, req *link.ListPublicShar
 	res := &link.ListPublicSharesResponse{
 		Status: status.NewOK(ctx),
 		Share:  shares,
+		Opaque: s.accessStatsOpaque(ctx, nil, false, shares...),
 	}
 	return res, nil
 }
@@ -219,14 +353,62 @@ func (s *service) UpdatePublicShare(ctx context.Context, req *link.UpdatePublicS
 		log.Error().Msg("error getting user from context")
 	}
 
-	updateR, err := s.sm.UpdatePublicShare(ctx, u, req, nil)
-	if err != nil {
-		log.Err(err).Msgf("error updating public shares: %v", err)
+	if req.GetUpdate().GetType() == link.UpdatePublicShareRequest_Update_TYPE_PASSWORD {
+		if st, o := s.checkPassword(ctx, req.Update.GetGrant().GetPassword()); st != nil {
+			return &link.UpdatePublicShareResponse{Status: st, Opaque: o}, nil
+		}
+	}
+
+	var updateR *link.PublicShare
+	var err error
+	if req.GetUpdate().GetType() != link.UpdatePublicShareRequest_Update_TYPE_INVALID {
+		updateR, err = s.sm.UpdatePublicShare(ctx, u, req, nil)
+		if err != nil {
+			log.Err(err).Msgf("error updating public shares: %v", err)
+		}
+	} else {
+		// only the upload options or the download limit are updated
+		updateR, err = s.sm.GetPublicShare(ctx, u, req.Ref, false)
+		if err != nil {
+			return &link.UpdatePublicShareResponse{
+				Status: status.NewNotFound(ctx, "public share not found"),
+			}, nil
+		}
+	}
+
+	if updateR != nil {
+		opts, err := s.checkUploadOptions(req.Opaque, updateR.GetPermissions().GetPermissions())
+		if err != nil {
+			return &link.UpdatePublicShareResponse{
+				Status: status.NewStatusFromErrType(ctx, "error reading upload options", err),
+			}, nil
+		}
+		if opts != nil {
+			if err := s.sm.(publicshare.UploadOptionsManager).SetUploadOptions(ctx, updateR.Id, opts); err != nil {
+				return &link.UpdatePublicShareResponse{
+					Status: status.NewInternal(ctx, err, "error storing upload options"),
+				}, nil
+			}
+		}
+		max, setMax, err := s.checkMaxDownloads(req.Opaque)
+		if err != nil {
+			return &link.UpdatePublicShareResponse{
+				Status: status.NewStatusFromErrType(ctx, "error reading max downloads", err),
+			}, nil
+		}
+		if setMax {
+			if err := s.sm.(publicshare.AccessStatsManager).SetMaxDownloads(ctx, updateR.Id, max); err != nil {
+				return &link.UpdatePublicShareResponse{
+					Status: status.NewInternal(ctx, err, "error storing max downloads"),
+				}, nil
+			}
+		}
 	}
 
 	res := &link.UpdatePublicShareResponse{
 		Status: status.NewOK(ctx),
 		Share:  updateR,
+		Opaque: s.accessStatsOpaque(ctx, s.uploadOptionsOpaque(ctx, updateR), false, updateR),
 	}
 	return res, nil
 }
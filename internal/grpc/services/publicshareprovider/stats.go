@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshareprovider
+
+import (
+	"context"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// checkMaxDownloads reads the download limit carried by a request, which can
+// only be set if the driver keeps the statistics of the links.
+func (s *service) checkMaxDownloads(o *types.Opaque) (uint64, bool, error) {
+	max, ok, err := publicshare.DecodeMaxDownloads(o)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if _, ok := s.sm.(publicshare.AccessStatsManager); !ok {
+		return 0, false, errtypes.NotSupported("publicshareprovider: driver " + s.conf.Driver + " does not support download limits")
+	}
+	return max, true, nil
+}
+
+// accessStats returns the statistics of the share, or nil if the driver does
+// not keep them.
+func (s *service) accessStats(ctx context.Context, share *link.PublicShare) *publicshare.AccessStats {
+	m, ok := s.sm.(publicshare.AccessStatsManager)
+	if !ok || share == nil {
+		return nil
+	}
+	stats, err := m.GetAccessStats(ctx, share.Id)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("share", share.Id.GetOpaqueId()).Msg("error getting access stats")
+		return nil
+	}
+	return stats
+}
+
+// accessStatsOpaque adds to the opaque the statistics of the shares the user
+// owns or created, of all of them for the admins, and returns it.
+func (s *service) accessStatsOpaque(ctx context.Context, o *types.Opaque, admin bool, shares ...*link.PublicShare) *types.Opaque {
+	if _, ok := s.sm.(publicshare.AccessStatsManager); !ok || len(shares) == 0 {
+		return o
+	}
+	u, _ := user.ContextGetUser(ctx)
+
+	stats := map[string]*publicshare.AccessStats{}
+	for _, ps := range shares {
+		if ps == nil || !admin && !utils.UserEqual(u.GetId(), ps.Owner) && !utils.UserEqual(u.GetId(), ps.Creator) {
+			continue
+		}
+		if st := s.accessStats(ctx, ps); st != nil {
+			stats[ps.Id.GetOpaqueId()] = st
+		}
+	}
+	if len(stats) == 0 {
+		return o
+	}
+
+	enc, err := publicshare.EncodeAccessStats(o, stats)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("error encoding access stats")
+		return o
+	}
+	return enc
+}
+
+// checkAccess returns NotFound if the share reached its download limit, and
+// records the access to it carried by the request otherwise.
+func (s *service) checkAccess(ctx context.Context, share *link.PublicShare, o *types.Opaque) error {
+	a, err := publicshare.DecodeAccess(o)
+	if err != nil {
+		return err
+	}
+	if s.accessStats(ctx, share).Exhausted() {
+		return errtypes.NotFound("publicshareprovider: download limit reached")
+	}
+	if a == nil {
+		return nil
+	}
+	m, ok := s.sm.(publicshare.AccessStatsManager)
+	if !ok {
+		return nil
+	}
+	_, err = m.RecordAccess(ctx, share.Id, a)
+	return err
+}
@@ -21,7 +21,9 @@ package publicstorageprovider
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"path"
+	"strconv"
 	"strings"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -31,9 +33,11 @@ import (
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
@@ -111,7 +115,7 @@ func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.Unse
 
 func (s *service) InitiateFileDownload(ctx context.Context, req *provider.InitiateFileDownloadRequest) (*provider.InitiateFileDownloadResponse, error) {
 	statReq := &provider.StatRequest{Ref: req.Ref}
-	statRes, err := s.Stat(ctx, statReq)
+	statRes, err := s.stat(ctx, statReq)
 	if err != nil {
 		return &provider.InitiateFileDownloadResponse{
 			Status: status.NewInternal(ctx, err, "gateway: error stating ref:"+req.Ref.String()),
@@ -130,7 +134,41 @@ func (s *service) InitiateFileDownload(ctx context.Context, req *provider.Initia
 	}
 
 	req.Opaque = statRes.Info.Opaque
-	return s.initiateFileDownload(ctx, req)
+	res, err := s.initiateFileDownload(ctx, req)
+	if err == nil && res.Status.Code == rpc.Code_CODE_OK {
+		s.recordAccess(ctx, req.Ref, &publicshare.Access{Download: true, Bytes: statRes.Info.Size})
+	}
+	return res, err
+}
+
+// recordAccess records an access to the link the reference points into,
+// the failures being logged only.
+func (s *service) recordAccess(ctx context.Context, ref *provider.Reference, a *publicshare.Access) {
+	log := appctx.GetLogger(ctx)
+	tkn, _, err := s.unwrap(ctx, ref)
+	if err != nil {
+		log.Debug().Err(err).Msg("publicstorageprovider: error reading token")
+		return
+	}
+	o, err := publicshare.EncodeAccess(nil, a)
+	if err != nil {
+		log.Debug().Err(err).Msg("publicstorageprovider: error encoding access")
+		return
+	}
+	res, err := s.gateway.GetPublicShare(ctx, &link.GetPublicShareRequest{
+		Opaque: o,
+		Ref: &link.PublicShareReference{
+			Spec: &link.PublicShareReference_Token{
+				Token: tkn,
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		log.Debug().Err(err).Msg("publicstorageprovider: error recording access")
+	case res.Status.Code != rpc.Code_CODE_OK:
+		log.Debug().Str("status", res.Status.Message).Msg("publicstorageprovider: error recording access")
+	}
 }
 
 func (s *service) translatePublicRefToCS3Ref(ctx context.Context, ref *provider.Reference) (*provider.Reference, string, *link.PublicShare, *rpc.Status, error) {
@@ -222,7 +260,7 @@ func (s *service) initiateFileDownload(ctx context.Context, req *provider.Initia
 }
 
 func (s *service) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*provider.InitiateFileUploadResponse, error) {
-	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	cs3Ref, tkn, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
 	switch {
 	case err != nil:
 		return nil, err
@@ -235,6 +273,20 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			Status: status.NewPermissionDenied(ctx, nil, "share does not grant InitiateFileUpload permission"),
 		}, nil
 	}
+
+	if publicshare.IsUploadOnly(ls.GetPermissions().GetPermissions()) {
+		cs3Ref, st, err = s.prepareFileDrop(ctx, tkn, cs3Ref, req.Opaque)
+		switch {
+		case err != nil:
+			return &provider.InitiateFileUploadResponse{
+				Status: status.NewInternal(ctx, err, "error preparing the upload to the file drop"),
+			}, nil
+		case st != nil:
+			return &provider.InitiateFileUploadResponse{
+				Status: st,
+			}, nil
+		}
+	}
 	uReq := &provider.InitiateFileUploadRequest{
 		Ref:    cs3Ref,
 		Opaque: req.Opaque,
@@ -277,6 +329,80 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 	return res, nil
 }
 
+// maxFileDropRenames is the number of names tried for a file uploaded to a
+// file drop already holding a file with the same name.
+const maxFileDropRenames = 100
+
+// prepareFileDrop checks an upload to a file drop against the upload options
+// of the link and returns the reference to upload to. Files already in the
+// file drop are never overwritten, the upload is given a new name instead.
+// The maximum size is checked against the declared length, which the data
+// providers do not let the uploads exceed.
+func (s *service) prepareFileDrop(ctx context.Context, tkn string, ref *provider.Reference, o *typesv1beta1.Opaque) (*provider.Reference, *rpc.Status, error) {
+	opts, st, err := s.getUploadOptions(ctx, tkn)
+	if err != nil || st != nil {
+		return nil, st, err
+	}
+
+	if opts != nil && opts.MaxFileSize > 0 {
+		e, ok := o.GetMap()["Upload-Length"]
+		if !ok {
+			return nil, status.NewInvalidArg(ctx, "the upload length is required by the file drop"), nil
+		}
+		length, err := strconv.ParseUint(string(e.Value), 10, 64)
+		if err != nil {
+			return nil, status.NewInvalidArg(ctx, "invalid upload length"), nil
+		}
+		if length > opts.MaxFileSize {
+			return nil, status.NewInvalidArg(ctx, fmt.Sprintf("the file exceeds the maximum size of %d bytes of the file drop", opts.MaxFileSize)), nil
+		}
+	}
+
+	fn := ref.GetPath()
+	dir, base := path.Split(fn)
+	ext := path.Ext(base)
+	for i := 1; i <= maxFileDropRenames; i++ {
+		if i > 1 {
+			fn = path.Join(dir, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), i, ext))
+		}
+		sRes, err := s.gateway.Stat(ctx, &provider.StatRequest{
+			Ref: &provider.Reference{
+				Spec: &provider.Reference_Path{Path: fn},
+			},
+		})
+		switch {
+		case err != nil:
+			return nil, nil, err
+		case sRes.Status.Code == rpc.Code_CODE_NOT_FOUND:
+			return &provider.Reference{
+				Spec: &provider.Reference_Path{Path: fn},
+			}, nil, nil
+		case sRes.Status.Code != rpc.Code_CODE_OK:
+			return nil, sRes.Status, nil
+		}
+	}
+	return nil, status.NewAlreadyExists(ctx, nil, "too many files with the same name in the file drop"), nil
+}
+
+// getUploadOptions returns the upload options of the link with the given token.
+func (s *service) getUploadOptions(ctx context.Context, tkn string) (*publicshare.UploadOptions, *rpc.Status, error) {
+	res, err := s.gateway.GetPublicShare(ctx, &link.GetPublicShareRequest{
+		Ref: &link.PublicShareReference{
+			Spec: &link.PublicShareReference_Token{
+				Token: tkn,
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil, res.Status, nil
+	}
+	opts, err := publicshare.DecodeUploadOptions(res.Opaque)
+	return opts, nil, err
+}
+
 func (s *service) GetPath(ctx context.Context, req *provider.GetPathRequest) (*provider.GetPathResponse, error) {
 	return nil, gstatus.Errorf(codes.Unimplemented, "method not implemented")
 }
@@ -392,6 +518,8 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 		trace.StringAttribute("destination", req.Destination.String()),
 	)
 
+	isCopy := req.Opaque != nil && req.Opaque.Map[storage.CopyOpaqueKey] != nil
+
 	cs3RefSource, tknSource, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Source)
 	switch {
 	case err != nil:
@@ -400,7 +528,11 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 		return &provider.MoveResponse{
 			Status: st,
 		}, nil
-	case ls.GetPermissions() == nil || !ls.GetPermissions().Permissions.Move:
+	case isCopy && (ls.GetPermissions() == nil || !ls.GetPermissions().Permissions.InitiateFileUpload):
+		return &provider.MoveResponse{
+			Status: status.NewPermissionDenied(ctx, nil, "share does not grant InitiateFileUpload permission"),
+		}, nil
+	case !isCopy && (ls.GetPermissions() == nil || !ls.GetPermissions().Permissions.Move):
 		return &provider.MoveResponse{
 			Status: status.NewPermissionDenied(ctx, nil, "share does not grant Move permission"),
 		}, nil
@@ -425,6 +557,7 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 	var res *provider.MoveResponse
 	// the call has to be made to the gateway instead of the storage.
 	res, err = s.gateway.Move(ctx, &provider.MoveRequest{
+		Opaque:      req.Opaque,
 		Source:      cs3RefSource,
 		Destination: cs3RefDestination,
 	})
@@ -441,6 +574,17 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 }
 
 func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	res, err := s.stat(ctx, req)
+	if err == nil && res.Status.Code == rpc.Code_CODE_OK {
+		// opening the link stats its root
+		if _, relativePath, err := s.unwrap(ctx, req.Ref); err == nil && strings.Trim(relativePath, "/") == "" {
+			s.recordAccess(ctx, req.Ref, &publicshare.Access{})
+		}
+	}
+	return res, err
+}
+
+func (s *service) stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
 	ctx, span := trace.StartSpan(ctx, "Stat")
 	defer span.End()
 
@@ -461,6 +605,8 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 		return &provider.StatResponse{
 			Status: st,
 		}, nil
+	case publicshare.IsUploadOnly(ls.GetPermissions().GetPermissions()):
+		return s.statFileDrop(ctx, tkn, relativePath, ls, ri), nil
 	case ls.GetPermissions() == nil || !ls.GetPermissions().Permissions.Stat:
 		return &provider.StatResponse{
 			Status: status.NewPermissionDenied(ctx, nil, "share does not grant Stat permission"),
@@ -498,6 +644,32 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 	return statResponse, nil
 }
 
+// statFileDrop stats a file drop: only its root can be stated, leaving out
+// anything revealing its contents, the files in it are never found.
+func (s *service) statFileDrop(ctx context.Context, tkn, relativePath string, ls *link.PublicShare, ri *provider.ResourceInfo) *provider.StatResponse {
+	if relativePath != "" {
+		return &provider.StatResponse{
+			Status: status.NewNotFound(ctx, "the contents of a file drop are not revealed"),
+		}
+	}
+	info := &provider.ResourceInfo{
+		Type:     ri.Type,
+		Id:       ri.Id,
+		MimeType: ri.MimeType,
+		Path:     path.Join(s.mountPath, "/", tkn),
+		PermissionSet: &provider.ResourcePermissions{
+			InitiateFileUpload: ri.GetPermissionSet().GetInitiateFileUpload(),
+		},
+	}
+	if err := addShare(info, ls); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Interface("share", ls).Interface("info", info).Msg("error when adding share")
+	}
+	return &provider.StatResponse{
+		Status: status.NewOK(ctx),
+		Info:   info,
+	}
+}
+
 func addShare(i *provider.ResourceInfo, ls *link.PublicShare) error {
 	if i.Opaque == nil {
 		i.Opaque = &typesv1beta1.Opaque{}
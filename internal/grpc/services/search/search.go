@@ -0,0 +1,255 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/registry"
+	searchpb "github.com/cs3org/reva/pkg/search/proto"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	rgrpc.Register("search", New)
+}
+
+type config struct {
+	Driver     string                            `mapstructure:"driver" docs:"bleve;The search index to be used."`
+	Drivers    map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/search/index/bleve/bleve.go;The configuration for the search indexes."`
+	GatewaySvc string                            `mapstructure:"gatewaysvc"`
+	MaxHits    int                               `mapstructure:"max_hits" docs:"1000;The maximum number of index hits checked for permissions per search."`
+	PageSize   int                               `mapstructure:"page_size" docs:"50;The number of results returned when the request has no limit."`
+}
+
+func (c *config) init() {
+	if c.Driver == "" {
+		c.Driver = "bleve"
+	}
+	if c.MaxHits == 0 {
+		c.MaxHits = 1000
+	}
+	if c.PageSize == 0 {
+		c.PageSize = 50
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type service struct {
+	conf  *config
+	index search.Index
+}
+
+func (s *service) Register(ss *grpc.Server) {
+	searchpb.RegisterSearchServiceServer(ss, s)
+}
+
+func getIndex(c *config) (search.Index, error) {
+	if f, ok := registry.NewFuncs[c.Driver]; ok {
+		return f(c.Drivers[c.Driver])
+	}
+	return nil, errtypes.NotFound("driver not found: " + c.Driver)
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New creates a new search service. The index is fed by the storages through
+// IndexResource and RemoveResource, see the search storage wrapper.
+func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+
+	index, err := getIndex(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service{
+		conf:  c,
+		index: index,
+	}, nil
+}
+
+func (s *service) Close() error {
+	if c, ok := s.index.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (s *service) UnprotectedEndpoints() []string {
+	return []string{}
+}
+
+// Search looks the query up in the index and returns the hits the user can stat,
+// which limits the results to the spaces and shares the user has access to.
+func (s *service) Search(ctx context.Context, req *searchpb.SearchRequest) (*searchpb.SearchResponse, error) {
+	log := appctx.GetLogger(ctx)
+
+	q, err := search.ParseQuery(req.Query)
+	if err != nil {
+		return &searchpb.SearchResponse{
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		}, nil
+	}
+
+	ids, err := s.index.Search(ctx, q, s.conf.MaxHits)
+	if err != nil {
+		return &searchpb.SearchResponse{
+			Status: status.NewInternal(ctx, err, "error searching index"),
+		}, nil
+	}
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return &searchpb.SearchResponse{
+			Status: status.NewInternal(ctx, err, "error getting gateway client"),
+		}, nil
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = s.conf.PageSize
+	}
+	scope := path.Clean(req.Scope)
+
+	infos := []*provider.ResourceInfo{}
+	var total int32
+	for _, id := range ids {
+		res, err := client.Stat(ctx, &provider.StatRequest{
+			Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: id}},
+		})
+		if err != nil {
+			return &searchpb.SearchResponse{
+				Status: status.NewInternal(ctx, err, "error stating search hit"),
+			}, nil
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			// deleted meanwhile or not accessible by the user
+			log.Debug().Interface("id", id).Interface("status", res.Status).Msg("search: skipping hit")
+			continue
+		}
+		if req.Scope != "" && scope != "/" && res.Info.Path != scope && !strings.HasPrefix(res.Info.Path, scope+"/") {
+			continue
+		}
+		if int(total) >= int(req.Offset) && len(infos) < limit {
+			infos = append(infos, res.Info)
+		}
+		total++
+	}
+
+	return &searchpb.SearchResponse{
+		Status: status.NewOK(ctx),
+		Infos:  infos,
+		Total:  total,
+	}, nil
+}
+
+func (s *service) IndexResource(ctx context.Context, req *searchpb.IndexResourceRequest) (*searchpb.IndexResourceResponse, error) {
+	if req.Info.GetId().GetOpaqueId() == "" {
+		return &searchpb.IndexResourceResponse{
+			Status: status.NewInvalidArg(ctx, "resource id missing"),
+		}, nil
+	}
+	// the callers are the storages acting for the users who changed the
+	// resource, which they have to be able to stat
+	res, err := s.stat(ctx, req.Info.Id)
+	if err != nil {
+		return &searchpb.IndexResourceResponse{
+			Status: status.NewInternal(ctx, err, "error stating resource"),
+		}, nil
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return &searchpb.IndexResourceResponse{
+			Status: status.NewPermissionDenied(ctx, nil, "resource not accessible"),
+		}, nil
+	}
+	if err := s.index.Add(ctx, search.NewDocument(req.Info, req.Content)); err != nil {
+		return &searchpb.IndexResourceResponse{
+			Status: status.NewInternal(ctx, err, "error indexing resource"),
+		}, nil
+	}
+	return &searchpb.IndexResourceResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+func (s *service) RemoveResource(ctx context.Context, req *searchpb.RemoveResourceRequest) (*searchpb.RemoveResourceResponse, error) {
+	if req.Id.GetOpaqueId() == "" {
+		return &searchpb.RemoveResourceResponse{
+			Status: status.NewInvalidArg(ctx, "resource id missing"),
+		}, nil
+	}
+	// the documents of the resources still existing are only replaced by
+	// IndexResource, removing them is for the deleted resources
+	res, err := s.stat(ctx, req.Id)
+	if err != nil {
+		return &searchpb.RemoveResourceResponse{
+			Status: status.NewInternal(ctx, err, "error stating resource"),
+		}, nil
+	}
+	if res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		return &searchpb.RemoveResourceResponse{
+			Status: status.NewPermissionDenied(ctx, nil, "resource still exists"),
+		}, nil
+	}
+	if err := s.index.Remove(ctx, req.Id); err != nil {
+		return &searchpb.RemoveResourceResponse{
+			Status: status.NewInternal(ctx, err, "error removing resource from index"),
+		}, nil
+	}
+	return &searchpb.RemoveResourceResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+// stat stats the resource through the gateway as the caller.
+func (s *service) stat(ctx context.Context, id *provider.ResourceId) (*provider.StatResponse, error) {
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return nil, err
+	}
+	return client.Stat(ctx, &provider.StatRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: id}},
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	changespb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// Subscribe streams the changes made to the referenced resource and below it,
+// notified by the drivers able to and found by walking the tree every
+// change_poll_interval seconds otherwise.
+func (s *service) Subscribe(req *changespb.SubscribeRequest, stream changespb.ChangeService_SubscribeServer) error {
+	ctx := stream.Context()
+	log := appctx.GetLogger(ctx)
+
+	ref, err := s.unwrap(ctx, req.Ref)
+	if err != nil {
+		return gstatus.Error(codes.InvalidArgument, "storageprovider: error unwrapping path: "+err.Error())
+	}
+	changes, err := storage.Watch(ctx, s.storage, ref, time.Duration(s.conf.ChangePollInterval)*time.Second)
+	if err != nil {
+		return watchError(err)
+	}
+
+	for ev := range changes {
+		n := &changespb.ChangeNotification{
+			Type:    ev.Type,
+			Path:    ev.Path,
+			OldPath: ev.OldPath,
+			Time:    &typespb.Timestamp{Seconds: uint64(ev.Time.Unix()), Nanos: uint32(ev.Time.Nanosecond())},
+		}
+		if ev.ID != nil {
+			n.Id = &provider.ResourceId{StorageId: ev.ID.StorageId, OpaqueId: ev.ID.OpaqueId}
+			if n.Id.StorageId == "" {
+				n.Id.StorageId = s.mountID
+			}
+		}
+		if err := stream.Send(n); err != nil {
+			log.Debug().Err(err).Msg("storageprovider: error sending change, closing subscription")
+			return err
+		}
+	}
+	// the watch ends with the context or when the watched resource is gone
+	return ctx.Err()
+}
+
+func watchError(err error) error {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		return gstatus.Error(codes.NotFound, err.Error())
+	case errtypes.IsPermissionDenied:
+		return gstatus.Error(codes.PermissionDenied, err.Error())
+	case errtypes.IsNotSupported:
+		return gstatus.Error(codes.Unimplemented, err.Error())
+	}
+	return gstatus.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,151 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	maintenancepb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const reloadSection = "grpc.services.storageprovider"
+
+// maintenance is the maintenance mode of the storage provider. It starts as
+// configured and is switched at runtime by a reload of the configuration or
+// by the maintenance admins.
+type maintenance struct {
+	mu         sync.RWMutex
+	enabled    bool
+	message    string
+	retryAfter time.Duration
+}
+
+func newMaintenance(c *config) *maintenance {
+	m := &maintenance{}
+	m.set(c.Maintenance, c.MaintenanceMessage, c.MaintenanceRetryAfter)
+	return m
+}
+
+func (m *maintenance) set(enabled bool, message string, retryAfter int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = enabled
+	m.message = message
+	m.retryAfter = time.Duration(retryAfter) * time.Second
+}
+
+func (m *maintenance) get() (enabled bool, message string, retryAfter time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.message, m.retryAfter
+}
+
+func (s *service) registerMaintenance(ss *grpc.Server) {
+	maintenancepb.RegisterMaintenanceServiceServer(ss, s)
+}
+
+// checkMaintenance returns the status rejecting an operation modifying the
+// storage while it is in maintenance, or nil.
+func (s *service) checkMaintenance(ctx context.Context) *rpc.Status {
+	enabled, message, retryAfter := s.maintenance.get()
+	if !enabled {
+		return nil
+	}
+	msg := "storage provider is in maintenance"
+	if message != "" {
+		msg += ": " + message
+	}
+	return status.NewUnavailable(ctx, nil, msg, retryAfter)
+}
+
+func (s *service) isMaintenanceAdmin(ctx context.Context) bool {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok {
+		return false
+	}
+	for _, a := range s.conf.MaintenanceAdmins {
+		if a == u.Username {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) SetMaintenance(ctx context.Context, req *maintenancepb.SetMaintenanceRequest) (*maintenancepb.SetMaintenanceResponse, error) {
+	if !s.isMaintenanceAdmin(ctx) {
+		err := errtypes.PermissionDenied("storageprovider: only the maintenance admins can set the maintenance mode")
+		return &maintenancepb.SetMaintenanceResponse{
+			Status: status.NewPermissionDenied(ctx, err, "only the maintenance admins can set the maintenance mode"),
+		}, nil
+	}
+	if req.RetryAfter < 0 {
+		return &maintenancepb.SetMaintenanceResponse{
+			Status: status.NewInvalidArg(ctx, "retry after must not be negative"),
+		}, nil
+	}
+
+	s.maintenance.set(req.Enabled, req.Message, int(req.RetryAfter))
+	appctx.GetLogger(ctx).Info().Bool("enabled", req.Enabled).Str("message", req.Message).Msg("storageprovider: maintenance mode set")
+	return &maintenancepb.SetMaintenanceResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (s *service) GetMaintenance(ctx context.Context, req *maintenancepb.GetMaintenanceRequest) (*maintenancepb.GetMaintenanceResponse, error) {
+	enabled, message, retryAfter := s.maintenance.get()
+	return &maintenancepb.GetMaintenanceResponse{
+		Status:     status.NewOK(ctx),
+		Enabled:    enabled,
+		Message:    message,
+		RetryAfter: int32(retryAfter / time.Second),
+	}, nil
+}
+
+// reload applies the maintenance settings of the new configuration. The other
+// settings only apply after a restart, so a configuration changing them is
+// refused as a whole.
+func (s *service) reload(m map[string]interface{}) error {
+	c, err := parseConfig(m)
+	if err != nil {
+		return err
+	}
+	c.init()
+	if c.MaintenanceRetryAfter < 0 {
+		return errors.New("storageprovider: maintenance_retry_after must not be negative")
+	}
+
+	rest := *c
+	rest.Maintenance = s.conf.Maintenance
+	rest.MaintenanceMessage = s.conf.MaintenanceMessage
+	rest.MaintenanceRetryAfter = s.conf.MaintenanceRetryAfter
+	if !reflect.DeepEqual(&rest, s.conf) {
+		return errors.New("storageprovider: only the maintenance settings can be reloaded")
+	}
+
+	s.maintenance.set(c.Maintenance, c.MaintenanceMessage, c.MaintenanceRetryAfter)
+	return nil
+}
@@ -27,17 +27,27 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	// link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	changespb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/health"
 	"github.com/cs3org/reva/pkg/mime"
+	svcregistry "github.com/cs3org/reva/pkg/registry"
+	"github.com/cs3org/reva/pkg/reload"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
@@ -49,15 +59,27 @@ func init() {
 }
 
 type config struct {
-	MountPath        string                            `mapstructure:"mount_path" docs:"/;The path where the file system would be mounted."`
-	MountID          string                            `mapstructure:"mount_id" docs:"-;The ID of the mounted file system."`
-	Driver           string                            `mapstructure:"driver" docs:"localhome;The storage driver to be used."`
-	Drivers          map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/storage/fs/localhome/localhome.go"`
-	TmpFolder        string                            `mapstructure:"tmp_folder" docs:"/var/tmp;Path to temporary folder."`
-	DataServerURL    string                            `mapstructure:"data_server_url" docs:"http://localhost/data;The URL for the data server."`
-	ExposeDataServer bool                              `mapstructure:"expose_data_server" docs:"false;Whether to expose data server."` // if true the client will be able to upload/download directly to it
-	AvailableXS      map[string]uint32                 `mapstructure:"available_checksums" docs:"nil;List of available checksums."`
-	MimeTypes        map[string]string                 `mapstructure:"mimetypes" docs:"nil;List of supported mime types and corresponding file extensions."`
+	MountPath             string                            `mapstructure:"mount_path" docs:"/;The path where the file system would be mounted."`
+	MountID               string                            `mapstructure:"mount_id" docs:"-;The ID of the mounted file system."`
+	Driver                string                            `mapstructure:"driver" docs:"localhome;The storage driver to be used."`
+	Drivers               map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/storage/fs/localhome/localhome.go"`
+	TmpFolder             string                            `mapstructure:"tmp_folder" docs:"/var/tmp;Path to temporary folder."`
+	DataServerURL         string                            `mapstructure:"data_server_url" docs:"http://localhost/data;The URL for the data server."`
+	DataServerService     string                            `mapstructure:"data_server_service" docs:"-;The name of the data servers in the service registry, one of which the transfers are sent to instead of the data_server_url."`
+	ExposeDataServer      bool                              `mapstructure:"expose_data_server" docs:"false;Whether to expose data server."` // if true the client will be able to upload/download directly to it
+	AvailableXS           map[string]uint32                 `mapstructure:"available_checksums" docs:"nil;List of available checksums."`
+	MimeTypes             map[string]string                 `mapstructure:"mimetypes" docs:"nil;List of supported mime types and corresponding file extensions."`
+	Wrappers              []string                          `mapstructure:"wrappers" docs:"nil;List of storage wrappers applied to the driver, the first one being the outermost."`
+	WrapperConfigs        map[string]map[string]interface{} `mapstructure:"wrapper_configs" docs:"url:pkg/storage/wrappers/readonly/readonly.go;The configuration for the storage wrappers"`
+	ListTimeout           int                               `mapstructure:"list_timeout" docs:"0;Milliseconds after which folder and recycle listings return the entries collected so far, flagged as truncated. 0 disables the timeout."`
+	EventStream           string                            `mapstructure:"event_stream" docs:"nil;The event stream the storage events are published to, none disables them."`
+	EventStreams          map[string]map[string]interface{} `mapstructure:"event_streams" docs:"url:pkg/events/nats/nats.go;The configuration for the event streams."`
+	QuotaAdmins           []string                          `mapstructure:"quota_admins" docs:"nil;The usernames of the users allowed to set the quotas."`
+	ChangePollInterval    int                               `mapstructure:"change_poll_interval" docs:"30;Seconds between the tree walks detecting the changes streamed to the subscribers when the driver doesn't notify them."`
+	Maintenance           bool                              `mapstructure:"maintenance" docs:"false;Whether the storage is in maintenance, rejecting the operations modifying it while the reads keep working. Can be reloaded at runtime."`
+	MaintenanceMessage    string                            `mapstructure:"maintenance_message" docs:"-;The reason of the maintenance told to the clients."`
+	MaintenanceRetryAfter int                               `mapstructure:"maintenance_retry_after" docs:"0;Seconds after which the clients are told to retry the rejected operations, 0 tells none."`
+	MaintenanceAdmins     []string                          `mapstructure:"maintenance_admins" docs:"nil;The usernames of the users allowed to set the maintenance mode at runtime."`
 }
 
 func (c *config) init() {
@@ -73,6 +95,10 @@ func (c *config) init() {
 		c.MountID = "00000000-0000-0000-0000-000000000000"
 	}
 
+	if c.ChangePollInterval == 0 {
+		c.ChangePollInterval = 30
+	}
+
 	if c.TmpFolder == "" {
 		c.TmpFolder = "/var/tmp/reva/tmp"
 	}
@@ -99,9 +125,16 @@ type service struct {
 	tmpFolder          string
 	dataServerURL      *url.URL
 	availableXS        []*provider.ResourceChecksumPriority
+	events             *events.Emitter
+	maintenance        *maintenance
 }
 
 func (s *service) Close() error {
+	if err := s.events.Close(); err != nil {
+		appctx.GetLogger(context.Background()).Error().Err(err).Msg("storageprovider: error closing event stream")
+	}
+	health.Unregister("storageprovider/" + s.mountID)
+	reload.Unregister(reloadSection)
 	return s.storage.Shutdown(context.Background())
 }
 
@@ -109,6 +142,8 @@ func (s *service) UnprotectedEndpoints() []string { return []string{} }
 
 func (s *service) Register(ss *grpc.Server) {
 	provider.RegisterProviderAPIServer(ss, s)
+	changespb.RegisterChangeServiceServer(ss, s)
+	s.registerMaintenance(ss)
 }
 
 func parseXSTypes(xsTypes map[string]uint32) ([]*provider.ResourceChecksumPriority, error) {
@@ -153,7 +188,11 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	mountPath := c.MountPath
 	mountID := c.MountID
 
-	fs, err := getFS(c)
+	driver, err := getFS(c)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := registry.Wrap(driver, c.Wrappers, c.WrapperConfigs)
 	if err != nil {
 		return nil, err
 	}
@@ -176,6 +215,11 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 
 	registerMimeTypes(c.MimeTypes)
 
+	emitter, err := eventsregistry.NewEmitter(c.EventStream, c.EventStreams)
+	if err != nil {
+		return nil, err
+	}
+
 	service := &service{
 		conf:          c,
 		storage:       fs,
@@ -184,6 +228,12 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		mountID:       mountID,
 		dataServerURL: u,
 		availableXS:   xsTypes,
+		events:        emitter,
+		maintenance:   newMaintenance(c),
+	}
+	reload.Register(reloadSection, service.reload)
+	if c, ok := driver.(health.Checker); ok {
+		health.Register("storageprovider/"+mountID, c.Check)
 	}
 
 	return service, nil
@@ -196,6 +246,28 @@ func registerMimeTypes(mimes map[string]string) {
 }
 
 func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArbitraryMetadataRequest) (*provider.SetArbitraryMetadataResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.SetArbitraryMetadataResponse{Status: st}, nil
+	}
+	if maxBytes, ok, err := storage.DecodeQuota(req.ArbitraryMetadata); ok {
+		if err != nil {
+			return &provider.SetArbitraryMetadataResponse{
+				Status: status.NewInvalidArg(ctx, err.Error()),
+			}, nil
+		}
+		return s.setQuota(ctx, req.Ref, maxBytes)
+	}
+
+	var changes []*storage.MetadataChange
+	if ok, err := storage.DecodeMetadataBatch(req.Opaque, &changes); ok {
+		if err != nil {
+			return &provider.SetArbitraryMetadataResponse{
+				Status: status.NewInvalidArg(ctx, "error decoding metadata batch: "+err.Error()),
+			}, nil
+		}
+		return s.setArbitraryMetadataBatch(ctx, changes)
+	}
+
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		err := errors.Wrap(err, "storageprovidersvc: error unwrapping path")
@@ -211,6 +283,12 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 			st = status.NewNotFound(ctx, "path not found when setting arbitrary metadata")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
+		case errtypes.IsInsufficientStorage:
+			st = status.NewInsufficientStorage(ctx, err, "insufficient storage")
+		case errtypes.IsNotSupported:
+			st = status.NewUnimplemented(ctx, err, "not supported")
 		default:
 			st = status.NewInternal(ctx, err, "error setting arbitrary metadata: "+req.Ref.String())
 		}
@@ -225,7 +303,56 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 	return res, nil
 }
 
+func (s *service) setArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) (*provider.SetArbitraryMetadataResponse, error) {
+	results := make([]*storage.MetadataResult, len(changes))
+	unwrapped := make([]*storage.MetadataChange, 0, len(changes))
+	indexes := make([]int, 0, len(changes))
+	for i, c := range changes {
+		newRef, err := s.unwrap(ctx, c.Reference())
+		if err != nil {
+			results[i] = &storage.MetadataResult{Code: rpc.Code_CODE_INVALID_ARGUMENT, Message: err.Error()}
+			continue
+		}
+		unwrappedChange := *c
+		unwrappedChange.SetReference(newRef)
+		unwrapped = append(unwrapped, &unwrappedChange)
+		indexes = append(indexes, i)
+	}
+
+	for i, err := range storage.SetArbitraryMetadataBatch(ctx, s.storage, unwrapped) {
+		var st *rpc.Status
+		switch err.(type) {
+		case nil:
+			st = status.NewOK(ctx)
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "path not found when setting arbitrary metadata")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
+		default:
+			st = status.NewInternal(ctx, err, "error setting arbitrary metadata")
+		}
+		results[indexes[i]] = &storage.MetadataResult{Code: st.Code, Message: st.Message}
+	}
+
+	opaque, err := storage.EncodeMetadataBatch(results)
+	if err != nil {
+		return &provider.SetArbitraryMetadataResponse{
+			Status: status.NewInternal(ctx, err, "error encoding metadata batch results"),
+		}, nil
+	}
+
+	return &provider.SetArbitraryMetadataResponse{
+		Status: status.NewOK(ctx),
+		Opaque: opaque,
+	}, nil
+}
+
 func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.UnsetArbitraryMetadataRequest) (*provider.UnsetArbitraryMetadataResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.UnsetArbitraryMetadataResponse{Status: st}, nil
+	}
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		err := errors.Wrap(err, "storageprovidersvc: error unwrapping path")
@@ -241,6 +368,12 @@ func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.Unse
 			st = status.NewNotFound(ctx, "path not found when unsetting arbitrary metadata")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
+		case errtypes.IsInsufficientStorage:
+			st = status.NewInsufficientStorage(ctx, err, "insufficient storage")
+		case errtypes.IsNotSupported:
+			st = status.NewUnimplemented(ctx, err, "not supported")
 		default:
 			st = status.NewInternal(ctx, err, "error unsetting arbitrary metadata: "+req.Ref.String())
 		}
@@ -262,7 +395,12 @@ func (s *service) InitiateFileDownload(ctx context.Context, req *provider.Initia
 	// For example, https://data-server.example.org/home/docs/myfile.txt
 	// or ownclouds://data-server.example.org/home/docs/myfile.txt
 	log := appctx.GetLogger(ctx)
-	u := *s.dataServerURL
+	u, err := s.dataServer()
+	if err != nil {
+		return &provider.InitiateFileDownloadResponse{
+			Status: status.NewUnavailable(ctx, err, "no data server available", 0),
+		}, nil
+	}
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.InitiateFileDownloadResponse{
@@ -273,6 +411,10 @@ func (s *service) InitiateFileDownload(ctx context.Context, req *provider.Initia
 	// Currently, we only support the simple protocol for GET requests
 	// Once we have multiple protocols, this would be moved to the fs layer
 	u.Path = path.Join(u.Path, "simple", newRef.GetPath())
+	if rev := storage.Revision(req.Opaque); rev != "" {
+		// the revision is part of the signed download location
+		u.RawQuery = url.Values{storage.RevisionOpaqueKey: []string{rev}}.Encode()
+	}
 
 	log.Info().Str("data-server", u.String()).Str("fn", req.Ref.GetPath()).Msg("file download")
 	res := &provider.InitiateFileDownloadResponse{
@@ -288,7 +430,65 @@ func (s *service) InitiateFileDownload(ctx context.Context, req *provider.Initia
 	return res, nil
 }
 
+// setQuota sets the quota of the space or home the reference points to, for
+// the quota admins only.
+func (s *service) setQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) (*provider.SetArbitraryMetadataResponse, error) {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok || !s.isQuotaAdmin(u.Username) {
+		err := errtypes.PermissionDenied("storageprovider: only quota admins can set quotas")
+		return &provider.SetArbitraryMetadataResponse{
+			Status: status.NewPermissionDenied(ctx, err, "only quota admins can set quotas"),
+		}, nil
+	}
+	newRef, err := s.unwrap(ctx, ref)
+	if err != nil {
+		return &provider.SetArbitraryMetadataResponse{
+			Status: status.NewInternal(ctx, err, "error unwrapping path"),
+		}, nil
+	}
+	if err := storage.SetQuota(ctx, s.storage, newRef, maxBytes); err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "path not found when setting quota")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsBadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		case errtypes.IsNotSupported:
+			st = status.NewUnimplemented(ctx, err, "setting quotas is not supported")
+		default:
+			st = status.NewInternal(ctx, err, "error setting quota: "+ref.String())
+		}
+		return &provider.SetArbitraryMetadataResponse{
+			Status: st,
+		}, nil
+	}
+
+	s.events.Emit(ctx, &events.QuotaSet{
+		Executant:  u.Id,
+		Path:       ref.GetPath(),
+		ResourceID: ref.GetId(),
+		MaxBytes:   maxBytes,
+	})
+	return &provider.SetArbitraryMetadataResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+func (s *service) isQuotaAdmin(username string) bool {
+	for _, a := range s.conf.QuotaAdmins {
+		if a == username {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *service) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*provider.InitiateFileUploadResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.InitiateFileUploadResponse{Status: st}, nil
+	}
 	// TODO(labkode): same considerations as download
 	log := appctx.GetLogger(ctx)
 	newRef, err := s.unwrap(ctx, req.Ref)
@@ -323,6 +523,10 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 		if req.Opaque.Map["X-OC-Mtime"] != nil {
 			metadata["mtime"] = string(req.Opaque.Map["X-OC-Mtime"].Value)
 		}
+		// TUS concatenation, partial uploads are assembled by the data provider
+		if req.Opaque.Map["Upload-Concat"] != nil {
+			metadata["concat"] = string(req.Opaque.Map["Upload-Concat"].Value)
+		}
 	}
 	uploadIDs, err := s.storage.InitiateUpload(ctx, newRef, uploadLength, metadata)
 	if err != nil {
@@ -345,6 +549,16 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
 		case errtypes.InsufficientStorage:
 			st = status.NewInsufficientStorage(ctx, err, "insufficient storage")
+			ev := &events.QuotaExceeded{Path: req.Ref.GetPath(), ResourceID: req.Ref.GetId()}
+			if uploadLength > 0 {
+				ev.UploadLength = uint64(uploadLength)
+			}
+			if u, ok := user.ContextGetUser(ctx); ok {
+				ev.Executant = u.Id
+			}
+			s.events.Emit(ctx, ev)
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error getting upload id: "+req.Ref.String())
 		}
@@ -353,10 +567,16 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 		}, nil
 	}
 
+	dataServer, err := s.dataServer()
+	if err != nil {
+		return &provider.InitiateFileUploadResponse{
+			Status: status.NewUnavailable(ctx, err, "no data server available", 0),
+		}, nil
+	}
 	protocols := make([]*provider.FileUploadProtocol, len(uploadIDs))
 	var i int
 	for protocol, ID := range uploadIDs {
-		u := *s.dataServerURL
+		u := dataServer
 		u.Path = path.Join(u.Path, protocol, ID)
 		protocols[i] = &provider.FileUploadProtocol{
 			Protocol:           protocol,
@@ -407,6 +627,9 @@ func (s *service) GetHome(ctx context.Context, req *provider.GetHomeRequest) (*p
 }
 
 func (s *service) CreateHome(ctx context.Context, req *provider.CreateHomeRequest) (*provider.CreateHomeResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.CreateHomeResponse{Status: st}, nil
+	}
 	log := appctx.GetLogger(ctx)
 	if err := s.storage.CreateHome(ctx); err != nil {
 		st := status.NewInternal(ctx, err, "error creating home")
@@ -447,6 +670,9 @@ func (s *service) DeleteStorageSpace(ctx context.Context, req *provider.DeleteSt
 }
 
 func (s *service) CreateContainer(ctx context.Context, req *provider.CreateContainerRequest) (*provider.CreateContainerResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.CreateContainerResponse{Status: st}, nil
+	}
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.CreateContainerResponse{
@@ -463,6 +689,8 @@ func (s *service) CreateContainer(ctx context.Context, req *provider.CreateConta
 			st = status.NewAlreadyExists(ctx, err, "container already exists")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error creating container: "+req.Ref.String())
 		}
@@ -478,6 +706,9 @@ func (s *service) CreateContainer(ctx context.Context, req *provider.CreateConta
 }
 
 func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*provider.DeleteResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.DeleteResponse{Status: st}, nil
+	}
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.DeleteResponse{
@@ -497,6 +728,8 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 			st = status.NewNotFound(ctx, "path not found when creating container")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error deleting file: "+req.Ref.String())
 		}
@@ -505,6 +738,12 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 		}, nil
 	}
 
+	ev := &events.FileDeleted{Path: req.Ref.GetPath(), ResourceID: req.Ref.GetId()}
+	if u, ok := user.ContextGetUser(ctx); ok {
+		ev.Executant = u.Id
+	}
+	s.events.Emit(ctx, ev)
+
 	res := &provider.DeleteResponse{
 		Status: status.NewOK(ctx),
 	}
@@ -512,6 +751,9 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 }
 
 func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provider.MoveResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.MoveResponse{Status: st}, nil
+	}
 	sourceRef, err := s.unwrap(ctx, req.Source)
 	if err != nil {
 		return &provider.MoveResponse{
@@ -525,6 +767,10 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 		}, nil
 	}
 
+	if req.Opaque != nil && req.Opaque.Map[storage.CopyOpaqueKey] != nil {
+		return s.copy(ctx, sourceRef, targetRef)
+	}
+
 	if err := s.storage.Move(ctx, sourceRef, targetRef); err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -532,6 +778,8 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 			st = status.NewNotFound(ctx, "path not found when moving")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error moving: "+sourceRef.String())
 		}
@@ -546,6 +794,34 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 	return res, nil
 }
 
+// copy is triggered by a MoveRequest carrying the storage.CopyOpaqueKey entry,
+// as the CS3 APIs do not have a dedicated copy call.
+func (s *service) copy(ctx context.Context, sourceRef, targetRef *provider.Reference) (*provider.MoveResponse, error) {
+	if err := s.storage.Copy(ctx, sourceRef, targetRef); err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "path not found when copying")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsNotSupported:
+			st = status.NewUnimplemented(ctx, err, "copy not supported by storage driver")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
+		default:
+			st = status.NewInternal(ctx, err, "error copying: "+sourceRef.String())
+		}
+		return &provider.MoveResponse{
+			Status: st,
+		}, nil
+	}
+
+	res := &provider.MoveResponse{
+		Status: status.NewOK(ctx),
+	}
+	return res, nil
+}
+
 func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
 	ctx, span := trace.StartSpan(ctx, "Stat")
 	defer span.End()
@@ -569,6 +845,8 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 			st = status.NewNotFound(ctx, "path not found when stating")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error stating: "+req.Ref.String())
 		}
@@ -577,6 +855,17 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 		}, nil
 	}
 
+	for _, k := range req.ArbitraryMetadataKeys {
+		if k == storage.RecursiveSizeKey {
+			if err := s.addRecursiveSize(ctx, newRef, md); err != nil {
+				return &provider.StatResponse{
+					Status: status.NewInternal(ctx, err, "error getting recursive size: "+req.Ref.String()),
+				}, nil
+			}
+			break
+		}
+	}
+
 	if err := s.wrap(ctx, md); err != nil {
 		return &provider.StatResponse{
 			Status: status.NewInternal(ctx, err, "error wrapping path"),
@@ -589,6 +878,28 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 	return res, nil
 }
 
+func (s *service) addRecursiveSize(ctx context.Context, ref *provider.Reference, md *provider.ResourceInfo) error {
+	size, files, err := s.storage.GetRecursiveSize(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if md.Opaque == nil {
+		md.Opaque = &typespb.Opaque{}
+	}
+	if md.Opaque.Map == nil {
+		md.Opaque.Map = map[string]*typespb.OpaqueEntry{}
+	}
+	md.Opaque.Map[storage.RecursiveSizeKey] = &typespb.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(strconv.FormatUint(size, 10)),
+	}
+	md.Opaque.Map[storage.RecursiveFileCountKey] = &typespb.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(strconv.FormatUint(files, 10)),
+	}
+	return nil
+}
+
 func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest, ss provider.ProviderAPI_ListContainerStreamServer) error {
 	ctx := ss.Context()
 	log := appctx.GetLogger(ctx)
@@ -605,7 +916,7 @@ func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest,
 		return nil
 	}
 
-	mds, err := s.storage.ListFolder(ctx, newRef, req.ArbitraryMetadataKeys)
+	mds, truncated, err := storage.ListFolderPartial(ctx, s.storage, newRef, req.ArbitraryMetadataKeys, time.Duration(s.conf.ListTimeout)*time.Millisecond)
 	if err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -613,6 +924,8 @@ func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest,
 			st = status.NewNotFound(ctx, "path not found when listing container")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error listing container: "+req.Ref.String())
 		}
@@ -647,6 +960,9 @@ func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest,
 			return err
 		}
 	}
+	if truncated {
+		log.Warn().Str("ref", req.Ref.String()).Int("entries", len(mds)).Msg("storageprovider: streaming container timed out, returned partial result")
+	}
 	return nil
 }
 
@@ -658,7 +974,7 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 		}, nil
 	}
 
-	mds, err := s.storage.ListFolder(ctx, newRef, req.ArbitraryMetadataKeys)
+	mds, truncated, err := storage.ListFolderPartial(ctx, s.storage, newRef, req.ArbitraryMetadataKeys, time.Duration(s.conf.ListTimeout)*time.Millisecond)
 	if err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -666,6 +982,8 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 			st = status.NewNotFound(ctx, "path not found when listing container")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error listing container: "+req.Ref.String())
 		}
@@ -687,6 +1005,10 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 		Status: status.NewOK(ctx),
 		Infos:  infos,
 	}
+	if truncated {
+		appctx.GetLogger(ctx).Warn().Str("ref", req.Ref.String()).Int("entries", len(infos)).Msg("storageprovider: listing container timed out, returning partial result")
+		res.Opaque = truncatedOpaque()
+	}
 	return res, nil
 }
 
@@ -706,6 +1028,8 @@ func (s *service) ListFileVersions(ctx context.Context, req *provider.ListFileVe
 			st = status.NewNotFound(ctx, "path not found when listing file versions")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error listing file versions: "+req.Ref.String())
 		}
@@ -722,6 +1046,9 @@ func (s *service) ListFileVersions(ctx context.Context, req *provider.ListFileVe
 }
 
 func (s *service) RestoreFileVersion(ctx context.Context, req *provider.RestoreFileVersionRequest) (*provider.RestoreFileVersionResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.RestoreFileVersionResponse{Status: st}, nil
+	}
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.RestoreFileVersionResponse{
@@ -736,6 +1063,8 @@ func (s *service) RestoreFileVersion(ctx context.Context, req *provider.RestoreF
 			st = status.NewNotFound(ctx, "path not found when restoring file versions")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error restoring version: "+req.Ref.String())
 		}
@@ -762,6 +1091,8 @@ func (s *service) ListRecycleStream(req *provider.ListRecycleStreamRequest, ss p
 			st = status.NewNotFound(ctx, "path not found when listing recycle stream")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error listing recycle stream")
 		}
@@ -790,7 +1121,22 @@ func (s *service) ListRecycleStream(req *provider.ListRecycleStreamRequest, ss p
 }
 
 func (s *service) ListRecycle(ctx context.Context, req *provider.ListRecycleRequest) (*provider.ListRecycleResponse, error) {
-	items, err := s.storage.ListRecycle(ctx)
+	var (
+		items     []*provider.RecycleItem
+		truncated bool
+		err       error
+	)
+	root, ok := req.Opaque.GetMap()[storage.RecycleRootOpaqueKey]
+	if ok {
+		items, err = storage.ListSpaceRecycle(ctx, s.storage, &provider.ResourceId{OpaqueId: string(root.Value)})
+	}
+	if _, perUser := err.(errtypes.IsNotSupported); !ok || perUser {
+		items, truncated, err = storage.ListRecyclePartial(ctx, s.storage, time.Duration(s.conf.ListTimeout)*time.Millisecond)
+		if err == nil && ok {
+			// a space is referenced by the id of its root, only list what was deleted inside it
+			items, err = s.filterRecycle(ctx, &provider.ResourceId{OpaqueId: string(root.Value)}, items)
+		}
+	}
 	// TODO(labkode): CRITICAL: fill recycle info with storage provider.
 	if err != nil {
 		var st *rpc.Status
@@ -799,6 +1145,8 @@ func (s *service) ListRecycle(ctx context.Context, req *provider.ListRecycleRequ
 			st = status.NewNotFound(ctx, "path not found when listing recycle")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error listing recycle")
 		}
@@ -811,18 +1159,55 @@ func (s *service) ListRecycle(ctx context.Context, req *provider.ListRecycleRequ
 		Status:       status.NewOK(ctx),
 		RecycleItems: items,
 	}
+	if truncated {
+		appctx.GetLogger(ctx).Warn().Int("entries", len(items)).Msg("storageprovider: listing recycle timed out, returning partial result")
+		res.Opaque = truncatedOpaque()
+	}
 	return res, nil
 }
 
+// filterRecycle only keeps the recycle items that were deleted below the resource with the given id.
+func (s *service) filterRecycle(ctx context.Context, id *provider.ResourceId, items []*provider.RecycleItem) ([]*provider.RecycleItem, error) {
+	root, err := s.storage.GetPathByID(ctx, &provider.ResourceId{OpaqueId: id.OpaqueId})
+	if err != nil {
+		return nil, err
+	}
+	root = path.Join("/", root)
+	if root == "/" {
+		return items, nil
+	}
+
+	filtered := make([]*provider.RecycleItem, 0, len(items))
+	for _, item := range items {
+		if p := path.Join("/", item.Path); p == root || strings.HasPrefix(p, root+"/") {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
 func (s *service) RestoreRecycleItem(ctx context.Context, req *provider.RestoreRecycleItemRequest) (*provider.RestoreRecycleItemResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.RestoreRecycleItemResponse{Status: st}, nil
+	}
 	// TODO(labkode): CRITICAL: fill recycle info with storage provider.
-	if err := s.storage.RestoreRecycleItem(ctx, req.Key, req.RestorePath); err != nil {
+	var err error
+	if req.Ref.GetId() != nil {
+		// the item is restored from the trash bin of the space whose root is referenced
+		err = storage.RestoreSpaceRecycleItem(ctx, s.storage, req.Ref.GetId(), req.Key, req.RestorePath)
+	}
+	if _, ok := err.(errtypes.IsNotSupported); ok || req.Ref.GetId() == nil {
+		err = s.storage.RestoreRecycleItem(ctx, req.Key, req.RestorePath)
+	}
+	if err != nil {
 		var st *rpc.Status
 		switch err.(type) {
 		case errtypes.IsNotFound:
 			st = status.NewNotFound(ctx, "path not found when restoring recycle bin item")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error restoring recycle bin item")
 		}
@@ -838,6 +1223,13 @@ func (s *service) RestoreRecycleItem(ctx context.Context, req *provider.RestoreR
 }
 
 func (s *service) PurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRequest) (*provider.PurgeRecycleResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.PurgeRecycleResponse{Status: st}, nil
+	}
+	if root, ok := req.Opaque.GetMap()[storage.RecycleRootOpaqueKey]; ok {
+		return s.purgeSpaceRecycle(ctx, &provider.ResourceId{OpaqueId: string(root.Value)}, req.GetRef().GetId().GetOpaqueId())
+	}
+
 	// if a key was sent as opacque id purge only that item
 	if req.GetRef().GetId() != nil && req.GetRef().GetId().GetOpaqueId() != "" {
 		if err := s.storage.PurgeRecycleItem(ctx, req.GetRef().GetId().GetOpaqueId()); err != nil {
@@ -847,6 +1239,8 @@ func (s *service) PurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRe
 				st = status.NewNotFound(ctx, "path not found when purging recycle item")
 			case errtypes.PermissionDenied:
 				st = status.NewPermissionDenied(ctx, err, "permission denied")
+			case errtypes.IsTooManyRequests:
+				st = status.NewResourceExhausted(ctx, err, "too many requests")
 			default:
 				st = status.NewInternal(ctx, err, "error purging recycle item")
 			}
@@ -862,6 +1256,8 @@ func (s *service) PurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRe
 			st = status.NewNotFound(ctx, "path not found when purging recycle bin")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error purging recycle bin")
 		}
@@ -876,6 +1272,51 @@ func (s *service) PurgeRecycle(ctx context.Context, req *provider.PurgeRecycleRe
 	return res, nil
 }
 
+// purgeSpaceRecycle purges the item with the given key from the trash bin of
+// the space, or all the items deleted inside the space without key.
+func (s *service) purgeSpaceRecycle(ctx context.Context, root *provider.ResourceId, key string) (*provider.PurgeRecycleResponse, error) {
+	var err error
+	if key != "" {
+		err = storage.PurgeSpaceRecycleItem(ctx, s.storage, root, key)
+	} else {
+		err = storage.EmptySpaceRecycle(ctx, s.storage, root)
+	}
+	if _, perUser := err.(errtypes.IsNotSupported); perUser && key != "" {
+		err = s.storage.PurgeRecycleItem(ctx, key)
+	} else if perUser {
+		// the drivers without trash bins per space only purge what the
+		// user deleted inside the space, not their whole recycle bin
+		var items []*provider.RecycleItem
+		if items, err = s.storage.ListRecycle(ctx); err == nil {
+			items, err = s.filterRecycle(ctx, root, items)
+		}
+		for i := 0; err == nil && i < len(items); i++ {
+			err = s.storage.PurgeRecycleItem(ctx, items[i].Key)
+		}
+	}
+
+	if err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "path not found when purging the trash bin of the space")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
+		default:
+			st = status.NewInternal(ctx, err, "error purging the trash bin of the space")
+		}
+		return &provider.PurgeRecycleResponse{
+			Status: st,
+		}, nil
+	}
+
+	return &provider.PurgeRecycleResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
 func (s *service) ListGrants(ctx context.Context, req *provider.ListGrantsRequest) (*provider.ListGrantsResponse, error) {
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
@@ -892,6 +1333,8 @@ func (s *service) ListGrants(ctx context.Context, req *provider.ListGrantsReques
 			st = status.NewNotFound(ctx, "path not found when listing grants")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error listing grants")
 		}
@@ -908,6 +1351,9 @@ func (s *service) ListGrants(ctx context.Context, req *provider.ListGrantsReques
 }
 
 func (s *service) AddGrant(ctx context.Context, req *provider.AddGrantRequest) (*provider.AddGrantResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.AddGrantResponse{Status: st}, nil
+	}
 	newRef, err := s.unwrap(ctx, req.Ref)
 	if err != nil {
 		return &provider.AddGrantResponse{
@@ -930,6 +1376,8 @@ func (s *service) AddGrant(ctx context.Context, req *provider.AddGrantRequest) (
 			st = status.NewNotFound(ctx, "path not found when setting grants")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error setting grants")
 		}
@@ -945,6 +1393,9 @@ func (s *service) AddGrant(ctx context.Context, req *provider.AddGrantRequest) (
 }
 
 func (s *service) UpdateGrant(ctx context.Context, req *provider.UpdateGrantRequest) (*provider.UpdateGrantResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.UpdateGrantResponse{Status: st}, nil
+	}
 	// check grantee type is valid
 	if req.Grant.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_INVALID {
 		return &provider.UpdateGrantResponse{
@@ -966,6 +1417,8 @@ func (s *service) UpdateGrant(ctx context.Context, req *provider.UpdateGrantRequ
 			st = status.NewNotFound(ctx, "path not found when updating grant")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error updating grant")
 		}
@@ -981,6 +1434,9 @@ func (s *service) UpdateGrant(ctx context.Context, req *provider.UpdateGrantRequ
 }
 
 func (s *service) RemoveGrant(ctx context.Context, req *provider.RemoveGrantRequest) (*provider.RemoveGrantResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.RemoveGrantResponse{Status: st}, nil
+	}
 	// check targetType is valid
 	if req.Grant.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_INVALID {
 		return &provider.RemoveGrantResponse{
@@ -1002,6 +1458,8 @@ func (s *service) RemoveGrant(ctx context.Context, req *provider.RemoveGrantRequ
 			st = status.NewNotFound(ctx, "path not found when removing grant")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error removing grant")
 		}
@@ -1017,6 +1475,9 @@ func (s *service) RemoveGrant(ctx context.Context, req *provider.RemoveGrantRequ
 }
 
 func (s *service) CreateReference(ctx context.Context, req *provider.CreateReferenceRequest) (*provider.CreateReferenceResponse, error) {
+	if st := s.checkMaintenance(ctx); st != nil {
+		return &provider.CreateReferenceResponse{Status: st}, nil
+	}
 	log := appctx.GetLogger(ctx)
 
 	// parse uri is valid
@@ -1049,6 +1510,8 @@ func (s *service) CreateReference(ctx context.Context, req *provider.CreateRefer
 			st = status.NewNotFound(ctx, "path not found when creating reference")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error creating reference")
 		}
@@ -1077,6 +1540,8 @@ func (s *service) GetQuota(ctx context.Context, req *provider.GetQuotaRequest) (
 			st = status.NewNotFound(ctx, "path not found when getting quota")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsTooManyRequests:
+			st = status.NewResourceExhausted(ctx, err, "too many requests")
 		default:
 			st = status.NewInternal(ctx, err, "error getting quota")
 		}
@@ -1093,11 +1558,40 @@ func (s *service) GetQuota(ctx context.Context, req *provider.GetQuotaRequest) (
 	return res, nil
 }
 
+func truncatedOpaque() *typespb.Opaque {
+	return &typespb.Opaque{
+		Map: map[string]*typespb.OpaqueEntry{
+			storage.TruncatedOpaqueKey: {
+				Decoder: "plain",
+				Value:   []byte("true"),
+			},
+		},
+	}
+}
+
 func getFS(c *config) (storage.FS, error) {
-	if f, ok := registry.NewFuncs[c.Driver]; ok {
-		return f(c.Drivers[c.Driver])
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("driver not found: " + c.Driver)
+	}
+	return f(c.Drivers[c.Driver])
+}
+
+// dataServer returns the URL of the data server the transfers are sent to, one
+// of the data servers in the service registry if configured.
+func (s *service) dataServer() (url.URL, error) {
+	if s.conf.DataServerService == "" {
+		return *s.dataServerURL, nil
+	}
+	addr, err := svcregistry.Resolve(utils.GlobalRegistry, s.conf.DataServerService)
+	if err != nil {
+		return url.URL{}, err
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return url.URL{}, err
 	}
-	return nil, errtypes.NotFound("driver not found: " + c.Driver)
+	return *u, nil
 }
 
 func (s *service) unwrap(ctx context.Context, ref *provider.Reference) (*provider.Reference, error) {
@@ -20,10 +20,14 @@ package storageregistry
 
 import (
 	"context"
+	"strings"
+	"sync"
 
 	registrypb "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/health"
+	"github.com/cs3org/reva/pkg/reload"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/storage"
@@ -36,11 +40,16 @@ func init() {
 	rgrpc.Register("storageregistry", New)
 }
 
+const reloadSection = "grpc.services.storageregistry"
+
 type service struct {
+	mu  sync.RWMutex
 	reg storage.Registry
 }
 
 func (s *service) Close() error {
+	reload.Unregister(reloadSection)
+	health.Unregister("storageregistry")
 	return nil
 }
 
@@ -80,10 +89,44 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	service := &service{
 		reg: reg,
 	}
+	reload.Register(reloadSection, service.reload)
+	health.Register("storageregistry", service.check)
 
 	return service, nil
 }
 
+// check resolves the providers, which the registry fails to do when its
+// rules or its catalog can't be read.
+func (s *service) check(ctx context.Context) error {
+	_, err := s.registry().ListProviders(ctx)
+	return err
+}
+
+// reload swaps the registry for one built from the new configuration, e.g.
+// to point the rules to new storage provider addresses.
+func (s *service) reload(m map[string]interface{}) error {
+	c, err := parseConfig(m)
+	if err != nil {
+		return err
+	}
+	c.init()
+	reg, err := getRegistry(c)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reg = reg
+	return nil
+}
+
+func (s *service) registry() storage.Registry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reg
+}
+
 func parseConfig(m map[string]interface{}) (*config, error) {
 	c := &config{}
 	if err := mapstructure.Decode(m, c); err != nil {
@@ -99,8 +142,29 @@ func getRegistry(c *config) (storage.Registry, error) {
 	return nil, errtypes.NotFound("driver not found: " + c.Driver)
 }
 
+// ListStorageProviders lists all the providers. When the request opaque has the
+// SpaceTypesOpaqueKey, the registry must keep a catalog of spaces and the providers
+// of the spaces of the given types, of all the spaces with an empty value, are listed.
 func (s *service) ListStorageProviders(ctx context.Context, req *registrypb.ListStorageProvidersRequest) (*registrypb.ListStorageProvidersResponse, error) {
-	pinfos, err := s.reg.ListProviders(ctx)
+	var pinfos []*registrypb.ProviderInfo
+	var err error
+	if e, ok := req.Opaque.GetMap()[storage.SpaceTypesOpaqueKey]; ok {
+		sr, ok := s.registry().(storage.SpacesRegistry)
+		if !ok {
+			return &registrypb.ListStorageProvidersResponse{
+				Status: status.NewUnimplemented(ctx, errtypes.NotSupported("space types"), "the storage registry does not keep a catalog of spaces"),
+			}, nil
+		}
+		var types []string
+		for _, t := range strings.Split(string(e.Value), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+		pinfos, err = sr.ListSpaceProviders(ctx, types...)
+	} else {
+		pinfos, err = s.registry().ListProviders(ctx)
+	}
 	if err != nil {
 		return &registrypb.ListStorageProvidersResponse{
 			Status: status.NewInternal(ctx, err, "error getting list of storage providers"),
@@ -115,7 +179,7 @@ func (s *service) ListStorageProviders(ctx context.Context, req *registrypb.List
 }
 
 func (s *service) GetStorageProviders(ctx context.Context, req *registrypb.GetStorageProvidersRequest) (*registrypb.GetStorageProvidersResponse, error) {
-	p, err := s.reg.FindProviders(ctx, req.Ref)
+	p, err := s.registry().FindProviders(ctx, req.Ref)
 	if err != nil {
 		switch err.(type) {
 		case errtypes.IsNotFound:
@@ -138,7 +202,7 @@ func (s *service) GetStorageProviders(ctx context.Context, req *registrypb.GetSt
 
 func (s *service) GetHome(ctx context.Context, req *registrypb.GetHomeRequest) (*registrypb.GetHomeResponse, error) {
 	log := appctx.GetLogger(ctx)
-	p, err := s.reg.GetHome(ctx)
+	p, err := s.registry().GetHome(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("error getting home")
 		res := &registrypb.GetHomeResponse{
@@ -0,0 +1,174 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package useradmin implements the service the admins manage the users of a
+// writable user manager with, creating their storage as on their first login.
+package useradmin
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	useradminpb "github.com/cs3org/reva/internal/grpc/services/useradmin/proto"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/provisioning"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	rgrpc.Register("useradmin", New)
+}
+
+type config struct {
+	// Admins are the usernames of the users allowed to manage the users.
+	Admins       []string               `mapstructure:"admins" docs:"nil;The usernames of the users allowed to manage the users."`
+	Provisioning map[string]interface{} `mapstructure:"provisioning" docs:"url:pkg/user/provisioning/provisioning.go;The user manager the users are stored in and the storage created for them."`
+}
+
+type service struct {
+	conf        *config
+	admins      map[string]bool
+	provisioner *provisioning.Provisioner
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New creates a new user admin service.
+func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := provisioning.NewProvisioner(c.Provisioning)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &service{
+		conf:        c,
+		admins:      map[string]bool{},
+		provisioner: p,
+	}
+	for _, a := range c.Admins {
+		s.admins[a] = true
+	}
+	return s, nil
+}
+
+func (s *service) Register(ss *grpc.Server) {
+	useradminpb.RegisterUserAdminServiceServer(ss, s)
+}
+
+func (s *service) Close() error {
+	return s.provisioner.Close()
+}
+
+func (s *service) UnprotectedEndpoints() []string {
+	return []string{}
+}
+
+// checkAdmin returns the status refusing the request of a user who is not an
+// admin, or nil.
+func (s *service) checkAdmin(ctx context.Context) *rpc.Status {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok || !s.admins[u.GetUsername()] {
+		err := errtypes.PermissionDenied("useradmin: only admins can manage the users")
+		return status.NewPermissionDenied(ctx, err, "only admins can manage the users")
+	}
+	return nil
+}
+
+// errorStatus maps the errors of the provisioner, which wraps them, to a status.
+func errorStatus(ctx context.Context, msg string, err error) *rpc.Status {
+	if _, ok := errors.Cause(err).(errtypes.IsAlreadyExists); ok {
+		return status.NewAlreadyExists(ctx, err, msg+": "+err.Error())
+	}
+	return status.NewStatusFromErrType(ctx, msg, errors.Cause(err))
+}
+
+func (s *service) CreateUser(ctx context.Context, req *useradminpb.CreateUserRequest) (*useradminpb.CreateUserResponse, error) {
+	if st := s.checkAdmin(ctx); st != nil {
+		return &useradminpb.CreateUserResponse{Status: st}, nil
+	}
+	if req.User == nil {
+		return &useradminpb.CreateUserResponse{Status: status.NewInvalidArg(ctx, "user missing")}, nil
+	}
+
+	u, err := s.provisioner.Create(ctx, req.User)
+	if err != nil {
+		return &useradminpb.CreateUserResponse{Status: errorStatus(ctx, "error creating user", err)}, nil
+	}
+	return &useradminpb.CreateUserResponse{Status: status.NewOK(ctx), User: u}, nil
+}
+
+func (s *service) UpdateUser(ctx context.Context, req *useradminpb.UpdateUserRequest) (*useradminpb.UpdateUserResponse, error) {
+	if st := s.checkAdmin(ctx); st != nil {
+		return &useradminpb.UpdateUserResponse{Status: st}, nil
+	}
+	if req.User.GetId() == nil {
+		return &useradminpb.UpdateUserResponse{Status: status.NewInvalidArg(ctx, "user id missing")}, nil
+	}
+
+	u, err := s.provisioner.Update(ctx, req.User)
+	if err != nil {
+		return &useradminpb.UpdateUserResponse{Status: errorStatus(ctx, "error updating user", err)}, nil
+	}
+	return &useradminpb.UpdateUserResponse{Status: status.NewOK(ctx), User: u}, nil
+}
+
+func (s *service) SetUserDisabled(ctx context.Context, req *useradminpb.SetUserDisabledRequest) (*useradminpb.SetUserDisabledResponse, error) {
+	if st := s.checkAdmin(ctx); st != nil {
+		return &useradminpb.SetUserDisabledResponse{Status: st}, nil
+	}
+	if req.Id == nil {
+		return &useradminpb.SetUserDisabledResponse{Status: status.NewInvalidArg(ctx, "user id missing")}, nil
+	}
+
+	u, err := s.provisioner.SetDisabled(ctx, req.Id, req.Disabled)
+	if err != nil {
+		return &useradminpb.SetUserDisabledResponse{Status: errorStatus(ctx, "error disabling user", err)}, nil
+	}
+	return &useradminpb.SetUserDisabledResponse{Status: status.NewOK(ctx), User: u}, nil
+}
+
+func (s *service) DeleteUser(ctx context.Context, req *useradminpb.DeleteUserRequest) (*useradminpb.DeleteUserResponse, error) {
+	if st := s.checkAdmin(ctx); st != nil {
+		return &useradminpb.DeleteUserResponse{Status: st}, nil
+	}
+	if req.Id == nil {
+		return &useradminpb.DeleteUserResponse{Status: status.NewInvalidArg(ctx, "user id missing")}, nil
+	}
+
+	if err := s.provisioner.Delete(ctx, req.Id); err != nil {
+		return &useradminpb.DeleteUserResponse{Status: errorStatus(ctx, "error deleting user", err)}, nil
+	}
+	return &useradminpb.DeleteUserResponse{Status: status.NewOK(ctx)}, nil
+}
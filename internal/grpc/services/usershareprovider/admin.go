@@ -0,0 +1,114 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usershareprovider
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+// checkAdmin returns the status refusing the admin request of a user who is
+// not an admin, or nil.
+func (s *service) checkAdmin(ctx context.Context) *rpc.Status {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok || !s.admins[u.GetUsername()] {
+		err := errtypes.PermissionDenied("usershareprovider: only admins can manage the shares of all the users")
+		return status.NewPermissionDenied(ctx, err, "only admins can manage the shares of all the users")
+	}
+	return nil
+}
+
+// allShares returns the shares of all the users.
+func (s *service) allShares(ctx context.Context) ([]*collaboration.Share, error) {
+	d, ok := s.sm.(share.Dumper)
+	if !ok {
+		return nil, errtypes.NotSupported("listing the shares of all the users not supported by driver " + s.conf.Driver)
+	}
+	shares, _, err := d.Dump(ctx)
+	return shares, err
+}
+
+func (s *service) adminListShares(ctx context.Context, req *collaboration.ListSharesRequest) *collaboration.ListSharesResponse {
+	if st := s.checkAdmin(ctx); st != nil {
+		return &collaboration.ListSharesResponse{Status: st}
+	}
+
+	all, err := s.allShares(ctx)
+	if err != nil {
+		return &collaboration.ListSharesResponse{Status: status.NewStatusFromErrType(ctx, "error listing shares", err)}
+	}
+
+	shares := []*collaboration.Share{}
+	for _, sh := range all {
+		if share.MatchFilters(sh, req.Filters) {
+			shares = append(shares, sh)
+		}
+	}
+	return &collaboration.ListSharesResponse{
+		Status: status.NewOK(ctx),
+		Shares: shares,
+		Opaque: s.expirationsOpaque(ctx, shares),
+	}
+}
+
+// adminRemoveShare removes the share of any user, on behalf of its creator
+// so that the grants on the storage go away with it.
+func (s *service) adminRemoveShare(ctx context.Context, ref *collaboration.ShareReference) *rpc.Status {
+	if st := s.checkAdmin(ctx); st != nil {
+		return st
+	}
+	if ref.GetId() == nil {
+		return status.NewInvalidArg(ctx, "the admins remove the shares by id")
+	}
+
+	all, err := s.allShares(ctx)
+	if err != nil {
+		return status.NewStatusFromErrType(ctx, "error listing shares", err)
+	}
+	var sh *collaboration.Share
+	for _, candidate := range all {
+		if candidate.Id.GetOpaqueId() == ref.GetId().OpaqueId {
+			sh = candidate
+			break
+		}
+	}
+	if sh == nil {
+		return status.NewNotFound(ctx, "share not found")
+	}
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return status.NewInternal(ctx, err, "error getting gateway client")
+	}
+	if err := s.removeAsCreator(ctx, client, sh); err != nil {
+		return status.NewInternal(ctx, err, "error removing share")
+	}
+
+	appctx.GetLogger(ctx).Info().Str("share", sh.Id.OpaqueId).Str("admin", user.ContextMustGetUser(ctx).Username).
+		Msg("usershareprovider: admin removed share")
+	return status.NewOK(ctx)
+}
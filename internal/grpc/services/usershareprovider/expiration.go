@@ -0,0 +1,199 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package usershareprovider
+
+import (
+	"context"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/token"
+	"google.golang.org/grpc/metadata"
+)
+
+// checkExpiration reads the expiration carried by a request, which can only
+// be set if the driver can expire shares and must lie in the future.
+func (s *service) checkExpiration(o *types.Opaque) (*types.Timestamp, bool, error) {
+	exp, ok, err := share.DecodeExpiration(o)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if _, isExpirer := s.sm.(share.Expirer); !isExpirer {
+		return nil, false, errtypes.NotSupported("share expiration not supported by driver " + s.conf.Driver)
+	}
+	if share.Expired(exp, time.Now()) {
+		return nil, false, errtypes.BadRequest("share expiration in the past")
+	}
+	return exp, true, nil
+}
+
+func (s *service) setExpiration(ctx context.Context, sh *collaboration.Share, exp *types.Timestamp) error {
+	// checkExpiration made sure the driver is an Expirer
+	return s.sm.(share.Expirer).SetExpiration(ctx, shareRef(sh), exp)
+}
+
+func shareRef(sh *collaboration.Share) *collaboration.ShareReference {
+	return &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: sh.Id}}
+}
+
+// expirationOpaque returns an opaque carrying the expiration of the share, or
+// nil if it has none.
+func (s *service) expirationOpaque(ctx context.Context, sh *collaboration.Share) *types.Opaque {
+	if sh == nil {
+		return nil
+	}
+	exps := s.expirations(ctx, []*collaboration.Share{sh})
+	exp, ok := exps[sh.Id.GetOpaqueId()]
+	if !ok {
+		return nil
+	}
+	return share.EncodeExpiration(nil, exp)
+}
+
+// expirationsOpaque returns an opaque carrying the expirations of the listed
+// shares, or nil if none of them expires.
+func (s *service) expirationsOpaque(ctx context.Context, shares []*collaboration.Share) *types.Opaque {
+	exps := s.expirations(ctx, shares)
+	if len(exps) == 0 {
+		return nil
+	}
+	o, err := share.EncodeExpirations(nil, exps)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("error encoding share expirations")
+		return nil
+	}
+	return o
+}
+
+func (s *service) expirations(ctx context.Context, shares []*collaboration.Share) map[string]*types.Timestamp {
+	e, ok := s.sm.(share.Expirer)
+	if !ok || len(shares) == 0 {
+		return nil
+	}
+	ids := make([]*collaboration.ShareId, 0, len(shares))
+	for _, sh := range shares {
+		ids = append(ids, sh.Id)
+	}
+	exps, err := e.GetExpirations(ctx, ids)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("error getting share expirations")
+		return nil
+	}
+	return exps
+}
+
+// reap removes the expired shares every interval until ctx is done.
+func (s *service) reap(ctx context.Context, e share.Expirer, interval time.Duration) {
+	defer s.wg.Done()
+	log := appctx.GetLogger(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.removeExpiredShares(ctx, e); err != nil {
+			log.Error().Err(err).Msg("usershareprovider: error removing expired shares")
+		}
+	}
+}
+
+// removeExpiredShares removes the expired shares through the gateway on behalf
+// of their creators, so that the grants on the storage go away with them.
+func (s *service) removeExpiredShares(ctx context.Context, e share.Expirer) error {
+	log := appctx.GetLogger(ctx)
+	expired, err := e.ListExpiredShares(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return err
+	}
+	exps := s.expirations(ctx, expired)
+	for _, sh := range expired {
+		if err := s.removeAsCreator(ctx, client, sh); err != nil {
+			log.Error().Err(err).Str("share", sh.Id.GetOpaqueId()).Msg("usershareprovider: error removing expired share")
+			continue
+		}
+		log.Info().Str("share", sh.Id.GetOpaqueId()).Msg("usershareprovider: removed expired share")
+		s.events.Emit(ctx, &events.ShareExpired{
+			ShareID:        sh.Id,
+			Sharer:         sh.Creator,
+			GranteeUserID:  sh.GetGrantee().GetUserId(),
+			GranteeGroupID: sh.GetGrantee().GetGroupId(),
+			ItemID:         sh.ResourceId,
+			Expiration:     exps[sh.Id.GetOpaqueId()],
+		})
+	}
+	return nil
+}
+
+// removeAsCreator removes a share through the gateway on behalf of its creator.
+func (s *service) removeAsCreator(ctx context.Context, client gateway.GatewayAPIClient, sh *collaboration.Share) error {
+	ures, err := client.GetUser(ctx, &userpb.GetUserRequest{UserId: sh.Creator})
+	if err != nil {
+		return err
+	}
+	if ures.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(ures.Status.Code, "usershareprovider")
+	}
+	ctx, err = s.asUser(ctx, ures.User)
+	if err != nil {
+		return err
+	}
+	res, err := client.RemoveShare(ctx, &collaboration.RemoveShareRequest{Ref: shareRef(sh)})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(res.Status.Code, "usershareprovider")
+	}
+	return nil
+}
+
+// asUser returns a context authenticated as the given user.
+func (s *service) asUser(ctx context.Context, u *userpb.User) (context.Context, error) {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return nil, err
+	}
+	tkn, err := s.tokenmgr.MintToken(token.ContextSetService(ctx, "usershareprovider"), u, ownerScope)
+	if err != nil {
+		return nil, err
+	}
+	ctx = token.ContextSetToken(ctx, tkn)
+	return metadata.AppendToOutgoingContext(ctx, token.TokenHeader, tkn), nil
+}
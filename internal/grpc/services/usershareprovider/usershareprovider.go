@@ -20,16 +20,23 @@ package usershareprovider
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/share/manager/registry"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/token"
+	tokenregistry "github.com/cs3org/reva/pkg/token/manager/registry"
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -43,17 +50,37 @@ func init() {
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// ExpiredSharesCleanupInterval is how often in seconds the expired shares
+	// are removed, 0 disables their removal.
+	ExpiredSharesCleanupInterval int                               `mapstructure:"expired_shares_cleanup_interval"`
+	GatewaySvc                   string                            `mapstructure:"gatewaysvc"`
+	TokenManager                 string                            `mapstructure:"token_manager"`
+	TokenManagers                map[string]map[string]interface{} `mapstructure:"token_managers"`
+	EventStream                  string                            `mapstructure:"event_stream"`
+	EventStreams                 map[string]map[string]interface{} `mapstructure:"event_streams"`
+	// Admins are the usernames of the users allowed to list and remove the
+	// shares of all the users.
+	Admins []string `mapstructure:"admins"`
 }
 
 func (c *config) init() {
 	if c.Driver == "" {
 		c.Driver = "json"
 	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
 
 type service struct {
-	conf *config
-	sm   share.Manager
+	conf     *config
+	sm       share.Manager
+	tokenmgr token.Manager
+	events   *events.Emitter
+	admins   map[string]bool
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
 }
 
 func getShareManager(c *config) (share.Manager, error) {
@@ -65,7 +92,11 @@ func getShareManager(c *config) (share.Manager, error) {
 
 // TODO(labkode): add ctx to Close.
 func (s *service) Close() error {
-	return nil
+	if s.cancel != nil {
+		s.cancel()
+		s.wg.Wait()
+	}
+	return s.events.Close()
 }
 
 func (s *service) UnprotectedEndpoints() []string {
@@ -101,8 +132,39 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	}
 
 	service := &service{
-		conf: c,
-		sm:   sm,
+		conf:   c,
+		sm:     sm,
+		admins: map[string]bool{},
+	}
+	for _, a := range c.Admins {
+		service.admins[a] = true
+	}
+
+	// the expired shares and the shares removed by the admins are removed
+	// on behalf of their creators
+	if c.ExpiredSharesCleanupInterval > 0 || len(c.Admins) > 0 {
+		tf, ok := tokenregistry.NewFuncs[c.TokenManager]
+		if !ok {
+			return nil, errtypes.NotFound("driver not found for token manager: " + c.TokenManager)
+		}
+		if service.tokenmgr, err = tf(c.TokenManagers[c.TokenManager]); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.ExpiredSharesCleanupInterval > 0 {
+		e, ok := sm.(share.Expirer)
+		if !ok {
+			return nil, errtypes.NotSupported("share expiration not supported by driver " + c.Driver)
+		}
+		if service.events, err = eventsregistry.NewEmitter(c.EventStream, c.EventStreams); err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		service.cancel = cancel
+		service.wg.Add(1)
+		go service.reap(ctx, e, time.Duration(c.ExpiredSharesCleanupInterval)*time.Second)
 	}
 
 	return service, nil
@@ -115,21 +177,42 @@ func (s *service) CreateShare(ctx context.Context, req *collaboration.CreateShar
 		g := &userpb.UserId{OpaqueId: req.Grant.Grantee.GetUserId().OpaqueId, Idp: u.Id.Idp}
 		req.Grant.Grantee.Id = &provider.Grantee_UserId{UserId: g}
 	}
+	exp, setExp, err := s.checkExpiration(req.Opaque)
+	if err != nil {
+		return &collaboration.CreateShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error reading share expiration", err),
+		}, nil
+	}
 	share, err := s.sm.Share(ctx, req.ResourceInfo, req.Grant)
 	if err != nil {
 		return &collaboration.CreateShareResponse{
 			Status: status.NewInternal(ctx, err, "error creating share"),
 		}, nil
 	}
+	if setExp && exp != nil {
+		if err := s.setExpiration(ctx, share, exp); err != nil {
+			if err := s.sm.Unshare(ctx, shareRef(share)); err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Str("share", share.Id.GetOpaqueId()).Msg("error removing share without expiration")
+			}
+			return &collaboration.CreateShareResponse{
+				Status: status.NewInternal(ctx, err, "error setting share expiration"),
+			}, nil
+		}
+	}
 
 	res := &collaboration.CreateShareResponse{
 		Status: status.NewOK(ctx),
 		Share:  share,
+		Opaque: s.expirationOpaque(ctx, share),
 	}
 	return res, nil
 }
 
 func (s *service) RemoveShare(ctx context.Context, req *collaboration.RemoveShareRequest) (*collaboration.RemoveShareResponse, error) {
+	if share.IsAdmin(req.Opaque) {
+		return &collaboration.RemoveShareResponse{Status: s.adminRemoveShare(ctx, req.Ref)}, nil
+	}
+
 	err := s.sm.Unshare(ctx, req.Ref)
 	if err != nil {
 		return &collaboration.RemoveShareResponse{
@@ -153,11 +236,29 @@ func (s *service) GetShare(ctx context.Context, req *collaboration.GetShareReque
 	return &collaboration.GetShareResponse{
 		Status: status.NewOK(ctx),
 		Share:  share,
+		Opaque: s.expirationOpaque(ctx, share),
 	}, nil
 }
 
 func (s *service) ListShares(ctx context.Context, req *collaboration.ListSharesRequest) (*collaboration.ListSharesResponse, error) {
-	shares, err := s.sm.ListShares(ctx, req.Filters) // TODO(labkode): add filter to share manager
+	if share.IsAdmin(req.Opaque) {
+		return s.adminListShares(ctx, req), nil
+	}
+
+	opts, err := share.DecodeListOptions(req.Opaque)
+	if err != nil {
+		return &collaboration.ListSharesResponse{
+			Status: status.NewStatusFromErrType(ctx, "error reading list options", err),
+		}, nil
+	}
+
+	var shares []*collaboration.Share
+	var total int
+	if opts != nil {
+		shares, total, err = s.listSharesPage(ctx, req.Filters, opts)
+	} else {
+		shares, err = s.sm.ListShares(ctx, req.Filters) // TODO(labkode): add filter to share manager
+	}
 	if err != nil {
 		return &collaboration.ListSharesResponse{
 			Status: status.NewInternal(ctx, err, "error listing shares"),
@@ -167,40 +268,114 @@ func (s *service) ListShares(ctx context.Context, req *collaboration.ListSharesR
 	res := &collaboration.ListSharesResponse{
 		Status: status.NewOK(ctx),
 		Shares: shares,
+		Opaque: s.expirationsOpaque(ctx, shares),
+	}
+	if opts != nil {
+		res.Opaque = share.EncodeListTotal(res.Opaque, total)
 	}
 	return res, nil
 }
 
+// listSharesPage returns the page of the shares matching the list options,
+// applied by the manager if it is able to.
+func (s *service) listSharesPage(ctx context.Context, filters []*collaboration.ListSharesRequest_Filter, opts *share.ListOptions) ([]*collaboration.Share, int, error) {
+	if pl, ok := s.sm.(share.PagedLister); ok {
+		return pl.ListSharesPage(ctx, filters, opts)
+	}
+	shares, err := s.sm.ListShares(ctx, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, total := share.ApplyListOptions(shares, opts)
+	return page, total, nil
+}
+
 func (s *service) UpdateShare(ctx context.Context, req *collaboration.UpdateShareRequest) (*collaboration.UpdateShareResponse, error) {
-	share, err := s.sm.UpdateShare(ctx, req.Ref, req.Field.GetPermissions()) // TODO(labkode): check what to update
+	exp, setExp, err := s.checkExpiration(req.Opaque)
+	if err != nil {
+		return &collaboration.UpdateShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error reading share expiration", err),
+		}, nil
+	}
+
+	var share *collaboration.Share
+	if setExp && req.Field.GetPermissions() == nil {
+		// only the expiration is updated
+		share, err = s.sm.GetShare(ctx, req.Ref)
+	} else {
+		share, err = s.sm.UpdateShare(ctx, req.Ref, req.Field.GetPermissions()) // TODO(labkode): check what to update
+	}
 	if err != nil {
 		return &collaboration.UpdateShareResponse{
 			Status: status.NewInternal(ctx, err, "error updating share"),
 		}, nil
 	}
+	if setExp {
+		if err := s.setExpiration(ctx, share, exp); err != nil {
+			return &collaboration.UpdateShareResponse{
+				Status: status.NewInternal(ctx, err, "error setting share expiration"),
+			}, nil
+		}
+	}
 
 	res := &collaboration.UpdateShareResponse{
 		Status: status.NewOK(ctx),
 		Share:  share,
+		Opaque: s.expirationOpaque(ctx, share),
 	}
 	return res, nil
 }
 
 func (s *service) ListReceivedShares(ctx context.Context, req *collaboration.ListReceivedSharesRequest) (*collaboration.ListReceivedSharesResponse, error) {
-	shares, err := s.sm.ListReceivedShares(ctx) // TODO(labkode): check what to update
+	opts, err := share.DecodeListOptions(req.Opaque)
+	if err != nil {
+		return &collaboration.ListReceivedSharesResponse{
+			Status: status.NewStatusFromErrType(ctx, "error reading list options", err),
+		}, nil
+	}
+
+	var shares []*collaboration.ReceivedShare
+	var total int
+	if opts != nil {
+		shares, total, err = s.listReceivedSharesPage(ctx, opts)
+	} else {
+		shares, err = s.sm.ListReceivedShares(ctx) // TODO(labkode): check what to update
+	}
 	if err != nil {
 		return &collaboration.ListReceivedSharesResponse{
 			Status: status.NewInternal(ctx, err, "error listing received shares"),
 		}, nil
 	}
 
+	received := make([]*collaboration.Share, 0, len(shares))
+	for _, rs := range shares {
+		received = append(received, rs.Share)
+	}
 	res := &collaboration.ListReceivedSharesResponse{
 		Status: status.NewOK(ctx),
 		Shares: shares,
+		Opaque: s.expirationsOpaque(ctx, received),
+	}
+	if opts != nil {
+		res.Opaque = share.EncodeListTotal(res.Opaque, total)
 	}
 	return res, nil
 }
 
+// listReceivedSharesPage returns the page of the received shares matching the
+// list options, applied by the manager if it is able to.
+func (s *service) listReceivedSharesPage(ctx context.Context, opts *share.ListOptions) ([]*collaboration.ReceivedShare, int, error) {
+	if pl, ok := s.sm.(share.PagedLister); ok {
+		return pl.ListReceivedSharesPage(ctx, opts)
+	}
+	shares, err := s.sm.ListReceivedShares(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, total := share.ApplyReceivedListOptions(shares, opts)
+	return page, total, nil
+}
+
 func (s *service) GetReceivedShare(ctx context.Context, req *collaboration.GetReceivedShareRequest) (*collaboration.GetReceivedShareResponse, error) {
 	log := appctx.GetLogger(ctx)
 
@@ -223,7 +398,7 @@ func (s *service) UpdateReceivedShare(ctx context.Context, req *collaboration.Up
 	share, err := s.sm.UpdateReceivedShare(ctx, req.Ref, req.Field) // TODO(labkode): check what to update
 	if err != nil {
 		return &collaboration.UpdateReceivedShareResponse{
-			Status: status.NewInternal(ctx, err, "error updating received share"),
+			Status: status.NewStatusFromErrType(ctx, "error updating received share", err),
 		}, nil
 	}
 
@@ -57,6 +57,12 @@ type config struct {
 	TokenManagers          map[string]map[string]interface{} `mapstructure:"token_managers"`
 	TokenWriter            string                            `mapstructure:"token_writer"`
 	TokenWriters           map[string]map[string]interface{} `mapstructure:"token_writers"`
+	// AppPasswordsAuthType is the auth type the basic auth credentials
+	// refused by their auth provider are checked against as app passwords.
+	AppPasswordsAuthType string `mapstructure:"app_passwords_auth_type"`
+	// TwoFactor configures the urls requiring a second factor for the
+	// basic auth logins.
+	TwoFactor twoFactorConfig `mapstructure:"two_factor"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -142,6 +148,14 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 		return nil, err
 	}
 
+	var twoFactor *twoFactorChecker
+	if len(conf.TwoFactor.Paths) > 0 {
+		twoFactor, err = newTwoFactorChecker(&conf.TwoFactor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	chain := func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -211,7 +225,18 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 					return
 				}
 
-				res, err := client.Authenticate(ctx, req)
+				var res *gateway.AuthenticateResponse
+				if twoFactor != nil && req.Type == "basic" && twoFactor.protects(r.URL.Path) {
+					res, err = twoFactor.authenticate(ctx, client, req)
+				} else {
+					res, err = client.Authenticate(ctx, req)
+				}
+				if err == nil && res.Status.Code != rpc.Code_CODE_OK && req.Type == "basic" && conf.AppPasswordsAuthType != "" {
+					// the password may be one of the app passwords of the user
+					log.Debug().Msgf("checking the credentials as app password against %s", conf.AppPasswordsAuthType)
+					req.Type = conf.AppPasswordsAuthType
+					res, err = client.Authenticate(ctx, req)
+				}
 				if err != nil {
 					log.Error().Err(err).Msg("error calling Authenticate")
 					w.WriteHeader(http.StatusUnauthorized)
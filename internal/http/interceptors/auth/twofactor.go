@@ -0,0 +1,157 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/twofactor"
+	"github.com/cs3org/reva/pkg/twofactor/registry"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+type twoFactorConfig struct {
+	// Paths are the prefixes of the urls whose basic auth logins require
+	// the TOTP code of the user, appended to the password.
+	Paths []string `mapstructure:"paths"`
+	// Enforce refuses the basic auth logins of the users without a second
+	// factor on these urls.
+	Enforce bool                              `mapstructure:"enforce"`
+	Driver  string                            `mapstructure:"driver"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+}
+
+func (c *twoFactorConfig) init() {
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+}
+
+type twoFactorChecker struct {
+	c *twoFactorConfig
+	m twofactor.Manager
+}
+
+func newTwoFactorChecker(c *twoFactorConfig) (*twoFactorChecker, error) {
+	c.init()
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, fmt.Errorf("twofactor driver not found: %s", c.Driver)
+	}
+	m, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactorChecker{c: c, m: m}, nil
+}
+
+func (t *twoFactorChecker) protects(path string) bool {
+	return utils.Skip(path, t.c.Paths)
+}
+
+// authenticate checks the basic auth credentials of a login to a protected
+// url: either the password alone for the users without a second factor, or
+// the password followed by the current code of the user.
+func (t *twoFactorChecker) authenticate(ctx context.Context, client gateway.GatewayAPIClient, req *gateway.AuthenticateRequest) (*gateway.AuthenticateResponse, error) {
+	res, err := client.Authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code == rpc.Code_CODE_OK {
+		e, err := t.getEnrollment(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+		if (e != nil && e.Confirmed) || (e == nil && t.c.Enforce) {
+			return &gateway.AuthenticateResponse{
+				Status: status.NewUnauthenticated(ctx, nil, "second factor required"),
+			}, nil
+		}
+		return res, nil
+	}
+
+	password, code, ok := splitCode(req.ClientSecret)
+	if !ok {
+		return res, nil
+	}
+	stripped := *req
+	stripped.ClientSecret = password
+	codeRes, err := client.Authenticate(ctx, &stripped)
+	if err != nil {
+		return nil, err
+	}
+	if codeRes.Status.Code != rpc.Code_CODE_OK {
+		return res, nil
+	}
+	if codeRes.User == nil {
+		return nil, errtypes.InternalError("auth: user missing in the authenticate response")
+	}
+	// the code is checked and recorded at once, so that it cannot be
+	// replayed by a concurrent login and the failures are limited
+	valid := false
+	err = t.m.UpdateEnrollment(ctx, codeRes.User.Id, func(e *twofactor.Enrollment) error {
+		valid = e.Confirmed && e.Check(code, time.Now())
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); !ok {
+			return nil, err
+		}
+	}
+	if !valid {
+		return &gateway.AuthenticateResponse{
+			Status: status.NewUnauthenticated(ctx, nil, "invalid second factor"),
+		}, nil
+	}
+	return codeRes, nil
+}
+
+func (t *twoFactorChecker) getEnrollment(ctx context.Context, res *gateway.AuthenticateResponse) (*twofactor.Enrollment, error) {
+	if res.User == nil {
+		return nil, errtypes.InternalError("auth: user missing in the authenticate response")
+	}
+	e, err := t.m.GetEnrollment(ctx, res.User.Id)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// splitCode splits the code appended to a password.
+func splitCode(secret string) (string, string, bool) {
+	if len(secret) <= twofactor.Digits {
+		return "", "", false
+	}
+	i := len(secret) - twofactor.Digits
+	for _, c := range secret[i:] {
+		if c < '0' || c > '9' {
+			return "", "", false
+		}
+	}
+	return secret[:i], secret[i:], true
+}
@@ -0,0 +1,168 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package archiver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// archive is an archive written to a stream entry by entry.
+type archive interface {
+	// AddDir adds a folder entry.
+	AddDir(name string, mtime time.Time) error
+	// AddFile adds a file entry of the given size and returns the writer the
+	// content is written to, until the next entry is added.
+	AddFile(name string, size int64, mtime time.Time) (io.Writer, error)
+	// Close writes the end of the archive, not closing the stream.
+	Close() error
+}
+
+// formats maps the archive formats to their file extension and mime type.
+var formats = map[string]struct{ ext, mimeType string }{
+	"zip": {".zip", "application/zip"},
+	"tar": {".tar.gz", "application/gzip"},
+}
+
+func newArchive(format string, w io.Writer) (archive, error) {
+	switch format {
+	case "zip":
+		return &zipArchive{w: zip.NewWriter(w)}, nil
+	case "tar":
+		gz := gzip.NewWriter(w)
+		return &tarArchive{gz: gz, w: tar.NewWriter(gz)}, nil
+	}
+	return nil, errors.New("unknown archive format " + format)
+}
+
+type zipArchive struct {
+	w *zip.Writer
+}
+
+func (a *zipArchive) AddDir(name string, mtime time.Time) error {
+	_, err := a.w.CreateHeader(&zip.FileHeader{
+		Name:     name + "/",
+		Method:   zip.Store,
+		Modified: mtime,
+	})
+	return err
+}
+
+func (a *zipArchive) AddFile(name string, size int64, mtime time.Time) (io.Writer, error) {
+	return a.w.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: mtime,
+	})
+}
+
+func (a *zipArchive) Close() error {
+	return a.w.Close()
+}
+
+type tarArchive struct {
+	gz *gzip.Writer
+	w  *tar.Writer
+	// pending is the file entry being written, whose size is fixed upfront
+	pending *tarEntry
+}
+
+func (a *tarArchive) AddDir(name string, mtime time.Time) error {
+	if err := a.finish(); err != nil {
+		return err
+	}
+	return a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     0755,
+		ModTime:  mtime,
+	})
+}
+
+func (a *tarArchive) AddFile(name string, size int64, mtime time.Time) (io.Writer, error) {
+	if err := a.finish(); err != nil {
+		return nil, err
+	}
+	if err := a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  mtime,
+	}); err != nil {
+		return nil, err
+	}
+	a.pending = &tarEntry{w: a.w, left: size}
+	return a.pending, nil
+}
+
+// finish pads the pending entry with zeros when less content than announced
+// was written, which keeps the archive readable when a download fails.
+func (a *tarArchive) finish() error {
+	if a.pending == nil {
+		return nil
+	}
+	e := a.pending
+	a.pending = nil
+	if e.left > 0 {
+		if _, err := io.CopyN(a.w, zeros{}, e.left); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *tarArchive) Close() error {
+	if err := a.finish(); err != nil {
+		return err
+	}
+	if err := a.w.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+// tarEntry writes the content of a file entry, up to its announced size.
+type tarEntry struct {
+	w    io.Writer
+	left int64
+}
+
+func (e *tarEntry) Write(p []byte) (int, error) {
+	if int64(len(p)) > e.left {
+		return 0, errors.New("file larger than announced")
+	}
+	n, err := e.w.Write(p)
+	e.left -= int64(n)
+	return n, err
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
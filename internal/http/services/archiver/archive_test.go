@@ -0,0 +1,146 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package archiver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func writeArchive(t *testing.T, format string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	a, err := newArchive(format, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddDir("docs", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	w, err := a.AddFile("docs/a.txt", 5, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	// a download failing halfway leaves a shorter file
+	w, err = a.AddFile("docs/b.txt", 4, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "ab"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestZipArchive(t *testing.T) {
+	buf := writeArchive(t, "zip")
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"docs/", "docs/a.txt", "docs/b.txt"}
+	if len(r.File) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(r.File))
+	}
+	for i, f := range r.File {
+		if f.Name != expected[i] {
+			t.Errorf("expected entry %s, got %s", expected[i], f.Name)
+		}
+	}
+	rc, err := r.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "hello" {
+		t.Errorf("unexpected content %q", b)
+	}
+}
+
+func TestTarArchive(t *testing.T) {
+	buf := writeArchive(t, "tar")
+	gz, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := tar.NewReader(gz)
+	var names []string
+	contents := map[string]string{}
+	for {
+		h, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+		b, _ := ioutil.ReadAll(r)
+		contents[h.Name] = string(b)
+	}
+	if len(names) != 3 || names[0] != "docs/" {
+		t.Fatalf("unexpected entries %v", names)
+	}
+	if contents["docs/a.txt"] != "hello" {
+		t.Errorf("unexpected content %q", contents["docs/a.txt"])
+	}
+	if contents["docs/b.txt"] != "ab\x00\x00" {
+		t.Errorf("expected the truncated file padded, got %q", contents["docs/b.txt"])
+	}
+}
+
+func TestTarEntryTooLarge(t *testing.T) {
+	a, err := newArchive("tar", ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := a.AddFile("a.txt", 2, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "abc"); err == nil {
+		t.Error("expected an error writing more than announced")
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	wk := &walker{names: map[string]bool{}}
+	for _, tc := range []struct{ name, expected string }{
+		{"docs", "docs"},
+		{"docs", "docs (2)"},
+		{"a.txt", "a.txt"},
+		{"a.txt", "a (2).txt"},
+		{"a.txt", "a (3).txt"},
+	} {
+		if n := wk.uniqueName(tc.name); n != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, n)
+		}
+	}
+}
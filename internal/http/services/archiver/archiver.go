@@ -0,0 +1,392 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package archiver implements an HTTP service streaming folders and files of
+// any storage as a zip or tar.gz archive, assembled while the files are
+// downloaded through the data gateway.
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("archiver", New)
+}
+
+// errorsFileName is the name of the file listing the resources which could
+// not be archived, added at the end of an archive.
+const errorsFileName = "ERRORS.txt"
+
+type config struct {
+	Prefix     string `mapstructure:"prefix" docs:"archiver;The prefix to be used for this HTTP service"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	Timeout    int64  `mapstructure:"timeout"`
+	Insecure   bool   `mapstructure:"insecure"`
+	// Namespace is the namespace the paths of the requests are relative to.
+	Namespace   string `mapstructure:"namespace" docs:"/home;The namespace the paths of the requests are relative to."`
+	MaxNumFiles int    `mapstructure:"max_num_files" docs:"10000;The maximum number of files and folders in an archive."`
+	MaxSize     uint64 `mapstructure:"max_size" docs:"1073741824;The maximum total size of the files in an archive."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "archiver"
+	}
+	if c.Namespace == "" {
+		c.Namespace = "/home"
+	}
+	if c.MaxNumFiles == 0 {
+		c.MaxNumFiles = 10000
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = 1024 * 1024 * 1024
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf   *config
+	client *http.Client
+}
+
+// New returns a new archiver service.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+
+	conf.init()
+
+	return &svc{
+		conf: conf,
+		client: rhttp.GetHTTPClient(
+			rhttp.Timeout(time.Duration(conf.Timeout*int64(time.Second))),
+			rhttp.Insecure(conf.Insecure),
+		),
+	}, nil
+}
+
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return nil
+}
+
+// entry is a resource of an archive.
+type entry struct {
+	name string
+	info *provider.ResourceInfo
+}
+
+// Handler streams the archive of the resources given by the path query
+// parameters, relative to the namespace, and the id query parameters, of the
+// form <storage id>:<opaque id>. The format query parameter is zip or tar.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx).With().Str("svc", "archiver").Logger()
+
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "zip"
+		}
+		f, ok := formats[format]
+		if !ok {
+			http.Error(w, "400 Bad Request: unknown format", http.StatusBadRequest)
+			return
+		}
+		refs, err := s.references(q)
+		if err != nil {
+			http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+		if err != nil {
+			log.Error().Err(err).Msg("error getting grpc gateway client")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		roots := make([]*provider.ResourceInfo, 0, len(refs))
+		for _, ref := range refs {
+			res, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+			if err != nil {
+				log.Error().Err(err).Msg("error sending grpc stat request")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				writeStatus(&log, w, res.Status)
+				return
+			}
+			roots = append(roots, res.Info)
+		}
+
+		wk := &walker{client: client, maxNumFiles: s.conf.MaxNumFiles, maxSize: s.conf.MaxSize, names: map[string]bool{}}
+		for _, info := range roots {
+			if err := wk.walk(ctx, info, wk.uniqueName(path.Base(info.Path))); err != nil {
+				if _, ok := err.(limitError); ok {
+					http.Error(w, "400 Bad Request: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+				log.Error().Err(err).Msg("error listing the resources to archive")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		name := "download"
+		if len(roots) == 1 {
+			name = path.Base(roots[0].Path)
+		}
+		name += f.ext
+		w.Header().Set("Content-Type", f.mimeType)
+		w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+
+			url.PathEscape(name)+"; filename=\""+name+"\"")
+		w.WriteHeader(http.StatusOK)
+
+		a, err := newArchive(format, w)
+		if err != nil {
+			log.Error().Err(err).Msg("error creating the archive")
+			return
+		}
+		failures := wk.failures
+		for _, e := range wk.entries {
+			mtime := utils.TSToTime(e.info.Mtime)
+			if e.info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				err = a.AddDir(e.name, mtime)
+			} else {
+				var fw io.Writer
+				if fw, err = a.AddFile(e.name, int64(e.info.Size), mtime); err == nil {
+					if derr := s.download(ctx, client, e.info, fw); derr != nil {
+						if ctx.Err() != nil {
+							err = ctx.Err()
+						} else {
+							log.Warn().Err(derr).Str("path", e.info.Path).Msg("error archiving file")
+							failures = append(failures, fmt.Sprintf("%s: %v", e.name, derr))
+						}
+					}
+				}
+			}
+			if err != nil {
+				// the client is gone or the stream broken, nothing more can be written
+				log.Error().Err(err).Msg("error writing the archive")
+				return
+			}
+		}
+
+		// the errors can only be told in the archive once the response is sent
+		if len(failures) > 0 {
+			report := strings.Join(failures, "\n") + "\n"
+			fw, err := a.AddFile(wk.uniqueName(errorsFileName), int64(len(report)), time.Now())
+			if err == nil {
+				_, err = io.WriteString(fw, report)
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("error writing the archive")
+				return
+			}
+		}
+		if err := a.Close(); err != nil {
+			log.Error().Err(err).Msg("error writing the archive")
+		}
+	})
+}
+
+// references returns the references of the resources to archive given in the query.
+func (s *svc) references(q url.Values) ([]*provider.Reference, error) {
+	var refs []*provider.Reference
+	for _, p := range q["path"] {
+		refs = append(refs, &provider.Reference{
+			Spec: &provider.Reference_Path{Path: path.Join(s.conf.Namespace, p)},
+		})
+	}
+	for _, id := range q["id"] {
+		parts := strings.SplitN(id, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.New("invalid id " + id)
+		}
+		refs = append(refs, &provider.Reference{
+			Spec: &provider.Reference_Id{Id: &provider.ResourceId{StorageId: parts[0], OpaqueId: parts[1]}},
+		})
+	}
+	if len(refs) == 0 {
+		return nil, errors.New("no resource to archive")
+	}
+	return refs, nil
+}
+
+// limitError is returned when the resources exceed the limits of an archive.
+type limitError string
+
+func (e limitError) Error() string { return string(e) }
+
+// walker lists the resources to archive, depth first.
+type walker struct {
+	client      gateway.GatewayAPIClient
+	maxNumFiles int
+	maxSize     uint64
+
+	entries  []entry
+	size     uint64
+	failures []string
+	// names are the names at the root of the archive
+	names map[string]bool
+}
+
+// uniqueName returns a name at the root of the archive not taken yet.
+func (wk *walker) uniqueName(name string) string {
+	unique := name
+	for i := 2; wk.names[unique]; i++ {
+		ext := path.Ext(name)
+		unique = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	wk.names[unique] = true
+	return unique
+}
+
+func (wk *walker) walk(ctx context.Context, info *provider.ResourceInfo, name string) error {
+	wk.entries = append(wk.entries, entry{name: name, info: info})
+	if len(wk.entries) > wk.maxNumFiles {
+		return limitError(fmt.Sprintf("more than %d files", wk.maxNumFiles))
+	}
+	if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		wk.size += info.Size
+		if wk.size > wk.maxSize {
+			return limitError(fmt.Sprintf("larger than %d bytes", wk.maxSize))
+		}
+		return nil
+	}
+
+	res, err := wk.client.ListContainer(ctx, &provider.ListContainerRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Path{Path: info.Path}},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error sending list container grpc request")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		// the folder goes empty into the archive, the others are still archived
+		wk.failures = append(wk.failures, fmt.Sprintf("%s: %s", name, res.Status.Message))
+		return nil
+	}
+	children := res.Infos
+	sort.Slice(children, func(i, j int) bool { return children[i].Path < children[j].Path })
+	for _, child := range children {
+		if err := wk.walk(ctx, child, path.Join(name, path.Base(child.Path))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// download writes the content of the file, downloaded through the data gateway, to w.
+func (s *svc) download(ctx context.Context, client gateway.GatewayAPIClient, info *provider.ResourceInfo, w io.Writer) error {
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: info.Path}}
+	dRes, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: ref})
+	if err != nil {
+		return errors.Wrap(err, "error initiating file download")
+	}
+	if dRes.Status.Code != rpc.Code_CODE_OK {
+		return errors.New("error initiating file download: " + dRes.Status.Message)
+	}
+
+	var ep, token string
+	for _, p := range dRes.Protocols {
+		if p.Protocol == "simple" {
+			ep, token = p.DownloadEndpoint, p.Token
+		}
+	}
+	if ep == "" {
+		return errors.New("no simple download protocol available")
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, "GET", ep, nil)
+	if err != nil {
+		return errors.Wrap(err, "error creating http request")
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		return errors.Wrap(err, "error performing http request")
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading file: %s", httpRes.Status)
+	}
+
+	// the size announced in the archive can't change anymore
+	n, err := io.Copy(w, io.LimitReader(httpRes.Body, int64(info.Size)))
+	if err != nil {
+		return errors.Wrap(err, "error copying file content")
+	}
+	if uint64(n) != info.Size {
+		return fmt.Errorf("file truncated after %d of %d bytes", n, info.Size)
+	}
+	return nil
+}
+
+func writeStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status) {
+	switch s.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		w.WriteHeader(http.StatusForbidden)
+	case rpc.Code_CODE_UNAUTHENTICATED:
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		log.Error().Interface("status", s).Msg("grpc request failed")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
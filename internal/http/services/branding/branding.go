@@ -0,0 +1,262 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package branding
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("branding", New)
+}
+
+// Branding holds the data the landing pages of public links are styled with.
+// Empty fields are inherited from the deployment branding.
+type Branding struct {
+	LogoURL        string `mapstructure:"logo_url" json:"logo_url,omitempty"`
+	PrimaryColor   string `mapstructure:"primary_color" json:"primary_color,omitempty"`
+	SecondaryColor string `mapstructure:"secondary_color" json:"secondary_color,omitempty"`
+	FooterText     string `mapstructure:"footer_text" json:"footer_text,omitempty"`
+	TermsURL       string `mapstructure:"terms_url" json:"terms_url,omitempty"`
+}
+
+// merge returns the branding with its empty fields taken from def.
+func (b Branding) merge(def Branding) Branding {
+	if b.LogoURL == "" {
+		b.LogoURL = def.LogoURL
+	}
+	if b.PrimaryColor == "" {
+		b.PrimaryColor = def.PrimaryColor
+	}
+	if b.SecondaryColor == "" {
+		b.SecondaryColor = def.SecondaryColor
+	}
+	if b.FooterText == "" {
+		b.FooterText = def.FooterText
+	}
+	if b.TermsURL == "" {
+		b.TermsURL = def.TermsURL
+	}
+	return b
+}
+
+// brandings is the branding of the deployment and its per space overrides.
+type brandings struct {
+	Default Branding            `mapstructure:"default" json:"default"`
+	Spaces  map[string]Branding `mapstructure:"spaces" json:"spaces"`
+}
+
+func (b *brandings) get(space string) Branding {
+	if sb, ok := b.Spaces[space]; ok && space != "" {
+		return sb.merge(b.Default)
+	}
+	return b.Default
+}
+
+type config struct {
+	Prefix     string `mapstructure:"prefix"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// File is a JSON file with the default and spaces brandings. It takes
+	// precedence over the ones in the config and is re-read when modified.
+	File    string              `mapstructure:"file"`
+	Default Branding            `mapstructure:"default"`
+	Spaces  map[string]Branding `mapstructure:"spaces"`
+	// MaxAge is the time in seconds clients may cache the branding for.
+	MaxAge int `mapstructure:"max_age"`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "branding"
+	}
+	if c.MaxAge == 0 {
+		c.MaxAge = 300
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf    *config
+	handler http.Handler
+
+	sync.Mutex
+	brandings *brandings
+	modTime   time.Time
+}
+
+// New returns a new branding service.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+
+	conf.init()
+
+	s := &svc{
+		conf:      conf,
+		brandings: &brandings{Default: conf.Default, Spaces: conf.Spaces},
+	}
+	if conf.File != "" {
+		if _, err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+	s.setHandler()
+	return s, nil
+}
+
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Handler() http.Handler {
+	return s.handler
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+// load returns the current brandings, reading the file again if it changed.
+func (s *svc) load() (*brandings, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.conf.File == "" {
+		return s.brandings, nil
+	}
+	fi, err := os.Stat(s.conf.File)
+	if err != nil {
+		return s.brandings, errors.Wrap(err, "branding: error reading file")
+	}
+	if !fi.ModTime().After(s.modTime) {
+		return s.brandings, nil
+	}
+
+	data, err := ioutil.ReadFile(s.conf.File)
+	if err != nil {
+		return s.brandings, errors.Wrap(err, "branding: error reading file")
+	}
+	b := &brandings{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return s.brandings, errors.Wrap(err, "branding: error decoding file")
+	}
+	s.brandings = b
+	s.modTime = fi.ModTime()
+	return b, nil
+}
+
+func (s *svc) setHandler() {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := appctx.GetLogger(r.Context())
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		b, err := s.load()
+		if err != nil {
+			// keep serving the last good branding
+			log.Error().Err(err).Msg("error loading branding")
+		}
+
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+		switch head {
+		case "":
+			s.write(w, r, b.Default)
+		case "spaces":
+			space, _ := router.ShiftPath(r.URL.Path)
+			s.write(w, r, b.get(space))
+		case "public":
+			token, _ := router.ShiftPath(r.URL.Path)
+			s.write(w, r, b.get(s.publicShareSpace(r, token)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// publicShareSpace returns the space the public share with the given token
+// points into. The deployment branding is used for unknown tokens and for
+// password protected shares, which can't be resolved by token alone.
+func (s *svc) publicShareSpace(r *http.Request, token string) string {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+	if token == "" {
+		return ""
+	}
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc gateway client")
+		return ""
+	}
+	res, err := client.GetPublicShareByToken(ctx, &link.GetPublicShareByTokenRequest{Token: token})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending a grpc get public share by token request")
+		return ""
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		log.Debug().Str("token", token).Interface("status", res.Status).Msg("public share not resolved, using the default branding")
+		return ""
+	}
+	return res.GetShare().GetResourceId().GetStorageId()
+}
+
+func (s *svc) write(w http.ResponseWriter, r *http.Request, b Branding) {
+	log := appctx.GetLogger(r.Context())
+	data, err := json.Marshal(b)
+	if err != nil {
+		log.Error().Err(err).Msg("error encoding branding")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(s.conf.MaxAge))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("error writing response")
+	}
+}
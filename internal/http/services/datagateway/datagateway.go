@@ -25,12 +25,14 @@ import (
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/ratelimit"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/mitchellh/mapstructure"
@@ -57,6 +59,10 @@ type config struct {
 	TransferSharedSecret string `mapstructure:"transfer_shared_secret"`
 	Timeout              int64  `mapstructure:"timeout"`
 	Insecure             bool   `mapstructure:"insecure"`
+	// MTLS calls the dataproviders over mutual TLS with the certificate of the process.
+	MTLS bool `mapstructure:"mtls"`
+	// RateLimit shapes the bandwidth of the transfers per user or client address.
+	RateLimit ratelimit.Config `mapstructure:"rate_limit"`
 }
 
 func (c *config) init() {
@@ -87,6 +93,7 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 		client: rhttp.GetHTTPClient(
 			rhttp.Timeout(time.Duration(conf.Timeout*int64(time.Second))),
 			rhttp.Insecure(conf.Insecure),
+			rhttp.MutualTLS(conf.MTLS),
 		),
 	}
 	s.setHandler()
@@ -126,11 +133,17 @@ func (s *svc) setHandler() {
 		case "PATCH":
 			s.doPatch(w, r)
 			return
+		case "POST":
+			s.doPost(w, r)
+			return
 		default:
 			w.WriteHeader(http.StatusNotImplemented)
 			return
 		}
 	})
+	if s.conf.RateLimit.Enabled() {
+		s.handler = ratelimit.New(&s.conf.RateLimit).Handler(s.handler)
+	}
 }
 
 func addCorsHeader(res http.ResponseWriter) {
@@ -148,6 +161,10 @@ func (s *svc) verify(ctx context.Context, r *http.Request) (*transferClaims, err
 		r.Header.Set(TokenTransportHeader, token)
 	}
 
+	return s.verifyToken(token)
+}
+
+func (s *svc) verifyToken(token string) (*transferClaims, error) {
 	j, err := jwt.ParseWithClaims(token, &transferClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(s.conf.TransferSharedSecret), nil
 	})
@@ -373,6 +390,87 @@ func (s *svc) doPatch(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// doPost creates the final upload of the tus concatenation extension. The
+// Upload-Concat header lists the urls of the partial uploads as handed out by
+// the gateway, each one carrying its transfer token. Every token is verified and
+// replaced by the upload it grants access to on the data server.
+func (s *svc) doPost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	concat := r.Header.Get("Upload-Concat")
+	if !strings.HasPrefix(concat, "final;") {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	if _, err := s.verify(ctx, r); err != nil {
+		err = errors.Wrap(err, "datagateway: error validating transfer token")
+		log.Err(err).Str("token", r.Header.Get(TokenTransportHeader)).Msg("invalid transfer token")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var target *url.URL
+	uploads := []string{}
+	for _, u := range strings.Fields(strings.TrimPrefix(concat, "final;")) {
+		claims, err := s.verifyToken(path.Base(u))
+		if err != nil {
+			err = errors.Wrap(err, "datagateway: error validating transfer token")
+			log.Err(err).Str("upload", u).Msg("invalid transfer token for partial upload")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		partialURL, err := url.Parse(claims.Target)
+		if err != nil {
+			log.Err(err).Msg("datagateway: error parsing target url")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// all the partial uploads have to live on the same data server
+		dir := *partialURL
+		dir.Path = path.Dir(partialURL.Path)
+		if target == nil {
+			target = &dir
+		} else if dir.String() != target.String() {
+			log.Warn().Str("upload", u).Msg("datagateway: partial uploads on different data servers")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		uploads = append(uploads, path.Base(partialURL.Path))
+	}
+	if target == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	log.Debug().Str("target", target.String()).Strs("uploads", uploads).Msg("sending concatenation request to internal data server")
+
+	httpReq, err := rhttp.NewRequest(ctx, "POST", target.String(), nil)
+	if err != nil {
+		log.Err(err).Msg("wrong request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpReq.Header = r.Header.Clone()
+	httpReq.Header.Set("Upload-Concat", "final;"+strings.Join(uploads, " "))
+
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		log.Err(err).Msg("error doing POST request to data service")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer httpRes.Body.Close()
+
+	// the location of the final upload is internal to the data server
+	// and of no use to the client, as the upload is already finished
+	copyHeader(w.Header(), httpRes.Header)
+	w.Header().Del("Location")
+	w.WriteHeader(httpRes.StatusCode)
+}
+
 func copyHeader(dst, src http.Header) {
 	for key, values := range src {
 		for i := range values {
@@ -0,0 +1,218 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	avregistry "github.com/cs3org/reva/pkg/antivirus/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/pkg/errors"
+)
+
+type antivirusConfig struct {
+	Enabled        bool                              `mapstructure:"enabled" docs:"false;Whether to scan the uploads completed on this dataprovider."`
+	Driver         string                            `mapstructure:"driver" docs:"clamd;The antivirus scanner to be used."`
+	Drivers        map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/antivirus/clamd/clamd.go;The configuration for the antivirus scanners."`
+	InfectedPolicy string                            `mapstructure:"infected_policy" docs:"delete;What to do with infected files: delete, quarantine or log."`
+	QuarantineDir  string                            `mapstructure:"quarantine_dir" docs:"/var/tmp/reva/quarantine;The local directory where infected files are copied to with the quarantine policy."`
+	AsyncThreshold int64                             `mapstructure:"async_threshold" docs:"0;Uploads of at least this many bytes are scanned in the background instead of delaying the response. 0 scans all uploads synchronously."`
+	EventEndpoint  string                            `mapstructure:"event_endpoint" docs:"nil;An HTTP endpoint the events about infected files are posted to as JSON."`
+}
+
+func (c *antivirusConfig) init() {
+	if c.Driver == "" {
+		c.Driver = "clamd"
+	}
+	if c.InfectedPolicy == "" {
+		c.InfectedPolicy = antivirus.PolicyDelete
+	}
+	if c.QuarantineDir == "" {
+		c.QuarantineDir = "/var/tmp/reva/quarantine"
+	}
+}
+
+// uploadScanner scans the files written by the uploads passing through the
+// data transfer handlers once they complete.
+type uploadScanner struct {
+	conf    *antivirusConfig
+	scanner antivirus.Scanner
+	fs      storage.FS
+	client  *http.Client
+}
+
+func newUploadScanner(c *antivirusConfig, fs storage.FS) (*uploadScanner, error) {
+	c.init()
+
+	switch c.InfectedPolicy {
+	case antivirus.PolicyDelete, antivirus.PolicyLog:
+	case antivirus.PolicyQuarantine:
+		if err := os.MkdirAll(c.QuarantineDir, 0700); err != nil {
+			return nil, errors.Wrap(err, "dataprovider: error creating quarantine dir")
+		}
+	default:
+		return nil, fmt.Errorf("dataprovider: unknown infected policy: %s", c.InfectedPolicy)
+	}
+
+	f, ok := avregistry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, fmt.Errorf("antivirus driver not found: %s", c.Driver)
+	}
+	scanner, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+
+	return &uploadScanner{
+		conf:    c,
+		scanner: scanner,
+		fs:      fs,
+		client:  rhttp.GetHTTPClient(rhttp.Timeout(10 * time.Second)),
+	}, nil
+}
+
+// handler wraps a data transfer handler, scanning the resource of every upload it completes.
+func (u *uploadScanner) handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := getUploadSession(r, u.fs)
+		if session == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// hold back the response until we know whether the file is clean
+		rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+
+		if uploadCompleted(r, rec, session) {
+			if u.conf.AsyncThreshold > 0 && session.Size >= u.conf.AsyncThreshold {
+				go u.scanAndLog(appctx.DetachContext(r.Context()), session.Ref)
+			} else if u.scanAndLog(r.Context(), session.Ref) {
+				rec.status = http.StatusForbidden
+				rec.body.Reset()
+			}
+		}
+		rec.flush(w)
+	})
+}
+
+// scanAndLog scans the given resource like scan, only logging the errors.
+func (u *uploadScanner) scanAndLog(ctx context.Context, ref *provider.Reference) bool {
+	removed, err := u.scan(ctx, ref)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", ref.GetPath()).Msg("dataprovider: error scanning file")
+	}
+	return removed
+}
+
+// scan scans the given resource and applies the infected policy,
+// returning whether the file was removed.
+func (u *uploadScanner) scan(ctx context.Context, ref *provider.Reference) (bool, error) {
+	log := appctx.GetLogger(ctx).With().Str("path", ref.GetPath()).Logger()
+
+	rc, err := u.fs.Download(ctx, ref)
+	if err != nil {
+		return false, errors.Wrap(err, "dataprovider: error downloading file to scan")
+	}
+	defer rc.Close()
+
+	var src io.Reader = rc
+	var quarantined *os.File
+	if u.conf.InfectedPolicy == antivirus.PolicyQuarantine {
+		if quarantined, err = ioutil.TempFile(u.conf.QuarantineDir, ".scan-"); err != nil {
+			return false, errors.Wrap(err, "dataprovider: error creating quarantine file")
+		}
+		defer func() {
+			quarantined.Close()
+			os.Remove(quarantined.Name())
+		}()
+		src = io.TeeReader(rc, quarantined)
+	}
+
+	res, err := u.scanner.Scan(ctx, src)
+	if err != nil {
+		return false, err
+	}
+	if !res.Infected {
+		return false, nil
+	}
+
+	action := u.conf.InfectedPolicy
+	if quarantined != nil {
+		name := fmt.Sprintf("%d-%s", time.Now().Unix(), strings.ReplaceAll(strings.TrimPrefix(ref.GetPath(), "/"), "/", "_"))
+		if err := os.Link(quarantined.Name(), filepath.Join(u.conf.QuarantineDir, name)); err != nil {
+			// keep the file in place rather than losing the only copy
+			log.Error().Err(err).Msg("dataprovider: error quarantining infected file")
+			action = antivirus.PolicyLog
+		}
+	}
+	if action != antivirus.PolicyLog {
+		if err := u.fs.Delete(ctx, ref); err != nil {
+			log.Error().Err(err).Msg("dataprovider: error deleting infected file")
+			action = antivirus.PolicyLog
+		}
+	}
+
+	u.emit(ctx, &antivirus.Event{
+		Type:        antivirus.EventInfected,
+		Time:        time.Now(),
+		Path:        ref.GetPath(),
+		Description: res.Description,
+		Action:      action,
+	})
+	return action != antivirus.PolicyLog, nil
+}
+
+func (u *uploadScanner) emit(ctx context.Context, e *antivirus.Event) {
+	log := appctx.GetLogger(ctx)
+	if usr, ok := user.ContextGetUser(ctx); ok {
+		e.User = usr.Username
+	}
+	log.Warn().Str("event", e.Type).Str("path", e.Path).Str("user", e.User).Str("threat", e.Description).Str("action", e.Action).Msg("dataprovider: infected file found")
+
+	if u.conf.EventEndpoint == "" {
+		return
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Error().Err(err).Msg("dataprovider: error encoding antivirus event")
+		return
+	}
+	res, err := u.client.Post(u.conf.EventEndpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Msg("dataprovider: error sending antivirus event")
+		return
+	}
+	res.Body.Close()
+}
@@ -19,16 +19,29 @@
 package dataprovider
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"path"
+	"sync/atomic"
 
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/rhttp/bodylimit"
 	datatxregistry "github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
 	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/ratelimit"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/user"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
@@ -37,12 +50,22 @@ func init() {
 }
 
 type config struct {
-	Prefix   string                            `mapstructure:"prefix" docs:"data;The prefix to be used for this HTTP service"`
-	Driver   string                            `mapstructure:"driver" docs:"localhome;The storage driver to be used."`
-	Drivers  map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/storage/fs/localhome/localhome.go;The configuration for the storage driver"`
-	DataTXs  map[string]map[string]interface{} `mapstructure:"data_txs" docs:"url:pkg/rhttp/datatx/manager/simple/simple.go;The configuration for the data tx protocols"`
-	Timeout  int64                             `mapstructure:"timeout"`
-	Insecure bool                              `mapstructure:"insecure"`
+	Prefix         string                            `mapstructure:"prefix" docs:"data;The prefix to be used for this HTTP service"`
+	Driver         string                            `mapstructure:"driver" docs:"localhome;The storage driver to be used."`
+	Drivers        map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/storage/fs/localhome/localhome.go;The configuration for the storage driver"`
+	DataTXs        map[string]map[string]interface{} `mapstructure:"data_txs" docs:"url:pkg/rhttp/datatx/manager/simple/simple.go;The configuration for the data tx protocols"`
+	Timeout        int64                             `mapstructure:"timeout"`
+	Insecure       bool                              `mapstructure:"insecure"`
+	Wrappers       []string                          `mapstructure:"wrappers" docs:"nil;List of storage wrappers applied to the driver, the first one being the outermost."`
+	WrapperConfigs map[string]map[string]interface{} `mapstructure:"wrapper_configs" docs:"url:pkg/storage/wrappers/readonly/readonly.go;The configuration for the storage wrappers"`
+	Antivirus      antivirusConfig                   `mapstructure:"antivirus" docs:"nil;The antivirus scanning of completed uploads."`
+	Postprocessing postprocessingConfig              `mapstructure:"postprocessing" docs:"nil;The post-processing of completed uploads in the background, before they are listed."`
+	Search         searchConfig                      `mapstructure:"search" docs:"nil;The indexing of completed uploads by the search service."`
+	UploadHooks    uploadHooksConfig                 `mapstructure:"upload_hooks" docs:"nil;The HTTP callbacks notified about completed uploads."`
+	EventStream    string                            `mapstructure:"event_stream" docs:"nil;The event stream the completed uploads are published to, none disables them."`
+	EventStreams   map[string]map[string]interface{} `mapstructure:"event_streams" docs:"url:pkg/events/nats/nats.go;The configuration for the event streams."`
+	RateLimit      ratelimit.Config                  `mapstructure:"rate_limit" docs:"url:pkg/rhttp/ratelimit/ratelimit.go;The bandwidth limits of the transfers per user or client address."`
+	BodyLimit      bodylimit.Config                  `mapstructure:"body_limit" docs:"url:pkg/rhttp/bodylimit/bodylimit.go;The size limits of the uploads, per method."`
 }
 
 func (c *config) init() {
@@ -55,10 +78,16 @@ func (c *config) init() {
 }
 
 type svc struct {
+	// uploads is the number of upload requests being served, first for the
+	// alignment of the atomic operations.
+	uploads int64
 	conf    *config
 	handler http.Handler
 	storage storage.FS
 	dataTXs map[string]http.Handler
+	events  *events.Emitter
+	// postprocessor is nil when the post-processing is disabled.
+	postprocessor *postprocessor
 }
 
 // New returns a new datasvc
@@ -75,52 +104,186 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 		return nil, err
 	}
 
-	dataTXs, err := getDataTXs(conf, fs)
+	dataTXs, err := getDataTXs(conf, fs, log)
 	if err != nil {
 		return nil, err
 	}
 
+	emitter, err := eventsregistry.NewEmitter(conf.EventStream, conf.EventStreams)
+	if err != nil {
+		return nil, err
+	}
+
+	// the antivirus step of the post-processing replaces the synchronous scanning
+	if conf.Antivirus.Enabled && !conf.Postprocessing.has(stepAntivirus) {
+		scanner, err := newUploadScanner(&conf.Antivirus, fs)
+		if err != nil {
+			return nil, err
+		}
+		for t, h := range dataTXs {
+			dataTXs[t] = scanner.handler(h)
+		}
+	}
+
+	// wrapped after the scanner so that the infected files it rejects are not processed
+	var p *postprocessor
+	if len(conf.Postprocessing.Steps) > 0 {
+		p, err = newPostprocessor(&conf.Postprocessing, &conf.Antivirus, fs, emitter)
+		if err != nil {
+			return nil, err
+		}
+		for t, h := range dataTXs {
+			dataTXs[t] = p.handler(h)
+		}
+	}
+
+	// wrapped last so that the infected files rejected by the scanner are not indexed
+	if conf.Search.Enabled {
+		indexer := newUploadIndexer(&conf.Search, fs)
+		for t, h := range dataTXs {
+			dataTXs[t] = indexer.handler(h)
+		}
+	}
+
+	if len(conf.UploadHooks.Hooks) > 0 {
+		notifier, err := newUploadNotifier(&conf.UploadHooks, fs)
+		if err != nil {
+			return nil, err
+		}
+		for t, h := range dataTXs {
+			dataTXs[t] = notifier.handler(h)
+		}
+	}
+
+	// the event step of the post-processing emits the event once the file is processed
+	if emitter != nil && !conf.Postprocessing.has(stepEvent) {
+		for t, h := range dataTXs {
+			dataTXs[t] = onUploadCompleted(h, fs, func(r *http.Request, session *storage.UploadSession) {
+				ctx := appctx.DetachContext(r.Context())
+				go emitter.Emit(ctx, newFileUploaded(ctx, fs, session.Ref))
+			})
+		}
+	}
+
+	// wrapped outermost so that no handler reads more than the declared length
+	uploadLimiter := bodylimit.New(&bodylimit.Config{})
+	for t, h := range dataTXs {
+		dataTXs[t] = limitUploads(h, fs, uploadLimiter)
+	}
+
 	s := &svc{
-		storage: fs,
-		conf:    conf,
-		dataTXs: dataTXs,
+		storage:       fs,
+		conf:          conf,
+		dataTXs:       dataTXs,
+		events:        emitter,
+		postprocessor: p,
 	}
 
 	err = s.setHandler()
+	ops.Register(ops.Uploads, "dataprovider/"+conf.Prefix, s.probe)
+	metrics.RegisterCollector("dataprovider/"+conf.Prefix, s.collect)
 	return s, err
 }
 
+// collect reports the uploads in progress, by driver.
+func (s *svc) collect() []metrics.Sample {
+	return []metrics.Sample{{
+		Name:   "dataprovider_uploads_active",
+		Help:   "The upload requests the data providers are serving",
+		Labels: map[string]string{"prefix": s.conf.Prefix, "driver": s.conf.Driver},
+		Value:  float64(atomic.LoadInt64(&s.uploads)),
+	}}
+}
+
+// probe reports the uploads in progress.
+func (s *svc) probe() ops.State {
+	return ops.State{
+		Health:  ops.Healthy,
+		Details: map[string]interface{}{"active": atomic.LoadInt64(&s.uploads)},
+	}
+}
+
+// newFileUploaded returns the event for an upload to the given reference,
+// completed with the id of the file and of the folder it was uploaded to.
+func newFileUploaded(ctx context.Context, fs storage.FS, ref *provider.Reference) *events.FileUploaded {
+	ev := &events.FileUploaded{Path: ref.GetPath(), ResourceID: ref.GetId()}
+	if u, ok := user.ContextGetUser(ctx); ok {
+		ev.Executant = u.Id
+	}
+
+	info, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		appctx.GetLogger(ctx).Debug().Err(err).Interface("ref", ref).Msg("dataprovider: error stating uploaded file")
+		return ev
+	}
+	ev.ResourceID, ev.Path = info.Id, info.Path
+	parent, err := fs.GetMD(ctx, &provider.Reference{Spec: &provider.Reference_Path{Path: path.Dir(info.Path)}}, nil)
+	if err == nil {
+		ev.ParentID = parent.Id
+	}
+	return ev
+}
+
 func getFS(c *config) (storage.FS, error) {
-	if f, ok := registry.NewFuncs[c.Driver]; ok {
-		return f(c.Drivers[c.Driver])
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, fmt.Errorf("driver not found: %s", c.Driver)
+	}
+	fs, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("driver not found: %s", c.Driver)
+	return registry.Wrap(fs, c.Wrappers, c.WrapperConfigs)
 }
 
-func getDataTXs(c *config, fs storage.FS) (map[string]http.Handler, error) {
+// getDataTXs returns the handlers of the configured data transfer protocols.
+// The protocols served by default are skipped when the storage doesn't
+// support them, the configured ones have to be served.
+func getDataTXs(c *config, fs storage.FS, log *zerolog.Logger) (map[string]http.Handler, error) {
+	defaults := len(c.DataTXs) == 0
 	if c.DataTXs == nil {
 		c.DataTXs = make(map[string]map[string]interface{})
 	}
-	if len(c.DataTXs) == 0 {
+	if defaults {
 		c.DataTXs["simple"] = make(map[string]interface{})
 		c.DataTXs["tus"] = make(map[string]interface{})
 	}
 
 	txs := make(map[string]http.Handler)
 	for t := range c.DataTXs {
-		if f, ok := datatxregistry.NewFuncs[t]; ok {
-			if tx, err := f(c.DataTXs[t]); err == nil {
-				if handler, err := tx.Handler(fs); err == nil {
-					txs[t] = handler
-				}
-			}
+		f, ok := datatxregistry.NewFuncs[t]
+		if !ok {
+			return nil, fmt.Errorf("dataprovider: data transfer protocol not found: %s", t)
+		}
+		tx, err := f(c.DataTXs[t])
+		if err != nil {
+			return nil, errors.Wrapf(err, "dataprovider: error creating the %s data transfer", t)
 		}
+		handler, err := tx.Handler(fs)
+		if _, ok := err.(errtypes.IsNotSupported); ok && defaults {
+			log.Warn().Err(err).Str("protocol", t).Msg("dataprovider: data transfer protocol not supported by the storage, not served")
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "dataprovider: error creating the %s data transfer handler", t)
+		}
+		txs[t] = handler
 	}
 	return txs, nil
 }
 
+// Close is called once the transfers are drained. The post-processing still
+// in progress is interrupted before the storage is shut down.
 func (s *svc) Close() error {
-	return nil
+	ops.Unregister(ops.Uploads, "dataprovider/"+s.conf.Prefix)
+	metrics.UnregisterCollector("dataprovider/" + s.conf.Prefix)
+	if s.postprocessor != nil {
+		s.postprocessor.close()
+	}
+	if err := s.events.Close(); err != nil {
+		return err
+	}
+	return s.storage.Shutdown(context.Background())
 }
 
 func (s *svc) Unprotected() []string {
@@ -141,6 +304,12 @@ func (s *svc) setHandler() error {
 		log := appctx.GetLogger(r.Context())
 		log.Debug().Msgf("dataprovider routing: path=%s", r.URL.Path)
 
+		switch r.Method {
+		case http.MethodPut, http.MethodPost, http.MethodPatch:
+			atomic.AddInt64(&s.uploads, 1)
+			defer atomic.AddInt64(&s.uploads, -1)
+		}
+
 		head, tail := router.ShiftPath(r.URL.Path)
 
 		if handler, ok := s.dataTXs[head]; ok {
@@ -159,5 +328,11 @@ func (s *svc) setHandler() error {
 		w.WriteHeader(http.StatusInternalServerError)
 	})
 
+	if s.conf.RateLimit.Enabled() {
+		s.handler = ratelimit.New(&s.conf.RateLimit).Handler(s.handler)
+	}
+	if s.conf.BodyLimit.Enabled() {
+		s.handler = bodylimit.New(&s.conf.BodyLimit).Handler(s.handler)
+	}
 	return nil
 }
@@ -0,0 +1,288 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/pkg/errors"
+)
+
+const (
+	// hookEventUploadCompleted is the event sent to the hooks when an upload completes.
+	hookEventUploadCompleted = "upload_completed"
+	// hookSignatureHeader carries the HMAC-SHA256 of the body, keyed with the secret of the hook.
+	hookSignatureHeader = "X-Reva-Signature"
+	// hookEventHeader carries the event of the notification.
+	hookEventHeader = "X-Reva-Event"
+)
+
+type uploadHooksConfig struct {
+	StorageID string       `mapstructure:"storage_id" docs:"nil;The mount id of the storage provider the uploads belong to."`
+	Hooks     []hookConfig `mapstructure:"hooks" docs:"nil;The HTTP callbacks notified about the completed uploads."`
+}
+
+type hookConfig struct {
+	URL          string            `mapstructure:"url" docs:"nil;The endpoint the notifications are posted to as JSON."`
+	Paths        []string          `mapstructure:"paths" docs:"nil;The path patterns the uploaded files must match, relative to the mount. A trailing /** matches a whole subtree. Empty matches all files."`
+	Secret       string            `mapstructure:"secret" docs:"nil;The secret used to sign the notifications, sent in the X-Reva-Signature header."`
+	Headers      map[string]string `mapstructure:"headers" docs:"nil;Additional headers sent with the notifications."`
+	MetadataKeys []string          `mapstructure:"metadata_keys" docs:"nil;The arbitrary metadata of the file included in the notifications."`
+	Timeout      int               `mapstructure:"timeout" docs:"10;The timeout in seconds of a notification attempt."`
+	Attempts     int               `mapstructure:"attempts" docs:"3;How many times a notification is attempted before giving up."`
+}
+
+func (c *hookConfig) init() {
+	if c.Timeout == 0 {
+		c.Timeout = 10
+	}
+	if c.Attempts <= 0 {
+		c.Attempts = 3
+	}
+}
+
+// hookPayload is the body posted to the hooks.
+type hookPayload struct {
+	Event     string            `json:"event"`
+	Time      time.Time         `json:"time"`
+	StorageID string            `json:"storage_id"`
+	OpaqueID  string            `json:"opaque_id"`
+	Path      string            `json:"path"`
+	Size      uint64            `json:"size"`
+	MimeType  string            `json:"mime_type,omitempty"`
+	Etag      string            `json:"etag,omitempty"`
+	Checksum  string            `json:"checksum,omitempty"`
+	Mtime     uint64            `json:"mtime,omitempty"`
+	Owner     string            `json:"owner,omitempty"`
+	Executant string            `json:"executant,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type uploadHook struct {
+	conf   *hookConfig
+	client *http.Client
+}
+
+// uploadNotifier notifies the configured hooks about the uploads completed
+// through the data transfer handlers.
+type uploadNotifier struct {
+	storageID string
+	hooks     []*uploadHook
+	mdKeys    []string
+	fs        storage.FS
+}
+
+func newUploadNotifier(c *uploadHooksConfig, fs storage.FS) (*uploadNotifier, error) {
+	n := &uploadNotifier{storageID: c.StorageID, fs: fs}
+	keys := map[string]bool{}
+	for i := range c.Hooks {
+		h := &c.Hooks[i]
+		if h.URL == "" {
+			return nil, fmt.Errorf("dataprovider: upload hook %d has no url", i)
+		}
+		for _, p := range h.Paths {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, errors.Wrapf(err, "dataprovider: invalid path pattern %q of upload hook %s", p, h.URL)
+			}
+		}
+		h.init()
+		for _, k := range h.MetadataKeys {
+			if !keys[k] {
+				keys[k] = true
+				n.mdKeys = append(n.mdKeys, k)
+			}
+		}
+		n.hooks = append(n.hooks, &uploadHook{
+			conf:   h,
+			client: rhttp.GetHTTPClient(rhttp.Timeout(time.Duration(h.Timeout) * time.Second)),
+		})
+	}
+	return n, nil
+}
+
+// handler wraps a data transfer handler, notifying the hooks about every upload it completes.
+func (n *uploadNotifier) handler(h http.Handler) http.Handler {
+	return onUploadCompleted(h, n.fs, func(r *http.Request, session *storage.UploadSession) {
+		ctx := appctx.DetachContext(r.Context())
+		go n.notify(ctx, session.Ref)
+	})
+}
+
+func (n *uploadNotifier) notify(ctx context.Context, ref *provider.Reference) {
+	log := appctx.GetLogger(ctx)
+
+	info, err := n.fs.GetMD(ctx, ref, n.mdKeys)
+	if err != nil {
+		log.Error().Err(err).Interface("ref", ref).Msg("dataprovider: error stating uploaded file for the upload hooks")
+		return
+	}
+
+	p := &hookPayload{
+		Event:    hookEventUploadCompleted,
+		Time:     time.Now(),
+		Path:     info.Path,
+		Size:     info.Size,
+		MimeType: info.MimeType,
+		Etag:     info.Etag,
+		Mtime:    info.Mtime.GetSeconds(),
+	}
+	if info.Id != nil {
+		p.StorageID, p.OpaqueID = info.Id.StorageId, info.Id.OpaqueId
+	}
+	if p.StorageID == "" {
+		p.StorageID = n.storageID
+	}
+	if info.Checksum != nil {
+		p.Checksum = info.Checksum.Sum
+	}
+	if info.Owner != nil {
+		p.Owner = info.Owner.OpaqueId
+	}
+	if u, ok := user.ContextGetUser(ctx); ok {
+		p.Executant = u.Username
+	}
+
+	for _, h := range n.hooks {
+		if !h.matches(info.Path) {
+			continue
+		}
+		hp := *p
+		hp.Metadata = h.metadata(info)
+		if err := h.post(ctx, &hp); err != nil {
+			log.Error().Err(err).Str("hook", h.conf.URL).Str("path", info.Path).Msg("dataprovider: error notifying upload hook")
+		}
+	}
+}
+
+func (h *uploadHook) matches(p string) bool {
+	if len(h.conf.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range h.conf.Paths {
+		if matchPath(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *uploadHook) metadata(info *provider.ResourceInfo) map[string]string {
+	if len(h.conf.MetadataKeys) == 0 || info.ArbitraryMetadata == nil {
+		return nil
+	}
+	md := map[string]string{}
+	for _, k := range h.conf.MetadataKeys {
+		if v, ok := info.ArbitraryMetadata.Metadata[k]; ok {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+// post sends the payload to the hook, retrying with a linear backoff.
+func (h *uploadHook) post(ctx context.Context, p *hookPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = h.send(ctx, body)
+		if err == nil || attempt == h.conf.Attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
+
+func (h *uploadHook) send(ctx context.Context, body []byte) error {
+	// the token of the user is deliberately not forwarded to the external endpoint
+	req, err := http.NewRequest(http.MethodPost, h.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(hookEventHeader, hookEventUploadCompleted)
+	for k, v := range h.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	if h.conf.Secret != "" {
+		req.Header.Set(hookSignatureHeader, "sha256="+sign(h.conf.Secret, body))
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// sign returns the hex encoded HMAC-SHA256 of the body.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// matchPath reports whether p matches the shell pattern, where a trailing
+// /** matches the directory and everything below it.
+func matchPath(pattern, p string) bool {
+	p = path.Clean("/" + p)
+	pattern = path.Clean("/" + pattern)
+	if !strings.HasSuffix(pattern, "/**") {
+		ok, _ := path.Match(pattern, p)
+		return ok
+	}
+
+	dir := strings.Split(strings.TrimSuffix(pattern, "/**"), "/")
+	segments := strings.Split(p, "/")
+	if len(segments) < len(dir) {
+		return false
+	}
+	for i := range dir {
+		if ok, _ := path.Match(dir[i], segments[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
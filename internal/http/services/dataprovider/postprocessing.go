@@ -0,0 +1,344 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/crypto"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/pkg/errors"
+)
+
+// The steps of the post-processing.
+const (
+	stepAntivirus = "antivirus"
+	stepChecksum  = "checksum"
+	stepThumbnail = "thumbnail"
+	stepEvent     = "event"
+)
+
+type postprocessingConfig struct {
+	Steps              []string `mapstructure:"steps" docs:"nil;The steps run in order on the completed uploads before they are listed: antivirus, checksum, thumbnail and event. Empty disables the post-processing."`
+	Workers            int      `mapstructure:"workers" docs:"4;The number of uploads processed at the same time."`
+	Attempts           int      `mapstructure:"attempts" docs:"3;How many times a failing step is attempted before the post-processing of the upload fails."`
+	RetryDelay         int      `mapstructure:"retry_delay" docs:"10;The delay in seconds before a failed step is retried, multiplied by the number of attempts."`
+	ThumbnailsEndpoint string   `mapstructure:"thumbnails_endpoint" docs:"http://localhost:19001/thumbnails;The thumbnails service pre-rendering the thumbnails, the files are requested at their path in the storage."`
+	ThumbnailSizes     []string `mapstructure:"thumbnail_sizes" docs:"[medium];The size presets of the thumbnails pre-rendered."`
+}
+
+func (c *postprocessingConfig) init() {
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.Attempts <= 0 {
+		c.Attempts = 3
+	}
+	if c.RetryDelay == 0 {
+		c.RetryDelay = 10
+	}
+	if c.ThumbnailsEndpoint == "" {
+		c.ThumbnailsEndpoint = "http://localhost:19001/thumbnails"
+	}
+	if len(c.ThumbnailSizes) == 0 {
+		c.ThumbnailSizes = []string{"medium"}
+	}
+}
+
+func (c *postprocessingConfig) has(step string) bool {
+	for _, s := range c.Steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+type processingStep struct {
+	name string
+	// run processes the uploaded file, returning whether it was removed.
+	run func(ctx context.Context, ref *provider.Reference) (bool, error)
+}
+
+// postprocessor runs the post-processing steps on the files written by the
+// uploads passing through the data transfer handlers once they complete, in
+// the background. The drivers implementing storage.Processor keep the files
+// out of the listings until all the steps succeeded.
+type postprocessor struct {
+	conf   *postprocessingConfig
+	fs     storage.FS
+	steps  []processingStep
+	slots  chan struct{}
+	delay  time.Duration
+	events *events.Emitter
+	client *http.Client
+	// stop interrupts the post-processing in progress on shutdown, wg waits for it.
+	stop chan struct{}
+	wg   sync.WaitGroup
+	// unsupported warns once that the driver lists the files while they are processed.
+	unsupported sync.Once
+}
+
+func newPostprocessor(c *postprocessingConfig, av *antivirusConfig, fs storage.FS, emitter *events.Emitter) (*postprocessor, error) {
+	c.init()
+
+	p := &postprocessor{
+		conf:   c,
+		fs:     fs,
+		slots:  make(chan struct{}, c.Workers),
+		delay:  time.Duration(c.RetryDelay) * time.Second,
+		events: emitter,
+		client: rhttp.GetHTTPClient(rhttp.Timeout(60 * time.Second)),
+		stop:   make(chan struct{}),
+	}
+
+	for _, name := range c.Steps {
+		step := processingStep{name: name}
+		switch name {
+		case stepAntivirus:
+			scanner, err := newUploadScanner(av, fs)
+			if err != nil {
+				return nil, err
+			}
+			step.run = scanner.scan
+		case stepChecksum:
+			step.run = p.verifyChecksums
+		case stepThumbnail:
+			step.run = p.renderThumbnails
+		case stepEvent:
+			if emitter == nil {
+				return nil, fmt.Errorf("dataprovider: the %s post-processing step needs an event_stream", stepEvent)
+			}
+			step.run = p.emitUploaded
+		default:
+			return nil, fmt.Errorf("dataprovider: unknown post-processing step: %s", name)
+		}
+		p.steps = append(p.steps, step)
+	}
+	return p, nil
+}
+
+// handler wraps a data transfer handler, processing the resource of every upload it completes.
+func (p *postprocessor) handler(h http.Handler) http.Handler {
+	return onUploadCompleted(h, p.fs, func(r *http.Request, session *storage.UploadSession) {
+		ctx := appctx.DetachContext(r.Context())
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.process(ctx, session.Ref)
+		}()
+	})
+}
+
+// close interrupts the post-processing in progress and waits for it to stop.
+// The interrupted files are recorded as failed, so that they are not left
+// in a step no process will finish.
+func (p *postprocessor) close() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// process runs the steps on the file, stopping at the first one failing all
+// its attempts, in which case the file is left unlisted.
+func (p *postprocessor) process(ctx context.Context, ref *provider.Reference) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		p.fail(ctx, ref, storage.ProcessingPending, ctx.Err())
+		return
+	}
+	defer func() { <-p.slots }()
+
+	for _, step := range p.steps {
+		p.setProcessing(ctx, ref, step.name)
+		removed, err := p.runStep(ctx, step, ref)
+		if err != nil {
+			p.fail(ctx, ref, step.name, err)
+			return
+		}
+		if removed {
+			return
+		}
+	}
+
+	if err := storage.FinishProcessing(ctx, p.fs, ref); err != nil {
+		if _, ok := err.(errtypes.IsNotSupported); !ok {
+			appctx.GetLogger(ctx).Error().Err(err).Str("path", ref.GetPath()).Msg("dataprovider: error finishing the post-processing")
+		}
+	}
+}
+
+// fail records the post-processing of the file as failed at the step.
+func (p *postprocessor) fail(ctx context.Context, ref *provider.Reference, step string, err error) {
+	if ctx.Err() != nil {
+		err = errors.New("dataprovider: post-processing interrupted by the shutdown")
+		// the failure is recorded after the interruption
+		ctx = appctx.DetachContext(ctx)
+	}
+	appctx.GetLogger(ctx).Error().Err(err).Str("path", ref.GetPath()).Str("step", step).Msg("dataprovider: post-processing failed, the file stays unlisted")
+	p.setProcessing(ctx, ref, storage.ProcessingFailed)
+	ev := &events.PostprocessingFailed{Path: ref.GetPath(), ResourceID: ref.GetId(), Step: step, Error: err.Error()}
+	if u, ok := user.ContextGetUser(ctx); ok {
+		ev.Executant = u.Id
+	}
+	p.events.Emit(ctx, ev)
+}
+
+// runStep runs a step, retrying it with a linear backoff.
+func (p *postprocessor) runStep(ctx context.Context, step processingStep, ref *provider.Reference) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		removed, err := step.run(ctx, ref)
+		if err == nil || attempt >= p.conf.Attempts {
+			return removed, err
+		}
+		// interrupted, the step is not run again
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		appctx.GetLogger(ctx).Warn().Err(err).Str("path", ref.GetPath()).Str("step", step.name).Int("attempt", attempt).Msg("dataprovider: post-processing step failed, retrying")
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(time.Duration(attempt) * p.delay):
+		}
+	}
+}
+
+func (p *postprocessor) setProcessing(ctx context.Context, ref *provider.Reference, step string) {
+	if err := storage.SetProcessing(ctx, p.fs, ref, step); err != nil {
+		if _, ok := err.(errtypes.IsNotSupported); ok {
+			p.unsupported.Do(func() {
+				appctx.GetLogger(ctx).Warn().Msg("dataprovider: the driver does not support post-processing, the uploads are listed before they are processed")
+			})
+			return
+		}
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", ref.GetPath()).Str("step", step).Msg("dataprovider: error recording the post-processing step")
+	}
+}
+
+// verifyChecksums compares the checksums the storage returns for the file with
+// the ones of its content, failing if any of them differs.
+func (p *postprocessor) verifyChecksums(ctx context.Context, ref *provider.Reference) (bool, error) {
+	info, err := p.fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// the storages return the checksums besides the main one in the opaque map
+	var expected []string
+	if info.Checksum != nil {
+		expected = append(expected, string(storageprovider.GRPC2PKGXS(info.Checksum.Type))+" "+info.Checksum.Sum)
+	}
+	for _, xs := range []string{storageprovider.XSMD5, storageprovider.XSAdler32, storageprovider.XSSHA256} {
+		if e, ok := info.GetOpaque().GetMap()[xs]; ok {
+			expected = append(expected, xs+" "+string(e.Value))
+		}
+	}
+	if len(expected) == 0 {
+		appctx.GetLogger(ctx).Debug().Str("path", ref.GetPath()).Msg("dataprovider: no checksums to verify")
+		return false, nil
+	}
+
+	algos := make([]string, 0, len(expected))
+	for _, e := range expected {
+		algos = append(algos, strings.SplitN(e, " ", 2)[0])
+	}
+	checksums, err := crypto.NewChecksums(algos...)
+	if err != nil {
+		return false, err
+	}
+	rc, err := p.fs.Download(ctx, ref)
+	if err != nil {
+		return false, errors.Wrap(err, "dataprovider: error downloading file to checksum")
+	}
+	defer rc.Close()
+	if _, err := io.Copy(checksums, rc); err != nil {
+		return false, errors.Wrap(err, "dataprovider: error reading file to checksum")
+	}
+	for _, e := range expected {
+		if err := checksums.Verify(e); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// renderThumbnails requests the thumbnails of the file from the thumbnails
+// service, which caches them. Files without thumbnails are skipped.
+func (p *postprocessor) renderThumbnails(ctx context.Context, ref *provider.Reference) (bool, error) {
+	info, err := p.fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return false, err
+	}
+	t, _ := token.ContextGetToken(ctx)
+
+	for _, size := range p.conf.ThumbnailSizes {
+		u := strings.TrimSuffix(p.conf.ThumbnailsEndpoint, "/") + (&url.URL{Path: path.Join("/", info.Path)}).EscapedPath() + "?size=" + url.QueryEscape(size)
+		req, err := http.NewRequest(http.MethodHead, u, nil)
+		if err != nil {
+			return false, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set(token.TokenHeader, t)
+
+		res, err := p.client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		res.Body.Close()
+		switch res.StatusCode {
+		case http.StatusOK, http.StatusUnsupportedMediaType:
+		default:
+			return false, fmt.Errorf("dataprovider: unexpected status %d rendering the %s thumbnail", res.StatusCode, size)
+		}
+	}
+	return false, nil
+}
+
+// emitUploaded emits the FileUploaded event, once the previous steps succeeded.
+func (p *postprocessor) emitUploaded(ctx context.Context, ref *provider.Reference) (bool, error) {
+	p.events.Emit(ctx, newFileUploaded(ctx, p.fs, ref))
+	return false, nil
+}
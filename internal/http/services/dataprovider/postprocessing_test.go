@@ -0,0 +1,126 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+type testProcessor struct {
+	storage.FS
+	steps    []string
+	finished bool
+}
+
+func (p *testProcessor) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	p.steps = append(p.steps, step)
+	return nil
+}
+
+func (p *testProcessor) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	p.finished = true
+	return nil
+}
+
+func TestPostprocessing(t *testing.T) {
+	// failing returns a step failing the given number of times before succeeding
+	failing := func(name string, failures int) processingStep {
+		return processingStep{name: name, run: func(ctx context.Context, ref *provider.Reference) (bool, error) {
+			if failures > 0 {
+				failures--
+				return false, errors.New("failed")
+			}
+			return false, nil
+		}}
+	}
+	removing := processingStep{name: "removing", run: func(ctx context.Context, ref *provider.Reference) (bool, error) {
+		return true, nil
+	}}
+
+	tests := map[string]struct {
+		steps    []processingStep
+		expected []string
+		finished bool
+	}{
+		"all succeed":      {[]processingStep{failing("a", 0), failing("b", 0)}, []string{"a", "b"}, true},
+		"retried":          {[]processingStep{failing("a", 2), failing("b", 0)}, []string{"a", "b"}, true},
+		"fails":            {[]processingStep{failing("a", 3), failing("b", 0)}, []string{"a", "failed"}, false},
+		"removes the file": {[]processingStep{removing, failing("b", 0)}, []string{"removing"}, false},
+	}
+
+	for name := range tests {
+		var tc = tests[name]
+		t.Run(name, func(t *testing.T) {
+			processor := &testProcessor{}
+			p := &postprocessor{
+				conf:  &postprocessingConfig{Attempts: 3},
+				fs:    processor,
+				steps: tc.steps,
+				slots: make(chan struct{}, 1),
+			}
+			p.process(context.Background(), &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}})
+
+			if len(processor.steps) != len(tc.expected) {
+				t.Fatalf("recorded steps %v, expected %v", processor.steps, tc.expected)
+			}
+			for i := range tc.expected {
+				if processor.steps[i] != tc.expected[i] {
+					t.Fatalf("recorded steps %v, expected %v", processor.steps, tc.expected)
+				}
+			}
+			if processor.finished != tc.finished {
+				t.Fatalf("finished = %v, expected %v", processor.finished, tc.finished)
+			}
+		})
+	}
+}
+
+func TestPostprocessingInterrupted(t *testing.T) {
+	started := make(chan struct{})
+	blocking := processingStep{name: "a", run: func(ctx context.Context, ref *provider.Reference) (bool, error) {
+		close(started)
+		<-ctx.Done()
+		return false, ctx.Err()
+	}}
+	processor := &testProcessor{}
+	p := &postprocessor{
+		conf:  &postprocessingConfig{Attempts: 3},
+		fs:    processor,
+		steps: []processingStep{blocking},
+		slots: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.process(context.Background(), &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}})
+	}()
+	<-started
+	p.close()
+
+	if len(processor.steps) != 2 || processor.steps[1] != "failed" || processor.finished {
+		t.Fatalf("recorded steps %v, finished = %v, expected the interrupted file to fail", processor.steps, processor.finished)
+	}
+}
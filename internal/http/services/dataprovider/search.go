@@ -0,0 +1,64 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+type searchConfig struct {
+	Enabled        bool   `mapstructure:"enabled" docs:"false;Whether to index the uploads completed on this dataprovider."`
+	SearchSvc      string `mapstructure:"searchsvc" docs:"localhost:9999;The address of the search service."`
+	StorageID      string `mapstructure:"storage_id" docs:"nil;The mount id of the storage provider the uploads belong to."`
+	MaxContentSize int64  `mapstructure:"max_content_size" docs:"1048576;The size up to which the content of text files is indexed, negative disables it."`
+}
+
+func (c *searchConfig) init() {
+	if c.SearchSvc == "" {
+		c.SearchSvc = "localhost:9999"
+	}
+	if c.MaxContentSize == 0 {
+		c.MaxContentSize = 1024 * 1024
+	}
+}
+
+// uploadIndexer sends the resources written by the uploads passing through the
+// data transfer handlers to the search service once they complete.
+type uploadIndexer struct {
+	notifier *search.Notifier
+	fs       storage.FS
+}
+
+func newUploadIndexer(c *searchConfig, fs storage.FS) *uploadIndexer {
+	c.init()
+	return &uploadIndexer{
+		notifier: search.NewNotifier(c.SearchSvc, c.StorageID, c.MaxContentSize),
+		fs:       fs,
+	}
+}
+
+// handler wraps a data transfer handler, indexing the resource of every upload it completes.
+func (u *uploadIndexer) handler(h http.Handler) http.Handler {
+	return onUploadCompleted(h, u.fs, func(r *http.Request, session *storage.UploadSession) {
+		u.notifier.Updated(r.Context(), u.fs, session.Ref)
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"bytes"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp/bodylimit"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// getUploadSession returns the upload a request writes to, or nil for any other request.
+func getUploadSession(r *http.Request, fs storage.FS) *storage.UploadSession {
+	var id string
+	switch {
+	case r.Method == "PUT" || r.Method == "PATCH":
+		id = path.Base(r.URL.Path)
+	case r.Method == "POST" && strings.HasPrefix(r.Header.Get("Upload-Concat"), "final;"):
+		// the final upload is written to the resource of its partial uploads
+		partials := strings.Fields(strings.TrimPrefix(r.Header.Get("Upload-Concat"), "final;"))
+		if len(partials) == 0 {
+			return nil
+		}
+		id = path.Base(partials[0])
+	default:
+		return nil
+	}
+
+	session, err := storage.GetUploadSession(r.Context(), fs, id)
+	if _, ok := err.(errtypes.IsNotSupported); ok {
+		// the driver uploads straight to the path of the resource
+		if r.Method != "PUT" {
+			return nil
+		}
+		return &storage.UploadSession{Ref: &provider.Reference{Spec: &provider.Reference_Path{Path: r.URL.Path}}}
+	}
+	if err != nil {
+		appctx.GetLogger(r.Context()).Debug().Err(err).Str("upload", id).Msg("dataprovider: no upload session found")
+		return nil
+	}
+	if session.Partial && r.Method != "POST" {
+		return nil
+	}
+	return session
+}
+
+// onUploadCompleted wraps a data transfer handler, calling f after every upload it completes.
+func onUploadCompleted(h http.Handler, fs storage.FS, f func(r *http.Request, session *storage.UploadSession)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := getUploadSession(r, fs)
+		if session == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		if uploadCompleted(r, rec, session) {
+			f(r, session)
+		}
+		rec.flush(w)
+	})
+}
+
+// limitUploads wraps a data transfer handler, rejecting the PUT requests
+// writing more than the length declared when their upload was initiated, as
+// the limits checked against it, e.g. the maximum file size of the file drops,
+// would not hold otherwise. The tus handler checks the PATCH requests itself.
+// The uploads of the drivers without upload sessions are not limited.
+func limitUploads(h http.Handler, fs storage.FS, l *bodylimit.Limiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		session := getUploadSession(r, fs)
+		if session == nil || session.Size <= 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		l.Serve(w, r, h, session.Size)
+	})
+}
+
+// uploadCompleted returns whether the request completed the given upload.
+func uploadCompleted(r *http.Request, rec *responseRecorder, session *storage.UploadSession) bool {
+	switch r.Method {
+	case "PUT":
+		return rec.status == http.StatusOK
+	case "PATCH":
+		return rec.status == http.StatusNoContent && session.Size > 0 &&
+			rec.header.Get("Upload-Offset") == strconv.FormatInt(session.Size, 10)
+	case "POST":
+		return rec.status == http.StatusCreated
+	}
+	return false
+}
+
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+
+func (r *responseRecorder) WriteHeader(status int) { r.status = status }
+
+func (r *responseRecorder) flush(w http.ResponseWriter) {
+	for k, v := range r.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(r.status)
+	_, _ = w.Write(r.body.Bytes())
+}
@@ -0,0 +1,92 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp/bodylimit"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+type sessionFS struct {
+	storage.FS
+	sessions map[string]*storage.UploadSession
+}
+
+func (fs *sessionFS) GetUploadSession(ctx context.Context, id string) (*storage.UploadSession, error) {
+	if s, ok := fs.sessions[id]; ok {
+		return s, nil
+	}
+	return nil, errtypes.NotFound(id)
+}
+
+func TestLimitUploads(t *testing.T) {
+	fs := &sessionFS{sessions: map[string]*storage.UploadSession{
+		"declared": {Size: 5},
+		"deferred": {},
+	}}
+	var written string
+	h := limitUploads(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		written = string(b)
+		w.WriteHeader(http.StatusOK)
+	}), fs, bodylimit.New(&bodylimit.Config{}))
+
+	tests := []struct {
+		name, upload, body string
+		chunked            bool
+		status             int
+	}{
+		{"declared length", "declared", "hello", false, http.StatusOK},
+		{"shorter body", "declared", "hi", false, http.StatusOK},
+		{"longer body", "declared", "hello world", false, http.StatusRequestEntityTooLarge},
+		{"longer chunked body", "declared", "hello world", true, http.StatusRequestEntityTooLarge},
+		{"deferred length", "deferred", "hello world", false, http.StatusOK},
+		{"unknown upload", "unknown", "hello world", false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		written = ""
+		r := httptest.NewRequest("PUT", "/"+tt.upload, strings.NewReader(tt.body))
+		if tt.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if tt.status == http.StatusOK && written != tt.body {
+			t.Errorf("%s: wrote %q, want %q", tt.name, written, tt.body)
+		}
+		if tt.status != http.StatusOK && written != "" {
+			t.Errorf("%s: wrote %q past the declared length", tt.name, written)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package graph
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// drive is the Graph representation of a storage space.
+type drive struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	DriveType            string       `json:"driveType"`
+	Owner                *identitySet `json:"owner,omitempty"`
+	Root                 *driveItem   `json:"root,omitempty"`
+	Quota                *quota       `json:"quota,omitempty"`
+	LastModifiedDateTime string       `json:"lastModifiedDateTime,omitempty"`
+}
+
+type identitySet struct {
+	User  *identity `json:"user,omitempty"`
+	Group *identity `json:"group,omitempty"`
+}
+
+type identity struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type driveItem struct {
+	ID        string `json:"id"`
+	WebDavURL string `json:"webDavUrl,omitempty"`
+}
+
+// quota is the quota of a drive, a total of 0 means there is no limit.
+type quota struct {
+	Total     uint64 `json:"total,omitempty"`
+	Used      uint64 `json:"used"`
+	Remaining uint64 `json:"remaining,omitempty"`
+	State     string `json:"state,omitempty"`
+}
+
+type driveCollection struct {
+	Value []*drive `json:"value"`
+}
+
+// newQuota computes the remaining bytes and the state of a quota the way the
+// Graph API does: nearing above 75% of the total, critical above 90% and
+// exceeded when all of it is used.
+func newQuota(total, used uint64) *quota {
+	q := &quota{Total: total, Used: used}
+	if total == 0 {
+		return q
+	}
+	if used < total {
+		q.Remaining = total - used
+	}
+	switch {
+	case used >= total:
+		q.State = "exceeded"
+	case used*10 >= total*9:
+		q.State = "critical"
+	case used*4 >= total*3:
+		q.State = "nearing"
+	default:
+		q.State = "normal"
+	}
+	return q
+}
+
+// wrapResourceID encodes the id of the root of a drive like ocdav does for the
+// space ids of the /dav/spaces endpoint.
+func wrapResourceID(id *provider.ResourceId) string {
+	return base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", id.StorageId, id.OpaqueId)))
+}
+
+func (s *svc) toDrive(space *provider.StorageSpace) *drive {
+	d := &drive{
+		ID:        space.Id.GetOpaqueId(),
+		Name:      space.Name,
+		DriveType: space.SpaceType,
+	}
+	if o := space.Owner; o != nil {
+		d.Owner = &identitySet{User: &identity{ID: o.Id.GetOpaqueId(), DisplayName: o.DisplayName}}
+	}
+	if space.Root != nil {
+		d.Root = &driveItem{ID: wrapResourceID(space.Root)}
+		if s.conf.WebDavURL != "" {
+			d.Root.WebDavURL = strings.TrimSuffix(s.conf.WebDavURL, "/") + "/" + d.Root.ID
+		}
+	}
+	if space.Quota != nil {
+		d.Quota = newQuota(space.Quota.QuotaMaxBytes, 0)
+	}
+	if space.Mtime != nil {
+		d.LastModifiedDateTime = utils.TSToTime(space.Mtime).UTC().Format(time.RFC3339)
+	}
+	return d
+}
+
+// withUsage fills in the used bytes of the drive, which the storage spaces do
+// not carry, from the quota of their root.
+func (s *svc) withUsage(r *http.Request, c gateway.GatewayAPIClient, space *provider.StorageSpace, d *drive) {
+	if space.Root == nil {
+		return
+	}
+	res, err := c.GetQuota(r.Context(), &gateway.GetQuotaRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: space.Root}},
+	})
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		appctx.GetLogger(r.Context()).Debug().Err(err).Str("drive", d.ID).Msg("graph: could not get the quota of the drive")
+		return
+	}
+	total := res.TotalBytes
+	if d.Quota != nil && d.Quota.Total != 0 {
+		total = d.Quota.Total
+	}
+	d.Quota = newQuota(total, res.UsedBytes)
+}
+
+func (s *svc) client(w http.ResponseWriter, r *http.Request) gateway.GatewayAPIClient {
+	c, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		handleError(w, r, err, "error getting gateway client")
+		return nil
+	}
+	return c
+}
+
+// parseFilter translates the $filter query parameter into the filters of a
+// ListStorageSpacesRequest. Only the equality on the driveType and the id,
+// joined with and, is supported, eg. $filter=driveType eq 'project'.
+func parseFilter(filter string) ([]*provider.ListStorageSpacesRequest_Filter, error) {
+	var filters []*provider.ListStorageSpacesRequest_Filter
+	if strings.TrimSpace(filter) == "" {
+		return filters, nil
+	}
+	for _, term := range strings.Split(filter, " and ") {
+		parts := strings.SplitN(strings.TrimSpace(term), " ", 3)
+		if len(parts) != 3 || parts[1] != "eq" || len(parts[2]) < 2 || !strings.HasPrefix(parts[2], "'") || !strings.HasSuffix(parts[2], "'") {
+			return nil, fmt.Errorf("unsupported filter term %q", term)
+		}
+		value := strings.ReplaceAll(parts[2][1:len(parts[2])-1], "''", "'")
+		switch parts[0] {
+		case "driveType":
+			filters = append(filters, &provider.ListStorageSpacesRequest_Filter{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: value},
+			})
+		case "id":
+			filters = append(filters, idFilter(value))
+		default:
+			return nil, fmt.Errorf("unsupported filter property %q", parts[0])
+		}
+	}
+	return filters, nil
+}
+
+func idFilter(id string) *provider.ListStorageSpacesRequest_Filter {
+	return &provider.ListStorageSpacesRequest_Filter{
+		Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+		Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: id}},
+	}
+}
+
+// listDrives lists the drives, the drives of the user are the spaces they own.
+func (s *svc) listDrives(w http.ResponseWriter, r *http.Request, mine bool) {
+	filters, err := parseFilter(r.URL.Query().Get("$filter"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, err.Error())
+		return
+	}
+	if mine {
+		u, ok := user.ContextGetUser(r.Context())
+		if !ok {
+			writeError(w, r, http.StatusUnauthorized, errUnauthenticated, "no user in context")
+			return
+		}
+		filters = append(filters, &provider.ListStorageSpacesRequest_Filter{
+			Type: provider.ListStorageSpacesRequest_Filter_TYPE_OWNER,
+			Term: &provider.ListStorageSpacesRequest_Filter_Owner{Owner: u.Id},
+		})
+	}
+
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.ListStorageSpaces(r.Context(), &provider.ListStorageSpacesRequest{Filters: filters})
+	if err != nil {
+		handleError(w, r, err, "error listing storage spaces")
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return
+	}
+
+	drives := make([]*drive, 0, len(res.StorageSpaces))
+	for _, space := range res.StorageSpaces {
+		d := s.toDrive(space)
+		s.withUsage(r, c, space, d)
+		drives = append(drives, d)
+	}
+	writeJSON(w, r, http.StatusOK, driveCollection{Value: drives})
+}
+
+// getSpace looks up the space of a drive, it writes the error response and
+// returns nil when the space cannot be found.
+func (s *svc) getSpace(w http.ResponseWriter, r *http.Request, c gateway.GatewayAPIClient, id string) *provider.StorageSpace {
+	res, err := c.ListStorageSpaces(r.Context(), &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{idFilter(id)},
+	})
+	if err != nil {
+		handleError(w, r, err, "error listing storage spaces")
+		return nil
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return nil
+	}
+	for _, space := range res.StorageSpaces {
+		if space.Id.GetOpaqueId() == id {
+			return space
+		}
+	}
+	writeError(w, r, http.StatusNotFound, errItemNotFound, "drive not found")
+	return nil
+}
+
+func (s *svc) getDrive(w http.ResponseWriter, r *http.Request, id string) {
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	space := s.getSpace(w, r, c, id)
+	if space == nil {
+		return
+	}
+	d := s.toDrive(space)
+	s.withUsage(r, c, space, d)
+	writeJSON(w, r, http.StatusOK, d)
+}
+
+// driveRequest is the body of the requests creating and updating drives.
+type driveRequest struct {
+	Name      string `json:"name"`
+	DriveType string `json:"driveType"`
+	Quota     *struct {
+		Total uint64 `json:"total"`
+	} `json:"quota"`
+}
+
+func readDriveRequest(w http.ResponseWriter, r *http.Request) *driveRequest {
+	req := &driveRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		appctx.GetLogger(r.Context()).Debug().Err(err).Msg("graph: error decoding drive")
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "invalid body")
+		return nil
+	}
+	return req
+}
+
+func (s *svc) createDrive(w http.ResponseWriter, r *http.Request) {
+	u, ok := user.ContextGetUser(r.Context())
+	if !ok {
+		writeError(w, r, http.StatusUnauthorized, errUnauthenticated, "no user in context")
+		return
+	}
+	req := readDriveRequest(w, r)
+	if req == nil {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "missing drive name")
+		return
+	}
+	if req.DriveType == "" {
+		req.DriveType = s.conf.DefaultDriveType
+	}
+
+	createReq := &provider.CreateStorageSpaceRequest{
+		Owner: u,
+		Type:  req.DriveType,
+		Name:  req.Name,
+	}
+	if req.Quota != nil {
+		createReq.Quota = &provider.Quota{QuotaMaxBytes: req.Quota.Total}
+	}
+
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.CreateStorageSpace(r.Context(), createReq)
+	if err != nil {
+		handleError(w, r, err, "error creating storage space")
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return
+	}
+	writeJSON(w, r, http.StatusCreated, s.toDrive(res.StorageSpace))
+}
+
+// updateDrive renames a drive or changes its quota, the fields missing from
+// the body are left untouched.
+func (s *svc) updateDrive(w http.ResponseWriter, r *http.Request, id string) {
+	req := readDriveRequest(w, r)
+	if req == nil {
+		return
+	}
+	if req.DriveType != "" {
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "the drive type cannot be changed")
+		return
+	}
+
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	space := s.getSpace(w, r, c, id)
+	if space == nil {
+		return
+	}
+
+	update := &provider.StorageSpace{
+		Id:   space.Id,
+		Root: space.Root,
+		Name: req.Name,
+	}
+	if req.Quota != nil {
+		update.Quota = &provider.Quota{QuotaMaxBytes: req.Quota.Total}
+	}
+	res, err := c.UpdateStorageSpace(r.Context(), &provider.UpdateStorageSpaceRequest{StorageSpace: update})
+	if err != nil {
+		handleError(w, r, err, "error updating storage space")
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return
+	}
+	updated := res.StorageSpace
+	if updated == nil {
+		updated = space
+		if update.Name != "" {
+			updated.Name = update.Name
+		}
+		if update.Quota != nil {
+			updated.Quota = update.Quota
+		}
+	}
+	writeJSON(w, r, http.StatusOK, s.toDrive(updated))
+}
+
+func (s *svc) deleteDrive(w http.ResponseWriter, r *http.Request, id string) {
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.DeleteStorageSpace(r.Context(), &provider.DeleteStorageSpaceRequest{
+		Id: &provider.StorageSpaceId{OpaqueId: id},
+	})
+	if err != nil {
+		handleError(w, r, err, "error deleting storage space")
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package graph
+
+import (
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestParseFilter(t *testing.T) {
+	filters, err := parseFilter("driveType eq 'project' and id eq 'it''s'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filters) != 2 {
+		t.Fatalf("expected 2 filters, got %d", len(filters))
+	}
+	if filters[0].Type != provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE || filters[0].GetSpaceType() != "project" {
+		t.Errorf("unexpected drive type filter %v", filters[0])
+	}
+	if filters[1].Type != provider.ListStorageSpacesRequest_Filter_TYPE_ID || filters[1].GetId().GetOpaqueId() != "it's" {
+		t.Errorf("unexpected id filter %v", filters[1])
+	}
+
+	if filters, err := parseFilter(""); err != nil || len(filters) != 0 {
+		t.Errorf("expected no filters, got %v, %v", filters, err)
+	}
+	for _, f := range []string{"name eq 'x'", "driveType ne 'project'", "driveType eq project"} {
+		if _, err := parseFilter(f); err == nil {
+			t.Errorf("expected an error for %q", f)
+		}
+	}
+}
+
+func TestNewQuota(t *testing.T) {
+	tests := []struct {
+		total, used, remaining uint64
+		state                  string
+	}{
+		{0, 10, 0, ""},
+		{100, 10, 90, "normal"},
+		{100, 75, 25, "nearing"},
+		{100, 95, 5, "critical"},
+		{100, 120, 0, "exceeded"},
+	}
+	for _, tt := range tests {
+		q := newQuota(tt.total, tt.used)
+		if q.Remaining != tt.remaining || q.State != tt.state {
+			t.Errorf("newQuota(%d, %d) = %+v, expected remaining %d and state %q", tt.total, tt.used, q, tt.remaining, tt.state)
+		}
+	}
+}
+
+func TestToDrive(t *testing.T) {
+	s := &svc{conf: &config{WebDavURL: "https://cloud.example.com/remote.php/dav/spaces/"}}
+	d := s.toDrive(&provider.StorageSpace{
+		Id:        &provider.StorageSpaceId{OpaqueId: "space-1"},
+		Root:      &provider.ResourceId{StorageId: "storage", OpaqueId: "root"},
+		Name:      "Project",
+		SpaceType: "project",
+		Quota:     &provider.Quota{QuotaMaxBytes: 1000},
+	})
+	if d.ID != "space-1" || d.Name != "Project" || d.DriveType != "project" {
+		t.Errorf("unexpected drive %+v", d)
+	}
+	if d.Root.WebDavURL != "https://cloud.example.com/remote.php/dav/spaces/"+d.Root.ID {
+		t.Errorf("unexpected webdav url %q", d.Root.WebDavURL)
+	}
+	if d.Quota.Total != 1000 || d.Quota.Remaining != 1000 {
+		t.Errorf("unexpected quota %+v", d.Quota)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package graph exposes the storage spaces as drives with the shape of the
+// Microsoft Graph API, eg. GET /graph/v1.0/me/drives, so that the web clients
+// speaking that API can list, create, update and delete spaces.
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("graph", New)
+}
+
+type config struct {
+	Prefix     string `mapstructure:"prefix"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	// WebDavURL is the base URL of the ocdav spaces endpoint, used for the
+	// webDavUrl of the drive roots, eg. https://cloud.example.com/remote.php/dav/spaces
+	WebDavURL string `mapstructure:"webdav_url"`
+	// DefaultDriveType is the type of the drives created without a driveType.
+	DefaultDriveType string `mapstructure:"default_drive_type"`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "graph"
+	}
+	if c.DefaultDriveType == "" {
+		c.DefaultDriveType = "project"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf *config
+}
+
+// New returns a new graph service
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+	return &svc{conf: conf}, nil
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var version, head string
+		version, r.URL.Path = router.ShiftPath(r.URL.Path)
+		if version != "v1.0" {
+			writeError(w, r, http.StatusNotFound, errItemNotFound, "unknown api version")
+			return
+		}
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+		switch head {
+		case "me":
+			head, r.URL.Path = router.ShiftPath(r.URL.Path)
+			if head != "drives" || r.URL.Path != "/" || r.Method != http.MethodGet {
+				writeError(w, r, http.StatusNotFound, errItemNotFound, "unknown resource")
+				return
+			}
+			s.listDrives(w, r, true)
+		case "drives":
+			s.drivesHandler(w, r)
+		default:
+			writeError(w, r, http.StatusNotFound, errItemNotFound, "unknown resource")
+		}
+	})
+}
+
+func (s *svc) drivesHandler(w http.ResponseWriter, r *http.Request) {
+	var id, head string
+	id, r.URL.Path = router.ShiftPath(r.URL.Path)
+	head, r.URL.Path = router.ShiftPath(r.URL.Path)
+	if id != "" && head == "root" {
+		s.rootHandler(w, r, id)
+		return
+	}
+	if head != "" {
+		writeError(w, r, http.StatusNotFound, errItemNotFound, "unknown resource")
+		return
+	}
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		s.listDrives(w, r, false)
+	case id == "" && r.Method == http.MethodPost:
+		s.createDrive(w, r)
+	case id != "" && r.Method == http.MethodGet:
+		s.getDrive(w, r, id)
+	case id != "" && r.Method == http.MethodPatch:
+		s.updateDrive(w, r, id)
+	case id != "" && r.Method == http.MethodDelete:
+		s.deleteDrive(w, r, id)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, errInvalidRequest, "method not allowed")
+	}
+}
+
+// rootHandler serves the permissions of the root of a drive, which are the
+// members of the space: GET /drives/{id}/root/permissions lists them, POST
+// /drives/{id}/root/invite adds some, PATCH and DELETE on
+// /drives/{id}/root/permissions/{permission} change their role and remove them.
+func (s *svc) rootHandler(w http.ResponseWriter, r *http.Request, id string) {
+	var head, permID string
+	head, r.URL.Path = router.ShiftPath(r.URL.Path)
+	permID, r.URL.Path = router.ShiftPath(r.URL.Path)
+	if r.URL.Path != "/" {
+		writeError(w, r, http.StatusNotFound, errItemNotFound, "unknown resource")
+		return
+	}
+
+	switch {
+	case head == "invite" && permID == "" && r.Method == http.MethodPost:
+		s.invite(w, r, id)
+	case head == "permissions" && permID == "" && r.Method == http.MethodGet:
+		s.listPermissions(w, r, id)
+	case head == "permissions" && permID != "" && r.Method == http.MethodPatch:
+		s.updatePermission(w, r, id, permID)
+	case head == "permissions" && permID != "" && r.Method == http.MethodDelete:
+		s.deletePermission(w, r, id, permID)
+	case head == "invite" || head == "permissions":
+		writeError(w, r, http.StatusMethodNotAllowed, errInvalidRequest, "method not allowed")
+	default:
+		writeError(w, r, http.StatusNotFound, errItemNotFound, "unknown resource")
+	}
+}
+
+// The error codes of the Graph API.
+const (
+	errAccessDenied      = "accessDenied"
+	errGeneralException  = "generalException"
+	errInvalidRequest    = "invalidRequest"
+	errItemNotFound      = "itemNotFound"
+	errNameAlreadyExists = "nameAlreadyExists"
+	errNotAllowed        = "notAllowed"
+	errNotSupported      = "notSupported"
+	errUnauthenticated   = "unauthenticated"
+)
+
+type graphError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorResponse is the body of the failed requests.
+type errorResponse struct {
+	Error graphError `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, code int, errCode, msg string) {
+	writeJSON(w, r, code, errorResponse{Error: graphError{Code: errCode, Message: msg}})
+}
+
+// graphStatus maps the codes of the gateway to HTTP statuses and Graph error codes.
+var graphStatus = map[rpc.Code]struct {
+	code    int
+	errCode string
+}{
+	rpc.Code_CODE_INVALID_ARGUMENT:    {http.StatusBadRequest, errInvalidRequest},
+	rpc.Code_CODE_UNAUTHENTICATED:     {http.StatusUnauthorized, errUnauthenticated},
+	rpc.Code_CODE_PERMISSION_DENIED:   {http.StatusForbidden, errAccessDenied},
+	rpc.Code_CODE_NOT_FOUND:           {http.StatusNotFound, errItemNotFound},
+	rpc.Code_CODE_ALREADY_EXISTS:      {http.StatusConflict, errNameAlreadyExists},
+	rpc.Code_CODE_FAILED_PRECONDITION: {http.StatusPreconditionFailed, errNotAllowed},
+	rpc.Code_CODE_UNIMPLEMENTED:       {http.StatusNotImplemented, errNotSupported},
+}
+
+func writeStatus(w http.ResponseWriter, r *http.Request, st *rpc.Status) {
+	m, ok := graphStatus[st.Code]
+	if !ok {
+		appctx.GetLogger(r.Context()).Error().Str("code", st.Code.String()).Str("message", st.Message).Msg("graph: error handling request")
+		writeError(w, r, http.StatusInternalServerError, errGeneralException, "internal error")
+		return
+	}
+	writeError(w, r, m.code, m.errCode, st.Message)
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("graph: " + msg)
+	writeError(w, r, http.StatusInternalServerError, errGeneralException, msg)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	log := appctx.GetLogger(r.Context())
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Error().Err(err).Msg("graph: error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("graph: error writing response")
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package graph
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+)
+
+// permission is the Graph representation of a member of a space.
+type permission struct {
+	ID        string       `json:"id"`
+	Roles     []string     `json:"roles"`
+	GrantedTo *identitySet `json:"grantedTo"`
+}
+
+type permissionCollection struct {
+	Value []*permission `json:"value"`
+}
+
+// recipient is an invited member, a user unless its type is group.
+type recipient struct {
+	ObjectID string `json:"objectId"`
+	Type     string `json:"@libre.graph.recipient.type"`
+}
+
+type inviteRequest struct {
+	Recipients []recipient `json:"recipients"`
+	Roles      []string    `json:"roles"`
+}
+
+type permissionRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// permissionID encodes the grantee of a member, which identifies its
+// permission on the root of the drive.
+func permissionID(g *provider.Grantee) string {
+	v := url.Values{}
+	switch {
+	case g.GetUserId() != nil:
+		v.Set("user", g.GetUserId().OpaqueId)
+		v.Set("idp", g.GetUserId().Idp)
+	case g.GetGroupId() != nil:
+		v.Set("group", g.GetGroupId().OpaqueId)
+		v.Set("idp", g.GetGroupId().Idp)
+	}
+	return base64.URLEncoding.EncodeToString([]byte(v.Encode()))
+}
+
+func granteeFromPermissionID(id string) *provider.Grantee {
+	b, err := base64.URLEncoding.DecodeString(id)
+	if err != nil {
+		return nil
+	}
+	v, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil
+	}
+	switch {
+	case v.Get("user") != "":
+		return userGrantee(&userpb.UserId{Idp: v.Get("idp"), OpaqueId: v.Get("user")})
+	case v.Get("group") != "":
+		return groupGrantee(&grouppb.GroupId{Idp: v.Get("idp"), OpaqueId: v.Get("group")})
+	}
+	return nil
+}
+
+func userGrantee(id *userpb.UserId) *provider.Grantee {
+	return &provider.Grantee{
+		Type: provider.GranteeType_GRANTEE_TYPE_USER,
+		Id:   &provider.Grantee_UserId{UserId: id},
+	}
+}
+
+func groupGrantee(id *grouppb.GroupId) *provider.Grantee {
+	return &provider.Grantee{
+		Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+		Id:   &provider.Grantee_GroupId{GroupId: id},
+	}
+}
+
+func toPermission(m *spacespb.SpaceMember) *permission {
+	p := &permission{
+		ID:        permissionID(m.Grantee),
+		Roles:     []string{},
+		GrantedTo: &identitySet{},
+	}
+	if m.Role != "" {
+		p.Roles = append(p.Roles, m.Role)
+	}
+	if u := m.Grantee.GetUserId(); u != nil {
+		p.GrantedTo.User = &identity{ID: u.OpaqueId}
+	}
+	if g := m.Grantee.GetGroupId(); g != nil {
+		p.GrantedTo.Group = &identity{ID: g.OpaqueId}
+	}
+	return p
+}
+
+// singleRole returns the role of a request, a member has exactly one.
+func singleRole(w http.ResponseWriter, r *http.Request, roles []string) (string, bool) {
+	if len(roles) != 1 {
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "exactly one role is expected")
+		return "", false
+	}
+	return roles[0], true
+}
+
+func (s *svc) spacesClient(w http.ResponseWriter, r *http.Request) spacespb.SpacesServiceClient {
+	c, err := pool.GetSpacesServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		handleError(w, r, err, "error getting spaces client")
+		return nil
+	}
+	return c
+}
+
+func (s *svc) listPermissions(w http.ResponseWriter, r *http.Request, id string) {
+	c := s.spacesClient(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.ListSpaceMembers(r.Context(), &spacespb.ListSpaceMembersRequest{
+		Id: &provider.StorageSpaceId{OpaqueId: id},
+	})
+	if err != nil {
+		handleError(w, r, err, "error listing space members")
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return
+	}
+	perms := make([]*permission, 0, len(res.Members))
+	for _, m := range res.Members {
+		perms = append(perms, toPermission(m))
+	}
+	writeJSON(w, r, http.StatusOK, permissionCollection{Value: perms})
+}
+
+// invite adds the recipients to the members of the space with the role of the request.
+func (s *svc) invite(w http.ResponseWriter, r *http.Request, id string) {
+	req := &inviteRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		appctx.GetLogger(r.Context()).Debug().Err(err).Msg("graph: error decoding invite")
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "invalid body")
+		return
+	}
+	role, ok := singleRole(w, r, req.Roles)
+	if !ok {
+		return
+	}
+	if len(req.Recipients) == 0 {
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "missing recipients")
+		return
+	}
+	grantees := make([]*provider.Grantee, 0, len(req.Recipients))
+	for _, rc := range req.Recipients {
+		if rc.ObjectID == "" {
+			writeError(w, r, http.StatusBadRequest, errInvalidRequest, "missing recipient id")
+			return
+		}
+		switch rc.Type {
+		case "", "user":
+			grantees = append(grantees, userGrantee(&userpb.UserId{OpaqueId: rc.ObjectID}))
+		case "group":
+			grantees = append(grantees, groupGrantee(&grouppb.GroupId{OpaqueId: rc.ObjectID}))
+		default:
+			writeError(w, r, http.StatusBadRequest, errInvalidRequest, "unknown recipient type "+rc.Type)
+			return
+		}
+	}
+
+	c := s.spacesClient(w, r)
+	if c == nil {
+		return
+	}
+	perms := make([]*permission, 0, len(grantees))
+	for _, g := range grantees {
+		res, err := c.AddSpaceMember(r.Context(), &spacespb.AddSpaceMemberRequest{
+			Id:      &provider.StorageSpaceId{OpaqueId: id},
+			Grantee: g,
+			Role:    role,
+		})
+		if err != nil {
+			handleError(w, r, err, "error adding space member")
+			return
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			writeStatus(w, r, res.Status)
+			return
+		}
+		perms = append(perms, toPermission(res.Member))
+	}
+	writeJSON(w, r, http.StatusOK, permissionCollection{Value: perms})
+}
+
+func (s *svc) updatePermission(w http.ResponseWriter, r *http.Request, id, permID string) {
+	grantee := granteeFromPermissionID(permID)
+	if grantee == nil {
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "invalid permission id")
+		return
+	}
+	req := &permissionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		appctx.GetLogger(r.Context()).Debug().Err(err).Msg("graph: error decoding permission")
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "invalid body")
+		return
+	}
+	role, ok := singleRole(w, r, req.Roles)
+	if !ok {
+		return
+	}
+
+	c := s.spacesClient(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.AddSpaceMember(r.Context(), &spacespb.AddSpaceMemberRequest{
+		Id:      &provider.StorageSpaceId{OpaqueId: id},
+		Grantee: grantee,
+		Role:    role,
+	})
+	if err != nil {
+		handleError(w, r, err, "error updating space member")
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, toPermission(res.Member))
+}
+
+func (s *svc) deletePermission(w http.ResponseWriter, r *http.Request, id, permID string) {
+	grantee := granteeFromPermissionID(permID)
+	if grantee == nil {
+		writeError(w, r, http.StatusBadRequest, errInvalidRequest, "invalid permission id")
+		return
+	}
+
+	c := s.spacesClient(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.RemoveSpaceMember(r.Context(), &spacespb.RemoveSpaceMemberRequest{
+		Id:      &provider.StorageSpaceId{OpaqueId: id},
+		Grantee: grantee,
+	})
+	if err != nil {
+		handleError(w, r, err, "error removing space member")
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeStatus(w, r, res.Status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
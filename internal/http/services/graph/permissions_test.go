@@ -0,0 +1,38 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package graph
+
+import (
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+func TestPermissionID(t *testing.T) {
+	g := userGrantee(&userpb.UserId{Idp: "https://idp.example.com", OpaqueId: "einstein"})
+	if got := granteeFromPermissionID(permissionID(g)); !utils.GranteeEqual(got, g) {
+		t.Errorf("expected %v, got %v", g, got)
+	}
+	for _, id := range []string{"", "not base64!", "aWRwPXg=", "Zm9vPWJhcg=="} {
+		if g := granteeFromPermissionID(id); g != nil {
+			t.Errorf("expected no grantee for %q, got %v", id, g)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package groupspaces provisions project spaces for the groups of the group
+// provider matching configured patterns: a space is created for every new
+// group, with the group as a member, and archived once the group is gone, so
+// that the lifecycle of the institutional groups drives the one of the spaces.
+package groupspaces
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/permissions"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/token"
+	tokenregistry "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	global.Register("groupspaces", New)
+}
+
+type ruleConfig struct {
+	Pattern      string `mapstructure:"pattern" docs:";The pattern of the names of the groups getting a space, e.g. project-*."`
+	Filter       string `mapstructure:"filter" docs:";The filter the groups are searched with, the pattern up to its first wildcard by default."`
+	SpaceType    string `mapstructure:"space_type" docs:"project;The type of the spaces."`
+	NameTemplate string `mapstructure:"name_template" docs:"{{.GroupName}};The template of the name of the spaces, executed with the group."`
+	Role         string `mapstructure:"role" docs:"editor;The role of the group in its space, viewer, editor or manager."`
+	Quota        uint64 `mapstructure:"quota" docs:"0;The quota of the spaces in bytes, 0 leaves it to the storage provider."`
+	Archive      bool   `mapstructure:"archive" docs:"false;Whether the spaces of the groups that are gone are archived, i.e. deleted from their storage provider."`
+}
+
+type config struct {
+	Prefix        string                            `mapstructure:"prefix" docs:"groupspaces;The prefix to be used for this HTTP service"`
+	GatewaySvc    string                            `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
+	Owner         string                            `mapstructure:"owner" docs:";The id of the user owning the spaces, the service acts on their behalf."`
+	OwnerIdp      string                            `mapstructure:"owner_idp" docs:";The identity provider of the owner."`
+	Rules         []ruleConfig                      `mapstructure:"rules" docs:"nil;The patterns of the groups getting a space and the spaces they get."`
+	Interval      int                               `mapstructure:"interval" docs:"300;How often in seconds the groups are reconciled with the spaces."`
+	StateFile     string                            `mapstructure:"state_file" docs:"/var/tmp/reva/groupspaces.json;The file the spaces created for the groups are kept in."`
+	TokenManager  string                            `mapstructure:"token_manager" docs:"jwt;The token manager minting the tokens of the owner."`
+	TokenManagers map[string]map[string]interface{} `mapstructure:"token_managers" docs:"url:pkg/token/manager/jwt/jwt.go;The configuration for the token managers."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "groupspaces"
+	}
+	if c.Interval == 0 {
+		c.Interval = 300
+	}
+	if c.StateFile == "" {
+		c.StateFile = "/var/tmp/reva/groupspaces.json"
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Filter == "" {
+			if i := strings.IndexAny(r.Pattern, "*?[\\"); i >= 0 {
+				r.Filter = r.Pattern[:i]
+			} else {
+				r.Filter = r.Pattern
+			}
+		}
+		if r.SpaceType == "" {
+			r.SpaceType = "project"
+		}
+		if r.NameTemplate == "" {
+			r.NameTemplate = "{{.GroupName}}"
+		}
+		if r.Role == "" {
+			r.Role = permissions.RoleEditor
+		}
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+// rule is a parsed rule of the config.
+type rule struct {
+	*ruleConfig
+	name *template.Template
+}
+
+// matches tells whether the group gets a space by the rule.
+func (r *rule) matches(g *grouppb.Group) bool {
+	ok, err := path.Match(r.Pattern, g.GroupName)
+	return err == nil && ok
+}
+
+func (r *rule) spaceName(g *grouppb.Group) (string, error) {
+	var b bytes.Buffer
+	if err := r.name.Execute(&b, g); err != nil {
+		return "", errors.Wrapf(err, "groupspaces: error executing the name template of %s", r.Pattern)
+	}
+	return b.String(), nil
+}
+
+type svc struct {
+	conf     *config
+	rules    []*rule
+	tokenmgr token.Manager
+	state    *state
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// New returns a new groupspaces service. It reconciles the groups with the
+// spaces in the background and does not serve any requests.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+	if conf.Owner == "" {
+		return nil, errors.New("groupspaces: owner not configured")
+	}
+
+	rules := make([]*rule, 0, len(conf.Rules))
+	for i := range conf.Rules {
+		rc := &conf.Rules[i]
+		if _, err := path.Match(rc.Pattern, ""); err != nil || rc.Pattern == "" {
+			return nil, errors.Errorf("groupspaces: invalid pattern %q", rc.Pattern)
+		}
+		if !isSpaceRole(rc.Role) {
+			return nil, errors.Errorf("groupspaces: invalid role %q of %s", rc.Role, rc.Pattern)
+		}
+		t, err := template.New(rc.Pattern).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(rc.NameTemplate)
+		if err != nil {
+			return nil, errors.Wrapf(err, "groupspaces: error parsing the name template of %s", rc.Pattern)
+		}
+		rules = append(rules, &rule{ruleConfig: rc, name: t})
+	}
+
+	tf, ok := tokenregistry.NewFuncs[conf.TokenManager]
+	if !ok {
+		return nil, errtypes.NotFound("groupspaces: token manager not found: " + conf.TokenManager)
+	}
+	tokenmgr, err := tf(conf.TokenManagers[conf.TokenManager])
+	if err != nil {
+		return nil, err
+	}
+	st, err := loadState(conf.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &svc{
+		conf:     conf,
+		rules:    rules,
+		tokenmgr: tokenmgr,
+		state:    st,
+	}
+	ctx, cancel := context.WithCancel(appctx.WithLogger(context.Background(), log))
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.loop(ctx)
+	return s, nil
+}
+
+func (s *svc) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.NotFoundHandler()
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func isSpaceRole(name string) bool {
+	switch name {
+	case permissions.RoleViewer, permissions.RoleEditor, permissions.RoleManager:
+		return true
+	}
+	return false
+}
+
+func (s *svc) loop(ctx context.Context) {
+	defer s.wg.Done()
+	log := appctx.GetLogger(ctx)
+	ticker := time.NewTicker(time.Duration(s.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		if err := s.reconcile(ctx); err != nil {
+			log.Error().Err(err).Msg("groupspaces: error reconciling the group spaces")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile creates the spaces of the new groups and archives the ones of the
+// groups that are gone, rule by rule. A rule whose groups can't be listed is
+// skipped rather than having all its spaces archived.
+func (s *svc) reconcile(ctx context.Context) error {
+	log := appctx.GetLogger(ctx)
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return err
+	}
+	spaces, err := pool.GetSpacesServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return err
+	}
+	ctx, owner, err := s.asOwner(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range s.rules {
+		res, err := client.FindGroups(ctx, &grouppb.FindGroupsRequest{Filter: r.Filter})
+		if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+			log.Error().Err(err).Interface("status", res.GetStatus()).Str("pattern", r.Pattern).Msg("groupspaces: error finding groups, skipping")
+			continue
+		}
+
+		s.state.mu.Lock()
+		create, archive := plan(r, res.Groups, s.state.Spaces)
+		s.state.mu.Unlock()
+
+		for _, g := range create {
+			if err := s.createSpace(ctx, client, spaces, owner, r, g); err != nil {
+				log.Error().Err(err).Str("group", g.GroupName).Msg("groupspaces: error creating space")
+			}
+		}
+		if !r.Archive {
+			continue
+		}
+		for _, m := range archive {
+			if err := s.archiveSpace(ctx, client, m); err != nil {
+				log.Error().Err(err).Str("space", m.SpaceID).Msg("groupspaces: error archiving space")
+			}
+		}
+	}
+	return nil
+}
+
+// plan returns the groups of the rule lacking a space and the spaces of the
+// rule whose group is gone or doesn't match anymore.
+func plan(r *rule, groups []*grouppb.Group, managed map[string]*managedSpace) ([]*grouppb.Group, []*managedSpace) {
+	var create []*grouppb.Group
+	current := map[string]bool{}
+	for _, g := range groups {
+		if !r.matches(g) {
+			continue
+		}
+		k := groupKey(g.Id)
+		current[k] = true
+		if _, ok := managed[k]; !ok {
+			create = append(create, g)
+		}
+	}
+	var archive []*managedSpace
+	for k, m := range managed {
+		if m.Pattern == r.Pattern && !current[k] {
+			archive = append(archive, m)
+		}
+	}
+	return create, archive
+}
+
+// createSpace creates the space of the group, or adopts the one of the owner
+// with its name left by a previous attempt, and adds the group as a member.
+func (s *svc) createSpace(ctx context.Context, client gateway.GatewayAPIClient, spaces spacespb.SpacesServiceClient, owner *userpb.User, r *rule, g *grouppb.Group) error {
+	name, err := r.spaceName(g)
+	if err != nil {
+		return err
+	}
+
+	id, err := findSpace(ctx, client, owner, r.SpaceType, name)
+	if err != nil {
+		return err
+	}
+	if id == nil {
+		req := &provider.CreateStorageSpaceRequest{Owner: owner, Type: r.SpaceType, Name: name}
+		if r.Quota > 0 {
+			req.Quota = &provider.Quota{QuotaMaxBytes: r.Quota}
+		}
+		res, err := client.CreateStorageSpace(ctx, req)
+		if err != nil {
+			return errors.Wrap(err, "groupspaces: error calling CreateStorageSpace")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return status.NewErrorFromCode(res.Status.Code, "groupspaces")
+		}
+		id = res.StorageSpace.GetId()
+	}
+
+	mres, err := spaces.AddSpaceMember(ctx, &spacespb.AddSpaceMemberRequest{
+		Id: id,
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+			Id:   &provider.Grantee_GroupId{GroupId: g.Id},
+		},
+		Role: r.Role,
+	})
+	if err != nil {
+		return errors.Wrap(err, "groupspaces: error calling AddSpaceMember")
+	}
+	if mres.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(mres.Status.Code, "groupspaces")
+	}
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	s.state.Spaces[groupKey(g.Id)] = &managedSpace{SpaceID: id.OpaqueId, Name: name, GroupID: g.Id, Pattern: r.Pattern}
+	appctx.GetLogger(ctx).Info().Str("group", g.GroupName).Str("space", id.OpaqueId).Msg("groupspaces: space provisioned")
+	return s.state.save()
+}
+
+// findSpace returns the id of the space of the owner with the type and name, nil if there is none.
+func findSpace(ctx context.Context, client gateway.GatewayAPIClient, owner *userpb.User, spaceType, name string) (*provider.StorageSpaceId, error) {
+	res, err := client.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: spaceType},
+			},
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_OWNER,
+				Term: &provider.ListStorageSpacesRequest_Filter_Owner{Owner: owner.Id},
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "groupspaces: error calling ListStorageSpaces")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "groupspaces")
+	}
+	for _, sp := range res.StorageSpaces {
+		if sp.Name == name {
+			return sp.Id, nil
+		}
+	}
+	return nil, nil
+}
+
+// archiveSpace deletes the space of a group that is gone from its storage provider.
+func (s *svc) archiveSpace(ctx context.Context, client gateway.GatewayAPIClient, m *managedSpace) error {
+	res, err := client.DeleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{
+		Id: &provider.StorageSpaceId{OpaqueId: m.SpaceID},
+	})
+	if err != nil {
+		return errors.Wrap(err, "groupspaces: error calling DeleteStorageSpace")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		return status.NewErrorFromCode(res.Status.Code, "groupspaces")
+	}
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	delete(s.state.Spaces, groupKey(m.GroupID))
+	appctx.GetLogger(ctx).Info().Str("space", m.SpaceID).Str("name", m.Name).Msg("groupspaces: space archived")
+	return s.state.save()
+}
+
+// asOwner returns a context authenticated as the owner of the spaces, and the owner.
+func (s *svc) asOwner(ctx context.Context, client gateway.GatewayAPIClient) (context.Context, *userpb.User, error) {
+	u := &userpb.User{Id: &userpb.UserId{OpaqueId: s.conf.Owner, Idp: s.conf.OwnerIdp}}
+	octx, err := s.withToken(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := client.GetUser(octx, &userpb.GetUserRequest{UserId: u.Id})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "groupspaces: error getting owner")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, nil, status.NewErrorFromCode(res.Status.Code, "groupspaces")
+	}
+	octx, err = s.withToken(ctx, res.User)
+	if err != nil {
+		return nil, nil, err
+	}
+	return octx, res.User, nil
+}
+
+func (s *svc) withToken(ctx context.Context, u *userpb.User) (context.Context, error) {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return nil, err
+	}
+	tkn, err := s.tokenmgr.MintToken(token.ContextSetService(ctx, "groupspaces"), u, ownerScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "groupspaces: error minting token")
+	}
+	ctx = token.ContextSetToken(ctx, tkn)
+	ctx = user.ContextSetUser(ctx, u)
+	return metadata.AppendToOutgoingContext(ctx, token.TokenHeader, tkn), nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package groupspaces
+
+import (
+	"sort"
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+)
+
+func group(name string) *grouppb.Group {
+	return &grouppb.Group{Id: &grouppb.GroupId{Idp: "idp", OpaqueId: name}, GroupName: name}
+}
+
+func TestPlan(t *testing.T) {
+	c := &config{Owner: "admin", Rules: []ruleConfig{{Pattern: "project-*"}}}
+	c.init()
+	if c.Rules[0].Filter != "project-" {
+		t.Fatalf("expected filter project-, got %q", c.Rules[0].Filter)
+	}
+	r := &rule{ruleConfig: &c.Rules[0]}
+
+	managed := map[string]*managedSpace{
+		"idp/project-a":   {SpaceID: "a", GroupID: group("project-a").Id, Pattern: "project-*"},
+		"idp/project-old": {SpaceID: "old", GroupID: group("project-old").Id, Pattern: "project-*"},
+		"idp/team-x":      {SpaceID: "x", GroupID: group("team-x").Id, Pattern: "team-*"},
+	}
+	groups := []*grouppb.Group{group("project-a"), group("project-b"), group("projects"), group("project-c")}
+
+	create, archive := plan(r, groups, managed)
+	var names []string
+	for _, g := range create {
+		names = append(names, g.GroupName)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "project-b" || names[1] != "project-c" {
+		t.Errorf("expected to create project-b and project-c, got %v", names)
+	}
+	if len(archive) != 1 || archive[0].SpaceID != "old" {
+		t.Errorf("expected to archive old, got %v", archive)
+	}
+
+	if create, archive := plan(r, nil, map[string]*managedSpace{}); len(create) != 0 || len(archive) != 0 {
+		t.Errorf("expected nothing to do, got %v %v", create, archive)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package groupspaces
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	"github.com/pkg/errors"
+)
+
+// managedSpace is a space created for a group.
+type managedSpace struct {
+	SpaceID string           `json:"space_id"`
+	Name    string           `json:"name"`
+	GroupID *grouppb.GroupId `json:"group_id"`
+	// Pattern is the pattern of the rule the space was created by.
+	Pattern string `json:"pattern"`
+}
+
+// state is what the service has to remember across restarts, the spaces it
+// manages by group.
+type state struct {
+	file string
+
+	mu     sync.Mutex
+	Spaces map[string]*managedSpace `json:"spaces"`
+}
+
+func loadState(file string) (*state, error) {
+	s := &state{file: file, Spaces: map[string]*managedSpace{}}
+	b, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "groupspaces: error reading state")
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, errors.Wrap(err, "groupspaces: error decoding state")
+	}
+	if s.Spaces == nil {
+		s.Spaces = map[string]*managedSpace{}
+	}
+	return s, nil
+}
+
+// save writes the state, the caller holding the lock.
+func (s *state) save() error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "groupspaces: error encoding state")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), 0700); err != nil {
+		return errors.Wrap(err, "groupspaces: error writing state")
+	}
+	tmp := s.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "groupspaces: error writing state")
+	}
+	return errors.Wrap(os.Rename(tmp, s.file), "groupspaces: error writing state")
+}
+
+func groupKey(id *grouppb.GroupId) string {
+	return id.GetIdp() + "/" + id.GetOpaqueId()
+}
@@ -0,0 +1,188 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package guests serves the page the guests accept their invitation at,
+// setting the password of their account.
+package guests
+
+import (
+	"html/template"
+	"net/http"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	guestspb "github.com/cs3org/reva/internal/grpc/services/guests/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/passwordpolicy"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("guests", New)
+}
+
+type config struct {
+	Prefix string `mapstructure:"prefix"`
+	// GuestsSvc is the endpoint of the guests gRPC service.
+	GuestsSvc string `mapstructure:"guestssvc"`
+	// LoginURL is the page the guests are pointed to once they set their password.
+	LoginURL string `mapstructure:"login_url"`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "guests"
+	}
+	c.GuestsSvc = sharedconf.GetGatewaySVC(c.GuestsSvc)
+}
+
+type svc struct {
+	conf *config
+}
+
+// page is the data the accept page is rendered with.
+type page struct {
+	Token string
+	Error string
+	// Violations are the rules of the password policy the password broke.
+	Violations []*passwordpolicy.Violation
+	Accepted   bool
+	Username   string
+	LoginURL   string
+}
+
+var acceptPage = template.Must(template.New("accept").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Guest account</title></head>
+<body>
+{{if .Accepted}}
+<p>The password of your guest account {{.Username}} was set.</p>
+{{if .LoginURL}}<p><a href="{{.LoginURL}}">Log in</a></p>{{end}}
+{{else}}
+<h1>Set the password of your guest account</h1>
+{{if .Error}}<p>{{.Error}}</p>{{end}}
+<form method="post">
+<input type="hidden" name="token" value="{{.Token}}">
+<p><label>Password <input type="password" name="password" required></label></p>
+{{if .Violations}}<ul>{{range .Violations}}<li>{{.Message}}</li>{{end}}</ul>{{end}}
+<p><label>Repeat the password <input type="password" name="password_confirmation" required></label></p>
+<p><button type="submit">Set password</button></p>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// New returns a new guests service
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+	return &svc{conf: conf}, nil
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	// the guests have no account they could log in with yet
+	return []string{"/accept"}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		switch {
+		case head == "accept" && r.Method == http.MethodGet:
+			s.render(w, r, http.StatusOK, &page{Token: r.URL.Query().Get("token")})
+		case head == "accept" && r.Method == http.MethodPost:
+			s.accept(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func (s *svc) accept(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+	p := &page{Token: r.FormValue("token")}
+	password := r.FormValue("password")
+	switch {
+	case p.Token == "":
+		p.Error = "The link of the invitation is incomplete."
+		s.render(w, r, http.StatusBadRequest, p)
+		return
+	case password != r.FormValue("password_confirmation"):
+		p.Error = "The passwords don't match."
+		s.render(w, r, http.StatusBadRequest, p)
+		return
+	}
+
+	c, err := pool.GetGuestServiceClient(s.conf.GuestsSvc)
+	if err != nil {
+		log.Error().Err(err).Msg("guests: error getting guest service client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res, err := c.AcceptInvitation(r.Context(), &guestspb.AcceptInvitationRequest{Token: p.Token, Password: password})
+	if err != nil {
+		log.Error().Err(err).Msg("guests: error accepting invitation")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		p.Error = "The password does not comply with the password policy."
+		p.Violations, _ = passwordpolicy.DecodeViolations(res.Opaque)
+		s.render(w, r, http.StatusBadRequest, p)
+		return
+	case rpc.Code_CODE_NOT_FOUND:
+		p.Error = "The invitation expired or was already accepted."
+		s.render(w, r, http.StatusNotFound, p)
+		return
+	default:
+		log.Error().Str("code", res.Status.Code.String()).Str("message", res.Status.Message).Msg("guests: error accepting invitation")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	s.render(w, r, http.StatusOK, &page{Accepted: true, Username: res.User.GetUsername(), LoginURL: s.conf.LoginURL})
+}
+
+func (s *svc) render(w http.ResponseWriter, r *http.Request, code int, p *page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	if err := acceptPage.Execute(w, p); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("guests: error rendering page")
+	}
+}
@@ -20,19 +20,30 @@ package loader
 
 import (
 	// Load core HTTP services
+	_ "github.com/cs3org/reva/internal/http/services/archiver"
+	_ "github.com/cs3org/reva/internal/http/services/branding"
 	_ "github.com/cs3org/reva/internal/http/services/datagateway"
 	_ "github.com/cs3org/reva/internal/http/services/dataprovider"
+	_ "github.com/cs3org/reva/internal/http/services/graph"
+	_ "github.com/cs3org/reva/internal/http/services/groupspaces"
+	_ "github.com/cs3org/reva/internal/http/services/guests"
 	_ "github.com/cs3org/reva/internal/http/services/helloworld"
 	_ "github.com/cs3org/reva/internal/http/services/mentix"
 	_ "github.com/cs3org/reva/internal/http/services/meshdirectory"
 	_ "github.com/cs3org/reva/internal/http/services/metrics"
+	_ "github.com/cs3org/reva/internal/http/services/notifications"
 	_ "github.com/cs3org/reva/internal/http/services/ocmd"
 	_ "github.com/cs3org/reva/internal/http/services/oidcprovider"
+	_ "github.com/cs3org/reva/internal/http/services/ops"
 	_ "github.com/cs3org/reva/internal/http/services/owncloud/ocdav"
 	_ "github.com/cs3org/reva/internal/http/services/owncloud/ocs"
 	_ "github.com/cs3org/reva/internal/http/services/prometheus"
 	_ "github.com/cs3org/reva/internal/http/services/siteacc"
 	_ "github.com/cs3org/reva/internal/http/services/sysinfo"
+	_ "github.com/cs3org/reva/internal/http/services/thumbnails"
+	_ "github.com/cs3org/reva/internal/http/services/tokenadmin"
+	_ "github.com/cs3org/reva/internal/http/services/twofactor"
+	_ "github.com/cs3org/reva/internal/http/services/useradmin"
 	_ "github.com/cs3org/reva/internal/http/services/wellknown"
 	// Add your own service here
 )
@@ -0,0 +1,594 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package notifications sends mails to the users about the events of the
+// services: the shares they receive, their public links about to expire and
+// the files uploaded to their public upload folders.
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/smtpclient"
+	"github.com/cs3org/reva/pkg/token"
+	tokenregistry "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	global.Register("notifications", New)
+}
+
+type config struct {
+	Prefix        string                            `mapstructure:"prefix" docs:"notifications;The prefix to be used for this HTTP service"`
+	GatewaySvc    string                            `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
+	EventStream   string                            `mapstructure:"event_stream" docs:"nats;The event stream the events are consumed from."`
+	EventStreams  map[string]map[string]interface{} `mapstructure:"event_streams" docs:"url:pkg/events/nats/nats.go;The configuration for the event streams."`
+	Group         string                            `mapstructure:"group" docs:"notifications;The consumer group, the services of a group share the events."`
+	SMTP          *smtpclient.SMTPCredentials       `mapstructure:"smtp" docs:";The SMTP server the mails are sent through."`
+	TemplatesDir  string                            `mapstructure:"templates_dir" docs:";The directory holding custom templates as <language>/<kind>.tmpl."`
+	Language      string                            `mapstructure:"language" docs:"en;The language of the users that did not choose one."`
+	BaseURL       string                            `mapstructure:"base_url" docs:";The URL of the web interface the links of the mails point to."`
+	LinkWarning   int                               `mapstructure:"link_expiration_warning" docs:"24;How many hours before the expiration of a public link its creator is warned."`
+	CheckInterval int                               `mapstructure:"check_interval" docs:"600;How often in seconds links are checked for their expiration and digests are sent."`
+	StateFile     string                            `mapstructure:"state_file" docs:"/var/tmp/reva/notifications.json;The file the followed links and pending digests are kept in."`
+	TokenManager  string                            `mapstructure:"token_manager" docs:"jwt;The token manager minting the tokens the preferences of the recipients are read with."`
+	TokenManagers map[string]map[string]interface{} `mapstructure:"token_managers" docs:"url:pkg/token/manager/jwt/jwt.go;The configuration for the token managers."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "notifications"
+	}
+	if c.EventStream == "" {
+		c.EventStream = "nats"
+	}
+	if c.Group == "" {
+		c.Group = "notifications"
+	}
+	if c.Language == "" {
+		c.Language = "en"
+	}
+	if c.LinkWarning == 0 {
+		c.LinkWarning = 24
+	}
+	if c.CheckInterval == 0 {
+		c.CheckInterval = 600
+	}
+	if c.StateFile == "" {
+		c.StateFile = "/var/tmp/reva/notifications.json"
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+	c.BaseURL = strings.TrimSuffix(c.BaseURL, "/")
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf      *config
+	stream    events.Stream
+	templates *notification.Templates
+	smtp      *smtpclient.SMTPCredentials
+	tokenmgr  token.Manager
+	state     *state
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// New returns a new notifications service. It consumes the events in the
+// background and does not serve any requests.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+	if conf.SMTP == nil {
+		return nil, errors.New("notifications: smtp not configured")
+	}
+
+	f, ok := eventsregistry.NewFuncs[conf.EventStream]
+	if !ok {
+		return nil, errtypes.NotFound("notifications: event stream not found: " + conf.EventStream)
+	}
+	stream, err := f(conf.EventStreams[conf.EventStream])
+	if err != nil {
+		return nil, err
+	}
+
+	tf, ok := tokenregistry.NewFuncs[conf.TokenManager]
+	if !ok {
+		return nil, errtypes.NotFound("notifications: token manager not found: " + conf.TokenManager)
+	}
+	tokenmgr, err := tf(conf.TokenManagers[conf.TokenManager])
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := notification.NewTemplates(conf.TemplatesDir, conf.Language)
+	if err != nil {
+		return nil, err
+	}
+	st, err := loadState(conf.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &svc{
+		conf:      conf,
+		stream:    stream,
+		templates: templates,
+		smtp:      smtpclient.NewSMTPCredentials(conf.SMTP),
+		tokenmgr:  tokenmgr,
+		state:     st,
+	}
+
+	ctx, cancel := context.WithCancel(appctx.WithLogger(context.Background(), log))
+	s.cancel = cancel
+	err = events.Consume(ctx, stream, conf.Group, func(ev events.Event) { s.handle(ctx, ev) },
+		events.ShareCreated{}.Type(),
+		events.PublicShareCreated{}.Type(),
+		events.PublicShareUpdated{}.Type(),
+		events.PublicShareRemoved{}.Type(),
+		events.FileUploaded{}.Type(),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.loop(ctx)
+	return s, nil
+}
+
+func (s *svc) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return s.stream.Close()
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.NotFoundHandler()
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) handle(ctx context.Context, ev events.Event) {
+	log := appctx.GetLogger(ctx)
+	var err error
+	switch e := ev.(type) {
+	case *events.ShareCreated:
+		err = s.shareCreated(ctx, e)
+	case *events.PublicShareCreated:
+		err = s.followLink(e)
+	case *events.PublicShareUpdated:
+		err = s.followLink((*events.PublicShareCreated)(e))
+	case *events.PublicShareRemoved:
+		err = s.unfollowLink(e)
+	case *events.FileUploaded:
+		err = s.fileUploaded(ctx, e)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("event", ev.Type()).Msg("notifications: error handling event")
+	}
+}
+
+func (s *svc) shareCreated(ctx context.Context, e *events.ShareCreated) error {
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return err
+	}
+	sharer, err := s.getUser(ctx, client, e.Sharer)
+	if err != nil {
+		return err
+	}
+
+	recipients := []*userpb.UserId{e.GranteeUserID}
+	if e.GranteeGroupID != nil {
+		res, err := client.GetMembers(s.asUser(ctx, sharer), &grouppb.GetMembersRequest{GroupId: e.GranteeGroupID})
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return status.NewErrorFromCode(res.Status.Code, "notifications")
+		}
+		recipients = res.Members
+	}
+
+	for _, id := range recipients {
+		if id == nil || utils.UserEqual(id, sharer.Id) {
+			continue
+		}
+		recipient, err := s.getUser(ctx, client, id)
+		if err != nil {
+			return err
+		}
+		data := &notification.Data{
+			Actor:        sharer.DisplayName,
+			ResourceName: s.resourceName(ctx, client, recipient, e.ItemID),
+			URL:          s.conf.BaseURL,
+		}
+		if err := s.notify(ctx, client, recipient, notification.KindShareReceived, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *svc) followLink(e *events.PublicShareCreated) error {
+	l := &link{
+		ID:          e.ShareID.GetOpaqueId(),
+		Token:       e.Token,
+		ItemID:      e.ItemID,
+		Creator:     e.Creator,
+		DisplayName: e.DisplayName,
+		Upload:      e.Permissions.GetPermissions().GetInitiateFileUpload(),
+	}
+	if e.NotifyUploads != nil && !*e.NotifyUploads {
+		// the creator of the file drop opted out of the upload notifications
+		l.Upload = false
+	}
+	if e.Expiration != nil {
+		l.Expiration = time.Unix(int64(e.Expiration.Seconds), 0)
+	}
+	if l.Creator == nil {
+		l.Creator = e.Owner
+	}
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if old, ok := s.state.Links[l.ID]; ok && old.Expiration.Equal(l.Expiration) {
+		l.Warned = old.Warned
+	}
+	if l.Expiration.IsZero() && !l.Upload {
+		// nothing to notify about
+		delete(s.state.Links, l.ID)
+	} else {
+		s.state.Links[l.ID] = l
+	}
+	return s.state.save()
+}
+
+func (s *svc) unfollowLink(e *events.PublicShareRemoved) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	for id, l := range s.state.Links {
+		if id == e.ShareID.GetOpaqueId() || (e.Token != "" && l.Token == e.Token) {
+			delete(s.state.Links, id)
+		}
+	}
+	return s.state.save()
+}
+
+func (s *svc) fileUploaded(ctx context.Context, e *events.FileUploaded) error {
+	if e.ParentID == nil {
+		return nil
+	}
+	var links []*link
+	s.state.mu.Lock()
+	for _, l := range s.state.Links {
+		if l.Upload && sameResource(l.ItemID, e.ParentID) {
+			links = append(links, l)
+		}
+	}
+	s.state.mu.Unlock()
+	if len(links) == 0 {
+		return nil
+	}
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return err
+	}
+	notified := map[string]bool{}
+	for _, l := range links {
+		if notified[userKey(l.Creator)] {
+			continue
+		}
+		notified[userKey(l.Creator)] = true
+		recipient, err := s.getUser(ctx, client, l.Creator)
+		if err != nil {
+			return err
+		}
+		data := &notification.Data{
+			ResourceName: path.Base(e.Path),
+			FolderName:   s.resourceName(ctx, client, recipient, l.ItemID),
+			LinkName:     l.DisplayName,
+			URL:          s.conf.BaseURL,
+		}
+		if err := s.notify(ctx, client, recipient, notification.KindUploadReceived, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameResource compares the ids of resources, the events of the data
+// providers may lack the storage id.
+func sameResource(a, b *provider.ResourceId) bool {
+	if a.GetOpaqueId() != b.GetOpaqueId() {
+		return false
+	}
+	return a.GetStorageId() == "" || b.GetStorageId() == "" || a.GetStorageId() == b.GetStorageId()
+}
+
+// loop warns about the expiring links and sends the digests until ctx is done.
+func (s *svc) loop(ctx context.Context) {
+	defer s.wg.Done()
+	log := appctx.GetLogger(ctx)
+	ticker := time.NewTicker(time.Duration(s.conf.CheckInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.checkLinks(ctx); err != nil {
+			log.Error().Err(err).Msg("notifications: error checking expiring links")
+		}
+		if err := s.sendDigests(ctx); err != nil {
+			log.Error().Err(err).Msg("notifications: error sending digests")
+		}
+	}
+}
+
+func (s *svc) checkLinks(ctx context.Context) error {
+	now := time.Now()
+	warnBefore := now.Add(time.Duration(s.conf.LinkWarning) * time.Hour)
+
+	var expiring []*link
+	s.state.mu.Lock()
+	for id, l := range s.state.Links {
+		switch {
+		case l.Expiration.IsZero():
+		case !l.Expiration.After(now):
+			// expired links can't be used for uploads either
+			delete(s.state.Links, id)
+		case !l.Warned && l.Expiration.Before(warnBefore):
+			expiring = append(expiring, l)
+		}
+	}
+	s.state.mu.Unlock()
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return err
+	}
+	for _, l := range expiring {
+		recipient, err := s.getUser(ctx, client, l.Creator)
+		if err != nil {
+			return err
+		}
+		data := &notification.Data{
+			ResourceName: s.resourceName(ctx, client, recipient, l.ItemID),
+			LinkName:     l.DisplayName,
+			Expiration:   l.Expiration,
+			URL:          s.conf.BaseURL + "/s/" + l.Token,
+		}
+		if s.conf.BaseURL == "" {
+			data.URL = ""
+		}
+		if err := s.notify(ctx, client, recipient, notification.KindLinkExpiring, data); err != nil {
+			return err
+		}
+		s.state.mu.Lock()
+		l.Warned = true
+		s.state.mu.Unlock()
+	}
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	return s.state.save()
+}
+
+func (s *svc) sendDigests(ctx context.Context) error {
+	now := time.Now()
+	s.state.mu.Lock()
+	pending := make([]*digest, 0, len(s.state.Digests))
+	for _, d := range s.state.Digests {
+		pending = append(pending, d)
+	}
+	s.state.mu.Unlock()
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+	if err != nil {
+		return err
+	}
+	for _, d := range pending {
+		recipient, err := s.getUser(ctx, client, d.UserID)
+		if err != nil {
+			return err
+		}
+		prefs := s.getPreferences(ctx, client, recipient)
+		interval := 24 * time.Hour
+		if prefs.Digest == notification.DigestHourly {
+			interval = time.Hour
+		}
+		// the users who turned digests off get the pending ones right away
+		if prefs.Digest != notification.DigestNone && now.Sub(d.Since) < interval {
+			continue
+		}
+
+		s.state.mu.Lock()
+		messages := d.Messages
+		delete(s.state.Digests, userKey(d.UserID))
+		err = s.state.save()
+		s.state.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		if err := s.sendDigest(prefs, recipient, messages); err != nil {
+			// keep the messages for the next attempt
+			s.state.mu.Lock()
+			if d, ok := s.state.Digests[userKey(recipient.Id)]; ok {
+				d.Messages = append(messages, d.Messages...)
+			} else {
+				s.state.Digests[userKey(recipient.Id)] = &digest{UserID: recipient.Id, Messages: messages, Since: now}
+			}
+			_ = s.state.save()
+			s.state.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+// sendDigest sends one mail with the summary of the messages followed by them.
+func (s *svc) sendDigest(prefs *notification.Preferences, recipient *userpb.User, messages []*notification.Message) error {
+	msg, err := s.templates.Render(prefs.Language, notification.KindDigest, &notification.DigestData{
+		Recipient: recipient.DisplayName,
+		Messages:  messages,
+	})
+	if err != nil {
+		return err
+	}
+	var body strings.Builder
+	body.WriteString(msg.Body)
+	for _, m := range messages {
+		body.WriteString("\n\n" + m.Subject + "\n\n" + m.Body)
+	}
+	return s.smtp.SendMail(recipient.Mail, msg.Subject, body.String())
+}
+
+// notify sends a notification to the recipient, or adds it to their digest,
+// according to their preferences.
+func (s *svc) notify(ctx context.Context, client gateway.GatewayAPIClient, recipient *userpb.User, kind string, data *notification.Data) error {
+	if recipient.Mail == "" {
+		return nil
+	}
+	prefs := s.getPreferences(ctx, client, recipient)
+	if !prefs.Enabled(kind) {
+		return nil
+	}
+
+	data.Recipient = recipient.DisplayName
+	msg, err := s.templates.Render(prefs.Language, kind, data)
+	if err != nil {
+		return err
+	}
+
+	if prefs.Digest == notification.DigestNone {
+		return s.smtp.SendMail(recipient.Mail, msg.Subject, msg.Body)
+	}
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	d, ok := s.state.Digests[userKey(recipient.Id)]
+	if !ok {
+		d = &digest{UserID: recipient.Id, Since: time.Now()}
+		s.state.Digests[userKey(recipient.Id)] = d
+	}
+	d.Messages = append(d.Messages, msg)
+	return s.state.save()
+}
+
+func (s *svc) getUser(ctx context.Context, client gateway.GatewayAPIClient, id *userpb.UserId) (*userpb.User, error) {
+	res, err := client.GetUser(ctx, &userpb.GetUserRequest{UserId: id})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "notifications")
+	}
+	return res.User, nil
+}
+
+// getPreferences returns the preferences of the user, the defaults if they
+// can't be read.
+func (s *svc) getPreferences(ctx context.Context, client gateway.GatewayAPIClient, u *userpb.User) *notification.Preferences {
+	log := appctx.GetLogger(ctx)
+	prefs := notification.DefaultPreferences()
+	res, err := client.GetKey(s.asUser(ctx, u), &preferences.GetKeyRequest{Key: notification.PreferencesKey})
+	switch {
+	case err != nil:
+		log.Error().Err(err).Str("user", u.Username).Msg("notifications: error getting preferences")
+	case res.Status.Code == rpc.Code_CODE_OK:
+		p, err := notification.ParsePreferences(res.Val)
+		if err != nil {
+			log.Error().Err(err).Str("user", u.Username).Msg("notifications: invalid preferences")
+			break
+		}
+		prefs = p
+	}
+	if prefs.Language == "" {
+		prefs.Language = s.conf.Language
+	}
+	return prefs
+}
+
+// resourceName returns the name of the resource as seen by the user, or an
+// empty name if the user can't stat it.
+func (s *svc) resourceName(ctx context.Context, client gateway.GatewayAPIClient, u *userpb.User, id *provider.ResourceId) string {
+	res, err := client.Stat(s.asUser(ctx, u), &provider.StatRequest{
+		Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: id}},
+	})
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		return ""
+	}
+	return path.Base(res.Info.Path)
+}
+
+// asUser returns a context authenticated as the given user.
+func (s *svc) asUser(ctx context.Context, u *userpb.User) context.Context {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return ctx
+	}
+	tkn, err := s.tokenmgr.MintToken(token.ContextSetService(ctx, "notifications"), u, ownerScope)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("notifications: error minting token")
+		return ctx
+	}
+	ctx = token.ContextSetToken(ctx, tkn)
+	return metadata.AppendToOutgoingContext(ctx, token.TokenHeader, tkn)
+}
@@ -0,0 +1,102 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/pkg/errors"
+)
+
+// link is a public link followed for its expiration and the uploads to it.
+type link struct {
+	ID          string               `json:"id"`
+	Token       string               `json:"token"`
+	ItemID      *provider.ResourceId `json:"item_id"`
+	Creator     *userpb.UserId       `json:"creator"`
+	DisplayName string               `json:"display_name,omitempty"`
+	Upload      bool                 `json:"upload"`
+	Expiration  time.Time            `json:"expiration,omitempty"`
+	Warned      bool                 `json:"warned,omitempty"`
+}
+
+// digest collects the notifications of a user until they are sent.
+type digest struct {
+	UserID   *userpb.UserId          `json:"user_id"`
+	Messages []*notification.Message `json:"messages"`
+	Since    time.Time               `json:"since"`
+}
+
+// state is what the service has to remember across restarts.
+type state struct {
+	file string
+
+	mu      sync.Mutex
+	Links   map[string]*link   `json:"links"`
+	Digests map[string]*digest `json:"digests"`
+}
+
+func loadState(file string) (*state, error) {
+	s := &state{file: file, Links: map[string]*link{}, Digests: map[string]*digest{}}
+	b, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "notifications: error reading state")
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, errors.Wrap(err, "notifications: error decoding state")
+	}
+	if s.Links == nil {
+		s.Links = map[string]*link{}
+	}
+	if s.Digests == nil {
+		s.Digests = map[string]*digest{}
+	}
+	return s, nil
+}
+
+// save writes the state, the caller holding the lock.
+func (s *state) save() error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "notifications: error encoding state")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), 0700); err != nil {
+		return errors.Wrap(err, "notifications: error writing state")
+	}
+	tmp := s.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "notifications: error writing state")
+	}
+	return errors.Wrap(os.Rename(tmp, s.file), "notifications: error writing state")
+}
+
+func userKey(id *userpb.UserId) string {
+	return id.GetIdp() + "/" + id.GetOpaqueId()
+}
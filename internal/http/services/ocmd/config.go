@@ -24,35 +24,17 @@ import (
 	"net/http"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ocm/discovery"
 )
 
-type configData struct {
-	Enabled       bool            `json:"enabled" xml:"enabled"`
-	APIVersion    string          `json:"apiVersion" xml:"apiVersion"`
-	Host          string          `json:"host" xml:"host"`
-	Endpoint      string          `json:"endpoint" xml:"endpoint"`
-	Provider      string          `json:"provider" xml:"provider"`
-	ResourceTypes []resourceTypes `json:"resourceTypes" xml:"resourceTypes"`
-}
-
-type resourceTypes struct {
-	Name       string                 `json:"name"`
-	ShareTypes []string               `json:"shareTypes"`
-	Protocols  resourceTypesProtocols `json:"protocols"`
-}
-
-type resourceTypesProtocols struct {
-	Webdav string `json:"webdav"`
-}
-
 type configHandler struct {
-	c configData
+	c discovery.Document
 }
 
 func (h *configHandler) init(c *Config) {
 	h.c = c.Config
 	if h.c.APIVersion == "" {
-		h.c.APIVersion = "1.0-proposal1"
+		h.c.APIVersion = discovery.APIVersion
 	}
 	if h.c.Host == "" {
 		h.c.Host = "localhost"
@@ -62,13 +44,18 @@ func (h *configHandler) init(c *Config) {
 	}
 	h.c.Enabled = true
 	h.c.Endpoint = fmt.Sprintf("https://%s/%s", h.c.Host, c.Prefix)
-	h.c.ResourceTypes = []resourceTypes{resourceTypes{
-		Name:       "file",
-		ShareTypes: []string{"user"},
-		Protocols: resourceTypesProtocols{
-			Webdav: fmt.Sprintf("/%s/ocm_webdav", h.c.Provider),
-		},
-	}}
+	if len(h.c.ResourceTypes) == 0 {
+		webdav := fmt.Sprintf("/%s/ocm_webdav", h.c.Provider)
+		h.c.ResourceTypes = []discovery.ResourceType{{
+			Name:       discovery.ResourceTypeFile,
+			ShareTypes: []string{discovery.ShareTypeUser},
+			Protocols: map[string]string{
+				discovery.ProtocolWebdav: webdav,
+				// the transfers pull the data through webdav
+				discovery.ProtocolDatatx: webdav,
+			},
+		}}
+	}
 }
 
 func (h *configHandler) Handler() http.Handler {
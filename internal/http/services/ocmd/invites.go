@@ -23,12 +23,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ocm/invite"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/smtpclient"
@@ -64,6 +66,10 @@ func (h *invitesHandler) Handler() http.Handler {
 			h.forwardInvite(w, r)
 		case "accept":
 			h.acceptInvite(w, r)
+		case "list":
+			h.listInviteTokens(w, r)
+		case "revoke":
+			h.revokeInviteToken(w, r)
 		default:
 			w.WriteHeader(http.StatusNotFound)
 		}
@@ -80,11 +86,33 @@ func (h *invitesHandler) generateInviteToken(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	token, err := gatewayClient.GenerateInviteToken(ctx, &invitepb.GenerateInviteTokenRequest{})
+	tokenReq := &invite.TokenRequest{
+		Action:     invite.ActionGenerate,
+		Expiration: r.FormValue("expiration"),
+	}
+	if v := r.FormValue("one_time"); v != "" {
+		oneTime, err := strconv.ParseBool(v)
+		if err != nil {
+			WriteError(w, r, APIErrorInvalidParameter, "one_time must be a boolean", err)
+			return
+		}
+		tokenReq.OneTime = &oneTime
+	}
+	opaque, err := invite.EncodeTokenRequest(tokenReq)
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error encoding token request", err)
+		return
+	}
+
+	token, err := gatewayClient.GenerateInviteToken(ctx, &invitepb.GenerateInviteTokenRequest{Opaque: opaque})
 	if err != nil {
 		WriteError(w, r, APIErrorServerError, "error generating token", err)
 		return
 	}
+	if token.Status.Code != rpc.Code_CODE_OK {
+		writeTokenStatusError(w, r, "grpc generate invite token request failed", token.Status)
+		return
+	}
 
 	if r.FormValue("recipient") != "" && h.smtpCredentials != nil {
 
@@ -125,6 +153,96 @@ func (h *invitesHandler) generateInviteToken(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusOK)
 }
 
+func (h *invitesHandler) listInviteTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	res, ok := h.manageTokens(w, r, &invite.TokenRequest{
+		Action: invite.ActionList,
+		All:    r.FormValue("all") == "true",
+	})
+	if !ok {
+		return
+	}
+
+	tokens, err := invite.DecodeTokens(res.Opaque)
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error decoding tokens", err)
+		return
+	}
+	jsonResponse, err := json.Marshal(tokens)
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error marshalling token data", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jsonResponse)
+}
+
+func (h *invitesHandler) revokeInviteToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.FormValue("token") == "" {
+		WriteError(w, r, APIErrorInvalidParameter, "token must not be null", nil)
+		return
+	}
+
+	if _, ok := h.manageTokens(w, r, &invite.TokenRequest{
+		Action: invite.ActionRevoke,
+		Token:  r.FormValue("token"),
+		All:    r.FormValue("all") == "true",
+	}); !ok {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// manageTokens sends the token request to the invite manager and writes the
+// error to the response if it fails.
+func (h *invitesHandler) manageTokens(w http.ResponseWriter, r *http.Request, tokenReq *invite.TokenRequest) (*invitepb.GenerateInviteTokenResponse, bool) {
+	gatewayClient, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error getting gateway grpc client", err)
+		return nil, false
+	}
+
+	opaque, err := invite.EncodeTokenRequest(tokenReq)
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error encoding token request", err)
+		return nil, false
+	}
+	res, err := gatewayClient.GenerateInviteToken(r.Context(), &invitepb.GenerateInviteTokenRequest{Opaque: opaque})
+	if err != nil {
+		WriteError(w, r, APIErrorServerError, "error sending a grpc "+tokenReq.Action+" invite tokens request", err)
+		return nil, false
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		writeTokenStatusError(w, r, "grpc "+tokenReq.Action+" invite tokens request failed", res.Status)
+		return nil, false
+	}
+	return res, true
+}
+
+func writeTokenStatusError(w http.ResponseWriter, r *http.Request, msg string, s *rpc.Status) {
+	switch s.Code {
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		WriteError(w, r, APIErrorInvalidParameter, s.Message, nil)
+	case rpc.Code_CODE_NOT_FOUND:
+		WriteError(w, r, APIErrorNotFound, s.Message, nil)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		WriteError(w, r, APIErrorPermissionDenied, s.Message, nil)
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		WriteError(w, r, APIErrorUnimplemented, s.Message, nil)
+	default:
+		WriteError(w, r, APIErrorServerError, msg, errors.New(s.Message))
+	}
+}
+
 func (h *invitesHandler) forwardInvite(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
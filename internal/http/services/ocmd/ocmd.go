@@ -22,6 +22,7 @@ import (
 	"net/http"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ocm/discovery"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/sharedconf"
@@ -37,7 +38,7 @@ type Config struct {
 	Host             string                      `mapstructure:"host"`
 	GatewaySvc       string                      `mapstructure:"gatewaysvc"`
 	MeshDirectoryURL string                      `mapstructure:"mesh_directory_url"`
-	Config           configData                  `mapstructure:"config"`
+	Config           discovery.Document          `mapstructure:"config"`
 }
 
 func (c *Config) init() {
@@ -94,7 +95,7 @@ func (s *svc) Prefix() string {
 }
 
 func (s *svc) Unprotected() []string {
-	return []string{"/invites/accept", "shares"}
+	return []string{"/invites/accept", "shares", "/" + discovery.Endpoint}
 }
 
 func (s *svc) Handler() http.Handler {
@@ -108,7 +109,7 @@ func (s *svc) Handler() http.Handler {
 		log.Debug().Str("head", head).Str("tail", r.URL.Path).Msg("http routing")
 
 		switch head {
-		case "ocm-provider":
+		case discovery.Endpoint:
 			s.ConfigHandler.Handler().ServeHTTP(w, r)
 			return
 		case "shares":
@@ -33,6 +33,7 @@ const (
 	APIErrorNotFound         APIErrorCode = "RESOURCE_NOT_FOUND"
 	APIErrorUnauthenticated  APIErrorCode = "UNAUTHENTICATED"
 	APIErrorUntrustedService APIErrorCode = "UNTRUSTED_SERVICE"
+	APIErrorPermissionDenied APIErrorCode = "PERMISSION_DENIED"
 	APIErrorUnimplemented    APIErrorCode = "FUNCTION_NOT_IMPLEMENTED"
 	APIErrorInvalidParameter APIErrorCode = "INVALID_PARAMETER"
 	APIErrorProviderError    APIErrorCode = "PROVIDER_ERROR"
@@ -44,6 +45,7 @@ var APIErrorCodeMapping = map[APIErrorCode]int{
 	APIErrorNotFound:         http.StatusNotFound,
 	APIErrorUnauthenticated:  http.StatusUnauthorized,
 	APIErrorUntrustedService: http.StatusForbidden,
+	APIErrorPermissionDenied: http.StatusForbidden,
 	APIErrorUnimplemented:    http.StatusNotImplemented,
 	APIErrorInvalidParameter: http.StatusBadRequest,
 	APIErrorProviderError:    http.StatusBadGateway,
@@ -0,0 +1,164 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ops
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/reload"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+func init() {
+	global.Register(serviceName, New)
+}
+
+type config struct {
+	Prefix string `mapstructure:"prefix"`
+	// Admins are the usernames of the users allowed to query the snapshot,
+	// any authenticated user is if empty.
+	Admins []string `mapstructure:"admins"`
+}
+
+type svc struct {
+	conf   *config
+	admins map[string]bool
+}
+
+const (
+	serviceName = "ops"
+)
+
+// Close is called when this service is being stopped.
+func (s *svc) Close() error {
+	return nil
+}
+
+// Prefix returns the main endpoint of this service.
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all endpoints that can be queried without prior authorization.
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+// Handler serves the snapshot of the deployment state as JSON. The sections
+// can be restricted with the section query parameter, e.g. ?section=queues.
+// The outcome of the last configuration reload is served at /reload.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		u, ok := user.ContextGetUser(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if len(s.admins) > 0 && !s.admins[u.Username] {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if head, _ := router.ShiftPath(r.URL.Path); head == "reload" {
+			st := reload.Last()
+			if st == nil {
+				// no reload since the process started
+				st = &reload.Status{}
+			}
+			s.serveJSON(w, r, st)
+			return
+		}
+
+		snapshot := ops.Collect()
+		if sections := r.URL.Query()["section"]; len(sections) > 0 {
+			filtered := map[string]map[string]ops.State{}
+			for _, section := range sections {
+				if states, ok := snapshot.Sections[section]; ok {
+					filtered[section] = states
+				}
+			}
+			snapshot.Sections = filtered
+		}
+
+		s.serveJSON(w, r, snapshot)
+	})
+}
+
+func (s *svc) serveJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	log := appctx.GetLogger(r.Context())
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		log.Err(err).Msg("error encoding ops response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		log.Err(err).Msg("error writing ops response")
+	}
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	cfg := &config{}
+	if err := mapstructure.Decode(m, &cfg); err != nil {
+		return nil, errors.Wrap(err, "ops: error decoding configuration")
+	}
+	applyDefaultConfig(cfg)
+	return cfg, nil
+}
+
+func applyDefaultConfig(conf *config) {
+	if conf.Prefix == "" {
+		conf.Prefix = serviceName
+	}
+}
+
+// New returns a new ops service serving the state of the subsystems of this
+// process to the operations dashboard.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+
+	admins := map[string]bool{}
+	for _, a := range conf.Admins {
+		admins[a] = true
+	}
+
+	s := &svc{
+		conf:   conf,
+		admins: admins,
+	}
+	return s, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// preconditionsMet evaluates the conditional headers of a request modifying
+// a resource against its current etag and mtime, in the order of
+// https://tools.ietf.org/html/rfc7232#section-6. info is nil when the
+// resource does not exist. Requests failing it are answered with a 412.
+func preconditionsMet(r *http.Request, info *provider.ResourceInfo) bool {
+	if im := r.Header.Get("If-Match"); im != "" {
+		if info == nil || !matchesETag(im, info.Etag, false) {
+			return false
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && info != nil && info.Mtime != nil {
+		// invalid dates are ignored, see https://tools.ietf.org/html/rfc7232#section-3.4
+		if t, err := http.ParseTime(ius); err == nil && time.Unix(int64(info.Mtime.Seconds), 0).After(t) {
+			return false
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && info != nil {
+		if matchesETag(inm, info.Etag, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesETag tells whether the etag is one of the entity tags of an
+// If-Match or If-None-Match header. The weak comparison is used for
+// If-None-Match, see https://tools.ietf.org/html/rfc7232#section-2.3.2.
+func matchesETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	// the storages don't agree on quoting the etags
+	etag = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if strings.HasPrefix(t, "W/") {
+			if !weak {
+				continue
+			}
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if strings.Trim(t, `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
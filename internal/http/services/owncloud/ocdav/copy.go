@@ -32,6 +32,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/datagateway"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/storage"
 	"go.opencensus.io/trace"
 )
 
@@ -101,7 +102,7 @@ func (s *svc) handleCopy(w http.ResponseWriter, r *http.Request, ns string) {
 	ref = &provider.Reference{
 		Spec: &provider.Reference_Path{Path: dst},
 	}
-	dstStatReq := &provider.StatRequest{Ref: ref}
+	dstStatReq := &provider.StatRequest{Ref: ref, ArbitraryMetadataKeys: []string{storage.LockKey}}
 	dstStatRes, err := client.Stat(ctx, dstStatReq)
 	if err != nil {
 		sublog.Error().Err(err).Msg("error sending grpc stat request")
@@ -123,6 +124,11 @@ func (s *svc) handleCopy(w http.ResponseWriter, r *http.Request, ns string) {
 			return
 		}
 
+		if !lockSatisfied(ctx, r, dstStatRes.Info) {
+			writeLocked(ctx, w, dst)
+			return
+		}
+
 	} else {
 		// check if an intermediate path / the parent exists
 		intermediateDir := path.Dir(dst)
@@ -149,12 +155,51 @@ func (s *svc) handleCopy(w http.ResponseWriter, r *http.Request, ns string) {
 		// TODO what if intermediate is a file?
 	}
 
+	// let the gateway copy the data server side, it returns unimplemented
+	// when it can't, in which case we stream the data ourselves.
+	if depth == "infinity" || srcStatRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		copyReq := &provider.MoveRequest{
+			Source: &provider.Reference{
+				Spec: &provider.Reference_Path{Path: src},
+			},
+			Destination: &provider.Reference{
+				Spec: &provider.Reference_Path{Path: dst},
+			},
+			Opaque: &typespb.Opaque{
+				Map: map[string]*typespb.OpaqueEntry{
+					storage.CopyOpaqueKey: {
+						Decoder: "plain",
+						Value:   []byte("true"),
+					},
+				},
+			},
+		}
+		copyRes, err := client.Move(ctx, copyReq)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error sending grpc copy request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		switch copyRes.Status.Code {
+		case rpc.Code_CODE_OK:
+			s.recordChange(ctx, false, dst)
+			w.WriteHeader(successCode)
+			return
+		case rpc.Code_CODE_UNIMPLEMENTED:
+			sublog.Debug().Msg("server side copy not available, streaming")
+		default:
+			HandleErrorStatus(&sublog, w, copyRes.Status)
+			return
+		}
+	}
+
 	err = s.descend(ctx, client, srcStatRes.Info, dst, depth == "infinity")
 	if err != nil {
 		sublog.Error().Err(err).Str("depth", depth).Msg("error descending directory")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	s.recordChange(ctx, false, dst)
 	w.WriteHeader(successCode)
 }
 
@@ -30,6 +30,7 @@ import (
 	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	tokenpkg "github.com/cs3org/reva/pkg/token"
@@ -42,13 +43,16 @@ type tokenStatInfoKey struct{}
 
 // DavHandler routes to the different sub handlers
 type DavHandler struct {
-	AvatarsHandler      *AvatarsHandler
-	FilesHandler        *WebDavHandler
-	FilesHomeHandler    *WebDavHandler
-	MetaHandler         *MetaHandler
-	TrashbinHandler     *TrashbinHandler
-	PublicFolderHandler *WebDavHandler
-	PublicFileHandler   *PublicFileHandler
+	AvatarsHandler        *AvatarsHandler
+	FilesHandler          *WebDavHandler
+	FilesHomeHandler      *WebDavHandler
+	MetaHandler           *MetaHandler
+	TrashbinHandler       *TrashbinHandler
+	PublicFolderHandler   *WebDavHandler
+	PublicFileHandler     *PublicFileHandler
+	PublicFileDropHandler *PublicFileDropHandler
+	SpacesHandler         *SpacesHandler
+	UploadsHandler        *UploadsHandler
 }
 
 func (h *DavHandler) init(c *Config) error {
@@ -80,6 +84,21 @@ func (h *DavHandler) init(c *Config) error {
 		return err
 	}
 
+	h.PublicFileDropHandler = new(PublicFileDropHandler)
+	if err := h.PublicFileDropHandler.init("public"); err != nil { // jail public file requests to /public/ prefix
+		return err
+	}
+
+	h.SpacesHandler = new(SpacesHandler)
+	if err := h.SpacesHandler.init(c); err != nil {
+		return err
+	}
+
+	h.UploadsHandler = new(UploadsHandler)
+	if err := h.UploadsHandler.init(c); err != nil {
+		return err
+	}
+
 	return h.TrashbinHandler.init(c)
 }
 
@@ -163,6 +182,16 @@ func (h *DavHandler) Handler(s *svc) http.Handler {
 			ctx := context.WithValue(ctx, ctxKeyBaseURI, base)
 			r = r.WithContext(ctx)
 			h.TrashbinHandler.Handler(s).ServeHTTP(w, r)
+		case "spaces":
+			base := path.Join(ctx.Value(ctxKeyBaseURI).(string), "spaces")
+			ctx := context.WithValue(ctx, ctxKeyBaseURI, base)
+			r = r.WithContext(ctx)
+			h.SpacesHandler.Handler(s).ServeHTTP(w, r)
+		case "uploads":
+			base := path.Join(ctx.Value(ctxKeyBaseURI).(string), "uploads")
+			ctx := context.WithValue(ctx, ctxKeyBaseURI, base)
+			r = r.WithContext(ctx)
+			h.UploadsHandler.Handler(s).ServeHTTP(w, r)
 		case "public-files":
 			base := path.Join(ctx.Value(ctxKeyBaseURI).(string), "public-files")
 			ctx = context.WithValue(ctx, ctxKeyBaseURI, base)
@@ -234,11 +263,14 @@ func (h *DavHandler) Handler(s *svc) http.Handler {
 			}
 			log.Debug().Interface("statInfo", sRes.Info).Msg("Stat info from public link token path")
 
-			if sRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			switch {
+			case sRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER:
 				ctx := context.WithValue(ctx, tokenStatInfoKey{}, sRes.Info)
 				r = r.WithContext(ctx)
 				h.PublicFileHandler.Handler(s).ServeHTTP(w, r)
-			} else {
+			case publicshare.IsUploadOnly(sRes.Info.PermissionSet):
+				h.PublicFileDropHandler.Handler(s).ServeHTTP(w, r)
+			default:
 				h.PublicFolderHandler.Handler(s).ServeHTTP(w, r)
 			}
 
@@ -44,6 +44,10 @@ func (s *svc) handleDelete(w http.ResponseWriter, r *http.Request, ns string) {
 		return
 	}
 
+	if !s.checkLocks(ctx, w, r, client, fn) {
+		return
+	}
+
 	ref := &provider.Reference{
 		Spec: &provider.Reference_Path{Path: fn},
 	}
@@ -59,5 +63,6 @@ func (s *svc) handleDelete(w http.ResponseWriter, r *http.Request, ns string) {
 		HandleErrorStatus(&sublog, w, res.Status)
 		return
 	}
+	s.recordChange(ctx, true, fn)
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -19,10 +19,15 @@
 package ocdav
 
 import (
+	"context"
 	"encoding/xml"
+	"math"
 	"net/http"
+	"strconv"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -74,6 +79,8 @@ type errorXML struct {
 
 var errInvalidPropfind = errors.New("webdav: invalid propfind")
 
+var errUnsupportedLock = errors.New("webdav: unsupported lock")
+
 // HandleErrorStatus checks the status code, logs a Debug or Error level message
 // and writes an appropriate http status
 func HandleErrorStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status) {
@@ -96,8 +103,28 @@ func HandleErrorStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status
 	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
 		log.Debug().Interface("status", s).Msg("insufficient storage")
 		w.WriteHeader(http.StatusInsufficientStorage)
+	case rpc.Code_CODE_RESOURCE_EXHAUSTED:
+		log.Debug().Interface("status", s).Msg("too many requests")
+		w.WriteHeader(http.StatusTooManyRequests)
+	case rpc.Code_CODE_UNAVAILABLE:
+		log.Debug().Interface("status", s).Msg("service unavailable")
+		if d, ok := status.RetryAfter(s); ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
 	default:
 		log.Error().Interface("status", s).Msg("grpc request failed")
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+// writeDAVError writes a DAV:error with the precondition that failed, see
+// http://www.webdav.org/specs/rfc4918.html#ELEMENT_error
+func writeDAVError(ctx context.Context, w http.ResponseWriter, code int, precondition string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+	b := `<?xml version="1.0" encoding="utf-8"?><d:error xmlns:d="DAV:"><d:` + precondition + `/></d:error>`
+	if _, err := w.Write([]byte(b)); err != nil {
+		appctx.GetLogger(ctx).Err(err).Msg("error writing response")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"sync"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/favorite"
+	favoriteregistry "github.com/cs3org/reva/pkg/favorite/manager/registry"
+	"github.com/cs3org/reva/pkg/storage/changes"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+// reportFilterFiles is the report the ownCloud clients list the favorites with.
+type reportFilterFiles struct {
+	XMLName xml.Name      `xml:"filter-files"`
+	Prop    propfindProps `xml:"DAV: prop"`
+	Rules   struct {
+		Favorite int `xml:"favorite"`
+	} `xml:"filter-rules"`
+}
+
+// favoritesCache holds the favorites of the user of a request, looked up the
+// first time a resource is formatted.
+type favoritesCache struct {
+	once sync.Once
+	keys map[string]bool
+}
+
+func (s *svc) initFavorites() error {
+	f, ok := favoriteregistry.NewFuncs[s.c.FavoriteStorageDriver]
+	if !ok {
+		return errtypes.NotFound("ocdav: favorite manager not found: " + s.c.FavoriteStorageDriver)
+	}
+	m, err := f(s.c.FavoriteStorageDrivers[s.c.FavoriteStorageDriver])
+	if err != nil {
+		return err
+	}
+	s.favorites = m
+	return nil
+}
+
+// withFavorites prepares the context of a request for the favorites of its
+// user to be looked up at most once.
+func (s *svc) withFavorites(ctx context.Context) context.Context {
+	if s.favorites == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyFavorites, &favoritesCache{})
+}
+
+// applyFavorite sets the favorite flag of the resource from the favorite
+// manager. The flag stored by the storage is kept when there is no favorite
+// manager or the favorites can't be looked up.
+func (s *svc) applyFavorite(ctx context.Context, md *provider.ResourceInfo) {
+	fc, ok := ctx.Value(ctxKeyFavorites).(*favoritesCache)
+	if !ok || md.Id == nil {
+		return
+	}
+	fc.once.Do(func() {
+		u, ok := ctxuser.ContextGetUser(ctx)
+		if !ok {
+			return
+		}
+		ids, err := s.favorites.ListFavorites(ctx, u.Id)
+		if err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Msg("error listing the favorites")
+			return
+		}
+		fc.keys = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			fc.keys[favorite.Key(id)] = true
+		}
+	})
+	if fc.keys == nil {
+		return
+	}
+
+	if md.ArbitraryMetadata == nil {
+		md.ArbitraryMetadata = &provider.ArbitraryMetadata{}
+	}
+	if md.ArbitraryMetadata.Metadata == nil {
+		md.ArbitraryMetadata.Metadata = map[string]string{}
+	}
+	if fc.keys[favorite.Key(md.Id)] {
+		md.ArbitraryMetadata.Metadata[_propOcFavorite] = "1"
+	} else {
+		md.ArbitraryMetadata.Metadata[_propOcFavorite] = "0"
+	}
+}
+
+// setFavorite marks the resource as a favorite of the user of the request, or
+// removes the mark, in the favorite manager.
+func (s *svc) setFavorite(ctx context.Context, md *provider.ResourceInfo, on bool) error {
+	u, ok := ctxuser.ContextGetUser(ctx)
+	if !ok {
+		return errtypes.UserRequired("ocdav: the favorites need a user")
+	}
+	if on {
+		return s.favorites.SetFavorite(ctx, u.Id, md.Id)
+	}
+	return s.favorites.UnsetFavorite(ctx, u.Id, md.Id)
+}
+
+// doFilterFiles answers the filter-files reports listing the favorites of the
+// user below the requested collection.
+func (s *svc) doFilterFiles(w http.ResponseWriter, r *http.Request, ff *reportFilterFiles, ns, fn string) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	if s.favorites == nil || ff.Rules.Favorite != 1 {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	u, ok := ctxuser.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ids, err := s.favorites.ListFavorites(ctx, u.Id)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error listing the favorites")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	pf := &propfindXML{Prop: ff.Prop}
+	metadataKeys := metadataKeysOf(pf)
+	infos := make([]*provider.ResourceInfo, 0, len(ids))
+	for _, id := range ids {
+		res, err := client.Stat(ctx, &provider.StatRequest{
+			Ref:                   &provider.Reference{Spec: &provider.Reference_Id{Id: id}},
+			ArbitraryMetadataKeys: metadataKeys,
+		})
+		if err != nil {
+			sublog.Error().Err(err).Interface("id", id).Msg("error sending a grpc stat request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			// the favorite was deleted or is no longer accessible
+			sublog.Debug().Interface("id", id).Interface("status", res.Status).Msg("skipping favorite")
+			continue
+		}
+		if changes.Below(res.Info.Path, fn) {
+			infos = append(infos, res.Info)
+		}
+	}
+
+	propRes, err := s.formatPropfind(ctx, pf, infos, ns)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error formatting the favorites")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("DAV", "1, 3, extended-mkcol")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(propRes)); err != nil {
+		sublog.Err(err).Msg("error writing response")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"go.opencensus.io/trace"
+)
+
+// PublicFileDropHandler handles requests on upload only public links: files can
+// be uploaded to them, but their contents are never revealed.
+type PublicFileDropHandler struct {
+	namespace string
+}
+
+func (h *PublicFileDropHandler) init(ns string) error {
+	h.namespace = path.Join("/", ns)
+	return nil
+}
+
+// Handler handles requests
+func (h *PublicFileDropHandler) Handler(s *svc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PROPFIND":
+			// only the file drop itself can be found
+			r.Header.Set("Depth", "0")
+			s.handlePropfind(w, r, h.namespace)
+		case http.MethodOptions:
+			s.handleOptions(w, r, h.namespace)
+		case http.MethodPut:
+			s.handleFileDropPut(w, r, h.namespace)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// handleFileDropPut uploads a file to a file drop. Unlike handlePut it does not
+// stat the file, the public storage provider picks a new name for the upload
+// when the file already exists.
+func (s *svc) handleFileDropPut(w http.ResponseWriter, r *http.Request, ns string) {
+	ctx := r.Context()
+	ctx, span := trace.StartSpan(ctx, "filedrop_put")
+	defer span.End()
+
+	fn := path.Join(ns, r.URL.Path)
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+
+	if r.Body == nil {
+		sublog.Debug().Msg("body is nil")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if isContentRange(r) {
+		sublog.Debug().Msg("Content-Range not supported for PUT")
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		sublog.Debug().Msg("the Content-Length is required by a file drop")
+		w.WriteHeader(http.StatusLengthRequired)
+		return
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	uRes, err := client.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: fn},
+		},
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatInt(length, 10)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error initiating file upload")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if uRes.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, uRes.Status)
+		return
+	}
+
+	var ep, token string
+	for _, p := range uRes.Protocols {
+		if p.Protocol == "simple" {
+			ep, token = p.UploadEndpoint, p.Token
+		}
+	}
+
+	if length > 0 {
+		httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, ep, r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+
+		httpRes, err := s.client.Do(httpReq)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error doing PUT request to data service")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer httpRes.Body.Close()
+		if httpRes.StatusCode != http.StatusOK {
+			sublog.Error().Int("status", httpRes.StatusCode).Msg("PUT request to data server failed")
+			w.WriteHeader(httpRes.StatusCode)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
@@ -113,6 +113,10 @@ func (s *svc) handleGet(w http.ResponseWriter, r *http.Request, ns string) {
 
 	if r.Header.Get("Range") != "" {
 		httpReq.Header.Set("Range", r.Header.Get("Range"))
+		// let the data provider decide whether the range still applies to the current content
+		if r.Header.Get("If-Range") != "" {
+			httpReq.Header.Set("If-Range", r.Header.Get("If-Range"))
+		}
 	}
 
 	httpClient := s.client
@@ -140,8 +144,17 @@ func (s *svc) handleGet(w http.ResponseWriter, r *http.Request, ns string) {
 	lastModifiedString := t.Format(time.RFC1123Z)
 	w.Header().Set("Last-Modified", lastModifiedString)
 
+	if httpRes.Header.Get("Accept-Ranges") != "" {
+		w.Header().Set("Accept-Ranges", httpRes.Header.Get("Accept-Ranges"))
+	}
+
 	if httpRes.StatusCode == http.StatusPartialContent {
-		w.Header().Set("Content-Range", httpRes.Header.Get("Content-Range"))
+		if ct := httpRes.Header.Get("Content-Type"); strings.HasPrefix(ct, "multipart/byteranges") {
+			w.Header().Set("Content-Type", ct)
+		}
+		if cr := httpRes.Header.Get("Content-Range"); cr != "" {
+			w.Header().Set("Content-Range", cr)
+		}
 		w.Header().Set("Content-Length", httpRes.Header.Get("Content-Length"))
 		w.WriteHeader(http.StatusPartialContent)
 	} else {
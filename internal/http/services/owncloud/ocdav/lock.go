@@ -19,33 +19,374 @@
 package ocdav
 
 import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/google/uuid"
+	"go.opencensus.io/trace"
 )
 
-// TODO(jfd) implement lock
+const (
+	defaultLockTimeout = 30 * time.Minute
+	maxLockTimeout     = 24 * time.Hour
+)
+
+// http://www.webdav.org/specs/rfc4918.html#ELEMENT_lockinfo
+type lockInfoXML struct {
+	XMLName   xml.Name  `xml:"DAV: lockinfo"`
+	Exclusive *struct{} `xml:"DAV: lockscope>exclusive"`
+	Shared    *struct{} `xml:"DAV: lockscope>shared"`
+	Write     *struct{} `xml:"DAV: locktype>write"`
+	Owner     *struct {
+		Href string `xml:"DAV: href"`
+		Text string `xml:",chardata"`
+	} `xml:"DAV: owner"`
+}
+
+// readLockInfo returns the owner of the lock requested in the body. It returns
+// refresh when the body is empty, which asks to refresh an existing lock.
+func (s *svc) readLockInfo(r io.Reader) (owner string, refresh bool, status int, err error) {
+	c := countingReader{r: r}
+	li := lockInfoXML{}
+	if err = xml.NewDecoder(&c).Decode(&li); err != nil {
+		if err == io.EOF && c.n == 0 {
+			return "", true, 0, nil
+		}
+		return "", false, http.StatusBadRequest, err
+	}
+	// only exclusive write locks are supported
+	if li.Shared != nil || li.Exclusive == nil || li.Write == nil {
+		return "", false, http.StatusNotImplemented, errUnsupportedLock
+	}
+	if li.Owner != nil {
+		if href := strings.TrimSpace(li.Owner.Href); href != "" {
+			owner = "<d:href>" + string(s.xmlEscaped(href)) + "</d:href>"
+		} else {
+			owner = string(s.xmlEscaped(strings.TrimSpace(li.Owner.Text)))
+		}
+	}
+	return owner, false, 0, nil
+}
+
+// lockTimeout returns the timeout requested in the Timeout header, see
+// https://tools.ietf.org/html/rfc4918#section-10.7. The first supported value
+// wins, the timeouts are capped at maxLockTimeout.
+func lockTimeout(h string) time.Duration {
+	for _, t := range strings.Split(h, ",") {
+		t = strings.TrimSpace(t)
+		if strings.EqualFold(t, "Infinite") {
+			return maxLockTimeout
+		}
+		if !strings.HasPrefix(t, "Second-") {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimPrefix(t, "Second-"), 10, 64)
+		if err != nil || n <= 0 {
+			continue
+		}
+		if n > int64(maxLockTimeout/time.Second) {
+			return maxLockTimeout
+		}
+		return time.Duration(n) * time.Second
+	}
+	return defaultLockTimeout
+}
+
+// lockTokens returns the lock tokens submitted in the If header of the request,
+// see https://tools.ietf.org/html/rfc4918#section-10.4. The resource tags, the
+// entity tags and the negated tokens are ignored.
+func lockTokens(r *http.Request) []string {
+	var tokens []string
+	for _, h := range r.Header.Values("If") {
+		list, not := false, false
+		for i := 0; i < len(h); i++ {
+			switch {
+			case h[i] == '(':
+				list, not = true, false
+			case h[i] == ')':
+				list = false
+			case h[i] == '[':
+				j := strings.IndexByte(h[i:], ']')
+				if j < 0 {
+					return tokens
+				}
+				i += j
+				not = false
+			case h[i] == '<':
+				j := strings.IndexByte(h[i:], '>')
+				if j < 0 {
+					return tokens
+				}
+				if list && !not {
+					tokens = append(tokens, h[i+1:i+j])
+				}
+				i += j
+				not = false
+			case list && strings.HasPrefix(h[i:], "Not"):
+				not = true
+				i += len("Not") - 1
+			}
+		}
+	}
+	return tokens
+}
+
+// holdsLock tells if the request comes from the user holding the lock and
+// submits its token.
+func holdsLock(ctx context.Context, r *http.Request, l *storage.Lock) bool {
+	u, ok := ctxuser.ContextGetUser(ctx)
+	if !ok || !utils.UserEqual(u.Id, l.User) {
+		return false
+	}
+	for _, t := range lockTokens(r) {
+		if t == l.Token {
+			return true
+		}
+	}
+	return false
+}
+
+// lockSatisfied tells if the request may modify the resource, which it may if
+// the resource is not locked or if the request holds the lock.
+func lockSatisfied(ctx context.Context, r *http.Request, info *provider.ResourceInfo) bool {
+	if l := storage.ActiveLock(info); l != nil {
+		return holdsLock(ctx, r, l)
+	}
+	return true
+}
+
+// checkLocks stats the resources and writes a 423 when one of them is locked
+// and the request does not hold the lock. It returns false when the request
+// must not go on.
+func (s *svc) checkLocks(ctx context.Context, w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, fns ...string) bool {
+	sublog := appctx.GetLogger(ctx)
+	for _, fn := range fns {
+		res, err := client.Stat(ctx, &provider.StatRequest{
+			Ref:                   &provider.Reference{Spec: &provider.Reference_Path{Path: fn}},
+			ArbitraryMetadataKeys: []string{storage.LockKey},
+		})
+		if err != nil {
+			sublog.Error().Err(err).Str("path", fn).Msg("error sending grpc stat request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return false
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			// missing resources are not locked, other errors are left to the request
+			continue
+		}
+		if !lockSatisfied(ctx, r, res.Info) {
+			writeLocked(ctx, w, fn)
+			return false
+		}
+	}
+	return true
+}
+
+// writeLocked writes the 423 answering requests to modify a locked resource.
+func writeLocked(ctx context.Context, w http.ResponseWriter, fn string) {
+	appctx.GetLogger(ctx).Debug().Str("path", fn).Msg("resource is locked")
+	writeDAVError(ctx, w, http.StatusLocked, "lock-token-submitted")
+}
+
+// activeLockXML returns the DAV:activelock describing the lock, the root of the
+// lock being the encoded href of the locked resource.
+func (s *svc) activeLockXML(l *storage.Lock, root string) string {
+	timeout := int64(time.Until(l.Expires).Round(time.Second) / time.Second)
+	if timeout < 0 {
+		timeout = 0
+	}
+	var b strings.Builder
+	b.WriteString("<d:activelock>")
+	b.WriteString("<d:locktype><d:write/></d:locktype>")
+	b.WriteString("<d:lockscope><d:exclusive/></d:lockscope>")
+	b.WriteString("<d:depth>0</d:depth>")
+	if l.Owner != "" {
+		b.WriteString("<d:owner>" + l.Owner + "</d:owner>")
+	}
+	b.WriteString(fmt.Sprintf("<d:timeout>Second-%d</d:timeout>", timeout))
+	b.WriteString("<d:locktoken><d:href>" + string(s.xmlEscaped(l.Token)) + "</d:href></d:locktoken>")
+	b.WriteString("<d:lockroot><d:href>" + string(s.xmlEscaped(root)) + "</d:href></d:lockroot>")
+	b.WriteString("</d:activelock>")
+	return b.String()
+}
+
+// lockDiscovery returns the DAV:lockdiscovery of the resource with the href.
+func (s *svc) lockDiscovery(md *provider.ResourceInfo, href string) string {
+	if l := storage.ActiveLock(md); l != nil {
+		return s.activeLockXML(l, href)
+	}
+	return ""
+}
+
+// supportedLockXML is the DAV:supportedlock of the files, only exclusive write
+// locks are supported.
+const supportedLockXML = "<d:lockentry><d:lockscope><d:exclusive/></d:lockscope><d:locktype><d:write/></d:locktype></d:lockentry>"
+
 func (s *svc) handleLock(w http.ResponseWriter, r *http.Request, ns string) {
-	log := appctx.GetLogger(r.Context())
-	xml := `<?xml version="1.0" encoding="utf-8"?>
-	<prop xmlns="DAV:">
-		<lockdiscovery>
-			<activelock>
-				<allprop/>
-				<timeout>Second-604800</timeout>
-				<depth>Infinity</depth>
-				<locktoken>
-				<href>opaquelocktoken:00000000-0000-0000-0000-000000000000</href>
-				</locktoken>
-			</activelock>
-		</lockdiscovery>
-	</prop>`
-
-	w.Header().Set("Content-Type", "text/xml; charset=\"utf-8\"")
-	w.Header().Set("Lock-Token",
-		"opaquelocktoken:00000000-0000-0000-0000-000000000000")
-	_, err := w.Write([]byte(xml))
+	ctx := r.Context()
+	ctx, span := trace.StartSpan(ctx, "lock")
+	defer span.End()
+
+	fn := path.Join(ns, r.URL.Path)
+
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+
+	owner, refresh, status, err := s.readLockInfo(r.Body)
+	if err != nil {
+		sublog.Debug().Err(err).Msg("error reading lockinfo")
+		w.WriteHeader(status)
+		return
+	}
+
+	// locks cover a single file
+	if depth := r.Header.Get("Depth"); depth != "" && depth != "0" && !strings.EqualFold(depth, "infinity") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	u, ok := ctxuser.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ref := &provider.Reference{
+		Spec: &provider.Reference_Path{Path: fn},
+	}
+	sReq := &provider.StatRequest{Ref: ref, ArbitraryMetadataKeys: []string{storage.LockKey}}
+	sRes, err := client.Stat(ctx, sReq)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	successCode := http.StatusOK
+	active := (*storage.Lock)(nil)
+	switch {
+	case sRes.Status.Code == rpc.Code_CODE_NOT_FOUND && !refresh:
+		// locking an unmapped url creates an empty file, see https://tools.ietf.org/html/rfc4918#section-7.3
+		if !s.createEmptyFile(ctx, w, client, ref) {
+			return
+		}
+		s.recordChange(ctx, false, fn)
+		successCode = http.StatusCreated
+	case sRes.Status.Code != rpc.Code_CODE_OK:
+		HandleErrorStatus(&sublog, w, sRes.Status)
+		return
+	case sRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_FILE:
+		sublog.Debug().Msg("only files can be locked")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	default:
+		active = storage.ActiveLock(sRes.Info)
+	}
+
+	var l *storage.Lock
+	if refresh {
+		if active == nil || !holdsLock(ctx, r, active) {
+			// https://tools.ietf.org/html/rfc4918#section-9.10.2
+			sublog.Debug().Msg("no lock to refresh")
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		l = active
+	} else {
+		if active != nil {
+			writeLocked(ctx, w, fn)
+			return
+		}
+		l = &storage.Lock{
+			Token: "opaquelocktoken:" + uuid.New().String(),
+			User:  u.Id,
+			Owner: owner,
+		}
+	}
+	l.Expires = time.Now().Add(lockTimeout(r.Header.Get("Timeout")))
+
+	v, err := l.Encode()
 	if err != nil {
-		log.Err(err).Msg("error writing response")
+		sublog.Error().Err(err).Msg("error encoding lock")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref: ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{storage.LockKey: v},
+		},
+	})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc SetArbitraryMetadata request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, res.Status)
+		return
+	}
+
+	root := encodePath(path.Join(ctx.Value(ctxKeyBaseURI).(string), r.URL.Path))
+	msg := `<?xml version="1.0" encoding="utf-8"?><d:prop xmlns:d="DAV:"><d:lockdiscovery>`
+	msg += s.activeLockXML(l, root) + `</d:lockdiscovery></d:prop>`
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if !refresh {
+		w.Header().Set("Lock-Token", "<"+l.Token+">")
+	}
+	w.WriteHeader(successCode)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		sublog.Err(err).Msg("error writing response")
+	}
+}
+
+// createEmptyFile creates the empty file a lock is taken on. Empty uploads are
+// finished when they are initiated.
+func (s *svc) createEmptyFile(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient, ref *provider.Reference) bool {
+	sublog := appctx.GetLogger(ctx).With().Str("path", ref.GetPath()).Logger()
+	uRes, err := client.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: ref,
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"Upload-Length": {Decoder: "plain", Value: []byte("0")},
+			},
+		},
+	})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error initiating file upload")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if uRes.Status.Code != rpc.Code_CODE_OK {
+		if uRes.Status.Code == rpc.Code_CODE_NOT_FOUND {
+			// 409 if the parent is missing, like for PUT
+			w.WriteHeader(http.StatusConflict)
+			return false
+		}
+		HandleErrorStatus(&sublog, w, uRes.Status)
+		return false
 	}
+	return true
 }
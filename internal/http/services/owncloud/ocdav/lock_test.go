@@ -0,0 +1,244 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+const lockInfo = `<?xml version="1.0" encoding="utf-8"?>
+<d:lockinfo xmlns:d="DAV:">
+  <d:lockscope><d:exclusive/></d:lockscope>
+  <d:locktype><d:write/></d:locktype>
+  <d:owner><d:href>mailto:einstein@example.org</d:href></d:owner>
+</d:lockinfo>`
+
+// lock locks the file and returns the token of the lock.
+func lock(t *testing.T, s *svc, fn string, headers ...string) string {
+	t.Helper()
+	w := do(s, einstein, "LOCK", "/remote.php/webdav"+fn, body(lockInfo), headers...)
+	if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		t.Fatalf("LOCK %s = %d, wanted 200 or 201", fn, w.Code)
+	}
+	token := strings.TrimSuffix(strings.TrimPrefix(w.Header().Get("Lock-Token"), "<"), ">")
+	if !strings.HasPrefix(token, "opaquelocktoken:") {
+		t.Fatalf("LOCK %s returned the token %q", fn, w.Header().Get("Lock-Token"))
+	}
+	if !strings.Contains(w.Body.String(), "<d:locktoken><d:href>"+token+"</d:href></d:locktoken>") {
+		t.Errorf("the lockdiscovery does not contain the token: %s", w.Body.String())
+	}
+	return token
+}
+
+func ifHeader(token string) []string {
+	return []string{"If", "(<" + token + ">)"}
+}
+
+func TestLockUnlock(t *testing.T) {
+	g := newFakeGateway(t)
+	s := newTestService(t, g)
+
+	// locking an unmapped url creates an empty file
+	w := do(s, einstein, "LOCK", "/remote.php/webdav/new.txt", body(lockInfo))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("LOCK of a missing file = %d, wanted 201", w.Code)
+	}
+	if _, ok := g.file("/home/new.txt"); !ok {
+		t.Fatal("LOCK did not create the file")
+	}
+	token := strings.Trim(w.Header().Get("Lock-Token"), "<>")
+	if !strings.Contains(w.Body.String(), "<d:owner><d:href>mailto:einstein@example.org</d:href></d:owner>") {
+		t.Errorf("the lockdiscovery does not contain the owner: %s", w.Body.String())
+	}
+
+	if w := do(s, einstein, "LOCK", "/remote.php/webdav/new.txt", body(lockInfo)); w.Code != http.StatusLocked {
+		t.Errorf("LOCK of a locked file = %d, wanted 423", w.Code)
+	}
+	// an empty body refreshes the lock held
+	if w := do(s, einstein, "LOCK", "/remote.php/webdav/new.txt", nil, ifHeader(token)...); w.Code != http.StatusOK {
+		t.Errorf("refreshing the lock = %d, wanted 200", w.Code)
+	}
+	if w := do(s, einstein, "LOCK", "/remote.php/webdav/new.txt", nil, ifHeader("opaquelocktoken:other")...); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("refreshing without the lock = %d, wanted 412", w.Code)
+	}
+
+	if w := do(s, einstein, "UNLOCK", "/remote.php/webdav/new.txt", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("UNLOCK without a token = %d, wanted 400", w.Code)
+	}
+	if w := do(s, einstein, "UNLOCK", "/remote.php/webdav/new.txt", nil, "Lock-Token", "<opaquelocktoken:other>"); w.Code != http.StatusConflict {
+		t.Errorf("UNLOCK with another token = %d, wanted 409", w.Code)
+	}
+	if w := do(s, marie, "UNLOCK", "/remote.php/webdav/new.txt", nil, "Lock-Token", "<"+token+">"); w.Code != http.StatusLocked {
+		t.Errorf("UNLOCK by another user = %d, wanted 423", w.Code)
+	}
+	if w := do(s, einstein, "UNLOCK", "/remote.php/webdav/new.txt", nil, "Lock-Token", "<"+token+">"); w.Code != http.StatusNoContent {
+		t.Fatalf("UNLOCK = %d, wanted 204", w.Code)
+	}
+	if f, _ := g.file("/home/new.txt"); f.metadata[storage.LockKey] != "" {
+		t.Error("UNLOCK did not remove the lock")
+	}
+	if w := do(s, einstein, http.MethodPut, "/remote.php/webdav/new.txt", body("data")); w.Code != http.StatusNoContent {
+		t.Errorf("PUT after UNLOCK = %d, wanted 204", w.Code)
+	}
+}
+
+func TestLockedWrites(t *testing.T) {
+	g := newFakeGateway(t)
+	g.files["/home/file.txt"] = &fakeFile{content: []byte("data")}
+	g.files["/home/other.txt"] = &fakeFile{content: []byte("data")}
+	s := newTestService(t, g)
+	token := lock(t, s, "/file.txt")
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		headers []string
+	}{
+		{"PUT", http.MethodPut, "/remote.php/webdav/file.txt", nil},
+		{"DELETE", http.MethodDelete, "/remote.php/webdav/file.txt", nil},
+		{"MOVE", "MOVE", "/remote.php/webdav/file.txt", []string{"Destination", "/remote.php/webdav/moved.txt"}},
+		{"MOVE onto", "MOVE", "/remote.php/webdav/other.txt", []string{"Destination", "/remote.php/webdav/file.txt"}},
+	}
+	for _, tt := range tests {
+		if w := do(s, einstein, tt.method, tt.url, body("new"), tt.headers...); w.Code != http.StatusLocked {
+			t.Errorf("%s without the token = %d, wanted 423", tt.name, w.Code)
+		}
+		headers := append(tt.headers, ifHeader("opaquelocktoken:other")...)
+		if w := do(s, einstein, tt.method, tt.url, body("new"), headers...); w.Code != http.StatusLocked {
+			t.Errorf("%s with another token = %d, wanted 423", tt.name, w.Code)
+		}
+		headers = append(tt.headers, ifHeader(token)...)
+		if w := do(s, marie, tt.method, tt.url, body("new"), headers...); w.Code != http.StatusLocked {
+			t.Errorf("%s of another user = %d, wanted 423", tt.name, w.Code)
+		}
+	}
+	if f, _ := g.file("/home/file.txt"); string(f.content) != "data" {
+		t.Fatalf("the locked file was written: %q", f.content)
+	}
+
+	if w := do(s, einstein, http.MethodPut, "/remote.php/webdav/file.txt", body("new"), ifHeader(token)...); w.Code != http.StatusNoContent {
+		t.Errorf("PUT with the token = %d, wanted 204", w.Code)
+	}
+	if f, _ := g.file("/home/file.txt"); string(f.content) != "new" {
+		t.Errorf("PUT with the token did not write the file: %q", f.content)
+	}
+
+	// the lock stays with the url, it is not moved with the file
+	if w := do(s, einstein, "MOVE", "/remote.php/webdav/file.txt", nil, append([]string{"Destination", "/remote.php/webdav/moved.txt"}, ifHeader(token)...)...); w.Code != http.StatusCreated {
+		t.Fatalf("MOVE with the token = %d, wanted 201", w.Code)
+	}
+	if f, ok := g.file("/home/moved.txt"); !ok || f.metadata[storage.LockKey] != "" {
+		t.Error("MOVE with the token did not move the file without its lock")
+	}
+
+	token = lock(t, s, "/moved.txt")
+	if w := do(s, einstein, http.MethodDelete, "/remote.php/webdav/moved.txt", nil, ifHeader(token)...); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE with the token = %d, wanted 204", w.Code)
+	}
+	if _, ok := g.file("/home/moved.txt"); ok {
+		t.Error("DELETE with the token did not remove the file")
+	}
+}
+
+func TestLockExpiry(t *testing.T) {
+	g := newFakeGateway(t)
+	g.files["/home/file.txt"] = &fakeFile{content: []byte("data")}
+	s := newTestService(t, g)
+
+	w := do(s, einstein, "LOCK", "/remote.php/webdav/file.txt", body(lockInfo), "Timeout", "Second-60")
+	if w.Code != http.StatusOK {
+		t.Fatalf("LOCK = %d, wanted 200", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "<d:timeout>Second-60</d:timeout>") {
+		t.Errorf("the lockdiscovery does not contain the timeout: %s", w.Body.String())
+	}
+	if w := do(s, einstein, http.MethodPut, "/remote.php/webdav/file.txt", body("new")); w.Code != http.StatusLocked {
+		t.Fatalf("PUT before the expiry = %d, wanted 423", w.Code)
+	}
+
+	// let the lock expire
+	f, _ := g.file("/home/file.txt")
+	g.mu.Lock()
+	l, err := storage.DecodeLock(f.metadata[storage.LockKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Expires = time.Now().Add(-time.Second)
+	if f.metadata[storage.LockKey], err = l.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Unlock()
+
+	if w := do(s, einstein, http.MethodPut, "/remote.php/webdav/file.txt", body("new")); w.Code != http.StatusNoContent {
+		t.Errorf("PUT after the expiry = %d, wanted 204", w.Code)
+	}
+	if w := do(s, einstein, "LOCK", "/remote.php/webdav/file.txt", nil, ifHeader(l.Token)...); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("refreshing an expired lock = %d, wanted 412", w.Code)
+	}
+	if w := do(s, marie, "LOCK", "/remote.php/webdav/file.txt", body(lockInfo)); w.Code != http.StatusOK {
+		t.Errorf("LOCK after the expiry = %d, wanted 200", w.Code)
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", defaultLockTimeout},
+		{"Second-60", time.Minute},
+		{"Infinite, Second-60", maxLockTimeout},
+		{"Second-0, Second-60", time.Minute},
+		{"Minute-5", defaultLockTimeout},
+		{"Second-999999999", maxLockTimeout},
+	}
+	for _, tt := range tests {
+		if got := lockTimeout(tt.header); got != tt.want {
+			t.Errorf("lockTimeout(%q) = %s, wanted %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLockTokens(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", nil},
+		{"(<opaquelocktoken:a>)", []string{"opaquelocktoken:a"}},
+		{`</remote.php/webdav/f> (<opaquelocktoken:a> ["etag"]) (Not <opaquelocktoken:b>)`, []string{"opaquelocktoken:a"}},
+		{"(<opaquelocktoken:a>) (<opaquelocktoken:b>)", []string{"opaquelocktoken:a", "opaquelocktoken:b"}},
+		{"(<opaquelocktoken:a", nil},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PUT", "/", nil)
+		r.Header.Set("If", tt.header)
+		if got := lockTokens(r); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lockTokens(%q) = %v, wanted %v", tt.header, got, tt.want)
+		}
+	}
+}
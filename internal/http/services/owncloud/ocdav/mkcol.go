@@ -83,6 +83,7 @@ func (s *svc) handleMkcol(w http.ResponseWriter, r *http.Request, ns string) {
 	}
 	switch res.Status.Code {
 	case rpc.Code_CODE_OK:
+		s.recordChange(ctx, false, fn)
 		w.WriteHeader(http.StatusCreated)
 	case rpc.Code_CODE_NOT_FOUND:
 		sublog.Debug().Str("path", fn).Interface("status", statRes.Status).Msg("conflict")
@@ -19,13 +19,17 @@
 package ocdav
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"path"
 	"strings"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
 	"go.opencensus.io/trace"
 )
 
@@ -70,6 +74,7 @@ func (s *svc) handleMove(w http.ResponseWriter, r *http.Request, ns string) {
 		Ref: &provider.Reference{
 			Spec: &provider.Reference_Path{Path: src},
 		},
+		ArbitraryMetadataKeys: []string{storage.LockKey},
 	}
 	srcStatRes, err := client.Stat(ctx, srcStatReq)
 	if err != nil {
@@ -81,12 +86,22 @@ func (s *svc) handleMove(w http.ResponseWriter, r *http.Request, ns string) {
 		HandleErrorStatus(&sublog, w, srcStatRes.Status)
 		return
 	}
+	if !lockSatisfied(ctx, r, srcStatRes.Info) {
+		writeLocked(ctx, w, src)
+		return
+	}
+	// the conditional headers apply to the source, the target of the request
+	if !preconditionsMet(r, srcStatRes.Info) {
+		sublog.Debug().Str("server-etag", srcStatRes.Info.GetEtag()).Msg("precondition failed")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
 
 	// check dst exists
 	dstStatRef := &provider.Reference{
 		Spec: &provider.Reference_Path{Path: dst},
 	}
-	dstStatReq := &provider.StatRequest{Ref: dstStatRef}
+	dstStatReq := &provider.StatRequest{Ref: dstStatRef, ArbitraryMetadataKeys: []string{storage.LockKey}}
 	dstStatRes, err := client.Stat(ctx, dstStatReq)
 	if err != nil {
 		sublog.Error().Err(err).Msg("error getting grpc client")
@@ -98,6 +113,11 @@ func (s *svc) handleMove(w http.ResponseWriter, r *http.Request, ns string) {
 		return
 	}
 
+	// locks stay with the urls, see https://tools.ietf.org/html/rfc4918#section-7.7:
+	// the lock of the source is not moved, the one of the destination is kept
+	srcLock := storage.ActiveLock(srcStatRes.Info)
+	var dstLock *storage.Lock
+
 	successCode := http.StatusCreated // 201 if new resource was created, see https://tools.ietf.org/html/rfc4918#section-9.9.4
 	if dstStatRes.Status.Code == rpc.Code_CODE_OK {
 		successCode = http.StatusNoContent // 204 if target already existed, see https://tools.ietf.org/html/rfc4918#section-9.9.4
@@ -108,6 +128,12 @@ func (s *svc) handleMove(w http.ResponseWriter, r *http.Request, ns string) {
 			return
 		}
 
+		if !lockSatisfied(ctx, r, dstStatRes.Info) {
+			writeLocked(ctx, w, dst)
+			return
+		}
+		dstLock = storage.ActiveLock(dstStatRes.Info)
+
 		// delete existing tree
 		delReq := &provider.DeleteRequest{Ref: dstStatRef}
 		delRes, err := client.Delete(ctx, delReq)
@@ -166,6 +192,16 @@ func (s *svc) handleMove(w http.ResponseWriter, r *http.Request, ns string) {
 		return
 	}
 
+	s.recordChange(ctx, true, src)
+	s.recordChange(ctx, false, dst)
+
+	if dstLock != nil || srcLock != nil {
+		if err := s.moveLock(ctx, client, dstRef, dstLock); err != nil {
+			// the resource has been moved, the lock is leftover or lost
+			sublog.Error().Err(err).Msg("error restoring the locks of the destination")
+		}
+	}
+
 	dstStatRes, err = client.Stat(ctx, dstStatReq)
 	if err != nil {
 		sublog.Error().Err(err).Msg("error sending grpc stat request")
@@ -185,3 +221,39 @@ func (s *svc) handleMove(w http.ResponseWriter, r *http.Request, ns string) {
 	w.Header().Set("OC-ETag", info.Etag)
 	w.WriteHeader(successCode)
 }
+
+// moveLock puts the lock the destination had before a move back on it, or
+// removes the lock of the source it got moved with when it had none.
+func (s *svc) moveLock(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference, l *storage.Lock) error {
+	if l == nil {
+		res, err := client.UnsetArbitraryMetadata(ctx, &provider.UnsetArbitraryMetadataRequest{
+			Ref:                   ref,
+			ArbitraryMetadataKeys: []string{storage.LockKey},
+		})
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return fmt.Errorf("status code %d", res.Status.Code)
+		}
+		return nil
+	}
+
+	v, err := l.Encode()
+	if err != nil {
+		return err
+	}
+	res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref: ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{storage.LockKey: v},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return fmt.Errorf("status code %d", res.Status.Code)
+	}
+	return nil
+}
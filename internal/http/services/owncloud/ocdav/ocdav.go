@@ -24,7 +24,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -34,11 +36,14 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/favorite"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/bodylimit"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/storage/changes"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
 	ctxuser "github.com/cs3org/reva/pkg/user"
 	"github.com/mitchellh/mapstructure"
@@ -50,6 +55,7 @@ type ctxKey int
 
 const (
 	ctxKeyBaseURI ctxKey = iota
+	ctxKeyFavorites
 )
 
 func init() {
@@ -73,11 +79,62 @@ type Config struct {
 	Timeout         int64  `mapstructure:"timeout"`
 	Insecure        bool   `mapstructure:"insecure"`
 	PublicURL       string `mapstructure:"public_url"`
+	// MTLS calls the data gateway over mutual TLS with the certificate of the process.
+	MTLS bool `mapstructure:"mtls"`
+	// SearchSvc is the address of the search service answering the search-files reports.
+	// Searching is disabled when it is not set.
+	SearchSvc string `mapstructure:"searchsvc"`
+	// PropfindInfinityLimit is the maximum number of resources returned by a
+	// Depth: infinity PROPFIND, larger trees get a truncated response.
+	// Depth: infinity is refused when it is negative.
+	PropfindInfinityLimit int `mapstructure:"propfind_infinity_limit"`
+	// ChangeJournal is the driver of the journal the sync-collection reports
+	// are answered from, they are not supported when it is not set.
+	ChangeJournal  string                            `mapstructure:"change_journal"`
+	ChangeJournals map[string]map[string]interface{} `mapstructure:"change_journals"`
+	// EventStream is the event stream the changes made through other services
+	// are recorded from, only the changes made through ocdav are recorded when
+	// it is not set.
+	EventStream  string                            `mapstructure:"event_stream"`
+	EventStreams map[string]map[string]interface{} `mapstructure:"event_streams"`
+	// SyncLimit is the maximum number of resources returned by a sync-collection report.
+	SyncLimit int `mapstructure:"sync_limit"`
+	// UploadsFolder is the local folder the chunks of the chunking NG uploads
+	// are kept in until they are assembled. The chunks of an upload are only
+	// found by the ocdav they were sent to: with several instances, the
+	// folder has to be shared or the uploads routed to the same instance,
+	// e.g. with sticky sessions.
+	UploadsFolder string `mapstructure:"uploads_folder"`
+	// UploadsUserQuota is the space in bytes the unfinished uploads of a user
+	// may take in the UploadsFolder, 10 GiB by default, which also bounds the
+	// size of the files uploaded in chunks.
+	UploadsUserQuota int64 `mapstructure:"uploads_user_quota"`
+	// FavoriteStorageDriver is the manager the favorites of the users are
+	// kept in, they are stored in the arbitrary metadata of the resources
+	// when it is not set. The favorites can only be listed with a manager.
+	FavoriteStorageDriver  string                            `mapstructure:"favorite_storage_driver"`
+	FavoriteStorageDrivers map[string]map[string]interface{} `mapstructure:"favorite_storage_drivers"`
+	// BodyLimit bounds the size of the request bodies, e.g. of the PROPPATCH
+	// requests or, with a limit for the method, of the PUT uploads.
+	BodyLimit bodylimit.Config `mapstructure:"body_limit"`
 }
 
 func (c *Config) init() {
 	// note: default c.Prefix is an empty string
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+
+	if c.PropfindInfinityLimit == 0 {
+		c.PropfindInfinityLimit = 10000
+	}
+	if c.SyncLimit <= 0 {
+		c.SyncLimit = 1000
+	}
+	if c.UploadsFolder == "" {
+		c.UploadsFolder = filepath.Join(os.TempDir(), "ocdav-uploads")
+	}
+	if c.UploadsUserQuota <= 0 {
+		c.UploadsUserQuota = 10 << 30
+	}
 }
 
 type svc struct {
@@ -85,6 +142,12 @@ type svc struct {
 	webDavHandler *WebDavHandler
 	davHandler    *DavHandler
 	client        *http.Client
+	journal       changes.Journal
+	stopEvents    func()
+	favorites     favorite.Manager
+	// gateway is the client of the gateway, the one of the GatewaySvc
+	// when not set.
+	gateway gateway.GatewayAPIClient
 }
 
 // New returns a new ocdav
@@ -103,6 +166,7 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 		client: rhttp.GetHTTPClient(
 			rhttp.Timeout(time.Duration(conf.Timeout*int64(time.Second))),
 			rhttp.Insecure(conf.Insecure),
+			rhttp.MutualTLS(conf.MTLS),
 		),
 	}
 	// initialize handlers and set default configs
@@ -112,6 +176,16 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 	if err := s.davHandler.init(conf); err != nil {
 		return nil, err
 	}
+	if conf.ChangeJournal != "" {
+		if err := s.initJournal(); err != nil {
+			return nil, err
+		}
+	}
+	if conf.FavoriteStorageDriver != "" {
+		if err := s.initFavorites(); err != nil {
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
@@ -120,6 +194,12 @@ func (s *svc) Prefix() string {
 }
 
 func (s *svc) Close() error {
+	if s.stopEvents != nil {
+		s.stopEvents()
+	}
+	if s.journal != nil {
+		return s.journal.Close()
+	}
 	return nil
 }
 
@@ -128,8 +208,9 @@ func (s *svc) Unprotected() []string {
 }
 
 func (s *svc) Handler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := s.withFavorites(r.Context())
+		r = r.WithContext(ctx)
 		log := appctx.GetLogger(ctx)
 
 		addAccessHeaders(w, r)
@@ -184,9 +265,17 @@ func (s *svc) Handler() http.Handler {
 		log.Warn().Msg("resource not found")
 		w.WriteHeader(http.StatusNotFound)
 	})
+
+	if s.c.BodyLimit.Enabled() {
+		return bodylimit.New(&s.c.BodyLimit).Handler(h)
+	}
+	return h
 }
 
 func (s *svc) getClient() (gateway.GatewayAPIClient, error) {
+	if s.gateway != nil {
+		return s.gateway, nil
+	}
 	return pool.GetGatewayServiceClient(s.c.GatewaySvc)
 }
 
@@ -0,0 +1,251 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+// fakeFile is a resource of the fakeGateway.
+type fakeFile struct {
+	dir      bool
+	content  []byte
+	metadata map[string]string
+	etag     int
+}
+
+// fakeGateway keeps the resources in memory and stores the uploads sent to
+// its data server.
+type fakeGateway struct {
+	gateway.GatewayAPIClient
+	mu    sync.Mutex
+	files map[string]*fakeFile
+	data  *httptest.Server
+}
+
+func newFakeGateway(t *testing.T) *fakeGateway {
+	g := &fakeGateway{files: map[string]*fakeFile{"/home": {dir: true}}}
+	g.data = httptest.NewServer(g)
+	t.Cleanup(g.data.Close)
+	return g
+}
+
+func (g *fakeGateway) info(fn string, f *fakeFile) *provider.ResourceInfo {
+	info := &provider.ResourceInfo{
+		Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+		Id:       &provider.ResourceId{StorageId: "storage", OpaqueId: fn},
+		Path:     fn,
+		Etag:     fmt.Sprintf(`"%d"`, f.etag),
+		MimeType: "application/octet-stream",
+		Size:     uint64(len(f.content)),
+		Mtime:    &typespb.Timestamp{Seconds: 1},
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{},
+		},
+	}
+	if f.dir {
+		info.Type = provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	}
+	for k, v := range f.metadata {
+		info.ArbitraryMetadata.Metadata[k] = v
+	}
+	return info
+}
+
+func (g *fakeGateway) Stat(ctx context.Context, in *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fn := in.Ref.GetPath()
+	f, ok := g.files[fn]
+	if !ok {
+		return &provider.StatResponse{Status: status.NewNotFound(ctx, fn)}, nil
+	}
+	return &provider.StatResponse{Status: status.NewOK(ctx), Info: g.info(fn, f)}, nil
+}
+
+func (g *fakeGateway) SetArbitraryMetadata(ctx context.Context, in *provider.SetArbitraryMetadataRequest, opts ...grpc.CallOption) (*provider.SetArbitraryMetadataResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fn := in.Ref.GetPath()
+	f, ok := g.files[fn]
+	if !ok {
+		return &provider.SetArbitraryMetadataResponse{Status: status.NewNotFound(ctx, fn)}, nil
+	}
+	if f.metadata == nil {
+		f.metadata = map[string]string{}
+	}
+	for k, v := range in.ArbitraryMetadata.Metadata {
+		f.metadata[k] = v
+	}
+	return &provider.SetArbitraryMetadataResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (g *fakeGateway) UnsetArbitraryMetadata(ctx context.Context, in *provider.UnsetArbitraryMetadataRequest, opts ...grpc.CallOption) (*provider.UnsetArbitraryMetadataResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fn := in.Ref.GetPath()
+	f, ok := g.files[fn]
+	if !ok {
+		return &provider.UnsetArbitraryMetadataResponse{Status: status.NewNotFound(ctx, fn)}, nil
+	}
+	for _, k := range in.ArbitraryMetadataKeys {
+		delete(f.metadata, k)
+	}
+	return &provider.UnsetArbitraryMetadataResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (g *fakeGateway) InitiateFileUpload(ctx context.Context, in *provider.InitiateFileUploadRequest, opts ...grpc.CallOption) (*gateway.InitiateFileUploadResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fn := in.Ref.GetPath()
+	if p, ok := g.files[path.Dir(fn)]; !ok || !p.dir {
+		return &gateway.InitiateFileUploadResponse{Status: status.NewNotFound(ctx, fn)}, nil
+	}
+	// empty uploads are finished when they are initiated
+	if string(in.Opaque.GetMap()["Upload-Length"].GetValue()) == "0" {
+		g.store(fn, nil)
+	}
+	return &gateway.InitiateFileUploadResponse{
+		Status: status.NewOK(ctx),
+		Protocols: []*gateway.FileUploadProtocol{{
+			Protocol:       "simple",
+			UploadEndpoint: g.data.URL,
+			Token:          fn,
+		}},
+	}, nil
+}
+
+func (g *fakeGateway) Delete(ctx context.Context, in *provider.DeleteRequest, opts ...grpc.CallOption) (*provider.DeleteResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fn := in.Ref.GetPath()
+	if _, ok := g.files[fn]; !ok {
+		return &provider.DeleteResponse{Status: status.NewNotFound(ctx, fn)}, nil
+	}
+	delete(g.files, fn)
+	return &provider.DeleteResponse{Status: status.NewOK(ctx)}, nil
+}
+
+func (g *fakeGateway) Move(ctx context.Context, in *provider.MoveRequest, opts ...grpc.CallOption) (*provider.MoveResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	src, dst := in.Source.GetPath(), in.Destination.GetPath()
+	f, ok := g.files[src]
+	if !ok {
+		return &provider.MoveResponse{Status: status.NewNotFound(ctx, src)}, nil
+	}
+	// the metadata move with the resource, like on the storages
+	delete(g.files, src)
+	g.files[dst] = f
+	return &provider.MoveResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// store writes the content of a file, the lock of the caller must be held.
+func (g *fakeGateway) store(fn string, content []byte) {
+	f, ok := g.files[fn]
+	if !ok {
+		f = &fakeFile{}
+		g.files[fn] = f
+	}
+	f.content = content
+	f.etag++
+}
+
+// ServeHTTP stores the uploads of the simple protocol, the token being the
+// path of the file.
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.store(r.Header.Get(datagateway.TokenTransportHeader), b)
+}
+
+func (g *fakeGateway) file(fn string) (*fakeFile, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f, ok := g.files[fn]
+	return f, ok
+}
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"}, Username: "einstein"}
+	marie    = &userpb.User{Id: &userpb.UserId{Idp: "cernbox", OpaqueId: "marie"}, Username: "marie"}
+)
+
+// newTestService returns an ocdav serving the /home namespace of the fake
+// gateway.
+func newTestService(t *testing.T, g *fakeGateway) *svc {
+	log := zerolog.Nop()
+	s, err := New(map[string]interface{}{
+		"webdav_namespace": "/home",
+		"files_namespace":  "/home",
+		"uploads_folder":   t.TempDir(),
+	}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	s.(*svc).gateway = g
+	return s.(*svc)
+}
+
+// do serves a request of the user, the headers being given as name and value
+// pairs. The body has to be nil or one of the readers of httptest.NewRequest.
+func do(s *svc, u *userpb.User, method, url string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, body)
+	if body != nil {
+		// like the clients, PUT requires the length
+		r.Header.Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	r = r.WithContext(ctxuser.ContextSetUser(r.Context(), u))
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func body(s string) io.Reader {
+	return strings.NewReader(s)
+}
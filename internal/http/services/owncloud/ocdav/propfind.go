@@ -34,6 +34,7 @@ import (
 
 	"go.opencensus.io/trace"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
@@ -41,6 +42,7 @@ import (
 	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
 	ctxuser "github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/utils"
 )
@@ -81,6 +83,12 @@ func (s *svc) handlePropfind(w http.ResponseWriter, r *http.Request, ns string)
 		return
 	}
 
+	if depth == "infinity" && s.c.PropfindInfinityLimit < 0 {
+		sublog.Debug().Msg("Depth: infinity propfinds are disabled")
+		writeDAVError(ctx, w, http.StatusForbidden, "propfind-finite-depth")
+		return
+	}
+
 	pf, status, err := readPropfind(r.Body)
 	if err != nil {
 		sublog.Debug().Err(err).Msg("error reading propfind request")
@@ -95,21 +103,7 @@ func (s *svc) handlePropfind(w http.ResponseWriter, r *http.Request, ns string)
 		return
 	}
 
-	metadataKeys := []string{}
-	if pf.Allprop != nil {
-		// TODO this changes the behavior and returns all properties if allprops has been set,
-		// but allprops should only return some default properties
-		// see https://tools.ietf.org/html/rfc4918#section-9.1
-		// the description of arbitrary_metadata_keys in https://cs3org.github.io/cs3apis/#cs3.storage.provider.v1beta1.ListContainerRequest an others may need clarification
-		// tracked in https://github.com/cs3org/cs3apis/issues/104
-		metadataKeys = append(metadataKeys, "*")
-	} else {
-		for i := range pf.Prop {
-			if requiresExplicitFetching(&pf.Prop[i]) {
-				metadataKeys = append(metadataKeys, metadataKeyOf(&pf.Prop[i]))
-			}
-		}
-	}
+	metadataKeys := metadataKeysOf(&pf)
 	ref := &provider.Reference{
 		Spec: &provider.Reference_Path{Path: fn},
 	}
@@ -130,6 +124,11 @@ func (s *svc) handlePropfind(w http.ResponseWriter, r *http.Request, ns string)
 	}
 
 	info := res.Info
+	if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && depth == "infinity" {
+		s.streamPropfind(ctx, w, client, &pf, info, ns, metadataKeys)
+		return
+	}
+
 	infos := []*provider.ResourceInfo{info}
 	if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && depth == "1" {
 		req := &provider.ListContainerRequest{
@@ -148,50 +147,6 @@ func (s *svc) handlePropfind(w http.ResponseWriter, r *http.Request, ns string)
 			return
 		}
 		infos = append(infos, res.Infos...)
-	} else if depth == "infinity" {
-		// FIXME: doesn't work cross-storage as the results will have the wrong paths!
-		// use a stack to explore sub-containers breadth-first
-		stack := []string{info.Path}
-		for len(stack) > 0 {
-			// retrieve path on top of stack
-			path := stack[len(stack)-1]
-			ref = &provider.Reference{
-				Spec: &provider.Reference_Path{Path: path},
-			}
-			req := &provider.ListContainerRequest{
-				Ref:                   ref,
-				ArbitraryMetadataKeys: metadataKeys,
-			}
-			res, err := client.ListContainer(ctx, req)
-			if err != nil {
-				sublog.Error().Err(err).Str("path", path).Msg("error sending list container grpc request")
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			if res.Status.Code != rpc.Code_CODE_OK {
-				HandleErrorStatus(&sublog, w, res.Status)
-				return
-			}
-
-			infos = append(infos, res.Infos...)
-
-			if depth != "infinity" {
-				break
-			}
-
-			// TODO: stream response to avoid storing too many results in memory
-
-			stack = stack[:len(stack)-1]
-
-			// check sub-containers in reverse order and add them to the stack
-			// the reversed order here will produce a more logical sorting of results
-			for i := len(res.Infos) - 1; i >= 0; i-- {
-				// for i := range res.Infos {
-				if res.Infos[i].Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
-					stack = append(stack, res.Infos[i].Path)
-				}
-			}
-		}
 	}
 
 	propRes, err := s.formatPropfind(ctx, &pf, infos, ns)
@@ -200,6 +155,14 @@ func (s *svc) handlePropfind(w http.ResponseWriter, r *http.Request, ns string)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setPropfindHeaders(w, info)
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(propRes)); err != nil {
+		sublog.Err(err).Msg("error writing response")
+	}
+}
+
+func setPropfindHeaders(w http.ResponseWriter, info *provider.ResourceInfo) {
 	w.Header().Set("DAV", "1, 3, extended-mkcol")
 	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
 
@@ -216,10 +179,143 @@ func (s *svc) handlePropfind(w http.ResponseWriter, r *http.Request, ns string)
 			w.Header().Set("Tus-Extension", "creation,creation-with-upload")
 		}
 	}
+}
+
+// streamPropfind answers a Depth: infinity PROPFIND on a collection. The tree is
+// walked depth first and the responses are flushed to the client after every
+// listed collection instead of being buffered until the whole tree is known.
+// Collections that cannot be listed get a response with the error status. When
+// the tree holds more than PropfindInfinityLimit resources the multistatus is
+// truncated with a 507 response for the collection, see
+// https://tools.ietf.org/html/rfc4918#section-9.1
+func (s *svc) streamPropfind(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient, pf *propfindXML, info *provider.ResourceInfo, ns string, metadataKeys []string) {
+	sublog := appctx.GetLogger(ctx).With().Str("path", info.Path).Logger()
+	baseURI := ctx.Value(ctxKeyBaseURI).(string)
+	href := func(fn string) string {
+		return encodePath(path.Join(baseURI, strings.TrimPrefix(fn, ns)) + "/")
+	}
+
+	write := func(res *responseXML) bool {
+		b, err := xml.Marshal(res)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error formatting propfind")
+			return false
+		}
+		if _, err := w.Write(b); err != nil {
+			sublog.Err(err).Msg("error writing response")
+			return false
+		}
+		return true
+	}
+	writeInfo := func(md *provider.ResourceInfo) bool {
+		res, err := s.mdToPropResponse(ctx, pf, md, ns)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error formatting propfind")
+			return false
+		}
+		return write(res)
+	}
+	writeStatus := func(fn string, code int, description string) bool {
+		return write(&responseXML{
+			Href:                href(fn),
+			Status:              fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code)),
+			ResponseDescription: description,
+		})
+	}
+
+	setPropfindHeaders(w, info)
 	w.WriteHeader(http.StatusMultiStatus)
-	if _, err := w.Write([]byte(propRes)); err != nil {
+	if _, err := io.WriteString(w, multistatusStart); err != nil {
 		sublog.Err(err).Msg("error writing response")
+		return
+	}
+	defer func() {
+		if _, err := io.WriteString(w, multistatusEnd); err != nil {
+			sublog.Err(err).Msg("error writing response")
+		}
+	}()
+
+	root := info.Path
+	stack := []string{root}
+	if !writeInfo(info) {
+		return
 	}
+	n := 1
+	for len(stack) > 0 {
+		fn := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		res, err := client.ListContainer(ctx, &provider.ListContainerRequest{
+			Ref:                   &provider.Reference{Spec: &provider.Reference_Path{Path: fn}},
+			ArbitraryMetadataKeys: metadataKeys,
+		})
+		if err != nil {
+			sublog.Error().Err(err).Str("path", fn).Msg("error sending list container grpc request")
+			if !writeStatus(fn, http.StatusInternalServerError, "") {
+				return
+			}
+			continue
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			sublog.Debug().Str("path", fn).Interface("status", res.Status).Msg("error listing container")
+			code := http.StatusInternalServerError
+			switch res.Status.Code {
+			case rpc.Code_CODE_NOT_FOUND:
+				code = http.StatusNotFound
+			case rpc.Code_CODE_PERMISSION_DENIED:
+				code = http.StatusForbidden
+			}
+			if !writeStatus(fn, code, "") {
+				return
+			}
+			continue
+		}
+
+		// check sub-containers in reverse order and add them to the stack
+		// the reversed order here will produce a more logical sorting of results
+		// the paths are taken before mdToPropResponse strips the namespace
+		for i := len(res.Infos) - 1; i >= 0; i-- {
+			if res.Infos[i].Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				stack = append(stack, res.Infos[i].Path)
+			}
+		}
+
+		for _, md := range res.Infos {
+			if n >= s.c.PropfindInfinityLimit {
+				sublog.Debug().Int("limit", s.c.PropfindInfinityLimit).Msg("propfind truncated")
+				writeStatus(root, http.StatusInsufficientStorage, fmt.Sprintf("The response is limited to %d resources.", s.c.PropfindInfinityLimit))
+				return
+			}
+			if !writeInfo(md) {
+				return
+			}
+			n++
+		}
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// metadataKeysOf returns the arbitrary metadata keys to fetch for the properties.
+func metadataKeysOf(pf *propfindXML) []string {
+	metadataKeys := []string{}
+	if pf.Allprop != nil {
+		// TODO this changes the behavior and returns all properties if allprops has been set,
+		// but allprops should only return some default properties
+		// see https://tools.ietf.org/html/rfc4918#section-9.1
+		// the description of arbitrary_metadata_keys in https://cs3org.github.io/cs3apis/#cs3.storage.provider.v1beta1.ListContainerRequest an others may need clarification
+		// tracked in https://github.com/cs3org/cs3apis/issues/104
+		metadataKeys = append(metadataKeys, "*")
+	} else {
+		for i := range pf.Prop {
+			if requiresExplicitFetching(&pf.Prop[i]) {
+				metadataKeys = append(metadataKeys, metadataKeyOf(&pf.Prop[i]))
+			}
+		}
+	}
+	return metadataKeys
 }
 
 func requiresExplicitFetching(n *xml.Name) bool {
@@ -230,6 +326,8 @@ func requiresExplicitFetching(n *xml.Name) bool {
 			//  A <DAV:allprop> PROPFIND request SHOULD NOT return DAV:quota-available-bytes and DAV:quota-used-bytes
 			// from https://www.rfc-editor.org/rfc/rfc4331.html#section-2
 			return true
+		case "lockdiscovery":
+			return true
 		default:
 			return false
 		}
@@ -291,12 +389,15 @@ func (s *svc) formatPropfind(ctx context.Context, pf *propfindXML, mds []*provid
 		return "", err
 	}
 
-	msg := `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" `
-	msg += `xmlns:s="http://sabredav.org/ns" xmlns:oc="http://owncloud.org/ns">`
-	msg += string(responsesXML) + `</d:multistatus>`
-	return msg, nil
+	return multistatusStart + string(responsesXML) + multistatusEnd, nil
 }
 
+const (
+	multistatusStart = `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" ` +
+		`xmlns:s="http://sabredav.org/ns" xmlns:oc="http://owncloud.org/ns">`
+	multistatusEnd = `</d:multistatus>`
+)
+
 func (s *svc) xmlEscaped(val string) []byte {
 	buf := new(bytes.Buffer)
 	xml.Escape(buf, []byte(val))
@@ -335,6 +436,7 @@ func (s *svc) newPropRaw(key, val string) *propertyXML {
 func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provider.ResourceInfo, ns string) (*responseXML, error) {
 	sublog := appctx.GetLogger(ctx).With().Interface("md", md).Str("ns", ns).Logger()
 	md.Path = strings.TrimPrefix(md.Path, ns)
+	s.applyFavorite(ctx, md)
 
 	baseURI := ctx.Value(ctxKeyBaseURI).(string)
 
@@ -442,35 +544,15 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:getlastmodified", lastModifiedString))
 		}
 
-		// stay bug compatible with oc10, see https://github.com/owncloud/core/pull/38304#issuecomment-762185241
-		var checksums strings.Builder
-		if md.Checksum != nil {
-			checksums.WriteString("<oc:checksum>")
-			checksums.WriteString(strings.ToUpper(string(storageprovider.GRPC2PKGXS(md.Checksum.Type))))
-			checksums.WriteString(":")
-			checksums.WriteString(md.Checksum.Sum)
-		}
-		if md.Opaque != nil {
-			if e, ok := md.Opaque.Map["md5"]; ok {
-				if checksums.Len() == 0 {
-					checksums.WriteString("<oc:checksum>MD5:")
-				} else {
-					checksums.WriteString(" MD5:")
-				}
-				checksums.WriteString(string(e.Value))
-			}
-			if e, ok := md.Opaque.Map["adler32"]; ok {
-				if checksums.Len() == 0 {
-					checksums.WriteString("<oc:checksum>ADLER32:")
-				} else {
-					checksums.WriteString(" ADLER32:")
-				}
-				checksums.WriteString(string(e.Value))
-			}
+		if checksums := checksumsXML(md); checksums != "" {
+			propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("oc:checksums", checksums))
 		}
-		if checksums.Len() > 0 {
-			checksums.WriteString("</oc:checksum>")
-			propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("oc:checksums", checksums.String()))
+
+		if md.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+			propstatOK.Prop = append(propstatOK.Prop,
+				s.newPropRaw("d:lockdiscovery", s.lockDiscovery(md, response.Href)),
+				s.newPropRaw("d:supportedlock", supportedLockXML),
+			)
 		}
 
 		// ls do not report any properties as missing by default
@@ -608,36 +690,8 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:favorite", ""))
 					}
 				case "checksums": // desktop ... not really ... the desktop sends the OC-Checksum header
-
-					// stay bug compatible with oc10, see https://github.com/owncloud/core/pull/38304#issuecomment-762185241
-					var checksums strings.Builder
-					if md.Checksum != nil {
-						checksums.WriteString("<oc:checksum>")
-						checksums.WriteString(strings.ToUpper(string(storageprovider.GRPC2PKGXS(md.Checksum.Type))))
-						checksums.WriteString(":")
-						checksums.WriteString(md.Checksum.Sum)
-					}
-					if md.Opaque != nil {
-						if e, ok := md.Opaque.Map["md5"]; ok {
-							if checksums.Len() == 0 {
-								checksums.WriteString("<oc:checksum>MD5:")
-							} else {
-								checksums.WriteString(" MD5:")
-							}
-							checksums.WriteString(string(e.Value))
-						}
-						if e, ok := md.Opaque.Map["adler32"]; ok {
-							if checksums.Len() == 0 {
-								checksums.WriteString("<oc:checksum>ADLER32:")
-							} else {
-								checksums.WriteString(" ADLER32:")
-							}
-							checksums.WriteString(string(e.Value))
-						}
-					}
-					if checksums.Len() > 13 {
-						checksums.WriteString("</oc:checksum>")
-						propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("oc:checksums", checksums.String()))
+					if checksums := checksumsXML(md); checksums != "" {
+						propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("oc:checksums", checksums))
 					} else {
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:checksums", ""))
 					}
@@ -749,6 +803,20 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 					} else {
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("d:quota-used-bytes", ""))
 					}
+				case "lockdiscovery": // RFC 4918
+					propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("d:lockdiscovery", s.lockDiscovery(md, response.Href)))
+				case "supportedlock": // RFC 4918
+					if md.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+						propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("d:supportedlock", supportedLockXML))
+					} else {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:supportedlock", ""))
+					}
+				case "sync-token": // RFC 6578
+					if t, ok := s.syncToken(ctx); ok && md.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:sync-token", t))
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("d:sync-token", ""))
+					}
 				case "quota-available-bytes": // RFC 4331
 					if md.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 						// oc10 returns -3 for unlimited, -2 for unknown, -1 for uncalculated
@@ -832,6 +900,8 @@ func metadataKeyOf(n *xml.Name) string {
 	switch {
 	case n.Space == _nsDav && n.Local == "quota-available-bytes":
 		return "quota"
+	case n.Space == _nsDav && n.Local == "lockdiscovery":
+		return storage.LockKey
 	default:
 		return fmt.Sprintf("%s/%s", n.Space, n.Local)
 	}
@@ -922,3 +992,35 @@ type propertyXML struct {
 	// even including the DAV: namespace.
 	InnerXML []byte `xml:",innerxml"`
 }
+
+// checksumsXML renders the checksums of the resource in a single oc:checksum
+// element to stay bug compatible with oc10, see https://github.com/owncloud/core/pull/38304#issuecomment-762185241
+// the storages return the checksums besides the main one in the opaque map.
+func checksumsXML(md *provider.ResourceInfo) string {
+	var checksums strings.Builder
+	if md.Checksum != nil {
+		checksums.WriteString("<oc:checksum>")
+		checksums.WriteString(strings.ToUpper(string(storageprovider.GRPC2PKGXS(md.Checksum.Type))))
+		checksums.WriteString(":")
+		checksums.WriteString(md.Checksum.Sum)
+	}
+	if md.Opaque != nil {
+		for _, xs := range []string{storageprovider.XSMD5, storageprovider.XSAdler32, storageprovider.XSSHA256} {
+			if e, ok := md.Opaque.Map[xs]; ok {
+				if checksums.Len() == 0 {
+					checksums.WriteString("<oc:checksum>")
+				} else {
+					checksums.WriteString(" ")
+				}
+				checksums.WriteString(strings.ToUpper(xs))
+				checksums.WriteString(":")
+				checksums.WriteString(string(e.Value))
+			}
+		}
+	}
+	if checksums.Len() == 0 {
+		return ""
+	}
+	checksums.WriteString("</oc:checksum>")
+	return checksums.String()
+}
@@ -32,6 +32,7 @@ import (
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/pkg/errors"
 )
 
@@ -66,6 +67,7 @@ func (s *svc) handleProppatch(w http.ResponseWriter, r *http.Request, ns string)
 		Ref: &provider.Reference{
 			Spec: &provider.Reference_Path{Path: fn},
 		},
+		ArbitraryMetadataKeys: []string{storage.LockKey},
 	}
 	statRes, err := c.Stat(ctx, statReq)
 	if err != nil {
@@ -79,6 +81,11 @@ func (s *svc) handleProppatch(w http.ResponseWriter, r *http.Request, ns string)
 		return
 	}
 
+	if !lockSatisfied(ctx, r, statRes.Info) {
+		writeLocked(ctx, w, fn)
+		return
+	}
+
 	rreq := &provider.UnsetArbitraryMetadataRequest{
 		Ref: &provider.Reference{
 			Spec: &provider.Reference_Path{Path: fn},
@@ -111,6 +118,20 @@ func (s *svc) handleProppatch(w http.ResponseWriter, r *http.Request, ns string)
 					remove = true
 				}
 			}
+			// the favorites are kept by the favorite manager, if there is one
+			if key == _propOcFavorite && s.favorites != nil {
+				if err := s.setFavorite(ctx, statRes.Info, !remove); err != nil {
+					sublog.Error().Err(err).Bool("remove", remove).Msg("error setting the favorite")
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if remove {
+					removedProps = append(removedProps, propNameXML)
+				} else {
+					acceptedProps = append(acceptedProps, propNameXML)
+				}
+				continue
+			}
 			// Webdav spec requires the operations to be executed in the order
 			// specified in the PROPPATCH request
 			// http://www.webdav.org/specs/rfc2518.html#rfc.section.8.2
@@ -152,6 +173,10 @@ func (s *svc) handleProppatch(w http.ResponseWriter, r *http.Request, ns string)
 		// http://www.webdav.org/specs/rfc2518.html#rfc.section.8.2
 	}
 
+	if len(acceptedProps) > 0 || len(removedProps) > 0 {
+		s.recordChange(ctx, false, fn)
+	}
+
 	ref := strings.TrimPrefix(fn, ns)
 	ref = path.Join(ctx.Value(ctxKeyBaseURI).(string), ref)
 	if statRes.Info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
@@ -33,6 +33,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/utils"
 	"go.opencensus.io/trace"
@@ -159,7 +160,7 @@ func (s *svc) handlePutHelper(w http.ResponseWriter, r *http.Request, content io
 	ref := &provider.Reference{
 		Spec: &provider.Reference_Path{Path: fn},
 	}
-	sReq := &provider.StatRequest{Ref: ref}
+	sReq := &provider.StatRequest{Ref: ref, ArbitraryMetadataKeys: []string{storage.LockKey}}
 	sRes, err := client.Stat(ctx, sReq)
 	if err != nil {
 		sublog.Error().Err(err).Msg("error sending grpc stat request")
@@ -178,16 +179,16 @@ func (s *svc) handlePutHelper(w http.ResponseWriter, r *http.Request, content io
 			w.WriteHeader(http.StatusConflict)
 			return
 		}
-		clientETag := r.Header.Get("If-Match")
-		serverETag := info.Etag
-		if clientETag != "" {
-			if clientETag != serverETag {
-				sublog.Debug().Str("client-etag", clientETag).Str("server-etag", serverETag).Msg("etags mismatch")
-				w.WriteHeader(http.StatusPreconditionFailed)
-				return
-			}
+		if !lockSatisfied(ctx, r, info) {
+			writeLocked(ctx, w, fn)
+			return
 		}
 	}
+	if !preconditionsMet(r, info) {
+		sublog.Debug().Str("server-etag", info.GetEtag()).Msg("precondition failed")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
 
 	opaqueMap := map[string]*typespb.OpaqueEntry{
 		"Upload-Length": {
@@ -338,6 +339,7 @@ func (s *svc) handlePutHelper(w http.ResponseWriter, r *http.Request, content io
 	}
 
 	newInfo := sRes.Info
+	s.recordChange(ctx, false, newInfo.Path)
 
 	w.Header().Add("Content-Type", newInfo.MimeType)
 	w.Header().Set("ETag", newInfo.Etag)
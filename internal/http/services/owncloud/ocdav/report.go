@@ -22,14 +22,18 @@ import (
 	"encoding/xml"
 	"io"
 	"net/http"
+	"path"
 
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	searchpb "github.com/cs3org/reva/pkg/search/proto"
 )
 
 func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
-	// fn := path.Join(ns, r.URL.Path)
+	fn := path.Join(ns, r.URL.Path)
 
 	rep, status, err := readReport(r.Body)
 	if err != nil {
@@ -38,7 +42,15 @@ func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
 		return
 	}
 	if rep.SearchFiles != nil {
-		s.doSearchFiles(w, r, rep.SearchFiles)
+		s.doSearchFiles(w, r, rep.SearchFiles, ns, fn)
+		return
+	}
+	if rep.SyncCollection != nil {
+		s.doSyncCollection(w, r, rep.SyncCollection, ns, fn)
+		return
+	}
+	if rep.FilterFiles != nil {
+		s.doFilterFiles(w, r, rep.FilterFiles, ns, fn)
 		return
 	}
 
@@ -47,21 +59,55 @@ func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
-func (s *svc) doSearchFiles(w http.ResponseWriter, r *http.Request, sf *reportSearchFiles) {
+func (s *svc) doSearchFiles(w http.ResponseWriter, r *http.Request, sf *reportSearchFiles, ns, fn string) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
-	_, err := s.getClient()
+	if s.c.SearchSvc == "" {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	client, err := pool.GetSearchServiceClient(s.c.SearchSvc)
 	if err != nil {
-		log.Error().Err(err).Msg("error getting grpc client")
+		log.Error().Err(err).Msg("error getting search client")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	w.WriteHeader(http.StatusNotImplemented)
+
+	res, err := client.Search(ctx, &searchpb.SearchRequest{
+		Query:  sf.Search.Pattern,
+		Limit:  int32(sf.Search.Limit),
+		Offset: int32(sf.Search.Offset),
+		Scope:  fn,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending a grpc search request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(log, w, res.Status)
+		return
+	}
+
+	propRes, err := s.formatPropfind(ctx, &propfindXML{Prop: sf.Prop}, res.Infos, ns)
+	if err != nil {
+		log.Error().Err(err).Msg("error formatting search results")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("DAV", "1, 3, extended-mkcol")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(propRes)); err != nil {
+		log.Err(err).Msg("error writing response")
+	}
 }
 
 type report struct {
-	SearchFiles *reportSearchFiles
-	// FilterFiles TODO add this for tag based search
+	SearchFiles    *reportSearchFiles
+	SyncCollection *reportSyncCollection
+	// FilterFiles lists the favorites, TODO add this for tag based search
+	FilterFiles *reportFilterFiles
 }
 type reportSearchFiles struct {
 	XMLName xml.Name                `xml:"search-files"`
@@ -70,7 +116,7 @@ type reportSearchFiles struct {
 	Search  reportSearchFilesSearch `xml:"search"`
 }
 type reportSearchFilesSearch struct {
-	Pattern string `xml:"search"`
+	Pattern string `xml:"pattern"`
 	Limit   int    `xml:"limit"`
 	Offset  int    `xml:"offset"`
 }
@@ -97,6 +143,22 @@ func readReport(r io.Reader) (rep *report, status int, err error) {
 				}
 				rep.SearchFiles = &repSF
 			}
+			if v.Name.Local == "sync-collection" {
+				var repSC reportSyncCollection
+				err = decoder.DecodeElement(&repSC, &v)
+				if err != nil {
+					return nil, http.StatusBadRequest, err
+				}
+				rep.SyncCollection = &repSC
+			}
+			if v.Name.Local == "filter-files" {
+				var repFF reportFilterFiles
+				err = decoder.DecodeElement(&repFF, &v)
+				if err != nil {
+					return nil, http.StatusBadRequest, err
+				}
+				rep.FilterFiles = &repFF
+			}
 		}
 	}
 }
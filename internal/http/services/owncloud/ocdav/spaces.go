@@ -0,0 +1,180 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"net/http"
+	"path"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+)
+
+// SpacesHandler handles the space scoped trash and versions requests
+type SpacesHandler struct {
+	TrashbinHandler *TrashbinHandler
+	VersionsHandler *VersionsHandler
+}
+
+func (h *SpacesHandler) init(c *Config) error {
+	h.TrashbinHandler = new(TrashbinHandler)
+	if err := h.TrashbinHandler.init(c); err != nil {
+		return err
+	}
+	h.VersionsHandler = new(VersionsHandler)
+	return h.VersionsHandler.init(c)
+}
+
+// Handler handles requests
+// a space is identified by the id of its root, eg. /remote.php/dav/spaces/<spaceid>
+// the trash of a space can be listed with a PROPFIND to /remote.php/dav/spaces/<spaceid>/trash
+// the versions of a file in a space can be listed with a PROPFIND to /remote.php/dav/spaces/<spaceid>/versions/<fileid>
+func (h *SpacesHandler) Handler(s *svc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var spaceID string
+		spaceID, r.URL.Path = router.ShiftPath(r.URL.Path)
+		if spaceID == "" {
+			// listing spaces is not supported
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		root := unwrap(spaceID)
+		if root == nil {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		base := path.Join(ctx.Value(ctxKeyBaseURI).(string), spaceID)
+
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+		switch head {
+		case "trash":
+			ctx = context.WithValue(ctx, ctxKeyBaseURI, path.Join(base, "trash"))
+			h.handleTrash(w, r.WithContext(ctx), s, root)
+		case "versions":
+			ctx = context.WithValue(ctx, ctxKeyBaseURI, path.Join(base, "versions"))
+			h.handleVersions(w, r.WithContext(ctx), s, root)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// handleTrash lists, restores and purges the recycle items deleted inside a space.
+// deleted items are restored to the path given in the destination header, relative to
+// /remote.php/dav/spaces/<spaceid>, or to their original location if there is none.
+// a DELETE without key empties the trash of the space, the storage providers with
+// trash bins per space only let the managers of the space purge it.
+func (h *SpacesHandler) handleTrash(w http.ResponseWriter, r *http.Request, s *svc, root *provider.ResourceId) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if r.Method == http.MethodOptions {
+		s.handleOptions(w, r, "trashbin")
+		return
+	}
+
+	// make sure the space exists and the user can access it
+	if !h.statSpace(w, r, s, root) {
+		return
+	}
+
+	ref := &provider.Reference{
+		Spec: &provider.Reference_Id{Id: root},
+	}
+
+	var key string
+	key, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+	switch {
+	case key == "" && r.Method == "PROPFIND":
+		h.TrashbinHandler.doListRecycle(w, r, s, ref, ctx.Value(ctxKeyBaseURI).(string))
+	case key != "" && r.Method == "MOVE":
+		var dst string
+		if dstHeader := r.Header.Get("Destination"); dstHeader != "" {
+			var err error
+			dst, err = extractDestination(dstHeader, path.Dir(ctx.Value(ctxKeyBaseURI).(string)))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			dst = path.Clean(dst)
+		}
+		log.Debug().Str("key", key).Str("dst", dst).Msg("restore")
+		h.TrashbinHandler.doRestore(w, r, s, ref, dst, key, nil, http.StatusCreated)
+	case r.Method == "DELETE":
+		h.TrashbinHandler.doPurge(w, r, s, root.StorageId, key, root)
+	default:
+		http.Error(w, "501 Not implemented", http.StatusNotImplemented)
+	}
+}
+
+// handleVersions lists and restores the versions of a file inside a space.
+func (h *SpacesHandler) handleVersions(w http.ResponseWriter, r *http.Request, s *svc, root *provider.ResourceId) {
+	var fileID string
+	fileID, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+	rid := unwrap(fileID)
+	if rid == nil || rid.StorageId != root.StorageId {
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodOptions && !h.statSpace(w, r, s, root) {
+		return
+	}
+
+	h.VersionsHandler.Handler(s, rid).ServeHTTP(w, r)
+}
+
+// statSpace writes the error response and returns false if the space root can't be stated.
+func (h *SpacesHandler) statSpace(w http.ResponseWriter, r *http.Request, s *svc, root *provider.ResourceId) bool {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Interface("space", root).Logger()
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	res, err := client.Stat(ctx, &provider.StatRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Id{Id: root},
+		},
+	})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, res.Status)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,405 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/storage/changes"
+	changesregistry "github.com/cs3org/reva/pkg/storage/changes/registry"
+)
+
+// syncTokenPrefix makes URIs of the change journal tokens, as sync tokens must be.
+const syncTokenPrefix = _nsOwncloud + "/sync/"
+
+// https://tools.ietf.org/html/rfc6578#section-6.1
+type reportSyncCollection struct {
+	XMLName   xml.Name `xml:"DAV: sync-collection"`
+	SyncToken string   `xml:"DAV: sync-token"`
+	SyncLevel string   `xml:"DAV: sync-level"`
+	Limit     *struct {
+		NResults int `xml:"DAV: nresults"`
+	} `xml:"DAV: limit"`
+	Prop propfindProps `xml:"DAV: prop"`
+}
+
+// initJournal sets up the change journal and records the file events of the
+// event stream into it, if there is one.
+func (s *svc) initJournal() error {
+	f, ok := changesregistry.NewFuncs[s.c.ChangeJournal]
+	if !ok {
+		return errtypes.NotFound("ocdav: change journal not found: " + s.c.ChangeJournal)
+	}
+	j, err := f(s.c.ChangeJournals[s.c.ChangeJournal])
+	if err != nil {
+		return err
+	}
+	s.journal = j
+
+	if s.c.EventStream == "" {
+		return nil
+	}
+	nf, ok := eventsregistry.NewFuncs[s.c.EventStream]
+	if !ok {
+		return errtypes.NotFound("ocdav: event stream not found: " + s.c.EventStream)
+	}
+	stream, err := nf(s.c.EventStreams[s.c.EventStream])
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// every ocdav records the changes in its journal, hence no consumer group.
+	// The events carrying only ids are not resolved to paths and not recorded.
+	err = events.Consume(ctx, stream, "", func(ev events.Event) {
+		switch e := ev.(type) {
+		case *events.FileUploaded:
+			if e.Path != "" {
+				s.recordChange(ctx, false, e.Path)
+			}
+		case *events.FileDeleted:
+			if e.Path != "" {
+				s.recordChange(ctx, true, e.Path)
+			}
+		}
+	}, events.FileUploaded{}.Type(), events.FileDeleted{}.Type())
+	if err != nil {
+		cancel()
+		_ = stream.Close()
+		return err
+	}
+	s.stopEvents = func() {
+		cancel()
+		_ = stream.Close()
+	}
+	return nil
+}
+
+// recordChange records the changes made to the resources at the paths.
+func (s *svc) recordChange(ctx context.Context, deleted bool, fns ...string) {
+	if s.journal == nil {
+		return
+	}
+	cs := make([]changes.Change, 0, len(fns))
+	for _, fn := range fns {
+		cs = append(cs, changes.Change{Path: fn, Deleted: deleted})
+	}
+	if err := s.journal.Record(ctx, cs...); err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Strs("paths", fns).Msg("ocdav: error recording changes")
+	}
+}
+
+// syncToken returns the current sync token, if sync-collection reports are supported.
+func (s *svc) syncToken(ctx context.Context) (string, bool) {
+	if s.journal == nil {
+		return "", false
+	}
+	t, err := s.journal.Current(ctx)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("ocdav: error getting the current sync token")
+		return "", false
+	}
+	return syncTokenPrefix + t.String(), true
+}
+
+// syncEntry is a resource reported by a sync-collection report, deleted when
+// it has been removed.
+type syncEntry struct {
+	fn      string
+	deleted bool
+}
+
+// doSyncCollection answers a sync-collection report, see https://tools.ietf.org/html/rfc6578.
+// The initial synchronization lists the members of the collection, the
+// following ones report the members changed since the token from the change
+// journal. With sync-level 1 the changes below the members are reported as
+// changes of the members, their etags having changed.
+func (s *svc) doSyncCollection(w http.ResponseWriter, r *http.Request, sc *reportSyncCollection, ns, fn string) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	if s.journal == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	infinite := false
+	switch sc.SyncLevel {
+	case "1":
+	case "infinite":
+		infinite = true
+	default:
+		sublog.Debug().Str("sync-level", sc.SyncLevel).Msg("invalid sync-level")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	limit := s.c.SyncLimit
+	if sc.Limit != nil {
+		if sc.Limit.NResults <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if sc.Limit.NResults < limit {
+			limit = sc.Limit.NResults
+		}
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	pf := &propfindXML{Prop: sc.Prop}
+	metadataKeys := metadataKeysOf(pf)
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: fn}}
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if sRes.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, sRes.Status)
+		return
+	}
+	if sRes.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		writeDAVError(ctx, w, http.StatusForbidden, "supported-report")
+		return
+	}
+
+	var responses []*responseXML
+	var token changes.Token
+	truncated := false
+	if sc.SyncToken == "" {
+		if token, err = s.journal.Current(ctx); err != nil {
+			sublog.Error().Err(err).Msg("error getting the current sync token")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		infos, status, err := s.listMembers(ctx, client, fn, infinite, limit, metadataKeys)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error listing the members")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case status == nil:
+			// the members do not fit in the limit and the initial synchronization can't be continued
+			writeDAVError(ctx, w, http.StatusInsufficientStorage, "number-of-matches-within-limits")
+			return
+		case status.Code != rpc.Code_CODE_OK:
+			HandleErrorStatus(&sublog, w, status)
+			return
+		}
+		for _, md := range infos {
+			res, err := s.mdToPropResponse(ctx, pf, md, ns)
+			if err != nil {
+				sublog.Error().Err(err).Msg("error formatting sync-collection")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			responses = append(responses, res)
+		}
+	} else {
+		since, err := changes.ParseToken(strings.TrimPrefix(sc.SyncToken, syncTokenPrefix))
+		if err != nil || !strings.HasPrefix(sc.SyncToken, syncTokenPrefix) {
+			writeDAVError(ctx, w, http.StatusForbidden, "valid-sync-token")
+			return
+		}
+		var entries []syncEntry
+		entries, token, truncated, err = s.changedMembers(ctx, fn, since, infinite, limit)
+		switch {
+		case err == changes.ErrInvalidToken:
+			writeDAVError(ctx, w, http.StatusForbidden, "valid-sync-token")
+			return
+		case err != nil:
+			sublog.Error().Err(err).Msg("error reading the change journal")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, e := range entries {
+			res, err := s.syncResponse(ctx, client, pf, e, ns, metadataKeys)
+			if err != nil {
+				sublog.Error().Err(err).Str("member", e.fn).Msg("error formatting sync-collection")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			responses = append(responses, res)
+		}
+	}
+
+	baseURI := ctx.Value(ctxKeyBaseURI).(string)
+	if truncated {
+		// https://tools.ietf.org/html/rfc6578#section-3.6
+		responses = append(responses, &responseXML{
+			Href:   encodePath(path.Join(baseURI, strings.TrimPrefix(fn, ns)) + "/"),
+			Status: fmt.Sprintf("HTTP/1.1 %d %s", http.StatusInsufficientStorage, http.StatusText(http.StatusInsufficientStorage)),
+		})
+	}
+	responsesXML, err := xml.Marshal(&responses)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error formatting sync-collection")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	msg := multistatusStart + string(responsesXML)
+	msg += "<d:sync-token>" + string(s.xmlEscaped(syncTokenPrefix+token.String())) + "</d:sync-token>"
+	msg += multistatusEnd
+
+	w.Header().Set("DAV", "1, 3, extended-mkcol")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		sublog.Err(err).Msg("error writing response")
+	}
+}
+
+// listMembers returns the members of the collection, all the resources below
+// it when infinite is set. It returns a nil status when there are more than
+// limit of them.
+func (s *svc) listMembers(ctx context.Context, client gateway.GatewayAPIClient, fn string, infinite bool, limit int, metadataKeys []string) ([]*provider.ResourceInfo, *rpc.Status, error) {
+	var infos []*provider.ResourceInfo
+	stack := []string{fn}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		res, err := client.ListContainer(ctx, &provider.ListContainerRequest{
+			Ref:                   &provider.Reference{Spec: &provider.Reference_Path{Path: p}},
+			ArbitraryMetadataKeys: metadataKeys,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return nil, res.Status, nil
+		}
+		if len(infos)+len(res.Infos) > limit {
+			return nil, nil, nil
+		}
+		infos = append(infos, res.Infos...)
+		if !infinite {
+			break
+		}
+		for i := len(res.Infos) - 1; i >= 0; i-- {
+			if res.Infos[i].Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				stack = append(stack, res.Infos[i].Path)
+			}
+		}
+	}
+	return infos, &rpc.Status{Code: rpc.Code_CODE_OK}, nil
+}
+
+// changedMembers returns the members of the collection changed since the
+// token, at most limit of them, and the token to continue from. The members
+// changed by several changes are reported once, for their last change.
+func (s *svc) changedMembers(ctx context.Context, fn string, since changes.Token, infinite bool, limit int) ([]syncEntry, changes.Token, bool, error) {
+	// one more change tells if there are more than limit
+	cs, next, err := s.journal.Since(ctx, fn, since, limit+1)
+	if err != nil {
+		return nil, changes.Token{}, false, err
+	}
+	truncated := false
+	if len(cs) > limit {
+		cs = cs[:limit]
+		next = changes.Token{Epoch: since.Epoch, Seq: cs[limit-1].Seq}
+		truncated = true
+	}
+
+	var entries []syncEntry
+	index := map[string]int{}
+	for i, c := range cs {
+		rel := strings.Trim(strings.TrimPrefix(path.Clean(c.Path), path.Clean(fn)), "/")
+		if rel == "" {
+			// changes of the collection itself are not reported
+			continue
+		}
+		// the members whose etags changed with the change, and the changed one
+		var reported []syncEntry
+		segments := strings.Split(rel, "/")
+		if !infinite {
+			member := path.Join(fn, segments[0])
+			reported = append(reported, syncEntry{fn: member, deleted: c.Deleted && len(segments) == 1})
+		} else {
+			for j := range segments {
+				reported = append(reported, syncEntry{fn: path.Join(fn, path.Join(segments[:j+1]...)), deleted: c.Deleted && j == len(segments)-1})
+			}
+		}
+
+		added := 0
+		for _, e := range reported {
+			if _, ok := index[e.fn]; !ok {
+				added++
+			}
+		}
+		if len(entries)+added > limit && i > 0 {
+			// report the previous changes and continue from the last of them
+			next = changes.Token{Epoch: since.Epoch, Seq: cs[i-1].Seq}
+			truncated = true
+			break
+		}
+		for _, e := range reported {
+			if k, ok := index[e.fn]; ok {
+				entries[k] = e
+				continue
+			}
+			index[e.fn] = len(entries)
+			entries = append(entries, e)
+		}
+	}
+	return entries, next, truncated, nil
+}
+
+// syncResponse returns the response reporting a changed member, a 404 for the
+// removed ones.
+func (s *svc) syncResponse(ctx context.Context, client gateway.GatewayAPIClient, pf *propfindXML, e syncEntry, ns string, metadataKeys []string) (*responseXML, error) {
+	if !e.deleted {
+		res, err := client.Stat(ctx, &provider.StatRequest{
+			Ref:                   &provider.Reference{Spec: &provider.Reference_Path{Path: e.fn}},
+			ArbitraryMetadataKeys: metadataKeys,
+		})
+		if err != nil {
+			return nil, err
+		}
+		switch res.Status.Code {
+		case rpc.Code_CODE_OK:
+			return s.mdToPropResponse(ctx, pf, res.Info, ns)
+		case rpc.Code_CODE_NOT_FOUND:
+		default:
+			return nil, errtypes.InternalError(res.Status.Message)
+		}
+	}
+	baseURI := ctx.Value(ctxKeyBaseURI).(string)
+	return &responseXML{
+		Href:   encodePath(path.Join(baseURI, strings.TrimPrefix(e.fn, ns))),
+		Status: fmt.Sprintf("HTTP/1.1 %d %s", http.StatusNotFound, http.StatusText(http.StatusNotFound)),
+	}, nil
+}
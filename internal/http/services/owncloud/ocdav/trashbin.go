@@ -33,9 +33,11 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/storage"
 	ctxuser "github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/utils"
 	"go.opencensus.io/trace"
@@ -149,13 +151,6 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 
 	sublog := appctx.GetLogger(ctx).With().Logger()
 
-	pf, status, err := readPropfind(r.Body)
-	if err != nil {
-		sublog.Debug().Err(err).Msg("error reading propfind request")
-		w.WriteHeader(status)
-		return
-	}
-
 	gc, err := pool.GetGatewayServiceClient(s.c.GatewaySvc)
 	if err != nil {
 		// TODO(jfd) how do we make the user aware that some storages are not available?
@@ -177,28 +172,68 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 		return
 	}
 
-	// ask gateway for recycle items
-	// TODO(labkode): add Reference to ListRecycleRequest
-	getRecycleRes, err := gc.ListRecycle(ctx, &gateway.ListRecycleRequest{
-		Ref: &provider.Reference{
-			Spec: &provider.Reference_Path{
-				Path: getHomeRes.Path,
-			},
+	ref := &provider.Reference{
+		Spec: &provider.Reference_Path{
+			Path: getHomeRes.Path,
 		},
-	})
+	}
+	baseURI := path.Join(ctx.Value(ctxKeyBaseURI).(string), u.Username)
+	h.doListRecycle(w, r.WithContext(ctx), s, ref, baseURI)
+}
 
-	if err != nil {
-		sublog.Error().Err(err).Msg("error calling ListRecycle")
-		w.WriteHeader(http.StatusInternalServerError)
+// doListRecycle lists the recycle items of the storage referenced by ref. The
+// hrefs of the items are relative to baseURI.
+func (h *TrashbinHandler) doListRecycle(w http.ResponseWriter, r *http.Request, s *svc, ref *provider.Reference, baseURI string) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Interface("ref", ref).Logger()
+
+	depth := r.Header.Get("Depth")
+	if depth == "" {
+		depth = "1"
+	}
+	// see https://tools.ietf.org/html/rfc4918#section-9.1
+	// the trash bin is flat, so a Depth: infinity listing is the same as a Depth: 1 one
+	if depth != "0" && depth != "1" && depth != "infinity" {
+		sublog.Debug().Str("depth", depth).Msg("invalid Depth header value")
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if getRecycleRes.Status.Code != rpc.Code_CODE_OK {
-		HandleErrorStatus(&sublog, w, getRecycleRes.Status)
+	pf, status, err := readPropfind(r.Body)
+	if err != nil {
+		sublog.Debug().Err(err).Msg("error reading propfind request")
+		w.WriteHeader(status)
 		return
 	}
 
-	propRes, err := h.formatTrashPropfind(ctx, s, u, &pf, getRecycleRes.RecycleItems)
+	var items []*provider.RecycleItem
+	if depth != "0" {
+		gc, err := pool.GetGatewayServiceClient(s.c.GatewaySvc)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error getting gateway client")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// ask gateway for recycle items
+		getRecycleRes, err := gc.ListRecycle(ctx, &gateway.ListRecycleRequest{
+			Ref: ref,
+		})
+
+		if err != nil {
+			sublog.Error().Err(err).Msg("error calling ListRecycle")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if getRecycleRes.Status.Code != rpc.Code_CODE_OK {
+			HandleErrorStatus(&sublog, w, getRecycleRes.Status)
+			return
+		}
+		items = getRecycleRes.RecycleItems
+	}
+
+	propRes, err := h.formatTrashPropfind(ctx, s, baseURI, &pf, items)
 	if err != nil {
 		sublog.Error().Err(err).Msg("error formatting propfind")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -214,11 +249,11 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 	}
 }
 
-func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *userpb.User, pf *propfindXML, items []*provider.RecycleItem) (string, error) {
+func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, baseURI string, pf *propfindXML, items []*provider.RecycleItem) (string, error) {
 	responses := make([]*responseXML, 0, len(items)+1)
 	// add trashbin dir . entry
 	responses = append(responses, &responseXML{
-		Href: encodePath(ctx.Value(ctxKeyBaseURI).(string) + "/"), // url encode response.Href TODO
+		Href: encodePath(baseURI + "/"), // url encode response.Href TODO
 		Propstat: []propstatXML{
 			{
 				Status: "HTTP/1.1 200 OK",
@@ -239,7 +274,7 @@ func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *us
 	})
 
 	for i := range items {
-		res, err := h.itemToPropResponse(ctx, s, u, pf, items[i])
+		res, err := h.itemToPropResponse(ctx, s, baseURI, pf, items[i])
 		if err != nil {
 			return "", err
 		}
@@ -259,10 +294,9 @@ func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *us
 // itemToPropResponse needs to create a listing that contains a key and destination
 // the key is the name of an entry in the trash listing
 // for now we need to limit trash to the users home, so we can expect all trash keys to have the home storage as the opaque id
-func (h *TrashbinHandler) itemToPropResponse(ctx context.Context, s *svc, u *userpb.User, pf *propfindXML, item *provider.RecycleItem) (*responseXML, error) {
+func (h *TrashbinHandler) itemToPropResponse(ctx context.Context, s *svc, baseURI string, pf *propfindXML, item *provider.RecycleItem) (*responseXML, error) {
 
-	baseURI := ctx.Value(ctxKeyBaseURI).(string)
-	ref := path.Join(baseURI, u.Username, item.Key)
+	ref := path.Join(baseURI, item.Key)
 	if item.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 		ref += "/"
 	}
@@ -394,16 +428,103 @@ func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc
 		return
 	}
 
-	req := &provider.RestoreRecycleItemRequest{
-		// use the target path to find the storage provider
-		// this means we can only undelete on the same storage, not to a different folder
-		// use the key which is prefixed with the StoragePath to lookup the correct storage ...
-		// TODO currently limited to the home storage
-		Ref: &provider.Reference{
-			Spec: &provider.Reference_Path{
-				Path: getHomeRes.Path,
+	overwrite := strings.ToUpper(r.Header.Get("Overwrite"))
+	if overwrite == "" {
+		overwrite = "T"
+	}
+	if overwrite != "T" && overwrite != "F" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// check dst exists
+	dstRef := &provider.Reference{
+		Spec: &provider.Reference_Path{Path: path.Join(getHomeRes.Path, dst)},
+	}
+	dstStatRes, err := client.Stat(ctx, &provider.StatRequest{Ref: dstRef})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if dstStatRes.Status.Code != rpc.Code_CODE_OK && dstStatRes.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		HandleErrorStatus(&sublog, w, dstStatRes.Status)
+		return
+	}
+
+	successCode := http.StatusCreated // 201 if new resource was created, see https://tools.ietf.org/html/rfc4918#section-9.9.4
+	if dstStatRes.Status.Code == rpc.Code_CODE_OK {
+		successCode = http.StatusNoContent // 204 if target already existed, see https://tools.ietf.org/html/rfc4918#section-9.9.4
+
+		if overwrite == "F" {
+			sublog.Warn().Str("overwrite", overwrite).Msg("dst already exists")
+			w.WriteHeader(http.StatusPreconditionFailed) // 412, see https://tools.ietf.org/html/rfc4918#section-9.9.4
+			return
+		}
+
+		// delete existing tree, it ends up in the trash bin itself
+		delRes, err := client.Delete(ctx, &provider.DeleteRequest{Ref: dstRef})
+		if err != nil {
+			sublog.Error().Err(err).Msg("error sending grpc delete request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if delRes.Status.Code != rpc.Code_CODE_OK && delRes.Status.Code != rpc.Code_CODE_NOT_FOUND {
+			HandleErrorStatus(&sublog, w, delRes.Status)
+			return
+		}
+	} else {
+		// check if an intermediate path / the parent exists
+		intStatRes, err := client.Stat(ctx, &provider.StatRequest{
+			Ref: &provider.Reference{
+				Spec: &provider.Reference_Path{Path: path.Dir(dstRef.GetPath())},
 			},
+		})
+		if err != nil {
+			sublog.Error().Err(err).Msg("error sending grpc stat request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if intStatRes.Status.Code != rpc.Code_CODE_OK {
+			if intStatRes.Status.Code == rpc.Code_CODE_NOT_FOUND {
+				// 409 if intermediate dir is missing, see https://tools.ietf.org/html/rfc4918#section-9.8.5
+				sublog.Debug().Str("parent", path.Dir(dst)).Interface("status", intStatRes.Status).Msg("conflict")
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				HandleErrorStatus(&sublog, w, intStatRes.Status)
+			}
+			return
+		}
+	}
+
+	// use the target path to find the storage provider
+	// this means we can only undelete on the same storage, not to a different folder
+	// use the key which is prefixed with the StoragePath to lookup the correct storage ...
+	// TODO currently limited to the home storage
+	ref := &provider.Reference{
+		Spec: &provider.Reference_Path{
+			Path: getHomeRes.Path,
 		},
+	}
+	h.doRestore(w, r.WithContext(ctx), s, ref, dst, key, dstRef, successCode)
+}
+
+// doRestore restores the recycle item with the given key of the storage referenced by ref.
+// When the reference of the restored resource is known, its etag and id are sent back
+// in the same headers a MOVE sends.
+func (h *TrashbinHandler) doRestore(w http.ResponseWriter, r *http.Request, s *svc, ref *provider.Reference, dst, key string, dstRef *provider.Reference, successCode int) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Str("key", key).Logger()
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	req := &provider.RestoreRecycleItemRequest{
+		Ref:         ref,
 		Key:         key,
 		RestorePath: dst,
 	}
@@ -419,7 +540,26 @@ func (h *TrashbinHandler) restore(w http.ResponseWriter, r *http.Request, s *svc
 		HandleErrorStatus(&sublog, w, res.Status)
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
+
+	if dstRef != nil {
+		dstStatRes, err := client.Stat(ctx, &provider.StatRequest{Ref: dstRef})
+		if err != nil {
+			sublog.Error().Err(err).Msg("error sending grpc stat request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if dstStatRes.Status.Code != rpc.Code_CODE_OK {
+			HandleErrorStatus(&sublog, w, dstStatRes.Status)
+			return
+		}
+
+		info := dstStatRes.Info
+		w.Header().Set("Content-Type", info.MimeType)
+		w.Header().Set("ETag", info.Etag)
+		w.Header().Set("OC-FileId", wrapResourceID(info.Id))
+		w.Header().Set("OC-ETag", info.Etag)
+	}
+	w.WriteHeader(successCode)
 }
 
 // delete has only a key
@@ -464,6 +604,23 @@ func (h *TrashbinHandler) delete(w http.ResponseWriter, r *http.Request, s *svc,
 		return
 	}
 
+	h.doPurge(w, r.WithContext(ctx), s, sRes.Info.Id.StorageId, key, nil)
+}
+
+// doPurge purges the recycle item with the given key from the storage with the given id.
+// With a space root, the item is purged from the trash bin of the space, which is
+// emptied when there is no key.
+func (h *TrashbinHandler) doPurge(w http.ResponseWriter, r *http.Request, s *svc, storageID, key string, root *provider.ResourceId) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Str("key", key).Logger()
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	// set key as opaque id, the storageprovider will use it as the key for the
 	// storage drives  PurgeRecycleItem key call
 
@@ -472,11 +629,18 @@ func (h *TrashbinHandler) delete(w http.ResponseWriter, r *http.Request, s *svc,
 			Spec: &provider.Reference_Id{
 				Id: &provider.ResourceId{
 					OpaqueId:  key,
-					StorageId: sRes.Info.Id.StorageId,
+					StorageId: storageID,
 				},
 			},
 		},
 	}
+	if root != nil {
+		req.Opaque = &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				storage.RecycleRootOpaqueKey: {Decoder: "plain", Value: []byte(root.OpaqueId)},
+			},
+		}
+	}
 
 	res, err := client.PurgeRecycle(ctx, req)
 	if err != nil {
@@ -488,7 +652,7 @@ func (h *TrashbinHandler) delete(w http.ResponseWriter, r *http.Request, s *svc,
 	case rpc.Code_CODE_OK:
 		w.WriteHeader(http.StatusNoContent)
 	case rpc.Code_CODE_NOT_FOUND:
-		sublog.Debug().Str("storageid", sRes.Info.Id.StorageId).Str("key", key).Interface("status", res.Status).Msg("resource not found")
+		sublog.Debug().Str("storageid", storageID).Interface("status", res.Status).Msg("resource not found")
 		w.WriteHeader(http.StatusConflict)
 	default:
 		HandleErrorStatus(&sublog, w, res.Status)
@@ -40,7 +40,7 @@ func (s *svc) handleTusPost(w http.ResponseWriter, r *http.Request, ns string) {
 	ctx, span := trace.StartSpan(ctx, "tus-post")
 	defer span.End()
 
-	w.Header().Add("Access-Control-Allow-Headers", "Tus-Resumable, Upload-Length, Upload-Metadata, If-Match")
+	w.Header().Add("Access-Control-Allow-Headers", "Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Concat, If-Match")
 	w.Header().Add("Access-Control-Expose-Headers", "Tus-Resumable, Location")
 
 	w.Header().Set("Tus-Resumable", "1.0.0")
@@ -134,6 +134,16 @@ func (s *svc) handleTusPost(w http.ResponseWriter, r *http.Request, ns string) {
 		}
 	}
 
+	// partial uploads of the concatenation extension are finalized
+	// by a POST to the data gateway listing their upload urls
+	partial := r.Header.Get("Upload-Concat") == "partial"
+	if partial {
+		opaqueMap["Upload-Concat"] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte("partial"),
+		}
+	}
+
 	// initiateUpload
 	uReq := &provider.InitiateFileUploadRequest{
 		Ref: &provider.Reference{
@@ -223,7 +233,7 @@ func (s *svc) handleTusPost(w http.ResponseWriter, r *http.Request, ns string) {
 		}
 
 		// check if upload was fully completed
-		if length == 0 || httpRes.Header.Get("Upload-Offset") == r.Header.Get("Upload-Length") {
+		if !partial && (length == 0 || httpRes.Header.Get("Upload-Offset") == r.Header.Get("Upload-Length")) {
 			// get uploaded file metadata
 			sRes, err := client.Stat(ctx, sReq)
 			if err != nil {
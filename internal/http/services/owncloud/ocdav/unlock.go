@@ -20,9 +20,78 @@ package ocdav
 
 import (
 	"net/http"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"go.opencensus.io/trace"
 )
 
-// TODO(jfd): implement unlock
 func (s *svc) handleUnlock(w http.ResponseWriter, r *http.Request, ns string) {
-	w.WriteHeader(http.StatusNotImplemented)
+	ctx := r.Context()
+	ctx, span := trace.StartSpan(ctx, "unlock")
+	defer span.End()
+
+	fn := path.Join(ns, r.URL.Path)
+
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+
+	token := strings.TrimSuffix(strings.TrimPrefix(r.Header.Get("Lock-Token"), "<"), ">")
+	if token == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ref := &provider.Reference{
+		Spec: &provider.Reference_Path{Path: fn},
+	}
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref, ArbitraryMetadataKeys: []string{storage.LockKey}})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if sRes.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, sRes.Status)
+		return
+	}
+
+	l := storage.ActiveLock(sRes.Info)
+	if l == nil || l.Token != token {
+		// 409 when the token does not lock the resource, see https://tools.ietf.org/html/rfc4918#section-9.11.1
+		sublog.Debug().Str("token", token).Msg("resource not locked by token")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if u, ok := ctxuser.ContextGetUser(ctx); !ok || !utils.UserEqual(u.Id, l.User) {
+		writeLocked(ctx, w, fn)
+		return
+	}
+
+	res, err := client.UnsetArbitraryMetadata(ctx, &provider.UnsetArbitraryMetadataRequest{
+		Ref:                   ref,
+		ArbitraryMetadataKeys: []string{storage.LockKey},
+	})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc UnsetArbitraryMetadata request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, res.Status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
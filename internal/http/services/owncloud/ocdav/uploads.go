@@ -0,0 +1,484 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"go.opencensus.io/trace"
+)
+
+// uploadExpiry is the time after which the unfinished uploads of a user are
+// removed, when the user starts a new one.
+const uploadExpiry = 24 * time.Hour
+
+// uploadNameRegex matches the valid transfer ids and chunk names.
+var uploadNameRegex = regexp.MustCompile(`^[\w-][\w.-]*$`)
+
+// UploadsHandler implements the chunked uploads of the ownCloud chunking NG
+// protocol under /dav/uploads/<user>/<transfer id>. The chunks are kept in a
+// local folder until the client moves the .file of the upload to its
+// destination, which uploads the chunks one after the other as the file.
+type UploadsHandler struct {
+	folder    string
+	namespace string
+	quota     int64
+}
+
+func (h *UploadsHandler) init(c *Config) error {
+	h.folder = c.UploadsFolder
+	h.quota = c.UploadsUserQuota
+	h.namespace = path.Join("/", c.WebdavNamespace)
+	return os.MkdirAll(h.folder, 0700)
+}
+
+// Handler handles requests
+func (h *UploadsHandler) Handler(s *svc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx)
+
+		if r.Method == http.MethodOptions {
+			s.handleOptions(w, r, "uploads")
+			return
+		}
+
+		var username, id, chunk string
+		username, r.URL.Path = router.ShiftPath(r.URL.Path)
+		id, r.URL.Path = router.ShiftPath(r.URL.Path)
+		chunk, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		if username == "" || id == "" {
+			// listing is disabled, no auth will change that
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		u, ok := ctxuser.ContextGetUser(ctx)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !isOwner(username, u) {
+			log.Debug().Str("username", username).Interface("user", u).Msg("trying to access the uploads of another user")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if !uploadNameRegex.MatchString(id) || (chunk != "" && chunk != ".file" && !uploadNameRegex.MatchString(chunk)) || r.URL.Path != "/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		dir := filepath.Join(h.folder, fmt.Sprintf("%x", u.Id.OpaqueId), id)
+		switch {
+		case r.Method == "MKCOL" && chunk == "":
+			h.createUpload(w, r, dir)
+		case r.Method == "PROPFIND" && chunk == "":
+			h.listChunks(s, w, r, dir, username, id)
+		case r.Method == http.MethodDelete && chunk == "":
+			h.deleteUpload(w, r, dir)
+		case r.Method == http.MethodPut && chunk != "" && chunk != ".file":
+			h.putChunk(w, r, dir, chunk)
+		case r.Method == "MOVE" && chunk == ".file":
+			h.finishUpload(s, w, r, u, dir)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (h *UploadsHandler) createUpload(w http.ResponseWriter, r *http.Request, dir string) {
+	log := appctx.GetLogger(r.Context()).With().Str("upload", dir).Logger()
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		log.Error().Err(err).Msg("error creating the uploads folder")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	h.purgeExpired(r, filepath.Dir(dir))
+
+	if err := os.Mkdir(dir, 0700); err != nil {
+		if os.IsExist(err) {
+			w.WriteHeader(http.StatusMethodNotAllowed) // 405 if it already exists
+			return
+		}
+		log.Error().Err(err).Msg("error creating the upload folder")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// purgeExpired removes the uploads of the user which were not changed for uploadExpiry.
+func (h *UploadsHandler) purgeExpired(r *http.Request, userDir string) {
+	log := appctx.GetLogger(r.Context())
+	uploads, err := ioutil.ReadDir(userDir)
+	if err != nil {
+		log.Warn().Err(err).Str("folder", userDir).Msg("error listing the uploads")
+		return
+	}
+	for _, u := range uploads {
+		if time.Since(u.ModTime()) > uploadExpiry {
+			if err := os.RemoveAll(filepath.Join(userDir, u.Name())); err != nil {
+				log.Warn().Err(err).Str("upload", u.Name()).Msg("error removing expired upload")
+			}
+		}
+	}
+}
+
+func (h *UploadsHandler) deleteUpload(w http.ResponseWriter, r *http.Request, dir string) {
+	log := appctx.GetLogger(r.Context()).With().Str("upload", dir).Logger()
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Msg("error reading the upload folder")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Error().Err(err).Msg("error removing the upload folder")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadsHandler) putChunk(w http.ResponseWriter, r *http.Request, dir, chunk string) {
+	ctx, span := trace.StartSpan(r.Context(), "put-chunk")
+	defer span.End()
+	log := appctx.GetLogger(ctx).With().Str("upload", dir).Str("chunk", chunk).Logger()
+
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			// 409 if the upload has not been created, like for a missing parent
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		log.Error().Err(err).Msg("error reading the upload folder")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// the chunk replaced by the upload does not count against the quota
+	target := filepath.Join(dir, chunk)
+	fi, err := os.Stat(target)
+	existed := err == nil
+	used, err := usage(filepath.Dir(dir))
+	if err != nil {
+		log.Error().Err(err).Msg("error reading the uploads of the user")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if existed {
+		used -= fi.Size()
+	}
+	available := h.quota - used
+	if r.ContentLength > available {
+		h.writeQuotaExceeded(w, r, used)
+		return
+	}
+
+	// chunks are written next to the others and renamed once complete, so
+	// that incomplete chunks are never assembled
+	tmp, err := ioutil.TempFile(dir, ".chunk-")
+	if err != nil {
+		log.Error().Err(err).Msg("error creating the chunk")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	// the body is bounded as well, its length may not be known in advance
+	n, err := io.Copy(tmp, io.LimitReader(r.Body, available+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("error writing the chunk")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if n > available {
+		h.writeQuotaExceeded(w, r, used)
+		return
+	}
+	if r.ContentLength >= 0 && n != r.ContentLength {
+		log.Debug().Int64("content-length", r.ContentLength).Int64("written", n).Msg("incomplete chunk")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		log.Error().Err(err).Msg("error storing the chunk")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if existed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// usage returns the space taken by the uploads of the user, including the
+// chunks being written.
+func usage(userDir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(userDir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// removed by a concurrent request
+				return nil
+			}
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// writeQuotaExceeded answers the chunks which do not fit into the quota of the
+// user, see https://tools.ietf.org/html/rfc4918#section-11.5.
+func (h *UploadsHandler) writeQuotaExceeded(w http.ResponseWriter, r *http.Request, used int64) {
+	appctx.GetLogger(r.Context()).Debug().Int64("used", used).Int64("quota", h.quota).Msg("uploads quota exceeded")
+	writeDAVError(r.Context(), w, http.StatusInsufficientStorage, "quota-not-exceeded")
+}
+
+// chunks returns the complete chunks of the upload in the order they are
+// assembled, by offset or number when their names are numbers.
+func chunks(dir string) ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []os.FileInfo
+	numeric := true
+	for _, fi := range infos {
+		if strings.HasPrefix(fi.Name(), ".") || fi.IsDir() {
+			continue
+		}
+		if _, err := strconv.ParseUint(fi.Name(), 10, 64); err != nil {
+			numeric = false
+		}
+		chunks = append(chunks, fi)
+	}
+	if numeric {
+		sort.Slice(chunks, func(i, j int) bool {
+			a, _ := strconv.ParseUint(chunks[i].Name(), 10, 64)
+			b, _ := strconv.ParseUint(chunks[j].Name(), 10, 64)
+			return a < b
+		})
+	}
+	return chunks, nil
+}
+
+// listChunks answers the PROPFIND of the clients resuming an upload, which
+// only need to know the chunks already uploaded and their sizes.
+func (h *UploadsHandler) listChunks(s *svc, w http.ResponseWriter, r *http.Request, dir, username, id string) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx).With().Str("upload", dir).Logger()
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Msg("error reading the upload folder")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	base := path.Join(ctx.Value(ctxKeyBaseURI).(string), username, id)
+	responses := []*responseXML{{
+		Href: encodePath(base + "/"),
+		Propstat: []propstatXML{{
+			Status: "HTTP/1.1 200 OK",
+			Prop: []*propertyXML{
+				s.newPropRaw("d:resourcetype", "<d:collection/>"),
+				s.newProp("d:getlastmodified", fi.ModTime().UTC().Format(RFC1123)),
+			},
+		}},
+	}}
+	if r.Header.Get("Depth") != "0" {
+		cs, err := chunks(dir)
+		if err != nil {
+			log.Error().Err(err).Msg("error listing the chunks")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, c := range cs {
+			responses = append(responses, &responseXML{
+				Href: encodePath(path.Join(base, c.Name())),
+				Propstat: []propstatXML{{
+					Status: "HTTP/1.1 200 OK",
+					Prop: []*propertyXML{
+						s.newProp("d:resourcetype", ""),
+						s.newProp("d:getcontentlength", strconv.FormatInt(c.Size(), 10)),
+						s.newProp("d:getlastmodified", c.ModTime().UTC().Format(RFC1123)),
+					},
+				}},
+			})
+		}
+	}
+
+	responsesXML, err := xml.Marshal(&responses)
+	if err != nil {
+		log.Error().Err(err).Msg("error formatting propfind")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("DAV", "1, 3, extended-mkcol")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(multistatusStart + string(responsesXML) + multistatusEnd)); err != nil {
+		log.Err(err).Msg("error writing response")
+	}
+}
+
+// finishUpload uploads the chunks as the file at the destination of the move.
+// The checksum, mtime and etag headers of the move apply to the file like for
+// a PUT. The chunks are removed once the file has been uploaded.
+func (h *UploadsHandler) finishUpload(s *svc, w http.ResponseWriter, r *http.Request, u *userpb.User, dir string) {
+	ctx, span := trace.StartSpan(r.Context(), "finish-upload")
+	defer span.End()
+	log := appctx.GetLogger(ctx).With().Str("upload", dir).Logger()
+
+	// the destination is below /dav/files/<user>, next to /dav/uploads
+	filesBase := path.Join(path.Dir(ctx.Value(ctxKeyBaseURI).(string)), "files")
+	dst, err := extractDestination(r.Header.Get("Destination"), filesBase)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	owner, dst := router.ShiftPath(dst)
+	if !isOwner(owner, u) || dst == "/" {
+		log.Debug().Str("destination", r.Header.Get("Destination")).Msg("invalid upload destination")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fn := path.Join(templates.WithUser(u, h.namespace), dst)
+
+	cs, err := chunks(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Msg("error listing the chunks")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var length int64
+	paths := make([]string, 0, len(cs))
+	for _, c := range cs {
+		length += c.Size()
+		paths = append(paths, filepath.Join(dir, c.Name()))
+	}
+	if total := r.Header.Get("OC-Total-Length"); total != "" {
+		expected, err := strconv.ParseInt(total, 10, 64)
+		if err != nil || expected != length {
+			log.Debug().Str("oc-total-length", total).Int64("length", length).Msg("the chunks do not add up to the total length")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	content := &chunkReader{paths: paths}
+	defer content.Close()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.handlePutHelper(rec, r.WithContext(ctx), content, fn, length)
+
+	if rec.status < http.StatusMultipleChoices {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warn().Err(err).Msg("error removing the finished upload")
+		}
+	}
+}
+
+// chunkReader reads the chunks of an upload one after the other, keeping a
+// single one open.
+type chunkReader struct {
+	paths []string
+	f     *os.File
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if c.f == nil {
+			if len(c.paths) == 0 {
+				return 0, io.EOF
+			}
+			f, err := os.Open(c.paths[0])
+			if err != nil {
+				return 0, err
+			}
+			c.f, c.paths = f, c.paths[1:]
+		}
+		n, err := c.f.Read(p)
+		if err == io.EOF {
+			_ = c.f.Close()
+			c.f = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	if c.f == nil {
+		return nil
+	}
+	return c.f.Close()
+}
+
+// statusRecorder remembers the status written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
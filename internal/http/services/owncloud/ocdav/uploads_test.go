@@ -0,0 +1,185 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const uploadsURL = "/remote.php/dav/uploads/einstein/"
+
+// upload creates the upload and puts the chunks, given as name and content
+// pairs.
+func upload(t *testing.T, s *svc, id string, chunks ...string) {
+	t.Helper()
+	if w := do(s, einstein, "MKCOL", uploadsURL+id, nil); w.Code != http.StatusCreated {
+		t.Fatalf("MKCOL %s = %d, wanted 201", id, w.Code)
+	}
+	for i := 0; i+1 < len(chunks); i += 2 {
+		if w := do(s, einstein, http.MethodPut, uploadsURL+id+"/"+chunks[i], body(chunks[i+1])); w.Code != http.StatusCreated {
+			t.Fatalf("PUT of the chunk %s = %d, wanted 201", chunks[i], w.Code)
+		}
+	}
+}
+
+// finish moves the .file of the upload to the file of einstein.
+func finish(s *svc, id, fn string, headers ...string) int {
+	headers = append([]string{"Destination", "/remote.php/dav/files/einstein" + fn}, headers...)
+	return do(s, einstein, "MOVE", uploadsURL+id+"/.file", nil, headers...).Code
+}
+
+func TestUploadChunks(t *testing.T) {
+	g := newFakeGateway(t)
+	s := newTestService(t, g)
+
+	upload(t, s, "tx1", "1", "hello ")
+	if w := do(s, einstein, "MKCOL", uploadsURL+"tx1", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("MKCOL of an existing upload = %d, wanted 405", w.Code)
+	}
+	if w := do(s, einstein, http.MethodPut, uploadsURL+"missing/1", body("data")); w.Code != http.StatusConflict {
+		t.Errorf("PUT of a chunk without upload = %d, wanted 409", w.Code)
+	}
+	if w := do(s, einstein, http.MethodPut, uploadsURL+"tx1/1", body("hello ")); w.Code != http.StatusNoContent {
+		t.Errorf("PUT of an existing chunk = %d, wanted 204", w.Code)
+	}
+	if w := do(s, marie, "MKCOL", uploadsURL+"tx2", nil); w.Code != http.StatusForbidden {
+		t.Errorf("MKCOL in the uploads of another user = %d, wanted 403", w.Code)
+	}
+	if w := do(s, einstein, http.MethodPut, uploadsURL+"tx1/a%20b", body("data")); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT of an invalid chunk name = %d, wanted 400", w.Code)
+	}
+
+	if w := do(s, einstein, http.MethodDelete, uploadsURL+"tx1", nil); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE of the upload = %d, wanted 204", w.Code)
+	}
+	if w := do(s, einstein, http.MethodDelete, uploadsURL+"tx1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE of a missing upload = %d, wanted 404", w.Code)
+	}
+}
+
+func TestUploadResume(t *testing.T) {
+	g := newFakeGateway(t)
+	s := newTestService(t, g)
+	upload(t, s, "tx1", "1", "hello ", "2", "world")
+
+	w := do(s, einstein, "PROPFIND", uploadsURL+"tx1", nil, "Depth", "1")
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND = %d, wanted 207", w.Code)
+	}
+	for _, want := range []string{
+		"<d:href>/remote.php/dav/uploads/einstein/tx1/</d:href>",
+		"<d:href>/remote.php/dav/uploads/einstein/tx1/1</d:href>",
+		"<d:getcontentlength>6</d:getcontentlength>",
+		"<d:href>/remote.php/dav/uploads/einstein/tx1/2</d:href>",
+		"<d:getcontentlength>5</d:getcontentlength>",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("the PROPFIND response does not contain %s: %s", want, w.Body.String())
+		}
+	}
+
+	w = do(s, einstein, "PROPFIND", uploadsURL+"tx1", nil, "Depth", "0")
+	if w.Code != http.StatusMultiStatus || strings.Contains(w.Body.String(), "tx1/1") {
+		t.Errorf("PROPFIND with depth 0 = %d %s, wanted the upload only", w.Code, w.Body.String())
+	}
+	if w := do(s, einstein, "PROPFIND", uploadsURL+"missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("PROPFIND of a missing upload = %d, wanted 404", w.Code)
+	}
+}
+
+func TestUploadFinish(t *testing.T) {
+	g := newFakeGateway(t)
+	s := newTestService(t, g)
+
+	// numeric chunks are assembled by number, not by name
+	upload(t, s, "tx1", "10", "world", "9", "hello ")
+	if code := finish(s, "tx1", "/numeric.txt", "OC-Total-Length", "11"); code != http.StatusCreated {
+		t.Fatalf("MOVE of the .file = %d, wanted 201", code)
+	}
+	if f, ok := g.file("/home/numeric.txt"); !ok || string(f.content) != "hello world" {
+		t.Errorf("the numeric chunks were assembled as %q", f.content)
+	}
+	if _, err := os.Stat(filepath.Join(s.c.UploadsFolder, "65696e737465696e", "tx1")); !os.IsNotExist(err) {
+		t.Errorf("the finished upload was not removed: %v", err)
+	}
+
+	// other chunks are assembled by name
+	upload(t, s, "tx2", "chunk-b", "world", "chunk-a", "hello ", "10", "!")
+	if code := finish(s, "tx2", "/named.txt"); code != http.StatusCreated {
+		t.Fatalf("MOVE of the .file = %d, wanted 201", code)
+	}
+	if f, ok := g.file("/home/named.txt"); !ok || string(f.content) != "!hello world" {
+		t.Errorf("the named chunks were assembled as %q", f.content)
+	}
+
+	if code := finish(s, "tx2", "/named.txt"); code != http.StatusNotFound {
+		t.Errorf("MOVE of a finished upload = %d, wanted 404", code)
+	}
+	upload(t, s, "tx3", "1", "data")
+	if code := do(s, einstein, "MOVE", uploadsURL+"tx3/.file", nil, "Destination", "/remote.php/dav/files/marie/stolen.txt").Code; code != http.StatusBadRequest {
+		t.Errorf("MOVE to the files of another user = %d, wanted 400", code)
+	}
+}
+
+func TestUploadTotalLength(t *testing.T) {
+	g := newFakeGateway(t)
+	s := newTestService(t, g)
+	upload(t, s, "tx1", "1", "hello ", "2", "world")
+
+	for _, total := range []string{"12", "10", "eleven"} {
+		if code := finish(s, "tx1", "/file.txt", "OC-Total-Length", total); code != http.StatusBadRequest {
+			t.Errorf("MOVE with the total length %s = %d, wanted 400", total, code)
+		}
+	}
+	if _, ok := g.file("/home/file.txt"); ok {
+		t.Fatal("the file was uploaded despite the length mismatch")
+	}
+	// the chunks are kept, the upload can still be finished
+	if code := finish(s, "tx1", "/file.txt", "OC-Total-Length", "11"); code != http.StatusCreated {
+		t.Errorf("MOVE with the total length = %d, wanted 201", code)
+	}
+}
+
+func TestUploadQuota(t *testing.T) {
+	g := newFakeGateway(t)
+	s := newTestService(t, g)
+	s.davHandler.UploadsHandler.quota = 10
+	upload(t, s, "tx1", "1", "hello")
+
+	w := do(s, einstein, http.MethodPut, uploadsURL+"tx1/2", body("world!"))
+	if w.Code != http.StatusInsufficientStorage || !strings.Contains(w.Body.String(), "<d:quota-not-exceeded/>") {
+		t.Errorf("PUT of a chunk over the quota = %d %s, wanted 507", w.Code, w.Body.String())
+	}
+	// the quota spans the uploads of the user
+	upload(t, s, "tx2")
+	if w := do(s, einstein, http.MethodPut, uploadsURL+"tx2/1", body("world!")); w.Code != http.StatusInsufficientStorage {
+		t.Errorf("PUT of a chunk over the quota in another upload = %d, wanted 507", w.Code)
+	}
+	// replacing a chunk only counts the new one
+	if w := do(s, einstein, http.MethodPut, uploadsURL+"tx1/1", body("hello!")); w.Code != http.StatusNoContent {
+		t.Errorf("PUT replacing a chunk = %d, wanted 204", w.Code)
+	}
+	if w := do(s, einstein, http.MethodPut, uploadsURL+"tx1/2", body("four")); w.Code != http.StatusCreated {
+		t.Errorf("PUT of a chunk within the quota = %d, wanted 201", w.Code)
+	}
+}
@@ -20,14 +20,20 @@ package ocdav
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"path"
+	"strconv"
+	"time"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/storage"
 	"go.opencensus.io/trace"
 )
 
@@ -42,6 +48,7 @@ func (h *VersionsHandler) init(c *Config) error {
 // Handler handles requests
 // versions can be listed with a PROPFIND to /remote.php/dav/meta/<fileid>/v
 // a version is identified by a timestamp, eg. /remote.php/dav/meta/<fileid>/v/1561410426
+// and can be downloaded with a GET and restored with a COPY
 func (h *VersionsHandler) Handler(s *svc, rid *provider.ResourceId) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -66,8 +73,11 @@ func (h *VersionsHandler) Handler(s *svc, rid *provider.ResourceId) http.Handler
 			h.doListVersions(w, r, s, rid)
 			return
 		}
+		if key != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			h.doDownload(w, r, s, rid, key)
+			return
+		}
 		if key != "" && r.Method == "COPY" {
-			// TODO(jfd) it seems we cannot directly GET version content with cs3 ...
 			// TODO(jfd) cs3api has no delete file version call
 			// TODO(jfd) restore version to given Destination, but cs3api has no destination
 			h.doRestore(w, r, s, rid, key)
@@ -211,3 +221,127 @@ func (h *VersionsHandler) doRestore(w http.ResponseWriter, r *http.Request, s *s
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// doDownload sends the content of the version with the given key of the file.
+// The version is resolved to its file by path, and downloaded through the
+// data gateway like the file itself.
+func (h *VersionsHandler) doDownload(w http.ResponseWriter, r *http.Request, s *svc, rid *provider.ResourceId, key string) {
+	ctx := r.Context()
+	ctx, span := trace.StartSpan(ctx, "downloadVersion")
+	defer span.End()
+
+	sublog := appctx.GetLogger(ctx).With().Interface("resourceid", rid).Str("key", key).Logger()
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ref := &provider.Reference{
+		Spec: &provider.Reference_Id{Id: rid},
+	}
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if sRes.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, sRes.Status)
+		return
+	}
+	info := sRes.Info
+
+	lvRes, err := client.ListFileVersions(ctx, &provider.ListFileVersionsRequest{Ref: ref})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending list file versions grpc request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if lvRes.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, lvRes.Status)
+		return
+	}
+	var version *provider.FileVersion
+	for _, v := range lvRes.GetVersions() {
+		if v.Key == key {
+			version = v
+			break
+		}
+	}
+	if version == nil {
+		sublog.Debug().Msg("version not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", info.MimeType)
+	w.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+
+		path.Base(info.Path)+"; filename=\""+path.Base(info.Path)+"\"")
+	w.Header().Set("ETag", version.Etag)
+	w.Header().Set("OC-FileId", wrapResourceID(info.Id))
+	w.Header().Set("OC-ETag", version.Etag)
+	w.Header().Set("Last-Modified", time.Unix(int64(version.Mtime), 0).UTC().Format(time.RFC1123Z))
+	w.Header().Set("Content-Length", strconv.FormatUint(version.Size, 10))
+
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	dRes, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: info.Path},
+		},
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				storage.RevisionOpaqueKey: {Decoder: "plain", Value: []byte(key)},
+			},
+		},
+	})
+	if err != nil {
+		sublog.Error().Err(err).Msg("error initiating file download")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if dRes.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, dRes.Status)
+		return
+	}
+
+	var ep, token string
+	for _, p := range dRes.Protocols {
+		if p.Protocol == "simple" {
+			ep, token = p.DownloadEndpoint, p.Token
+		}
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, ep, nil)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error creating http request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error performing http request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusOK {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(httpRes.StatusCode)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, httpRes.Body); err != nil {
+		sublog.Error().Err(err).Msg("error finishing copying data to response")
+	}
+}
@@ -20,6 +20,7 @@ package config
 
 import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
+	"github.com/cs3org/reva/pkg/rhttp/bodylimit"
 	"github.com/cs3org/reva/pkg/sharedconf"
 )
 
@@ -38,6 +39,33 @@ type Config struct {
 	CacheWarmupDrivers      map[string]map[string]interface{} `mapstructure:"cache_warmup_drivers"`
 	ResourceInfoCacheSize   int                               `mapstructure:"resource_info_cache_size"`
 	ResourceInfoCacheTTL    int                               `mapstructure:"resource_info_cache_ttl"`
+	// StorageRegistrySvc is the address of the storage registry listing the
+	// providers whose features are announced in the capabilities.
+	StorageRegistrySvc string `mapstructure:"storage_registry_svc"`
+	// CapabilitiesDiscoveryTTL is the time in seconds the capabilities found
+	// by querying the services are cached. A negative value disables the
+	// discovery, the configured capabilities are then announced as they are.
+	CapabilitiesDiscoveryTTL int `mapstructure:"capabilities_discovery_ttl"`
+	// FeatureOverrides switches feature flags of the capabilities on or off,
+	// for everybody with the "*" key, for the members of a group with
+	// "group:<name>" or for a user with "user:<username>".
+	FeatureOverrides map[string]map[string]bool `mapstructure:"feature_overrides"`
+	// UserHomeTemplate is the template of the path of the homes of the users
+	// in the gateway namespace, e.g. /eos/user/{{substr 0 1 .Username}}/{{.Username}}.
+	// It is needed to set the quota of other users.
+	UserHomeTemplate string `mapstructure:"user_home_template"`
+	// FavoriteStorageDriver is the manager the favorites listed by the files
+	// app are read from, the same as the one of ocdav. The favorites can't be
+	// listed when it is not set.
+	FavoriteStorageDriver  string                            `mapstructure:"favorite_storage_driver"`
+	FavoriteStorageDrivers map[string]map[string]interface{} `mapstructure:"favorite_storage_drivers"`
+	// GuestInvitations enables the sharing with unknown mail addresses, for
+	// which guest accounts are created and invited.
+	GuestInvitations bool `mapstructure:"guest_invitations"`
+	// GuestsSvc is the address of the guests service inviting the guests.
+	GuestsSvc string `mapstructure:"guests_svc"`
+	// BodyLimit bounds the size of the request bodies.
+	BodyLimit bodylimit.Config `mapstructure:"body_limit"`
 }
 
 // Init sets sane defaults
@@ -66,5 +94,17 @@ func (c *Config) Init() {
 		c.ResourceInfoCacheSize = 1000000
 	}
 
+	if c.CapabilitiesDiscoveryTTL == 0 {
+		c.CapabilitiesDiscoveryTTL = 60
+	}
+
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+
+	if c.StorageRegistrySvc == "" {
+		c.StorageRegistrySvc = c.GatewaySvc
+	}
+
+	if c.GuestsSvc == "" {
+		c.GuestsSvc = c.GatewaySvc
+	}
 }
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/storage/utils/grants"
 	"github.com/cs3org/reva/pkg/user"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
@@ -90,6 +91,8 @@ type ShareData struct {
 	// The permission attribute set on the file.
 	// TODO(jfd) change the default to read only
 	Permissions Permissions `json:"permissions" xml:"permissions"`
+	// Deny tells whether the share denies the access to the resource instead of granting it.
+	Deny bool `json:"deny,omitempty" xml:"deny,omitempty"`
 	// The UNIX timestamp when the share was created.
 	STime uint64 `json:"stime" xml:"stime"`
 	// ?
@@ -139,6 +142,20 @@ type ShareData struct {
 	URL string `json:"url,omitempty" xml:"url,omitempty"`
 	// Attributes associated
 	Attributes string `json:"attributes,omitempty" xml:"attributes,omitempty"`
+	// MaxFileSize is the maximum size in bytes of a file uploaded to an upload only public share
+	MaxFileSize uint64 `json:"max_file_size,omitempty" xml:"max_file_size,omitempty"`
+	// NotifyUploads tells whether the creator of an upload only public share is notified about the uploads
+	NotifyUploads bool `json:"notify_uploads,omitempty" xml:"notify_uploads,omitempty"`
+	// AccessCount is the number of times a public share was opened
+	AccessCount uint64 `json:"access_count,omitempty" xml:"access_count,omitempty"`
+	// DownloadCount is the number of files downloaded through a public share
+	DownloadCount uint64 `json:"download_count,omitempty" xml:"download_count,omitempty"`
+	// BytesServed is the size in bytes of the files downloaded through a public share
+	BytesServed uint64 `json:"bytes_served,omitempty" xml:"bytes_served,omitempty"`
+	// LastAccess is the unix time of the last access to a public share
+	LastAccess int64 `json:"last_access,omitempty" xml:"last_access,omitempty"`
+	// MaxDownloads is the number of downloads after which a public share expires
+	MaxDownloads uint64 `json:"max_downloads,omitempty" xml:"max_downloads,omitempty"`
 	// PasswordProtected represents a public share is password protected
 	// PasswordProtected bool `json:"password_protected,omitempty" xml:"password_protected,omitempty"`
 }
@@ -193,6 +210,7 @@ func CS3Share2ShareData(ctx context.Context, share *collaboration.Share) (*Share
 	}
 	if share.GetPermissions() != nil && share.GetPermissions().GetPermissions() != nil {
 		sd.Permissions = RoleFromResourcePermissions(share.GetPermissions().GetPermissions()).OCSPermissions()
+		sd.Deny = grants.IsDeny(share.GetPermissions().GetPermissions())
 	}
 	if share.Ctime != nil {
 		sd.STime = share.Ctime.Seconds // TODO CS3 api birth time = btime
@@ -220,7 +238,7 @@ func PublicShare2ShareData(share *link.PublicShare, r *http.Request, publicURL s
 		sd.Permissions = RoleFromResourcePermissions(share.GetPermissions().GetPermissions()).OCSPermissions()
 	}
 	if share.Expiration != nil {
-		sd.Expiration = timestampToExpiration(share.Expiration)
+		sd.Expiration = TimestampToExpiration(share.Expiration)
 	}
 	if share.Ctime != nil {
 		sd.STime = share.Ctime.Seconds // TODO CS3 api birth time = btime
@@ -283,9 +301,10 @@ func GetPublicShareManager(manager string, m map[string]map[string]interface{})
 	return nil, fmt.Errorf("driver %s not found for public shares manager", manager)
 }
 
+// TimestampToExpiration formats the timestamp as an ocs expiry.
 // timestamp is assumed to be UTC ... just human readable ...
 // FIXME and ambiguous / error prone because there is no time zone ...
-func timestampToExpiration(t *types.Timestamp) string {
+func TimestampToExpiration(t *types.Timestamp) string {
 	return time.Unix(int64(t.Seconds), int64(t.Nanos)).UTC().Format("2006-01-02 15:05:05")
 }
 
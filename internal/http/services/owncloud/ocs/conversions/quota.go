@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package conversions
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var quotaUnits = map[string]float64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+	"p":  1 << 50,
+	"pb": 1 << 50,
+}
+
+// ParseQuota parses a quota the way ownCloud expresses them, a number of
+// bytes, a human readable size like "5 GB" using 1024 based units, or "none"
+// for no quota, which is returned as 0.
+func ParseQuota(v string) (uint64, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "none" || v == "unlimited" {
+		return 0, nil
+	}
+
+	i := strings.IndexFunc(v, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(v)
+	}
+	n, err := strconv.ParseFloat(v[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quota %q", v)
+	}
+	unit, ok := quotaUnits[strings.TrimSpace(v[i:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid quota unit in %q", v)
+	}
+	q := math.Round(n * unit)
+	if q >= math.MaxUint64 {
+		return 0, fmt.Errorf("quota %q is too large", v)
+	}
+	return uint64(q), nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package conversions
+
+import "testing"
+
+func TestParseQuota(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+		err  bool
+	}{
+		{"1024", 1024, false},
+		{"5 GB", 5 << 30, false},
+		{"1.5gb", 3 << 29, false},
+		{"10 KB", 10 << 10, false},
+		{"2T", 2 << 40, false},
+		{"none", 0, false},
+		{" Unlimited ", 0, false},
+		{"", 0, true},
+		{"GB", 0, true},
+		{"5 XB", 0, true},
+		{"-5 GB", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuota(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseQuota(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseQuota(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -24,6 +24,9 @@ import (
 	"strings"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/permissions"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/storage/utils/grants"
 )
 
 // Role describes the interface to transform different permission sets into each other
@@ -48,6 +51,8 @@ const (
 	RoleCoowner string = "coowner"
 	// RoleUploader FIXME: uploader role with only write permission can use InitiateFileUpload, not anything else
 	RoleUploader string = "uploader"
+	// RoleDenied denies any access to a resource, it wins over the roles granted by other shares
+	RoleDenied string = "denied"
 )
 
 // CS3ResourcePermissions for the role
@@ -125,14 +130,28 @@ func RoleFromName(name string) *Role {
 		return NewEditorRole()
 	case RoleFileEditor:
 		return NewFileEditorRole()
-	case RoleCoowner:
+	case RoleCoowner, permissions.RoleManager:
 		return NewCoownerRole()
 	case RoleUploader:
 		return NewUploaderRole()
+	case RoleDenied:
+		return NewDeniedRole()
+	}
+	if IsCustomRole(name) {
+		role, _ := permissions.Get(name)
+		r := RoleFromResourcePermissions(role.Permissions)
+		r.Name = role.Name
+		return r
 	}
 	return NewUnknownRole()
 }
 
+// IsCustomRole tells whether the name is the one of a role defined by the deployment
+func IsCustomRole(name string) bool {
+	_, ok := permissions.Get(name)
+	return ok && !permissions.IsBuiltin(name)
+}
+
 // NewUnknownRole creates an unknown role
 func NewUnknownRole() *Role {
 	return &Role{
@@ -145,53 +164,18 @@ func NewUnknownRole() *Role {
 // NewViewerRole creates a viewer role
 func NewViewerRole() *Role {
 	return &Role{
-		Name: RoleViewer,
-		cS3ResourcePermissions: &provider.ResourcePermissions{
-			// read
-			GetPath:              true,
-			GetQuota:             true,
-			InitiateFileDownload: true,
-			ListGrants:           true,
-			ListContainer:        true,
-			ListFileVersions:     true,
-			ListRecycle:          true,
-			Stat:                 true,
-		},
-		ocsPermissions: PermissionRead,
+		Name:                   RoleViewer,
+		cS3ResourcePermissions: permissions.Viewer(),
+		ocsPermissions:         PermissionRead,
 	}
 }
 
 // NewEditorRole creates an editor role
 func NewEditorRole() *Role {
 	return &Role{
-		Name: RoleEditor,
-		cS3ResourcePermissions: &provider.ResourcePermissions{
-			// read
-			GetPath:              true,
-			GetQuota:             true,
-			InitiateFileDownload: true,
-			ListGrants:           true,
-			ListContainer:        true,
-			ListFileVersions:     true,
-			ListRecycle:          true,
-			Stat:                 true,
-
-			// write
-			InitiateFileUpload: true,
-			RestoreFileVersion: true,
-			RestoreRecycleItem: true,
-
-			// create
-			CreateContainer: true,
-
-			// delete
-			Delete: true,
-
-			// not sure where to put these, but they are part of an editor
-			Move:         true,
-			PurgeRecycle: true,
-		},
-		ocsPermissions: PermissionRead | PermissionCreate | PermissionWrite | PermissionDelete,
+		Name:                   RoleEditor,
+		cS3ResourcePermissions: permissions.Editor(),
+		ocsPermissions:         PermissionRead | PermissionCreate | PermissionWrite | PermissionDelete,
 	}
 }
 
@@ -222,39 +206,9 @@ func NewFileEditorRole() *Role {
 // NewCoownerRole creates a coowner role
 func NewCoownerRole() *Role {
 	return &Role{
-		Name: RoleCoowner,
-		cS3ResourcePermissions: &provider.ResourcePermissions{
-			// read
-			GetPath:              true,
-			GetQuota:             true,
-			InitiateFileDownload: true,
-			ListGrants:           true,
-			ListContainer:        true,
-			ListFileVersions:     true,
-			ListRecycle:          true,
-			Stat:                 true,
-
-			// write
-			InitiateFileUpload: true,
-			RestoreFileVersion: true,
-			RestoreRecycleItem: true,
-
-			// create
-			CreateContainer: true,
-
-			// delete
-			Delete: true,
-
-			// not sure where to put these, but they are part of an editor
-			Move:         true,
-			PurgeRecycle: true,
-
-			// grants
-			AddGrant:    true,
-			UpdateGrant: true,
-			RemoveGrant: true,
-		},
-		ocsPermissions: PermissionAll,
+		Name:                   RoleCoowner,
+		cS3ResourcePermissions: permissions.Manager(),
+		ocsPermissions:         PermissionAll,
 	}
 }
 
@@ -278,6 +232,15 @@ func NewUploaderRole() *Role {
 	}
 }
 
+// NewDeniedRole creates a denied role
+func NewDeniedRole() *Role {
+	return &Role{
+		Name:                   RoleDenied,
+		cS3ResourcePermissions: &provider.ResourcePermissions{},
+		ocsPermissions:         PermissionInvalid,
+	}
+}
+
 // RoleFromOCSPermissions tries to map ocs permissions to a role
 func RoleFromOCSPermissions(p Permissions) *Role {
 	if p.Contain(PermissionRead) {
@@ -354,6 +317,16 @@ func RoleFromResourcePermissions(rp *provider.ResourcePermissions) *Role {
 	if rp == nil {
 		return r
 	}
+	if grants.IsDeny(rp) {
+		r.Name = RoleDenied
+		return r
+	}
+	if publicshare.IsUploadOnly(rp) {
+		// a file drop, its contents are not revealed
+		r.Name = RoleUploader
+		r.ocsPermissions = PermissionCreate
+		return r
+	}
 	if rp.ListContainer &&
 		rp.ListGrants &&
 		rp.ListFileVersions &&
@@ -384,6 +357,15 @@ func RoleFromResourcePermissions(rp *provider.ResourcePermissions) *Role {
 		rp.UpdateGrant {
 		r.ocsPermissions |= PermissionShare
 	}
+	if role, ok := permissions.FromResourcePermissions(rp); ok && !permissions.IsBuiltin(role.Name) {
+		// a role defined by the deployment, clients need the read permission to
+		// show a resource that can be listed at all
+		r.Name = role.Name
+		if rp.Stat {
+			r.ocsPermissions |= PermissionRead
+		}
+		return r
+	}
 	if r.ocsPermissions.Contain(PermissionRead) {
 		if r.ocsPermissions.Contain(PermissionWrite) && r.ocsPermissions.Contain(PermissionCreate) && r.ocsPermissions.Contain(PermissionDelete) {
 			r.Name = RoleEditor
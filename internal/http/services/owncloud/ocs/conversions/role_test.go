@@ -0,0 +1,69 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package conversions
+
+import (
+	"testing"
+
+	"github.com/cs3org/reva/pkg/permissions"
+)
+
+func TestCustomRoles(t *testing.T) {
+	if err := permissions.Configure(map[string]interface{}{
+		"no-download-viewer": map[string]interface{}{"base": "viewer", "exclude": []string{"initiate_file_download"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := permissions.Configure(nil); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	r := RoleFromName("no-download-viewer")
+	if r.Name != "no-download-viewer" || r.OCSPermissions() != PermissionRead {
+		t.Fatalf("unexpected role %s with ocs permissions %d", r.Name, r.OCSPermissions())
+	}
+	if r.CS3ResourcePermissions().InitiateFileDownload {
+		t.Error("the custom role should not grant downloads")
+	}
+	if got := RoleFromResourcePermissions(r.CS3ResourcePermissions()).Name; got != "no-download-viewer" {
+		t.Errorf("the permissions of the custom role should map back to it, got %s", got)
+	}
+
+	if got := RoleFromName(permissions.RoleManager).Name; got != RoleCoowner {
+		t.Errorf("the manager role should be the coowner one, got %s", got)
+	}
+	if got := RoleFromResourcePermissions(NewViewerRole().CS3ResourcePermissions()).Name; got != RoleViewer {
+		t.Errorf("the built-in roles should keep their names, got %s", got)
+	}
+}
+
+func TestDeniedRole(t *testing.T) {
+	r := RoleFromName(RoleDenied)
+	if r.OCSPermissions() != PermissionInvalid {
+		t.Errorf("the denied role should not have ocs permissions, got %d", r.OCSPermissions())
+	}
+	if got := RoleFromResourcePermissions(r.CS3ResourcePermissions()).Name; got != RoleDenied {
+		t.Errorf("empty permissions should map to the denied role, got %s", got)
+	}
+	if got := RoleFromResourcePermissions(nil).Name; got != RoleUnknown {
+		t.Errorf("missing permissions should not deny, got %s", got)
+	}
+}
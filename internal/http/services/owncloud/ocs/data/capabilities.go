@@ -42,6 +42,11 @@ func (c ocsBool) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return e.EncodeElement("0", start)
 }
 
+// Set switches the flag on or off.
+func (c *ocsBool) Set(b bool) {
+	*c = ocsBool(b)
+}
+
 // CapabilitiesData TODO document
 type CapabilitiesData struct {
 	Capabilities *Capabilities `json:"capabilities" xml:"capabilities"`
@@ -0,0 +1,315 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package appprovider
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+
+	registry "github.com/cs3org/go-cs3apis/cs3/app/registry/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/app"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+)
+
+// Handler implements the app provider endpoints, listing the apps of the mime
+// types, opening files in them and choosing the default apps of the user
+type Handler struct {
+	gatewayAddr   string
+	homeNamespace string
+}
+
+// MimeType holds the apps the files of a mime type can be opened with
+type MimeType struct {
+	MimeType   string `json:"mime_type" xml:"mime_type"`
+	DefaultApp string `json:"default_app,omitempty" xml:"default_app,omitempty"`
+	Apps       []*App `json:"app_providers" xml:"app_providers>element"`
+}
+
+// App describes an app
+type App struct {
+	Name        string `json:"name" xml:"name"`
+	Description string `json:"description,omitempty" xml:"description,omitempty"`
+	Icon        string `json:"icon,omitempty" xml:"icon,omitempty"`
+}
+
+// OpenInApp holds the url a file is opened at
+type OpenInApp struct {
+	AppURL string `json:"app_url" xml:"app_url"`
+}
+
+var viewModes = map[string]gateway.OpenInAppRequest_ViewMode{
+	"view":  gateway.OpenInAppRequest_VIEW_MODE_VIEW_ONLY,
+	"read":  gateway.OpenInAppRequest_VIEW_MODE_READ_ONLY,
+	"write": gateway.OpenInAppRequest_VIEW_MODE_READ_WRITE,
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) {
+	h.gatewayAddr = c.GatewaySvc
+	h.homeNamespace = c.HomeNamespace
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var head string
+	head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+	switch {
+	case head == "list" && r.Method == http.MethodGet:
+		h.list(w, r)
+	case head == "open" && r.Method == http.MethodPost:
+		h.open(w, r)
+	case head == "default" && r.Method == http.MethodPut:
+		h.setDefault(w, r)
+	case head == "default" && r.Method == http.MethodDelete:
+		h.unsetDefault(w, r)
+	default:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "Not found", nil)
+	}
+}
+
+// list returns the mime types with the apps they can be opened with and the default app, the one of the user first
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := client.ListAppProviders(ctx, &registry.ListAppProvidersRequest{})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc list app providers request", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+
+	defaults, err := app.DecodeDefaultApps(res.Opaque.GetMap()[app.DefaultAppsKey].GetValue())
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error decoding the default apps", err)
+		return
+	}
+	userDefaults, err := h.getUserDefaults(ctx, client)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error reading the default apps of the user", err)
+		return
+	}
+	for m, name := range userDefaults {
+		defaults[m] = name
+	}
+
+	mimeTypes := map[string]*MimeType{}
+	for _, p := range res.Providers {
+		name := app.ProviderName(p)
+		if name == "" {
+			// the providers of the mime type rules have no name to be chosen by
+			continue
+		}
+		for _, m := range p.MimeTypes {
+			mt, ok := mimeTypes[m]
+			if !ok {
+				mt = &MimeType{MimeType: m, DefaultApp: defaults[m]}
+				mimeTypes[m] = mt
+			}
+			mt.Apps = append(mt.Apps, &App{
+				Name:        name,
+				Description: p.Description,
+				Icon:        app.ProviderIcon(p),
+			})
+		}
+	}
+
+	list := make([]*MimeType, 0, len(mimeTypes))
+	for _, mt := range mimeTypes {
+		list = append(list, mt)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].MimeType < list[j].MimeType })
+	response.WriteOCSSuccess(w, r, list)
+}
+
+// open opens the file at the path given in the form, relative to the home, in an app
+func (h *Handler) open(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	p := r.FormValue("path")
+	if p == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "path missing", nil)
+		return
+	}
+	vm := gateway.OpenInAppRequest_VIEW_MODE_INVALID
+	if v := r.FormValue("view_mode"); v != "" {
+		var ok bool
+		if vm, ok = viewModes[v]; !ok {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "view_mode must be one of view, read or write", nil)
+			return
+		}
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := client.OpenInApp(ctx, &gateway.OpenInAppRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: path.Join(h.homeNamespace, p)},
+		},
+		ViewMode: vm,
+		App:      r.FormValue("app_name"),
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc open in app request", err)
+		return
+	}
+
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, res.Status.Message, nil)
+		return
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, res.Status.Message, nil)
+		return
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+
+	appctx.GetLogger(ctx).Debug().Str("path", p).Str("app_url", res.AppUrl).Msg("opened in app")
+	response.WriteOCSSuccess(w, r, &OpenInApp{AppURL: res.AppUrl})
+}
+
+// setDefault makes the app given in the form the default one of the user for a mime type
+func (h *Handler) setDefault(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	mimeType, name := r.FormValue("mime_type"), r.FormValue("app_name")
+	if mimeType == "" || name == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "mime_type and app_name are required", nil)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := client.GetAppProviders(ctx, &registry.GetAppProvidersRequest{
+		ResourceInfo: &provider.ResourceInfo{MimeType: mimeType},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc get app providers request", err)
+		return
+	}
+	found := false
+	if res.Status.Code == rpc.Code_CODE_OK {
+		for _, p := range res.Providers {
+			if app.ProviderName(p) == name {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "the app can't open files of mime type "+mimeType, nil)
+		return
+	}
+
+	h.updateUserDefaults(w, r, client, func(defaults map[string]string) {
+		defaults[mimeType] = name
+	})
+}
+
+// unsetDefault removes the default app of the user for the mime type given in the query
+func (h *Handler) unsetDefault(w http.ResponseWriter, r *http.Request) {
+	mimeType := r.URL.Query().Get("mime_type")
+	if mimeType == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "mime_type missing", nil)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	h.updateUserDefaults(w, r, client, func(defaults map[string]string) {
+		delete(defaults, mimeType)
+	})
+}
+
+func (h *Handler) updateUserDefaults(w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, update func(map[string]string)) {
+	ctx := r.Context()
+
+	defaults, err := h.getUserDefaults(ctx, client)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error reading the default apps of the user", err)
+		return
+	}
+	update(defaults)
+
+	val, err := app.EncodeDefaultApps(defaults)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error encoding the default apps of the user", err)
+		return
+	}
+	res, err := client.SetKey(ctx, &preferences.SetKeyRequest{Key: app.DefaultAppsKey, Val: string(val)})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc set key request", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// getUserDefaults returns the default apps the user chose for the mime types
+func (h *Handler) getUserDefaults(ctx context.Context, client gateway.GatewayAPIClient) (map[string]string, error) {
+	res, err := client.GetKey(ctx, &preferences.GetKeyRequest{Key: app.DefaultAppsKey})
+	if err != nil {
+		return nil, err
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return app.DecodeDefaultApps([]byte(res.Val))
+	case rpc.Code_CODE_NOT_FOUND:
+		return map[string]string{}, nil
+	default:
+		return nil, status.NewErrorFromCode(res.Status.Code, "ocs")
+	}
+}
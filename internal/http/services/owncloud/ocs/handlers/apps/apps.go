@@ -22,6 +22,8 @@ import (
 	"net/http"
 
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/appprovider"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/files"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/notifications"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/sharing"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
@@ -32,12 +34,21 @@ import (
 type Handler struct {
 	SharingHandler       *sharing.Handler
 	NotificationsHandler *notifications.Handler
+	FilesHandler         *files.Handler
+	AppProviderHandler   *appprovider.Handler
 }
 
 // Init initializes this and any contained handlers
 func (h *Handler) Init(c *config.Config) error {
 	h.SharingHandler = new(sharing.Handler)
 	h.NotificationsHandler = new(notifications.Handler)
+	h.NotificationsHandler.Init(c)
+	h.FilesHandler = new(files.Handler)
+	if err := h.FilesHandler.Init(c); err != nil {
+		return err
+	}
+	h.AppProviderHandler = new(appprovider.Handler)
+	h.AppProviderHandler.Init(c)
 	return h.SharingHandler.Init(c)
 }
 
@@ -66,6 +77,26 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "Not found", nil)
+	case "files":
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+		if head == "api" {
+			head, r.URL.Path = router.ShiftPath(r.URL.Path)
+			if head == "v1" {
+				h.FilesHandler.ServeHTTP(w, r)
+				return
+			}
+		}
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "Not found", nil)
+	case "app_provider":
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+		if head == "api" {
+			head, r.URL.Path = router.ShiftPath(r.URL.Path)
+			if head == "v1" {
+				h.AppProviderHandler.ServeHTTP(w, r)
+				return
+			}
+		}
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "Not found", nil)
 	default:
 		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "Not found", nil)
 	}
@@ -0,0 +1,116 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package files
+
+import (
+	"encoding/base64"
+	"net/http"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+// Favorite holds a favorite resource of the current user
+type Favorite struct {
+	// ID is the file id, encoded like the oc:fileid property of the webdav endpoints
+	ID       string `json:"id" xml:"id"`
+	Path     string `json:"path" xml:"path"`
+	Name     string `json:"name" xml:"name"`
+	Type     string `json:"type" xml:"type"`
+	MimeType string `json:"mimetype" xml:"mimetype"`
+	Size     uint64 `json:"size" xml:"size"`
+	Etag     string `json:"etag" xml:"etag"`
+	Mtime    uint64 `json:"mtime" xml:"mtime"`
+}
+
+// listFavorites returns the favorites of the user which still exist, the paths
+// of the ones in the home relative to it
+func (h *Handler) listFavorites(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	if h.favorites == nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "favorites not supported", nil)
+		return
+	}
+	u, ok := ctxuser.ContextGetUser(ctx)
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, "user missing", nil)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	ids, err := h.favorites.ListFavorites(ctx, u.Id)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error listing the favorites", err)
+		return
+	}
+
+	favorites := make([]*Favorite, 0, len(ids))
+	for _, id := range ids {
+		statRes, err := client.Stat(ctx, &provider.StatRequest{
+			Ref: &provider.Reference{Spec: &provider.Reference_Id{Id: id}},
+		})
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc stat request", err)
+			return
+		}
+		if statRes.Status.Code != rpc.Code_CODE_OK {
+			// the favorite was deleted or is no longer accessible
+			log.Debug().Interface("id", id).Interface("status", statRes.Status).Msg("skipping favorite")
+			continue
+		}
+
+		info := statRes.Info
+		p := info.Path
+		if strings.HasPrefix(p, h.homeNamespace+"/") {
+			p = strings.TrimPrefix(p, h.homeNamespace)
+		}
+		typ := "file"
+		if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			typ = "folder"
+		}
+		fav := &Favorite{
+			ID:       base64.URLEncoding.EncodeToString([]byte(info.Id.StorageId + ":" + info.Id.OpaqueId)),
+			Path:     p,
+			Name:     path.Base(info.Path),
+			Type:     typ,
+			MimeType: info.MimeType,
+			Size:     info.Size,
+			Etag:     info.Etag,
+		}
+		if info.Mtime != nil {
+			fav.Mtime = info.Mtime.Seconds
+		}
+		favorites = append(favorites, fav)
+	}
+
+	response.WriteOCSSuccess(w, r, favorites)
+}
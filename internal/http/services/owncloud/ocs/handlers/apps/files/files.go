@@ -0,0 +1,166 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package files
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/favorite"
+	favoriteregistry "github.com/cs3org/reva/pkg/favorite/manager/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// Handler implements the files app endpoints
+type Handler struct {
+	gatewayAddr   string
+	homeNamespace string
+	favorites     favorite.Manager
+}
+
+// Size holds the recursive size of a folder
+type Size struct {
+	Path  string `json:"path" xml:"path"`
+	Size  uint64 `json:"size" xml:"size"`
+	Files uint64 `json:"files" xml:"files"`
+}
+
+// Pin holds the pin state of a file for the current user
+type Pin struct {
+	Path        string `json:"path" xml:"path"`
+	Pinned      bool   `json:"pinned" xml:"pinned"`
+	AdminPinned bool   `json:"admin_pinned" xml:"admin_pinned"`
+	PinnedBytes uint64 `json:"pinned_bytes" xml:"pinned_bytes"`
+	PinQuota    uint64 `json:"pin_quota" xml:"pin_quota"`
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) error {
+	h.gatewayAddr = c.GatewaySvc
+	h.homeNamespace = c.HomeNamespace
+
+	if c.FavoriteStorageDriver != "" {
+		f, ok := favoriteregistry.NewFuncs[c.FavoriteStorageDriver]
+		if !ok {
+			return errtypes.NotFound("ocs: favorite manager not found: " + c.FavoriteStorageDriver)
+		}
+		m, err := f(c.FavoriteStorageDrivers[c.FavoriteStorageDriver])
+		if err != nil {
+			return err
+		}
+		h.favorites = m
+	}
+	return nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var head string
+	head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+	switch {
+	case head == "size" && r.Method == http.MethodGet:
+		h.getSize(w, r)
+	case head == "pin" && r.Method == http.MethodGet:
+		h.getPin(w, r)
+	case head == "pin" && r.Method == http.MethodPost:
+		h.pin(w, r)
+	case head == "pin" && r.Method == http.MethodDelete:
+		h.unpin(w, r)
+	case head == "quota" && r.Method == http.MethodPut:
+		h.setQuota(w, r)
+	case head == "favorites" && r.Method == http.MethodGet:
+		h.listFavorites(w, r)
+	default:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "Not found", nil)
+	}
+}
+
+// getSize returns the size and number of files below the path given in the query, relative to the home
+func (h *Handler) getSize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "path missing", nil)
+		return
+	}
+	fn := path.Join(h.homeNamespace, p)
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	statRes, err := client.Stat(ctx, &provider.StatRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: fn},
+		},
+		ArbitraryMetadataKeys: []string{storage.RecursiveSizeKey},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc stat request", err)
+		return
+	}
+
+	switch statRes.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "path not found", nil)
+		return
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, statRes.Status.Message, nil)
+		return
+	}
+
+	size, err := getOpaqueUint(statRes.Info, storage.RecursiveSizeKey)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error reading recursive size", err)
+		return
+	}
+	files, err := getOpaqueUint(statRes.Info, storage.RecursiveFileCountKey)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error reading recursive file count", err)
+		return
+	}
+
+	log.Debug().Str("path", fn).Uint64("size", size).Uint64("files", files).Msg("recursive size")
+	response.WriteOCSSuccess(w, r, &Size{
+		Path:  p,
+		Size:  size,
+		Files: files,
+	})
+}
+
+func getOpaqueUint(info *provider.ResourceInfo, key string) (uint64, error) {
+	if info.Opaque == nil || info.Opaque.Map == nil || info.Opaque.Map[key] == nil {
+		return 0, errtypes.InternalError("storage provider did not return " + key)
+	}
+	return strconv.ParseUint(string(info.Opaque.Map[key].Value), 10, 64)
+}
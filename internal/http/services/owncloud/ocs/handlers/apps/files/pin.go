@@ -0,0 +1,179 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package files
+
+import (
+	"net/http"
+	"path"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+// pinRef returns the reference to the file given in the query, relative to the home
+func (h *Handler) pinRef(w http.ResponseWriter, r *http.Request) (string, *provider.Reference, bool) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "path missing", nil)
+		return "", nil, false
+	}
+	return p, &provider.Reference{
+		Spec: &provider.Reference_Path{Path: path.Join(h.homeNamespace, p)},
+	}, true
+}
+
+// pinKey returns the metadata key the pin is set with, scope=admin addresses the admin pin
+func pinKey(r *http.Request) string {
+	if r.URL.Query().Get("scope") == "admin" {
+		return storage.AdminPinKey
+	}
+	return storage.PinKey
+}
+
+func writePinStatus(w http.ResponseWriter, r *http.Request, s *rpc.Status) bool {
+	switch s.Code {
+	case rpc.Code_CODE_OK:
+		return true
+	case rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "path not found", nil)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, s.Message, nil)
+	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "pin quota exceeded", nil)
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "pinning not supported", nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, s.Message, nil)
+	}
+	return false
+}
+
+// getPin returns whether the file given in the query is pinned and the bytes pinned by the user
+func (h *Handler) getPin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	p, ref, ok := h.pinRef(w, r)
+	if !ok {
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	statRes, err := client.Stat(ctx, &provider.StatRequest{
+		Ref:                   ref,
+		ArbitraryMetadataKeys: []string{storage.PinKey, storage.AdminPinKey},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc stat request", err)
+		return
+	}
+	if !writePinStatus(w, r, statRes.Status) {
+		return
+	}
+
+	pinned, err := getOpaqueUint(statRes.Info, storage.PinnedBytesKey)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "pinning not supported", err)
+		return
+	}
+	quota, err := getOpaqueUint(statRes.Info, storage.PinQuotaKey)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error reading pin quota", err)
+		return
+	}
+
+	md := statRes.Info.GetArbitraryMetadata().GetMetadata()
+	pin := &Pin{
+		Path:        p,
+		AdminPinned: md[storage.AdminPinKey] != "",
+		PinnedBytes: pinned,
+		PinQuota:    quota,
+	}
+	if u, ok := ctxuser.ContextGetUser(ctx); ok {
+		for _, id := range storage.ParsePinners(md[storage.PinKey]) {
+			if id == u.Id.GetOpaqueId() {
+				pin.Pinned = true
+			}
+		}
+	}
+	response.WriteOCSSuccess(w, r, pin)
+}
+
+// pin pins the file given in the query for the user, or for the admins with scope=admin
+func (h *Handler) pin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, ref, ok := h.pinRef(w, r)
+	if !ok {
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref: ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{pinKey(r): "1"},
+		},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc set arbitrary metadata request", err)
+		return
+	}
+	if writePinStatus(w, r, res.Status) {
+		appctx.GetLogger(ctx).Debug().Str("path", ref.GetPath()).Str("key", pinKey(r)).Msg("pinned")
+		response.WriteOCSSuccess(w, r, nil)
+	}
+}
+
+// unpin releases the pin of the user, or the admin pin with scope=admin, on the file given in the query
+func (h *Handler) unpin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, ref, ok := h.pinRef(w, r)
+	if !ok {
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.UnsetArbitraryMetadata(ctx, &provider.UnsetArbitraryMetadataRequest{
+		Ref:                   ref,
+		ArbitraryMetadataKeys: []string{pinKey(r)},
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc unset arbitrary metadata request", err)
+		return
+	}
+	if writePinStatus(w, r, res.Status) {
+		appctx.GetLogger(ctx).Debug().Str("path", ref.GetPath()).Str("key", pinKey(r)).Msg("unpinned")
+		response.WriteOCSSuccess(w, r, nil)
+	}
+}
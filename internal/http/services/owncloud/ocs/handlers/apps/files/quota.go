@@ -0,0 +1,90 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package files
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// setQuota sets the quota of the space whose id, as found in the webdav
+// fileid property, is given in the query. Only the quota admins of the
+// storage provider holding the space are allowed to.
+func (h *Handler) setQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := unwrapResourceID(r.URL.Query().Get("id"))
+	if id == nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid or missing id", nil)
+		return
+	}
+	maxBytes, err := conversions.ParseQuota(r.FormValue("quota"))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref:               &provider.Reference{Spec: &provider.Reference_Id{Id: id}},
+		ArbitraryMetadata: storage.QuotaMetadata(maxBytes),
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc set arbitrary metadata request", err)
+		return
+	}
+
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		response.WriteOCSSuccess(w, r, nil)
+	case rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "space not found", nil)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, res.Status.Message, nil)
+	case rpc.Code_CODE_INVALID_ARGUMENT, rpc.Code_CODE_UNIMPLEMENTED:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, res.Status.Message, nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+	}
+}
+
+// unwrapResourceID decodes the base64 encoded storage:opaque ids of webdav.
+func unwrapResourceID(rid string) *provider.ResourceId {
+	decoded, err := base64.URLEncoding.DecodeString(rid)
+	if err != nil {
+		return nil
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil
+	}
+	return &provider.ResourceId{StorageId: parts[0], OpaqueId: parts[1]}
+}
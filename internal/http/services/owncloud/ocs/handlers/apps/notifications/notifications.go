@@ -20,13 +20,33 @@ package notifications
 
 import (
 	"net/http"
+	"strings"
 
+	preferences "github.com/cs3org/go-cs3apis/cs3/preferences/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 )
 
-// Handler placeholder for notifications
+// Handler implements the notifications app endpoints
 type Handler struct {
+	gatewayAddr string
+}
+
+// Preferences holds the notification preferences of the current user
+type Preferences struct {
+	Language string   `json:"language" xml:"language"`
+	Digest   string   `json:"digest" xml:"digest"`
+	Disabled []string `json:"disabled" xml:"disabled>element"`
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) {
+	h.gatewayAddr = c.GatewaySvc
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -37,5 +57,82 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	log.Debug().Str("head", head).Str("tail", r.URL.Path).Msg("http routing")
 
-	w.WriteHeader(http.StatusOK)
+	switch {
+	case head == "preferences" && r.Method == http.MethodGet:
+		h.getPreferences(w, r)
+	case head == "preferences" && (r.Method == http.MethodPut || r.Method == http.MethodPost):
+		h.setPreferences(w, r)
+	default:
+		// the clients polling for notifications get none
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *Handler) getPreferences(w http.ResponseWriter, r *http.Request) {
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.GetKey(r.Context(), &preferences.GetKeyRequest{Key: notification.PreferencesKey})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc get key request", err)
+		return
+	}
+
+	var v string
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		v = res.Val
+	case rpc.Code_CODE_NOT_FOUND:
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+	prefs, err := notification.ParsePreferences(v)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error reading preferences", err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, &Preferences{Language: prefs.Language, Digest: prefs.Digest, Disabled: prefs.Disabled})
+}
+
+// setPreferences replaces the preferences of the user with the ones given as
+// the form values language, digest and disabled, a comma separated list of
+// notification kinds.
+func (h *Handler) setPreferences(w http.ResponseWriter, r *http.Request) {
+	prefs := &notification.Preferences{
+		Language: r.FormValue("language"),
+		Digest:   r.FormValue("digest"),
+	}
+	for _, k := range strings.Split(r.FormValue("disabled"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			prefs.Disabled = append(prefs.Disabled, k)
+		}
+	}
+	if err := prefs.Validate(); err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+		return
+	}
+	v, err := prefs.Encode()
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error encoding preferences", err)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.SetKey(r.Context(), &preferences.SetKeyRequest{Key: notification.PreferencesKey, Val: v})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc set key request", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+	response.WriteOCSSuccess(w, r, &Preferences{Language: prefs.Language, Digest: prefs.Digest, Disabled: prefs.Disabled})
 }
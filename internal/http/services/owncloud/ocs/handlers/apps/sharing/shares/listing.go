@@ -0,0 +1,133 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package shares
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/pkg/errors"
+)
+
+// listOptions returns the pagination, sorting and space filtering of a
+// listing request, nil if it requests none so that all the shares are listed.
+//   - limit and offset select the page
+//   - sort is stime (default) or id, order is asc (default) or desc
+//   - space is the id of a space, as in the /dav/spaces urls
+func listOptions(r *http.Request) (*share.ListOptions, error) {
+	q := r.URL.Query()
+	if q.Get("limit") == "" && q.Get("offset") == "" && q.Get("sort") == "" && q.Get("order") == "" && q.Get("space") == "" {
+		return nil, nil
+	}
+
+	o := &share.ListOptions{}
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if o.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, errors.Wrap(err, "invalid limit")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if o.Offset, err = strconv.Atoi(v); err != nil {
+			return nil, errors.Wrap(err, "invalid offset")
+		}
+	}
+	switch q.Get("sort") {
+	case "", "stime":
+		o.SortBy = share.SortByCtime
+	case "id":
+		o.SortBy = share.SortByID
+	default:
+		return nil, errors.New("invalid sort " + q.Get("sort"))
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		o.Descending = true
+	default:
+		return nil, errors.New("invalid order " + q.Get("order"))
+	}
+	if v := q.Get("space"); v != "" {
+		storageID, ok := unwrapSpaceID(v)
+		if !ok {
+			return nil, errors.New("invalid space " + v)
+		}
+		o.SpaceID = storageID
+	}
+	return o, o.Validate()
+}
+
+// unwrapSpaceID returns the storage id of a space id, the base64 encoded
+// storage:opaque id of its root.
+func unwrapSpaceID(id string) (string, bool) {
+	decoded, err := base64.URLEncoding.DecodeString(id)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// sortShares sorts the shares in the order of the list options, like the
+// share providers do.
+func sortShares(shares []*conversions.ShareData, o *share.ListOptions) {
+	sort.SliceStable(shares, func(i, j int) bool {
+		a, b := shares[i], shares[j]
+		c := 0
+		if o.SortBy != share.SortByID {
+			switch {
+			case a.STime < b.STime:
+				c = -1
+			case a.STime > b.STime:
+				c = 1
+			}
+		}
+		if c == 0 {
+			c = share.CompareIDs(a.ID, b.ID)
+		}
+		if o.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// writeShares writes the listed shares, with the total number of matching
+// shares and the page size in the meta when the listing is paged.
+func writeShares(w http.ResponseWriter, r *http.Request, shares []*conversions.ShareData, o *share.ListOptions, total int) {
+	if o == nil {
+		response.WriteOCSSuccess(w, r, shares)
+		return
+	}
+	meta := response.MetaOK
+	meta.TotalItems = strconv.Itoa(total)
+	if o.Limit > 0 {
+		meta.ItemsPerPage = strconv.Itoa(o.Limit)
+	}
+	response.WriteOCSData(w, r, meta, shares, nil)
+}
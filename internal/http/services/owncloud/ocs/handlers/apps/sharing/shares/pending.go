@@ -24,10 +24,12 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
 	"github.com/pkg/errors"
 )
 
@@ -76,6 +78,10 @@ func (h *Handler) updateReceivedShare(w http.ResponseWriter, r *http.Request, sh
 			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "not found", nil)
 			return
 		}
+		if shareRes.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, shareRes.Status.Message, nil)
+			return
+		}
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc update received share request failed", errors.Errorf("code: %d, message: %s", shareRes.Status.Code, shareRes.Status.Message))
 		return
 	}
@@ -100,10 +106,23 @@ func (h *Handler) updateReceivedShare(w http.ResponseWriter, r *http.Request, sh
 	h.mapUserIds(r.Context(), client, data)
 
 	if data.State == ocsStateAccepted {
-		// Needed because received shares can be jailed in a folder in the users home
-		data.FileTarget = path.Join(h.sharePrefix, path.Base(info.Path))
-		data.Path = path.Join(h.sharePrefix, path.Base(info.Path))
+		h.setMountPoint(data, info, share.DecodeMountPoint(shareRes.Opaque))
 	}
 
 	response.WriteOCSSuccess(w, r, []*conversions.ShareData{data})
 }
+
+// setMountPoint sets the path of an accepted share to the name it is mounted
+// with in the share folder, or to the name of the resource if the gateway
+// doesn't mount the shares.
+func (h *Handler) setMountPoint(data *conversions.ShareData, info *provider.ResourceInfo, name string) {
+	if name == "" {
+		if info == nil {
+			return
+		}
+		name = path.Base(info.Path)
+	}
+	// Needed because received shares can be jailed in a folder in the users home
+	data.FileTarget = path.Join(h.sharePrefix, name)
+	data.Path = path.Join(h.sharePrefix, name)
+}
@@ -34,6 +34,8 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/passwordpolicy"
+	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/pkg/errors"
 )
@@ -103,6 +105,32 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 		}
 	}
 
+	uploadOptions, err := uploadOptionsFromRequest(r, nil)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid upload options", err)
+		return
+	}
+	if uploadOptions != nil {
+		if !publicshare.IsUploadOnly(newPermissions) {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "upload options can only be set on upload only links", nil)
+			return
+		}
+		req.Opaque, err = publicshare.EncodeUploadOptions(req.Opaque, uploadOptions)
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error encoding upload options", err)
+			return
+		}
+	}
+
+	maxDownloads, hasMaxDownloads, err := maxDownloadsFromRequest(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid maxDownloads", err)
+		return
+	}
+	if hasMaxDownloads {
+		req.Opaque = publicshare.EncodeMaxDownloads(req.Opaque, maxDownloads)
+	}
+
 	// set displayname and password protected as arbitrary metadata
 	req.ResourceInfo.ArbitraryMetadata = &provider.ArbitraryMetadata{
 		Metadata: map[string]string{
@@ -118,6 +146,9 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if writePasswordViolations(w, r, createRes.Opaque) {
+		return
+	}
 	if createRes.Status.Code != rpc.Code_CODE_OK {
 		log.Debug().Err(errors.New("create public share failed")).Str("shares", "createShare").Msgf("create public share failed with status code: %v", createRes.Status.Code.String())
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc create public share request failed", err)
@@ -125,6 +156,8 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 	}
 
 	s := conversions.PublicShare2ShareData(createRes.Share, r, h.publicURL)
+	addUploadOptions(s, createRes.Opaque)
+	addAccessStats(s, createRes.Opaque, createRes.Share.GetId())
 	err = h.addFileInfo(ctx, s, statInfo)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error enhancing response with share data", err)
@@ -159,6 +192,7 @@ func (h *Handler) listPublicShares(r *http.Request, filters []*link.ListPublicSh
 			return ocsDataPayload, res.Status, nil
 		}
 
+		stats, _ := publicshare.DecodeAccessStats(res.Opaque)
 		for _, share := range res.GetShare() {
 			info, status, err := h.getResourceInfoByID(ctx, client, share.ResourceId)
 			if err != nil || status.Code != rpc.Code_CODE_OK {
@@ -169,6 +203,7 @@ func (h *Handler) listPublicShares(r *http.Request, filters []*link.ListPublicSh
 			sData := conversions.PublicShare2ShareData(share, r, h.publicURL)
 
 			sData.Name = share.DisplayName
+			addStats(sData, stats[share.GetId().GetOpaqueId()])
 
 			if err := h.addFileInfo(ctx, sData, info); err != nil {
 				log.Debug().Interface("share", share).Interface("info", info).Err(err).Msg("could not add file info, skipping")
@@ -349,7 +384,36 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 		})
 	}
 
+	// Upload options, set on their own after the other updates
+	beforeOptions, _ := publicshare.DecodeUploadOptions(before.Opaque)
+	uploadOptions, err := uploadOptionsFromRequest(r, beforeOptions)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid upload options", err)
+		return
+	}
+	var optionsOpaque *types.Opaque
+	if uploadOptions != nil {
+		updatesFound = true
+		optionsOpaque, err = publicshare.EncodeUploadOptions(nil, uploadOptions)
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error encoding upload options", err)
+			return
+		}
+	}
+
+	// Download limit, set along with the upload options
+	maxDownloads, hasMaxDownloads, err := maxDownloadsFromRequest(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid maxDownloads", err)
+		return
+	}
+	if hasMaxDownloads {
+		updatesFound = true
+		optionsOpaque = publicshare.EncodeMaxDownloads(optionsOpaque, maxDownloads)
+	}
+
 	publicShare := before.Share
+	publicShareOpaque := before.Opaque
 
 	// Updates are atomical. See: https://github.com/cs3org/cs3apis/pull/67#issuecomment-617651428 so in order to get the latest updated version
 	if len(updates) > 0 {
@@ -370,13 +434,44 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 				response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "Error sending update request to public link provider", err)
 				return
 			}
+			if writePasswordViolations(w, r, uRes.Opaque) {
+				return
+			}
 		}
 		publicShare = uRes.Share
+		publicShareOpaque = uRes.Opaque
 	} else if !updatesFound {
 		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "No updates specified in request", nil)
 		return
 	}
 
+	if optionsOpaque != nil {
+		uRes, err := gwC.UpdatePublicShare(r.Context(), &link.UpdatePublicShareRequest{
+			Ref: &link.PublicShareReference{
+				Spec: &link.PublicShareReference_Id{
+					Id: &link.PublicShareId{
+						OpaqueId: shareID,
+					},
+				},
+			},
+			Opaque: optionsOpaque,
+		})
+		switch {
+		case err != nil:
+			log.Err(err).Str("shareID", shareID).Msg("sending upload options to public link provider")
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "Error sending update request to public link provider", err)
+			return
+		case uRes.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT:
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, uRes.Status.Message, nil)
+			return
+		case uRes.Status.Code != rpc.Code_CODE_OK:
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc update link options request failed", nil)
+			return
+		}
+		publicShare = uRes.Share
+		publicShareOpaque = uRes.Opaque
+	}
+
 	statReq := provider.StatRequest{
 		Ref: &provider.Reference{
 			Spec: &provider.Reference_Id{
@@ -393,6 +488,8 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 	}
 
 	s := conversions.PublicShare2ShareData(publicShare, r, h.publicURL)
+	addUploadOptions(s, publicShareOpaque)
+	addAccessStats(s, publicShareOpaque, publicShare.GetId())
 	err = h.addFileInfo(r.Context(), s, statRes.Info)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error enhancing response with share data", err)
@@ -439,6 +536,83 @@ func (h *Handler) removePublicShare(w http.ResponseWriter, r *http.Request, shar
 	response.WriteOCSSuccess(w, r, nil)
 }
 
+// uploadOptionsFromRequest reads the options of an upload only link from the
+// form, applying them to the current ones. It returns nil if the form has none.
+func uploadOptionsFromRequest(r *http.Request, current *publicshare.UploadOptions) (*publicshare.UploadOptions, error) {
+	maxFileSize, hasMaxFileSize := r.Form["maxFileSize"]
+	notifyUploads, hasNotifyUploads := r.Form["notifyUploads"]
+	if !hasMaxFileSize && !hasNotifyUploads {
+		return nil, nil
+	}
+
+	// the creators of the links are notified about the uploads by default
+	opts := &publicshare.UploadOptions{NotifyUploads: true}
+	if current != nil {
+		*opts = *current
+	}
+	if hasMaxFileSize {
+		opts.MaxFileSize = 0
+		if maxFileSize[0] != "" {
+			size, err := strconv.ParseUint(maxFileSize[0], 10, 64)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid maxFileSize")
+			}
+			opts.MaxFileSize = size
+		}
+	}
+	if hasNotifyUploads {
+		notify, err := strconv.ParseBool(notifyUploads[0])
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid notifyUploads")
+		}
+		opts.NotifyUploads = notify
+	}
+	return opts, nil
+}
+
+// addUploadOptions adds the upload options carried by the opaque of a link
+// API response to the share data.
+func addUploadOptions(s *conversions.ShareData, o *types.Opaque) {
+	opts, _ := publicshare.DecodeUploadOptions(o)
+	if opts == nil {
+		return
+	}
+	s.MaxFileSize = opts.MaxFileSize
+	s.NotifyUploads = opts.NotifyUploads
+}
+
+// maxDownloadsFromRequest reads the download limit of a link from the form,
+// an empty value removing it.
+func maxDownloadsFromRequest(r *http.Request) (uint64, bool, error) {
+	maxDownloads, ok := r.Form["maxDownloads"]
+	if !ok || maxDownloads[0] == "" {
+		return 0, ok, nil
+	}
+	max, err := strconv.ParseUint(maxDownloads[0], 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return max, true, nil
+}
+
+// addAccessStats adds the statistics of the link with the given id carried by
+// the opaque of a link API response to the share data.
+func addAccessStats(s *conversions.ShareData, o *types.Opaque, id *link.PublicShareId) {
+	stats, _ := publicshare.DecodeAccessStats(o)
+	addStats(s, stats[id.GetOpaqueId()])
+}
+
+func addStats(s *conversions.ShareData, stats *publicshare.AccessStats) {
+	if stats == nil {
+		return
+	}
+	s.AccessCount = stats.Accesses
+	s.DownloadCount = stats.Downloads
+	s.BytesServed = stats.Bytes
+	s.LastAccess = stats.LastAccess
+	s.MaxDownloads = stats.MaxDownloads
+}
+
 func ocPublicPermToCs3(permKey int, h *Handler) (*provider.ResourcePermissions, error) {
 	// TODO refactor this ocPublicPermToRole[permKey] check into a conversions.NewPublicSharePermissions?
 	// not all permissions are possible for public shares
@@ -453,6 +627,11 @@ func ocPublicPermToCs3(permKey int, h *Handler) (*provider.ResourcePermissions,
 		return nil, err
 	}
 
+	if perm == conversions.PermissionCreate {
+		// upload only links are file drops, their contents are never revealed
+		return publicshare.UploadOnlyPermissions(), nil
+	}
+
 	return conversions.RoleFromOCSPermissions(perm).CS3ResourcePermissions(), nil
 }
 
@@ -512,3 +691,16 @@ var ocPublicPermToRole = map[int]string{
 	// Recipients can view, download and upload contents
 	5: "contributor",
 }
+
+// writePasswordViolations responds with the rules of the password policy a
+// link password was rejected for, one element per rule, and tells whether
+// there were any.
+func writePasswordViolations(w http.ResponseWriter, r *http.Request, o *types.Opaque) bool {
+	violations, err := passwordpolicy.DecodeViolations(o)
+	if err != nil || len(violations) == 0 {
+		return false
+	}
+	m := response.Meta{Status: "error", StatusCode: response.MetaBadRequest.StatusCode, Message: "the password does not comply with the password policy"}
+	response.WriteOCSData(w, r, m, violations, nil)
+	return true
+}
@@ -0,0 +1,75 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package shares
+
+import (
+	"net/http"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
+)
+
+// listPublicShareStats lists the public shares of all the users along with
+// their access statistics, for the admins of the public share provider.
+func (h *Handler) listPublicShareStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	c, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := c.ListPublicShares(ctx, &link.ListPublicSharesRequest{Opaque: share.EncodeAdmin(nil)})
+	switch {
+	case err != nil:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc list public shares request", err)
+		return
+	case res.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, res.Status.Message, nil)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc list public shares request failed", nil)
+		return
+	}
+
+	stats, err := publicshare.DecodeAccessStats(res.Opaque)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error decoding access stats", err)
+		return
+	}
+
+	data := make([]*conversions.ShareData, 0, len(res.Share))
+	for _, ps := range res.Share {
+		// the files of the other users can't be stated, so only the share
+		// itself is returned
+		s := conversions.PublicShare2ShareData(ps, r, h.publicURL)
+		s.Name = ps.DisplayName
+		addStats(s, stats[ps.Id.GetOpaqueId()])
+		h.mapUserIds(ctx, c, s)
+		data = append(data, s)
+	}
+
+	response.WriteOCSSuccess(w, r, data)
+}
@@ -50,6 +50,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/share/cache"
 	"github.com/cs3org/reva/pkg/share/cache/registry"
 	"github.com/pkg/errors"
@@ -65,6 +66,8 @@ type Handler struct {
 	userIdentifierCache    *ttlcache.Cache
 	resourceInfoCache      gcache.Cache
 	resourceInfoCacheTTL   time.Duration
+	guestInvitations       bool
+	guestsAddr             string
 }
 
 // we only cache the minimal set of data instead of the full user metadata
@@ -87,6 +90,8 @@ func (h *Handler) Init(c *config.Config) error {
 	h.publicURL = c.Config.Host
 	h.sharePrefix = c.SharePrefix
 	h.homeNamespace = c.HomeNamespace
+	h.guestInvitations = c.GuestInvitations
+	h.guestsAddr = c.GuestsSvc
 	h.resourceInfoCache = gcache.New(c.ResourceInfoCacheSize).LFU().Build()
 	h.resourceInfoCacheTTL = time.Second * time.Duration(c.ResourceInfoCacheTTL)
 
@@ -173,6 +178,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "Only GET method is allowed", nil)
 		}
 
+	case "public_stats":
+		switch r.Method {
+		case "GET":
+			h.listPublicShareStats(w, r)
+		default:
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "Only GET method is allowed", nil)
+		}
+
 	default:
 		switch r.Method {
 		case "GET":
@@ -244,15 +257,28 @@ func (h *Handler) createShare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// deny shares revoke the access the recipient gets from other shares, eg. of its groups
+	deny := r.FormValue("role") == conversions.RoleDenied
+	if d := r.FormValue("deny"); d != "" {
+		if deny, err = strconv.ParseBool(d); err != nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "deny must be a boolean", nil)
+			return
+		}
+	}
+	if deny && shareType != int(conversions.ShareTypeUser) && shareType != int(conversions.ShareTypeGroup) {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "only user and group shares can deny access", nil)
+		return
+	}
+
 	switch shareType {
 	case int(conversions.ShareTypeUser):
 		// user collaborations default to coowner
-		if role, val, err := h.extractPermissions(w, r, statRes.Info, conversions.NewCoownerRole()); err == nil {
+		if role, val, err := h.extractCollaborationPermissions(w, r, statRes.Info, deny); err == nil {
 			h.createUserShare(w, r, statRes.Info, role, val)
 		}
 	case int(conversions.ShareTypeGroup):
 		// group collaborations default to coowner
-		if role, val, err := h.extractPermissions(w, r, statRes.Info, conversions.NewCoownerRole()); err == nil {
+		if role, val, err := h.extractCollaborationPermissions(w, r, statRes.Info, deny); err == nil {
 			h.createGroupShare(w, r, statRes.Info, role, val)
 		}
 	case int(conversions.ShareTypePublicLink):
@@ -270,6 +296,21 @@ func (h *Handler) createShare(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// extractCollaborationPermissions returns the role of a user or group share,
+// which is the denied role for deny shares and defaults to coowner otherwise.
+func (h *Handler) extractCollaborationPermissions(w http.ResponseWriter, r *http.Request, ri *provider.ResourceInfo, deny bool) (*conversions.Role, []byte, error) {
+	if !deny {
+		return h.extractPermissions(w, r, ri, conversions.NewCoownerRole())
+	}
+	role := conversions.NewDeniedRole()
+	val, err := json.Marshal(map[string]string{"name": role.Name})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "could not encode role", err)
+		return nil, nil, err
+	}
+	return role, val, nil
+}
+
 func (h *Handler) extractPermissions(w http.ResponseWriter, r *http.Request, ri *provider.ResourceInfo, defaultPermissions *conversions.Role) (*conversions.Role, []byte, error) {
 	reqRole, reqPermissions := r.FormValue("role"), r.FormValue("permissions")
 	var role *conversions.Role
@@ -318,7 +359,11 @@ func (h *Handler) extractPermissions(w http.ResponseWriter, r *http.Request, ri
 		return nil, nil, errors.New("cannot set the requested share permissions")
 	}
 
-	role = conversions.RoleFromOCSPermissions(permissions)
+	// the roles defined by the deployment can't be told from their ocs permissions,
+	// they are kept unless the permissions had to be reduced
+	if !conversions.IsCustomRole(reqRole) || permissions != role.OCSPermissions() {
+		role = conversions.RoleFromOCSPermissions(permissions)
+	}
 	roleMap := map[string]string{"name": role.Name}
 	val, err := json.Marshal(roleMap)
 	if err != nil {
@@ -374,6 +419,8 @@ func (h *Handler) getShare(w http.ResponseWriter, r *http.Request, shareID strin
 
 	if err == nil && psRes.GetShare() != nil {
 		share = conversions.PublicShare2ShareData(psRes.Share, r, h.publicURL)
+		addUploadOptions(share, psRes.Opaque)
+		addAccessStats(share, psRes.Opaque, psRes.Share.Id)
 		resourceID = psRes.Share.ResourceId
 	}
 
@@ -413,6 +460,7 @@ func (h *Handler) getShare(w http.ResponseWriter, r *http.Request, shareID strin
 				response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error mapping share data", err)
 				return
 			}
+			addExpiration(share, uRes.Opaque)
 		}
 	}
 
@@ -448,30 +496,48 @@ func (h *Handler) getShare(w http.ResponseWriter, r *http.Request, shareID strin
 func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID string) {
 	ctx := r.Context()
 
-	pval := r.FormValue("permissions")
-	if pval == "" {
-		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions missing", nil)
-		return
-	}
+	var role *conversions.Role
+	// the share role overrides the requested permissions
+	if rval := r.FormValue("role"); rval != "" {
+		role = conversions.RoleFromName(rval)
+		if role.Name == conversions.RoleUnknown {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "unknown role "+rval, nil)
+			return
+		}
+	} else {
+		pval := r.FormValue("permissions")
+		if pval == "" {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions missing", nil)
+			return
+		}
 
-	pint, err := strconv.Atoi(pval)
-	if err != nil {
-		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions must be an integer", nil)
-		return
+		pint, err := strconv.Atoi(pval)
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions must be an integer", nil)
+			return
+		}
+		permissions, err := conversions.NewPermissions(pint)
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+			return
+		}
+		role = conversions.RoleFromOCSPermissions(permissions)
 	}
-	permissions, err := conversions.NewPermissions(pint)
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
 	if err != nil {
-		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
 		return
 	}
 
-	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	opaque, err := expirationFromRequest(r, nil)
 	if err != nil {
-		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid datetime format", err)
 		return
 	}
 
 	uReq := &collaboration.UpdateShareRequest{
+		Opaque: opaque,
 		Ref: &collaboration.ShareReference{
 			Spec: &collaboration.ShareReference_Id{
 				Id: &collaboration.ShareId{
@@ -483,7 +549,7 @@ func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID st
 			Field: &collaboration.UpdateShareRequest_UpdateField_Permissions{
 				Permissions: &collaboration.SharePermissions{
 					// this completely overwrites the permissions for this user
-					Permissions: conversions.RoleFromOCSPermissions(permissions).CS3ResourcePermissions(),
+					Permissions: role.CS3ResourcePermissions(),
 				},
 			},
 		},
@@ -499,6 +565,10 @@ func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID st
 			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "not found", nil)
 			return
 		}
+		if uRes.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, uRes.Status.Message, nil)
+			return
+		}
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc update share request failed", err)
 		return
 	}
@@ -508,6 +578,7 @@ func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID st
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error mapping share data", err)
 		return
 	}
+	addExpiration(share, uRes.Opaque)
 
 	statReq := provider.StatRequest{
 		Ref: &provider.Reference{
@@ -566,6 +637,12 @@ func (h *Handler) listSharesWithMe(w http.ResponseWriter, r *http.Request) {
 	// which pending state to list
 	stateFilter := getStateFilter(r.FormValue("state"))
 
+	opts, err := listOptions(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid list options", err)
+		return
+	}
+
 	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
@@ -600,7 +677,19 @@ func (h *Handler) listSharesWithMe(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	lrsRes, err := client.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	lrsReq := &collaboration.ListReceivedSharesRequest{}
+	if opts != nil {
+		// the state and path filters are applied by the provider so that the page holds the matching shares
+		if stateFilter != ocsStateUnknown {
+			opts.States = []collaboration.ShareState{stateFilter}
+		}
+		opts.ResourceID = pinfo.GetId()
+		if lrsReq.Opaque, err = share.EncodeListOptions(nil, opts); err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error encoding list options", err)
+			return
+		}
+	}
+	lrsRes, err := client.ListReceivedShares(ctx, lrsReq)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc ListReceivedShares request", err)
 		return
@@ -615,10 +704,25 @@ func (h *Handler) listSharesWithMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shares := make([]*conversions.ShareData, 0, len(lrsRes.GetShares()))
+	mountPoints, err := share.DecodeMountPoints(lrsRes.Opaque)
+	if err != nil {
+		log.Debug().Err(err).Msg("could not decode share mount points")
+	}
+
+	received := lrsRes.GetShares()
+	var total int
+	if opts != nil {
+		var ok bool
+		if total, ok = share.DecodeListTotal(lrsRes.Opaque); !ok {
+			// the provider doesn't apply the list options
+			received, total = share.ApplyReceivedListOptions(received, opts)
+		}
+	}
+
+	shares := make([]*conversions.ShareData, 0, len(received))
 
 	// TODO(refs) filter out "invalid" shares
-	for _, rs := range lrsRes.GetShares() {
+	for _, rs := range received {
 		if stateFilter != ocsStateUnknown && rs.GetState() != stateFilter {
 			continue
 		}
@@ -656,15 +760,13 @@ func (h *Handler) listSharesWithMe(w http.ResponseWriter, r *http.Request) {
 		h.mapUserIds(r.Context(), client, data)
 
 		if data.State == ocsStateAccepted {
-			// Needed because received shares can be jailed in a folder in the users home
-			data.FileTarget = path.Join(h.sharePrefix, path.Base(info.Path))
-			data.Path = path.Join(h.sharePrefix, path.Base(info.Path))
+			h.setMountPoint(data, info, mountPoints[rs.Share.Id.GetOpaqueId()])
 		}
 
 		shares = append(shares, data)
 	}
 
-	response.WriteOCSSuccess(w, r, shares)
+	writeShares(w, r, shares, opts, total)
 }
 
 func (h *Handler) listSharesWithOthers(w http.ResponseWriter, r *http.Request) {
@@ -674,6 +776,12 @@ func (h *Handler) listSharesWithOthers(w http.ResponseWriter, r *http.Request) {
 	linkFilters := []*link.ListPublicSharesRequest_Filter{}
 	var e error
 
+	opts, e := listOptions(r)
+	if e != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid list options", e)
+		return
+	}
+
 	// shared with others
 	p := r.URL.Query().Get("path")
 	if p != "" {
@@ -685,26 +793,71 @@ func (h *Handler) listSharesWithOthers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	shareTypes := strings.Split(r.URL.Query().Get("share_types"), ",")
-	for _, s := range shareTypes {
-		shareType, err := strconv.Atoi(strings.TrimSpace(s))
-		if err != nil && s != "" {
+	var granteeTypes []provider.GranteeType
+	var listLinks bool
+	for _, s := range strings.Split(r.URL.Query().Get("share_types"), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			granteeTypes = append(granteeTypes, provider.GranteeType_GRANTEE_TYPE_USER, provider.GranteeType_GRANTEE_TYPE_GROUP)
+			listLinks = true
+			continue
+		}
+		shareType, err := strconv.Atoi(s)
+		if err != nil {
 			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid share type", err)
 			return
 		}
-		if s == "" || shareType == int(conversions.ShareTypeUser) || shareType == int(conversions.ShareTypeGroup) {
-			userShares, status, err := h.listUserShares(r, filters)
-			h.logProblems(status, err, "could not listUserShares")
-			shares = append(shares, userShares...)
+		switch shareType {
+		case int(conversions.ShareTypeUser):
+			granteeTypes = append(granteeTypes, provider.GranteeType_GRANTEE_TYPE_USER)
+		case int(conversions.ShareTypeGroup):
+			granteeTypes = append(granteeTypes, provider.GranteeType_GRANTEE_TYPE_GROUP)
+		case int(conversions.ShareTypePublicLink):
+			listLinks = true
+		}
+	}
+
+	var total int
+	if len(granteeTypes) > 0 {
+		userOpts := opts
+		if opts != nil {
+			o := *opts
+			o.GranteeTypes = granteeTypes
+			if listLinks {
+				// the page is taken from the user shares merged with the links,
+				// the user shares are listed up to its end
+				o.Offset = 0
+				if opts.Limit > 0 {
+					o.Limit = opts.Offset + opts.Limit
+				}
+			}
+			userOpts = &o
 		}
-		if s == "" || shareType == int(conversions.ShareTypePublicLink) {
-			publicShares, status, err := h.listPublicShares(r, linkFilters)
-			h.logProblems(status, err, "could not listPublicShares")
-			shares = append(shares, publicShares...)
+		userShares, n, status, err := h.listUserShares(r, filters, userOpts)
+		h.logProblems(status, err, "could not listUserShares")
+		shares = append(shares, userShares...)
+		total += n
+	}
+	if listLinks {
+		publicShares, status, err := h.listPublicShares(r, linkFilters)
+		h.logProblems(status, err, "could not listPublicShares")
+		for _, ps := range publicShares {
+			if opts != nil && opts.SpaceID != "" && ps.StorageID != opts.SpaceID {
+				continue
+			}
+			shares = append(shares, ps)
+			total++
 		}
 	}
 
-	response.WriteOCSSuccess(w, r, shares)
+	if opts != nil && listLinks {
+		// the public share providers list all the links, they are paged here
+		sortShares(shares, opts)
+		start, end := opts.Page(len(shares))
+		shares = shares[start:end]
+	}
+
+	writeShares(w, r, shares, opts, total)
 }
 
 func (h *Handler) logProblems(s *rpc.Status, e error, msg string) {
@@ -993,6 +1146,12 @@ func (h *Handler) getResourceInfo(ctx context.Context, client gateway.GatewayAPI
 }
 
 func (h *Handler) createCs3Share(ctx context.Context, w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, req *collaboration.CreateShareRequest, info *provider.ResourceInfo) {
+	var err error
+	req.Opaque, err = expirationFromRequest(r, req.Opaque)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid datetime format", err)
+		return
+	}
 	createShareResponse, err := client.CreateShare(ctx, req)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc create share request", err)
@@ -1003,6 +1162,10 @@ func (h *Handler) createCs3Share(ctx context.Context, w http.ResponseWriter, r *
 			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "not found", nil)
 			return
 		}
+		if createShareResponse.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, createShareResponse.Status.Message, nil)
+			return
+		}
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc create share request failed", err)
 		return
 	}
@@ -1011,6 +1174,7 @@ func (h *Handler) createCs3Share(ctx context.Context, w http.ResponseWriter, r *
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error mapping share data", err)
 		return
 	}
+	addExpiration(s, createShareResponse.Opaque)
 	err = h.addFileInfo(ctx, s, info)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error adding fileinfo to share", err)
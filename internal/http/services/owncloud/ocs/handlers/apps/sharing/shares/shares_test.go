@@ -18,9 +18,15 @@
 package shares
 
 import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/share"
 )
 
 func TestGetStateFilter(t *testing.T) {
@@ -70,3 +76,50 @@ func TestMapState(t *testing.T) {
 		}
 	}
 }
+
+func TestListOptions(t *testing.T) {
+	space := base64.URLEncoding.EncodeToString([]byte("storage:root"))
+	tests := []struct {
+		query string
+		opts  *share.ListOptions
+		err   bool
+	}{
+		{"", nil, false},
+		{"path=/docs", nil, false},
+		{"limit=10&offset=20", &share.ListOptions{SortBy: share.SortByCtime, Limit: 10, Offset: 20}, false},
+		{"sort=id&order=desc", &share.ListOptions{SortBy: share.SortByID, Descending: true}, false},
+		{"space=" + space, &share.ListOptions{SortBy: share.SortByCtime, SpaceID: "storage"}, false},
+		{"limit=ten", nil, true},
+		{"offset=-1", nil, true},
+		{"sort=size", nil, true},
+		{"order=random", nil, true},
+		{"space=not-base64!", nil, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/shares?"+tt.query, nil)
+		opts, err := listOptions(r)
+		if (err != nil) != tt.err {
+			t.Errorf("listOptions(%q) returned the error %v", tt.query, err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(opts, tt.opts) {
+			t.Errorf("listOptions(%q) = %+v, want %+v", tt.query, opts, tt.opts)
+		}
+	}
+}
+
+func TestSortShares(t *testing.T) {
+	shares := []*conversions.ShareData{
+		{ID: "10", STime: 1},
+		{ID: "2", STime: 3},
+		{ID: "9", STime: 1},
+	}
+	sortShares(shares, &share.ListOptions{})
+	if shares[0].ID != "9" || shares[1].ID != "10" || shares[2].ID != "2" {
+		t.Errorf("unexpected order by stime %s %s %s", shares[0].ID, shares[1].ID, shares[2].ID)
+	}
+	sortShares(shares, &share.ListOptions{SortBy: share.SortByID, Descending: true})
+	if shares[0].ID != "10" || shares[1].ID != "9" || shares[2].ID != "2" {
+		t.Errorf("unexpected order by id %s %s %s", shares[0].ID, shares[1].ID, shares[2].ID)
+	}
+}
@@ -20,6 +20,7 @@ package shares
 
 import (
 	"net/http"
+	"path"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
@@ -27,10 +28,14 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 
+	guestspb "github.com/cs3org/reva/internal/grpc/services/guests/proto"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/guest"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/pkg/errors"
 )
 
 func (h *Handler) createUserShare(w http.ResponseWriter, r *http.Request, statInfo *provider.ResourceInfo, role *conversions.Role, roleVal []byte) {
@@ -56,9 +61,15 @@ func (h *Handler) createUserShare(w http.ResponseWriter, r *http.Request, statIn
 		return
 	}
 
+	recipient := userRes.User
 	if userRes.Status.Code != rpc.Code_CODE_OK {
-		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "user not found", err)
-		return
+		if userRes.Status.Code != rpc.Code_CODE_NOT_FOUND || !h.guestInvitations || !guest.IsMail(shareWith) {
+			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "user not found", err)
+			return
+		}
+		if recipient = h.inviteGuest(w, r, shareWith, statInfo); recipient == nil {
+			return
+		}
 	}
 
 	createShareReq := &collaboration.CreateShareRequest{
@@ -74,7 +85,7 @@ func (h *Handler) createUserShare(w http.ResponseWriter, r *http.Request, statIn
 		Grant: &collaboration.ShareGrant{
 			Grantee: &provider.Grantee{
 				Type: provider.GranteeType_GRANTEE_TYPE_USER,
-				Id:   &provider.Grantee_UserId{UserId: userRes.User.GetId()},
+				Id:   &provider.Grantee_UserId{UserId: recipient.GetId()},
 			},
 			Permissions: &collaboration.SharePermissions{
 				Permissions: role.CS3ResourcePermissions(),
@@ -85,6 +96,35 @@ func (h *Handler) createUserShare(w http.ResponseWriter, r *http.Request, statIn
 	h.createCs3Share(ctx, w, r, c, createShareReq, statInfo)
 }
 
+// inviteGuest creates the guest account of the mail address a resource is
+// shared with, which is invited to set its password.
+func (h *Handler) inviteGuest(w http.ResponseWriter, r *http.Request, mail string, statInfo *provider.ResourceInfo) *userpb.User {
+	c, err := pool.GetGuestServiceClient(h.guestsAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc guests client", err)
+		return nil
+	}
+	res, err := c.InviteGuest(r.Context(), &guestspb.InviteGuestRequest{
+		Mail:         mail,
+		ResourceName: path.Base(statInfo.Path),
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error inviting guest", err)
+		return nil
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return res.User
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, "not allowed to invite guests", nil)
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, res.Status.Message, nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error inviting guest", errors.New(res.Status.Message))
+	}
+	return nil
+}
+
 func (h *Handler) removeUserShare(w http.ResponseWriter, r *http.Request, shareID string) {
 	ctx := r.Context()
 
@@ -120,7 +160,10 @@ func (h *Handler) removeUserShare(w http.ResponseWriter, r *http.Request, shareI
 	response.WriteOCSSuccess(w, r, nil)
 }
 
-func (h *Handler) listUserShares(r *http.Request, filters []*collaboration.ListSharesRequest_Filter) ([]*conversions.ShareData, *rpc.Status, error) {
+// listUserShares lists the user and group shares matching the filters. When
+// the list options are set it returns the page of the options and the number
+// of matching shares.
+func (h *Handler) listUserShares(r *http.Request, filters []*collaboration.ListSharesRequest_Filter, opts *share.ListOptions) ([]*conversions.ShareData, int, *rpc.Status, error) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
 
@@ -129,29 +172,54 @@ func (h *Handler) listUserShares(r *http.Request, filters []*collaboration.ListS
 	}
 
 	ocsDataPayload := make([]*conversions.ShareData, 0)
+	var total int
 	if h.gatewayAddr != "" {
 		// get a connection to the users share provider
 		client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
 		if err != nil {
-			return ocsDataPayload, nil, err
+			return ocsDataPayload, 0, nil, err
+		}
+
+		if opts != nil {
+			if lsUserSharesRequest.Opaque, err = share.EncodeListOptions(nil, opts); err != nil {
+				return ocsDataPayload, 0, nil, err
+			}
 		}
 
 		// do list shares request. filtered
 		lsUserSharesResponse, err := client.ListShares(ctx, &lsUserSharesRequest)
 		if err != nil {
-			return ocsDataPayload, nil, err
+			return ocsDataPayload, 0, nil, err
 		}
 		if lsUserSharesResponse.Status.Code != rpc.Code_CODE_OK {
-			return ocsDataPayload, lsUserSharesResponse.Status, nil
+			return ocsDataPayload, 0, lsUserSharesResponse.Status, nil
+		}
+
+		exps, err := share.DecodeExpirations(lsUserSharesResponse.Opaque)
+		if err != nil {
+			log.Debug().Err(err).Msg("could not decode share expirations")
+		}
+
+		listed := lsUserSharesResponse.Shares
+		total = len(listed)
+		if opts != nil {
+			var ok bool
+			if total, ok = share.DecodeListTotal(lsUserSharesResponse.Opaque); !ok {
+				// the provider doesn't apply the list options
+				listed, total = share.ApplyListOptions(listed, opts)
+			}
 		}
 
 		// build OCS response payload
-		for _, s := range lsUserSharesResponse.Shares {
+		for _, s := range listed {
 			data, err := conversions.CS3Share2ShareData(ctx, s)
 			if err != nil {
 				log.Debug().Interface("share", s).Interface("shareData", data).Err(err).Msg("could not CS3Share2ShareData, skipping")
 				continue
 			}
+			if exp, ok := exps[s.Id.GetOpaqueId()]; ok {
+				data.Expiration = conversions.TimestampToExpiration(exp)
+			}
 
 			info, status, err := h.getResourceInfoByID(ctx, client, s.ResourceId)
 			if err != nil || status.Code != rpc.Code_CODE_OK {
@@ -170,5 +238,29 @@ func (h *Handler) listUserShares(r *http.Request, filters []*collaboration.ListS
 		}
 	}
 
-	return ocsDataPayload, nil, nil
+	return ocsDataPayload, total, nil, nil
+}
+
+// expirationFromRequest adds the expireDate of the request, an empty one
+// removing the expiration, to the opaque of a user or group share request.
+func expirationFromRequest(r *http.Request, o *types.Opaque) (*types.Opaque, error) {
+	expireDate, ok := r.Form["expireDate"]
+	if !ok {
+		return o, nil
+	}
+	var exp *types.Timestamp
+	if expireDate[0] != "" {
+		var err error
+		if exp, err = conversions.ParseTimestamp(expireDate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return share.EncodeExpiration(o, exp), nil
+}
+
+func addExpiration(s *conversions.ShareData, o *types.Opaque) {
+	exp, ok, _ := share.DecodeExpiration(o)
+	if ok && exp != nil {
+		s.Expiration = conversions.TimestampToExpiration(exp)
+	}
 }
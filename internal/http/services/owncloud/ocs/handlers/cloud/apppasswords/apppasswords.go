@@ -0,0 +1,190 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package apppasswords
+
+import (
+	"net/http"
+	"sort"
+
+	appauthpb "github.com/cs3org/go-cs3apis/cs3/auth/applications/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+)
+
+// Handler implements the app passwords endpoints, the long-lived credentials
+// the users create for their sync clients and devices
+type Handler struct {
+	gatewayAddr string
+}
+
+// AppPassword is the data of an app password. The password itself is only
+// returned when it is created, it is identified afterwards by the id.
+type AppPassword struct {
+	ID         string `json:"id" xml:"id"`
+	Password   string `json:"password,omitempty" xml:"password,omitempty"`
+	Label      string `json:"label" xml:"label"`
+	Expiration string `json:"expiration,omitempty" xml:"expiration,omitempty"`
+	Ctime      uint64 `json:"ctime" xml:"ctime"`
+	LastUsed   uint64 `json:"last_used" xml:"last_used"`
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config) {
+	h.gatewayAddr = c.GatewaySvc
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var head string
+	head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+	switch {
+	case head == "" && r.Method == http.MethodGet:
+		h.list(w, r)
+	case head == "" && r.Method == http.MethodPost:
+		h.create(w, r)
+	case head == "" && r.Method == http.MethodDelete:
+		h.invalidate(w, r)
+	default:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "Not found", nil)
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := client.ListAppPasswords(ctx, &appauthpb.ListAppPasswordsRequest{})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc list app passwords request", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+
+	passwords := make([]*AppPassword, 0, len(res.AppPasswords))
+	for _, pw := range res.AppPasswords {
+		passwords = append(passwords, formatAppPassword(pw))
+	}
+	sort.Slice(passwords, func(i, j int) bool { return passwords[i].Ctime < passwords[j].Ctime })
+	response.WriteOCSSuccess(w, r, passwords)
+}
+
+// create generates an app password giving access to all the resources of the user
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	label := r.FormValue("label")
+	if label == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing label", nil)
+		return
+	}
+	var expiration *types.Timestamp
+	if exp := r.FormValue("expireDate"); exp != "" {
+		var err error
+		if expiration, err = conversions.ParseTimestamp(exp); err != nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid expireDate", err)
+			return
+		}
+	}
+
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error creating the scope of the app password", err)
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	res, err := client.GenerateAppPassword(ctx, &appauthpb.GenerateAppPasswordRequest{
+		TokenScope: ownerScope,
+		Label:      label,
+		Expiration: expiration,
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc generate app password request", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+
+	pw := formatAppPassword(res.AppPassword)
+	pw.Password = res.AppPassword.Password
+	response.WriteOCSSuccess(w, r, pw)
+}
+
+// invalidate revokes an app password, whose id is passed as a query
+// parameter as it may contain the characters of a password
+func (h *Handler) invalidate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing id", nil)
+		return
+	}
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+
+	res, err := client.InvalidateAppPassword(ctx, &appauthpb.InvalidateAppPasswordRequest{Password: id})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc invalidate app password request", err)
+		return
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		response.WriteOCSSuccess(w, r, nil)
+	case rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "app password not found", nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+	}
+}
+
+// formatAppPassword returns the data of an app password. The stores which
+// don't keep the passwords list their hashes, which identify them as well.
+func formatAppPassword(pw *appauthpb.AppPassword) *AppPassword {
+	data := &AppPassword{
+		ID:       pw.Password,
+		Label:    pw.Label,
+		Ctime:    pw.Ctime.GetSeconds(),
+		LastUsed: pw.Utime.GetSeconds(),
+	}
+	if pw.Expiration != nil && pw.Expiration.Seconds != 0 {
+		data.Expiration = conversions.TimestampToExpiration(pw.Expiration)
+	}
+	return data
+}
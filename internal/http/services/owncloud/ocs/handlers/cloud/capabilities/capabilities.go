@@ -19,11 +19,16 @@
 package capabilities
 
 import (
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	ctxuser "github.com/cs3org/reva/pkg/user"
 )
 
 // Handler renders the capability endpoint
@@ -31,13 +36,33 @@ type Handler struct {
 	c                     data.CapabilitiesData
 	defaultUploadProtocol string
 	userAgentChunkingMap  map[string]string
+	gatewayAddr           string
+	registryAddr          string
+	discoveryTTL          time.Duration
+	overrides             map[string]map[string]bool
+
+	discoveryMu      sync.Mutex
+	discovered       *discovery
+	discoveryExpires time.Time
 }
 
 // Init initializes this and any contained handlers
-func (h *Handler) Init(c *config.Config) {
+func (h *Handler) Init(c *config.Config) error {
 	h.c = c.Capabilities
 	h.defaultUploadProtocol = c.DefaultUploadProtocol
 	h.userAgentChunkingMap = c.UserAgentChunkingMap
+	h.gatewayAddr = c.GatewaySvc
+	h.registryAddr = c.StorageRegistrySvc
+	h.discoveryTTL = time.Duration(c.CapabilitiesDiscoveryTTL) * time.Second
+	h.overrides = c.FeatureOverrides
+
+	for k, flags := range h.overrides {
+		for f := range flags {
+			if _, ok := featureFlags[f]; !ok {
+				return fmt.Errorf("capabilities: unknown feature flag %s in the overrides of %s", f, k)
+			}
+		}
+	}
 
 	// capabilities
 	if h.c.Capabilities == nil {
@@ -211,15 +236,51 @@ func (h *Handler) Init(c *config.Config) {
 		}
 	}
 
-	// upload protocol-specific details
-	setCapabilitiesForChunkProtocol(chunkProtocol(h.defaultUploadProtocol), &h.c)
-
+	return nil
 }
 
 // Handler renders the capabilities
 func (h *Handler) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		c := h.getCapabilitiesForUserAgent(r.UserAgent())
+		ctx := r.Context()
+		u, _ := ctxuser.ContextGetUser(ctx)
+		c := h.getCapabilities(r.UserAgent(), u, h.discover(ctx))
 		response.WriteOCSSuccess(w, r, c)
 	})
 }
+
+// getCapabilities returns the capabilities announced to the user, derived
+// from the configured ones, what was discovered, the upload protocol of the
+// user agent and the feature overrides of the user, in that order.
+func (h *Handler) getCapabilities(userAgent string, u *userpb.User, d *discovery) data.CapabilitiesData {
+	c := clone(h.c)
+	protocol := h.getChunkProtocolForUserAgent(userAgent)
+	if d != nil {
+		d.apply(&c)
+		if protocol == chunkTUS && d.uploadProtocols && !d.tus {
+			// chunking NG is served by ocdav whatever the storage
+			protocol = chunkNG
+		}
+	}
+	setCapabilitiesForChunkProtocol(protocol, &c)
+	h.applyOverrides(&c, u)
+	return c
+}
+
+// clone copies the parts of the capabilities changed per request, leaving
+// the configured ones untouched.
+func clone(c data.CapabilitiesData) data.CapabilitiesData {
+	caps := *c.Capabilities
+	files := *caps.Files
+	caps.Files = &files
+	dav := *caps.Dav
+	caps.Dav = &dav
+	sharing := *caps.FilesSharing
+	public := *sharing.Public
+	sharing.Public = &public
+	federation := *sharing.Federation
+	sharing.Federation = &federation
+	caps.FilesSharing = &sharing
+	c.Capabilities = &caps
+	return c
+}
@@ -23,6 +23,8 @@ import (
 	"encoding/xml"
 	"testing"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
 )
 
@@ -56,3 +58,65 @@ func TestMarshal(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestGetCapabilities(t *testing.T) {
+	h := &Handler{}
+	err := h.Init(&config.Config{
+		DefaultUploadProtocol: "tus",
+		UserAgentChunkingMap:  map[string]string{"mirall": "ng"},
+		FeatureOverrides: map[string]map[string]bool{
+			"*":             {"favorites": true},
+			"group:physics": {"public_links": false},
+			"user:einstein": {"versioning": false},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	einstein := &userpb.User{Username: "einstein", Groups: []string{"physics"}}
+	d := &discovery{storageFeatures: true, undelete: true, versioning: true, publicLinks: true, federation: true, tus: true}
+
+	c := h.getCapabilities("", nil, d)
+	files, sharing := c.Capabilities.Files, c.Capabilities.FilesSharing
+	if !files.Undelete || !files.Versioning || !files.Favorites || !sharing.Public.Enabled || !sharing.Federation.Outgoing {
+		t.Errorf("discovered features not announced: %+v %+v", files, sharing)
+	}
+	if files.TusSupport == nil || c.Capabilities.Dav.Chunking != "" {
+		t.Errorf("expected tus, got chunking %q", c.Capabilities.Dav.Chunking)
+	}
+
+	c = h.getCapabilities("", einstein, d)
+	if c.Capabilities.Files.Versioning || c.Capabilities.FilesSharing.Public.Enabled || !c.Capabilities.Files.Undelete {
+		t.Errorf("overrides not applied: %+v %+v", c.Capabilities.Files, c.Capabilities.FilesSharing.Public)
+	}
+
+	c = h.getCapabilities("Mozilla/5.0 (Linux) mirall/2.7.1", nil, d)
+	if c.Capabilities.Dav.Chunking != "1.0" || c.Capabilities.Files.TusSupport != nil {
+		t.Errorf("expected chunking NG for the user agent, got %q", c.Capabilities.Dav.Chunking)
+	}
+
+	d = &discovery{uploadProtocols: true, tus: false}
+	c = h.getCapabilities("", nil, d)
+	if c.Capabilities.Dav.Chunking != "1.0" || c.Capabilities.Files.TusSupport != nil {
+		t.Errorf("expected chunking NG when the storages don't support tus, got %q", c.Capabilities.Dav.Chunking)
+	}
+	if c.Capabilities.FilesSharing.Public.Enabled {
+		t.Error("public links announced without a public share provider")
+	}
+
+	// the configured capabilities must not change across requests
+	if h.c.Capabilities.Files.Versioning || h.c.Capabilities.Files.Favorites || h.c.Capabilities.Dav.Chunking != "" {
+		t.Errorf("configured capabilities changed: %+v", h.c.Capabilities.Files)
+	}
+}
+
+func TestInitUnknownFeatureFlag(t *testing.T) {
+	h := &Handler{}
+	err := h.Init(&config.Config{
+		FeatureOverrides: map[string]map[string]bool{"*": {"teleportation": true}},
+	})
+	if err == nil {
+		t.Error("expected an error for an unknown feature flag")
+	}
+}
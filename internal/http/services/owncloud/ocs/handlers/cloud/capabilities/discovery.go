@@ -0,0 +1,193 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package capabilities
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// probeToken is the token of the public link looked up to find out whether a
+// public share provider is answering, which can't be a real token as they
+// are made of letters only.
+const probeToken = "capabilities-probe-0"
+
+// discovery holds the capabilities found by querying the services.
+type discovery struct {
+	// storageFeatures is whether a storage provider advertises its features.
+	storageFeatures bool
+	undelete        bool
+	versioning      bool
+	// uploadProtocols is whether a storage provider advertises its upload protocols.
+	uploadProtocols bool
+	// tus is whether all the providers advertising their protocols support tus.
+	tus         bool
+	publicLinks bool
+	federation  bool
+}
+
+// apply sets the capabilities discovered.
+func (d *discovery) apply(c *data.CapabilitiesData) {
+	if d.storageFeatures {
+		c.Capabilities.Files.Undelete.Set(d.undelete)
+		c.Capabilities.Files.Versioning.Set(d.versioning)
+	}
+	c.Capabilities.FilesSharing.Public.Enabled.Set(d.publicLinks)
+	c.Capabilities.FilesSharing.Federation.Outgoing.Set(d.federation)
+	c.Capabilities.FilesSharing.Federation.Incoming.Set(d.federation)
+}
+
+// discover returns the capabilities discovered, refreshed once they are
+// older than the discovery ttl, or nil if the discovery is disabled or has
+// never succeeded.
+func (h *Handler) discover(ctx context.Context) *discovery {
+	if h.discoveryTTL < 0 {
+		return nil
+	}
+
+	h.discoveryMu.Lock()
+	defer h.discoveryMu.Unlock()
+	if h.discovered != nil && time.Now().Before(h.discoveryExpires) {
+		return h.discovered
+	}
+
+	d, err := h.discoverServices(ctx)
+	if err != nil {
+		// keep announcing what was last discovered until the services are back
+		appctx.GetLogger(ctx).Warn().Err(err).Msg("error discovering the capabilities")
+		return h.discovered
+	}
+	h.discovered = d
+	h.discoveryExpires = time.Now().Add(h.discoveryTTL)
+	return d
+}
+
+func (h *Handler) discoverServices(ctx context.Context) (*discovery, error) {
+	d := &discovery{tus: true}
+
+	rc, err := pool.GetStorageRegistryClient(h.registryAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting storage registry client")
+	}
+	res, err := rc.ListStorageProviders(ctx, &registry.ListStorageProvidersRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the storage providers")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.Errorf("error listing the storage providers: %s", res.Status.Message)
+	}
+	for _, p := range res.Providers {
+		if f := p.Features; f != nil {
+			d.storageFeatures = true
+			d.undelete = d.undelete || f.Recycle
+			d.versioning = d.versioning || f.FileVersions
+		}
+		if e, ok := p.GetOpaque().GetMap()[storage.UploadProtocolsOpaqueKey]; ok {
+			d.uploadProtocols = true
+			protocols := strings.Split(string(e.Value), ",")
+			d.tus = d.tus && contains(protocols, string(chunkTUS))
+		}
+	}
+
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting gateway client")
+	}
+
+	// the services which are not configured fail to answer
+	lres, err := gc.GetPublicShareByToken(ctx, &link.GetPublicShareByTokenRequest{Token: probeToken})
+	if err == nil {
+		switch lres.Status.Code {
+		case rpc.Code_CODE_OK, rpc.Code_CODE_NOT_FOUND, rpc.Code_CODE_PERMISSION_DENIED:
+			d.publicLinks = true
+		}
+	}
+	ores, err := gc.ListAllProviders(ctx, &ocmprovider.ListAllProvidersRequest{})
+	d.federation = err == nil && ores.Status.Code == rpc.Code_CODE_OK
+
+	return d, nil
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// featureFlags are the flags of the capabilities which can be switched on or
+// off in the feature overrides.
+var featureFlags = map[string]func(c *data.CapabilitiesData, on bool){
+	"undelete": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.Files.Undelete.Set(on)
+	},
+	"versioning": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.Files.Versioning.Set(on)
+	},
+	"favorites": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.Files.Favorites.Set(on)
+	},
+	"public_links": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.FilesSharing.Public.Enabled.Set(on)
+	},
+	"public_upload": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.FilesSharing.Public.Upload.Set(on)
+	},
+	"resharing": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.FilesSharing.Resharing.Set(on)
+	},
+	"group_sharing": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.FilesSharing.GroupSharing.Set(on)
+	},
+	"federation": func(c *data.CapabilitiesData, on bool) {
+		c.Capabilities.FilesSharing.Federation.Outgoing.Set(on)
+		c.Capabilities.FilesSharing.Federation.Incoming.Set(on)
+	},
+}
+
+// applyOverrides switches the feature flags overridden for everybody, then
+// for the groups of the user and last for the user.
+func (h *Handler) applyOverrides(c *data.CapabilitiesData, u *userpb.User) {
+	keys := []string{"*"}
+	if u != nil {
+		for _, g := range u.Groups {
+			keys = append(keys, "group:"+g)
+		}
+		keys = append(keys, "user:"+u.Username)
+	}
+	for _, k := range keys {
+		for f, on := range h.overrides[k] {
+			featureFlags[f](c, on)
+		}
+	}
+}
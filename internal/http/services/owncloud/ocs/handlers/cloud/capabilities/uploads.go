@@ -32,19 +32,16 @@ var (
 	chunkTUS chunkProtocol = "tus"
 )
 
-func (h *Handler) getCapabilitiesForUserAgent(userAgent string) data.CapabilitiesData {
+func (h *Handler) getChunkProtocolForUserAgent(userAgent string) chunkProtocol {
 	if userAgent != "" {
 		for k, v := range h.userAgentChunkingMap {
 			// we could also use a regexp for pattern matching
 			if strings.Contains(userAgent, k) {
-				// Creating a copy of the capabilities struct is less expensive than taking a lock
-				c := h.c
-				setCapabilitiesForChunkProtocol(chunkProtocol(v), &c)
-				return c
+				return chunkProtocol(v)
 			}
 		}
 	}
-	return h.c
+	return chunkProtocol(h.defaultUploadProtocol)
 }
 
 func setCapabilitiesForChunkProtocol(cp chunkProtocol, c *data.CapabilitiesData) {
@@ -22,6 +22,7 @@ import (
 	"net/http"
 
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/apppasswords"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/capabilities"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/user"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/users"
@@ -34,13 +35,18 @@ type Handler struct {
 	UserHandler         *user.Handler
 	UsersHandler        *users.Handler
 	CapabilitiesHandler *capabilities.Handler
+	AppPasswordsHandler *apppasswords.Handler
 }
 
 // Init initializes this and any contained handlers
 func (h *Handler) Init(c *config.Config) error {
 	h.UserHandler = new(user.Handler)
 	h.CapabilitiesHandler = new(capabilities.Handler)
-	h.CapabilitiesHandler.Init(c)
+	if err := h.CapabilitiesHandler.Init(c); err != nil {
+		return err
+	}
+	h.AppPasswordsHandler = new(apppasswords.Handler)
+	h.AppPasswordsHandler.Init(c)
 	h.UsersHandler = new(users.Handler)
 	return h.UsersHandler.Init(c)
 }
@@ -51,6 +57,8 @@ func (h *Handler) Handler() http.Handler {
 		var head string
 		head, r.URL.Path = router.ShiftPath(r.URL.Path)
 		switch head {
+		case "apppasswords":
+			h.AppPasswordsHandler.ServeHTTP(w, r)
 		case "capabilities":
 			h.CapabilitiesHandler.Handler().ServeHTTP(w, r)
 		case "user":
@@ -0,0 +1,101 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package users
+
+import (
+	"net/http"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+)
+
+// handleEditUser implements the provisioning api edit user endpoint, which
+// changes the attribute given by the key form value to value. Only the quota
+// can be changed, the storage provider holding the home only lets its quota
+// admins set it.
+func (h *Handler) handleEditUser(w http.ResponseWriter, r *http.Request, username string) {
+	ctx := r.Context()
+
+	if key := r.FormValue("key"); key != "quota" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "unsupported key "+key, nil)
+		return
+	}
+	maxBytes, err := conversions.ParseQuota(r.FormValue("value"))
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+		return
+	}
+	if h.userHomeTemplate == "" {
+		response.WriteOCSError(w, r, http.StatusNotImplemented, "no user home template configured", nil)
+		return
+	}
+
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting grpc gateway client", err)
+		return
+	}
+	userRes, err := gc.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{
+		Claim: "username",
+		Value: username,
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc get user by claim request", err)
+		return
+	}
+	switch userRes.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "user not found", nil)
+		return
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, userRes.Status.Message, nil)
+		return
+	}
+
+	res, err := gc.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: templates.WithUser(userRes.User, h.userHomeTemplate)},
+		},
+		ArbitraryMetadata: storage.QuotaMetadata(maxBytes),
+	})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc set arbitrary metadata request", err)
+		return
+	}
+
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		response.WriteOCSSuccess(w, r, nil)
+	case rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "home not found", nil)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, res.Status.Message, nil)
+	case rpc.Code_CODE_INVALID_ARGUMENT, rpc.Code_CODE_UNIMPLEMENTED:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, res.Status.Message, nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+	}
+}
@@ -37,12 +37,14 @@ import (
 
 // Handler renders user data for the user id given in the url path
 type Handler struct {
-	gatewayAddr string
+	gatewayAddr      string
+	userHomeTemplate string
 }
 
 // Init initializes this and any contained handlers
 func (h *Handler) Init(c *config.Config) error {
 	h.gatewayAddr = c.GatewaySvc
+	h.userHomeTemplate = c.UserHomeTemplate
 	return nil
 }
 
@@ -58,6 +60,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
 		return
 	}
+	if r.Method == http.MethodPut && r.URL.Path == "/" {
+		// the storage providers check that the user is allowed to edit the user
+		h.handleEditUser(w, r, user)
+		return
+	}
 	if user != u.Username {
 		// FIXME allow fetching other users info? only for admins
 		response.WriteOCSError(w, r, http.StatusForbidden, "user id mismatch", fmt.Errorf("%s tried to access %s user info endpoint", u.Id.OpaqueId, user))
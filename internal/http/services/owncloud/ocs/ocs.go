@@ -24,6 +24,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp/bodylimit"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/mitchellh/mapstructure"
@@ -79,7 +80,7 @@ func (s *svc) Unprotected() []string {
 }
 
 func (s *svc) Handler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log := appctx.GetLogger(r.Context())
 
 		var head string
@@ -94,4 +95,9 @@ func (s *svc) Handler() http.Handler {
 		ctx := response.WithAPIVersion(r.Context(), head)
 		s.V1Handler.Handler().ServeHTTP(w, r.WithContext(ctx))
 	})
+
+	if s.c.BodyLimit.Enabled() {
+		return bodylimit.New(&s.c.BodyLimit).Handler(h)
+	}
+	return h
 }
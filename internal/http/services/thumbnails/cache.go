@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package thumbnails
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/pkg/errors"
+)
+
+// cache keeps the last generated thumbnail of every file and size preset on
+// disk, along with the etag of the file it was generated from. Thumbnails of
+// older versions are overwritten, so the cache only grows with new files.
+type cache struct {
+	dir string
+}
+
+func cacheKey(id *provider.ResourceId, preset string) string {
+	sum := sha256.Sum256([]byte(id.GetStorageId() + ":" + id.GetOpaqueId()))
+	return hex.EncodeToString(sum[:]) + "-" + preset
+}
+
+// get returns the cached thumbnail and its mime type, if it was generated from the given etag.
+func (c *cache) get(key, etag string) ([]byte, string, bool) {
+	meta, err := ioutil.ReadFile(filepath.Join(c.dir, key+".meta"))
+	if err != nil {
+		return nil, "", false
+	}
+	// the metadata holds the etag and the mime type on separate lines
+	lines := strings.SplitN(string(meta), "\n", 2)
+	if len(lines) != 2 || lines[0] != etag {
+		return nil, "", false
+	}
+	data, err := ioutil.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return nil, "", false
+	}
+	return data, lines[1], true
+}
+
+func (c *cache) put(key, etag, mimeType string, data []byte) error {
+	// the metadata is removed first and written last, so that a thumbnail is
+	// never served with the etag of another version
+	_ = os.Remove(filepath.Join(c.dir, key+".meta"))
+	if err := writeFile(filepath.Join(c.dir, key), data); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(c.dir, key+".meta"), []byte(etag+"\n"+mimeType))
+}
+
+func writeFile(name string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "thumbnails: error creating cache file")
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrap(err, "thumbnails: error writing cache file")
+	}
+	return errors.Wrap(os.Rename(f.Name(), name), "thumbnails: error writing cache file")
+}
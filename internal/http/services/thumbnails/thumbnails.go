@@ -0,0 +1,339 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package thumbnails
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/thumbnail"
+	"github.com/cs3org/reva/pkg/thumbnail/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("thumbnails", New)
+}
+
+type size struct {
+	Width  int `mapstructure:"width"`
+	Height int `mapstructure:"height"`
+}
+
+type config struct {
+	Prefix     string `mapstructure:"prefix" docs:"thumbnails;The prefix to be used for this HTTP service"`
+	GatewaySvc string `mapstructure:"gatewaysvc"`
+	Timeout    int64  `mapstructure:"timeout"`
+	Insecure   bool   `mapstructure:"insecure"`
+	// Namespace is the namespace the paths of the requests are relative to.
+	Namespace      string                            `mapstructure:"namespace" docs:"/home;The namespace the paths of the requests are relative to."`
+	Resizers       []string                          `mapstructure:"resizers" docs:"[image];The resizers generating the thumbnails, the first one supporting the mime type of a file is used."`
+	ResizerConfigs map[string]map[string]interface{} `mapstructure:"resizer_configs" docs:"url:pkg/thumbnail/command/command.go;The configuration for the resizers."`
+	Sizes          map[string]size                   `mapstructure:"sizes" docs:"small: 64x64, medium: 256x256, large: 1024x1024;The thumbnail size presets."`
+	DefaultSize    string                            `mapstructure:"default_size" docs:"medium;The size preset used when the request names none."`
+	CacheDir       string                            `mapstructure:"cache_dir" docs:"/var/tmp/reva/thumbnails;The local directory the generated thumbnails are cached in."`
+	MaxInputSize   uint64                            `mapstructure:"max_input_size" docs:"52428800;The size of the largest file thumbnails are generated for."`
+	MaxAge         int                               `mapstructure:"max_age" docs:"3600;The time in seconds clients may cache the thumbnails for."`
+	Concurrency    int                               `mapstructure:"concurrency" docs:"4;The number of thumbnails generated at the same time."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "thumbnails"
+	}
+	if c.Namespace == "" {
+		c.Namespace = "/home"
+	}
+	if len(c.Resizers) == 0 {
+		c.Resizers = []string{"image"}
+	}
+	if len(c.Sizes) == 0 {
+		c.Sizes = map[string]size{
+			"small":  {Width: 64, Height: 64},
+			"medium": {Width: 256, Height: 256},
+			"large":  {Width: 1024, Height: 1024},
+		}
+	}
+	if c.DefaultSize == "" {
+		c.DefaultSize = "medium"
+	}
+	if c.CacheDir == "" {
+		c.CacheDir = "/var/tmp/reva/thumbnails"
+	}
+	if c.MaxInputSize == 0 {
+		c.MaxInputSize = 50 * 1024 * 1024
+	}
+	if c.MaxAge == 0 {
+		c.MaxAge = 3600
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 4
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf     *config
+	resizers []thumbnail.Resizer
+	cache    *cache
+	client   *http.Client
+	// slots limits the number of thumbnails generated at the same time
+	slots chan struct{}
+}
+
+// New returns a new thumbnails service.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+
+	conf.init()
+
+	if _, ok := conf.Sizes[conf.DefaultSize]; !ok {
+		return nil, fmt.Errorf("thumbnails: unknown default size: %s", conf.DefaultSize)
+	}
+	for name, sz := range conf.Sizes {
+		if sz.Width <= 0 || sz.Height <= 0 {
+			return nil, fmt.Errorf("thumbnails: invalid size %s", name)
+		}
+	}
+
+	resizers := make([]thumbnail.Resizer, 0, len(conf.Resizers))
+	for _, name := range conf.Resizers {
+		f, ok := registry.NewFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("thumbnails: resizer not found: %s", name)
+		}
+		r, err := f(conf.ResizerConfigs[name])
+		if err != nil {
+			return nil, err
+		}
+		resizers = append(resizers, r)
+	}
+
+	if err := os.MkdirAll(conf.CacheDir, 0700); err != nil {
+		return nil, errors.Wrap(err, "thumbnails: error creating cache dir")
+	}
+
+	return &svc{
+		conf:     conf,
+		resizers: resizers,
+		cache:    &cache{dir: conf.CacheDir},
+		client: rhttp.GetHTTPClient(
+			rhttp.Timeout(time.Duration(conf.Timeout*int64(time.Second))),
+			rhttp.Insecure(conf.Insecure),
+		),
+		slots: make(chan struct{}, conf.Concurrency),
+	}, nil
+}
+
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return nil
+}
+
+func (s *svc) resizer(mimeType string) thumbnail.Resizer {
+	for _, r := range s.resizers {
+		if r.Supports(mimeType) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Handler serves the thumbnail of the file at the request path, in the size
+// preset given by the size query parameter.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		fn := path.Join(s.conf.Namespace, r.URL.Path)
+		log := appctx.GetLogger(ctx).With().Str("path", fn).Str("svc", "thumbnails").Logger()
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		preset := r.URL.Query().Get("size")
+		if preset == "" {
+			preset = s.conf.DefaultSize
+		}
+		sz, ok := s.conf.Sizes[preset]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		client, err := pool.GetGatewayServiceClient(s.conf.GatewaySvc)
+		if err != nil {
+			log.Error().Err(err).Msg("error getting grpc gateway client")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		ref := &provider.Reference{Spec: &provider.Reference_Path{Path: fn}}
+		sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+		if err != nil {
+			log.Error().Err(err).Msg("error sending grpc stat request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if sRes.Status.Code != rpc.Code_CODE_OK {
+			writeStatus(&log, w, sRes.Status)
+			return
+		}
+		info := sRes.Info
+
+		resizer := s.resizer(info.MimeType)
+		if info.Type != provider.ResourceType_RESOURCE_TYPE_FILE || resizer == nil || info.Size > s.conf.MaxInputSize {
+			log.Debug().Str("mimetype", info.MimeType).Uint64("size", info.Size).Msg("no thumbnail for file")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		etag := fmt.Sprintf(`"%s-%s"`, strings.Trim(info.Etag, `"`), preset)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(s.conf.MaxAge))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		key := cacheKey(info.Id, preset)
+		data, mimeType, ok := s.cache.get(key, info.Etag)
+		if !ok {
+			data, mimeType, err = s.generate(ctx, client, ref, resizer, info, sz)
+			if err != nil {
+				switch err.(type) {
+				case errtypes.IsBadRequest, errtypes.IsNotSupported:
+					log.Debug().Err(err).Msg("no thumbnail for file")
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+				case errtypes.IsNotFound:
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					log.Error().Err(err).Msg("error generating thumbnail")
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+				return
+			}
+			if err := s.cache.put(key, info.Etag, mimeType, data); err != nil {
+				log.Error().Err(err).Msg("error caching thumbnail")
+			}
+		}
+
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+		if _, err := w.Write(data); err != nil {
+			log.Error().Err(err).Msg("error writing response")
+		}
+	})
+}
+
+// generate downloads the file through the data gateway and generates its thumbnail.
+func (s *svc) generate(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference, resizer thumbnail.Resizer, info *provider.ResourceInfo, sz size) ([]byte, string, error) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+
+	dRes, err := client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: ref})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error initiating file download")
+	}
+	switch dRes.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, "", errtypes.NotFound(ref.GetPath())
+	default:
+		return nil, "", errors.New("error initiating file download: " + dRes.Status.Message)
+	}
+
+	var ep, token string
+	for _, p := range dRes.Protocols {
+		if p.Protocol == "simple" {
+			ep, token = p.DownloadEndpoint, p.Token
+		}
+	}
+	if ep == "" {
+		return nil, "", errors.New("no simple download protocol available")
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, "GET", ep, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error creating http request")
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error performing http request")
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error downloading file: %s", httpRes.Status)
+	}
+
+	body := io.LimitReader(httpRes.Body, int64(s.conf.MaxInputSize))
+	return resizer.Thumbnail(ctx, body, info.MimeType, sz.Width, sz.Height)
+}
+
+func writeStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status) {
+	switch s.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		w.WriteHeader(http.StatusForbidden)
+	case rpc.Code_CODE_UNAUTHENTICATED:
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		log.Error().Interface("status", s).Msg("grpc request failed")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package tokenadmin lets the admins revoke the tokens minted by the token
+// manager by their ids, which are logged when the tokens are minted.
+package tokenadmin
+
+import (
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/token"
+	tokenregistry "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("tokenadmin", New)
+}
+
+type config struct {
+	Prefix        string                            `mapstructure:"prefix" docs:"tokenadmin;The prefix to be used for this HTTP service"`
+	Admins        []string                          `mapstructure:"admins" docs:";The usernames of the users allowed to revoke tokens."`
+	TokenManager  string                            `mapstructure:"token_manager" docs:"jwt;The token manager whose tokens are revoked, configured with the revocation store shared with the other services."`
+	TokenManagers map[string]map[string]interface{} `mapstructure:"token_managers" docs:"url:pkg/token/manager/jwt/jwt.go;The configuration for the token managers."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "tokenadmin"
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+}
+
+type svc struct {
+	conf    *config
+	admins  map[string]bool
+	revoker token.Revoker
+}
+
+// New returns a new tokenadmin service.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, errors.Wrap(err, "tokenadmin: error decoding conf")
+	}
+	conf.init()
+	if len(conf.Admins) == 0 {
+		return nil, errors.New("tokenadmin: no admins configured")
+	}
+
+	f, ok := tokenregistry.NewFuncs[conf.TokenManager]
+	if !ok {
+		return nil, errtypes.NotFound("tokenadmin: token manager not found: " + conf.TokenManager)
+	}
+	mgr, err := f(conf.TokenManagers[conf.TokenManager])
+	if err != nil {
+		return nil, err
+	}
+	revoker, ok := mgr.(token.Revoker)
+	if !ok {
+		return nil, errtypes.NotSupported("tokenadmin: the token manager can't revoke tokens: " + conf.TokenManager)
+	}
+
+	admins := map[string]bool{}
+	for _, a := range conf.Admins {
+		admins[a] = true
+	}
+	return &svc{conf: conf, admins: admins, revoker: revoker}, nil
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+// Handler revokes the token with the id given in the path of a POST to
+// /<jti>/revoke.
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id, action string
+		id, r.URL.Path = router.ShiftPath(r.URL.Path)
+		action, _ = router.ShiftPath(r.URL.Path)
+		if id == "" || action != "revoke" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+		u, ok := user.ContextGetUser(ctx)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !s.admins[u.Username] {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if err := s.revoker.RevokeToken(ctx, id); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("jti", id).Msg("tokenadmin: error revoking token")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
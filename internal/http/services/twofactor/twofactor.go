@@ -0,0 +1,283 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package twofactor exposes the endpoints the users enroll a TOTP second
+// factor with, which the auth interceptor requires for the basic auth logins
+// to the services configured in its two_factor section.
+package twofactor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/twofactor"
+	"github.com/cs3org/reva/pkg/twofactor/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("twofactor", New)
+}
+
+type config struct {
+	Prefix  string                            `mapstructure:"prefix"`
+	Driver  string                            `mapstructure:"driver"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// Issuer is the name the authenticator apps show next to the codes.
+	Issuer string `mapstructure:"issuer"`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "twofactor"
+	}
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.Issuer == "" {
+		c.Issuer = "reva"
+	}
+}
+
+type svc struct {
+	conf *config
+	m    twofactor.Manager
+}
+
+type enrollmentStatus struct {
+	Enrolled  bool `json:"enrolled"`
+	Confirmed bool `json:"confirmed"`
+}
+
+type newEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// New returns a new twofactor service
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+
+	f, ok := registry.NewFuncs[conf.Driver]
+	if !ok {
+		return nil, fmt.Errorf("twofactor: driver not found: %s", conf.Driver)
+	}
+	mgr, err := f(conf.Drivers[conf.Driver])
+	if err != nil {
+		return nil, err
+	}
+
+	return &svc{conf: conf, m: mgr}, nil
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var head string
+		head, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		switch {
+		case head == "" && r.Method == http.MethodGet:
+			s.getStatus(w, r)
+		case head == "" && r.Method == http.MethodDelete:
+			s.remove(w, r)
+		case head == "enroll" && r.Method == http.MethodPost:
+			s.enroll(w, r)
+		case head == "confirm" && r.Method == http.MethodPost:
+			s.confirm(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// getEnrollment returns the enrollment of the user of the request, nil if
+// there is none.
+func (s *svc) getEnrollment(r *http.Request) (*twofactor.Enrollment, error) {
+	ctx := r.Context()
+	u, ok := user.ContextGetUser(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("twofactor: user not found in context")
+	}
+	e, err := s.m.GetEnrollment(ctx, u.Id)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *svc) getStatus(w http.ResponseWriter, r *http.Request) {
+	e, err := s.getEnrollment(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	st := enrollmentStatus{}
+	if e != nil {
+		st.Enrolled = true
+		st.Confirmed = e.Confirmed
+	}
+	s.writeJSON(w, r, st)
+}
+
+func (s *svc) enroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	e, err := s.getEnrollment(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if e != nil && e.Confirmed {
+		// replacing a confirmed second factor must go through its removal,
+		// which requires a valid code
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	secret, err := twofactor.GenerateSecret()
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	u := user.ContextMustGetUser(ctx)
+	if err := s.m.SetEnrollment(ctx, u.Id, &twofactor.Enrollment{Secret: secret}); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeJSON(w, r, newEnrollment{
+		Secret: secret,
+		URI:    twofactor.KeyURI(s.conf.Issuer, u.Username, secret),
+	})
+}
+
+func (s *svc) confirm(w http.ResponseWriter, r *http.Request) {
+	code := s.checkCode(w, r, func(e *twofactor.Enrollment) {
+		e.Confirmed = true
+	})
+	if code != 0 {
+		w.WriteHeader(code)
+	}
+}
+
+func (s *svc) remove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	e, err := s.getEnrollment(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if e.Confirmed {
+		if code := s.checkCode(w, r, nil); code != http.StatusNoContent {
+			if code != 0 {
+				w.WriteHeader(code)
+			}
+			return
+		}
+	}
+
+	if err := s.m.DeleteEnrollment(ctx, user.ContextMustGetUser(ctx).Id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkCode checks the code of the request against the enrollment of the
+// user, recording the outcome, and calls accepted with the enrollment if the
+// code is valid. It returns the status of the response, the errors being
+// handled.
+func (s *svc) checkCode(w http.ResponseWriter, r *http.Request, accepted func(e *twofactor.Enrollment)) int {
+	ctx := r.Context()
+	code := http.StatusNoContent
+	err := s.m.UpdateEnrollment(ctx, user.ContextMustGetUser(ctx).Id, func(e *twofactor.Enrollment) error {
+		now := time.Now()
+		switch {
+		case e.Locked(now):
+			code = http.StatusTooManyRequests
+		case !e.Check(r.FormValue("code"), now):
+			code = http.StatusBadRequest
+		case accepted != nil:
+			accepted(e)
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return http.StatusNotFound
+		}
+		s.handleError(w, r, err)
+		return 0
+	}
+	return code
+}
+
+func (s *svc) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	log := appctx.GetLogger(r.Context())
+	log.Error().Err(err).Msg("twofactor: error handling request")
+	if _, ok := err.(errtypes.IsUserRequired); ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func (s *svc) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	log := appctx.GetLogger(r.Context())
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Error().Err(err).Msg("twofactor: error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("twofactor: error writing response")
+	}
+}
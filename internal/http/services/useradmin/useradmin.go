@@ -0,0 +1,283 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package useradmin exposes the user admin service over HTTP, for the admins
+// to create, update, disable and delete the users with a plain JSON API.
+package useradmin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	useradminpb "github.com/cs3org/reva/internal/grpc/services/useradmin/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	userpkg "github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("useradmin", New)
+}
+
+type config struct {
+	Prefix string `mapstructure:"prefix"`
+	// UserAdminSvc is the endpoint of the user admin gRPC service.
+	UserAdminSvc string `mapstructure:"useradminsvc"`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "useradmin"
+	}
+	c.UserAdminSvc = sharedconf.GetGatewaySVC(c.UserAdminSvc)
+}
+
+type svc struct {
+	conf *config
+}
+
+// user is the JSON representation of a user.
+type user struct {
+	ID          string   `json:"id"`
+	Idp         string   `json:"idp,omitempty"`
+	Username    string   `json:"username"`
+	DisplayName string   `json:"display_name,omitempty"`
+	Mail        string   `json:"mail,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+	UIDNumber   int64    `json:"uid_number,omitempty"`
+	GIDNumber   int64    `json:"gid_number,omitempty"`
+	Disabled    bool     `json:"disabled"`
+}
+
+func (u *user) toCS3() *userpb.User {
+	cs3 := &userpb.User{
+		Id:          &userpb.UserId{Idp: u.Idp, OpaqueId: u.ID},
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Mail:        u.Mail,
+		Groups:      u.Groups,
+		UidNumber:   u.UIDNumber,
+		GidNumber:   u.GIDNumber,
+	}
+	userpkg.SetDisabled(cs3, u.Disabled)
+	return cs3
+}
+
+func fromCS3(u *userpb.User) *user {
+	return &user{
+		ID:          u.Id.GetOpaqueId(),
+		Idp:         u.Id.GetIdp(),
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Mail:        u.Mail,
+		Groups:      u.Groups,
+		UIDNumber:   u.UidNumber,
+		GIDNumber:   u.GidNumber,
+		Disabled:    userpkg.IsDisabled(u),
+	}
+}
+
+// New returns a new useradmin service
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return nil, err
+	}
+	conf.init()
+	return &svc{conf: conf}, nil
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id, action string
+		id, r.URL.Path = router.ShiftPath(r.URL.Path)
+		action, r.URL.Path = router.ShiftPath(r.URL.Path)
+
+		switch {
+		case id == "" && r.Method == http.MethodPost:
+			s.create(w, r)
+		case id != "" && action == "" && r.Method == http.MethodPut:
+			s.update(w, r, id)
+		case id != "" && action == "" && r.Method == http.MethodDelete:
+			s.remove(w, r, id)
+		case id != "" && action == "disable" && r.Method == http.MethodPost:
+			s.setDisabled(w, r, id, true)
+		case id != "" && action == "enable" && r.Method == http.MethodPost:
+			s.setDisabled(w, r, id, false)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func (s *svc) client(w http.ResponseWriter, r *http.Request) useradminpb.UserAdminServiceClient {
+	c, err := pool.GetUserAdminServiceClient(s.conf.UserAdminSvc)
+	if err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("useradmin: error getting user admin client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil
+	}
+	return c
+}
+
+// userID returns the id of the user of the path, the idp is given as a
+// query parameter when the ids are not unique across the idps.
+func userID(r *http.Request, id string) *userpb.UserId {
+	return &userpb.UserId{Idp: r.URL.Query().Get("idp"), OpaqueId: id}
+}
+
+func readUser(w http.ResponseWriter, r *http.Request) *user {
+	u := &user{}
+	if err := json.NewDecoder(r.Body).Decode(u); err != nil {
+		appctx.GetLogger(r.Context()).Debug().Err(err).Msg("useradmin: error decoding user")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+	return u
+}
+
+func (s *svc) create(w http.ResponseWriter, r *http.Request) {
+	u := readUser(w, r)
+	if u == nil {
+		return
+	}
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.CreateUser(r.Context(), &useradminpb.CreateUserRequest{User: u.toCS3()})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.writeUser(w, r, res.Status, res.User, http.StatusCreated)
+}
+
+func (s *svc) update(w http.ResponseWriter, r *http.Request, id string) {
+	u := readUser(w, r)
+	if u == nil {
+		return
+	}
+	u.ID = id
+	if idp := r.URL.Query().Get("idp"); idp != "" {
+		u.Idp = idp
+	}
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.UpdateUser(r.Context(), &useradminpb.UpdateUserRequest{User: u.toCS3()})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.writeUser(w, r, res.Status, res.User, http.StatusOK)
+}
+
+func (s *svc) setDisabled(w http.ResponseWriter, r *http.Request, id string, disabled bool) {
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.SetUserDisabled(r.Context(), &useradminpb.SetUserDisabledRequest{Id: userID(r, id), Disabled: disabled})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	s.writeUser(w, r, res.Status, res.User, http.StatusOK)
+}
+
+func (s *svc) remove(w http.ResponseWriter, r *http.Request, id string) {
+	c := s.client(w, r)
+	if c == nil {
+		return
+	}
+	res, err := c.DeleteUser(r.Context(), &useradminpb.DeleteUserRequest{Id: userID(r, id)})
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		s.writeStatus(w, r, res.Status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// httpStatus maps the codes of the user admin service to HTTP statuses.
+var httpStatus = map[rpc.Code]int{
+	rpc.Code_CODE_INVALID_ARGUMENT:  http.StatusBadRequest,
+	rpc.Code_CODE_UNAUTHENTICATED:   http.StatusUnauthorized,
+	rpc.Code_CODE_PERMISSION_DENIED: http.StatusForbidden,
+	rpc.Code_CODE_NOT_FOUND:         http.StatusNotFound,
+	rpc.Code_CODE_ALREADY_EXISTS:    http.StatusConflict,
+	rpc.Code_CODE_UNIMPLEMENTED:     http.StatusNotImplemented,
+}
+
+func (s *svc) writeStatus(w http.ResponseWriter, r *http.Request, st *rpc.Status) {
+	code, ok := httpStatus[st.Code]
+	if !ok {
+		appctx.GetLogger(r.Context()).Error().Str("code", st.Code.String()).Str("message", st.Message).Msg("useradmin: error handling request")
+		code = http.StatusInternalServerError
+	}
+	w.WriteHeader(code)
+}
+
+func (s *svc) writeUser(w http.ResponseWriter, r *http.Request, st *rpc.Status, u *userpb.User, code int) {
+	if st.Code != rpc.Code_CODE_OK {
+		s.writeStatus(w, r, st)
+		return
+	}
+	log := appctx.GetLogger(r.Context())
+	data, err := json.Marshal(fromCS3(u))
+	if err != nil {
+		log.Error().Err(err).Msg("useradmin: error encoding response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("useradmin: error writing response")
+	}
+}
+
+func (s *svc) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	appctx.GetLogger(r.Context()).Error().Err(err).Msg("useradmin: error calling user admin service")
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -0,0 +1,63 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package antivirus provides the clients used to scan uploaded files for
+// malware, along with the policies applied to infected files.
+package antivirus
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Scanner scans a stream of data for malware.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// Result is the outcome of a scan.
+type Result struct {
+	Infected bool
+	// Description names the detected threat, if any.
+	Description string
+}
+
+// The policies that can be applied to infected files.
+const (
+	// PolicyDelete deletes infected files.
+	PolicyDelete = "delete"
+	// PolicyQuarantine moves a copy of infected files to a quarantine
+	// directory before deleting them.
+	PolicyQuarantine = "quarantine"
+	// PolicyLog only reports infected files.
+	PolicyLog = "log"
+)
+
+// Event is emitted when an infected file is found.
+type Event struct {
+	Type        string    `json:"type"`
+	Time        time.Time `json:"time"`
+	Path        string    `json:"path"`
+	User        string    `json:"user,omitempty"`
+	Description string    `json:"description"`
+	Action      string    `json:"action"`
+}
+
+// EventInfected is the type of the events emitted for infected files.
+const EventInfected = "antivirus.infected"
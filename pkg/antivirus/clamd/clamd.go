@@ -0,0 +1,159 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package clamd implements an antivirus scanner talking to a clamd daemon
+// using the INSTREAM command.
+package clamd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("clamd", New)
+}
+
+type config struct {
+	Address   string `mapstructure:"address" docs:"tcp://localhost:3310;The address of clamd, either tcp://host:port or unix:///path/to/socket."`
+	Timeout   int    `mapstructure:"timeout" docs:"300;Timeout of a scan in seconds."`
+	ChunkSize int    `mapstructure:"chunk_size" docs:"65536;Size of the chunks streamed to clamd, which must not exceed its StreamMaxLength."`
+}
+
+func (c *config) init() {
+	if c.Address == "" {
+		c.Address = "tcp://localhost:3310"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 300
+	}
+	if c.ChunkSize == 0 {
+		c.ChunkSize = 64 * 1024
+	}
+}
+
+type scanner struct {
+	c       *config
+	network string
+	address string
+}
+
+// New returns a scanner relying on clamd.
+func New(m map[string]interface{}) (antivirus.Scanner, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "clamd: error decoding conf")
+	}
+	c.init()
+
+	u, err := url.Parse(c.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "clamd: error parsing address")
+	}
+
+	s := &scanner{c: c, network: u.Scheme}
+	switch u.Scheme {
+	case "tcp":
+		s.address = u.Host
+	case "unix":
+		s.address = u.Path
+	default:
+		return nil, fmt.Errorf("clamd: unsupported address scheme: %s", u.Scheme)
+	}
+	return s, nil
+}
+
+func (s *scanner) Scan(ctx context.Context, r io.Reader) (*antivirus.Result, error) {
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, errors.Wrap(err, "clamd: error connecting")
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Duration(s.c.Timeout) * time.Second)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, errors.Wrap(err, "clamd: error sending command")
+	}
+
+	// the stream is sent as chunks prefixed by their length,
+	// a zero length chunk marks its end
+	buf := make([]byte, s.c.ChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, rerr := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return nil, errors.Wrap(err, "clamd: error sending data")
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return nil, errors.Wrap(err, "clamd: error sending data")
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return nil, errors.Wrap(rerr, "clamd: error reading data")
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, errors.Wrap(err, "clamd: error sending data")
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "clamd: error reading reply")
+	}
+	return parseReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseReply parses replies in the form "stream: OK", "stream: <name> FOUND"
+// or "<message> ERROR".
+func parseReply(reply string) (*antivirus.Result, error) {
+	msg := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case msg == "OK":
+		return &antivirus.Result{}, nil
+	case strings.HasSuffix(msg, " FOUND"):
+		return &antivirus.Result{Infected: true, Description: strings.TrimSuffix(msg, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd: scan failed: %s", reply)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package clamd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeClamd accepts a single INSTREAM session and flags streams containing "EICAR".
+func fakeClamd(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		cmd := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+			_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+
+		data := &bytes.Buffer{}
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(data, conn, int64(n)); err != nil {
+				return
+			}
+		}
+
+		if strings.Contains(data.String(), "EICAR") {
+			_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			return
+		}
+		_, _ = conn.Write([]byte("stream: OK\x00"))
+	}()
+
+	return "tcp://" + l.Addr().String()
+}
+
+func TestScan(t *testing.T) {
+	tests := map[string]struct {
+		data        string
+		infected    bool
+		description string
+	}{
+		"clean":    {data: strings.Repeat("a", 10), infected: false},
+		"infected": {data: strings.Repeat("a", 10) + "EICAR", infected: true, description: "Eicar-Test-Signature"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// use a small chunk size to exercise the chunked stream
+			s, err := New(map[string]interface{}{"address": fakeClamd(t), "chunk_size": 4})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := s.Scan(context.Background(), strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Infected != tt.infected || res.Description != tt.description {
+				t.Errorf("got %+v, want infected=%v description=%q", res, tt.infected, tt.description)
+			}
+		})
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	if _, err := parseReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Error("expected an error for a failed scan")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package icap implements an antivirus scanner relying on an ICAP server,
+// as specified in RFC 3507, sending the data to scan in a RESPMOD request.
+package icap
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("icap", New)
+}
+
+type config struct {
+	URL     string `mapstructure:"url" docs:"icap://localhost:1344/avscan;The URL of the ICAP service."`
+	Timeout int    `mapstructure:"timeout" docs:"300;Timeout of a scan in seconds."`
+}
+
+func (c *config) init() {
+	if c.URL == "" {
+		c.URL = "icap://localhost:1344/avscan"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 300
+	}
+}
+
+type scanner struct {
+	c   *config
+	url *url.URL
+}
+
+// New returns a scanner relying on an ICAP server.
+func New(m map[string]interface{}) (antivirus.Scanner, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "icap: error decoding conf")
+	}
+	c.init()
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error parsing url")
+	}
+	if u.Scheme != "icap" {
+		return nil, fmt.Errorf("icap: unsupported url scheme: %s", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "1344")
+	}
+	return &scanner{c: c, url: u}, nil
+}
+
+// the encapsulated HTTP response carrying the data to scan
+const resHeader = "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"
+
+func (s *scanner) Scan(ctx context.Context, r io.Reader) (*antivirus.Result, error) {
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", s.url.Host)
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error connecting")
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Duration(s.c.Timeout) * time.Second)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", s.url.String())
+	fmt.Fprintf(w, "Host: %s\r\n", s.url.Hostname())
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(resHeader))
+	fmt.Fprint(w, resHeader)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			if _, err := w.Write(buf[:n]); err != nil {
+				return nil, errors.Wrap(err, "icap: error sending data")
+			}
+			fmt.Fprint(w, "\r\n")
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return nil, errors.Wrap(rerr, "icap: error reading data")
+		}
+	}
+	fmt.Fprint(w, "0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return nil, errors.Wrap(err, "icap: error sending data")
+	}
+
+	return parseResponse(textproto.NewReader(bufio.NewReader(conn)))
+}
+
+// parseResponse interprets the ICAP response: 204 means that the content was
+// left untouched, a 200 that the server replaced it because of a threat.
+func parseResponse(tp *textproto.Reader) (*antivirus.Result, error) {
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error reading response")
+	}
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return nil, fmt.Errorf("icap: malformed response: %s", line)
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("icap: malformed response: %s", line)
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "icap: error reading response headers")
+	}
+
+	switch code {
+	case 204:
+		return &antivirus.Result{}, nil
+	case 200:
+		// X-Infection-Found values look like "Type=0; Resolution=2; Threat=Eicar-Test-Signature;"
+		desc := header.Get("X-Infection-Found")
+		for _, field := range strings.Split(desc, ";") {
+			if kv := strings.SplitN(strings.TrimSpace(field), "=", 2); len(kv) == 2 && kv[0] == "Threat" {
+				desc = kv[1]
+			}
+		}
+		if desc == "" {
+			desc = header.Get("X-Virus-ID")
+		}
+		if desc == "" {
+			desc = "content blocked by the icap server"
+		}
+		return &antivirus.Result{Infected: true, Description: desc}, nil
+	default:
+		return nil, fmt.Errorf("icap: scan failed: %s", line)
+	}
+}
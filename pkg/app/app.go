@@ -20,25 +20,98 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	registrypb "github.com/cs3org/go-cs3apis/cs3/app/registry/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+const (
+	// DefaultAppsKey is the opaque key of the ListAppProvidersResponse holding
+	// the default app of the mime types, as a json map of mime type to app name.
+	// It is also the user preference holding the default apps chosen by the user.
+	DefaultAppsKey = "default_apps"
+
+	nameKey = "name"
+	iconKey = "icon"
 )
 
 // Registry is the interface that application registries implement
 // for discovering application providers
 type Registry interface {
-	FindProvider(ctx context.Context, mimeType string) (*ProviderInfo, error)
+	// FindProviders returns the providers of a mime type, the default one first.
+	FindProviders(ctx context.Context, mimeType string) ([]*ProviderInfo, error)
 	ListProviders(ctx context.Context) ([]*ProviderInfo, error)
+	// DefaultApps returns the name of the default app of the mime types.
+	DefaultApps(ctx context.Context) (map[string]string, error)
 }
 
 // ProviderInfo contains the information
 // about a Application Provider
 type ProviderInfo struct {
-	Location string
+	Location    string
+	Name        string
+	Description string
+	Icon        string
+	MimeTypes   []string
 }
 
 // Provider is the interface that application providers implement
-// for providing the iframe location to a iframe UI Provider
+// for providing the URL of the app a resource is opened in
 type Provider interface {
-	GetIFrame(ctx context.Context, resID *provider.ResourceId, token string) (string, error)
+	// GetAppURL returns the URL the resource is opened at in the given view mode.
+	// The app, if not empty, is the one the user asked for.
+	GetAppURL(ctx context.Context, resource *provider.ResourceInfo, viewMode appprovider.OpenInAppRequest_ViewMode, token, app string) (string, error)
+}
+
+// EncodeProviderInfo adds the name and the icon of an app to the opaque of its
+// cs3 provider info, which has no field for them.
+func EncodeProviderInfo(p *registrypb.ProviderInfo, name, icon string) {
+	if name == "" && icon == "" {
+		return
+	}
+	if p.Opaque == nil {
+		p.Opaque = &types.Opaque{}
+	}
+	if p.Opaque.Map == nil {
+		p.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	if name != "" {
+		p.Opaque.Map[nameKey] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(name)}
+	}
+	if icon != "" {
+		p.Opaque.Map[iconKey] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(icon)}
+	}
+}
+
+// ProviderName returns the name of the app of a cs3 provider info.
+func ProviderName(p *registrypb.ProviderInfo) string {
+	return string(p.GetOpaque().GetMap()[nameKey].GetValue())
+}
+
+// ProviderIcon returns the icon of the app of a cs3 provider info.
+func ProviderIcon(p *registrypb.ProviderInfo) string {
+	return string(p.GetOpaque().GetMap()[iconKey].GetValue())
+}
+
+// EncodeDefaultApps returns the json representation of the default apps of the mime types.
+func EncodeDefaultApps(apps map[string]string) ([]byte, error) {
+	if apps == nil {
+		apps = map[string]string{}
+	}
+	return json.Marshal(apps)
+}
+
+// DecodeDefaultApps parses the json representation of the default apps of the mime types.
+func DecodeDefaultApps(v []byte) (map[string]string, error) {
+	apps := map[string]string{}
+	if len(v) == 0 {
+		return apps, nil
+	}
+	if err := json.Unmarshal(v, &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
 }
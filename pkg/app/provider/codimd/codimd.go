@@ -0,0 +1,428 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package codimd implements an app provider opening markdown files in CodiMD,
+// or HedgeDoc, pads which are saved back to the files while they are edited.
+package codimd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/app"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+type config struct {
+	CodiMDURL    string `mapstructure:"codimd_url" docs:";The public URL of CodiMD."`
+	CodiMDIntURL string `mapstructure:"codimd_int_url" docs:";The internal URL of CodiMD, used to create and read the pads. Defaults to codimd_url."`
+	GatewaySvc   string `mapstructure:"gatewaysvc" docs:";The gateway used to read and write the files."`
+	SyncInterval int    `mapstructure:"sync_interval" docs:"30;The seconds between the save-backs of the edited pads to their files."`
+	IdleTimeout  int    `mapstructure:"idle_timeout" docs:"1800;The seconds without changes after which a pad is closed: it is saved back a last time and not synced anymore."`
+	Insecure     bool   `mapstructure:"insecure" docs:"false;Whether to skip the verification of the certificates of CodiMD."`
+}
+
+func (c *config) init() {
+	if c.CodiMDIntURL == "" {
+		c.CodiMDIntURL = c.CodiMDURL
+	}
+	if c.SyncInterval == 0 {
+		c.SyncInterval = 30
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = 1800
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+// pad is a CodiMD note holding the content of a file
+type pad struct {
+	id  string
+	ref *providerpb.Reference
+	// etag and content of the file as of the last sync
+	etag    string
+	content []byte
+	// token of the last user opening the pad for editing, used to save it back
+	token      string
+	lastChange time.Time
+	syncing    bool
+	closed     bool
+}
+
+type provider struct {
+	conf    *config
+	client  *http.Client
+	gateway func() (gateway.GatewayAPIClient, error)
+
+	mu   sync.Mutex
+	pads map[string]*pad
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns an implementation of the app.Provider interface that
+// opens markdown files in CodiMD.
+func New(m map[string]interface{}) (app.Provider, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+	if c.CodiMDURL == "" {
+		return nil, errtypes.BadRequest("codimd: missing codimd_url")
+	}
+	return newProvider(c, func() (gateway.GatewayAPIClient, error) {
+		return pool.GetGatewayServiceClient(c.GatewaySvc)
+	}), nil
+}
+
+func newProvider(c *config, gw func() (gateway.GatewayAPIClient, error)) *provider {
+	client := rhttp.GetHTTPClient(
+		rhttp.Timeout(10*time.Second),
+		rhttp.Insecure(c.Insecure),
+	)
+	// the location of the pads created by CodiMD is in its redirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &provider{
+		conf:    c,
+		client:  client,
+		gateway: gw,
+		pads:    map[string]*pad{},
+	}
+}
+
+func (p *provider) GetAppURL(ctx context.Context, resource *providerpb.ResourceInfo, viewMode appprovider.OpenInAppRequest_ViewMode, tkn, appName string) (string, error) {
+	if resource.GetType() != providerpb.ResourceType_RESOURCE_TYPE_FILE {
+		return "", errtypes.BadRequest("codimd: only files can be opened")
+	}
+	key := resource.GetId().GetStorageId() + ":" + resource.GetId().GetOpaqueId()
+	edit := viewMode == appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE
+
+	p.mu.Lock()
+	pd, ok := p.pads[key]
+	if ok && pd.etag != resource.GetEtag() {
+		// the file changed since the pad was synced, its content is rather taken from the file
+		pd.closed = true
+		delete(p.pads, key)
+		ok = false
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		var err error
+		if pd, err = p.createPad(ctx, resource, tkn); err != nil {
+			return "", err
+		}
+		p.mu.Lock()
+		if existing, found := p.pads[key]; found && existing.etag == pd.etag {
+			// opened concurrently, the first pad is kept
+			pd = existing
+		} else {
+			p.pads[key] = pd
+		}
+		p.mu.Unlock()
+	}
+
+	if !edit {
+		return p.conf.CodiMDURL + "/" + pd.id + "/publish", nil
+	}
+
+	p.mu.Lock()
+	pd.token = tkn
+	start := !pd.syncing
+	pd.syncing = true
+	p.mu.Unlock()
+	if start {
+		go p.sync(key, pd)
+	}
+	return p.conf.CodiMDURL + "/" + pd.id, nil
+}
+
+// createPad creates a CodiMD note with the content of the file
+func (p *provider) createPad(ctx context.Context, resource *providerpb.ResourceInfo, tkn string) (*pad, error) {
+	ref := &providerpb.Reference{Spec: &providerpb.Reference_Id{Id: resource.GetId()}}
+	ctx = withToken(ctx, tkn)
+	content, err := p.download(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := rhttp.NewRequest(ctx, "POST", p.conf.CodiMDIntURL+"/new", bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/markdown")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "codimd: error creating the pad")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		return nil, errtypes.InternalError(fmt.Sprintf("codimd: creating the pad returned %d", res.StatusCode))
+	}
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return nil, errors.Wrap(err, "codimd: error parsing the location of the pad")
+	}
+	id := path.Base(location.Path)
+	if id == "" || id == "/" || id == "." {
+		return nil, errtypes.InternalError("codimd: no pad in the location " + location.String())
+	}
+
+	appctx.GetLogger(ctx).Debug().Str("pad", id).Str("path", resource.GetPath()).Msg("codimd: created pad")
+	return &pad{
+		id:         id,
+		ref:        ref,
+		etag:       resource.GetEtag(),
+		content:    content,
+		lastChange: time.Now(),
+	}, nil
+}
+
+// sync saves the pad back to its file until it is closed
+func (p *provider) sync(key string, pd *pad) {
+	log := appctx.GetLogger(context.Background()).With().Str("pad", pd.id).Logger()
+	ticker := time.NewTicker(time.Duration(p.conf.SyncInterval) * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if done := p.syncPad(key, pd, &log); done {
+			return
+		}
+	}
+}
+
+// syncPad writes the changes of the pad to the file and tells whether the pad is closed
+func (p *provider) syncPad(key string, pd *pad, log *zerolog.Logger) bool {
+	p.mu.Lock()
+	if pd.closed {
+		p.mu.Unlock()
+		return true
+	}
+	ctx := withToken(context.Background(), pd.token)
+	content, etag, lastChange := pd.content, pd.etag, pd.lastChange
+	p.mu.Unlock()
+
+	current, err := p.readPad(ctx, pd.id)
+	if err != nil {
+		log.Error().Err(err).Msg("codimd: error reading the pad")
+		return false
+	}
+
+	if bytes.Equal(current, content) {
+		if time.Since(lastChange) > time.Duration(p.conf.IdleTimeout)*time.Second {
+			log.Debug().Msg("codimd: closing the idle pad")
+			p.close(key, pd)
+			return true
+		}
+		return false
+	}
+
+	fileEtag, err := p.stat(ctx, pd.ref)
+	if err != nil {
+		log.Error().Err(err).Msg("codimd: error getting the file of the pad")
+		return false
+	}
+	if fileEtag != etag {
+		// don't overwrite the changes made to the file, the next opening creates a new pad
+		log.Warn().Str("etag", fileEtag).Msg("codimd: the file of the pad changed, the pad isn't saved back anymore")
+		p.close(key, pd)
+		return true
+	}
+
+	if err := p.upload(ctx, pd.ref, current); err != nil {
+		log.Error().Err(err).Msg("codimd: error saving the pad back")
+		return false
+	}
+	if etag, err = p.stat(ctx, pd.ref); err != nil {
+		log.Error().Err(err).Msg("codimd: error getting the file of the pad")
+		return false
+	}
+
+	p.mu.Lock()
+	pd.content, pd.etag, pd.lastChange = current, etag, time.Now()
+	p.mu.Unlock()
+	log.Debug().Str("etag", etag).Msg("codimd: saved the pad back")
+	return false
+}
+
+func (p *provider) close(key string, pd *pad) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pd.closed = true
+	if p.pads[key] == pd {
+		delete(p.pads, key)
+	}
+}
+
+// readPad returns the markdown of a pad
+func (p *provider) readPad(ctx context.Context, id string) ([]byte, error) {
+	req, err := rhttp.NewRequest(ctx, "GET", p.conf.CodiMDIntURL+"/"+id+"/download", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errtypes.InternalError(fmt.Sprintf("codimd: reading the pad returned %d", res.StatusCode))
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+func (p *provider) stat(ctx context.Context, ref *providerpb.Reference) (string, error) {
+	client, err := p.gateway()
+	if err != nil {
+		return "", err
+	}
+	res, err := client.Stat(ctx, &providerpb.StatRequest{Ref: ref})
+	if err != nil {
+		return "", err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return "", status.NewErrorFromCode(res.Status.Code, "codimd")
+	}
+	return res.Info.GetEtag(), nil
+}
+
+func (p *provider) download(ctx context.Context, ref *providerpb.Reference) ([]byte, error) {
+	client, err := p.gateway()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.InitiateFileDownload(ctx, &providerpb.InitiateFileDownloadRequest{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "codimd")
+	}
+
+	var ep, tkn string
+	for _, proto := range res.Protocols {
+		if proto.Protocol == "simple" {
+			ep, tkn = proto.DownloadEndpoint, proto.Token
+		}
+	}
+	req, err := rhttp.NewRequest(ctx, "GET", ep, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(datagateway.TokenTransportHeader, tkn)
+	httpRes, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "codimd: error downloading the file")
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return nil, errtypes.InternalError(fmt.Sprintf("codimd: downloading the file returned %d", httpRes.StatusCode))
+	}
+	return ioutil.ReadAll(httpRes.Body)
+}
+
+func (p *provider) upload(ctx context.Context, ref *providerpb.Reference, content []byte) error {
+	client, err := p.gateway()
+	if err != nil {
+		return err
+	}
+	res, err := client.InitiateFileUpload(ctx, &providerpb.InitiateFileUploadRequest{
+		Ref: ref,
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.Itoa(len(content))),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(res.Status.Code, "codimd")
+	}
+
+	var ep, tkn string
+	for _, proto := range res.Protocols {
+		if proto.Protocol == "simple" {
+			ep, tkn = proto.UploadEndpoint, proto.Token
+		}
+	}
+	req, err := rhttp.NewRequest(ctx, "PUT", ep, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(datagateway.TokenTransportHeader, tkn)
+	httpRes, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "codimd: error uploading the file")
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode != http.StatusOK {
+		return errtypes.InternalError(fmt.Sprintf("codimd: uploading the file returned %d", httpRes.StatusCode))
+	}
+	return nil
+}
+
+// withToken returns a context authenticating the gateway calls with the token of a user
+func withToken(ctx context.Context, tkn string) context.Context {
+	ctx = token.ContextSetToken(ctx, tkn)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	} else {
+		md = md.Copy()
+	}
+	md.Set(token.TokenHeader, tkn)
+	return metadata.NewOutgoingContext(ctx, md)
+}
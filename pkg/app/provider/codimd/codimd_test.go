@@ -0,0 +1,202 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package codimd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	"google.golang.org/grpc"
+)
+
+// fakeGateway serves a single file
+type fakeGateway struct {
+	gateway.GatewayAPIClient
+	endpoint string
+
+	mu      sync.Mutex
+	content string
+	etag    string
+}
+
+func (g *fakeGateway) Stat(ctx context.Context, in *providerpb.StatRequest, opts ...grpc.CallOption) (*providerpb.StatResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return &providerpb.StatResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Info:   &providerpb.ResourceInfo{Etag: g.etag},
+	}, nil
+}
+
+func (g *fakeGateway) InitiateFileDownload(ctx context.Context, in *providerpb.InitiateFileDownloadRequest, opts ...grpc.CallOption) (*gateway.InitiateFileDownloadResponse, error) {
+	return &gateway.InitiateFileDownloadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileDownloadProtocol{{Protocol: "simple", DownloadEndpoint: g.endpoint, Token: "transfer"}},
+	}, nil
+}
+
+func (g *fakeGateway) InitiateFileUpload(ctx context.Context, in *providerpb.InitiateFileUploadRequest, opts ...grpc.CallOption) (*gateway.InitiateFileUploadResponse, error) {
+	return &gateway.InitiateFileUploadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileUploadProtocol{{Protocol: "simple", UploadEndpoint: g.endpoint, Token: "transfer"}},
+	}, nil
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(datagateway.TokenTransportHeader) != "transfer" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch r.Method {
+	case "GET":
+		_, _ = w.Write([]byte(g.content))
+	case "PUT":
+		b, _ := ioutil.ReadAll(r.Body)
+		g.content = string(b)
+		g.etag += "+"
+	}
+}
+
+// fakeCodiMD keeps the pads in memory
+type fakeCodiMD struct {
+	mu   sync.Mutex
+	pads map[string]string
+}
+
+func (c *fakeCodiMD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r.Method == "POST" && r.URL.Path == "/new" {
+		b, _ := ioutil.ReadAll(r.Body)
+		c.pads["pad1"] = string(b)
+		w.Header().Set("Location", "/pad1")
+		w.WriteHeader(http.StatusFound)
+		return
+	}
+	content, ok := c.pads[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/download")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write([]byte(content))
+}
+
+func (c *fakeCodiMD) edit(id, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pads[id] = content
+}
+
+func newTestProvider(t *testing.T) (*provider, *fakeGateway, *fakeCodiMD) {
+	gw := &fakeGateway{content: "# notes", etag: "1"}
+	data := httptest.NewServer(gw)
+	t.Cleanup(data.Close)
+	gw.endpoint = data.URL
+
+	codi := &fakeCodiMD{pads: map[string]string{}}
+	srv := httptest.NewServer(codi)
+	t.Cleanup(srv.Close)
+
+	c := &config{CodiMDURL: "https://codimd.example.org", CodiMDIntURL: srv.URL, SyncInterval: 3600}
+	c.init()
+	p := newProvider(c, func() (gateway.GatewayAPIClient, error) { return gw, nil })
+	return p, gw, codi
+}
+
+var testFile = &providerpb.ResourceInfo{
+	Type: providerpb.ResourceType_RESOURCE_TYPE_FILE,
+	Id:   &providerpb.ResourceId{StorageId: "storage", OpaqueId: "notes"},
+	Path: "/notes.md",
+	Etag: "1",
+}
+
+func TestGetAppURL(t *testing.T) {
+	p, _, codi := newTestProvider(t)
+	ctx := context.Background()
+
+	u, err := p.GetAppURL(ctx, testFile, appprovider.OpenInAppRequest_VIEW_MODE_READ_ONLY, "token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://codimd.example.org/pad1/publish" {
+		t.Fatalf("unexpected read only url %s", u)
+	}
+	if codi.pads["pad1"] != "# notes" {
+		t.Fatalf("the pad wasn't created with the file content: %q", codi.pads["pad1"])
+	}
+
+	u, err = p.GetAppURL(ctx, testFile, appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE, "token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://codimd.example.org/pad1" {
+		t.Fatalf("unexpected edit url %s", u)
+	}
+
+	folder := &providerpb.ResourceInfo{Type: providerpb.ResourceType_RESOURCE_TYPE_CONTAINER}
+	if _, err := p.GetAppURL(ctx, folder, appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE, "token", ""); err == nil {
+		t.Fatal("folders must not be opened")
+	}
+}
+
+func TestSyncPad(t *testing.T) {
+	p, gw, codi := newTestProvider(t)
+	log := appctx.GetLogger(context.Background())
+	if _, err := p.GetAppURL(context.Background(), testFile, appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE, "token", ""); err != nil {
+		t.Fatal(err)
+	}
+	pd := p.pads["storage:notes"]
+
+	codi.edit("pad1", "# notes\nedited")
+	if done := p.syncPad("storage:notes", pd, log); done {
+		t.Fatal("the pad must still be synced")
+	}
+	if gw.content != "# notes\nedited" {
+		t.Fatalf("the pad wasn't saved back: %q", gw.content)
+	}
+	if pd.etag != gw.etag {
+		t.Fatalf("the pad etag %s doesn't follow the file etag %s", pd.etag, gw.etag)
+	}
+
+	// changes to the file made elsewhere are not overwritten
+	gw.etag = "2"
+	codi.edit("pad1", "# notes\nedited again")
+	if done := p.syncPad("storage:notes", pd, log); !done {
+		t.Fatal("the pad must be closed when the file changed")
+	}
+	if gw.content != "# notes\nedited" {
+		t.Fatalf("the file was overwritten: %q", gw.content)
+	}
+	if _, ok := p.pads["storage:notes"]; ok {
+		t.Fatal("the closed pad must be removed")
+	}
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/cs3org/reva/pkg/app"
 
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
 	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/mitchellh/mapstructure"
 )
@@ -32,8 +33,9 @@ type provider struct {
 	iframeUIProvider string
 }
 
-func (p *provider) GetIFrame(ctx context.Context, resID *providerpb.ResourceId, token string) (string, error) {
-	msg := fmt.Sprintf("<iframe src=%s/open/%s?access-token=%s />", p.iframeUIProvider, resID.StorageId+":"+resID.OpaqueId, token)
+func (p *provider) GetAppURL(ctx context.Context, resource *providerpb.ResourceInfo, viewMode appprovider.OpenInAppRequest_ViewMode, token, app string) (string, error) {
+	resID := resource.GetId()
+	msg := fmt.Sprintf("%s/open/%s?view-mode=%s&access-token=%s", p.iframeUIProvider, resID.GetStorageId()+":"+resID.GetOpaqueId(), viewMode.String(), token)
 	return msg, nil
 }
 
@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// discovery is the WOPI discovery document of an app, served at /hosting/discovery.
+// See https://docs.microsoft.com/en-us/microsoft-365/cloud-storage-partner-program/online/discovery
+type discovery struct {
+	NetZones []struct {
+		Name string `xml:"name,attr"`
+		Apps []struct {
+			Name    string `xml:"name,attr"`
+			Actions []struct {
+				Name   string `xml:"name,attr"`
+				Ext    string `xml:"ext,attr"`
+				URLSrc string `xml:"urlsrc,attr"`
+			} `xml:"action"`
+		} `xml:"app"`
+	} `xml:"net-zone"`
+}
+
+// actions maps the file extensions to the urls of the actions, eg. view or edit,
+// the app supports for them
+type actions map[string]map[string]string
+
+// placeholders matches the optional query parameters of the action urls, eg. <ui=UI_LLCC&>
+var placeholders = regexp.MustCompile(`<[^>]*>`)
+
+// parseDiscovery parses a WOPI discovery document. The actions of the
+// preferred net zone win over the ones of the other zones.
+func parseDiscovery(r io.Reader, zone string) (actions, error) {
+	var d discovery
+	if err := xml.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+
+	a := actions{}
+	add := func(preferred bool) {
+		for _, z := range d.NetZones {
+			if (z.Name == zone) != preferred {
+				continue
+			}
+			for _, app := range z.Apps {
+				for _, action := range app.Actions {
+					ext := strings.ToLower(strings.TrimPrefix(action.Ext, "."))
+					if ext == "" || action.URLSrc == "" {
+						continue
+					}
+					if a[ext] == nil {
+						a[ext] = map[string]string{}
+					}
+					if _, ok := a[ext][action.Name]; !ok {
+						a[ext][action.Name] = placeholders.ReplaceAllString(action.URLSrc, "")
+					}
+				}
+			}
+		}
+	}
+	add(true)
+	add(false)
+	return a, nil
+}
+
+// find returns the url of the first action of the list the app supports for a file extension
+func (a actions) find(ext string, names ...string) (string, bool) {
+	byName := a[strings.ToLower(strings.TrimPrefix(ext, "."))]
+	for _, n := range names {
+		if u, ok := byName[n]; ok {
+			return u, true
+		}
+	}
+	return "", false
+}
+
+// appURL adds the WOPISrc of a file to the url of an action
+func appURL(action, wopiSrc string) string {
+	action = strings.TrimRight(action, "&")
+	switch {
+	case strings.HasSuffix(action, "?"):
+	case strings.Contains(action, "?"):
+		action += "&"
+	default:
+		action += "?"
+	}
+	return action + "WOPISrc=" + url.QueryEscape(wopiSrc)
+}
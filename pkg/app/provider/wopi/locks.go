@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/google/uuid"
+)
+
+// The wopiserver keeps the WOPI locks the apps take on the files they edit as
+// arbitrary metadata of the files, which no other protocol knows about. The
+// locks are mirrored into the write locks of the storage, see storage.Lock, so
+// that the files can't be changed through WebDAV while they are edited.
+const (
+	// LockKey is the arbitrary metadata key the wopiserver keeps the WOPI lock of a file under.
+	LockKey = "iop.lock"
+	// LockDuration is the time a WOPI lock lasts unless it is refreshed.
+	LockDuration = 30 * time.Minute
+	// LockOwner is the owner of the write locks mirroring WOPI locks.
+	LockOwner = "WOPI"
+)
+
+// IsMirrorLock tells whether the write lock mirrors a WOPI lock.
+func IsMirrorLock(l *storage.Lock) bool {
+	return l != nil && l.Owner == LockOwner
+}
+
+// MirrorLock returns the write lock mirroring the WOPI lock taken or refreshed
+// by the user at the given time. A refreshed lock keeps the token of the lock
+// it refreshes, the one currently mirroring the WOPI lock, if any.
+func MirrorLock(current *storage.Lock, u *userpb.UserId, now time.Time) *storage.Lock {
+	token := "opaquelocktoken:" + uuid.New().String()
+	if IsMirrorLock(current) {
+		token = current.Token
+	}
+	return &storage.Lock{
+		Token:   token,
+		User:    u,
+		Owner:   LockOwner,
+		Expires: now.Add(LockDuration),
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package wopi implements an app provider opening the resources in the apps,
+// eg. Collabora or OnlyOffice, served through a WOPI server.
+package wopi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/app"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+type config struct {
+	IopSecret    string `mapstructure:"iop_secret" docs:";The iopsecret used to connect to the wopiserver."`
+	WopiURL      string `mapstructure:"wopi_url" docs:";The wopiserver's URL."`
+	WopiBrURL    string `mapstructure:"wopi_bridge_url" docs:";The wopibridge's URL."`
+	AppName      string `mapstructure:"app_name" docs:";The name of the app, as configured in the app registry."`
+	AppURL       string `mapstructure:"app_url" docs:";The URL of the app, whose WOPI discovery tells how to open the files. Without it the app URLs are the ones of the wopiserver's endpoints."`
+	AppIntURL    string `mapstructure:"app_int_url" docs:";The internal URL of the app, used to fetch the WOPI discovery. Defaults to app_url."`
+	DiscoveryTTL int    `mapstructure:"discovery_ttl" docs:"3600;The seconds the WOPI discovery of the app is cached."`
+	Insecure     bool   `mapstructure:"insecure" docs:"false;Whether to skip the verification of the certificates of the app."`
+}
+
+func (c *config) init() {
+	if c.AppIntURL == "" {
+		c.AppIntURL = c.AppURL
+	}
+	if c.DiscoveryTTL == 0 {
+		c.DiscoveryTTL = 3600
+	}
+	if c.IopSecret == "" {
+		c.IopSecret = os.Getenv("REVA_APPPROVIDER_IOPSECRET")
+	}
+}
+
+type wopiProvider struct {
+	conf   *config
+	client *http.Client
+
+	mu        sync.Mutex
+	actions   actions
+	fetchedAt time.Time
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns an implementation of the app.Provider interface that
+// connects to an application in the backend.
+func New(m map[string]interface{}) (app.Provider, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+	if c.WopiURL == "" {
+		return nil, errtypes.BadRequest("wopi: missing wopi_url")
+	}
+
+	return &wopiProvider{
+		conf: c,
+		client: rhttp.GetHTTPClient(
+			rhttp.Timeout(5*time.Second),
+			rhttp.Insecure(c.Insecure),
+		),
+	}, nil
+}
+
+func (p *wopiProvider) GetAppURL(ctx context.Context, resource *providerpb.ResourceInfo, viewMode appprovider.OpenInAppRequest_ViewMode, token, appName string) (string, error) {
+	if p.conf.AppURL == "" {
+		return p.getEndpointsAppURL(ctx, resource, viewMode, token, appName)
+	}
+
+	if appName != "" && p.conf.AppName != "" && appName != p.conf.AppName {
+		return "", errtypes.BadRequest("wopi: the app " + appName + " is not served by this provider")
+	}
+
+	a, err := p.getActions(ctx)
+	if err != nil {
+		return "", err
+	}
+	names := []string{"view", "embedview"}
+	if viewMode == appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE {
+		names = append([]string{"edit"}, names...)
+	}
+	ext := path.Ext(resource.GetPath())
+	action, ok := a.find(ext, names...)
+	if !ok {
+		return "", errtypes.NotFound("wopi: the app can't open files with extension " + ext)
+	}
+
+	wopiSrc, err := p.open(ctx, resource, viewMode, token)
+	if err != nil {
+		return "", err
+	}
+	return appURL(action, wopiSrc), nil
+}
+
+// open registers the resource with the wopiserver and returns its WOPISrc
+func (p *wopiProvider) open(ctx context.Context, resource *providerpb.ResourceInfo, viewMode appprovider.OpenInAppRequest_ViewMode, token string) (string, error) {
+	wopiurl, err := url.Parse(p.conf.WopiURL)
+	if err != nil {
+		return "", err
+	}
+	wopiurl.Path = path.Join(wopiurl.Path, "/wopi/iop/open")
+	httpReq, err := rhttp.NewRequest(ctx, "GET", wopiurl.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	q := httpReq.URL.Query()
+	q.Add("fileid", resource.GetId().OpaqueId)
+	q.Add("endpoint", resource.GetId().StorageId)
+	q.Add("viewmode", viewMode.String())
+	// TODO the folder URL should be resolved as e.g. `'https://cernbox.cern.ch/index.php/apps/files/?dir=' + filepath.Dir(req.Ref.GetPath())`
+	// or should be deprecated/removed altogether, needs discussion and decision.
+	q.Add("folderurl", "undefined")
+	u, ok := user.ContextGetUser(ctx)
+	if ok {
+		q.Add("username", u.Username)
+	}
+	// else defaults to "Anonymous Guest"
+
+	httpReq.Header.Set("Authorization", "Bearer "+p.conf.IopSecret)
+	httpReq.Header.Set("TokenHeader", token)
+
+	httpReq.URL.RawQuery = q.Encode()
+
+	openRes, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", errors.Wrap(err, "wopi: error performing open request to WOPI")
+	}
+	defer openRes.Body.Close()
+
+	if openRes.StatusCode != http.StatusOK {
+		return "", errtypes.InternalError(fmt.Sprintf("wopi: error performing open request to WOPI, status code: %d", openRes.StatusCode))
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err = buf.ReadFrom(openRes.Body); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// getActions returns the actions of the WOPI discovery of the app, which is
+// fetched again once it is older than the configured ttl.
+func (p *wopiProvider) getActions(ctx context.Context) (actions, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.actions != nil && time.Since(p.fetchedAt) < time.Duration(p.conf.DiscoveryTTL)*time.Second {
+		return p.actions, nil
+	}
+
+	a, err := p.fetchDiscovery(ctx)
+	if err != nil {
+		if p.actions != nil {
+			// better outdated actions than none
+			appctx.GetLogger(ctx).Error().Err(err).Msg("wopi: error refreshing the WOPI discovery, using the cached one")
+			return p.actions, nil
+		}
+		return nil, err
+	}
+	p.actions, p.fetchedAt = a, time.Now()
+	return a, nil
+}
+
+func (p *wopiProvider) fetchDiscovery(ctx context.Context) (actions, error) {
+	discoveryURL, err := url.Parse(p.conf.AppIntURL)
+	if err != nil {
+		return nil, err
+	}
+	publicURL, err := url.Parse(p.conf.AppURL)
+	if err != nil {
+		return nil, err
+	}
+	discoveryURL.Path = path.Join(discoveryURL.Path, "/hosting/discovery")
+
+	req, err := rhttp.NewRequest(ctx, "GET", discoveryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: error getting the WOPI discovery")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errtypes.InternalError(fmt.Sprintf("wopi: request to the WOPI discovery returned %d", res.StatusCode))
+	}
+
+	a, err := parseDiscovery(res.Body, "external-"+publicURL.Scheme)
+	if err != nil {
+		return nil, errors.Wrap(err, "wopi: error parsing the WOPI discovery")
+	}
+	appctx.GetLogger(ctx).Info().Msgf("wopi: retrieved the WOPI discovery with %d file extensions", len(a))
+	return a, nil
+}
+
+// getEndpointsAppURL resolves the app URL with the app endpoints of the wopiserver.
+func (p *wopiProvider) getEndpointsAppURL(ctx context.Context, resource *providerpb.ResourceInfo, viewMode appprovider.OpenInAppRequest_ViewMode, token, appName string) (string, error) {
+	wopiSrc, err := p.open(ctx, resource, viewMode, token)
+	if err != nil {
+		return "", err
+	}
+
+	var viewmode string
+	if viewMode == appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE {
+		viewmode = "edit"
+	} else {
+		viewmode = "view"
+	}
+
+	var appProviderURL string
+	// the app can also be the name of the app in the registry, which the endpoints don't know about
+	if u, err := url.Parse(appName); err != nil || !u.IsAbs() {
+		// Default behavior: work out the application URL to be used for this file
+		appsURLMap, err := p.getWopiAppEndpoints(ctx)
+		if err != nil {
+			return "", errors.Wrap(err, "wopi: getWopiAppEndpoints failed")
+		}
+		viewOptions := appsURLMap[path.Ext(resource.GetPath())]
+		viewOptionsMap, ok := viewOptions.(map[string]interface{})
+		if !ok {
+			return "", errtypes.BadRequest("wopi: incorrect parsing of the App URLs map from the WOPI server")
+		}
+
+		appProviderURL = fmt.Sprintf("%v", viewOptionsMap[viewmode])
+		if strings.Contains(appProviderURL, "?") {
+			appProviderURL += "&"
+		} else {
+			appProviderURL += "?"
+		}
+		appProviderURL = fmt.Sprintf("%sWOPISrc=%s", appProviderURL, wopiSrc)
+	} else {
+		// User specified the application to use, generate the URL out of that
+		// TODO map the given req.App to the URL via config. For now assume it's a URL!
+		appProviderURL = fmt.Sprintf("%sWOPISrc=%s", appName, wopiSrc)
+	}
+
+	// In case of applications served by the WOPI bridge, resolve the URL and go to the app
+	// Note that URL matching is performed via string matching, not via IP resolution: may need to fix this
+	if len(p.conf.WopiBrURL) > 0 && strings.Contains(appProviderURL, p.conf.WopiBrURL) {
+		httpClient := rhttp.GetHTTPClient(
+			rhttp.Context(ctx),
+			rhttp.Timeout(time.Duration(5*int64(time.Second))),
+		)
+		httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			// do not follow a redirect
+			return http.ErrUseLastResponse
+		}
+
+		bridgeReq, err := rhttp.NewRequest(ctx, "GET", appProviderURL, nil)
+		if err != nil {
+			return "", err
+		}
+		bridgeRes, err := httpClient.Do(bridgeReq)
+		if err != nil {
+			return "", err
+		}
+		defer bridgeRes.Body.Close()
+		if bridgeRes.StatusCode != http.StatusFound {
+			return "", errtypes.InternalError(fmt.Sprintf("Request to WOPI bridge returned %d", bridgeRes.StatusCode))
+		}
+		appProviderURL = bridgeRes.Header.Get("Location")
+	}
+	return appProviderURL, nil
+}
+
+func (p *wopiProvider) getWopiAppEndpoints(ctx context.Context) (map[string]interface{}, error) {
+	// TODO this query will eventually be served by Reva.
+	// For the time being it is a remnant of the CERNBox-specific WOPI server, which justifies the /cbox path in the URL.
+	wopiurl, err := url.Parse(p.conf.WopiURL)
+	if err != nil {
+		return nil, err
+	}
+	wopiurl.Path = path.Join(wopiurl.Path, "/wopi/cbox/endpoints")
+	appsReq, err := rhttp.NewRequest(ctx, "GET", wopiurl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	appsRes, err := p.client.Do(appsReq)
+	if err != nil {
+		return nil, err
+	}
+	defer appsRes.Body.Close()
+	if appsRes.StatusCode != http.StatusOK {
+		return nil, errtypes.InternalError(fmt.Sprintf("Request to WOPI server returned %d", appsRes.StatusCode))
+	}
+	appsBody, err := ioutil.ReadAll(appsRes.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	appsURLMap := make(map[string]interface{})
+	err = json.Unmarshal(appsBody, &appsURLMap)
+	if err != nil {
+		return nil, err
+	}
+
+	log := appctx.GetLogger(ctx)
+	log.Info().Msg(fmt.Sprintf("Successfully retrieved %d WOPI app endpoints", len(appsURLMap)))
+	return appsURLMap, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
+	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+const discoveryXML = `<?xml version="1.0" encoding="utf-8"?>
+<wopi-discovery>
+  <net-zone name="external-http">
+    <app name="writer">
+      <action name="view" ext="odt" urlsrc="http://internal/loleaflet/dist/loleaflet.html?"/>
+    </app>
+  </net-zone>
+  <net-zone name="external-https">
+    <app name="writer">
+      <action name="edit" ext="odt" urlsrc="https://office/loleaflet/dist/loleaflet.html?&lt;ui=UI_LLCC&amp;&gt;&lt;rs=DC_LLCC&amp;&gt;"/>
+      <action name="view" ext="pdf" urlsrc="https://office/loleaflet/dist/loleaflet.html?"/>
+    </app>
+  </net-zone>
+</wopi-discovery>`
+
+func TestParseDiscovery(t *testing.T) {
+	a, err := parseDiscovery(strings.NewReader(discoveryXML), "external-https")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u, ok := a.find(".odt", "edit", "view"); !ok || u != "https://office/loleaflet/dist/loleaflet.html?" {
+		t.Errorf("unexpected edit action %q", u)
+	}
+	// the actions missing in the preferred zone are taken from the other ones
+	if u, ok := a.find("ODT", "view"); !ok || u != "http://internal/loleaflet/dist/loleaflet.html?" {
+		t.Errorf("unexpected view action %q", u)
+	}
+	if _, ok := a.find(".pdf", "edit"); ok {
+		t.Error("pdf files can't be edited")
+	}
+
+	if got := appURL("https://office/app.html?", "https://wopi/files/1"); got != "https://office/app.html?WOPISrc=https%3A%2F%2Fwopi%2Ffiles%2F1" {
+		t.Errorf("unexpected app url %s", got)
+	}
+	if got := appURL("https://office/app.html?lang=en&", "x"); got != "https://office/app.html?lang=en&WOPISrc=x" {
+		t.Errorf("unexpected app url %s", got)
+	}
+}
+
+func TestGetAppURL(t *testing.T) {
+	discoveries := 0
+	office := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hosting/discovery" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		discoveries++
+		fmt.Fprint(w, discoveryXML)
+	}))
+	defer office.Close()
+
+	wopiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wopi/iop/open" || r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("TokenHeader") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "https://wopi/files/%s?mode=%s", r.URL.Query().Get("fileid"), r.URL.Query().Get("viewmode"))
+	}))
+	defer wopiserver.Close()
+
+	p, err := New(map[string]interface{}{
+		"wopi_url":    wopiserver.URL,
+		"iop_secret":  "secret",
+		"app_name":    "Collabora",
+		"app_url":     "https://office",
+		"app_int_url": office.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	ri := &providerpb.ResourceInfo{
+		Id:   &providerpb.ResourceId{StorageId: "s", OpaqueId: "1"},
+		Path: "/home/report.odt",
+	}
+	u, err := p.GetAppURL(ctx, ri, appprovider.OpenInAppRequest_VIEW_MODE_READ_WRITE, "token", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, "https://office/loleaflet/dist/loleaflet.html?WOPISrc=") || !strings.Contains(u, "VIEW_MODE_READ_WRITE") {
+		t.Errorf("unexpected app url %s", u)
+	}
+
+	u, err = p.GetAppURL(ctx, ri, appprovider.OpenInAppRequest_VIEW_MODE_READ_ONLY, "token", "Collabora")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, "http://internal/loleaflet/dist/loleaflet.html?WOPISrc=") {
+		t.Errorf("read only files should be opened with the view action, got %s", u)
+	}
+	if discoveries != 1 {
+		t.Errorf("the discovery should be cached, fetched it %d times", discoveries)
+	}
+
+	if _, err := p.GetAppURL(ctx, ri, appprovider.OpenInAppRequest_VIEW_MODE_READ_ONLY, "token", "OnlyOffice"); err == nil {
+		t.Error("opening files in another app should fail")
+	}
+	ri.Path = "/home/image.png"
+	if _, err := p.GetAppURL(ctx, ri, appprovider.OpenInAppRequest_VIEW_MODE_READ_ONLY, "token", ""); err == nil {
+		t.Error("opening files the app doesn't support should fail")
+	}
+}
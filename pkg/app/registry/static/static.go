@@ -29,11 +29,13 @@ import (
 )
 
 type registry struct {
-	rules map[string]string
+	rules       map[string]string
+	providers   []*app.ProviderInfo
+	defaultApps map[string]string
 }
 
 func (c *config) init() {
-	if len(c.Rules) == 0 {
+	if len(c.Rules) == 0 && len(c.Providers) == 0 {
 		c.Rules = map[string]string{
 			"text/plain": sharedconf.GetGatewaySVC(""),
 		}
@@ -41,16 +43,30 @@ func (c *config) init() {
 }
 
 func (b *registry) ListProviders(ctx context.Context) ([]*app.ProviderInfo, error) {
-	var providers = make([]*app.ProviderInfo, 0, len(b.rules))
+	var providers = make([]*app.ProviderInfo, 0, len(b.rules)+len(b.providers))
 	for _, address := range b.rules {
 		providers = append(providers, &app.ProviderInfo{
 			Location: address,
 		})
 	}
+	providers = append(providers, b.providers...)
 	return providers, nil
 }
 
-func (b *registry) FindProvider(ctx context.Context, mimeType string) (*app.ProviderInfo, error) {
+func (b *registry) FindProviders(ctx context.Context, mimeType string) ([]*app.ProviderInfo, error) {
+	var providers []*app.ProviderInfo
+	for _, p := range b.providers {
+		if !handles(p, mimeType) {
+			continue
+		}
+		// the default app of the mime type goes first
+		if p.Name == b.defaultApps[mimeType] {
+			providers = append([]*app.ProviderInfo{p}, providers...)
+		} else {
+			providers = append(providers, p)
+		}
+	}
+
 	// find longest match
 	var match string
 
@@ -60,18 +76,49 @@ func (b *registry) FindProvider(ctx context.Context, mimeType string) (*app.Prov
 		}
 	}
 
-	if match == "" {
+	if match != "" {
+		providers = append(providers, &app.ProviderInfo{
+			Location: b.rules[match],
+		})
+	}
+
+	if len(providers) == 0 {
 		return nil, errtypes.NotFound("application provider not found for mime type " + mimeType)
 	}
+	return providers, nil
+}
 
-	p := &app.ProviderInfo{
-		Location: b.rules[match],
+func (b *registry) DefaultApps(ctx context.Context) (map[string]string, error) {
+	apps := make(map[string]string, len(b.defaultApps))
+	for m, name := range b.defaultApps {
+		apps[m] = name
 	}
-	return p, nil
+	return apps, nil
+}
+
+func handles(p *app.ProviderInfo, mimeType string) bool {
+	for _, m := range p.MimeTypes {
+		if m == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+type providerConfig struct {
+	Address     string   `mapstructure:"address"`
+	Name        string   `mapstructure:"name"`
+	Description string   `mapstructure:"description"`
+	Icon        string   `mapstructure:"icon"`
+	MimeTypes   []string `mapstructure:"mimetypes"`
 }
 
 type config struct {
 	Rules map[string]string
+	// Providers are the app providers with the mime types they handle
+	Providers []*providerConfig `mapstructure:"providers"`
+	// DefaultApps maps mime types to the name of their default app
+	DefaultApps map[string]string `mapstructure:"default_apps"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -90,5 +137,30 @@ func New(m map[string]interface{}) (app.Registry, error) {
 		return nil, err
 	}
 	c.init()
-	return &registry{rules: c.Rules}, nil
+
+	providers := make([]*app.ProviderInfo, 0, len(c.Providers))
+	names := make(map[string]struct{}, len(c.Providers))
+	for _, p := range c.Providers {
+		if p.Address == "" || p.Name == "" {
+			return nil, errtypes.BadRequest("static: app providers need an address and a name")
+		}
+		if _, ok := names[p.Name]; ok {
+			return nil, errtypes.BadRequest("static: duplicate app provider " + p.Name)
+		}
+		names[p.Name] = struct{}{}
+		providers = append(providers, &app.ProviderInfo{
+			Location:    p.Address,
+			Name:        p.Name,
+			Description: p.Description,
+			Icon:        p.Icon,
+			MimeTypes:   p.MimeTypes,
+		})
+	}
+	for m, name := range c.DefaultApps {
+		if _, ok := names[name]; !ok {
+			return nil, errtypes.BadRequest("static: unknown default app " + name + " for mime type " + m)
+		}
+	}
+
+	return &registry{rules: c.Rules, providers: providers, defaultApps: c.DefaultApps}, nil
 }
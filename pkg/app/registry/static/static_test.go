@@ -0,0 +1,79 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package static
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindProviders(t *testing.T) {
+	r, err := New(map[string]interface{}{
+		"rules": map[string]interface{}{"text/": "localhost:19000"},
+		"providers": []map[string]interface{}{
+			{"name": "Collabora", "address": "localhost:19001", "mimetypes": []string{"application/vnd.oasis.opendocument.text", "text/plain"}},
+			{"name": "OnlyOffice", "address": "localhost:19002", "mimetypes": []string{"application/vnd.oasis.opendocument.text"}},
+		},
+		"default_apps": map[string]string{"application/vnd.oasis.opendocument.text": "OnlyOffice"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	ps, err := r.FindProviders(ctx, "application/vnd.oasis.opendocument.text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 || ps[0].Name != "OnlyOffice" || ps[1].Name != "Collabora" {
+		t.Errorf("the default app should go first, got %+v", ps)
+	}
+
+	ps, err = r.FindProviders(ctx, "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 || ps[0].Name != "Collabora" || ps[1].Location != "localhost:19000" {
+		t.Errorf("the providers of the rules should come after the apps, got %+v", ps)
+	}
+
+	if _, err := r.FindProviders(ctx, "image/png"); err == nil {
+		t.Error("no provider should be found for images")
+	}
+
+	defaults, err := r.DefaultApps(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defaults) != 1 || defaults["application/vnd.oasis.opendocument.text"] != "OnlyOffice" {
+		t.Errorf("unexpected default apps %v", defaults)
+	}
+}
+
+func TestNewInvalidDefaultApp(t *testing.T) {
+	_, err := New(map[string]interface{}{
+		"providers": []map[string]interface{}{
+			{"name": "Collabora", "address": "localhost:19001", "mimetypes": []string{"text/plain"}},
+		},
+		"default_apps": map[string]string{"text/plain": "OnlyOffice"},
+	})
+	if err == nil {
+		t.Error("a default app must be one of the providers")
+	}
+}
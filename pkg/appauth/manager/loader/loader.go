@@ -21,5 +21,6 @@ package loader
 import (
 	// Load core application auth manager drivers.
 	_ "github.com/cs3org/reva/pkg/appauth/manager/json"
+	_ "github.com/cs3org/reva/pkg/appauth/manager/sql"
 	// Add your own here
 )
@@ -0,0 +1,292 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements an app password manager storing the passwords in a
+// SQL database, MySQL, PostgreSQL or SQLite, whose schema is created and
+// upgraded on startup. Only the SHA-256 hashes of the passwords are stored,
+// they are listed in place of the passwords and identify them to be
+// invalidated.
+package sql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	apppb "github.com/cs3org/go-cs3apis/cs3/auth/applications/v1beta1"
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appauth"
+	"github.com/cs3org/reva/pkg/appauth/manager/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/sqlmigrate"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/sethvargo/go-password/password"
+
+	// Provide the supported database drivers.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const migrationsTable = "app_passwords_migrations"
+
+// columns are the columns an app password is read from, in the order of
+// dbPassword.dest.
+const columns = "password_hash, user_idp, user_id, label, scope, expiration, ctime, utime"
+
+var migrations = []sqlmigrate.Migration{
+	{
+		Version:     1,
+		Description: "create the app passwords table",
+		Up: map[sqlmigrate.Dialect][]string{
+			"": {
+				`CREATE TABLE app_passwords (
+					password_hash VARCHAR(64) NOT NULL PRIMARY KEY,
+					user_idp VARCHAR(255) NOT NULL,
+					user_id VARCHAR(255) NOT NULL,
+					label VARCHAR(255) NOT NULL,
+					scope TEXT NOT NULL,
+					expiration BIGINT NULL,
+					ctime BIGINT NOT NULL,
+					utime BIGINT NOT NULL
+				)`,
+				"CREATE INDEX app_passwords_user ON app_passwords (user_id)",
+			},
+		},
+	},
+}
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	// DbDriver is the database/sql driver: mysql, postgres or sqlite3.
+	DbDriver       string `mapstructure:"db_driver"`
+	DbDSN          string `mapstructure:"db_dsn"`
+	SkipMigrations bool   `mapstructure:"db_skip_migrations"`
+	TokenStrength  int    `mapstructure:"token_strength"`
+	// UsageInterval is the number of seconds the last use of a password
+	// is recorded with, to not write on every authentication.
+	UsageInterval int `mapstructure:"usage_interval"`
+}
+
+func (c *config) init() {
+	if c.DbDriver == "" {
+		c.DbDriver = string(sqlmigrate.SQLite)
+	}
+	if c.DbDSN == "" && c.DbDriver == string(sqlmigrate.SQLite) {
+		c.DbDSN = "/var/tmp/reva/appauth.db"
+	}
+	if c.TokenStrength == 0 {
+		c.TokenStrength = 32
+	}
+	if c.UsageInterval == 0 {
+		c.UsageInterval = 60
+	}
+}
+
+type manager struct {
+	c       *config
+	dialect sqlmigrate.Dialect
+	db      *sql.DB
+}
+
+// New returns an app password manager storing the passwords in a SQL database.
+func New(m map[string]interface{}) (appauth.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding conf")
+	}
+	c.init()
+
+	dialect := sqlmigrate.Dialect(c.DbDriver)
+	switch dialect {
+	case sqlmigrate.MySQL, sqlmigrate.Postgres:
+	case sqlmigrate.SQLite:
+		if err := os.MkdirAll(filepath.Dir(c.DbDSN), 0755); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errtypes.NotSupported("sql: unsupported database driver " + c.DbDriver)
+	}
+
+	db, err := sql.Open(c.DbDriver, c.DbDSN)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error opening the database")
+	}
+	if !c.SkipMigrations {
+		if err := sqlmigrate.Apply(context.Background(), db, dialect, migrationsTable, migrations); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	return &manager{
+		c:       c,
+		dialect: dialect,
+		db:      db,
+	}, nil
+}
+
+// dbPassword is the row of an app password.
+type dbPassword struct {
+	hash       string
+	userIdp    string
+	userID     string
+	label      string
+	scope      string
+	expiration sql.NullInt64
+	ctime      int64
+	utime      int64
+}
+
+func (p *dbPassword) dest() []interface{} {
+	return []interface{}{&p.hash, &p.userIdp, &p.userID, &p.label, &p.scope, &p.expiration, &p.ctime, &p.utime}
+}
+
+func (p *dbPassword) appPassword() (*apppb.AppPassword, error) {
+	var scope map[string]*authpb.Scope
+	if err := json.Unmarshal([]byte(p.scope), &scope); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding the scope of the app password")
+	}
+	pw := &apppb.AppPassword{
+		Password:   p.hash,
+		TokenScope: scope,
+		Label:      p.label,
+		User:       &userpb.UserId{Idp: p.userIdp, OpaqueId: p.userID},
+		Ctime:      &typespb.Timestamp{Seconds: uint64(p.ctime)},
+		Utime:      &typespb.Timestamp{Seconds: uint64(p.utime)},
+	}
+	if p.expiration.Valid {
+		pw.Expiration = &typespb.Timestamp{Seconds: uint64(p.expiration.Int64)}
+	}
+	return pw, nil
+}
+
+func hash(password string) string {
+	h := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(h[:])
+}
+
+func (m *manager) GenerateAppPassword(ctx context.Context, scope map[string]*authpb.Scope, label string, expiration *typespb.Timestamp) (*apppb.AppPassword, error) {
+	token, err := password.Generate(m.c.TokenStrength, 10, 10, false, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating new token")
+	}
+	scopeJSON, err := json.Marshal(scope)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error encoding the scope of the app password")
+	}
+	userID := user.ContextMustGetUser(ctx).GetId()
+	now := time.Now().Unix()
+
+	var exp sql.NullInt64
+	if expiration != nil && expiration.Seconds != 0 {
+		exp = sql.NullInt64{Int64: int64(expiration.Seconds), Valid: true}
+	}
+
+	query := m.dialect.Rebind("INSERT INTO app_passwords (" + columns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if _, err := m.db.ExecContext(ctx, query, hash(token), userID.Idp, userID.OpaqueId, label, string(scopeJSON), exp, now, now); err != nil {
+		return nil, errors.Wrap(err, "sql: error storing the app password")
+	}
+
+	return &apppb.AppPassword{
+		Password:   token,
+		TokenScope: scope,
+		Label:      label,
+		Expiration: expiration,
+		Ctime:      &typespb.Timestamp{Seconds: uint64(now)},
+		Utime:      &typespb.Timestamp{Seconds: uint64(now)},
+		User:       userID,
+	}, nil
+}
+
+func (m *manager) ListAppPasswords(ctx context.Context) ([]*apppb.AppPassword, error) {
+	userID := user.ContextMustGetUser(ctx).GetId()
+	query := m.dialect.Rebind("SELECT " + columns + " FROM app_passwords WHERE user_id = ? AND user_idp = ? ORDER BY ctime")
+	rows, err := m.db.QueryContext(ctx, query, userID.OpaqueId, userID.Idp)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error listing the app passwords")
+	}
+	defer rows.Close()
+
+	appPasswords := []*apppb.AppPassword{}
+	for rows.Next() {
+		var p dbPassword
+		if err := rows.Scan(p.dest()...); err != nil {
+			return nil, errors.Wrap(err, "sql: error reading the app passwords")
+		}
+		pw, err := p.appPassword()
+		if err != nil {
+			return nil, err
+		}
+		appPasswords = append(appPasswords, pw)
+	}
+	return appPasswords, rows.Err()
+}
+
+// InvalidateAppPassword removes a password given either the password or its
+// hash, as listed by ListAppPasswords
+func (m *manager) InvalidateAppPassword(ctx context.Context, secret string) error {
+	userID := user.ContextMustGetUser(ctx).GetId()
+	query := m.dialect.Rebind("DELETE FROM app_passwords WHERE password_hash IN (?, ?) AND user_id = ? AND user_idp = ?")
+	res, err := m.db.ExecContext(ctx, query, secret, hash(secret), userID.OpaqueId, userID.Idp)
+	if err != nil {
+		return errors.Wrap(err, "sql: error invalidating the app password")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errtypes.NotFound("password not found")
+	}
+	return nil
+}
+
+func (m *manager) GetAppPassword(ctx context.Context, userID *userpb.UserId, secret string) (*apppb.AppPassword, error) {
+	var p dbPassword
+	query := m.dialect.Rebind("SELECT " + columns + " FROM app_passwords WHERE password_hash = ? AND user_id = ? AND user_idp = ?")
+	err := m.db.QueryRowContext(ctx, query, hash(secret), userID.OpaqueId, userID.Idp).Scan(p.dest()...)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errtypes.NotFound("password not found")
+	case err != nil:
+		return nil, errors.Wrap(err, "sql: error getting the app password")
+	}
+
+	now := time.Now().Unix()
+	if p.expiration.Valid && p.expiration.Int64 != 0 && now > p.expiration.Int64 {
+		return nil, errtypes.NotFound("password not found")
+	}
+
+	if now-p.utime >= int64(m.c.UsageInterval) {
+		query := m.dialect.Rebind("UPDATE app_passwords SET utime = ? WHERE password_hash = ?")
+		if _, err := m.db.ExecContext(ctx, query, now, p.hash); err != nil {
+			return nil, errors.Wrap(err, "sql: error recording the use of the app password")
+		}
+		p.utime = now
+	}
+	return p.appPassword()
+}
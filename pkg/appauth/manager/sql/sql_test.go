@@ -0,0 +1,107 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+func TestAppPasswords(t *testing.T) {
+	m, err := New(map[string]interface{}{
+		"db_driver": "sqlite3",
+		"db_dsn":    filepath.Join(t.TempDir(), "appauth.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	einstein := &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}}
+	marie := &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "marie"}}
+	ctx := user.ContextSetUser(context.Background(), einstein)
+	scope := map[string]*authpb.Scope{"user": {Role: authpb.Role_ROLE_OWNER}}
+	past := &typespb.Timestamp{Seconds: uint64(time.Now().Add(-time.Hour).Unix())}
+
+	laptop, err := m.GenerateAppPassword(ctx, scope, "laptop", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GenerateAppPassword(ctx, scope, "expired", past); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.GetAppPassword(ctx, einstein.Id, laptop.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Label != "laptop" || got.TokenScope["user"].Role != authpb.Role_ROLE_OWNER {
+		t.Fatalf("unexpected app password %v", got)
+	}
+	if got.Password == laptop.Password {
+		t.Fatal("the password must not be stored")
+	}
+	if _, err := m.GetAppPassword(ctx, marie.Id, laptop.Password); err == nil {
+		t.Fatal("the password of a user must not authenticate another one")
+	}
+	if _, err := m.GetAppPassword(ctx, einstein.Id, "wrong"); err == nil {
+		t.Fatal("unknown passwords must be rejected")
+	}
+
+	list, err := m.ListAppPasswords(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 app passwords, got %d", len(list))
+	}
+	for _, pw := range list {
+		if pw.Label == "expired" {
+			if _, err := m.GetAppPassword(ctx, einstein.Id, pw.Password); err == nil {
+				t.Fatal("expired passwords must be rejected")
+			}
+		}
+	}
+	if others, _ := m.ListAppPasswords(user.ContextSetUser(context.Background(), marie)); len(others) != 0 {
+		t.Fatalf("unexpected app passwords of another user %v", others)
+	}
+
+	// the passwords are invalidated by their listed hash
+	if err := m.InvalidateAppPassword(user.ContextSetUser(context.Background(), marie), got.Password); err == nil {
+		t.Fatal("the passwords of other users must not be invalidated")
+	}
+	if err := m.InvalidateAppPassword(ctx, got.Password); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetAppPassword(ctx, einstein.Id, laptop.Password); err == nil {
+		t.Fatal("invalidated passwords must be rejected")
+	}
+	if err := m.InvalidateAppPassword(ctx, got.Password); err == nil {
+		t.Fatal("invalidating twice must fail")
+	} else if _, ok := err.(errtypes.NotFound); !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package appctx
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/cs3org/reva/pkg/user"
+	"google.golang.org/grpc/metadata"
+)
+
+// DetachContext returns a context carrying the logger, user, token and grpc
+// metadata of ctx which is not canceled when ctx is. It is used for the work
+// that outlives the request that triggered it.
+func DetachContext(ctx context.Context) context.Context {
+	c := WithLogger(context.Background(), GetLogger(ctx))
+	if u, ok := user.ContextGetUser(ctx); ok {
+		c = user.ContextSetUser(c, u)
+	}
+	if t, ok := token.ContextGetToken(ctx); ok {
+		c = token.ContextSetToken(c, t)
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		c = metadata.NewOutgoingContext(c, md)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		c = metadata.NewIncomingContext(c, md)
+	}
+	return c
+}
@@ -0,0 +1,256 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package audit records permission relevant operations in a hash chained
+// log. Every record carries the hash of its predecessor, so removing,
+// reordering or altering records breaks the chain and is detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The actions recorded in the audit log.
+const (
+	ActionGrantAdd         = "grant.add"
+	ActionGrantUpdate      = "grant.update"
+	ActionGrantRemove      = "grant.remove"
+	ActionShareCreate      = "share.create"
+	ActionShareUpdate      = "share.update"
+	ActionShareRemove      = "share.remove"
+	ActionShareState       = "share.state"
+	ActionPublicLinkCreate = "publiclink.create"
+	ActionPublicLinkUpdate = "publiclink.update"
+	ActionPublicLinkRemove = "publiclink.remove"
+	ActionPublicLinkAccess = "publiclink.access"
+	ActionImpersonate      = "impersonate"
+	ActionTrashPurge       = "trash.purge"
+	ActionOCMShareCreate   = "ocmshare.create"
+)
+
+// Record is a single entry of the audit log.
+type Record struct {
+	Seq      uint64            `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Action   string            `json:"action"`
+	Method   string            `json:"method"`
+	Actor    string            `json:"actor,omitempty"`
+	Username string            `json:"username,omitempty"`
+	From     string            `json:"from,omitempty"`
+	Target   map[string]string `json:"target,omitempty"`
+	Status   string            `json:"status"`
+	PrevHash string            `json:"prev_hash"`
+	Hash     string            `json:"hash"`
+}
+
+// Sink receives the encoded records of the audit log, one JSON object per
+// call, in the order of the chain.
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// Logger appends records to the hash chain and writes them to its sinks.
+type Logger struct {
+	mu    sync.Mutex
+	key   []byte
+	state string
+	sinks []Sink
+	seq   uint64
+	prev  string
+}
+
+type chainState struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// New returns a logger writing to the given sinks. The position of the chain
+// is kept in the state file so that it continues across restarts. When a key
+// is given the records are chained with HMAC-SHA256 instead of SHA-256, which
+// prevents rewriting the log without knowing the key.
+func New(key []byte, state string, sinks ...Sink) (*Logger, error) {
+	l := &Logger{key: key, state: state, sinks: sinks}
+	if state == "" {
+		return l, nil
+	}
+	b, err := ioutil.ReadFile(state)
+	switch {
+	case os.IsNotExist(err):
+		return l, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "audit: error reading chain state")
+	}
+	var s chainState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, errors.Wrap(err, "audit: error decoding chain state")
+	}
+	l.seq, l.prev = s.Seq, s.Hash
+	return l, nil
+}
+
+// Log appends the record to the chain and writes it to all sinks. The
+// sequence number and the hashes of the record are set by the logger.
+// All sinks are written even if one of them fails, the first error is
+// returned.
+func (l *Logger) Log(r *Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r.Seq = l.seq + 1
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.Time = r.Time.UTC()
+	r.PrevHash = l.prev
+	h, err := sum(l.key, r)
+	if err != nil {
+		return err
+	}
+	r.Hash = h
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "audit: error encoding record")
+	}
+
+	var first error
+	for _, s := range l.sinks {
+		if err := s.Write(line); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	l.seq, l.prev = r.Seq, r.Hash
+	if err := l.saveState(); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
+
+// Close closes all sinks.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var first error
+	for _, s := range l.sinks {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (l *Logger) saveState() error {
+	if l.state == "" {
+		return nil
+	}
+	b, err := json.Marshal(chainState{Seq: l.seq, Hash: l.prev})
+	if err != nil {
+		return errors.Wrap(err, "audit: error encoding chain state")
+	}
+	tmp := l.state + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(l.state), 0700); err != nil {
+		return errors.Wrap(err, "audit: error creating chain state dir")
+	}
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "audit: error writing chain state")
+	}
+	return errors.Wrap(os.Rename(tmp, l.state), "audit: error writing chain state")
+}
+
+// sum computes the hash of the record, which covers all fields but the hash
+// itself. The hash of the previous record is part of the input and links the
+// records together.
+func sum(key []byte, r *Record) (string, error) {
+	c := *r
+	c.Hash = ""
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "audit: error encoding record")
+	}
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	_, _ = h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify checks the chain of the records read from r, one JSON record per
+// line, and returns the number of verified records. The first record may
+// continue a chain started in an earlier, rotated log.
+func Verify(r io.Reader, key []byte) (int, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var n int
+	var prev *Record
+	for s.Scan() {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		rec := &Record{}
+		if err := json.Unmarshal(s.Bytes(), rec); err != nil {
+			return n, errors.Wrapf(err, "audit: error decoding record after seq %d", lastSeq(prev))
+		}
+		if prev != nil {
+			if rec.Seq != prev.Seq+1 {
+				return n, errors.Errorf("audit: record %d follows record %d", rec.Seq, prev.Seq)
+			}
+			if rec.PrevHash != prev.Hash {
+				return n, errors.Errorf("audit: record %d is not chained to record %d", rec.Seq, prev.Seq)
+			}
+		}
+		h, err := sum(key, rec)
+		if err != nil {
+			return n, err
+		}
+		if !hmac.Equal([]byte(h), []byte(rec.Hash)) {
+			return n, errors.Errorf("audit: record %d has been modified", rec.Seq)
+		}
+		prev = rec
+		n++
+	}
+	if err := s.Err(); err != nil {
+		return n, errors.Wrap(err, "audit: error reading records")
+	}
+	return n, nil
+}
+
+func lastSeq(r *Record) uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.Seq
+}
@@ -0,0 +1,119 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package audit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type bufferSink struct {
+	bytes.Buffer
+}
+
+func (s *bufferSink) Write(line []byte) error {
+	s.Buffer.Write(line)
+	s.Buffer.WriteByte('\n')
+	return nil
+}
+
+func (s *bufferSink) Close() error {
+	return nil
+}
+
+func logRecords(t *testing.T, l *Logger, actions ...string) {
+	for _, a := range actions {
+		err := l.Log(&Record{
+			Action: a,
+			Method: "/cs3.gateway.v1beta1.GatewayAPI/CreateShare",
+			Actor:  "einstein",
+			Target: map[string]string{"path": "/home/file", "grantee": "marie"},
+			Status: "CODE_OK",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("secret")
+	s := &bufferSink{}
+	l, err := New(key, "", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, ActionShareCreate, ActionPublicLinkAccess, ActionTrashPurge)
+	log := s.String()
+
+	if n, err := Verify(strings.NewReader(log), key); err != nil || n != 3 {
+		t.Fatalf("valid log: got %d records and error %v", n, err)
+	}
+	if _, err := Verify(strings.NewReader(log), []byte("other")); err == nil {
+		t.Fatal("log verified with the wrong key")
+	}
+
+	lines := strings.SplitAfter(log, "\n")
+	tests := map[string]string{
+		"modified":  strings.Replace(log, "marie", "richard", 1),
+		"removed":   lines[0] + lines[2],
+		"reordered": lines[1] + lines[0] + lines[2],
+	}
+	for name, tampered := range tests {
+		if _, err := Verify(strings.NewReader(tampered), key); err == nil {
+			t.Errorf("%s log verified", name)
+		}
+	}
+
+	// a rotated log continues the chain of the previous one
+	if n, err := Verify(strings.NewReader(lines[1]+lines[2]), key); err != nil || n != 2 {
+		t.Fatalf("rotated log: got %d records and error %v", n, err)
+	}
+}
+
+func TestChainState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	state := filepath.Join(dir, "audit.chain")
+
+	s := &bufferSink{}
+	l, err := New(nil, state, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, ActionGrantAdd)
+
+	// the restarted logger continues the chain
+	l, err = New(nil, state, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logRecords(t, l, ActionGrantRemove)
+
+	if n, err := Verify(strings.NewReader(s.String()), nil); err != nil || n != 2 {
+		t.Fatalf("got %d records and error %v", n, err)
+	}
+}
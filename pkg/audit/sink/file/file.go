@@ -0,0 +1,91 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/audit"
+	"github.com/cs3org/reva/pkg/audit/sink/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("file", New)
+}
+
+type config struct {
+	// File is the file the records are appended to.
+	File string `mapstructure:"file"`
+	// Sync flushes every record to disk before the call returns.
+	Sync bool `mapstructure:"sync"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/log/reva/audit.log"
+	}
+}
+
+type sink struct {
+	mu   sync.Mutex
+	fd   *os.File
+	sync bool
+}
+
+// New returns a sink appending the records to a file, one per line.
+func New(m map[string]interface{}) (audit.Sink, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "file: error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(filepath.Dir(c.File), 0700); err != nil {
+		return nil, errors.Wrap(err, "file: error creating audit log dir")
+	}
+	fd, err := os.OpenFile(c.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "file: error opening audit log")
+	}
+	return &sink{fd: fd, sync: c.Sync}, nil
+}
+
+func (s *sink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := make([]byte, 0, len(line)+1)
+	b = append(append(b, line...), '\n')
+	if _, err := s.fd.Write(b); err != nil {
+		return errors.Wrap(err, "file: error writing audit record")
+	}
+	if s.sync {
+		return errors.Wrap(s.fd.Sync(), "file: error syncing audit log")
+	}
+	return nil
+}
+
+func (s *sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fd.Close()
+}
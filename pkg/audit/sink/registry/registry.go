@@ -0,0 +1,58 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"fmt"
+
+	"github.com/cs3org/reva/pkg/audit"
+)
+
+// NewFunc is the function that audit sinks
+// should register at init time.
+type NewFunc func(map[string]interface{}) (audit.Sink, error)
+
+// NewFuncs is a map containing all the registered audit sinks.
+var NewFuncs = map[string]NewFunc{}
+
+// Register registers a new audit sink new function.
+// Not safe for concurrent use. Safe for use from package init.
+func Register(name string, f NewFunc) {
+	NewFuncs[name] = f
+}
+
+// NewSinks returns the named audit sinks with their configurations.
+func NewSinks(configs map[string]map[string]interface{}) ([]audit.Sink, error) {
+	sinks := make([]audit.Sink, 0, len(configs))
+	for name, c := range configs {
+		f, ok := NewFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("audit sink not found: %s", name)
+		}
+		s, err := f(c)
+		if err != nil {
+			for _, s := range sinks {
+				_ = s.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
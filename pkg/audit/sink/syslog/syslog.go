@@ -0,0 +1,59 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package syslog
+
+import (
+	"github.com/cs3org/reva/pkg/audit"
+	"github.com/cs3org/reva/pkg/audit/sink/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("syslog", New)
+}
+
+type config struct {
+	// Network and Address of the syslog server, e.g. udp and
+	// localhost:514. The local syslog daemon is used if left empty.
+	Network string `mapstructure:"network"`
+	Address string `mapstructure:"address"`
+	// Facility is one of auth, authpriv, daemon, user and local0 to local7.
+	Facility string `mapstructure:"facility"`
+	Tag      string `mapstructure:"tag"`
+}
+
+func (c *config) init() {
+	if c.Facility == "" {
+		c.Facility = "authpriv"
+	}
+	if c.Tag == "" {
+		c.Tag = "reva-audit"
+	}
+}
+
+// New returns a sink sending the records to syslog, one message per record.
+func New(m map[string]interface{}) (audit.Sink, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "syslog: error decoding conf")
+	}
+	c.init()
+	return dial(c)
+}
@@ -0,0 +1,68 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package syslog
+
+import (
+	"log/syslog"
+
+	"github.com/cs3org/reva/pkg/audit"
+	"github.com/pkg/errors"
+)
+
+var facilities = map[string]syslog.Priority{
+	"auth":     syslog.LOG_AUTH,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"daemon":   syslog.LOG_DAEMON,
+	"user":     syslog.LOG_USER,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+type sink struct {
+	w *syslog.Writer
+}
+
+func dial(c *config) (audit.Sink, error) {
+	f, ok := facilities[c.Facility]
+	if !ok {
+		return nil, errors.Errorf("syslog: unknown facility %q", c.Facility)
+	}
+	w, err := syslog.Dial(c.Network, c.Address, f|syslog.LOG_NOTICE, c.Tag)
+	if err != nil {
+		return nil, errors.Wrap(err, "syslog: error connecting")
+	}
+	return &sink{w: w}, nil
+}
+
+func (s *sink) Write(line []byte) error {
+	return errors.Wrap(s.w.Notice(string(line)), "syslog: error writing audit record")
+}
+
+func (s *sink) Close() error {
+	return s.w.Close()
+}
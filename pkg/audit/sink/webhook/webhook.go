@@ -0,0 +1,192 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cs3org/reva/pkg/audit"
+	"github.com/cs3org/reva/pkg/audit/sink/registry"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register("webhook", New)
+}
+
+type config struct {
+	// URL is the endpoint the records are posted to as JSON.
+	URL string `mapstructure:"url"`
+	// Secret signs the records, the signature is sent in the
+	// X-Reva-Signature header as sha256=<hex encoded HMAC-SHA256>.
+	Secret  string            `mapstructure:"secret"`
+	Headers map[string]string `mapstructure:"headers"`
+	// Timeout is the timeout in seconds of a delivery attempt.
+	Timeout int `mapstructure:"timeout"`
+	// Attempts is how many times a record is delivered before giving up.
+	Attempts int `mapstructure:"attempts"`
+	// Queue is the number of records waiting for delivery, the records
+	// written to a full queue are rejected.
+	Queue int `mapstructure:"queue"`
+}
+
+func (c *config) init() {
+	if c.Timeout == 0 {
+		c.Timeout = 10
+	}
+	if c.Attempts == 0 {
+		c.Attempts = 3
+	}
+	if c.Queue == 0 {
+		c.Queue = 1000
+	}
+}
+
+type sink struct {
+	conf   *config
+	client *http.Client
+	queue  chan []byte
+	wg     sync.WaitGroup
+	probe  string
+	// failed is the number of records that could not be delivered,
+	// failing is set while the last delivery failed.
+	failed  uint64
+	failing int32
+}
+
+// New returns a sink posting every record to a webhook. The records are
+// delivered in order by a single worker so that the receiver sees a
+// continuous chain.
+func New(m map[string]interface{}) (audit.Sink, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "webhook: error decoding conf")
+	}
+	c.init()
+	u, err := url.Parse(c.URL)
+	if err != nil || c.URL == "" {
+		return nil, errors.New("webhook: a valid url is required")
+	}
+
+	s := &sink{
+		conf:   c,
+		client: rhttp.GetHTTPClient(rhttp.Timeout(time.Duration(c.Timeout) * time.Second)),
+		queue:  make(chan []byte, c.Queue),
+		probe:  "audit/webhook/" + u.Host,
+	}
+	s.wg.Add(1)
+	go s.run()
+	ops.Register(ops.Queues, s.probe, s.state)
+	return s, nil
+}
+
+// state reports the delivery queue, the sink is degraded while the queue is
+// full or the records can't be delivered.
+func (s *sink) state() ops.State {
+	depth, failed := len(s.queue), atomic.LoadUint64(&s.failed)
+	health := ops.Healthy
+	if depth == cap(s.queue) || atomic.LoadInt32(&s.failing) == 1 {
+		health = ops.Degraded
+	}
+	return ops.State{
+		Health: health,
+		Details: map[string]interface{}{
+			"depth":    depth,
+			"capacity": cap(s.queue),
+			"failed":   failed,
+		},
+	}
+}
+
+func (s *sink) Write(line []byte) error {
+	select {
+	case s.queue <- line:
+		return nil
+	default:
+		return errors.New("webhook: delivery queue is full, record dropped")
+	}
+}
+
+func (s *sink) Close() error {
+	ops.Unregister(ops.Queues, s.probe)
+	close(s.queue)
+	s.wg.Wait()
+	return nil
+}
+
+func (s *sink) run() {
+	defer s.wg.Done()
+	for line := range s.queue {
+		var err error
+		for i := 0; i < s.conf.Attempts; i++ {
+			if i > 0 {
+				time.Sleep(time.Duration(i) * time.Second)
+			}
+			if err = s.post(line); err == nil {
+				break
+			}
+		}
+		if err == nil {
+			atomic.StoreInt32(&s.failing, 0)
+			continue
+		}
+		atomic.AddUint64(&s.failed, 1)
+		atomic.StoreInt32(&s.failing, 1)
+		log.Error().Err(err).Str("url", s.conf.URL).Bytes("record", line).Msg("webhook: error delivering audit record")
+	}
+}
+
+func (s *sink) post(line []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.conf.URL, bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.conf.Headers {
+		req.Header.Set(k, v)
+	}
+	if s.conf.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.conf.Secret))
+		mac.Write(line)
+		req.Header.Set("X-Reva-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook: unexpected status %s", res.Status)
+	}
+	return nil
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 )
@@ -48,6 +49,7 @@ func New(m map[string]interface{}) (auth.Manager, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "error decoding conf")
 	}
+	mgr.GatewayAddr = sharedconf.GetGatewaySVC(mgr.GatewayAddr)
 	return mgr, nil
 }
 
@@ -0,0 +1,117 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package guest provides an auth manager wrapping another auth manager to
+// let the guest accounts log in as well.
+package guest
+
+import (
+	"context"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/guest"
+	guestregistry "github.com/cs3org/reva/pkg/guest/manager/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	// Load the guest manager drivers.
+	_ "github.com/cs3org/reva/pkg/guest/manager/loader"
+)
+
+func init() {
+	registry.Register("guest", New)
+}
+
+type config struct {
+	// Driver is the auth manager authenticating the regular users.
+	Driver  string                            `mapstructure:"driver" docs:"json"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// GuestDriver is the guest manager providing the guest accounts.
+	GuestDriver  string                            `mapstructure:"guest_driver" docs:"json"`
+	GuestDrivers map[string]map[string]interface{} `mapstructure:"guest_drivers"`
+	// ShareFolder is the folder of the home of the guests holding the
+	// shares they received, the only one they can access.
+	ShareFolder string `mapstructure:"share_folder" docs:"MyShares"`
+}
+
+func (c *config) init() {
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.GuestDriver == "" {
+		c.GuestDriver = "json"
+	}
+	if c.ShareFolder == "" {
+		c.ShareFolder = "MyShares"
+	}
+}
+
+type manager struct {
+	c      *config
+	auth   auth.Manager
+	guests guest.Manager
+}
+
+// New returns an auth manager authenticating the guest accounts with the
+// guest manager and the other users with the configured auth manager.
+func New(m map[string]interface{}) (auth.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "guest: error decoding conf")
+	}
+	c.init()
+	if c.Driver == "guest" {
+		return nil, errtypes.BadRequest("guest: the guest auth manager can't wrap itself")
+	}
+
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("guest: auth manager driver not found: " + c.Driver)
+	}
+	a, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+
+	gf, ok := guestregistry.NewFuncs[c.GuestDriver]
+	if !ok {
+		return nil, errtypes.NotFound("guest: guest manager driver not found: " + c.GuestDriver)
+	}
+	guests, err := gf(c.GuestDrivers[c.GuestDriver])
+	if err != nil {
+		return nil, err
+	}
+	return &manager{c: c, auth: a, guests: guests}, nil
+}
+
+func (m *manager) Authenticate(ctx context.Context, username, secret string) (*userpb.User, map[string]*authpb.Scope, error) {
+	if _, err := m.guests.GetGuestByClaim(ctx, "username", username); err != nil {
+		return m.auth.Authenticate(ctx, username, secret)
+	}
+
+	u, err := m.guests.Authenticate(ctx, username, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, scope.GetGuestScope(m.c.ShareFolder), nil
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
 	"github.com/cs3org/reva/pkg/auth/scope"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/health"
 	"github.com/cs3org/reva/pkg/logger"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/sharedconf"
@@ -119,9 +120,21 @@ func New(m map[string]interface{}) (auth.Manager, error) {
 
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 
-	return &mgr{
+	am := &mgr{
 		c: c,
-	}, nil
+	}
+	health.Register("auth/ldap", am.check)
+	return am, nil
+}
+
+// check binds with the read only user, which every authentication starts with.
+func (am *mgr) check(ctx context.Context) error {
+	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", am.c.Hostname, am.c.Port), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return l.Bind(am.c.BindUsername, am.c.BindPassword)
 }
 
 func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string) (*user.User, map[string]*authpb.Scope, error) {
@@ -0,0 +1,149 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package machine authenticates trusted services acting on behalf of users.
+// The services send a shared API key as secret and the user to impersonate as
+// client ID, in the form <claim>:<value>, where the claim is one of the claims
+// of the user providers, e.g. username:einstein, or userid:<opaqueid>@<idp>.
+package machine
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("machine", New)
+}
+
+// userIDClaim is the claim identifying the users by their ID
+const userIDClaim = "userid"
+
+type config struct {
+	APIKey      string `mapstructure:"api_key" docs:";The API key shared with the trusted services."`
+	GatewayAddr string `mapstructure:"gateway_addr" docs:";The address of the gateway looking up the users."`
+}
+
+func (c *config) init() {
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
+}
+
+type manager struct {
+	c *config
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns an auth manager implementation authenticating the trusted
+// services with an API key on behalf of the users.
+func New(m map[string]interface{}) (auth.Manager, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+
+	if c.APIKey == "" {
+		return nil, errors.New("machine: api_key is not defined in config")
+	}
+
+	return &manager{c: c}, nil
+}
+
+func (m *manager) Authenticate(ctx context.Context, clientID, clientSecret string) (*user.User, map[string]*authpb.Scope, error) {
+	if subtle.ConstantTimeCompare([]byte(clientSecret), []byte(m.c.APIKey)) != 1 {
+		return nil, nil, errtypes.InvalidCredentials("machine: invalid api key")
+	}
+
+	parts := strings.SplitN(clientID, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, nil, errtypes.BadRequest("machine: the client id must be of the form <claim>:<value>")
+	}
+	claim, value := parts[0], parts[1]
+
+	u, err := m.getUser(ctx, claim, value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scope, err := scope.GetOwnerScope()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, scope, nil
+}
+
+func (m *manager) getUser(ctx context.Context, claim, value string) (*user.User, error) {
+	gtw, err := pool.GetGatewayServiceClient(m.c.GatewayAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if claim == userIDClaim {
+		// allow passing in uid as <opaqueid>@<idp>
+		uid := &user.UserId{OpaqueId: value}
+		if at := strings.LastIndex(value, "@"); at >= 0 {
+			uid.OpaqueId, uid.Idp = value[:at], value[at+1:]
+		}
+		res, err := gtw.GetUser(ctx, &user.GetUserRequest{UserId: uid})
+		switch {
+		case err != nil:
+			return nil, err
+		case res.Status.Code == rpcv1beta1.Code_CODE_NOT_FOUND:
+			return nil, errtypes.NotFound(res.Status.Message)
+		case res.Status.Code != rpcv1beta1.Code_CODE_OK:
+			return nil, errtypes.InternalError(res.Status.Message)
+		}
+		return res.GetUser(), nil
+	}
+
+	res, err := gtw.GetUserByClaim(ctx, &user.GetUserByClaimRequest{
+		Claim: claim,
+		Value: value,
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.Status.Code == rpcv1beta1.Code_CODE_NOT_FOUND:
+		return nil, errtypes.NotFound(res.Status.Message)
+	case res.Status.Code != rpcv1beta1.Code_CODE_OK:
+		return nil, errtypes.InternalError(res.Status.Message)
+	}
+	return res.GetUser(), nil
+}
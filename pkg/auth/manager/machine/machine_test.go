@@ -0,0 +1,50 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func TestAuthenticate(t *testing.T) {
+	if _, err := New(map[string]interface{}{}); err == nil {
+		t.Fatal("the api key must be required")
+	}
+
+	m, err := New(map[string]interface{}{"api_key": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, _, err := m.Authenticate(ctx, "username:einstein", "wrong"); err == nil {
+		t.Fatal("wrong api keys must be rejected")
+	} else if _, ok := err.(errtypes.InvalidCredentials); !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, clientID := range []string{"einstein", "username:", ":einstein"} {
+		if _, _, err := m.Authenticate(ctx, clientID, "secret"); err == nil {
+			t.Errorf("the client id %q must be rejected", clientID)
+		}
+	}
+}
@@ -16,13 +16,20 @@
 // granted to it by virtue of its status as an Intergovernmental Organization
 // or submit itself to any jurisdiction.
 
-// Package oidc  verifies an OIDC token against the configured OIDC provider
+// Package oidc verifies an OIDC token against the configured OIDC provider
 // and obtains the necessary claims to obtain user information.
+//
+// The tokens are validated by the userinfo endpoint of the provider, by its
+// introspection endpoint for the opaque access tokens or locally with its keys
+// for the JWT access tokens. The claims nested in objects, like the federated
+// claims, are configured with dots, e.g. "federated_claims.connector_id".
 package oidc
 
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	oidc "github.com/coreos/go-oidc"
@@ -36,6 +43,7 @@ import (
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/user/provisioning"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -47,17 +55,35 @@ func init() {
 }
 
 type mgr struct {
+	c           *config
+	provisioner *provisioning.Provisioner
+
+	mu       sync.Mutex
 	provider *oidc.Provider // cached on first request
-	c        *config
+	keySet   *keySet        // cached on first request
 }
 
 type config struct {
-	Insecure   bool   `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
-	Issuer     string `mapstructure:"issuer" docs:";The issuer of the OIDC token."`
-	IDClaim    string `mapstructure:"id_claim" docs:"sub;The claim containing the ID of the user."`
-	UIDClaim   string `mapstructure:"uid_claim" docs:";The claim containing the UID of the user."`
-	GIDClaim   string `mapstructure:"gid_claim" docs:";The claim containing the GID of the user."`
-	GatewaySvc string `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
+	Insecure              bool                   `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+	Issuer                string                 `mapstructure:"issuer" docs:";The issuer of the OIDC token."`
+	IDClaim               string                 `mapstructure:"id_claim" docs:"sub;The claim containing the ID of the user."`
+	IdpClaim              string                 `mapstructure:"idp_claim" docs:"issuer;The claim containing the identity provider of the user, the issuer when missing."`
+	UsernameClaim         string                 `mapstructure:"username_claim" docs:"preferred_username;The claim containing the username of the user."`
+	DisplayNameClaim      string                 `mapstructure:"display_name_claim" docs:"name;The claim containing the display name of the user."`
+	MailClaim             string                 `mapstructure:"mail_claim" docs:"email;The claim containing the mail of the user."`
+	GroupsClaim           string                 `mapstructure:"groups_claim" docs:";The claim containing the groups of the user. The groups are looked up through the gateway when empty."`
+	UIDClaim              string                 `mapstructure:"uid_claim" docs:";The claim containing the UID of the user."`
+	GIDClaim              string                 `mapstructure:"gid_claim" docs:";The claim containing the GID of the user."`
+	Introspection         bool                   `mapstructure:"introspection" docs:"false;Whether to validate the tokens through the introspection endpoint of the provider, as needed by opaque access tokens."`
+	IntrospectionEndpoint string                 `mapstructure:"introspection_endpoint" docs:";The introspection endpoint, taken from the discovery document of the provider when empty."`
+	ClientID              string                 `mapstructure:"client_id" docs:";The client ID authenticating the introspection requests."`
+	ClientSecret          string                 `mapstructure:"client_secret" docs:";The client secret authenticating the introspection requests."`
+	VerifyJWT             bool                   `mapstructure:"verify_jwt" docs:"false;Whether to verify the JWT access tokens locally with the keys of the provider instead of sending them to the provider."`
+	Audience              string                 `mapstructure:"audience" docs:";The audience required in the JWT access tokens verified locally. Not checked when empty."`
+	JWKSRefreshInterval   int                    `mapstructure:"jwks_refresh_interval" docs:"3600;The seconds after which the keys of the provider are fetched again. They are also fetched again when a token is signed with an unknown key, at most once a minute."`
+	ClockSkew             int                    `mapstructure:"clock_skew" docs:"60;The seconds of difference tolerated between the clocks of reva and of the provider when checking the validity of the tokens."`
+	GatewaySvc            string                 `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
+	Provisioning          map[string]interface{} `mapstructure:"provisioning" docs:"url:pkg/user/provisioning/provisioning.go;The creation of the users on their first login."`
 }
 
 func (c *config) init() {
@@ -65,6 +91,24 @@ func (c *config) init() {
 		// sub is stable and defined as unique. the user manager needs to take care of the sub to user metadata lookup
 		c.IDClaim = "sub"
 	}
+	if c.IdpClaim == "" {
+		c.IdpClaim = "issuer"
+	}
+	if c.UsernameClaim == "" {
+		c.UsernameClaim = "preferred_username"
+	}
+	if c.DisplayNameClaim == "" {
+		c.DisplayNameClaim = "name"
+	}
+	if c.MailClaim == "" {
+		c.MailClaim = "email"
+	}
+	if c.JWKSRefreshInterval == 0 {
+		c.JWKSRefreshInterval = 3600
+	}
+	if c.ClockSkew == 0 {
+		c.ClockSkew = 60
+	}
 
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
@@ -86,7 +130,12 @@ func New(m map[string]interface{}) (auth.Manager, error) {
 	}
 	c.init()
 
-	return &mgr{c: c}, nil
+	provisioner, err := provisioning.New(c.Provisioning)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mgr{c: c, provisioner: provisioner}, nil
 }
 
 // the clientID it would be empty as we only need to validate the clientSecret variable
@@ -100,90 +149,103 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 		return nil, nil, fmt.Errorf("error creating oidc provider: +%v", err)
 	}
 
-	oauth2Token := &oauth2.Token{
-		AccessToken: clientSecret,
-	}
-	userInfo, err := oidcProvider.UserInfo(ctx, oauth2.StaticTokenSource(oauth2Token))
-	if err != nil {
-		return nil, nil, fmt.Errorf("oidc: error getting userinfo: +%v", err)
-	}
-
 	// claims contains the standard OIDC claims like issuer, iat, aud, ... and any other non-standard one.
-	// TODO(labkode): make claims configuration dynamic from the config file so we can add arbitrary mappings from claims to user struct.
-	var claims map[string]interface{}
-	if err := userInfo.Claims(&claims); err != nil {
-		return nil, nil, fmt.Errorf("oidc: error unmarshaling userinfo claims: %v", err)
+	claims, err := am.getClaims(ctx, oidcProvider, clientSecret)
+	if err != nil {
+		return nil, nil, err
 	}
-	log.Debug().Interface("claims", claims).Interface("userInfo", userInfo).Msg("unmarshalled userinfo")
+	log.Debug().Interface("claims", claims).Msg("unmarshalled claims")
 
 	if claims["issuer"] == nil { // This is not set in simplesamlphp
 		claims["issuer"] = am.c.Issuer
 	}
-	if claims["email_verified"] == nil { // This is not set in simplesamlphp
-		claims["email_verified"] = false
+
+	mail, ok := claimString(claims, am.c.MailClaim)
+	if !ok {
+		return nil, nil, fmt.Errorf("no %q attribute found in userinfo: maybe the client did not request the oidc \"email\"-scope", am.c.MailClaim)
 	}
+	// This is not set in simplesamlphp
+	mailVerified, _ := claims["email_verified"].(bool)
 
-	if claims["email"] == nil {
-		return nil, nil, fmt.Errorf("no \"email\" attribute found in userinfo: maybe the client did not request the oidc \"email\"-scope")
+	username, ok := claimString(claims, am.c.UsernameClaim)
+	if !ok {
+		return nil, nil, fmt.Errorf("no %q attribute found in userinfo: maybe the client did not request the oidc \"profile\"-scope", am.c.UsernameClaim)
+	}
+	displayName, ok := claimString(claims, am.c.DisplayNameClaim)
+	if !ok {
+		return nil, nil, fmt.Errorf("no %q attribute found in userinfo: maybe the client did not request the oidc \"profile\"-scope", am.c.DisplayNameClaim)
 	}
 
-	if claims["preferred_username"] == nil || claims["name"] == nil {
-		return nil, nil, fmt.Errorf("no \"preferred_username\" or \"name\" attribute found in userinfo: maybe the client did not request the oidc \"profile\"-scope")
+	id, ok := claimString(claims, am.c.IDClaim)
+	if !ok {
+		return nil, nil, fmt.Errorf("no %q attribute found in userinfo", am.c.IDClaim)
+	}
+	idp, ok := claimString(claims, am.c.IdpClaim)
+	if !ok {
+		return nil, nil, fmt.Errorf("no %q attribute found in userinfo", am.c.IdpClaim)
 	}
 
 	opaqueObj := &types.Opaque{
 		Map: map[string]*types.OpaqueEntry{},
 	}
 	if am.c.UIDClaim != "" {
-		uid, ok := claims[am.c.UIDClaim]
+		uid, ok := claim(claims, am.c.UIDClaim)
 		if ok {
 			opaqueObj.Map["uid"] = &types.OpaqueEntry{
 				Decoder: "plain",
-				Value:   []byte(fmt.Sprintf("%0.f", uid)),
+				Value:   []byte(numericClaim(uid)),
 			}
 		}
 	}
 	if am.c.GIDClaim != "" {
-		gid, ok := claims[am.c.GIDClaim]
+		gid, ok := claim(claims, am.c.GIDClaim)
 		if ok {
 			opaqueObj.Map["gid"] = &types.OpaqueEntry{
 				Decoder: "plain",
-				Value:   []byte(fmt.Sprintf("%0.f", gid)),
+				Value:   []byte(numericClaim(gid)),
 			}
 		}
 	}
 
 	userID := &user.UserId{
-		OpaqueId: claims[am.c.IDClaim].(string), // a stable non reassignable id
-		Idp:      claims["issuer"].(string),     // in the scope of this issuer
+		OpaqueId: id,  // a stable non reassignable id
+		Idp:      idp, // in the scope of this issuer
 	}
-	gwc, err := pool.GetGatewayServiceClient(am.c.GatewaySvc)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "oidc: error getting gateway grpc client")
+
+	// the user has to exist before its groups can be looked up
+	var provisioned *user.User
+	if am.provisioner != nil {
+		provisioned, err = am.provisioner.Provision(ctx, userID, claims)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "oidc: error provisioning user")
+		}
+		for _, k := range []string{"uid", "gid"} {
+			if _, ok := opaqueObj.Map[k]; !ok && provisioned.Opaque != nil && provisioned.Opaque.Map[k] != nil {
+				opaqueObj.Map[k] = provisioned.Opaque.Map[k]
+			}
+		}
 	}
-	getGroupsResp, err := gwc.GetUserGroups(ctx, &user.GetUserGroupsRequest{
-		UserId: userID,
-	})
+
+	groups, err := am.getGroups(ctx, userID, claims)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "oidc: error getting user groups")
-	}
-	if getGroupsResp.Status.Code != rpc.Code_CODE_OK {
-		return nil, nil, errors.Wrap(err, "oidc: grpc getting user groups failed")
+		return nil, nil, err
 	}
 
 	u := &user.User{
 		Id:       userID,
-		Username: claims["preferred_username"].(string),
-		// TODO(labkode) if we can get groups from the claim we need to give the possibility
-		// to the admin to choose what claim provides the groups.
+		Username: username,
 		// TODO(labkode) ... use all claims from oidc?
 		// TODO(labkode): do like K8s does it: https://github.com/kubernetes/kubernetes/blob/master/staging/src/k8s.io/apiserver/plugin/pkg/authenticator/token/oidc/oidc.go
-		Groups:       getGroupsResp.Groups,
-		Mail:         claims["email"].(string),
-		MailVerified: claims["email_verified"].(bool),
-		DisplayName:  claims["name"].(string),
+		Groups:       groups,
+		Mail:         mail,
+		MailVerified: mailVerified,
+		DisplayName:  displayName,
 		Opaque:       opaqueObj,
 	}
+	if provisioned != nil {
+		// the homes and spaces were created for the provisioned username
+		u.Username = provisioned.Username
+	}
 
 	scope, err := scope.GetOwnerScope()
 	if err != nil {
@@ -193,6 +255,87 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 	return u, scope, nil
 }
 
+// getClaims validates the token and returns its claims
+func (am *mgr) getClaims(ctx context.Context, oidcProvider *oidc.Provider, token string) (map[string]interface{}, error) {
+	var claims map[string]interface{}
+	var err error
+	switch {
+	case am.c.VerifyJWT && isJWT(token):
+		claims, err = am.verifyJWT(ctx, oidcProvider, token)
+	case am.c.Introspection:
+		claims, err = am.introspect(ctx, oidcProvider, token)
+	default:
+		return am.getUserInfo(ctx, oidcProvider, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the access tokens and the introspection responses don't always contain the profile of the user
+	if !am.hasUserClaims(claims) {
+		userInfo, err := am.getUserInfo(ctx, oidcProvider, token)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range userInfo {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+	return claims, nil
+}
+
+func (am *mgr) getUserInfo(ctx context.Context, oidcProvider *oidc.Provider, token string) (map[string]interface{}, error) {
+	oauth2Token := &oauth2.Token{
+		AccessToken: token,
+	}
+	userInfo, err := oidcProvider.UserInfo(ctx, oauth2.StaticTokenSource(oauth2Token))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: error getting userinfo: +%v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := userInfo.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc: error unmarshaling userinfo claims: %v", err)
+	}
+	return claims, nil
+}
+
+func (am *mgr) hasUserClaims(claims map[string]interface{}) bool {
+	names := []string{am.c.IDClaim, am.c.UsernameClaim, am.c.DisplayNameClaim, am.c.MailClaim}
+	if am.c.GroupsClaim != "" {
+		names = append(names, am.c.GroupsClaim)
+	}
+	for _, name := range names {
+		if _, ok := claim(claims, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (am *mgr) getGroups(ctx context.Context, userID *user.UserId, claims map[string]interface{}) ([]string, error) {
+	if am.c.GroupsClaim != "" {
+		return claimStrings(claims, am.c.GroupsClaim), nil
+	}
+
+	gwc, err := pool.GetGatewayServiceClient(am.c.GatewaySvc)
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc: error getting gateway grpc client")
+	}
+	getGroupsResp, err := gwc.GetUserGroups(ctx, &user.GetUserGroupsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc: error getting user groups")
+	}
+	if getGroupsResp.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.Wrap(err, "oidc: grpc getting user groups failed")
+	}
+	return getGroupsResp.Groups, nil
+}
+
 func (am *mgr) getOAuthCtx(ctx context.Context) context.Context {
 	// Sometimes for testing we need to skip the TLS check, that's why we need a
 	// custom HTTP client.
@@ -208,6 +351,8 @@ func (am *mgr) getOAuthCtx(ctx context.Context) context.Context {
 }
 
 func (am *mgr) getOIDCProvider(ctx context.Context) (*oidc.Provider, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
 	if am.provider != nil {
 		return am.provider, nil
 	}
@@ -224,3 +369,52 @@ func (am *mgr) getOIDCProvider(ctx context.Context) (*oidc.Provider, error) {
 	am.provider = provider
 	return am.provider, nil
 }
+
+// claim returns the value of a claim. The claims nested in objects are
+// addressed with dots, unless a claim is named so.
+func claim(claims map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := claims[name]; ok && v != nil {
+		return v, true
+	}
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) < 2 {
+		return nil, false
+	}
+	nested, ok := claims[parts[0]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return claim(nested, parts[1])
+}
+
+func claimString(claims map[string]interface{}, name string) (string, bool) {
+	v, _ := claim(claims, name)
+	s, ok := v.(string)
+	return s, ok && s != ""
+}
+
+// claimStrings returns the values of a claim holding either a list or a single value
+func claimStrings(claims map[string]interface{}, name string) []string {
+	v, _ := claim(claims, name)
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		values := make([]string, 0, len(t))
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func numericClaim(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%0.f", v)
+}
@@ -0,0 +1,245 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// testProvider is an OIDC provider introspecting the "opaque" token and
+// publishing the keys signing the JWT tokens
+type testProvider struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	p := &testProvider{keys: map[string]*rsa.PrivateKey{}}
+	p.addKey(t, "key1")
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *testProvider) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys[kid] = key
+}
+
+func (p *testProvider) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(p.keys[kid])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func (p *testProvider) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/auth",
+			"token_endpoint":         p.URL + "/token",
+			"userinfo_endpoint":      p.URL + "/userinfo",
+			"introspection_endpoint": p.URL + "/introspect",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	case "/jwks":
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		keys := []map[string]string{}
+		for kid, key := range p.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	case "/userinfo":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"sub":                "einstein-id",
+			"preferred_username": "einstein",
+			"name":               "Albert Einstein",
+			"email":              "einstein@example.org",
+			"groups":             []string{"physics"},
+		})
+	case "/introspect":
+		if id, secret, _ := r.BasicAuth(); id != "reva" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("token") != "opaque" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": false})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"active":   true,
+			"exp":      time.Now().Add(time.Hour).Unix(),
+			"sub":      "marie-id",
+			"username": "marie",
+			"name":     "Marie Curie",
+			"email":    "marie@example.org",
+			"groups":   []string{"radium"},
+			"federated_claims": map[string]string{
+				"connector_id": "ldap",
+				"user_id":      "mcurie",
+			},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestManager(t *testing.T, m map[string]interface{}) *mgr {
+	am, err := New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return am.(*mgr)
+}
+
+func TestIntrospection(t *testing.T) {
+	p := newTestProvider(t)
+	am := newTestManager(t, map[string]interface{}{
+		"issuer":         p.URL,
+		"introspection":  true,
+		"client_id":      "reva",
+		"client_secret":  "secret",
+		"id_claim":       "federated_claims.user_id",
+		"idp_claim":      "federated_claims.connector_id",
+		"username_claim": "username",
+		"groups_claim":   "groups",
+	})
+
+	u, _, err := am.Authenticate(context.Background(), "", "opaque")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Id.OpaqueId != "mcurie" || u.Id.Idp != "ldap" {
+		t.Fatalf("unexpected user id %v", u.Id)
+	}
+	if u.Username != "marie" || u.DisplayName != "Marie Curie" || u.Mail != "marie@example.org" {
+		t.Fatalf("unexpected user %v", u)
+	}
+	if len(u.Groups) != 1 || u.Groups[0] != "radium" {
+		t.Fatalf("unexpected groups %v", u.Groups)
+	}
+
+	if _, _, err := am.Authenticate(context.Background(), "", "revoked"); err == nil {
+		t.Fatal("inactive tokens must be rejected")
+	} else if _, ok := err.(errtypes.InvalidCredentials); !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	p := newTestProvider(t)
+	am := newTestManager(t, map[string]interface{}{
+		"issuer":       p.URL,
+		"verify_jwt":   true,
+		"audience":     "reva",
+		"groups_claim": "groups",
+		"clock_skew":   120,
+	})
+	claims := func(exp time.Duration) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": p.URL,
+			"aud": "reva",
+			"sub": "einstein-id",
+			"exp": time.Now().Add(exp).Unix(),
+		}
+	}
+
+	// the profile missing in the token is taken from the userinfo
+	u, _, err := am.Authenticate(context.Background(), "", p.sign(t, "key1", claims(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Id.OpaqueId != "einstein-id" || u.Id.Idp != p.URL || u.Username != "einstein" || len(u.Groups) != 1 {
+		t.Fatalf("unexpected user %v", u)
+	}
+
+	if _, _, err := am.Authenticate(context.Background(), "", p.sign(t, "key1", claims(-time.Minute))); err != nil {
+		t.Fatalf("tokens expired within the clock skew must be accepted: %v", err)
+	}
+	if _, _, err := am.Authenticate(context.Background(), "", p.sign(t, "key1", claims(-time.Hour))); err == nil {
+		t.Fatal("expired tokens must be rejected")
+	}
+
+	wrongAudience := claims(time.Hour)
+	wrongAudience["aud"] = "other"
+	if _, _, err := am.Authenticate(context.Background(), "", p.sign(t, "key1", wrongAudience)); err == nil {
+		t.Fatal("tokens for other audiences must be rejected")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	defer func(delay time.Duration) { keysRefreshDelay = delay }(keysRefreshDelay)
+	keysRefreshDelay = 0
+
+	p := newTestProvider(t)
+	am := newTestManager(t, map[string]interface{}{
+		"issuer":       p.URL,
+		"verify_jwt":   true,
+		"groups_claim": "groups",
+	})
+	claims := jwt.MapClaims{
+		"iss": p.URL,
+		"sub": "einstein-id",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	if _, _, err := am.Authenticate(context.Background(), "", p.sign(t, "key1", claims)); err != nil {
+		t.Fatal(err)
+	}
+	p.addKey(t, "key2")
+	if _, _, err := am.Authenticate(context.Background(), "", p.sign(t, "key2", claims)); err != nil {
+		t.Fatalf("tokens signed with rotated keys must be accepted: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	oidc "github.com/coreos/go-oidc"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/pkg/errors"
+)
+
+// keysRefreshDelay is the minimum delay between two fetches of the keys of the
+// provider triggered by tokens signed with unknown keys.
+var keysRefreshDelay = time.Minute
+
+// keySet verifies the signatures of the tokens with the keys of the provider.
+// The keys are fetched again after some time, and when a token is signed with
+// an unknown key as happens after the rotation of the keys.
+type keySet struct {
+	ctx     context.Context
+	jwksURL string
+	maxAge  time.Duration
+
+	mu      sync.Mutex
+	keys    oidc.KeySet
+	fetched time.Time
+}
+
+func newKeySet(client *http.Client, jwksURL string, maxAge time.Duration) *keySet {
+	return &keySet{
+		// the keys are fetched outside of the requests verifying the tokens
+		ctx:     oidc.ClientContext(context.Background(), client),
+		jwksURL: jwksURL,
+		maxAge:  maxAge,
+	}
+}
+
+func (k *keySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	keys, fresh := k.current()
+	payload, err := keys.VerifySignature(ctx, jwt)
+	if err == nil || fresh {
+		return payload, err
+	}
+
+	// the token may be signed with a key added since the keys were fetched
+	if keys = k.refresh(keys); keys == nil {
+		return nil, err
+	}
+	return keys.VerifySignature(ctx, jwt)
+}
+
+// current returns the keys, which are fetched again when expired, and whether they are new
+func (k *keySet) current() (oidc.KeySet, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keys == nil || time.Since(k.fetched) > k.maxAge {
+		k.keys, k.fetched = oidc.NewRemoteKeySet(k.ctx, k.jwksURL), time.Now()
+		return k.keys, true
+	}
+	return k.keys, false
+}
+
+// refresh fetches the keys again unless they were fetched recently, and returns
+// nil when the keys didn't change since the given ones
+func (k *keySet) refresh(old oidc.KeySet) oidc.KeySet {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keys != old {
+		return k.keys
+	}
+	if time.Since(k.fetched) < keysRefreshDelay {
+		return nil
+	}
+	k.keys, k.fetched = oidc.NewRemoteKeySet(k.ctx, k.jwksURL), time.Now()
+	return k.keys
+}
+
+func (am *mgr) getKeySet(oidcProvider *oidc.Provider) (*keySet, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	if am.keySet != nil {
+		return am.keySet, nil
+	}
+
+	var metadata struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
+	if err := oidcProvider.Claims(&metadata); err != nil {
+		return nil, errors.Wrap(err, "oidc: error reading the provider metadata")
+	}
+	if metadata.JWKSURL == "" {
+		return nil, errors.New("oidc: the provider doesn't publish its keys")
+	}
+	am.keySet = newKeySet(am.getHTTPClient(), metadata.JWKSURL, time.Duration(am.c.JWKSRefreshInterval)*time.Second)
+	return am.keySet, nil
+}
+
+// verifyJWT verifies a JWT access token with the keys of the provider and returns its claims
+func (am *mgr) verifyJWT(ctx context.Context, oidcProvider *oidc.Provider, token string) (map[string]interface{}, error) {
+	keys, err := am.getKeySet(oidcProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := oidc.NewVerifier(am.c.Issuer, keys, &oidc.Config{
+		ClientID:          am.c.Audience,
+		SkipClientIDCheck: am.c.Audience == "",
+		// the validity is checked with the tolerated clock skew
+		SkipExpiryCheck: true,
+	})
+	idToken, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, errtypes.InvalidCredentials(fmt.Sprintf("oidc: error verifying the token: %v", err))
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errors.Wrap(err, "oidc: error unmarshaling the token claims")
+	}
+	if err := am.checkValidity(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// introspect validates a token through the introspection endpoint of the provider,
+// as described in RFC 7662, and returns its claims
+func (am *mgr) introspect(ctx context.Context, oidcProvider *oidc.Provider, token string) (map[string]interface{}, error) {
+	endpoint := am.c.IntrospectionEndpoint
+	if endpoint == "" {
+		var metadata struct {
+			IntrospectionEndpoint string `json:"introspection_endpoint"`
+		}
+		if err := oidcProvider.Claims(&metadata); err != nil {
+			return nil, errors.Wrap(err, "oidc: error reading the provider metadata")
+		}
+		if metadata.IntrospectionEndpoint == "" {
+			return nil, errors.New("oidc: the provider has no introspection endpoint")
+		}
+		endpoint = metadata.IntrospectionEndpoint
+	}
+
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := rhttp.NewRequest(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(am.c.ClientID), url.QueryEscape(am.c.ClientSecret))
+
+	res, err := am.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc: error introspecting the token")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: introspecting the token returned %d", res.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&claims); err != nil {
+		return nil, errors.Wrap(err, "oidc: error unmarshaling the introspection response")
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, errtypes.InvalidCredentials("oidc: the token is not active")
+	}
+	delete(claims, "active")
+	if err := am.checkValidity(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkValidity checks the validity period of a token, tolerating the configured clock skew
+func (am *mgr) checkValidity(claims map[string]interface{}) error {
+	now := time.Now()
+	skew := time.Duration(am.c.ClockSkew) * time.Second
+	if exp, ok := numericDate(claims["exp"]); ok && now.Add(-skew).After(exp) {
+		return errtypes.InvalidCredentials(fmt.Sprintf("oidc: the token expired at %v", exp))
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(skew).Before(nbf) {
+		return errtypes.InvalidCredentials(fmt.Sprintf("oidc: the token is not valid before %v", nbf))
+	}
+	if iat, ok := numericDate(claims["iat"]); ok && now.Add(skew).Before(iat) {
+		return errtypes.InvalidCredentials(fmt.Sprintf("oidc: the token is issued in the future at %v", iat))
+	}
+	return nil
+}
+
+func (am *mgr) getHTTPClient() *http.Client {
+	return rhttp.GetHTTPClient(
+		rhttp.Timeout(time.Second*10),
+		rhttp.Insecure(am.c.Insecure),
+	)
+}
+
+func numericDate(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case float64:
+		return time.Unix(int64(t), 0), true
+	case json.Number:
+		i, err := t.Int64()
+		return time.Unix(i, 0), err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
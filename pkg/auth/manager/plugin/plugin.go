@@ -0,0 +1,143 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package plugin provides an auth manager served by an external binary. The
+// binary creates its auth manager with the configuration of the driver and
+// serves it with Serve:
+//
+//	func main() {
+//		plugin.Serve(mymanager.New)
+//	}
+package plugin
+
+import (
+	"context"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	revaplugin "github.com/cs3org/reva/pkg/plugin"
+	pluginpb "github.com/cs3org/reva/pkg/plugin/proto"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const kind = "authprovider"
+
+func init() {
+	registry.Register("plugin", New)
+}
+
+type config struct {
+	// Path is the binary of the plugin.
+	Path string `mapstructure:"path"`
+	// Config is the configuration the plugin creates its auth manager with.
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// New starts the plugin and returns the auth manager it serves.
+func New(m map[string]interface{}) (auth.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	p, err := revaplugin.Load(kind, c.Path, &Plugin{})
+	if err != nil {
+		return nil, err
+	}
+	mgr := p.Raw.(*grpcClient)
+	if err := mgr.configure(c.Config); err != nil {
+		p.Kill()
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// Serve serves the auth manager created by the given function from a plugin
+// binary.
+func Serve(f registry.NewFunc) {
+	revaplugin.Serve(kind, &Plugin{New: f})
+}
+
+// Plugin is the go-plugin of the auth managers.
+type Plugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	// New creates the auth manager on the plugin side.
+	New registry.NewFunc
+}
+
+// GRPCServer registers the server of the plugin binary.
+func (p *Plugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	pluginpb.RegisterAuthProviderPluginServer(s, &grpcServer{new: p.New})
+	return nil
+}
+
+// GRPCClient returns the auth manager calling the plugin binary.
+func (p *Plugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{client: pluginpb.NewAuthProviderPluginClient(c)}, nil
+}
+
+type grpcClient struct {
+	client pluginpb.AuthProviderPluginClient
+}
+
+func (c *grpcClient) configure(m map[string]interface{}) error {
+	config, err := revaplugin.EncodeConfig(m)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Configure(context.Background(), &pluginpb.ConfigureRequest{Config: config})
+	return err
+}
+
+func (c *grpcClient) Authenticate(ctx context.Context, clientID, clientSecret string) (*userpb.User, map[string]*authpb.Scope, error) {
+	res, err := c.client.Authenticate(ctx, &pluginpb.AuthenticateRequest{ClientId: clientID, ClientSecret: clientSecret})
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.User, res.Scopes, nil
+}
+
+type grpcServer struct {
+	new registry.NewFunc
+	mgr auth.Manager
+}
+
+func (s *grpcServer) Configure(_ context.Context, req *pluginpb.ConfigureRequest) (*pluginpb.ConfigureResponse, error) {
+	mgr, err := s.new(revaplugin.DecodeConfig(req.Config))
+	if err != nil {
+		return nil, err
+	}
+	s.mgr = mgr
+	return &pluginpb.ConfigureResponse{}, nil
+}
+
+func (s *grpcServer) Authenticate(ctx context.Context, req *pluginpb.AuthenticateRequest) (*pluginpb.AuthenticateResponse, error) {
+	if s.mgr == nil {
+		return nil, errors.New("plugin: auth manager not configured")
+	}
+	u, scopes, err := s.mgr.Authenticate(ctx, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.AuthenticateResponse{User: u, Scopes: scopes}, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scope
+
+import (
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func guestScope(scope *authpb.Scope, resource interface{}) (bool, error) {
+	switch resource.(type) {
+	case *gateway.WhoAmIRequest:
+		return true, nil
+	case *userpb.GetUserRequest, *userpb.GetUserByClaimRequest, *userpb.GetUserGroupsRequest:
+		return true, nil
+	case *provider.GetHomeRequest, *registry.GetHomeRequest:
+		return true, nil
+	case *collaboration.ListReceivedSharesRequest, *collaboration.GetReceivedShareRequest, *collaboration.UpdateReceivedShareRequest:
+		return true, nil
+	case string:
+		// The HTTP services only reach the resources through the gRPC
+		// services, which check the requests themselves.
+		return true, nil
+	}
+
+	// The storage requests depend on the shares the guest received,
+	// they're checked by the auth interceptor.
+	return false, nil
+}
+
+// GetGuestScope returns the scope of the guest accounts, which only gives
+// access to the resources shared with the guest, mounted in the share folder
+// of their home.
+func GetGuestScope(shareFolder string) map[string]*authpb.Scope {
+	return map[string]*authpb.Scope{
+		"guest": &authpb.Scope{
+			Resource: &types.OpaqueEntry{
+				Decoder: "plain",
+				Value:   []byte(shareFolder),
+			},
+			Role: authpb.Role_ROLE_VIEWER,
+		},
+	}
+}
+
+// GetGuestShareFolder returns the share folder of a guest scope and whether
+// the scope is a guest scope.
+func GetGuestShareFolder(scopeMap map[string]*authpb.Scope) (string, bool) {
+	s, ok := scopeMap["guest"]
+	if !ok {
+		return "", false
+	}
+	return string(s.GetResource().GetValue()), true
+}
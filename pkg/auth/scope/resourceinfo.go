@@ -49,16 +49,14 @@ func resourceinfoScope(scope *authpb.Scope, resource interface{}) (bool, error)
 		return checkResourceInfo(&r, v.GetRef()), nil
 
 		// Editor role
-		// TODO(ishank011): Add role checks,
-		// need to return appropriate status codes in the ocs/ocdav layers.
 	case *provider.CreateContainerRequest:
-		return checkResourceInfo(&r, v.GetRef()), nil
+		return canEdit(scope.Role) && checkResourceInfo(&r, v.GetRef()), nil
 	case *provider.DeleteRequest:
-		return checkResourceInfo(&r, v.GetRef()), nil
+		return canEdit(scope.Role) && checkResourceInfo(&r, v.GetRef()), nil
 	case *provider.MoveRequest:
-		return checkResourceInfo(&r, v.GetSource()) && checkResourceInfo(&r, v.GetDestination()), nil
+		return canEdit(scope.Role) && checkResourceInfo(&r, v.GetSource()) && checkResourceInfo(&r, v.GetDestination()), nil
 	case *provider.InitiateFileUploadRequest:
-		return checkResourceInfo(&r, v.GetRef()), nil
+		return canEdit(scope.Role) && checkResourceInfo(&r, v.GetRef()), nil
 
 	case string:
 		return checkPath(v), nil
@@ -79,6 +77,11 @@ func checkResourceInfo(inf *provider.ResourceInfo, ref *provider.Reference) bool
 	return false
 }
 
+// canEdit tells whether the role of a scope allows to change the resource
+func canEdit(role authpb.Role) bool {
+	return role != authpb.Role_ROLE_VIEWER
+}
+
 func checkPath(path string) bool {
 	paths := []string{
 		"/dataprovider",
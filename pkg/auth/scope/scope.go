@@ -19,11 +19,18 @@
 package scope
 
 import (
+	"encoding/json"
 	"strings"
 
 	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/pkg/errors"
 )
 
+// RestrictionKey is the key of the opaque entry of the authentication requests
+// holding the scope the gateway restricts the minted token to.
+const RestrictionKey = "scope"
+
 // Verifier is the function signature which every scope verifier should implement.
 type Verifier func(*authpb.Scope, interface{}) (bool, error)
 
@@ -31,6 +38,7 @@ var supportedScopes = map[string]Verifier{
 	"user":         userScope,
 	"publicshare":  publicshareScope,
 	"resourceinfo": resourceinfoScope,
+	"guest":        guestScope,
 }
 
 // VerifyScope is the function to be called when dismantling tokens to check if
@@ -51,3 +59,46 @@ func VerifyScope(scopeMap map[string]*authpb.Scope, resource interface{}) (bool,
 	}
 	return false, nil
 }
+
+// IsOwnerScope tells whether a scope gives access to all the resources of the user.
+func IsOwnerScope(scopeMap map[string]*authpb.Scope) bool {
+	s, ok := scopeMap["user"]
+	return ok && s.Role == authpb.Role_ROLE_OWNER
+}
+
+// SetRestriction adds to the opaque of an authentication request the scope
+// the minted token has to be restricted to.
+func SetRestriction(o *types.Opaque, scopeMap map[string]*authpb.Scope) (*types.Opaque, error) {
+	val, err := json.Marshal(scopeMap)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		o = &types.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*types.OpaqueEntry{}
+	}
+	o.Map[RestrictionKey] = &types.OpaqueEntry{
+		Decoder: "json",
+		Value:   val,
+	}
+	return o, nil
+}
+
+// GetRestriction returns the scope an authentication request restricts the
+// minted token to, nil when the token isn't restricted.
+func GetRestriction(o *types.Opaque) (map[string]*authpb.Scope, error) {
+	entry, ok := o.GetMap()[RestrictionKey]
+	if !ok {
+		return nil, nil
+	}
+	var scopeMap map[string]*authpb.Scope
+	if err := json.Unmarshal(entry.Value, &scopeMap); err != nil {
+		return nil, errors.Wrap(err, "scope: error unmarshaling the restriction")
+	}
+	if len(scopeMap) == 0 {
+		return nil, errors.New("scope: the restriction is empty")
+	}
+	return scopeMap, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scope
+
+import (
+	"testing"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func TestRestriction(t *testing.T) {
+	r, err := GetRestriction(nil)
+	if err != nil || r != nil {
+		t.Fatalf("requests without restriction must not be restricted: %v %v", r, err)
+	}
+
+	info := &provider.ResourceInfo{
+		Id:   &provider.ResourceId{StorageId: "storage", OpaqueId: "report"},
+		Path: "/home/report.pdf",
+	}
+	viewer, err := GetResourceInfoScope(info, authpb.Role_ROLE_VIEWER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := SetRestriction(&types.Opaque{}, viewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = GetRestriction(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsOwnerScope(r) {
+		t.Fatal("the restriction must not be an owner scope")
+	}
+
+	ref := &provider.Reference{Spec: &provider.Reference_Id{Id: info.Id}}
+	other := &provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{StorageId: "storage", OpaqueId: "other"}}}
+	tests := []struct {
+		resource interface{}
+		expected bool
+	}{
+		{&provider.StatRequest{Ref: ref}, true},
+		{&provider.InitiateFileDownloadRequest{Ref: ref}, true},
+		{&provider.StatRequest{Ref: other}, false},
+		{&provider.InitiateFileUploadRequest{Ref: ref}, false},
+		{&provider.DeleteRequest{Ref: ref}, false},
+	}
+	for _, tt := range tests {
+		ok, err := VerifyScope(r, tt.resource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.expected {
+			t.Errorf("VerifyScope(%T) = %v, wanted %v", tt.resource, ok, tt.expected)
+		}
+	}
+
+	editor, err := GetResourceInfoScope(info, authpb.Role_ROLE_EDITOR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := VerifyScope(editor, &provider.InitiateFileUploadRequest{Ref: ref}); !ok {
+		t.Error("editors must be allowed to upload")
+	}
+
+	owner, err := GetOwnerScope()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsOwnerScope(owner) {
+		t.Error("the owner scope must be recognized")
+	}
+}
+
+func TestGuestScope(t *testing.T) {
+	s := GetGuestScope("MyShares")
+	if IsOwnerScope(s) {
+		t.Fatal("the guest scope must not be an owner scope")
+	}
+	folder, ok := GetGuestShareFolder(s)
+	if !ok || folder != "MyShares" {
+		t.Fatalf("the share folder of the guest scope = %q %v, wanted MyShares", folder, ok)
+	}
+	if _, ok := GetGuestShareFolder(map[string]*authpb.Scope{}); ok {
+		t.Error("a scope without guest entry must not be recognized as guest scope")
+	}
+
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/home/MyShares"}}
+	tests := []struct {
+		resource interface{}
+		expected bool
+	}{
+		{&collaboration.ListReceivedSharesRequest{}, true},
+		{&provider.GetHomeRequest{}, true},
+		{"/remote.php/webdav", true},
+		{&provider.StatRequest{Ref: ref}, false},
+		{&collaboration.CreateShareRequest{}, false},
+	}
+	for _, tt := range tests {
+		ok, err := VerifyScope(s, tt.resource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.expected {
+			t.Errorf("VerifyScope(%T) = %v, wanted %v", tt.resource, ok, tt.expected)
+		}
+	}
+}
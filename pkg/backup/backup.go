@@ -0,0 +1,222 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package backup exports the state of the share, public share and OCM invite
+// managers into a portable archive and imports it into any other manager
+// supporting it, so that deployments can switch between backends safely.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/invite"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/share"
+)
+
+// Version is the version of the archive format produced by Export.
+const Version = 1
+
+// Archive is the portable representation of the data held by the managers.
+type Archive struct {
+	Version       int
+	Created       time.Time
+	Shares        []*collaboration.Share
+	ShareStates   []*share.ReceivedShareState
+	PublicShares  []*publicshare.WithPassword
+	Invites       []*invitepb.InviteToken
+	AcceptedUsers map[string][]*userpb.User
+}
+
+// Managers groups the managers taking part in an export or an import.
+// Managers left nil are skipped.
+type Managers struct {
+	Shares       share.Manager
+	PublicShares publicshare.Manager
+	Invites      invite.Manager
+}
+
+// Export dumps the content of the given managers into a new archive.
+func Export(ctx context.Context, m Managers) (*Archive, error) {
+	a := &Archive{
+		Version:       Version,
+		Created:       time.Now().UTC(),
+		AcceptedUsers: map[string][]*userpb.User{},
+	}
+
+	if m.Shares != nil {
+		d, ok := m.Shares.(share.Dumper)
+		if !ok {
+			return nil, errtypes.NotSupported(fmt.Sprintf("backup: share manager %T cannot be exported", m.Shares))
+		}
+		shares, states, err := d.Dump(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.Shares, a.ShareStates = shares, states
+	}
+
+	if m.PublicShares != nil {
+		d, ok := m.PublicShares.(publicshare.Dumper)
+		if !ok {
+			return nil, errtypes.NotSupported(fmt.Sprintf("backup: public share manager %T cannot be exported", m.PublicShares))
+		}
+		shares, err := d.Dump(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.PublicShares = shares
+	}
+
+	if m.Invites != nil {
+		d, ok := m.Invites.(invite.Dumper)
+		if !ok {
+			return nil, errtypes.NotSupported(fmt.Sprintf("backup: invite manager %T cannot be exported", m.Invites))
+		}
+		tokens, accepted, err := d.Dump(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.Invites, a.AcceptedUsers = tokens, accepted
+	}
+
+	return a, nil
+}
+
+// Import validates the archive, loads it into the given managers and checks
+// that every archived entry can be found in them afterwards.
+func Import(ctx context.Context, m Managers, a *Archive) error {
+	if err := Validate(a); err != nil {
+		return err
+	}
+
+	// check all the managers upfront so that nothing is loaded
+	// if any of them lacks support for it
+	var (
+		sl  share.Loader
+		psl publicshare.Loader
+		il  invite.Loader
+		ok  bool
+	)
+	if m.Shares != nil {
+		if sl, ok = m.Shares.(share.Loader); !ok {
+			return errtypes.NotSupported(fmt.Sprintf("backup: share manager %T cannot be imported into", m.Shares))
+		}
+	}
+	if m.PublicShares != nil {
+		if psl, ok = m.PublicShares.(publicshare.Loader); !ok {
+			return errtypes.NotSupported(fmt.Sprintf("backup: public share manager %T cannot be imported into", m.PublicShares))
+		}
+	}
+	if m.Invites != nil {
+		if il, ok = m.Invites.(invite.Loader); !ok {
+			return errtypes.NotSupported(fmt.Sprintf("backup: invite manager %T cannot be imported into", m.Invites))
+		}
+	}
+
+	if sl != nil {
+		if err := sl.Load(ctx, a.Shares, a.ShareStates); err != nil {
+			return err
+		}
+	}
+	if psl != nil {
+		if err := psl.Load(ctx, a.PublicShares); err != nil {
+			return err
+		}
+	}
+	if il != nil {
+		if err := il.Load(ctx, a.Invites, a.AcceptedUsers); err != nil {
+			return err
+		}
+	}
+
+	return Verify(ctx, m, a)
+}
+
+// Verify checks that every entry of the archive is present in the given managers.
+// Shares are matched by owner, resource and grantee and public shares by token,
+// as backends may assign new ids on import.
+func Verify(ctx context.Context, m Managers, a *Archive) error {
+	got, err := Export(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	if m.Shares != nil {
+		keys := make(map[string]bool, len(got.Shares))
+		for _, s := range got.Shares {
+			keys[shareKey(s)] = true
+		}
+		if n := countMissing(len(a.Shares), func(i int) bool { return keys[shareKey(a.Shares[i])] }); n > 0 {
+			return errtypes.InternalError(fmt.Sprintf("backup: %d shares missing after import", n))
+		}
+	}
+
+	if m.PublicShares != nil {
+		tokens := make(map[string]bool, len(got.PublicShares))
+		for _, s := range got.PublicShares {
+			tokens[s.PublicShare.Token] = true
+		}
+		if n := countMissing(len(a.PublicShares), func(i int) bool { return tokens[a.PublicShares[i].PublicShare.Token] }); n > 0 {
+			return errtypes.InternalError(fmt.Sprintf("backup: %d public shares missing after import", n))
+		}
+	}
+
+	if m.Invites != nil {
+		tokens := make(map[string]bool, len(got.Invites))
+		for _, t := range got.Invites {
+			tokens[t.Token] = true
+		}
+		if n := countMissing(len(a.Invites), func(i int) bool { return tokens[a.Invites[i].Token] }); n > 0 {
+			return errtypes.InternalError(fmt.Sprintf("backup: %d invites missing after import", n))
+		}
+		for k, users := range a.AcceptedUsers {
+			if len(got.AcceptedUsers[k]) < len(users) {
+				return errtypes.InternalError(fmt.Sprintf("backup: accepted users of %s missing after import", k))
+			}
+		}
+	}
+
+	return nil
+}
+
+func countMissing(n int, found func(int) bool) int {
+	missing := 0
+	for i := 0; i < n; i++ {
+		if !found(i) {
+			missing++
+		}
+	}
+	return missing
+}
+
+// shareKey only relies on opaque ids, as not all backends keep track of the idp.
+func shareKey(s *collaboration.Share) string {
+	g := s.GetGrantee()
+	grantee := g.GetUserId().GetOpaqueId()
+	if grantee == "" {
+		grantee = g.GetGroupId().GetOpaqueId()
+	}
+	return fmt.Sprintf("%s:%s:%s:%d:%s", s.GetOwner().GetOpaqueId(), s.GetResourceId().GetStorageId(), s.GetResourceId().GetOpaqueId(), g.GetType(), grantee)
+}
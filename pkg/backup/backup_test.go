@@ -0,0 +1,178 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/backup"
+	"github.com/cs3org/reva/pkg/errtypes"
+	invitejson "github.com/cs3org/reva/pkg/ocm/invite/manager/json"
+	"github.com/cs3org/reva/pkg/publicshare"
+	publicsharejson "github.com/cs3org/reva/pkg/publicshare/manager/json"
+	"github.com/cs3org/reva/pkg/share"
+	sharejson "github.com/cs3org/reva/pkg/share/manager/json"
+)
+
+var (
+	einstein = &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein"}
+	marie    = &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie"}
+)
+
+func newArchive() *backup.Archive {
+	perms := &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true}
+	shareID := &collaboration.ShareId{OpaqueId: "1"}
+	return &backup.Archive{
+		Version: backup.Version,
+		Shares: []*collaboration.Share{{
+			Id:          shareID,
+			ResourceId:  &provider.ResourceId{StorageId: "storage", OpaqueId: "file"},
+			Permissions: &collaboration.SharePermissions{Permissions: perms},
+			Grantee:     &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: marie}},
+			Owner:       einstein,
+			Creator:     einstein,
+		}},
+		ShareStates: []*share.ReceivedShareState{{
+			UserID:  marie,
+			ShareID: shareID,
+			State:   collaboration.ShareState_SHARE_STATE_ACCEPTED,
+		}},
+		PublicShares: []*publicshare.WithPassword{{
+			PublicShare: &link.PublicShare{
+				Id:                &link.PublicShareId{OpaqueId: "2"},
+				Token:             "token",
+				ResourceId:        &provider.ResourceId{StorageId: "storage", OpaqueId: "folder"},
+				Permissions:       &link.PublicSharePermissions{Permissions: perms},
+				Owner:             einstein,
+				Creator:           einstein,
+				PasswordProtected: true,
+			},
+			Password: "$2a$11$hash",
+		}},
+		Invites: []*invitepb.InviteToken{{Token: "invite", UserId: einstein}},
+		AcceptedUsers: map[string][]*userpb.User{
+			"einstein": {{Id: &userpb.UserId{Idp: "remote.org", OpaqueId: "richard"}}},
+		},
+	}
+}
+
+func newJSONManagers(t *testing.T) backup.Managers {
+	dir, err := ioutil.TempDir("", "reva-backup-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	shares, err := sharejson.New(map[string]interface{}{"file": path.Join(dir, "shares.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicShares, err := publicsharejson.New(map[string]interface{}{"file": path.Join(dir, "publicshares.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invites, err := invitejson.New(map[string]interface{}{"file": path.Join(dir, "invites.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backup.Managers{Shares: shares, PublicShares: publicShares, Invites: invites}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := newJSONManagers(t)
+	if err := backup.Import(ctx, src, newArchive()); err != nil {
+		t.Fatalf("import into source failed: %v", err)
+	}
+
+	exported, err := backup.Export(ctx, src)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := backup.Encode(buf, exported); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	decoded, err := backup.Decode(buf)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	dst := newJSONManagers(t)
+	if err := backup.Import(ctx, dst, decoded); err != nil {
+		t.Fatalf("import into destination failed: %v", err)
+	}
+
+	got, err := backup.Export(ctx, dst)
+	if err != nil {
+		t.Fatalf("export of destination failed: %v", err)
+	}
+	if len(got.Shares) != 1 || len(got.ShareStates) != 1 || len(got.PublicShares) != 1 || len(got.Invites) != 1 || len(got.AcceptedUsers["einstein"]) != 1 {
+		t.Fatalf("unexpected content after round trip: %+v", got)
+	}
+	if got.ShareStates[0].State != collaboration.ShareState_SHARE_STATE_ACCEPTED || got.ShareStates[0].UserID.OpaqueId != "marie" {
+		t.Errorf("share state not preserved: %+v", got.ShareStates[0])
+	}
+	if got.PublicShares[0].Password != "$2a$11$hash" || got.PublicShares[0].PublicShare.Token != "token" {
+		t.Errorf("public share not preserved: %+v", got.PublicShares[0])
+	}
+
+	// importing the same archive twice must not duplicate entries
+	if err := backup.Import(ctx, dst, decoded); err == nil {
+		t.Error("expected a second import to fail")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]func(a *backup.Archive){
+		"wrong version":        func(a *backup.Archive) { a.Version = 0 },
+		"incomplete share":     func(a *backup.Archive) { a.Shares[0].Owner = nil },
+		"duplicate share":      func(a *backup.Archive) { a.Shares = append(a.Shares, a.Shares[0]) },
+		"dangling share state": func(a *backup.Archive) { a.ShareStates[0].ShareID = &collaboration.ShareId{OpaqueId: "unknown"} },
+		"missing password":     func(a *backup.Archive) { a.PublicShares[0].Password = "" },
+		"duplicate token":      func(a *backup.Archive) { a.PublicShares = append(a.PublicShares, a.PublicShares[0]) },
+		"invite without user":  func(a *backup.Archive) { a.Invites[0].UserId = nil },
+		"accepted user no id":  func(a *backup.Archive) { a.AcceptedUsers["einstein"][0].Id = nil },
+	}
+
+	if err := backup.Validate(newArchive()); err != nil {
+		t.Fatalf("valid archive rejected: %v", err)
+	}
+
+	for name, corrupt := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := newArchive()
+			corrupt(a)
+			err := backup.Validate(a)
+			if _, ok := err.(errtypes.IsBadRequest); !ok {
+				t.Errorf("expected a bad request error, got %v", err)
+			}
+		})
+	}
+}
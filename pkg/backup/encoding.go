@@ -0,0 +1,174 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package backup
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// jsonArchive is the on-disk form of an Archive. CS3 messages are embedded
+// as encoded by protojson.
+type jsonArchive struct {
+	Version       int                          `json:"version"`
+	Created       time.Time                    `json:"created"`
+	Shares        []json.RawMessage            `json:"shares"`
+	ShareStates   []jsonShareState             `json:"share_states"`
+	PublicShares  []jsonPublicShare            `json:"public_shares"`
+	Invites       []json.RawMessage            `json:"ocm_invites"`
+	AcceptedUsers map[string][]json.RawMessage `json:"ocm_accepted_users"`
+}
+
+type jsonShareState struct {
+	UserID  json.RawMessage `json:"user_id"`
+	ShareID json.RawMessage `json:"share_id"`
+	State   string          `json:"state"`
+}
+
+type jsonPublicShare struct {
+	Share    json.RawMessage `json:"share"`
+	Password string          `json:"password,omitempty"`
+}
+
+// Encode writes the archive to w in the portable JSON format.
+func Encode(w io.Writer, a *Archive) error {
+	j := &jsonArchive{
+		Version:       a.Version,
+		Created:       a.Created,
+		AcceptedUsers: make(map[string][]json.RawMessage, len(a.AcceptedUsers)),
+	}
+
+	var err error
+	if j.Shares, err = encodeAll(len(a.Shares), func(i int) proto.Message { return a.Shares[i] }); err != nil {
+		return err
+	}
+	for _, rs := range a.ShareStates {
+		st := jsonShareState{State: rs.State.String()}
+		if st.UserID, err = utils.MarshalProtoV1ToJSON(rs.UserID); err != nil {
+			return errors.Wrap(err, "backup: error encoding user id")
+		}
+		if st.ShareID, err = utils.MarshalProtoV1ToJSON(rs.ShareID); err != nil {
+			return errors.Wrap(err, "backup: error encoding share id")
+		}
+		j.ShareStates = append(j.ShareStates, st)
+	}
+	for _, s := range a.PublicShares {
+		ps := jsonPublicShare{Password: s.Password}
+		if ps.Share, err = utils.MarshalProtoV1ToJSON(s.PublicShare); err != nil {
+			return errors.Wrap(err, "backup: error encoding public share")
+		}
+		j.PublicShares = append(j.PublicShares, ps)
+	}
+	if j.Invites, err = encodeAll(len(a.Invites), func(i int) proto.Message { return a.Invites[i] }); err != nil {
+		return err
+	}
+	for k, users := range a.AcceptedUsers {
+		if j.AcceptedUsers[k], err = encodeAll(len(users), func(i int) proto.Message { return users[i] }); err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j)
+}
+
+// Decode reads an archive in the portable JSON format from r.
+func Decode(r io.Reader) (*Archive, error) {
+	j := &jsonArchive{}
+	if err := json.NewDecoder(r).Decode(j); err != nil {
+		return nil, errors.Wrap(err, "backup: error decoding archive")
+	}
+
+	a := &Archive{
+		Version:       j.Version,
+		Created:       j.Created,
+		AcceptedUsers: make(map[string][]*userpb.User, len(j.AcceptedUsers)),
+	}
+	for _, raw := range j.Shares {
+		s := &collaboration.Share{}
+		if err := utils.UnmarshalJSONToProtoV1(raw, s); err != nil {
+			return nil, errors.Wrap(err, "backup: error decoding share")
+		}
+		a.Shares = append(a.Shares, s)
+	}
+	for _, st := range j.ShareStates {
+		rs := &share.ReceivedShareState{UserID: &userpb.UserId{}, ShareID: &collaboration.ShareId{}}
+		if err := utils.UnmarshalJSONToProtoV1(st.UserID, rs.UserID); err != nil {
+			return nil, errors.Wrap(err, "backup: error decoding user id")
+		}
+		if err := utils.UnmarshalJSONToProtoV1(st.ShareID, rs.ShareID); err != nil {
+			return nil, errors.Wrap(err, "backup: error decoding share id")
+		}
+		state, ok := collaboration.ShareState_value[st.State]
+		if !ok {
+			return nil, errors.New("backup: unknown share state " + st.State)
+		}
+		rs.State = collaboration.ShareState(state)
+		a.ShareStates = append(a.ShareStates, rs)
+	}
+	for _, ps := range j.PublicShares {
+		s := &link.PublicShare{}
+		if err := utils.UnmarshalJSONToProtoV1(ps.Share, s); err != nil {
+			return nil, errors.Wrap(err, "backup: error decoding public share")
+		}
+		a.PublicShares = append(a.PublicShares, &publicshare.WithPassword{PublicShare: s, Password: ps.Password})
+	}
+	for _, raw := range j.Invites {
+		t := &invitepb.InviteToken{}
+		if err := utils.UnmarshalJSONToProtoV1(raw, t); err != nil {
+			return nil, errors.Wrap(err, "backup: error decoding invite")
+		}
+		a.Invites = append(a.Invites, t)
+	}
+	for k, users := range j.AcceptedUsers {
+		for _, raw := range users {
+			u := &userpb.User{}
+			if err := utils.UnmarshalJSONToProtoV1(raw, u); err != nil {
+				return nil, errors.Wrap(err, "backup: error decoding accepted user")
+			}
+			a.AcceptedUsers[k] = append(a.AcceptedUsers[k], u)
+		}
+	}
+
+	return a, nil
+}
+
+func encodeAll(n int, get func(int) proto.Message) ([]json.RawMessage, error) {
+	res := make([]json.RawMessage, 0, n)
+	for i := 0; i < n; i++ {
+		b, err := utils.MarshalProtoV1ToJSON(get(i))
+		if err != nil {
+			return nil, errors.Wrap(err, "backup: error encoding message")
+		}
+		res = append(res, b)
+	}
+	return res, nil
+}
@@ -0,0 +1,288 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package backup_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/backup"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/golang/protobuf/proto"
+)
+
+// TestEncodingProperties round-trips randomized archives through the JSON
+// encoding and checks that nothing is lost, in particular the oneof grantees
+// and the enums, which silently vanish with a naive JSON encoding.
+func TestEncodingProperties(t *testing.T) {
+	for seed := int64(0); seed < 500; seed++ {
+		g := &generator{rand.New(rand.NewSource(seed))}
+		a := g.archive()
+
+		var buf bytes.Buffer
+		if err := backup.Encode(&buf, a); err != nil {
+			t.Fatalf("seed %d: error encoding: %v", seed, err)
+		}
+		b, err := backup.Decode(&buf)
+		if err != nil {
+			t.Fatalf("seed %d: error decoding: %v", seed, err)
+		}
+		if err := compareArchives(a, b); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+}
+
+type generator struct {
+	*rand.Rand
+}
+
+var runes = []rune("abcXYZ019 _-./:@\"\\\n\téßü日本🙂")
+
+func (g *generator) string() string {
+	r := make([]rune, g.Intn(12))
+	for i := range r {
+		r[i] = runes[g.Intn(len(runes))]
+	}
+	return string(r)
+}
+
+func (g *generator) id() string {
+	return fmt.Sprintf("%s%d", g.string(), g.Int63())
+}
+
+func (g *generator) bool() bool {
+	return g.Intn(2) == 0
+}
+
+func (g *generator) userID() *userpb.UserId {
+	return &userpb.UserId{Idp: g.string(), OpaqueId: g.id()}
+}
+
+func (g *generator) resourceID() *provider.ResourceId {
+	return &provider.ResourceId{StorageId: g.string(), OpaqueId: g.id()}
+}
+
+func (g *generator) timestamp() *types.Timestamp {
+	if g.bool() {
+		return nil
+	}
+	return &types.Timestamp{Seconds: uint64(g.Int63n(1 << 40)), Nanos: uint32(g.Int31n(1e9))}
+}
+
+func (g *generator) permissions() *provider.ResourcePermissions {
+	return &provider.ResourcePermissions{
+		AddGrant:             g.bool(),
+		CreateContainer:      g.bool(),
+		Delete:               g.bool(),
+		GetPath:              g.bool(),
+		GetQuota:             g.bool(),
+		InitiateFileDownload: g.bool(),
+		InitiateFileUpload:   g.bool(),
+		ListGrants:           g.bool(),
+		ListContainer:        g.bool(),
+		ListFileVersions:     g.bool(),
+		ListRecycle:          g.bool(),
+		Move:                 g.bool(),
+		RemoveGrant:          g.bool(),
+		PurgeRecycle:         g.bool(),
+		RestoreFileVersion:   g.bool(),
+		RestoreRecycleItem:   g.bool(),
+		Stat:                 g.bool(),
+		UpdateGrant:          g.bool(),
+	}
+}
+
+func (g *generator) grantee() *provider.Grantee {
+	if g.bool() {
+		return &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: g.userID()},
+		}
+	}
+	return &provider.Grantee{
+		Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+		Id:   &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{Idp: g.string(), OpaqueId: g.id()}},
+	}
+}
+
+func (g *generator) opaque() *types.Opaque {
+	if g.bool() {
+		return nil
+	}
+	o := &types.Opaque{Map: map[string]*types.OpaqueEntry{}}
+	for i := g.Intn(3); i >= 0; i-- {
+		v := make([]byte, g.Intn(16))
+		g.Read(v)
+		o.Map[g.id()] = &types.OpaqueEntry{Decoder: "plain", Value: v}
+	}
+	return o
+}
+
+func (g *generator) user() *userpb.User {
+	return &userpb.User{
+		Id:           g.userID(),
+		Username:     g.string(),
+		Mail:         g.string(),
+		MailVerified: g.bool(),
+		DisplayName:  g.string(),
+		Groups:       []string{g.string(), g.string()}[:g.Intn(3)],
+		Opaque:       g.opaque(),
+	}
+}
+
+func (g *generator) archive() *backup.Archive {
+	a := &backup.Archive{
+		Version:       backup.Version,
+		Created:       time.Unix(g.Int63n(1<<33), g.Int63n(1e9)).UTC(),
+		AcceptedUsers: map[string][]*userpb.User{},
+	}
+	states := []collaboration.ShareState{
+		collaboration.ShareState_SHARE_STATE_PENDING,
+		collaboration.ShareState_SHARE_STATE_ACCEPTED,
+		collaboration.ShareState_SHARE_STATE_REJECTED,
+	}
+
+	for i := g.Intn(5); i > 0; i-- {
+		s := &collaboration.Share{
+			Id:          &collaboration.ShareId{OpaqueId: g.id()},
+			ResourceId:  g.resourceID(),
+			Permissions: &collaboration.SharePermissions{Permissions: g.permissions()},
+			Grantee:     g.grantee(),
+			Owner:       g.userID(),
+			Creator:     g.userID(),
+			Ctime:       g.timestamp(),
+			Mtime:       g.timestamp(),
+		}
+		a.Shares = append(a.Shares, s)
+		if g.bool() {
+			a.ShareStates = append(a.ShareStates, &share.ReceivedShareState{
+				UserID:  g.userID(),
+				ShareID: s.Id,
+				State:   states[g.Intn(len(states))],
+			})
+		}
+	}
+
+	for i := g.Intn(5); i > 0; i-- {
+		ps := &publicshare.WithPassword{PublicShare: &link.PublicShare{
+			Id:                &link.PublicShareId{OpaqueId: g.id()},
+			Token:             g.id(),
+			ResourceId:        g.resourceID(),
+			Permissions:       &link.PublicSharePermissions{Permissions: g.permissions()},
+			Owner:             g.userID(),
+			Creator:           g.userID(),
+			Ctime:             g.timestamp(),
+			Mtime:             g.timestamp(),
+			PasswordProtected: g.bool(),
+			Expiration:        g.timestamp(),
+			DisplayName:       g.string(),
+		}}
+		if ps.PublicShare.PasswordProtected {
+			ps.Password = g.string()
+		}
+		a.PublicShares = append(a.PublicShares, ps)
+	}
+
+	for i := g.Intn(5); i > 0; i-- {
+		a.Invites = append(a.Invites, &invitepb.InviteToken{
+			Token:      g.id(),
+			UserId:     g.userID(),
+			Expiration: g.timestamp(),
+		})
+	}
+	for i := g.Intn(3); i > 0; i-- {
+		users := []*userpb.User{g.user()}
+		if g.bool() {
+			users = append(users, g.user())
+		}
+		a.AcceptedUsers[g.id()] = users
+	}
+	return a
+}
+
+func compareArchives(a, b *backup.Archive) error {
+	if a.Version != b.Version || !a.Created.Equal(b.Created) {
+		return fmt.Errorf("header changed: %d %v, %d %v", a.Version, a.Created, b.Version, b.Created)
+	}
+
+	if len(a.Shares) != len(b.Shares) {
+		return fmt.Errorf("got %d shares, expected %d", len(b.Shares), len(a.Shares))
+	}
+	for i := range a.Shares {
+		if !proto.Equal(a.Shares[i], b.Shares[i]) {
+			return fmt.Errorf("share %d changed: %v, expected %v", i, b.Shares[i], a.Shares[i])
+		}
+	}
+
+	if len(a.ShareStates) != len(b.ShareStates) {
+		return fmt.Errorf("got %d share states, expected %d", len(b.ShareStates), len(a.ShareStates))
+	}
+	for i, s := range a.ShareStates {
+		o := b.ShareStates[i]
+		if !proto.Equal(s.UserID, o.UserID) || !proto.Equal(s.ShareID, o.ShareID) || s.State != o.State {
+			return fmt.Errorf("share state %d changed: %v, expected %v", i, o, s)
+		}
+	}
+
+	if len(a.PublicShares) != len(b.PublicShares) {
+		return fmt.Errorf("got %d public shares, expected %d", len(b.PublicShares), len(a.PublicShares))
+	}
+	for i, s := range a.PublicShares {
+		o := b.PublicShares[i]
+		if !proto.Equal(s.PublicShare, o.PublicShare) || s.Password != o.Password {
+			return fmt.Errorf("public share %d changed: %v, expected %v", i, o.PublicShare, s.PublicShare)
+		}
+	}
+
+	if len(a.Invites) != len(b.Invites) {
+		return fmt.Errorf("got %d invites, expected %d", len(b.Invites), len(a.Invites))
+	}
+	for i := range a.Invites {
+		if !proto.Equal(a.Invites[i], b.Invites[i]) {
+			return fmt.Errorf("invite %d changed: %v, expected %v", i, b.Invites[i], a.Invites[i])
+		}
+	}
+
+	if len(a.AcceptedUsers) != len(b.AcceptedUsers) {
+		return fmt.Errorf("got accepted users of %d users, expected %d", len(b.AcceptedUsers), len(a.AcceptedUsers))
+	}
+	for k, users := range a.AcceptedUsers {
+		if len(users) != len(b.AcceptedUsers[k]) {
+			return fmt.Errorf("got %d users accepted by %q, expected %d", len(b.AcceptedUsers[k]), k, len(users))
+		}
+		for i := range users {
+			if !proto.Equal(users[i], b.AcceptedUsers[k][i]) {
+				return fmt.Errorf("user %d accepted by %q changed: %v, expected %v", i, k, b.AcceptedUsers[k][i], users[i])
+			}
+		}
+	}
+	return nil
+}
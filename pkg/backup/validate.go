@@ -0,0 +1,100 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package backup
+
+import (
+	"fmt"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// Validate checks the consistency of an archive before it gets imported.
+func Validate(a *Archive) error {
+	if a == nil {
+		return errtypes.BadRequest("backup: empty archive")
+	}
+	if a.Version != Version {
+		return errtypes.BadRequest(fmt.Sprintf("backup: unsupported archive version %d", a.Version))
+	}
+
+	ids := make(map[string]bool, len(a.Shares))
+	keys := make(map[string]bool, len(a.Shares))
+	for i, s := range a.Shares {
+		if s.GetId().GetOpaqueId() == "" || s.GetOwner() == nil || s.GetResourceId() == nil || s.GetGrantee() == nil || s.GetPermissions().GetPermissions() == nil {
+			return errtypes.BadRequest(fmt.Sprintf("backup: share %d is incomplete", i))
+		}
+		if ids[s.Id.OpaqueId] {
+			return errtypes.BadRequest("backup: duplicate share id " + s.Id.OpaqueId)
+		}
+		k := shareKey(s)
+		if keys[k] {
+			return errtypes.BadRequest("backup: duplicate share " + s.Id.OpaqueId)
+		}
+		ids[s.Id.OpaqueId], keys[k] = true, true
+	}
+
+	for _, rs := range a.ShareStates {
+		if rs.UserID == nil || rs.ShareID == nil {
+			return errtypes.BadRequest("backup: incomplete received share state")
+		}
+		if !ids[rs.ShareID.OpaqueId] {
+			return errtypes.BadRequest("backup: received share state refers to unknown share " + rs.ShareID.OpaqueId)
+		}
+	}
+
+	ids = make(map[string]bool, len(a.PublicShares))
+	tokens := make(map[string]bool, len(a.PublicShares))
+	for i, s := range a.PublicShares {
+		ps := s.PublicShare
+		if ps.GetId().GetOpaqueId() == "" || ps.GetToken() == "" || ps.GetResourceId() == nil || ps.GetPermissions().GetPermissions() == nil {
+			return errtypes.BadRequest(fmt.Sprintf("backup: public share %d is incomplete", i))
+		}
+		if ps.PasswordProtected && s.Password == "" {
+			return errtypes.BadRequest("backup: password missing for public share " + ps.Id.OpaqueId)
+		}
+		if ids[ps.Id.OpaqueId] {
+			return errtypes.BadRequest("backup: duplicate public share id " + ps.Id.OpaqueId)
+		}
+		if tokens[ps.Token] {
+			return errtypes.BadRequest("backup: duplicate public share token " + ps.Token)
+		}
+		ids[ps.Id.OpaqueId], tokens[ps.Token] = true, true
+	}
+
+	tokens = make(map[string]bool, len(a.Invites))
+	for i, t := range a.Invites {
+		if t.GetToken() == "" || t.GetUserId() == nil {
+			return errtypes.BadRequest(fmt.Sprintf("backup: invite %d is incomplete", i))
+		}
+		if tokens[t.Token] {
+			return errtypes.BadRequest("backup: duplicate invite token " + t.Token)
+		}
+		tokens[t.Token] = true
+	}
+
+	for k, users := range a.AcceptedUsers {
+		for _, u := range users {
+			if u.GetId() == nil {
+				return errtypes.BadRequest("backup: accepted user without id for " + k)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,323 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package dbrouter routes the queries of the SQL backed managers between a
+// primary database and its read replicas. Writes always go to the primary,
+// reads go to the replicas which are not lagging behind, except for the
+// users who wrote recently and have to see their own changes.
+package dbrouter
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Config holds the replica options of the SQL backed managers.
+type Config struct {
+	// ReadDSNs are the data source names of the read replicas, which have to
+	// use the same driver as the primary.
+	ReadDSNs []string `mapstructure:"db_read_dsns"`
+	// MaxLag is the replication lag in seconds above which a replica is not
+	// used for reads.
+	MaxLag int `mapstructure:"db_replica_max_lag"`
+	// Stickiness is the time in seconds the reads of a user go to the primary
+	// after the user wrote, so that the user sees their own changes.
+	Stickiness int `mapstructure:"db_replica_stickiness"`
+	// CheckInterval is the interval in seconds the replication lag is checked.
+	CheckInterval int `mapstructure:"db_replica_check_interval"`
+	// LagQuery returns the replication lag in seconds in its first column.
+	// The Seconds_Behind_Master column of SHOW SLAVE STATUS is used if empty.
+	LagQuery string `mapstructure:"db_replica_lag_query"`
+}
+
+func (c *Config) init() {
+	if c.MaxLag == 0 {
+		c.MaxLag = 5
+	}
+	if c.Stickiness == 0 {
+		c.Stickiness = 10
+	}
+	if c.CheckInterval == 0 {
+		c.CheckInterval = 5
+	}
+}
+
+type replica struct {
+	db      *sql.DB
+	dsn     int
+	healthy int32
+	// lag is the last replication lag checked, in milliseconds, or -1 if it could not be checked.
+	lag int64
+}
+
+// Router routes the queries between the primary and the replicas.
+type Router struct {
+	c        *Config
+	primary  *sql.DB
+	replicas []*replica
+	next     uint32
+
+	mu      sync.Mutex
+	writes  map[string]time.Time
+	pruned  time.Time
+	closing chan struct{}
+}
+
+// New returns a router for the primary database and the replicas of the
+// configuration. The replicas are only used once their lag was checked.
+func New(driver string, primary *sql.DB, c *Config) (*Router, error) {
+	c.init()
+	r := &Router{
+		c:       c,
+		primary: primary,
+		writes:  map[string]time.Time{},
+		pruned:  time.Now(),
+		closing: make(chan struct{}),
+	}
+	for i, dsn := range c.ReadDSNs {
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			r.Close()
+			return nil, errors.Wrapf(err, "dbrouter: error opening replica %d", i)
+		}
+		r.replicas = append(r.replicas, &replica{db: db, dsn: i, lag: -1})
+	}
+	if len(r.replicas) > 0 {
+		go r.checkReplicas()
+	}
+	return r, nil
+}
+
+// Writer returns the primary database for a write on behalf of the user of
+// the context, whose reads go to the primary from now on for the configured
+// stickiness.
+func (r *Router) Writer(ctx context.Context) *sql.DB {
+	if len(r.replicas) == 0 {
+		return r.primary
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	r.writes[writer(ctx)] = now
+	stickiness := time.Duration(r.c.Stickiness) * time.Second
+	if now.Sub(r.pruned) > stickiness {
+		for k, t := range r.writes {
+			if now.Sub(t) > stickiness {
+				delete(r.writes, k)
+			}
+		}
+		r.pruned = now
+	}
+	r.mu.Unlock()
+	return r.primary
+}
+
+// Reader returns the database for a read on behalf of the user of the
+// context, a healthy replica or the primary if there is none or the user
+// wrote recently.
+func (r *Router) Reader(ctx context.Context) *sql.DB {
+	if len(r.replicas) == 0 || r.sticky(ctx) {
+		return r.primary
+	}
+	n := atomic.AddUint32(&r.next, 1)
+	for i := range r.replicas {
+		rep := r.replicas[(int(n)+i)%len(r.replicas)]
+		if atomic.LoadInt32(&rep.healthy) == 1 {
+			return rep.db
+		}
+	}
+	return r.primary
+}
+
+// QueryRow reads a single row into dest. A row missing on a replica is
+// looked up on the primary, as it might not have been replicated yet.
+func (r *Router) QueryRow(ctx context.Context, dest []interface{}, query string, args ...interface{}) error {
+	db := r.Reader(ctx)
+	err := db.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if err == sql.ErrNoRows && db != r.primary {
+		err = r.primary.QueryRowContext(ctx, query, args...).Scan(dest...)
+	}
+	return err
+}
+
+// Probe reports the connections to the primary and the replicas. The router is
+// degraded while reads can't be sent to some of the replicas.
+func (r *Router) Probe() ops.State {
+	health := ops.Healthy
+	replicas := make([]map[string]interface{}, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		healthy := atomic.LoadInt32(&rep.healthy) == 1
+		if !healthy {
+			health = ops.Degraded
+		}
+		st := map[string]interface{}{"replica": rep.dsn, "healthy": healthy}
+		if lag := atomic.LoadInt64(&rep.lag); lag >= 0 {
+			st["lag_seconds"] = float64(lag) / 1000
+		}
+		replicas = append(replicas, st)
+	}
+	stats := r.primary.Stats()
+	return ops.State{
+		Health: health,
+		Details: map[string]interface{}{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"wait_count":       stats.WaitCount,
+			"replicas":         replicas,
+		},
+	}
+}
+
+// Collector returns the collector of the connections of the router, whose
+// samples are labeled with the name of the manager using it.
+func (r *Router) Collector(manager string) metrics.Collector {
+	return func() []metrics.Sample {
+		stats := r.primary.Stats()
+		labels := map[string]string{"manager": manager}
+		samples := []metrics.Sample{
+			{Name: "sql_connections_open", Help: "The connections open to the primary database", Labels: labels, Value: float64(stats.OpenConnections)},
+			{Name: "sql_connections_in_use", Help: "The connections to the primary database in use", Labels: labels, Value: float64(stats.InUse)},
+			{Name: "sql_connection_waits_total", Help: "The times a query waited for a connection to the primary database", Kind: metrics.Counter, Labels: labels, Value: float64(stats.WaitCount)},
+			{Name: "sql_connection_wait_seconds_total", Help: "The time the queries waited for a connection to the primary database", Kind: metrics.Counter, Labels: labels, Value: stats.WaitDuration.Seconds()},
+		}
+		for _, rep := range r.replicas {
+			labels := map[string]string{"manager": manager, "replica": strconv.Itoa(rep.dsn)}
+			healthy := float64(atomic.LoadInt32(&rep.healthy))
+			samples = append(samples, metrics.Sample{Name: "sql_replica_healthy", Help: "Whether the reads are sent to the replica", Labels: labels, Value: healthy})
+			if lag := atomic.LoadInt64(&rep.lag); lag >= 0 {
+				samples = append(samples, metrics.Sample{Name: "sql_replica_lag_seconds", Help: "The replication lag of the replica", Labels: labels, Value: float64(lag) / 1000})
+			}
+		}
+		return samples
+	}
+}
+
+// Ping checks the connection to the primary. The replicas are left out, the
+// reads fall back to the primary when they are down.
+func (r *Router) Ping(ctx context.Context) error {
+	return r.primary.PingContext(ctx)
+}
+
+// Close stops the lag checks and closes the replicas.
+func (r *Router) Close() {
+	close(r.closing)
+	for _, rep := range r.replicas {
+		_ = rep.db.Close()
+	}
+}
+
+func (r *Router) sticky(ctx context.Context) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.writes[writer(ctx)]
+	return ok && time.Since(t) <= time.Duration(r.c.Stickiness)*time.Second
+}
+
+func writer(ctx context.Context) string {
+	if u, ok := user.ContextGetUser(ctx); ok {
+		return u.GetId().GetIdp() + "/" + u.GetId().GetOpaqueId()
+	}
+	return ""
+}
+
+func (r *Router) checkReplicas() {
+	ticker := time.NewTicker(time.Duration(r.c.CheckInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		for _, rep := range r.replicas {
+			var healthy int32
+			lag, err := r.lag(rep.db)
+			switch {
+			case err != nil:
+				lag = -1
+				log.Warn().Err(err).Int("replica", rep.dsn).Msg("dbrouter: error checking replication lag")
+			case lag > float64(r.c.MaxLag):
+				log.Warn().Int("replica", rep.dsn).Float64("lag", lag).Msg("dbrouter: replica is lagging behind")
+			default:
+				healthy = 1
+			}
+			atomic.StoreInt32(&rep.healthy, healthy)
+			atomic.StoreInt64(&rep.lag, int64(lag*1000))
+		}
+
+		select {
+		case <-r.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lag returns the replication lag of the replica in seconds.
+func (r *Router) lag(db *sql.DB) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.c.CheckInterval)*time.Second)
+	defer cancel()
+
+	if r.c.LagQuery != "" {
+		var lag sql.NullFloat64
+		if err := db.QueryRowContext(ctx, r.c.LagQuery).Scan(&lag); err != nil {
+			return 0, err
+		}
+		if !lag.Valid {
+			return 0, errors.New("dbrouter: replication is not running")
+		}
+		return lag.Float64, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		// not a replica, there is nothing to lag behind
+		return 0, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+	for i, c := range cols {
+		if c != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errors.New("dbrouter: replication is not running")
+		}
+		return strconv.ParseFloat(string(values[i]), 64)
+	}
+	return 0, errors.New("dbrouter: replication lag not reported")
+}
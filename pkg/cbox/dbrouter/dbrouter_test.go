@@ -0,0 +1,69 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dbrouter
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+func TestRouting(t *testing.T) {
+	primary, repl := &sql.DB{}, &sql.DB{}
+	c := &Config{}
+	c.init()
+	r := &Router{
+		c:        c,
+		primary:  primary,
+		replicas: []*replica{{db: repl}},
+		writes:   map[string]time.Time{},
+		pruned:   time.Now(),
+	}
+
+	einstein := user.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"}})
+	marie := user.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "cernbox", OpaqueId: "marie"}})
+
+	if r.Reader(einstein) != primary {
+		t.Fatal("read from a replica which was not checked")
+	}
+
+	r.replicas[0].healthy = 1
+	if r.Reader(einstein) != repl {
+		t.Fatal("read from the primary with a healthy replica")
+	}
+
+	if r.Writer(einstein) != primary {
+		t.Fatal("write to a replica")
+	}
+	if r.Reader(einstein) != primary {
+		t.Fatal("read of a user who just wrote from a replica")
+	}
+	if r.Reader(marie) != repl {
+		t.Fatal("read of another user from the primary")
+	}
+
+	r.writes["cernbox/einstein"] = time.Now().Add(-time.Duration(c.Stickiness+1) * time.Second)
+	if r.Reader(einstein) != repl {
+		t.Fatal("read from the primary after the stickiness expired")
+	}
+}
@@ -35,8 +35,12 @@ import (
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/cbox/dbrouter"
 	conversions "github.com/cs3org/reva/pkg/cbox/utils"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/health"
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/ops"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
 	"github.com/cs3org/reva/pkg/utils"
@@ -59,11 +63,15 @@ type config struct {
 	DbHost                     string `mapstructure:"db_host"`
 	DbPort                     int    `mapstructure:"db_port"`
 	DbName                     string `mapstructure:"db_name"`
+	// Replicas configures the read replicas the listings and lookups
+	// of public shares are routed to.
+	Replicas dbrouter.Config `mapstructure:",squash"`
 }
 
 type manager struct {
-	c  *config
-	db *sql.DB
+	c      *config
+	db     *sql.DB
+	router *dbrouter.Router
 }
 
 func (c *config) init() {
@@ -107,9 +115,18 @@ func New(m map[string]interface{}) (publicshare.Manager, error) {
 		return nil, err
 	}
 
+	router, err := dbrouter.New("mysql", db, &c.Replicas)
+	if err != nil {
+		return nil, err
+	}
+	ops.Register(ops.Backends, "publicshare/sql", router.Probe)
+	health.Register("publicshare/sql", router.Ping)
+	metrics.RegisterCollector("publicshare/sql", router.Collector("publicshare/sql"))
+
 	mgr := manager{
-		c:  c,
-		db: db,
+		c:      c,
+		db:     db,
+		router: router,
 	}
 	go mgr.startJanitorRun()
 
@@ -164,7 +181,7 @@ func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *pr
 		params = append(params, t)
 	}
 
-	stmt, err := m.db.Prepare(query)
+	stmt, err := m.router.Writer(ctx).Prepare(query)
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +256,7 @@ func (m *manager) UpdatePublicShare(ctx context.Context, u *user.User, req *link
 		return nil, errtypes.NotFound(req.Ref.String())
 	}
 
-	stmt, err := m.db.Prepare(query)
+	stmt, err := m.router.Writer(ctx).Prepare(query)
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +270,7 @@ func (m *manager) UpdatePublicShare(ctx context.Context, u *user.User, req *link
 func (m *manager) getByToken(ctx context.Context, token string, u *user.User) (*link.PublicShare, string, error) {
 	s := conversions.DBShare{Token: token}
 	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, coalesce(expiration, '') as expiration, coalesce(share_name, '') as share_name, id, stime, permissions FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND share_type=? AND token=?"
-	if err := m.db.QueryRow(query, publicShareType, token).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.Expiration, &s.ShareName, &s.ID, &s.STime, &s.Permissions); err != nil {
+	if err := m.router.QueryRow(ctx, []interface{}{&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.Expiration, &s.ShareName, &s.ID, &s.STime, &s.Permissions}, query, publicShareType, token); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, "", errtypes.NotFound(token)
 		}
@@ -266,7 +283,7 @@ func (m *manager) getByID(ctx context.Context, id *link.PublicShareId, u *user.U
 	uid := conversions.FormatUserID(u.Id)
 	s := conversions.DBShare{ID: id.OpaqueId}
 	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, coalesce(token,'') as token, coalesce(expiration, '') as expiration, coalesce(share_name, '') as share_name, stime, permissions FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND share_type=? AND id=? AND (uid_owner=? OR uid_initiator=?)"
-	if err := m.db.QueryRow(query, publicShareType, id.OpaqueId, uid, uid).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.Token, &s.Expiration, &s.ShareName, &s.STime, &s.Permissions); err != nil {
+	if err := m.router.QueryRow(ctx, []interface{}{&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.Token, &s.Expiration, &s.ShareName, &s.STime, &s.Permissions}, query, publicShareType, id.OpaqueId, uid, uid); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, "", errtypes.NotFound(id.OpaqueId)
 		}
@@ -337,7 +354,7 @@ func (m *manager) ListPublicShares(ctx context.Context, u *user.User, filters []
 		query = fmt.Sprintf("%s AND (%s)", query, filterQuery)
 	}
 
-	rows, err := m.db.Query(query, params...)
+	rows, err := m.router.Reader(ctx).Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -382,7 +399,7 @@ func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link
 		return errtypes.NotFound(ref.String())
 	}
 
-	stmt, err := m.db.Prepare(query)
+	stmt, err := m.router.Writer(ctx).Prepare(query)
 	if err != nil {
 		return err
 	}
@@ -404,7 +421,7 @@ func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link
 func (m *manager) GetPublicShareByToken(ctx context.Context, token string, auth *link.PublicShareAuthentication, sign bool) (*link.PublicShare, error) {
 	s := conversions.DBShare{Token: token}
 	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, coalesce(expiration, '') as expiration, coalesce(share_name, '') as share_name, id, stime, permissions FROM oc_share WHERE share_type=? AND token=?"
-	if err := m.db.QueryRow(query, publicShareType, token).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.Expiration, &s.ShareName, &s.ID, &s.STime, &s.Permissions); err != nil {
+	if err := m.router.QueryRow(ctx, []interface{}{&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.Expiration, &s.ShareName, &s.ID, &s.STime, &s.Permissions}, query, publicShareType, token); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errtypes.NotFound(token)
 		}
@@ -485,3 +502,92 @@ func authenticate(share *link.PublicShare, pw string, auth *link.PublicShareAuth
 	}
 	return false
 }
+
+// Dump returns all the public shares stored in the database along with their password hashes.
+func (m *manager) Dump(ctx context.Context) ([]*publicshare.WithPassword, error) {
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, coalesce(token,'') as token, coalesce(expiration, '') as expiration, coalesce(share_name, '') as share_name, id, stime, permissions FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND share_type=?"
+	rows, err := m.db.Query(query, publicShareType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var s conversions.DBShare
+	shares := []*publicshare.WithPassword{}
+	for rows.Next() {
+		if err := rows.Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.Token, &s.Expiration, &s.ShareName, &s.ID, &s.STime, &s.Permissions); err != nil {
+			return nil, err
+		}
+		shares = append(shares, &publicshare.WithPassword{PublicShare: conversions.ConvertToCS3PublicShare(s), Password: s.ShareWith})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// Load inserts the given public shares in a single transaction. Numeric ids are
+// preserved, any other id is replaced by the one assigned by the database.
+func (m *manager) Load(ctx context.Context, shares []*publicshare.WithPassword) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, s := range shares {
+		ps := s.PublicShare
+		itemSource := ps.ResourceId.OpaqueId
+		fileSource, err := strconv.ParseUint(itemSource, 10, 64)
+		if err != nil {
+			fileSource = 0
+		}
+
+		query := "insert into oc_share set share_type=?,uid_owner=?,uid_initiator=?,fileid_prefix=?,item_source=?,file_source=?,permissions=?,stime=?,token=?,share_name=?"
+		params := []interface{}{publicShareType, conversions.FormatUserID(ps.Owner), conversions.FormatUserID(ps.Creator), ps.ResourceId.StorageId, itemSource, fileSource, conversions.SharePermToInt(ps.Permissions.Permissions), ps.Ctime.GetSeconds(), ps.Token, ps.DisplayName}
+		if s.Password != "" {
+			query += ",share_with=?"
+			params = append(params, s.Password)
+		}
+		if ps.Expiration != nil && ps.Expiration.Seconds != 0 {
+			query += ",expiration=?"
+			params = append(params, time.Unix(int64(ps.Expiration.Seconds), 0))
+		}
+		if _, err := strconv.ParseUint(ps.Id.GetOpaqueId(), 10, 64); err == nil {
+			query += ",id=?"
+			params = append(params, ps.Id.OpaqueId)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, params...); err != nil {
+			return errors.Wrap(err, "sql: error inserting public share "+ps.Token)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RenameUser replaces the id of a user in the owner and initiator of the public shares.
+func (m *manager) RenameUser(ctx context.Context, oldID, newID *user.UserId) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	oldUID, newUID := conversions.FormatUserID(oldID), conversions.FormatUserID(newID)
+	for _, q := range []string{
+		"update oc_share set uid_owner=? where uid_owner=? AND share_type=?",
+		"update oc_share set uid_initiator=? where uid_initiator=? AND share_type=?",
+	} {
+		if _, err := tx.ExecContext(ctx, q, newUID, oldUID, publicShareType); err != nil {
+			return errors.Wrap(err, "sql: error renaming user "+oldUID)
+		}
+	}
+
+	return tx.Commit()
+}
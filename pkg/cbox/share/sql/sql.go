@@ -22,16 +22,22 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
 	"path"
 	"strconv"
 	"strings"
 	"time"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/cbox/dbrouter"
 	conversions "github.com/cs3org/reva/pkg/cbox/utils"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/health"
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/ops"
 	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/share/manager/registry"
 	"github.com/cs3org/reva/pkg/user"
@@ -53,11 +59,15 @@ type config struct {
 	DbHost     string `mapstructure:"db_host"`
 	DbPort     int    `mapstructure:"db_port"`
 	DbName     string `mapstructure:"db_name"`
+	// Replicas configures the read replicas the listings and lookups
+	// of shares are routed to.
+	Replicas dbrouter.Config `mapstructure:",squash"`
 }
 
 type mgr struct {
-	c  *config
-	db *sql.DB
+	c      *config
+	db     *sql.DB
+	router *dbrouter.Router
 }
 
 // New returns a new share manager.
@@ -73,9 +83,18 @@ func New(m map[string]interface{}) (share.Manager, error) {
 		return nil, err
 	}
 
+	router, err := dbrouter.New("mysql", db, &c.Replicas)
+	if err != nil {
+		return nil, err
+	}
+	ops.Register(ops.Backends, "share/sql", router.Probe)
+	health.Register("share/sql", router.Ping)
+	metrics.RegisterCollector("share/sql", router.Collector("share/sql"))
+
 	return &mgr{
-		c:  c,
-		db: db,
+		c:      c,
+		db:     db,
+		router: router,
 	}, nil
 }
 
@@ -131,7 +150,7 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collabora
 	stmtString := "insert into oc_share set share_type=?,uid_owner=?,uid_initiator=?,item_type=?,fileid_prefix=?,item_source=?,file_source=?,permissions=?,stime=?,share_with=?,file_target=?"
 	stmtValues := []interface{}{shareType, conversions.FormatUserID(md.Owner), conversions.FormatUserID(user.Id), itemType, prefix, itemSource, fileSource, permissions, now, shareWith, targetPath}
 
-	stmt, err := m.db.Prepare(stmtString)
+	stmt, err := m.router.Writer(ctx).Prepare(stmtString)
 	if err != nil {
 		return nil, err
 	}
@@ -162,7 +181,7 @@ func (m *mgr) getByID(ctx context.Context, id *collaboration.ShareId) (*collabor
 	uid := conversions.FormatUserID(user.ContextMustGetUser(ctx).Id)
 	s := conversions.DBShare{ID: id.OpaqueId}
 	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, stime, permissions, share_type FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND id=? AND (uid_owner=? or uid_initiator=?)"
-	if err := m.db.QueryRow(query, id.OpaqueId, uid, uid).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.STime, &s.Permissions, &s.ShareType); err != nil {
+	if err := m.router.QueryRow(ctx, []interface{}{&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.STime, &s.Permissions, &s.ShareType}, query, id.OpaqueId, uid, uid); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errtypes.NotFound(id.OpaqueId)
 		}
@@ -178,7 +197,7 @@ func (m *mgr) getByKey(ctx context.Context, key *collaboration.ShareKey) (*colla
 	s := conversions.DBShare{}
 	shareType, shareWith := conversions.FormatGrantee(key.Grantee)
 	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, id, stime, permissions, share_type FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND uid_owner=? AND fileid_prefix=? AND item_source=? AND share_type=? AND share_with=? AND (uid_owner=? or uid_initiator=?)"
-	if err := m.db.QueryRow(query, owner, key.ResourceId.StorageId, key.ResourceId.OpaqueId, shareType, shareWith, uid, uid).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType); err != nil {
+	if err := m.router.QueryRow(ctx, []interface{}{&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType}, query, owner, key.ResourceId.StorageId, key.ResourceId.OpaqueId, shareType, shareWith, uid, uid); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errtypes.NotFound(key.String())
 		}
@@ -224,7 +243,7 @@ func (m *mgr) Unshare(ctx context.Context, ref *collaboration.ShareReference) er
 		return errtypes.NotFound(ref.String())
 	}
 
-	stmt, err := m.db.Prepare(query)
+	stmt, err := m.router.Writer(ctx).Prepare(query)
 	if err != nil {
 		return err
 	}
@@ -263,7 +282,7 @@ func (m *mgr) UpdateShare(ctx context.Context, ref *collaboration.ShareReference
 		return nil, errtypes.NotFound(ref.String())
 	}
 
-	stmt, err := m.db.Prepare(query)
+	stmt, err := m.router.Writer(ctx).Prepare(query)
 	if err != nil {
 		return nil, err
 	}
@@ -292,7 +311,7 @@ func (m *mgr) ListShares(ctx context.Context, filters []*collaboration.ListShare
 		query = fmt.Sprintf("%s AND (%s)", query, filterQuery)
 	}
 
-	rows, err := m.db.Query(query, params...)
+	rows, err := m.router.Reader(ctx).Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -318,19 +337,19 @@ func (m *mgr) ListReceivedShares(ctx context.Context) ([]*collaboration.Received
 	user := user.ContextMustGetUser(ctx)
 	uid := conversions.FormatUserID(user.Id)
 
-	params := []interface{}{uid, uid, uid, uid}
+	params := []interface{}{uid, uid, uid, time.Now(), uid}
 	for _, v := range user.Groups {
 		params = append(params, v)
 	}
 
-	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, ts.id, stime, permissions, share_type, accepted, coalesce(tr.rejected_by, '') as rejected_by FROM oc_share ts LEFT JOIN oc_share_acl tr ON (ts.id = tr.id AND tr.rejected_by = ?) WHERE (orphan = 0 or orphan IS NULL) AND (uid_owner != ? AND uid_initiator != ?) "
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, ts.id, stime, permissions, share_type, accepted, coalesce(tr.rejected_by, '') as rejected_by FROM oc_share ts LEFT JOIN oc_share_acl tr ON (ts.id = tr.id AND tr.rejected_by = ?) WHERE (orphan = 0 or orphan IS NULL) AND (uid_owner != ? AND uid_initiator != ?) AND (expiration IS NULL OR expiration > ?) "
 	if len(user.Groups) > 0 {
 		query += "AND (share_with=? OR share_with in (?" + strings.Repeat(",?", len(user.Groups)-1) + "))"
 	} else {
 		query += "AND (share_with=?)"
 	}
 
-	rows, err := m.db.Query(query, params...)
+	rows, err := m.router.Reader(ctx).Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -351,23 +370,179 @@ func (m *mgr) ListReceivedShares(ctx context.Context) ([]*collaboration.Received
 	return shares, nil
 }
 
+// ListSharesPage returns the page of the shares created by the user matching
+// the filters and the options, which are applied in the query.
+func (m *mgr) ListSharesPage(ctx context.Context, filters []*collaboration.ListSharesRequest_Filter, o *share.ListOptions) ([]*collaboration.Share, int, error) {
+	uid := conversions.FormatUserID(user.ContextMustGetUser(ctx).Id)
+	where := "(orphan = 0 or orphan IS NULL) AND (uid_owner=? or uid_initiator=?) AND (share_type=? OR share_type=?)"
+	params := []interface{}{uid, uid, 0, 1}
+	for _, f := range filters {
+		if f.Type == collaboration.ListSharesRequest_Filter_TYPE_RESOURCE_ID {
+			where += " AND (fileid_prefix=? AND item_source=?)"
+			params = append(params, f.GetResourceId().StorageId, f.GetResourceId().OpaqueId)
+		}
+	}
+	where, params = listOptionsQuery(where, params, o)
+
+	var total int
+	if err := m.router.QueryRow(ctx, []interface{}{&total}, "SELECT COUNT(*) FROM oc_share WHERE "+where, params...); err != nil {
+		return nil, 0, err
+	}
+
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, id, stime, permissions, share_type FROM oc_share WHERE " + where
+	query, params = pageQuery(query, params, o, "id")
+	rows, err := m.router.Reader(ctx).Query(query, params...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var s conversions.DBShare
+	shares := []*collaboration.Share{}
+	for rows.Next() {
+		if err := rows.Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType); err != nil {
+			continue
+		}
+		shares = append(shares, conversions.ConvertToCS3Share(s))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return shares, total, nil
+}
+
+// ListReceivedSharesPage returns the page of the shares received by the user
+// matching the options, which are applied in the query.
+func (m *mgr) ListReceivedSharesPage(ctx context.Context, o *share.ListOptions) ([]*collaboration.ReceivedShare, int, error) {
+	user := user.ContextMustGetUser(ctx)
+	uid := conversions.FormatUserID(user.Id)
+
+	params := []interface{}{uid, uid, uid, time.Now(), uid}
+	for _, v := range user.Groups {
+		params = append(params, v)
+	}
+	where := "(orphan = 0 or orphan IS NULL) AND (uid_owner != ? AND uid_initiator != ?) AND (expiration IS NULL OR expiration > ?) "
+	if len(user.Groups) > 0 {
+		where += "AND (share_with=? OR share_with in (?" + strings.Repeat(",?", len(user.Groups)-1) + "))"
+	} else {
+		where += "AND (share_with=?)"
+	}
+	where, params = listOptionsQuery(where, params, o)
+	if len(o.States) > 0 {
+		states := make([]string, 0, len(o.States))
+		for _, st := range o.States {
+			switch st {
+			case collaboration.ShareState_SHARE_STATE_PENDING:
+				states = append(states, "(coalesce(tr.rejected_by, '') = '' AND accepted = 0)")
+			case collaboration.ShareState_SHARE_STATE_ACCEPTED:
+				states = append(states, "(coalesce(tr.rejected_by, '') = '' AND accepted = 1)")
+			case collaboration.ShareState_SHARE_STATE_REJECTED:
+				states = append(states, "coalesce(tr.rejected_by, '') != ''")
+			}
+		}
+		if len(states) == 0 {
+			return []*collaboration.ReceivedShare{}, 0, nil
+		}
+		where += " AND (" + strings.Join(states, " OR ") + ")"
+	}
+
+	from := "FROM oc_share ts LEFT JOIN oc_share_acl tr ON (ts.id = tr.id AND tr.rejected_by = ?) WHERE "
+	var total int
+	if err := m.router.QueryRow(ctx, []interface{}{&total}, "SELECT COUNT(*) "+from+where, params...); err != nil {
+		return nil, 0, err
+	}
+
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, ts.id, stime, permissions, share_type, accepted, coalesce(tr.rejected_by, '') as rejected_by " + from + where
+	query, params = pageQuery(query, params, o, "ts.id")
+	rows, err := m.router.Reader(ctx).Query(query, params...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var s conversions.DBShare
+	shares := []*collaboration.ReceivedShare{}
+	for rows.Next() {
+		if err := rows.Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType, &s.State, &s.RejectedBy); err != nil {
+			continue
+		}
+		shares = append(shares, conversions.ConvertToCS3ReceivedShare(s))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return shares, total, nil
+}
+
+// listOptionsQuery narrows the where clause to the resource, space and
+// grantee types of the list options.
+func listOptionsQuery(where string, params []interface{}, o *share.ListOptions) (string, []interface{}) {
+	if o.ResourceID != nil {
+		where += " AND (fileid_prefix=? AND item_source=?)"
+		params = append(params, o.ResourceID.StorageId, o.ResourceID.OpaqueId)
+	}
+	if o.SpaceID != "" {
+		where += " AND fileid_prefix=?"
+		params = append(params, o.SpaceID)
+	}
+	if len(o.GranteeTypes) > 0 {
+		types := make([]string, 0, len(o.GranteeTypes))
+		for _, t := range o.GranteeTypes {
+			switch t {
+			case provider.GranteeType_GRANTEE_TYPE_USER:
+				types = append(types, "share_type=0")
+			case provider.GranteeType_GRANTEE_TYPE_GROUP:
+				types = append(types, "share_type=1")
+			}
+		}
+		if len(types) == 0 {
+			types = append(types, "1=0")
+		}
+		where += " AND (" + strings.Join(types, " OR ") + ")"
+	}
+	return where, params
+}
+
+// pageQuery sorts and pages the query as set by the list options.
+func pageQuery(query string, params []interface{}, o *share.ListOptions, id string) (string, []interface{}) {
+	order := "ASC"
+	if o.Descending {
+		order = "DESC"
+	}
+	if o.SortBy == share.SortByID {
+		query += fmt.Sprintf(" ORDER BY %s %s", id, order)
+	} else {
+		query += fmt.Sprintf(" ORDER BY stime %s, %s %s", order, id, order)
+	}
+	if o.Limit > 0 || o.Offset > 0 {
+		limit := int64(o.Limit)
+		if limit == 0 {
+			// an offset needs a limit
+			limit = math.MaxInt64
+		}
+		query += " LIMIT ? OFFSET ?"
+		params = append(params, limit, o.Offset)
+	}
+	return query, params
+}
+
 func (m *mgr) getReceivedByID(ctx context.Context, id *collaboration.ShareId) (*collaboration.ReceivedShare, error) {
 	user := user.ContextMustGetUser(ctx)
 	uid := conversions.FormatUserID(user.Id)
 
-	params := []interface{}{uid, id.OpaqueId, uid}
+	params := []interface{}{uid, id.OpaqueId, time.Now(), uid}
 	for _, v := range user.Groups {
 		params = append(params, v)
 	}
 
 	s := conversions.DBShare{ID: id.OpaqueId}
-	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, stime, permissions, share_type, accepted, coalesce(tr.rejected_by, '') as rejected_by FROM oc_share ts LEFT JOIN oc_share_acl tr ON (ts.id = tr.id AND tr.rejected_by = ?) WHERE (orphan = 0 or orphan IS NULL) AND ts.id=? "
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, stime, permissions, share_type, accepted, coalesce(tr.rejected_by, '') as rejected_by FROM oc_share ts LEFT JOIN oc_share_acl tr ON (ts.id = tr.id AND tr.rejected_by = ?) WHERE (orphan = 0 or orphan IS NULL) AND ts.id=? AND (expiration IS NULL OR expiration > ?) "
 	if len(user.Groups) > 0 {
 		query += "AND (share_with=? OR share_with in (?" + strings.Repeat(",?", len(user.Groups)-1) + "))"
 	} else {
 		query += "AND (share_with=?)"
 	}
-	if err := m.db.QueryRow(query, params...).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.STime, &s.Permissions, &s.ShareType, &s.State, &s.RejectedBy); err != nil {
+	if err := m.router.QueryRow(ctx, []interface{}{&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.STime, &s.Permissions, &s.ShareType, &s.State, &s.RejectedBy}, query, params...); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errtypes.NotFound(id.OpaqueId)
 		}
@@ -381,20 +556,20 @@ func (m *mgr) getReceivedByKey(ctx context.Context, key *collaboration.ShareKey)
 	uid := conversions.FormatUserID(user.Id)
 
 	shareType, shareWith := conversions.FormatGrantee(key.Grantee)
-	params := []interface{}{uid, conversions.FormatUserID(key.Owner), key.ResourceId.StorageId, key.ResourceId.OpaqueId, shareType, shareWith, shareWith}
+	params := []interface{}{uid, conversions.FormatUserID(key.Owner), key.ResourceId.StorageId, key.ResourceId.OpaqueId, shareType, shareWith, time.Now(), shareWith}
 	for _, v := range user.Groups {
 		params = append(params, v)
 	}
 
 	s := conversions.DBShare{}
-	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, ts.id, stime, permissions, share_type, accepted, coalesce(tr.rejected_by, '') as rejected_by FROM oc_share ts LEFT JOIN oc_share_acl tr ON (ts.id = tr.id AND tr.rejected_by = ?) WHERE (orphan = 0 or orphan IS NULL) AND uid_owner=? AND fileid_prefix=? AND item_source=? AND share_type=? AND share_with=? "
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, ts.id, stime, permissions, share_type, accepted, coalesce(tr.rejected_by, '') as rejected_by FROM oc_share ts LEFT JOIN oc_share_acl tr ON (ts.id = tr.id AND tr.rejected_by = ?) WHERE (orphan = 0 or orphan IS NULL) AND uid_owner=? AND fileid_prefix=? AND item_source=? AND share_type=? AND share_with=? AND (expiration IS NULL OR expiration > ?) "
 	if len(user.Groups) > 0 {
 		query += "AND (share_with=? OR share_with in (?" + strings.Repeat(",?", len(user.Groups)-1) + "))"
 	} else {
 		query += "AND (share_with=?)"
 	}
 
-	if err := m.db.QueryRow(query, params...).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType, &s.State, &s.RejectedBy); err != nil {
+	if err := m.router.QueryRow(ctx, []interface{}{&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType, &s.State, &s.RejectedBy}, query, params...); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errtypes.NotFound(key.String())
 		}
@@ -430,6 +605,13 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareR
 	if err != nil {
 		return nil, err
 	}
+	if err := share.CheckStateTransition(rs.State, f.GetState()); err != nil {
+		return nil, err
+	}
+	if rs.State == f.GetState() {
+		// declining twice would clash with the existing acl entry
+		return rs, nil
+	}
 
 	var query, queryAccept string
 	params := []interface{}{rs.Share.Id.OpaqueId, conversions.FormatUserID(user.Id)}
@@ -441,7 +623,7 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareR
 		queryAccept = "update oc_share set accepted=1 where id=?"
 	}
 
-	stmt, err := m.db.Prepare(query)
+	stmt, err := m.router.Writer(ctx).Prepare(query)
 	if err != nil {
 		return nil, err
 	}
@@ -451,7 +633,7 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareR
 	}
 
 	if queryAccept != "" {
-		stmt, err = m.db.Prepare(queryAccept)
+		stmt, err = m.router.Writer(ctx).Prepare(queryAccept)
 		if err != nil {
 			return nil, err
 		}
@@ -464,3 +646,219 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareR
 	rs.State = f.GetState()
 	return rs, nil
 }
+
+// Dump returns all the user and group shares stored in the database together
+// with the states the grantees have set on them.
+func (m *mgr) Dump(ctx context.Context) ([]*collaboration.Share, []*share.ReceivedShareState, error) {
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, id, stime, permissions, share_type, accepted FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND (share_type=? OR share_type=?)"
+	rows, err := m.db.Query(query, 0, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var s conversions.DBShare
+	shares := []*collaboration.Share{}
+	states := []*share.ReceivedShareState{}
+	for rows.Next() {
+		if err := rows.Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType, &s.State); err != nil {
+			return nil, nil, err
+		}
+		cs3Share := conversions.ConvertToCS3Share(s)
+		shares = append(shares, cs3Share)
+
+		// the accepted flag is kept per share, so it can only be attributed
+		// to a grantee in the case of user shares
+		if cs3Share.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && conversions.IntToShareState(s.State) == collaboration.ShareState_SHARE_STATE_ACCEPTED {
+			states = append(states, &share.ReceivedShareState{
+				UserID:  cs3Share.Grantee.GetUserId(),
+				ShareID: cs3Share.Id,
+				State:   collaboration.ShareState_SHARE_STATE_ACCEPTED,
+			})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	aclRows, err := m.db.Query("select id, rejected_by FROM oc_share_acl")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer aclRows.Close()
+
+	for aclRows.Next() {
+		var id, rejectedBy string
+		if err := aclRows.Scan(&id, &rejectedBy); err != nil {
+			return nil, nil, err
+		}
+		states = append(states, &share.ReceivedShareState{
+			UserID:  conversions.ExtractUserID(rejectedBy),
+			ShareID: &collaboration.ShareId{OpaqueId: id},
+			State:   collaboration.ShareState_SHARE_STATE_REJECTED,
+		})
+	}
+	if err = aclRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return shares, states, nil
+}
+
+// Load inserts the given shares and received share states in a single transaction.
+// Numeric share ids are preserved, any other id is replaced by the one assigned by
+// the database. The item type and the file target are not part of a share and are
+// left unset.
+func (m *mgr) Load(ctx context.Context, shares []*collaboration.Share, states []*share.ReceivedShareState) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	ids := make(map[string]string, len(shares))
+	for _, s := range shares {
+		shareType, shareWith := conversions.FormatGrantee(s.Grantee)
+		itemSource := s.ResourceId.OpaqueId
+		fileSource, err := strconv.ParseUint(itemSource, 10, 64)
+		if err != nil {
+			fileSource = 0
+		}
+
+		query := "insert into oc_share set share_type=?,uid_owner=?,uid_initiator=?,fileid_prefix=?,item_source=?,file_source=?,permissions=?,stime=?,share_with=?"
+		params := []interface{}{shareType, conversions.FormatUserID(s.Owner), conversions.FormatUserID(s.Creator), s.ResourceId.StorageId, itemSource, fileSource, conversions.SharePermToInt(s.Permissions.Permissions), s.Ctime.GetSeconds(), shareWith}
+		if _, err := strconv.ParseUint(s.Id.OpaqueId, 10, 64); err == nil {
+			query += ",id=?"
+			params = append(params, s.Id.OpaqueId)
+		}
+
+		result, err := tx.ExecContext(ctx, query, params...)
+		if err != nil {
+			return errors.Wrap(err, "sql: error inserting share "+s.Id.OpaqueId)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ids[s.Id.OpaqueId] = strconv.FormatInt(lastID, 10)
+	}
+
+	for _, rs := range states {
+		id, ok := ids[rs.ShareID.OpaqueId]
+		if !ok {
+			return errtypes.NotFound(rs.ShareID.OpaqueId)
+		}
+		switch rs.State {
+		case collaboration.ShareState_SHARE_STATE_REJECTED:
+			_, err = tx.ExecContext(ctx, "insert into oc_share_acl(id, rejected_by) values(?, ?)", id, conversions.FormatUserID(rs.UserID))
+		case collaboration.ShareState_SHARE_STATE_ACCEPTED:
+			_, err = tx.ExecContext(ctx, "update oc_share set accepted=1 where id=?", id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RenameUser replaces the id of a user in the owner, initiator and recipient of
+// the user and group shares and in the rejections, all in one transaction.
+func (m *mgr) RenameUser(ctx context.Context, oldID, newID *userpb.UserId) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	oldUID, newUID := conversions.FormatUserID(oldID), conversions.FormatUserID(newID)
+	queries := []struct {
+		query  string
+		params []interface{}
+	}{
+		{"update oc_share set uid_owner=? where uid_owner=? AND (share_type=? OR share_type=?)", []interface{}{newUID, oldUID, 0, 1}},
+		{"update oc_share set uid_initiator=? where uid_initiator=? AND (share_type=? OR share_type=?)", []interface{}{newUID, oldUID, 0, 1}},
+		{"update oc_share set share_with=? where share_with=? AND share_type=?", []interface{}{newUID, oldUID, 0}},
+		{"update oc_share_acl set rejected_by=? where rejected_by=?", []interface{}{newUID, oldUID}},
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q.query, q.params...); err != nil {
+			return errors.Wrap(err, "sql: error renaming user "+oldUID)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetExpiration sets the expiration of a share owned or created by the user in context.
+func (m *mgr) SetExpiration(ctx context.Context, ref *collaboration.ShareReference, exp *typespb.Timestamp) error {
+	s, err := m.GetShare(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	var expiration interface{}
+	if exp != nil {
+		expiration = time.Unix(int64(exp.Seconds), 0)
+	}
+	if _, err := m.router.Writer(ctx).ExecContext(ctx, "update oc_share set expiration=? where id=?", expiration, s.Id.OpaqueId); err != nil {
+		return errors.Wrap(err, "sql: error setting the expiration of share "+s.Id.OpaqueId)
+	}
+	return nil
+}
+
+// GetExpirations returns the expirations of the given shares.
+func (m *mgr) GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error) {
+	exps := map[string]*typespb.Timestamp{}
+	if len(ids) == 0 {
+		return exps, nil
+	}
+
+	params := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		params = append(params, id.OpaqueId)
+	}
+	query := "select id, expiration FROM oc_share WHERE expiration IS NOT NULL AND id in (?" + strings.Repeat(",?", len(ids)-1) + ")"
+	rows, err := m.router.Reader(ctx).QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, expiration string
+		if err := rows.Scan(&id, &expiration); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse("2006-01-02 15:04:05", expiration)
+		if err != nil {
+			return nil, errors.Wrap(err, "sql: error parsing the expiration of share "+id)
+		}
+		exps[id] = &typespb.Timestamp{Seconds: uint64(t.Unix())}
+	}
+	return exps, rows.Err()
+}
+
+// ListExpiredShares returns the user and group shares expired at t.
+func (m *mgr) ListExpiredShares(ctx context.Context, t time.Time) ([]*collaboration.Share, error) {
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(fileid_prefix, '') as fileid_prefix, coalesce(item_source, '') as item_source, id, stime, permissions, share_type FROM oc_share WHERE (orphan = 0 or orphan IS NULL) AND (share_type=? OR share_type=?) AND expiration IS NOT NULL AND expiration <= ?"
+	rows, err := m.db.QueryContext(ctx, query, 0, 1, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var s conversions.DBShare
+	shares := []*collaboration.Share{}
+	for rows.Next() {
+		if err := rows.Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.Prefix, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType); err != nil {
+			return nil, err
+		}
+		shares = append(shares, conversions.ConvertToCS3Share(s))
+	}
+	return shares, rows.Err()
+}
@@ -0,0 +1,127 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package crypto
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"io"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// DefaultChecksums are the algorithms computed on upload when none are configured.
+var DefaultChecksums = []string{"sha1", "md5", "adler32"}
+
+var checksumFuncs = map[string]func() hash.Hash{
+	"sha1":    sha1.New,
+	"md5":     md5.New,
+	"adler32": func() hash.Hash { return adler32.New() },
+	"sha256":  sha256.New,
+}
+
+// IsSupportedChecksum tells whether the checksums of the algorithm can be computed.
+func IsSupportedChecksum(algo string) bool {
+	_, ok := checksumFuncs[algo]
+	return ok
+}
+
+// ParseChecksum splits a checksum in the '[algorithm] [checksum]' format
+// used by the TUS Upload-Checksum header, with a lowercase algorithm.
+func ParseChecksum(checksum string) (algo, sum string, err error) {
+	parts := strings.SplitN(checksum, " ", 2)
+	if len(parts) != 2 {
+		return "", "", errtypes.BadRequest("invalid checksum format. must be '[algorithm] [checksum]'")
+	}
+	if !IsSupportedChecksum(parts[0]) {
+		return "", "", errtypes.BadRequest("unsupported checksum algorithm: " + parts[0])
+	}
+	return parts[0], parts[1], nil
+}
+
+// Checksums computes the checksums of several algorithms in a single pass
+// over the bytes written to it.
+type Checksums struct {
+	algos  []string
+	hashes map[string]hash.Hash
+	w      io.Writer
+}
+
+// NewChecksums returns a Checksums computing the given algorithms, ignoring duplicates.
+func NewChecksums(algos ...string) (*Checksums, error) {
+	c := &Checksums{hashes: make(map[string]hash.Hash, len(algos))}
+	writers := make([]io.Writer, 0, len(algos))
+	for _, algo := range algos {
+		if _, ok := c.hashes[algo]; ok {
+			continue
+		}
+		f, ok := checksumFuncs[algo]
+		if !ok {
+			return nil, errtypes.BadRequest("unsupported checksum algorithm: " + algo)
+		}
+		h := f()
+		c.algos = append(c.algos, algo)
+		c.hashes[algo] = h
+		writers = append(writers, h)
+	}
+	c.w = io.MultiWriter(writers...)
+	return c, nil
+}
+
+// Write adds the bytes to all the checksums.
+func (c *Checksums) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+// Algorithms returns the computed algorithms in the order they were given.
+func (c *Checksums) Algorithms() []string {
+	return c.algos
+}
+
+// Sum returns the checksum of the algorithm, or nil if it is not computed.
+func (c *Checksums) Sum(algo string) []byte {
+	h, ok := c.hashes[algo]
+	if !ok {
+		return nil
+	}
+	return h.Sum(nil)
+}
+
+// Verify compares a checksum in the '[algorithm] [checksum]' format with the
+// computed one, the algorithm must be one of the computed algorithms.
+func (c *Checksums) Verify(checksum string) error {
+	algo, expected, err := ParseChecksum(checksum)
+	if err != nil {
+		return err
+	}
+	sum := c.Sum(algo)
+	if sum == nil {
+		return errtypes.BadRequest("checksum algorithm not computed: " + algo)
+	}
+	if !strings.EqualFold(expected, hex.EncodeToString(sum)) {
+		return errtypes.ChecksumMismatch(fmt.Sprintf("invalid checksum: expected %s got %x", checksum, sum))
+	}
+	return nil
+}
@@ -22,6 +22,8 @@ import (
 	"io"
 	"strings"
 	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
 )
 
 func TestChecksums(t *testing.T) {
@@ -49,3 +51,54 @@ func TestChecksums(t *testing.T) {
 		})
 	}
 }
+
+func TestMultipleChecksums(t *testing.T) {
+	c, err := NewChecksums("sha1", "md5", "adler32", "sha256", "sha1")
+	if err != nil {
+		t.Fatalf("NewChecksums returned an unexpected error: %v", err)
+	}
+	if _, err := io.Copy(c, strings.NewReader("Hello World!")); err != nil {
+		t.Fatalf("could not write to the checksums: %v", err)
+	}
+	if len(c.Algorithms()) != 4 {
+		t.Fatalf("duplicate algorithm not ignored: %v", c.Algorithms())
+	}
+
+	tests := map[string]struct {
+		checksum string
+		err      error
+	}{
+		"sha1":          {"sha1 2ef7bde608ce5404e97d5f042f95f89f1c232871", nil},
+		"md5_uppercase": {"md5 ED076287532E86365E841E92BFC50D8C", nil},
+		"adler32":       {"adler32 1c49043e", nil},
+		"sha256":        {"sha256 7f83b1657ff1fc53b92dc18148a1d65dfc2d4b1fa3d677284addd200126d9069", nil},
+		"mismatch":      {"adler32 00000000", errtypes.ChecksumMismatch("")},
+		"unsupported":   {"crc32 1c49043e", errtypes.BadRequest("")},
+		"malformed":     {"sha1", errtypes.BadRequest("")},
+	}
+
+	for name := range tests {
+		var tc = tests[name]
+		t.Run(name, func(t *testing.T) {
+			err := c.Verify(tc.checksum)
+			switch tc.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("%v returned an unexpected error: %v", t.Name(), err)
+				}
+			case errtypes.ChecksumMismatch:
+				if _, ok := err.(errtypes.ChecksumMismatch); !ok {
+					t.Fatalf("%v returned %v, expected a checksum mismatch", t.Name(), err)
+				}
+			case errtypes.BadRequest:
+				if _, ok := err.(errtypes.BadRequest); !ok {
+					t.Fatalf("%v returned %v, expected a bad request", t.Name(), err)
+				}
+			}
+		})
+	}
+
+	if _, err := NewChecksums("crc32"); err == nil {
+		t.Fatal("NewChecksums accepted an unsupported algorithm")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package datatx contains the managers of the data transfers between sites.
+package datatx
+
+import (
+	"context"
+	"encoding/json"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// TransferOpaqueKey is the opaque key of a CreateTransferRequest carrying the
+// JSON encoded Request describing the transfer.
+const TransferOpaqueKey = "transfer"
+
+// The opaque keys under which the progress of a transfer is returned in the
+// opaque of the transfer responses.
+const (
+	BytesOpaqueKey    = "bytes"
+	TotalOpaqueKey    = "total"
+	FilesOpaqueKey    = "files"
+	AttemptsOpaqueKey = "attempts"
+	ErrorOpaqueKey    = "error"
+)
+
+// Endpoint is one side of a transfer: a path below a webdav endpoint
+// accessed with the given token.
+type Endpoint struct {
+	// URL is the webdav endpoint, left empty for a destination on this site.
+	URL   string `json:"url,omitempty"`
+	Path  string `json:"path"`
+	Token string `json:"token"`
+}
+
+// Request describes a transfer copying Src to Dest.
+type Request struct {
+	Src  Endpoint `json:"src"`
+	Dest Endpoint `json:"dest"`
+	// ShareID is the OCM share the transfer was created for, if any.
+	ShareID string `json:"share_id,omitempty"`
+}
+
+// Transfer is the state of a transfer as reported by a Manager. The CS3 TxInfo
+// has no opaque, the progress is carried separately.
+type Transfer struct {
+	Info     *datatx.TxInfo
+	Progress *types.Opaque
+}
+
+// Manager is the interface that data transfer drivers implement.
+type Manager interface {
+	// StartTransfer starts copying the resource described by r on behalf of owner
+	// and returns right away, the transfer runs in the background.
+	StartTransfer(ctx context.Context, owner *userpb.UserId, r *Request) (*Transfer, error)
+
+	// GetTransferStatus returns the status of the transfer with the given id.
+	GetTransferStatus(ctx context.Context, id string) (*Transfer, error)
+
+	// CancelTransfer stops the transfer with the given id.
+	CancelTransfer(ctx context.Context, id string) (*Transfer, error)
+
+	// GetTransferOwner returns the user that created the transfer with the given id.
+	GetTransferOwner(ctx context.Context, id string) (*userpb.UserId, error)
+}
+
+// EncodeRequest returns an opaque carrying the request, to be sent in a CreateTransferRequest.
+func EncodeRequest(r *Request) (*types.Opaque, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "datatx: error encoding transfer request")
+	}
+	return &types.Opaque{
+		Map: map[string]*types.OpaqueEntry{
+			TransferOpaqueKey: {
+				Decoder: "json",
+				Value:   data,
+			},
+		},
+	}, nil
+}
+
+// DecodeRequest reads the request carried by the opaque of a CreateTransferRequest.
+func DecodeRequest(o *types.Opaque) (*Request, error) {
+	e, ok := o.GetMap()[TransferOpaqueKey]
+	if !ok {
+		return nil, errtypes.BadRequest("datatx: missing transfer request")
+	}
+	if e.Decoder != "json" {
+		return nil, errtypes.BadRequest("datatx: opaque entry decoder not recognized: " + e.Decoder)
+	}
+	r := &Request{}
+	if err := json.Unmarshal(e.Value, r); err != nil {
+		return nil, errtypes.BadRequest("datatx: error decoding transfer request: " + err.Error())
+	}
+	if r.Src.URL == "" || r.Src.Path == "" || r.Dest.Path == "" {
+		return nil, errtypes.BadRequest("datatx: transfer source and destination must be set")
+	}
+	return r, nil
+}
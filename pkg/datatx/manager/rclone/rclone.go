@@ -0,0 +1,573 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package rclone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	txpb "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/datatx"
+	"github.com/cs3org/reva/pkg/datatx/manager/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register("rclone", New)
+}
+
+type config struct {
+	// Endpoint is the address of the rclone remote control server (rclone rcd).
+	Endpoint string `mapstructure:"endpoint"`
+	AuthUser string `mapstructure:"auth_user"`
+	AuthPass string `mapstructure:"auth_pass"`
+	// WebdavEndpoint is the webdav endpoint of this site, the destination of
+	// the transfers that do not name one.
+	WebdavEndpoint string `mapstructure:"webdav_endpoint"`
+	// File is where the transfers are persisted.
+	File string `mapstructure:"file"`
+	// JobStatusCheckInterval is the interval in milliseconds between two status checks of a job.
+	JobStatusCheckInterval int `mapstructure:"job_status_check_interval"`
+	// JobTimeout is the time in seconds after which a transfer is stopped and
+	// marked as failed, 0 never times out.
+	JobTimeout int `mapstructure:"job_timeout"`
+	// MaxRetries is how many times a failed copy is restarted. A restarted copy
+	// only transfers the files that are missing or differ at the destination.
+	MaxRetries int `mapstructure:"max_retries"`
+	// RetryDelay is the time in milliseconds to wait before restarting a failed copy.
+	RetryDelay int  `mapstructure:"retry_delay"`
+	Insecure   bool `mapstructure:"insecure"`
+}
+
+func (c *config) init() {
+	if c.Endpoint == "" {
+		c.Endpoint = "http://localhost:5572"
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.File == "" {
+		c.File = "/var/tmp/reva/datatx-transfers.json"
+	}
+	if c.JobStatusCheckInterval == 0 {
+		c.JobStatusCheckInterval = 2000
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.RetryDelay == 0 {
+		c.RetryDelay = 30000
+	}
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// job is a transfer as persisted in the transfers file.
+type job struct {
+	ID          string             `json:"id"`
+	Owner       *userpb.UserId     `json:"owner"`
+	Request     *datatx.Request    `json:"request"`
+	RcloneJobID int64              `json:"rclone_job_id"`
+	Status      txpb.TxInfo_Status `json:"status"`
+	Attempts    int                `json:"attempts"`
+	Bytes       int64              `json:"bytes"`
+	Total       int64              `json:"total"`
+	Files       int64              `json:"files"`
+	Error       string             `json:"error,omitempty"`
+	Ctime       int64              `json:"ctime"`
+	Mtime       int64              `json:"mtime"`
+}
+
+func (j *job) finished() bool {
+	switch j.Status {
+	case txpb.TxInfo_STATUS_TRANSFER_COMPLETE, txpb.TxInfo_STATUS_TRANSFER_FAILED, txpb.TxInfo_STATUS_TRANSFER_CANCELLED:
+		return true
+	}
+	return false
+}
+
+func (j *job) info() *datatx.Transfer {
+	opaque := &types.Opaque{Map: map[string]*types.OpaqueEntry{}}
+	add := func(k, v string) {
+		opaque.Map[k] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(v)}
+	}
+	add(datatx.BytesOpaqueKey, strconv.FormatInt(j.Bytes, 10))
+	add(datatx.TotalOpaqueKey, strconv.FormatInt(j.Total, 10))
+	add(datatx.FilesOpaqueKey, strconv.FormatInt(j.Files, 10))
+	add(datatx.AttemptsOpaqueKey, strconv.Itoa(j.Attempts))
+	if j.Error != "" {
+		add(datatx.ErrorOpaqueKey, j.Error)
+	}
+	return &datatx.Transfer{
+		Info: &txpb.TxInfo{
+			Id:          &txpb.TxId{OpaqueId: j.ID},
+			Status:      j.Status,
+			Creator:     j.Owner,
+			Description: j.Error,
+		},
+		Progress: opaque,
+	}
+}
+
+type transferModel struct {
+	file string
+	Jobs map[string]*job `json:"jobs"`
+}
+
+func loadOrCreate(file string) (*transferModel, error) {
+	m := &transferModel{file: file, Jobs: map[string]*job{}}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, m.save()
+		}
+		return nil, errors.Wrap(err, "error reading the file: "+file)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrap(err, "error decoding data to json")
+	}
+	if m.Jobs == nil {
+		m.Jobs = map[string]*job{}
+	}
+	return m, nil
+}
+
+// save writes the model through a temporary file so that a crash never
+// leaves a truncated transfers file behind.
+func (m *transferModel) save() error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "error encoding to json")
+	}
+	if err := os.MkdirAll(filepath.Dir(m.file), 0700); err != nil {
+		return errors.Wrap(err, "error creating the directory of: "+m.file)
+	}
+	tmp := m.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "error writing to file: "+tmp)
+	}
+	return errors.Wrap(os.Rename(tmp, m.file), "error renaming file: "+tmp)
+}
+
+type driver struct {
+	c      *config
+	client *http.Client
+
+	sync.Mutex // protects the model and stops
+	model      *transferModel
+	// stops wakes up the workers of the transfers being canceled.
+	stops map[string]chan struct{}
+	// counts holds the number of transfers by status, read by the probe
+	// without taking the lock held while rclone is called.
+	counts atomic.Value
+}
+
+// New returns a data transfer manager running the transfers as copy jobs
+// of an rclone remote control server. The transfers that were running when
+// the service stopped are resumed.
+func New(m map[string]interface{}) (datatx.Manager, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+
+	model, err := loadOrCreate(c.File)
+	if err != nil {
+		err = errors.Wrap(err, "error loading the file containing the transfers")
+		return nil, err
+	}
+
+	d := &driver{
+		c: c,
+		client: rhttp.GetHTTPClient(
+			rhttp.Timeout(30*time.Second),
+			rhttp.Insecure(c.Insecure),
+		),
+		model: model,
+		stops: map[string]chan struct{}{},
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	for _, j := range model.Jobs {
+		if !j.finished() {
+			d.spawn(j.ID)
+		}
+	}
+	d.count()
+	ops.Register(ops.Transfers, "datatx/rclone", d.probe)
+	return d, nil
+}
+
+// count updates the number of transfers by status, d must be locked.
+func (d *driver) count() {
+	counts := map[string]int{}
+	for _, j := range d.model.Jobs {
+		counts[j.Status.String()]++
+	}
+	d.counts.Store(counts)
+}
+
+// probe reports the transfers by status.
+func (d *driver) probe() ops.State {
+	return ops.State{
+		Health:  ops.Healthy,
+		Details: map[string]interface{}{"status": d.counts.Load()},
+	}
+}
+
+func (d *driver) StartTransfer(ctx context.Context, owner *userpb.UserId, r *datatx.Request) (*datatx.Transfer, error) {
+	if r.Dest.URL == "" {
+		if d.c.WebdavEndpoint == "" {
+			return nil, errtypes.BadRequest("rclone: the transfer has no destination endpoint")
+		}
+		r.Dest.URL = d.c.WebdavEndpoint
+	}
+
+	now := time.Now().Unix()
+	j := &job{
+		ID:      uuid.New().String(),
+		Owner:   owner,
+		Request: r,
+		Status:  txpb.TxInfo_STATUS_TRANSFER_NEW,
+		Ctime:   now,
+		Mtime:   now,
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.model.Jobs[j.ID] = j
+	if err := d.model.save(); err != nil {
+		delete(d.model.Jobs, j.ID)
+		return nil, err
+	}
+	d.count()
+	d.spawn(j.ID)
+	return j.info(), nil
+}
+
+func (d *driver) GetTransferStatus(ctx context.Context, id string) (*datatx.Transfer, error) {
+	d.Lock()
+	defer d.Unlock()
+	j, ok := d.model.Jobs[id]
+	if !ok {
+		return nil, errtypes.NotFound("rclone: transfer " + id)
+	}
+	return j.info(), nil
+}
+
+func (d *driver) GetTransferOwner(ctx context.Context, id string) (*userpb.UserId, error) {
+	d.Lock()
+	defer d.Unlock()
+	j, ok := d.model.Jobs[id]
+	if !ok {
+		return nil, errtypes.NotFound("rclone: transfer " + id)
+	}
+	return j.Owner, nil
+}
+
+func (d *driver) CancelTransfer(ctx context.Context, id string) (*datatx.Transfer, error) {
+	d.Lock()
+	defer d.Unlock()
+	j, ok := d.model.Jobs[id]
+	if !ok {
+		return nil, errtypes.NotFound("rclone: transfer " + id)
+	}
+	if j.finished() {
+		return j.info(), nil
+	}
+
+	if j.RcloneJobID != 0 {
+		if err := d.rc("job/stop", map[string]interface{}{"jobid": j.RcloneJobID}, nil); err != nil && !isJobNotFound(err) {
+			j.Status = txpb.TxInfo_STATUS_TRANSFER_CANCEL_FAILED
+			j.Error = err.Error()
+			d.update(j)
+			return j.info(), nil
+		}
+	}
+	j.Status = txpb.TxInfo_STATUS_TRANSFER_CANCELLED
+	j.Error = ""
+	d.update(j)
+	if stop, ok := d.stops[id]; ok {
+		close(stop)
+		delete(d.stops, id)
+	}
+	return j.info(), nil
+}
+
+// spawn starts the worker of a transfer, d must be locked.
+func (d *driver) spawn(id string) {
+	stop := make(chan struct{})
+	d.stops[id] = stop
+	go d.run(id, stop)
+}
+
+// update persists the changed job, d must be locked.
+func (d *driver) update(j *job) {
+	j.Mtime = time.Now().Unix()
+	d.count()
+	if err := d.model.save(); err != nil {
+		log.Error().Err(err).Str("transfer", j.ID).Msg("rclone: error persisting transfer")
+	}
+}
+
+// run drives a transfer until it completes, fails for good or is canceled.
+func (d *driver) run(id string, stop chan struct{}) {
+	defer func() {
+		d.Lock()
+		if d.stops[id] == stop {
+			delete(d.stops, id)
+		}
+		d.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if d.c.JobTimeout > 0 {
+		d.Lock()
+		started := time.Unix(d.model.Jobs[id].Ctime, 0)
+		d.Unlock()
+		timeout = time.After(time.Until(started.Add(time.Duration(d.c.JobTimeout) * time.Second)))
+	}
+
+	wait := time.Duration(0)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timeout:
+			d.fail(id, "transfer timed out")
+			return
+		case <-time.After(wait):
+		}
+
+		d.Lock()
+		j := d.model.Jobs[id]
+		if j.finished() {
+			d.Unlock()
+			return
+		}
+		var done bool
+		done, wait = d.step(j)
+		d.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// step starts or checks the rclone job of the transfer. It reports whether
+// the transfer is over and otherwise how long to wait for the next step,
+// d must be locked.
+func (d *driver) step(j *job) (bool, time.Duration) {
+	interval := time.Duration(d.c.JobStatusCheckInterval) * time.Millisecond
+	retry := time.Duration(d.c.RetryDelay) * time.Millisecond
+
+	if j.RcloneJobID == 0 {
+		if j.Attempts > d.c.MaxRetries {
+			j.Status = txpb.TxInfo_STATUS_TRANSFER_FAILED
+			d.update(j)
+			return true, 0
+		}
+		j.Attempts++
+		jobID, err := d.copy(j.Request)
+		if err != nil {
+			log.Error().Err(err).Str("transfer", j.ID).Msg("rclone: error starting the copy")
+			j.Error = err.Error()
+			d.update(j)
+			return false, retry
+		}
+		j.RcloneJobID = jobID
+		j.Status = txpb.TxInfo_STATUS_TRANSFER_IN_PROGRESS
+		j.Error = ""
+		d.update(j)
+		return false, interval
+	}
+
+	var st jobStatus
+	if err := d.rc("job/status", map[string]interface{}{"jobid": j.RcloneJobID}, &st); err != nil {
+		if isJobNotFound(err) {
+			// the rclone server was restarted, the copy starts over
+			j.RcloneJobID = 0
+			j.Status = txpb.TxInfo_STATUS_TRANSFER_NEW
+			d.update(j)
+			return false, 0
+		}
+		log.Error().Err(err).Str("transfer", j.ID).Msg("rclone: error checking the job status")
+		return false, interval
+	}
+
+	var stats jobStats
+	if err := d.rc("core/stats", map[string]interface{}{"group": fmt.Sprintf("job/%d", j.RcloneJobID)}, &stats); err == nil {
+		j.Bytes, j.Total, j.Files = stats.Bytes, stats.TotalBytes, stats.Transfers
+	}
+
+	if !st.Finished {
+		return false, interval
+	}
+	if st.Success {
+		j.Status = txpb.TxInfo_STATUS_TRANSFER_COMPLETE
+		j.Error = ""
+		d.update(j)
+		return true, 0
+	}
+
+	log.Warn().Str("transfer", j.ID).Int("attempt", j.Attempts).Str("error", st.Error).Msg("rclone: copy failed")
+	j.RcloneJobID = 0
+	j.Error = st.Error
+	if j.Attempts > d.c.MaxRetries {
+		j.Status = txpb.TxInfo_STATUS_TRANSFER_FAILED
+		d.update(j)
+		return true, 0
+	}
+	j.Status = txpb.TxInfo_STATUS_TRANSFER_NEW
+	d.update(j)
+	return false, retry
+}
+
+func (d *driver) fail(id, reason string) {
+	d.Lock()
+	defer d.Unlock()
+	j := d.model.Jobs[id]
+	if j.finished() {
+		return
+	}
+	if j.RcloneJobID != 0 {
+		if err := d.rc("job/stop", map[string]interface{}{"jobid": j.RcloneJobID}, nil); err != nil && !isJobNotFound(err) {
+			log.Error().Err(err).Str("transfer", id).Msg("rclone: error stopping the job")
+		}
+	}
+	j.Status = txpb.TxInfo_STATUS_TRANSFER_FAILED
+	j.Error = reason
+	d.update(j)
+}
+
+// copy starts an asynchronous rclone copy of the transfer and returns its job id.
+func (d *driver) copy(r *datatx.Request) (int64, error) {
+	// a file is copied into the destination folder, a folder becomes the destination folder
+	body := map[string]interface{}{
+		"srcFs":  webdavFs(r.Src),
+		"dstFs":  webdavFs(r.Dest),
+		"_async": true,
+	}
+	var res struct {
+		JobID int64 `json:"jobid"`
+	}
+	if err := d.rc("sync/copy", body, &res); err != nil {
+		return 0, err
+	}
+	return res.JobID, nil
+}
+
+type jobStatus struct {
+	Finished bool   `json:"finished"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error"`
+}
+
+type jobStats struct {
+	Bytes      int64 `json:"bytes"`
+	TotalBytes int64 `json:"totalBytes"`
+	Transfers  int64 `json:"transfers"`
+}
+
+type rcError struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+// isJobNotFound reports whether rclone does not know the job (anymore).
+func isJobNotFound(err error) bool {
+	return strings.Contains(err.Error(), "job not found")
+}
+
+// rc calls a method of the rclone remote control API.
+func (d *driver) rc(method string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return errors.Wrap(err, "rclone: error encoding request")
+	}
+	req, err := http.NewRequest(http.MethodPost, d.c.Endpoint+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "rclone: error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.c.AuthUser != "" {
+		req.SetBasicAuth(d.c.AuthUser, d.c.AuthPass)
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "rclone: error calling "+method)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrap(err, "rclone: error reading response of "+method)
+	}
+	if res.StatusCode != http.StatusOK {
+		e := &rcError{}
+		if err := json.Unmarshal(body, e); err != nil || e.Error == "" {
+			return fmt.Errorf("rclone: %s failed with status %d", method, res.StatusCode)
+		}
+		return fmt.Errorf("rclone: %s failed: %s", method, e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal(body, out), "rclone: error decoding response of "+method)
+}
+
+// webdavFs returns the rclone connection string of an on the fly webdav remote
+// authenticating with the reva token header.
+func webdavFs(e datatx.Endpoint) string {
+	return fmt.Sprintf(":webdav,headers=%s,url=%s:%s",
+		quote("x-access-token,"+e.Token), quote(e.URL), path.Clean("/"+e.Path))
+}
+
+// quote quotes a value of a connection string, quotes in the value are doubled.
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
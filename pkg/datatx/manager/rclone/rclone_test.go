@@ -0,0 +1,200 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package rclone
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	txpb "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	"github.com/cs3org/reva/pkg/datatx"
+)
+
+// fakeRC mimics the rclone remote control API. The copies fail until
+// failures is reached, a copy never finishes while hang is set.
+type fakeRC struct {
+	sync.Mutex
+	failures int
+	hang     bool
+	copies   []map[string]interface{}
+	stopped  []int64
+}
+
+func (f *fakeRC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	in := map[string]interface{}{}
+	_ = json.NewDecoder(r.Body).Decode(&in)
+
+	var out interface{}
+	switch strings.TrimPrefix(r.URL.Path, "/") {
+	case "sync/copy":
+		f.copies = append(f.copies, in)
+		out = map[string]interface{}{"jobid": len(f.copies)}
+	case "job/status":
+		id := int(in["jobid"].(float64))
+		switch {
+		case f.hang:
+			out = map[string]interface{}{"finished": false}
+		case id <= f.failures:
+			out = map[string]interface{}{"finished": true, "success": false, "error": "copy failed"}
+		default:
+			out = map[string]interface{}{"finished": true, "success": true}
+		}
+	case "core/stats":
+		out = map[string]interface{}{"bytes": 42, "totalBytes": 42, "transfers": 1}
+	case "job/stop":
+		f.stopped = append(f.stopped, int64(in["jobid"].(float64)))
+		out = map[string]interface{}{}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func newDriver(t *testing.T, endpoint, file string) *driver {
+	m, err := New(map[string]interface{}{
+		"endpoint":                  endpoint,
+		"file":                      file,
+		"webdav_endpoint":           "https://local/remote.php/webdav",
+		"job_status_check_interval": 5,
+		"retry_delay":               5,
+		"max_retries":               2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.(*driver)
+}
+
+func waitFor(t *testing.T, d *driver, id string, want txpb.TxInfo_Status) *datatx.Transfer {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tx, err := d.GetTransferStatus(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Info.Status == want {
+			return tx
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transfer %s is %v, want %v", id, tx.Info.Status, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+var request = &datatx.Request{
+	Src:  datatx.Endpoint{URL: "https://remote/remote.php/webdav", Path: "/data", Token: "src-token"},
+	Dest: datatx.Endpoint{Path: "/Data-Transfers/data", Token: "dest-token"},
+}
+
+func TestTransfer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "transfers.json")
+	owner := &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}
+
+	tests := []struct {
+		name     string
+		failures int
+		want     txpb.TxInfo_Status
+		attempts string
+	}{
+		{"succeeds", 0, txpb.TxInfo_STATUS_TRANSFER_COMPLETE, "1"},
+		{"retried", 2, txpb.TxInfo_STATUS_TRANSFER_COMPLETE, "3"},
+		{"fails", 3, txpb.TxInfo_STATUS_TRANSFER_FAILED, "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &fakeRC{failures: tt.failures}
+			srv := httptest.NewServer(rc)
+			defer srv.Close()
+
+			d := newDriver(t, srv.URL, filepath.Join(t.TempDir(), "transfers.json"))
+			r := *request
+			tx, err := d.StartTransfer(context.Background(), owner, &r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx = waitFor(t, d, tx.Info.Id.OpaqueId, tt.want)
+			if got := string(tx.Progress.Map[datatx.AttemptsOpaqueKey].Value); got != tt.attempts {
+				t.Errorf("attempts = %s, want %s", got, tt.attempts)
+			}
+
+			rc.Lock()
+			defer rc.Unlock()
+			dst := rc.copies[0]["dstFs"].(string)
+			if !strings.HasPrefix(dst, `:webdav,headers="x-access-token,dest-token",url="https://local/remote.php/webdav":`) {
+				t.Errorf("unexpected destination %s", dst)
+			}
+		})
+	}
+
+	t.Run("cancel and resume", func(t *testing.T) {
+		rc := &fakeRC{hang: true}
+		srv := httptest.NewServer(rc)
+		defer srv.Close()
+
+		d := newDriver(t, srv.URL, file)
+		r := *request
+		tx, err := d.StartTransfer(context.Background(), owner, &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := tx.Info.Id.OpaqueId
+		waitFor(t, d, id, txpb.TxInfo_STATUS_TRANSFER_IN_PROGRESS)
+
+		other, err := d.StartTransfer(context.Background(), owner, &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, d, other.Info.Id.OpaqueId, txpb.TxInfo_STATUS_TRANSFER_IN_PROGRESS)
+
+		tx, err = d.CancelTransfer(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Info.Status != txpb.TxInfo_STATUS_TRANSFER_CANCELLED {
+			t.Fatalf("status after cancel = %v", tx.Info.Status)
+		}
+		rc.Lock()
+		if len(rc.stopped) != 1 || rc.stopped[0] != 1 {
+			t.Errorf("stopped jobs = %v, want [1]", rc.stopped)
+		}
+		rc.hang = false
+		rc.Unlock()
+
+		// a new instance resumes the unfinished transfer and keeps the canceled one
+		d = newDriver(t, srv.URL, file)
+		waitFor(t, d, other.Info.Id.OpaqueId, txpb.TxInfo_STATUS_TRANSFER_COMPLETE)
+		waitFor(t, d, id, txpb.TxInfo_STATUS_TRANSFER_CANCELLED)
+		o, err := d.GetTransferOwner(context.Background(), id)
+		if err != nil || o.OpaqueId != owner.OpaqueId {
+			t.Errorf("owner = %v, %v", o, err)
+		}
+	})
+}
@@ -219,8 +219,11 @@ func (c *Client) executeEOS(ctx context.Context, cmd *exec.Cmd) (string, string,
 		cmd.Env = append(cmd.Env, "XrdSecSSSKT="+c.opt.Keytab)
 	}
 
-	trace := trace.FromContext(ctx).SpanContext().TraceID.String()
-	cmd.Args = append(cmd.Args, "--comment", trace)
+	// the command is traced as a call to the backend, the MGM logs the trace
+	// id passed as the comment
+	_, span := trace.StartSpan(ctx, "eos", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	cmd.Args = append(cmd.Args, "--comment", span.SpanContext().TraceID.String())
 
 	err := cmd.Run()
 
@@ -250,6 +253,10 @@ func (c *Client) executeEOS(ctx context.Context, cmd *exec.Cmd) (string, string,
 	args := fmt.Sprintf("%s", cmd.Args)
 	env := fmt.Sprintf("%s", cmd.Env)
 	log.Info().Str("args", args).Str("env", env).Int("exit", exitStatus).Str("err", errBuf.String()).Msg("eos cmd")
+	span.AddAttributes(trace.StringAttribute("args", args), trace.Int64Attribute("exit", int64(exitStatus)))
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
 
 	if err != nil && exitStatus != int(syscall.ENOENT) { // don't wrap the errtypes.NotFoundError
 		err = errors.Wrap(err, "eosclient: error while executing command")
@@ -40,6 +40,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"go.opencensus.io/plugin/ocgrpc"
 	"google.golang.org/grpc"
 
 	"github.com/cs3org/reva/pkg/logger"
@@ -131,7 +132,8 @@ func newgrpc(ctx context.Context, opt *Options) (erpc.EosClient, error) {
 	log := appctx.GetLogger(ctx)
 	log.Debug().Str("Connecting to ", "'"+opt.GrpcURI+"'").Msg("")
 
-	conn, err := grpc.Dial(opt.GrpcURI, grpc.WithInsecure())
+	stats := statsFor(opt.GrpcURI)
+	conn, err := grpc.Dial(opt.GrpcURI, grpc.WithInsecure(), grpc.WithUnaryInterceptor(stats.unary), grpc.WithStreamInterceptor(stats.stream), grpc.WithStatsHandler(&ocgrpc.ClientHandler{}))
 	if err != nil {
 		log.Debug().Str("Error connecting to ", "'"+opt.GrpcURI+"' ").Str("err:", err.Error()).Msg("")
 		return nil, err
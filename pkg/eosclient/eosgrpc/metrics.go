@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package eosgrpc
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	erpc "github.com/cs3org/reva/pkg/eosclient/eosgrpc/eos_grpc"
+	"github.com/cs3org/reva/pkg/metrics"
+	"google.golang.org/grpc"
+)
+
+// rpcStats counts the calls to the grpc interface of an MGM, by method.
+type rpcStats struct {
+	uri     string
+	mu      sync.Mutex
+	methods map[string]*methodStats
+}
+
+type methodStats struct {
+	calls, errors uint64
+	seconds       float64
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*rpcStats{}
+)
+
+// statsFor returns the stats of the calls to the MGM, shared by its clients.
+func statsFor(uri string) *rpcStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	s, ok := stats[uri]
+	if !ok {
+		s = &rpcStats{uri: uri, methods: map[string]*methodStats{}}
+		stats[uri] = s
+		metrics.RegisterCollector("eosgrpc/"+uri, s.collect)
+	}
+	return s
+}
+
+func (s *rpcStats) record(method string, d time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.methods[method]
+	if !ok {
+		m = &methodStats{}
+		s.methods[method] = m
+	}
+	m.calls++
+	m.seconds += d.Seconds()
+	if failed {
+		m.errors++
+	}
+}
+
+// unary records the unary calls. The calls answered with an error code by the
+// MGM count as errors too.
+func (s *rpcStats) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	failed := err != nil
+	if r, ok := reply.(interface {
+		GetError() *erpc.NSResponse_ErrorResponse
+	}); ok && r.GetError().GetCode() != 0 {
+		failed = true
+	}
+	s.record(path.Base(method), time.Since(start), failed)
+	return err
+}
+
+// stream records the streaming calls, until the stream is opened.
+func (s *rpcStats) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	s.record(path.Base(method), time.Since(start), err != nil)
+	return cs, err
+}
+
+func (s *rpcStats) collect() []metrics.Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := make([]metrics.Sample, 0, 3*len(s.methods))
+	for method, m := range s.methods {
+		labels := map[string]string{"mgm": s.uri, "method": method}
+		samples = append(samples,
+			metrics.Sample{Name: "eos_rpc_calls_total", Help: "The calls to the grpc interface of the EOS MGMs", Kind: metrics.Counter, Labels: labels, Value: float64(m.calls)},
+			metrics.Sample{Name: "eos_rpc_errors_total", Help: "The calls to the grpc interface of the EOS MGMs which failed", Kind: metrics.Counter, Labels: labels, Value: float64(m.errors)},
+			metrics.Sample{Name: "eos_rpc_seconds_total", Help: "The time spent in calls to the grpc interface of the EOS MGMs", Kind: metrics.Counter, Labels: labels, Value: m.seconds},
+		)
+	}
+	return samples
+}
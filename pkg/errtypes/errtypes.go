@@ -122,6 +122,14 @@ func (e InsufficientStorage) IsInsufficientStorage() {}
 // https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/507
 const StatusInssufficientStorage = 507
 
+// TooManyRequests is the error to use when the client has sent too many requests in a given amount of time.
+type TooManyRequests string
+
+func (e TooManyRequests) Error() string { return "error: too many requests: " + string(e) }
+
+// IsTooManyRequests implements the IsTooManyRequests interface.
+func (e TooManyRequests) IsTooManyRequests() {}
+
 // IsNotFound is the interface to implement
 // to specify that an a resource is not found.
 type IsNotFound interface {
@@ -187,3 +195,9 @@ type IsChecksumMismatch interface {
 type IsInsufficientStorage interface {
 	IsInsufficientStorage()
 }
+
+// IsTooManyRequests is the interface to implement
+// to specify that the client has sent too many requests.
+type IsTooManyRequests interface {
+	IsTooManyRequests()
+}
@@ -0,0 +1,181 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package events provides the typed events emitted by the reva services and
+// the streams they are published to, so that external consumers such as
+// indexers, audit trails or notification services can react to changes
+// without polling.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/pkg/errors"
+)
+
+// SubjectPrefix is the prefix of the subjects the events are published to,
+// followed by the type of the event.
+const SubjectPrefix = "reva.events."
+
+// Stream is the transport events are published to and consumed from.
+type Stream interface {
+	// Publish publishes data to the given subject.
+	Publish(ctx context.Context, subject string, data []byte) error
+	// Subscribe calls handler with the data published to the subjects
+	// matching subject, until ctx is done. The subject may contain the *
+	// wildcard, matching a single token, and end with the > wildcard, matching
+	// the remaining tokens. Subscribers in the same non empty group share the
+	// messages, each message being delivered to one of them.
+	Subscribe(ctx context.Context, subject, group string, handler func(data []byte)) error
+	Close() error
+}
+
+// Event is an event emitted by a reva service.
+type Event interface {
+	// Type returns the name of the event, which is the last token of the subject it is published to.
+	Type() string
+}
+
+// envelope is the encoding of the events on the streams, Time being the time
+// the event was emitted at.
+type envelope struct {
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// newEvents returns a new event of each of the known types, by type name.
+var newEvents = map[string]func() Event{
+	FileUploaded{}.Type():         func() Event { return &FileUploaded{} },
+	PostprocessingFailed{}.Type(): func() Event { return &PostprocessingFailed{} },
+	FileDeleted{}.Type():          func() Event { return &FileDeleted{} },
+	QuotaSet{}.Type():             func() Event { return &QuotaSet{} },
+	QuotaExceeded{}.Type():        func() Event { return &QuotaExceeded{} },
+	ShareCreated{}.Type():         func() Event { return &ShareCreated{} },
+	SpaceCreated{}.Type():         func() Event { return &SpaceCreated{} },
+	SpaceDisabled{}.Type():        func() Event { return &SpaceDisabled{} },
+	SpaceRestored{}.Type():        func() Event { return &SpaceRestored{} },
+	SpaceDeleted{}.Type():         func() Event { return &SpaceDeleted{} },
+	PublicShareCreated{}.Type():   func() Event { return &PublicShareCreated{} },
+	PublicShareUpdated{}.Type():   func() Event { return &PublicShareUpdated{} },
+	PublicShareRemoved{}.Type():   func() Event { return &PublicShareRemoved{} },
+	UserProvisioned{}.Type():      func() Event { return &UserProvisioned{} },
+	UserUpdated{}.Type():          func() Event { return &UserUpdated{} },
+	UserDisabled{}.Type():         func() Event { return &UserDisabled{} },
+	UserDeleted{}.Type():          func() Event { return &UserDeleted{} },
+}
+
+// Subject returns the subject events of the given type are published to.
+func Subject(eventType string) string {
+	return SubjectPrefix + eventType
+}
+
+// Encode returns the encoding of an event on the streams.
+func Encode(ev Event) ([]byte, error) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil, errors.Wrap(err, "events: error encoding event")
+	}
+	return json.Marshal(&envelope{Type: ev.Type(), Time: time.Now(), Data: data})
+}
+
+// Decode returns the event encoded in data.
+func Decode(data []byte) (Event, error) {
+	env := &envelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, errors.Wrap(err, "events: error decoding event")
+	}
+	f, ok := newEvents[env.Type]
+	if !ok {
+		return nil, errors.New("events: unknown event type " + env.Type)
+	}
+	ev := f()
+	if err := json.Unmarshal(env.Data, ev); err != nil {
+		return nil, errors.Wrap(err, "events: error decoding "+env.Type)
+	}
+	return ev, nil
+}
+
+// Publish publishes an event to the stream.
+func Publish(ctx context.Context, s Stream, ev Event) error {
+	data, err := Encode(ev)
+	if err != nil {
+		return err
+	}
+	return s.Publish(ctx, Subject(ev.Type()), data)
+}
+
+// Consume calls handler with the events of the given types published to the
+// stream, or with all events if no type is given, until ctx is done. The
+// events that can't be decoded are logged and skipped.
+func Consume(ctx context.Context, s Stream, group string, handler func(Event), eventTypes ...string) error {
+	if len(eventTypes) == 0 {
+		eventTypes = []string{">"}
+	}
+	log := appctx.GetLogger(ctx)
+	for _, t := range eventTypes {
+		err := s.Subscribe(ctx, Subject(t), group, func(data []byte) {
+			ev, err := Decode(data)
+			if err != nil {
+				log.Error().Err(err).Msg("events: error decoding event")
+				return
+			}
+			handler(ev)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Emitter publishes the events of a service in the background, so that the
+// requests emitting them are neither slowed down nor failed by the stream.
+// A nil Emitter drops the events.
+type Emitter struct {
+	stream Stream
+}
+
+// NewEmitter returns an emitter publishing to the given stream.
+func NewEmitter(s Stream) *Emitter {
+	return &Emitter{stream: s}
+}
+
+// Emit publishes an event, only logging the failures.
+func (e *Emitter) Emit(ctx context.Context, ev Event) {
+	if e == nil || e.stream == nil {
+		return
+	}
+	ctx = appctx.DetachContext(ctx)
+	go func() {
+		if err := Publish(ctx, e.stream, ev); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("event", ev.Type()).Msg("events: error publishing event")
+		}
+	}()
+}
+
+// Close closes the stream of the emitter.
+func (e *Emitter) Close() error {
+	if e == nil || e.stream == nil {
+		return nil
+	}
+	return e.stream.Close()
+}
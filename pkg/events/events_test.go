@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/memory"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	data, err := events.Encode(&events.FileDeleted{
+		Executant:  &userpb.UserId{OpaqueId: "einstein"},
+		ResourceID: &provider.ResourceId{StorageId: "s", OpaqueId: "o"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := events.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, ok := ev.(*events.FileDeleted)
+	if !ok {
+		t.Fatalf("decoded %T, want *events.FileDeleted", ev)
+	}
+	if fd.Executant.OpaqueId != "einstein" || fd.ResourceID.OpaqueId != "o" {
+		t.Errorf("decoded %+v", fd)
+	}
+
+	if _, err := events.Decode([]byte(`{"type":"Unknown","data":{}}`)); err == nil {
+		t.Error("expected an error for unknown event types")
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"reva.events.FileDeleted", "reva.events.FileDeleted", true},
+		{"reva.events.FileDeleted", "reva.events.FileUploaded", false},
+		{"reva.events.*", "reva.events.FileDeleted", true},
+		{"reva.*", "reva.events.FileDeleted", false},
+		{"reva.>", "reva.events.FileDeleted", true},
+		{"reva.events.>", "reva.events", false},
+	}
+	for _, tt := range tests {
+		if got := events.MatchSubject(tt.pattern, tt.subject); got != tt.want {
+			t.Errorf("MatchSubject(%q, %q) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestConsume(t *testing.T) {
+	s, err := memory.New(map[string]interface{}{"name": t.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan events.Event, 2)
+	if err := events.Consume(ctx, s, "", func(ev events.Event) { got <- ev }, events.ShareCreated{}.Type()); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Publish(ctx, s, &events.FileDeleted{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Publish(ctx, s, &events.ShareCreated{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-got:
+		if _, ok := ev.(*events.ShareCreated); !ok {
+			t.Errorf("consumed %T, want *events.ShareCreated", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event consumed")
+	}
+	select {
+	case ev := <-got:
+		t.Errorf("unexpected event %T", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
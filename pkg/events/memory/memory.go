@@ -0,0 +1,190 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package memory provides an in process event stream, for the services
+// running in a single reva instance.
+package memory
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type config struct {
+	// Name is the name of the stream, the services of a reva instance
+	// configured with the same name share the stream.
+	Name string `mapstructure:"name"`
+	// Buffer is the number of messages queued for every subscriber,
+	// the messages published to a subscriber with a full queue are dropped.
+	Buffer int `mapstructure:"buffer"`
+}
+
+func (c *config) init() {
+	if c.Name == "" {
+		c.Name = "default"
+	}
+	if c.Buffer <= 0 {
+		c.Buffer = 1024
+	}
+}
+
+type subscriber struct {
+	subject string
+	group   string
+	ch      chan []byte
+	dropped uint64
+}
+
+type stream struct {
+	conf *config
+
+	sync.RWMutex
+	subs []*subscriber
+	// next is the index of the next subscriber of each group a message is delivered to
+	next map[string]int
+}
+
+var (
+	streamsMu sync.Mutex
+	streams   = map[string]*stream{}
+)
+
+// New returns the in process event stream with the configured name.
+func New(m map[string]interface{}) (events.Stream, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "memory: error decoding conf")
+	}
+	c.init()
+
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	if s, ok := streams[c.Name]; ok {
+		return s, nil
+	}
+	s := &stream{conf: c, next: map[string]int{}}
+	streams[c.Name] = s
+	ops.Register(ops.Queues, "events/memory/"+c.Name, s.probe)
+	return s, nil
+}
+
+func (s *stream) Publish(ctx context.Context, subject string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	groups := map[string][]*subscriber{}
+	for _, sub := range s.subs {
+		if !events.MatchSubject(sub.subject, subject) {
+			continue
+		}
+		if sub.group == "" {
+			deliver(sub, data)
+			continue
+		}
+		groups[sub.group] = append(groups[sub.group], sub)
+	}
+	for g, subs := range groups {
+		// the group members take turns
+		deliver(subs[s.next[g]%len(subs)], data)
+		s.next[g]++
+	}
+	return nil
+}
+
+func deliver(sub *subscriber, data []byte) {
+	select {
+	case sub.ch <- data:
+	default:
+		atomic.AddUint64(&sub.dropped, 1)
+	}
+}
+
+// probe reports the queues of the subscribers, the stream is degraded while
+// one of them is full and drops messages.
+func (s *stream) probe() ops.State {
+	s.RLock()
+	defer s.RUnlock()
+	health := ops.Healthy
+	depth, full := 0, 0
+	var dropped uint64
+	for _, sub := range s.subs {
+		n := len(sub.ch)
+		depth += n
+		if n == cap(sub.ch) {
+			full++
+			health = ops.Degraded
+		}
+		dropped += atomic.LoadUint64(&sub.dropped)
+	}
+	return ops.State{
+		Health: health,
+		Details: map[string]interface{}{
+			"subscribers":      len(s.subs),
+			"depth":            depth,
+			"capacity":         s.conf.Buffer,
+			"full_subscribers": full,
+			"dropped":          dropped,
+		},
+	}
+}
+
+func (s *stream) Subscribe(ctx context.Context, subject, group string, handler func([]byte)) error {
+	sub := &subscriber{subject: subject, group: group, ch: make(chan []byte, s.conf.Buffer)}
+	s.Lock()
+	s.subs = append(s.subs, sub)
+	s.Unlock()
+
+	go func() {
+		for {
+			select {
+			case data := <-sub.ch:
+				handler(data)
+			case <-ctx.Done():
+				s.unsubscribe(sub)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *stream) unsubscribe(sub *subscriber) {
+	s.Lock()
+	defer s.Unlock()
+	for i, v := range s.subs {
+		if v == sub {
+			s.subs = append(s.subs[:i], s.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *stream) Close() error {
+	return nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package nats provides an event stream backed by a NATS server. With
+// jetstream enabled the events are stored in a JetStream stream and the
+// subscribers of a group share a durable consumer, so that the events
+// published while they are not connected are delivered once they are back.
+package nats
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/cs3org/reva/pkg/events/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register("nats", New)
+}
+
+type config struct {
+	// Address is the address of the NATS server, e.g. nats://localhost:4222,
+	// or tls://localhost:4222 for TLS connections. Several servers of a
+	// cluster can be given separated by commas.
+	Address  string `mapstructure:"address"`
+	Insecure bool   `mapstructure:"insecure"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Token    string `mapstructure:"token"`
+	// JetStream stores the events in a JetStream stream, the publications
+	// waiting for the acknowledgement of the stream.
+	JetStream bool `mapstructure:"jetstream"`
+	// Stream is the name of the JetStream stream storing the events, which is
+	// created if it does not exist.
+	Stream string `mapstructure:"stream"`
+	// Timeout is the time in seconds to wait for the server.
+	Timeout int `mapstructure:"timeout"`
+	// Buffer is the number of messages queued for every subscription,
+	// the messages received for a subscription with a full queue are dropped.
+	Buffer int `mapstructure:"buffer"`
+}
+
+func (c *config) init() {
+	if c.Address == "" {
+		c.Address = nats.DefaultURL
+	}
+	if c.Stream == "" {
+		c.Stream = "REVA_EVENTS"
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5
+	}
+	if c.Buffer <= 0 {
+		c.Buffer = 1024
+	}
+}
+
+type stream struct {
+	conf *config
+	nc   *nats.Conn
+	js   nats.JetStreamContext
+
+	mu            sync.Mutex
+	streamCreated bool
+}
+
+// New returns an event stream backed by a NATS server. The connection is
+// retried in the background when the server is not reachable.
+func New(m map[string]interface{}) (events.Stream, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "nats: error decoding conf")
+	}
+	c.init()
+
+	opts := []nats.Option{
+		nats.Name("reva"),
+		nats.Timeout(time.Duration(c.Timeout) * time.Second),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Error().Err(err).Str("address", c.Address).Msg("nats: connection lost")
+			}
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, sub *nats.Subscription, err error) {
+			l := log.Error().Err(err)
+			if sub != nil {
+				l = l.Str("subject", sub.Subject)
+			}
+			l.Msg("nats: asynchronous error")
+		}),
+	}
+	if c.Username != "" {
+		opts = append(opts, nats.UserInfo(c.Username, c.Password))
+	}
+	if c.Token != "" {
+		opts = append(opts, nats.Token(c.Token))
+	}
+	if c.Insecure {
+		opts = append(opts, nats.Secure(&tls.Config{InsecureSkipVerify: true}))
+	}
+
+	nc, err := nats.Connect(c.Address, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "nats: error connecting to "+c.Address)
+	}
+	s := &stream{conf: c, nc: nc}
+	if c.JetStream {
+		if s.js, err = nc.JetStream(nats.MaxWait(time.Duration(c.Timeout) * time.Second)); err != nil {
+			nc.Close()
+			return nil, errors.Wrap(err, "nats: error getting JetStream context")
+		}
+	}
+	return s, nil
+}
+
+// createStream creates the JetStream stream storing the events if it does not exist.
+func (s *stream) createStream() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streamCreated {
+		return nil
+	}
+
+	_, err := s.js.StreamInfo(s.conf.Stream)
+	if err != nil {
+		_, err = s.js.AddStream(&nats.StreamConfig{
+			Name:     s.conf.Stream,
+			Subjects: []string{events.SubjectPrefix + ">"},
+		})
+	}
+	if err != nil {
+		return errors.Wrap(err, "nats: error creating stream "+s.conf.Stream)
+	}
+	s.streamCreated = true
+	return nil
+}
+
+func (s *stream) Publish(ctx context.Context, subject string, data []byte) error {
+	if s.js == nil {
+		return errors.Wrap(s.nc.Publish(subject, data), "nats: error publishing")
+	}
+	if err := s.createStream(); err != nil {
+		return err
+	}
+	_, err := s.js.Publish(subject, data, nats.Context(ctx))
+	return errors.Wrap(err, "nats: error publishing to JetStream")
+}
+
+// durableNames replaces the characters not allowed in consumer names.
+var durableNames = strings.NewReplacer(".", "_", "*", "any", ">", "all")
+
+// Subscribe subscribes to subject. With JetStream the subscribers of a group
+// share a durable consumer named after the group and the subject, which
+// starts with the events published after its creation, while the subscribers
+// without group get an ephemeral consumer deleted when ctx is done.
+func (s *stream) Subscribe(ctx context.Context, subject, group string, handler func([]byte)) error {
+	cb := func(m *nats.Msg) { handler(m.Data) }
+
+	var sub *nats.Subscription
+	var err error
+	switch {
+	case s.js == nil:
+		sub, err = s.nc.QueueSubscribe(subject, group, cb)
+	case group == "":
+		if err = s.createStream(); err == nil {
+			sub, err = s.js.Subscribe(subject, cb, nats.BindStream(s.conf.Stream), nats.DeliverNew())
+		}
+	default:
+		if err = s.createStream(); err == nil {
+			durable := durableNames.Replace(group + "_" + strings.TrimPrefix(subject, events.SubjectPrefix))
+			sub, err = s.js.QueueSubscribe(subject, group, cb, nats.BindStream(s.conf.Stream), nats.Durable(durable), nats.DeliverNew())
+		}
+	}
+	if err != nil {
+		return errors.Wrap(err, "nats: error subscribing to "+subject)
+	}
+	if err := sub.SetPendingLimits(s.conf.Buffer, -1); err != nil {
+		_ = sub.Unsubscribe()
+		return errors.Wrap(err, "nats: error subscribing to "+subject)
+	}
+
+	go func() {
+		<-ctx.Done()
+		if s.js != nil && group != "" {
+			// draining keeps the durable consumer
+			_ = sub.Drain()
+			return
+		}
+		_ = sub.Unsubscribe()
+	}()
+	return nil
+}
+
+func (s *stream) Close() error {
+	s.nc.Close()
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/events"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func startServer(t *testing.T) string {
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s.ClientURL()
+}
+
+func receive(t *testing.T, got <-chan string, want string) {
+	t.Helper()
+	select {
+	case data := <-got:
+		if data != want {
+			t.Errorf("received %q, want %q", data, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s not received", want)
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	addr := startServer(t)
+	for _, js := range []bool{false, true} {
+		s, err := New(map[string]interface{}{"address": addr, "jetstream": js, "timeout": 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		got := make(chan string, 1)
+		if err := s.Subscribe(ctx, events.SubjectPrefix+">", "", func(data []byte) { got <- string(data) }); err != nil {
+			t.Fatalf("jetstream %v: %v", js, err)
+		}
+		if err := s.Publish(ctx, events.Subject("FileUploaded"), []byte("hello")); err != nil {
+			t.Fatalf("jetstream %v: %v", js, err)
+		}
+		receive(t, got, "hello")
+		cancel()
+		s.Close()
+	}
+}
+
+func TestDurableGroup(t *testing.T) {
+	addr := startServer(t)
+	s, err := New(map[string]interface{}{"address": addr, "jetstream": true, "timeout": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	subject := events.Subject("FileUploaded")
+
+	got := make(chan string, 10)
+	handler := func(data []byte) { got <- string(data) }
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 2; i++ {
+		if err := s.Subscribe(ctx, subject, "indexer", handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Publish(ctx, subject, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, got, "first")
+	select {
+	case data := <-got:
+		t.Fatalf("%s delivered to both subscribers of the group", data)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// the events published while the group is away are kept for it
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	if err := s.Publish(context.Background(), subject, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Subscribe(ctx, subject, "indexer", handler); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, got, "second")
+}
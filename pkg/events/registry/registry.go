@@ -0,0 +1,55 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"fmt"
+
+	"github.com/cs3org/reva/pkg/events"
+)
+
+// NewFunc is the function that event streams
+// should register at init time.
+type NewFunc func(map[string]interface{}) (events.Stream, error)
+
+// NewFuncs is a map containing all the registered event streams.
+var NewFuncs = map[string]NewFunc{}
+
+// Register registers a new event stream new function.
+// Not safe for concurrent use. Safe for use from package init.
+func Register(name string, f NewFunc) {
+	NewFuncs[name] = f
+}
+
+// NewEmitter returns an emitter publishing to the named event stream,
+// or nil, which drops the events, when no stream is named.
+func NewEmitter(name string, configs map[string]map[string]interface{}) (*events.Emitter, error) {
+	if name == "" {
+		return nil, nil
+	}
+	f, ok := NewFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("event stream not found: %s", name)
+	}
+	s, err := f(configs[name])
+	if err != nil {
+		return nil, err
+	}
+	return events.NewEmitter(s), nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package events
+
+import (
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// The file events carry the path or the id of the resource, depending on
+// the reference the request was made with. References are not used as such
+// because their oneof can't be decoded from JSON.
+
+// FileUploaded is emitted by the data providers when an upload completes.
+type FileUploaded struct {
+	Executant  *userpb.UserId       `json:"executant,omitempty"`
+	Path       string               `json:"path,omitempty"`
+	ResourceID *provider.ResourceId `json:"resource_id,omitempty"`
+	// ParentID is the id of the folder the file was uploaded to.
+	ParentID *provider.ResourceId `json:"parent_id,omitempty"`
+}
+
+// Type implements Event.
+func (FileUploaded) Type() string { return "FileUploaded" }
+
+// PostprocessingFailed is emitted by the data providers when a step of the
+// post-processing of an upload keeps failing, the file staying unlisted.
+type PostprocessingFailed struct {
+	Executant  *userpb.UserId       `json:"executant,omitempty"`
+	Path       string               `json:"path,omitempty"`
+	ResourceID *provider.ResourceId `json:"resource_id,omitempty"`
+	Step       string               `json:"step"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// Type implements Event.
+func (PostprocessingFailed) Type() string { return "PostprocessingFailed" }
+
+// FileDeleted is emitted by the storage providers when a resource is deleted.
+type FileDeleted struct {
+	Executant  *userpb.UserId       `json:"executant,omitempty"`
+	Path       string               `json:"path,omitempty"`
+	ResourceID *provider.ResourceId `json:"resource_id,omitempty"`
+}
+
+// Type implements Event.
+func (FileDeleted) Type() string { return "FileDeleted" }
+
+// QuotaSet is emitted by the storage providers when the quota of a space or
+// of a home is set, MaxBytes 0 lifting it.
+type QuotaSet struct {
+	Executant  *userpb.UserId       `json:"executant,omitempty"`
+	Path       string               `json:"path,omitempty"`
+	ResourceID *provider.ResourceId `json:"resource_id,omitempty"`
+	MaxBytes   uint64               `json:"max_bytes"`
+}
+
+// Type implements Event.
+func (QuotaSet) Type() string { return "QuotaSet" }
+
+// QuotaExceeded is emitted by the storage providers when an upload is refused
+// because it doesn't fit in the remaining quota.
+type QuotaExceeded struct {
+	Executant    *userpb.UserId       `json:"executant,omitempty"`
+	Path         string               `json:"path,omitempty"`
+	ResourceID   *provider.ResourceId `json:"resource_id,omitempty"`
+	UploadLength uint64               `json:"upload_length,omitempty"`
+}
+
+// Type implements Event.
+func (QuotaExceeded) Type() string { return "QuotaExceeded" }
+
+// ShareCreated is emitted by the gateway when a user or group share is created.
+type ShareCreated struct {
+	ShareID        *collaboration.ShareId          `json:"share_id"`
+	Sharer         *userpb.UserId                  `json:"sharer,omitempty"`
+	GranteeUserID  *userpb.UserId                  `json:"grantee_user_id,omitempty"`
+	GranteeGroupID *grouppb.GroupId                `json:"grantee_group_id,omitempty"`
+	ItemID         *provider.ResourceId            `json:"item_id"`
+	Permissions    *collaboration.SharePermissions `json:"permissions,omitempty"`
+	CTime          *types.Timestamp                `json:"ctime,omitempty"`
+}
+
+// Type implements Event.
+func (ShareCreated) Type() string { return "ShareCreated" }
+
+// ShareExpired is emitted by the user share provider when it removes a share
+// whose expiration has passed.
+type ShareExpired struct {
+	ShareID        *collaboration.ShareId `json:"share_id"`
+	Sharer         *userpb.UserId         `json:"sharer,omitempty"`
+	GranteeUserID  *userpb.UserId         `json:"grantee_user_id,omitempty"`
+	GranteeGroupID *grouppb.GroupId       `json:"grantee_group_id,omitempty"`
+	ItemID         *provider.ResourceId   `json:"item_id"`
+	Expiration     *types.Timestamp       `json:"expiration,omitempty"`
+}
+
+// Type implements Event.
+func (ShareExpired) Type() string { return "ShareExpired" }
+
+// PublicShareCreated is emitted by the gateway when a public link is created.
+type PublicShareCreated struct {
+	ShareID     *link.PublicShareId          `json:"share_id"`
+	Token       string                       `json:"token"`
+	ItemID      *provider.ResourceId         `json:"item_id"`
+	Owner       *userpb.UserId               `json:"owner,omitempty"`
+	Creator     *userpb.UserId               `json:"creator,omitempty"`
+	Permissions *link.PublicSharePermissions `json:"permissions,omitempty"`
+	Expiration  *types.Timestamp             `json:"expiration,omitempty"`
+	DisplayName string                       `json:"display_name,omitempty"`
+	// NotifyUploads is set for the upload only links, telling whether their
+	// creator wants to be notified about the uploaded files.
+	NotifyUploads *bool `json:"notify_uploads,omitempty"`
+}
+
+// Type implements Event.
+func (PublicShareCreated) Type() string { return "PublicShareCreated" }
+
+// PublicShareUpdated is emitted by the gateway when a public link is updated,
+// carrying the link as it is after the update.
+type PublicShareUpdated PublicShareCreated
+
+// Type implements Event.
+func (PublicShareUpdated) Type() string { return "PublicShareUpdated" }
+
+// PublicShareRemoved is emitted by the gateway when a public link is removed.
+// It carries either the id or the token of the link, the one the link was
+// removed with.
+type PublicShareRemoved struct {
+	ShareID *link.PublicShareId `json:"share_id,omitempty"`
+	Token   string              `json:"token,omitempty"`
+}
+
+// Type implements Event.
+func (PublicShareRemoved) Type() string { return "PublicShareRemoved" }
+
+// SpaceCreated is emitted by the gateway when a storage space is created.
+type SpaceCreated struct {
+	ID        *provider.StorageSpaceId `json:"id"`
+	Owner     *userpb.UserId           `json:"owner,omitempty"`
+	Root      *provider.ResourceId     `json:"root,omitempty"`
+	Name      string                   `json:"name"`
+	SpaceType string                   `json:"space_type"`
+}
+
+// Type implements Event.
+func (SpaceCreated) Type() string { return "SpaceCreated" }
+
+// SpaceDisabled is emitted by the gateway when a storage space is deleted with
+// a grace period, the space being disabled until it is restored or purged.
+type SpaceDisabled struct {
+	ID        *provider.StorageSpaceId `json:"id"`
+	Executant *userpb.UserId           `json:"executant,omitempty"`
+	Name      string                   `json:"name"`
+	SpaceType string                   `json:"space_type"`
+	Purge     *types.Timestamp         `json:"purge,omitempty"`
+}
+
+// Type implements Event.
+func (SpaceDisabled) Type() string { return "SpaceDisabled" }
+
+// SpaceRestored is emitted by the gateway when a disabled storage space is restored.
+type SpaceRestored struct {
+	ID        *provider.StorageSpaceId `json:"id"`
+	Executant *userpb.UserId           `json:"executant,omitempty"`
+}
+
+// Type implements Event.
+func (SpaceRestored) Type() string { return "SpaceRestored" }
+
+// SpaceDeleted is emitted by the gateway when a storage space is removed for
+// good, either at once or once its grace period is over.
+type SpaceDeleted struct {
+	ID        *provider.StorageSpaceId `json:"id"`
+	Executant *userpb.UserId           `json:"executant,omitempty"`
+}
+
+// Type implements Event.
+func (SpaceDeleted) Type() string { return "SpaceDeleted" }
+
+// UserProvisioned is emitted when a user is provisioned on the first login
+// or created by an admin.
+type UserProvisioned struct {
+	UserID   *userpb.UserId             `json:"user_id"`
+	Username string                     `json:"username"`
+	Home     bool                       `json:"home"`
+	Spaces   []*provider.StorageSpaceId `json:"spaces,omitempty"`
+}
+
+// Type implements Event.
+func (UserProvisioned) Type() string { return "UserProvisioned" }
+
+// UserUpdated is emitted when the attributes of a user are changed.
+type UserUpdated struct {
+	UserID    *userpb.UserId `json:"user_id"`
+	Executant *userpb.UserId `json:"executant,omitempty"`
+}
+
+// Type implements Event.
+func (UserUpdated) Type() string { return "UserUpdated" }
+
+// UserDisabled is emitted when a user is disabled or enabled again.
+type UserDisabled struct {
+	UserID    *userpb.UserId `json:"user_id"`
+	Executant *userpb.UserId `json:"executant,omitempty"`
+	Disabled  bool           `json:"disabled"`
+}
+
+// Type implements Event.
+func (UserDisabled) Type() string { return "UserDisabled" }
+
+// UserDeleted is emitted when a user is deleted, its storage is kept.
+type UserDeleted struct {
+	UserID    *userpb.UserId `json:"user_id"`
+	Executant *userpb.UserId `json:"executant,omitempty"`
+}
+
+// Type implements Event.
+func (UserDeleted) Type() string { return "UserDeleted" }
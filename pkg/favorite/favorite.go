@@ -0,0 +1,48 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package favorite defines the managers keeping the resources the users
+// marked as favorites. They are kept apart from the storages, so that the
+// favorites work the same on all the storage drivers, including the ones
+// which can't store per user flags.
+package favorite
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// Manager keeps the favorite resources of the users, by resource id.
+type Manager interface {
+	// ListFavorites returns the ids of the favorite resources of the user.
+	ListFavorites(ctx context.Context, userID *userpb.UserId) ([]*provider.ResourceId, error)
+	// SetFavorite marks the resource as a favorite of the user, which is a
+	// no-op when it already is one.
+	SetFavorite(ctx context.Context, userID *userpb.UserId, id *provider.ResourceId) error
+	// UnsetFavorite removes the resource from the favorites of the user, which
+	// is a no-op when it isn't one.
+	UnsetFavorite(ctx context.Context, userID *userpb.UserId, id *provider.ResourceId) error
+}
+
+// Key returns the key identifying the resource with the given id in a set of
+// favorites.
+func Key(id *provider.ResourceId) string {
+	return id.GetStorageId() + "!" + id.GetOpaqueId()
+}
@@ -0,0 +1,95 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package memory provides a favorite manager local to the process. The
+// favorites are lost when the process restarts and are not shared with the
+// other services.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/favorite"
+	"github.com/cs3org/reva/pkg/favorite/manager/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type manager struct {
+	mu sync.RWMutex
+	// favorites holds the favorite resources by user and resource key
+	favorites map[string]map[string]*provider.ResourceId
+}
+
+// New returns a favorite manager keeping the favorites in memory.
+func New(m map[string]interface{}) (favorite.Manager, error) {
+	return NewManager(), nil
+}
+
+// NewManager returns a favorite manager keeping the favorites in memory.
+func NewManager() favorite.Manager {
+	return &manager{favorites: map[string]map[string]*provider.ResourceId{}}
+}
+
+func userKey(u *userpb.UserId) string {
+	return u.GetIdp() + "!" + u.GetOpaqueId()
+}
+
+func (m *manager) ListFavorites(ctx context.Context, userID *userpb.UserId) ([]*provider.ResourceId, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	favs := m.favorites[userKey(userID)]
+	keys := make([]string, 0, len(favs))
+	for k := range favs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ids := make([]*provider.ResourceId, 0, len(keys))
+	for _, k := range keys {
+		ids = append(ids, favs[k])
+	}
+	return ids, nil
+}
+
+func (m *manager) SetFavorite(ctx context.Context, userID *userpb.UserId, id *provider.ResourceId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := userKey(userID)
+	if m.favorites[u] == nil {
+		m.favorites[u] = map[string]*provider.ResourceId{}
+	}
+	m.favorites[u][favorite.Key(id)] = &provider.ResourceId{StorageId: id.StorageId, OpaqueId: id.OpaqueId}
+	return nil
+}
+
+func (m *manager) UnsetFavorite(ctx context.Context, userID *userpb.UserId, id *provider.ResourceId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := userKey(userID)
+	delete(m.favorites[u], favorite.Key(id))
+	if len(m.favorites[u]) == 0 {
+		delete(m.favorites, u)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/favorite"
+)
+
+func TestFavorites(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	einstein := &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}
+	marie := &userpb.UserId{Idp: "idp", OpaqueId: "marie"}
+	a := &provider.ResourceId{StorageId: "home", OpaqueId: "a"}
+	b := &provider.ResourceId{StorageId: "home", OpaqueId: "b"}
+
+	for _, id := range []*provider.ResourceId{b, a, a} {
+		if err := m.SetFavorite(ctx, einstein, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.SetFavorite(ctx, marie, b); err != nil {
+		t.Fatal(err)
+	}
+
+	favs, err := m.ListFavorites(ctx, einstein)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favs) != 2 || favorite.Key(favs[0]) != favorite.Key(a) || favorite.Key(favs[1]) != favorite.Key(b) {
+		t.Fatalf("unexpected favorites %v", favs)
+	}
+
+	if err := m.UnsetFavorite(ctx, einstein, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UnsetFavorite(ctx, einstein, b); err != nil {
+		t.Fatal(err)
+	}
+	if favs, _ := m.ListFavorites(ctx, einstein); len(favs) != 1 || favorite.Key(favs[0]) != favorite.Key(a) {
+		t.Fatalf("unexpected favorites after unset %v", favs)
+	}
+	if favs, _ := m.ListFavorites(ctx, marie); len(favs) != 1 || favorite.Key(favs[0]) != favorite.Key(b) {
+		t.Fatalf("the favorites of another user must be kept, got %v", favs)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a favorite manager storing the favorites in a SQL
+// database, MySQL, PostgreSQL or SQLite, whose schema is created and upgraded
+// on startup. The services using the same database share the favorites.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/favorite"
+	"github.com/cs3org/reva/pkg/favorite/manager/registry"
+	"github.com/cs3org/reva/pkg/sqlmigrate"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	// Provide the supported database drivers.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const migrationsTable = "favorites_migrations"
+
+var migrations = []sqlmigrate.Migration{
+	{
+		Version:     1,
+		Description: "create the favorites table",
+		Up: map[sqlmigrate.Dialect][]string{
+			"": {
+				`CREATE TABLE favorites (
+					user_idp VARCHAR(255) NOT NULL,
+					user_id VARCHAR(255) NOT NULL,
+					storage_id VARCHAR(255) NOT NULL,
+					opaque_id VARCHAR(255) NOT NULL,
+					ctime BIGINT NOT NULL,
+					PRIMARY KEY (user_idp, user_id, storage_id, opaque_id)
+				)`,
+			},
+		},
+	},
+}
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	// DbDriver is the database/sql driver: mysql, postgres or sqlite3.
+	DbDriver       string `mapstructure:"db_driver"`
+	DbDSN          string `mapstructure:"db_dsn"`
+	SkipMigrations bool   `mapstructure:"db_skip_migrations"`
+}
+
+func (c *config) init() {
+	if c.DbDriver == "" {
+		c.DbDriver = string(sqlmigrate.SQLite)
+	}
+	if c.DbDSN == "" && c.DbDriver == string(sqlmigrate.SQLite) {
+		c.DbDSN = "/var/tmp/reva/favorites.db"
+	}
+}
+
+type manager struct {
+	dialect sqlmigrate.Dialect
+	db      *sql.DB
+}
+
+// New returns a favorite manager storing the favorites in a SQL database.
+func New(m map[string]interface{}) (favorite.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding conf")
+	}
+	c.init()
+
+	dialect := sqlmigrate.Dialect(c.DbDriver)
+	switch dialect {
+	case sqlmigrate.MySQL, sqlmigrate.Postgres:
+	case sqlmigrate.SQLite:
+		if err := os.MkdirAll(filepath.Dir(c.DbDSN), 0755); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errtypes.NotSupported("sql: unsupported database driver " + c.DbDriver)
+	}
+
+	db, err := sql.Open(c.DbDriver, c.DbDSN)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error opening the database")
+	}
+	if !c.SkipMigrations {
+		if err := sqlmigrate.Apply(context.Background(), db, dialect, migrationsTable, migrations); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	return &manager{
+		dialect: dialect,
+		db:      db,
+	}, nil
+}
+
+func (m *manager) ListFavorites(ctx context.Context, userID *userpb.UserId) ([]*provider.ResourceId, error) {
+	query := m.dialect.Rebind("SELECT storage_id, opaque_id FROM favorites WHERE user_id = ? AND user_idp = ? ORDER BY ctime, storage_id, opaque_id")
+	rows, err := m.db.QueryContext(ctx, query, userID.GetOpaqueId(), userID.GetIdp())
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error listing the favorites")
+	}
+	defer rows.Close()
+
+	ids := []*provider.ResourceId{}
+	for rows.Next() {
+		id := &provider.ResourceId{}
+		if err := rows.Scan(&id.StorageId, &id.OpaqueId); err != nil {
+			return nil, errors.Wrap(err, "sql: error reading the favorites")
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (m *manager) SetFavorite(ctx context.Context, userID *userpb.UserId, id *provider.ResourceId) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error starting a transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var n int
+	query := m.dialect.Rebind("SELECT COUNT(*) FROM favorites WHERE user_id = ? AND user_idp = ? AND storage_id = ? AND opaque_id = ?")
+	if err := tx.QueryRowContext(ctx, query, userID.GetOpaqueId(), userID.GetIdp(), id.GetStorageId(), id.GetOpaqueId()).Scan(&n); err != nil {
+		return errors.Wrap(err, "sql: error reading the favorite")
+	}
+	if n > 0 {
+		return nil
+	}
+
+	query = m.dialect.Rebind("INSERT INTO favorites (user_idp, user_id, storage_id, opaque_id, ctime) VALUES (?, ?, ?, ?, ?)")
+	if _, err := tx.ExecContext(ctx, query, userID.GetIdp(), userID.GetOpaqueId(), id.GetStorageId(), id.GetOpaqueId(), time.Now().Unix()); err != nil {
+		return errors.Wrap(err, "sql: error storing the favorite")
+	}
+	return errors.Wrap(tx.Commit(), "sql: error storing the favorite")
+}
+
+func (m *manager) UnsetFavorite(ctx context.Context, userID *userpb.UserId, id *provider.ResourceId) error {
+	query := m.dialect.Rebind("DELETE FROM favorites WHERE user_id = ? AND user_idp = ? AND storage_id = ? AND opaque_id = ?")
+	if _, err := m.db.ExecContext(ctx, query, userID.GetOpaqueId(), userID.GetIdp(), id.GetStorageId(), id.GetOpaqueId()); err != nil {
+		return errors.Wrap(err, "sql: error removing the favorite")
+	}
+	return nil
+}
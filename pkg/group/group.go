@@ -20,11 +20,19 @@ package group
 
 import (
 	"context"
+	"encoding/json"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
 )
 
+// ParentGroupsOpaqueKey is the opaque key of the get group requests asking for
+// the parent groups of the group, and of the groups returned with them as a
+// JSON list of group names.
+const ParentGroupsOpaqueKey = "parent_groups"
+
 // Manager is the interface to implement to manipulate groups.
 type Manager interface {
 	GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.Group, error)
@@ -33,3 +41,48 @@ type Manager interface {
 	GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error)
 	HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error)
 }
+
+// NestedManager is implemented by the managers supporting groups that are
+// members of other groups.
+type NestedManager interface {
+	// GetParentGroups returns the names of the groups the group with the given
+	// name is a direct member of.
+	GetParentGroups(ctx context.Context, name string) ([]string, error)
+}
+
+// EncodeParentGroups adds the names of the parent groups to the opaque, which
+// is created if nil.
+func EncodeParentGroups(o *types.Opaque, parents []string) (*types.Opaque, error) {
+	if parents == nil {
+		parents = []string{}
+	}
+	v, err := json.Marshal(parents)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		o = &types.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*types.OpaqueEntry{}
+	}
+	o.Map[ParentGroupsOpaqueKey] = &types.OpaqueEntry{Decoder: "json", Value: v}
+	return o, nil
+}
+
+// DecodeParentGroups returns the names of the parent groups held by the opaque.
+// The boolean is false if the opaque holds none.
+func DecodeParentGroups(o *types.Opaque) ([]string, bool, error) {
+	e, ok := o.GetMap()[ParentGroupsOpaqueKey]
+	if !ok {
+		return nil, false, nil
+	}
+	if e.Decoder != "json" {
+		return nil, false, errtypes.BadRequest("group: unsupported decoder for " + ParentGroupsOpaqueKey + ": " + e.Decoder)
+	}
+	var parents []string
+	if err := json.Unmarshal(e.Value, &parents); err != nil {
+		return nil, false, errtypes.BadRequest("group: invalid " + ParentGroupsOpaqueKey + ": " + err.Error())
+	}
+	return parents, true, nil
+}
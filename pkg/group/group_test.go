@@ -0,0 +1,53 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package group
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParentGroupsOpaque(t *testing.T) {
+	if _, ok, err := DecodeParentGroups(nil); ok || err != nil {
+		t.Fatalf("expected no parent groups, got %v %v", ok, err)
+	}
+
+	o, err := EncodeParentGroups(nil, []string{"physics", "staff"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents, ok, err := DecodeParentGroups(o)
+	if err != nil || !ok || !reflect.DeepEqual(parents, []string{"physics", "staff"}) {
+		t.Fatalf("unexpected parent groups %v %v %v", parents, ok, err)
+	}
+
+	// groups without parents are answered with an empty list
+	o, err = EncodeParentGroups(o, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parents, ok, err := DecodeParentGroups(o); err != nil || !ok || len(parents) != 0 {
+		t.Fatalf("expected an empty list of parent groups, got %v %v %v", parents, ok, err)
+	}
+
+	o.Map[ParentGroupsOpaqueKey].Decoder = "plain"
+	if _, _, err := DecodeParentGroups(o); err == nil {
+		t.Fatal("expected an unsupported decoder to be rejected")
+	}
+}
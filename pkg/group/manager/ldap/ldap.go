@@ -21,7 +21,6 @@ package ldap
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"fmt"
 	"strconv"
 	"strings"
@@ -34,6 +33,8 @@ import (
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/group"
 	"github.com/cs3org/reva/pkg/group/manager/registry"
+	"github.com/cs3org/reva/pkg/health"
+	ldaputils "github.com/cs3org/reva/pkg/utils/ldap"
 	"github.com/go-ldap/ldap/v3"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -45,22 +46,29 @@ func init() {
 
 type manager struct {
 	c            *config
+	pool         *ldaputils.Pool
 	groupfilter  *template.Template
 	memberfilter *template.Template
+	parentfilter *template.Template
 }
 
 type config struct {
-	Hostname        string     `mapstructure:"hostname"`
-	Port            int        `mapstructure:"port"`
-	BaseDN          string     `mapstructure:"base_dn"`
-	GroupFilter     string     `mapstructure:"groupfilter"`
-	MemberFilter    string     `mapstructure:"memberfilter"`
-	AttributeFilter string     `mapstructure:"attributefilter"`
-	FindFilter      string     `mapstructure:"findfilter"`
-	BindUsername    string     `mapstructure:"bind_username"`
-	BindPassword    string     `mapstructure:"bind_password"`
-	Idp             string     `mapstructure:"idp"`
-	Schema          attributes `mapstructure:"schema"`
+	LDAP            ldaputils.Config `mapstructure:",squash"`
+	BaseDN          string           `mapstructure:"base_dn"`
+	GroupFilter     string           `mapstructure:"groupfilter"`
+	MemberFilter    string           `mapstructure:"memberfilter"`
+	AttributeFilter string           `mapstructure:"attributefilter"`
+	FindFilter      string           `mapstructure:"findfilter"`
+	Idp             string           `mapstructure:"idp"`
+	Schema          attributes       `mapstructure:"schema"`
+	// Opaque maps additional attributes of the groups to their opaque
+	// entries, see ldaputils.OpaqueMapping.
+	Opaque ldaputils.OpaqueMapping `mapstructure:"opaque"`
+	// ParentFilter finds the groups a group is a direct member of, with %s
+	// standing for the name of the group, e.g.
+	// `(&(objectclass=groupOfNames)(member=cn=%s,ou=groups,dc=example,dc=org))`.
+	// Nested groups are not resolved if it is empty.
+	ParentFilter string `mapstructure:"parentfilter"`
 }
 
 type attributes struct {
@@ -90,6 +98,8 @@ var ldapDefaults = attributes{
 
 func parseConfig(m map[string]interface{}) (*config, error) {
 	c := config{
+		// the certificates of the servers were never verified
+		LDAP:   ldaputils.Config{Insecure: true},
 		Schema: ldapDefaults,
 	}
 	if err := mapstructure.Decode(m, &c); err != nil {
@@ -112,10 +122,13 @@ func New(m map[string]interface{}) (group.Manager, error) {
 		c.FindFilter = c.GroupFilter
 	}
 	c.MemberFilter = strings.ReplaceAll(c.MemberFilter, "%s", "{{.OpaqueId}}")
+	c.ParentFilter = strings.ReplaceAll(c.ParentFilter, "%s", "{{.Name}}")
 
 	mgr := &manager{
-		c: c,
+		c:    c,
+		pool: ldaputils.NewPool(&c.LDAP),
 	}
+	health.Register("group/ldap", mgr.pool.Check)
 
 	mgr.groupfilter, err = template.New("gf").Funcs(sprig.TxtFuncMap()).Parse(c.GroupFilter)
 	if err != nil {
@@ -127,67 +140,79 @@ func New(m map[string]interface{}) (group.Manager, error) {
 		err := errors.Wrap(err, fmt.Sprintf("error parsing memberfilter tpl:%s", c.MemberFilter))
 		panic(err)
 	}
+	mgr.parentfilter, err = template.New("pf").Funcs(sprig.TxtFuncMap()).Parse(c.ParentFilter)
+	if err != nil {
+		err := errors.Wrap(err, fmt.Sprintf("error parsing parentfilter tpl:%s", c.ParentFilter))
+		panic(err)
+	}
 
 	return mgr, nil
 }
 
-func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.Group, error) {
-	log := appctx.GetLogger(ctx)
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return nil, err
-	}
+func (m *manager) groupAttributes() []string {
+	attrs := []string{m.c.Schema.DN, m.c.Schema.GID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.GIDNumber}
+	return append(attrs, m.c.Opaque.Attributes()...)
+}
 
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
+func (m *manager) search(filter string, attributes []string) (*ldap.SearchResult, error) {
+	return m.pool.Search(ldap.NewSearchRequest(
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getGroupFilter(gid),
-		[]string{m.c.Schema.DN, m.c.Schema.GID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.GIDNumber},
+		filter,
+		attributes,
 		nil,
-	)
+	))
+}
 
-	sr, err := l.Search(searchRequest)
+// getGroup returns the single group matched by the filter, with its members.
+func (m *manager) getGroup(ctx context.Context, filter, notFound string) (*grouppb.Group, error) {
+	log := appctx.GetLogger(ctx)
+	sr, err := m.search(filter, m.groupAttributes())
 	if err != nil {
 		return nil, err
 	}
 
 	if len(sr.Entries) != 1 {
-		return nil, errtypes.NotFound(gid.OpaqueId)
+		return nil, errtypes.NotFound(notFound)
 	}
 
 	log.Debug().Interface("entries", sr.Entries).Msg("entries")
 
-	id := &grouppb.GroupId{
-		Idp:      m.c.Idp,
-		OpaqueId: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GID),
+	g, err := m.groupFromEntry(sr.Entries[0])
+	if err != nil {
+		return nil, err
 	}
-	members, err := m.GetMembers(ctx, id)
+	if g.Members, err = m.GetMembers(ctx, g.Id); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (m *manager) groupFromEntry(entry *ldap.Entry) (*grouppb.Group, error) {
+	gidNumber, err := strconv.ParseInt(entry.GetEqualFoldAttributeValue(m.c.Schema.GIDNumber), 10, 64)
 	if err != nil {
 		return nil, err
 	}
-	gidNumber, err := strconv.ParseInt(sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GIDNumber), 10, 64)
+	opaque, err := m.c.Opaque.Apply(entry, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	g := &grouppb.Group{
-		Id:          id,
-		GroupName:   sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.CN),
-		Members:     members,
-		Mail:        sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.Mail),
-		DisplayName: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
+	return &grouppb.Group{
+		Id: &grouppb.GroupId{
+			Idp:      m.c.Idp,
+			OpaqueId: entry.GetEqualFoldAttributeValue(m.c.Schema.GID),
+		},
+		GroupName:   entry.GetEqualFoldAttributeValue(m.c.Schema.CN),
+		Mail:        entry.GetEqualFoldAttributeValue(m.c.Schema.Mail),
+		DisplayName: entry.GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
 		GidNumber:   gidNumber,
-	}
+		Opaque:      opaque,
+	}, nil
+}
 
-	return g, nil
+func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.Group, error) {
+	return m.getGroup(ctx, m.getGroupFilter(gid), gid.OpaqueId)
 }
 
 func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string) (*grouppb.Group, error) {
@@ -205,87 +230,11 @@ func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string) (*gr
 		return nil, errors.New("ldap: invalid field " + claim)
 	}
 
-	log := appctx.GetLogger(ctx)
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return nil, err
-	}
-
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
-		m.c.BaseDN,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getAttributeFilter(claim, value),
-		[]string{m.c.Schema.DN, m.c.Schema.GID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.GIDNumber},
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(sr.Entries) != 1 {
-		return nil, errtypes.NotFound(claim + ": " + value)
-	}
-
-	log.Debug().Interface("entries", sr.Entries).Msg("entries")
-
-	id := &grouppb.GroupId{
-		Idp:      m.c.Idp,
-		OpaqueId: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GID),
-	}
-	members, err := m.GetMembers(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	gidNumber, err := strconv.ParseInt(sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GIDNumber), 10, 64)
-	if err != nil {
-		return nil, err
-	}
-
-	g := &grouppb.Group{
-		Id:          id,
-		GroupName:   sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.CN),
-		Members:     members,
-		Mail:        sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.Mail),
-		DisplayName: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-		GidNumber:   gidNumber,
-	}
-
-	return g, nil
+	return m.getGroup(ctx, m.getAttributeFilter(claim, value), claim+": "+value)
 }
 
 func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Group, error) {
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return nil, err
-	}
-
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
-		m.c.BaseDN,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getFindFilter(query),
-		[]string{m.c.Schema.DN, m.c.Schema.GID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.GIDNumber},
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
+	sr, err := m.search(m.getFindFilter(query), m.groupAttributes())
 	if err != nil {
 		return nil, err
 	}
@@ -293,22 +242,10 @@ func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Grou
 	groups := []*grouppb.Group{}
 
 	for _, entry := range sr.Entries {
-		id := &grouppb.GroupId{
-			Idp:      m.c.Idp,
-			OpaqueId: entry.GetEqualFoldAttributeValue(m.c.Schema.GID),
-		}
-		gidNumber, err := strconv.ParseInt(entry.GetEqualFoldAttributeValue(m.c.Schema.GIDNumber), 10, 64)
+		g, err := m.groupFromEntry(entry)
 		if err != nil {
 			return nil, err
 		}
-
-		g := &grouppb.Group{
-			Id:          id,
-			GroupName:   entry.GetEqualFoldAttributeValue(m.c.Schema.CN),
-			Mail:        entry.GetEqualFoldAttributeValue(m.c.Schema.Mail),
-			DisplayName: entry.GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-			GidNumber:   gidNumber,
-		}
 		groups = append(groups, g)
 	}
 
@@ -316,28 +253,7 @@ func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Grou
 }
 
 func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return nil, err
-	}
-
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
-		m.c.BaseDN,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getMemberFilter(gid),
-		[]string{m.c.Schema.CN}, // TODO use DN to look up user id
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
+	sr, err := m.search(m.getMemberFilter(gid), []string{m.c.Schema.CN}) // TODO use DN to look up user id
 	if err != nil {
 		return nil, err
 	}
@@ -368,6 +284,24 @@ func (m *manager) HasMember(ctx context.Context, gid *grouppb.GroupId, uid *user
 	return false, nil
 }
 
+// GetParentGroups returns the names of the groups the group is a direct member of.
+func (m *manager) GetParentGroups(ctx context.Context, name string) ([]string, error) {
+	if m.c.ParentFilter == "" {
+		return nil, nil
+	}
+
+	sr, err := m.search(m.getParentFilter(name), []string{m.c.Schema.CN})
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make([]string, 0, len(sr.Entries))
+	for _, entry := range sr.Entries {
+		parents = append(parents, entry.GetEqualFoldAttributeValue(m.c.Schema.CN))
+	}
+	return parents, nil
+}
+
 func (m *manager) getGroupFilter(gid *grouppb.GroupId) string {
 	b := bytes.Buffer{}
 	if err := m.groupfilter.Execute(&b, gid); err != nil {
@@ -386,6 +320,15 @@ func (m *manager) getMemberFilter(gid *grouppb.GroupId) string {
 	return b.String()
 }
 
+func (m *manager) getParentFilter(name string) string {
+	b := bytes.Buffer{}
+	if err := m.parentfilter.Execute(&b, struct{ Name string }{Name: ldap.EscapeFilter(name)}); err != nil {
+		err := errors.Wrap(err, fmt.Sprintf("error executing parent template: group: %s", name))
+		panic(err)
+	}
+	return b.String()
+}
+
 func (m *manager) getAttributeFilter(attribute, value string) string {
 	attr := strings.ReplaceAll(m.c.AttributeFilter, "{{attr}}", attribute)
 	return strings.ReplaceAll(attr, "{{value}}", value)
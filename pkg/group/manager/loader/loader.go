@@ -22,5 +22,6 @@ import (
 	// Load core group manager drivers.
 	_ "github.com/cs3org/reva/pkg/group/manager/json"
 	_ "github.com/cs3org/reva/pkg/group/manager/ldap"
+	_ "github.com/cs3org/reva/pkg/group/manager/sql"
 	// Add your own here
 )
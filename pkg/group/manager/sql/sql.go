@@ -0,0 +1,197 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a group manager reading the groups and their members
+// from a SQL database, MySQL, PostgreSQL or SQLite, shared with the sql user
+// manager.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/group"
+	"github.com/cs3org/reva/pkg/group/manager/registry"
+	"github.com/cs3org/reva/pkg/sqlidentity"
+	"github.com/cs3org/reva/pkg/sqlmigrate"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// columns are the columns a group is read from, in the order of scanGroup.
+const columns = "idp, group_id, group_name, mail, display_name, gid_number"
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	sqlidentity.Config `mapstructure:",squash"`
+	// SearchLimit is the maximum number of groups returned by a search.
+	SearchLimit int `mapstructure:"search_limit"`
+}
+
+func (c *config) init() {
+	if c.SearchLimit == 0 {
+		c.SearchLimit = 100
+	}
+}
+
+type manager struct {
+	c       *config
+	dialect sqlmigrate.Dialect
+	db      *sql.DB
+}
+
+// New returns a group manager reading the groups from a SQL database.
+func New(m map[string]interface{}) (group.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding conf")
+	}
+	c.init()
+
+	db, dialect, err := sqlidentity.Open(&c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &manager{c: c, dialect: dialect, db: db}, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGroup(s scanner) (*grouppb.Group, error) {
+	g := &grouppb.Group{Id: &grouppb.GroupId{}}
+	if err := s.Scan(&g.Id.Idp, &g.Id.OpaqueId, &g.GroupName, &g.Mail, &g.DisplayName, &g.GidNumber); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// getGroup returns the group matched by the condition, with its members.
+func (m *manager) getGroup(ctx context.Context, cond, notFound string, args ...interface{}) (*grouppb.Group, error) {
+	query := m.dialect.Rebind("SELECT " + columns + " FROM user_groups WHERE " + cond)
+	g, err := scanGroup(m.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, errtypes.NotFound(notFound)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error reading group")
+	}
+
+	if g.Members, err = m.getMembers(ctx, g.Id); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.Group, error) {
+	if gid.Idp == "" {
+		return m.getGroup(ctx, "group_id = ? OR group_name = ?", gid.OpaqueId, gid.OpaqueId, gid.OpaqueId)
+	}
+	return m.getGroup(ctx, "idp = ? AND (group_id = ? OR group_name = ?)", gid.OpaqueId, gid.Idp, gid.OpaqueId, gid.OpaqueId)
+}
+
+func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string) (*grouppb.Group, error) {
+	switch claim {
+	case "mail", "group_name", "display_name":
+		return m.getGroup(ctx, claim+" = ?", value, value)
+	case "groupid":
+		return m.getGroup(ctx, "group_id = ?", value, value)
+	case "gid_number":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errtypes.BadRequest("sql: invalid gid_number " + value)
+		}
+		return m.getGroup(ctx, "gid_number = ?", value, n)
+	}
+	return nil, errtypes.NotSupported("sql: invalid field " + claim)
+}
+
+// FindGroups returns the groups matching the query, without their members.
+func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Group, error) {
+	cond, args := sqlidentity.Contains(query, "group_name", "mail", "display_name", "group_id")
+	q := m.dialect.Rebind("SELECT " + columns + " FROM user_groups WHERE " + cond + " ORDER BY group_name LIMIT " + strconv.Itoa(m.c.SearchLimit))
+	rows, err := m.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error searching groups")
+	}
+	defer rows.Close()
+
+	groups := []*grouppb.Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "sql: error reading group")
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
+	g, err := m.GetGroup(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+	return g.Members, nil
+}
+
+func (m *manager) getMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
+	query := m.dialect.Rebind("SELECT user_idp, user_id FROM group_members WHERE group_idp = ? AND group_id = ? ORDER BY user_id")
+	rows, err := m.db.QueryContext(ctx, query, gid.Idp, gid.OpaqueId)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error reading the members of the group")
+	}
+	defer rows.Close()
+
+	members := []*userpb.UserId{}
+	for rows.Next() {
+		u := &userpb.UserId{}
+		if err := rows.Scan(&u.Idp, &u.OpaqueId); err != nil {
+			return nil, errors.Wrap(err, "sql: error reading the members of the group")
+		}
+		members = append(members, u)
+	}
+	return members, rows.Err()
+}
+
+// HasMember looks up the membership alone, without listing the members of
+// the group.
+func (m *manager) HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error) {
+	cond := "(g.group_id = ? OR g.group_name = ?)"
+	args := []interface{}{uid.OpaqueId, uid.Idp, gid.OpaqueId, gid.OpaqueId}
+	if gid.Idp != "" {
+		cond = "g.idp = ? AND " + cond
+		args = []interface{}{uid.OpaqueId, uid.Idp, gid.Idp, gid.OpaqueId, gid.OpaqueId}
+	}
+	query := m.dialect.Rebind(`SELECT COUNT(*) FROM group_members m
+		JOIN user_groups g ON g.idp = m.group_idp AND g.group_id = m.group_id
+		WHERE m.user_id = ? AND m.user_idp = ? AND ` + cond)
+	var n int
+	if err := m.db.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return false, errors.Wrap(err, "sql: error reading the membership")
+	}
+	return n > 0, nil
+}
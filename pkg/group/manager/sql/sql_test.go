@@ -0,0 +1,90 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+func TestGroups(t *testing.T) {
+	mgr, err := New(map[string]interface{}{
+		"db_driver": "sqlite3",
+		"db_dsn":    filepath.Join(t.TempDir(), "identity.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	ctx := context.Background()
+
+	for _, s := range []string{
+		"INSERT INTO user_groups (idp, group_id, group_name, mail, display_name, gid_number) VALUES ('idp', 'g1', 'physics-lovers', 'physics@example.org', 'Physics lovers', 101)",
+		"INSERT INTO user_groups (idp, group_id, group_name, mail, display_name, gid_number) VALUES ('idp', 'g2', 'sailing-lovers', 'sailing@example.org', 'Sailing lovers', 102)",
+		"INSERT INTO group_members (group_idp, group_id, user_idp, user_id) VALUES ('idp', 'g1', 'idp', 'einstein'), ('idp', 'g1', 'idp', 'marie'), ('idp', 'g2', 'idp', 'einstein')",
+	} {
+		if _, err := m.db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := m.GetGroup(ctx, &grouppb.GroupId{OpaqueId: "physics-lovers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Id.OpaqueId != "g1" || g.GidNumber != 101 || len(g.Members) != 2 || g.Members[0].OpaqueId != "einstein" {
+		t.Fatalf("unexpected group %v", g)
+	}
+
+	for claim, value := range map[string]string{"mail": "sailing@example.org", "group_name": "sailing-lovers", "groupid": "g2", "gid_number": "102", "display_name": "Sailing lovers"} {
+		g, err := m.GetGroupByClaim(ctx, claim, value)
+		if err != nil {
+			t.Fatalf("claim %s: %v", claim, err)
+		}
+		if g.GroupName != "sailing-lovers" || len(g.Members) != 1 {
+			t.Fatalf("claim %s: unexpected group %v", claim, g)
+		}
+	}
+
+	found, err := m.FindGroups(ctx, "LOVERS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].GroupName != "physics-lovers" {
+		t.Fatalf("unexpected groups %v", found)
+	}
+
+	marie := &userpb.UserId{Idp: "idp", OpaqueId: "marie"}
+	for gid, expected := range map[string]bool{"g1": true, "physics-lovers": true, "g2": false, "g3": false} {
+		ok, err := m.HasMember(ctx, &grouppb.GroupId{Idp: "idp", OpaqueId: gid}, marie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != expected {
+			t.Errorf("group %s: expected membership %v", gid, expected)
+		}
+	}
+	if _, err := m.GetMembers(ctx, &grouppb.GroupId{OpaqueId: "g3"}); err == nil {
+		t.Fatal("the members of unknown groups must not be listed")
+	}
+}
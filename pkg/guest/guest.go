@@ -0,0 +1,64 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package guest holds the guest accounts, the lightweight users created when
+// a resource is shared with a mail address unknown to the user provider. The
+// guests set their password through the link of the invitation mailed to
+// them and can only access the resources shared with them.
+package guest
+
+import (
+	"context"
+	"net/mail"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+// Manager stores the guest accounts and their credentials.
+type Manager interface {
+	// CreateGuest creates the guest with the given mail address, invited by
+	// inviter, and returns it with the token of its invitation.
+	CreateGuest(ctx context.Context, mail string, inviter *userpb.UserId) (*userpb.User, string, error)
+	// GetGuest returns the guest with the given id.
+	GetGuest(ctx context.Context, id *userpb.UserId) (*userpb.User, error)
+	// GetGuestByClaim returns the guest whose mail, username or userid has
+	// the given value.
+	GetGuestByClaim(ctx context.Context, claim, value string) (*userpb.User, error)
+	// Invite issues a new invitation for the guest, the previous one can't be
+	// accepted anymore.
+	Invite(ctx context.Context, id *userpb.UserId) (string, error)
+	// AcceptInvitation sets the password of the guest the invitation was
+	// issued for. An invitation can only be accepted once.
+	AcceptInvitation(ctx context.Context, token, password string) (*userpb.User, error)
+	// Authenticate returns the guest with the given username and password.
+	Authenticate(ctx context.Context, username, password string) (*userpb.User, error)
+	// DeleteGuest removes the guest.
+	DeleteGuest(ctx context.Context, id *userpb.UserId) error
+}
+
+// IsGuest tells whether the user is a guest.
+func IsGuest(u *userpb.User) bool {
+	return u.GetId().GetType() == userpb.UserType_USER_TYPE_GUEST
+}
+
+// IsMail tells whether s is a bare mail address, which can be invited as a
+// guest.
+func IsMail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
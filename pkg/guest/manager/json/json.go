@@ -0,0 +1,363 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package json provides a guest manager keeping the guests in a JSON file,
+// which can be shared by the services reading the guests on the same host.
+package json
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/guest"
+	"github.com/cs3org/reva/pkg/guest/manager/registry"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File          string `mapstructure:"file" docs:"/var/tmp/reva/guests.json;The file the guests are kept in."`
+	Idp           string `mapstructure:"idp" docs:";The idp of the guests."`
+	InvitationTTL int    `mapstructure:"invitation_ttl" docs:"168;How many hours an invitation can be accepted for."`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/guests.json"
+	}
+	if c.InvitationTTL == 0 {
+		c.InvitationTTL = 168
+	}
+}
+
+// account is a guest as stored in the file.
+type account struct {
+	User    *userpb.User   `json:"user"`
+	Inviter *userpb.UserId `json:"inviter,omitempty"`
+	// Password is the bcrypt hash of the password, empty until the
+	// invitation is accepted.
+	Password string `json:"password,omitempty"`
+	// Invitation is the sha256 hash of the pending invitation token.
+	Invitation        string `json:"invitation,omitempty"`
+	InvitationExpires int64  `json:"invitation_expires,omitempty"`
+}
+
+type manager struct {
+	c *config
+
+	mu       sync.Mutex
+	accounts []*account
+	// mtime and size of the file when it was last read
+	mtime time.Time
+	size  int64
+}
+
+// New returns a guest manager keeping the guests in a JSON file.
+func New(m map[string]interface{}) (guest.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "json: error decoding conf")
+	}
+	c.init()
+
+	mgr := &manager{c: c}
+	if err := mgr.refresh(); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// refresh reads the file again when another process changed it, the caller
+// holding mu.
+func (m *manager) refresh() error {
+	info, err := os.Stat(m.c.File)
+	switch {
+	case os.IsNotExist(err):
+		m.accounts = nil
+		return nil
+	case err != nil:
+		return errors.Wrap(err, "json: error reading guests")
+	case info.ModTime().Equal(m.mtime) && info.Size() == m.size:
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(m.c.File)
+	if err != nil {
+		return errors.Wrap(err, "json: error reading guests")
+	}
+	accounts := []*account{}
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return errors.Wrap(err, "json: error decoding guests")
+	}
+	m.accounts, m.mtime, m.size = accounts, info.ModTime(), info.Size()
+	return nil
+}
+
+// write replaces the file, readers never see a partially written file.
+func (m *manager) write(accounts []*account) error {
+	data, err := json.MarshalIndent(accounts, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json: error encoding guests")
+	}
+	if err := os.MkdirAll(filepath.Dir(m.c.File), 0700); err != nil {
+		return errors.Wrap(err, "json: error writing guests")
+	}
+	tmp := m.c.File + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "json: error writing guests")
+	}
+	if err := os.Rename(tmp, m.c.File); err != nil {
+		return errors.Wrap(err, "json: error writing guests")
+	}
+	if info, err := os.Stat(m.c.File); err == nil {
+		m.mtime, m.size = info.ModTime(), info.Size()
+	}
+	m.accounts = accounts
+	return nil
+}
+
+// update applies f to a copy of the account at index i and writes it back.
+func (m *manager) update(i int, f func(a *account)) (*account, error) {
+	a := *m.accounts[i]
+	a.User = proto.Clone(a.User).(*userpb.User)
+	f(&a)
+
+	accounts := make([]*account, len(m.accounts))
+	copy(accounts, m.accounts)
+	accounts[i] = &a
+	if err := m.write(accounts); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (m *manager) find(match func(a *account) bool) int {
+	for i, a := range m.accounts {
+		if match(a) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *manager) byID(id *userpb.UserId) int {
+	return m.find(func(a *account) bool {
+		return a.User.Id.GetOpaqueId() == id.GetOpaqueId() && (id.GetIdp() == "" || id.GetIdp() == a.User.Id.GetIdp())
+	})
+}
+
+func hash(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "json: error generating invitation")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (m *manager) invite(a *account) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	a.Invitation = hash(token)
+	a.InvitationExpires = time.Now().Add(time.Duration(m.c.InvitationTTL) * time.Hour).Unix()
+	return token, nil
+}
+
+func (m *manager) CreateGuest(ctx context.Context, mail string, inviter *userpb.UserId) (*userpb.User, string, error) {
+	if !guest.IsMail(mail) {
+		return nil, "", errtypes.BadRequest("json: not a mail address: " + mail)
+	}
+	mail = strings.ToLower(mail)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return nil, "", err
+	}
+	if m.find(func(a *account) bool { return a.User.Mail == mail }) >= 0 {
+		return nil, "", errtypes.AlreadyExists(mail)
+	}
+
+	a := &account{
+		User: &userpb.User{
+			Id: &userpb.UserId{
+				Idp:      m.c.Idp,
+				OpaqueId: uuid.New().String(),
+				Type:     userpb.UserType_USER_TYPE_GUEST,
+			},
+			Username:    mail,
+			Mail:        mail,
+			DisplayName: mail,
+		},
+		Inviter: inviter,
+	}
+	token, err := m.invite(a)
+	if err != nil {
+		return nil, "", err
+	}
+
+	accounts := make([]*account, len(m.accounts), len(m.accounts)+1)
+	copy(accounts, m.accounts)
+	if err := m.write(append(accounts, a)); err != nil {
+		return nil, "", err
+	}
+	return a.User, token, nil
+}
+
+func (m *manager) GetGuest(ctx context.Context, id *userpb.UserId) (*userpb.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return nil, err
+	}
+	i := m.byID(id)
+	if i < 0 {
+		return nil, errtypes.NotFound(id.GetOpaqueId())
+	}
+	return m.accounts[i].User, nil
+}
+
+func (m *manager) GetGuestByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
+	var match func(a *account) bool
+	switch claim {
+	case "mail", "username":
+		// the username of the guests is their mail address
+		value = strings.ToLower(value)
+		match = func(a *account) bool { return a.User.Mail == value }
+	case "userid":
+		match = func(a *account) bool { return a.User.Id.GetOpaqueId() == value }
+	default:
+		return nil, errtypes.NotSupported("json: invalid claim " + claim)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return nil, err
+	}
+	i := m.find(match)
+	if i < 0 {
+		return nil, errtypes.NotFound(value)
+	}
+	return m.accounts[i].User, nil
+}
+
+func (m *manager) Invite(ctx context.Context, id *userpb.UserId) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return "", err
+	}
+	i := m.byID(id)
+	if i < 0 {
+		return "", errtypes.NotFound(id.GetOpaqueId())
+	}
+
+	var token string
+	var err error
+	if _, werr := m.update(i, func(a *account) { token, err = m.invite(a) }); werr != nil {
+		return "", werr
+	}
+	return token, err
+}
+
+func (m *manager) AcceptInvitation(ctx context.Context, token, password string) (*userpb.User, error) {
+	if password == "" {
+		return nil, errtypes.BadRequest("json: the password can't be empty")
+	}
+	pwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.Wrap(err, "json: error hashing password")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return nil, err
+	}
+	h := hash(token)
+	i := m.find(func(a *account) bool { return token != "" && a.Invitation == h })
+	if i < 0 || time.Now().Unix() > m.accounts[i].InvitationExpires {
+		return nil, errtypes.NotFound("json: invitation not found or expired")
+	}
+
+	a, err := m.update(i, func(a *account) {
+		a.Password = string(pwd)
+		a.Invitation, a.InvitationExpires = "", 0
+		a.User.MailVerified = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.User, nil
+}
+
+func (m *manager) Authenticate(ctx context.Context, username, password string) (*userpb.User, error) {
+	username = strings.ToLower(username)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return nil, err
+	}
+	i := m.find(func(a *account) bool { return a.User.Username == username })
+	if i < 0 || m.accounts[i].Password == "" ||
+		bcrypt.CompareHashAndPassword([]byte(m.accounts[i].Password), []byte(password)) != nil {
+		return nil, errtypes.InvalidCredentials(username)
+	}
+	return m.accounts[i].User, nil
+}
+
+func (m *manager) DeleteGuest(ctx context.Context, id *userpb.UserId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return err
+	}
+	i := m.byID(id)
+	if i < 0 {
+		return errtypes.NotFound(id.GetOpaqueId())
+	}
+	accounts := make([]*account, 0, len(m.accounts)-1)
+	accounts = append(accounts, m.accounts[:i]...)
+	return m.write(append(accounts, m.accounts[i+1:]...))
+}
@@ -0,0 +1,137 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/guest"
+)
+
+var ctx = context.Background()
+
+func TestGuests(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "guests_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+	conf := map[string]interface{}{"file": filepath.Join(tempdir, "guests", "guests.json"), "idp": "guests"}
+
+	m, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inviter := &userpb.UserId{Idp: "example.org", OpaqueId: "einstein"}
+
+	if _, _, err := m.CreateGuest(ctx, "Marie <marie@example.org>", inviter); err == nil {
+		t.Fatal("expected a bad request for a mail with a display name")
+	}
+	g, token, err := m.CreateGuest(ctx, "Marie@example.org", inviter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !guest.IsGuest(g) || g.Username != "marie@example.org" || g.Id.Idp != "guests" || token == "" {
+		t.Fatalf("unexpected guest %v with invitation %q", g, token)
+	}
+	if _, _, err := m.CreateGuest(ctx, "marie@example.org", inviter); !isAlreadyExists(err) {
+		t.Fatalf("expected the guest to exist already, got %v", err)
+	}
+
+	// the guest can't log in before accepting the invitation
+	if _, err := m.Authenticate(ctx, "marie@example.org", ""); err == nil {
+		t.Fatal("expected the login without password to fail")
+	}
+
+	// a new invitation replaces the old one
+	renewed, err := m.Invite(ctx, g.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.AcceptInvitation(ctx, token, "secret"); !isNotFound(err) {
+		t.Fatalf("expected the replaced invitation to be refused, got %v", err)
+	}
+
+	// the file is shared with the other services
+	other, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := other.AcceptInvitation(ctx, renewed, "secret")
+	if err != nil || !accepted.MailVerified {
+		t.Fatalf("unexpected accepted guest %v (%v)", accepted, err)
+	}
+	if _, err := m.AcceptInvitation(ctx, renewed, "other"); !isNotFound(err) {
+		t.Fatalf("expected the invitation to be accepted only once, got %v", err)
+	}
+
+	if _, err := m.Authenticate(ctx, "MARIE@example.org", "wrong"); err == nil {
+		t.Fatal("expected the wrong password to be refused")
+	}
+	u, err := m.Authenticate(ctx, "MARIE@example.org", "secret")
+	if err != nil || u.Id.OpaqueId != g.Id.OpaqueId {
+		t.Fatalf("unexpected authenticated guest %v (%v)", u, err)
+	}
+
+	if u, err := m.GetGuestByClaim(ctx, "userid", g.Id.OpaqueId); err != nil || u.Mail != "marie@example.org" {
+		t.Fatalf("unexpected guest %v (%v)", u, err)
+	}
+	if err := m.DeleteGuest(ctx, g.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.GetGuest(ctx, g.Id); !isNotFound(err) {
+		t.Fatalf("expected the guest to be deleted, got %v", err)
+	}
+}
+
+func TestExpiredInvitation(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "guests_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	m, err := New(map[string]interface{}{"file": filepath.Join(tempdir, "guests.json"), "invitation_ttl": -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, token, err := m.CreateGuest(ctx, "marie@example.org", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.AcceptInvitation(ctx, token, "secret"); !isNotFound(err) {
+		t.Fatalf("expected the expired invitation to be refused, got %v", err)
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
+
+func isAlreadyExists(err error) bool {
+	_, ok := err.(errtypes.IsAlreadyExists)
+	return ok
+}
@@ -0,0 +1,168 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package health holds the checks of the dependencies a process needs to
+// serve its requests, e.g. the databases or the LDAP servers of its drivers.
+// They are run on each readiness probe, unlike the ops probes which report
+// what the subsystems already know.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check or of the probe.
+type Status string
+
+const (
+	// OK tells the dependency is reachable.
+	OK Status = "ok"
+	// Failing tells the dependency can't be used.
+	Failing Status = "failing"
+)
+
+// Check tells whether a dependency can be used. It must give up when the
+// context is done.
+type Check func(ctx context.Context) error
+
+// Checker is implemented by the drivers which can check their backend.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+var (
+	mu     sync.RWMutex
+	checks = map[string]Check{}
+)
+
+// Register registers the check under the name, e.g. share/sql, replacing the
+// check previously registered under the same name.
+func Register(name string, c Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = c
+}
+
+// Unregister removes the check registered under the name.
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(checks, name)
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Status     Status `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of a probe.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Live reports whether the process is alive, which it is as long as it can
+// answer: the dependencies are not checked, a process shouldn't be restarted
+// because one of them is down.
+func Live() *Report {
+	return &Report{Status: OK}
+}
+
+// Ready runs the checks concurrently, but the excluded ones, each given at
+// most the timeout. The process is ready when all the checks pass.
+func Ready(ctx context.Context, timeout time.Duration, exclude ...string) *Report {
+	excluded := map[string]bool{}
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	// the checks are run without holding the lock, so that they may (un)register
+	mu.RLock()
+	registered := map[string]Check{}
+	for name, c := range checks {
+		if !excluded[name] {
+			registered[name] = c
+		}
+	}
+	mu.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		resMu   sync.Mutex
+		results = make(map[string]Result, len(registered))
+	)
+	for name, c := range registered {
+		wg.Add(1)
+		go func(name string, c Check) {
+			defer wg.Done()
+			res := run(ctx, timeout, c)
+			resMu.Lock()
+			results[name] = res
+			resMu.Unlock()
+		}(name, c)
+	}
+	wg.Wait()
+
+	r := &Report{Status: OK, Checks: results}
+	for _, res := range results {
+		if res.Status != OK {
+			r.Status = Failing
+		}
+	}
+	return r
+}
+
+// Failed returns the names of the failed checks, sorted.
+func (r *Report) Failed() []string {
+	var names []string
+	for name, res := range r.Checks {
+		if res.Status != OK {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func run(ctx context.Context, timeout time.Duration, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- c(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		// a check ignoring the context doesn't hold the probe
+		err = ctx.Err()
+	}
+
+	res := Result{Status: OK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status = Failing
+		res.Error = err.Error()
+	}
+	return res
+}
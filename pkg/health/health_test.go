@@ -0,0 +1,72 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	Register("test/ok", func(ctx context.Context) error { return nil })
+	Register("test/down", func(ctx context.Context) error { return errors.New("connection refused") })
+	Register("test/hung", func(ctx context.Context) error {
+		// ignores the context, must not hold the probe
+		time.Sleep(time.Second)
+		return nil
+	})
+	defer func() {
+		for _, name := range []string{"test/ok", "test/down", "test/hung"} {
+			Unregister(name)
+		}
+	}()
+
+	r := Ready(context.Background(), 50*time.Millisecond)
+	if r.Status != Failing {
+		t.Errorf("status = %s, want %s", r.Status, Failing)
+	}
+	if res := r.Checks["test/ok"]; res.Status != OK || res.Error != "" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if res := r.Checks["test/down"]; res.Status != Failing || res.Error != "connection refused" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if res := r.Checks["test/hung"]; res.Status != Failing || res.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if failed := r.Failed(); len(failed) != 2 || failed[0] != "test/down" || failed[1] != "test/hung" {
+		t.Errorf("failed = %v", failed)
+	}
+
+	r = Ready(context.Background(), 50*time.Millisecond, "test/down", "test/hung")
+	if r.Status != OK || len(r.Checks) != 1 {
+		t.Errorf("excluded checks still run: %+v", r)
+	}
+}
+
+func TestLive(t *testing.T) {
+	Register("test/down", func(ctx context.Context) error { return errors.New("down") })
+	defer Unregister("test/down")
+
+	if r := Live(); r.Status != OK || len(r.Checks) != 0 {
+		t.Errorf("unexpected report %+v", r)
+	}
+}
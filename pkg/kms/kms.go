@@ -0,0 +1,40 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package kms defines the key management services providing the keys
+// the storage content is encrypted with.
+package kms
+
+import "context"
+
+// Key is a key encryption key of a space.
+type Key struct {
+	// ID identifies the key, including its version, among all keys of the KMS.
+	ID string
+	// Material is the 256 bit key.
+	Material []byte
+}
+
+// KMS provides the key encryption keys of the spaces.
+type KMS interface {
+	// GetKey returns the current key of the space, creating it if needed.
+	GetKey(ctx context.Context, space string) (*Key, error)
+	// GetKeyByID returns a key previously returned by GetKey, even if it
+	// is no longer the current key of its space.
+	GetKeyByID(ctx context.Context, id string) (*Key, error)
+}
@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package static provides a key management service reading the keys from a
+// file. Spaces without a key of their own get a key derived from the master key.
+package static
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/kms"
+	"github.com/cs3org/reva/pkg/kms/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("static", New)
+}
+
+type config struct {
+	// File holds the keys as {"master": "<base64>", "spaces": {"<space>": "<base64>"}}.
+	File string `mapstructure:"file"`
+	// MasterKey is the base64 encoded master key, it takes precedence over the one in the file.
+	MasterKey string `mapstructure:"master_key"`
+}
+
+type keysFile struct {
+	Master string            `json:"master"`
+	Spaces map[string]string `json:"spaces"`
+}
+
+type manager struct {
+	master []byte
+	spaces map[string][]byte
+}
+
+// New returns a key management service with static keys.
+func New(m map[string]interface{}) (kms.KMS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "static: error decoding conf")
+	}
+
+	f := &keysFile{}
+	if c.File != "" {
+		data, err := ioutil.ReadFile(c.File)
+		if err != nil {
+			return nil, errors.Wrap(err, "static: error reading keys file")
+		}
+		if err := json.Unmarshal(data, f); err != nil {
+			return nil, errors.Wrap(err, "static: error decoding keys file")
+		}
+	}
+	if c.MasterKey != "" {
+		f.Master = c.MasterKey
+	}
+
+	mgr := &manager{spaces: make(map[string][]byte, len(f.Spaces))}
+	if f.Master != "" {
+		k, err := decodeKey(f.Master)
+		if err != nil {
+			return nil, errors.Wrap(err, "static: invalid master key")
+		}
+		mgr.master = k
+	}
+	for space, v := range f.Spaces {
+		k, err := decodeKey(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "static: invalid key of space "+space)
+		}
+		mgr.spaces[space] = k
+	}
+	if mgr.master == nil && len(mgr.spaces) == 0 {
+		return nil, errors.New("static: no keys configured")
+	}
+	return mgr, nil
+}
+
+func decodeKey(s string) ([]byte, error) {
+	k, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(k) != 32 {
+		return nil, errors.New("keys must be 32 bytes long")
+	}
+	return k, nil
+}
+
+func (m *manager) GetKey(ctx context.Context, space string) (*kms.Key, error) {
+	if k, ok := m.spaces[space]; ok {
+		return &kms.Key{ID: "space/" + space, Material: k}, nil
+	}
+	if m.master == nil {
+		return nil, errtypes.NotFound("static: no key for space " + space)
+	}
+	mac := hmac.New(sha256.New, m.master)
+	_, _ = mac.Write([]byte(space))
+	return &kms.Key{ID: "master/" + space, Material: mac.Sum(nil)}, nil
+}
+
+func (m *manager) GetKeyByID(ctx context.Context, id string) (*kms.Key, error) {
+	switch {
+	case strings.HasPrefix(id, "space/"):
+		k, ok := m.spaces[strings.TrimPrefix(id, "space/")]
+		if !ok {
+			return nil, errtypes.NotFound("static: key " + id)
+		}
+		return &kms.Key{ID: id, Material: k}, nil
+	case strings.HasPrefix(id, "master/") && m.master != nil:
+		mac := hmac.New(sha256.New, m.master)
+		_, _ = mac.Write([]byte(strings.TrimPrefix(id, "master/")))
+		return &kms.Key{ID: id, Material: mac.Sum(nil)}, nil
+	}
+	return nil, errtypes.NotFound("static: key " + id)
+}
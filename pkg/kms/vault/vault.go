@@ -0,0 +1,256 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package vault provides a key management service keeping the keys of the
+// spaces in the key/value secrets engine (version 2) of HashiCorp Vault.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/kms"
+	"github.com/cs3org/reva/pkg/kms/registry"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("vault", New)
+}
+
+type config struct {
+	Address  string `mapstructure:"address"`
+	Token    string `mapstructure:"token"`
+	Mount    string `mapstructure:"mount"`
+	Prefix   string `mapstructure:"prefix"`
+	Insecure bool   `mapstructure:"insecure"`
+	Timeout  int    `mapstructure:"timeout"`
+	// CacheTTL is the time in seconds the current key of a space is cached.
+	CacheTTL int `mapstructure:"cache_ttl"`
+}
+
+func (c *config) init() {
+	if c.Address == "" {
+		c.Address = "http://localhost:8200"
+	}
+	if c.Mount == "" {
+		c.Mount = "secret"
+	}
+	if c.Prefix == "" {
+		c.Prefix = "reva/spaces"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10
+	}
+	if c.CacheTTL == 0 {
+		c.CacheTTL = 300
+	}
+}
+
+type cached struct {
+	key     *kms.Key
+	expires time.Time
+}
+
+type manager struct {
+	c      *config
+	client *http.Client
+
+	mu      sync.Mutex
+	current map[string]cached
+	// key versions never change, they are kept forever
+	versions map[string]*kms.Key
+}
+
+// New returns a key management service backed by Vault.
+func New(m map[string]interface{}) (kms.KMS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "vault: error decoding conf")
+	}
+	c.init()
+	if c.Token == "" {
+		return nil, errors.New("vault: token not configured")
+	}
+
+	return &manager{
+		c:        c,
+		client:   rhttp.GetHTTPClient(rhttp.Timeout(time.Duration(c.Timeout)*time.Second), rhttp.Insecure(c.Insecure)),
+		current:  map[string]cached{},
+		versions: map[string]*kms.Key{},
+	}, nil
+}
+
+type secretData struct {
+	Key string `json:"key"`
+}
+
+type secretResponse struct {
+	Data struct {
+		Data     secretData `json:"data"`
+		Metadata struct {
+			Version int `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+type writeResponse struct {
+	Data struct {
+		Version int `json:"version"`
+	} `json:"data"`
+}
+
+func (m *manager) url(space string, version int) string {
+	u := fmt.Sprintf("%s/v1/%s/data/%s/%s", strings.TrimSuffix(m.c.Address, "/"), m.c.Mount, m.c.Prefix, url.PathEscape(space))
+	if version > 0 {
+		u += "?version=" + strconv.Itoa(version)
+	}
+	return u
+}
+
+func (m *manager) do(ctx context.Context, method, u string, body interface{}, res interface{}) (int, error) {
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, &b)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Vault-Token", m.c.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "vault: error sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return resp.StatusCode, errors.Wrap(err, "vault: error decoding response")
+	}
+	return resp.StatusCode, nil
+}
+
+func (m *manager) read(ctx context.Context, space string, version int) (*kms.Key, error) {
+	res := &secretResponse{}
+	code, err := m.do(ctx, http.MethodGet, m.url(space, version), nil, res)
+	switch {
+	case err != nil:
+		return nil, err
+	case code == http.StatusNotFound:
+		return nil, errtypes.NotFound("vault: key of space " + space)
+	case code != http.StatusOK:
+		return nil, fmt.Errorf("vault: unexpected status %d reading key of space %s", code, space)
+	}
+	k, err := base64.StdEncoding.DecodeString(res.Data.Data.Key)
+	if err != nil || len(k) != 32 {
+		return nil, fmt.Errorf("vault: invalid key of space %s", space)
+	}
+	return &kms.Key{ID: space + "@" + strconv.Itoa(res.Data.Metadata.Version), Material: k}, nil
+}
+
+// create stores a new key for the space, failing if another one has been created meanwhile.
+func (m *manager) create(ctx context.Context, space string) (*kms.Key, error) {
+	k := make([]byte, 32)
+	if _, err := rand.Read(k); err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"options": map[string]interface{}{"cas": 0},
+		"data":    secretData{Key: base64.StdEncoding.EncodeToString(k)},
+	}
+	res := &writeResponse{}
+	code, err := m.do(ctx, http.MethodPost, m.url(space, 0), body, res)
+	switch {
+	case err != nil:
+		return nil, err
+	case code == http.StatusBadRequest:
+		// check-and-set failed, the key exists
+		return m.read(ctx, space, 0)
+	case code != http.StatusOK:
+		return nil, fmt.Errorf("vault: unexpected status %d creating key of space %s", code, space)
+	}
+	return &kms.Key{ID: space + "@" + strconv.Itoa(res.Data.Version), Material: k}, nil
+}
+
+func (m *manager) GetKey(ctx context.Context, space string) (*kms.Key, error) {
+	m.mu.Lock()
+	c, ok := m.current[space]
+	m.mu.Unlock()
+	if ok && time.Now().Before(c.expires) {
+		return c.key, nil
+	}
+
+	k, err := m.read(ctx, space, 0)
+	if _, notFound := err.(errtypes.IsNotFound); notFound {
+		k, err = m.create(ctx, space)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.current[space] = cached{key: k, expires: time.Now().Add(time.Duration(m.c.CacheTTL) * time.Second)}
+	m.versions[k.ID] = k
+	m.mu.Unlock()
+	return k, nil
+}
+
+func (m *manager) GetKeyByID(ctx context.Context, id string) (*kms.Key, error) {
+	m.mu.Lock()
+	k, ok := m.versions[id]
+	m.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+
+	i := strings.LastIndex(id, "@")
+	if i < 0 {
+		return nil, errtypes.BadRequest("vault: invalid key id " + id)
+	}
+	version, err := strconv.Atoi(id[i+1:])
+	if err != nil || version <= 0 {
+		return nil, errtypes.BadRequest("vault: invalid key id " + id)
+	}
+	if k, err = m.read(ctx, id[:i], version); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.versions[id] = k
+	m.mu.Unlock()
+	return k, nil
+}
@@ -59,6 +59,7 @@ type Configuration struct {
 			Endpoint          string   `mapstructure:"endpoint"`
 			EnabledConnectors []string `mapstructure:"enabled_connectors"`
 			IsProtected       bool     `mapstructure:"is_protected"`
+			SigningKeyFile    string   `mapstructure:"signing_key_file"`
 		} `mapstructure:"cs3api"`
 
 		SiteLocations struct {
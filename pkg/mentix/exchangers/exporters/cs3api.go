@@ -19,10 +19,12 @@
 package exporters
 
 import (
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 
 	"github.com/cs3org/reva/pkg/mentix/config"
 	"github.com/cs3org/reva/pkg/mentix/exchangers/exporters/cs3api"
+	"github.com/cs3org/reva/pkg/mentix/key"
 )
 
 // CS3APIExporter implements the CS3API exporter.
@@ -40,6 +42,15 @@ func (exporter *CS3APIExporter) Activate(conf *config.Configuration, log *zerolo
 	exporter.SetEndpoint(conf.Exporters.CS3API.Endpoint, conf.Exporters.CS3API.IsProtected)
 	exporter.SetEnabledConnectors(conf.Exporters.CS3API.EnabledConnectors)
 
+	// Sign the exported data for the clients to verify it
+	if conf.Exporters.CS3API.SigningKeyFile != "" {
+		privKey, err := key.LoadSigningKey(conf.Exporters.CS3API.SigningKeyFile)
+		if err != nil {
+			return errors.Wrap(err, "unable to load the CS3API signing key")
+		}
+		exporter.SetSigningKey(privKey)
+	}
+
 	exporter.RegisterActionHandler("", cs3api.HandleDefaultQuery)
 
 	return nil
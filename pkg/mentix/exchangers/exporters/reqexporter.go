@@ -19,6 +19,7 @@
 package exporters
 
 import (
+	"crypto/ed25519"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -27,12 +28,20 @@ import (
 
 	"github.com/cs3org/reva/pkg/mentix/config"
 	"github.com/cs3org/reva/pkg/mentix/exchangers"
+	"github.com/cs3org/reva/pkg/mentix/key"
 )
 
 // BaseRequestExporter implements basic exporter functionality common to all request exporters.
 type BaseRequestExporter struct {
 	BaseExporter
 	exchangers.BaseRequestExchanger
+
+	signingKey ed25519.PrivateKey
+}
+
+// SetSigningKey sets the key the successful responses are signed with.
+func (exporter *BaseRequestExporter) SetSigningKey(privKey ed25519.PrivateKey) {
+	exporter.signingKey = privKey
 }
 
 // HandleRequest handles the actual HTTP request.
@@ -41,6 +50,8 @@ func (exporter *BaseRequestExporter) HandleRequest(resp http.ResponseWriter, req
 	status, respData, err := exporter.handleQuery(body, req.URL.Query(), conf, log)
 	if err != nil {
 		respData = []byte(err.Error())
+	} else if exporter.signingKey != nil {
+		resp.Header().Set(key.SignatureHeader, key.Sign(exporter.signingKey, respData))
 	}
 	resp.WriteHeader(status)
 	_, _ = resp.Write(respData)
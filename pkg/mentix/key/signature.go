@@ -0,0 +1,101 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package key
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SignatureHeader is the header of the responses of Mentix holding the
+// signature of their body, for the clients to check the data they fetch.
+const SignatureHeader = "X-Mentix-Signature"
+
+// LoadSigningKey reads the Ed25519 private key, PEM encoded in PKCS #8, from
+// the given file.
+func LoadSigningKey(file string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the signing key")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in %v", file)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse the signing key")
+	}
+	privKey, ok := k.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("the signing key in %v is not an Ed25519 key", file)
+	}
+	return privKey, nil
+}
+
+// ParseVerificationKey parses an Ed25519 public key, either PEM encoded in
+// PKIX or as its base64 encoded bytes.
+func ParseVerificationKey(s string) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse the verification key")
+		}
+		pubKey, ok := k.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("the verification key is not an Ed25519 key")
+		}
+		return pubKey, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode the verification key")
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("invalid verification key size %v", len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// Sign returns the base64 encoded signature of the data.
+func Sign(privKey ed25519.PrivateKey, data []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, data))
+}
+
+// Verify checks that the base64 encoded signature is the one of the data.
+func Verify(pubKey ed25519.PublicKey, data []byte, signature string) error {
+	if signature == "" {
+		return errors.Errorf("the data is not signed")
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "unable to decode the signature")
+	}
+	if !ed25519.Verify(pubKey, data, sig) {
+		return errors.Errorf("invalid signature")
+	}
+	return nil
+}
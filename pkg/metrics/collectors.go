@@ -0,0 +1,153 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/metric/metricproducer"
+)
+
+// Kind is the type of the metric of a sample.
+type Kind int
+
+const (
+	// Gauge is a value going up and down, e.g. the upload sessions in progress.
+	Gauge Kind = iota
+	// Counter is a count only going up, e.g. of the hits of a cache.
+	Counter
+)
+
+// Sample is the current value of a metric contributed by a driver.
+type Sample struct {
+	// Name is the name of the metric, which the exporter prefixes with its
+	// namespace, e.g. storage_upload_sessions is scraped as
+	// revad_storage_upload_sessions.
+	Name string
+	Help string
+	Kind Kind
+	// Labels tell the samples of a metric apart, e.g. by driver. The samples
+	// of a metric all have the same label keys.
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector returns the samples of the metrics of a driver. It is called on
+// every scrape, so it must not block but report what the driver already knows.
+type Collector func() []Sample
+
+var (
+	mu         sync.RWMutex
+	collectors = map[string]Collector{}
+	start      = time.Now()
+)
+
+func init() {
+	// the exporters read the metrics of all the producers besides the views
+	metricproducer.GlobalManager().AddProducer(producer{})
+}
+
+// RegisterCollector registers the collector under the name, e.g.
+// share/sql, replacing the collector previously registered under it.
+func RegisterCollector(name string, c Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors[name] = c
+}
+
+// UnregisterCollector removes the collector registered under the name.
+func UnregisterCollector(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(collectors, name)
+}
+
+// producer exports the samples of the collectors as OpenCensus metrics.
+type producer struct{}
+
+// Read implements metricproducer.Producer.
+func (producer) Read() []*metricdata.Metric {
+	mu.RLock()
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	registered := make([]Collector, 0, len(names))
+	for _, name := range names {
+		registered = append(registered, collectors[name])
+	}
+	mu.RUnlock()
+
+	now := time.Now()
+	var ms []*metricdata.Metric
+	byName := map[string]*metricdata.Metric{}
+	for _, c := range registered {
+		for _, s := range c() {
+			m, ok := byName[s.Name]
+			if !ok {
+				m = newMetric(s)
+				byName[s.Name] = m
+				ms = append(ms, m)
+			}
+			ts := &metricdata.TimeSeries{
+				LabelValues: make([]metricdata.LabelValue, len(m.Descriptor.LabelKeys)),
+				Points:      []metricdata.Point{metricdata.NewFloat64Point(now, s.Value)},
+				StartTime:   start,
+			}
+			for i, k := range m.Descriptor.LabelKeys {
+				if v, ok := s.Labels[k.Key]; ok {
+					ts.LabelValues[i] = metricdata.NewLabelValue(v)
+				}
+			}
+			m.TimeSeries = append(m.TimeSeries, ts)
+		}
+	}
+	return ms
+}
+
+// newMetric returns the metric of the sample, without time series.
+func newMetric(s Sample) *metricdata.Metric {
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	labelKeys := make([]metricdata.LabelKey, len(keys))
+	for i, k := range keys {
+		labelKeys[i] = metricdata.LabelKey{Key: k}
+	}
+
+	t := metricdata.TypeGaugeFloat64
+	if s.Kind == Counter {
+		t = metricdata.TypeCumulativeFloat64
+	}
+	return &metricdata.Metric{
+		Descriptor: metricdata.Descriptor{
+			Name:        s.Name,
+			Description: s.Help,
+			Unit:        metricdata.UnitDimensionless,
+			Type:        t,
+			LabelKeys:   labelKeys,
+		},
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package metrics
+
+import (
+	"testing"
+
+	"go.opencensus.io/metric/metricdata"
+)
+
+func TestProducerRead(t *testing.T) {
+	RegisterCollector("test/a", func() []Sample {
+		return []Sample{
+			{Name: "test_sessions", Labels: map[string]string{"driver": "a", "root": "/a"}, Value: 2},
+			{Name: "test_hits_total", Kind: Counter, Labels: map[string]string{"cache": "stat"}, Value: 5},
+		}
+	})
+	RegisterCollector("test/b", func() []Sample {
+		return []Sample{{Name: "test_sessions", Labels: map[string]string{"root": "/b", "driver": "b"}, Value: 3}}
+	})
+	defer UnregisterCollector("test/a")
+	defer UnregisterCollector("test/b")
+
+	byName := map[string]*metricdata.Metric{}
+	for _, m := range (producer{}).Read() {
+		byName[m.Descriptor.Name] = m
+	}
+
+	sessions, ok := byName["test_sessions"]
+	if !ok {
+		t.Fatal("test_sessions not exported")
+	}
+	if sessions.Descriptor.Type != metricdata.TypeGaugeFloat64 {
+		t.Errorf("test_sessions type = %v, want gauge", sessions.Descriptor.Type)
+	}
+	if len(sessions.Descriptor.LabelKeys) != 2 || sessions.Descriptor.LabelKeys[0].Key != "driver" || sessions.Descriptor.LabelKeys[1].Key != "root" {
+		t.Fatalf("test_sessions label keys = %v, want [driver root]", sessions.Descriptor.LabelKeys)
+	}
+	if len(sessions.TimeSeries) != 2 {
+		t.Fatalf("test_sessions has %d time series, want 2", len(sessions.TimeSeries))
+	}
+	for i, want := range []struct {
+		driver, root string
+		value        float64
+	}{{"a", "/a", 2}, {"b", "/b", 3}} {
+		ts := sessions.TimeSeries[i]
+		if ts.LabelValues[0].Value != want.driver || ts.LabelValues[1].Value != want.root {
+			t.Errorf("time series %d labels = %v, want %s %s", i, ts.LabelValues, want.driver, want.root)
+		}
+		if v := ts.Points[0].Value.(float64); v != want.value {
+			t.Errorf("time series %d value = %v, want %v", i, v, want.value)
+		}
+	}
+
+	if hits := byName["test_hits_total"]; hits == nil || hits.Descriptor.Type != metricdata.TypeCumulativeFloat64 {
+		t.Errorf("test_hits_total not exported as a counter: %v", hits)
+	}
+
+	UnregisterCollector("test/a")
+	for _, m := range (producer{}).Read() {
+		if m.Descriptor.Name == "test_hits_total" {
+			t.Error("test_hits_total still exported after unregistering its collector")
+		}
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package mtls holds the certificate of the process, used both to serve and to
+// call the other reva services over mutual TLS. The certificates are read from
+// files, either given explicitly or written by the SPIFFE helper, and reloaded
+// when the files change, so that they can be rotated without a restart: the
+// peers are verified against the current CA bundle on each handshake.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// File names the SPIFFE helper writes the X509-SVID to.
+const (
+	svidFile   = "svid.pem"
+	keyFile    = "svid_key.pem"
+	bundleFile = "svid_bundle.pem"
+)
+
+// Config configures the certificate of the process.
+type Config struct {
+	// Source is file, the certificate being read from CertFile, KeyFile and
+	// CAFile, or spiffe, the X509-SVID being read from the files the SPIFFE
+	// helper writes to Dir.
+	Source   string `mapstructure:"source"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	CAFile   string `mapstructure:"ca_file"`
+	Dir      string `mapstructure:"dir"`
+	// TrustDomain is the SPIFFE trust domain the peers must belong to, by
+	// default the one of the process.
+	TrustDomain string `mapstructure:"trust_domain"`
+	// AllowedIDs are the identities of the clients allowed to call the
+	// services of the process, their SPIFFE ID or, with the file source, the
+	// common name or a DNS or URI name of their certificate. Any client
+	// certificate issued by the CA is allowed by default.
+	AllowedIDs []string `mapstructure:"allowed_ids"`
+	// ReloadInterval is the time in seconds after which the files are checked
+	// for changes.
+	ReloadInterval int `mapstructure:"reload_interval"`
+	// Endpoints are the grpc endpoints, addresses or names in the service
+	// registry, the process calls over mutual TLS. The other ones are called
+	// in plaintext, so that the servers can enable mutual TLS one at a time.
+	Endpoints []string `mapstructure:"endpoints"`
+}
+
+func (c *Config) init() {
+	if c.ReloadInterval == 0 {
+		c.ReloadInterval = 30
+	}
+	if c.Source == "spiffe" {
+		c.CertFile = filepath.Join(c.Dir, svidFile)
+		c.KeyFile = filepath.Join(c.Dir, keyFile)
+		c.CAFile = filepath.Join(c.Dir, bundleFile)
+	}
+}
+
+// Source provides the current certificate and CA bundle.
+type Source struct {
+	c         *Config
+	allowed   map[string]bool
+	endpoints map[string]bool
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	roots       *x509.CertPool
+	trustDomain string
+	modTimes    []time.Time
+	checked     time.Time
+}
+
+// New returns a Source reading the files of the configuration.
+func New(c *Config) (*Source, error) {
+	switch c.Source {
+	case "file":
+		if c.CertFile == "" || c.KeyFile == "" || c.CAFile == "" {
+			return nil, errors.New("mtls: cert_file, key_file and ca_file are required")
+		}
+	case "spiffe":
+		if c.Dir == "" {
+			return nil, errors.New("mtls: dir is required")
+		}
+	default:
+		return nil, fmt.Errorf("mtls: unknown source %q", c.Source)
+	}
+	c.init()
+
+	s := &Source{c: c, allowed: map[string]bool{}, endpoints: map[string]bool{}}
+	for _, id := range c.AllowedIDs {
+		s.allowed[id] = true
+	}
+	for _, e := range c.Endpoints {
+		s.endpoints[e] = true
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Source) files() []string {
+	return []string{s.c.CertFile, s.c.KeyFile, s.c.CAFile}
+}
+
+// load reads the files.
+func (s *Source) load() error {
+	modTimes := make([]time.Time, 0, 3)
+	for _, f := range s.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return errors.Wrap(err, "mtls: error reading certificate")
+		}
+		modTimes = append(modTimes, fi.ModTime())
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.c.CertFile, s.c.KeyFile)
+	if err != nil {
+		return errors.Wrap(err, "mtls: error loading certificate")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return errors.Wrap(err, "mtls: error parsing certificate")
+	}
+	cert.Leaf = leaf
+
+	pem, err := ioutil.ReadFile(s.c.CAFile)
+	if err != nil {
+		return errors.Wrap(err, "mtls: error reading CA bundle")
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return errors.New("mtls: no certificate in the CA bundle " + s.c.CAFile)
+	}
+
+	trustDomain := s.c.TrustDomain
+	if s.c.Source == "spiffe" && trustDomain == "" {
+		id, err := spiffeID(leaf)
+		if err != nil {
+			return err
+		}
+		trustDomain = id.Host
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cert, s.roots, s.trustDomain, s.modTimes = &cert, roots, trustDomain, modTimes
+	s.checked = time.Now()
+	return nil
+}
+
+// current returns the certificate and CA bundle, reloaded first if the files
+// changed since they were last checked.
+func (s *Source) current() (*tls.Certificate, *x509.CertPool, string) {
+	s.mu.RLock()
+	due := time.Since(s.checked) > time.Duration(s.c.ReloadInterval)*time.Second
+	s.mu.RUnlock()
+	if due && s.changed() {
+		if err := s.load(); err != nil {
+			// the files may be caught while they are written, the old certificate is kept until the next check
+			log.Error().Err(err).Msg("mtls: error reloading the certificate")
+		} else {
+			log.Info().Str("cert", s.c.CertFile).Msg("mtls: certificate reloaded")
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, s.roots, s.trustDomain
+}
+
+func (s *Source) changed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checked = time.Now()
+	for i, f := range s.files() {
+		fi, err := os.Stat(f)
+		if err != nil || !fi.ModTime().Equal(s.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerConfig returns the TLS configuration of the servers, requiring a
+// client certificate.
+func (s *Source) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _, _ := s.current()
+			return cert, nil
+		},
+		ClientAuth: tls.RequireAnyClientCert,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return s.verify(cs, true)
+		},
+	}
+}
+
+// ClientConfig returns the TLS configuration of the clients, presenting the
+// certificate.
+func (s *Source) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _, _ := s.current()
+			return cert, nil
+		},
+		// the server is verified against the current CA bundle by VerifyConnection
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return s.verify(cs, false)
+		},
+	}
+}
+
+// Mutual tells whether the grpc endpoint is called over mutual TLS.
+func (s *Source) Mutual(endpoint string) bool {
+	return s.endpoints[endpoint]
+}
+
+// verify verifies the certificate of the peer, a client if server is set.
+func (s *Source) verify(cs tls.ConnectionState, server bool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("mtls: no peer certificate")
+	}
+	_, roots, trustDomain := s.current()
+
+	leaf := cs.PeerCertificates[0]
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if server {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+
+	if s.c.Source == "spiffe" {
+		// the SVIDs are identified by their SPIFFE ID, not by a host name
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+		if _, err := leaf.Verify(opts); err != nil {
+			return errors.Wrap(err, "mtls: invalid peer certificate")
+		}
+		id, err := spiffeID(leaf)
+		if err != nil {
+			return err
+		}
+		if id.Host != trustDomain {
+			return fmt.Errorf("mtls: peer %s not in trust domain %s", id, trustDomain)
+		}
+		if server && len(s.allowed) > 0 && !s.allowed[id.String()] {
+			return fmt.Errorf("mtls: peer %s not allowed", id)
+		}
+		return nil
+	}
+
+	if !server {
+		opts.DNSName = cs.ServerName
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return errors.Wrap(err, "mtls: invalid peer certificate")
+	}
+	if server && len(s.allowed) > 0 && !s.allowedName(leaf) {
+		return fmt.Errorf("mtls: peer %s not allowed", leaf.Subject.CommonName)
+	}
+	return nil
+}
+
+func (s *Source) allowedName(c *x509.Certificate) bool {
+	if s.allowed[c.Subject.CommonName] {
+		return true
+	}
+	for _, n := range c.DNSNames {
+		if s.allowed[n] {
+			return true
+		}
+	}
+	for _, u := range c.URIs {
+		if s.allowed[u.String()] {
+			return true
+		}
+	}
+	return false
+}
+
+// spiffeID returns the SPIFFE ID of the X509-SVID, its only URI name.
+func spiffeID(c *x509.Certificate) (*url.URL, error) {
+	if len(c.URIs) != 1 || c.URIs[0].Scheme != "spiffe" || c.URIs[0].Host == "" {
+		return nil, errors.New("mtls: the certificate is not an X509-SVID")
+	}
+	return c.URIs[0], nil
+}
+
+var (
+	mu      sync.RWMutex
+	process *Source
+)
+
+// Set sets the certificate source of the process.
+func Set(s *Source) {
+	mu.Lock()
+	defer mu.Unlock()
+	process = s
+}
+
+// Get returns the certificate source of the process, nil if mutual TLS is not
+// configured.
+func Get() *Source {
+	mu.RLock()
+	defer mu.RUnlock()
+	return process
+}
+
+// ClientConfig returns the TLS configuration of the clients of the process,
+// nil if mutual TLS is not configured.
+func ClientConfig() *tls.Config {
+	if s := Get(); s != nil {
+		return s.ClientConfig()
+	}
+	return nil
+}
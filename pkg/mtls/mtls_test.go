@@ -0,0 +1,252 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type ca struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T) *ca {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "reva test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &ca{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate for the name, a SPIFFE ID if it has the spiffe
+// scheme, and the CA bundle to the files of the configuration.
+func (a *ca) issue(t *testing.T, c *Config, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if u, err := url.Parse(name); err == nil && u.Scheme == "spiffe" {
+		tmpl.URIs = []*url.URL{u}
+	} else {
+		tmpl.Subject = pkix.Name{CommonName: name}
+		tmpl.DNSNames = []string{name}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.init()
+	for f, b := range map[string][]byte{
+		c.CertFile: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		c.KeyFile:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}),
+		c.CAFile:   a.pem,
+	} {
+		if err := ioutil.WriteFile(f, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		// the rotated files must look changed however coarse the modification times
+		future := time.Now().Add(time.Duration(time.Now().UnixNano()%1000) * time.Second)
+		if err := os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func fileConfig(dir, name string) *Config {
+	return &Config{
+		Source:   "file",
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+".key"),
+		CAFile:   filepath.Join(dir, name+"-ca.pem"),
+	}
+}
+
+// handshake connects a client of the client source to a server of the server
+// source over TCP, returning the error of the server, or else of the client.
+func handshake(t *testing.T, server, client *Source, serverName string) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cfg := client.ClientConfig()
+	cfg.ServerName = serverName
+	clientErr := make(chan error, 1)
+	go func() {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			clientErr <- err
+			return
+		}
+		defer c.Close()
+		_ = c.SetDeadline(time.Now().Add(5 * time.Second))
+		clientErr <- tls.Client(c, cfg).Handshake()
+	}()
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(5 * time.Second))
+	err = tls.Server(c, server.ServerConfig()).Handshake()
+	c.Close()
+	if cerr := <-clientErr; err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func mustNew(t *testing.T, c *Config) *Source {
+	s, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	a := newCA(t)
+	sc, gc, oc := fileConfig(dir, "storage"), fileConfig(dir, "gateway"), fileConfig(dir, "other")
+	a.issue(t, sc, "storage.example.org")
+	a.issue(t, gc, "gateway.example.org")
+	newCA(t).issue(t, oc, "gateway.example.org")
+
+	sc.AllowedIDs = []string{"gateway.example.org"}
+	gc.Endpoints = []string{"storage-home", "storage.example.org:9142"}
+	server, gateway, other := mustNew(t, sc), mustNew(t, gc), mustNew(t, oc)
+
+	if !gateway.Mutual("storage-home") || gateway.Mutual("localhost:19000") || server.Mutual("storage-home") {
+		t.Error("mutual TLS not limited to the endpoints")
+	}
+
+	if err := handshake(t, server, gateway, "storage.example.org"); err != nil {
+		t.Errorf("gateway refused: %v", err)
+	}
+	if err := handshake(t, server, gateway, "evil.example.org"); err == nil {
+		t.Error("server name not verified")
+	}
+	if err := handshake(t, server, other, "storage.example.org"); err == nil {
+		t.Error("client of another CA accepted")
+	}
+	// the roles swapped, the gateway verifies the storage
+	if err := handshake(t, mustNew(t, gc), server, "gateway.example.org"); err != nil {
+		t.Errorf("storage refused by the gateway: %v", err)
+	}
+	if err := handshake(t, server, server, "storage.example.org"); err == nil {
+		t.Error("client not in the allowed ids accepted")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	a := newCA(t)
+	sc, gc := fileConfig(dir, "storage"), fileConfig(dir, "gateway")
+	a.issue(t, sc, "storage.example.org")
+	a.issue(t, gc, "gateway.example.org")
+	server, gateway := mustNew(t, sc), mustNew(t, gc)
+
+	// the storage gets certificates from a new CA
+	b := newCA(t)
+	b.issue(t, sc, "storage.example.org")
+	server.mu.Lock()
+	server.checked = time.Time{}
+	server.mu.Unlock()
+	if err := handshake(t, server, gateway, "storage.example.org"); err == nil {
+		t.Error("client of the old CA accepted after the rotation")
+	}
+
+	b.issue(t, gc, "gateway.example.org")
+	gateway.mu.Lock()
+	gateway.checked = time.Time{}
+	gateway.mu.Unlock()
+	if err := handshake(t, server, gateway, "storage.example.org"); err != nil {
+		t.Errorf("gateway refused after the rotation: %v", err)
+	}
+}
+
+func TestSPIFFE(t *testing.T) {
+	a := newCA(t)
+	spiffe := func(id string) *Config {
+		c := &Config{Source: "spiffe", Dir: t.TempDir()}
+		a.issue(t, c, id)
+		return c
+	}
+	sc := spiffe("spiffe://example.org/storage")
+	sc.AllowedIDs = []string{"spiffe://example.org/gateway"}
+	server := mustNew(t, sc)
+
+	if err := handshake(t, server, mustNew(t, spiffe("spiffe://example.org/gateway")), "any"); err != nil {
+		t.Errorf("gateway refused: %v", err)
+	}
+	if err := handshake(t, server, mustNew(t, spiffe("spiffe://example.org/ocdav")), "any"); err == nil {
+		t.Error("client not in the allowed ids accepted")
+	}
+	if err := handshake(t, server, mustNew(t, spiffe("spiffe://other.org/gateway")), "any"); err == nil {
+		t.Error("client of another trust domain accepted")
+	}
+
+	if _, err := New(fileConfig(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for missing files")
+	}
+}
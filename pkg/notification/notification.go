@@ -0,0 +1,128 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package notification holds the notifications sent to the users, their
+// templates and the preferences of the users about them.
+package notification
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// The kinds of notifications.
+const (
+	// KindShareReceived is sent to the grantees of a new share.
+	KindShareReceived = "share_received"
+	// KindLinkExpiring is sent to the creator of a public link about to expire.
+	KindLinkExpiring = "link_expiring"
+	// KindUploadReceived is sent to the owner of a public upload folder when
+	// a file is uploaded to it.
+	KindUploadReceived = "upload_received"
+)
+
+// Kinds are all the kinds of notifications.
+var Kinds = []string{KindShareReceived, KindLinkExpiring, KindUploadReceived}
+
+// The digest modes.
+const (
+	// DigestNone sends every notification right away.
+	DigestNone = "none"
+	// DigestHourly collects the notifications into one mail per hour.
+	DigestHourly = "hourly"
+	// DigestDaily collects the notifications into one mail per day.
+	DigestDaily = "daily"
+)
+
+// PreferencesKey is the key the preferences are stored under in the
+// preferences service.
+const PreferencesKey = "notifications"
+
+// Preferences are the choices of a user about the notifications sent to them.
+type Preferences struct {
+	// Language of the notifications, empty for the default of the deployment.
+	Language string `json:"language,omitempty"`
+	// Digest is one of the digest modes.
+	Digest string `json:"digest"`
+	// Disabled holds the kinds of the notifications not sent to the user.
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// DefaultPreferences returns the preferences of the users that did not set any.
+func DefaultPreferences() *Preferences {
+	return &Preferences{Digest: DigestNone}
+}
+
+// ParsePreferences decodes the preferences stored for a user, an empty
+// value returns the defaults.
+func ParsePreferences(v string) (*Preferences, error) {
+	p := DefaultPreferences()
+	if v == "" {
+		return p, nil
+	}
+	if err := json.Unmarshal([]byte(v), p); err != nil {
+		return nil, errors.Wrap(err, "notification: error decoding preferences")
+	}
+	return p, p.Validate()
+}
+
+// Validate checks that the preferences only hold known values.
+func (p *Preferences) Validate() error {
+	switch p.Digest {
+	case DigestNone, DigestHourly, DigestDaily:
+	case "":
+		p.Digest = DigestNone
+	default:
+		return errors.New("notification: unknown digest mode " + p.Digest)
+	}
+	for _, k := range p.Disabled {
+		if !isKind(k) {
+			return errors.New("notification: unknown notification kind " + k)
+		}
+	}
+	return nil
+}
+
+// Encode returns the value the preferences are stored with.
+func (p *Preferences) Encode() (string, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", errors.Wrap(err, "notification: error encoding preferences")
+	}
+	return string(b), nil
+}
+
+// Enabled returns whether the notifications of the given kind are sent.
+func (p *Preferences) Enabled(kind string) bool {
+	for _, k := range p.Disabled {
+		if k == kind {
+			return false
+		}
+	}
+	return true
+}
+
+func isKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notification
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreferences(t *testing.T) {
+	p, err := ParsePreferences("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Digest != DigestNone || !p.Enabled(KindShareReceived) {
+		t.Errorf("unexpected default preferences: %+v", p)
+	}
+
+	p, err = ParsePreferences(`{"language":"de","digest":"daily","disabled":["upload_received"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Language != "de" || p.Digest != DigestDaily || p.Enabled(KindUploadReceived) || !p.Enabled(KindLinkExpiring) {
+		t.Errorf("unexpected preferences: %+v", p)
+	}
+
+	for _, v := range []string{`{"digest":"weekly"}`, `{"disabled":["unknown"]}`, `{`} {
+		if _, err := ParsePreferences(v); err == nil {
+			t.Errorf("expected error parsing %s", v)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notification_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "fr"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "fr", KindShareReceived+".tmpl"), []byte("{{.Actor}} a partagé {{.ResourceName}}\n\nBonjour {{.Recipient}}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tpls, err := NewTemplates(dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &Data{Recipient: "Marie", Actor: "Albert", ResourceName: "notes.txt", Expiration: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	m, err := tpls.Render("fr", KindShareReceived, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Albert a partagé notes.txt" || m.Body != "Bonjour Marie" {
+		t.Errorf("unexpected message from custom template: %+v", m)
+	}
+
+	// the languages without a template fall back to the default
+	m, err = tpls.Render("fr", KindLinkExpiring, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != `Your public link to "notes.txt" expires soon` || !strings.Contains(m.Body, "2021-06-01 12:00 UTC") {
+		t.Errorf("unexpected message from fallback template: %+v", m)
+	}
+
+	m, err = tpls.Render("de", KindDigest, &DigestData{Recipient: "Marie", Messages: []*Message{{Subject: "a"}, {Subject: "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Sie haben 2 neue Benachrichtigungen" || !strings.Contains(m.Body, "* b") {
+		t.Errorf("unexpected digest: %+v", m)
+	}
+}
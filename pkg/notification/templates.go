@@ -0,0 +1,249 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notification
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// KindDigest is the template of the mails collecting several notifications.
+const KindDigest = "digest"
+
+// KindGuestInvited is the template of the invitations sent to the guests,
+// which they can't opt out of.
+const KindGuestInvited = "guest_invited"
+
+// Data is passed to the templates of the notifications.
+type Data struct {
+	// Recipient is the display name of the recipient.
+	Recipient string
+	// Actor is the display name of the user causing the notification.
+	Actor string
+	// ResourceName is the name of the shared or uploaded resource.
+	ResourceName string
+	// FolderName is the name of the folder a file was uploaded to.
+	FolderName string
+	// LinkName is the display name of a public link.
+	LinkName string
+	// Expiration is the time a public link expires at.
+	Expiration time.Time
+	// URL points to the resource or link.
+	URL string
+}
+
+// Message is a rendered notification.
+type Message struct {
+	Kind    string `json:"kind"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// DigestData is passed to the digest template.
+type DigestData struct {
+	Recipient string
+	Messages  []*Message
+}
+
+// Templates renders the notifications in the language of the recipients.
+// A template holds the subject on its first line and the body after the
+// following empty line. Deployments can override and add languages with
+// files named <dir>/<language>/<kind>.tmpl.
+type Templates struct {
+	dir      string
+	language string
+
+	mu     sync.Mutex
+	parsed map[string]*template.Template // by language/kind
+}
+
+// NewTemplates returns the templates read from dir, which may be empty to
+// only use the built in ones, falling back to the given language.
+func NewTemplates(dir, language string) (*Templates, error) {
+	if language == "" {
+		language = "en"
+	}
+	t := &Templates{dir: dir, language: language, parsed: map[string]*template.Template{}}
+	// fail early on broken templates of the fallback language
+	for _, k := range append(Kinds, KindDigest, KindGuestInvited) {
+		if _, err := t.get(language, k); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Render returns the notification of the given kind in the given language,
+// or in the fallback language if the given one has no template for it.
+func (t *Templates) Render(language, kind string, data interface{}) (*Message, error) {
+	tpl, err := t.get(language, kind)
+	if err != nil {
+		if language == t.language {
+			return nil, err
+		}
+		if tpl, err = t.get(t.language, kind); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, data); err != nil {
+		return nil, errors.Wrapf(err, "notification: error executing %s template", kind)
+	}
+	subject, body := b.String(), ""
+	if i := strings.Index(subject, "\n"); i >= 0 {
+		subject, body = subject[:i], strings.TrimLeft(subject[i+1:], "\n")
+	}
+	return &Message{Kind: kind, Subject: strings.TrimSpace(subject), Body: body}, nil
+}
+
+func (t *Templates) get(language, kind string) (*template.Template, error) {
+	key := language + "/" + kind
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tpl, ok := t.parsed[key]; ok {
+		return tpl, nil
+	}
+
+	text, ok := builtinTemplates[language][kind]
+	if t.dir != "" {
+		b, err := ioutil.ReadFile(filepath.Join(t.dir, filepath.Base(language), kind+".tmpl"))
+		switch {
+		case err == nil:
+			text, ok = string(b), true
+		case !os.IsNotExist(err):
+			return nil, errors.Wrapf(err, "notification: error reading %s template", key)
+		}
+	}
+	if !ok {
+		return nil, errors.New("notification: no template for " + key)
+	}
+
+	tpl, err := template.New(kind).Parse(strings.TrimLeft(text, "\n"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "notification: error parsing %s template", key)
+	}
+	t.parsed[key] = tpl
+	return tpl, nil
+}
+
+var builtinTemplates = map[string]map[string]string{
+	"en": {
+		KindShareReceived: `
+{{.Actor}} shared "{{.ResourceName}}" with you
+
+Hello {{.Recipient}},
+
+{{.Actor}} shared "{{.ResourceName}}" with you.
+{{if .URL}}
+Open it at {{.URL}}
+{{end}}`,
+		KindLinkExpiring: `
+Your public link to "{{.ResourceName}}" expires soon
+
+Hello {{.Recipient}},
+
+Your public link {{if .LinkName}}"{{.LinkName}}" {{end}}to "{{.ResourceName}}" expires on {{.Expiration.Format "2006-01-02 15:04 MST"}}.
+{{if .URL}}
+{{.URL}}
+{{end}}`,
+		KindUploadReceived: `
+New file "{{.ResourceName}}" in "{{.FolderName}}"
+
+Hello {{.Recipient}},
+
+The file "{{.ResourceName}}" was uploaded to your public upload folder "{{.FolderName}}".
+`,
+		KindDigest: `
+You have {{len .Messages}} new notifications
+
+Hello {{.Recipient}},
+
+this is a summary of your recent notifications.
+{{range .Messages}}
+* {{.Subject}}
+{{end}}`,
+		KindGuestInvited: `
+{{.Actor}} invited you to access "{{.ResourceName}}"
+
+Hello,
+
+{{.Actor}} shared "{{.ResourceName}}" with you. To access it, set the password of your guest account at
+
+{{.URL}}
+
+Your username is {{.Recipient}}.
+`,
+	},
+	"de": {
+		KindShareReceived: `
+{{.Actor}} hat "{{.ResourceName}}" mit Ihnen geteilt
+
+Hallo {{.Recipient}},
+
+{{.Actor}} hat "{{.ResourceName}}" mit Ihnen geteilt.
+{{if .URL}}
+Öffnen Sie es unter {{.URL}}
+{{end}}`,
+		KindLinkExpiring: `
+Ihr öffentlicher Link auf "{{.ResourceName}}" läuft bald ab
+
+Hallo {{.Recipient}},
+
+Ihr öffentlicher Link {{if .LinkName}}"{{.LinkName}}" {{end}}auf "{{.ResourceName}}" läuft am {{.Expiration.Format "02.01.2006 15:04 MST"}} ab.
+{{if .URL}}
+{{.URL}}
+{{end}}`,
+		KindUploadReceived: `
+Neue Datei "{{.ResourceName}}" in "{{.FolderName}}"
+
+Hallo {{.Recipient}},
+
+Die Datei "{{.ResourceName}}" wurde in Ihren öffentlichen Upload-Ordner "{{.FolderName}}" hochgeladen.
+`,
+		KindDigest: `
+Sie haben {{len .Messages}} neue Benachrichtigungen
+
+Hallo {{.Recipient}},
+
+hier ist eine Zusammenfassung Ihrer letzten Benachrichtigungen.
+{{range .Messages}}
+* {{.Subject}}
+{{end}}`,
+		KindGuestInvited: `
+{{.Actor}} hat Sie eingeladen, auf "{{.ResourceName}}" zuzugreifen
+
+Hallo,
+
+{{.Actor}} hat "{{.ResourceName}}" mit Ihnen geteilt. Um darauf zuzugreifen, legen Sie das Passwort Ihres Gastkontos fest unter
+
+{{.URL}}
+
+Ihr Benutzername ist {{.Recipient}}.
+`,
+	},
+}
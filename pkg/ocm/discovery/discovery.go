@@ -0,0 +1,170 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package discovery implements the OCM discovery document, which the OCM
+// providers serve to advertise the version of the API, the share types and
+// the protocols they support.
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// APIVersion is the version of the OCM API implemented by reva.
+const APIVersion = "1.0-proposal1"
+
+// Endpoint is the path the discovery document is served at.
+const Endpoint = "ocm-provider"
+
+// The resource types, share types and protocols of the OCM shares.
+const (
+	ResourceTypeFile = "file"
+
+	ShareTypeUser  = "user"
+	ShareTypeGroup = "group"
+
+	ProtocolWebdav = "webdav"
+	ProtocolWebapp = "webapp"
+	ProtocolDatatx = "datatx"
+)
+
+// Document is the discovery document of an OCM provider.
+type Document struct {
+	Enabled       bool           `json:"enabled" xml:"enabled"`
+	APIVersion    string         `json:"apiVersion" xml:"apiVersion"`
+	Host          string         `json:"host" xml:"host"`
+	Endpoint      string         `json:"endpoint" xml:"endpoint"`
+	Provider      string         `json:"provider" xml:"provider"`
+	ResourceTypes []ResourceType `json:"resourceTypes" xml:"resourceTypes"`
+}
+
+// ResourceType lists the share types and the protocols, by name with the path
+// they are served at, supported for a type of resource.
+type ResourceType struct {
+	Name       string            `json:"name"`
+	ShareTypes []string          `json:"shareTypes"`
+	Protocols  map[string]string `json:"protocols"`
+}
+
+// Supports returns an error telling why the provider does not accept the
+// shares of the given resource type, share type and protocol, or nil.
+func (d *Document) Supports(resourceType, shareType, protocol string) error {
+	if !d.Enabled {
+		return errtypes.NotSupported(fmt.Sprintf("ocm: provider %s has OCM disabled", d.Provider))
+	}
+	if major(d.APIVersion) != major(APIVersion) {
+		return errtypes.NotSupported(fmt.Sprintf("ocm: provider %s implements the OCM API %s, incompatible with %s", d.Provider, d.APIVersion, APIVersion))
+	}
+
+	for _, rt := range d.ResourceTypes {
+		if rt.Name != resourceType {
+			continue
+		}
+		if !contains(rt.ShareTypes, shareType) {
+			return errtypes.NotSupported(fmt.Sprintf("ocm: provider %s does not accept %s shares of type %s, only %s",
+				d.Provider, rt.Name, shareType, strings.Join(rt.ShareTypes, ", ")))
+		}
+		if _, ok := rt.Protocols[protocol]; !ok {
+			return errtypes.NotSupported(fmt.Sprintf("ocm: provider %s does not support the %s protocol for %s shares, only %s",
+				d.Provider, protocol, rt.Name, strings.Join(rt.protocolNames(), ", ")))
+		}
+		return nil
+	}
+	return errtypes.NotSupported(fmt.Sprintf("ocm: provider %s does not accept shares of resources of type %s", d.Provider, resourceType))
+}
+
+func (rt ResourceType) protocolNames() []string {
+	names := make([]string, 0, len(rt.Protocols))
+	for n := range rt.Protocols {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// major returns the major version of an API version like 1.0-proposal1.
+func major(v string) string {
+	return strings.SplitN(strings.TrimPrefix(v, "v"), ".", 2)[0]
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch returns the discovery document of the provider whose OCM API is served
+// at the endpoint. The document is looked up under the endpoint, where reva
+// serves it, then at the root of the host, where the OCM specification puts it.
+func Fetch(ctx context.Context, client *http.Client, endpoint string) (*Document, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "ocm: invalid endpoint "+endpoint)
+	}
+
+	locations := []string{path.Join("/", u.Path, Endpoint)}
+	if root := "/" + Endpoint; locations[0] != root {
+		locations = append(locations, root)
+	}
+
+	for _, l := range locations {
+		u.Path = l
+		var d *Document
+		d, err = fetch(ctx, client, u.String())
+		if err == nil {
+			return d, nil
+		}
+	}
+	return nil, err
+}
+
+func fetch(ctx context.Context, client *http.Client, u string) (*Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "ocm: error framing the discovery request")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "ocm: error fetching the discovery document "+u)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("ocm: error fetching the discovery document %s: %s", u, resp.Status)
+	}
+
+	d := &Document{}
+	if err := json.NewDecoder(resp.Body).Decode(d); err != nil {
+		return nil, errors.Wrap(err, "ocm: error decoding the discovery document "+u)
+	}
+	return d, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+var doc = &Document{
+	Enabled:    true,
+	APIVersion: "1.0-proposal1",
+	Provider:   "remote",
+	ResourceTypes: []ResourceType{{
+		Name:       ResourceTypeFile,
+		ShareTypes: []string{ShareTypeUser},
+		Protocols:  map[string]string{ProtocolWebdav: "/remote/ocm_webdav"},
+	}},
+}
+
+func TestSupports(t *testing.T) {
+	if err := doc.Supports(ResourceTypeFile, ShareTypeUser, ProtocolWebdav); err != nil {
+		t.Fatalf("expected the share to be supported: %v", err)
+	}
+
+	tests := []struct {
+		resourceType, shareType, protocol string
+	}{
+		{ResourceTypeFile, ShareTypeGroup, ProtocolWebdav},
+		{ResourceTypeFile, ShareTypeUser, ProtocolDatatx},
+		{"calendar", ShareTypeUser, ProtocolWebdav},
+	}
+	for _, tt := range tests {
+		err := doc.Supports(tt.resourceType, tt.shareType, tt.protocol)
+		if _, ok := err.(errtypes.IsNotSupported); !ok {
+			t.Errorf("expected %v to be refused, got %v", tt, err)
+		}
+	}
+
+	incompatible := *doc
+	incompatible.APIVersion = "2.0"
+	if err := incompatible.Supports(ResourceTypeFile, ShareTypeUser, ProtocolWebdav); err == nil {
+		t.Error("expected an incompatible API version to be refused")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	for _, served := range []string{"/ocm/ocm-provider", "/ocm-provider"} {
+		served := served
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != served {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(doc)
+		}))
+
+		d, err := Fetch(context.Background(), ts.Client(), ts.URL+"/ocm/")
+		ts.Close()
+		if err != nil {
+			t.Fatalf("error fetching the document served at %s: %v", served, err)
+		}
+		if d.Provider != "remote" || len(d.ResourceTypes) != 1 {
+			t.Errorf("unexpected document %+v", d)
+		}
+	}
+}
@@ -20,12 +20,56 @@ package invite
 
 import (
 	"context"
+	"encoding/json"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	invitepb "github.com/cs3org/go-cs3apis/cs3/ocm/invite/v1beta1"
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
 )
 
+// The invite API has no calls to manage the tokens, the GenerateInviteToken
+// call is extended with these opaque keys instead.
+const (
+	// TokenRequestOpaqueKey holds the JSON encoded TokenRequest of a
+	// GenerateInviteToken request.
+	TokenRequestOpaqueKey = "token_request"
+	// TokensOpaqueKey holds the JSON encoded tokens listed in a
+	// GenerateInviteToken response.
+	TokensOpaqueKey = "tokens"
+)
+
+// The actions of a TokenRequest.
+const (
+	ActionGenerate = "generate"
+	ActionList     = "list"
+	ActionRevoke   = "revoke"
+)
+
+// TokenRequest generates, lists or revokes invite tokens.
+type TokenRequest struct {
+	// Action defaults to ActionGenerate.
+	Action string `json:"action,omitempty"`
+	// Expiration is the validity of a generated token, e.g. 72h.
+	// The expiration configured in the manager is used if empty.
+	Expiration string `json:"expiration,omitempty"`
+	// OneTime invalidates a generated token once it has been accepted.
+	// The default of the invite manager service is used if nil.
+	OneTime *bool `json:"one_time,omitempty"`
+	// Token is the revoked token.
+	Token string `json:"token,omitempty"`
+	// All lists or revokes the tokens of all users, which is reserved
+	// to the admins.
+	All bool `json:"all,omitempty"`
+}
+
+// Token is a pending invite token.
+type Token struct {
+	*invitepb.InviteToken
+	OneTime bool `json:"one_time"`
+}
+
 // Manager is the interface that is used to perform operations to invites.
 type Manager interface {
 	// GenerateToken creates a new token for the user with a specified validity.
@@ -43,3 +87,90 @@ type Manager interface {
 	// FindAcceptedUsers finds remote users who have accepted invites based on their attributes.
 	FindAcceptedUsers(ctx context.Context, query string) ([]*userpb.User, error)
 }
+
+// Dumper is implemented by the managers that are able to export all the invite
+// tokens they hold and the remote users who accepted them, keyed by the opaque id
+// of the user who generated the invite.
+type Dumper interface {
+	Dump(ctx context.Context) ([]*invitepb.InviteToken, map[string][]*userpb.User, error)
+}
+
+// Loader is implemented by the managers that are able to import invites
+// previously exported by a Dumper.
+type Loader interface {
+	Load(ctx context.Context, tokens []*invitepb.InviteToken, accepted map[string][]*userpb.User) error
+}
+
+// TokenManager is implemented by the managers that are able to manage the
+// lifecycle of their invite tokens.
+type TokenManager interface {
+	// GenerateTokenWithOptions creates a new token for the user valid for the
+	// given duration, or the configured one if empty. A one time token is
+	// removed once it has been accepted.
+	GenerateTokenWithOptions(ctx context.Context, expiration string, oneTime bool) (*invitepb.InviteToken, error)
+
+	// ListTokens returns the tokens of the user which are neither expired nor
+	// used, or the ones of all users if the user is nil.
+	ListTokens(ctx context.Context, userID *userpb.UserId) ([]*Token, error)
+
+	// RevokeToken removes a token of the user, or of any user if the user is nil.
+	RevokeToken(ctx context.Context, userID *userpb.UserId, token string) error
+}
+
+// EncodeTokenRequest encodes the token request into an opaque.
+func EncodeTokenRequest(r *TokenRequest) (*types.Opaque, error) {
+	return encode(TokenRequestOpaqueKey, r)
+}
+
+// DecodeTokenRequest decodes the token request held in the opaque. A request
+// generating a token is returned if the opaque doesn't hold one.
+func DecodeTokenRequest(o *types.Opaque) (*TokenRequest, error) {
+	r := &TokenRequest{}
+	if err := decode(o, TokenRequestOpaqueKey, r); err != nil {
+		return nil, err
+	}
+	if r.Action == "" {
+		r.Action = ActionGenerate
+	}
+	return r, nil
+}
+
+// EncodeTokens encodes the listed tokens into an opaque.
+func EncodeTokens(tokens []*Token) (*types.Opaque, error) {
+	return encode(TokensOpaqueKey, tokens)
+}
+
+// DecodeTokens decodes the listed tokens held in the opaque.
+func DecodeTokens(o *types.Opaque) ([]*Token, error) {
+	tokens := []*Token{}
+	if err := decode(o, TokensOpaqueKey, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func encode(key string, v interface{}) (*types.Opaque, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Opaque{
+		Map: map[string]*types.OpaqueEntry{
+			key: {
+				Decoder: "json",
+				Value:   b,
+			},
+		},
+	}, nil
+}
+
+func decode(o *types.Opaque, key string, v interface{}) error {
+	entry, ok := o.GetMap()[key]
+	if !ok {
+		return nil
+	}
+	if entry.Decoder != "json" {
+		return errtypes.NotSupported("opaque entry decoder not recognized: " + entry.Decoder)
+	}
+	return json.Unmarshal(entry.Value, v)
+}
@@ -50,6 +50,8 @@ type inviteModel struct {
 	File          string
 	Invites       map[string]*invitepb.InviteToken `json:"invites"`
 	AcceptedUsers map[string][]*userpb.User        `json:"accepted_users"`
+	// OneTime holds the tokens which are removed once accepted.
+	OneTime map[string]bool `json:"one_time,omitempty"`
 }
 
 type manager struct {
@@ -156,6 +158,9 @@ func loadOrCreate(file string) (*inviteModel, error) {
 	if model.AcceptedUsers == nil {
 		model.AcceptedUsers = make(map[string][]*userpb.User)
 	}
+	if model.OneTime == nil {
+		model.OneTime = make(map[string]bool)
+	}
 
 	model.File = file
 	return model, nil
@@ -177,9 +182,18 @@ func (model *inviteModel) Save() error {
 }
 
 func (m *manager) GenerateToken(ctx context.Context) (*invitepb.InviteToken, error) {
+	return m.GenerateTokenWithOptions(ctx, "", false)
+}
+
+// GenerateTokenWithOptions creates a new token valid for the given duration,
+// which is removed once accepted if it is a one time token.
+func (m *manager) GenerateTokenWithOptions(ctx context.Context, expiration string, oneTime bool) (*invitepb.InviteToken, error) {
+	if expiration == "" {
+		expiration = m.config.Expiration
+	}
 
 	contexUser := user.ContextMustGetUser(ctx)
-	inviteToken, err := token.CreateToken(m.config.Expiration, contexUser.GetId())
+	inviteToken, err := token.CreateToken(expiration, contexUser.GetId())
 	if err != nil {
 		return nil, err
 	}
@@ -189,6 +203,9 @@ func (m *manager) GenerateToken(ctx context.Context) (*invitepb.InviteToken, err
 	defer m.Unlock()
 
 	m.model.Invites[inviteToken.GetToken()] = inviteToken
+	if oneTime {
+		m.model.OneTime[inviteToken.GetToken()] = true
+	}
 	if err := m.model.Save(); err != nil {
 		err = errors.Wrap(err, "error saving model")
 		return nil, err
@@ -266,6 +283,10 @@ func (m *manager) AcceptInvite(ctx context.Context, invite *invitepb.InviteToken
 		}
 	}
 	m.model.AcceptedUsers[currUser.GetOpaqueId()] = append(m.model.AcceptedUsers[currUser.GetOpaqueId()], remoteUser)
+	if m.model.OneTime[inviteToken.GetToken()] {
+		delete(m.model.Invites, inviteToken.GetToken())
+		delete(m.model.OneTime, inviteToken.GetToken())
+	}
 	if err := m.model.Save(); err != nil {
 		err = errors.Wrap(err, "json: error saving model")
 		return err
@@ -273,6 +294,43 @@ func (m *manager) AcceptInvite(ctx context.Context, invite *invitepb.InviteToken
 	return nil
 }
 
+// ListTokens returns the pending tokens of the user, or of all users if nil.
+func (m *manager) ListTokens(ctx context.Context, userID *userpb.UserId) ([]*invite.Token, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	now := uint64(time.Now().Unix())
+	tokens := []*invite.Token{}
+	for _, t := range m.model.Invites {
+		if now > t.GetExpiration().GetSeconds() || (userID != nil && !sameUser(t.GetUserId(), userID)) {
+			continue
+		}
+		tokens = append(tokens, &invite.Token{InviteToken: t, OneTime: m.model.OneTime[t.GetToken()]})
+	}
+	return tokens, nil
+}
+
+// RevokeToken removes a token of the user, or of any user if nil.
+func (m *manager) RevokeToken(ctx context.Context, userID *userpb.UserId, tkn string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	t, ok := m.model.Invites[tkn]
+	if !ok || (userID != nil && !sameUser(t.GetUserId(), userID)) {
+		return errtypes.NotFound(tkn)
+	}
+	delete(m.model.Invites, tkn)
+	delete(m.model.OneTime, tkn)
+	if err := m.model.Save(); err != nil {
+		return errors.Wrap(err, "json: error saving model")
+	}
+	return nil
+}
+
+func sameUser(a, b *userpb.UserId) bool {
+	return a.GetOpaqueId() == b.GetOpaqueId() && a.GetIdp() == b.GetIdp()
+}
+
 func (m *manager) GetAcceptedUser(ctx context.Context, remoteUserID *userpb.UserId) (*userpb.User, error) {
 
 	userKey := user.ContextMustGetUser(ctx).GetId().GetOpaqueId()
@@ -321,3 +379,57 @@ func getOCMEndpoint(originProvider *ocmprovider.ProviderInfo) (string, error) {
 	}
 	return "", errors.New("json: ocm endpoint not specified for mesh provider")
 }
+
+// Dump returns all the invite tokens and accepted users held by the manager.
+func (m *manager) Dump(ctx context.Context) ([]*invitepb.InviteToken, map[string][]*userpb.User, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	tokens := make([]*invitepb.InviteToken, 0, len(m.model.Invites))
+	for _, t := range m.model.Invites {
+		tokens = append(tokens, t)
+	}
+	accepted := make(map[string][]*userpb.User, len(m.model.AcceptedUsers))
+	for k, v := range m.model.AcceptedUsers {
+		accepted[k] = append([]*userpb.User{}, v...)
+	}
+	return tokens, accepted, nil
+}
+
+// Load adds the given invite tokens and accepted users to the manager.
+// Remote users already accepted by the same user are not added twice.
+func (m *manager) Load(ctx context.Context, tokens []*invitepb.InviteToken, accepted map[string][]*userpb.User) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, t := range tokens {
+		if _, ok := m.model.Invites[t.GetToken()]; ok {
+			return errtypes.AlreadyExists(t.GetToken())
+		}
+	}
+	for _, t := range tokens {
+		m.model.Invites[t.GetToken()] = t
+	}
+
+	for k, users := range accepted {
+		for _, u := range users {
+			if !containsUser(m.model.AcceptedUsers[k], u) {
+				m.model.AcceptedUsers[k] = append(m.model.AcceptedUsers[k], u)
+			}
+		}
+	}
+
+	if err := m.model.Save(); err != nil {
+		return errors.Wrap(err, "json: error saving model")
+	}
+	return nil
+}
+
+func containsUser(users []*userpb.User, u *userpb.User) bool {
+	for _, e := range users {
+		if e.Id.GetOpaqueId() == u.Id.GetOpaqueId() && e.Id.GetIdp() == u.Id.GetIdp() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/invite"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+func TestTokenLifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ocm-invites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	im, err := New(map[string]interface{}{"file": filepath.Join(dir, "invites.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := im.(invite.TokenManager)
+
+	einstein := &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein"}}
+	marie := &userpb.User{Id: &userpb.UserId{Idp: "cesnet.cz", OpaqueId: "marie"}}
+	richard := &userpb.User{Id: &userpb.UserId{Idp: "example.org", OpaqueId: "richard"}}
+	ctx := user.ContextSetUser(context.Background(), einstein)
+
+	oneTime, err := tm.GenerateTokenWithOptions(ctx, "1h", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := tm.GenerateTokenWithOptions(ctx, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := tm.ListTokens(ctx, einstein.Id)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("got %d tokens and error %v", len(tokens), err)
+	}
+	if tokens, _ := tm.ListTokens(ctx, marie.Id); len(tokens) != 0 {
+		t.Fatalf("got %d tokens of another user", len(tokens))
+	}
+
+	if err := tm.RevokeToken(ctx, marie.Id, revoked.Token); !isNotFound(err) {
+		t.Fatalf("revoked the token of another user: %v", err)
+	}
+	if err := tm.RevokeToken(ctx, einstein.Id, revoked.Token); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.AcceptInvite(ctx, revoked, marie); err == nil {
+		t.Fatal("accepted a revoked token")
+	}
+
+	if err := im.AcceptInvite(ctx, oneTime, marie); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.AcceptInvite(ctx, oneTime, richard); err == nil {
+		t.Fatal("accepted a one time token twice")
+	}
+	if tokens, _ := tm.ListTokens(ctx, nil); len(tokens) != 0 {
+		t.Fatalf("got %d pending tokens", len(tokens))
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
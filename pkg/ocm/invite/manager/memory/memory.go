@@ -76,6 +76,7 @@ func New(m map[string]interface{}) (invite.Manager, error) {
 type manager struct {
 	Invites       sync.Map
 	AcceptedUsers sync.Map
+	OneTime       sync.Map
 	Client        *http.Client
 	Config        *config
 }
@@ -86,17 +87,60 @@ type config struct {
 }
 
 func (m *manager) GenerateToken(ctx context.Context) (*invitepb.InviteToken, error) {
+	return m.GenerateTokenWithOptions(ctx, "", false)
+}
+
+// GenerateTokenWithOptions creates a new token valid for the given duration,
+// which is removed once accepted if it is a one time token.
+func (m *manager) GenerateTokenWithOptions(ctx context.Context, expiration string, oneTime bool) (*invitepb.InviteToken, error) {
+	if expiration == "" {
+		expiration = m.Config.Expiration
+	}
 
 	ctxUser := user.ContextMustGetUser(ctx)
-	inviteToken, err := token.CreateToken(m.Config.Expiration, ctxUser.GetId())
+	inviteToken, err := token.CreateToken(expiration, ctxUser.GetId())
 	if err != nil {
 		return nil, errors.Wrap(err, "memory: error creating token")
 	}
 
+	if oneTime {
+		m.OneTime.Store(inviteToken.GetToken(), true)
+	}
 	m.Invites.Store(inviteToken.GetToken(), inviteToken)
 	return inviteToken, nil
 }
 
+// ListTokens returns the pending tokens of the user, or of all users if nil.
+func (m *manager) ListTokens(ctx context.Context, userID *userpb.UserId) ([]*invite.Token, error) {
+	now := uint64(time.Now().Unix())
+	tokens := []*invite.Token{}
+	m.Invites.Range(func(k, v interface{}) bool {
+		t := v.(*invitepb.InviteToken)
+		if now > t.GetExpiration().GetSeconds() || (userID != nil && !sameUser(t.GetUserId(), userID)) {
+			return true
+		}
+		_, oneTime := m.OneTime.Load(k)
+		tokens = append(tokens, &invite.Token{InviteToken: t, OneTime: oneTime})
+		return true
+	})
+	return tokens, nil
+}
+
+// RevokeToken removes a token of the user, or of any user if nil.
+func (m *manager) RevokeToken(ctx context.Context, userID *userpb.UserId, tkn string) error {
+	v, ok := m.Invites.Load(tkn)
+	if !ok || (userID != nil && !sameUser(v.(*invitepb.InviteToken).GetUserId(), userID)) {
+		return errtypes.NotFound(tkn)
+	}
+	m.Invites.Delete(tkn)
+	m.OneTime.Delete(tkn)
+	return nil
+}
+
+func sameUser(a, b *userpb.UserId) bool {
+	return a.GetOpaqueId() == b.GetOpaqueId() && a.GetIdp() == b.GetIdp()
+}
+
 func (m *manager) ForwardInvite(ctx context.Context, invite *invitepb.InviteToken, originProvider *ocmprovider.ProviderInfo) error {
 
 	contextUser := user.ContextMustGetUser(ctx)
@@ -153,15 +197,26 @@ func (m *manager) AcceptInvite(ctx context.Context, invite *invitepb.InviteToken
 		return errors.New("memory: token creator and recipient are the same")
 	}
 
-	usersList, ok := m.AcceptedUsers.Load(currUser)
-	acceptedUsers := usersList.([]*userpb.User)
+	usersList, ok := m.AcceptedUsers.Load(currUser.GetOpaqueId())
 	if ok {
+		acceptedUsers := usersList.([]*userpb.User)
 		for _, acceptedUser := range acceptedUsers {
 			if acceptedUser.Id.GetOpaqueId() == remoteUser.Id.OpaqueId && acceptedUser.Id.GetIdp() == remoteUser.Id.Idp {
 				return errors.New("memory: user already added to accepted users")
 			}
 		}
+	}
 
+	// a one time token is consumed by the first acceptance, the mark is
+	// kept so that concurrent acceptances which already got the token fail
+	if _, oneTime := m.OneTime.Load(inviteToken.GetToken()); oneTime {
+		if _, loaded := m.Invites.LoadAndDelete(inviteToken.GetToken()); !loaded {
+			return errors.New("memory: token already used")
+		}
+	}
+
+	if ok {
+		acceptedUsers := usersList.([]*userpb.User)
 		acceptedUsers = append(acceptedUsers, remoteUser)
 		m.AcceptedUsers.Store(currUser.GetOpaqueId(), acceptedUsers)
 	} else {
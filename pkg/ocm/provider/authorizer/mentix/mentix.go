@@ -20,8 +20,10 @@ package mentix
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -32,7 +34,9 @@ import (
 	"github.com/cs3org/reva/pkg/rhttp"
 
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/mentix/key"
 	"github.com/cs3org/reva/pkg/ocm/provider"
 	"github.com/cs3org/reva/pkg/ocm/provider/authorizer/registry"
 	"github.com/mitchellh/mapstructure"
@@ -67,19 +71,34 @@ func New(m map[string]interface{}) (provider.Authorizer, error) {
 		),
 	}
 
-	return &authorizer{
-		client:      client,
-		providerIPs: sync.Map{},
-		conf:        c,
-	}, nil
+	a := &authorizer{
+		client:         client,
+		providerIPs:    sync.Map{},
+		conf:           c,
+		allowedDomains: map[string]bool{},
+	}
+	if c.PublicKey != "" {
+		pubKey, err := key.ParseVerificationKey(c.PublicKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing the public key of the mesh directory")
+		}
+		a.publicKey = pubKey
+	}
+	for _, d := range c.AllowedDomains {
+		a.allowedDomains[d] = true
+	}
+	return a, nil
 }
 
 type config struct {
-	URL                   string `mapstructure:"url"`
-	Timeout               int64  `mapstructure:"timeout"`
-	RefreshInterval       int64  `mapstructure:"refresh"`
-	VerifyRequestHostname bool   `mapstructure:"verify_request_hostname"`
-	Insecure              bool   `mapstructure:"insecure"`
+	URL                   string   `mapstructure:"url"`
+	Timeout               int64    `mapstructure:"timeout"`
+	RefreshInterval       int64    `mapstructure:"refresh"`
+	VerifyRequestHostname bool     `mapstructure:"verify_request_hostname"`
+	Insecure              bool     `mapstructure:"insecure"`
+	PublicKey             string   `mapstructure:"public_key"`
+	AllowedDomains        []string `mapstructure:"allowed_domains"`
+	CacheFile             string   `mapstructure:"cache_file"`
 }
 
 func (c *config) init() {
@@ -89,18 +108,59 @@ func (c *config) init() {
 }
 
 type authorizer struct {
+	mu                  sync.Mutex
 	providers           []*ocmprovider.ProviderInfo
 	providersExpiration int64
 	client              *Client
 	providerIPs         sync.Map
 	conf                *config
+	publicKey           ed25519.PublicKey
+	allowedDomains      map[string]bool
+}
+
+// directoryCache is the content of the cache file, holding the last list of
+// providers fetched from the mesh directory with its signature.
+type directoryCache struct {
+	Data      json.RawMessage `json:"data"`
+	Signature string          `json:"signature,omitempty"`
 }
 
-func (a *authorizer) fetchProviders() ([]*ocmprovider.ProviderInfo, error) {
+// fetchProviders returns the providers of the mesh directory, fetched again
+// once the refresh interval has passed. The last list fetched, or the one of
+// the cache file after a restart, is used while the directory is unreachable.
+func (a *authorizer) fetchProviders(ctx context.Context) ([]*ocmprovider.ProviderInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	if (a.providers != nil) && (time.Now().Unix() < a.providersExpiration) {
 		return a.providers, nil
 	}
 
+	providers, err := a.fetchDirectory()
+	if err != nil {
+		log := appctx.GetLogger(ctx)
+		if a.providers == nil && a.conf.CacheFile != "" {
+			cached, cacheErr := a.loadCache()
+			if cacheErr != nil {
+				log.Warn().Err(cacheErr).Msg("mentix: error loading the cached provider list")
+			}
+			a.providers = cached
+		}
+		if a.providers == nil {
+			return nil, err
+		}
+		log.Warn().Err(err).Msg("mentix: using the previous provider list")
+		return a.providers, nil
+	}
+
+	a.providers = providers
+	if a.conf.RefreshInterval > 0 {
+		a.providersExpiration = time.Now().Unix() + a.conf.RefreshInterval
+	}
+	return a.providers, nil
+}
+
+func (a *authorizer) fetchDirectory() ([]*ocmprovider.ProviderInfo, error) {
 	req, err := http.NewRequest("GET", a.client.BaseURL, nil)
 	if err != nil {
 		return nil, err
@@ -116,21 +176,69 @@ func (a *authorizer) fetchProviders() ([]*ocmprovider.ProviderInfo, error) {
 	}
 
 	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("error fetching provider list from: %s: %s", a.client.BaseURL, res.Status)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading provider list")
+	}
+
+	signature := res.Header.Get(key.SignatureHeader)
+	providers, err := a.parseProviders(data, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.conf.CacheFile != "" {
+		if err := a.saveCache(data, signature); err != nil {
+			return nil, err
+		}
+	}
+	return providers, nil
+}
+
+// parseProviders checks the signature of the provider list, when a public key
+// is configured, and returns the trusted providers it holds.
+func (a *authorizer) parseProviders(data []byte, signature string) ([]*ocmprovider.ProviderInfo, error) {
+	if a.publicKey != nil {
+		if err := key.Verify(a.publicKey, data, signature); err != nil {
+			return nil, errors.Wrap(err, "error verifying the provider list")
+		}
+	}
 
 	providers := make([]*ocmprovider.ProviderInfo, 0)
-	if err = json.NewDecoder(res.Body).Decode(&providers); err != nil {
+	if err := json.Unmarshal(data, &providers); err != nil {
 		return nil, err
 	}
+	return a.getOCMProviders(providers), nil
+}
 
-	a.providers = a.getOCMProviders(providers)
-	if a.conf.RefreshInterval > 0 {
-		a.providersExpiration = time.Now().Unix() + a.conf.RefreshInterval
+func (a *authorizer) saveCache(data []byte, signature string) error {
+	cache, err := json.Marshal(&directoryCache{Data: data, Signature: signature})
+	if err != nil {
+		return err
 	}
-	return a.providers, nil
+	if err := ioutil.WriteFile(a.conf.CacheFile, cache, 0600); err != nil {
+		return errors.Wrap(err, "error writing the provider list cache")
+	}
+	return nil
+}
+
+func (a *authorizer) loadCache() ([]*ocmprovider.ProviderInfo, error) {
+	data, err := ioutil.ReadFile(a.conf.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+	cache := &directoryCache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, errors.Wrap(err, "error decoding the provider list cache")
+	}
+	return a.parseProviders(cache.Data, cache.Signature)
 }
 
 func (a *authorizer) GetInfoByDomain(ctx context.Context, domain string) (*ocmprovider.ProviderInfo, error) {
-	providers, err := a.fetchProviders()
+	providers, err := a.fetchProviders(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +252,7 @@ func (a *authorizer) GetInfoByDomain(ctx context.Context, domain string) (*ocmpr
 }
 
 func (a *authorizer) IsProviderAllowed(ctx context.Context, provider *ocmprovider.ProviderInfo) error {
-	providers, err := a.fetchProviders()
+	providers, err := a.fetchProviders(ctx)
 	if err != nil {
 		return err
 	}
@@ -211,15 +319,20 @@ func (a *authorizer) IsProviderAllowed(ctx context.Context, provider *ocmprovide
 }
 
 func (a *authorizer) ListAllProviders(ctx context.Context) ([]*ocmprovider.ProviderInfo, error) {
-	providers, err := a.fetchProviders()
+	providers, err := a.fetchProviders(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return providers, nil
 }
 
+// getOCMProviders returns the providers exposing an OCM endpoint, among the
+// allowed domains if any.
 func (a *authorizer) getOCMProviders(providers []*ocmprovider.ProviderInfo) (po []*ocmprovider.ProviderInfo) {
 	for _, p := range providers {
+		if len(a.allowedDomains) > 0 && !a.allowedDomains[p.Domain] {
+			continue
+		}
 		_, err := a.getOCMHost(p)
 		if err == nil {
 			po = append(po, p)
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package mentix
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/mentix/key"
+)
+
+func ocmProvider(domain string) *ocmprovider.ProviderInfo {
+	return &ocmprovider.ProviderInfo{
+		Domain: domain,
+		Services: []*ocmprovider.Service{{
+			Host:     "https://" + domain,
+			Endpoint: &ocmprovider.ServiceEndpoint{Type: &ocmprovider.ServiceType{Name: "OCM"}, Path: "https://" + domain + "/ocm/"},
+		}},
+	}
+}
+
+func TestFetchProviders(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal([]*ocmprovider.ProviderInfo{ocmProvider("cernbox.cern.ch"), ocmProvider("cesnet.cz")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := key.Sign(privKey, data)
+	up := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(key.SignatureHeader, signature)
+		_, _ = w.Write(data)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	conf := map[string]interface{}{
+		"url":             ts.URL,
+		"public_key":      base64.StdEncoding.EncodeToString(pubKey),
+		"allowed_domains": []string{"cesnet.cz"},
+		"cache_file":      filepath.Join(t.TempDir(), "providers.json"),
+	}
+	a, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providers, err := a.ListAllProviders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 1 || providers[0].Domain != "cesnet.cz" {
+		t.Fatalf("expected only the allowed provider, got %v", providers)
+	}
+
+	// a restarted authorizer falls back to the cache while the directory is down
+	up = false
+	a, err = New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providers, err := a.ListAllProviders(ctx); err != nil || len(providers) != 1 {
+		t.Fatalf("expected the cached providers, got %v %v", providers, err)
+	}
+
+	// a list with an invalid signature is refused
+	up, signature = true, key.Sign(privKey, []byte("other data"))
+	delete(conf, "cache_file")
+	a, err = New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ListAllProviders(ctx); err == nil {
+		t.Fatal("expected the provider list with an invalid signature to be refused")
+	}
+}
@@ -37,6 +37,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/discovery"
 	"github.com/cs3org/reva/pkg/ocm/share"
 	"github.com/cs3org/reva/pkg/ocm/share/manager/registry"
 	"github.com/cs3org/reva/pkg/rhttp"
@@ -195,6 +196,25 @@ func getOCMEndpoint(originProvider *ocmprovider.ProviderInfo) (string, error) {
 	return "", errors.New("json: ocm endpoint not specified for mesh provider")
 }
 
+// checkRemoteSupport consumes the discovery document of the remote provider
+// to refuse the shares it does not support before sending them.
+func (m *mgr) checkRemoteSupport(ctx context.Context, ocmEndpoint string, g *provider.Grantee, st ocm.Share_ShareType) error {
+	d, err := discovery.Fetch(ctx, m.client, ocmEndpoint)
+	if err != nil {
+		return errors.Wrap(err, "json: error discovering the capabilities of the remote provider")
+	}
+
+	shareType := discovery.ShareTypeUser
+	if g.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
+		shareType = discovery.ShareTypeGroup
+	}
+	protocol := discovery.ProtocolWebdav
+	if st == ocm.Share_SHARE_TYPE_TRANSFER {
+		protocol = discovery.ProtocolDatatx
+	}
+	return d.Supports(discovery.ResourceTypeFile, shareType, protocol)
+}
+
 func (m *mgr) Share(ctx context.Context, md *provider.ResourceId, g *ocm.ShareGrant, name string,
 	pi *ocmprovider.ProviderInfo, pm string, owner *userpb.UserId, token string, st ocm.Share_ShareType) (*ocm.Share, error) {
 
@@ -318,6 +338,9 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceId, g *ocm.ShareGr
 		if err != nil {
 			return nil, err
 		}
+		if err := m.checkRemoteSupport(ctx, ocmEndpoint, g.Grantee, st); err != nil {
+			return nil, err
+		}
 		u, err := url.Parse(ocmEndpoint)
 		if err != nil {
 			return nil, err
@@ -574,6 +597,27 @@ func (m *mgr) ListShares(ctx context.Context, filters []*ocm.ListOCMSharesReques
 	return ss, nil
 }
 
+// Dump returns all the shares, regardless of the user in context.
+func (m *mgr) Dump(ctx context.Context) ([]*ocm.Share, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.model.ReadFile(); err != nil {
+		err = errors.Wrap(err, "error reading model")
+		return nil, err
+	}
+
+	ss := make([]*ocm.Share, 0, len(m.model.Shares))
+	for _, s := range m.model.Shares {
+		var share ocm.Share
+		if err := utils.UnmarshalJSONToProtoV1([]byte(s.(string)), &share); err != nil {
+			continue
+		}
+		ss = append(ss, &share)
+	}
+	return ss, nil
+}
+
 func (m *mgr) ListReceivedShares(ctx context.Context) ([]*ocm.ReceivedShare, error) {
 	var rss []*ocm.ReceivedShare
 	m.Lock()
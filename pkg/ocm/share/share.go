@@ -25,6 +25,8 @@ import (
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	usershare "github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/utils"
 )
 
 // Manager is the interface that manipulates the OCM shares.
@@ -55,3 +57,39 @@ type Manager interface {
 	// UpdateReceivedShare updates the received share with share state.
 	UpdateReceivedShare(ctx context.Context, ref *ocm.ShareReference, f *ocm.UpdateReceivedOCMShareRequest_UpdateField) (*ocm.ReceivedShare, error)
 }
+
+// Dumper is implemented by the managers that are able to list all the shares
+// they hold, regardless of the user in context.
+type Dumper interface {
+	Dump(ctx context.Context) ([]*ocm.Share, error)
+}
+
+// MatchFilters tells whether the share matches the filters of a listing: one
+// of the filters of each type has to match.
+func MatchFilters(s *ocm.Share, filters []*ocm.ListOCMSharesRequest_Filter) bool {
+	matched := map[ocm.ListOCMSharesRequest_Filter_Type]bool{}
+	for _, f := range filters {
+		if !matched[f.Type] {
+			matched[f.Type] = matchFilter(s, f)
+		}
+	}
+	for _, m := range matched {
+		if !m {
+			return false
+		}
+	}
+	return true
+}
+
+func matchFilter(s *ocm.Share, f *ocm.ListOCMSharesRequest_Filter) bool {
+	switch f.Type {
+	case ocm.ListOCMSharesRequest_Filter_TYPE_RESOURCE_ID:
+		return utils.ResourceEqual(s.ResourceId, f.GetResourceId())
+	case ocm.ListOCMSharesRequest_Filter_TYPE_OWNER:
+		return usershare.MatchUser(s.Owner, f.GetOwner())
+	case ocm.ListOCMSharesRequest_Filter_TYPE_CREATOR:
+		return usershare.MatchUser(s.Creator, f.GetCreator())
+	default:
+		return true
+	}
+}
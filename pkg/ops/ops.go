@@ -0,0 +1,144 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package ops keeps the probes the subsystems of a reva process register,
+// from which a snapshot of the deployment state is assembled for the
+// operations dashboard.
+package ops
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/sysinfo"
+)
+
+// Health is the health of a subsystem.
+type Health string
+
+// The healths a probe reports, from best to worst.
+const (
+	Healthy  Health = "ok"
+	Degraded Health = "degraded"
+	Down     Health = "down"
+)
+
+var severity = map[Health]int{Healthy: 0, Degraded: 1, Down: 2}
+
+// Worst returns the worst of the given healths.
+func Worst(hs ...Health) Health {
+	w := Healthy
+	for _, h := range hs {
+		if severity[h] > severity[w] {
+			w = h
+		}
+	}
+	return w
+}
+
+// The sections of the snapshot the probes are registered in.
+const (
+	Services  = "services"
+	Queues    = "queues"
+	Caches    = "caches"
+	Backends  = "backends"
+	Uploads   = "uploads"
+	Transfers = "transfers"
+)
+
+// State is what a probe reports.
+type State struct {
+	Health Health `json:"health"`
+	// Details are the probe specific values, e.g. the depth of a queue.
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Probe returns the current state of a subsystem. Probes are called for
+// every snapshot and must not block, they report what the subsystem already
+// knows instead of contacting its backends.
+type Probe func() State
+
+var (
+	mu     sync.RWMutex
+	probes = map[string]map[string]Probe{}
+	start  = time.Now()
+)
+
+// Register registers the probe of a subsystem under the given section and
+// name, replacing the probe already registered there.
+func Register(section, name string, p Probe) {
+	mu.Lock()
+	defer mu.Unlock()
+	if probes[section] == nil {
+		probes[section] = map[string]Probe{}
+	}
+	probes[section][name] = p
+}
+
+// Unregister removes the probe registered under the given section and name.
+func Unregister(section, name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(probes[section], name)
+}
+
+// Snapshot is the state of the deployment at a point in time.
+type Snapshot struct {
+	Time          time.Time            `json:"time"`
+	UptimeSeconds int64                `json:"uptime_seconds"`
+	Reva          *sysinfo.RevaVersion `json:"reva,omitempty"`
+	// Health is the worst health reported by the probes.
+	Health   Health                      `json:"health"`
+	Sections map[string]map[string]State `json:"sections"`
+}
+
+// Collect calls all the registered probes and returns the snapshot of their states.
+func Collect() *Snapshot {
+	// the probes are called without holding the lock, so that they may (un)register
+	mu.RLock()
+	registered := map[string]map[string]Probe{}
+	for section, ps := range probes {
+		registered[section] = map[string]Probe{}
+		for name, p := range ps {
+			registered[section][name] = p
+		}
+	}
+	mu.RUnlock()
+
+	now := time.Now()
+	s := &Snapshot{
+		Time:          now,
+		UptimeSeconds: int64(now.Sub(start).Seconds()),
+		Reva:          sysinfo.SysInfo.Reva,
+		Health:        Healthy,
+		Sections:      map[string]map[string]State{},
+	}
+	for section, ps := range registered {
+		states := map[string]State{}
+		for name, p := range ps {
+			st := p()
+			if st.Health == "" {
+				st.Health = Healthy
+			}
+			states[name] = st
+			s.Health = Worst(s.Health, st.Health)
+		}
+		s.Sections[section] = states
+	}
+	return s
+}
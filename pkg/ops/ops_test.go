@@ -0,0 +1,56 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ops
+
+import "testing"
+
+func TestCollect(t *testing.T) {
+	Register(Queues, "test/a", func() State {
+		return State{Details: map[string]interface{}{"depth": 3}}
+	})
+	Register(Backends, "test/b", func() State {
+		// a probe may unregister itself
+		Unregister(Backends, "test/b")
+		return State{Health: Degraded}
+	})
+	defer Unregister(Queues, "test/a")
+
+	s := Collect()
+	if s.Health != Degraded {
+		t.Errorf("health = %s, want %s", s.Health, Degraded)
+	}
+	a := s.Sections[Queues]["test/a"]
+	if a.Health != Healthy || a.Details["depth"] != 3 {
+		t.Errorf("unexpected state %+v", a)
+	}
+
+	s = Collect()
+	if _, ok := s.Sections[Backends]["test/b"]; ok || s.Health != Healthy {
+		t.Errorf("unregistered probe still reported: %+v", s)
+	}
+}
+
+func TestWorst(t *testing.T) {
+	if w := Worst(); w != Healthy {
+		t.Errorf("Worst() = %s", w)
+	}
+	if w := Worst(Degraded, Down, Healthy); w != Down {
+		t.Errorf("Worst(degraded, down, ok) = %s", w)
+	}
+}
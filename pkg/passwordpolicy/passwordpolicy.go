@@ -0,0 +1,266 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package passwordpolicy checks the passwords the users set for their public
+// links and the guests for their accounts against the configured rules. The
+// rules a password breaks are reported one by one, for the clients to show
+// them next to the field they were entered in.
+package passwordpolicy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// OpaqueKey is the opaque entry of the responses holding the rules a
+// rejected password broke.
+const OpaqueKey = "password_policy_violations"
+
+// The rules of a policy.
+const (
+	RuleMinLength    = "min_length"
+	RuleMinLowercase = "min_lowercase"
+	RuleMinUppercase = "min_uppercase"
+	RuleMinDigits    = "min_digits"
+	RuleMinSpecial   = "min_special"
+	RuleBanned       = "banned"
+	RuleBreached     = "breached"
+)
+
+// Config holds the rules of a password policy, the zero value accepts any
+// password.
+type Config struct {
+	MinLength           int      `mapstructure:"min_length" docs:"0;The minimum number of characters."`
+	MinLowercase        int      `mapstructure:"min_lowercase" docs:"0;The minimum number of lowercase letters."`
+	MinUppercase        int      `mapstructure:"min_uppercase" docs:"0;The minimum number of uppercase letters."`
+	MinDigits           int      `mapstructure:"min_digits" docs:"0;The minimum number of digits."`
+	MinSpecial          int      `mapstructure:"min_special" docs:"0;The minimum number of characters which are neither letters nor digits."`
+	BannedPasswords     []string `mapstructure:"banned_passwords" docs:"nil;Passwords which are refused, compared case insensitively."`
+	BannedPasswordsFile string   `mapstructure:"banned_passwords_file" docs:";A file holding further banned passwords, one per line."`
+	HIBP                bool     `mapstructure:"hibp" docs:"false;Whether to refuse the passwords found in data breaches by the Have I Been Pwned service. Only the first 5 characters of the SHA-1 hash of the password are sent."`
+	HIBPURL             string   `mapstructure:"hibp_url" docs:"https://api.pwnedpasswords.com/range/;The range endpoint of the Have I Been Pwned service."`
+	HIBPTimeout         int      `mapstructure:"hibp_timeout" docs:"5;The timeout in seconds of the requests to the Have I Been Pwned service, the check is skipped when it is not answering."`
+}
+
+func (c *Config) init() {
+	if c.HIBPURL == "" {
+		c.HIBPURL = "https://api.pwnedpasswords.com/range/"
+	}
+	if !strings.HasSuffix(c.HIBPURL, "/") {
+		c.HIBPURL += "/"
+	}
+	if c.HIBPTimeout == 0 {
+		c.HIBPTimeout = 5
+	}
+}
+
+// Violation is a rule broken by a password, as returned by the APIs.
+type Violation struct {
+	// Field is the field of the request the password was given in.
+	Field string `json:"field" xml:"field"`
+	// Rule is one of the rules of a policy.
+	Rule string `json:"rule" xml:"rule"`
+	// Limit is the number the rule requires, for the counting rules.
+	Limit int `json:"limit,omitempty" xml:"limit,omitempty"`
+	// Message describes the violation in English.
+	Message string `json:"message" xml:"message"`
+}
+
+// Error is returned for the passwords breaking the rules of a policy.
+type Error struct {
+	Violations []*Violation
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Message)
+	}
+	return "passwordpolicy: " + strings.Join(msgs, ", ")
+}
+
+// Policy checks passwords against the rules of its configuration.
+type Policy struct {
+	c      *Config
+	banned map[string]bool
+	client *http.Client
+}
+
+// New returns the policy configured by m.
+func New(m map[string]interface{}) (*Policy, error) {
+	c := &Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "passwordpolicy: error decoding conf")
+	}
+	c.init()
+
+	p := &Policy{
+		c:      c,
+		banned: map[string]bool{},
+		client: rhttp.GetHTTPClient(rhttp.Timeout(time.Duration(c.HIBPTimeout) * time.Second)),
+	}
+	for _, b := range c.BannedPasswords {
+		p.banned[strings.ToLower(b)] = true
+	}
+	if c.BannedPasswordsFile != "" {
+		f, err := os.Open(c.BannedPasswordsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "passwordpolicy: error opening banned passwords file")
+		}
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if b := strings.TrimSpace(s.Text()); b != "" {
+				p.banned[strings.ToLower(b)] = true
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, errors.Wrap(err, "passwordpolicy: error reading banned passwords file")
+		}
+	}
+	return p, nil
+}
+
+// Validate checks the password given in field, it returns an *Error listing
+// the rules the password breaks.
+func (p *Policy) Validate(ctx context.Context, field, password string) error {
+	var lower, upper, digits, special int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower++
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsDigit(r):
+			digits++
+		case !unicode.IsLetter(r):
+			special++
+		}
+	}
+
+	e := &Error{}
+	count := func(rule string, n, min int, what string) {
+		if n < min {
+			e.Violations = append(e.Violations, &Violation{
+				Field:   field,
+				Rule:    rule,
+				Limit:   min,
+				Message: fmt.Sprintf("at least %d %s required", min, what),
+			})
+		}
+	}
+	count(RuleMinLength, len([]rune(password)), p.c.MinLength, "characters")
+	count(RuleMinLowercase, lower, p.c.MinLowercase, "lowercase letters")
+	count(RuleMinUppercase, upper, p.c.MinUppercase, "uppercase letters")
+	count(RuleMinDigits, digits, p.c.MinDigits, "digits")
+	count(RuleMinSpecial, special, p.c.MinSpecial, "special characters")
+
+	if p.banned[strings.ToLower(password)] {
+		e.Violations = append(e.Violations, &Violation{Field: field, Rule: RuleBanned, Message: "the password is not allowed"})
+	}
+	if p.c.HIBP && password != "" {
+		breached, err := p.breached(ctx, password)
+		if err != nil {
+			appctx.GetLogger(ctx).Warn().Err(err).Msg("passwordpolicy: skipping breach check")
+		}
+		if breached {
+			e.Violations = append(e.Violations, &Violation{Field: field, Rule: RuleBreached, Message: "the password appeared in a data breach"})
+		}
+	}
+
+	if len(e.Violations) > 0 {
+		return e
+	}
+	return nil
+}
+
+// breached looks the password up in the Have I Been Pwned service by the
+// prefix of its hash, the service returns the suffixes of the hashes
+// starting with it.
+func (p *Policy) breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// not rhttp.NewRequest, the token of the user must not leave the deployment
+	req, err := http.NewRequest(http.MethodGet, p.c.HIBPURL+hash[:5], nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false, errors.New("passwordpolicy: unexpected status of breach check: " + res.Status)
+	}
+
+	s := bufio.NewScanner(res.Body)
+	for s.Scan() {
+		// lines are SUFFIX:COUNT
+		if suffix := strings.SplitN(s.Text(), ":", 2)[0]; strings.EqualFold(strings.TrimSpace(suffix), hash[5:]) {
+			return true, nil
+		}
+	}
+	return false, s.Err()
+}
+
+// EncodeViolations adds the violations of a policy error to an opaque.
+func EncodeViolations(o *types.Opaque, err *Error) (*types.Opaque, error) {
+	val, jerr := json.Marshal(err.Violations)
+	if jerr != nil {
+		return nil, jerr
+	}
+	if o == nil {
+		o = &types.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*types.OpaqueEntry{}
+	}
+	o.Map[OpaqueKey] = &types.OpaqueEntry{Decoder: "json", Value: val}
+	return o, nil
+}
+
+// DecodeViolations returns the violations held by an opaque, nil when there
+// are none.
+func DecodeViolations(o *types.Opaque) ([]*Violation, error) {
+	entry, ok := o.GetMap()[OpaqueKey]
+	if !ok {
+		return nil, nil
+	}
+	var v []*Violation
+	if err := json.Unmarshal(entry.Value, &v); err != nil {
+		return nil, errors.Wrap(err, "passwordpolicy: error decoding violations")
+	}
+	return v, nil
+}
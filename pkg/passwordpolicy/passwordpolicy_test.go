@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package passwordpolicy
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func rules(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var r []string
+	for _, v := range err.(*Error).Violations {
+		r = append(r, v.Rule)
+	}
+	return r
+}
+
+func TestValidate(t *testing.T) {
+	f, err := ioutil.TempFile("", "banned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("Summer2021!\n\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	p, err := New(map[string]interface{}{
+		"min_length":            8,
+		"min_lowercase":         1,
+		"min_uppercase":         1,
+		"min_digits":            1,
+		"min_special":           1,
+		"banned_passwords":      []string{"Passw0rd!"},
+		"banned_passwords_file": f.Name(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		expected []string
+	}{
+		{"C0rrect-horse", nil},
+		{"short", []string{RuleMinLength, RuleMinUppercase, RuleMinDigits, RuleMinSpecial}},
+		{"ALLUPPER1!", []string{RuleMinLowercase}},
+		{"passw0rd!", []string{RuleMinUppercase, RuleBanned}},
+		{"summer2021!", []string{RuleMinUppercase, RuleBanned}},
+	}
+	for _, tt := range tests {
+		got := rules(p.Validate(context.Background(), "password", tt.password))
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("Validate(%q) broke %v, wanted %v", tt.password, got, tt.expected)
+		}
+	}
+
+	err = p.Validate(context.Background(), "password", "short")
+	v := err.(*Error).Violations[0]
+	if v.Field != "password" || v.Limit != 8 {
+		t.Errorf("unexpected violation %+v", v)
+	}
+	o, err := EncodeViolations(nil, err.(*Error))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeViolations(o)
+	if err != nil || len(decoded) != 4 || decoded[0].Rule != RuleMinLength {
+		t.Errorf("unexpected decoded violations %v %v", decoded, err)
+	}
+}
+
+func TestBreached(t *testing.T) {
+	sum := sha1.Sum([]byte("123456"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/range/"+hash[:5] {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:37359195\r\n", strings.ToLower(hash[5:]))
+	}))
+	defer srv.Close()
+
+	p, err := New(map[string]interface{}{"hibp": true, "hibp_url": srv.URL + "/range"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rules(p.Validate(context.Background(), "password", "123456")); fmt.Sprint(r) != fmt.Sprint([]string{RuleBreached}) {
+		t.Errorf("the breached password broke %v", r)
+	}
+	if err := p.Validate(context.Background(), "password", "a much better passphrase"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the check is skipped when the service can't be reached
+	srv.Close()
+	if err := p.Validate(context.Background(), "password", "123456"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package permissions maps named roles to sets of CS3 resource permissions, so
+// that the roles a deployment defines once are understood by all the services
+// translating permissions.
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/golang/protobuf/proto"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RoleViewer can read a resource.
+	RoleViewer = "viewer"
+	// RoleEditor can read, change and delete a resource.
+	RoleEditor = "editor"
+	// RoleManager can do anything with a resource, including sharing it.
+	RoleManager = "manager"
+
+	// RoleOpaqueKey is the opaque key holding the role of a share in the share
+	// requests, as a JSON object with the name of the role.
+	RoleOpaqueKey = "role"
+)
+
+// Role is a named set of resource permissions.
+type Role struct {
+	Name        string
+	Description string
+	Permissions *provider.ResourcePermissions
+}
+
+// Viewer returns the permissions of the viewer role.
+func Viewer() *provider.ResourcePermissions {
+	return &provider.ResourcePermissions{
+		GetPath:              true,
+		GetQuota:             true,
+		InitiateFileDownload: true,
+		ListGrants:           true,
+		ListContainer:        true,
+		ListFileVersions:     true,
+		ListRecycle:          true,
+		Stat:                 true,
+	}
+}
+
+// Editor returns the permissions of the editor role.
+func Editor() *provider.ResourcePermissions {
+	p := Viewer()
+	p.InitiateFileUpload = true
+	p.RestoreFileVersion = true
+	p.RestoreRecycleItem = true
+	p.CreateContainer = true
+	p.Delete = true
+	p.Move = true
+	p.PurgeRecycle = true
+	return p
+}
+
+// Manager returns the permissions of the manager role.
+func Manager() *provider.ResourcePermissions {
+	p := Editor()
+	p.AddGrant = true
+	p.UpdateGrant = true
+	p.RemoveGrant = true
+	return p
+}
+
+var builtins = []*Role{
+	{Name: RoleViewer, Description: "Read the resource", Permissions: Viewer()},
+	{Name: RoleEditor, Description: "Read, change and delete the resource", Permissions: Editor()},
+	{Name: RoleManager, Description: "Read, change, delete and share the resource", Permissions: Manager()},
+}
+
+// the permissions by their name in the configuration, the one of the CS3 API
+var fields = []struct {
+	name  string
+	field func(p *provider.ResourcePermissions) *bool
+}{
+	{"add_grant", func(p *provider.ResourcePermissions) *bool { return &p.AddGrant }},
+	{"create_container", func(p *provider.ResourcePermissions) *bool { return &p.CreateContainer }},
+	{"delete", func(p *provider.ResourcePermissions) *bool { return &p.Delete }},
+	{"get_path", func(p *provider.ResourcePermissions) *bool { return &p.GetPath }},
+	{"get_quota", func(p *provider.ResourcePermissions) *bool { return &p.GetQuota }},
+	{"initiate_file_download", func(p *provider.ResourcePermissions) *bool { return &p.InitiateFileDownload }},
+	{"initiate_file_upload", func(p *provider.ResourcePermissions) *bool { return &p.InitiateFileUpload }},
+	{"list_grants", func(p *provider.ResourcePermissions) *bool { return &p.ListGrants }},
+	{"list_container", func(p *provider.ResourcePermissions) *bool { return &p.ListContainer }},
+	{"list_file_versions", func(p *provider.ResourcePermissions) *bool { return &p.ListFileVersions }},
+	{"list_recycle", func(p *provider.ResourcePermissions) *bool { return &p.ListRecycle }},
+	{"move", func(p *provider.ResourcePermissions) *bool { return &p.Move }},
+	{"remove_grant", func(p *provider.ResourcePermissions) *bool { return &p.RemoveGrant }},
+	{"purge_recycle", func(p *provider.ResourcePermissions) *bool { return &p.PurgeRecycle }},
+	{"restore_file_version", func(p *provider.ResourcePermissions) *bool { return &p.RestoreFileVersion }},
+	{"restore_recycle_item", func(p *provider.ResourcePermissions) *bool { return &p.RestoreRecycleItem }},
+	{"stat", func(p *provider.ResourcePermissions) *bool { return &p.Stat }},
+	{"update_grant", func(p *provider.ResourcePermissions) *bool { return &p.UpdateGrant }},
+}
+
+// Names returns the names of the granted permissions.
+func Names(p *provider.ResourcePermissions) []string {
+	var names []string
+	if p == nil {
+		return names
+	}
+	for _, f := range fields {
+		if *f.field(p) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+func setPermissions(p *provider.ResourcePermissions, names []string, v bool) error {
+	for _, n := range names {
+		found := false
+		for _, f := range fields {
+			if f.name == n {
+				*f.field(p) = v
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errtypes.BadRequest("permissions: unknown permission " + n)
+		}
+	}
+	return nil
+}
+
+type roleConfig struct {
+	Description string   `mapstructure:"description"`
+	Base        string   `mapstructure:"base"`
+	Permissions []string `mapstructure:"permissions"`
+	Exclude     []string `mapstructure:"exclude"`
+}
+
+// Roles holds the built-in roles along with the custom ones of a deployment.
+type Roles struct {
+	roles  map[string]*Role
+	custom []string // names of the custom roles, sorted
+}
+
+// New returns the roles defined by the configuration, keyed by role name, on
+// top of the built-in ones. A custom role grants the permissions of its base
+// role, if any, plus the listed ones, minus the excluded ones.
+func New(m map[string]interface{}) (*Roles, error) {
+	c := map[string]*roleConfig{}
+	if len(m) > 0 {
+		if err := mapstructure.Decode(m, &c); err != nil {
+			return nil, errors.Wrap(err, "permissions: error decoding conf")
+		}
+	}
+
+	r := &Roles{roles: map[string]*Role{}}
+	for _, b := range builtins {
+		r.roles[b.Name] = b
+	}
+	for name, rc := range c {
+		if _, ok := r.roles[name]; ok {
+			return nil, fmt.Errorf("permissions: role %s is built in", name)
+		}
+		p := &provider.ResourcePermissions{}
+		if rc.Base != "" {
+			if !IsBuiltin(rc.Base) {
+				return nil, fmt.Errorf("permissions: role %s is based on %s which is not a built in role", name, rc.Base)
+			}
+			p = proto.Clone(r.roles[rc.Base].Permissions).(*provider.ResourcePermissions)
+		}
+		if err := setPermissions(p, rc.Permissions, true); err != nil {
+			return nil, errors.Wrap(err, "permissions: invalid role "+name)
+		}
+		if err := setPermissions(p, rc.Exclude, false); err != nil {
+			return nil, errors.Wrap(err, "permissions: invalid role "+name)
+		}
+		if len(Names(p)) == 0 {
+			return nil, fmt.Errorf("permissions: role %s grants no permission", name)
+		}
+		r.roles[name] = &Role{Name: name, Description: rc.Description, Permissions: p}
+		r.custom = append(r.custom, name)
+	}
+	sort.Strings(r.custom)
+	return r, nil
+}
+
+// IsBuiltin tells whether the role is one of the built-in ones.
+func IsBuiltin(name string) bool {
+	for _, b := range builtins {
+		if b.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func copyRole(r *Role) *Role {
+	return &Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: proto.Clone(r.Permissions).(*provider.ResourcePermissions),
+	}
+}
+
+// Get returns the role with the given name.
+func (r *Roles) Get(name string) (*Role, bool) {
+	role, ok := r.roles[name]
+	if !ok {
+		return nil, false
+	}
+	return copyRole(role), true
+}
+
+// FromResourcePermissions returns the role granting exactly the given
+// permissions, the built-in roles taking precedence over the custom ones.
+func (r *Roles) FromResourcePermissions(p *provider.ResourcePermissions) (*Role, bool) {
+	if p == nil {
+		return nil, false
+	}
+	for _, role := range r.List() {
+		if proto.Equal(role.Permissions, p) {
+			return role, true
+		}
+	}
+	return nil, false
+}
+
+// List returns all the roles, the built-in ones first.
+func (r *Roles) List() []*Role {
+	roles := make([]*Role, 0, len(r.roles))
+	for _, b := range builtins {
+		roles = append(roles, copyRole(b))
+	}
+	for _, name := range r.custom {
+		roles = append(roles, copyRole(r.roles[name]))
+	}
+	return roles
+}
+
+var (
+	mu       sync.RWMutex
+	defaults *Roles
+)
+
+func init() {
+	// the built-in roles only
+	defaults, _ = New(nil)
+}
+
+// Configure sets the roles of the deployment, used by the package level
+// functions. It is meant to be called once at startup.
+func Configure(m map[string]interface{}) error {
+	r, err := New(m)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defaults = r
+	mu.Unlock()
+	return nil
+}
+
+func configured() *Roles {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaults
+}
+
+// Get returns the configured role with the given name.
+func Get(name string) (*Role, bool) {
+	return configured().Get(name)
+}
+
+// FromResourcePermissions returns the configured role granting exactly the
+// given permissions.
+func FromResourcePermissions(p *provider.ResourcePermissions) (*Role, bool) {
+	return configured().FromResourcePermissions(p)
+}
+
+// List returns all the configured roles.
+func List() []*Role {
+	return configured().List()
+}
+
+// RoleFromOpaque returns the configured role named in the opaque of a share request.
+func RoleFromOpaque(o *typespb.Opaque) (*Role, bool) {
+	e, ok := o.GetMap()[RoleOpaqueKey]
+	if !ok || e.Decoder != "json" {
+		return nil, false
+	}
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(e.Value, &v); err != nil {
+		return nil, false
+	}
+	return Get(v.Name)
+}
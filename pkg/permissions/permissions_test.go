@@ -0,0 +1,168 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package permissions
+
+import (
+	"reflect"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    map[string]interface{}
+		role    string
+		perms   []string
+		wantErr bool
+	}{
+		{
+			name: "base with exclusions",
+			conf: map[string]interface{}{
+				"no-download-viewer": map[string]interface{}{"base": "viewer", "exclude": []string{"initiate_file_download"}},
+			},
+			role:  "no-download-viewer",
+			perms: []string{"get_path", "get_quota", "list_grants", "list_container", "list_file_versions", "list_recycle", "stat"},
+		},
+		{
+			name: "listed permissions",
+			conf: map[string]interface{}{
+				"lister": map[string]interface{}{"permissions": []string{"stat", "list_container"}},
+			},
+			role:  "lister",
+			perms: []string{"list_container", "stat"},
+		},
+		{
+			name: "base with additions",
+			conf: map[string]interface{}{
+				"curator": map[string]interface{}{"base": "viewer", "permissions": []string{"delete"}},
+			},
+			role:  "curator",
+			perms: []string{"delete", "get_path", "get_quota", "initiate_file_download", "list_grants", "list_container", "list_file_versions", "list_recycle", "stat"},
+		},
+		{
+			name:    "built in role redefined",
+			conf:    map[string]interface{}{"viewer": map[string]interface{}{"permissions": []string{"stat"}}},
+			wantErr: true,
+		},
+		{
+			name: "custom base",
+			conf: map[string]interface{}{
+				"a": map[string]interface{}{"permissions": []string{"stat"}},
+				"b": map[string]interface{}{"base": "a"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown permission",
+			conf:    map[string]interface{}{"a": map[string]interface{}{"permissions": []string{"fly"}}},
+			wantErr: true,
+		},
+		{
+			name:    "no permission",
+			conf:    map[string]interface{}{"a": map[string]interface{}{"base": "viewer", "exclude": Names(Viewer())}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.conf)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			role, ok := r.Get(tt.role)
+			if !ok {
+				t.Fatalf("role %s not found", tt.role)
+			}
+			if got := Names(role.Permissions); !reflect.DeepEqual(got, tt.perms) {
+				t.Errorf("got permissions %v, want %v", got, tt.perms)
+			}
+		})
+	}
+}
+
+func TestFromResourcePermissions(t *testing.T) {
+	r, err := New(map[string]interface{}{
+		"no-download-viewer": map[string]interface{}{"base": "viewer", "exclude": []string{"initiate_file_download"}},
+		"also-viewer":        map[string]interface{}{"base": "viewer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noDownload := Viewer()
+	noDownload.InitiateFileDownload = false
+	tests := []struct {
+		perms *provider.ResourcePermissions
+		want  string
+	}{
+		{Viewer(), RoleViewer}, // the built-in roles take precedence
+		{Editor(), RoleEditor},
+		{Manager(), RoleManager},
+		{noDownload, "no-download-viewer"},
+		{&provider.ResourcePermissions{Stat: true}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		var got string
+		role, ok := r.FromResourcePermissions(tt.perms)
+		if ok {
+			got = role.Name
+		}
+		if got != tt.want {
+			t.Errorf("role of %v: got %q, want %q", Names(tt.perms), got, tt.want)
+		}
+	}
+
+	// the roles returned are copies
+	role, _ := r.Get(RoleViewer)
+	role.Permissions.Delete = true
+	if role, _ := r.Get(RoleViewer); role.Permissions.Delete {
+		t.Error("changing a returned role should not change the defined one")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer func() {
+		if err := Configure(nil); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, ok := Get("lister"); ok {
+		t.Fatal("custom roles should not be defined before configuring them")
+	}
+	if err := Configure(map[string]interface{}{"lister": map[string]interface{}{"permissions": []string{"stat"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Get("lister"); !ok {
+		t.Error("configured role not found")
+	}
+	if got := len(List()); got != 4 {
+		t.Errorf("expected the 3 built-in roles and the configured one, got %d", got)
+	}
+}
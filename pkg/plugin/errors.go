@@ -0,0 +1,83 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package plugin
+
+import (
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Every errtype is sent as a gRPC status code of its own, so that plugins in
+// other languages can return them too. The errtypes are recognized by the
+// prefixes of their messages, the status messages don't carry the prefixes.
+var kinds = []struct {
+	prefix string
+	code   codes.Code
+	new    func(string) error
+}{
+	{"error: not found: ", codes.NotFound, func(s string) error { return errtypes.NotFound(s) }},
+	{"internal error: ", codes.Internal, func(s string) error { return errtypes.InternalError(s) }},
+	{"error: permission denied: ", codes.PermissionDenied, func(s string) error { return errtypes.PermissionDenied(s) }},
+	{"error: already exists: ", codes.AlreadyExists, func(s string) error { return errtypes.AlreadyExists(s) }},
+	{"error: user required: ", codes.FailedPrecondition, func(s string) error { return errtypes.UserRequired(s) }},
+	{"error: invalid credentials: ", codes.Unauthenticated, func(s string) error { return errtypes.InvalidCredentials(s) }},
+	{"error: not supported: ", codes.Unimplemented, func(s string) error { return errtypes.NotSupported(s) }},
+	{"error: partial content: ", codes.Aborted, func(s string) error { return errtypes.PartialContent(s) }},
+	{"error: bad request: ", codes.InvalidArgument, func(s string) error { return errtypes.BadRequest(s) }},
+	{"error: checksum mismatch: ", codes.DataLoss, func(s string) error { return errtypes.ChecksumMismatch(s) }},
+	{"error: insufficient storage: ", codes.ResourceExhausted, func(s string) error { return errtypes.InsufficientStorage(s) }},
+	{"error: too many requests: ", codes.Unavailable, func(s string) error { return errtypes.TooManyRequests(s) }},
+}
+
+// EncodeError returns the gRPC status a plugin sends for err, with the code
+// of the errtype it wraps if there is one.
+func EncodeError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	cause := errors.Cause(err)
+	for _, k := range kinds {
+		if strings.HasPrefix(cause.Error(), k.prefix) {
+			return status.Error(k.code, strings.TrimPrefix(cause.Error(), k.prefix))
+		}
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// DecodeError returns the error a plugin sent, as the errtype of its code if
+// any.
+func DecodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	for _, k := range kinds {
+		if st.Code() == k.code {
+			return k.new(st.Message())
+		}
+	}
+	return errors.New(st.Message())
+}
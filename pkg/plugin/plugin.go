@@ -0,0 +1,239 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package plugin runs the drivers shipped as external binaries, which lets
+// sites maintain their own drivers out of the reva tree. The binaries are
+// started as children of revad with hashicorp/go-plugin and called over
+// gRPC, the services they serve are defined in pkg/plugin/proto so plugins
+// can be written in other languages too. Every kind of driver provides both
+// sides of its plugin in a driver called plugin, e.g. pkg/user/manager/plugin,
+// whose Serve function is what the main function of a Go plugin binary calls.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	hclog "github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Handshake is shared by revad and the plugins, a binary that isn't a reva
+// plugin fails it instead of being used as one.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  2,
+	MagicCookieKey:   "REVA_PLUGIN",
+	MagicCookieValue: "reva",
+}
+
+// userHeader is the metadata key of the user of the calls, the binary
+// header carries the marshaled cs3 user.
+const userHeader = "reva-user-bin"
+
+// Plugin is a running plugin.
+type Plugin struct {
+	// Raw is the client side of the plugin, as returned by the GRPCClient
+	// method of its go-plugin.
+	Raw    interface{}
+	client *goplugin.Client
+}
+
+// Kill stops the plugin process.
+func (p *Plugin) Kill() {
+	p.client.Kill()
+}
+
+// Load starts the plugin binary at path and returns the client side of the
+// given go-plugin, which the binary must serve under the same kind.
+func Load(kind, path string, p goplugin.Plugin) (*Plugin, error) {
+	if path == "" {
+		return nil, errors.New("plugin: path of the " + kind + " plugin not configured")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.Wrap(err, "plugin: error finding the "+kind+" plugin")
+	}
+
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          goplugin.PluginSet{kind: p},
+		Cmd:              exec.Command(path),
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		GRPCDialOptions: []grpc.DialOption{
+			grpc.WithUnaryInterceptor(UnaryClientInterceptor),
+			grpc.WithStreamInterceptor(StreamClientInterceptor),
+		},
+		Managed: true,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin." + kind,
+			Output: os.Stderr,
+			Level:  hclog.Info,
+		}),
+	})
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, errors.Wrapf(err, "plugin: error starting %s", path)
+	}
+	raw, err := rpcClient.Dispense(kind)
+	if err != nil {
+		client.Kill()
+		return nil, errors.Wrapf(err, "plugin: %s does not serve a %s plugin", path, kind)
+	}
+	return &Plugin{Raw: raw, client: client}, nil
+}
+
+// Serve serves the given go-plugin under the kind from a plugin binary. It
+// blocks until revad stops the plugin.
+func Serve(kind string, p goplugin.Plugin) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         goplugin.PluginSet{kind: p},
+		GRPCServer: func(opts []grpc.ServerOption) *grpc.Server {
+			opts = append(opts,
+				grpc.UnaryInterceptor(UnaryServerInterceptor),
+				grpc.StreamInterceptor(StreamServerInterceptor),
+			)
+			return goplugin.DefaultGRPCServer(opts)
+		},
+	})
+}
+
+// Cleanup stops all the plugins started by this process.
+func Cleanup() {
+	goplugin.CleanupClients()
+}
+
+// EncodeConfig returns the configuration of a driver as sent to its plugin.
+func EncodeConfig(m map[string]interface{}) (*structpb.Struct, error) {
+	// the configuration holds the types the toml decoder creates, json knows
+	// all of them
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "plugin: error encoding the configuration")
+	}
+	s := &structpb.Struct{}
+	if err := jsonpb.UnmarshalString(string(b), s); err != nil {
+		return nil, errors.Wrap(err, "plugin: error encoding the configuration")
+	}
+	return s, nil
+}
+
+// DecodeConfig returns the configuration a plugin received.
+func DecodeConfig(s *structpb.Struct) map[string]interface{} {
+	if s == nil {
+		return map[string]interface{}{}
+	}
+	return s.AsMap()
+}
+
+// outgoingContext adds the user and the token of the context to the
+// metadata of the calls.
+func outgoingContext(ctx context.Context) (context.Context, error) {
+	if u, ok := user.ContextGetUser(ctx); ok {
+		b, err := proto.Marshal(u)
+		if err != nil {
+			return nil, errors.Wrap(err, "plugin: error marshaling the user")
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, userHeader, string(b))
+	}
+	if t, ok := token.ContextGetToken(ctx); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, token.TokenHeader, t)
+	}
+	return ctx, nil
+}
+
+// incomingContext sets the user and the token of the metadata of a call in
+// its context.
+func incomingContext(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	if v := md.Get(userHeader); len(v) > 0 {
+		u := &userpb.User{}
+		if err := proto.Unmarshal([]byte(v[0]), u); err != nil {
+			return nil, errors.Wrap(err, "plugin: error unmarshaling the user")
+		}
+		ctx = user.ContextSetUser(ctx, u)
+	}
+	if v := md.Get(token.TokenHeader); len(v) > 0 {
+		ctx = token.ContextSetToken(ctx, v[0])
+	}
+	return ctx, nil
+}
+
+// UnaryClientInterceptor sends the user and the token of the context along
+// the unary calls to a plugin, and returns its errors as errtypes.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, err := outgoingContext(ctx)
+	if err != nil {
+		return err
+	}
+	return DecodeError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// StreamClientInterceptor sends the user and the token of the context along
+// the streaming calls to a plugin.
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, err := outgoingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// UnaryServerInterceptor sets the user and the token in the context of the
+// unary calls a plugin serves, and sends its errors as status codes.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := incomingContext(ctx)
+	if err != nil {
+		return nil, EncodeError(err)
+	}
+	res, err := handler(ctx, req)
+	return res, EncodeError(err)
+}
+
+// StreamServerInterceptor sets the user and the token in the context of the
+// streaming calls a plugin serves, and sends its errors as status codes.
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := incomingContext(ss.Context())
+	if err != nil {
+		return EncodeError(err)
+	}
+	return EncodeError(handler(srv, &serverStream{ServerStream: ss, ctx: ctx}))
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
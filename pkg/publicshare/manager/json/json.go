@@ -597,3 +597,224 @@ type publicShare struct {
 	link.PublicShare
 	Password string `json:"password"`
 }
+
+// Dump returns all the public shares held by the manager along with their password hashes.
+func (m *manager) Dump(ctx context.Context) ([]*publicshare.WithPassword, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]*publicshare.WithPassword, 0, len(db))
+	for _, v := range db {
+		d := v.(map[string]interface{})
+		var local publicShare
+		if err := utils.UnmarshalJSONToProtoV1([]byte(d["share"].(string)), &local.PublicShare); err != nil {
+			return nil, err
+		}
+		pw, _ := d["password"].(string)
+		shares = append(shares, &publicshare.WithPassword{PublicShare: &local.PublicShare, Password: pw})
+	}
+	return shares, nil
+}
+
+// Load adds the given public shares to the manager. Nothing is stored if any
+// of the shares clashes with an existing id or token.
+func (m *manager) Load(ctx context.Context, shares []*publicshare.WithPassword) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]bool, len(db))
+	for _, v := range db {
+		var local publicShare
+		if err := utils.UnmarshalJSONToProtoV1([]byte(v.(map[string]interface{})["share"].(string)), &local.PublicShare); err != nil {
+			return err
+		}
+		tokens[local.PublicShare.Token] = true
+	}
+
+	for _, s := range shares {
+		if _, ok := db[s.PublicShare.Id.GetOpaqueId()]; ok {
+			return errtypes.AlreadyExists(s.PublicShare.Id.GetOpaqueId())
+		}
+		if tokens[s.PublicShare.Token] {
+			return errtypes.AlreadyExists(s.PublicShare.Token)
+		}
+
+		encShare, err := utils.MarshalProtoV1ToJSON(s.PublicShare)
+		if err != nil {
+			return err
+		}
+		db[s.PublicShare.Id.GetOpaqueId()] = map[string]interface{}{
+			"share":    string(encShare),
+			"password": s.Password,
+		}
+		tokens[s.PublicShare.Token] = true
+	}
+
+	return m.writeDb(db)
+}
+
+// RenameUser replaces the id of a user in the owner and creator of the public shares.
+func (m *manager) RenameUser(ctx context.Context, oldID, newID *user.UserId) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return err
+	}
+
+	for k, v := range db {
+		d := v.(map[string]interface{})
+		var local publicShare
+		if err := utils.UnmarshalJSONToProtoV1([]byte(d["share"].(string)), &local.PublicShare); err != nil {
+			return err
+		}
+		ps := &local.PublicShare
+		if !utils.UserEqual(ps.Owner, oldID) && !utils.UserEqual(ps.Creator, oldID) {
+			continue
+		}
+		if utils.UserEqual(ps.Owner, oldID) {
+			ps.Owner = newID
+		}
+		if utils.UserEqual(ps.Creator, oldID) {
+			ps.Creator = newID
+		}
+
+		encShare, err := utils.MarshalProtoV1ToJSON(ps)
+		if err != nil {
+			return err
+		}
+		d["share"] = string(encShare)
+		db[k] = d
+	}
+
+	return m.writeDb(db)
+}
+
+// GetUploadOptions returns the upload options of the public share with the given id.
+func (m *manager) GetUploadOptions(ctx context.Context, id *link.PublicShareId) (*publicshare.UploadOptions, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return nil, err
+	}
+
+	d, ok := db[id.GetOpaqueId()].(map[string]interface{})
+	if !ok {
+		return nil, errtypes.NotFound(id.GetOpaqueId())
+	}
+	raw, ok := d["upload_options"].(string)
+	if !ok {
+		return nil, nil
+	}
+	opts := &publicshare.UploadOptions{}
+	if err := json.Unmarshal([]byte(raw), opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// SetUploadOptions stores the upload options of the public share with the given id.
+func (m *manager) SetUploadOptions(ctx context.Context, id *link.PublicShareId, o *publicshare.UploadOptions) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return err
+	}
+
+	d, ok := db[id.GetOpaqueId()].(map[string]interface{})
+	if !ok {
+		return errtypes.NotFound(id.GetOpaqueId())
+	}
+	enc, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	d["upload_options"] = string(enc)
+	db[id.GetOpaqueId()] = d
+
+	return m.writeDb(db)
+}
+
+// GetAccessStats returns the statistics of the public share with the given id.
+func (m *manager) GetAccessStats(ctx context.Context, id *link.PublicShareId) (*publicshare.AccessStats, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return nil, err
+	}
+	return accessStats(db, id)
+}
+
+// RecordAccess records an access to the public share with the given id.
+func (m *manager) RecordAccess(ctx context.Context, id *link.PublicShareId, a *publicshare.Access) (*publicshare.AccessStats, error) {
+	return m.updateAccessStats(id, func(s *publicshare.AccessStats) {
+		s.Record(a, time.Now())
+	})
+}
+
+// SetMaxDownloads sets the download limit of the public share with the given id.
+func (m *manager) SetMaxDownloads(ctx context.Context, id *link.PublicShareId, max uint64) error {
+	_, err := m.updateAccessStats(id, func(s *publicshare.AccessStats) {
+		s.MaxDownloads = max
+	})
+	return err
+}
+
+func (m *manager) updateAccessStats(id *link.PublicShareId, update func(*publicshare.AccessStats)) (*publicshare.AccessStats, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.readDb()
+	if err != nil {
+		return nil, err
+	}
+	s, err := accessStats(db, id)
+	if err != nil {
+		return nil, err
+	}
+	update(s)
+
+	enc, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	d := db[id.GetOpaqueId()].(map[string]interface{})
+	d["access_stats"] = string(enc)
+	db[id.GetOpaqueId()] = d
+
+	if err := m.writeDb(db); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func accessStats(db map[string]interface{}, id *link.PublicShareId) (*publicshare.AccessStats, error) {
+	d, ok := db[id.GetOpaqueId()].(map[string]interface{})
+	if !ok {
+		return nil, errtypes.NotFound(id.GetOpaqueId())
+	}
+	s := &publicshare.AccessStats{}
+	if raw, ok := d["access_stats"].(string); ok {
+		if err := json.Unmarshal([]byte(raw), s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
@@ -22,5 +22,6 @@ import (
 	// Load core share manager drivers.
 	_ "github.com/cs3org/reva/pkg/publicshare/manager/json"
 	_ "github.com/cs3org/reva/pkg/publicshare/manager/memory"
+	_ "github.com/cs3org/reva/pkg/publicshare/manager/sql"
 	// Add your own here
 )
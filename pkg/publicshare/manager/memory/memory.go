@@ -49,7 +49,11 @@ func New(c map[string]interface{}) (publicshare.Manager, error) {
 }
 
 type manager struct {
-	shares sync.Map
+	shares        sync.Map
+	uploadOptions sync.Map
+
+	statsMu sync.Mutex
+	stats   map[string]*publicshare.AccessStats
 }
 
 var (
@@ -201,11 +205,14 @@ func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link
 			return errors.New("reference does not exist")
 		}
 		m.shares.Delete(s.Token)
+		m.uploadOptions.Delete(s.Id.GetOpaqueId())
 	case ref.GetToken() != "":
-		if _, err := m.GetPublicShareByToken(ctx, ref.GetToken(), &link.PublicShareAuthentication{}, false); err != nil {
+		s, err := m.GetPublicShareByToken(ctx, ref.GetToken(), &link.PublicShareAuthentication{}, false)
+		if err != nil {
 			return errors.New("reference does not exist")
 		}
 		m.shares.Delete(ref.GetToken())
+		m.uploadOptions.Delete(s.Id.GetOpaqueId())
 	default:
 		return errors.New("reference does not exist")
 	}
@@ -243,3 +250,79 @@ func (m *manager) getPublicShareByTokenID(ctx context.Context, targetID link.Pub
 	}
 	return nil, errors.New("resource not found")
 }
+
+// GetUploadOptions returns the upload options of the public share with the given id.
+func (m *manager) GetUploadOptions(ctx context.Context, id *link.PublicShareId) (*publicshare.UploadOptions, error) {
+	if _, err := m.getPublicShareByTokenID(ctx, *id); err != nil {
+		return nil, errtypes.NotFound(id.GetOpaqueId())
+	}
+	if o, ok := m.uploadOptions.Load(id.GetOpaqueId()); ok {
+		opts := *o.(*publicshare.UploadOptions)
+		return &opts, nil
+	}
+	return nil, nil
+}
+
+// SetUploadOptions stores the upload options of the public share with the given id.
+func (m *manager) SetUploadOptions(ctx context.Context, id *link.PublicShareId, o *publicshare.UploadOptions) error {
+	if _, err := m.getPublicShareByTokenID(ctx, *id); err != nil {
+		return errtypes.NotFound(id.GetOpaqueId())
+	}
+	opts := *o
+	m.uploadOptions.Store(id.GetOpaqueId(), &opts)
+	return nil
+}
+
+// stat returns the statistics of the public share with the given id, the
+// caller holding statsMu.
+func (m *manager) stat(ctx context.Context, id *link.PublicShareId) (*publicshare.AccessStats, error) {
+	if _, err := m.getPublicShareByTokenID(ctx, *id); err != nil {
+		return nil, errtypes.NotFound(id.GetOpaqueId())
+	}
+	if m.stats == nil {
+		m.stats = map[string]*publicshare.AccessStats{}
+	}
+	s, ok := m.stats[id.GetOpaqueId()]
+	if !ok {
+		s = &publicshare.AccessStats{}
+		m.stats[id.GetOpaqueId()] = s
+	}
+	return s, nil
+}
+
+// GetAccessStats returns the statistics of the public share with the given id.
+func (m *manager) GetAccessStats(ctx context.Context, id *link.PublicShareId) (*publicshare.AccessStats, error) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	s, err := m.stat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stats := *s
+	return &stats, nil
+}
+
+// RecordAccess records an access to the public share with the given id.
+func (m *manager) RecordAccess(ctx context.Context, id *link.PublicShareId, a *publicshare.Access) (*publicshare.AccessStats, error) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	s, err := m.stat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.Record(a, time.Now())
+	stats := *s
+	return &stats, nil
+}
+
+// SetMaxDownloads sets the download limit of the public share with the given id.
+func (m *manager) SetMaxDownloads(ctx context.Context, id *link.PublicShareId, max uint64) error {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	s, err := m.stat(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.MaxDownloads = max
+	return nil
+}
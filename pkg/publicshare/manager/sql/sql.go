@@ -0,0 +1,755 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a public share manager storing the links in a SQL
+// database, MySQL, PostgreSQL or SQLite, whose schema is created and upgraded
+// on startup.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/cbox/dbrouter"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/health"
+	"github.com/cs3org/reva/pkg/metrics"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
+	"github.com/cs3org/reva/pkg/sqlmigrate"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	// Provide the supported database drivers.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const migrationsTable = "public_shares_migrations"
+
+// columns are the columns a public share is read from, in the order of
+// dbShare.dest.
+const columns = "id, token, item_storage, item_opaque, owner_idp, owner_id, creator_idp, creator_id, permissions, password, display_name, expiration, ctime, mtime"
+
+var migrations = []sqlmigrate.Migration{
+	{
+		Version:     1,
+		Description: "create the public shares table",
+		Up: map[sqlmigrate.Dialect][]string{
+			"": {
+				`CREATE TABLE public_shares (
+					id VARCHAR(64) NOT NULL PRIMARY KEY,
+					token VARCHAR(64) NOT NULL,
+					item_storage VARCHAR(255) NOT NULL,
+					item_opaque VARCHAR(255) NOT NULL,
+					owner_idp VARCHAR(255) NOT NULL,
+					owner_id VARCHAR(255) NOT NULL,
+					creator_idp VARCHAR(255) NOT NULL,
+					creator_id VARCHAR(255) NOT NULL,
+					permissions TEXT NOT NULL,
+					password VARCHAR(255) NOT NULL,
+					display_name VARCHAR(255) NOT NULL,
+					expiration BIGINT NULL,
+					ctime BIGINT NOT NULL,
+					mtime BIGINT NOT NULL
+				)`,
+				"CREATE UNIQUE INDEX public_shares_token ON public_shares (token)",
+				"CREATE INDEX public_shares_item ON public_shares (item_storage, item_opaque)",
+				"CREATE INDEX public_shares_owner ON public_shares (owner_id)",
+				"CREATE INDEX public_shares_creator ON public_shares (creator_id)",
+				"CREATE INDEX public_shares_expiration ON public_shares (expiration)",
+			},
+		},
+	},
+	{
+		Version:     2,
+		Description: "add the upload options of the file drops",
+		Up: map[sqlmigrate.Dialect][]string{
+			"": {"ALTER TABLE public_shares ADD COLUMN upload_options TEXT NULL"},
+		},
+	},
+	{
+		Version:     3,
+		Description: "add the access statistics and the download limit",
+		Up: map[sqlmigrate.Dialect][]string{
+			"": {
+				"ALTER TABLE public_shares ADD COLUMN access_count BIGINT NOT NULL DEFAULT 0",
+				"ALTER TABLE public_shares ADD COLUMN download_count BIGINT NOT NULL DEFAULT 0",
+				"ALTER TABLE public_shares ADD COLUMN bytes_served BIGINT NOT NULL DEFAULT 0",
+				"ALTER TABLE public_shares ADD COLUMN last_access BIGINT NOT NULL DEFAULT 0",
+				"ALTER TABLE public_shares ADD COLUMN max_downloads BIGINT NOT NULL DEFAULT 0",
+			},
+		},
+	},
+}
+
+func init() {
+	registry.Register("sqldb", New)
+}
+
+type config struct {
+	// DbDriver is the database/sql driver: mysql, postgres or sqlite3.
+	DbDriver                   string `mapstructure:"db_driver"`
+	DbDSN                      string `mapstructure:"db_dsn"`
+	SkipMigrations             bool   `mapstructure:"db_skip_migrations"`
+	SharePasswordHashCost      int    `mapstructure:"password_hash_cost"`
+	JanitorRunInterval         int    `mapstructure:"janitor_run_interval"`
+	EnableExpiredSharesCleanup bool   `mapstructure:"enable_expired_shares_cleanup"`
+	// Replicas configures the read replicas the listings and lookups
+	// of public shares are routed to.
+	Replicas dbrouter.Config `mapstructure:",squash"`
+}
+
+func (c *config) init() {
+	if c.DbDriver == "" {
+		c.DbDriver = string(sqlmigrate.SQLite)
+	}
+	if c.DbDSN == "" && c.DbDriver == string(sqlmigrate.SQLite) {
+		c.DbDSN = "/var/tmp/reva/publicshares.db"
+	}
+	if c.SharePasswordHashCost == 0 {
+		c.SharePasswordHashCost = 11
+	}
+	if c.JanitorRunInterval == 0 {
+		c.JanitorRunInterval = 3600
+	}
+}
+
+type manager struct {
+	c       *config
+	dialect sqlmigrate.Dialect
+	db      *sql.DB
+	router  *dbrouter.Router
+}
+
+// New returns a public share manager storing the links in a SQL database.
+func New(m map[string]interface{}) (publicshare.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "sqldb: error decoding conf")
+	}
+	c.init()
+
+	dialect := sqlmigrate.Dialect(c.DbDriver)
+	switch dialect {
+	case sqlmigrate.MySQL, sqlmigrate.Postgres:
+	case sqlmigrate.SQLite:
+		if err := os.MkdirAll(filepath.Dir(c.DbDSN), 0755); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errtypes.NotSupported("sqldb: unsupported database driver " + c.DbDriver)
+	}
+
+	db, err := sql.Open(c.DbDriver, c.DbDSN)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqldb: error opening the database")
+	}
+	if !c.SkipMigrations {
+		if err := sqlmigrate.Apply(context.Background(), db, dialect, migrationsTable, migrations); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	router, err := dbrouter.New(c.DbDriver, db, &c.Replicas)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	ops.Register(ops.Backends, "publicshare/sqldb", router.Probe)
+	health.Register("publicshare/sqldb", router.Ping)
+	metrics.RegisterCollector("publicshare/sqldb", router.Collector("publicshare/sqldb"))
+
+	mgr := &manager{
+		c:       c,
+		dialect: dialect,
+		db:      db,
+		router:  router,
+	}
+	go mgr.startJanitorRun()
+
+	return mgr, nil
+}
+
+func (m *manager) startJanitorRun() {
+	if !m.c.EnableExpiredSharesCleanup {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(m.c.JanitorRunInterval) * time.Second)
+	work := make(chan os.Signal, 1)
+	signal.Notify(work, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT)
+
+	for {
+		select {
+		case <-work:
+			return
+		case <-ticker.C:
+			if err := m.cleanupExpiredShares(context.Background()); err != nil {
+				appctx.GetLogger(context.Background()).Error().Err(err).Msg("sqldb: error deleting expired public shares")
+			}
+		}
+	}
+}
+
+// dbShare is a row of the public shares table.
+type dbShare struct {
+	ID, Token               string
+	ItemStorage, ItemOpaque string
+	OwnerIdp, OwnerID       string
+	CreatorIdp, CreatorID   string
+	Permissions             string
+	Password, DisplayName   string
+	Expiration              sql.NullInt64
+	Ctime, Mtime            int64
+}
+
+func (s *dbShare) dest() []interface{} {
+	return []interface{}{&s.ID, &s.Token, &s.ItemStorage, &s.ItemOpaque, &s.OwnerIdp, &s.OwnerID, &s.CreatorIdp, &s.CreatorID, &s.Permissions, &s.Password, &s.DisplayName, &s.Expiration, &s.Ctime, &s.Mtime}
+}
+
+func (s *dbShare) publicShare() (*link.PublicShare, error) {
+	perms := &link.PublicSharePermissions{}
+	if err := utils.UnmarshalJSONToProtoV1([]byte(s.Permissions), perms); err != nil {
+		return nil, errors.Wrap(err, "sqldb: error decoding the permissions of public share "+s.ID)
+	}
+	ps := &link.PublicShare{
+		Id:                &link.PublicShareId{OpaqueId: s.ID},
+		Token:             s.Token,
+		ResourceId:        &provider.ResourceId{StorageId: s.ItemStorage, OpaqueId: s.ItemOpaque},
+		Owner:             &user.UserId{Idp: s.OwnerIdp, OpaqueId: s.OwnerID},
+		Creator:           &user.UserId{Idp: s.CreatorIdp, OpaqueId: s.CreatorID},
+		Permissions:       perms,
+		PasswordProtected: s.Password != "",
+		DisplayName:       s.DisplayName,
+		Ctime:             timestamp(s.Ctime),
+		Mtime:             timestamp(s.Mtime),
+	}
+	if s.Expiration.Valid {
+		ps.Expiration = timestamp(s.Expiration.Int64)
+	}
+	return ps, nil
+}
+
+func newDBShare(ps *link.PublicShare, password string) (*dbShare, error) {
+	perms, err := utils.MarshalProtoV1ToJSON(ps.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	s := &dbShare{
+		ID:          ps.Id.GetOpaqueId(),
+		Token:       ps.Token,
+		ItemStorage: ps.ResourceId.GetStorageId(),
+		ItemOpaque:  ps.ResourceId.GetOpaqueId(),
+		OwnerIdp:    ps.Owner.GetIdp(),
+		OwnerID:     ps.Owner.GetOpaqueId(),
+		CreatorIdp:  ps.Creator.GetIdp(),
+		CreatorID:   ps.Creator.GetOpaqueId(),
+		Permissions: string(perms),
+		Password:    password,
+		DisplayName: ps.DisplayName,
+		Ctime:       int64(utils.TSToUnixNano(ps.Ctime)),
+		Mtime:       int64(utils.TSToUnixNano(ps.Mtime)),
+	}
+	if ps.Expiration != nil {
+		s.Expiration = sql.NullInt64{Int64: int64(utils.TSToUnixNano(ps.Expiration)), Valid: true}
+	}
+	return s, nil
+}
+
+func timestamp(nanos int64) *typespb.Timestamp {
+	return &typespb.Timestamp{
+		Seconds: uint64(nanos / int64(time.Second)),
+		Nanos:   uint32(nanos % int64(time.Second)),
+	}
+}
+
+func (m *manager) insert(ctx context.Context, x interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}, s *dbShare) error {
+	query := m.dialect.Rebind("INSERT INTO public_shares (" + columns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	_, err := x.ExecContext(ctx, query, s.ID, s.Token, s.ItemStorage, s.ItemOpaque, s.OwnerIdp, s.OwnerID, s.CreatorIdp, s.CreatorID, s.Permissions, s.Password, s.DisplayName, s.Expiration, s.Ctime, s.Mtime)
+	return err
+}
+
+// CreatePublicShare stores a new public share of the resource.
+func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *provider.ResourceInfo, g *link.Grant) (*link.PublicShare, error) {
+	tkn := utils.RandString(15)
+	now := time.Now().UnixNano()
+
+	displayName, ok := rInfo.ArbitraryMetadata.GetMetadata()["name"]
+	if !ok {
+		displayName = tkn
+	}
+
+	var password string
+	if g.Password != "" {
+		h, err := bcrypt.GenerateFromPassword([]byte(g.Password), m.c.SharePasswordHashCost)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not hash share password")
+		}
+		password = string(h)
+	}
+
+	ps := &link.PublicShare{
+		Id:                &link.PublicShareId{OpaqueId: utils.RandString(15)},
+		Owner:             rInfo.GetOwner(),
+		Creator:           u.Id,
+		ResourceId:        rInfo.Id,
+		Token:             tkn,
+		Permissions:       g.Permissions,
+		Ctime:             timestamp(now),
+		Mtime:             timestamp(now),
+		PasswordProtected: password != "",
+		Expiration:        g.Expiration,
+		DisplayName:       displayName,
+	}
+
+	s, err := newDBShare(ps, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.insert(ctx, m.router.Writer(ctx), s); err != nil {
+		return nil, errors.Wrap(err, "sqldb: error inserting public share")
+	}
+	return ps, nil
+}
+
+// UpdatePublicShare updates the field of the public share given by the request.
+func (m *manager) UpdatePublicShare(ctx context.Context, u *user.User, req *link.UpdatePublicShareRequest, g *link.Grant) (*link.PublicShare, error) {
+	share, err := m.GetPublicShare(ctx, u, req.Ref, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var query string
+	var params []interface{}
+	switch req.GetUpdate().GetType() {
+	case link.UpdatePublicShareRequest_Update_TYPE_DISPLAYNAME:
+		share.DisplayName = req.Update.GetDisplayName()
+		query, params = "display_name=?", []interface{}{share.DisplayName}
+	case link.UpdatePublicShareRequest_Update_TYPE_PERMISSIONS:
+		share.Permissions = req.Update.GetGrant().GetPermissions()
+		perms, err := utils.MarshalProtoV1ToJSON(share.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		query, params = "permissions=?", []interface{}{string(perms)}
+	case link.UpdatePublicShareRequest_Update_TYPE_EXPIRATION:
+		share.Expiration = req.Update.GetGrant().GetExpiration()
+		var exp sql.NullInt64
+		if share.Expiration != nil {
+			exp = sql.NullInt64{Int64: int64(utils.TSToUnixNano(share.Expiration)), Valid: true}
+		}
+		query, params = "expiration=?", []interface{}{exp}
+	case link.UpdatePublicShareRequest_Update_TYPE_PASSWORD:
+		var password string
+		if pw := req.Update.GetGrant().GetPassword(); pw != "" {
+			h, err := bcrypt.GenerateFromPassword([]byte(pw), m.c.SharePasswordHashCost)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not hash share password")
+			}
+			password = string(h)
+		}
+		share.PasswordProtected = password != ""
+		query, params = "password=?", []interface{}{password}
+	default:
+		return nil, errtypes.BadRequest(fmt.Sprintf("invalid update type: %v", req.GetUpdate().GetType()))
+	}
+
+	now := time.Now().UnixNano()
+	share.Mtime = timestamp(now)
+	query = m.dialect.Rebind("UPDATE public_shares SET " + query + ", mtime=? WHERE id=?")
+	params = append(params, now, share.Id.OpaqueId)
+	if _, err := m.router.Writer(ctx).ExecContext(ctx, query, params...); err != nil {
+		return nil, errors.Wrap(err, "sqldb: error updating public share "+share.Id.OpaqueId)
+	}
+	return share, nil
+}
+
+func (m *manager) getShare(ctx context.Context, where string, args ...interface{}) (*link.PublicShare, string, error) {
+	var s dbShare
+	query := m.dialect.Rebind("SELECT " + columns + " FROM public_shares WHERE " + where)
+	if err := m.router.QueryRow(ctx, s.dest(), query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", errtypes.NotFound(fmt.Sprint(args[0]))
+		}
+		return nil, "", err
+	}
+	ps, err := s.publicShare()
+	if err != nil {
+		return nil, "", err
+	}
+	if expired(ps) {
+		return nil, "", errtypes.NotFound(fmt.Sprint(args[0]))
+	}
+	return ps, s.Password, nil
+}
+
+func (m *manager) getByToken(ctx context.Context, token string) (*link.PublicShare, string, error) {
+	return m.getShare(ctx, "token=?", token)
+}
+
+func (m *manager) getByID(ctx context.Context, id *link.PublicShareId, u *user.User) (*link.PublicShare, string, error) {
+	if u == nil {
+		return nil, "", errtypes.NotFound(id.GetOpaqueId())
+	}
+	return m.getShare(ctx, "id=? AND ((owner_idp=? AND owner_id=?) OR (creator_idp=? AND creator_id=?))",
+		id.GetOpaqueId(), u.Id.GetIdp(), u.Id.GetOpaqueId(), u.Id.GetIdp(), u.Id.GetOpaqueId())
+}
+
+// GetPublicShare gets a public share either by id, among the ones owned or
+// created by the user, or by token.
+func (m *manager) GetPublicShare(ctx context.Context, u *user.User, ref *link.PublicShareReference, sign bool) (*link.PublicShare, error) {
+	var s *link.PublicShare
+	var pw string
+	var err error
+	switch {
+	case ref.GetId() != nil:
+		s, pw, err = m.getByID(ctx, ref.GetId(), u)
+	case ref.GetToken() != "":
+		s, pw, err = m.getByToken(ctx, ref.GetToken())
+	default:
+		err = errtypes.NotFound(ref.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.PasswordProtected && sign {
+		publicshare.AddSignature(s, pw)
+	}
+	return s, nil
+}
+
+// ListPublicShares lists the unexpired public shares owned or created by the user
+// which match all the filters.
+func (m *manager) ListPublicShares(ctx context.Context, u *user.User, filters []*link.ListPublicSharesRequest_Filter, md *provider.ResourceInfo, sign bool) ([]*link.PublicShare, error) {
+	where := []string{
+		"((owner_idp=? AND owner_id=?) OR (creator_idp=? AND creator_id=?))",
+		"(expiration IS NULL OR expiration > ?)",
+	}
+	params := []interface{}{u.Id.GetIdp(), u.Id.GetOpaqueId(), u.Id.GetIdp(), u.Id.GetOpaqueId(), time.Now().UnixNano()}
+
+	for _, f := range filters {
+		switch f.Type {
+		case link.ListPublicSharesRequest_Filter_TYPE_RESOURCE_ID:
+			where = append(where, "item_storage=? AND item_opaque=?")
+			params = append(params, f.GetResourceId().GetStorageId(), f.GetResourceId().GetOpaqueId())
+		case link.ListPublicSharesRequest_Filter_TYPE_OWNER:
+			where = append(where, "owner_idp=? AND owner_id=?")
+			params = append(params, f.GetOwner().GetIdp(), f.GetOwner().GetOpaqueId())
+		case link.ListPublicSharesRequest_Filter_TYPE_CREATOR:
+			where = append(where, "creator_idp=? AND creator_id=?")
+			params = append(params, f.GetCreator().GetIdp(), f.GetCreator().GetOpaqueId())
+		default:
+			return nil, errtypes.BadRequest(fmt.Sprintf("unsupported filter type: %v", f.Type))
+		}
+	}
+
+	query := m.dialect.Rebind("SELECT " + columns + " FROM public_shares WHERE " + strings.Join(where, " AND ") + " ORDER BY ctime")
+	rows, err := m.router.Reader(ctx).QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqldb: error listing public shares")
+	}
+	defer rows.Close()
+
+	shares := []*link.PublicShare{}
+	for rows.Next() {
+		var s dbShare
+		if err := rows.Scan(s.dest()...); err != nil {
+			return nil, err
+		}
+		ps, err := s.publicShare()
+		if err != nil {
+			return nil, err
+		}
+		if ps.PasswordProtected && sign {
+			publicshare.AddSignature(ps, s.Password)
+		}
+		shares = append(shares, ps)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// RevokePublicShare deletes the public share.
+func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link.PublicShareReference) error {
+	var query string
+	var params []interface{}
+	switch {
+	case ref.GetId() != nil && ref.GetId().OpaqueId != "":
+		if u == nil {
+			return errtypes.NotFound(ref.GetId().OpaqueId)
+		}
+		query = "DELETE FROM public_shares WHERE id=? AND ((owner_idp=? AND owner_id=?) OR (creator_idp=? AND creator_id=?))"
+		params = []interface{}{ref.GetId().OpaqueId, u.Id.GetIdp(), u.Id.GetOpaqueId(), u.Id.GetIdp(), u.Id.GetOpaqueId()}
+	case ref.GetToken() != "":
+		query = "DELETE FROM public_shares WHERE token=?"
+		params = []interface{}{ref.GetToken()}
+	default:
+		return errtypes.NotFound(ref.String())
+	}
+
+	res, err := m.router.Writer(ctx).ExecContext(ctx, m.dialect.Rebind(query), params...)
+	if err != nil {
+		return errors.Wrap(err, "sqldb: error deleting public share")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errtypes.NotFound(ref.String())
+	}
+	return nil
+}
+
+// GetPublicShareByToken gets a public share by its token, checking the
+// authentication of the password protected ones.
+func (m *manager) GetPublicShareByToken(ctx context.Context, token string, auth *link.PublicShareAuthentication, sign bool) (*link.PublicShare, error) {
+	ps, pw, err := m.getByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if ps.PasswordProtected {
+		if !authenticate(ps, pw, auth) {
+			return nil, errtypes.InvalidCredentials("sqldb: invalid password")
+		}
+		if sign {
+			publicshare.AddSignature(ps, pw)
+		}
+	}
+	return ps, nil
+}
+
+func (m *manager) cleanupExpiredShares(ctx context.Context) error {
+	query := m.dialect.Rebind("DELETE FROM public_shares WHERE expiration IS NOT NULL AND expiration <= ?")
+	_, err := m.db.ExecContext(ctx, query, time.Now().UnixNano())
+	return err
+}
+
+func expired(s *link.PublicShare) bool {
+	return s.Expiration != nil && utils.TSToTime(s.Expiration).Before(time.Now())
+}
+
+func authenticate(share *link.PublicShare, pw string, auth *link.PublicShareAuthentication) bool {
+	switch {
+	case auth.GetPassword() != "":
+		if err := bcrypt.CompareHashAndPassword([]byte(pw), []byte(auth.GetPassword())); err == nil {
+			return true
+		}
+	case auth.GetSignature() != nil:
+		sig := auth.GetSignature()
+		now := time.Now()
+		expiration := time.Unix(int64(sig.GetSignatureExpiration().GetSeconds()), int64(sig.GetSignatureExpiration().GetNanos()))
+		if now.After(expiration) {
+			return false
+		}
+		s := publicshare.CreateSignature(share.Token, pw, expiration)
+		return sig.GetSignature() == s
+	}
+	return false
+}
+
+// Dump returns all the public shares stored in the database along with their password hashes.
+func (m *manager) Dump(ctx context.Context) ([]*publicshare.WithPassword, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT "+columns+" FROM public_shares ORDER BY ctime")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*publicshare.WithPassword{}
+	for rows.Next() {
+		var s dbShare
+		if err := rows.Scan(s.dest()...); err != nil {
+			return nil, err
+		}
+		ps, err := s.publicShare()
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, &publicshare.WithPassword{PublicShare: ps, Password: s.Password})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// Load inserts the given public shares in a single transaction. Nothing is
+// stored if any of the shares clashes with an existing id or token.
+func (m *manager) Load(ctx context.Context, shares []*publicshare.WithPassword) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	exists := m.dialect.Rebind("SELECT COUNT(*) FROM public_shares WHERE id=? OR token=?")
+	for _, ws := range shares {
+		ps := ws.PublicShare
+		var n int
+		if err := tx.QueryRowContext(ctx, exists, ps.Id.GetOpaqueId(), ps.Token).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			return errtypes.AlreadyExists(ps.Id.GetOpaqueId())
+		}
+
+		s, err := newDBShare(ps, ws.Password)
+		if err != nil {
+			return err
+		}
+		if err := m.insert(ctx, tx, s); err != nil {
+			return errors.Wrap(err, "sqldb: error inserting public share "+ps.Token)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RenameUser replaces the id of a user in the owner and creator of the public shares.
+func (m *manager) RenameUser(ctx context.Context, oldID, newID *user.UserId) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, q := range []string{
+		"UPDATE public_shares SET owner_idp=?, owner_id=? WHERE owner_idp=? AND owner_id=?",
+		"UPDATE public_shares SET creator_idp=?, creator_id=? WHERE creator_idp=? AND creator_id=?",
+	} {
+		if _, err := tx.ExecContext(ctx, m.dialect.Rebind(q), newID.GetIdp(), newID.GetOpaqueId(), oldID.GetIdp(), oldID.GetOpaqueId()); err != nil {
+			return errors.Wrap(err, "sqldb: error renaming user "+oldID.GetOpaqueId())
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUploadOptions returns the upload options of the public share with the given id.
+func (m *manager) GetUploadOptions(ctx context.Context, id *link.PublicShareId) (*publicshare.UploadOptions, error) {
+	var raw sql.NullString
+	query := m.dialect.Rebind("SELECT upload_options FROM public_shares WHERE id=?")
+	if err := m.router.QueryRow(ctx, []interface{}{&raw}, query, id.GetOpaqueId()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errtypes.NotFound(id.GetOpaqueId())
+		}
+		return nil, err
+	}
+	if !raw.Valid || raw.String == "" {
+		return nil, nil
+	}
+	opts := &publicshare.UploadOptions{}
+	if err := json.Unmarshal([]byte(raw.String), opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// SetUploadOptions stores the upload options of the public share with the given id.
+func (m *manager) SetUploadOptions(ctx context.Context, id *link.PublicShareId, o *publicshare.UploadOptions) error {
+	enc, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	query := m.dialect.Rebind("UPDATE public_shares SET upload_options=? WHERE id=?")
+	res, err := m.router.Writer(ctx).ExecContext(ctx, query, string(enc), id.GetOpaqueId())
+	if err != nil {
+		return errors.Wrap(err, "sqldb: error storing upload options")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		// MySQL counts the changed rows only, so check that the share exists.
+		var count int
+		exists := m.dialect.Rebind("SELECT COUNT(*) FROM public_shares WHERE id=?")
+		if err := m.db.QueryRowContext(ctx, exists, id.GetOpaqueId()).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return errtypes.NotFound(id.GetOpaqueId())
+		}
+	}
+	return nil
+}
+
+// GetAccessStats returns the statistics of the public share with the given id.
+func (m *manager) GetAccessStats(ctx context.Context, id *link.PublicShareId) (*publicshare.AccessStats, error) {
+	s := &publicshare.AccessStats{}
+	query := m.dialect.Rebind("SELECT access_count, download_count, bytes_served, last_access, max_downloads FROM public_shares WHERE id=?")
+	if err := m.router.QueryRow(ctx, []interface{}{&s.Accesses, &s.Downloads, &s.Bytes, &s.LastAccess, &s.MaxDownloads}, query, id.GetOpaqueId()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errtypes.NotFound(id.GetOpaqueId())
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// RecordAccess records an access to the public share with the given id.
+func (m *manager) RecordAccess(ctx context.Context, id *link.PublicShareId, a *publicshare.Access) (*publicshare.AccessStats, error) {
+	var accesses, downloads uint64
+	if a.Download {
+		downloads = 1
+	} else {
+		accesses = 1
+	}
+	query := m.dialect.Rebind("UPDATE public_shares SET access_count=access_count+?, download_count=download_count+?, bytes_served=bytes_served+?, last_access=? WHERE id=?")
+	if _, err := m.router.Writer(ctx).ExecContext(ctx, query, accesses, downloads, a.Bytes, time.Now().Unix(), id.GetOpaqueId()); err != nil {
+		return nil, errors.Wrap(err, "sqldb: error recording access")
+	}
+	return m.GetAccessStats(ctx, id)
+}
+
+// SetMaxDownloads sets the download limit of the public share with the given id.
+func (m *manager) SetMaxDownloads(ctx context.Context, id *link.PublicShareId, max uint64) error {
+	query := m.dialect.Rebind("UPDATE public_shares SET max_downloads=? WHERE id=?")
+	if _, err := m.router.Writer(ctx).ExecContext(ctx, query, max, id.GetOpaqueId()); err != nil {
+		return errors.Wrap(err, "sqldb: error storing max downloads")
+	}
+	// MySQL counts the changed rows only, so check that the share exists.
+	_, err := m.GetAccessStats(ctx, id)
+	return err
+}
@@ -0,0 +1,171 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
+)
+
+func newTestManager(t *testing.T, dsn string) *manager {
+	m, err := New(map[string]interface{}{
+		"db_driver":          "sqlite3",
+		"db_dsn":             dsn,
+		"password_hash_cost": 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.(*manager)
+}
+
+func resource(id string) *provider.ResourceInfo {
+	return &provider.ResourceInfo{
+		Id:    &provider.ResourceId{StorageId: "storage", OpaqueId: id},
+		Owner: &user.UserId{Idp: "idp", OpaqueId: "owner"},
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{"name": id},
+		},
+	}
+}
+
+func TestPublicShares(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "publicshares.db")
+	m := newTestManager(t, dsn)
+
+	creator := &user.User{Id: &user.UserId{Idp: "idp", OpaqueId: "creator"}}
+	other := &user.User{Id: &user.UserId{Idp: "idp", OpaqueId: "other"}}
+	perms := &link.PublicSharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}}
+	past := &typespb.Timestamp{Seconds: uint64(time.Now().Add(-time.Hour).Unix())}
+
+	a, err := m.CreatePublicShare(ctx, creator, resource("a"), &link.Grant{Permissions: perms, Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.CreatePublicShare(ctx, creator, resource("b"), &link.Grant{Permissions: perms}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.CreatePublicShare(ctx, creator, resource("c"), &link.Grant{Permissions: perms, Expiration: past}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.GetPublicShare(ctx, creator, &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: a.Id}}, false)
+	if err != nil || got.Token != a.Token || !got.PasswordProtected || got.DisplayName != "a" {
+		t.Fatalf("unexpected share %v (%v)", got, err)
+	}
+	if _, err := m.GetPublicShare(ctx, other, &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: a.Id}}, false); !isNotFound(err) {
+		t.Fatalf("expected the share to be hidden from other users, got %v", err)
+	}
+
+	if _, err := m.GetPublicShareByToken(ctx, a.Token, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: "wrong"}}, false); err == nil {
+		t.Fatal("expected a wrong password to be rejected")
+	}
+	signed, err := m.GetPublicShareByToken(ctx, a.Token, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: "secret"}}, true)
+	if err != nil || signed.Signature == nil {
+		t.Fatalf("expected a signed share, got %v (%v)", signed, err)
+	}
+	if _, err := m.GetPublicShareByToken(ctx, a.Token, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Signature{Signature: signed.Signature}}, false); err != nil {
+		t.Fatalf("expected the signature to authenticate: %v", err)
+	}
+
+	all, err := m.ListPublicShares(ctx, creator, nil, nil, false)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("expected the two unexpired shares, got %d (%v)", len(all), err)
+	}
+	filtered, err := m.ListPublicShares(ctx, creator, []*link.ListPublicSharesRequest_Filter{
+		{Type: link.ListPublicSharesRequest_Filter_TYPE_RESOURCE_ID, Term: &link.ListPublicSharesRequest_Filter_ResourceId{ResourceId: resource("b").Id}},
+	}, nil, false)
+	if err != nil || len(filtered) != 1 || filtered[0].ResourceId.OpaqueId != "b" {
+		t.Fatalf("unexpected filtered shares %v (%v)", filtered, err)
+	}
+	if others, err := m.ListPublicShares(ctx, other, nil, nil, false); err != nil || len(others) != 0 {
+		t.Fatalf("expected no shares for other users, got %d (%v)", len(others), err)
+	}
+
+	updated, err := m.UpdatePublicShare(ctx, creator, &link.UpdatePublicShareRequest{
+		Ref:    &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: a.Id}},
+		Update: &link.UpdatePublicShareRequest_Update{Type: link.UpdatePublicShareRequest_Update_TYPE_PASSWORD, Grant: &link.Grant{}},
+	}, nil)
+	if err != nil || updated.PasswordProtected {
+		t.Fatalf("expected the password to be removed, got %v (%v)", updated, err)
+	}
+
+	opts := &publicshare.UploadOptions{MaxFileSize: 1024, NotifyUploads: true}
+	if err := m.SetUploadOptions(ctx, a.Id, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetUploadOptions(ctx, a.Id, opts); err != nil {
+		t.Fatalf("storing the same options again failed: %v", err)
+	}
+	if got, err := m.GetUploadOptions(ctx, a.Id); err != nil || *got != *opts {
+		t.Fatalf("unexpected upload options %v (%v)", got, err)
+	}
+
+	if err := m.SetMaxDownloads(ctx, a.Id, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.RecordAccess(ctx, a.Id, &publicshare.Access{}); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := m.RecordAccess(ctx, a.Id, &publicshare.Access{Download: true, Bytes: 100})
+	if err != nil || stats.Accesses != 1 || stats.Downloads != 1 || stats.Bytes != 100 || stats.LastAccess == 0 || stats.Exhausted() {
+		t.Fatalf("unexpected access stats %+v (%v)", stats, err)
+	}
+	if stats, err := m.RecordAccess(ctx, a.Id, &publicshare.Access{Download: true, Bytes: 50}); err != nil || stats.Bytes != 150 || !stats.Exhausted() {
+		t.Fatalf("expected the download limit to be reached, got %+v (%v)", stats, err)
+	}
+	if err := m.SetMaxDownloads(ctx, &link.PublicShareId{OpaqueId: "nope"}, 1); !isNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	// the schema is not migrated again when the database is reopened
+	reopened := newTestManager(t, dsn)
+	if err := reopened.RevokePublicShare(ctx, creator, &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: a.Token}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.GetPublicShareByToken(ctx, a.Token, nil, false); !isNotFound(err) {
+		t.Fatalf("expected the revoked share to be gone, got %v", err)
+	}
+
+	if err := reopened.cleanupExpiredShares(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dump, err := reopened.Dump(ctx)
+	if err != nil || len(dump) != 1 || dump[0].PublicShare.ResourceId.OpaqueId != "b" {
+		t.Fatalf("unexpected shares left %v (%v)", dump, err)
+	}
+	if err := reopened.Load(ctx, dump); err == nil {
+		t.Fatal("expected loading an existing share to fail")
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
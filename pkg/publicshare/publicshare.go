@@ -24,14 +24,22 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
 	"time"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/utils"
 )
 
+// UploadOptionsOpaqueKey is the opaque key under which the JSON encoded
+// UploadOptions of a link travel in the requests and responses of the link API.
+const UploadOptionsOpaqueKey = "upload_options"
+
 // Manager manipulates public shares.
 type Manager interface {
 	CreatePublicShare(ctx context.Context, u *user.User, md *provider.ResourceInfo, g *link.Grant) (*link.PublicShare, error)
@@ -42,6 +50,25 @@ type Manager interface {
 	GetPublicShareByToken(ctx context.Context, token string, auth *link.PublicShareAuthentication, sign bool) (*link.PublicShare, error)
 }
 
+// WithPassword holds a public share together with the hash of its password.
+type WithPassword struct {
+	PublicShare *link.PublicShare
+	Password    string
+}
+
+// Dumper is implemented by the managers that are able to export all the public
+// shares they hold, regardless of the user who created them.
+type Dumper interface {
+	Dump(ctx context.Context) ([]*WithPassword, error)
+}
+
+// Loader is implemented by the managers that are able to import public shares
+// previously exported by a Dumper. Tokens and password hashes are stored as they
+// are, so the links keep working after the import.
+type Loader interface {
+	Load(ctx context.Context, shares []*WithPassword) error
+}
+
 // CreateSignature calculates a signature for a public share.
 func CreateSignature(token, pw string, expiration time.Time) string {
 	h := sha256.New()
@@ -71,3 +98,108 @@ func AddSignature(share *link.PublicShare, pw string) {
 		},
 	}
 }
+
+// UserRenamer is implemented by the managers that are able to replace the id of
+// a user in all the public shares it owns or created.
+type UserRenamer interface {
+	RenameUser(ctx context.Context, oldID, newID *user.UserId) error
+}
+
+// UploadOptions are the settings of an upload only (file drop) link.
+type UploadOptions struct {
+	// MaxFileSize is the maximum size in bytes of a file uploaded to the
+	// link, 0 means no limit.
+	MaxFileSize uint64 `json:"max_file_size,omitempty"`
+	// NotifyUploads tells whether the creator of the link is notified about
+	// the files uploaded to it.
+	NotifyUploads bool `json:"notify_uploads"`
+}
+
+// UploadOptionsManager is implemented by the managers that are able to store
+// the upload options of the links.
+type UploadOptionsManager interface {
+	// GetUploadOptions returns the upload options of a link, or nil if none were set.
+	GetUploadOptions(ctx context.Context, id *link.PublicShareId) (*UploadOptions, error)
+	// SetUploadOptions stores the upload options of a link.
+	SetUploadOptions(ctx context.Context, id *link.PublicShareId, o *UploadOptions) error
+}
+
+// UploadOnlyPermissions returns the permissions of a file drop: files can be
+// uploaded to the shared folder, but its contents are never revealed.
+func UploadOnlyPermissions() *provider.ResourcePermissions {
+	return &provider.ResourcePermissions{
+		InitiateFileUpload: true,
+	}
+}
+
+// IsUploadOnly tells whether the permissions are the ones of a file drop.
+func IsUploadOnly(p *provider.ResourcePermissions) bool {
+	return p.GetInitiateFileUpload() && !p.GetStat() && !p.GetListContainer() && !p.GetInitiateFileDownload()
+}
+
+// EncodeUploadOptions adds the upload options to the opaque, which is created
+// if nil, and returns it.
+func EncodeUploadOptions(o *typesv1beta1.Opaque, opts *UploadOptions) (*typesv1beta1.Opaque, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		o = &typesv1beta1.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*typesv1beta1.OpaqueEntry{}
+	}
+	o.Map[UploadOptionsOpaqueKey] = &typesv1beta1.OpaqueEntry{
+		Decoder: "json",
+		Value:   data,
+	}
+	return o, nil
+}
+
+// DecodeUploadOptions reads the upload options carried by the opaque, it
+// returns nil if there are none.
+func DecodeUploadOptions(o *typesv1beta1.Opaque) (*UploadOptions, error) {
+	e, ok := o.GetMap()[UploadOptionsOpaqueKey]
+	if !ok {
+		return nil, nil
+	}
+	if e.Decoder != "json" {
+		return nil, errtypes.BadRequest("publicshare: opaque entry decoder not recognized: " + e.Decoder)
+	}
+	opts := &UploadOptions{}
+	if err := json.Unmarshal(e.Value, opts); err != nil {
+		return nil, errtypes.BadRequest("publicshare: error decoding upload options: " + err.Error())
+	}
+	return opts, nil
+}
+
+// MatchFilters tells whether the public share matches the filters of a
+// listing: one of the filters of each type has to match.
+func MatchFilters(s *link.PublicShare, filters []*link.ListPublicSharesRequest_Filter) bool {
+	matched := map[link.ListPublicSharesRequest_Filter_Type]bool{}
+	for _, f := range filters {
+		if !matched[f.Type] {
+			matched[f.Type] = matchFilter(s, f)
+		}
+	}
+	for _, m := range matched {
+		if !m {
+			return false
+		}
+	}
+	return true
+}
+
+func matchFilter(s *link.PublicShare, f *link.ListPublicSharesRequest_Filter) bool {
+	switch f.Type {
+	case link.ListPublicSharesRequest_Filter_TYPE_RESOURCE_ID:
+		return utils.ResourceEqual(s.ResourceId, f.GetResourceId())
+	case link.ListPublicSharesRequest_Filter_TYPE_OWNER:
+		return share.MatchUser(s.Owner, f.GetOwner())
+	case link.ListPublicSharesRequest_Filter_TYPE_CREATOR:
+		return share.MatchUser(s.Creator, f.GetCreator())
+	default:
+		return true
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshare
+
+import (
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func TestUploadOptionsOpaque(t *testing.T) {
+	opts, err := DecodeUploadOptions(nil)
+	if err != nil || opts != nil {
+		t.Fatalf("expected no options from a nil opaque, got %+v, %v", opts, err)
+	}
+
+	o := &typesv1beta1.Opaque{
+		Map: map[string]*typesv1beta1.OpaqueEntry{
+			"other": {Decoder: "plain", Value: []byte("value")},
+		},
+	}
+	o, err = EncodeUploadOptions(o, &UploadOptions{MaxFileSize: 1024, NotifyUploads: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := o.Map["other"]; !ok {
+		t.Fatal("the other opaque entries must be kept")
+	}
+
+	opts, err = DecodeUploadOptions(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts == nil || opts.MaxFileSize != 1024 || !opts.NotifyUploads {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+
+	o.Map[UploadOptionsOpaqueKey].Decoder = "plain"
+	if _, err := DecodeUploadOptions(o); err == nil {
+		t.Fatal("expected an error for an unknown decoder")
+	}
+}
+
+func TestIsUploadOnly(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions *provider.ResourcePermissions
+		expected    bool
+	}{
+		{"file drop", UploadOnlyPermissions(), true},
+		{"file drop creating folders", &provider.ResourcePermissions{InitiateFileUpload: true, CreateContainer: true}, true},
+		{"uploader listing the contents", &provider.ResourcePermissions{InitiateFileUpload: true, Stat: true, ListContainer: true}, false},
+		{"viewer", &provider.ResourcePermissions{Stat: true, ListContainer: true, InitiateFileDownload: true}, false},
+		{"none", nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsUploadOnly(tt.permissions); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestAccessStatsOpaque(t *testing.T) {
+	if stats, err := DecodeAccessStats(nil); err != nil || stats != nil {
+		t.Fatalf("expected no stats, got %v (%v)", stats, err)
+	}
+	o, err := EncodeAccessStats(nil, map[string]*AccessStats{"id": {Accesses: 2, Downloads: 1, Bytes: 10, MaxDownloads: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = EncodeAccess(o, &Access{Download: true, Bytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o = EncodeMaxDownloads(o, 5)
+
+	stats, err := DecodeAccessStats(o)
+	if err != nil || len(stats) != 1 || *stats["id"] != (AccessStats{Accesses: 2, Downloads: 1, Bytes: 10, MaxDownloads: 3}) {
+		t.Fatalf("unexpected stats %v (%v)", stats, err)
+	}
+	if a, err := DecodeAccess(o); err != nil || *a != (Access{Download: true, Bytes: 10}) {
+		t.Fatalf("unexpected access %v (%v)", a, err)
+	}
+	if max, ok, err := DecodeMaxDownloads(o); err != nil || !ok || max != 5 {
+		t.Fatalf("unexpected max downloads %d %v (%v)", max, ok, err)
+	}
+
+	o.Map[MaxDownloadsOpaqueKey].Value = []byte("many")
+	if _, _, err := DecodeMaxDownloads(o); err == nil {
+		t.Fatal("expected an invalid max downloads to fail")
+	}
+	o.Map[AccessOpaqueKey].Decoder = "plain"
+	if _, err := DecodeAccess(o); err == nil {
+		t.Fatal("expected an unknown decoder to fail")
+	}
+}
+
+func TestAccessStatsRecord(t *testing.T) {
+	now := time.Now()
+	s := &AccessStats{MaxDownloads: 2}
+	s.Record(&Access{}, now)
+	s.Record(&Access{Download: true, Bytes: 100}, now)
+	if s.Accesses != 1 || s.Downloads != 1 || s.Bytes != 100 || s.LastAccess != now.Unix() || s.Exhausted() {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	s.Record(&Access{Download: true, Bytes: 50}, now)
+	if !s.Exhausted() || s.Bytes != 150 {
+		t.Fatalf("expected the link to reach its download limit, got %+v", s)
+	}
+	var none *AccessStats
+	if none.Exhausted() {
+		t.Fatal("expected a link without stats not to be exhausted")
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshare
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+const (
+	// AccessStatsOpaqueKey is the opaque key under which the JSON encoded
+	// AccessStats of the links, by link id, travel in the responses of the
+	// link API.
+	AccessStatsOpaqueKey = "access_stats"
+	// AccessOpaqueKey is the opaque key of the JSON encoded Access a
+	// GetPublicShare request by token records on the link.
+	AccessOpaqueKey = "record_access"
+	// MaxDownloadsOpaqueKey is the opaque key of the number of downloads
+	// after which a link created or updated with it expires, 0 removing the
+	// limit.
+	MaxDownloadsOpaqueKey = "max_downloads"
+)
+
+// AccessStats are the statistics of the accesses to a link.
+type AccessStats struct {
+	// Accesses counts the times the link was opened.
+	Accesses uint64 `json:"accesses"`
+	// Downloads counts the files downloaded through the link.
+	Downloads uint64 `json:"downloads"`
+	// Bytes is the size of the files downloaded through the link.
+	Bytes uint64 `json:"bytes"`
+	// LastAccess is the unix time of the last access, 0 if there was none.
+	LastAccess int64 `json:"last_access,omitempty"`
+	// MaxDownloads is the number of downloads after which the link expires,
+	// 0 means no limit.
+	MaxDownloads uint64 `json:"max_downloads,omitempty"`
+}
+
+// Access is an access to a link to record.
+type Access struct {
+	// Download tells whether a file was downloaded, or the link opened.
+	Download bool `json:"download,omitempty"`
+	// Bytes is the size of the downloaded file.
+	Bytes uint64 `json:"bytes,omitempty"`
+}
+
+// Record adds the access made at the given time to the statistics.
+func (s *AccessStats) Record(a *Access, t time.Time) {
+	if a.Download {
+		s.Downloads++
+		s.Bytes += a.Bytes
+	} else {
+		s.Accesses++
+	}
+	s.LastAccess = t.Unix()
+}
+
+// Exhausted tells whether the link reached its download limit, the link then
+// being expired.
+func (s *AccessStats) Exhausted() bool {
+	return s != nil && s.MaxDownloads > 0 && s.Downloads >= s.MaxDownloads
+}
+
+// AccessStatsManager is implemented by the managers that are able to keep the
+// statistics of the accesses to the links.
+type AccessStatsManager interface {
+	// GetAccessStats returns the statistics of a link.
+	GetAccessStats(ctx context.Context, id *link.PublicShareId) (*AccessStats, error)
+	// RecordAccess records an access to a link and returns its statistics.
+	RecordAccess(ctx context.Context, id *link.PublicShareId, a *Access) (*AccessStats, error)
+	// SetMaxDownloads sets the download limit of a link, 0 removing it.
+	SetMaxDownloads(ctx context.Context, id *link.PublicShareId, max uint64) error
+}
+
+func setOpaqueEntry(o *typesv1beta1.Opaque, key string, e *typesv1beta1.OpaqueEntry) *typesv1beta1.Opaque {
+	if o == nil {
+		o = &typesv1beta1.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*typesv1beta1.OpaqueEntry{}
+	}
+	o.Map[key] = e
+	return o
+}
+
+func decodeJSONEntry(o *typesv1beta1.Opaque, key string, v interface{}) (bool, error) {
+	e, ok := o.GetMap()[key]
+	if !ok {
+		return false, nil
+	}
+	if e.Decoder != "json" {
+		return false, errtypes.BadRequest("publicshare: opaque entry decoder not recognized: " + e.Decoder)
+	}
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return false, errtypes.BadRequest("publicshare: error decoding " + key + ": " + err.Error())
+	}
+	return true, nil
+}
+
+// EncodeAccessStats adds the statistics of the links, by link id, to the
+// opaque, which is created if nil, and returns it.
+func EncodeAccessStats(o *typesv1beta1.Opaque, stats map[string]*AccessStats) (*typesv1beta1.Opaque, error) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+	return setOpaqueEntry(o, AccessStatsOpaqueKey, &typesv1beta1.OpaqueEntry{Decoder: "json", Value: data}), nil
+}
+
+// DecodeAccessStats reads the statistics of the links carried by the opaque,
+// it returns nil if there are none.
+func DecodeAccessStats(o *typesv1beta1.Opaque) (map[string]*AccessStats, error) {
+	var stats map[string]*AccessStats
+	if ok, err := decodeJSONEntry(o, AccessStatsOpaqueKey, &stats); !ok {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// EncodeAccess adds the access to record to the opaque, which is created if
+// nil, and returns it.
+func EncodeAccess(o *typesv1beta1.Opaque, a *Access) (*typesv1beta1.Opaque, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return setOpaqueEntry(o, AccessOpaqueKey, &typesv1beta1.OpaqueEntry{Decoder: "json", Value: data}), nil
+}
+
+// DecodeAccess reads the access to record carried by the opaque, it returns
+// nil if there is none.
+func DecodeAccess(o *typesv1beta1.Opaque) (*Access, error) {
+	a := &Access{}
+	if ok, err := decodeJSONEntry(o, AccessOpaqueKey, a); !ok {
+		return nil, err
+	}
+	return a, nil
+}
+
+// EncodeMaxDownloads adds the download limit to the opaque, which is created
+// if nil, and returns it.
+func EncodeMaxDownloads(o *typesv1beta1.Opaque, max uint64) *typesv1beta1.Opaque {
+	return setOpaqueEntry(o, MaxDownloadsOpaqueKey, &typesv1beta1.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(strconv.FormatUint(max, 10)),
+	})
+}
+
+// DecodeMaxDownloads reads the download limit carried by the opaque, ok being
+// false if there is none.
+func DecodeMaxDownloads(o *typesv1beta1.Opaque) (max uint64, ok bool, err error) {
+	e, ok := o.GetMap()[MaxDownloadsOpaqueKey]
+	if !ok {
+		return 0, false, nil
+	}
+	if e.Decoder != "plain" {
+		return 0, false, errtypes.BadRequest("publicshare: opaque entry decoder not recognized: " + e.Decoder)
+	}
+	max, err = strconv.ParseUint(string(e.Value), 10, 64)
+	if err != nil {
+		return 0, false, errtypes.BadRequest("publicshare: invalid max downloads: " + string(e.Value))
+	}
+	return max, true, nil
+}
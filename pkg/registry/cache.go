@@ -0,0 +1,75 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"sync"
+	"time"
+)
+
+type cachedService struct {
+	svc     Service
+	err     error
+	expires time.Time
+}
+
+type cache struct {
+	r   Registry
+	ttl time.Duration
+
+	mu       sync.Mutex
+	services map[string]cachedService
+}
+
+// NewCache returns a registry keeping the services looked up in the registry, or the errors looking them up,
+// for the ttl, so that the addresses of the calls are not resolved by querying its backend each time.
+func NewCache(r Registry, ttl time.Duration) Registry {
+	return &cache{r: r, ttl: ttl, services: map[string]cachedService{}}
+}
+
+func (c *cache) Add(s Service) error {
+	c.forget(s.Name())
+	return c.r.Add(s)
+}
+
+func (c *cache) Remove(s Service) error {
+	c.forget(s.Name())
+	return c.r.Remove(s)
+}
+
+func (c *cache) GetService(name string) (Service, error) {
+	c.mu.Lock()
+	cs, ok := c.services[name]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cs.expires) {
+		return cs.svc, cs.err
+	}
+
+	svc, err := c.r.GetService(name)
+	c.mu.Lock()
+	c.services[name] = cachedService{svc: svc, err: err, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return svc, err
+}
+
+func (c *cache) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.services, name)
+}
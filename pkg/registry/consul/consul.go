@@ -0,0 +1,168 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package consul registers the services with the local consul agent, through its api client. Each node gets a TTL
+// check, passed or failed according to its health each time it is registered again, and is deregistered by consul
+// once the check has been critical for the deregister_after time, e.g. when the process died.
+package consul
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cs3org/reva/pkg/health"
+	"github.com/cs3org/reva/pkg/registry"
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("consul", New)
+}
+
+// addressKey is the key of the metadata holding the address of the node, which consul splits in host and port.
+const addressKey = "reva_address"
+
+type config struct {
+	// Address is the URL of the consul agent.
+	Address string `mapstructure:"address"`
+	// Token is the ACL token sent to the agent.
+	Token string `mapstructure:"token"`
+	// TTL is the time in seconds after which the check of a node not registered again becomes critical.
+	TTL int `mapstructure:"ttl"`
+	// DeregisterAfter is the time in seconds after which consul deregisters a node whose check is critical.
+	DeregisterAfter int `mapstructure:"deregister_after"`
+	// Timeout is the timeout in seconds of the requests to the agent.
+	Timeout int `mapstructure:"timeout"`
+}
+
+func (c *config) init() {
+	if c.Address == "" {
+		c.Address = "http://localhost:8500"
+	}
+	if c.TTL == 0 {
+		c.TTL = 30
+	}
+	if c.DeregisterAfter == 0 {
+		c.DeregisterAfter = 120
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5
+	}
+}
+
+type reg struct {
+	c      *config
+	client *api.Client
+}
+
+// New returns a registry registering the services with a consul agent.
+func New(m map[string]interface{}) (registry.Registry, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "consul: error decoding conf")
+	}
+	c.init()
+
+	client, err := api.NewClient(&api.Config{
+		Address:    c.Address,
+		Token:      c.Token,
+		HttpClient: &http.Client{Timeout: time.Duration(c.Timeout) * time.Second},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "consul: error creating client")
+	}
+	return &reg{c: c, client: client}, nil
+}
+
+func checkID(id string) string {
+	return "service:" + id
+}
+
+// Add implements the Registry interface. Registering a node again updates its metadata.
+func (r *reg) Add(s registry.Service) error {
+	for _, n := range s.Nodes() {
+		meta := map[string]string{addressKey: n.Address()}
+		for k, v := range n.Metadata() {
+			meta[k] = v
+		}
+		if err := r.client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+			ID:      n.ID(),
+			Name:    s.Name(),
+			Address: n.Address(),
+			Meta:    meta,
+			Check: &api.AgentServiceCheck{
+				CheckID:                        checkID(n.ID()),
+				TTL:                            fmt.Sprintf("%ds", r.c.TTL),
+				DeregisterCriticalServiceAfter: fmt.Sprintf("%ds", r.c.DeregisterAfter),
+			},
+		}); err != nil {
+			return errors.Wrap(err, "consul: error registering node")
+		}
+
+		status := api.HealthPassing
+		if !registry.Healthy(n) {
+			status = api.HealthCritical
+		}
+		if err := r.client.Agent().UpdateTTL(checkID(n.ID()), n.Metadata()[registry.HealthKey], status); err != nil {
+			return errors.Wrap(err, "consul: error updating check")
+		}
+	}
+	return nil
+}
+
+// GetService implements the Registry interface. The nodes whose checks are not all passing are reported unhealthy.
+func (r *reg) GetService(name string) (registry.Service, error) {
+	entries, _, err := r.client.Health().Service(name, "", false, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "consul: error looking up service")
+	}
+
+	nodes := make([]registry.Node, 0, len(entries))
+	for _, e := range entries {
+		meta := map[string]string{}
+		for k, v := range e.Service.Meta {
+			meta[k] = v
+		}
+		address := e.Service.Address
+		if a, ok := meta[addressKey]; ok {
+			address = a
+			delete(meta, addressKey)
+		}
+		if e.Checks.AggregatedStatus() != api.HealthPassing {
+			meta[registry.HealthKey] = string(health.Failing)
+		}
+		nodes = append(nodes, registry.NewNode(e.Service.ID, address, meta))
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("service %v not found", name)
+	}
+	return registry.NewService(name, nodes...), nil
+}
+
+// Remove implements the Registry interface.
+func (r *reg) Remove(s registry.Service) error {
+	for _, n := range s.Nodes() {
+		if err := r.client.Agent().ServiceDeregister(n.ID()); err != nil {
+			return errors.Wrap(err, "consul: error deregistering node")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/registry"
+	"github.com/hashicorp/consul/api"
+)
+
+// fakeAgent serves the endpoints of the consul agent used by the driver.
+type fakeAgent struct {
+	mu       sync.Mutex
+	services map[string]api.AgentServiceRegistration
+	checks   map[string]string
+}
+
+func (f *fakeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch p := r.URL.Path; {
+	case p == "/v1/agent/service/register":
+		var s api.AgentServiceRegistration
+		_ = json.NewDecoder(r.Body).Decode(&s)
+		f.services[s.ID] = s
+		if _, ok := f.checks[s.Check.CheckID]; !ok {
+			f.checks[s.Check.CheckID] = "critical"
+		}
+	case strings.HasPrefix(p, "/v1/agent/check/update/"):
+		var u struct{ Status string }
+		_ = json.NewDecoder(r.Body).Decode(&u)
+		f.checks[strings.TrimPrefix(p, "/v1/agent/check/update/")] = u.Status
+	case strings.HasPrefix(p, "/v1/agent/service/deregister/"):
+		delete(f.services, strings.TrimPrefix(p, "/v1/agent/service/deregister/"))
+	case strings.HasPrefix(p, "/v1/health/service/"):
+		entries := []map[string]interface{}{}
+		for _, s := range f.services {
+			if s.Name == strings.TrimPrefix(p, "/v1/health/service/") {
+				entries = append(entries, map[string]interface{}{
+					"Service": map[string]interface{}{"ID": s.ID, "Address": s.Address, "Meta": s.Meta},
+					"Checks":  []map[string]string{{"Status": f.checks[s.Check.CheckID]}},
+				})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(entries)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	f := &fakeAgent{services: map[string]api.AgentServiceRegistration{}, checks: map[string]string{}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	r, err := New(map[string]interface{}{"address": srv.URL, "token": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home := registry.NewService("storage-home",
+		registry.NewNode("a", "a:9142", map[string]string{registry.WeightKey: "2"}),
+		registry.NewNode("b", "http://b:9143/data", map[string]string{registry.HealthKey: "failing"}),
+	)
+	if err := r.Add(home); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := r.GetService("storage-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Nodes()) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(s.Nodes()))
+	}
+	for _, n := range s.Nodes() {
+		switch n.ID() {
+		case "a":
+			if n.Address() != "a:9142" || registry.Weight(n) != 2 || !registry.Healthy(n) {
+				t.Errorf("unexpected node %v %v", n.Address(), n.Metadata())
+			}
+			if _, ok := n.Metadata()[addressKey]; ok {
+				t.Error("address metadata not removed")
+			}
+		case "b":
+			if n.Address() != "http://b:9143/data" || registry.Healthy(n) {
+				t.Errorf("unexpected node %v %v", n.Address(), n.Metadata())
+			}
+		}
+	}
+	if addr, err := registry.Resolve(r, "storage-home"); err != nil || addr != "a:9142" {
+		t.Errorf("expected the healthy node, got %q, %v", addr, err)
+	}
+
+	if err := r.Remove(home); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetService("storage-home"); err == nil {
+		t.Error("expected the service to be removed")
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package etcd registers the services in etcd with its v3 client. The nodes are kept under
+// <prefix><service>/<node id> with a lease of the ttl, renewed each time they are registered again, so that the
+// nodes of the processes which died expire.
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func init() {
+	registry.Register("etcd", New)
+}
+
+type config struct {
+	// Endpoints are the addresses of the etcd members.
+	Endpoints []string `mapstructure:"endpoints"`
+	// Prefix is the prefix of the keys of the nodes.
+	Prefix string `mapstructure:"prefix"`
+	// TTL is the time in seconds after which a node not registered again expires.
+	TTL int `mapstructure:"ttl"`
+	// Timeout is the timeout in seconds of the requests to etcd.
+	Timeout int `mapstructure:"timeout"`
+	// Username and Password authenticate the client, if set.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+func (c *config) init() {
+	if len(c.Endpoints) == 0 {
+		c.Endpoints = []string{"http://localhost:2379"}
+	}
+	if c.Prefix == "" {
+		c.Prefix = "/reva/registry/"
+	}
+	if !strings.HasSuffix(c.Prefix, "/") {
+		c.Prefix += "/"
+	}
+	if c.TTL == 0 {
+		c.TTL = 30
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5
+	}
+}
+
+type reg struct {
+	c      *config
+	client *clientv3.Client
+
+	// leases maps the keys of the nodes registered by the process to their lease
+	mu     sync.Mutex
+	leases map[string]clientv3.LeaseID
+}
+
+// nodeValue is the value of a node key.
+type nodeValue struct {
+	ID       string            `json:"id"`
+	Address  string            `json:"address"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// New returns a registry storing the services in etcd.
+func New(m map[string]interface{}) (registry.Registry, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "etcd: error decoding conf")
+	}
+	c.init()
+
+	// the client connects in the background, the calls fail while no member is reachable
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   c.Endpoints,
+		DialTimeout: time.Duration(c.Timeout) * time.Second,
+		Username:    c.Username,
+		Password:    c.Password,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "etcd: error creating client")
+	}
+	return &reg{
+		c:      c,
+		client: client,
+		leases: map[string]clientv3.LeaseID{},
+	}, nil
+}
+
+func (r *reg) key(service, id string) string {
+	return r.c.Prefix + service + "/" + id
+}
+
+func (r *reg) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Duration(r.c.Timeout)*time.Second)
+}
+
+// Add implements the Registry interface, renewing the lease of the nodes already registered.
+func (r *reg) Add(s registry.Service) error {
+	ctx, cancel := r.context()
+	defer cancel()
+	for _, n := range s.Nodes() {
+		key := r.key(s.Name(), n.ID())
+		lease, err := r.lease(ctx, key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(nodeValue{ID: n.ID(), Address: n.Address(), Metadata: n.Metadata()})
+		if err != nil {
+			return err
+		}
+		if _, err := r.client.Put(ctx, key, string(v), clientv3.WithLease(lease)); err != nil {
+			return errors.Wrap(err, "etcd: error registering node")
+		}
+	}
+	return nil
+}
+
+// lease renews the lease of the key, or grants a new one if it has none or it expired.
+func (r *reg) lease(ctx context.Context, key string) (clientv3.LeaseID, error) {
+	r.mu.Lock()
+	id, ok := r.leases[key]
+	r.mu.Unlock()
+
+	if ok {
+		// a lease which expired, or which etcd lost, is replaced
+		if res, err := r.client.KeepAliveOnce(ctx, id); err == nil && res.TTL > 0 {
+			return id, nil
+		}
+	}
+
+	res, err := r.client.Grant(ctx, int64(r.c.TTL))
+	if err != nil {
+		return 0, errors.Wrap(err, "etcd: error granting lease")
+	}
+	r.mu.Lock()
+	r.leases[key] = res.ID
+	r.mu.Unlock()
+	return res.ID, nil
+}
+
+// GetService implements the Registry interface.
+func (r *reg) GetService(name string) (registry.Service, error) {
+	ctx, cancel := r.context()
+	defer cancel()
+	res, err := r.client.Get(ctx, r.c.Prefix+name+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Wrap(err, "etcd: error looking up service")
+	}
+
+	nodes := make([]registry.Node, 0, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		var v nodeValue
+		if err := json.Unmarshal(kv.Value, &v); err != nil {
+			return nil, errors.Wrap(err, "etcd: error decoding node")
+		}
+		nodes = append(nodes, registry.NewNode(v.ID, v.Address, v.Metadata))
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("service %v not found", name)
+	}
+	return registry.NewService(name, nodes...), nil
+}
+
+// Remove implements the Registry interface, revoking the leases of the nodes.
+func (r *reg) Remove(s registry.Service) error {
+	ctx, cancel := r.context()
+	defer cancel()
+	for _, n := range s.Nodes() {
+		key := r.key(s.Name(), n.ID())
+		if _, err := r.client.Delete(ctx, key); err != nil {
+			return errors.Wrap(err, "etcd: error removing node")
+		}
+
+		r.mu.Lock()
+		id, ok := r.leases[key]
+		delete(r.leases, key)
+		r.mu.Unlock()
+		if ok {
+			if _, err := r.client.Revoke(ctx, id); err != nil {
+				return errors.Wrap(err, "etcd: error revoking lease")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package etcd
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/registry"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"google.golang.org/grpc"
+)
+
+// fakeEtcd serves the parts of the KV and lease APIs used by the driver.
+type fakeEtcd struct {
+	pb.UnimplementedKVServer
+	pb.UnimplementedLeaseServer
+
+	mu      sync.Mutex
+	kvs     map[string]string
+	leases  map[int64]bool
+	granted int64
+}
+
+func (f *fakeEtcd) Put(ctx context.Context, req *pb.PutRequest) (*pb.PutResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kvs[string(req.Key)] = string(req.Value)
+	return &pb.PutResponse{Header: &pb.ResponseHeader{}}, nil
+}
+
+func (f *fakeEtcd) Range(ctx context.Context, req *pb.RangeRequest) (*pb.RangeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := &pb.RangeResponse{Header: &pb.ResponseHeader{}}
+	start, end := string(req.Key), string(req.RangeEnd)
+	for k, v := range f.kvs {
+		if k >= start && k < end {
+			res.Kvs = append(res.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+		}
+	}
+	res.Count = int64(len(res.Kvs))
+	return res, nil
+}
+
+func (f *fakeEtcd) DeleteRange(ctx context.Context, req *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.kvs, string(req.Key))
+	return &pb.DeleteRangeResponse{Header: &pb.ResponseHeader{}}, nil
+}
+
+func (f *fakeEtcd) LeaseGrant(ctx context.Context, req *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.granted++
+	f.leases[f.granted] = true
+	return &pb.LeaseGrantResponse{Header: &pb.ResponseHeader{}, ID: f.granted, TTL: req.TTL}, nil
+}
+
+func (f *fakeEtcd) LeaseRevoke(ctx context.Context, req *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.leases, req.ID)
+	return &pb.LeaseRevokeResponse{Header: &pb.ResponseHeader{}}, nil
+}
+
+func (f *fakeEtcd) LeaseKeepAlive(s pb.Lease_LeaseKeepAliveServer) error {
+	for {
+		req, err := s.Recv()
+		if err != nil {
+			return nil
+		}
+		f.mu.Lock()
+		res := &pb.LeaseKeepAliveResponse{Header: &pb.ResponseHeader{}, ID: req.ID}
+		if f.leases[req.ID] {
+			res.TTL = 30
+		}
+		f.mu.Unlock()
+		if err := s.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *fakeEtcd) counts() (int64, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.granted, len(f.leases)
+}
+
+func TestRegistry(t *testing.T) {
+	f := &fakeEtcd{kvs: map[string]string{}, leases: map[int64]bool{}}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterKVServer(srv, f)
+	pb.RegisterLeaseServer(srv, f)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	r, err := New(map[string]interface{}{"endpoints": []string{l.Addr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home := registry.NewService("storage-home",
+		registry.NewNode("a", "a:9142", map[string]string{registry.WeightKey: "2"}),
+		registry.NewNode("b", "b:9142", nil),
+	)
+	// registering again renews the leases
+	for i := 0; i < 2; i++ {
+		if err := r.Add(home); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if granted, _ := f.counts(); granted != 2 {
+		t.Errorf("expected 2 leases, got %d", granted)
+	}
+	if err := r.Add(registry.NewService("storage-homes", registry.NewNode("c", "c:9142", nil))); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := r.GetService("storage-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Nodes()) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(s.Nodes()))
+	}
+	for _, n := range s.Nodes() {
+		if n.ID() == "a" && (n.Address() != "a:9142" || registry.Weight(n) != 2) {
+			t.Errorf("unexpected node %v %v", n.Address(), n.Metadata())
+		}
+	}
+
+	// an expired lease is replaced
+	f.mu.Lock()
+	f.leases = map[int64]bool{}
+	f.mu.Unlock()
+	if err := r.Add(home); err != nil {
+		t.Fatal(err)
+	}
+	if granted, _ := f.counts(); granted != 5 {
+		t.Errorf("expected 5 leases, got %d", granted)
+	}
+
+	if err := r.Remove(home); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetService("storage-home"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected the service to be removed, got %v", err)
+	}
+	if _, leases := f.counts(); leases != 0 {
+		t.Errorf("expected the leases to be revoked, %d left", leases)
+	}
+}
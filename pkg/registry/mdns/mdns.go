@@ -0,0 +1,266 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package mdns announces the services with multicast DNS on the local network, for the installations without a
+// registry server. Each node is an instance of the _<service>._tcp service, its id, address and metadata being kept
+// in its TXT records, and looking a service up queries the network for the timeout. The nodes of a process are
+// withdrawn when it stops answering, e.g. when it died.
+package mdns
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/registry"
+	"github.com/hashicorp/mdns"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("mdns", New)
+}
+
+// the keys of the TXT records of a node, the metadata keys being prefixed
+const (
+	idKey      = "id="
+	addressKey = "address="
+	metaPrefix = "meta."
+)
+
+type config struct {
+	// Domain is the domain the services are announced in.
+	Domain string `mapstructure:"domain"`
+	// Timeout is the time in milliseconds the answers to a lookup are waited for.
+	Timeout int `mapstructure:"timeout"`
+}
+
+func (c *config) init() {
+	if c.Domain == "" {
+		c.Domain = "local"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 500
+	}
+}
+
+type reg struct {
+	c *config
+
+	// servers maps the services and ids of the nodes announced by the process to their server
+	mu      sync.Mutex
+	servers map[string]*server
+}
+
+type server struct {
+	txt []string
+	*mdns.Server
+}
+
+// New returns a registry announcing the services with multicast DNS.
+func New(m map[string]interface{}) (registry.Registry, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "mdns: error decoding conf")
+	}
+	c.init()
+	return &reg{c: c, servers: map[string]*server{}}, nil
+}
+
+func serviceName(name string) string {
+	return "_" + name + "._tcp"
+}
+
+func txt(n registry.Node) []string {
+	records := []string{idKey + n.ID(), addressKey + n.Address()}
+	for k, v := range n.Metadata() {
+		records = append(records, metaPrefix+k+"="+v)
+	}
+	return records
+}
+
+// hostPort returns the host and the port of the address of a node, which may be an URL.
+func hostPort(address string) (string, int, error) {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		address = u.Host
+		if u.Port() == "" {
+			port := "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+			address = net.JoinHostPort(u.Hostname(), port)
+		}
+	}
+	host, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "mdns: invalid node address "+address)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "mdns: invalid node address "+address)
+	}
+	return host, port, nil
+}
+
+// zone returns the records announcing the node, under the host name of the machine when the node has an IP address.
+func (r *reg) zone(service string, n registry.Node, records []string) (*mdns.MDNSService, error) {
+	host, port, err := hostPort(n.Address())
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil || host == "" {
+		if ip != nil && !ip.IsUnspecified() {
+			ips = []net.IP{ip}
+		}
+		if host, err = os.Hostname(); err != nil {
+			return nil, errors.Wrap(err, "mdns: error getting host name")
+		}
+	}
+	// the instance name is a single label
+	instance := strings.ReplaceAll(n.ID(), ".", "-")
+	zone, err := mdns.NewMDNSService(instance, serviceName(service), r.c.Domain+".", strings.TrimSuffix(host, ".")+".", port, ips, records)
+	if err != nil {
+		return nil, errors.Wrap(err, "mdns: error creating service")
+	}
+	return zone, nil
+}
+
+// Add implements the Registry interface. A node is announced again when its metadata changed.
+func (r *reg) Add(s registry.Service) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, n := range s.Nodes() {
+		key := s.Name() + "/" + n.ID()
+		records := txt(n)
+		old, ok := r.servers[key]
+		if ok && sameRecords(old.txt, records) {
+			continue
+		}
+
+		zone, err := r.zone(s.Name(), n, records)
+		if err != nil {
+			return err
+		}
+		if ok {
+			delete(r.servers, key)
+			_ = old.Shutdown()
+		}
+		srv, err := mdns.NewServer(&mdns.Config{Zone: zone})
+		if err != nil {
+			return errors.Wrap(err, "mdns: error starting server")
+		}
+		r.servers[key] = &server{txt: records, Server: srv}
+	}
+	return nil
+}
+
+func sameRecords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		if !seen[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// maxEntries is the number of answers to a lookup kept.
+const maxEntries = 256
+
+// GetService implements the Registry interface.
+func (r *reg) GetService(name string) (registry.Service, error) {
+	// the entries are only read once the lookup is over, as the client keeps updating those it sent
+	entries := make(chan *mdns.ServiceEntry, maxEntries)
+	if err := mdns.Query(&mdns.QueryParam{
+		Service: serviceName(name),
+		Domain:  r.c.Domain,
+		Timeout: time.Duration(r.c.Timeout) * time.Millisecond,
+		Entries: entries,
+	}); err != nil {
+		return nil, errors.Wrap(err, "mdns: error looking up service")
+	}
+	close(entries)
+
+	var nodes []registry.Node
+	seen := map[string]bool{}
+	suffix := "." + serviceName(name) + "." + r.c.Domain + "."
+	for e := range entries {
+		if !strings.HasSuffix(e.Name, suffix) {
+			continue
+		}
+		if n, ok := node(e); ok && !seen[n.ID()] {
+			seen[n.ID()] = true
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("service %v not found", name)
+	}
+	return registry.NewService(name, nodes...), nil
+}
+
+// node decodes the node announced in the TXT records of an entry.
+func node(e *mdns.ServiceEntry) (registry.Node, bool) {
+	var id, address string
+	meta := map[string]string{}
+	for _, f := range e.InfoFields {
+		switch {
+		case strings.HasPrefix(f, idKey):
+			id = strings.TrimPrefix(f, idKey)
+		case strings.HasPrefix(f, addressKey):
+			address = strings.TrimPrefix(f, addressKey)
+		case strings.HasPrefix(f, metaPrefix):
+			if kv := strings.SplitN(strings.TrimPrefix(f, metaPrefix), "=", 2); len(kv) == 2 {
+				meta[kv[0]] = kv[1]
+			}
+		}
+	}
+	if id == "" || address == "" {
+		return nil, false
+	}
+	return registry.NewNode(id, address, meta), true
+}
+
+// Remove implements the Registry interface, withdrawing the nodes.
+func (r *reg) Remove(s registry.Service) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, n := range s.Nodes() {
+		key := s.Name() + "/" + n.ID()
+		if srv, ok := r.servers[key]; ok {
+			delete(r.servers, key)
+			if err := srv.Shutdown(); err != nil {
+				return errors.Wrap(err, "mdns: error stopping server")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package mdns
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/registry"
+)
+
+func TestRegistry(t *testing.T) {
+	r, err := New(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home := registry.NewService("storage-home",
+		registry.NewNode("a", "127.0.0.1:9142", map[string]string{registry.WeightKey: "2"}),
+		registry.NewNode("b", "http://127.0.0.1:9143/data", map[string]string{registry.HealthKey: "failing"}),
+	)
+	if err := r.Add(home); err != nil {
+		if strings.Contains(err.Error(), "multicast") {
+			t.Skip("multicast is not available:", err)
+		}
+		t.Fatal(err)
+	}
+	// registering again with the same metadata keeps the servers
+	servers := r.(*reg).servers["storage-home/a"]
+	if err := r.Add(home); err != nil {
+		t.Fatal(err)
+	}
+	if r.(*reg).servers["storage-home/a"] != servers {
+		t.Error("the node was announced again")
+	}
+	defer func() { _ = r.Remove(home) }()
+
+	s, err := r.GetService("storage-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Nodes()) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(s.Nodes()))
+	}
+	for _, n := range s.Nodes() {
+		switch n.ID() {
+		case "a":
+			if n.Address() != "127.0.0.1:9142" || registry.Weight(n) != 2 || !registry.Healthy(n) {
+				t.Errorf("unexpected node %v %v", n.Address(), n.Metadata())
+			}
+		case "b":
+			if n.Address() != "http://127.0.0.1:9143/data" || registry.Healthy(n) {
+				t.Errorf("unexpected node %v %v", n.Address(), n.Metadata())
+			}
+		default:
+			t.Errorf("unexpected node %v", n.ID())
+		}
+	}
+	if _, err := r.GetService("storage-homes"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected another service not to be found, got %v", err)
+	}
+
+	if err := r.Remove(home); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetService("storage-home"); err == nil {
+		t.Error("expected the service to be removed")
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	for address, want := range map[string]string{
+		"localhost:9142":             "localhost 9142",
+		"http://localhost:9143/data": "localhost 9143",
+		"https://cloud.example.org":  "cloud.example.org 443",
+		"[::1]:9142":                 "::1 9142",
+	} {
+		host, port, err := hostPort(address)
+		if err != nil {
+			t.Errorf("hostPort(%s): %v", address, err)
+			continue
+		}
+		if got := host + " " + strconv.Itoa(port); got != want {
+			t.Errorf("hostPort(%s) = %s, want %s", address, got, want)
+		}
+	}
+	if _, _, err := hostPort("localhost"); err == nil {
+		t.Error("expected an address without port to be invalid")
+	}
+}
@@ -33,7 +33,8 @@ type Registry struct {
 	services map[string]registry.Service
 }
 
-// Add implements the Registry interface. If the service is already known in this registry it will only update the nodes.
+// Add implements the Registry interface. If the service is already known in this registry it will only update the nodes,
+// the nodes added replacing the registered ones with the same id.
 func (r *Registry) Add(svc registry.Service) error {
 	r.Lock()
 	defer r.Unlock()
@@ -51,7 +52,38 @@ func (r *Registry) Add(svc registry.Service) error {
 		return nil
 	}
 
-	r.services[svc.Name()] = svc
+	// keep a copy, the caller may reuse the service it registered
+	s := service{name: svc.Name()}
+	s.mergeNodes(svc.Nodes(), nil)
+	r.services[svc.Name()] = s
+	return nil
+}
+
+// Remove implements the Registry interface.
+func (r *Registry) Remove(svc registry.Service) error {
+	r.Lock()
+	defer r.Unlock()
+
+	registered, ok := r.services[svc.Name()]
+	if !ok {
+		return nil
+	}
+
+	removed := map[string]bool{}
+	for _, n := range svc.Nodes() {
+		removed[n.ID()] = true
+	}
+	s := service{name: svc.Name()}
+	for _, n := range registered.Nodes() {
+		if !removed[n.ID()] {
+			s.nodes = append(s.nodes, node{id: n.ID(), address: n.Address(), metadata: n.Metadata()})
+		}
+	}
+	if len(s.nodes) == 0 {
+		delete(r.services, svc.Name())
+		return nil
+	}
+	r.services[svc.Name()] = s
 	return nil
 }
 
@@ -68,6 +100,12 @@ func (r *Registry) GetService(name string) (registry.Service, error) {
 	return nil, fmt.Errorf("service %v not found", name)
 }
 
+func init() {
+	registry.Register("memory", func(m map[string]interface{}) (registry.Registry, error) {
+		return New(m), nil
+	})
+}
+
 // New returns an implementation of the Registry interface.
 func New(m map[string]interface{}) registry.Registry {
 	// c, err := registry.ParseConfig(m)
@@ -18,18 +18,29 @@
 
 package memory
 
-import "github.com/cs3org/reva/pkg/registry"
+import (
+	"fmt"
+
+	"github.com/cs3org/reva/pkg/registry"
+)
 
 // NewService creates a new memory registry.Service.
 func NewService(name string, nodes []interface{}) registry.Service {
 	n := make([]node, 0)
 	for i := 0; i < len(nodes); i++ {
-		n = append(n, node{
-			// explicit type conversions because types are not exported to prevent from circular dependencies until released.
-			id:      nodes[i].(map[string]interface{})["id"].(string),
-			address: nodes[i].(map[string]interface{})["address"].(string),
-			//metadata: nodes[i].(map[string]interface{})["metadata"].(map[string]string),
-		})
+		// explicit type conversions because types are not exported to prevent from circular dependencies until released.
+		m := nodes[i].(map[string]interface{})
+		nd := node{
+			id:      m["id"].(string),
+			address: m["address"].(string),
+		}
+		if md, ok := m["metadata"].(map[string]interface{}); ok {
+			nd.metadata = make(map[string]string, len(md))
+			for k, v := range md {
+				nd.metadata[k] = fmt.Sprint(v)
+			}
+		}
+		n = append(n, nd)
 	}
 
 	return service{
@@ -58,9 +69,14 @@ func (s service) Nodes() []registry.Node {
 	return ret
 }
 
+// mergeNodes merges the nodes, the first ones replacing the nodes of the second with the same id.
 func (s *service) mergeNodes(n1, n2 []registry.Node) {
-	n1 = append(n1, n2...)
-	for _, n := range n1 {
+	seen := map[string]bool{}
+	for _, n := range append(n1, n2...) {
+		if seen[n.ID()] {
+			continue
+		}
+		seen[n.ID()] = true
 		s.nodes = append(s.nodes, node{
 			id:       n.ID(),
 			address:  n.Address(),
@@ -0,0 +1,139 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/cs3org/reva/pkg/health"
+)
+
+const (
+	// WeightKey is the metadata key of the weight of a node: the calls are spread over the nodes of a service
+	// in proportion to their weights, 1 by default. A node of weight 0 is drained.
+	WeightKey = "weight"
+	// HealthKey is the metadata key of the health of a node, the status of its readiness checks.
+	HealthKey = "health"
+)
+
+// NewNode returns a Node.
+func NewNode(id, address string, metadata map[string]string) Node {
+	return basicNode{id: id, address: address, metadata: metadata}
+}
+
+// NewService returns a Service made of the nodes.
+func NewService(name string, nodes ...Node) Service {
+	return basicService{name: name, nodes: nodes}
+}
+
+type basicNode struct {
+	id       string
+	address  string
+	metadata map[string]string
+}
+
+func (n basicNode) Address() string             { return n.address }
+func (n basicNode) Metadata() map[string]string { return n.metadata }
+func (n basicNode) ID() string                  { return n.id }
+
+type basicService struct {
+	name  string
+	nodes []Node
+}
+
+func (s basicService) Name() string  { return s.name }
+func (s basicService) Nodes() []Node { return s.nodes }
+
+// Weight returns the weight of the node.
+func Weight(n Node) int {
+	w, ok := n.Metadata()[WeightKey]
+	if !ok {
+		return 1
+	}
+	i, err := strconv.Atoi(w)
+	if err != nil || i < 0 {
+		return 1
+	}
+	return i
+}
+
+// Healthy tells whether the node is healthy, which the nodes not reporting their health are assumed to be.
+func Healthy(n Node) bool {
+	h, ok := n.Metadata()[HealthKey]
+	return !ok || h == string(health.OK)
+}
+
+// Select picks one of the healthy nodes of the service, with a probability proportional to its weight.
+func Select(s Service) (Node, error) {
+	var (
+		nodes []Node
+		total int
+	)
+	for _, n := range s.Nodes() {
+		if w := Weight(n); w > 0 && Healthy(n) {
+			nodes = append(nodes, n)
+			total += w
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("registry: no healthy node of service %v", s.Name())
+	}
+
+	r := rand.Intn(total)
+	for _, n := range nodes {
+		if r -= Weight(n); r < 0 {
+			return n, nil
+		}
+	}
+	return nodes[len(nodes)-1], nil
+}
+
+// Resolve returns the address of a node of the service registered under the name, picked by Select.
+func Resolve(r Registry, name string) (string, error) {
+	s, err := r.GetService(name)
+	if err != nil {
+		return "", err
+	}
+	n, err := Select(s)
+	if err != nil {
+		return "", err
+	}
+	return n.Address(), nil
+}
+
+// AdvertisedAddress returns the address the other processes reach a server listening on the address at,
+// the host name replacing an unspecified host.
+func AdvertisedAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return address
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return address
+	}
+	return net.JoinHostPort(hostname, port)
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSelect(t *testing.T) {
+	s := NewService("storage-home",
+		NewNode("a", "a:9142", map[string]string{WeightKey: "3"}),
+		NewNode("b", "b:9142", nil),
+		NewNode("c", "c:9142", map[string]string{HealthKey: "failing"}),
+		NewNode("d", "d:9142", map[string]string{WeightKey: "0"}),
+	)
+
+	picked := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		n, err := Select(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picked[n.ID()]++
+	}
+	if picked["c"] != 0 || picked["d"] != 0 {
+		t.Errorf("unhealthy or drained nodes picked: %v", picked)
+	}
+	if picked["a"] < 2*picked["b"] {
+		t.Errorf("weights not honored: %v", picked)
+	}
+
+	if _, err := Select(NewService("down", NewNode("c", "c:9142", map[string]string{HealthKey: "failing"}))); err == nil {
+		t.Error("expected an error without healthy nodes")
+	}
+}
+
+type countingRegistry struct {
+	services map[string]Service
+	lookups  int
+}
+
+func (r *countingRegistry) Add(s Service) error {
+	r.services[s.Name()] = s
+	return nil
+}
+
+func (r *countingRegistry) GetService(name string) (Service, error) {
+	r.lookups++
+	if s, ok := r.services[name]; ok {
+		return s, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (r *countingRegistry) Remove(s Service) error {
+	delete(r.services, s.Name())
+	return nil
+}
+
+func TestCache(t *testing.T) {
+	backend := &countingRegistry{services: map[string]Service{}}
+	c := NewCache(backend, time.Hour)
+
+	if _, err := c.GetService("storage-home"); err == nil {
+		t.Fatal("expected an error for an unknown service")
+	}
+	if _, err := c.GetService("storage-home"); err == nil || backend.lookups != 1 {
+		t.Fatalf("error not cached, %d lookups", backend.lookups)
+	}
+
+	// adding the service forgets the error
+	if err := c.Add(NewService("storage-home", NewNode("a", "a:9142", nil))); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		addr, err := Resolve(c, "storage-home")
+		if err != nil || addr != "a:9142" {
+			t.Fatalf("got %q, %v", addr, err)
+		}
+	}
+	if backend.lookups != 2 {
+		t.Errorf("expected 2 lookups, got %d", backend.lookups)
+	}
+}
+
+func TestAdvertisedAddress(t *testing.T) {
+	if a := AdvertisedAddress("10.0.0.5:9142"); a != "10.0.0.5:9142" {
+		t.Errorf("got %q", a)
+	}
+	for _, addr := range []string{"0.0.0.0:9142", ":9142", "[::]:9142"} {
+		if a := AdvertisedAddress(addr); a == addr {
+			t.Errorf("unspecified host of %q not replaced", addr)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/health"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// healthTimeout is the time each readiness check of the process is given.
+const healthTimeout = 5 * time.Second
+
+// Registrar keeps the services served by the process registered, along with their weight and the status of the
+// readiness checks of the process, by registering them again every interval, until it is stopped.
+type Registrar struct {
+	r        Registry
+	id       string
+	weight   int
+	interval time.Duration
+	names    map[string]string
+
+	mu       sync.Mutex
+	services map[string]string
+	announce chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewRegistrar returns a Registrar registering the services in the registry every interval with the weight. The
+// names map the names of the services to the ones they are registered under, which default to the former.
+func NewRegistrar(r Registry, weight int, interval time.Duration, names map[string]string) *Registrar {
+	return &Registrar{
+		r:        r,
+		id:       uuid.New().String(),
+		weight:   weight,
+		interval: interval,
+		names:    names,
+		services: map[string]string{},
+		announce: make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Announce adds the service served at the address to the services of the process, registered right away.
+func (r *Registrar) Announce(name, address string) {
+	if n, ok := r.names[name]; ok {
+		name = n
+	}
+	r.mu.Lock()
+	r.services[name] = address
+	r.mu.Unlock()
+
+	select {
+	case r.announce <- struct{}{}:
+	default:
+	}
+}
+
+// Run registers the services until the registrar is stopped.
+func (r *Registrar) Run() {
+	defer close(r.done)
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		r.register()
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+		case <-r.announce:
+		}
+	}
+}
+
+// Stop stops registering the services and deregisters them, so that they get no new calls.
+func (r *Registrar) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+		<-r.done
+		for _, s := range r.snapshot(string(health.Failing)) {
+			if err := r.r.Remove(s); err != nil {
+				log.Error().Err(err).Str("service", s.Name()).Msg("registry: error deregistering service")
+			}
+		}
+	})
+}
+
+func (r *Registrar) register() {
+	r.mu.Lock()
+	empty := len(r.services) == 0
+	r.mu.Unlock()
+	if empty {
+		return
+	}
+
+	status := health.Ready(context.Background(), healthTimeout).Status
+	for _, s := range r.snapshot(string(status)) {
+		if err := r.r.Add(s); err != nil {
+			log.Error().Err(err).Str("service", s.Name()).Msg("registry: error registering service")
+		}
+	}
+}
+
+// snapshot returns the services of the process, their node having the status.
+func (r *Registrar) snapshot(status string) []Service {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	services := make([]Service, 0, len(r.services))
+	for name, address := range r.services {
+		services = append(services, NewService(name, NewNode(name+"-"+r.id, address, map[string]string{
+			WeightKey: strconv.Itoa(r.weight),
+			HealthKey: status,
+		})))
+	}
+	return services
+}
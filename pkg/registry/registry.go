@@ -26,6 +26,21 @@ type Registry interface {
 	// GetService retrieves a Service and all of its nodes by Service name. It returns []*Service because we can have
 	// multiple versions of the same Service running alongside each others.
 	GetService(string) (Service, error)
+
+	// Remove deregisters the nodes of the Service, e.g. those of a process shutting down.
+	Remove(Service) error
+}
+
+// NewFunc is the function that registry implementations should register at init time.
+type NewFunc func(map[string]interface{}) (Registry, error)
+
+// NewFuncs is a map containing all the registered registry drivers.
+var NewFuncs = map[string]NewFunc{}
+
+// Register registers a new registry driver new function.
+// Not safe for concurrent use. Safe for use from package init.
+func Register(name string, f NewFunc) {
+	NewFuncs[name] = f
 }
 
 // Service defines a service.
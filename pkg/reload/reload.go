@@ -0,0 +1,247 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package reload applies the parts of the configuration of a reva process
+// that can be swapped at runtime, like the log level or the options of the
+// auth managers, without restarting the process and dropping its
+// connections. The components register a reload function for the section of
+// the configuration they are built from, which is called with the new
+// section whenever it changes. The outcome of the last reload is kept for
+// the operators.
+package reload
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Func applies the new configuration of a section. It must either apply it
+// completely or keep the current one and return an error.
+type Func func(conf map[string]interface{}) error
+
+// The outcomes of the reload of a section.
+const (
+	Reloaded = "reloaded"
+	Failed   = "failed"
+)
+
+// Result is the outcome of the reload of a section.
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Status is the outcome of a reload.
+type Status struct {
+	// Generation counts the reloads since the process started.
+	Generation int       `json:"generation"`
+	Time       time.Time `json:"time"`
+	// Trigger is what started the reload, e.g. a signal or a change of the
+	// configuration file.
+	Trigger string `json:"trigger"`
+	// Error is set when the new configuration couldn't be read.
+	Error string `json:"error,omitempty"`
+	// Sections are the results of the reloadable sections that changed.
+	Sections map[string]Result `json:"sections,omitempty"`
+	// RestartRequired lists the changed parts of the configuration that
+	// can't be reloaded at runtime and only apply after a restart.
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	funcs   = map[string]Func{}
+	started map[string]interface{}
+	applied = map[string]map[string]interface{}{}
+	last    *Status
+
+	// applying serializes the reloads, the reload functions are called
+	// without holding mu so that they may (un)register.
+	applying sync.Mutex
+)
+
+// Register registers the reload function of a section, given as the dotted
+// path of its keys, e.g. "grpc.services.authprovider".
+func Register(section string, f Func) {
+	mu.Lock()
+	defer mu.Unlock()
+	funcs[section] = f
+}
+
+// Unregister removes the reload function of a section.
+func Unregister(section string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(funcs, section)
+	delete(applied, section)
+}
+
+// Init sets the configuration the process was started with, to which the
+// reloaded configurations are compared.
+func Init(conf map[string]interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	started = conf
+	applied = map[string]map[string]interface{}{}
+	last = nil
+}
+
+// Apply calls the reload functions of the sections that differ in the given
+// configuration and returns the outcome. A section removed from the
+// configuration is not reloaded, as it only takes effect after a restart.
+func Apply(conf map[string]interface{}, trigger string) *Status {
+	applying.Lock()
+	defer applying.Unlock()
+
+	mu.Lock()
+	registered := make(map[string]Func, len(funcs))
+	for section, f := range funcs {
+		registered[section] = f
+	}
+	current := map[string]map[string]interface{}{}
+	for section := range registered {
+		if c, ok := applied[section]; ok {
+			current[section] = c
+		} else {
+			current[section] = lookup(started, section)
+		}
+	}
+	base := started
+	mu.Unlock()
+
+	s := newStatus(trigger)
+	for section, f := range registered {
+		c := lookup(conf, section)
+		if c == nil || reflect.DeepEqual(c, current[section]) {
+			continue
+		}
+		if err := f(c); err != nil {
+			s.Sections[section] = Result{Status: Failed, Error: err.Error()}
+			continue
+		}
+		s.Sections[section] = Result{Status: Reloaded}
+		mu.Lock()
+		applied[section] = c
+		mu.Unlock()
+	}
+
+	var restart []string
+	diff("", base, conf, registered, &restart)
+	for section := range registered {
+		if lookup(conf, section) == nil && lookup(base, section) != nil {
+			restart = append(restart, section)
+		}
+	}
+	sort.Strings(restart)
+	s.RestartRequired = restart
+
+	return record(s)
+}
+
+// Fail records a reload that failed before the new configuration could be
+// applied, e.g. because it couldn't be read.
+func Fail(trigger string, err error) *Status {
+	applying.Lock()
+	defer applying.Unlock()
+
+	s := newStatus(trigger)
+	s.Error = err.Error()
+	return record(s)
+}
+
+// Last returns the outcome of the last reload, nil if there was none.
+func Last() *Status {
+	mu.Lock()
+	defer mu.Unlock()
+	return last
+}
+
+func newStatus(trigger string) *Status {
+	return &Status{
+		Time:     time.Now(),
+		Trigger:  trigger,
+		Sections: map[string]Result{},
+	}
+}
+
+func record(s *Status) *Status {
+	mu.Lock()
+	defer mu.Unlock()
+	if last != nil {
+		s.Generation = last.Generation
+	}
+	s.Generation++
+	last = s
+	return s
+}
+
+// lookup returns the section at the dotted path, nil if there is none.
+func lookup(conf map[string]interface{}, section string) map[string]interface{} {
+	m := conf
+	for _, k := range strings.Split(section, ".") {
+		v, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		m = v
+	}
+	return m
+}
+
+// diff appends the paths of the keys that differ between old and cur to
+// changed, skipping the reloadable sections.
+func diff(prefix string, old, cur map[string]interface{}, reloadable map[string]Func, changed *[]string) {
+	keys := map[string]bool{}
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range cur {
+		keys[k] = true
+	}
+	for k := range keys {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if _, ok := reloadable[path]; ok {
+			continue
+		}
+		if reflect.DeepEqual(old[k], cur[k]) {
+			continue
+		}
+		o, ook := old[k].(map[string]interface{})
+		n, nok := cur[k].(map[string]interface{})
+		if ook && nok && containsReloadable(path, reloadable) {
+			diff(path, o, n, reloadable, changed)
+			continue
+		}
+		*changed = append(*changed, path)
+	}
+}
+
+func containsReloadable(path string, reloadable map[string]Func) bool {
+	for section := range reloadable {
+		if strings.HasPrefix(section, path+".") {
+			return true
+		}
+	}
+	return false
+}
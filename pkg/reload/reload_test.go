@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package reload
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func conf(level string, limit int, address string) map[string]interface{} {
+	return map[string]interface{}{
+		"log": map[string]interface{}{"level": level},
+		"grpc": map[string]interface{}{
+			"address": address,
+			"interceptors": map[string]interface{}{
+				"ratelimit": map[string]interface{}{"limit": limit},
+			},
+		},
+	}
+}
+
+func TestApply(t *testing.T) {
+	Init(conf("info", 10, "0.0.0.0:19000"))
+
+	var levels []interface{}
+	Register("log", func(c map[string]interface{}) error {
+		levels = append(levels, c["level"])
+		return nil
+	})
+	defer Unregister("log")
+	Register("grpc.interceptors.ratelimit", func(c map[string]interface{}) error {
+		return errors.New("invalid limit")
+	})
+	defer Unregister("grpc.interceptors.ratelimit")
+
+	s := Apply(conf("debug", 20, "0.0.0.0:19001"), "test")
+	if s.Generation != 1 || s.Trigger != "test" {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if s.Sections["log"].Status != Reloaded {
+		t.Errorf("log not reloaded: %+v", s.Sections)
+	}
+	if r := s.Sections["grpc.interceptors.ratelimit"]; r.Status != Failed || r.Error != "invalid limit" {
+		t.Errorf("unexpected ratelimit result %+v", r)
+	}
+	if !reflect.DeepEqual(s.RestartRequired, []string{"grpc.address"}) {
+		t.Errorf("unexpected restart required %v", s.RestartRequired)
+	}
+
+	// the applied sections are not reloaded again, the failed ones are retried
+	s = Apply(conf("debug", 20, "0.0.0.0:19000"), "test")
+	if _, ok := s.Sections["log"]; ok || len(levels) != 1 {
+		t.Errorf("unchanged log reloaded again: %+v", s.Sections)
+	}
+	if s.Sections["grpc.interceptors.ratelimit"].Status != Failed {
+		t.Errorf("failed section not retried: %+v", s.Sections)
+	}
+	if len(s.RestartRequired) != 0 {
+		t.Errorf("unexpected restart required %v", s.RestartRequired)
+	}
+
+	if s := Fail("test", errors.New("syntax error")); s.Generation != 3 || Last() != s {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
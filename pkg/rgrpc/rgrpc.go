@@ -23,12 +23,17 @@ import (
 	"io"
 	"net"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/cs3org/reva/internal/grpc/interceptors/appctx"
 	"github.com/cs3org/reva/internal/grpc/interceptors/auth"
 	"github.com/cs3org/reva/internal/grpc/interceptors/log"
 	"github.com/cs3org/reva/internal/grpc/interceptors/recovery"
 	"github.com/cs3org/reva/internal/grpc/interceptors/token"
+	"github.com/cs3org/reva/pkg/mtls"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/registry"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/mitchellh/mapstructure"
@@ -36,6 +41,7 @@ import (
 	"github.com/rs/zerolog"
 	"go.opencensus.io/plugin/ocgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -99,6 +105,11 @@ type config struct {
 	Services         map[string]map[string]interface{} `mapstructure:"services"`
 	Interceptors     map[string]map[string]interface{} `mapstructure:"interceptors"`
 	EnableReflection bool                              `mapstructure:"enable_reflection"`
+	// Advertise is the address the services are reachable at, registered in the service
+	// registry, by default the address with the host name replacing an unspecified host.
+	Advertise string `mapstructure:"advertise"`
+	// MTLS serves the services over mutual TLS with the certificate of the process.
+	MTLS bool `mapstructure:"mtls"`
 }
 
 func (c *config) init() {
@@ -109,15 +120,25 @@ func (c *config) init() {
 	if c.Address == "" {
 		c.Address = sharedconf.GetGatewaySVC("0.0.0.0:19000")
 	}
+
+	if c.ShutdownDeadline == 0 {
+		c.ShutdownDeadline = 10
+	}
+
+	if c.Advertise == "" {
+		c.Advertise = registry.AdvertisedAddress(c.Address)
+	}
 }
 
 // Server is a gRPC server.
 type Server struct {
-	s        *grpc.Server
-	conf     *config
-	listener net.Listener
-	log      zerolog.Logger
-	services map[string]Service
+	s         *grpc.Server
+	conf      *config
+	listener  net.Listener
+	log       zerolog.Logger
+	services  map[string]Service
+	cleanup   sync.Once
+	registrar *registry.Registrar
 }
 
 // NewServer returns a new Server.
@@ -134,6 +155,12 @@ func NewServer(m interface{}, log zerolog.Logger) (*Server, error) {
 	return server, nil
 }
 
+// SetRegistrar makes the server announce its services to the registrar once
+// they are started.
+func (s *Server) SetRegistrar(r *registry.Registrar) {
+	s.registrar = r
+}
+
 // Start starts the server.
 func (s *Server) Start(ln net.Listener) error {
 	if err := s.registerServices(); err != nil {
@@ -178,6 +205,7 @@ func (s *Server) registerServices() error {
 				return errors.Wrapf(err, "rgrpc: grpc service %s could not be started,", svcName)
 			}
 			s.services[svcName] = svc
+			ops.Register(ops.Services, "grpc/"+svcName, s.serviceProbe)
 			s.log.Info().Msgf("rgrpc: grpc service enabled: %s", svcName)
 		} else {
 			message := fmt.Sprintf("rgrpc: grpc service %s does not exist", svcName)
@@ -196,6 +224,13 @@ func (s *Server) registerServices() error {
 		return err
 	}
 	opts = append(opts, grpc.StatsHandler(&ocgrpc.ServerHandler{}))
+	if s.conf.MTLS {
+		src := mtls.Get()
+		if src == nil {
+			return errors.New("rgrpc: mtls enabled without the [mtls] section")
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(src.ServerConfig())))
+	}
 	grpcServer := grpc.NewServer(opts...)
 
 	for _, svc := range s.services {
@@ -209,31 +244,62 @@ func (s *Server) registerServices() error {
 
 	s.s = grpcServer
 
+	if s.registrar != nil {
+		for name := range s.services {
+			s.registrar.Announce(name, s.conf.Advertise)
+		}
+	}
+
 	return nil
 }
 
-// TODO(labkode): make closing with deadline.
+// serviceProbe reports the services as healthy while the server runs.
+func (s *Server) serviceProbe() ops.State {
+	return ops.State{
+		Health:  ops.Healthy,
+		Details: map[string]interface{}{"network": s.conf.Network, "address": s.conf.Address},
+	}
+}
+
+// cleanupServices closes the services, once the server stopped serving their
+// calls, e.g. so that the storage drivers are shut down after the transfers.
 func (s *Server) cleanupServices() {
-	for name, svc := range s.services {
-		if err := svc.Close(); err != nil {
-			s.log.Error().Err(err).Msgf("error closing service %q", name)
-		} else {
-			s.log.Info().Msgf("service %q correctly closed", name)
+	s.cleanup.Do(func() {
+		for name, svc := range s.services {
+			ops.Unregister(ops.Services, "grpc/"+name)
+			if err := svc.Close(); err != nil {
+				s.log.Error().Err(err).Msgf("error closing service %q", name)
+			} else {
+				s.log.Info().Msgf("service %q correctly closed", name)
+			}
 		}
-	}
+	})
 }
 
-// Stop stops the server.
+// Stop stops the server, aborting the calls in progress.
 func (s *Server) Stop() error {
-	s.cleanupServices()
 	s.s.Stop()
+	s.cleanupServices()
 	return nil
 }
 
-// GracefulStop gracefully stops the server.
+// GracefulStop stops the server from accepting new calls and waits for the
+// ones in progress until the shutdown deadline, after which they are aborted.
 func (s *Server) GracefulStop() error {
+	done := make(chan struct{})
+	go func() {
+		s.s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Duration(s.conf.ShutdownDeadline) * time.Second):
+		s.log.Warn().Msgf("grpc: calls still in progress after %d seconds, aborting them", s.conf.ShutdownDeadline)
+		s.s.Stop()
+		<-done
+	}
 	s.cleanupServices()
-	s.s.GracefulStop()
 	return nil
 }
 
@@ -24,6 +24,9 @@ package status
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
@@ -113,6 +116,52 @@ func NewInsufficientStorage(ctx context.Context, err error, msg string) *rpc.Sta
 	}
 }
 
+// NewResourceExhausted returns a Status with CODE_RESOURCE_EXHAUSTED and logs the msg.
+func NewResourceExhausted(ctx context.Context, err error, msg string) *rpc.Status {
+	log := appctx.GetLogger(ctx).With().CallerWithSkipFrameCount(3).Logger()
+	log.Warn().Err(err).Msg(msg)
+	return &rpc.Status{
+		Code:    rpc.Code_CODE_RESOURCE_EXHAUSTED,
+		Message: msg,
+		Trace:   getTrace(ctx),
+	}
+}
+
+// NewUnavailable returns a Status with CODE_UNAVAILABLE and logs the msg. A
+// positive retryAfter is appended to the message, from which RetryAfter
+// reads it back on the other side of the gateway.
+func NewUnavailable(ctx context.Context, err error, msg string, retryAfter time.Duration) *rpc.Status {
+	log := appctx.GetLogger(ctx).With().CallerWithSkipFrameCount(3).Logger()
+	log.Warn().Err(err).Msg(msg)
+	if retryAfter > 0 {
+		msg = fmt.Sprintf("%s (%s%s)", msg, retryAfterPrefix, retryAfter)
+	}
+	return &rpc.Status{
+		Code:    rpc.Code_CODE_UNAVAILABLE,
+		Message: msg,
+		Trace:   getTrace(ctx),
+	}
+}
+
+const retryAfterPrefix = "retry after "
+
+// RetryAfter returns the delay after which the request failed with an
+// unavailable status can be retried, as set by NewUnavailable.
+func RetryAfter(s *rpc.Status) (time.Duration, bool) {
+	if s.GetCode() != rpc.Code_CODE_UNAVAILABLE || !strings.HasSuffix(s.Message, ")") {
+		return 0, false
+	}
+	i := strings.LastIndex(s.Message, "("+retryAfterPrefix)
+	if i < 0 {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s.Message[i+len(retryAfterPrefix)+1 : len(s.Message)-1])
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
 // NewUnimplemented returns a Status with CODE_UNIMPLEMENTED and logs the msg.
 func NewUnimplemented(ctx context.Context, err error, msg string) *rpc.Status {
 	log := appctx.GetLogger(ctx).With().CallerWithSkipFrameCount(3).Logger()
@@ -159,6 +208,8 @@ func NewStatusFromErrType(ctx context.Context, msg string, err error) *rpc.Statu
 		return NewUnimplemented(ctx, err, "gateway: "+msg+":"+err.Error())
 	case errtypes.BadRequest:
 		return NewInvalidArg(ctx, "gateway: "+msg+":"+err.Error())
+	case errtypes.IsTooManyRequests:
+		return NewResourceExhausted(ctx, err, "gateway: "+msg+":"+err.Error())
 	}
 	return NewInternal(ctx, err, "gateway: "+msg+":"+err.Error())
 }
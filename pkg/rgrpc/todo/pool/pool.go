@@ -19,6 +19,7 @@
 package pool
 
 import (
+	"strings"
 	"sync"
 
 	appprovider "github.com/cs3org/go-cs3apis/cs3/app/provider/v1beta1"
@@ -39,8 +40,18 @@ import (
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	storageregistry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	spacespb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	guestspb "github.com/cs3org/reva/internal/grpc/services/guests/proto"
+	changespb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	useradminpb "github.com/cs3org/reva/internal/grpc/services/useradmin/proto"
+	"github.com/cs3org/reva/pkg/mtls"
+	"github.com/cs3org/reva/pkg/registry"
+	searchpb "github.com/cs3org/reva/pkg/search/proto"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
 	"go.opencensus.io/plugin/ocgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type provider struct {
@@ -76,13 +87,28 @@ var (
 	userProviders          = newProvider()
 	groupProviders         = newProvider()
 	dataTxs                = newProvider()
+	searchProviders        = newProvider()
+	userAdminProviders     = newProvider()
+	spacesProviders        = newProvider()
+	changeProviders        = newProvider()
+	guestProviders         = newProvider()
 )
 
+// mutual holds the addresses resolved from the names of the services
+// called over mutual TLS.
+var mutual sync.Map
+
 // NewConn creates a new connection to a grpc server
-// with open census tracing support.
-// TODO(labkode): make grpc tls configurable.
+// with open census tracing support, over mutual TLS
+// if the endpoint is one of the mtls endpoints.
 func NewConn(endpoint string) (*grpc.ClientConn, error) {
-	conn, err := grpc.Dial(endpoint, grpc.WithInsecure(), grpc.WithStatsHandler(&ocgrpc.ClientHandler{}))
+	creds := grpc.WithInsecure()
+	if s := mtls.Get(); s != nil {
+		if _, ok := mutual.Load(endpoint); ok || s.Mutual(endpoint) {
+			creds = grpc.WithTransportCredentials(credentials.NewTLS(s.ClientConfig()))
+		}
+	}
+	conn, err := grpc.Dial(endpoint, creds, grpc.WithStatsHandler(&ocgrpc.ClientHandler{}))
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +118,10 @@ func NewConn(endpoint string) (*grpc.ClientConn, error) {
 
 // GetGatewayServiceClient returns a GatewayServiceClient.
 func GetGatewayServiceClient(endpoint string) (gateway.GatewayAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	gatewayProviders.m.Lock()
 	defer gatewayProviders.m.Unlock()
 
@@ -112,6 +142,10 @@ func GetGatewayServiceClient(endpoint string) (gateway.GatewayAPIClient, error)
 
 // GetUserProviderServiceClient returns a UserProviderServiceClient.
 func GetUserProviderServiceClient(endpoint string) (user.UserAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	userProviders.m.Lock()
 	defer userProviders.m.Unlock()
 
@@ -131,6 +165,10 @@ func GetUserProviderServiceClient(endpoint string) (user.UserAPIClient, error) {
 
 // GetGroupProviderServiceClient returns a GroupProviderServiceClient.
 func GetGroupProviderServiceClient(endpoint string) (group.GroupAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	groupProviders.m.Lock()
 	defer groupProviders.m.Unlock()
 
@@ -150,6 +188,10 @@ func GetGroupProviderServiceClient(endpoint string) (group.GroupAPIClient, error
 
 // GetStorageProviderServiceClient returns a StorageProviderServiceClient.
 func GetStorageProviderServiceClient(endpoint string) (storageprovider.ProviderAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	storageProviders.m.Lock()
 	defer storageProviders.m.Unlock()
 
@@ -169,6 +211,10 @@ func GetStorageProviderServiceClient(endpoint string) (storageprovider.ProviderA
 
 // GetAuthRegistryServiceClient returns a new AuthRegistryServiceClient.
 func GetAuthRegistryServiceClient(endpoint string) (authregistry.RegistryAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	authRegistries.m.Lock()
 	defer authRegistries.m.Unlock()
 
@@ -191,6 +237,10 @@ func GetAuthRegistryServiceClient(endpoint string) (authregistry.RegistryAPIClie
 
 // GetAuthProviderServiceClient returns a new AuthProviderServiceClient.
 func GetAuthProviderServiceClient(endpoint string) (authprovider.ProviderAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	authProviders.m.Lock()
 	defer authProviders.m.Unlock()
 
@@ -210,6 +260,10 @@ func GetAuthProviderServiceClient(endpoint string) (authprovider.ProviderAPIClie
 
 // GetAppAuthProviderServiceClient returns a new AppAuthProviderServiceClient.
 func GetAppAuthProviderServiceClient(endpoint string) (applicationauth.ApplicationsAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	appAuthProviders.m.Lock()
 	defer appAuthProviders.m.Unlock()
 
@@ -229,6 +283,10 @@ func GetAppAuthProviderServiceClient(endpoint string) (applicationauth.Applicati
 
 // GetUserShareProviderClient returns a new UserShareProviderClient.
 func GetUserShareProviderClient(endpoint string) (collaboration.CollaborationAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	userShareProviders.m.Lock()
 	defer userShareProviders.m.Unlock()
 
@@ -248,6 +306,10 @@ func GetUserShareProviderClient(endpoint string) (collaboration.CollaborationAPI
 
 // GetOCMShareProviderClient returns a new OCMShareProviderClient.
 func GetOCMShareProviderClient(endpoint string) (ocm.OcmAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	ocmShareProviders.m.Lock()
 	defer ocmShareProviders.m.Unlock()
 
@@ -267,6 +329,10 @@ func GetOCMShareProviderClient(endpoint string) (ocm.OcmAPIClient, error) {
 
 // GetOCMInviteManagerClient returns a new OCMInviteManagerClient.
 func GetOCMInviteManagerClient(endpoint string) (invitepb.InviteAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	ocmInviteManagers.m.Lock()
 	defer ocmInviteManagers.m.Unlock()
 
@@ -286,6 +352,10 @@ func GetOCMInviteManagerClient(endpoint string) (invitepb.InviteAPIClient, error
 
 // GetPublicShareProviderClient returns a new PublicShareProviderClient.
 func GetPublicShareProviderClient(endpoint string) (link.LinkAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	publicShareProviders.m.Lock()
 	defer publicShareProviders.m.Unlock()
 
@@ -305,6 +375,10 @@ func GetPublicShareProviderClient(endpoint string) (link.LinkAPIClient, error) {
 
 // GetPreferencesClient returns a new PreferencesClient.
 func GetPreferencesClient(endpoint string) (preferences.PreferencesAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	preferencesProviders.m.Lock()
 	defer preferencesProviders.m.Unlock()
 
@@ -324,6 +398,10 @@ func GetPreferencesClient(endpoint string) (preferences.PreferencesAPIClient, er
 
 // GetAppRegistryClient returns a new AppRegistryClient.
 func GetAppRegistryClient(endpoint string) (appregistry.RegistryAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	appRegistries.m.Lock()
 	defer appRegistries.m.Unlock()
 
@@ -343,6 +421,10 @@ func GetAppRegistryClient(endpoint string) (appregistry.RegistryAPIClient, error
 
 // GetAppProviderClient returns a new AppRegistryClient.
 func GetAppProviderClient(endpoint string) (appprovider.ProviderAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	appProviders.m.Lock()
 	defer appProviders.m.Unlock()
 
@@ -362,6 +444,10 @@ func GetAppProviderClient(endpoint string) (appprovider.ProviderAPIClient, error
 
 // GetStorageRegistryClient returns a new StorageRegistryClient.
 func GetStorageRegistryClient(endpoint string) (storageregistry.RegistryAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	storageRegistries.m.Lock()
 	defer storageRegistries.m.Unlock()
 
@@ -381,6 +467,10 @@ func GetStorageRegistryClient(endpoint string) (storageregistry.RegistryAPIClien
 
 // GetOCMProviderAuthorizerClient returns a new OCMProviderAuthorizerClient.
 func GetOCMProviderAuthorizerClient(endpoint string) (ocmprovider.ProviderAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	ocmProviderAuthorizers.m.Lock()
 	defer ocmProviderAuthorizers.m.Unlock()
 
@@ -400,6 +490,10 @@ func GetOCMProviderAuthorizerClient(endpoint string) (ocmprovider.ProviderAPICli
 
 // GetOCMCoreClient returns a new OCMCoreClient.
 func GetOCMCoreClient(endpoint string) (ocmcore.OcmCoreAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	ocmCores.m.Lock()
 	defer ocmCores.m.Unlock()
 
@@ -419,6 +513,10 @@ func GetOCMCoreClient(endpoint string) (ocmcore.OcmCoreAPIClient, error) {
 
 // GetDataTxClient returns a new DataTxClient.
 func GetDataTxClient(endpoint string) (datatx.TxAPIClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
 	dataTxs.m.Lock()
 	defer dataTxs.m.Unlock()
 
@@ -436,18 +534,141 @@ func GetDataTxClient(endpoint string) (datatx.TxAPIClient, error) {
 	return v, nil
 }
 
-// getEndpointByName resolve service names to ip addresses present on the registry.
-//	func getEndpointByName(name string) (string, error) {
-//		if services, err := utils.GlobalRegistry.GetService(name); err == nil {
-//			if len(services) > 0 {
-//				for i := range services {
-//					for j := range services[i].Nodes() {
-//						// return the first one. This MUST be improved upon with selectors.
-//						return services[i].Nodes()[j].Address(), nil
-//					}
-//				}
-//			}
-//		}
-//
-//		return "", fmt.Errorf("could not get service by name: %v", name)
-//	}
+// GetSearchServiceClient returns a new SearchServiceClient.
+func GetSearchServiceClient(endpoint string) (searchpb.SearchServiceClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	searchProviders.m.Lock()
+	defer searchProviders.m.Unlock()
+
+	if c, ok := searchProviders.conn[endpoint]; ok {
+		return c.(searchpb.SearchServiceClient), nil
+	}
+
+	conn, err := NewConn(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	v := searchpb.NewSearchServiceClient(conn)
+	searchProviders.conn[endpoint] = v
+	return v, nil
+}
+
+// GetUserAdminServiceClient returns a new UserAdminServiceClient.
+func GetUserAdminServiceClient(endpoint string) (useradminpb.UserAdminServiceClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	userAdminProviders.m.Lock()
+	defer userAdminProviders.m.Unlock()
+
+	if c, ok := userAdminProviders.conn[endpoint]; ok {
+		return c.(useradminpb.UserAdminServiceClient), nil
+	}
+
+	conn, err := NewConn(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	v := useradminpb.NewUserAdminServiceClient(conn)
+	userAdminProviders.conn[endpoint] = v
+	return v, nil
+}
+
+// GetGuestServiceClient returns a new GuestServiceClient.
+func GetGuestServiceClient(endpoint string) (guestspb.GuestServiceClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	guestProviders.m.Lock()
+	defer guestProviders.m.Unlock()
+
+	if c, ok := guestProviders.conn[endpoint]; ok {
+		return c.(guestspb.GuestServiceClient), nil
+	}
+
+	conn, err := NewConn(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	v := guestspb.NewGuestServiceClient(conn)
+	guestProviders.conn[endpoint] = v
+	return v, nil
+}
+
+// GetSpacesServiceClient returns a new SpacesServiceClient, the service is
+// served by the gateway.
+func GetSpacesServiceClient(endpoint string) (spacespb.SpacesServiceClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	spacesProviders.m.Lock()
+	defer spacesProviders.m.Unlock()
+
+	if c, ok := spacesProviders.conn[endpoint]; ok {
+		return c.(spacespb.SpacesServiceClient), nil
+	}
+
+	conn, err := NewConn(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	v := spacespb.NewSpacesServiceClient(conn)
+	spacesProviders.conn[endpoint] = v
+	return v, nil
+}
+
+// GetChangeServiceClient returns a new ChangeServiceClient, the service is
+// served by the storage providers and the gateway.
+func GetChangeServiceClient(endpoint string) (changespb.ChangeServiceClient, error) {
+	endpoint, err := resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	changeProviders.m.Lock()
+	defer changeProviders.m.Unlock()
+
+	if c, ok := changeProviders.conn[endpoint]; ok {
+		return c.(changespb.ChangeServiceClient), nil
+	}
+
+	conn, err := NewConn(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	v := changespb.NewChangeServiceClient(conn)
+	changeProviders.conn[endpoint] = v
+	return v, nil
+}
+
+// resolve returns the address of a node of the service the endpoint names in
+// the service registry, picked among the healthy ones according to their
+// weights, so that the calls are spread over them. The endpoints which are
+// addresses, or the names unknown to the registry, are returned as they are.
+func resolve(endpoint string) (string, error) {
+	if strings.Contains(endpoint, ":") {
+		return endpoint, nil
+	}
+	s, err := utils.GlobalRegistry.GetService(endpoint)
+	if err != nil {
+		return endpoint, nil
+	}
+	n, err := registry.Select(s)
+	if err != nil {
+		return "", errors.Wrap(err, "pool: error resolving "+endpoint)
+	}
+	if s := mtls.Get(); s != nil && s.Mutual(endpoint) {
+		mutual.Store(n.Address(), true)
+	}
+	return n.Address(), nil
+}
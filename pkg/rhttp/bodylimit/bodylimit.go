@@ -0,0 +1,224 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package bodylimit bounds the size of the request bodies, rejecting the
+// oversized ones with 413 Request Entity Too Large before they are buffered
+// by the handlers, and drains the bodies left unread so that the connections
+// can be reused.
+package bodylimit
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cs3org/reva/pkg/appctx"
+)
+
+// ErrTooLarge is returned when reading a request body beyond its limit.
+var ErrTooLarge = errors.New("bodylimit: request body too large")
+
+// Config is the configuration of a Limiter.
+type Config struct {
+	// MaxSize is the maximum number of bytes of the request bodies, 0
+	// disables the limit.
+	MaxSize int64 `mapstructure:"max_size"`
+	// Methods overrides the maximum size for request methods, e.g. PUT.
+	// A size of 0 disables the limit of the method.
+	Methods map[string]int64 `mapstructure:"methods"`
+	// DrainSize is the maximum number of bytes of a body left unread by the
+	// handler that are discarded to reuse the connection, defaulting to 1 MiB.
+	// Larger bodies have the connection closed.
+	DrainSize int64 `mapstructure:"drain_size"`
+}
+
+func (c *Config) init() {
+	if c.DrainSize == 0 {
+		c.DrainSize = 1 << 20
+	}
+}
+
+// Enabled returns whether the config limits the bodies of any method.
+func (c *Config) Enabled() bool {
+	if c.MaxSize > 0 {
+		return true
+	}
+	for _, n := range c.Methods {
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Limiter bounds the bodies of the requests it handles.
+type Limiter struct {
+	maxSize   int64
+	methods   map[string]int64
+	drainSize int64
+}
+
+// New returns a Limiter for the config.
+func New(c *Config) *Limiter {
+	c.init()
+	methods := make(map[string]int64, len(c.Methods))
+	for m, n := range c.Methods {
+		methods[strings.ToUpper(m)] = n
+	}
+	return &Limiter{
+		maxSize:   c.MaxSize,
+		methods:   methods,
+		drainSize: c.DrainSize,
+	}
+}
+
+// limit returns the maximum body size of the method, 0 if it is unlimited.
+func (l *Limiter) limit(method string) int64 {
+	if n, ok := l.methods[method]; ok {
+		return n
+	}
+	return l.maxSize
+}
+
+// Handler bounds the bodies of the requests served by h. The requests
+// declaring a larger body are rejected without calling h, the others fail
+// reading beyond the limit and have their response replaced by a 413.
+func (l *Limiter) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Serve(w, r, h, l.limit(r.Method))
+	})
+}
+
+// Serve serves the request with h, bounding its body to max bytes the way
+// Handler does. A max of 0 or less does not limit the body.
+func (l *Limiter) Serve(w http.ResponseWriter, r *http.Request, h http.Handler, max int64) {
+	if max <= 0 || r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	log := appctx.GetLogger(r.Context())
+	if r.ContentLength > max {
+		log.Debug().Int64("length", r.ContentLength).Int64("max", max).Msg("bodylimit: request body too large")
+		tooLarge(w)
+		return
+	}
+
+	b := &body{ReadCloser: r.Body, n: max}
+	r.Body = b
+	h.ServeHTTP(&limitedWriter{ResponseWriter: w, b: b}, r)
+
+	if b.tooLarge() {
+		log.Debug().Int64("max", max).Msg("bodylimit: request body too large")
+		return
+	}
+	l.drain(b)
+}
+
+// drain discards what is left of the body after the handler returned, so
+// that the server can read the next request of the connection. The server
+// closes the connection when more than the drain size is left.
+func (l *Limiter) drain(b *body) {
+	_, _ = io.CopyN(ioutil.Discard, b, l.drainSize)
+}
+
+func tooLarge(w http.ResponseWriter) {
+	// the body is not read, the client must not send another request over the connection
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+}
+
+// body reads up to n bytes of a request body. It may be read by other
+// goroutines than the handler's, e.g. when it is forwarded.
+type body struct {
+	io.ReadCloser
+	n        int64
+	exceeded int32
+	err      error
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one more byte than allowed to tell the end of the body from an overflow
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.n {
+		b.n -= int64(n)
+		b.err = err
+		return n, err
+	}
+	n = int(b.n)
+	b.n = 0
+	b.err = ErrTooLarge
+	atomic.StoreInt32(&b.exceeded, 1)
+	return n, b.err
+}
+
+func (b *body) tooLarge() bool {
+	return atomic.LoadInt32(&b.exceeded) == 1
+}
+
+// limitedWriter replaces the response of the handler with a 413 once the body
+// was read beyond its limit, as the handlers report the failed reads as they
+// see fit.
+type limitedWriter struct {
+	http.ResponseWriter
+	b           *body
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *limitedWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.b.tooLarge() {
+		w.discard = true
+		tooLarge(w.ResponseWriter)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends the buffered data to the client, if the wrapped writer supports it.
+func (w *limitedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package bodylimit
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// unsized hides the length of a body, as for a chunked request.
+type unsized struct{ io.Reader }
+
+func (unsized) Close() error { return nil }
+
+func TestHandler(t *testing.T) {
+	// echo answers with the body read, or 500 when reading it fails
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+	conf := Config{MaxSize: 10, Methods: map[string]int64{"put": 20, "POST": 0}}
+	l := New(&conf)
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		sized   bool
+		code    int
+		reached bool
+	}{
+		{"within the limit", http.MethodPatch, "0123456789", true, http.StatusOK, true},
+		{"declared too large", http.MethodPatch, "0123456789a", true, http.StatusRequestEntityTooLarge, false},
+		{"chunked within the limit", http.MethodPatch, "0123456789", false, http.StatusOK, true},
+		{"chunked too large", http.MethodPatch, "0123456789a", false, http.StatusRequestEntityTooLarge, true},
+		{"method override", http.MethodPut, strings.Repeat("a", 20), true, http.StatusOK, true},
+		{"method override too large", http.MethodPut, strings.Repeat("a", 21), false, http.StatusRequestEntityTooLarge, true},
+		{"method unlimited", http.MethodPost, strings.Repeat("a", 100), true, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if !tt.sized {
+				r.Body = unsized{strings.NewReader(tt.body)}
+				r.ContentLength = -1
+			}
+			reached := false
+			h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+				echo.ServeHTTP(w, r)
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("expected %d, got %d", tt.code, w.Code)
+			}
+			if reached != tt.reached {
+				t.Errorf("expected the handler to be reached: %v", tt.reached)
+			}
+			if tt.code == http.StatusOK && w.Body.String() != tt.body {
+				t.Errorf("expected the body %q, got %q", tt.body, w.Body.String())
+			}
+			if tt.code == http.StatusRequestEntityTooLarge {
+				if w.Header().Get("Connection") != "close" {
+					t.Error("expected the connection to be closed")
+				}
+				if w.Body.Len() != 0 {
+					t.Errorf("expected no body, got %q", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	conf := Config{MaxSize: 100, DrainSize: 5}
+	l := New(&conf)
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tt := range []struct {
+		body string
+		left int
+	}{
+		{"0123", 0},
+		{"0123456789", 5},
+	} {
+		rd := strings.NewReader(tt.body)
+		r := httptest.NewRequest(http.MethodPut, "/", rd)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusNoContent {
+			t.Errorf("expected 204, got %d", w.Code)
+		}
+		if rd.Len() != tt.left {
+			t.Errorf("expected %d bytes of %q left, got %d", tt.left, tt.body, rd.Len())
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	if (&Config{}).Enabled() {
+		t.Error("expected the empty config to be disabled")
+	}
+	if (&Config{Methods: map[string]int64{"PUT": 0}}).Enabled() {
+		t.Error("expected the unlimited methods to be disabled")
+	}
+	if !(&Config{Methods: map[string]int64{"PUT": 1}}).Enabled() {
+		t.Error("expected a method limit to be enabled")
+	}
+}
@@ -26,7 +26,9 @@ import (
 
 	"go.opencensus.io/plugin/ochttp"
 
+	"github.com/cs3org/reva/pkg/mtls"
 	"github.com/cs3org/reva/pkg/token"
+	"github.com/cs3org/reva/pkg/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -41,11 +43,17 @@ func GetHTTPClient(opts ...Option) *http.Client {
 	tr.TLSClientConfig = &tls.Config{
 		InsecureSkipVerify: options.Insecure,
 	}
+	if options.MutualTLS {
+		if c := mtls.ClientConfig(); c != nil {
+			tr.TLSClientConfig = c
+		}
+	}
 
 	httpClient := &http.Client{
 		Timeout: options.Timeout,
 		Transport: &ochttp.Transport{
-			Base: tr,
+			Base:        tr,
+			Propagation: tracing.HTTPFormat,
 		},
 	}
 
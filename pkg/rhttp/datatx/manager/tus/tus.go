@@ -20,9 +20,12 @@ package tus
 
 import (
 	"net/http"
+	"path"
+	"strings"
 
 	"github.com/pkg/errors"
 
+	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
@@ -62,11 +65,6 @@ func New(m map[string]interface{}) (datatx.DataTX, error) {
 }
 
 func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
-	composable, ok := fs.(composable)
-	if !ok {
-		return nil, errtypes.NotSupported("file system does not support the tus protocol")
-	}
-
 	// A storage backend for tusd may consist of multiple different parts which
 	// handle upload creation, locking, termination and so on. The composer is a
 	// place where all those separated pieces are joined together. In this example
@@ -74,7 +72,9 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 	composer := tusd.NewStoreComposer()
 
 	// let the composable storage tell tus which extensions it supports
-	composable.UseIn(composer)
+	if err := storage.UseIn(fs, composer); err != nil {
+		return nil, errtypes.NotSupported("file system does not support the tus protocol")
+	}
 
 	config := tusd.Config{
 		StoreComposer: composer,
@@ -95,6 +95,10 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 
 		switch method {
 		case "POST":
+			if strings.HasPrefix(r.Header.Get("Upload-Concat"), "final;") {
+				concatUploads(w, r, fs)
+				return
+			}
 			handler.PostFile(w, r)
 		case "HEAD":
 			handler.HeadFile(w, r)
@@ -112,8 +116,39 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 	return h, nil
 }
 
-// Composable is the interface that a struct needs to implement
-// to be composable, so that it can support the TUS methods
-type composable interface {
-	UseIn(composer *tusd.StoreComposer)
+// concatUploads creates the final upload of the tus concatenation extension.
+// Partial uploads are initiated through the storage provider, so instead of
+// letting tusd create the final upload, which requires the destination to be
+// given in the upload metadata, the storage assembles them into the resource
+// they were initiated for.
+func concatUploads(w http.ResponseWriter, r *http.Request, fs storage.FS) {
+	log := appctx.GetLogger(r.Context())
+
+	var ids []string
+	for _, u := range strings.Fields(strings.TrimPrefix(r.Header.Get("Upload-Concat"), "final;")) {
+		ids = append(ids, path.Base(u))
+	}
+
+	id, err := storage.ConcatUploads(r.Context(), fs, ids)
+	if err != nil {
+		log.Error().Err(err).Strs("uploads", ids).Msg("tus: error concatenating uploads")
+		switch err.(type) {
+		case errtypes.IsNotSupported:
+			w.WriteHeader(http.StatusNotImplemented)
+		case errtypes.IsPermissionDenied:
+			w.WriteHeader(http.StatusForbidden)
+		case errtypes.IsNotFound:
+			w.WriteHeader(http.StatusNotFound)
+		case errtypes.IsBadRequest:
+			w.WriteHeader(http.StatusBadRequest)
+		case errtypes.IsInsufficientStorage:
+			w.WriteHeader(http.StatusInsufficientStorage)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Location", id)
+	w.WriteHeader(http.StatusCreated)
 }
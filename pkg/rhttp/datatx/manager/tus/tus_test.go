@@ -0,0 +1,148 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+	_ "github.com/cs3org/reva/pkg/storage/fs/ocis"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/audit"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/hidden"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/readonly"
+	ruser "github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/tests/helpers"
+)
+
+const content = "hello tus"
+
+func ref(p string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+}
+
+// newDecomposedfs returns a decomposedfs with the home of the user in ctx created.
+func newDecomposedfs(t *testing.T, ctx context.Context) storage.FS {
+	root, err := helpers.TempDir("reva-unit-tests-*-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	fs, err := registry.NewFuncs["ocis"](map[string]interface{}{
+		"root":         root,
+		"enable_home":  true,
+		"user_layout":  "{{.Id.OpaqueId}}",
+		"share_folder": "/Shares",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+// patch writes the content to the upload in a single tus request.
+func patch(ctx context.Context, h http.Handler, id string) int {
+	r := httptest.NewRequest(http.MethodPatch, "/"+id, strings.NewReader(content)).WithContext(ctx)
+	r.Header.Set("Tus-Resumable", "1.0.0")
+	r.Header.Set("Upload-Offset", "0")
+	r.Header.Set("Content-Type", "application/offset+octet-stream")
+	r.Header.Set("Content-Length", strconv.Itoa(len(content)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestWrappedStorage(t *testing.T) {
+	ctx := ruser.ContextSetUser(context.Background(), &userpb.User{
+		Id:       &userpb.UserId{Idp: "idp", OpaqueId: "userid"},
+		Username: "username",
+	})
+	m, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := newDecomposedfs(t, ctx)
+	wrapped, err := registry.Wrap(fs, []string{"audit", "hidden"}, map[string]map[string]interface{}{
+		"hidden": {"patterns": []string{".*"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := m.Handler(wrapped)
+	if err != nil {
+		t.Fatalf("the wrapped storage doesn't serve tus: %v", err)
+	}
+
+	ids, err := wrapped.InitiateUpload(ctx, ref("/file.txt"), int64(len(content)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := patch(ctx, h, ids["tus"]); code != http.StatusNoContent {
+		t.Fatalf("expected the upload to be written, got status %d", code)
+	}
+	rc, err := wrapped.Download(ctx, ref("/file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != content {
+		t.Errorf("expected %q, got %q", content, b)
+	}
+
+	// the policy of the wrappers applies to the uploads initiated before
+	ids, err = fs.InitiateUpload(ctx, ref("/.hidden"), int64(len(content)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := patch(ctx, h, ids["tus"]); code != http.StatusForbidden {
+		t.Errorf("expected the upload to a hidden path to be refused, got status %d", code)
+	}
+
+	readonly, err := registry.Wrap(fs, []string{"readonly"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err = m.Handler(readonly)
+	if err != nil {
+		t.Fatalf("the read-only storage doesn't serve tus: %v", err)
+	}
+	ids, err = fs.InitiateUpload(ctx, ref("/other.txt"), int64(len(content)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := patch(ctx, h, ids["tus"]); code != http.StatusForbidden {
+		t.Errorf("expected the upload to a read-only storage to be refused, got status %d", code)
+	}
+	if _, err := fs.GetMD(ctx, ref("/other.txt"), nil); err == nil {
+		t.Error("the upload refused by the read-only storage was written")
+	}
+}
@@ -22,14 +22,18 @@ package download
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/rs/zerolog"
 )
 
@@ -48,7 +52,7 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS) {
 
 	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: fn}}
 
-	// TODO check preconditions like If-Range, If-Match ...
+	// TODO check preconditions like If-Match ...
 
 	var md *provider.ResourceInfo
 	var err error
@@ -60,9 +64,28 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS) {
 		return
 	}
 
+	// a revision of the file is downloaded, its validators and size are served
+	revision := r.URL.Query().Get(storage.RevisionOpaqueKey)
+	if revision != "" {
+		if md, err = storage.RevisionInfo(ctx, fs, ref, md, revision); err != nil {
+			handleError(w, &sublog, err, "list revisions")
+			return
+		}
+	}
+
+	// expose the validators clients need to resume a download with If-Range
+	if md.Etag != "" {
+		w.Header().Set("ETag", quoteEtag(md.Etag))
+	}
+	if md.Mtime != nil {
+		w.Header().Set("Last-Modified", utils.TSToTime(md.Mtime).UTC().Format(http.TimeFormat))
+	}
+	// ranges of all files can be served, by seeking, by the driver or by skipping the leading bytes
+	w.Header().Set("Accept-Ranges", "bytes")
+
 	var ranges []HTTPRange
 
-	if r.Header.Get("Range") != "" {
+	if r.Header.Get("Range") != "" && checkIfRange(r.Header.Get("If-Range"), md) {
 		ranges, err = ParseRange(r.Header.Get("Range"), int64(md.Size))
 		if err != nil {
 			if err == ErrNoOverlap {
@@ -81,30 +104,69 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS) {
 		}
 	}
 
-	content, err := fs.Download(ctx, ref)
+	code := http.StatusOK
+	sendSize := int64(md.Size)
+
+	// let drivers able to read a range natively do so, through the storage wrappers
+	if len(ranges) == 1 && revision == "" {
+		ra := ranges[0]
+		content, err := storage.DownloadRange(ctx, fs, ref, ra.Start, ra.Length)
+		switch err.(type) {
+		case nil:
+			sublog.Debug().Int64("start", ra.Start).Int64("length", ra.Length).Msg("range request served by the driver")
+			defer content.Close()
+			w.Header().Set("Content-Range", ra.ContentRange(int64(md.Size)))
+			sendResponse(w, r, &sublog, content, http.StatusPartialContent, ra.Length)
+			return
+		case errtypes.IsNotSupported:
+			// read the range from the full content below
+		default:
+			handleError(w, &sublog, err, "download range")
+			return
+		}
+	}
+
+	var content io.ReadCloser
+	if revision != "" {
+		content, err = fs.DownloadRevision(ctx, ref, revision)
+	} else {
+		content, err = fs.Download(ctx, ref)
+	}
 	if err != nil {
 		handleError(w, &sublog, err, "download")
 		return
 	}
 	defer content.Close()
 
-	code := http.StatusOK
-	sendSize := int64(md.Size)
 	var sendContent io.Reader = content
 
-	var s io.Seeker
-	if s, ok = content.(io.Seeker); ok {
-		// tell clients they can send range requests
-		w.Header().Set("Accept-Ranges", "bytes")
+	s, seekable := content.(io.Seeker)
+	if len(ranges) > 0 && !seekable {
+		switch {
+		case len(ranges) == 1:
+			// skip the bytes before the range
+			ra := ranges[0]
+			sublog.Debug().Int64("start", ra.Start).Int64("length", ra.Length).Msg("range request on a stream, skipping leading bytes")
+			if r.Method != "HEAD" {
+				if _, err := io.CopyN(ioutil.Discard, content, ra.Start); err != nil {
+					sublog.Error().Err(err).Int64("start", ra.Start).Int64("length", ra.Length).Msg("error skipping to the range start")
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}
+			w.Header().Set("Content-Range", ra.ContentRange(int64(md.Size)))
+			sendResponse(w, r, &sublog, content, http.StatusPartialContent, ra.Length)
+			return
+		default:
+			// RFC 7233, Section 3.1: a server MAY ignore the Range header field,
+			// multiple ranges can't be served from a stream that can't go backwards.
+			sublog.Debug().Int("ranges", len(ranges)).Msg("multiple ranges on a stream, sending the full content")
+			ranges = nil
+		}
 	}
 
 	if len(ranges) > 0 {
 		sublog.Debug().Int64("start", ranges[0].Start).Int64("length", ranges[0].Length).Msg("range request")
-		if s == nil {
-			sublog.Error().Int64("start", ranges[0].Start).Int64("length", ranges[0].Length).Msg("ReadCloser is not seekable")
-			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
-			return
-		}
 
 		switch {
 		case len(ranges) == 1:
@@ -159,24 +221,50 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS) {
 		}
 	}
 
+	sendResponse(w, r, &sublog, sendContent, code, sendSize)
+}
+
+func sendResponse(w http.ResponseWriter, r *http.Request, log *zerolog.Logger, content io.Reader, code int, size int64) {
 	if w.Header().Get("Content-Encoding") == "" {
-		w.Header().Set("Content-Length", strconv.FormatInt(sendSize, 10))
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
 	}
 
 	w.WriteHeader(code)
 
 	if r.Method != "HEAD" {
-		var c int64
-		c, err = io.CopyN(w, sendContent, sendSize)
+		c, err := io.CopyN(w, content, size)
 		if err != nil {
-			sublog.Error().Err(err).Msg("error copying data to response")
+			log.Error().Err(err).Msg("error copying data to response")
 			return
 		}
-		if c != sendSize {
-			sublog.Error().Int64("copied", c).Int64("size", sendSize).Msg("copied vs size mismatch")
+		if c != size {
+			log.Error().Int64("copied", c).Int64("size", size).Msg("copied vs size mismatch")
 		}
 	}
+}
+
+// checkIfRange evaluates the If-Range precondition as per RFC 7233, Section 3.2.
+// The range is only served if the validator matches the current representation.
+func checkIfRange(ir string, md *provider.ResourceInfo) bool {
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, "W/") {
+		// weak entity tags cannot be used with If-Range
+		return false
+	}
+	if strings.HasPrefix(ir, "\"") {
+		return md.Etag != "" && ir == quoteEtag(md.Etag)
+	}
+	t, err := http.ParseTime(ir)
+	if err != nil || md.Mtime == nil {
+		return false
+	}
+	return t.Equal(utils.TSToTime(md.Mtime).UTC().Truncate(time.Second))
+}
 
+func quoteEtag(etag string) string {
+	return "\"" + strings.Trim(etag, "\"") + "\""
 }
 
 func handleError(w http.ResponseWriter, log *zerolog.Logger, err error, action string) {
@@ -0,0 +1,249 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package download
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/wrappers/readonly"
+)
+
+const content = "0123456789"
+
+var mtime = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// seekableContent is a download the handler can seek in.
+type seekableContent struct {
+	*strings.Reader
+}
+
+func (seekableContent) Close() error { return nil }
+
+// testFS serves a single file, as a stream unless seekable is set.
+type testFS struct {
+	storage.FS
+	seekable bool
+}
+
+func (fs *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	return &provider.ResourceInfo{
+		Path:     ref.GetPath(),
+		Size:     uint64(len(content)),
+		Etag:     "abc",
+		MimeType: "text/plain",
+		Mtime:    &types.Timestamp{Seconds: uint64(mtime.Unix())},
+	}, nil
+}
+
+func (fs *testFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	if fs.seekable {
+		return seekableContent{strings.NewReader(content)}, nil
+	}
+	return ioutil.NopCloser(strings.NewReader(content)), nil
+}
+
+// rangeFS reads ranges natively and records them.
+type rangeFS struct {
+	testFS
+	ranges [][2]int64
+}
+
+func (fs *rangeFS) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	fs.ranges = append(fs.ranges, [2]int64{offset, length})
+	return ioutil.NopCloser(strings.NewReader(content[offset : offset+length])), nil
+}
+
+func get(t *testing.T, fs storage.FS, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/file", nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	GetOrHeadFile(w, r, fs)
+	return w
+}
+
+func TestGetOrHeadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		fs      storage.FS
+		header  map[string]string
+		code    int
+		body    string
+		cr      string
+		partial bool
+	}{
+		{
+			name: "full content",
+			fs:   &testFS{},
+			code: http.StatusOK,
+			body: content,
+		},
+		{
+			name:   "single range on a seekable download",
+			fs:     &testFS{seekable: true},
+			header: map[string]string{"Range": "bytes=2-4"},
+			code:   http.StatusPartialContent,
+			body:   "234",
+			cr:     "bytes 2-4/10",
+		},
+		{
+			name:   "single range on a stream skips the leading bytes",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=5-"},
+			code:   http.StatusPartialContent,
+			body:   "56789",
+			cr:     "bytes 5-9/10",
+		},
+		{
+			name:   "matching If-Range etag",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=0-1", "If-Range": `"abc"`},
+			code:   http.StatusPartialContent,
+			body:   "01",
+			cr:     "bytes 0-1/10",
+		},
+		{
+			name:   "matching If-Range date",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=0-1", "If-Range": mtime.Format(http.TimeFormat)},
+			code:   http.StatusPartialContent,
+			body:   "01",
+			cr:     "bytes 0-1/10",
+		},
+		{
+			name:   "mismatching If-Range etag",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=0-1", "If-Range": `"other"`},
+			code:   http.StatusOK,
+			body:   content,
+		},
+		{
+			name:   "weak If-Range etag",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=0-1", "If-Range": `W/"abc"`},
+			code:   http.StatusOK,
+			body:   content,
+		},
+		{
+			name:   "mismatching If-Range date",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=0-1", "If-Range": mtime.Add(time.Hour).Format(http.TimeFormat)},
+			code:   http.StatusOK,
+			body:   content,
+		},
+		{
+			name:   "multiple ranges on a stream fall back to the full content",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=0-1,4-5"},
+			code:   http.StatusOK,
+			body:   content,
+		},
+		{
+			name:    "multiple ranges on a seekable download",
+			fs:      &testFS{seekable: true},
+			header:  map[string]string{"Range": "bytes=0-1,4-5"},
+			code:    http.StatusPartialContent,
+			partial: true,
+		},
+		{
+			name:   "unsatisfiable range",
+			fs:     &testFS{},
+			header: map[string]string{"Range": "bytes=20-30"},
+			code:   http.StatusRequestedRangeNotSatisfiable,
+			cr:     "bytes */10",
+		},
+		{
+			name:   "wrapped driver without native ranges",
+			fs:     mustWrap(t, &testFS{}),
+			header: map[string]string{"Range": "bytes=5-6"},
+			code:   http.StatusPartialContent,
+			body:   "56",
+			cr:     "bytes 5-6/10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(t, tt.fs, tt.header)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, expected %d", w.Code, tt.code)
+			}
+			if cr := w.Header().Get("Content-Range"); cr != tt.cr {
+				t.Errorf("got Content-Range %q, expected %q", cr, tt.cr)
+			}
+			if tt.partial {
+				ct := w.Header().Get("Content-Type")
+				if !strings.HasPrefix(ct, "multipart/byteranges") {
+					t.Fatalf("got Content-Type %q, expected a multipart response", ct)
+				}
+				if body := w.Body.String(); !strings.Contains(body, "bytes 0-1/10") || !strings.Contains(body, "bytes 4-5/10") {
+					t.Errorf("unexpected multipart body %q", body)
+				}
+				return
+			}
+			if tt.code != http.StatusRequestedRangeNotSatisfiable && w.Body.String() != tt.body {
+				t.Errorf("got body %q, expected %q", w.Body.String(), tt.body)
+			}
+			if tt.code != http.StatusRequestedRangeNotSatisfiable && w.Header().Get("ETag") != `"abc"` {
+				t.Errorf("got ETag %q", w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestGetOrHeadFileNativeRange(t *testing.T) {
+	driver := &rangeFS{}
+	for _, fs := range []storage.FS{driver, mustWrap(t, driver)} {
+		w := get(t, fs, map[string]string{"Range": "bytes=3-5"})
+		if w.Code != http.StatusPartialContent || w.Body.String() != "345" || w.Header().Get("Content-Range") != "bytes 3-5/10" {
+			t.Errorf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+		}
+	}
+	// the wrapped driver read the range natively too
+	if len(driver.ranges) != 2 || driver.ranges[1] != [2]int64{3, 3} {
+		t.Errorf("unexpected native range reads %v", driver.ranges)
+	}
+
+	// multiple ranges are not read natively
+	driver.ranges = nil
+	if w := get(t, driver, map[string]string{"Range": "bytes=0-1,4-5"}); w.Code != http.StatusOK || len(driver.ranges) != 0 {
+		t.Errorf("unexpected response %d with native range reads %v", w.Code, driver.ranges)
+	}
+}
+
+func mustWrap(t *testing.T, fs storage.FS) storage.FS {
+	t.Helper()
+	w, err := readonly.New(fs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
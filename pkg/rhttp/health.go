@@ -0,0 +1,76 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package rhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/health"
+)
+
+const (
+	livenessPath  = "/healthz"
+	readinessPath = "/readyz"
+)
+
+// healthHandler answers the liveness probe at /healthz and the readiness
+// probe at /readyz, which runs the dependency checks of the process. A check
+// can be left out with the exclude query parameter, e.g. ?exclude=share/sql.
+func (s *Server) healthHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != livenessPath && r.URL.Path != readinessPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		report := health.Live()
+		if r.URL.Path == readinessPath {
+			var exclude []string
+			for _, e := range r.URL.Query()["exclude"] {
+				exclude = append(exclude, strings.Split(e, ",")...)
+			}
+			report = health.Ready(r.Context(), time.Duration(s.conf.HealthTimeout)*time.Second, exclude...)
+		}
+
+		code := http.StatusOK
+		if report.Status != health.OK {
+			s.log.Warn().Strs("checks", report.Failed()).Msg("http: readiness checks failed")
+			code = http.StatusServiceUnavailable
+		}
+		data, err := json.MarshalIndent(report, "", "\t")
+		if err != nil {
+			s.log.Err(err).Msg("http: error encoding health report")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		if _, err := w.Write(data); err != nil {
+			s.log.Err(err).Msg("http: error writing health report")
+		}
+	})
+}
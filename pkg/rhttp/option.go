@@ -32,6 +32,7 @@ type Options struct {
 	Timeout          time.Duration
 	Insecure         bool
 	DisableKeepAlive bool
+	MutualTLS        bool
 }
 
 // newOptions initializes the available default options.
@@ -72,3 +73,12 @@ func DisableKeepAlive(disable bool) Option {
 		o.DisableKeepAlive = disable
 	}
 }
+
+// MutualTLS provides a function to set the mutual TLS option, making the
+// client present the certificate of the process and verify the servers against
+// its CA bundle, if mutual TLS is configured.
+func MutualTLS(enabled bool) Option {
+	return func(o *Options) {
+		o.MutualTLS = enabled
+	}
+}
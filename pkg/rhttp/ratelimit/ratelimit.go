@@ -0,0 +1,304 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package ratelimit shapes the bandwidth of the data transfers per user or
+// client address with token buckets holding bytes, rejecting the transfers
+// that would be delayed too long with 429 Too Many Requests.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+// Budget is the bandwidth granted to every user or client address.
+type Budget struct {
+	// Rate is the number of bytes per second, 0 disables the limit.
+	Rate float64 `mapstructure:"rate"`
+	// Burst is the number of bytes that can be transferred at once,
+	// defaulting to one second worth of the rate.
+	Burst float64 `mapstructure:"burst"`
+}
+
+// Config is the configuration of a Limiter.
+type Config struct {
+	// Read is the bandwidth of the downloads.
+	Read Budget `mapstructure:"read"`
+	// Write is the bandwidth of the uploads.
+	Write Budget `mapstructure:"write"`
+	// MaxWait is the time in seconds the transfers of a user may be delayed
+	// when a new one starts, beyond which it is rejected.
+	MaxWait int `mapstructure:"max_wait"`
+	// IPHeader is the header holding the client address set by a trusted
+	// proxy, e.g. X-Real-IP, used for the requests without a user.
+	IPHeader string `mapstructure:"ip_header"`
+}
+
+func (c *Config) init() {
+	if c.MaxWait == 0 {
+		c.MaxWait = 5
+	}
+	for _, b := range []*Budget{&c.Read, &c.Write} {
+		if b.Burst <= 0 {
+			b.Burst = b.Rate
+		}
+	}
+}
+
+// Enabled returns whether the config limits the reads or the writes.
+func (c *Config) Enabled() bool {
+	return c.Read.Rate > 0 || c.Write.Rate > 0
+}
+
+// Limiter shapes the data transfers of the requests it handles.
+type Limiter struct {
+	read, write *budget
+	maxWait     time.Duration
+	ipHeader    string
+}
+
+// New returns a Limiter for the config.
+func New(c *Config) *Limiter {
+	c.init()
+	return &Limiter{
+		read:     newBudget(c.Read),
+		write:    newBudget(c.Write),
+		maxWait:  time.Duration(c.MaxWait) * time.Second,
+		ipHeader: c.IPHeader,
+	}
+}
+
+// Handler shapes the downloads and uploads served by h.
+func (l *Limiter) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b *budget
+		switch r.Method {
+		case http.MethodGet:
+			b = l.read
+		case http.MethodPut, http.MethodPost, http.MethodPatch:
+			b = l.write
+		}
+		if b == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		key := l.key(r)
+		if d := b.delay(key); d > l.maxWait {
+			appctx.GetLogger(r.Context()).Debug().Str("key", key).Dur("delay", d).Msg("ratelimit: too many transfers")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		t := transfer{ctx: r.Context(), b: b, key: key}
+		if b == l.write {
+			r.Body = &shapedReader{ReadCloser: r.Body, t: t}
+			h.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(&shapedWriter{ResponseWriter: w, t: t}, r)
+	})
+}
+
+// key returns the key of the bucket of the request, the user or else the
+// client address.
+func (l *Limiter) key(r *http.Request) string {
+	if u, ok := ctxuser.ContextGetUser(r.Context()); ok {
+		return "user:" + u.Id.Idp + "!" + u.Id.OpaqueId
+	}
+	if l.ipHeader != "" {
+		if ip := r.Header.Get(l.ipHeader); ip != "" {
+			// the first address of a list is the client's
+			return "ip:" + strings.TrimSpace(strings.Split(ip, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// budget holds the buckets of a bandwidth. The bytes transferred are taken
+// from the buckets even when they are empty, the debt being the time the
+// transfers have to wait.
+type budget struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newBudget(b Budget) *budget {
+	if b.Rate <= 0 {
+		return nil
+	}
+	return &budget{
+		rate:    b.Rate,
+		burst:   b.Burst,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// refill returns the bucket of the key, refilled since it was last used.
+func (b *budget) refill(key string) *bucket {
+	now := b.now()
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: b.burst, last: now}
+		b.buckets[key] = bk
+		b.purge(now)
+	}
+	bk.tokens += now.Sub(bk.last).Seconds() * b.rate
+	if bk.tokens > b.burst {
+		bk.tokens = b.burst
+	}
+	bk.last = now
+	return bk
+}
+
+// purge drops the buckets that have been refilled completely, they carry no state.
+func (b *budget) purge(now time.Time) {
+	if len(b.buckets) < 1024 {
+		return
+	}
+	for k, bk := range b.buckets {
+		if bk.tokens+now.Sub(bk.last).Seconds()*b.rate >= b.burst {
+			delete(b.buckets, k)
+		}
+	}
+}
+
+// reserve takes n bytes from the bucket of the key and returns the time to
+// wait before transferring them.
+func (b *budget) reserve(key string, n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bk := b.refill(key)
+	bk.tokens -= float64(n)
+	return b.debt(bk)
+}
+
+// delay returns the time the transfers of the key currently wait.
+func (b *budget) delay(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.debt(b.refill(key))
+}
+
+func (b *budget) debt(bk *bucket) time.Duration {
+	if bk.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bk.tokens / b.rate * float64(time.Second))
+}
+
+// transfer takes the bytes of a request from a budget.
+type transfer struct {
+	ctx context.Context
+	b   *budget
+	key string
+}
+
+// chunk returns the size of the next part of a buffer of n bytes to
+// transfer, no more than a burst so that the waits stay short.
+func (t transfer) chunk(n int) int {
+	if max := int(t.b.burst); max > 0 && n > max {
+		return max
+	}
+	return n
+}
+
+// wait waits until n bytes may be transferred.
+func (t transfer) wait(n int) error {
+	d := t.b.reserve(t.key, n)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	}
+}
+
+type shapedReader struct {
+	io.ReadCloser
+	t transfer
+}
+
+func (r *shapedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p[:r.t.chunk(len(p))])
+	if n > 0 {
+		if werr := r.t.wait(n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type shapedWriter struct {
+	http.ResponseWriter
+	t transfer
+}
+
+func (w *shapedWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		c := w.t.chunk(len(p))
+		if err := w.t.wait(c); err != nil {
+			return written, err
+		}
+		n, err := w.ResponseWriter.Write(p[:c])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[c:]
+	}
+	return written, nil
+}
+
+// Flush sends the buffered data to the client, if the wrapped writer supports it.
+func (w *shapedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
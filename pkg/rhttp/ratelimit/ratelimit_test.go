@@ -0,0 +1,124 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+func TestBudget(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBudget(Budget{Rate: 100, Burst: 100})
+	b.now = func() time.Time { return now }
+
+	if d := b.reserve("a", 100); d != 0 {
+		t.Errorf("expected the burst to be free, got %v", d)
+	}
+	if d := b.reserve("a", 50); d != 500*time.Millisecond {
+		t.Errorf("expected a wait of 500ms, got %v", d)
+	}
+	if d := b.delay("b"); d != 0 {
+		t.Errorf("expected the buckets to be distinct, got %v", d)
+	}
+
+	now = now.Add(time.Second)
+	if d := b.delay("a"); d != 0 {
+		t.Errorf("expected the bucket to be refilled, got %v", d)
+	}
+	// the bucket never holds more than the burst
+	now = now.Add(time.Hour)
+	if d := b.reserve("a", 150); d != 500*time.Millisecond {
+		t.Errorf("expected a wait of 500ms, got %v", d)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	l := New(&Config{Read: Budget{Rate: 1000}, MaxWait: 1})
+	now := time.Unix(0, 0)
+	l.read.now = func() time.Time { return now }
+
+	var handled int
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled++
+	}))
+	ctx := ctxuser.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}})
+
+	// a transfer of the user is 3 seconds behind
+	l.read.reserve(l.key(httptest.NewRequest("GET", "/", nil).WithContext(ctx)), 4000)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/file", nil).WithContext(ctx))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "3" {
+		t.Errorf("expected 429 with Retry-After 3, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// the uploads and other users are not limited
+	for _, r := range []*http.Request{
+		httptest.NewRequest("PUT", "/file", strings.NewReader("data")).WithContext(ctx),
+		httptest.NewRequest("GET", "/file", nil),
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %s to pass, got %d", r.Method, w.Code)
+		}
+	}
+	if handled != 2 {
+		t.Errorf("expected 2 requests handled, got %d", handled)
+	}
+}
+
+func TestShapedWriter(t *testing.T) {
+	l := New(&Config{Read: Budget{Rate: 1 << 20, Burst: 1024}})
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 40*1024))
+	}))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/file", nil))
+	if w.Body.Len() != 40*1024 {
+		t.Fatalf("expected 40KiB written, got %d", w.Body.Len())
+	}
+	// 39KiB beyond the burst at 1MiB/s
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected the download to be shaped, took %v", elapsed)
+	}
+}
+
+func TestKey(t *testing.T) {
+	l := New(&Config{IPHeader: "X-Real-IP"})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	if k := l.key(r); k != "ip:10.0.0.1" {
+		t.Errorf("unexpected key %s", k)
+	}
+	r.Header.Set("X-Real-IP", "192.168.1.1, 10.0.0.2")
+	if k := l.key(r); k != "ip:192.168.1.1" {
+		t.Errorf("unexpected key %s", k)
+	}
+}
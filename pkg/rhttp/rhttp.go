@@ -20,19 +20,26 @@ package rhttp
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"path"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cs3org/reva/internal/http/interceptors/appctx"
 	"github.com/cs3org/reva/internal/http/interceptors/auth"
 	"github.com/cs3org/reva/internal/http/interceptors/log"
 	"github.com/cs3org/reva/internal/http/interceptors/providerauthorizer"
+	"github.com/cs3org/reva/pkg/mtls"
+	"github.com/cs3org/reva/pkg/ops"
+	"github.com/cs3org/reva/pkg/registry"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/tracing"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -71,6 +78,8 @@ type Server struct {
 	handlers    map[string]http.Handler
 	middlewares []*middlewareTriple
 	log         zerolog.Logger
+	closing     sync.Once
+	registrar   *registry.Registrar
 }
 
 type config struct {
@@ -78,6 +87,17 @@ type config struct {
 	Address     string                            `mapstructure:"address"`
 	Services    map[string]map[string]interface{} `mapstructure:"services"`
 	Middlewares map[string]map[string]interface{} `mapstructure:"middlewares"`
+	// HealthTimeout is the time in seconds each dependency check of the
+	// readiness probe is given.
+	HealthTimeout int `mapstructure:"health_timeout"`
+	// ShutdownDeadline is the time in seconds the requests in progress, e.g.
+	// the transfers, are given to complete on a graceful shutdown.
+	ShutdownDeadline int `mapstructure:"shutdown_deadline"`
+	// Advertise is the URL the services are reachable under, followed by their prefix in the
+	// service registry, by default the address with the host name replacing an unspecified host.
+	Advertise string `mapstructure:"advertise"`
+	// MTLS serves the services over mutual TLS with the certificate of the process.
+	MTLS bool `mapstructure:"mtls"`
 }
 
 func (c *config) init() {
@@ -89,6 +109,28 @@ func (c *config) init() {
 	if c.Address == "" {
 		c.Address = "0.0.0.0:19001"
 	}
+
+	if c.HealthTimeout == 0 {
+		c.HealthTimeout = 5
+	}
+
+	if c.ShutdownDeadline == 0 {
+		c.ShutdownDeadline = 10
+	}
+
+	if c.Advertise == "" {
+		scheme := "http://"
+		if c.MTLS {
+			scheme = "https://"
+		}
+		c.Advertise = scheme + registry.AdvertisedAddress(c.Address)
+	}
+}
+
+// SetRegistrar makes the server announce its services to the registrar once
+// they are started.
+func (s *Server) SetRegistrar(r *registry.Registrar) {
+	s.registrar = r
 }
 
 // Start starts the server
@@ -109,7 +151,18 @@ func (s *Server) Start(ln net.Listener) error {
 	s.httpServer.Handler = handler
 	s.listener = ln
 
-	s.log.Info().Msgf("http server listening at %s://%s", "http", s.conf.Address)
+	scheme := "http"
+	if s.conf.MTLS {
+		src := mtls.Get()
+		if src == nil {
+			return errors.New("rhttp: mtls enabled without the [mtls] section")
+		}
+		s.httpServer.TLSConfig = src.ServerConfig()
+		s.listener = tls.NewListener(ln, s.httpServer.TLSConfig)
+		scheme = "https"
+	}
+
+	s.log.Info().Msgf("http server listening at %s://%s", scheme, s.conf.Address)
 	err = s.httpServer.Serve(s.listener)
 	if err == nil || err == http.ErrServerClosed {
 		return nil
@@ -117,25 +170,37 @@ func (s *Server) Start(ln net.Listener) error {
 	return err
 }
 
-// Stop stops the server.
+// Stop stops the server, aborting the requests in progress.
 func (s *Server) Stop() error {
+	err := s.httpServer.Close()
 	s.closeServices()
-	// TODO(labkode): set ctx deadline to zero
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	return s.httpServer.Shutdown(ctx)
+	return err
 }
 
+// closeServices closes the services once the server stopped serving their
+// requests, e.g. so that the storage drivers are shut down after the transfers.
 // TODO(labkode): we can't stop the server shutdown because a service cannot be shutdown.
 // What do we do in case a service cannot be properly closed? Now we just log the error.
-// TODO(labkode): the close should be given a deadline using context.Context.
 func (s *Server) closeServices() {
-	for _, svc := range s.svcs {
-		if err := svc.Close(); err != nil {
-			s.log.Error().Err(err).Msgf("error closing service %q", svc.Prefix())
-		} else {
-			s.log.Info().Msgf("service %q correctly closed", svc.Prefix())
+	s.closing.Do(func() {
+		for name := range s.conf.Services {
+			ops.Unregister(ops.Services, "http/"+name)
+		}
+		for _, svc := range s.svcs {
+			if err := svc.Close(); err != nil {
+				s.log.Error().Err(err).Msgf("error closing service %q", svc.Prefix())
+			} else {
+				s.log.Info().Msgf("service %q correctly closed", svc.Prefix())
+			}
 		}
+	})
+}
+
+// serviceProbe reports the services as healthy while the server runs.
+func (s *Server) serviceProbe() ops.State {
+	return ops.State{
+		Health:  ops.Healthy,
+		Details: map[string]interface{}{"network": s.conf.Network, "address": s.conf.Address},
 	}
 }
 
@@ -149,10 +214,18 @@ func (s *Server) Address() string {
 	return s.conf.Address
 }
 
-// GracefulStop gracefully stops the server.
+// GracefulStop stops the server from accepting new requests and waits for the
+// ones in progress until the shutdown deadline, after which they are aborted.
 func (s *Server) GracefulStop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.conf.ShutdownDeadline)*time.Second)
+	defer cancel()
+	err := s.httpServer.Shutdown(ctx)
+	if err == context.DeadlineExceeded {
+		s.log.Warn().Msgf("http: requests still in progress after %d seconds, aborting them", s.conf.ShutdownDeadline)
+		err = s.httpServer.Close()
+	}
 	s.closeServices()
-	return s.httpServer.Shutdown(context.Background())
+	return err
 }
 
 // middlewareTriple represents a middleware with the
@@ -203,7 +276,11 @@ func (s *Server) registerServices() error {
 			h := traceHandler(svcName, svc.Handler())
 			s.handlers[svc.Prefix()] = h
 			s.svcs[svc.Prefix()] = svc
+			ops.Register(ops.Services, "http/"+svcName, s.serviceProbe)
 			s.unprotected = append(s.unprotected, getUnprotected(svc.Prefix(), svc.Unprotected())...)
+			if s.registrar != nil {
+				s.registrar.Announce(svcName, strings.TrimSuffix(s.conf.Advertise, "/")+path.Join("/", svc.Prefix()))
+			}
 			s.log.Info().Msgf("http service enabled: %s@/%s", svcName, svc.Prefix())
 		} else {
 			message := fmt.Sprintf("http service %s does not exist", svcName)
@@ -292,10 +369,15 @@ func (s *Server) getHandler() (http.Handler, error) {
 	// use opencensus handler to trace endpoints.
 	// TODO(labkode): enable also opencensus telemetry.
 	handler = &ochttp.Handler{
-		Handler: handler,
+		Handler:     handler,
+		Propagation: tracing.HTTPFormat,
 		//IsPublicEndpoint: true,
 	}
 
+	// the probes are answered before any middleware, they are polled often
+	// and must not depend on the auth.
+	handler = s.healthHandler(handler)
+
 	return handler, nil
 }
 
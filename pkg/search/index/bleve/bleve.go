@@ -0,0 +1,220 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package bleve provides a search index stored on disk with bleve.
+package bleve
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("bleve", New)
+}
+
+type config struct {
+	// Path is the directory of the index, the index is only kept in memory if it is "-".
+	Path string `mapstructure:"path" docs:"/var/tmp/reva/search;The directory of the index."`
+}
+
+func (c *config) init() {
+	if c.Path == "" {
+		c.Path = "/var/tmp/reva/search"
+	}
+}
+
+type index struct {
+	bleve.Index
+}
+
+// New returns a search index stored with bleve.
+func New(m map[string]interface{}) (search.Index, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "bleve: error decoding conf")
+	}
+	c.init()
+
+	if c.Path == "-" {
+		i, err := bleve.NewMemOnly(newMapping())
+		if err != nil {
+			return nil, errors.Wrap(err, "bleve: error creating index")
+		}
+		return &index{i}, nil
+	}
+
+	i, err := bleve.Open(c.Path)
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		// bleve creates the directory of the index itself
+		if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+			return nil, errors.Wrap(err, "bleve: error creating index directory")
+		}
+		i, err = bleve.New(c.Path, newMapping())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "bleve: error opening index")
+	}
+	return &index{i}, nil
+}
+
+// newMapping stores all the fields as keywords, the documents are tokenized
+// by reva. Only the ids are stored.
+func newMapping() mapping.IndexMapping {
+	field := func(store bool) *mapping.FieldMapping {
+		f := bleve.NewTextFieldMapping()
+		f.Analyzer = keyword.Name
+		f.Store = store
+		f.IncludeTermVectors = false
+		return f
+	}
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("storage_id", field(true))
+	doc.AddFieldMappingsAt("opaque_id", field(true))
+	doc.AddFieldMappingsAt("name", field(false))
+	doc.AddFieldMappingsAt("mime_type", field(false))
+	doc.AddFieldMappingsAt("tags", field(false))
+	doc.AddFieldMappingsAt("content", field(false))
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = doc
+	m.DefaultAnalyzer = keyword.Name
+	return m
+}
+
+func key(id *provider.ResourceId) string {
+	return id.GetStorageId() + "!" + id.GetOpaqueId()
+}
+
+func (i *index) Add(ctx context.Context, d *search.Document) error {
+	if err := i.Index.Index(key(d.ID()), d); err != nil {
+		return errors.Wrap(err, "bleve: error indexing document")
+	}
+	return nil
+}
+
+func (i *index) Remove(ctx context.Context, id *provider.ResourceId) error {
+	if err := i.Delete(key(id)); err != nil {
+		return errors.Wrap(err, "bleve: error removing document")
+	}
+	return nil
+}
+
+func (i *index) Search(ctx context.Context, q *search.Query, max int) ([]*provider.ResourceId, error) {
+	if max <= 0 {
+		max = 1000
+	}
+	req := bleve.NewSearchRequestOptions(newQuery(q), max, 0, false)
+	req.SortBy([]string{"-_score", "name"})
+	req.Fields = []string{"storage_id", "opaque_id"}
+	res, err := i.SearchInContext(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "bleve: error searching")
+	}
+
+	ids := make([]*provider.ResourceId, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		storageID, _ := h.Fields["storage_id"].(string)
+		opaqueID, _ := h.Fields["opaque_id"].(string)
+		ids = append(ids, &provider.ResourceId{StorageId: storageID, OpaqueId: opaqueID})
+	}
+	return ids, nil
+}
+
+// newQuery translates a search query into a bleve conjunction query. The name
+// matches score higher than the content ones.
+func newQuery(q *search.Query) query.Query {
+	must := []query.Query{}
+	for _, t := range q.Terms {
+		content := []query.Query{}
+		for _, token := range search.Tokenize(t) {
+			content = append(content, field(bleve.NewTermQuery(token), "content"))
+		}
+		name := field(bleve.NewRegexpQuery(".*"+regexp.QuoteMeta(t)+".*"), "name")
+		name.SetBoost(2)
+		must = append(must, bleve.NewDisjunctionQuery(name, bleve.NewConjunctionQuery(content...)))
+	}
+	for _, n := range q.Names {
+		name := field(bleve.NewRegexpQuery(namePattern(n)), "name")
+		name.SetBoost(2)
+		must = append(must, name)
+	}
+	for _, mt := range q.MimeTypes {
+		must = append(must, field(bleve.NewPrefixQuery(mt), "mime_type"))
+	}
+	for _, t := range q.Tags {
+		must = append(must, field(bleve.NewTermQuery(t), "tags"))
+	}
+	for _, c := range q.Content {
+		must = append(must, field(bleve.NewTermQuery(c), "content"))
+	}
+	return bleve.NewConjunctionQuery(must...)
+}
+
+type fieldQuery interface {
+	query.FieldableQuery
+	query.BoostableQuery
+}
+
+func field(q fieldQuery, f string) fieldQuery {
+	q.SetField(f)
+	return q
+}
+
+// namePattern translates a name pattern into a regular expression with the
+// semantics of search.MatchName.
+func namePattern(pattern string) string {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return ".*" + regexp.QuoteMeta(pattern) + ".*"
+	}
+	var b strings.Builder
+	class := false
+	for _, r := range pattern {
+		switch {
+		case class:
+			if r == ']' {
+				class = false
+			}
+			b.WriteRune(r)
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		case r == '[':
+			class = true
+			b.WriteRune(r)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,147 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package bleve_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/bleve"
+)
+
+func doc(id, name, mime, tags, content string) *search.Document {
+	return search.NewDocument(&provider.ResourceInfo{
+		Id:       &provider.ResourceId{StorageId: "s", OpaqueId: id},
+		Path:     "/" + name,
+		MimeType: mime,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{search.TagsKey: tags},
+		},
+	}, content)
+}
+
+func find(t *testing.T, i search.Index, query string) []string {
+	q, err := search.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := i.Search(context.Background(), q, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := []string{}
+	for _, id := range ids {
+		found = append(found, id.OpaqueId)
+	}
+	return found
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "search")
+	i, err := bleve.New(map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []*search.Document{
+		doc("1", "budget.txt", "text/plain", "finance", "the budget of the year"),
+		doc("2", "report.txt", "text/plain", "finance, draft", "the budget was exceeded"),
+		doc("3", "photo.jpg", "image/jpeg", "", ""),
+		doc("4", "a+b.txt", "text/plain", "", ""),
+	} {
+		if err := i.Add(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for query, want := range map[string][]string{
+		// the name matches score higher than the content ones
+		"budget":                 {"1", "2"},
+		"budget exceeded":        {"2"},
+		"name:port":              {"2"},
+		"name:*.txt":             {"4", "1", "2"},
+		"name:[bp]*":             {"1", "3"},
+		"name:a+b":               {"4"},
+		"name:a+*":               {"4"},
+		"mime:image/":            {"3"},
+		"tag:finance":            {"1", "2"},
+		"tag:draft content:year": {},
+		"content:year":           {"1"},
+	} {
+		if got := find(t, i, query); !reflect.DeepEqual(got, want) {
+			t.Errorf("search %s = %v, want %v", query, got, want)
+		}
+	}
+
+	// adding a document with the same id replaces it
+	if err := i.Add(ctx, doc("2", "report.txt", "text/plain", "", "all good")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := find(t, i, "budget"), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search budget after update = %v, want %v", got, want)
+	}
+
+	if err := i.Remove(ctx, &provider.ResourceId{StorageId: "s", OpaqueId: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Remove(ctx, &provider.ResourceId{StorageId: "s", OpaqueId: "unknown"}); err != nil {
+		t.Errorf("removing an unknown document failed: %v", err)
+	}
+	if got := find(t, i, "budget"); len(got) != 0 {
+		t.Errorf("search budget after remove = %v, want no hits", got)
+	}
+
+	// the index is opened again from its directory
+	if err := i.(interface{ Close() error }).Close(); err != nil {
+		t.Fatal(err)
+	}
+	i, err = bleve.New(map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := find(t, i, "name:*.txt"), []string{"4", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search in reopened index = %v, want %v", got, want)
+	}
+}
+
+func TestSearchMax(t *testing.T) {
+	ctx := context.Background()
+	i, err := bleve.New(map[string]interface{}{"path": "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := i.Add(ctx, doc(id, id+".txt", "", "", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q, _ := search.ParseQuery("name:*.txt")
+	ids, err := i.Search(ctx, q, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0].OpaqueId != "a" || ids[1].OpaqueId != "b" {
+		t.Errorf("search with max 2 = %v", ids)
+	}
+}
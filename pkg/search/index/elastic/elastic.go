@@ -0,0 +1,264 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package elastic provides a search index stored in Elasticsearch.
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("elastic", New)
+}
+
+type config struct {
+	Address  string `mapstructure:"address"`
+	Index    string `mapstructure:"index"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Insecure bool   `mapstructure:"insecure"`
+	Timeout  int    `mapstructure:"timeout"`
+}
+
+func (c *config) init() {
+	if c.Address == "" {
+		c.Address = "http://localhost:9200"
+	}
+	if c.Index == "" {
+		c.Index = "reva"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10
+	}
+}
+
+// mapping stores all the fields as keywords, the documents are tokenized by reva.
+var mapping = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"properties": map[string]interface{}{
+			"storage_id": map[string]string{"type": "keyword"},
+			"opaque_id":  map[string]string{"type": "keyword"},
+			"name":       map[string]string{"type": "keyword"},
+			"mime_type":  map[string]string{"type": "keyword"},
+			"tags":       map[string]string{"type": "keyword"},
+			"content":    map[string]string{"type": "keyword"},
+		},
+	},
+}
+
+type index struct {
+	c      *config
+	client *http.Client
+
+	mu      sync.Mutex
+	created bool
+}
+
+// New returns a search index backed by Elasticsearch.
+func New(m map[string]interface{}) (search.Index, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "elastic: error decoding conf")
+	}
+	c.init()
+
+	return &index{
+		c:      c,
+		client: rhttp.GetHTTPClient(rhttp.Timeout(time.Duration(c.Timeout)*time.Second), rhttp.Insecure(c.Insecure)),
+	}, nil
+}
+
+func (i *index) url(p string) string {
+	return strings.TrimSuffix(i.c.Address, "/") + "/" + url.PathEscape(i.c.Index) + p
+}
+
+func (i *index) docURL(id *provider.ResourceId) string {
+	return i.url("/_doc/" + url.PathEscape(id.GetStorageId()+"!"+id.GetOpaqueId()))
+}
+
+func (i *index) do(ctx context.Context, method, u string, body interface{}, res interface{}) (int, error) {
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, &b)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if i.c.Username != "" {
+		req.SetBasicAuth(i.c.Username, i.c.Password)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "elastic: error sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 || res == nil {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return resp.StatusCode, errors.Wrap(err, "elastic: error decoding response")
+	}
+	return resp.StatusCode, nil
+}
+
+// ensureIndex creates the index with its mapping the first time it is used.
+func (i *index) ensureIndex(ctx context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.created {
+		return nil
+	}
+	code, err := i.do(ctx, http.MethodHead, i.url(""), nil, nil)
+	if err != nil {
+		return err
+	}
+	if code == http.StatusNotFound {
+		code, err = i.do(ctx, http.MethodPut, i.url(""), mapping, nil)
+		if err != nil {
+			return err
+		}
+		// another reva instance may have created it meanwhile
+		if code/100 != 2 && code != http.StatusBadRequest {
+			return fmt.Errorf("elastic: unexpected status %d creating index %s", code, i.c.Index)
+		}
+	}
+	i.created = true
+	return nil
+}
+
+func (i *index) Add(ctx context.Context, d *search.Document) error {
+	if err := i.ensureIndex(ctx); err != nil {
+		return err
+	}
+	code, err := i.do(ctx, http.MethodPut, i.docURL(d.ID()), d, nil)
+	if err != nil {
+		return err
+	}
+	if code/100 != 2 {
+		return fmt.Errorf("elastic: unexpected status %d indexing document", code)
+	}
+	return nil
+}
+
+func (i *index) Remove(ctx context.Context, id *provider.ResourceId) error {
+	code, err := i.do(ctx, http.MethodDelete, i.docURL(id), nil, nil)
+	if err != nil {
+		return err
+	}
+	if code/100 != 2 && code != http.StatusNotFound {
+		return fmt.Errorf("elastic: unexpected status %d removing document", code)
+	}
+	return nil
+}
+
+type searchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source search.Document `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (i *index) Search(ctx context.Context, q *search.Query, max int) ([]*provider.ResourceId, error) {
+	if max <= 0 {
+		max = 1000
+	}
+	body := map[string]interface{}{
+		"size":    max,
+		"_source": []string{"storage_id", "opaque_id"},
+		"query":   query(q),
+	}
+	res := &searchResponse{}
+	code, err := i.do(ctx, http.MethodPost, i.url("/_search"), body, res)
+	switch {
+	case err != nil:
+		return nil, err
+	case code == http.StatusNotFound:
+		// nothing has been indexed yet
+		return nil, nil
+	case code != http.StatusOK:
+		return nil, fmt.Errorf("elastic: unexpected status %d searching", code)
+	}
+
+	ids := make([]*provider.ResourceId, 0, len(res.Hits.Hits))
+	for _, h := range res.Hits.Hits {
+		ids = append(ids, h.Source.ID())
+	}
+	return ids, nil
+}
+
+type obj = map[string]interface{}
+
+// query translates a search query into an Elasticsearch bool query.
+func query(q *search.Query) obj {
+	must := []obj{}
+	for _, t := range q.Terms {
+		content := []obj{}
+		for _, token := range search.Tokenize(t) {
+			content = append(content, obj{"term": obj{"content": token}})
+		}
+		must = append(must, obj{"bool": obj{
+			"should": []obj{
+				{"wildcard": obj{"name": obj{"value": "*" + escapeWildcard(t) + "*"}}},
+				{"bool": obj{"must": content}},
+			},
+			"minimum_should_match": 1,
+		}})
+	}
+	for _, n := range q.Names {
+		if !strings.ContainsAny(n, "*?[") {
+			n = "*" + escapeWildcard(n) + "*"
+		}
+		must = append(must, obj{"wildcard": obj{"name": obj{"value": n}}})
+	}
+	for _, mt := range q.MimeTypes {
+		must = append(must, obj{"prefix": obj{"mime_type": mt}})
+	}
+	for _, t := range q.Tags {
+		must = append(must, obj{"term": obj{"tags": t}})
+	}
+	for _, c := range q.Content {
+		must = append(must, obj{"term": obj{"content": c}})
+	}
+	return obj{"bool": obj{"must": must}}
+}
+
+func escapeWildcard(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`).Replace(s)
+}
@@ -0,0 +1,230 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package local provides a search index kept in memory and optionally
+// persisted to a file. It scans all documents on every search and is meant
+// for small installations and testing.
+//
+// The file is a log of the changes to the index, one JSON object per line,
+// which is compacted once it holds more changes than twice the documents.
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("local", New)
+}
+
+// minCompaction is the number of changes logged before the file is first compacted.
+const minCompaction = 1000
+
+type config struct {
+	// File is where the index is persisted, the index is only kept in memory if empty.
+	File string `mapstructure:"file"`
+}
+
+// change is an entry of the index file, either an added document or a
+// removed one, of which only the id is kept.
+type change struct {
+	Doc     *search.Document `json:"doc,omitempty"`
+	Removed *search.Document `json:"removed,omitempty"`
+}
+
+type index struct {
+	file string
+	sync.RWMutex
+	docs map[string]*search.Document
+	// changes is the number of changes in the file
+	changes int
+}
+
+// New returns a new local search index.
+func New(m map[string]interface{}) (search.Index, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "local: error decoding conf")
+	}
+
+	i := &index{
+		file: c.File,
+		docs: map[string]*search.Document{},
+	}
+	if c.File != "" {
+		if err := i.load(); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+// load replays the changes of the index file. A change truncated by a crash
+// ends the log, which is then compacted.
+func (i *index) load() error {
+	f, err := os.Open(i.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "local: error reading index file")
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for {
+		c := &change{}
+		err := dec.Decode(c)
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			return i.compact()
+		}
+		if err != nil {
+			return errors.Wrap(err, "local: error decoding index file")
+		}
+		i.apply(c)
+		i.changes++
+	}
+}
+
+func (i *index) apply(c *change) {
+	switch {
+	case c.Doc != nil:
+		i.docs[key(c.Doc.ID())] = c.Doc
+	case c.Removed != nil:
+		delete(i.docs, key(c.Removed.ID()))
+	}
+}
+
+func key(id *provider.ResourceId) string {
+	return id.GetStorageId() + "!" + id.GetOpaqueId()
+}
+
+func (i *index) Add(ctx context.Context, d *search.Document) error {
+	i.Lock()
+	defer i.Unlock()
+	c := &change{Doc: d}
+	i.apply(c)
+	return i.log(c)
+}
+
+func (i *index) Remove(ctx context.Context, id *provider.ResourceId) error {
+	i.Lock()
+	defer i.Unlock()
+	if _, ok := i.docs[key(id)]; !ok {
+		return nil
+	}
+	c := &change{Removed: &search.Document{StorageID: id.GetStorageId(), OpaqueID: id.GetOpaqueId()}}
+	i.apply(c)
+	return i.log(c)
+}
+func (i *index) Search(ctx context.Context, q *search.Query, max int) ([]*provider.ResourceId, error) {
+	type hit struct {
+		doc   *search.Document
+		score int
+	}
+
+	i.RLock()
+	hits := []hit{}
+	for _, d := range i.docs {
+		if score, ok := q.Match(d); ok {
+			hits = append(hits, hit{doc: d, score: score})
+		}
+	}
+	i.RUnlock()
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].score != hits[b].score {
+			return hits[a].score > hits[b].score
+		}
+		return hits[a].doc.Name < hits[b].doc.Name
+	})
+	if max > 0 && len(hits) > max {
+		hits = hits[:max]
+	}
+
+	ids := make([]*provider.ResourceId, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.doc.ID())
+	}
+	return ids, nil
+}
+
+// log appends a change to the file, it has to be called with the lock held.
+func (i *index) log(c *change) error {
+	if i.file == "" {
+		return nil
+	}
+	if i.changes >= minCompaction && i.changes > 2*len(i.docs) {
+		return i.compact()
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return errors.Wrap(err, "local: error encoding index change")
+	}
+	f, err := os.OpenFile(i.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "local: error opening index file")
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrap(err, "local: error writing index file")
+	}
+	i.changes++
+	return nil
+}
+
+// compact rewrites the file with a change per document, it has to be called
+// with the lock held.
+func (i *index) compact() error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, d := range i.docs {
+		if err := enc.Encode(&change{Doc: d}); err != nil {
+			return errors.Wrap(err, "local: error encoding index")
+		}
+	}
+	tmp := i.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b.Bytes(), 0600); err != nil {
+		return errors.Wrap(err, "local: error writing index file")
+	}
+	if err := os.Rename(tmp, i.file); err != nil {
+		return errors.Wrap(err, "local: error writing index file")
+	}
+	i.changes = len(i.docs)
+	return nil
+}
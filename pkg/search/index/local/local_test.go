@@ -0,0 +1,186 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package local_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/index/local"
+)
+
+func doc(id, name, content string) *search.Document {
+	return search.NewDocument(&provider.ResourceInfo{
+		Id:   &provider.ResourceId{StorageId: "s", OpaqueId: id},
+		Path: "/" + name,
+	}, content)
+}
+
+func find(t *testing.T, i search.Index, query string) []string {
+	q, err := search.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := i.Search(context.Background(), q, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := []string{}
+	for _, id := range ids {
+		found = append(found, id.OpaqueId)
+	}
+	return found
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "index.json")
+	i, err := local.New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []*search.Document{
+		doc("1", "budget.txt", "the budget of the year"),
+		doc("2", "report.txt", "the budget was exceeded"),
+		doc("3", "photo.jpg", ""),
+	} {
+		if err := i.Add(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the name matches score higher than the content ones
+	if got, want := find(t, i, "budget"), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search budget = %v, want %v", got, want)
+	}
+
+	// adding a document with the same id replaces it
+	if err := i.Add(ctx, doc("2", "report.txt", "all good")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := find(t, i, "budget"), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search budget after update = %v, want %v", got, want)
+	}
+
+	if err := i.Remove(ctx, &provider.ResourceId{StorageId: "s", OpaqueId: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Remove(ctx, &provider.ResourceId{StorageId: "s", OpaqueId: "unknown"}); err != nil {
+		t.Errorf("removing an unknown document failed: %v", err)
+	}
+	if got := find(t, i, "budget"); len(got) != 0 {
+		t.Errorf("search budget after remove = %v, want no hits", got)
+	}
+
+	// the index is restored from its file
+	i, err = local.New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := find(t, i, "name:*.txt"), []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search in reloaded index = %v, want %v", got, want)
+	}
+}
+
+func TestSearchMax(t *testing.T) {
+	ctx := context.Background()
+	i, err := local.New(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := i.Add(ctx, doc(id, id+".txt", "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q, _ := search.ParseQuery("name:*.txt")
+	ids, err := i.Search(ctx, q, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0].OpaqueId != "a" || ids[1].OpaqueId != "b" {
+		t.Errorf("search with max 2 = %v", ids)
+	}
+}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "index.json")
+	i, err := local.New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := func() int {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.Count(data, []byte("\n"))
+	}
+
+	// the changes are appended
+	if err := i.Add(ctx, doc("1", "a.txt", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Add(ctx, doc("2", "b.txt", "")); err != nil {
+		t.Fatal(err)
+	}
+	if got := lines(); got != 2 {
+		t.Fatalf("expected 2 logged changes, got %d", got)
+	}
+
+	// the log is compacted once it holds many more changes than documents
+	for n := 0; n < 2000; n++ {
+		if err := i.Add(ctx, doc("1", "a.txt", strconv.Itoa(n))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := lines(); got >= 1100 {
+		t.Fatalf("expected the log to be compacted, got %d changes", got)
+	}
+
+	// a change truncated by a crash is dropped
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"doc":{"storage_id":"s","opaque_id":"3","na`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	i, err = local.New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := find(t, i, "name:*.txt"), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("search in recovered index = %v, want %v", got, want)
+	}
+	if got := lines(); got != 2 {
+		t.Errorf("expected the recovered log to be compacted, got %d changes", got)
+	}
+}
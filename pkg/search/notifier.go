@@ -0,0 +1,151 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	searchpb "github.com/cs3org/reva/pkg/search/proto"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// textMimeTypes are the mime types besides text/* whose content is indexed.
+var textMimeTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+}
+
+// Notifier keeps the search index up to date with the changes made to a storage.
+// The search service is called in the background so that the storage operations
+// are not slowed down, failures are only logged.
+type Notifier struct {
+	endpoint  string
+	storageID string
+	// maxContentSize is the size up to which the content of text files is indexed, negative disables it
+	maxContentSize int64
+}
+
+// NewNotifier returns a notifier sending the changes to the search service at endpoint.
+// The storage id is used for the resources the driver returns without one.
+func NewNotifier(endpoint, storageID string, maxContentSize int64) *Notifier {
+	return &Notifier{
+		endpoint:       endpoint,
+		storageID:      storageID,
+		maxContentSize: maxContentSize,
+	}
+}
+
+// Updated indexes the resource referenced by ref.
+func (n *Notifier) Updated(ctx context.Context, fs storage.FS, ref *provider.Reference) {
+	ctx = appctx.DetachContext(ctx)
+	go func() {
+		if err := n.update(ctx, fs, ref); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Interface("ref", ref).Msg("search: error indexing resource")
+		}
+	}()
+}
+
+// Removed removes the resource with the given id from the index.
+func (n *Notifier) Removed(ctx context.Context, id *provider.ResourceId) {
+	ctx = appctx.DetachContext(ctx)
+	go func() {
+		if err := n.remove(ctx, id); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Interface("id", id).Msg("search: error removing resource from index")
+		}
+	}()
+}
+
+func (n *Notifier) update(ctx context.Context, fs storage.FS, ref *provider.Reference) error {
+	info, err := fs.GetMD(ctx, ref, []string{TagsKey})
+	if err != nil {
+		return err
+	}
+	if info.Id == nil {
+		return errors.New("search: resource has no id")
+	}
+	if info.Id.StorageId == "" {
+		info.Id.StorageId = n.storageID
+	}
+
+	var content string
+	if info.Type == provider.ResourceType_RESOURCE_TYPE_FILE && n.isText(info) {
+		rc, err := fs.Download(ctx, ref)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		b, err := ioutil.ReadAll(io.LimitReader(rc, n.maxContentSize))
+		if err != nil {
+			return err
+		}
+		content = string(b)
+	}
+
+	c, err := pool.GetSearchServiceClient(n.endpoint)
+	if err != nil {
+		return err
+	}
+	res, err := c.IndexResource(ctx, &searchpb.IndexResourceRequest{Info: info, Content: content})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(res.Status.Code, "search")
+	}
+	return nil
+}
+
+func (n *Notifier) remove(ctx context.Context, id *provider.ResourceId) error {
+	if id.StorageId == "" {
+		id = &provider.ResourceId{StorageId: n.storageID, OpaqueId: id.OpaqueId}
+	}
+	c, err := pool.GetSearchServiceClient(n.endpoint)
+	if err != nil {
+		return err
+	}
+	res, err := c.RemoveResource(ctx, &searchpb.RemoveResourceRequest{Id: id})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(res.Status.Code, "search")
+	}
+	return nil
+}
+
+func (n *Notifier) isText(info *provider.ResourceInfo) bool {
+	if n.maxContentSize < 0 || info.Size > uint64(n.maxContentSize) {
+		return false
+	}
+	mime := strings.ToLower(info.MimeType)
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = strings.TrimSpace(mime[:i])
+	}
+	return strings.HasPrefix(mime, "text/") || textMimeTypes[mime]
+}
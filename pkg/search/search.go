@@ -0,0 +1,235 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package search implements the query language and the index abstraction
+// of the search service.
+//
+// A query is made of whitespace separated terms, all of which have to match:
+//
+//	report                 the name or the content contains "report"
+//	name:*.pdf             the name matches the pattern, or contains the value without wildcards
+//	mime:image/            the mime type starts with the value
+//	tag:finance            the resource is tagged with the value
+//	content:budget         the content contains the word
+//
+// Values containing spaces can be quoted, eg. name:"annual report".
+package search
+
+import (
+	"context"
+	"path"
+	"strings"
+	"unicode"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// TagsKey is the arbitrary metadata key holding the comma separated tags of a resource.
+const TagsKey = "tags"
+
+// Query is a parsed search query.
+type Query struct {
+	// Terms match the name or the content.
+	Terms     []string
+	Names     []string
+	MimeTypes []string
+	Tags      []string
+	Content   []string
+}
+
+// ParseQuery parses a query. All values are lower cased.
+func ParseQuery(q string) (*Query, error) {
+	fields, err := splitQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errtypes.BadRequest("search: empty query")
+	}
+
+	query := &Query{}
+	for _, f := range fields {
+		key, value := "", f
+		if i := strings.Index(f, ":"); i > 0 {
+			key, value = f[:i], f[i+1:]
+		}
+		value = strings.ToLower(value)
+		if value == "" {
+			return nil, errtypes.BadRequest("search: empty value in query term: " + f)
+		}
+		switch key {
+		case "name":
+			query.Names = append(query.Names, value)
+		case "mime":
+			query.MimeTypes = append(query.MimeTypes, value)
+		case "tag":
+			query.Tags = append(query.Tags, value)
+		case "content":
+			query.Content = append(query.Content, Tokenize(value)...)
+		default:
+			// not a known field, eg. a time like 10:30
+			query.Terms = append(query.Terms, strings.ToLower(f))
+		}
+	}
+	return query, nil
+}
+
+// splitQuery splits the query at whitespace, keeping quoted values together.
+func splitQuery(q string) ([]string, error) {
+	var fields []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if b.Len() > 0 {
+				fields = append(fields, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errtypes.BadRequest("search: unterminated quote in query")
+	}
+	if b.Len() > 0 {
+		fields = append(fields, b.String())
+	}
+	return fields, nil
+}
+
+// Document is a resource as stored in the index.
+type Document struct {
+	StorageID string   `json:"storage_id"`
+	OpaqueID  string   `json:"opaque_id"`
+	Name      string   `json:"name"`
+	MimeType  string   `json:"mime_type"`
+	Tags      []string `json:"tags,omitempty"`
+	// Content holds the distinct words of the content.
+	Content []string `json:"content,omitempty"`
+}
+
+// NewDocument creates the document to index for a resource.
+func NewDocument(info *provider.ResourceInfo, content string) *Document {
+	d := &Document{
+		StorageID: info.GetId().GetStorageId(),
+		OpaqueID:  info.GetId().GetOpaqueId(),
+		Name:      strings.ToLower(path.Base(info.Path)),
+		MimeType:  strings.ToLower(info.MimeType),
+		Content:   Tokenize(content),
+	}
+	if tags, ok := info.GetArbitraryMetadata().GetMetadata()[TagsKey]; ok {
+		for _, t := range strings.Split(tags, ",") {
+			if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+				d.Tags = append(d.Tags, t)
+			}
+		}
+	}
+	return d
+}
+
+// ID returns the id of the resource the document belongs to.
+func (d *Document) ID() *provider.ResourceId {
+	return &provider.ResourceId{StorageId: d.StorageID, OpaqueId: d.OpaqueID}
+}
+
+// Match returns whether the document matches the query and how well.
+func (q *Query) Match(d *Document) (int, bool) {
+	score := 0
+	for _, t := range q.Terms {
+		switch {
+		case strings.Contains(d.Name, t):
+			score += 2
+		case contains(d.Content, Tokenize(t)...):
+			score++
+		default:
+			return 0, false
+		}
+	}
+	for _, n := range q.Names {
+		if !MatchName(d.Name, n) {
+			return 0, false
+		}
+		score += 2
+	}
+	for _, m := range q.MimeTypes {
+		if !strings.HasPrefix(d.MimeType, m) {
+			return 0, false
+		}
+	}
+	if !contains(d.Tags, q.Tags...) || !contains(d.Content, q.Content...) {
+		return 0, false
+	}
+	return score + len(q.Tags) + len(q.Content), true
+}
+
+// MatchName matches a lower cased name against a pattern. Patterns without
+// wildcards match if the name contains them.
+func MatchName(name, pattern string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.Contains(name, pattern)
+	}
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+func contains(set []string, values ...string) bool {
+	for _, v := range values {
+		found := false
+		for _, s := range set {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Tokenize returns the distinct lower cased words of a text.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	seen := make(map[string]struct{}, len(words))
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		if _, ok := seen[w]; !ok {
+			seen[w] = struct{}{}
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// Index stores the documents of the indexed resources.
+type Index interface {
+	// Add adds a document, replacing the one with the same id.
+	Add(ctx context.Context, d *Document) error
+	// Remove removes the document of a resource. Removing an unknown document is not an error.
+	Remove(ctx context.Context, id *provider.ResourceId) error
+	// Search returns the ids of at most max documents matching the query, best matches first.
+	Search(ctx context.Context, q *Query, max int) ([]*provider.ResourceId, error)
+}
@@ -0,0 +1,114 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search_test
+
+import (
+	"reflect"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/search"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  *search.Query
+	}{
+		{"Report", &search.Query{Terms: []string{"report"}}},
+		{"name:*.PDF mime:image/", &search.Query{Names: []string{"*.pdf"}, MimeTypes: []string{"image/"}}},
+		{`name:"annual report" tag:Finance`, &search.Query{Names: []string{"annual report"}, Tags: []string{"finance"}}},
+		{"content:budget,Plan", &search.Query{Content: []string{"budget", "plan"}}},
+		{"10:30  meeting", &search.Query{Terms: []string{"10:30", "meeting"}}},
+	}
+	for _, tt := range tests {
+		got, err := search.ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, q := range []string{"", "   ", "name:", `name:"report`} {
+		if _, err := search.ParseQuery(q); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded, want an error", q)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"The budget, the PLAN; the budget!", []string{"the", "budget", "plan"}},
+		{"Größe 2021-05", []string{"größe", "2021", "05"}},
+	}
+	for _, tt := range tests {
+		if got := search.Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	doc := search.NewDocument(&provider.ResourceInfo{
+		Id:       &provider.ResourceId{StorageId: "s", OpaqueId: "o"},
+		Path:     "/home/Reports/Annual Report.pdf",
+		MimeType: "application/PDF",
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{search.TagsKey: "Finance, 2021 ,"},
+		},
+	}, "The budget of the year")
+
+	tests := []struct {
+		query string
+		score int
+		match bool
+	}{
+		{"report", 2, true},
+		{"budget", 1, true},
+		{"report budget", 3, true},
+		{"invoice", 0, false},
+		{"name:*.pdf", 2, true},
+		{"name:annual", 2, true},
+		{"name:*.txt", 0, false},
+		{"mime:application/", 0, true},
+		{"mime:image/", 0, false},
+		{"tag:finance tag:2021", 2, true},
+		{"tag:hr", 0, false},
+		{"content:year", 1, true},
+		{"content:month", 0, false},
+		{"report tag:hr", 0, false},
+	}
+	for _, tt := range tests {
+		q, err := search.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		score, ok := q.Match(doc)
+		if ok != tt.match || score != tt.score {
+			t.Errorf("query %q: Match = %d, %v, want %d, %v", tt.query, score, ok, tt.score, tt.match)
+		}
+	}
+}
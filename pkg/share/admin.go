@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// AdminOpaqueKey is the opaque key of the list and remove requests made by an
+// admin on the shares of all the users. The share providers serve them only
+// to the users they are configured with as admins.
+const AdminOpaqueKey = "admin"
+
+// EncodeAdmin marks the request of the opaque, which is created if nil, as
+// made by an admin.
+func EncodeAdmin(o *typespb.Opaque) *typespb.Opaque {
+	return setOpaqueEntry(o, AdminOpaqueKey, &typespb.OpaqueEntry{Decoder: "plain", Value: []byte("true")})
+}
+
+// IsAdmin tells whether the request of the opaque is made by an admin.
+func IsAdmin(o *typespb.Opaque) bool {
+	e, ok := o.GetMap()[AdminOpaqueKey]
+	return ok && e.Decoder == "plain" && string(e.Value) == "true"
+}
+
+// MatchFilters tells whether the share matches the filters of a listing: one
+// of the filters of each type has to match.
+func MatchFilters(s *collaboration.Share, filters []*collaboration.ListSharesRequest_Filter) bool {
+	matched := map[collaboration.ListSharesRequest_Filter_Type]bool{}
+	for _, f := range filters {
+		if !matched[f.Type] {
+			matched[f.Type] = matchFilter(s, f)
+		}
+	}
+	for _, m := range matched {
+		if !m {
+			return false
+		}
+	}
+	return true
+}
+
+func matchFilter(s *collaboration.Share, f *collaboration.ListSharesRequest_Filter) bool {
+	switch f.Type {
+	case collaboration.ListSharesRequest_Filter_TYPE_RESOURCE_ID:
+		return utils.ResourceEqual(s.ResourceId, f.GetResourceId())
+	case collaboration.ListSharesRequest_Filter_TYPE_OWNER:
+		return MatchUser(s.Owner, f.GetOwner())
+	case collaboration.ListSharesRequest_Filter_TYPE_CREATOR:
+		return MatchUser(s.Creator, f.GetCreator())
+	default:
+		return true
+	}
+}
+
+// MatchUser tells whether the user id matches the one of a filter, which may
+// leave the idp out.
+func MatchUser(u, filter *userpb.UserId) bool {
+	if filter.GetIdp() == "" {
+		return u != nil && u.OpaqueId == filter.GetOpaqueId()
+	}
+	return utils.UserEqual(u, filter)
+}
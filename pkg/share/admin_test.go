@@ -0,0 +1,78 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestAdminOpaque(t *testing.T) {
+	if IsAdmin(nil) {
+		t.Fatal("expected a request without opaque not to be an admin request")
+	}
+	o := EncodeAdmin(nil)
+	if !IsAdmin(o) {
+		t.Fatal("expected an admin request")
+	}
+	o.Map[AdminOpaqueKey].Value = []byte("false")
+	if IsAdmin(o) {
+		t.Fatal("expected the admin entry to be false")
+	}
+}
+
+func TestMatchFilters(t *testing.T) {
+	s := &collaboration.Share{
+		ResourceId: &provider.ResourceId{StorageId: "s", OpaqueId: "r"},
+		Owner:      &userpb.UserId{Idp: "idp", OpaqueId: "einstein"},
+		Creator:    &userpb.UserId{Idp: "idp", OpaqueId: "marie"},
+	}
+	owner := func(idp, id string) *collaboration.ListSharesRequest_Filter {
+		return &collaboration.ListSharesRequest_Filter{
+			Type: collaboration.ListSharesRequest_Filter_TYPE_OWNER,
+			Term: &collaboration.ListSharesRequest_Filter_Owner{Owner: &userpb.UserId{Idp: idp, OpaqueId: id}},
+		}
+	}
+	resource := func(id string) *collaboration.ListSharesRequest_Filter {
+		return &collaboration.ListSharesRequest_Filter{
+			Type: collaboration.ListSharesRequest_Filter_TYPE_RESOURCE_ID,
+			Term: &collaboration.ListSharesRequest_Filter_ResourceId{ResourceId: &provider.ResourceId{StorageId: "s", OpaqueId: id}},
+		}
+	}
+
+	tests := []struct {
+		filters []*collaboration.ListSharesRequest_Filter
+		match   bool
+	}{
+		{nil, true},
+		{[]*collaboration.ListSharesRequest_Filter{owner("", "einstein")}, true},
+		{[]*collaboration.ListSharesRequest_Filter{owner("other", "einstein")}, false},
+		{[]*collaboration.ListSharesRequest_Filter{owner("idp", "marie"), owner("idp", "einstein")}, true},
+		{[]*collaboration.ListSharesRequest_Filter{owner("idp", "einstein"), resource("other")}, false},
+		{[]*collaboration.ListSharesRequest_Filter{owner("idp", "einstein"), resource("r")}, true},
+	}
+	for i, tt := range tests {
+		if got := MatchFilters(s, tt.filters); got != tt.match {
+			t.Errorf("test %d: MatchFilters = %v, want %v", i, got, tt.match)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+const (
+	// ExpirationOpaqueKey is the opaque key holding the expiration of a share
+	// in the create and update share requests and in the share responses, as
+	// seconds since the epoch. An empty value removes the expiration.
+	ExpirationOpaqueKey = "expiration"
+	// ExpirationsOpaqueKey is the opaque key holding the expirations of the
+	// shares of a listing, as a JSON object from the share ids to seconds
+	// since the epoch. Shares without expiration are left out.
+	ExpirationsOpaqueKey = "expirations"
+)
+
+// Expirer is implemented by the managers that are able to expire shares. Expired
+// shares are not returned to their grantees anymore, but their owners and
+// creators still see them until they are removed.
+type Expirer interface {
+	// SetExpiration sets the time the share expires at, nil removes the expiration.
+	SetExpiration(ctx context.Context, ref *collaboration.ShareReference, exp *typespb.Timestamp) error
+	// GetExpirations returns the expirations of the given shares by share id.
+	// The shares without expiration are left out.
+	GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error)
+	// ListExpiredShares returns the shares expired at the given time, regardless
+	// of the user in context.
+	ListExpiredShares(ctx context.Context, t time.Time) ([]*collaboration.Share, error)
+}
+
+// Expired tells whether a share with the given expiration is expired at t.
+func Expired(exp *typespb.Timestamp, t time.Time) bool {
+	return exp != nil && !time.Unix(int64(exp.Seconds), int64(exp.Nanos)).After(t)
+}
+
+// EncodeExpiration adds the expiration to the opaque, which is created if nil.
+// A nil expiration is encoded as its removal.
+func EncodeExpiration(o *typespb.Opaque, exp *typespb.Timestamp) *typespb.Opaque {
+	var v string
+	if exp != nil {
+		v = strconv.FormatUint(exp.Seconds, 10)
+	}
+	return setOpaqueEntry(o, ExpirationOpaqueKey, &typespb.OpaqueEntry{Decoder: "plain", Value: []byte(v)})
+}
+
+// DecodeExpiration returns the expiration held by the opaque. The boolean is
+// false if the opaque holds none, the expiration is nil if it is removed.
+func DecodeExpiration(o *typespb.Opaque) (*typespb.Timestamp, bool, error) {
+	e, ok := o.GetMap()[ExpirationOpaqueKey]
+	if !ok {
+		return nil, false, nil
+	}
+	if e.Decoder != "plain" {
+		return nil, false, errtypes.BadRequest("share: unsupported decoder for " + ExpirationOpaqueKey + ": " + e.Decoder)
+	}
+	if len(e.Value) == 0 {
+		return nil, true, nil
+	}
+	secs, err := strconv.ParseUint(string(e.Value), 10, 64)
+	if err != nil {
+		return nil, false, errtypes.BadRequest("share: invalid " + ExpirationOpaqueKey + ": " + string(e.Value))
+	}
+	return &typespb.Timestamp{Seconds: secs}, true, nil
+}
+
+// EncodeExpirations adds the expirations of listed shares to the opaque, which
+// is created if nil.
+func EncodeExpirations(o *typespb.Opaque, exps map[string]*typespb.Timestamp) (*typespb.Opaque, error) {
+	secs := make(map[string]uint64, len(exps))
+	for id, exp := range exps {
+		secs[id] = exp.GetSeconds()
+	}
+	v, err := json.Marshal(secs)
+	if err != nil {
+		return nil, err
+	}
+	return setOpaqueEntry(o, ExpirationsOpaqueKey, &typespb.OpaqueEntry{Decoder: "json", Value: v}), nil
+}
+
+// DecodeExpirations returns the expirations of listed shares held by the
+// opaque, an empty map if it holds none.
+func DecodeExpirations(o *typespb.Opaque) (map[string]*typespb.Timestamp, error) {
+	exps := map[string]*typespb.Timestamp{}
+	e, ok := o.GetMap()[ExpirationsOpaqueKey]
+	if !ok {
+		return exps, nil
+	}
+	if e.Decoder != "json" {
+		return nil, errtypes.BadRequest("share: unsupported decoder for " + ExpirationsOpaqueKey + ": " + e.Decoder)
+	}
+	secs := map[string]uint64{}
+	if err := json.Unmarshal(e.Value, &secs); err != nil {
+		return nil, errtypes.BadRequest("share: invalid " + ExpirationsOpaqueKey + ": " + err.Error())
+	}
+	for id, s := range secs {
+		exps[id] = &typespb.Timestamp{Seconds: s}
+	}
+	return exps, nil
+}
+
+func setOpaqueEntry(o *typespb.Opaque, key string, e *typespb.OpaqueEntry) *typespb.Opaque {
+	if o == nil {
+		o = &typespb.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*typespb.OpaqueEntry{}
+	}
+	o.Map[key] = e
+	return o
+}
@@ -0,0 +1,80 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"testing"
+	"time"
+
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func TestExpirationOpaque(t *testing.T) {
+	if _, ok, err := DecodeExpiration(nil); ok || err != nil {
+		t.Fatalf("expected no expiration, got %v %v", ok, err)
+	}
+
+	o := EncodeExpiration(nil, &typespb.Timestamp{Seconds: 1700000000, Nanos: 5})
+	exp, ok, err := DecodeExpiration(o)
+	if err != nil || !ok || exp.Seconds != 1700000000 {
+		t.Fatalf("unexpected expiration %v %v %v", exp, ok, err)
+	}
+
+	o = EncodeExpiration(o, nil)
+	if exp, ok, err := DecodeExpiration(o); err != nil || !ok || exp != nil {
+		t.Fatalf("expected the expiration to be removed, got %v %v %v", exp, ok, err)
+	}
+
+	o.Map[ExpirationOpaqueKey].Value = []byte("tomorrow")
+	if _, _, err := DecodeExpiration(o); err == nil {
+		t.Fatal("expected an invalid expiration to be rejected")
+	}
+}
+
+func TestExpirationsOpaque(t *testing.T) {
+	o, err := EncodeExpirations(nil, map[string]*typespb.Timestamp{"a": {Seconds: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exps, err := DecodeExpirations(o)
+	if err != nil || len(exps) != 1 || exps["a"].Seconds != 10 {
+		t.Fatalf("unexpected expirations %v %v", exps, err)
+	}
+	if exps, err := DecodeExpirations(nil); err != nil || len(exps) != 0 {
+		t.Fatalf("expected no expirations, got %v %v", exps, err)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		exp     *typespb.Timestamp
+		expired bool
+	}{
+		{nil, false},
+		{&typespb.Timestamp{Seconds: 999}, true},
+		{&typespb.Timestamp{Seconds: 1000}, true},
+		{&typespb.Timestamp{Seconds: 1001}, false},
+	}
+	for _, tt := range tests {
+		if got := Expired(tt.exp, now); got != tt.expired {
+			t.Errorf("Expired(%v) = %v, want %v", tt.exp, got, tt.expired)
+		}
+	}
+}
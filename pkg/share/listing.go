@@ -0,0 +1,269 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+const (
+	// ListOptionsOpaqueKey is the opaque key holding the list options of the
+	// list shares and list received shares requests, as a JSON object.
+	ListOptionsOpaqueKey = "list_options"
+	// ListTotalOpaqueKey is the opaque key holding the number of shares
+	// matching the list options in the list responses, before paging.
+	ListTotalOpaqueKey = "list_total"
+)
+
+const (
+	// SortByCtime sorts the shares by creation time, then by id.
+	SortByCtime = "ctime"
+	// SortByID sorts the shares by id, numerically when the ids are numbers.
+	SortByID = "id"
+)
+
+// ListOptions narrow, sort and page the listings of shares. The zero value
+// lists all the shares by creation time.
+type ListOptions struct {
+	// ResourceID keeps the shares of the resource.
+	ResourceID *provider.ResourceId `json:"resource_id,omitempty"`
+	// SpaceID keeps the shares of the resources of the storage space.
+	SpaceID string `json:"space_id,omitempty"`
+	// GranteeTypes keeps the shares whose grantee is of one of the types.
+	GranteeTypes []provider.GranteeType `json:"grantee_types,omitempty"`
+	// States keeps the received shares in one of the states.
+	States []collaboration.ShareState `json:"states,omitempty"`
+	// SortBy is the order of the shares, SortByCtime by default.
+	SortBy string `json:"sort_by,omitempty"`
+	// Descending reverses the order.
+	Descending bool `json:"descending,omitempty"`
+	// Offset is the number of matching shares skipped.
+	Offset int `json:"offset,omitempty"`
+	// Limit is the maximum number of shares returned, 0 returns all.
+	Limit int `json:"limit,omitempty"`
+}
+
+// PagedLister is implemented by the managers applying the list options
+// themselves, e.g. in their queries. The shares of the other managers are
+// listed entirely and the options are applied in memory.
+type PagedLister interface {
+	// ListSharesPage returns the page of the shares created by the user
+	// matching the filters and the options, and the number of matching shares.
+	ListSharesPage(ctx context.Context, filters []*collaboration.ListSharesRequest_Filter, o *ListOptions) ([]*collaboration.Share, int, error)
+	// ListReceivedSharesPage returns the page of the shares received by the
+	// user matching the options, and the number of matching shares.
+	ListReceivedSharesPage(ctx context.Context, o *ListOptions) ([]*collaboration.ReceivedShare, int, error)
+}
+
+// Validate checks that the options can be applied.
+func (o *ListOptions) Validate() error {
+	switch o.SortBy {
+	case "", SortByCtime, SortByID:
+	default:
+		return errtypes.BadRequest("share: unsupported sort order " + o.SortBy)
+	}
+	if o.Offset < 0 || o.Limit < 0 {
+		return errtypes.BadRequest("share: negative offset or limit")
+	}
+	return nil
+}
+
+// Match tells whether the share matches the resource, space and grantee types
+// of the options.
+func (o *ListOptions) Match(s *collaboration.Share) bool {
+	if o.ResourceID != nil && !utils.ResourceEqual(o.ResourceID, s.GetResourceId()) {
+		return false
+	}
+	if o.SpaceID != "" && s.GetResourceId().GetStorageId() != o.SpaceID {
+		return false
+	}
+	if len(o.GranteeTypes) > 0 {
+		found := false
+		for _, t := range o.GranteeTypes {
+			if s.GetGrantee().GetType() == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchReceived tells whether the received share matches the options.
+func (o *ListOptions) MatchReceived(rs *collaboration.ReceivedShare) bool {
+	if !o.Match(rs.GetShare()) {
+		return false
+	}
+	if len(o.States) == 0 {
+		return true
+	}
+	for _, st := range o.States {
+		if rs.GetState() == st {
+			return true
+		}
+	}
+	return false
+}
+
+// Less tells whether the share a comes before b in the order of the options.
+func (o *ListOptions) Less(a, b *collaboration.Share) bool {
+	c := 0
+	if o.SortBy != SortByID {
+		at, bt := a.GetCtime().GetSeconds(), b.GetCtime().GetSeconds()
+		switch {
+		case at < bt:
+			c = -1
+		case at > bt:
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = CompareIDs(a.GetId().GetOpaqueId(), b.GetId().GetOpaqueId())
+	}
+	if o.Descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+// Page returns the bounds of the page of the options in a listing of n items.
+func (o *ListOptions) Page(n int) (int, int) {
+	start := o.Offset
+	if start > n {
+		start = n
+	}
+	end := n
+	if o.Limit > 0 && start+o.Limit < n {
+		end = start + o.Limit
+	}
+	return start, end
+}
+
+// CompareIDs compares two share ids, numerically when both are numbers as
+// with the SQL managers.
+func CompareIDs(a, b string) int {
+	ai, aerr := strconv.ParseInt(a, 10, 64)
+	bi, berr := strconv.ParseInt(b, 10, 64)
+	if aerr == nil && berr == nil {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// ApplyListOptions returns the page of the shares matching the options, and
+// the number of matching shares.
+func ApplyListOptions(shares []*collaboration.Share, o *ListOptions) ([]*collaboration.Share, int) {
+	matched := make([]*collaboration.Share, 0, len(shares))
+	for _, s := range shares {
+		if o.Match(s) {
+			matched = append(matched, s)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return o.Less(matched[i], matched[j]) })
+	start, end := o.Page(len(matched))
+	return matched[start:end], len(matched)
+}
+
+// ApplyReceivedListOptions returns the page of the received shares matching
+// the options, and the number of matching shares.
+func ApplyReceivedListOptions(shares []*collaboration.ReceivedShare, o *ListOptions) ([]*collaboration.ReceivedShare, int) {
+	matched := make([]*collaboration.ReceivedShare, 0, len(shares))
+	for _, rs := range shares {
+		if o.MatchReceived(rs) {
+			matched = append(matched, rs)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return o.Less(matched[i].GetShare(), matched[j].GetShare()) })
+	start, end := o.Page(len(matched))
+	return matched[start:end], len(matched)
+}
+
+// EncodeListOptions adds the list options to the opaque, which is created if nil.
+func EncodeListOptions(o *typespb.Opaque, opts *ListOptions) (*typespb.Opaque, error) {
+	v, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	return setOpaqueEntry(o, ListOptionsOpaqueKey, &typespb.OpaqueEntry{Decoder: "json", Value: v}), nil
+}
+
+// DecodeListOptions returns the validated list options held by the opaque,
+// nil if it holds none.
+func DecodeListOptions(o *typespb.Opaque) (*ListOptions, error) {
+	e, ok := o.GetMap()[ListOptionsOpaqueKey]
+	if !ok {
+		return nil, nil
+	}
+	if e.Decoder != "json" {
+		return nil, errtypes.BadRequest("share: unsupported decoder for " + ListOptionsOpaqueKey + ": " + e.Decoder)
+	}
+	opts := &ListOptions{}
+	if err := json.Unmarshal(e.Value, opts); err != nil {
+		return nil, errtypes.BadRequest("share: invalid " + ListOptionsOpaqueKey + ": " + err.Error())
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// EncodeListTotal adds the number of shares matching the list options to the
+// opaque, which is created if nil.
+func EncodeListTotal(o *typespb.Opaque, total int) *typespb.Opaque {
+	return setOpaqueEntry(o, ListTotalOpaqueKey, &typespb.OpaqueEntry{Decoder: "plain", Value: []byte(strconv.Itoa(total))})
+}
+
+// DecodeListTotal returns the number of shares matching the list options held
+// by the opaque. The boolean is false if the opaque holds none, i.e. when the
+// provider didn't apply the options.
+func DecodeListTotal(o *typespb.Opaque) (int, bool) {
+	e, ok := o.GetMap()[ListTotalOpaqueKey]
+	if !ok || e.Decoder != "plain" {
+		return 0, false
+	}
+	total, err := strconv.Atoi(string(e.Value))
+	if err != nil {
+		return 0, false
+	}
+	return total, true
+}
@@ -0,0 +1,143 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"reflect"
+	"testing"
+
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func testShare(id, space string, ctime uint64, t provider.GranteeType) *collaboration.Share {
+	return &collaboration.Share{
+		Id:         &collaboration.ShareId{OpaqueId: id},
+		ResourceId: &provider.ResourceId{StorageId: space, OpaqueId: "file-" + id},
+		Grantee:    &provider.Grantee{Type: t},
+		Ctime:      &typespb.Timestamp{Seconds: ctime},
+	}
+}
+
+func ids(shares []*collaboration.Share) []string {
+	r := make([]string, 0, len(shares))
+	for _, s := range shares {
+		r = append(r, s.Id.OpaqueId)
+	}
+	return r
+}
+
+func TestApplyListOptions(t *testing.T) {
+	user, group := provider.GranteeType_GRANTEE_TYPE_USER, provider.GranteeType_GRANTEE_TYPE_GROUP
+	shares := []*collaboration.Share{
+		testShare("10", "a", 3, user),
+		testShare("9", "b", 1, group),
+		testShare("2", "a", 2, user),
+		testShare("1", "a", 2, group),
+	}
+	tests := []struct {
+		name  string
+		opts  ListOptions
+		ids   []string
+		total int
+	}{
+		{"all by ctime", ListOptions{}, []string{"9", "1", "2", "10"}, 4},
+		{"by id", ListOptions{SortBy: SortByID}, []string{"1", "2", "9", "10"}, 4},
+		{"descending", ListOptions{SortBy: SortByID, Descending: true}, []string{"10", "9", "2", "1"}, 4},
+		{"space", ListOptions{SpaceID: "a"}, []string{"1", "2", "10"}, 3},
+		{"grantee type", ListOptions{GranteeTypes: []provider.GranteeType{group}}, []string{"9", "1"}, 2},
+		{"resource", ListOptions{ResourceID: &provider.ResourceId{StorageId: "a", OpaqueId: "file-2"}}, []string{"2"}, 1},
+		{"page", ListOptions{Offset: 1, Limit: 2}, []string{"1", "2"}, 4},
+		{"last page", ListOptions{Offset: 3, Limit: 2}, []string{"10"}, 4},
+		{"beyond the end", ListOptions{Offset: 7, Limit: 2}, []string{}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total := ApplyListOptions(shares, &tt.opts)
+			if got := ids(page); !reflect.DeepEqual(got, tt.ids) {
+				t.Errorf("expected %v, got %v", tt.ids, got)
+			}
+			if total != tt.total {
+				t.Errorf("expected a total of %d, got %d", tt.total, total)
+			}
+		})
+	}
+}
+
+func TestApplyReceivedListOptions(t *testing.T) {
+	user := provider.GranteeType_GRANTEE_TYPE_USER
+	received := []*collaboration.ReceivedShare{
+		{Share: testShare("1", "a", 1, user), State: collaboration.ShareState_SHARE_STATE_ACCEPTED},
+		{Share: testShare("2", "a", 2, user), State: collaboration.ShareState_SHARE_STATE_PENDING},
+		{Share: testShare("3", "a", 3, user), State: collaboration.ShareState_SHARE_STATE_ACCEPTED},
+	}
+	opts := &ListOptions{States: []collaboration.ShareState{collaboration.ShareState_SHARE_STATE_ACCEPTED}, Descending: true, Limit: 1}
+	page, total := ApplyReceivedListOptions(received, opts)
+	if total != 2 || len(page) != 1 || page[0].Share.Id.OpaqueId != "3" {
+		t.Fatalf("unexpected page %v of %d", page, total)
+	}
+}
+
+func TestListOptionsOpaque(t *testing.T) {
+	if opts, err := DecodeListOptions(nil); opts != nil || err != nil {
+		t.Fatalf("expected no options, got %v %v", opts, err)
+	}
+
+	in := &ListOptions{SpaceID: "a", SortBy: SortByID, Offset: 20, Limit: 10}
+	o, err := EncodeListOptions(nil, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := DecodeListOptions(o)
+	if err != nil || out.SpaceID != "a" || out.SortBy != SortByID || out.Offset != 20 || out.Limit != 10 {
+		t.Fatalf("unexpected options %+v %v", out, err)
+	}
+
+	if o, err = EncodeListOptions(nil, &ListOptions{SortBy: "size"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeListOptions(o); err == nil {
+		t.Fatal("expected an unsupported order to be rejected")
+	}
+
+	if _, ok := DecodeListTotal(nil); ok {
+		t.Fatal("expected no total")
+	}
+	if total, ok := DecodeListTotal(EncodeListTotal(nil, 42)); !ok || total != 42 {
+		t.Fatalf("unexpected total %d %v", total, ok)
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		c    int
+	}{
+		{"2", "10", -1},
+		{"10", "10", 0},
+		{"b", "a", 1},
+		{"10", "a", -1},
+	}
+	for _, tt := range tests {
+		if c := CompareIDs(tt.a, tt.b); c != tt.c {
+			t.Errorf("CompareIDs(%q, %q) = %d, want %d", tt.a, tt.b, c, tt.c)
+		}
+	}
+}
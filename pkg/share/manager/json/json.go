@@ -26,11 +26,14 @@ import (
 	"sync"
 	"time"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/metrics"
 	"github.com/cs3org/reva/pkg/share"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -61,10 +64,24 @@ func New(m map[string]interface{}) (share.Manager, error) {
 		return nil, err
 	}
 
-	return &mgr{
+	mgr := &mgr{
 		c:     c,
 		model: model,
-	}, nil
+	}
+	metrics.RegisterCollector("share/json", mgr.collect)
+	return mgr, nil
+}
+
+// collect reports the number of shares in the file.
+func (m *mgr) collect() []metrics.Sample {
+	m.Lock()
+	defer m.Unlock()
+	return []metrics.Sample{{
+		Name:   "share_manager_shares",
+		Help:   "The shares held by the share managers",
+		Labels: map[string]string{"manager": "json"},
+		Value:  float64(len(m.model.Shares)),
+	}}
 }
 
 func loadOrCreate(file string) (*shareModel, error) {
@@ -95,7 +112,7 @@ func loadOrCreate(file string) (*shareModel, error) {
 		return nil, err
 	}
 
-	m := &shareModel{State: j.State}
+	m := &shareModel{State: j.State, Expirations: j.Expirations}
 	for _, s := range j.Shares {
 		var decShare collaboration.Share
 		if err = utils.UnmarshalJSONToProtoV1([]byte(s), &decShare); err != nil {
@@ -107,24 +124,29 @@ func loadOrCreate(file string) (*shareModel, error) {
 	if m.State == nil {
 		m.State = map[string]map[string]collaboration.ShareState{}
 	}
+	if m.Expirations == nil {
+		m.Expirations = map[string]uint64{}
+	}
 
 	m.file = file
 	return m, nil
 }
 
 type shareModel struct {
-	file   string
-	State  map[string]map[string]collaboration.ShareState `json:"state"` // map[username]map[share_id]ShareState
-	Shares []*collaboration.Share                         `json:"shares"`
+	file        string
+	State       map[string]map[string]collaboration.ShareState `json:"state"` // map[username]map[share_id]ShareState
+	Shares      []*collaboration.Share                         `json:"shares"`
+	Expirations map[string]uint64                              `json:"expirations"` // map[share_id]seconds since the epoch
 }
 
 type jsonEncoding struct {
-	State  map[string]map[string]collaboration.ShareState `json:"state"` // map[username]map[share_id]ShareState
-	Shares []string                                       `json:"shares"`
+	State       map[string]map[string]collaboration.ShareState `json:"state"` // map[username]map[share_id]ShareState
+	Shares      []string                                       `json:"shares"`
+	Expirations map[string]uint64                              `json:"expirations,omitempty"` // map[share_id]seconds since the epoch
 }
 
 func (m *shareModel) Save() error {
-	j := &jsonEncoding{State: m.State}
+	j := &jsonEncoding{State: m.State, Expirations: m.Expirations}
 	for _, s := range m.Shares {
 		encShare, err := utils.MarshalProtoV1ToJSON(s)
 		if err != nil {
@@ -272,7 +294,13 @@ func (m *mgr) get(ctx context.Context, ref *collaboration.ShareReference) (s *co
 		return s, nil
 	}
 
-	// or the grantee
+	// or the grantee of an unexpired share
+	m.Lock()
+	expired := m.expired(s, time.Now())
+	m.Unlock()
+	if expired {
+		return nil, errtypes.NotFound(ref.String())
+	}
 	if s.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, s.Grantee.GetUserId()) {
 		return s, nil
 	} else if s.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
@@ -305,6 +333,7 @@ func (m *mgr) Unshare(ctx context.Context, ref *collaboration.ShareReference) er
 			if utils.UserEqual(user.Id, s.Owner) || utils.UserEqual(user.Id, s.Creator) {
 				m.model.Shares[len(m.model.Shares)-1], m.model.Shares[i] = m.model.Shares[i], m.model.Shares[len(m.model.Shares)-1]
 				m.model.Shares = m.model.Shares[:len(m.model.Shares)-1]
+				delete(m.model.Expirations, s.Id.OpaqueId)
 				if err := m.model.Save(); err != nil {
 					err = errors.Wrap(err, "error saving model")
 					return err
@@ -386,11 +415,15 @@ func (m *mgr) ListReceivedShares(ctx context.Context) ([]*collaboration.Received
 	m.Lock()
 	defer m.Unlock()
 	user := user.ContextMustGetUser(ctx)
+	now := time.Now()
 	for _, s := range m.model.Shares {
 		if utils.UserEqual(user.Id, s.Owner) || utils.UserEqual(user.Id, s.Creator) {
 			// omit shares created by me
 			continue
 		}
+		if m.expired(s, now) {
+			continue
+		}
 		if s.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, s.Grantee.GetUserId()) {
 			rs := m.convert(ctx, s)
 			rss = append(rss, rs)
@@ -430,8 +463,9 @@ func (m *mgr) getReceived(ctx context.Context, ref *collaboration.ShareReference
 	m.Lock()
 	defer m.Unlock()
 	user := user.ContextMustGetUser(ctx)
+	now := time.Now()
 	for _, s := range m.model.Shares {
-		if sharesEqual(ref, s) {
+		if sharesEqual(ref, s) && !m.expired(s, now) {
 			if s.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, s.Grantee.GetUserId()) {
 				rs := m.convert(ctx, s)
 				return rs, nil
@@ -453,6 +487,9 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareR
 	if err != nil {
 		return nil, err
 	}
+	if err := share.CheckStateTransition(rs.State, f.GetState()); err != nil {
+		return nil, err
+	}
 
 	user := user.ContextMustGetUser(ctx)
 	m.Lock()
@@ -476,3 +513,163 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareR
 	rs.State = f.GetState()
 	return rs, nil
 }
+
+// Dump returns all the shares held by the manager together with the states
+// the grantees have set on them.
+func (m *mgr) Dump(ctx context.Context) ([]*collaboration.Share, []*share.ReceivedShareState, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	shares := make([]*collaboration.Share, 0, len(m.model.Shares))
+	for _, s := range m.model.Shares {
+		shares = append(shares, proto.Clone(s).(*collaboration.Share))
+	}
+
+	states := []*share.ReceivedShareState{}
+	for u, v := range m.model.State {
+		uid := &userpb.UserId{}
+		if err := proto.UnmarshalText(u, uid); err != nil {
+			return nil, nil, errors.Wrap(err, "json: error decoding user id "+u)
+		}
+		for s, state := range v {
+			sid := &collaboration.ShareId{}
+			if err := proto.UnmarshalText(s, sid); err != nil {
+				return nil, nil, errors.Wrap(err, "json: error decoding share id "+s)
+			}
+			states = append(states, &share.ReceivedShareState{UserID: uid, ShareID: sid, State: state})
+		}
+	}
+
+	return shares, states, nil
+}
+
+// Load adds the given shares and received share states to the manager.
+// Nothing is stored if any of the shares clashes with an existing one.
+func (m *mgr) Load(ctx context.Context, shares []*collaboration.Share, states []*share.ReceivedShareState) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, s := range shares {
+		for _, e := range m.model.Shares {
+			if e.GetId().GetOpaqueId() == s.GetId().GetOpaqueId() {
+				return errtypes.AlreadyExists(s.GetId().GetOpaqueId())
+			}
+			if utils.UserEqual(e.Owner, s.Owner) && utils.ResourceEqual(e.ResourceId, s.ResourceId) && utils.GranteeEqual(e.Grantee, s.Grantee) {
+				return errtypes.AlreadyExists(s.GetId().GetOpaqueId())
+			}
+		}
+	}
+
+	m.model.Shares = append(m.model.Shares, shares...)
+	for _, rs := range states {
+		key := rs.UserID.String()
+		if _, ok := m.model.State[key]; !ok {
+			m.model.State[key] = map[string]collaboration.ShareState{}
+		}
+		m.model.State[key][rs.ShareID.String()] = rs.State
+	}
+
+	if err := m.model.Save(); err != nil {
+		return errors.Wrap(err, "error saving model")
+	}
+	return nil
+}
+
+// RenameUser replaces the id of a user in the owner, creator and grantee of
+// the shares and in the received share states.
+func (m *mgr) RenameUser(ctx context.Context, oldID, newID *userpb.UserId) error {
+	m.Lock()
+	defer m.Unlock()
+
+	shares := make([]*collaboration.Share, 0, len(m.model.Shares))
+	for _, s := range m.model.Shares {
+		s = proto.Clone(s).(*collaboration.Share)
+		if utils.UserEqual(s.Owner, oldID) {
+			s.Owner = newID
+		}
+		if utils.UserEqual(s.Creator, oldID) {
+			s.Creator = newID
+		}
+		if g, ok := s.GetGrantee().GetId().(*provider.Grantee_UserId); ok && utils.UserEqual(g.UserId, oldID) {
+			g.UserId = newID
+		}
+		shares = append(shares, s)
+	}
+
+	state := make(map[string]map[string]collaboration.ShareState, len(m.model.State))
+	for k, v := range m.model.State {
+		state[k] = v
+	}
+	if v, ok := state[oldID.String()]; ok {
+		delete(state, oldID.String())
+		state[newID.String()] = v
+	}
+
+	oldShares, oldState := m.model.Shares, m.model.State
+	m.model.Shares, m.model.State = shares, state
+	if err := m.model.Save(); err != nil {
+		m.model.Shares, m.model.State = oldShares, oldState
+		return errors.Wrap(err, "error saving model")
+	}
+	return nil
+}
+
+// expired must be called in a lock-controlled block.
+func (m *mgr) expired(s *collaboration.Share, t time.Time) bool {
+	secs, ok := m.model.Expirations[s.Id.OpaqueId]
+	return ok && share.Expired(&typespb.Timestamp{Seconds: secs}, t)
+}
+
+// SetExpiration sets the expiration of a share owned or created by the user in context.
+func (m *mgr) SetExpiration(ctx context.Context, ref *collaboration.ShareReference, exp *typespb.Timestamp) error {
+	m.Lock()
+	defer m.Unlock()
+	user := user.ContextMustGetUser(ctx)
+	for _, s := range m.model.Shares {
+		if !sharesEqual(ref, s) || !(utils.UserEqual(user.Id, s.Owner) || utils.UserEqual(user.Id, s.Creator)) {
+			continue
+		}
+		old, had := m.model.Expirations[s.Id.OpaqueId]
+		if exp == nil {
+			delete(m.model.Expirations, s.Id.OpaqueId)
+		} else {
+			m.model.Expirations[s.Id.OpaqueId] = exp.Seconds
+		}
+		if err := m.model.Save(); err != nil {
+			if had {
+				m.model.Expirations[s.Id.OpaqueId] = old
+			} else {
+				delete(m.model.Expirations, s.Id.OpaqueId)
+			}
+			return errors.Wrap(err, "error saving model")
+		}
+		return nil
+	}
+	return errtypes.NotFound(ref.String())
+}
+
+// GetExpirations returns the expirations of the given shares.
+func (m *mgr) GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error) {
+	m.Lock()
+	defer m.Unlock()
+	exps := map[string]*typespb.Timestamp{}
+	for _, id := range ids {
+		if secs, ok := m.model.Expirations[id.GetOpaqueId()]; ok {
+			exps[id.GetOpaqueId()] = &typespb.Timestamp{Seconds: secs}
+		}
+	}
+	return exps, nil
+}
+
+// ListExpiredShares returns the shares expired at t.
+func (m *mgr) ListExpiredShares(ctx context.Context, t time.Time) ([]*collaboration.Share, error) {
+	m.Lock()
+	defer m.Unlock()
+	var ss []*collaboration.Share
+	for _, s := range m.model.Shares {
+		if m.expired(s, t) {
+			ss = append(ss, proto.Clone(s).(*collaboration.Share))
+		}
+	}
+	return ss, nil
+}
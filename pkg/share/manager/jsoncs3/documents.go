@@ -0,0 +1,165 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package jsoncs3
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"sort"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// The documents are laid out under the root as follows:
+//
+//   spaces/<storage id>.json                 the shares of the resources of a storage space
+//   users/<user>/created.json                the spaces the user owns or created shares in
+//   users/<user>/received.json               the spaces holding shares granted to the user
+//   groups/<group>/received.json             the spaces holding shares granted to the group
+//
+// so that listing the shares of a user only reads the spaces involved.
+
+func spacesDir(root string) string {
+	return path.Join(root, "spaces")
+}
+
+func spacePath(root, storageID string) string {
+	return path.Join(spacesDir(root), url.PathEscape(storageID)+".json")
+}
+
+func createdPath(root string, u *userpb.UserId) string {
+	return path.Join(root, "users", url.PathEscape(userKey(u)), "created.json")
+}
+
+func userReceivedPath(root string, u *userpb.UserId) string {
+	return path.Join(root, "users", url.PathEscape(userKey(u)), "received.json")
+}
+
+func groupReceivedPath(root, group string) string {
+	return path.Join(root, "groups", url.PathEscape(group), "received.json")
+}
+
+func userKey(u *userpb.UserId) string {
+	if u.GetIdp() == "" {
+		return u.GetOpaqueId()
+	}
+	return u.GetOpaqueId() + "@" + u.GetIdp()
+}
+
+// docValue is the decoded content of a document.
+type docValue interface {
+	decode(data []byte) error
+	encode() ([]byte, error)
+}
+
+// space holds the shares of the resources of a storage space along with the
+// states the grantees set on them and their expirations.
+type space struct {
+	Shares      map[string]*collaboration.Share
+	States      map[string]map[string]collaboration.ShareState // map[share_id]map[user]ShareState
+	Expirations map[string]uint64                              // map[share_id]seconds since the epoch
+}
+
+type spaceEncoding struct {
+	Shares      map[string]string                              `json:"shares"`
+	States      map[string]map[string]collaboration.ShareState `json:"states"`
+	Expirations map[string]uint64                              `json:"expirations,omitempty"`
+}
+
+func newSpace() docValue {
+	return &space{
+		Shares:      map[string]*collaboration.Share{},
+		States:      map[string]map[string]collaboration.ShareState{},
+		Expirations: map[string]uint64{},
+	}
+}
+
+func (s *space) decode(data []byte) error {
+	j := &spaceEncoding{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return errors.Wrap(err, "error decoding data from json")
+	}
+	for id, enc := range j.Shares {
+		sh := &collaboration.Share{}
+		if err := utils.UnmarshalJSONToProtoV1([]byte(enc), sh); err != nil {
+			return errors.Wrap(err, "error decoding share from json")
+		}
+		s.Shares[id] = sh
+	}
+	if j.States != nil {
+		s.States = j.States
+	}
+	if j.Expirations != nil {
+		s.Expirations = j.Expirations
+	}
+	return nil
+}
+
+func (s *space) encode() ([]byte, error) {
+	j := &spaceEncoding{
+		Shares:      make(map[string]string, len(s.Shares)),
+		States:      s.States,
+		Expirations: s.Expirations,
+	}
+	for id, sh := range s.Shares {
+		enc, err := utils.MarshalProtoV1ToJSON(sh)
+		if err != nil {
+			return nil, errors.Wrap(err, "error encoding to json")
+		}
+		j.Shares[id] = string(enc)
+	}
+	return json.Marshal(j)
+}
+
+// index holds the ids of the spaces relevant to a user or group.
+type index struct {
+	Spaces map[string]struct{}
+}
+
+type indexEncoding struct {
+	Spaces []string `json:"spaces"`
+}
+
+func newIndex() docValue {
+	return &index{Spaces: map[string]struct{}{}}
+}
+
+func (i *index) decode(data []byte) error {
+	j := &indexEncoding{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return errors.Wrap(err, "error decoding data from json")
+	}
+	for _, id := range j.Spaces {
+		i.Spaces[id] = struct{}{}
+	}
+	return nil
+}
+
+func (i *index) encode() ([]byte, error) {
+	j := &indexEncoding{Spaces: make([]string, 0, len(i.Spaces))}
+	for id := range i.Spaces {
+		j.Spaces = append(j.Spaces, id)
+	}
+	sort.Strings(j.Spaces)
+	return json.Marshal(j)
+}
@@ -0,0 +1,868 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package jsoncs3 provides a share manager storing the shares as JSON documents
+// on a storage provider, one per storage space, accessed through the CS3 storage
+// API itself.
+package jsoncs3
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/share/manager/registry"
+	"github.com/cs3org/reva/pkg/token"
+	tokenregistry "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// maxAttempts is the number of times an update is computed again on a fresh
+// copy of a document that was changed concurrently.
+const maxAttempts = 5
+
+func init() {
+	registry.Register("jsoncs3", New)
+}
+
+type config struct {
+	ProviderAddr   string                            `mapstructure:"provider_addr"`
+	Root           string                            `mapstructure:"root"`
+	ServiceUserID  string                            `mapstructure:"service_user_id"`
+	ServiceUserIdp string                            `mapstructure:"service_user_idp"`
+	TokenManager   string                            `mapstructure:"token_manager"`
+	TokenManagers  map[string]map[string]interface{} `mapstructure:"token_managers"`
+	Insecure       bool                              `mapstructure:"insecure"`
+}
+
+func (c *config) init() {
+	if c.ProviderAddr == "" {
+		c.ProviderAddr = "localhost:19000"
+	}
+	if c.Root == "" {
+		c.Root = "/shares"
+	}
+	if c.ServiceUserID == "" {
+		c.ServiceUserID = "jsoncs3-share-manager"
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns a share manager storing the shares on a storage provider.
+func New(m map[string]interface{}) (share.Manager, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		err = errors.Wrap(err, "error creating a new manager")
+		return nil, err
+	}
+	c.init()
+
+	client, err := pool.GetStorageProviderServiceClient(c.ProviderAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error getting the storage provider client")
+	}
+
+	tf, ok := tokenregistry.NewFuncs[c.TokenManager]
+	if !ok {
+		return nil, errtypes.NotFound("driver not found for token manager: " + c.TokenManager)
+	}
+	tokenmgr, err := tf(c.TokenManagers[c.TokenManager])
+	if err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error creating the token manager")
+	}
+
+	storage := &metadataStorage{
+		client:     client,
+		httpClient: rhttp.GetHTTPClient(rhttp.Insecure(c.Insecure)),
+	}
+	return newManager(c, storage, tokenmgr), nil
+}
+
+func newManager(c *config, storage *metadataStorage, tokenmgr token.Manager) *mgr {
+	return &mgr{
+		c:        c,
+		storage:  storage,
+		tokenmgr: tokenmgr,
+		serviceUser: &userpb.User{
+			Id:       &userpb.UserId{OpaqueId: c.ServiceUserID, Idp: c.ServiceUserIdp},
+			Username: c.ServiceUserID,
+		},
+		docs: map[string]*document{},
+	}
+}
+
+type mgr struct {
+	c           *config
+	storage     *metadataStorage
+	tokenmgr    token.Manager
+	serviceUser *userpb.User
+
+	sync.Mutex // concurrent access to the documents
+	docs       map[string]*document
+}
+
+// document is the cached copy of a stored document.
+type document struct {
+	sync.Mutex // serializes the reads and updates of the document
+	path       string
+	newValue   func() docValue
+	value      docValue
+	etag       string // etag of the stored copy the value was decoded from, empty if there is none
+	loaded     bool
+}
+
+func (m *mgr) doc(p string, newValue func() docValue) *document {
+	m.Lock()
+	defer m.Unlock()
+	d, ok := m.docs[p]
+	if !ok {
+		d = &document{path: p, newValue: newValue}
+		m.docs[p] = d
+	}
+	return d
+}
+
+// refresh makes sure the cached copy matches the stored one, which is only
+// downloaded when its etag changed. It must be called with the document locked.
+func (m *mgr) refresh(ctx context.Context, d *document) error {
+	etag, err := m.storage.stat(ctx, d.path)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); !ok {
+			return err
+		}
+	}
+	if d.loaded && etag == d.etag {
+		return nil
+	}
+
+	v := d.newValue()
+	if etag != "" {
+		data, err := m.storage.download(ctx, d.path)
+		switch err.(type) {
+		case nil:
+			if err := v.decode(data); err != nil {
+				return errors.Wrap(err, "jsoncs3: error decoding "+d.path)
+			}
+		case errtypes.IsNotFound:
+			// removed meanwhile
+			etag = ""
+		default:
+			return err
+		}
+	}
+	d.value, d.etag, d.loaded = v, etag, true
+	return nil
+}
+
+// view calls fn with the up to date content of the document.
+func (m *mgr) view(ctx context.Context, p string, newValue func() docValue, fn func(docValue) error) error {
+	d := m.doc(p, newValue)
+	d.Lock()
+	defer d.Unlock()
+	if err := m.refresh(ctx, d); err != nil {
+		return err
+	}
+	return fn(d.value)
+}
+
+// update applies fn to the up to date content of the document and stores the
+// result if fn reports a change. fn must leave the content untouched when it
+// fails. If the document was changed concurrently, fn is applied again to the
+// new content.
+func (m *mgr) update(ctx context.Context, p string, newValue func() docValue, fn func(docValue) (bool, error)) error {
+	d := m.doc(p, newValue)
+	for i := 0; i < maxAttempts; i++ {
+		if err := m.tryUpdate(ctx, d, fn); err != errConflict {
+			return err
+		}
+	}
+	return errtypes.TooManyRequests("jsoncs3: too many concurrent updates of " + p)
+}
+
+func (m *mgr) tryUpdate(ctx context.Context, d *document, fn func(docValue) (bool, error)) error {
+	d.Lock()
+	defer d.Unlock()
+	if err := m.refresh(ctx, d); err != nil {
+		return err
+	}
+	changed, err := fn(d.value)
+	if err != nil || !changed {
+		return err
+	}
+
+	// the cached copy is now ahead of the stored one, and the etag of the
+	// uploaded copy is only known by reading it back
+	d.loaded = false
+	data, err := d.value.encode()
+	if err != nil {
+		return errors.Wrap(err, "jsoncs3: error encoding "+d.path)
+	}
+	return m.storage.upload(ctx, d.path, data, d.etag)
+}
+
+// serviceContext returns a context authenticated as the user the documents
+// are accessed as.
+func (m *mgr) serviceContext(ctx context.Context) (context.Context, error) {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return nil, err
+	}
+	tkn, err := m.tokenmgr.MintToken(token.ContextSetService(ctx, "jsoncs3"), m.serviceUser, ownerScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error minting the service user token")
+	}
+	ctx = token.ContextSetToken(ctx, tkn)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(token.TokenHeader, tkn)
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+func (m *mgr) addToIndex(ctx context.Context, p, spaceID string) error {
+	return m.update(ctx, p, newIndex, func(v docValue) (bool, error) {
+		i := v.(*index)
+		if _, ok := i.Spaces[spaceID]; ok {
+			return false, nil
+		}
+		i.Spaces[spaceID] = struct{}{}
+		return true, nil
+	})
+}
+
+func (m *mgr) removeFromIndex(ctx context.Context, p, spaceID string) error {
+	return m.update(ctx, p, newIndex, func(v docValue) (bool, error) {
+		i := v.(*index)
+		if _, ok := i.Spaces[spaceID]; !ok {
+			return false, nil
+		}
+		delete(i.Spaces, spaceID)
+		return true, nil
+	})
+}
+
+// userSpaces returns the ids of the spaces holding the shares the user owns or
+// created and the ones holding the shares granted to the user or its groups.
+func (m *mgr) userSpaces(ctx context.Context, u *userpb.User, created, received bool) ([]string, error) {
+	var paths []string
+	if created {
+		paths = append(paths, createdPath(m.c.Root, u.Id))
+	}
+	if received {
+		paths = append(paths, userReceivedPath(m.c.Root, u.Id))
+		for _, g := range u.Groups {
+			paths = append(paths, groupReceivedPath(m.c.Root, g))
+		}
+	}
+
+	seen := map[string]struct{}{}
+	var spaces []string
+	for _, p := range paths {
+		err := m.view(ctx, p, newIndex, func(v docValue) error {
+			for id := range v.(*index).Spaces {
+				if _, ok := seen[id]; !ok {
+					seen[id] = struct{}{}
+					spaces = append(spaces, id)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spaces, nil
+}
+
+func (m *mgr) granteePath(g *provider.Grantee) string {
+	if g.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
+		return groupReceivedPath(m.c.Root, g.GetGroupId().GetOpaqueId())
+	}
+	return userReceivedPath(m.c.Root, g.GetUserId())
+}
+
+func isCreator(u *userpb.User, s *collaboration.Share) bool {
+	return utils.UserEqual(u.Id, s.Owner) || utils.UserEqual(u.Id, s.Creator)
+}
+
+func isGrantee(u *userpb.User, s *collaboration.Share) bool {
+	switch s.Grantee.Type {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		return utils.UserEqual(u.Id, s.Grantee.GetUserId())
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		for _, g := range u.Groups {
+			if g == s.Grantee.GetGroupId().GetOpaqueId() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sharesEqual(ref *collaboration.ShareReference, s *collaboration.Share) bool {
+	if ref.GetId() != nil && s.Id != nil {
+		if ref.GetId().OpaqueId == s.Id.OpaqueId {
+			return true
+		}
+	} else if ref.GetKey() != nil {
+		if (utils.UserEqual(ref.GetKey().Owner, s.Owner) || utils.UserEqual(ref.GetKey().Owner, s.Creator)) &&
+			utils.ResourceEqual(ref.GetKey().ResourceId, s.ResourceId) && utils.GranteeEqual(ref.GetKey().Grantee, s.Grantee) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *space) expired(id string, t time.Time) bool {
+	secs, ok := s.Expirations[id]
+	return ok && share.Expired(&typespb.Timestamp{Seconds: secs}, t)
+}
+
+func (s *space) find(ref *collaboration.ShareReference) *collaboration.Share {
+	if id := ref.GetId(); id != nil {
+		return s.Shares[id.OpaqueId]
+	}
+	for _, sh := range s.Shares {
+		if sharesEqual(ref, sh) {
+			return sh
+		}
+	}
+	return nil
+}
+
+// entry is a share found in a space, as seen by a user.
+type entry struct {
+	space   string
+	share   *collaboration.Share
+	state   collaboration.ShareState
+	expired bool
+}
+
+// lookup finds the share among the spaces relevant to the user.
+func (m *mgr) lookup(ctx context.Context, u *userpb.User, ref *collaboration.ShareReference) (*entry, error) {
+	var spaces []string
+	switch {
+	case ref.GetKey() != nil:
+		spaces = []string{ref.GetKey().GetResourceId().GetStorageId()}
+	case ref.GetId() != nil:
+		var err error
+		if spaces, err = m.userSpaces(ctx, u, true, true); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errtypes.NotFound(ref.String())
+	}
+
+	t := time.Now()
+	for _, id := range spaces {
+		var e *entry
+		err := m.view(ctx, spacePath(m.c.Root, id), newSpace, func(v docValue) error {
+			sp := v.(*space)
+			if sh := sp.find(ref); sh != nil {
+				e = &entry{
+					space:   id,
+					share:   proto.Clone(sh).(*collaboration.Share),
+					state:   collaboration.ShareState_SHARE_STATE_PENDING,
+					expired: sp.expired(sh.Id.OpaqueId, t),
+				}
+				if state, ok := sp.States[sh.Id.OpaqueId][userKey(u.Id)]; ok {
+					e.state = state
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			return e, nil
+		}
+	}
+	return nil, errtypes.NotFound(ref.String())
+}
+
+func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant) (*collaboration.Share, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// do not allow share to myself or the owner if share is for a user
+	if g.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER &&
+		(utils.UserEqual(g.Grantee.GetUserId(), user.Id) || utils.UserEqual(g.Grantee.GetUserId(), md.Owner)) {
+		return nil, errors.New("jsoncs3: owner/creator and grantee are the same")
+	}
+
+	ts := now()
+	s := &collaboration.Share{
+		Id: &collaboration.ShareId{
+			OpaqueId: uuid.New().String(),
+		},
+		ResourceId:  md.Id,
+		Permissions: g.Permissions,
+		Grantee:     g.Grantee,
+		Owner:       md.Owner,
+		Creator:     user.Id,
+		Ctime:       ts,
+		Mtime:       ts,
+	}
+	spaceID := md.Id.GetStorageId()
+
+	// the indexes are updated first, a space listed in an index without
+	// holding any share of the user costs a read but hides nothing
+	if err := m.addToIndex(ctx, createdPath(m.c.Root, user.Id), spaceID); err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error indexing the share")
+	}
+	if !utils.UserEqual(md.Owner, user.Id) {
+		if err := m.addToIndex(ctx, createdPath(m.c.Root, md.Owner), spaceID); err != nil {
+			return nil, errors.Wrap(err, "jsoncs3: error indexing the share")
+		}
+	}
+	if err := m.addToIndex(ctx, m.granteePath(g.Grantee), spaceID); err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error indexing the share")
+	}
+
+	key := &collaboration.ShareReference{Spec: &collaboration.ShareReference_Key{Key: &collaboration.ShareKey{
+		Owner:      md.Owner,
+		ResourceId: md.Id,
+		Grantee:    g.Grantee,
+	}}}
+	err = m.update(ctx, spacePath(m.c.Root, spaceID), newSpace, func(v docValue) (bool, error) {
+		sp := v.(*space)
+		if sp.find(key) != nil {
+			return false, errtypes.AlreadyExists(key.GetKey().String())
+		}
+		sp.Shares[s.Id.OpaqueId] = s
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proto.Clone(s).(*collaboration.Share), nil
+}
+
+func (m *mgr) GetShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.Share, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := m.lookup(ctx, user, ref)
+	if err != nil {
+		return nil, err
+	}
+	if isCreator(user, e.share) || (!e.expired && isGrantee(user, e.share)) {
+		return e.share, nil
+	}
+	// we return not found to not disclose information
+	return nil, errtypes.NotFound(ref.String())
+}
+
+func (m *mgr) Unshare(ctx context.Context, ref *collaboration.ShareReference) error {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	e, err := m.lookup(ctx, user, ref)
+	if err != nil {
+		return err
+	}
+	if !isCreator(user, e.share) {
+		return errtypes.NotFound(ref.String())
+	}
+
+	var creatorLeft, ownerLeft, granteeLeft bool
+	err = m.update(ctx, spacePath(m.c.Root, e.space), newSpace, func(v docValue) (bool, error) {
+		sp := v.(*space)
+		id := e.share.Id.OpaqueId
+		if _, ok := sp.Shares[id]; !ok {
+			return false, errtypes.NotFound(ref.String())
+		}
+		delete(sp.Shares, id)
+		delete(sp.States, id)
+		delete(sp.Expirations, id)
+
+		creatorLeft, ownerLeft, granteeLeft = false, false, false
+		for _, sh := range sp.Shares {
+			creatorLeft = creatorLeft || utils.UserEqual(sh.Owner, e.share.Creator) || utils.UserEqual(sh.Creator, e.share.Creator)
+			ownerLeft = ownerLeft || utils.UserEqual(sh.Owner, e.share.Owner) || utils.UserEqual(sh.Creator, e.share.Owner)
+			granteeLeft = granteeLeft || utils.GranteeEqual(sh.Grantee, e.share.Grantee)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// stale index entries are harmless, failing to remove them is only logged
+	log := appctx.GetLogger(ctx)
+	var paths []string
+	if !creatorLeft {
+		paths = append(paths, createdPath(m.c.Root, e.share.Creator))
+	}
+	if !ownerLeft && !utils.UserEqual(e.share.Owner, e.share.Creator) {
+		paths = append(paths, createdPath(m.c.Root, e.share.Owner))
+	}
+	if !granteeLeft {
+		paths = append(paths, m.granteePath(e.share.Grantee))
+	}
+	for _, p := range paths {
+		if err := m.removeFromIndex(ctx, p, e.space); err != nil {
+			log.Warn().Err(err).Str("index", p).Str("space", e.space).Msg("jsoncs3: error removing space from index")
+		}
+	}
+	return nil
+}
+
+func (m *mgr) UpdateShare(ctx context.Context, ref *collaboration.ShareReference, p *collaboration.SharePermissions) (*collaboration.Share, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := m.lookup(ctx, user, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !isCreator(user, e.share) {
+		return nil, errtypes.NotFound(ref.String())
+	}
+
+	var updated *collaboration.Share
+	err = m.update(ctx, spacePath(m.c.Root, e.space), newSpace, func(v docValue) (bool, error) {
+		sh, ok := v.(*space).Shares[e.share.Id.OpaqueId]
+		if !ok {
+			return false, errtypes.NotFound(ref.String())
+		}
+		sh.Permissions = p
+		sh.Mtime = now()
+		updated = proto.Clone(sh).(*collaboration.Share)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (m *mgr) ListShares(ctx context.Context, filters []*collaboration.ListSharesRequest_Filter) ([]*collaboration.Share, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the spaces of the filtered resources need to be read
+	var spaces []string
+	if len(filters) == 0 {
+		if spaces, err = m.userSpaces(ctx, user, true, false); err != nil {
+			return nil, err
+		}
+	} else {
+		seen := map[string]struct{}{}
+		for _, f := range filters {
+			if f.Type != collaboration.ListSharesRequest_Filter_TYPE_RESOURCE_ID {
+				continue
+			}
+			id := f.GetResourceId().GetStorageId()
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				spaces = append(spaces, id)
+			}
+		}
+	}
+
+	var ss []*collaboration.Share
+	for _, id := range spaces {
+		err := m.view(ctx, spacePath(m.c.Root, id), newSpace, func(v docValue) error {
+			for _, s := range v.(*space).Shares {
+				if !isCreator(user, s) {
+					continue
+				}
+				if len(filters) == 0 {
+					ss = append(ss, proto.Clone(s).(*collaboration.Share))
+					continue
+				}
+				// TODO(labkode): add the rest of filters.
+				for _, f := range filters {
+					if f.Type == collaboration.ListSharesRequest_Filter_TYPE_RESOURCE_ID && utils.ResourceEqual(s.ResourceId, f.GetResourceId()) {
+						ss = append(ss, proto.Clone(s).(*collaboration.Share))
+						break
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(ss, func(i, j int) bool { return created(ss[i], ss[j]) })
+	return ss, nil
+}
+
+func now() *typespb.Timestamp {
+	t := time.Now().UnixNano()
+	return &typespb.Timestamp{
+		Seconds: uint64(t / 1000000000),
+		Nanos:   uint32(t % 1000000000),
+	}
+}
+
+// created orders the shares by creation time.
+func created(a, b *collaboration.Share) bool {
+	if a.Ctime.GetSeconds() != b.Ctime.GetSeconds() {
+		return a.Ctime.GetSeconds() < b.Ctime.GetSeconds()
+	}
+	if a.Ctime.GetNanos() != b.Ctime.GetNanos() {
+		return a.Ctime.GetNanos() < b.Ctime.GetNanos()
+	}
+	return a.Id.GetOpaqueId() < b.Id.GetOpaqueId()
+}
+
+// we list the shares that are targeted to the user in context or to the user groups.
+func (m *mgr) ListReceivedShares(ctx context.Context) ([]*collaboration.ReceivedShare, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spaces, err := m.userSpaces(ctx, user, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var rss []*collaboration.ReceivedShare
+	t := time.Now()
+	for _, id := range spaces {
+		err := m.view(ctx, spacePath(m.c.Root, id), newSpace, func(v docValue) error {
+			sp := v.(*space)
+			for _, s := range sp.Shares {
+				// omit shares created by me
+				if isCreator(user, s) || !isGrantee(user, s) || sp.expired(s.Id.OpaqueId, t) {
+					continue
+				}
+				rs := &collaboration.ReceivedShare{
+					Share: proto.Clone(s).(*collaboration.Share),
+					State: collaboration.ShareState_SHARE_STATE_PENDING,
+				}
+				if state, ok := sp.States[s.Id.OpaqueId][userKey(user.Id)]; ok {
+					rs.State = state
+				}
+				rss = append(rss, rs)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(rss, func(i, j int) bool { return created(rss[i].Share, rss[j].Share) })
+	return rss, nil
+}
+
+func (m *mgr) getReceived(ctx context.Context, u *userpb.User, ref *collaboration.ShareReference) (*entry, error) {
+	e, err := m.lookup(ctx, u, ref)
+	if err != nil {
+		return nil, err
+	}
+	if e.expired || !isGrantee(u, e.share) {
+		return nil, errtypes.NotFound(ref.String())
+	}
+	return e, nil
+}
+
+func (m *mgr) GetReceivedShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.ReceivedShare, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := m.getReceived(ctx, user, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &collaboration.ReceivedShare{Share: e.share, State: e.state}, nil
+}
+
+func (m *mgr) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareReference, f *collaboration.UpdateReceivedShareRequest_UpdateField) (*collaboration.ReceivedShare, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := m.getReceived(ctx, user, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := share.CheckStateTransition(e.state, f.GetState()); err != nil {
+		return nil, err
+	}
+
+	err = m.update(ctx, spacePath(m.c.Root, e.space), newSpace, func(v docValue) (bool, error) {
+		sp := v.(*space)
+		id := e.share.Id.OpaqueId
+		if _, ok := sp.Shares[id]; !ok {
+			return false, errtypes.NotFound(ref.String())
+		}
+		if sp.States[id] == nil {
+			sp.States[id] = map[string]collaboration.ShareState{}
+		}
+		sp.States[id][userKey(user.Id)] = f.GetState()
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &collaboration.ReceivedShare{Share: e.share, State: f.GetState()}, nil
+}
+
+// SetExpiration sets the expiration of a share owned or created by the user in context.
+func (m *mgr) SetExpiration(ctx context.Context, ref *collaboration.ShareReference, exp *typespb.Timestamp) error {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	e, err := m.lookup(ctx, user, ref)
+	if err != nil {
+		return err
+	}
+	if !isCreator(user, e.share) {
+		return errtypes.NotFound(ref.String())
+	}
+
+	return m.update(ctx, spacePath(m.c.Root, e.space), newSpace, func(v docValue) (bool, error) {
+		sp := v.(*space)
+		id := e.share.Id.OpaqueId
+		if _, ok := sp.Shares[id]; !ok {
+			return false, errtypes.NotFound(ref.String())
+		}
+		old, had := sp.Expirations[id]
+		if exp == nil {
+			delete(sp.Expirations, id)
+			return had, nil
+		}
+		sp.Expirations[id] = exp.Seconds
+		return !had || old != exp.Seconds, nil
+	})
+}
+
+// GetExpirations returns the expirations of the given shares.
+func (m *mgr) GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error) {
+	user := user.ContextMustGetUser(ctx)
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exps := map[string]*typespb.Timestamp{}
+	if len(ids) == 0 {
+		return exps, nil
+	}
+	spaces, err := m.userSpaces(ctx, user, true, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range spaces {
+		err := m.view(ctx, spacePath(m.c.Root, id), newSpace, func(v docValue) error {
+			sp := v.(*space)
+			for _, id := range ids {
+				if secs, ok := sp.Expirations[id.GetOpaqueId()]; ok {
+					exps[id.GetOpaqueId()] = &typespb.Timestamp{Seconds: secs}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return exps, nil
+}
+
+// ListExpiredShares returns the shares expired at t, reading all the spaces.
+func (m *mgr) ListExpiredShares(ctx context.Context, t time.Time) ([]*collaboration.Share, error) {
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := m.storage.list(ctx, spacesDir(m.c.Root))
+	if err != nil {
+		return nil, err
+	}
+	var ss []*collaboration.Share
+	for _, name := range names {
+		id, err := url.PathUnescape(strings.TrimSuffix(name, ".json"))
+		if err != nil || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		err = m.view(ctx, spacePath(m.c.Root, id), newSpace, func(v docValue) error {
+			sp := v.(*space)
+			for _, s := range sp.Shares {
+				if sp.expired(s.Id.OpaqueId, t) {
+					ss = append(ss, proto.Clone(s).(*collaboration.Share))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(ss, func(i, j int) bool { return created(ss[i], ss[j]) })
+	return ss, nil
+}
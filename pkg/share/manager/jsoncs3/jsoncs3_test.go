@@ -0,0 +1,374 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package jsoncs3
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"google.golang.org/grpc"
+)
+
+// fakeProvider is an in-memory storage provider serving its data over http.
+type fakeProvider struct {
+	provider.ProviderAPIClient
+	srv *httptest.Server
+
+	sync.Mutex
+	files    map[string][]byte
+	versions map[string]int
+	dirs     map[string]bool
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	p := &fakeProvider{files: map[string][]byte{}, versions: map[string]int{}, dirs: map[string]bool{"/": true}}
+	p.srv = httptest.NewServer(http.HandlerFunc(p.serveData))
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+func (p *fakeProvider) serveData(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(token.TokenHeader) != "token-service" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := p.files[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		p.files[r.URL.EscapedPath()] = data
+		p.versions[r.URL.EscapedPath()]++
+	}
+}
+
+func (p *fakeProvider) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	p.Lock()
+	defer p.Unlock()
+	fn := req.Ref.GetPath()
+	if _, ok := p.files[fn]; ok {
+		return &provider.StatResponse{
+			Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+			Info:   &provider.ResourceInfo{Path: fn, Etag: strconv.Itoa(p.versions[fn])},
+		}, nil
+	}
+	if p.dirs[fn] {
+		return &provider.StatResponse{
+			Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+			Info:   &provider.ResourceInfo{Path: fn, Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER},
+		}, nil
+	}
+	return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+}
+
+func (p *fakeProvider) InitiateFileDownload(ctx context.Context, req *provider.InitiateFileDownloadRequest, opts ...grpc.CallOption) (*provider.InitiateFileDownloadResponse, error) {
+	return &provider.InitiateFileDownloadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*provider.FileDownloadProtocol{{Protocol: "simple", DownloadEndpoint: p.srv.URL + req.Ref.GetPath()}},
+	}, nil
+}
+
+func (p *fakeProvider) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest, opts ...grpc.CallOption) (*provider.InitiateFileUploadResponse, error) {
+	p.Lock()
+	defer p.Unlock()
+	if !p.dirs[path.Dir(req.Ref.GetPath())] {
+		return &provider.InitiateFileUploadResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	return &provider.InitiateFileUploadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*provider.FileUploadProtocol{{Protocol: "simple", UploadEndpoint: p.srv.URL + req.Ref.GetPath()}},
+	}, nil
+}
+
+func (p *fakeProvider) CreateContainer(ctx context.Context, req *provider.CreateContainerRequest, opts ...grpc.CallOption) (*provider.CreateContainerResponse, error) {
+	p.Lock()
+	defer p.Unlock()
+	fn := req.Ref.GetPath()
+	switch {
+	case p.dirs[fn]:
+		return &provider.CreateContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_ALREADY_EXISTS}}, nil
+	case !p.dirs[path.Dir(fn)]:
+		return &provider.CreateContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	p.dirs[fn] = true
+	return &provider.CreateContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil
+}
+
+func (p *fakeProvider) ListContainer(ctx context.Context, req *provider.ListContainerRequest, opts ...grpc.CallOption) (*provider.ListContainerResponse, error) {
+	p.Lock()
+	defer p.Unlock()
+	fn := req.Ref.GetPath()
+	if !p.dirs[fn] {
+		return &provider.ListContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	res := &provider.ListContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}
+	for f := range p.files {
+		if path.Dir(f) == fn {
+			res.Infos = append(res.Infos, &provider.ResourceInfo{Path: f})
+		}
+	}
+	return res, nil
+}
+
+type fakeTokens struct{}
+
+func (fakeTokens) MintToken(ctx context.Context, u *userpb.User, scope map[string]*authpb.Scope) (string, error) {
+	return "token-" + u.Username, nil
+}
+
+func (fakeTokens) DismantleToken(ctx context.Context, tkn string) (*userpb.User, map[string]*authpb.Scope, error) {
+	return nil, nil, errtypes.NotSupported("fake token manager")
+}
+
+func newTestManager(p *fakeProvider) *mgr {
+	c := &config{ServiceUserID: "service"}
+	c.init()
+	return newManager(c, &metadataStorage{client: p, httpClient: http.DefaultClient}, fakeTokens{})
+}
+
+var (
+	alice = &userpb.User{Id: &userpb.UserId{Idp: "https://idp", OpaqueId: "alice"}, Username: "alice"}
+	bob   = &userpb.User{Id: &userpb.UserId{Idp: "https://idp", OpaqueId: "bob"}, Username: "bob", Groups: []string{"physics"}}
+	carol = &userpb.User{Id: &userpb.UserId{Idp: "https://idp", OpaqueId: "carol"}, Username: "carol"}
+)
+
+func resource(storageID, opaqueID string) *provider.ResourceInfo {
+	return &provider.ResourceInfo{
+		Id:    &provider.ResourceId{StorageId: storageID, OpaqueId: opaqueID},
+		Owner: alice.Id,
+	}
+}
+
+func userGrant(u *userpb.User) *collaboration.ShareGrant {
+	return &collaboration.ShareGrant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: u.Id},
+		},
+		Permissions: &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}},
+	}
+}
+
+func groupGrant(g string) *collaboration.ShareGrant {
+	return &collaboration.ShareGrant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_GROUP,
+			Id:   &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: g}},
+		},
+		Permissions: &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}},
+	}
+}
+
+func idRef(s *collaboration.Share) *collaboration.ShareReference {
+	return &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: s.Id}}
+}
+
+func TestShares(t *testing.T) {
+	p := newFakeProvider(t)
+	m := newTestManager(p)
+	actx := ctxuser.ContextSetUser(context.Background(), alice)
+	bctx := ctxuser.ContextSetUser(context.Background(), bob)
+
+	s1, err := m.Share(actx, resource("home", "file"), userGrant(bob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := m.Share(actx, resource("project", "dir"), groupGrant("physics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Share(actx, resource("home", "file"), userGrant(bob)); err == nil {
+		t.Fatal("sharing the same resource twice with the same grantee should fail")
+	}
+
+	all, err := m.ListShares(actx, nil)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("alice should list 2 shares, got %v, %v", all, err)
+	}
+	filtered, err := m.ListShares(actx, []*collaboration.ListSharesRequest_Filter{{
+		Type: collaboration.ListSharesRequest_Filter_TYPE_RESOURCE_ID,
+		Term: &collaboration.ListSharesRequest_Filter_ResourceId{ResourceId: s2.ResourceId},
+	}})
+	if err != nil || len(filtered) != 1 || filtered[0].Id.OpaqueId != s2.Id.OpaqueId {
+		t.Fatalf("alice should list the 1 share of the resource, got %v, %v", filtered, err)
+	}
+
+	received, err := m.ListReceivedShares(bctx)
+	if err != nil || len(received) != 2 {
+		t.Fatalf("bob should receive 2 shares, got %v, %v", received, err)
+	}
+	for _, rs := range received {
+		if rs.State != collaboration.ShareState_SHARE_STATE_PENDING {
+			t.Errorf("share %s should be pending, got %s", rs.Share.Id.OpaqueId, rs.State)
+		}
+	}
+	if _, err := m.GetShare(ctxuser.ContextSetUser(context.Background(), carol), idRef(s1)); err == nil {
+		t.Error("carol should not get a share she neither created nor received")
+	}
+
+	accept := &collaboration.UpdateReceivedShareRequest_UpdateField{
+		Field: &collaboration.UpdateReceivedShareRequest_UpdateField_State{State: collaboration.ShareState_SHARE_STATE_ACCEPTED},
+	}
+	if _, err := m.UpdateReceivedShare(bctx, idRef(s2), accept); err != nil {
+		t.Fatal(err)
+	}
+
+	// a manager with empty caches reads everything back from the storage
+	m = newTestManager(p)
+	rs, err := m.GetReceivedShare(bctx, idRef(s2))
+	if err != nil || rs.State != collaboration.ShareState_SHARE_STATE_ACCEPTED {
+		t.Fatalf("bob should have accepted the group share, got %v, %v", rs, err)
+	}
+
+	if err := m.Unshare(actx, idRef(s1)); err != nil {
+		t.Fatal(err)
+	}
+	received, err = m.ListReceivedShares(bctx)
+	if err != nil || len(received) != 1 || received[0].Share.Id.OpaqueId != s2.Id.OpaqueId {
+		t.Fatalf("bob should only receive the group share, got %v, %v", received, err)
+	}
+	if spaces, _ := m.userSpaces(m.mustServiceContext(t, bctx), bob, false, true); len(spaces) != 1 || spaces[0] != "project" {
+		t.Errorf("bob should only have the project space left, got %v", spaces)
+	}
+}
+
+func TestConcurrentManagers(t *testing.T) {
+	p := newFakeProvider(t)
+	m1, m2 := newTestManager(p), newTestManager(p)
+	actx := ctxuser.ContextSetUser(context.Background(), alice)
+	bctx := ctxuser.ContextSetUser(context.Background(), bob)
+
+	s, err := m1.Share(actx, resource("home", "file"), userGrant(bob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m2.GetReceivedShare(bctx, idRef(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	perms := &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true}}
+	if _, err := m1.UpdateShare(actx, idRef(s), perms); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m2.Share(actx, resource("home", "other"), userGrant(carol)); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := m2.GetReceivedShare(bctx, idRef(s))
+	if err != nil || !rs.Share.Permissions.Permissions.InitiateFileDownload {
+		t.Fatalf("the cached space should have been refreshed, got %v, %v", rs, err)
+	}
+	all, err := m1.ListShares(actx, nil)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("alice should list the shares created through both managers, got %v, %v", all, err)
+	}
+}
+
+func TestUpdateConflict(t *testing.T) {
+	p := newFakeProvider(t)
+	m1, m2 := newTestManager(p), newTestManager(p)
+	ctx := m1.mustServiceContext(t, context.Background())
+	fn := path.Join(m1.c.Root, "test.json")
+
+	attempts := 0
+	err := m1.update(ctx, fn, newIndex, func(v docValue) (bool, error) {
+		attempts++
+		if attempts == 1 {
+			// another manager writes the document while this update is computed
+			if err := m2.addToIndex(ctx, fn, "b"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		v.(*index).Spaces["a"] = struct{}{}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("the update should have been retried once, got %d attempts", attempts)
+	}
+	if got := string(p.files[fn]); !strings.Contains(got, `"a"`) || !strings.Contains(got, `"b"`) {
+		t.Errorf("both updates should have been stored, got %s", got)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	p := newFakeProvider(t)
+	m := newTestManager(p)
+	actx := ctxuser.ContextSetUser(context.Background(), alice)
+	bctx := ctxuser.ContextSetUser(context.Background(), bob)
+
+	s, err := m.Share(actx, resource("home", "file"), userGrant(bob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := &typespb.Timestamp{Seconds: uint64(time.Now().Add(-time.Minute).Unix())}
+	if err := m.SetExpiration(actx, idRef(s), exp); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.GetReceivedShare(bctx, idRef(s)); err == nil {
+		t.Error("bob should not get an expired share")
+	}
+	if _, err := m.GetShare(actx, idRef(s)); err != nil {
+		t.Errorf("alice should still get her expired share: %v", err)
+	}
+	exps, err := m.GetExpirations(actx, []*collaboration.ShareId{s.Id})
+	if err != nil || exps[s.Id.OpaqueId].GetSeconds() != exp.Seconds {
+		t.Errorf("the expiration should be returned, got %v, %v", exps, err)
+	}
+	expired, err := m.ListExpiredShares(context.Background(), time.Now())
+	if err != nil || len(expired) != 1 || expired[0].Id.OpaqueId != s.Id.OpaqueId {
+		t.Errorf("the share should be listed as expired, got %v, %v", expired, err)
+	}
+}
+
+func (m *mgr) mustServiceContext(t *testing.T, ctx context.Context) context.Context {
+	ctx, err := m.serviceContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
@@ -0,0 +1,211 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package jsoncs3
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/pkg/errors"
+)
+
+// errConflict is returned when a document is uploaded on top of a copy
+// different from the one the changes were computed from.
+var errConflict = errors.New("jsoncs3: document changed meanwhile")
+
+// metadataStorage reads and writes the documents through the CS3 storage API
+// of the storage provider holding the metadata.
+type metadataStorage struct {
+	client     provider.ProviderAPIClient
+	httpClient *http.Client
+	dirs       sync.Map // directories known to exist
+}
+
+func pathRef(p string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+}
+
+// stat returns the etag of the document.
+func (s *metadataStorage) stat(ctx context.Context, p string) (string, error) {
+	res, err := s.client.Stat(ctx, &provider.StatRequest{Ref: pathRef(p)})
+	if err != nil {
+		return "", errors.Wrap(err, "jsoncs3: error stating "+p)
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+		return res.Info.Etag, nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return "", errtypes.NotFound(p)
+	}
+	return "", status.NewErrorFromCode(res.Status.Code, "jsoncs3")
+}
+
+func (s *metadataStorage) download(ctx context.Context, p string) ([]byte, error) {
+	res, err := s.client.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: pathRef(p)})
+	if err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error initiating download of "+p)
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, errtypes.NotFound(p)
+	default:
+		return nil, status.NewErrorFromCode(res.Status.Code, "jsoncs3")
+	}
+
+	var ep string
+	for _, proto := range res.Protocols {
+		if proto.Protocol == "simple" {
+			ep = proto.DownloadEndpoint
+		}
+	}
+	if ep == "" {
+		return nil, errtypes.NotSupported("jsoncs3: no simple download protocol for " + p)
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, ep, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpRes, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error downloading "+p)
+	}
+	defer httpRes.Body.Close()
+	switch httpRes.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errtypes.NotFound(p)
+	default:
+		return nil, errtypes.InternalError("jsoncs3: error downloading " + p + ": " + httpRes.Status)
+	}
+	return ioutil.ReadAll(httpRes.Body)
+}
+
+// upload stores the document if its current etag is still the given one, an
+// empty etag meaning that the document does not exist yet. The check and the
+// upload are not atomic, the storage API has no conditional uploads, but it
+// turns all but the closest concurrent updates into conflicts.
+func (s *metadataStorage) upload(ctx context.Context, p string, data []byte, etag string) error {
+	current, err := s.stat(ctx, p)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); !ok {
+			return err
+		}
+	}
+	if current != etag {
+		return errConflict
+	}
+
+	if err := s.mkdirAll(ctx, path.Dir(p)); err != nil {
+		return err
+	}
+
+	res, err := s.client.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: pathRef(p),
+		Opaque: &typespb.Opaque{Map: map[string]*typespb.OpaqueEntry{
+			"Upload-Length": {Decoder: "plain", Value: []byte(strconv.Itoa(len(data)))},
+		}},
+	})
+	if err != nil {
+		return errors.Wrap(err, "jsoncs3: error initiating upload of "+p)
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(res.Status.Code, "jsoncs3")
+	}
+
+	var ep string
+	for _, proto := range res.Protocols {
+		if proto.Protocol == "simple" {
+			ep = proto.UploadEndpoint
+		}
+	}
+	if ep == "" {
+		return errtypes.NotSupported("jsoncs3: no simple upload protocol for " + p)
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, ep, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpRes, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return errors.Wrap(err, "jsoncs3: error uploading "+p)
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode < 200 || httpRes.StatusCode > 299 {
+		return errtypes.InternalError("jsoncs3: error uploading " + p + ": " + httpRes.Status)
+	}
+	return nil
+}
+
+func (s *metadataStorage) mkdirAll(ctx context.Context, dir string) error {
+	if dir == "/" || dir == "." {
+		return nil
+	}
+	if _, ok := s.dirs.Load(dir); ok {
+		return nil
+	}
+	if err := s.mkdirAll(ctx, path.Dir(dir)); err != nil {
+		return err
+	}
+
+	res, err := s.client.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: pathRef(dir)})
+	if err != nil {
+		return errors.Wrap(err, "jsoncs3: error creating "+dir)
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK, rpc.Code_CODE_ALREADY_EXISTS:
+		s.dirs.Store(dir, struct{}{})
+		return nil
+	}
+	return status.NewErrorFromCode(res.Status.Code, "jsoncs3")
+}
+
+// list returns the names of the entries of the directory, none if it does
+// not exist.
+func (s *metadataStorage) list(ctx context.Context, dir string) ([]string, error) {
+	res, err := s.client.ListContainer(ctx, &provider.ListContainerRequest{Ref: pathRef(dir)})
+	if err != nil {
+		return nil, errors.Wrap(err, "jsoncs3: error listing "+dir)
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_NOT_FOUND:
+		return nil, nil
+	default:
+		return nil, status.NewErrorFromCode(res.Status.Code, "jsoncs3")
+	}
+	names := make([]string, 0, len(res.Infos))
+	for _, info := range res.Infos {
+		names = append(names, path.Base(info.Path))
+	}
+	return names, nil
+}
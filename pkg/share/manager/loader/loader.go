@@ -21,6 +21,8 @@ package loader
 import (
 	// Load core share manager drivers.
 	_ "github.com/cs3org/reva/pkg/share/manager/json"
+	_ "github.com/cs3org/reva/pkg/share/manager/jsoncs3"
 	_ "github.com/cs3org/reva/pkg/share/manager/memory"
+	_ "github.com/cs3org/reva/pkg/share/manager/plugin"
 	// Add your own here
 )
@@ -47,8 +47,9 @@ func init() {
 func New(c map[string]interface{}) (share.Manager, error) {
 	state := map[string]map[*collaboration.ShareId]collaboration.ShareState{}
 	return &manager{
-		shareState: state,
-		lock:       &sync.Mutex{},
+		shareState:  state,
+		expirations: map[string]*typespb.Timestamp{},
+		lock:        &sync.Mutex{},
 	}, nil
 }
 
@@ -58,6 +59,8 @@ type manager struct {
 	// shareState contains the share state for a user.
 	// map["alice"]["share-id"]state.
 	shareState map[string]map[*collaboration.ShareId]collaboration.ShareState
+	// expirations contains the expiration of the shares by share id.
+	expirations map[string]*typespb.Timestamp
 }
 
 func (m *manager) add(ctx context.Context, s *collaboration.Share) {
@@ -174,6 +177,7 @@ func (m *manager) Unshare(ctx context.Context, ref *collaboration.ShareReference
 			if utils.UserEqual(user.Id, s.Owner) || utils.UserEqual(user.Id, s.Creator) {
 				m.shares[len(m.shares)-1], m.shares[i] = m.shares[i], m.shares[len(m.shares)-1]
 				m.shares = m.shares[:len(m.shares)-1]
+				delete(m.expirations, s.Id.OpaqueId)
 				return nil
 			}
 		}
@@ -247,11 +251,15 @@ func (m *manager) ListReceivedShares(ctx context.Context) ([]*collaboration.Rece
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	user := user.ContextMustGetUser(ctx)
+	now := time.Now()
 	for _, s := range m.shares {
 		if utils.UserEqual(user.Id, s.Owner) || utils.UserEqual(user.Id, s.Creator) {
 			// omit shares created by me
 			continue
 		}
+		if share.Expired(m.expirations[s.Id.OpaqueId], now) {
+			continue
+		}
 		if s.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, s.Grantee.GetUserId()) {
 			rs := m.convert(ctx, s)
 			rss = append(rss, rs)
@@ -291,8 +299,9 @@ func (m *manager) getReceived(ctx context.Context, ref *collaboration.ShareRefer
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	user := user.ContextMustGetUser(ctx)
+	now := time.Now()
 	for _, s := range m.shares {
-		if sharesEqual(ref, s) {
+		if sharesEqual(ref, s) && !share.Expired(m.expirations[s.Id.OpaqueId], now) {
 			if s.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, s.Grantee.GetUserId()) {
 				rs := m.convert(ctx, s)
 				return rs, nil
@@ -314,6 +323,9 @@ func (m *manager) UpdateReceivedShare(ctx context.Context, ref *collaboration.Sh
 	if err != nil {
 		return nil, err
 	}
+	if err := share.CheckStateTransition(rs.State, f.GetState()); err != nil {
+		return nil, err
+	}
 
 	user := user.ContextMustGetUser(ctx)
 	m.lock.Lock()
@@ -328,5 +340,50 @@ func (m *manager) UpdateReceivedShare(ctx context.Context, ref *collaboration.Sh
 		}
 		m.shareState[user.Id.String()] = a
 	}
+	rs.State = f.GetState()
 	return rs, nil
 }
+
+// SetExpiration sets the expiration of a share owned or created by the user in context.
+func (m *manager) SetExpiration(ctx context.Context, ref *collaboration.ShareReference, exp *typespb.Timestamp) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	user := user.ContextMustGetUser(ctx)
+	for _, s := range m.shares {
+		if sharesEqual(ref, s) && (utils.UserEqual(user.Id, s.Owner) || utils.UserEqual(user.Id, s.Creator)) {
+			if exp == nil {
+				delete(m.expirations, s.Id.OpaqueId)
+			} else {
+				m.expirations[s.Id.OpaqueId] = exp
+			}
+			return nil
+		}
+	}
+	return errtypes.NotFound(ref.String())
+}
+
+// GetExpirations returns the expirations of the given shares.
+func (m *manager) GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	exps := map[string]*typespb.Timestamp{}
+	for _, id := range ids {
+		if exp, ok := m.expirations[id.GetOpaqueId()]; ok {
+			exps[id.GetOpaqueId()] = exp
+		}
+	}
+	return exps, nil
+}
+
+// ListExpiredShares returns the shares expired at t.
+func (m *manager) ListExpiredShares(ctx context.Context, t time.Time) ([]*collaboration.Share, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var ss []*collaboration.Share
+	for _, s := range m.shares {
+		if share.Expired(m.expirations[s.Id.OpaqueId], t) {
+			ss = append(ss, s)
+		}
+	}
+	return ss, nil
+}
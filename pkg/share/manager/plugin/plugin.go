@@ -0,0 +1,287 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package plugin provides a share manager served by an external binary. The
+// binary creates its share manager with the configuration of the driver and
+// serves it with Serve:
+//
+//	func main() {
+//		plugin.Serve(mymanager.New)
+//	}
+package plugin
+
+import (
+	"context"
+
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	revaplugin "github.com/cs3org/reva/pkg/plugin"
+	pluginpb "github.com/cs3org/reva/pkg/plugin/proto"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/share/manager/registry"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const kind = "sharemanager"
+
+func init() {
+	registry.Register("plugin", New)
+}
+
+type config struct {
+	// Path is the binary of the plugin.
+	Path string `mapstructure:"path"`
+	// Config is the configuration the plugin creates its share manager with.
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// New starts the plugin and returns the share manager it serves.
+func New(m map[string]interface{}) (share.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	p, err := revaplugin.Load(kind, c.Path, &Plugin{})
+	if err != nil {
+		return nil, err
+	}
+	mgr := p.Raw.(*grpcClient)
+	if err := mgr.configure(c.Config); err != nil {
+		p.Kill()
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// Serve serves the share manager created by the given function from a
+// plugin binary.
+func Serve(f registry.NewFunc) {
+	revaplugin.Serve(kind, &Plugin{New: f})
+}
+
+// Plugin is the go-plugin of the share managers.
+type Plugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	// New creates the share manager on the plugin side.
+	New registry.NewFunc
+}
+
+// GRPCServer registers the server of the plugin binary.
+func (p *Plugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	pluginpb.RegisterShareManagerPluginServer(s, &grpcServer{new: p.New})
+	return nil
+}
+
+// GRPCClient returns the share manager calling the plugin binary.
+func (p *Plugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{client: pluginpb.NewShareManagerPluginClient(c)}, nil
+}
+
+type grpcClient struct {
+	client pluginpb.ShareManagerPluginClient
+}
+
+func (c *grpcClient) configure(m map[string]interface{}) error {
+	config, err := revaplugin.EncodeConfig(m)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Configure(context.Background(), &pluginpb.ConfigureRequest{Config: config})
+	return err
+}
+
+func (c *grpcClient) Share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant) (*collaboration.Share, error) {
+	res, err := c.client.Share(ctx, &pluginpb.ShareRequest{ResourceInfo: md, Grant: g})
+	if err != nil {
+		return nil, err
+	}
+	return res.Share, nil
+}
+
+func (c *grpcClient) GetShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.Share, error) {
+	res, err := c.client.GetShare(ctx, &pluginpb.GetShareRequest{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	return res.Share, nil
+}
+
+func (c *grpcClient) Unshare(ctx context.Context, ref *collaboration.ShareReference) error {
+	_, err := c.client.Unshare(ctx, &pluginpb.UnshareRequest{Ref: ref})
+	return err
+}
+
+func (c *grpcClient) UpdateShare(ctx context.Context, ref *collaboration.ShareReference, p *collaboration.SharePermissions) (*collaboration.Share, error) {
+	res, err := c.client.UpdateShare(ctx, &pluginpb.UpdateShareRequest{Ref: ref, Permissions: p})
+	if err != nil {
+		return nil, err
+	}
+	return res.Share, nil
+}
+
+func (c *grpcClient) ListShares(ctx context.Context, filters []*collaboration.ListSharesRequest_Filter) ([]*collaboration.Share, error) {
+	res, err := c.client.ListShares(ctx, &pluginpb.ListSharesRequest{Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+	return res.Shares, nil
+}
+
+func (c *grpcClient) ListReceivedShares(ctx context.Context) ([]*collaboration.ReceivedShare, error) {
+	res, err := c.client.ListReceivedShares(ctx, &pluginpb.ListReceivedSharesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Shares, nil
+}
+
+func (c *grpcClient) GetReceivedShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.ReceivedShare, error) {
+	res, err := c.client.GetReceivedShare(ctx, &pluginpb.GetReceivedShareRequest{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	return res.Share, nil
+}
+
+func (c *grpcClient) UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareReference, f *collaboration.UpdateReceivedShareRequest_UpdateField) (*collaboration.ReceivedShare, error) {
+	res, err := c.client.UpdateReceivedShare(ctx, &pluginpb.UpdateReceivedShareRequest{Ref: ref, Field: f})
+	if err != nil {
+		return nil, err
+	}
+	return res.Share, nil
+}
+
+type grpcServer struct {
+	new registry.NewFunc
+	mgr share.Manager
+}
+
+func (s *grpcServer) manager() (share.Manager, error) {
+	if s.mgr == nil {
+		return nil, errors.New("plugin: share manager not configured")
+	}
+	return s.mgr, nil
+}
+
+func (s *grpcServer) Configure(_ context.Context, req *pluginpb.ConfigureRequest) (*pluginpb.ConfigureResponse, error) {
+	mgr, err := s.new(revaplugin.DecodeConfig(req.Config))
+	if err != nil {
+		return nil, err
+	}
+	s.mgr = mgr
+	return &pluginpb.ConfigureResponse{}, nil
+}
+
+func (s *grpcServer) Share(ctx context.Context, req *pluginpb.ShareRequest) (*pluginpb.ShareResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	sh, err := mgr.Share(ctx, req.ResourceInfo, req.Grant)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ShareResponse{Share: sh}, nil
+}
+
+func (s *grpcServer) Unshare(ctx context.Context, req *pluginpb.UnshareRequest) (*pluginpb.UnshareResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.Unshare(ctx, req.Ref); err != nil {
+		return nil, err
+	}
+	return &pluginpb.UnshareResponse{}, nil
+}
+
+func (s *grpcServer) GetShare(ctx context.Context, req *pluginpb.GetShareRequest) (*pluginpb.GetShareResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	sh, err := mgr.GetShare(ctx, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetShareResponse{Share: sh}, nil
+}
+
+func (s *grpcServer) UpdateShare(ctx context.Context, req *pluginpb.UpdateShareRequest) (*pluginpb.UpdateShareResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	sh, err := mgr.UpdateShare(ctx, req.Ref, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.UpdateShareResponse{Share: sh}, nil
+}
+
+func (s *grpcServer) ListShares(ctx context.Context, req *pluginpb.ListSharesRequest) (*pluginpb.ListSharesResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	shares, err := mgr.ListShares(ctx, req.Filters)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ListSharesResponse{Shares: shares}, nil
+}
+
+func (s *grpcServer) ListReceivedShares(ctx context.Context, _ *pluginpb.ListReceivedSharesRequest) (*pluginpb.ListReceivedSharesResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	shares, err := mgr.ListReceivedShares(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ListReceivedSharesResponse{Shares: shares}, nil
+}
+
+func (s *grpcServer) GetReceivedShare(ctx context.Context, req *pluginpb.GetReceivedShareRequest) (*pluginpb.GetReceivedShareResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	sh, err := mgr.GetReceivedShare(ctx, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetReceivedShareResponse{Share: sh}, nil
+}
+
+func (s *grpcServer) UpdateReceivedShare(ctx context.Context, req *pluginpb.UpdateReceivedShareRequest) (*pluginpb.UpdateReceivedShareResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	sh, err := mgr.UpdateReceivedShare(ctx, req.Ref, req.Field)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.UpdateReceivedShareResponse{Share: sh}, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package plugin
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	revaplugin "github.com/cs3org/reva/pkg/plugin"
+	pluginpb "github.com/cs3org/reva/pkg/plugin/proto"
+	"github.com/cs3org/reva/pkg/share"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// manager records the calls the plugin side receives.
+type manager struct {
+	share.Manager
+	user    *userpb.User
+	filters []*collaboration.ListSharesRequest_Filter
+}
+
+func (m *manager) GetShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.Share, error) {
+	m.user, _ = ctxuser.ContextGetUser(ctx)
+	if ref.GetId().GetOpaqueId() != "1" {
+		return nil, errtypes.NotFound(ref.GetId().GetOpaqueId())
+	}
+	return &collaboration.Share{Id: ref.GetId(), Grantee: &provider.Grantee{
+		Type: provider.GranteeType_GRANTEE_TYPE_USER,
+		Id:   &provider.Grantee_UserId{UserId: &userpb.UserId{OpaqueId: "marie"}},
+	}}, nil
+}
+
+func (m *manager) ListShares(ctx context.Context, filters []*collaboration.ListSharesRequest_Filter) ([]*collaboration.Share, error) {
+	m.filters = filters
+	return nil, nil
+}
+
+func TestGRPC(t *testing.T) {
+	mgr := &manager{}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(revaplugin.UnaryServerInterceptor))
+	pluginpb.RegisterShareManagerPluginServer(srv, &grpcServer{new: func(map[string]interface{}) (share.Manager, error) { return mgr, nil }})
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(revaplugin.UnaryClientInterceptor),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := &grpcClient{client: pluginpb.NewShareManagerPluginClient(conn)}
+
+	if _, err := c.ListReceivedShares(context.Background()); err == nil {
+		t.Error("expected an error before the plugin is configured")
+	}
+	if err := c.configure(map[string]interface{}{"nested": map[string]interface{}{"list": []interface{}{"a"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := ctxuser.ContextSetUser(context.Background(), &userpb.User{Username: "einstein"})
+	s, err := c.GetShare(ctx, &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: &collaboration.ShareId{OpaqueId: "1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetGrantee().GetUserId().GetOpaqueId() != "marie" {
+		t.Errorf("unexpected share %v", s)
+	}
+	if mgr.user.GetUsername() != "einstein" {
+		t.Errorf("the user of the context was not passed to the plugin: %v", mgr.user)
+	}
+
+	_, err = c.GetShare(ctx, &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: &collaboration.ShareId{OpaqueId: "2"}}})
+	if _, ok := err.(errtypes.NotFound); !ok {
+		t.Errorf("expected a not found error, got %T %v", err, err)
+	}
+
+	filters := []*collaboration.ListSharesRequest_Filter{{
+		Type: collaboration.ListSharesRequest_Filter_TYPE_OWNER,
+		Term: &collaboration.ListSharesRequest_Filter_Owner{Owner: &userpb.UserId{OpaqueId: "einstein"}},
+	}}
+	if _, err := c.ListShares(ctx, filters); err != nil {
+		t.Fatal(err)
+	}
+	if len(mgr.filters) != 1 || mgr.filters[0].GetOwner().GetOpaqueId() != "einstein" {
+		t.Errorf("unexpected filters %v", mgr.filters)
+	}
+}
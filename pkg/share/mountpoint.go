@@ -0,0 +1,78 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"encoding/json"
+
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+const (
+	// MountPointOpaqueKey is the opaque key of the received share responses holding
+	// the name the share is mounted with in the share folder of the grantee.
+	MountPointOpaqueKey = "mount_point"
+	// MountPointsOpaqueKey is the opaque key of the received share listings holding
+	// the mount points of the shares, as a JSON object from the share ids to the
+	// names. Shares that are not mounted are left out.
+	MountPointsOpaqueKey = "mount_points"
+)
+
+// EncodeMountPoint adds the mount point to the opaque, which is created if nil.
+func EncodeMountPoint(o *typespb.Opaque, name string) *typespb.Opaque {
+	return setOpaqueEntry(o, MountPointOpaqueKey, &typespb.OpaqueEntry{Decoder: "plain", Value: []byte(name)})
+}
+
+// DecodeMountPoint returns the mount point held by the opaque, or an empty
+// name if it holds none.
+func DecodeMountPoint(o *typespb.Opaque) string {
+	e, ok := o.GetMap()[MountPointOpaqueKey]
+	if !ok || e.Decoder != "plain" {
+		return ""
+	}
+	return string(e.Value)
+}
+
+// EncodeMountPoints adds the mount points of listed shares to the opaque, which
+// is created if nil.
+func EncodeMountPoints(o *typespb.Opaque, names map[string]string) (*typespb.Opaque, error) {
+	v, err := json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+	return setOpaqueEntry(o, MountPointsOpaqueKey, &typespb.OpaqueEntry{Decoder: "json", Value: v}), nil
+}
+
+// DecodeMountPoints returns the mount points of listed shares held by the
+// opaque, an empty map if it holds none.
+func DecodeMountPoints(o *typespb.Opaque) (map[string]string, error) {
+	names := map[string]string{}
+	e, ok := o.GetMap()[MountPointsOpaqueKey]
+	if !ok {
+		return names, nil
+	}
+	if e.Decoder != "json" {
+		return nil, errtypes.BadRequest("share: unsupported decoder for " + MountPointsOpaqueKey + ": " + e.Decoder)
+	}
+	if err := json.Unmarshal(e.Value, &names); err != nil {
+		return nil, errtypes.BadRequest("share: invalid " + MountPointsOpaqueKey + ": " + err.Error())
+	}
+	return names, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package policy implements the default share settings a space manager can
+// configure for a space. The settings are stored as arbitrary metadata on the
+// space root and apply to all the shares created inside the space.
+package policy
+
+import (
+	"strconv"
+	"time"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+const (
+	// DefaultExpirationKey is the arbitrary metadata key holding the number of days after which public links expire by default.
+	DefaultExpirationKey = "reva.share.default_expiration_days"
+	// PasswordRequiredKey is the arbitrary metadata key requiring public links to be password protected.
+	PasswordRequiredKey = "reva.share.password_required"
+	// ExternalSharingKey is the arbitrary metadata key allowing or forbidding federated shares.
+	ExternalSharingKey = "reva.share.external_allowed"
+)
+
+// Keys are the arbitrary metadata keys to request when looking up a policy.
+var Keys = []string{DefaultExpirationKey, PasswordRequiredKey, ExternalSharingKey}
+
+// Policy holds the share settings of a space.
+type Policy struct {
+	// DefaultExpiration is applied to public links created without expiration, 0 means no expiration.
+	DefaultExpiration time.Duration
+	// PasswordRequired rejects public links without password.
+	PasswordRequired bool
+	// ExternalSharingAllowed allows federated shares.
+	ExternalSharingAllowed bool
+}
+
+// Default returns the policy of spaces without configured policy.
+func Default() *Policy {
+	return &Policy{ExternalSharingAllowed: true}
+}
+
+// Merge restricts the policy with the settings found in the arbitrary metadata.
+// When spaces are nested the strictest setting wins, so that the manager of a
+// subfolder can't lift the restrictions of the enclosing space.
+func (p *Policy) Merge(md map[string]string) error {
+	if v, ok := md[DefaultExpirationKey]; ok && v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return errtypes.BadRequest("policy: invalid " + DefaultExpirationKey + ": " + v)
+		}
+		d := time.Duration(days) * 24 * time.Hour
+		if d > 0 && (p.DefaultExpiration == 0 || d < p.DefaultExpiration) {
+			p.DefaultExpiration = d
+		}
+	}
+	if v, ok := md[PasswordRequiredKey]; ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errtypes.BadRequest("policy: invalid " + PasswordRequiredKey + ": " + v)
+		}
+		p.PasswordRequired = p.PasswordRequired || b
+	}
+	if v, ok := md[ExternalSharingKey]; ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errtypes.BadRequest("policy: invalid " + ExternalSharingKey + ": " + v)
+		}
+		p.ExternalSharingAllowed = p.ExternalSharingAllowed && b
+	}
+	return nil
+}
+
+// ApplyToLink sets the default expiration on the grant of a new public link
+// and checks that it is password protected when required.
+func (p *Policy) ApplyToLink(g *link.Grant, now time.Time) error {
+	if p.PasswordRequired && g.GetPassword() == "" {
+		return errtypes.PermissionDenied("policy: public links in this space must be password protected")
+	}
+	if p.DefaultExpiration > 0 && g.Expiration == nil {
+		exp := now.Add(p.DefaultExpiration)
+		g.Expiration = &types.Timestamp{
+			Seconds: uint64(exp.Unix()),
+			Nanos:   uint32(exp.Nanosecond()),
+		}
+	}
+	return nil
+}
+
+// CheckExternal checks that federated shares may be created.
+func (p *Policy) CheckExternal() error {
+	if !p.ExternalSharingAllowed {
+		return errtypes.PermissionDenied("policy: sharing with external users is not allowed in this space")
+	}
+	return nil
+}
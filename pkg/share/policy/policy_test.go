@@ -0,0 +1,77 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+)
+
+func TestMergeKeepsTheStrictestSettings(t *testing.T) {
+	p := Default()
+	if err := p.Merge(map[string]string{DefaultExpirationKey: "30", ExternalSharingKey: "false"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Merge(map[string]string{DefaultExpirationKey: "60", ExternalSharingKey: "true", PasswordRequiredKey: "true"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.DefaultExpiration != 30*24*time.Hour {
+		t.Errorf("expected 30 days default expiration, got %s", p.DefaultExpiration)
+	}
+	if !p.PasswordRequired {
+		t.Error("expected password to be required")
+	}
+	if p.ExternalSharingAllowed {
+		t.Error("expected external sharing to be forbidden")
+	}
+}
+
+func TestMergeRejectsInvalidValues(t *testing.T) {
+	tests := []map[string]string{
+		{DefaultExpirationKey: "-1"},
+		{DefaultExpirationKey: "soon"},
+		{PasswordRequiredKey: "maybe"},
+		{ExternalSharingKey: "sometimes"},
+	}
+	for _, md := range tests {
+		if err := Default().Merge(md); err == nil {
+			t.Errorf("expected an error for %v", md)
+		}
+	}
+}
+
+func TestApplyToLink(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	p := &Policy{DefaultExpiration: 24 * time.Hour, PasswordRequired: true}
+
+	if err := p.ApplyToLink(&link.Grant{}, now); err == nil {
+		t.Error("expected links without password to be rejected")
+	}
+
+	g := &link.Grant{Password: "secret"}
+	if err := p.ApplyToLink(g, now); err != nil {
+		t.Fatal(err)
+	}
+	if g.Expiration == nil || int64(g.Expiration.Seconds) != now.Add(24*time.Hour).Unix() {
+		t.Errorf("expected the default expiration to be set, got %v", g.Expiration)
+	}
+}
@@ -20,9 +20,12 @@ package share
 
 import (
 	"context"
+	"fmt"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
 )
 
 // Manager is the interface that manipulates shares.
@@ -52,3 +55,40 @@ type Manager interface {
 	// UpdateReceivedShare updates the received share with share state.
 	UpdateReceivedShare(ctx context.Context, ref *collaboration.ShareReference, f *collaboration.UpdateReceivedShareRequest_UpdateField) (*collaboration.ReceivedShare, error)
 }
+
+// ReceivedShareState is the state a single user has set on a received share.
+type ReceivedShareState struct {
+	UserID  *userpb.UserId
+	ShareID *collaboration.ShareId
+	State   collaboration.ShareState
+}
+
+// CheckStateTransition checks that a received share can be moved from one state
+// to the other. Pending shares are accepted or declined by their grantees, who can
+// change their mind later on, but shares never become pending again.
+func CheckStateTransition(from, to collaboration.ShareState) error {
+	switch to {
+	case collaboration.ShareState_SHARE_STATE_ACCEPTED, collaboration.ShareState_SHARE_STATE_REJECTED:
+		return nil
+	}
+	return errtypes.BadRequest(fmt.Sprintf("share: invalid state transition from %s to %s", from, to))
+}
+
+// Dumper is implemented by the managers that are able to export all the shares
+// they hold, regardless of the user in context.
+type Dumper interface {
+	Dump(ctx context.Context) ([]*collaboration.Share, []*ReceivedShareState, error)
+}
+
+// Loader is implemented by the managers that are able to import shares previously
+// exported by a Dumper, preserving their ids wherever the backend allows it.
+type Loader interface {
+	Load(ctx context.Context, shares []*collaboration.Share, states []*ReceivedShareState) error
+}
+
+// UserRenamer is implemented by the managers that are able to replace the id of
+// a user in all the shares it owns, created or is the grantee of, as well as in
+// the states of the shares it received.
+type UserRenamer interface {
+	RenameUser(ctx context.Context, oldID, newID *userpb.UserId) error
+}
@@ -0,0 +1,52 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"testing"
+
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+)
+
+func TestCheckStateTransition(t *testing.T) {
+	var (
+		pending  = collaboration.ShareState_SHARE_STATE_PENDING
+		accepted = collaboration.ShareState_SHARE_STATE_ACCEPTED
+		rejected = collaboration.ShareState_SHARE_STATE_REJECTED
+		invalid  = collaboration.ShareState_SHARE_STATE_INVALID
+	)
+	tests := []struct {
+		from, to collaboration.ShareState
+		ok       bool
+	}{
+		{pending, accepted, true},
+		{pending, rejected, true},
+		{accepted, rejected, true},
+		{rejected, accepted, true},
+		{accepted, accepted, true},
+		{accepted, pending, false},
+		{rejected, pending, false},
+		{pending, invalid, false},
+	}
+	for _, tt := range tests {
+		if err := CheckStateTransition(tt.from, tt.to); (err == nil) != tt.ok {
+			t.Errorf("transition from %s to %s: got error %v", tt.from, tt.to, err)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"mime"
 	"net/smtp"
 	"os"
 	"strings"
@@ -68,7 +69,7 @@ func (creds *SMTPCredentials) SendMail(recipient, subject, body string) error {
 	headers := map[string]string{
 		"From":                      creds.SenderMail,
 		"To":                        recipient,
-		"Subject":                   subject,
+		"Subject":                   mime.QEncoding.Encode("utf-8", subject),
 		"Date":                      time.Now().Format(time.RFC1123Z),
 		"Message-ID":                uuid.New().String(),
 		"MIME-Version":              "1.0",
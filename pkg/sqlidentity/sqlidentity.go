@@ -0,0 +1,175 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sqlidentity holds the schema of the SQL backed user and group
+// managers, which share their database, and opens the pool of connections to
+// it.
+package sqlidentity
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/sqlmigrate"
+	"github.com/pkg/errors"
+
+	// Provide the supported database drivers.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const migrationsTable = "identity_migrations"
+
+var migrations = []sqlmigrate.Migration{
+	{
+		Version:     1,
+		Description: "create the users, groups and memberships tables",
+		Up: map[sqlmigrate.Dialect][]string{
+			"": {
+				`CREATE TABLE users (
+					idp VARCHAR(255) NOT NULL,
+					user_id VARCHAR(255) NOT NULL,
+					username VARCHAR(255) NOT NULL,
+					mail VARCHAR(255) NOT NULL,
+					display_name VARCHAR(255) NOT NULL,
+					uid_number BIGINT NOT NULL,
+					gid_number BIGINT NOT NULL,
+					PRIMARY KEY (idp, user_id)
+				)`,
+				"CREATE UNIQUE INDEX users_username ON users (username)",
+				"CREATE INDEX users_user_id ON users (user_id)",
+				"CREATE INDEX users_mail ON users (mail)",
+				"CREATE INDEX users_display_name ON users (display_name)",
+				"CREATE INDEX users_uid_number ON users (uid_number)",
+				`CREATE TABLE user_groups (
+					idp VARCHAR(255) NOT NULL,
+					group_id VARCHAR(255) NOT NULL,
+					group_name VARCHAR(255) NOT NULL,
+					mail VARCHAR(255) NOT NULL,
+					display_name VARCHAR(255) NOT NULL,
+					gid_number BIGINT NOT NULL,
+					PRIMARY KEY (idp, group_id)
+				)`,
+				"CREATE UNIQUE INDEX user_groups_group_name ON user_groups (group_name)",
+				"CREATE INDEX user_groups_group_id ON user_groups (group_id)",
+				"CREATE INDEX user_groups_mail ON user_groups (mail)",
+				"CREATE INDEX user_groups_display_name ON user_groups (display_name)",
+				"CREATE INDEX user_groups_gid_number ON user_groups (gid_number)",
+				`CREATE TABLE group_members (
+					group_idp VARCHAR(255) NOT NULL,
+					group_id VARCHAR(255) NOT NULL,
+					user_idp VARCHAR(255) NOT NULL,
+					user_id VARCHAR(255) NOT NULL,
+					PRIMARY KEY (group_idp, group_id, user_idp, user_id)
+				)`,
+				"CREATE INDEX group_members_user ON group_members (user_id, user_idp)",
+			},
+		},
+	},
+	{
+		Version:     2,
+		Description: "add the disabled flag of the users",
+		Up: map[sqlmigrate.Dialect][]string{
+			"": {"ALTER TABLE users ADD COLUMN disabled SMALLINT NOT NULL DEFAULT 0"},
+		},
+	},
+}
+
+// Config holds the database options of the SQL backed user and group
+// managers.
+type Config struct {
+	// DbDriver is the database/sql driver: mysql, postgres or sqlite3.
+	DbDriver       string `mapstructure:"db_driver"`
+	DbDSN          string `mapstructure:"db_dsn"`
+	SkipMigrations bool   `mapstructure:"db_skip_migrations"`
+	// MaxOpenConns limits the connections to the database, 0 for no limit.
+	MaxOpenConns int `mapstructure:"db_max_open_conns"`
+	// MaxIdleConns is the number of idle connections kept in the pool.
+	MaxIdleConns int `mapstructure:"db_max_idle_conns"`
+	// ConnMaxLifetime is the time in seconds after which a connection is
+	// closed, 0 to reuse the connections forever.
+	ConnMaxLifetime int `mapstructure:"db_conn_max_lifetime"`
+}
+
+func (c *Config) init() {
+	if c.DbDriver == "" {
+		c.DbDriver = string(sqlmigrate.SQLite)
+	}
+	if c.DbDSN == "" && c.DbDriver == string(sqlmigrate.SQLite) {
+		c.DbDSN = "/var/tmp/reva/identity.db"
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = 2
+	}
+}
+
+// Open opens the pool of connections to the database of the configuration
+// and applies the pending migrations unless they are skipped.
+func Open(c *Config) (*sql.DB, sqlmigrate.Dialect, error) {
+	c.init()
+
+	dialect := sqlmigrate.Dialect(c.DbDriver)
+	switch dialect {
+	case sqlmigrate.MySQL, sqlmigrate.Postgres:
+	case sqlmigrate.SQLite:
+		if err := os.MkdirAll(filepath.Dir(c.DbDSN), 0755); err != nil {
+			return nil, "", err
+		}
+	default:
+		return nil, "", errtypes.NotSupported("sqlidentity: unsupported database driver " + c.DbDriver)
+	}
+
+	db, err := sql.Open(c.DbDriver, c.DbDSN)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "sqlidentity: error opening the database")
+	}
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(c.ConnMaxLifetime) * time.Second)
+
+	if !c.SkipMigrations {
+		if err := sqlmigrate.Apply(context.Background(), db, dialect, migrationsTable, migrations); err != nil {
+			_ = db.Close()
+			return nil, "", err
+		}
+	}
+	return db, dialect, nil
+}
+
+// Contains returns the condition matching the rows where one of the columns
+// contains the search term, case insensitively, and its arguments.
+func Contains(query string, columns ...string) (string, []interface{}) {
+	// ! is the escape character as the backslash is a special one in the
+	// string literals of MySQL
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	pattern := "%" + r.Replace(strings.ToLower(query)) + "%"
+
+	conds := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns))
+	for _, c := range columns {
+		conds = append(conds, "LOWER("+c+") LIKE ? ESCAPE '!'")
+		args = append(args, pattern)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sqlmigrate applies the versioned schema migrations of the SQL backed
+// drivers, so that their tables are created and upgraded on startup.
+package sqlmigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Dialect is the flavour of SQL spoken by a database, named after its
+// database/sql driver.
+type Dialect string
+
+const (
+	// MySQL is the dialect of MySQL and MariaDB.
+	MySQL Dialect = "mysql"
+	// Postgres is the dialect of PostgreSQL.
+	Postgres Dialect = "postgres"
+	// SQLite is the dialect of SQLite.
+	SQLite Dialect = "sqlite3"
+)
+
+// Rebind replaces the ? placeholders of the query with the ones of the
+// dialect. The query must not contain question marks in its literals.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Migration is a step in the evolution of a schema.
+type Migration struct {
+	// Version orders the migrations. It must be unique and never change once
+	// the migration was released.
+	Version     int
+	Description string
+	// Up holds the statements of the migration per dialect, the ones under
+	// the empty dialect are run on the dialects which are not listed.
+	Up map[Dialect][]string
+}
+
+// Apply runs on db the migrations which were not applied yet, in the order of
+// their versions. The applied versions are recorded in the given table. Every
+// migration runs in a transaction, keep in mind that MySQL commits the schema
+// changes right away.
+func Apply(ctx context.Context, db *sql.DB, d Dialect, table string, migrations []Migration) error {
+	create := "CREATE TABLE IF NOT EXISTS " + table + " (version INTEGER NOT NULL PRIMARY KEY, description VARCHAR(255) NOT NULL, applied_at BIGINT NOT NULL)"
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return errors.Wrap(err, "sqlmigrate: error creating table "+table)
+	}
+
+	applied, err := appliedVersions(ctx, db, table)
+	if err != nil {
+		return err
+	}
+
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return errors.Errorf("sqlmigrate: duplicate migration version %d", sorted[i].Version)
+		}
+	}
+
+	for _, m := range sorted {
+		if applied[m.Version] {
+			continue
+		}
+		stmts, ok := m.Up[d]
+		if !ok {
+			stmts, ok = m.Up[""]
+		}
+		if !ok {
+			return errors.Errorf("sqlmigrate: migration %d has no statements for %s", m.Version, d)
+		}
+		if err := apply(ctx, db, d, table, m, stmts); err != nil {
+			return err
+		}
+		log.Info().Str("table", table).Int("version", m.Version).Str("description", m.Description).Msg("sqlmigrate: migration applied")
+	}
+	return nil
+}
+
+// Version returns the latest migration applied to db, or 0 if none was.
+func Version(ctx context.Context, db *sql.DB, table string) (int, error) {
+	var v sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM "+table).Scan(&v); err != nil {
+		return 0, errors.Wrap(err, "sqlmigrate: error reading the schema version")
+	}
+	return int(v.Int64), nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB, table string) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM "+table)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlmigrate: error reading the applied migrations")
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, errors.Wrap(err, "sqlmigrate: error reading the applied migrations")
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+func apply(ctx context.Context, db *sql.DB, d Dialect, table string, m Migration, stmts []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, s := range stmts {
+		if _, err := tx.ExecContext(ctx, s); err != nil {
+			return errors.Wrapf(err, "sqlmigrate: error applying migration %d (%s)", m.Version, m.Description)
+		}
+	}
+	insert := d.Rebind("INSERT INTO " + table + " (version, description, applied_at) VALUES (?, ?, ?)")
+	if _, err := tx.ExecContext(ctx, insert, m.Version, m.Description, time.Now().Unix()); err != nil {
+		return errors.Wrapf(err, "sqlmigrate: error recording migration %d", m.Version)
+	}
+	return tx.Commit()
+}
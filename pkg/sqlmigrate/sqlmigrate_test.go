@@ -0,0 +1,79 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sqlmigrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRebind(t *testing.T) {
+	q := "SELECT a FROM t WHERE b = ? AND c = ?"
+	if got := Postgres.Rebind(q); got != "SELECT a FROM t WHERE b = $1 AND c = $2" {
+		t.Errorf("unexpected postgres query: %s", got)
+	}
+	if got := MySQL.Rebind(q); got != q {
+		t.Errorf("unexpected mysql query: %s", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations := []Migration{
+		{Version: 2, Description: "add column", Up: map[Dialect][]string{"": {"ALTER TABLE things ADD COLUMN color TEXT"}}},
+		{Version: 1, Description: "create table", Up: map[Dialect][]string{
+			SQLite: {"CREATE TABLE things (id TEXT PRIMARY KEY)"},
+			MySQL:  {"CREATE TABLE things (id VARCHAR(64) PRIMARY KEY)"},
+		}},
+	}
+	for i := 0; i < 2; i++ {
+		if err := Apply(ctx, db, SQLite, "migrations", migrations); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+	if v, err := Version(ctx, db, "migrations"); err != nil || v != 2 {
+		t.Fatalf("expected version 2, got %d (%v)", v, err)
+	}
+	if _, err := db.Exec("INSERT INTO things (id, color) VALUES ('a', 'red')"); err != nil {
+		t.Fatalf("migrated schema is not usable: %v", err)
+	}
+
+	migrations = append(migrations, Migration{Version: 3, Description: "broken", Up: map[Dialect][]string{"": {"ALTER TABLE nope ADD COLUMN x TEXT"}}})
+	if err := Apply(ctx, db, SQLite, "migrations", migrations); err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	if v, _ := Version(ctx, db, "migrations"); v != 2 {
+		t.Fatalf("failed migration was recorded, version %d", v)
+	}
+
+	dup := []Migration{{Version: 4, Up: map[Dialect][]string{"": {"SELECT 1"}}}, {Version: 4, Up: map[Dialect][]string{"": {"SELECT 1"}}}}
+	if err := Apply(ctx, db, SQLite, "migrations", dup); err == nil {
+		t.Fatal("expected duplicate versions to be rejected")
+	}
+}
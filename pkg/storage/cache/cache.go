@@ -0,0 +1,84 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package cache defines the stat, listing and etag caches shared by the
+// services talking to the storage providers.
+package cache
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// Cache keeps stat results, folder listings and folder etags. Implementations backed by a
+// shared store allow horizontally scaled services to reuse each others results.
+type Cache interface {
+	// GetStat returns the cached stat result of the path as seen by the user.
+	GetStat(userID, p string) (*provider.ResourceInfo, error)
+	// SetStat caches the stat result of the path as seen by the user.
+	SetStat(userID, p string, info *provider.ResourceInfo) error
+	// GetList returns the cached listing of the folder as seen by the user,
+	// provided it was listed while the folder had the given etag.
+	GetList(userID, p, etag string) ([]*provider.ResourceInfo, error)
+	// SetList caches the listing of the folder with the given etag as seen by the user.
+	SetList(userID, p, etag string, infos []*provider.ResourceInfo) error
+	// GetEtag returns a cached etag and the time it was computed.
+	GetEtag(key string) (string, time.Time, error)
+	// SetEtag caches an etag computed at the given time.
+	SetEtag(key, etag string, ts time.Time) error
+	// Invalidate drops the stat results and listings of the paths for all users.
+	Invalidate(paths ...string) error
+	// InvalidateTree drops the stat results and listings of the paths and of
+	// all the paths below them for all users.
+	InvalidateTree(paths ...string) error
+	Close() error
+}
+
+// Ancestors returns the path and all its parents up to the root. A change to
+// a resource changes the etag and mtime of all of them.
+func Ancestors(p string) []string {
+	p = path.Clean(p)
+	paths := []string{p}
+	for p != "/" && p != "." {
+		p = path.Dir(p)
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// TreePrefix returns the prefix of the paths below p.
+func TreePrefix(p string) string {
+	p = path.Clean(p)
+	if p == "/" {
+		return p
+	}
+	return p + "/"
+}
+
+// InTree tells whether p is one of the roots or below one of them.
+func InTree(p string, roots ...string) bool {
+	for _, r := range roots {
+		if p == path.Clean(r) || strings.HasPrefix(p, TreePrefix(r)) {
+			return true
+		}
+	}
+	return false
+}
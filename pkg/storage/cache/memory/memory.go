@@ -0,0 +1,257 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package memory provides a stat cache local to the process.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/cache"
+	"github.com/cs3org/reva/pkg/storage/cache/registry"
+	"github.com/golang/protobuf/proto"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type config struct {
+	StatTTL int `mapstructure:"stat_ttl" docs:"0;Seconds a stat result is cached."`
+	ListTTL int `mapstructure:"list_ttl" docs:"0;Seconds a folder listing is cached."`
+	EtagTTL int `mapstructure:"etag_ttl" docs:"0;Seconds an etag is cached, 0 keeps it until it is overwritten."`
+}
+
+type statEntry struct {
+	info    *provider.ResourceInfo
+	expires time.Time
+}
+
+type listEntry struct {
+	etag    string
+	infos   []*provider.ResourceInfo
+	expires time.Time
+}
+
+type etagEntry struct {
+	etag    string
+	ts      time.Time
+	expires time.Time
+}
+
+// Cache is a stat cache kept in memory.
+type Cache struct {
+	statTTL, listTTL, etagTTL time.Duration
+
+	mu sync.RWMutex
+	// stats holds the stat results by path and user
+	stats map[string]map[string]statEntry
+	// lists holds the folder listings by path and user
+	lists map[string]map[string]listEntry
+	etags map[string]etagEntry
+}
+
+// New returns a new stat cache kept in memory.
+func New(m map[string]interface{}) (cache.Cache, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "memory: error decoding conf")
+	}
+	return NewCache(time.Duration(c.StatTTL)*time.Second, time.Duration(c.ListTTL)*time.Second, time.Duration(c.EtagTTL)*time.Second), nil
+}
+
+// NewCache returns a new stat cache with the given expiration times.
+func NewCache(statTTL, listTTL, etagTTL time.Duration) *Cache {
+	return &Cache{
+		statTTL: statTTL,
+		listTTL: listTTL,
+		etagTTL: etagTTL,
+		stats:   map[string]map[string]statEntry{},
+		lists:   map[string]map[string]listEntry{},
+		etags:   map[string]etagEntry{},
+	}
+}
+
+// GetStat returns the cached stat result of the path as seen by the user.
+func (c *Cache) GetStat(userID, p string) (*provider.ResourceInfo, error) {
+	c.mu.RLock()
+	e, ok := c.stats[p][userID]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(e.expires) {
+		return nil, errtypes.NotFound(p)
+	}
+	return proto.Clone(e.info).(*provider.ResourceInfo), nil
+}
+
+// SetStat caches the stat result of the path as seen by the user.
+func (c *Cache) SetStat(userID, p string, info *provider.ResourceInfo) error {
+	if c.statTTL <= 0 {
+		return nil
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.purge(now)
+	if c.stats[p] == nil {
+		c.stats[p] = map[string]statEntry{}
+	}
+	c.stats[p][userID] = statEntry{info: proto.Clone(info).(*provider.ResourceInfo), expires: now.Add(c.statTTL)}
+	return nil
+}
+
+// GetList returns the cached listing of the folder as seen by the user,
+// provided it was listed while the folder had the given etag.
+func (c *Cache) GetList(userID, p, etag string) ([]*provider.ResourceInfo, error) {
+	c.mu.RLock()
+	e, ok := c.lists[p][userID]
+	c.mu.RUnlock()
+	if !ok || e.etag != etag || time.Now().After(e.expires) {
+		return nil, errtypes.NotFound(p)
+	}
+	return cloneInfos(e.infos), nil
+}
+
+// SetList caches the listing of the folder with the given etag as seen by the user.
+func (c *Cache) SetList(userID, p, etag string, infos []*provider.ResourceInfo) error {
+	if c.listTTL <= 0 {
+		return nil
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.purge(now)
+	if c.lists[p] == nil {
+		c.lists[p] = map[string]listEntry{}
+	}
+	c.lists[p][userID] = listEntry{etag: etag, infos: cloneInfos(infos), expires: now.Add(c.listTTL)}
+	return nil
+}
+
+func cloneInfos(infos []*provider.ResourceInfo) []*provider.ResourceInfo {
+	clones := make([]*provider.ResourceInfo, 0, len(infos))
+	for _, info := range infos {
+		clones = append(clones, proto.Clone(info).(*provider.ResourceInfo))
+	}
+	return clones
+}
+
+// GetEtag returns a cached etag and the time it was computed.
+func (c *Cache) GetEtag(key string) (string, time.Time, error) {
+	c.mu.RLock()
+	e, ok := c.etags[key]
+	c.mu.RUnlock()
+	if !ok || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+		return "", time.Time{}, errtypes.NotFound(key)
+	}
+	return e.etag, e.ts, nil
+}
+
+// SetEtag caches an etag computed at the given time.
+func (c *Cache) SetEtag(key, etag string, ts time.Time) error {
+	e := etagEntry{etag: etag, ts: ts}
+	if c.etagTTL > 0 {
+		e.expires = time.Now().Add(c.etagTTL)
+	}
+	c.mu.Lock()
+	c.etags[key] = e
+	c.mu.Unlock()
+	return nil
+}
+
+// Invalidate drops the stat results and listings of the paths for all users.
+func (c *Cache) Invalidate(paths ...string) error {
+	c.mu.Lock()
+	for _, p := range paths {
+		delete(c.stats, p)
+		delete(c.lists, p)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// InvalidateTree drops the stat results and listings of the paths and of all
+// the paths below them for all users.
+func (c *Cache) InvalidateTree(paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	for p := range c.stats {
+		if cache.InTree(p, paths...) {
+			delete(c.stats, p)
+		}
+	}
+	for p := range c.lists {
+		if cache.InTree(p, paths...) {
+			delete(c.lists, p)
+		}
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// Reset drops all the entries.
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	c.stats = map[string]map[string]statEntry{}
+	c.lists = map[string]map[string]listEntry{}
+	c.etags = map[string]etagEntry{}
+	c.mu.Unlock()
+}
+
+// purge drops the expired entries once the cache has grown, must be called with the lock held.
+func (c *Cache) purge(now time.Time) {
+	if len(c.stats) < 10000 && len(c.lists) < 10000 && len(c.etags) < 10000 {
+		return
+	}
+	for p, users := range c.stats {
+		for u, e := range users {
+			if now.After(e.expires) {
+				delete(users, u)
+			}
+		}
+		if len(users) == 0 {
+			delete(c.stats, p)
+		}
+	}
+	for p, users := range c.lists {
+		for u, e := range users {
+			if now.After(e.expires) {
+				delete(users, u)
+			}
+		}
+		if len(users) == 0 {
+			delete(c.lists, p)
+		}
+	}
+	for k, e := range c.etags {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(c.etags, k)
+		}
+	}
+}
+
+// Close does nothing for the memory cache.
+func (c *Cache) Close() error {
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage/cache/memory"
+)
+
+func info(p string) *provider.ResourceInfo {
+	return &provider.ResourceInfo{Path: p, Etag: "etag-" + p}
+}
+
+func TestStat(t *testing.T) {
+	c := memory.NewCache(time.Minute, time.Minute, 0)
+	if err := c.SetStat("einstein", "/home", info("/home")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetStat("einstein", "/home")
+	if err != nil || got.Path != "/home" {
+		t.Fatalf("GetStat = %v, %v", got, err)
+	}
+	// the results are cloned
+	got.Path = "/changed"
+	if got, _ := c.GetStat("einstein", "/home"); got.Path != "/home" {
+		t.Errorf("cached result was modified: %v", got)
+	}
+	// the results are cached per user
+	if _, err := c.GetStat("marie", "/home"); err == nil {
+		t.Error("expected no result for another user")
+	}
+}
+
+func TestDisabledAndExpired(t *testing.T) {
+	c := memory.NewCache(0, 0, 0)
+	_ = c.SetStat("einstein", "/home", info("/home"))
+	_ = c.SetList("einstein", "/home", "e", []*provider.ResourceInfo{info("/home/a")})
+	if _, err := c.GetStat("einstein", "/home"); err == nil {
+		t.Error("expected no stat result with a zero ttl")
+	}
+	if _, err := c.GetList("einstein", "/home", "e"); err == nil {
+		t.Error("expected no listing with a zero ttl")
+	}
+
+	c = memory.NewCache(time.Millisecond, time.Millisecond, time.Millisecond)
+	_ = c.SetStat("einstein", "/home", info("/home"))
+	_ = c.SetEtag("k", "e", time.Now())
+	time.Sleep(5 * time.Millisecond)
+	if _, err := c.GetStat("einstein", "/home"); err == nil {
+		t.Error("expected the stat result to expire")
+	}
+	if _, _, err := c.GetEtag("k"); err == nil {
+		t.Error("expected the etag to expire")
+	}
+}
+
+func TestList(t *testing.T) {
+	c := memory.NewCache(time.Minute, time.Minute, 0)
+	infos := []*provider.ResourceInfo{info("/home/a"), info("/home/b")}
+	if err := c.SetList("einstein", "/home", "e1", infos); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetList("einstein", "/home", "e1")
+	if err != nil || len(got) != 2 || got[1].Path != "/home/b" {
+		t.Fatalf("GetList = %v, %v", got, err)
+	}
+	// the listing is only valid for the etag of the folder it was made with
+	if _, err := c.GetList("einstein", "/home", "e2"); err == nil {
+		t.Error("expected no listing for another etag")
+	}
+}
+
+func TestEtag(t *testing.T) {
+	c := memory.NewCache(0, 0, 0)
+	ts := time.Unix(1600000000, 0)
+	if err := c.SetEtag("k", "e", ts); err != nil {
+		t.Fatal(err)
+	}
+	etag, got, err := c.GetEtag("k")
+	if err != nil || etag != "e" || !got.Equal(ts) {
+		t.Errorf("GetEtag = %q, %v, %v", etag, got, err)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	paths := []string{"/", "/home", "/home/docs", "/home/docs/a.txt", "/home/docsets", "/other"}
+	tests := []struct {
+		name    string
+		tree    bool
+		paths   []string
+		dropped []string
+	}{
+		{"paths", false, []string{"/home", "/home/docs"}, []string{"/home", "/home/docs"}},
+		{"tree", true, []string{"/home/docs"}, []string{"/home/docs", "/home/docs/a.txt"}},
+		{"trees", true, []string{"/home/docs/", "/other"}, []string{"/home/docs", "/home/docs/a.txt", "/other"}},
+		{"root", true, []string{"/"}, paths},
+	}
+	for _, tt := range tests {
+		c := memory.NewCache(time.Minute, time.Minute, 0)
+		for _, p := range paths {
+			_ = c.SetStat("einstein", p, info(p))
+			_ = c.SetList("marie", p, "e", nil)
+		}
+
+		var err error
+		if tt.tree {
+			err = c.InvalidateTree(tt.paths...)
+		} else {
+			err = c.Invalidate(tt.paths...)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dropped := map[string]bool{}
+		for _, p := range tt.dropped {
+			dropped[p] = true
+		}
+		for _, p := range paths {
+			_, statErr := c.GetStat("einstein", p)
+			_, listErr := c.GetList("marie", p, "e")
+			if dropped[p] && (statErr == nil || listErr == nil) {
+				t.Errorf("%s: %s was not invalidated", tt.name, p)
+			}
+			if !dropped[p] && (statErr != nil || listErr != nil) {
+				t.Errorf("%s: %s was invalidated", tt.name, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,464 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package redis provides a stat cache stored in redis, shared by all the
+// service replicas using the same redis server. Every replica keeps the hot
+// entries in memory for a short time, invalidations are broadcast to the
+// other replicas over a redis pub/sub channel.
+package redis
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/cache"
+	"github.com/cs3org/reva/pkg/storage/cache/memory"
+	"github.com/cs3org/reva/pkg/storage/cache/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+func init() {
+	registry.Register("redis", New)
+}
+
+const (
+	statPrefix = "reva:stat:"
+	listPrefix = "reva:list:"
+	etagPrefix = "reva:etag:"
+)
+
+type config struct {
+	// The address at which the redis server is running
+	RedisAddress string `mapstructure:"redis_address" docs:"localhost:6379"`
+	// The username for connecting to the redis server
+	RedisUsername string `mapstructure:"redis_username" docs:""`
+	// The password for connecting to the redis server
+	RedisPassword string `mapstructure:"redis_password" docs:""`
+	// The channel used to broadcast invalidations
+	Channel string `mapstructure:"channel" docs:"reva:stat:invalidate"`
+	StatTTL int    `mapstructure:"stat_ttl" docs:"0;Seconds a stat result is cached."`
+	ListTTL int    `mapstructure:"list_ttl" docs:"0;Seconds a folder listing is cached."`
+	EtagTTL int    `mapstructure:"etag_ttl" docs:"0;Seconds an etag is cached, 0 keeps it until it is overwritten."`
+	// LocalTTL is the time in seconds a stat result or listing is kept in memory before asking redis again
+	LocalTTL int `mapstructure:"local_ttl" docs:"5"`
+}
+
+func (c *config) init() {
+	if c.RedisAddress == "" {
+		c.RedisAddress = "localhost:6379"
+	}
+	if c.Channel == "" {
+		c.Channel = "reva:stat:invalidate"
+	}
+	if c.LocalTTL == 0 {
+		c.LocalTTL = 5
+	}
+	if c.LocalTTL > c.StatTTL {
+		c.LocalTTL = c.StatTTL
+	}
+}
+
+type listEntry struct {
+	Etag  string            `json:"etag"`
+	Infos []json.RawMessage `json:"infos"`
+}
+
+// treeInvalidation is the message broadcast for InvalidateTree.
+type treeInvalidation struct {
+	Trees []string `json:"trees"`
+}
+
+type etagEntry struct {
+	Etag string    `json:"etag"`
+	TS   time.Time `json:"ts"`
+}
+
+type redisCache struct {
+	c     *config
+	pool  *redis.Pool
+	local *memory.Cache
+
+	mu     sync.Mutex
+	closed bool
+	psc    *redis.PubSubConn
+}
+
+// New returns a new stat cache stored in redis.
+func New(m map[string]interface{}) (cache.Cache, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "redis: error decoding conf")
+	}
+	c.init()
+
+	r := &redisCache{
+		c:     c,
+		pool:  initRedisPool(c.RedisAddress, c.RedisUsername, c.RedisPassword),
+		local: memory.NewCache(time.Duration(c.LocalTTL)*time.Second, time.Duration(localListTTL(c))*time.Second, 0),
+	}
+	go r.subscribe()
+	return r, nil
+}
+
+// localListTTL is the time in seconds a listing is kept in memory, which
+// is not longer than it is kept in redis.
+func localListTTL(c *config) int {
+	if c.LocalTTL > c.ListTTL {
+		return c.ListTTL
+	}
+	return c.LocalTTL
+}
+
+func initRedisPool(address, username, password string) *redis.Pool {
+	return &redis.Pool{
+
+		MaxIdle:     50,
+		MaxActive:   1000,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if username != "" {
+				opts = append(opts, redis.DialUsername(username))
+			}
+			if password != "" {
+				opts = append(opts, redis.DialPassword(password))
+			}
+			return redis.Dial("tcp", address, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
+
+// subscribe listens for invalidations published by the other replicas and
+// drops the affected entries from the memory cache, reconnecting until closed.
+func (r *redisCache) subscribe() {
+	for {
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return
+		}
+		psc := &redis.PubSubConn{Conn: r.pool.Get()}
+		r.psc = psc
+		r.mu.Unlock()
+
+		if err := psc.Subscribe(r.c.Channel); err != nil {
+			log.Error().Err(err).Str("channel", r.c.Channel).Msg("redis: error subscribing to invalidations")
+		} else {
+			r.receive(psc)
+		}
+		psc.Close()
+
+		// we may have missed invalidations, start over with an empty memory cache
+		r.local.Reset()
+		time.Sleep(time.Second)
+	}
+}
+
+func (r *redisCache) receive(psc *redis.PubSubConn) {
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			// the paths are sent as an array, the trees as an object
+			if bytes.HasPrefix(v.Data, []byte("[")) {
+				var paths []string
+				if err := json.Unmarshal(v.Data, &paths); err != nil {
+					log.Error().Err(err).Msg("redis: error decoding invalidation")
+					continue
+				}
+				_ = r.local.Invalidate(paths...)
+				continue
+			}
+			var inv treeInvalidation
+			if err := json.Unmarshal(v.Data, &inv); err != nil {
+				log.Error().Err(err).Msg("redis: error decoding invalidation")
+				continue
+			}
+			_ = r.local.InvalidateTree(inv.Trees...)
+		case error:
+			r.mu.Lock()
+			closed := r.closed
+			r.mu.Unlock()
+			if !closed {
+				log.Error().Err(v).Msg("redis: error receiving invalidations")
+			}
+			return
+		}
+	}
+}
+
+func (r *redisCache) GetStat(userID, p string) (*provider.ResourceInfo, error) {
+	if info, err := r.local.GetStat(userID, p); err == nil {
+		return info, nil
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+	b, err := redis.Bytes(conn.Do("HGET", statPrefix+p, userID))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, errtypes.NotFound(p)
+		}
+		return nil, err
+	}
+	info := &provider.ResourceInfo{}
+	if err := utils.UnmarshalJSONToProtoV1(b, info); err != nil {
+		return nil, err
+	}
+	_ = r.local.SetStat(userID, p, info)
+	return info, nil
+}
+
+func (r *redisCache) SetStat(userID, p string, info *provider.ResourceInfo) error {
+	if r.c.StatTTL <= 0 {
+		return nil
+	}
+	b, err := utils.MarshalProtoV1ToJSON(info)
+	if err != nil {
+		return err
+	}
+	conn := r.pool.Get()
+	defer conn.Close()
+	// the expiration applies to the stat results of all users for this path
+	if err := conn.Send("HSET", statPrefix+p, userID, b); err != nil {
+		return err
+	}
+	if _, err := conn.Do("EXPIRE", statPrefix+p, r.c.StatTTL); err != nil {
+		return err
+	}
+	return r.local.SetStat(userID, p, info)
+}
+
+func (r *redisCache) GetList(userID, p, etag string) ([]*provider.ResourceInfo, error) {
+	if infos, err := r.local.GetList(userID, p, etag); err == nil {
+		return infos, nil
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+	b, err := redis.Bytes(conn.Do("HGET", listPrefix+p, userID))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, errtypes.NotFound(p)
+		}
+		return nil, err
+	}
+	e := listEntry{}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	if e.Etag != etag {
+		return nil, errtypes.NotFound(p)
+	}
+	infos := make([]*provider.ResourceInfo, 0, len(e.Infos))
+	for _, raw := range e.Infos {
+		info := &provider.ResourceInfo{}
+		if err := utils.UnmarshalJSONToProtoV1(raw, info); err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	_ = r.local.SetList(userID, p, etag, infos)
+	return infos, nil
+}
+
+func (r *redisCache) SetList(userID, p, etag string, infos []*provider.ResourceInfo) error {
+	if r.c.ListTTL <= 0 {
+		return nil
+	}
+	e := listEntry{Etag: etag, Infos: make([]json.RawMessage, 0, len(infos))}
+	for _, info := range infos {
+		raw, err := utils.MarshalProtoV1ToJSON(info)
+		if err != nil {
+			return err
+		}
+		e.Infos = append(e.Infos, raw)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	conn := r.pool.Get()
+	defer conn.Close()
+	// the expiration applies to the listings of all users for this path
+	if err := conn.Send("HSET", listPrefix+p, userID, b); err != nil {
+		return err
+	}
+	if _, err := conn.Do("EXPIRE", listPrefix+p, r.c.ListTTL); err != nil {
+		return err
+	}
+	return r.local.SetList(userID, p, etag, infos)
+}
+
+func (r *redisCache) GetEtag(key string) (string, time.Time, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+	b, err := redis.Bytes(conn.Do("GET", etagPrefix+key))
+	if err != nil {
+		if err == redis.ErrNil {
+			return "", time.Time{}, errtypes.NotFound(key)
+		}
+		return "", time.Time{}, err
+	}
+	e := etagEntry{}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return "", time.Time{}, err
+	}
+	return e.Etag, e.TS, nil
+}
+
+func (r *redisCache) SetEtag(key, etag string, ts time.Time) error {
+	b, err := json.Marshal(etagEntry{Etag: etag, TS: ts})
+	if err != nil {
+		return err
+	}
+	conn := r.pool.Get()
+	defer conn.Close()
+	if r.c.EtagTTL > 0 {
+		_, err = conn.Do("SET", etagPrefix+key, b, "EX", r.c.EtagTTL)
+	} else {
+		_, err = conn.Do("SET", etagPrefix+key, b)
+	}
+	return err
+}
+
+func (r *redisCache) Invalidate(paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	_ = r.local.Invalidate(paths...)
+
+	b, err := json.Marshal(paths)
+	if err != nil {
+		return err
+	}
+	keys := make([]interface{}, 0, 2*len(paths))
+	for _, p := range paths {
+		keys = append(keys, statPrefix+p, listPrefix+p)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", keys...); err != nil {
+		return err
+	}
+	_, err = conn.Do("PUBLISH", r.c.Channel, b)
+	return err
+}
+
+func (r *redisCache) InvalidateTree(paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	_ = r.local.InvalidateTree(paths...)
+
+	b, err := json.Marshal(treeInvalidation{Trees: paths})
+	if err != nil {
+		return err
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+	keys := make([]interface{}, 0, 2*len(paths))
+	for _, p := range paths {
+		p = path.Clean(p)
+		keys = append(keys, statPrefix+p, listPrefix+p)
+		for _, prefix := range []string{statPrefix, listPrefix} {
+			found, err := scan(conn, treePattern(prefix, p))
+			if err != nil {
+				return err
+			}
+			keys = append(keys, found...)
+		}
+	}
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > scanCount {
+			n = scanCount
+		}
+		if _, err := conn.Do("DEL", keys[:n]...); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	_, err = conn.Do("PUBLISH", r.c.Channel, b)
+	return err
+}
+
+// scanCount is the number of keys asked for per SCAN call and deleted per DEL call.
+const scanCount = 1000
+
+// scan returns the keys matching the pattern.
+func scan(conn redis.Conn, pattern string) ([]interface{}, error) {
+	var keys []interface{}
+	cursor := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", scanCount))
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, errors.New("redis: unexpected SCAN reply")
+		}
+		if cursor, err = redis.Int(values[0], nil); err != nil {
+			return nil, err
+		}
+		found, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range found {
+			keys = append(keys, k)
+		}
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// treePattern returns the pattern matching the keys of the paths below p.
+func treePattern(prefix, p string) string {
+	return prefix + globEscaper.Replace(cache.TreePrefix(p)) + "*"
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+func (r *redisCache) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	if r.psc != nil {
+		_ = r.psc.Unsubscribe()
+	}
+	r.mu.Unlock()
+	return r.pool.Close()
+}
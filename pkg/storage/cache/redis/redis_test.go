@@ -0,0 +1,36 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package redis
+
+import "testing"
+
+func TestTreePattern(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/", statPrefix + "/*"},
+		{"/home/docs/", statPrefix + "/home/docs/*"},
+		{`/home/[a]*?\b`, statPrefix + `/home/\[a\]\*\?\\b/*`},
+	}
+	for _, tt := range tests {
+		if got := treePattern(statPrefix, tt.path); got != tt.want {
+			t.Errorf("treePattern(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package changes defines the change journals recording the changes made to
+// the resources, so that clients can ask for what changed since they last
+// synchronized instead of walking whole trees.
+package changes
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidToken is returned for the tokens which are not of the journal and
+// for those whose following changes are no longer kept.
+var ErrInvalidToken = errors.New("changes: invalid token")
+
+// Change is a change made to a resource.
+type Change struct {
+	// Seq is the position of the change in the journal, set when it is recorded.
+	Seq  uint64
+	Path string
+	// Deleted is set when the resource was removed.
+	Deleted bool
+	Time    time.Time
+}
+
+// Token is a position in a journal. The epoch identifies the journal, the
+// tokens of another journal or of a previous run of a journal kept in memory
+// being invalid.
+type Token struct {
+	Epoch string
+	Seq   uint64
+}
+
+// String returns the encoding of the token.
+func (t Token) String() string {
+	return t.Epoch + "-" + strconv.FormatUint(t.Seq, 10)
+}
+
+// ParseToken returns the token encoded by Token.String.
+func ParseToken(s string) (Token, error) {
+	i := strings.LastIndexByte(s, '-')
+	if i <= 0 {
+		return Token{}, ErrInvalidToken
+	}
+	seq, err := strconv.ParseUint(s[i+1:], 10, 64)
+	if err != nil {
+		return Token{}, ErrInvalidToken
+	}
+	return Token{Epoch: s[:i], Seq: seq}, nil
+}
+
+// Journal records the changes made to the resources, by path.
+type Journal interface {
+	// Record appends the changes to the journal.
+	Record(ctx context.Context, changes ...Change) error
+	// Current returns the token of the last recorded change.
+	Current(ctx context.Context) (Token, error)
+	// Since returns the changes made to root or below it after the token, in
+	// the order they were recorded. When limit is positive at most limit
+	// changes are returned, the returned token being the one to continue from.
+	Since(ctx context.Context, root string, token Token, limit int) ([]Change, Token, error)
+	Close() error
+}
+
+// Below tells if p is root or a path below it.
+func Below(p, root string) bool {
+	p, root = path.Clean(p), path.Clean(root)
+	return root == "/" || p == root || strings.HasPrefix(p, root+"/")
+}
+
+// NewEpoch returns a new epoch for the journals losing their changes when they
+// are restarted.
+func NewEpoch() string {
+	return fmt.Sprintf("%x", time.Now().UnixNano())
+}
@@ -0,0 +1,130 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package memory provides a change journal local to the process. Its tokens
+// are invalidated when the process restarts.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/storage/changes"
+	"github.com/cs3org/reva/pkg/storage/changes/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type config struct {
+	Capacity int `mapstructure:"capacity" docs:"100000;The number of changes kept, the tokens older than the oldest kept change are invalidated."`
+}
+
+func (c *config) init() {
+	if c.Capacity <= 0 {
+		c.Capacity = 100000
+	}
+}
+
+// Journal is a change journal kept in memory.
+type Journal struct {
+	epoch    string
+	capacity int
+
+	mu sync.RWMutex
+	// entries holds the kept changes, their seqs following each other
+	entries []changes.Change
+	// seq is the seq of the last recorded change, dropped the one of the last
+	// change dropped to keep the capacity
+	seq, dropped uint64
+}
+
+// New returns a new change journal kept in memory.
+func New(m map[string]interface{}) (changes.Journal, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "memory: error decoding conf")
+	}
+	c.init()
+	return NewJournal(c.Capacity), nil
+}
+
+// NewJournal returns a journal keeping the given number of changes.
+func NewJournal(capacity int) *Journal {
+	return &Journal{epoch: changes.NewEpoch(), capacity: capacity}
+}
+
+// Record implements changes.Journal.
+func (j *Journal) Record(ctx context.Context, cs ...changes.Change) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cs {
+		j.seq++
+		c.Seq = j.seq
+		if c.Time.IsZero() {
+			c.Time = time.Now()
+		}
+		j.entries = append(j.entries, c)
+	}
+	if n := len(j.entries) - j.capacity; n > 0 {
+		j.dropped = j.entries[n-1].Seq
+		// copy to let the dropped changes be collected
+		j.entries = append([]changes.Change(nil), j.entries[n:]...)
+	}
+	return nil
+}
+
+// Current implements changes.Journal.
+func (j *Journal) Current(ctx context.Context) (changes.Token, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return changes.Token{Epoch: j.epoch, Seq: j.seq}, nil
+}
+
+// Since implements changes.Journal.
+func (j *Journal) Since(ctx context.Context, root string, token changes.Token, limit int) ([]changes.Change, changes.Token, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if token.Epoch != j.epoch || token.Seq < j.dropped || token.Seq > j.seq {
+		return nil, changes.Token{}, changes.ErrInvalidToken
+	}
+
+	next := changes.Token{Epoch: j.epoch, Seq: j.seq}
+	var found []changes.Change
+	for i := int(token.Seq - j.dropped); i < len(j.entries); i++ {
+		c := j.entries[i]
+		if !changes.Below(c.Path, root) {
+			continue
+		}
+		if limit > 0 && len(found) == limit {
+			next.Seq = found[len(found)-1].Seq
+			break
+		}
+		found = append(found, c)
+	}
+	return found, next, nil
+}
+
+// Close implements changes.Journal.
+func (j *Journal) Close() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/storage/changes"
+)
+
+func paths(cs []changes.Change) []string {
+	var ps []string
+	for _, c := range cs {
+		ps = append(ps, c.Path)
+	}
+	return ps
+}
+
+func TestSince(t *testing.T) {
+	ctx := context.Background()
+	j := NewJournal(10)
+
+	start, _ := j.Current(ctx)
+	_ = j.Record(ctx,
+		changes.Change{Path: "/home/a/one"},
+		changes.Change{Path: "/home/b"},
+		changes.Change{Path: "/home/a/two", Deleted: true},
+		changes.Change{Path: "/home/ab"},
+	)
+
+	cs, next, err := j.Since(ctx, "/home/a", start, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(cs); len(got) != 2 || got[0] != "/home/a/one" || got[1] != "/home/a/two" || !cs[1].Deleted {
+		t.Errorf("got changes %v", got)
+	}
+	if current, _ := j.Current(ctx); next != current {
+		t.Errorf("got token %v, want the current one %v", next, current)
+	}
+
+	cs, next, err = j.Since(ctx, "/", start, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 3 || next.Seq != cs[2].Seq {
+		t.Errorf("got %v continuing from %v", paths(cs), next)
+	}
+	cs, _, err = j.Since(ctx, "/", next, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(cs); len(got) != 1 || got[0] != "/home/ab" {
+		t.Errorf("got remaining changes %v", got)
+	}
+}
+
+func TestInvalidTokens(t *testing.T) {
+	ctx := context.Background()
+	j := NewJournal(2)
+
+	start, _ := j.Current(ctx)
+	_ = j.Record(ctx, changes.Change{Path: "/a"}, changes.Change{Path: "/b"})
+	if _, _, err := j.Since(ctx, "/", start, 0); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the first change is dropped to keep the capacity
+	_ = j.Record(ctx, changes.Change{Path: "/c"})
+	if _, _, err := j.Since(ctx, "/", start, 0); err != changes.ErrInvalidToken {
+		t.Errorf("got %v for a token whose changes were dropped", err)
+	}
+	current, _ := j.Current(ctx)
+	if _, _, err := NewJournal(2).Since(ctx, "/", current, 0); err != changes.ErrInvalidToken {
+		t.Errorf("got %v for the token of another journal", err)
+	}
+
+	parsed, err := changes.ParseToken(current.String())
+	if err != nil || parsed != current {
+		t.Errorf("parsed %v %v, want %v", parsed, err, current)
+	}
+	if _, err := changes.ParseToken("nope"); err != changes.ErrInvalidToken {
+		t.Errorf("got %v parsing an invalid token", err)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// UploadConcatenator is implemented by drivers supporting the tus concatenation
+// extension for uploads initiated as partial, allowing clients to transfer the
+// chunks of a file in parallel.
+type UploadConcatenator interface {
+	// ConcatUploads assembles the given finished partial uploads, in the given
+	// order, into the resource they were initiated for and returns the id of the
+	// resulting final upload.
+	ConcatUploads(ctx context.Context, uploadIDs []string) (string, error)
+}
+
+// ConcatUploads assembles the uploads with the UploadConcatenator of fs.
+// Storage wrappers implement UploadConcatenator by calling it with the storage
+// they wrap, it returns an errtypes.NotSupported error when the driver can't
+// concatenate uploads.
+func ConcatUploads(ctx context.Context, fs FS, uploadIDs []string) (string, error) {
+	c, ok := fs.(UploadConcatenator)
+	if !ok {
+		return "", errtypes.NotSupported("upload concatenation")
+	}
+	return c.ConcatUploads(ctx, uploadIDs)
+}
@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// CopyOpaqueKey is the opaque entry that turns a MoveRequest into a
+// server-side copy: the source is kept and the destination is created.
+const CopyOpaqueKey = "Copy"
+
+// StreamCopy copies the resource referenced by src to the path referenced by dst
+// by streaming the content of every file through the given FS.
+// It is the default Copy implementation for drivers without a native copy.
+func StreamCopy(ctx context.Context, fs FS, src, dst *provider.Reference) error {
+	if dst.GetPath() == "" {
+		return errtypes.BadRequest("copy destination must be a path reference: " + dst.String())
+	}
+
+	md, err := fs.GetMD(ctx, src, nil)
+	if err != nil {
+		return err
+	}
+
+	if md.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && strings.HasPrefix(path.Clean(dst.GetPath())+"/", path.Clean(md.Path)+"/") {
+		return errtypes.BadRequest("cannot copy a folder into itself: " + md.Path)
+	}
+
+	return streamCopy(ctx, fs, md, dst.GetPath())
+}
+
+func streamCopy(ctx context.Context, fs FS, md *provider.ResourceInfo, dst string) error {
+	ref := &provider.Reference{
+		Spec: &provider.Reference_Id{Id: md.Id},
+	}
+
+	if md.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		if err := fs.CreateDir(ctx, dst); err != nil {
+			if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+				return err
+			}
+		}
+
+		children, err := fs.ListFolder(ctx, ref, nil)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := streamCopy(ctx, fs, child, path.Join(dst, path.Base(child.Path))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	r, err := fs.Download(ctx, ref)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return fs.Upload(ctx, &provider.Reference{Spec: &provider.Reference_Path{Path: dst}}, r)
+}
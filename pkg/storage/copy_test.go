@@ -0,0 +1,210 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// memFS keeps the files in memory, the folders have a nil content. The ids
+// of the resources are their paths.
+type memFS struct {
+	FS
+	files map[string][]byte
+}
+
+func newMemFS(files ...string) *memFS {
+	fs := &memFS{files: map[string][]byte{"/": nil}}
+	for _, f := range files {
+		if strings.HasSuffix(f, "/") {
+			fs.files[path.Clean(f)] = nil
+			continue
+		}
+		fs.files[f] = []byte(path.Base(f))
+	}
+	return fs
+}
+
+func (fs *memFS) path(ref *provider.Reference) string {
+	if ref.GetId() != nil {
+		return ref.GetId().OpaqueId
+	}
+	return ref.GetPath()
+}
+
+func (fs *memFS) info(fn string) *provider.ResourceInfo {
+	info := &provider.ResourceInfo{
+		Id:   &provider.ResourceId{StorageId: "mem", OpaqueId: fn},
+		Path: fn,
+		Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+	}
+	if fs.files[fn] == nil {
+		info.Type = provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	}
+	return info
+}
+
+func (fs *memFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	fn := fs.path(ref)
+	if _, ok := fs.files[fn]; !ok {
+		return nil, errtypes.NotFound(fn)
+	}
+	return fs.info(fn), nil
+}
+
+func (fs *memFS) CreateDir(ctx context.Context, fn string) error {
+	if _, ok := fs.files[fn]; ok {
+		return errtypes.AlreadyExists(fn)
+	}
+	if _, ok := fs.files[path.Dir(fn)]; !ok {
+		return errtypes.NotFound(path.Dir(fn))
+	}
+	fs.files[fn] = nil
+	return nil
+}
+
+func (fs *memFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	var infos []*provider.ResourceInfo
+	for fn := range fs.files {
+		if fn != "/" && path.Dir(fn) == fs.path(ref) {
+			infos = append(infos, fs.info(fn))
+		}
+	}
+	return infos, nil
+}
+
+func (fs *memFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(fs.files[fs.path(ref)])), nil
+}
+
+func (fs *memFS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	fs.files[fs.path(ref)] = b
+	return nil
+}
+
+// below returns the resources below the folder, their content is their name
+// in the source of the copy.
+func (fs *memFS) below(dir string) map[string]string {
+	files := map[string]string{}
+	for fn, c := range fs.files {
+		if strings.HasPrefix(fn, dir+"/") {
+			rel := strings.TrimPrefix(fn, dir+"/")
+			if c == nil {
+				rel += "/"
+			}
+			files[rel] = string(c)
+		}
+	}
+	return files
+}
+
+func pathRef(fn string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: fn}}
+}
+
+func TestStreamCopy(t *testing.T) {
+	ctx := context.Background()
+	fs := newMemFS("/docs/", "/docs/a.txt", "/docs/sub/", "/docs/sub/b.txt", "/docs/sub/empty/", "/other/", "/other/c.txt")
+
+	if err := StreamCopy(ctx, fs, pathRef("/docs"), pathRef("/copy")); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a.txt": "a.txt", "sub/": "", "sub/b.txt": "b.txt", "sub/empty/": ""}
+	if got := fs.below("/copy"); !reflect.DeepEqual(got, want) {
+		t.Errorf("the folder was copied as %v, wanted %v", got, want)
+	}
+	if got := fs.below("/docs"); !reflect.DeepEqual(got, want) {
+		t.Errorf("the source was changed to %v", got)
+	}
+
+	// a file is copied by id as well
+	id := &provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{StorageId: "mem", OpaqueId: "/other/c.txt"}}}
+	if err := StreamCopy(ctx, fs, id, pathRef("/copy/c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(fs.files["/copy/c.txt"]); got != "c.txt" {
+		t.Errorf("the file was copied as %q", got)
+	}
+
+	if err := StreamCopy(ctx, fs, pathRef("/missing"), pathRef("/copy2")); err == nil {
+		t.Error("the copy of a missing resource must fail")
+	}
+	if err := StreamCopy(ctx, fs, pathRef("/docs"), id); err == nil {
+		t.Error("the destination must be a path")
+	}
+}
+
+func TestStreamCopyIntoItself(t *testing.T) {
+	ctx := context.Background()
+	fs := newMemFS("/docs/", "/docs/a.txt", "/docs/sub/")
+
+	for _, dst := range []string{"/docs", "/docs/", "/docs/sub/copy"} {
+		err := StreamCopy(ctx, fs, pathRef("/docs"), pathRef(dst))
+		if _, ok := err.(errtypes.IsBadRequest); !ok {
+			t.Errorf("copying the folder to %s = %v, wanted a bad request", dst, err)
+		}
+	}
+	if _, ok := fs.files["/docs/sub/copy"]; ok {
+		t.Error("the folder was copied into itself")
+	}
+
+	// only folders contain the destination, nor does a sibling with the same prefix
+	if err := StreamCopy(ctx, fs, pathRef("/docs"), pathRef("/docs2")); err != nil {
+		t.Errorf("copying the folder to a sibling: %v", err)
+	}
+	if err := StreamCopy(ctx, fs, pathRef("/docs/a.txt"), pathRef("/docs/b.txt")); err != nil {
+		t.Errorf("copying a file next to itself: %v", err)
+	}
+}
+
+func TestStreamCopyOverwrite(t *testing.T) {
+	ctx := context.Background()
+	fs := newMemFS("/docs/", "/docs/a.txt", "/docs/sub/", "/docs/sub/b.txt", "/dst/", "/dst/a.txt", "/dst/kept.txt", "/dst/sub/")
+	fs.files["/dst/a.txt"] = []byte("old")
+
+	// the files are replaced, the folders merged
+	if err := StreamCopy(ctx, fs, pathRef("/docs"), pathRef("/dst")); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a.txt": "a.txt", "kept.txt": "kept.txt", "sub/": "", "sub/b.txt": "b.txt"}
+	if got := fs.below("/dst"); !reflect.DeepEqual(got, want) {
+		t.Errorf("the folder was copied as %v, wanted %v", got, want)
+	}
+
+	if err := StreamCopy(ctx, fs, pathRef("/docs/sub/b.txt"), pathRef("/dst/kept.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(fs.files["/dst/kept.txt"]); got != "b.txt" {
+		t.Errorf("the file was overwritten with %q", got)
+	}
+}
@@ -27,7 +27,9 @@ import (
 	_ "github.com/cs3org/reva/pkg/storage/fs/local"
 	_ "github.com/cs3org/reva/pkg/storage/fs/localhome"
 	_ "github.com/cs3org/reva/pkg/storage/fs/ocis"
+	_ "github.com/cs3org/reva/pkg/storage/fs/ocmreceived"
 	_ "github.com/cs3org/reva/pkg/storage/fs/owncloud"
+	_ "github.com/cs3org/reva/pkg/storage/fs/plugin"
 	_ "github.com/cs3org/reva/pkg/storage/fs/s3"
 	_ "github.com/cs3org/reva/pkg/storage/fs/s3ng"
 	// Add your own here
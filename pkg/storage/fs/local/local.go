@@ -31,8 +31,9 @@ func init() {
 }
 
 type config struct {
-	Root        string `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
-	ShareFolder string `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
+	Root             string `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
+	ShareFolder      string `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
+	PropagationDelay int    `mapstructure:"propagation_delay" docs:"0;Milliseconds during which the changes are batched before being propagated to the etags of their ancestors, 0 propagates them at once."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -53,9 +54,10 @@ func New(m map[string]interface{}) (storage.FS, error) {
 	}
 
 	conf := localfs.Config{
-		Root:        c.Root,
-		ShareFolder: c.ShareFolder,
-		DisableHome: true,
+		Root:             c.Root,
+		ShareFolder:      c.ShareFolder,
+		PropagationDelay: c.PropagationDelay,
+		DisableHome:      true,
 	}
 	return localfs.NewLocalFS(&conf)
 }
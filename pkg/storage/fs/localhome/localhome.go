@@ -31,9 +31,10 @@ func init() {
 }
 
 type config struct {
-	Root        string `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
-	ShareFolder string `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
-	UserLayout  string `mapstructure:"user_layout" docs:"{{.Username}};Template for user home directories"`
+	Root             string `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
+	ShareFolder      string `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
+	UserLayout       string `mapstructure:"user_layout" docs:"{{.Username}};Template for user home directories"`
+	PropagationDelay int    `mapstructure:"propagation_delay" docs:"0;Milliseconds during which the changes are batched before being propagated to the etags of their ancestors, 0 propagates them at once."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -54,9 +55,10 @@ func New(m map[string]interface{}) (storage.FS, error) {
 	}
 
 	conf := localfs.Config{
-		Root:        c.Root,
-		ShareFolder: c.ShareFolder,
-		UserLayout:  c.UserLayout,
+		Root:             c.Root,
+		ShareFolder:      c.ShareFolder,
+		PropagationDelay: c.PropagationDelay,
+		UserLayout:       c.UserLayout,
 	}
 	return localfs.NewLocalFS(&conf)
 }
@@ -19,9 +19,17 @@
 package ocis_test
 
 import (
+	"context"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/ocis"
+	ruser "github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/tests/helpers"
 
 	. "github.com/onsi/ginkgo"
@@ -35,7 +43,8 @@ var _ = Describe("Ocis", func() {
 	)
 
 	BeforeEach(func() {
-		tmpRoot, err := helpers.TempDir("reva-unit-tests-*-root")
+		var err error
+		tmpRoot, err = helpers.TempDir("reva-unit-tests-*-root")
 		Expect(err).ToNot(HaveOccurred())
 
 		options = map[string]interface{}{
@@ -57,4 +66,66 @@ var _ = Describe("Ocis", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
+
+	Describe("with deduplication", func() {
+		var (
+			ctx context.Context
+		)
+
+		ref := func(p string) *provider.Reference {
+			return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+		}
+		upload := func(fs storage.FS, p, content string) {
+			Expect(fs.Upload(ctx, ref(p), ioutil.NopCloser(strings.NewReader(content)))).To(Succeed())
+		}
+		// blobs returns the number of blobs in the blobstore
+		blobs := func() int {
+			n := 0
+			_ = filepath.Walk(filepath.Join(tmpRoot, "blobs"), func(p string, fi os.FileInfo, err error) error {
+				if err == nil && fi.Mode().IsRegular() {
+					n++
+				}
+				return nil
+			})
+			return n
+		}
+
+		BeforeEach(func() {
+			options["deduplication"] = true
+			options["user_layout"] = "{{.Id.OpaqueId}}"
+			ctx = ruser.ContextSetUser(context.Background(), &userpb.User{
+				Id:       &userpb.UserId{Idp: "idp", OpaqueId: "userid"},
+				Username: "username",
+			})
+		})
+
+		It("deletes the blobs of the purged files and revisions", func() {
+			fs, err := ocis.New(options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.CreateHome(ctx)).To(Succeed())
+			Expect(fs.CreateDir(ctx, "/dir")).To(Succeed())
+
+			upload(fs, "/dir/a", "same content")
+			upload(fs, "/b", "same content")
+			Expect(blobs()).To(Equal(1))
+			// the first version of /dir/a becomes a revision
+			upload(fs, "/dir/a", "new content")
+			Expect(blobs()).To(Equal(2))
+			revs, err := fs.ListRevisions(ctx, ref("/dir/a"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revs).To(HaveLen(1))
+
+			Expect(fs.Delete(ctx, ref("/dir"))).To(Succeed())
+			Expect(fs.Delete(ctx, ref("/b"))).To(Succeed())
+			Expect(blobs()).To(Equal(2))
+
+			items, err := fs.ListRecycle(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(items).To(HaveLen(2))
+			for _, item := range items {
+				Expect(fs.PurgeRecycleItem(ctx, item.Key)).To(Succeed())
+			}
+			Expect(blobs()).To(Equal(0))
+		})
+	})
 })
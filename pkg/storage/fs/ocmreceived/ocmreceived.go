@@ -0,0 +1,549 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package ocmreceived implements a storage driver exposing the OCM shares
+// accepted by the user as the folders of its root, whose content is read and
+// written through the webdav endpoints of the providers of the shares.
+package ocmreceived
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/studio-b12/gowebdav"
+)
+
+func init() {
+	registry.Register("ocmreceived", New)
+}
+
+type config struct {
+	GatewaySvc string `mapstructure:"gatewaysvc" docs:";The gateway listing the OCM shares received by the users and resolving the webdav endpoints of their providers."`
+	Insecure   bool   `mapstructure:"insecure" docs:"false;Whether to skip the verification of the certificates of the webdav endpoints."`
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+type ocmfs struct {
+	conf   *config
+	client *http.Client
+}
+
+// New returns an implementation of the storage.FS interface that exposes the
+// OCM shares accepted by the user.
+func New(m map[string]interface{}) (storage.FS, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+
+	return &ocmfs{
+		conf:   c,
+		client: rhttp.GetHTTPClient(rhttp.Insecure(c.Insecure)),
+	}, nil
+}
+
+// remote is a path within an accepted share, resolved to the webdav endpoint
+// of the provider of the share.
+type remote struct {
+	share  *ocm.Share
+	client *gowebdav.Client
+	// fn is the path in the storage, name the path at the webdav endpoint
+	fn, name string
+}
+
+// shares returns the OCM shares accepted by the user, by the name of the
+// folders they are exposed at. The first share with a name wins.
+func (fs *ocmfs) shares(ctx context.Context) (map[string]*ocm.Share, []string, error) {
+	client, err := pool.GetGatewayServiceClient(fs.conf.GatewaySvc)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "ocmreceived: error getting gateway client")
+	}
+	res, err := client.ListReceivedOCMShares(ctx, &ocm.ListReceivedOCMSharesRequest{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "ocmreceived: error listing received shares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, nil, status.NewErrorFromCode(res.Status.Code, "ocmreceived")
+	}
+
+	shares := map[string]*ocm.Share{}
+	names := []string{}
+	for _, rs := range res.Shares {
+		if rs.State != ocm.ShareState_SHARE_STATE_ACCEPTED || rs.Share.ShareType == ocm.Share_SHARE_TYPE_TRANSFER {
+			continue
+		}
+		name := path.Base(rs.Share.Name)
+		if _, ok := shares[name]; ok {
+			appctx.GetLogger(ctx).Warn().Str("share", rs.Share.Id.GetOpaqueId()).Str("name", name).
+				Msg("ocmreceived: hiding the share with the name of another one")
+			continue
+		}
+		shares[name] = rs.Share
+		names = append(names, name)
+	}
+	return shares, names, nil
+}
+
+// resolve returns the path of the reference in the storage.
+func (fs *ocmfs) resolve(ref *provider.Reference) (string, error) {
+	if ref.GetPath() != "" {
+		return path.Join("/", ref.GetPath()), nil
+	}
+	if ref.GetId() != nil {
+		fn, err := url.QueryUnescape(strings.TrimPrefix(ref.GetId().OpaqueId, "fileid-"))
+		if err != nil {
+			return "", errtypes.BadRequest("ocmreceived: invalid resource id " + ref.GetId().OpaqueId)
+		}
+		return path.Join("/", fn), nil
+	}
+	return "", errtypes.BadRequest("ocmreceived: invalid reference " + ref.String())
+}
+
+// remote returns the remote resource a path within a share is at, an error if
+// the path is the root.
+func (fs *ocmfs) remote(ctx context.Context, fn string) (*remote, error) {
+	parts := strings.SplitN(strings.TrimPrefix(fn, "/"), "/", 2)
+	if parts[0] == "" {
+		return nil, errtypes.PermissionDenied("ocmreceived: the root only holds the received shares")
+	}
+	shares, _, err := fs.shares(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := shares[parts[0]]
+	if !ok {
+		return nil, errtypes.NotFound(fn)
+	}
+	return fs.remoteOf(ctx, s, fn)
+}
+
+func (fs *ocmfs) remoteOf(ctx context.Context, s *ocm.Share, fn string) (*remote, error) {
+	t, ok := s.Grantee.GetOpaque().GetMap()["token"]
+	if !ok || t.Decoder != "plain" {
+		return nil, errtypes.NotFound("ocmreceived: token of the share " + s.Id.GetOpaqueId())
+	}
+	endpoint, err := fs.webdavEndpoint(ctx, s.Creator.GetIdp())
+	if err != nil {
+		return nil, err
+	}
+
+	c := gowebdav.NewClient(endpoint, "", "")
+	c.SetTransport(fs.client.Transport)
+	c.SetHeader(token.TokenHeader, string(t.Value))
+
+	parts := strings.SplitN(strings.TrimPrefix(fn, "/"), "/", 2)
+	name := s.Name
+	if len(parts) == 2 {
+		name = path.Join(name, parts[1])
+	}
+	return &remote{share: s, client: c, fn: fn, name: name}, nil
+}
+
+func (fs *ocmfs) webdavEndpoint(ctx context.Context, domain string) (string, error) {
+	client, err := pool.GetGatewayServiceClient(fs.conf.GatewaySvc)
+	if err != nil {
+		return "", errors.Wrap(err, "ocmreceived: error getting gateway client")
+	}
+	res, err := client.GetInfoByDomain(ctx, &ocmprovider.GetInfoByDomainRequest{Domain: domain})
+	if err != nil {
+		return "", errors.Wrap(err, "ocmreceived: error getting the info of provider "+domain)
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return "", status.NewErrorFromCode(res.Status.Code, "ocmreceived")
+	}
+	for _, s := range res.ProviderInfo.Services {
+		if strings.ToLower(s.Endpoint.Type.Name) == "webdav" {
+			return s.Endpoint.Path, nil
+		}
+	}
+	return "", errtypes.NotFound("ocmreceived: webdav endpoint of provider " + domain)
+}
+
+// convertError converts the errors of the webdav requests to the errors of
+// the storage drivers.
+func convertError(err error, fn string) error {
+	if pe, ok := err.(*os.PathError); ok {
+		switch pe.Err.Error() {
+		case "404":
+			return errtypes.NotFound(fn)
+		case "401", "403":
+			return errtypes.PermissionDenied(fn)
+		case "507":
+			return errtypes.InsufficientStorage(fn)
+		}
+	}
+	return errors.Wrap(err, "ocmreceived: error accessing "+fn+" at the remote provider")
+}
+
+func (fs *ocmfs) rootInfo() *provider.ResourceInfo {
+	return &provider.ResourceInfo{
+		Id:       &provider.ResourceId{OpaqueId: "fileid-" + url.QueryEscape("/")},
+		Path:     "/",
+		Type:     provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+		MimeType: mime.Detect(true, "/"),
+		PermissionSet: &provider.ResourcePermissions{
+			GetPath:       true,
+			ListContainer: true,
+			Stat:          true,
+		},
+		Mtime: &types.Timestamp{},
+	}
+}
+
+func (r *remote) info(fi os.FileInfo, fn string) *provider.ResourceInfo {
+	var etag string
+	switch f := fi.(type) {
+	case *gowebdav.File:
+		etag = f.ETag()
+	case gowebdav.File:
+		etag = f.ETag()
+	}
+
+	perms := r.share.Permissions.GetPermissions()
+	if perms == nil {
+		perms = &provider.ResourcePermissions{GetPath: true, InitiateFileDownload: true, ListContainer: true, Stat: true}
+	}
+
+	return &provider.ResourceInfo{
+		Id:            &provider.ResourceId{OpaqueId: "fileid-" + url.QueryEscape(fn)},
+		Path:          fn,
+		Type:          getResourceType(fi.IsDir()),
+		Etag:          etag,
+		MimeType:      mime.Detect(fi.IsDir(), fn),
+		Size:          uint64(fi.Size()),
+		PermissionSet: perms,
+		Owner:         r.share.Owner,
+		Mtime: &types.Timestamp{
+			Seconds: uint64(fi.ModTime().Unix()),
+		},
+	}
+}
+
+func getResourceType(isDir bool) provider.ResourceType {
+	if isDir {
+		return provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	}
+	return provider.ResourceType_RESOURCE_TYPE_FILE
+}
+
+func (fs *ocmfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	fn, err := fs.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	if fn == "/" {
+		return fs.rootInfo(), nil
+	}
+
+	r, err := fs.remote(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := r.client.Stat(r.name)
+	if err != nil {
+		return nil, convertError(err, fn)
+	}
+	return r.info(fi, fn), nil
+}
+
+func (fs *ocmfs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	fn, err := fs.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if fn == "/" {
+		shares, names, err := fs.shares(ctx)
+		if err != nil {
+			return nil, err
+		}
+		infos := []*provider.ResourceInfo{}
+		for _, name := range names {
+			r, err := fs.remoteOf(ctx, shares[name], "/"+name)
+			if err == nil {
+				var fi os.FileInfo
+				if fi, err = r.client.Stat(r.name); err == nil {
+					infos = append(infos, r.info(fi, r.fn))
+					continue
+				}
+			}
+			// an unreachable provider does not prevent listing the other shares
+			appctx.GetLogger(ctx).Error().Err(err).Str("share", shares[name].Id.GetOpaqueId()).
+				Msg("ocmreceived: error statting the received share")
+		}
+		return infos, nil
+	}
+
+	r, err := fs.remote(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	fis, err := r.client.ReadDir(r.name)
+	if err != nil {
+		return nil, convertError(err, fn)
+	}
+	infos := make([]*provider.ResourceInfo, 0, len(fis))
+	for _, fi := range fis {
+		infos = append(infos, r.info(fi, path.Join(fn, fi.Name())))
+	}
+	return infos, nil
+}
+
+func (fs *ocmfs) CreateDir(ctx context.Context, fn string) error {
+	fn = path.Join("/", fn)
+	if path.Dir(fn) == "/" {
+		return errtypes.PermissionDenied("ocmreceived: the root only holds the received shares")
+	}
+	r, err := fs.remote(ctx, fn)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Mkdir(r.name, 0700); err != nil {
+		return convertError(err, fn)
+	}
+	return nil
+}
+
+func (fs *ocmfs) Delete(ctx context.Context, ref *provider.Reference) error {
+	fn, err := fs.resolve(ref)
+	if err != nil {
+		return err
+	}
+	if path.Dir(fn) == "/" {
+		return errtypes.PermissionDenied("ocmreceived: the received shares are removed by declining them")
+	}
+	r, err := fs.remote(ctx, fn)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Remove(r.name); err != nil {
+		return convertError(err, fn)
+	}
+	return nil
+}
+
+// sameShare resolves the references of a move or copy, which cannot cross
+// the shares.
+func (fs *ocmfs) sameShare(ctx context.Context, src, dst *provider.Reference) (*remote, *remote, error) {
+	srcFn, err := fs.resolve(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	dstFn, err := fs.resolve(dst)
+	if err != nil {
+		return nil, nil, err
+	}
+	if path.Dir(srcFn) == "/" || path.Dir(dstFn) == "/" {
+		return nil, nil, errtypes.PermissionDenied("ocmreceived: the root only holds the received shares")
+	}
+
+	s, err := fs.remote(ctx, srcFn)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := fs.remote(ctx, dstFn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.share.Id.GetOpaqueId() != d.share.Id.GetOpaqueId() {
+		return nil, nil, errtypes.NotSupported("ocmreceived: moving or copying across the received shares")
+	}
+	return s, d, nil
+}
+
+func (fs *ocmfs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	s, d, err := fs.sameShare(ctx, oldRef, newRef)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Rename(s.name, d.name, false); err != nil {
+		return convertError(err, s.fn)
+	}
+	return nil
+}
+
+func (fs *ocmfs) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	s, d, err := fs.sameShare(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Copy(s.name, d.name, false); err != nil {
+		return convertError(err, s.fn)
+	}
+	return nil
+}
+
+// InitiateUpload returns upload ids corresponding to different protocols it supports
+func (fs *ocmfs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	fn, err := fs.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	if path.Dir(fn) == "/" {
+		return nil, errtypes.PermissionDenied("ocmreceived: the root only holds the received shares")
+	}
+	return map[string]string{
+		"simple": fn,
+	}, nil
+}
+
+func (fs *ocmfs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	defer r.Close()
+	fn, err := fs.resolve(ref)
+	if err != nil {
+		return err
+	}
+	if path.Dir(fn) == "/" {
+		return errtypes.PermissionDenied("ocmreceived: the root only holds the received shares")
+	}
+	rem, err := fs.remote(ctx, fn)
+	if err != nil {
+		return err
+	}
+	if err := rem.client.WriteStream(rem.name, r, 0644); err != nil {
+		return convertError(err, fn)
+	}
+	return nil
+}
+
+func (fs *ocmfs) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	fn, err := fs.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	r, err := fs.remote(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := r.client.ReadStream(r.name)
+	if err != nil {
+		return nil, convertError(err, fn)
+	}
+	return rc, nil
+}
+
+func (fs *ocmfs) GetPathByID(ctx context.Context, id *provider.ResourceId) (string, error) {
+	return fs.resolve(&provider.Reference{Spec: &provider.Reference_Id{Id: id}})
+}
+
+func (fs *ocmfs) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	return storage.WalkRecursiveSize(ctx, fs, ref)
+}
+
+func (fs *ocmfs) GetHome(ctx context.Context) (string, error) {
+	return "", errtypes.NotSupported("ocmreceived: the received shares have no home")
+}
+
+func (fs *ocmfs) CreateHome(ctx context.Context) error {
+	return errtypes.NotSupported("ocmreceived: the received shares have no home")
+}
+
+func (fs *ocmfs) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	return nil, errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	return nil, errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	return errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) ListRecycle(ctx context.Context) ([]*provider.RecycleItem, error) {
+	return nil, errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	return errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) PurgeRecycleItem(ctx context.Context, key string) error {
+	return errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) EmptyRecycle(ctx context.Context) error {
+	return errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return errtypes.NotSupported("ocmreceived: the received shares cannot be shared again")
+}
+
+func (fs *ocmfs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return errtypes.NotSupported("ocmreceived: the received shares cannot be shared again")
+}
+
+func (fs *ocmfs) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return errtypes.NotSupported("ocmreceived: the received shares cannot be shared again")
+}
+
+func (fs *ocmfs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	return []*provider.Grant{}, nil
+}
+
+func (fs *ocmfs) GetQuota(ctx context.Context) (uint64, uint64, error) {
+	return 0, 0, errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) CreateReference(ctx context.Context, path string, targetURI *url.URL) error {
+	return errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	return errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	return errtypes.NotSupported("ocmreceived: operation not supported")
+}
+
+func (fs *ocmfs) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -219,6 +219,20 @@ func (fs *ocfs) Shutdown(ctx context.Context) error {
 	return fs.pool.Close()
 }
 
+// Check tells whether the data directory and the redis server holding the
+// file ids can be used.
+func (fs *ocfs) Check(ctx context.Context) error {
+	if _, err := os.Stat(fs.c.DataDirectory); err != nil {
+		return errors.Wrap(err, "ocfs: error accessing data directory")
+	}
+	c := fs.pool.Get()
+	defer c.Close()
+	if _, err := c.Do("PING"); err != nil {
+		return errors.Wrap(err, "ocfs: error pinging redis")
+	}
+	return nil
+}
+
 // scan files and add uuid to path mapping to kv store
 func (fs *ocfs) scanFiles(ctx context.Context, conn redis.Conn) {
 	if fs.c.Scan {
@@ -1118,6 +1132,10 @@ func (fs *ocfs) GetQuota(ctx context.Context) (uint64, uint64, error) {
 	return 0, 0, nil
 }
 
+func (fs *ocfs) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	return storage.WalkRecursiveSize(ctx, fs, ref)
+}
+
 func (fs *ocfs) CreateHome(ctx context.Context) error {
 	u, ok := user.ContextGetUser(ctx)
 	if !ok {
@@ -1619,6 +1637,10 @@ func (fs *ocfs) Move(ctx context.Context, oldRef, newRef *provider.Reference) (e
 	return nil
 }
 
+func (fs *ocfs) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	return storage.StreamCopy(ctx, fs, src, dst)
+}
+
 func (fs *ocfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
 	ip, err := fs.resolve(ctx, ref)
 	if err != nil {
@@ -0,0 +1,395 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package plugin provides a storage driver served by an external binary. The
+// binary creates its driver with the configuration of this one and serves it
+// with Serve:
+//
+//	func main() {
+//		plugin.Serve(myfs.New)
+//	}
+//
+// Only the methods of storage.FS are served, the optional interfaces of the
+// drivers are not. The contents of the uploads and downloads are streamed in
+// chunks.
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	revaplugin "github.com/cs3org/reva/pkg/plugin"
+	pluginpb "github.com/cs3org/reva/pkg/plugin/proto"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const kind = "storage"
+
+// chunkSize is the size of the chunks the contents are streamed in, below
+// the default limit of the gRPC messages.
+const chunkSize = 1 << 20
+
+func init() {
+	registry.Register("plugin", New)
+}
+
+type config struct {
+	// Path is the binary of the plugin.
+	Path string `mapstructure:"path"`
+	// Config is the configuration the plugin creates its driver with.
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// New starts the plugin and returns the storage driver it serves.
+func New(m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	p, err := revaplugin.Load(kind, c.Path, &Plugin{})
+	if err != nil {
+		return nil, err
+	}
+	fs := p.Raw.(*grpcClient)
+	fs.plugin = p
+	if err := fs.configure(c.Config); err != nil {
+		p.Kill()
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Serve serves the storage driver created by the given function from a
+// plugin binary.
+func Serve(f registry.NewFunc) {
+	revaplugin.Serve(kind, &Plugin{New: f})
+}
+
+// Plugin is the go-plugin of the storage drivers.
+type Plugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	// New creates the storage driver on the plugin side.
+	New registry.NewFunc
+}
+
+// GRPCServer registers the server of the plugin binary.
+func (p *Plugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	pluginpb.RegisterStoragePluginServer(s, &grpcServer{new: p.New})
+	return nil
+}
+
+// GRPCClient returns the storage driver calling the plugin binary.
+func (p *Plugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{client: pluginpb.NewStoragePluginClient(c)}, nil
+}
+
+type grpcClient struct {
+	client pluginpb.StoragePluginClient
+	plugin *revaplugin.Plugin
+}
+
+func (c *grpcClient) configure(m map[string]interface{}) error {
+	config, err := revaplugin.EncodeConfig(m)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Configure(context.Background(), &pluginpb.ConfigureRequest{Config: config})
+	return err
+}
+
+func (c *grpcClient) GetHome(ctx context.Context) (string, error) {
+	res, err := c.client.GetHome(ctx, &pluginpb.GetHomeRequest{})
+	if err != nil {
+		return "", err
+	}
+	return res.Path, nil
+}
+
+func (c *grpcClient) CreateHome(ctx context.Context) error {
+	_, err := c.client.CreateHome(ctx, &pluginpb.CreateHomeRequest{})
+	return err
+}
+
+func (c *grpcClient) CreateDir(ctx context.Context, fn string) error {
+	_, err := c.client.CreateDir(ctx, &pluginpb.CreateDirRequest{Path: fn})
+	return err
+}
+
+func (c *grpcClient) Delete(ctx context.Context, ref *provider.Reference) error {
+	_, err := c.client.Delete(ctx, &pluginpb.DeleteRequest{Ref: ref})
+	return err
+}
+
+func (c *grpcClient) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	_, err := c.client.Move(ctx, &pluginpb.MoveRequest{Source: oldRef, Destination: newRef})
+	return err
+}
+
+func (c *grpcClient) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	_, err := c.client.Copy(ctx, &pluginpb.CopyRequest{Source: src, Destination: dst})
+	return err
+}
+
+func (c *grpcClient) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	res, err := c.client.GetMD(ctx, &pluginpb.GetMDRequest{Ref: ref, ArbitraryMetadataKeys: mdKeys})
+	if err != nil {
+		return nil, err
+	}
+	return res.Info, nil
+}
+
+func (c *grpcClient) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	res, err := c.client.ListFolder(ctx, &pluginpb.ListFolderRequest{Ref: ref, ArbitraryMetadataKeys: mdKeys})
+	if err != nil {
+		return nil, err
+	}
+	return res.Infos, nil
+}
+
+func (c *grpcClient) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	res, err := c.client.InitiateUpload(ctx, &pluginpb.InitiateUploadRequest{Ref: ref, UploadLength: uploadLength, Metadata: metadata})
+	if err != nil {
+		return nil, err
+	}
+	return res.Params, nil
+}
+
+// Upload streams the content to the plugin in chunks, the first one carries
+// the reference.
+func (c *grpcClient) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	defer r.Close()
+	// cancelling the stream keeps the plugin from finishing the upload when
+	// the content can't be read to the end
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.Upload(ctx)
+	if err != nil {
+		return revaplugin.DecodeError(err)
+	}
+
+	req := &pluginpb.UploadRequest{Ref: ref}
+	buf := make([]byte, chunkSize)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 || (rerr == io.EOF && req.Ref != nil) {
+			req.Data = buf[:n]
+			if err := stream.Send(req); err != nil {
+				if err == io.EOF {
+					// the plugin ended the call, its status tells why
+					break
+				}
+				return revaplugin.DecodeError(err)
+			}
+			req = &pluginpb.UploadRequest{}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return errors.Wrap(rerr, "plugin: error reading the upload")
+		}
+	}
+	_, err = stream.CloseAndRecv()
+	return revaplugin.DecodeError(err)
+}
+
+func (c *grpcClient) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.client.Download(ctx, &pluginpb.DownloadRequest{Ref: ref})
+	if err != nil {
+		cancel()
+		return nil, revaplugin.DecodeError(err)
+	}
+	return newDownload(stream, cancel)
+}
+
+func (c *grpcClient) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	res, err := c.client.ListRevisions(ctx, &pluginpb.ListRevisionsRequest{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	return res.Revisions, nil
+}
+
+func (c *grpcClient) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.client.DownloadRevision(ctx, &pluginpb.DownloadRevisionRequest{Ref: ref, Key: key})
+	if err != nil {
+		cancel()
+		return nil, revaplugin.DecodeError(err)
+	}
+	return newDownload(stream, cancel)
+}
+
+func (c *grpcClient) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	_, err := c.client.RestoreRevision(ctx, &pluginpb.RestoreRevisionRequest{Ref: ref, Key: key})
+	return err
+}
+
+func (c *grpcClient) ListRecycle(ctx context.Context) ([]*provider.RecycleItem, error) {
+	res, err := c.client.ListRecycle(ctx, &pluginpb.ListRecycleRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func (c *grpcClient) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	_, err := c.client.RestoreRecycleItem(ctx, &pluginpb.RestoreRecycleItemRequest{Key: key, RestorePath: restorePath})
+	return err
+}
+
+func (c *grpcClient) PurgeRecycleItem(ctx context.Context, key string) error {
+	_, err := c.client.PurgeRecycleItem(ctx, &pluginpb.PurgeRecycleItemRequest{Key: key})
+	return err
+}
+
+func (c *grpcClient) EmptyRecycle(ctx context.Context) error {
+	_, err := c.client.EmptyRecycle(ctx, &pluginpb.EmptyRecycleRequest{})
+	return err
+}
+
+func (c *grpcClient) GetPathByID(ctx context.Context, id *provider.ResourceId) (string, error) {
+	res, err := c.client.GetPathByID(ctx, &pluginpb.GetPathByIDRequest{Id: id})
+	if err != nil {
+		return "", err
+	}
+	return res.Path, nil
+}
+
+func (c *grpcClient) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	_, err := c.client.AddGrant(ctx, &pluginpb.GrantRequest{Ref: ref, Grant: g})
+	return err
+}
+
+func (c *grpcClient) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	_, err := c.client.RemoveGrant(ctx, &pluginpb.GrantRequest{Ref: ref, Grant: g})
+	return err
+}
+
+func (c *grpcClient) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	_, err := c.client.UpdateGrant(ctx, &pluginpb.GrantRequest{Ref: ref, Grant: g})
+	return err
+}
+
+func (c *grpcClient) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	res, err := c.client.ListGrants(ctx, &pluginpb.ListGrantsRequest{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	return res.Grants, nil
+}
+
+func (c *grpcClient) GetQuota(ctx context.Context) (uint64, uint64, error) {
+	res, err := c.client.GetQuota(ctx, &pluginpb.GetQuotaRequest{})
+	if err != nil {
+		return 0, 0, err
+	}
+	return res.TotalBytes, res.UsedBytes, nil
+}
+
+func (c *grpcClient) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	res, err := c.client.GetRecursiveSize(ctx, &pluginpb.GetRecursiveSizeRequest{Ref: ref})
+	if err != nil {
+		return 0, 0, err
+	}
+	return res.Size, res.Files, nil
+}
+
+func (c *grpcClient) CreateReference(ctx context.Context, path string, targetURI *url.URL) error {
+	req := &pluginpb.CreateReferenceRequest{Path: path}
+	if targetURI != nil {
+		req.TargetUri = targetURI.String()
+	}
+	_, err := c.client.CreateReference(ctx, req)
+	return err
+}
+
+// Shutdown shuts the driver of the plugin down and stops the plugin.
+func (c *grpcClient) Shutdown(ctx context.Context) error {
+	_, err := c.client.Shutdown(ctx, &pluginpb.ShutdownRequest{})
+	c.plugin.Kill()
+	return err
+}
+
+func (c *grpcClient) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	_, err := c.client.SetArbitraryMetadata(ctx, &pluginpb.SetArbitraryMetadataRequest{Ref: ref, ArbitraryMetadata: md})
+	return err
+}
+
+func (c *grpcClient) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	_, err := c.client.UnsetArbitraryMetadata(ctx, &pluginpb.UnsetArbitraryMetadataRequest{Ref: ref, ArbitraryMetadataKeys: keys})
+	return err
+}
+
+// downloadStream is the stream of Download and DownloadRevision.
+type downloadStream interface {
+	Recv() (*pluginpb.DownloadResponse, error)
+}
+
+// download reads the content of a download from its stream.
+type download struct {
+	stream downloadStream
+	cancel context.CancelFunc
+	buf    []byte
+}
+
+// newDownload waits for the first message of the stream, which the plugin
+// only sends once the driver started the download, so that the errors of
+// the driver are returned before the content is read.
+func newDownload(stream downloadStream, cancel context.CancelFunc) (io.ReadCloser, error) {
+	res, err := stream.Recv()
+	if err != nil {
+		cancel()
+		if err == io.EOF {
+			return nil, errors.New("plugin: download ended without content")
+		}
+		return nil, revaplugin.DecodeError(err)
+	}
+	return &download{stream: stream, cancel: cancel, buf: res.Data}, nil
+}
+
+func (d *download) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		res, err := d.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, revaplugin.DecodeError(err)
+		}
+		d.buf = res.Data
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// Close ends the stream, the plugin stops sending the rest of the content.
+func (d *download) Close() error {
+	d.cancel()
+	return nil
+}
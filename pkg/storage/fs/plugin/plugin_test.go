@@ -0,0 +1,133 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	revaplugin "github.com/cs3org/reva/pkg/plugin"
+	pluginpb "github.com/cs3org/reva/pkg/plugin/proto"
+	"github.com/cs3org/reva/pkg/storage"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// driver keeps the uploaded files in memory.
+type driver struct {
+	storage.FS
+	files map[string][]byte
+	user  *userpb.User
+}
+
+func (d *driver) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	d.user, _ = ctxuser.ContextGetUser(ctx)
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	d.files[ref.GetPath()] = b
+	return nil
+}
+
+func (d *driver) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	b, ok := d.files[ref.GetPath()]
+	if !ok {
+		return nil, errtypes.NotFound(ref.GetPath())
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func newClient(t *testing.T, d *driver) (*grpcClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(revaplugin.UnaryServerInterceptor),
+		grpc.StreamInterceptor(revaplugin.StreamServerInterceptor),
+	)
+	pluginpb.RegisterStoragePluginServer(srv, &grpcServer{new: func(map[string]interface{}) (storage.FS, error) { return d, nil }})
+	go func() { _ = srv.Serve(lis) }()
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(revaplugin.UnaryClientInterceptor),
+		grpc.WithStreamInterceptor(revaplugin.StreamClientInterceptor),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &grpcClient{client: pluginpb.NewStoragePluginClient(conn)}
+	if err := c.configure(map[string]interface{}{"root": "/var/tmp/reva", "users": []map[string]interface{}{{"id": 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	return c, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestStreams(t *testing.T) {
+	d := &driver{files: map[string][]byte{}}
+	c, stop := newClient(t, d)
+	defer stop()
+
+	ctx := ctxuser.ContextSetUser(context.Background(), &userpb.User{Username: "einstein"})
+	ref := func(p string) *provider.Reference {
+		return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+	}
+
+	// larger than a chunk, so the content takes several messages
+	large := strings.Repeat("0123456789", chunkSize/4)
+	for name, content := range map[string]string{"/empty.txt": "", "/small.txt": "hello", "/large.txt": large} {
+		if err := c.Upload(ctx, ref(name), ioutil.NopCloser(strings.NewReader(content))); err != nil {
+			t.Fatalf("upload of %s: %v", name, err)
+		}
+		if d.user.GetUsername() != "einstein" {
+			t.Errorf("the user of the context was not passed to the plugin: %v", d.user)
+		}
+
+		r, err := c.Download(ctx, ref(name))
+		if err != nil {
+			t.Fatalf("download of %s: %v", name, err)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("download of %s returned %d bytes, expected %d", name, len(b), len(content))
+		}
+	}
+
+	if _, err := c.Download(ctx, ref("/missing.txt")); err == nil {
+		t.Error("expected an error downloading a missing file")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Errorf("expected a not found error, got %T %v", err, err)
+	}
+}
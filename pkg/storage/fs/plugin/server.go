@@ -0,0 +1,446 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	revaplugin "github.com/cs3org/reva/pkg/plugin"
+	pluginpb "github.com/cs3org/reva/pkg/plugin/proto"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/pkg/errors"
+)
+
+// grpcServer serves the storage driver in the plugin binary.
+type grpcServer struct {
+	new registry.NewFunc
+	fs  storage.FS
+}
+
+func (s *grpcServer) driver() (storage.FS, error) {
+	if s.fs == nil {
+		return nil, errors.New("plugin: storage driver not configured")
+	}
+	return s.fs, nil
+}
+
+func (s *grpcServer) Configure(_ context.Context, req *pluginpb.ConfigureRequest) (*pluginpb.ConfigureResponse, error) {
+	fs, err := s.new(revaplugin.DecodeConfig(req.Config))
+	if err != nil {
+		return nil, err
+	}
+	s.fs = fs
+	return &pluginpb.ConfigureResponse{}, nil
+}
+
+func (s *grpcServer) GetHome(ctx context.Context, _ *pluginpb.GetHomeRequest) (*pluginpb.GetHomeResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	p, err := fs.GetHome(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetHomeResponse{Path: p}, nil
+}
+
+func (s *grpcServer) CreateHome(ctx context.Context, _ *pluginpb.CreateHomeRequest) (*pluginpb.CreateHomeResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.CreateHome(ctx); err != nil {
+		return nil, err
+	}
+	return &pluginpb.CreateHomeResponse{}, nil
+}
+
+func (s *grpcServer) CreateDir(ctx context.Context, req *pluginpb.CreateDirRequest) (*pluginpb.CreateDirResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.CreateDir(ctx, req.Path); err != nil {
+		return nil, err
+	}
+	return &pluginpb.CreateDirResponse{}, nil
+}
+
+func (s *grpcServer) Delete(ctx context.Context, req *pluginpb.DeleteRequest) (*pluginpb.DeleteResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Delete(ctx, req.Ref); err != nil {
+		return nil, err
+	}
+	return &pluginpb.DeleteResponse{}, nil
+}
+
+func (s *grpcServer) Move(ctx context.Context, req *pluginpb.MoveRequest) (*pluginpb.MoveResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Move(ctx, req.Source, req.Destination); err != nil {
+		return nil, err
+	}
+	return &pluginpb.MoveResponse{}, nil
+}
+
+func (s *grpcServer) Copy(ctx context.Context, req *pluginpb.CopyRequest) (*pluginpb.CopyResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Copy(ctx, req.Source, req.Destination); err != nil {
+		return nil, err
+	}
+	return &pluginpb.CopyResponse{}, nil
+}
+
+func (s *grpcServer) GetMD(ctx context.Context, req *pluginpb.GetMDRequest) (*pluginpb.GetMDResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.GetMD(ctx, req.Ref, req.ArbitraryMetadataKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetMDResponse{Info: info}, nil
+}
+
+func (s *grpcServer) ListFolder(ctx context.Context, req *pluginpb.ListFolderRequest) (*pluginpb.ListFolderResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := fs.ListFolder(ctx, req.Ref, req.ArbitraryMetadataKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ListFolderResponse{Infos: infos}, nil
+}
+
+func (s *grpcServer) InitiateUpload(ctx context.Context, req *pluginpb.InitiateUploadRequest) (*pluginpb.InitiateUploadResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	params, err := fs.InitiateUpload(ctx, req.Ref, req.UploadLength, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.InitiateUploadResponse{Params: params}, nil
+}
+
+// Upload passes the chunks of the stream to the driver while it reads them.
+func (s *grpcServer) Upload(stream pluginpb.StoragePlugin_UploadServer) error {
+	fs, err := s.driver()
+	if err != nil {
+		return err
+	}
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	ref := req.Ref
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		for {
+			if len(req.Data) > 0 {
+				if _, err := pw.Write(req.Data); err != nil {
+					// the driver stopped reading
+					return
+				}
+			}
+			var err error
+			if req, err = stream.Recv(); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	if err := fs.Upload(stream.Context(), ref, pr); err != nil {
+		return err
+	}
+	return stream.SendAndClose(&pluginpb.UploadResponse{})
+}
+
+func (s *grpcServer) Download(req *pluginpb.DownloadRequest, stream pluginpb.StoragePlugin_DownloadServer) error {
+	fs, err := s.driver()
+	if err != nil {
+		return err
+	}
+	rc, err := fs.Download(stream.Context(), req.Ref)
+	if err != nil {
+		return err
+	}
+	return send(rc, stream)
+}
+
+func (s *grpcServer) ListRevisions(ctx context.Context, req *pluginpb.ListRevisionsRequest) (*pluginpb.ListRevisionsResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := fs.ListRevisions(ctx, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ListRevisionsResponse{Revisions: revisions}, nil
+}
+
+func (s *grpcServer) DownloadRevision(req *pluginpb.DownloadRevisionRequest, stream pluginpb.StoragePlugin_DownloadRevisionServer) error {
+	fs, err := s.driver()
+	if err != nil {
+		return err
+	}
+	rc, err := fs.DownloadRevision(stream.Context(), req.Ref, req.Key)
+	if err != nil {
+		return err
+	}
+	return send(rc, stream)
+}
+
+func (s *grpcServer) RestoreRevision(ctx context.Context, req *pluginpb.RestoreRevisionRequest) (*pluginpb.RestoreRevisionResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.RestoreRevision(ctx, req.Ref, req.Key); err != nil {
+		return nil, err
+	}
+	return &pluginpb.RestoreRevisionResponse{}, nil
+}
+
+func (s *grpcServer) ListRecycle(ctx context.Context, _ *pluginpb.ListRecycleRequest) (*pluginpb.ListRecycleResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	items, err := fs.ListRecycle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ListRecycleResponse{Items: items}, nil
+}
+
+func (s *grpcServer) RestoreRecycleItem(ctx context.Context, req *pluginpb.RestoreRecycleItemRequest) (*pluginpb.RestoreRecycleItemResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.RestoreRecycleItem(ctx, req.Key, req.RestorePath); err != nil {
+		return nil, err
+	}
+	return &pluginpb.RestoreRecycleItemResponse{}, nil
+}
+
+func (s *grpcServer) PurgeRecycleItem(ctx context.Context, req *pluginpb.PurgeRecycleItemRequest) (*pluginpb.PurgeRecycleItemResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.PurgeRecycleItem(ctx, req.Key); err != nil {
+		return nil, err
+	}
+	return &pluginpb.PurgeRecycleItemResponse{}, nil
+}
+
+func (s *grpcServer) EmptyRecycle(ctx context.Context, _ *pluginpb.EmptyRecycleRequest) (*pluginpb.EmptyRecycleResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.EmptyRecycle(ctx); err != nil {
+		return nil, err
+	}
+	return &pluginpb.EmptyRecycleResponse{}, nil
+}
+
+func (s *grpcServer) GetPathByID(ctx context.Context, req *pluginpb.GetPathByIDRequest) (*pluginpb.GetPathByIDResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	p, err := fs.GetPathByID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetPathByIDResponse{Path: p}, nil
+}
+
+func (s *grpcServer) AddGrant(ctx context.Context, req *pluginpb.GrantRequest) (*pluginpb.GrantResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.AddGrant(ctx, req.Ref, req.Grant); err != nil {
+		return nil, err
+	}
+	return &pluginpb.GrantResponse{}, nil
+}
+
+func (s *grpcServer) RemoveGrant(ctx context.Context, req *pluginpb.GrantRequest) (*pluginpb.GrantResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.RemoveGrant(ctx, req.Ref, req.Grant); err != nil {
+		return nil, err
+	}
+	return &pluginpb.GrantResponse{}, nil
+}
+
+func (s *grpcServer) UpdateGrant(ctx context.Context, req *pluginpb.GrantRequest) (*pluginpb.GrantResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.UpdateGrant(ctx, req.Ref, req.Grant); err != nil {
+		return nil, err
+	}
+	return &pluginpb.GrantResponse{}, nil
+}
+
+func (s *grpcServer) ListGrants(ctx context.Context, req *pluginpb.ListGrantsRequest) (*pluginpb.ListGrantsResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	grants, err := fs.ListGrants(ctx, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.ListGrantsResponse{Grants: grants}, nil
+}
+
+func (s *grpcServer) GetQuota(ctx context.Context, _ *pluginpb.GetQuotaRequest) (*pluginpb.GetQuotaResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	total, used, err := fs.GetQuota(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetQuotaResponse{TotalBytes: total, UsedBytes: used}, nil
+}
+
+func (s *grpcServer) GetRecursiveSize(ctx context.Context, req *pluginpb.GetRecursiveSizeRequest) (*pluginpb.GetRecursiveSizeResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	size, files, err := fs.GetRecursiveSize(ctx, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetRecursiveSizeResponse{Size: size, Files: files}, nil
+}
+
+func (s *grpcServer) CreateReference(ctx context.Context, req *pluginpb.CreateReferenceRequest) (*pluginpb.CreateReferenceResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	var target *url.URL
+	if req.TargetUri != "" {
+		if target, err = url.Parse(req.TargetUri); err != nil {
+			return nil, err
+		}
+	}
+	if err := fs.CreateReference(ctx, req.Path, target); err != nil {
+		return nil, err
+	}
+	return &pluginpb.CreateReferenceResponse{}, nil
+}
+
+func (s *grpcServer) Shutdown(ctx context.Context, _ *pluginpb.ShutdownRequest) (*pluginpb.ShutdownResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Shutdown(ctx); err != nil {
+		return nil, err
+	}
+	return &pluginpb.ShutdownResponse{}, nil
+}
+
+func (s *grpcServer) SetArbitraryMetadata(ctx context.Context, req *pluginpb.SetArbitraryMetadataRequest) (*pluginpb.SetArbitraryMetadataResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.SetArbitraryMetadata(ctx, req.Ref, req.ArbitraryMetadata); err != nil {
+		return nil, err
+	}
+	return &pluginpb.SetArbitraryMetadataResponse{}, nil
+}
+
+func (s *grpcServer) UnsetArbitraryMetadata(ctx context.Context, req *pluginpb.UnsetArbitraryMetadataRequest) (*pluginpb.UnsetArbitraryMetadataResponse, error) {
+	fs, err := s.driver()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.UnsetArbitraryMetadata(ctx, req.Ref, req.ArbitraryMetadataKeys); err != nil {
+		return nil, err
+	}
+	return &pluginpb.UnsetArbitraryMetadataResponse{}, nil
+}
+
+// downloadSender is the stream of Download and DownloadRevision.
+type downloadSender interface {
+	Send(*pluginpb.DownloadResponse) error
+}
+
+// send streams the content in chunks. The first chunk is sent right away,
+// even empty, it tells revad that the download started.
+func send(rc io.ReadCloser, stream downloadSender) error {
+	defer rc.Close()
+	buf := make([]byte, chunkSize)
+	sent := false
+	for {
+		n, err := rc.Read(buf)
+		if n > 0 || !sent {
+			if err := stream.Send(&pluginpb.DownloadResponse{Data: buf[:n]}); err != nil {
+				return err
+			}
+			sent = true
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -18,7 +18,10 @@
 
 package registry
 
-import "github.com/cs3org/reva/pkg/storage"
+import (
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
 
 // NewFunc is the function that storage implementations
 // should register at init time.
@@ -32,3 +35,34 @@ var NewFuncs = map[string]NewFunc{}
 func Register(name string, f NewFunc) {
 	NewFuncs[name] = f
 }
+
+// NewWrapperFunc is the function that storage wrappers
+// should register at init time. A wrapper decorates the given
+// storage backend, e.g. to enforce a policy or to log operations.
+type NewWrapperFunc func(storage.FS, map[string]interface{}) (storage.FS, error)
+
+// NewWrapperFuncs is a map containing all the registered storage wrappers.
+var NewWrapperFuncs = map[string]NewWrapperFunc{}
+
+// RegisterWrapper registers a new storage wrapper function.
+// Not safe for concurrent use. Safe for use from package init.
+func RegisterWrapper(name string, f NewWrapperFunc) {
+	NewWrapperFuncs[name] = f
+}
+
+// Wrap applies the named wrappers to the storage backend. The first wrapper
+// in the list is the outermost one, i.e. it sees every call first.
+func Wrap(fs storage.FS, wrappers []string, m map[string]map[string]interface{}) (storage.FS, error) {
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		f, ok := NewWrapperFuncs[wrappers[i]]
+		if !ok {
+			return nil, errtypes.NotFound("storage wrapper not found: " + wrappers[i])
+		}
+		wrapped, err := f(fs, m[wrappers[i]])
+		if err != nil {
+			return nil, err
+		}
+		fs = wrapped
+	}
+	return fs, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+)
+
+// recorder records the names of the wrappers a call goes through.
+type recorder struct {
+	storage.FS
+	name  string
+	calls *[]string
+}
+
+func (r *recorder) CreateDir(ctx context.Context, fn string) error {
+	*r.calls = append(*r.calls, r.name)
+	if r.FS == nil {
+		return nil
+	}
+	return r.FS.CreateDir(ctx, fn)
+}
+
+func TestWrap(t *testing.T) {
+	var calls []string
+	var configs []interface{}
+	for _, name := range []string{"test-outer", "test-inner"} {
+		name := name
+		registry.RegisterWrapper(name, func(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+			configs = append(configs, m["name"])
+			return &recorder{FS: fs, name: name, calls: &calls}, nil
+		})
+	}
+
+	base := &recorder{name: "driver", calls: &calls}
+	fs, err := registry.Wrap(base, []string{"test-outer", "test-inner"}, map[string]map[string]interface{}{
+		"test-outer": {"name": "outer"},
+		"test-inner": {"name": "inner"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.CreateDir(context.Background(), "/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first wrapper is the outermost one, the wrappers are created from the inside out
+	if want := []string{"test-outer", "test-inner", "driver"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls went through %v, want %v", calls, want)
+	}
+	if want := []interface{}{"inner", "outer"}; !reflect.DeepEqual(configs, want) {
+		t.Errorf("wrappers got the configs %v, want %v", configs, want)
+	}
+
+	if fs, err := registry.Wrap(base, nil, nil); err != nil || fs != base {
+		t.Errorf("Wrap without wrappers = %v, %v, want the driver", fs, err)
+	}
+	if _, err := registry.Wrap(base, []string{"test-outer", "unknown"}, nil); err == nil {
+		t.Error("expected an error for an unknown wrapper")
+	}
+}
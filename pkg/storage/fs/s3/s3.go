@@ -22,7 +22,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"path"
 	"strings"
@@ -39,6 +38,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
 	"github.com/mitchellh/mapstructure"
@@ -76,7 +76,7 @@ func New(m map[string]interface{}) (storage.FS, error) {
 	}
 
 	awsConfig := aws.NewConfig().
-		WithHTTPClient(http.DefaultClient).
+		WithHTTPClient(rhttp.GetHTTPClient()).
 		WithMaxRetries(aws.UseServiceDefaultRetries).
 		WithLogger(aws.NewDefaultLogger()).
 		WithLogLevel(aws.LogOff).
@@ -266,6 +266,10 @@ func (fs *s3FS) GetQuota(ctx context.Context) (uint64, uint64, error) {
 	return 0, 0, nil
 }
 
+func (fs *s3FS) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	return storage.WalkRecursiveSize(ctx, fs, ref)
+}
+
 func (fs *s3FS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
 	return errtypes.NotSupported("s3: operation not supported")
 }
@@ -298,7 +302,7 @@ func (fs *s3FS) CreateDir(ctx context.Context, fn string) error {
 		ContentLength: aws.Int64(0),
 	}
 
-	result, err := fs.client.PutObject(input)
+	result, err := fs.client.PutObjectWithContext(ctx, input)
 	if err != nil {
 		log.Error().Err(err)
 		if aerr, ok := err.(awserr.Error); ok {
@@ -324,7 +328,7 @@ func (fs *s3FS) Delete(ctx context.Context, ref *provider.Reference) error {
 
 	// first we need to find out if fn is a dir or a file
 
-	_, err = fs.client.HeadObject(&s3.HeadObjectInput{
+	_, err = fs.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(fs.config.Bucket),
 		Key:    aws.String(fn),
 	})
@@ -343,7 +347,7 @@ func (fs *s3FS) Delete(ctx context.Context, ref *provider.Reference) error {
 			Prefix: aws.String(fn + "/"),
 		})
 		batcher := s3manager.NewBatchDeleteWithClient(fs.client)
-		if err := batcher.Delete(aws.BackgroundContext(), iter); err != nil {
+		if err := batcher.Delete(ctx, iter); err != nil {
 			return err
 		}
 		// ok, we are done
@@ -351,7 +355,7 @@ func (fs *s3FS) Delete(ctx context.Context, ref *provider.Reference) error {
 	}
 
 	// we found an object, let's get rid of it
-	result, err := fs.client.DeleteObject(&s3.DeleteObjectInput{
+	result, err := fs.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(fs.config.Bucket),
 		Key:    aws.String(fn),
 	})
@@ -371,12 +375,10 @@ func (fs *s3FS) Delete(ctx context.Context, ref *provider.Reference) error {
 	return nil
 }
 
-func (fs *s3FS) moveObject(ctx context.Context, oldKey string, newKey string) error {
-
-	// Copy
+func (fs *s3FS) copyObject(ctx context.Context, oldKey string, newKey string) error {
 	// TODO double check CopyObject can deal with >5GB files.
 	// Docs say we need to use multipart upload: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectCOPY.html
-	_, err := fs.client.CopyObject(&s3.CopyObjectInput{
+	_, err := fs.client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
 		Bucket:     aws.String(fs.config.Bucket),
 		CopySource: aws.String("/" + fs.config.Bucket + oldKey),
 		Key:        aws.String(newKey),
@@ -388,9 +390,18 @@ func (fs *s3FS) moveObject(ctx context.Context, oldKey string, newKey string) er
 		return err
 	}
 	// TODO cache etag and mtime?
+	return nil
+}
+
+func (fs *s3FS) moveObject(ctx context.Context, oldKey string, newKey string) error {
+
+	// Copy
+	if err := fs.copyObject(ctx, oldKey, newKey); err != nil {
+		return err
+	}
 
 	// Delete
-	_, err = fs.client.DeleteObject(&s3.DeleteObjectInput{
+	_, err := fs.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(fs.config.Bucket),
 		Key:    aws.String(oldKey),
 	})
@@ -420,7 +431,7 @@ func (fs *s3FS) Move(ctx context.Context, oldRef, newRef *provider.Reference) er
 
 	// first we need to find out if fn is a dir or a file
 
-	_, err = fs.client.HeadObject(&s3.HeadObjectInput{
+	_, err = fs.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(fs.config.Bucket),
 		Key:    aws.String(fn),
 	})
@@ -442,7 +453,7 @@ func (fs *s3FS) Move(ctx context.Context, oldRef, newRef *provider.Reference) er
 		isTruncated := true
 
 		for isTruncated {
-			output, err := fs.client.ListObjectsV2(input)
+			output, err := fs.client.ListObjectsV2WithContext(ctx, input)
 			if err != nil {
 				return errors.Wrap(err, "s3FS: error listing "+fn)
 			}
@@ -474,6 +485,29 @@ func (fs *s3FS) Move(ctx context.Context, oldRef, newRef *provider.Reference) er
 	return nil
 }
 
+func (fs *s3FS) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	fn, err := fs.resolve(ctx, src)
+	if err != nil {
+		return errors.Wrap(err, "error resolving ref")
+	}
+
+	newName, err := fs.resolve(ctx, dst)
+	if err != nil {
+		return errors.Wrap(err, "error resolving ref")
+	}
+
+	// single objects can be copied by the backend, directories are walked
+	_, err = fs.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.config.Bucket),
+		Key:    aws.String(fn),
+	})
+	if err != nil {
+		return storage.StreamCopy(ctx, fs, src, dst)
+	}
+
+	return fs.copyObject(ctx, fn, newName)
+}
+
 func (fs *s3FS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
 	log := appctx.GetLogger(ctx)
 
@@ -491,7 +525,7 @@ func (fs *s3FS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []str
 		Bucket: aws.String(fs.config.Bucket),
 		Key:    aws.String(fn),
 	}
-	output, err := fs.client.HeadObject(input)
+	output, err := fs.client.HeadObjectWithContext(ctx, input)
 	if err != nil {
 		log.Error().Err(err)
 		if aerr, ok := err.(awserr.Error); ok {
@@ -513,7 +547,7 @@ func (fs *s3FS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []str
 		isTruncated := true
 
 		for isTruncated {
-			output, err := fs.client.ListObjectsV2(input)
+			output, err := fs.client.ListObjectsV2WithContext(ctx, input)
 			if err != nil {
 				return nil, errors.Wrap(err, "s3FS: error listing "+fn)
 			}
@@ -553,7 +587,7 @@ func (fs *s3FS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys
 	finfos := []*provider.ResourceInfo{}
 
 	for isTruncated {
-		output, err := fs.client.ListObjectsV2(input)
+		output, err := fs.client.ListObjectsV2WithContext(ctx, input)
 		if err != nil {
 			return nil, errors.Wrap(err, "s3FS: error listing "+fn)
 		}
@@ -587,7 +621,7 @@ func (fs *s3FS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCl
 		Body:   r,
 	}
 	uploader := s3manager.NewUploaderWithClient(fs.client)
-	result, err := uploader.Upload(upParams)
+	result, err := uploader.UploadWithContext(ctx, upParams)
 
 	if err != nil {
 		log.Error().Err(err)
@@ -614,7 +648,7 @@ func (fs *s3FS) Download(ctx context.Context, ref *provider.Reference) (io.ReadC
 	// use GetObject instead of s3manager.Downloader:
 	// the result.Body is a ReadCloser, which allows streaming
 	// TODO double check we are not caching bytes in memory
-	r, err := fs.client.GetObject(&s3.GetObjectInput{
+	r, err := fs.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(fs.config.Bucket),
 		Key:    aws.String(fn),
 	})
@@ -632,6 +666,31 @@ func (fs *s3FS) Download(ctx context.Context, ref *provider.Reference) (io.ReadC
 	return r.Body, nil
 }
 
+// DownloadRange uses a ranged GetObject request to only fetch the requested bytes.
+func (fs *s3FS) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "error resolving ref")
+	}
+
+	r, err := fs.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(fs.config.Bucket),
+		Key:    aws.String(fn),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case s3.ErrCodeNoSuchBucket:
+			case s3.ErrCodeNoSuchKey:
+				return nil, errtypes.NotFound(fn)
+			}
+		}
+		return nil, errors.Wrap(err, "s3fs: error downloading range of "+fn)
+	}
+	return r.Body, nil
+}
+
 func (fs *s3FS) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
 	return nil, errtypes.NotSupported("list revisions")
 }
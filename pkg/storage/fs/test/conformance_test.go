@@ -0,0 +1,440 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package test_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	_ "github.com/cs3org/reva/pkg/storage/fs/loader"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	ruser "github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/tests/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// driverConfig configures one driver under test
+type driverConfig struct {
+	Driver string                 `toml:"driver"`
+	Skip   []string               `toml:"skip"`
+	Config map[string]interface{} `toml:"config"`
+}
+
+func loadDrivers() map[string]driverConfig {
+	file := os.Getenv("REVA_STORAGE_CONFORMANCE_CONFIG")
+	if file == "" {
+		file = "fixtures/conformance.toml"
+	}
+	drivers := map[string]driverConfig{}
+	if _, err := toml.DecodeFile(file, &drivers); err != nil {
+		panic("conformance: error reading " + file + ": " + err.Error())
+	}
+	return drivers
+}
+
+// expandRoot replaces $ROOT in the string values of the driver configuration
+func expandRoot(m map[string]interface{}, root string) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch t := v.(type) {
+		case string:
+			res[k] = strings.ReplaceAll(t, "$ROOT", root)
+		case map[string]interface{}:
+			res[k] = expandRoot(t, root)
+		default:
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func ref(p string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+}
+
+// skipUnsupported skips the current test when the driver does not implement the feature
+func skipUnsupported(err error) {
+	if _, ok := err.(errtypes.IsNotSupported); ok {
+		Skip("not supported by the driver: " + err.Error())
+	}
+}
+
+var _ = Describe("storage.FS conformance", func() {
+	for name, dc := range loadDrivers() {
+		name, dc := name, dc
+
+		Describe(name, func() {
+			var (
+				ctx  context.Context
+				root string
+				fs   storage.FS
+			)
+
+			skips := map[string]bool{}
+			for _, s := range dc.Skip {
+				skips[s] = true
+			}
+			feature := func(f string) {
+				if skips[f] {
+					Skip(f + " skipped by configuration")
+				}
+			}
+
+			upload := func(p, content string) {
+				err := fs.Upload(ctx, ref(p), ioutil.NopCloser(bytes.NewReader([]byte(content))))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			download := func(p string) string {
+				r, err := fs.Download(ctx, ref(p))
+				Expect(err).ToNot(HaveOccurred())
+				defer r.Close()
+				b, err := ioutil.ReadAll(r)
+				Expect(err).ToNot(HaveOccurred())
+				return string(b)
+			}
+
+			names := func(p string) []string {
+				infos, err := fs.ListFolder(ctx, ref(p), nil)
+				Expect(err).ToNot(HaveOccurred())
+				n := make([]string, 0, len(infos))
+				for _, info := range infos {
+					n = append(n, path.Base(info.Path))
+				}
+				return n
+			}
+
+			BeforeEach(func() {
+				var err error
+				root, err = helpers.TempDir("reva-conformance-*-root")
+				Expect(err).ToNot(HaveOccurred())
+
+				f, ok := registry.NewFuncs[dc.Driver]
+				Expect(ok).To(BeTrue(), "driver "+dc.Driver+" is not registered")
+				fs, err = f(expandRoot(dc.Config, root))
+				Expect(err).ToNot(HaveOccurred())
+
+				ctx = ruser.ContextSetUser(context.Background(), &userpb.User{
+					Id: &userpb.UserId{
+						Idp:      "idp",
+						OpaqueId: "conformance-user",
+					},
+					Username: "conformance",
+				})
+				if err := fs.CreateHome(ctx); err != nil {
+					if _, ok := err.(errtypes.IsNotSupported); !ok {
+						Expect(err).ToNot(HaveOccurred())
+					}
+				}
+			})
+
+			AfterEach(func() {
+				if fs != nil {
+					_ = fs.Shutdown(ctx)
+				}
+				if root != "" {
+					os.RemoveAll(root)
+				}
+			})
+
+			Describe("folders", func() {
+				It("creates and stats a folder", func() {
+					Expect(fs.CreateDir(ctx, "/dir")).To(Succeed())
+
+					info, err := fs.GetMD(ctx, ref("/dir"), nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(info.Type).To(Equal(provider.ResourceType_RESOURCE_TYPE_CONTAINER))
+					Expect(path.Base(info.Path)).To(Equal("dir"))
+					Expect(info.Id).ToNot(BeNil())
+					Expect(info.Etag).ToNot(BeEmpty())
+				})
+
+				It("refuses to create an existing folder", func() {
+					Expect(fs.CreateDir(ctx, "/dir")).To(Succeed())
+					err := fs.CreateDir(ctx, "/dir")
+					Expect(err).To(HaveOccurred())
+					Expect(err).To(BeAssignableToTypeOf(errtypes.AlreadyExists("")))
+				})
+
+				It("reports missing resources as not found", func() {
+					_, err := fs.GetMD(ctx, ref("/missing"), nil)
+					Expect(err).To(HaveOccurred())
+					_, ok := err.(errtypes.IsNotFound)
+					Expect(ok).To(BeTrue(), err.Error())
+				})
+
+				It("lists the children of a folder", func() {
+					Expect(fs.CreateDir(ctx, "/dir")).To(Succeed())
+					Expect(fs.CreateDir(ctx, "/dir/sub")).To(Succeed())
+					upload("/dir/file.txt", "content")
+
+					Expect(names("/dir")).To(ConsistOf("sub", "file.txt"))
+				})
+
+				It("stats a resource by id", func() {
+					Expect(fs.CreateDir(ctx, "/dir")).To(Succeed())
+					info, err := fs.GetMD(ctx, ref("/dir"), nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					byID, err := fs.GetMD(ctx, &provider.Reference{Spec: &provider.Reference_Id{Id: info.Id}}, nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(byID.Path).To(Equal(info.Path))
+				})
+
+				It("moves a folder with its content", func() {
+					Expect(fs.CreateDir(ctx, "/dir")).To(Succeed())
+					upload("/dir/file.txt", "content")
+
+					Expect(fs.Move(ctx, ref("/dir"), ref("/renamed"))).To(Succeed())
+
+					_, err := fs.GetMD(ctx, ref("/dir"), nil)
+					Expect(err).To(HaveOccurred())
+					Expect(download("/renamed/file.txt")).To(Equal("content"))
+				})
+
+				It("deletes a folder", func() {
+					Expect(fs.CreateDir(ctx, "/dir")).To(Succeed())
+					Expect(fs.Delete(ctx, ref("/dir"))).To(Succeed())
+
+					_, err := fs.GetMD(ctx, ref("/dir"), nil)
+					Expect(err).To(HaveOccurred())
+				})
+			})
+
+			Describe("uploads", func() {
+				It("downloads what was uploaded", func() {
+					upload("/file.txt", "hello world")
+
+					info, err := fs.GetMD(ctx, ref("/file.txt"), nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(info.Type).To(Equal(provider.ResourceType_RESOURCE_TYPE_FILE))
+					Expect(info.Size).To(Equal(uint64(len("hello world"))))
+					Expect(download("/file.txt")).To(Equal("hello world"))
+				})
+
+				It("uploads empty files", func() {
+					upload("/empty.txt", "")
+
+					info, err := fs.GetMD(ctx, ref("/empty.txt"), nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(info.Size).To(Equal(uint64(0)))
+				})
+
+				It("overwrites a file and changes its etag", func() {
+					upload("/file.txt", "v1")
+					before, err := fs.GetMD(ctx, ref("/file.txt"), nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					upload("/file.txt", "version 2")
+					after, err := fs.GetMD(ctx, ref("/file.txt"), nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(download("/file.txt")).To(Equal("version 2"))
+					Expect(after.Etag).ToNot(Equal(before.Etag))
+				})
+
+				It("copies a file", func() {
+					feature("copy")
+					upload("/file.txt", "content")
+
+					err := fs.Copy(ctx, ref("/file.txt"), ref("/copy.txt"))
+					skipUnsupported(err)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(download("/copy.txt")).To(Equal("content"))
+					Expect(download("/file.txt")).To(Equal("content"))
+				})
+
+				It("initiates uploads", func() {
+					ids, err := fs.InitiateUpload(ctx, ref("/file.txt"), 10, map[string]string{})
+					skipUnsupported(err)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(ids).ToNot(BeEmpty())
+				})
+			})
+
+			Describe("revisions", func() {
+				BeforeEach(func() {
+					feature("revisions")
+				})
+
+				It("keeps, downloads and restores revisions", func() {
+					upload("/file.txt", "v1")
+					upload("/file.txt", "version 2")
+
+					revs, err := fs.ListRevisions(ctx, ref("/file.txt"))
+					skipUnsupported(err)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(revs).ToNot(BeEmpty())
+
+					r, err := fs.DownloadRevision(ctx, ref("/file.txt"), revs[0].Key)
+					Expect(err).ToNot(HaveOccurred())
+					b, err := ioutil.ReadAll(r)
+					r.Close()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(b)).To(Equal("v1"))
+
+					Expect(fs.RestoreRevision(ctx, ref("/file.txt"), revs[0].Key)).To(Succeed())
+					Expect(download("/file.txt")).To(Equal("v1"))
+				})
+			})
+
+			Describe("recycle", func() {
+				BeforeEach(func() {
+					feature("recycle")
+				})
+
+				It("restores deleted files", func() {
+					upload("/file.txt", "content")
+					Expect(fs.Delete(ctx, ref("/file.txt"))).To(Succeed())
+
+					items, err := fs.ListRecycle(ctx)
+					skipUnsupported(err)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(items).To(HaveLen(1))
+
+					Expect(fs.RestoreRecycleItem(ctx, items[0].Key, "")).To(Succeed())
+					Expect(download("/file.txt")).To(Equal("content"))
+
+					items, err = fs.ListRecycle(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(items).To(BeEmpty())
+				})
+
+				It("purges deleted files", func() {
+					upload("/file.txt", "content")
+					Expect(fs.Delete(ctx, ref("/file.txt"))).To(Succeed())
+
+					items, err := fs.ListRecycle(ctx)
+					skipUnsupported(err)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(items).To(HaveLen(1))
+
+					Expect(fs.PurgeRecycleItem(ctx, items[0].Key)).To(Succeed())
+					items, err = fs.ListRecycle(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(items).To(BeEmpty())
+				})
+			})
+
+			Describe("grants", func() {
+				BeforeEach(func() {
+					feature("grants")
+				})
+
+				It("adds, lists and removes grants", func() {
+					Expect(fs.CreateDir(ctx, "/shared")).To(Succeed())
+					g := &provider.Grant{
+						Grantee: &provider.Grantee{
+							Type: provider.GranteeType_GRANTEE_TYPE_USER,
+							Id: &provider.Grantee_UserId{UserId: &userpb.UserId{
+								Idp:      "idp",
+								OpaqueId: "grantee",
+							}},
+						},
+						Permissions: &provider.ResourcePermissions{
+							Stat:          true,
+							ListContainer: true,
+						},
+					}
+
+					err := fs.AddGrant(ctx, ref("/shared"), g)
+					skipUnsupported(err)
+					Expect(err).ToNot(HaveOccurred())
+
+					grants, err := fs.ListGrants(ctx, ref("/shared"))
+					Expect(err).ToNot(HaveOccurred())
+					var found bool
+					for _, lg := range grants {
+						if lg.Grantee.GetUserId().GetOpaqueId() == "grantee" {
+							found = true
+							Expect(lg.Permissions.Stat).To(BeTrue())
+						}
+					}
+					Expect(found).To(BeTrue())
+
+					Expect(fs.RemoveGrant(ctx, ref("/shared"), g)).To(Succeed())
+					grants, err = fs.ListGrants(ctx, ref("/shared"))
+					Expect(err).ToNot(HaveOccurred())
+					for _, lg := range grants {
+						Expect(lg.Grantee.GetUserId().GetOpaqueId()).ToNot(Equal("grantee"))
+					}
+				})
+			})
+
+			Describe("arbitrary metadata", func() {
+				BeforeEach(func() {
+					feature("metadata")
+				})
+
+				It("sets and unsets metadata", func() {
+					upload("/file.txt", "content")
+
+					err := fs.SetArbitraryMetadata(ctx, ref("/file.txt"), &provider.ArbitraryMetadata{
+						Metadata: map[string]string{"conformance": "value"},
+					})
+					skipUnsupported(err)
+					Expect(err).ToNot(HaveOccurred())
+
+					info, err := fs.GetMD(ctx, ref("/file.txt"), []string{"conformance"})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(info.ArbitraryMetadata.GetMetadata()).To(HaveKeyWithValue("conformance", "value"))
+
+					Expect(fs.UnsetArbitraryMetadata(ctx, ref("/file.txt"), []string{"conformance"})).To(Succeed())
+					info, err = fs.GetMD(ctx, ref("/file.txt"), []string{"conformance"})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(info.ArbitraryMetadata.GetMetadata()).ToNot(HaveKey("conformance"))
+				})
+			})
+
+			Describe("edge case paths", func() {
+				for _, n := range []string{"with space", "ünïcödé", "hash#tag", "per%20cent", "question?mark", ".hidden", "semi;colon", "quo'te"} {
+					n := n
+					It("handles the name "+n, func() {
+						feature("paths")
+						Expect(fs.CreateDir(ctx, "/"+n)).To(Succeed())
+						upload("/"+n+"/"+n+".txt", n)
+
+						Expect(names("/")).To(ContainElement(n))
+						Expect(names("/" + n)).To(ConsistOf(n + ".txt"))
+						Expect(download("/" + n + "/" + n + ".txt")).To(Equal(n))
+					})
+				}
+
+				It("does not escape the storage root", func() {
+					feature("paths")
+					_ = fs.CreateDir(ctx, "/../../escaped")
+					_, err := os.Stat(filepath.Join(filepath.Dir(root), "escaped"))
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				})
+			})
+		})
+	}
+})
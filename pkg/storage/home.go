@@ -0,0 +1,46 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// HomeRenamer is implemented by drivers deriving the home path of a user
+// from its attributes, e.g. with a user layout containing the username.
+type HomeRenamer interface {
+	// RenameHome moves the home of oldUser to the path of newUser and makes
+	// newUser the owner of it. It is a no-op if the path does not change.
+	RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error
+}
+
+// RenameHome moves the home with the HomeRenamer of fs. Storage wrappers
+// implement HomeRenamer by calling it with the storage they wrap, it returns
+// an errtypes.NotSupported error when the home path of the driver doesn't
+// depend on the attributes of the user.
+func RenameHome(ctx context.Context, fs FS, oldUser, newUser *userpb.User) error {
+	hr, ok := fs.(HomeRenamer)
+	if !ok {
+		return errtypes.NotSupported("rename home")
+	}
+	return hr.RenameHome(ctx, oldUser, newUser)
+}
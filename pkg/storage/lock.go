@@ -0,0 +1,80 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// LockKey is the arbitrary metadata key the write locks of resources are kept
+// under. The storage stores the metadata like any other, the lock is enforced
+// by the services handing out and checking the tokens, like the WebDAV one.
+const LockKey = "reva.lock"
+
+// Lock is an exclusive write lock on a resource.
+type Lock struct {
+	Token   string         `json:"token"`
+	User    *userpb.UserId `json:"user"`
+	Owner   string         `json:"owner,omitempty"`
+	Expires time.Time      `json:"expires"`
+}
+
+// Expired tells if the lock has run out at the given time.
+func (l *Lock) Expired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+// Encode returns the metadata to store under LockKey for the lock.
+func (l *Lock) Encode() (string, error) {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecodeLock returns the lock stored under LockKey.
+func DecodeLock(v string) (*Lock, error) {
+	l := &Lock{}
+	if err := json.Unmarshal([]byte(v), l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ActiveLock returns the lock of the resource if it has one which has not
+// expired yet. Unreadable locks are ignored.
+func ActiveLock(info *provider.ResourceInfo) *Lock {
+	if info.GetArbitraryMetadata() == nil {
+		return nil
+	}
+	v, ok := info.ArbitraryMetadata.Metadata[LockKey]
+	if !ok || v == "" {
+		return nil
+	}
+	l, err := DecodeLock(v)
+	if err != nil || l.Expired(time.Now()) {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,138 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// MetadataBatchOpaqueKey is the opaque entry of a SetArbitraryMetadataRequest
+// holding a json encoded list of MetadataChange. The response carries the
+// json encoded list of MetadataResult under the same key, in the same order.
+const MetadataBatchOpaqueKey = "metadata_batch"
+
+// MetadataChange describes the metadata keys to set and unset on a single resource.
+type MetadataChange struct {
+	Path      string            `json:"path,omitempty"`
+	StorageID string            `json:"storage_id,omitempty"`
+	OpaqueID  string            `json:"opaque_id,omitempty"`
+	Set       map[string]string `json:"set,omitempty"`
+	Unset     []string          `json:"unset,omitempty"`
+}
+
+// MetadataResult is the outcome of a single MetadataChange.
+type MetadataResult struct {
+	Code    rpc.Code `json:"code"`
+	Message string   `json:"message,omitempty"`
+}
+
+// Reference returns the CS3 reference of the resource the change applies to.
+func (c *MetadataChange) Reference() *provider.Reference {
+	if c.OpaqueID != "" {
+		return &provider.Reference{
+			Spec: &provider.Reference_Id{
+				Id: &provider.ResourceId{StorageId: c.StorageID, OpaqueId: c.OpaqueID},
+			},
+		}
+	}
+	return &provider.Reference{
+		Spec: &provider.Reference_Path{Path: c.Path},
+	}
+}
+
+// SetReference points the change to the given reference.
+func (c *MetadataChange) SetReference(ref *provider.Reference) {
+	if id := ref.GetId(); id != nil {
+		c.Path, c.StorageID, c.OpaqueID = "", id.StorageId, id.OpaqueId
+		return
+	}
+	c.Path, c.StorageID, c.OpaqueID = ref.GetPath(), "", ""
+}
+
+// BatchMetadataFS is implemented by drivers that can apply metadata changes
+// to many resources with a single call to their backend.
+type BatchMetadataFS interface {
+	SetArbitraryMetadataBatch(ctx context.Context, changes []*MetadataChange) []error
+}
+
+// SetArbitraryMetadataBatch applies the changes using the batch support of the driver
+// if available, one resource at a time otherwise. The returned slice has one entry per change.
+func SetArbitraryMetadataBatch(ctx context.Context, fs FS, changes []*MetadataChange) []error {
+	if bfs, ok := fs.(BatchMetadataFS); ok {
+		return bfs.SetArbitraryMetadataBatch(ctx, changes)
+	}
+	return SetArbitraryMetadataEach(ctx, fs, changes)
+}
+
+// SetArbitraryMetadataEach applies the changes one resource at a time, for the
+// storage wrappers implementing BatchMetadataFS to apply their policy to the
+// batches they can't forward.
+func SetArbitraryMetadataEach(ctx context.Context, fs FS, changes []*MetadataChange) []error {
+	errs := make([]error, len(changes))
+	for i, c := range changes {
+		ref := c.Reference()
+		if len(c.Set) > 0 {
+			if err := fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: c.Set}); err != nil {
+				errs[i] = err
+				continue
+			}
+		}
+		if len(c.Unset) > 0 {
+			if err := fs.UnsetArbitraryMetadata(ctx, ref, c.Unset); err != nil {
+				errs[i] = err
+			}
+		}
+	}
+	return errs
+}
+
+// EncodeMetadataBatch encodes the values, either changes or results, into an opaque.
+func EncodeMetadataBatch(v interface{}) (*types.Opaque, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Opaque{
+		Map: map[string]*types.OpaqueEntry{
+			MetadataBatchOpaqueKey: {
+				Decoder: "json",
+				Value:   b,
+			},
+		},
+	}, nil
+}
+
+// DecodeMetadataBatch decodes the batch held in the opaque into v. It returns false if the
+// opaque doesn't hold a batch.
+func DecodeMetadataBatch(o *types.Opaque, v interface{}) (bool, error) {
+	if o == nil || o.Map == nil || o.Map[MetadataBatchOpaqueKey] == nil {
+		return false, nil
+	}
+	entry := o.Map[MetadataBatchOpaqueKey]
+	if entry.Decoder != "json" {
+		return true, errtypes.NotSupported("opaque entry decoder not recognized: " + entry.Decoder)
+	}
+	return true, json.Unmarshal(entry.Value, v)
+}
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// TruncatedOpaqueKey is set in the opaque of listing responses that only
+// contain the entries the storage driver returned before the deadline.
+const TruncatedOpaqueKey = "truncated"
+
+// DegradedOpaqueKey is set in the opaque of listing responses aggregating
+// several storage providers when some of them failed to answer. Its value
+// holds the comma separated paths of the failed providers.
+const DegradedOpaqueKey = "degraded"
+
+// ListFolderPartial lists the folder, giving up after the timeout.
+//
+// Drivers honouring the context return the entries collected so far together
+// with the context error; those entries are returned with truncated set.
+// Drivers ignoring the context are abandoned at the deadline and no entries
+// are returned. A zero timeout lists the folder without deadline.
+func ListFolderPartial(ctx context.Context, fs FS, ref *provider.Reference, mdKeys []string, timeout time.Duration) (infos []*provider.ResourceInfo, truncated bool, err error) {
+	if timeout <= 0 {
+		infos, err = fs.ListFolder(ctx, ref, mdKeys)
+		return infos, false, err
+	}
+
+	type result struct {
+		infos []*provider.ResourceInfo
+		err   error
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c := make(chan result, 1)
+	go func() {
+		infos, err := fs.ListFolder(ctx, ref, mdKeys)
+		c <- result{infos, err}
+	}()
+
+	select {
+	case r := <-c:
+		if r.err == context.DeadlineExceeded {
+			return r.infos, true, nil
+		}
+		return r.infos, false, r.err
+	case <-ctx.Done():
+		return nil, true, nil
+	}
+}
+
+// RecycleRootOpaqueKey is set in the opaque of the recycle listing requests,
+// which carry no reference, to the opaque id of the resource whose deleted
+// children are listed, e.g. the root of a space.
+const RecycleRootOpaqueKey = "recycle_root"
+
+// ListRecyclePartial lists the recycle bin, giving up after the timeout.
+// It follows the same semantics as ListFolderPartial.
+func ListRecyclePartial(ctx context.Context, fs FS, timeout time.Duration) (items []*provider.RecycleItem, truncated bool, err error) {
+	if timeout <= 0 {
+		items, err = fs.ListRecycle(ctx)
+		return items, false, err
+	}
+
+	type result struct {
+		items []*provider.RecycleItem
+		err   error
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c := make(chan result, 1)
+	go func() {
+		items, err := fs.ListRecycle(ctx)
+		c <- result{items, err}
+	}()
+
+	select {
+	case r := <-c:
+		if r.err == context.DeadlineExceeded {
+			return r.items, true, nil
+		}
+		return r.items, false, r.err
+	case <-ctx.Done():
+		return nil, true, nil
+	}
+}
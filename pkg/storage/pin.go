@@ -0,0 +1,91 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// PinKey and AdminPinKey are the arbitrary metadata keys resources are pinned
+// with, by their users and by the admins respectively. Setting one of them pins
+// the resource for the user making the request, unsetting it releases the pin.
+// The metadata stored under PinKey is the comma separated list of the ids of
+// the users pinning the resource, the one under AdminPinKey the username of the
+// admin who pinned it.
+const (
+	PinKey      = "reva.pin"
+	AdminPinKey = "reva.pin.admin"
+)
+
+// PinnedBytesKey and PinQuotaKey are the opaque keys the storage adds to the
+// info of resources stated with PinKey. They hold the bytes pinned by the user
+// making the request and the quota those bytes are accounted against.
+const (
+	PinnedBytesKey = "reva.pinned_bytes"
+	PinQuotaKey    = "reva.pin_quota"
+)
+
+// ResidencyPinner is implemented by the drivers that move content between
+// storage tiers. Pinned resources must be kept on the fastest tier until they
+// are unpinned.
+type ResidencyPinner interface {
+	PinResidency(ctx context.Context, ref *provider.Reference) error
+	UnpinResidency(ctx context.Context, ref *provider.Reference) error
+}
+
+// PinResidency pins the resource with the ResidencyPinner of fs. Storage
+// wrappers implement ResidencyPinner by calling it and UnpinResidency with the
+// storage they wrap, they return an errtypes.NotSupported error when the
+// driver doesn't tier its content.
+func PinResidency(ctx context.Context, fs FS, ref *provider.Reference) error {
+	rp, ok := fs.(ResidencyPinner)
+	if !ok {
+		return errtypes.NotSupported("pin residency")
+	}
+	return rp.PinResidency(ctx, ref)
+}
+
+// UnpinResidency releases the pin with the ResidencyPinner of fs.
+func UnpinResidency(ctx context.Context, fs FS, ref *provider.Reference) error {
+	rp, ok := fs.(ResidencyPinner)
+	if !ok {
+		return errtypes.NotSupported("unpin residency")
+	}
+	return rp.UnpinResidency(ctx, ref)
+}
+
+// ParsePinners returns the ids of the users in the metadata stored under PinKey.
+func ParsePinners(v string) []string {
+	var pinners []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			pinners = append(pinners, p)
+		}
+	}
+	return pinners
+}
+
+// FormatPinners returns the metadata to store under PinKey for the given users.
+func FormatPinners(pinners []string) string {
+	return strings.Join(pinners, ",")
+}
@@ -0,0 +1,77 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"strconv"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// QuotaKey is the arbitrary metadata key setting the quota in bytes of the
+// space or home a reference points to, through SetArbitraryMetadata. The
+// storage providers only accept it from their quota admins and don't store
+// it as metadata.
+const QuotaKey = "reva.quota"
+
+// QuotaSetter is implemented by the drivers whose quotas can be managed.
+type QuotaSetter interface {
+	// SetQuota sets the maximum number of bytes of the space the reference
+	// points to, the home of a user for the per user quotas. 0 lifts the
+	// quota, if the backend supports it.
+	SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error
+}
+
+// SetQuota sets the quota with the QuotaSetter of fs. Storage wrappers
+// implement QuotaSetter by calling it with the storage they wrap, it returns
+// an errtypes.NotSupported error when the quotas of the driver can't be
+// managed.
+func SetQuota(ctx context.Context, fs FS, ref *provider.Reference, maxBytes uint64) error {
+	qs, ok := fs.(QuotaSetter)
+	if !ok {
+		return errtypes.NotSupported("set quota")
+	}
+	return qs.SetQuota(ctx, ref, maxBytes)
+}
+
+// QuotaMetadata returns the arbitrary metadata setting the quota to maxBytes.
+func QuotaMetadata(maxBytes uint64) *provider.ArbitraryMetadata {
+	return &provider.ArbitraryMetadata{
+		Metadata: map[string]string{QuotaKey: strconv.FormatUint(maxBytes, 10)},
+	}
+}
+
+// DecodeQuota returns the quota set by the arbitrary metadata, if any.
+func DecodeQuota(md *provider.ArbitraryMetadata) (uint64, bool, error) {
+	v, ok := md.GetMetadata()[QuotaKey]
+	if !ok {
+		return 0, false, nil
+	}
+	if len(md.Metadata) > 1 {
+		return 0, true, errors.New("the quota can't be set with other metadata")
+	}
+	q, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, true, errors.Wrap(err, "invalid quota")
+	}
+	return q, true, nil
+}
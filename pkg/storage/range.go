@@ -0,0 +1,46 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"io"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// RangeDownloader is implemented by drivers that can read a byte range of a
+// file without transferring the preceding bytes, eg. object stores.
+type RangeDownloader interface {
+	// DownloadRange returns the length bytes of the file starting at offset.
+	DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error)
+}
+
+// DownloadRange reads a byte range with the RangeDownloader of fs. Storage
+// wrappers implement RangeDownloader by calling it with the storage they wrap,
+// it returns an errtypes.NotSupported error when the driver can't read ranges
+// and the caller has to read the range from the full content instead.
+func DownloadRange(ctx context.Context, fs FS, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	rd, ok := fs.(RangeDownloader)
+	if !ok {
+		return nil, errtypes.NotSupported("download range")
+	}
+	return rd.DownloadRange(ctx, ref, offset, length)
+}
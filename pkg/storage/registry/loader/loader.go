@@ -20,6 +20,7 @@ package loader
 
 import (
 	// Load core storage broker drivers.
+	_ "github.com/cs3org/reva/pkg/storage/registry/spaces"
 	_ "github.com/cs3org/reva/pkg/storage/registry/static"
 	// Add your own here
 )
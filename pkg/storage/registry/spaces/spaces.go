@@ -0,0 +1,346 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package spaces implements a storage registry keeping a catalog of the
+// storage spaces the users can access, which routes the references by the
+// space they point into rather than by the path prefixes of the providers.
+package spaces
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registrypb "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/registry/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("spaces", New)
+}
+
+type spaceType struct {
+	// Alias is the template of the path the spaces of the type are reached at,
+	// rendered with the space, e.g. /users/{{.Owner.Id.OpaqueId}} or /projects/{{.Name}}.
+	Alias string `mapstructure:"alias"`
+	// PerUser means the provider holds one space of the type for every user and
+	// does not list them, e.g. the homes of the drivers without spaces support.
+	PerUser bool `mapstructure:"per_user"`
+}
+
+type providerConfig struct {
+	// ProviderID is the storage id of the resources of the provider.
+	ProviderID string `mapstructure:"provider_id"`
+	// Spaces maps the types of the spaces the provider holds to their config.
+	Spaces map[string]spaceType `mapstructure:"spaces"`
+}
+
+type config struct {
+	// Providers maps the addresses of the storage providers to the spaces they hold.
+	Providers map[string]providerConfig `mapstructure:"providers"`
+	// HomeSpaceType is the type of the spaces serving as the homes of their owners.
+	HomeSpaceType string `mapstructure:"home_space_type"`
+	// CacheTTL is the time in seconds the catalog of the spaces of a user is cached.
+	CacheTTL int `mapstructure:"cache_ttl"`
+}
+
+func (c *config) init() {
+	if c.HomeSpaceType == "" {
+		c.HomeSpaceType = "personal"
+	}
+	if c.CacheTTL == 0 {
+		c.CacheTTL = 30
+	}
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "spaces: error decoding conf")
+	}
+	return c, nil
+}
+
+// New returns an implementation of the storage.SpacesRegistry interface
+// routing the requests to the providers of the spaces.
+func New(m map[string]interface{}) (storage.Registry, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+	for addr, p := range c.Providers {
+		for t, st := range p.Spaces {
+			if !strings.HasPrefix(st.Alias, "/") {
+				return nil, errors.Errorf("spaces: the alias of the %s spaces of provider %s must be an absolute path", t, addr)
+			}
+		}
+	}
+	return &reg{c: c, catalogs: map[string]catalog{}}, nil
+}
+
+// space is a storage space of the catalog, reached at its alias on the provider address.
+type space struct {
+	*provider.StorageSpace
+	alias   string
+	address string
+}
+
+func (s *space) info() *registrypb.ProviderInfo {
+	info := &registrypb.ProviderInfo{
+		ProviderId:   s.GetRoot().GetStorageId(),
+		ProviderPath: s.alias,
+		Address:      s.address,
+	}
+	if b, err := utils.MarshalProtoV1ToJSON(s.StorageSpace); err == nil {
+		info.Opaque = &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				storage.SpaceOpaqueKey: {
+					Decoder: "json",
+					Value:   b,
+				},
+			},
+		}
+	}
+	return info
+}
+
+type catalog struct {
+	spaces  []*space
+	expires time.Time
+}
+
+type reg struct {
+	c *config
+
+	mu sync.Mutex
+	// catalogs holds the spaces by user
+	catalogs map[string]catalog
+}
+
+// catalog returns the spaces the user of the context can access, sorted by alias.
+// Without a user only the references by id of the configured providers are routed.
+func (r *reg) catalog(ctx context.Context) []*space {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok {
+		return nil
+	}
+	key := u.Id.Idp + "!" + u.Id.OpaqueId
+
+	now := time.Now()
+	r.mu.Lock()
+	c, ok := r.catalogs[key]
+	r.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.spaces
+	}
+
+	spaces, complete := r.discover(ctx, u)
+	// retry the failed providers with the next request
+	if complete {
+		r.mu.Lock()
+		for k, c := range r.catalogs {
+			if now.After(c.expires) {
+				delete(r.catalogs, k)
+			}
+		}
+		r.catalogs[key] = catalog{spaces: spaces, expires: now.Add(time.Duration(r.c.CacheTTL) * time.Second)}
+		r.mu.Unlock()
+	}
+	return spaces
+}
+
+// discover collects the spaces of the user from the providers. It reports
+// whether all the providers could be asked.
+func (r *reg) discover(ctx context.Context, u *userpb.User) ([]*space, bool) {
+	log := appctx.GetLogger(ctx)
+	var spaces []*space
+	complete := true
+	for addr, p := range r.c.Providers {
+		for t, st := range p.Spaces {
+			var found []*provider.StorageSpace
+			if st.PerUser {
+				found = []*provider.StorageSpace{{
+					Id:        &provider.StorageSpaceId{OpaqueId: u.Id.OpaqueId},
+					Owner:     u,
+					Root:      &provider.ResourceId{StorageId: p.ProviderID},
+					Name:      u.Username,
+					SpaceType: t,
+				}}
+			} else {
+				var err error
+				if found, err = listSpaces(ctx, addr, t); err != nil {
+					log.Warn().Err(err).Str("address", addr).Str("type", t).Msg("spaces: error listing storage spaces")
+					complete = false
+					continue
+				}
+			}
+
+			for _, s := range found {
+				alias, err := templates.WithSpace(s, st.Alias)
+				if err != nil {
+					log.Error().Err(err).Str("address", addr).Str("space", s.GetId().GetOpaqueId()).Msg("spaces: error rendering space alias")
+					continue
+				}
+				spaces = append(spaces, &space{StorageSpace: s, alias: alias, address: addr})
+			}
+		}
+	}
+	sort.Slice(spaces, func(i, j int) bool { return spaces[i].alias < spaces[j].alias })
+	return spaces, complete
+}
+
+func listSpaces(ctx context.Context, addr, spaceType string) ([]*provider.StorageSpace, error) {
+	c, err := pool.GetStorageProviderServiceClient(addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "spaces: error getting storage provider client")
+	}
+	res, err := c.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: spaceType},
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "spaces: error calling ListStorageSpaces")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(res.Status.Code, "spaces")
+	}
+	for _, s := range res.StorageSpaces {
+		if s.SpaceType == "" {
+			s.SpaceType = spaceType
+		}
+	}
+	return res.StorageSpaces, nil
+}
+
+func (r *reg) ListProviders(ctx context.Context) ([]*registrypb.ProviderInfo, error) {
+	return r.ListSpaceProviders(ctx)
+}
+
+func (r *reg) ListSpaceProviders(ctx context.Context, spaceTypes ...string) ([]*registrypb.ProviderInfo, error) {
+	providers := []*registrypb.ProviderInfo{}
+	for _, s := range r.catalog(ctx) {
+		if len(spaceTypes) == 0 || contains(spaceTypes, s.SpaceType) {
+			providers = append(providers, s.info())
+		}
+	}
+	return providers, nil
+}
+
+// GetHome returns the provider of the space of the home type owned by the user.
+func (r *reg) GetHome(ctx context.Context) (*registrypb.ProviderInfo, error) {
+	u, ok := user.ContextGetUser(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("spaces: user not found in context")
+	}
+	for _, s := range r.catalog(ctx) {
+		if s.SpaceType == r.c.HomeSpaceType && utils.UserEqual(s.GetOwner().GetId(), u.Id) {
+			return s.info(), nil
+		}
+	}
+	return nil, errtypes.NotFound("spaces: home not found")
+}
+
+func (r *reg) FindProviders(ctx context.Context, ref *provider.Reference) ([]*registrypb.ProviderInfo, error) {
+	spaces := r.catalog(ctx)
+
+	if fn := ref.GetPath(); fn != "" {
+		fn = path.Clean(fn)
+		// the space with the longest alias containing the path, or all the spaces below it
+		var match *space
+		var below []*registrypb.ProviderInfo
+		for _, s := range spaces {
+			switch {
+			case s.alias == "/" || fn == s.alias || strings.HasPrefix(fn, s.alias+"/"):
+				if match == nil || len(s.alias) > len(match.alias) {
+					match = s
+				}
+			case fn == "/" || strings.HasPrefix(s.alias, fn+"/"):
+				below = append(below, s.info())
+			}
+		}
+		if match != nil {
+			return []*registrypb.ProviderInfo{match.info()}, nil
+		}
+		if len(below) > 0 {
+			return below, nil
+		}
+		return nil, errtypes.NotFound("spaces: storage provider not found for ref " + ref.String())
+	}
+
+	id := ref.GetId()
+	if id == nil {
+		return nil, errtypes.NotFound("spaces: storage provider not found for ref " + ref.String())
+	}
+	// a space is referenced by the id of its root
+	var inStorage *space
+	for _, s := range spaces {
+		if s.GetRoot().GetStorageId() != id.StorageId {
+			continue
+		}
+		if s.GetRoot().GetOpaqueId() == id.OpaqueId {
+			return []*registrypb.ProviderInfo{s.info()}, nil
+		}
+		if inStorage == nil {
+			inStorage = s
+		}
+	}
+	if inStorage != nil {
+		return []*registrypb.ProviderInfo{inStorage.info()}, nil
+	}
+	// resources in the spaces of other users, e.g. shared ones, are found by storage id
+	for addr, p := range r.c.Providers {
+		if p.ProviderID != "" && p.ProviderID == id.StorageId {
+			return []*registrypb.ProviderInfo{{
+				ProviderId: id.StorageId,
+				Address:    addr,
+			}}, nil
+		}
+	}
+	return nil, errtypes.NotFound("spaces: storage provider not found for ref " + ref.String())
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spaces
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/user"
+	"google.golang.org/grpc"
+)
+
+type projects struct {
+	provider.UnimplementedProviderAPIServer
+	calls int32
+}
+
+func (p *projects) ListStorageSpaces(ctx context.Context, req *provider.ListStorageSpacesRequest) (*provider.ListStorageSpacesResponse, error) {
+	atomic.AddInt32(&p.calls, 1)
+	return &provider.ListStorageSpacesResponse{
+		Status: status.NewOK(ctx),
+		StorageSpaces: []*provider.StorageSpace{
+			{Id: &provider.StorageSpaceId{OpaqueId: "p1"}, Name: "physics", Root: &provider.ResourceId{StorageId: "projects", OpaqueId: "r1"}},
+			{Id: &provider.StorageSpaceId{OpaqueId: "p2"}, Name: "chemistry", Root: &provider.ResourceId{StorageId: "projects", OpaqueId: "r2"}},
+		},
+	}, nil
+}
+
+func TestRouting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	fake := &projects{}
+	provider.RegisterProviderAPIServer(srv, fake)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+	addr := l.Addr().String()
+
+	r, err := New(map[string]interface{}{
+		"providers": map[string]interface{}{
+			addr: map[string]interface{}{
+				"provider_id": "projects",
+				"spaces": map[string]interface{}{
+					"project": map[string]interface{}{"alias": "/projects/{{.Name}}"},
+				},
+			},
+			"homes:9142": map[string]interface{}{
+				"provider_id": "homes",
+				"spaces": map[string]interface{}{
+					"personal": map[string]interface{}{"alias": "/users/{{.Owner.Id.OpaqueId}}", "per_user": true},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := user.ContextSetUser(context.Background(), &userpb.User{
+		Id:       &userpb.UserId{Idp: "idp", OpaqueId: "einstein"},
+		Username: "einstein",
+	})
+
+	home, err := r.GetHome(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if home.ProviderPath != "/users/einstein" || home.Address != "homes:9142" {
+		t.Errorf("unexpected home %v", home)
+	}
+
+	tests := []struct {
+		ref   *provider.Reference
+		paths []string
+	}{
+		{&provider.Reference{Spec: &provider.Reference_Path{Path: "/projects/physics/notes.txt"}}, []string{"/projects/physics"}},
+		{&provider.Reference{Spec: &provider.Reference_Path{Path: "/users/einstein"}}, []string{"/users/einstein"}},
+		{&provider.Reference{Spec: &provider.Reference_Path{Path: "/projects"}}, []string{"/projects/chemistry", "/projects/physics"}},
+		{&provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{StorageId: "projects", OpaqueId: "r2"}}}, []string{"/projects/chemistry"}},
+		// resources of the homes of other users only route to the provider
+		{&provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{StorageId: "homes", OpaqueId: "file"}}}, []string{"/users/einstein"}},
+		{&provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{StorageId: "projects", OpaqueId: "file"}}}, []string{"/projects/chemistry"}},
+	}
+	for _, tt := range tests {
+		infos, err := r.FindProviders(ctx, tt.ref)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.ref, err)
+		}
+		var got []string
+		for _, info := range infos {
+			got = append(got, info.ProviderPath)
+		}
+		if len(got) != len(tt.paths) {
+			t.Fatalf("%v: got %v, want %v", tt.ref, got, tt.paths)
+		}
+		for i := range got {
+			if got[i] != tt.paths[i] {
+				t.Errorf("%v: got %v, want %v", tt.ref, got, tt.paths)
+			}
+		}
+	}
+
+	if _, err := r.FindProviders(ctx, &provider.Reference{Spec: &provider.Reference_Path{Path: "/eos"}}); err == nil {
+		t.Error("expected no provider for /eos")
+	}
+
+	infos, err := r.(storage.SpacesRegistry).ListSpaceProviders(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Opaque.Map[storage.SpaceOpaqueKey] == nil {
+		t.Errorf("unexpected project providers %v", infos)
+	}
+	if calls := atomic.LoadInt32(&fake.calls); calls != 1 {
+		t.Errorf("the spaces were listed %d times, want them cached", calls)
+	}
+
+	// without a user only the ids are routed, by storage id
+	infos, err = r.FindProviders(context.Background(), &provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{StorageId: "homes", OpaqueId: "file"}}})
+	if err != nil || len(infos) != 1 || infos[0].Address != "homes:9142" {
+		t.Errorf("unexpected providers %v, %v", infos, err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	registrypb "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage"
@@ -43,9 +44,64 @@ func init() {
 var bracketRegex = regexp.MustCompile(`\[(.*?)\]`)
 
 type rule struct {
-	Mapping string            `mapstructure:"mapping"`
-	Address string            `mapstructure:"address"`
-	Aliases map[string]string `mapstructure:"aliases"`
+	Mapping  string            `mapstructure:"mapping"`
+	Address  string            `mapstructure:"address"`
+	Aliases  map[string]string `mapstructure:"aliases"`
+	Features *features         `mapstructure:"features"`
+	// Replicas are the addresses of the other storage providers serving the
+	// same storage as the one at address.
+	Replicas []string `mapstructure:"replicas"`
+}
+
+// features are the features of a provider advertised in the provider infos
+// listed, e.g. to derive the capabilities announced to the clients.
+type features struct {
+	Recycle         bool     `mapstructure:"recycle"`
+	FileVersions    bool     `mapstructure:"file_versions"`
+	UploadProtocols []string `mapstructure:"upload_protocols"`
+}
+
+// info returns the provider info of the rule, with the features it advertises.
+func (r rule) info(providerPath, addr string) *registrypb.ProviderInfo {
+	info := &registrypb.ProviderInfo{
+		ProviderPath: providerPath,
+		Address:      addr,
+	}
+	if r.Features != nil {
+		info.Features = &registrypb.ProviderInfo_Features{
+			Recycle:      r.Features.Recycle,
+			FileVersions: r.Features.FileVersions,
+		}
+		if len(r.Features.UploadProtocols) > 0 {
+			info.Opaque = &typespb.Opaque{
+				Map: map[string]*typespb.OpaqueEntry{
+					storage.UploadProtocolsOpaqueKey: {
+						Decoder: "plain",
+						Value:   []byte(strings.Join(r.Features.UploadProtocols, ",")),
+					},
+				},
+			}
+		}
+	}
+	return r.withReplicas(info)
+}
+
+// withReplicas adds the replicas of the rule to the provider info.
+func (r rule) withReplicas(info *registrypb.ProviderInfo) *registrypb.ProviderInfo {
+	if len(r.Replicas) == 0 || r.Address == "" {
+		return info
+	}
+	if info.Opaque == nil {
+		info.Opaque = &typespb.Opaque{}
+	}
+	if info.Opaque.Map == nil {
+		info.Opaque.Map = map[string]*typespb.OpaqueEntry{}
+	}
+	info.Opaque.Map[storage.ReplicasOpaqueKey] = &typespb.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(strings.Join(r.Replicas, ",")),
+	}
+	return info
 }
 
 type config struct {
@@ -114,10 +170,7 @@ func (b *reg) ListProviders(ctx context.Context) ([]*registrypb.ProviderInfo, er
 		if addr := getProviderAddr(ctx, v); addr != "" {
 			combs := generateRegexCombinations(k)
 			for _, c := range combs {
-				providers = append(providers, &registrypb.ProviderInfo{
-					ProviderPath: c,
-					Address:      addr,
-				})
+				providers = append(providers, v.info(c, addr))
 			}
 		}
 	}
@@ -153,19 +206,19 @@ func (b *reg) FindProviders(ctx context.Context, ref *provider.Reference) ([]*re
 				continue
 			}
 			if m := r.FindString(fn); m != "" {
-				match = &registrypb.ProviderInfo{
+				match = rule.withReplicas(&registrypb.ProviderInfo{
 					ProviderPath: m,
 					Address:      addr,
-				}
+				})
 			}
 			// Check if the current rule forms a part of a reference spread across storage providers.
 			if strings.HasPrefix(prefix, fn) {
 				combs := generateRegexCombinations(prefix)
 				for _, c := range combs {
-					shardedMatches = append(shardedMatches, &registrypb.ProviderInfo{
+					shardedMatches = append(shardedMatches, rule.withReplicas(&registrypb.ProviderInfo{
 						ProviderPath: c,
 						Address:      addr,
-					})
+					}))
 				}
 			}
 		}
@@ -193,10 +246,10 @@ func (b *reg) FindProviders(ctx context.Context, ref *provider.Reference) ([]*re
 		}
 		// TODO(labkode): fill path info based on provider id, if path and storage id points to same id, take that.
 		if m := r.FindString(id.StorageId); m != "" {
-			return []*registrypb.ProviderInfo{&registrypb.ProviderInfo{
+			return []*registrypb.ProviderInfo{rule.withReplicas(&registrypb.ProviderInfo{
 				ProviderId: id.StorageId,
 				Address:    addr,
-			}}, nil
+			})}, nil
 		}
 	}
 
@@ -67,7 +67,8 @@ var _ = Describe("Static", func() {
 				"address": "project-00",
 			},
 			"/eos/media": map[string]interface{}{
-				"address": "media-00",
+				"address":  "media-00",
+				"replicas": []string{"media-01", "media-02"},
 			},
 			"123e4567-e89b-12d3-a456-426655440000": map[string]interface{}{
 				"address": "home-00-home",
@@ -208,6 +209,22 @@ var _ = Describe("Static", func() {
 		})
 	})
 
+	Describe("FindProviders for replicated reference", func() {
+		ref := &provider.Reference{
+			Spec: &provider.Reference_Path{
+				Path: "/eos/media/video",
+			},
+		}
+
+		It("finds the provider with its replicas", func() {
+			providers, err := handler.FindProviders(ctxAlice, ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(providers).To(HaveLen(1))
+			Expect(providers[0].Address).To(Equal("media-00"))
+			Expect(string(providers[0].Opaque.Map["replicas"].Value)).To(Equal("media-01,media-02"))
+		})
+	})
+
 	Describe("FindProviders for virtual references", func() {
 		ref1 := &provider.Reference{
 			Spec: &provider.Reference_Path{
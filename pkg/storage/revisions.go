@@ -0,0 +1,61 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// RevisionOpaqueKey is the opaque entry of an InitiateFileDownloadRequest
+// selecting the revision of the file to download. It is also the query
+// parameter of the download endpoint the data server reads the revision from.
+const RevisionOpaqueKey = "revision"
+
+// Revision returns the revision set in the given opaque, if any.
+func Revision(o *types.Opaque) string {
+	if o == nil || o.Map == nil || o.Map[RevisionOpaqueKey] == nil {
+		return ""
+	}
+	return string(o.Map[RevisionOpaqueKey].Value)
+}
+
+// RevisionInfo returns the metadata of the revision with the given key of the
+// file with the given metadata: the size, etag and mtime are the ones of the
+// revision, the rest is inherited from the file.
+func RevisionInfo(ctx context.Context, fs FS, ref *provider.Reference, md *provider.ResourceInfo, key string) (*provider.ResourceInfo, error) {
+	revisions, err := fs.ListRevisions(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, rev := range revisions {
+		if rev.Key != key {
+			continue
+		}
+		info := *md
+		info.Size = rev.Size
+		info.Etag = rev.Etag
+		info.Mtime = &types.Timestamp{Seconds: rev.Mtime}
+		return &info, nil
+	}
+	return nil, errtypes.NotFound("revision " + key + " of " + md.Path)
+}
@@ -0,0 +1,66 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// RecursiveSizeKey is the arbitrary metadata key that makes the storage provider
+// add the recursive size and file count of a resource to the opaque of its info,
+// under RecursiveSizeKey and RecursiveFileCountKey.
+const (
+	RecursiveSizeKey      = "reva.recursive_size"
+	RecursiveFileCountKey = "reva.recursive_file_count"
+)
+
+// WalkRecursiveSize returns the size in bytes and the number of files of the tree
+// referenced by ref by listing every folder. It is the default GetRecursiveSize
+// implementation for drivers that can't ask their backend.
+func WalkRecursiveSize(ctx context.Context, fs FS, ref *provider.Reference) (uint64, uint64, error) {
+	md, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	return walkRecursiveSize(ctx, fs, md)
+}
+
+func walkRecursiveSize(ctx context.Context, fs FS, md *provider.ResourceInfo) (uint64, uint64, error) {
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return md.Size, 1, nil
+	}
+
+	children, err := fs.ListFolder(ctx, &provider.Reference{Spec: &provider.Reference_Id{Id: md.Id}}, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var size, files uint64
+	for _, child := range children {
+		s, f, err := walkRecursiveSize(ctx, fs, child)
+		if err != nil {
+			return 0, 0, err
+		}
+		size += s
+		files += f
+	}
+	return size, files, nil
+}
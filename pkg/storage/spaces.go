@@ -0,0 +1,103 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// SpaceTypesOpaqueKey is the opaque key of a ListStorageProvidersRequest
+// holding the comma separated types of the spaces to list the providers of.
+// SpaceOpaqueKey is the opaque key of the provider infos returned by the
+// registries keeping a catalog of storage spaces, holding the JSON encoded
+// space the provider info points to.
+const (
+	SpaceTypesOpaqueKey = "space_types"
+	SpaceOpaqueKey      = "space"
+)
+
+// SpacesRegistry is implemented by the registries keeping a catalog of the
+// storage spaces, which route the references to the space they point into.
+type SpacesRegistry interface {
+	Registry
+	// ListSpaceProviders returns the providers of the spaces of the given
+	// types the user can access, those of all the spaces when none is given.
+	ListSpaceProviders(ctx context.Context, spaceTypes ...string) ([]*registry.ProviderInfo, error)
+}
+
+// TrashRetentionKey is the arbitrary metadata key setting the number of days
+// the items deleted inside a space are kept in its trash bin, set on the root
+// of the space by its managers. 0 keeps them until they are purged.
+const TrashRetentionKey = "reva.trash_retention"
+
+// SpaceRecycler is implemented by the drivers keeping a trash bin per space,
+// shared by all the users who can access the space, rather than per user.
+// Spaces are referenced by the id of their root. Only the managers of a space
+// can purge its trash bin.
+type SpaceRecycler interface {
+	ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error)
+	// RestoreSpaceRecycleItem restores the item to the path relative to the
+	// root of the space, or to its original location when it is empty.
+	RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error
+	PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error
+	EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error
+}
+
+// ListSpaceRecycle lists the trash bin of the space with the SpaceRecycler of
+// fs. Storage wrappers implement SpaceRecycler by calling it and the other
+// space recycle helpers with the storage they wrap, they return an
+// errtypes.NotSupported error when the driver keeps a trash bin per user.
+func ListSpaceRecycle(ctx context.Context, fs FS, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	sr, ok := fs.(SpaceRecycler)
+	if !ok {
+		return nil, errtypes.NotSupported("space recycle")
+	}
+	return sr.ListSpaceRecycle(ctx, root)
+}
+
+// RestoreSpaceRecycleItem restores the item with the SpaceRecycler of fs.
+func RestoreSpaceRecycleItem(ctx context.Context, fs FS, root *provider.ResourceId, key, restorePath string) error {
+	sr, ok := fs.(SpaceRecycler)
+	if !ok {
+		return errtypes.NotSupported("space recycle")
+	}
+	return sr.RestoreSpaceRecycleItem(ctx, root, key, restorePath)
+}
+
+// PurgeSpaceRecycleItem purges the item with the SpaceRecycler of fs.
+func PurgeSpaceRecycleItem(ctx context.Context, fs FS, root *provider.ResourceId, key string) error {
+	sr, ok := fs.(SpaceRecycler)
+	if !ok {
+		return errtypes.NotSupported("space recycle")
+	}
+	return sr.PurgeSpaceRecycleItem(ctx, root, key)
+}
+
+// EmptySpaceRecycle empties the trash bin with the SpaceRecycler of fs.
+func EmptySpaceRecycle(ctx context.Context, fs FS, root *provider.ResourceId) error {
+	sr, ok := fs.(SpaceRecycler)
+	if !ok {
+		return errtypes.NotSupported("space recycle")
+	}
+	return sr.EmptySpaceRecycle(ctx, root)
+}
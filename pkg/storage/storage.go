@@ -34,6 +34,7 @@ type FS interface {
 	CreateDir(ctx context.Context, fn string) error
 	Delete(ctx context.Context, ref *provider.Reference) error
 	Move(ctx context.Context, oldRef, newRef *provider.Reference) error
+	Copy(ctx context.Context, src, dst *provider.Reference) error
 	GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error)
 	ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error)
 	InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error)
@@ -52,12 +53,22 @@ type FS interface {
 	UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error
 	ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error)
 	GetQuota(ctx context.Context) (uint64, uint64, error)
+	GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error)
 	CreateReference(ctx context.Context, path string, targetURI *url.URL) error
 	Shutdown(ctx context.Context) error
 	SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error
 	UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error
 }
 
+// UploadProtocolsOpaqueKey is the opaque key of the provider infos holding the
+// comma separated upload protocols the storage provider supports.
+const UploadProtocolsOpaqueKey = "upload_protocols"
+
+// ReplicasOpaqueKey is the opaque key of the provider infos holding the comma
+// separated addresses of the replicas serving the same storage as the
+// provider, which the gateway may send the idempotent reads to as well.
+const ReplicasOpaqueKey = "replicas"
+
 // Registry is the interface that storage registries implement
 // for discovering storage providers
 type Registry interface {
@@ -0,0 +1,244 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// Composable is implemented by the drivers serving their uploads with the tus
+// protocol, they register the tus extensions they support in the composer.
+type Composable interface {
+	UseIn(composer *tusd.StoreComposer)
+}
+
+// UseIn lets the Composable fs register its tus extensions in the composer.
+// Storage wrappers implement Composable by calling it with the storage they
+// wrap and WrapUploads to apply their policy, it returns an
+// errtypes.NotSupported error when the driver doesn't serve tus uploads.
+func UseIn(fs FS, composer *tusd.StoreComposer) error {
+	c, ok := fs.(Composable)
+	if !ok {
+		return errtypes.NotSupported("tus")
+	}
+	c.UseIn(composer)
+	if composer.Core == nil {
+		return errtypes.NotSupported("tus")
+	}
+	return nil
+}
+
+// UploadReference returns the reference of the resource a tus upload is
+// written to, which the drivers keep in the metadata of the upload.
+func UploadReference(info tusd.FileInfo) *provider.Reference {
+	return &provider.Reference{
+		Spec: &provider.Reference_Path{Path: filepath.Join(info.MetaData["dir"], info.MetaData["filename"])},
+	}
+}
+
+// UploadHooks are the policy a storage wrapper applies to the tus uploads of
+// the storage it wraps.
+type UploadHooks struct {
+	// Write is called before an upload is created, written to, finished,
+	// assembled, terminated or its length declared. An error refuses the
+	// request.
+	Write func(ctx context.Context, info tusd.FileInfo) error
+	// Finished is called once an upload has been written to its resource.
+	Finished func(ctx context.Context, info tusd.FileInfo)
+}
+
+// WrapUploads applies the hooks to the uploads of the data store and of the
+// extensions registered in the composer.
+func WrapUploads(composer *tusd.StoreComposer, hooks UploadHooks) {
+	s := &hookedStore{
+		core:           composer.Core,
+		terminater:     composer.Terminater,
+		concater:       composer.Concater,
+		lengthDeferrer: composer.LengthDeferrer,
+		hooks:          hooks,
+	}
+	composer.UseCore(s)
+	if composer.UsesTerminater {
+		composer.UseTerminater(s)
+	}
+	if composer.UsesConcater {
+		composer.UseConcater(s)
+	}
+	if composer.UsesLengthDeferrer {
+		composer.UseLengthDeferrer(s)
+	}
+}
+
+type hookedStore struct {
+	core           tusd.DataStore
+	terminater     tusd.TerminaterDataStore
+	concater       tusd.ConcaterDataStore
+	lengthDeferrer tusd.LengthDeferrerDataStore
+	hooks          UploadHooks
+}
+
+func (s *hookedStore) write(ctx context.Context, info tusd.FileInfo) error {
+	if s.hooks.Write == nil {
+		return nil
+	}
+	return tusError(s.hooks.Write(ctx, info))
+}
+
+func (s *hookedStore) NewUpload(ctx context.Context, info tusd.FileInfo) (tusd.Upload, error) {
+	if err := s.write(ctx, info); err != nil {
+		return nil, err
+	}
+	u, err := s.core.NewUpload(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	return &hookedUpload{Upload: u, store: s}, nil
+}
+
+func (s *hookedStore) GetUpload(ctx context.Context, id string) (tusd.Upload, error) {
+	u, err := s.core.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &hookedUpload{Upload: u, store: s}, nil
+}
+
+func (s *hookedStore) AsTerminatableUpload(u tusd.Upload) tusd.TerminatableUpload {
+	h := u.(*hookedUpload)
+	return &terminatableUpload{upload: h, t: s.terminater.AsTerminatableUpload(h.Upload)}
+}
+
+func (s *hookedStore) AsConcatableUpload(u tusd.Upload) tusd.ConcatableUpload {
+	h := u.(*hookedUpload)
+	return &concatableUpload{upload: h, c: s.concater.AsConcatableUpload(h.Upload)}
+}
+
+func (s *hookedStore) AsLengthDeclarableUpload(u tusd.Upload) tusd.LengthDeclarableUpload {
+	h := u.(*hookedUpload)
+	return &lengthDeclarableUpload{upload: h, l: s.lengthDeferrer.AsLengthDeclarableUpload(h.Upload)}
+}
+
+type hookedUpload struct {
+	tusd.Upload
+	store *hookedStore
+}
+
+func (u *hookedUpload) write(ctx context.Context) error {
+	if u.store.hooks.Write == nil {
+		return nil
+	}
+	info, err := u.Upload.GetInfo(ctx)
+	if err != nil {
+		return err
+	}
+	return u.store.write(ctx, info)
+}
+
+func (u *hookedUpload) WriteChunk(ctx context.Context, offset int64, src io.Reader) (int64, error) {
+	if err := u.write(ctx); err != nil {
+		return 0, err
+	}
+	return u.Upload.WriteChunk(ctx, offset, src)
+}
+
+func (u *hookedUpload) FinishUpload(ctx context.Context) error {
+	if u.store.hooks.Write == nil && u.store.hooks.Finished == nil {
+		return u.Upload.FinishUpload(ctx)
+	}
+	// the drivers discard the info of the uploads they finish
+	info, err := u.Upload.GetInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if err := u.store.write(ctx, info); err != nil {
+		return err
+	}
+	if err := u.Upload.FinishUpload(ctx); err != nil {
+		return err
+	}
+	if u.store.hooks.Finished != nil {
+		u.store.hooks.Finished(ctx, info)
+	}
+	return nil
+}
+
+type terminatableUpload struct {
+	upload *hookedUpload
+	t      tusd.TerminatableUpload
+}
+
+func (u *terminatableUpload) Terminate(ctx context.Context) error {
+	if err := u.upload.write(ctx); err != nil {
+		return err
+	}
+	return u.t.Terminate(ctx)
+}
+
+type concatableUpload struct {
+	upload *hookedUpload
+	c      tusd.ConcatableUpload
+}
+
+func (u *concatableUpload) ConcatUploads(ctx context.Context, partials []tusd.Upload) error {
+	if err := u.upload.write(ctx); err != nil {
+		return err
+	}
+	inner := make([]tusd.Upload, 0, len(partials))
+	for _, p := range partials {
+		inner = append(inner, p.(*hookedUpload).Upload)
+	}
+	return u.c.ConcatUploads(ctx, inner)
+}
+
+type lengthDeclarableUpload struct {
+	upload *hookedUpload
+	l      tusd.LengthDeclarableUpload
+}
+
+func (u *lengthDeclarableUpload) DeclareLength(ctx context.Context, length int64) error {
+	if err := u.upload.write(ctx); err != nil {
+		return err
+	}
+	return u.l.DeclareLength(ctx, length)
+}
+
+// tusError gives the errors of the hooks the status tusd answers with.
+func tusError(err error) error {
+	switch err.(type) {
+	case nil:
+		return nil
+	case errtypes.IsNotFound:
+		return tusd.ErrNotFound
+	case errtypes.IsPermissionDenied:
+		return tusd.NewHTTPError(err, http.StatusForbidden)
+	case errtypes.IsTooManyRequests:
+		return tusd.NewHTTPError(err, http.StatusTooManyRequests)
+	case errtypes.IsInsufficientStorage:
+		return tusd.NewHTTPError(err, http.StatusInsufficientStorage)
+	default:
+		return err
+	}
+}
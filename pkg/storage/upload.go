@@ -0,0 +1,98 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// UploadSession describes an upload initiated with InitiateUpload.
+type UploadSession struct {
+	// Ref points to the resource the upload will be written to.
+	Ref *provider.Reference
+	// Size is the expected length of the upload, 0 when it was deferred.
+	Size int64
+	// Partial is set for the partial uploads of the tus concatenation
+	// extension, which only get written to the resource once assembled.
+	Partial bool
+}
+
+// UploadSessionGetter is implemented by drivers whose upload endpoints are
+// identified by an upload id rather than by the path of the resource.
+type UploadSessionGetter interface {
+	GetUploadSession(ctx context.Context, uploadID string) (*UploadSession, error)
+}
+
+// GetUploadSession returns the upload with the UploadSessionGetter of fs.
+// Storage wrappers implement UploadSessionGetter by calling it with the
+// storage they wrap, it returns an errtypes.NotSupported error when the
+// uploads of the driver aren't identified by an upload id.
+func GetUploadSession(ctx context.Context, fs FS, uploadID string) (*UploadSession, error) {
+	g, ok := fs.(UploadSessionGetter)
+	if !ok {
+		return nil, errtypes.NotSupported("get upload session")
+	}
+	return g.GetUploadSession(ctx, uploadID)
+}
+
+// ProcessingOpaqueKey is the key of the resource info opaque entry holding the
+// step of the post-processing a resource is in, while it is kept out of the
+// listings of its folder.
+const ProcessingOpaqueKey = "processing"
+
+// The post-processing steps set by the drivers and the post-processing itself.
+const (
+	// ProcessingPending is the step of the uploaded files waiting for their post-processing.
+	ProcessingPending = "pending"
+	// ProcessingFailed is the step of the files a post-processing step failed on.
+	ProcessingFailed = "failed"
+)
+
+// Processor is implemented by drivers able to keep the uploaded files out of
+// the listings until their post-processing finishes.
+type Processor interface {
+	// SetProcessing records the step of the post-processing the resource is in.
+	SetProcessing(ctx context.Context, ref *provider.Reference, step string) error
+	// FinishProcessing lists the resource in its folder again.
+	FinishProcessing(ctx context.Context, ref *provider.Reference) error
+}
+
+// SetProcessing records the step with the Processor of fs. Storage wrappers
+// implement Processor by calling it and FinishProcessing with the storage they
+// wrap, they return an errtypes.NotSupported error when the driver can't keep
+// files out of the listings.
+func SetProcessing(ctx context.Context, fs FS, ref *provider.Reference, step string) error {
+	p, ok := fs.(Processor)
+	if !ok {
+		return errtypes.NotSupported("set processing")
+	}
+	return p.SetProcessing(ctx, ref, step)
+}
+
+// FinishProcessing lists the resource again with the Processor of fs.
+func FinishProcessing(ctx context.Context, fs FS, ref *provider.Reference) error {
+	p, ok := fs.(Processor)
+	if !ok {
+		return errtypes.NotSupported("finish processing")
+	}
+	return p.FinishProcessing(ctx, ref)
+}
@@ -39,14 +39,15 @@ import (
 // see https://linux.die.net/man/5/nfs4_acl:
 // the extended attributes will look like this
 // "user.oc.grant.<type>:<flags>:<principal>:<permissions>"
-// - *type* will be limited to A for now
+// - *type* will be limited to A and D for now
 //     A: Allow - allow *principal* to perform actions requiring *permissions*
+//     D: Deny - deny *principal* any access, grants without permissions are
+//        stored as D and win over the A entries of the principal
 //   In the future we can use:
 //     U: aUdit - log any attempted access by principal which requires
 //                permissions.
 //     L: aLarm - generate a system alarm at any attempted access by
 //                principal which requires permissions
-//     D: for Deny is not recommended
 // - *flags* for now empty or g for group, no inheritance yet
 //   - d directory-inherit - newly-created subdirectories will inherit the
 //                           ACE.
@@ -131,6 +132,9 @@ func FromGrant(g *provider.Grant) *ACE {
 		permissions: getACEPerm(g.Permissions),
 		// TODO creator ...
 	}
+	if e.permissions == "" {
+		e._type = "D"
+	}
 	if g.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
 		e.flags = "g"
 		e.principal = "g:" + g.Grantee.GetGroupId().OpaqueId
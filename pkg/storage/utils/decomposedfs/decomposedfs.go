@@ -30,10 +30,12 @@ import (
 	"strconv"
 	"strings"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/logger"
+	"github.com/cs3org/reva/pkg/metrics"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
@@ -64,9 +66,12 @@ type Tree interface {
 	Move(ctx context.Context, oldNode *node.Node, newNode *node.Node) (err error)
 	Delete(ctx context.Context, node *node.Node) (err error)
 	RestoreRecycleItemFunc(ctx context.Context, key, restorePath string) (*node.Node, func() error, error)
+	RestoreRecycleItemToFunc(ctx context.Context, key string, target func(rn *node.Node, origin string) (*node.Node, error)) (*node.Node, func() error, error)
 	PurgeRecycleItemFunc(ctx context.Context, key string) (*node.Node, func() error, error)
 
 	WriteBlob(key string, reader io.Reader) error
+	WriteDeduplicatedBlob(key string, reader io.Reader) error
+	ReferenceBlob(key string) error
 	ReadBlob(key string) (io.ReadCloser, error)
 	DeleteBlob(key string) error
 
@@ -80,6 +85,7 @@ type Decomposedfs struct {
 	o            *options.Options
 	p            PermissionsChecker
 	chunkHandler *chunking.ChunkHandler
+	notifier     storage.Notifier
 }
 
 // NewDefault returns an instance with default components
@@ -108,17 +114,54 @@ func New(o *options.Options, lu *Lookup, p PermissionsChecker, tp Tree) (storage
 		return nil, errors.Wrap(err, "could not setup tree")
 	}
 
-	return &Decomposedfs{
+	fs := &Decomposedfs{
 		tp:           tp,
 		lu:           lu,
 		o:            o,
 		p:            p,
 		chunkHandler: chunking.NewChunkHandler(filepath.Join(o.Root, "uploads")),
-	}, nil
+	}
+	metrics.RegisterCollector("storage/decomposedfs/"+o.Root, fs.collect)
+	return fs, nil
 }
 
 // Shutdown shuts down the storage
 func (fs *Decomposedfs) Shutdown(ctx context.Context) error {
+	metrics.UnregisterCollector("storage/decomposedfs/" + fs.o.Root)
+	return nil
+}
+
+// collect reports the upload sessions which were initiated and not finished
+// yet, from their info files.
+func (fs *Decomposedfs) collect() []metrics.Sample {
+	d, err := os.Open(filepath.Join(fs.o.Root, "uploads"))
+	if err != nil {
+		return nil
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil
+	}
+	sessions := 0
+	for _, name := range names {
+		if strings.HasSuffix(name, ".info") {
+			sessions++
+		}
+	}
+	return []metrics.Sample{{
+		Name:   "storage_upload_sessions",
+		Help:   "The upload sessions initiated and not finished yet",
+		Labels: map[string]string{"driver": "decomposedfs", "root": fs.o.Root},
+		Value:  float64(sessions),
+	}}
+}
+
+// Check tells whether the root of the storage can be accessed
+func (fs *Decomposedfs) Check(ctx context.Context) error {
+	if _, err := os.Stat(fs.o.Root); err != nil {
+		return errors.Wrap(err, "Decomposedfs: error accessing root")
+	}
 	return nil
 }
 
@@ -174,6 +217,89 @@ func (fs *Decomposedfs) GetQuota(ctx context.Context) (total uint64, inUse uint6
 	return total, ri.Size, nil
 }
 
+// GetRecursiveSize returns the size and number of files of the tree below the given reference.
+// With treesize accounting it reads the propagated tree size and file count of the node,
+// otherwise it sums up the blob sizes from the node attributes instead of assembling a
+// resource info for every node.
+func (fs *Decomposedfs) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (size uint64, files uint64, err error) {
+	var n *node.Node
+	if n, err = fs.lu.NodeFromResource(ctx, ref); err != nil {
+		return 0, 0, err
+	}
+
+	if !n.Exists {
+		return 0, 0, errtypes.NotFound(filepath.Join(n.ParentID, n.Name))
+	}
+
+	ok, err := fs.p.HasPermission(ctx, n, func(rp *provider.ResourcePermissions) bool {
+		return rp.Stat && rp.ListContainer
+	})
+	switch {
+	case err != nil:
+		return 0, 0, errtypes.InternalError(err.Error())
+	case !ok:
+		return 0, 0, errtypes.PermissionDenied(n.ID)
+	}
+
+	if fs.o.TreeSizeAccounting {
+		if size, files, ok := propagatedSize(n); ok {
+			return size, files, nil
+		}
+	}
+	return recursiveSize(n.InternalPath())
+}
+
+// propagatedSize reads the tree size and file count propagated to a directory node.
+// ok is false for files and for directories that do not carry both attributes yet.
+func propagatedSize(n *node.Node) (size uint64, files uint64, ok bool) {
+	fi, err := os.Stat(n.InternalPath())
+	if err != nil || !fi.IsDir() {
+		return 0, 0, false
+	}
+	if size, err = n.GetTreeSize(); err != nil {
+		return 0, 0, false
+	}
+	if files, err = n.GetTreeFileCount(); err != nil {
+		return 0, 0, false
+	}
+	return size, files, true
+}
+
+func recursiveSize(nodePath string) (size uint64, files uint64, err error) {
+	fi, err := os.Stat(nodePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !fi.IsDir() {
+		blobSize, err := node.ReadBlobSizeAttr(nodePath)
+		if err != nil {
+			return 0, 0, err
+		}
+		return uint64(blobSize), 1, nil
+	}
+
+	f, err := os.Open(nodePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	names, err := f.Readdirnames(0)
+	f.Close()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range names {
+		// children are symlinks to their node, os.Stat and xattr.Get follow them
+		s, c, err := recursiveSize(filepath.Join(nodePath, names[i]))
+		if err != nil {
+			return 0, 0, err
+		}
+		size += s
+		files += c
+	}
+	return size, files, nil
+}
+
 // CreateHome creates a new home node for the given user
 func (fs *Decomposedfs) CreateHome(ctx context.Context) (err error) {
 	if !fs.o.EnableHome || fs.o.UserLayout == "" {
@@ -224,6 +350,66 @@ func (fs *Decomposedfs) GetHome(ctx context.Context) (string, error) {
 	return filepath.Join(fs.o.Root, layout), nil // TODO use a namespace?
 }
 
+// RenameHome moves the home node of a user whose attributes used in the user layout changed
+func (fs *Decomposedfs) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) (err error) {
+	if !fs.o.EnableHome || fs.o.UserLayout == "" {
+		return errtypes.NotSupported("Decomposedfs: RenameHome() home supported disabled")
+	}
+
+	var root, oldNode, parent, newNode *node.Node
+	if root, err = fs.lu.RootNode(ctx); err != nil {
+		return
+	}
+	if oldNode, err = fs.lu.WalkPath(ctx, root, templates.WithUser(oldUser, fs.o.UserLayout), nil); err != nil {
+		return
+	}
+	if !oldNode.Exists {
+		// the home has not been created yet
+		return nil
+	}
+
+	newLayout := templates.WithUser(newUser, fs.o.UserLayout)
+	if dir := filepath.Dir(strings.Trim(newLayout, "/")); dir != "." {
+		parent, err = fs.lu.WalkPath(ctx, root, dir, func(ctx context.Context, n *node.Node) error {
+			if !n.Exists {
+				if err := fs.tp.CreateDir(ctx, n); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	} else {
+		parent = root
+	}
+	if newNode, err = parent.Child(ctx, filepath.Base(newLayout)); err != nil {
+		return
+	}
+	if newNode.ID == oldNode.ID {
+		// the layout does not depend on the changed attributes
+		return nil
+	}
+	if newNode.Exists {
+		return errtypes.AlreadyExists(newLayout)
+	}
+
+	if err = fs.tp.Move(ctx, oldNode, newNode); err != nil {
+		return
+	}
+
+	// the node id is unchanged, only the owner needs to be updated
+	homePath := oldNode.InternalPath()
+	if err = xattr.Set(homePath, xattrs.OwnerIDAttr, []byte(newUser.Id.OpaqueId)); err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not set owner id attribute")
+	}
+	if err = xattr.Set(homePath, xattrs.OwnerIDPAttr, []byte(newUser.Id.Idp)); err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not set owner idp attribute")
+	}
+	return nil
+}
+
 // GetPathByID returns the fn pointed by the file id, without the internal namespace
 func (fs *Decomposedfs) GetPathByID(ctx context.Context, id *provider.ResourceId) (string, error) {
 	node, err := fs.lu.NodeFromID(ctx, id)
@@ -268,6 +454,9 @@ func (fs *Decomposedfs) CreateDir(ctx context.Context, fn string) (err error) {
 			return
 		}
 	}
+	if err == nil {
+		fs.notify(ctx, storage.ChangeCreated, n.ID, nil)
+	}
 	return
 }
 
@@ -349,7 +538,17 @@ func (fs *Decomposedfs) Move(ctx context.Context, oldRef, newRef *provider.Refer
 		return
 	}
 
-	return fs.tp.Move(ctx, oldNode, newNode)
+	from := fs.ancestryBefore(oldNode)
+	if err = fs.tp.Move(ctx, oldNode, newNode); err != nil {
+		return
+	}
+	fs.notify(ctx, storage.ChangeMoved, oldNode.ID, from)
+	return
+}
+
+// Copy copies a resource from one reference to another by streaming its content
+func (fs *Decomposedfs) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	return storage.StreamCopy(ctx, fs, src, dst)
 }
 
 // GetMD returns the metadata for the specified resource
@@ -397,11 +596,19 @@ func (fs *Decomposedfs) ListFolder(ctx context.Context, ref *provider.Reference,
 
 	var children []*node.Node
 	children, err = fs.tp.ListFolder(ctx, n)
-	if err != nil {
+	if err != nil && err != context.DeadlineExceeded {
 		return
 	}
 
 	for i := range children {
+		// return what we have so far when the deadline is exceeded
+		if ctx.Err() == context.DeadlineExceeded {
+			return finfos, ctx.Err()
+		}
+		// uploads are listed once their post-processing finished
+		if fs.o.Postprocessing && children[i].ProcessingStep() != "" {
+			continue
+		}
 		np := rp
 		// add this childs permissions
 		node.AddPermissions(np, n.PermissionSet(ctx))
@@ -409,7 +616,7 @@ func (fs *Decomposedfs) ListFolder(ctx context.Context, ref *provider.Reference,
 			finfos = append(finfos, ri)
 		}
 	}
-	return
+	return finfos, err
 }
 
 // Delete deletes the specified resource
@@ -433,7 +640,12 @@ func (fs *Decomposedfs) Delete(ctx context.Context, ref *provider.Reference) (er
 		return errtypes.PermissionDenied(filepath.Join(node.ParentID, node.Name))
 	}
 
-	return fs.tp.Delete(ctx, node)
+	from := fs.ancestryBefore(node)
+	if err = fs.tp.Delete(ctx, node); err != nil {
+		return
+	}
+	fs.notify(ctx, storage.ChangeDeleted, node.ID, from)
+	return
 }
 
 // Download returns a reader to the specified resource
@@ -21,10 +21,15 @@ package decomposedfs_test
 import (
 	"github.com/stretchr/testify/mock"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs"
 	helpers "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/testhelpers"
 	treemocks "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree/mocks"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	ruser "github.com/cs3org/reva/pkg/user"
+	"github.com/pkg/xattr"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -91,4 +96,165 @@ var _ = Describe("Decomposed", func() {
 			})
 		})
 	})
+
+	Describe("RenameHome", func() {
+		var renamed *userpb.User
+
+		JustBeforeEach(func() {
+			renamed = &userpb.User{
+				Id:       &userpb.UserId{Idp: "idp", OpaqueId: "renamed"},
+				Username: "renamed",
+			}
+		})
+
+		It("moves the home to the path of the renamed user", func() {
+			err := env.Fs.(storage.HomeRenamer).RenameHome(env.Ctx, env.Owner, renamed)
+			Expect(err).ToNot(HaveOccurred())
+
+			old, err := env.Lookup.HomeNode(env.Ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(old.Exists).To(BeFalse())
+
+			h, err := env.Lookup.HomeNode(ruser.ContextSetUser(env.Ctx, renamed))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(h.Exists).To(BeTrue())
+			owner, err := h.Owner()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(owner.OpaqueId).To(Equal("renamed"))
+
+			dir1, err := h.Child(env.Ctx, "dir1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dir1.Exists).To(BeTrue())
+		})
+
+		It("does not overwrite an existing home", func() {
+			err := env.Fs.CreateHome(ruser.ContextSetUser(env.Ctx, renamed))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = env.Fs.(storage.HomeRenamer).RenameHome(env.Ctx, env.Owner, renamed)
+			Expect(err).To(MatchError(ContainSubstring("renamed")))
+		})
+	})
+
+	Describe("SetQuota", func() {
+		It("sets the quota of the home", func() {
+			home := &provider.Reference{Spec: &provider.Reference_Path{Path: "/"}}
+			err := env.Fs.(storage.QuotaSetter).SetQuota(env.Ctx, home, 1024)
+			Expect(err).ToNot(HaveOccurred())
+
+			h, err := env.Lookup.HomeNode(env.Ctx)
+			Expect(err).ToNot(HaveOccurred())
+			v, err := xattr.Get(h.InternalPath(), xattrs.QuotaAttr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(v)).To(Equal("1024"))
+
+			err = env.Fs.(storage.QuotaSetter).SetQuota(env.Ctx, home, 0)
+			Expect(err).ToNot(HaveOccurred())
+			v, err = xattr.Get(h.InternalPath(), xattrs.QuotaAttr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(v)).To(Equal("-3"))
+		})
+
+		It("refuses folders below the homes", func() {
+			err := env.Fs.(storage.QuotaSetter).SetQuota(env.Ctx, ref, 1024)
+			Expect(err).To(MatchError(ContainSubstring("root or on the homes")))
+		})
+	})
+
+	Describe("ListSpaceRecycle", func() {
+		var space *provider.ResourceId
+
+		JustBeforeEach(func() {
+			env.Permissions.On("HasPermission", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+			dir1, err := env.Lookup.NodeFromPath(env.Ctx, "/dir1")
+			Expect(err).ToNot(HaveOccurred())
+			space = &provider.ResourceId{OpaqueId: dir1.ID}
+
+			for _, p := range []string{"/dir1/subdir1", "/emptydir"} {
+				err = env.Fs.Delete(env.Ctx, &provider.Reference{Spec: &provider.Reference_Path{Path: p}})
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("only lists the items deleted inside the space", func() {
+			items, err := env.Fs.(storage.SpaceRecycler).ListSpaceRecycle(env.Ctx, space)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(items)).To(Equal(1))
+			Expect(items[0].Path).To(Equal("/dir1/subdir1"))
+
+			items, err = env.Fs.ListRecycle(env.Ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(items)).To(Equal(2))
+		})
+
+		It("refuses items deleted outside of the space", func() {
+			items, err := env.Fs.ListRecycle(env.Ctx)
+			Expect(err).ToNot(HaveOccurred())
+			for _, item := range items {
+				if item.Path == "/emptydir" {
+					err = env.Fs.(storage.SpaceRecycler).PurgeSpaceRecycleItem(env.Ctx, space, item.Key)
+					Expect(err).To(MatchError(ContainSubstring("not found")))
+				}
+			}
+		})
+
+		It("refuses an invalid retention", func() {
+			err := env.Fs.SetArbitraryMetadata(env.Ctx, &provider.Reference{Spec: &provider.Reference_Id{Id: space}}, &provider.ArbitraryMetadata{
+				Metadata: map[string]string{storage.TrashRetentionKey: "-1"},
+			})
+			Expect(err).To(MatchError(ContainSubstring("number of days")))
+		})
+
+		It("empties the trash bin of the space", func() {
+			err := env.Fs.SetArbitraryMetadata(env.Ctx, &provider.Reference{Spec: &provider.Reference_Id{Id: space}}, &provider.ArbitraryMetadata{
+				Metadata: map[string]string{storage.TrashRetentionKey: "30"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			items, err := env.Fs.(storage.SpaceRecycler).ListSpaceRecycle(env.Ctx, space)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(items)).To(Equal(1))
+
+			err = env.Fs.(storage.SpaceRecycler).EmptySpaceRecycle(env.Ctx, space)
+			Expect(err).ToNot(HaveOccurred())
+			items, err = env.Fs.(storage.SpaceRecycler).ListSpaceRecycle(env.Ctx, space)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(items)).To(Equal(0))
+		})
+	})
+
+	Describe("GetRecursiveSize", func() {
+		JustBeforeEach(func() {
+			env.Permissions.On("HasPermission", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+			file1, err := env.Lookup.NodeFromPath(env.Ctx, "dir1/file1")
+			Expect(err).ToNot(HaveOccurred())
+			err = env.Tree.Propagate(env.Ctx, file1)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reads the propagated tree size and file count", func() {
+			dir1, err := env.Lookup.NodeFromPath(env.Ctx, "dir1")
+			Expect(err).ToNot(HaveOccurred())
+			err = dir1.SetTreeSize(4321)
+			Expect(err).ToNot(HaveOccurred())
+
+			size, files, err := env.Fs.GetRecursiveSize(env.Ctx, ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(4321)))
+			Expect(files).To(Equal(uint64(1)))
+		})
+
+		It("walks the tree when the file count is missing", func() {
+			dir1, err := env.Lookup.NodeFromPath(env.Ctx, "dir1")
+			Expect(err).ToNot(HaveOccurred())
+			err = xattr.Remove(dir1.InternalPath(), xattrs.TreeFileCountAttr)
+			Expect(err).ToNot(HaveOccurred())
+
+			size, files, err := env.Fs.GetRecursiveSize(env.Ctx, ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(1234)))
+			Expect(files).To(Equal(uint64(1)))
+		})
+	})
 })
@@ -26,6 +26,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
 	"github.com/cs3org/reva/pkg/user"
@@ -100,6 +101,12 @@ func (fs *Decomposedfs) SetArbitraryMetadata(ctx context.Context, ref *provider.
 				errs = append(errs, errors.Wrap(errtypes.UserRequired("userrequired"), "error getting user from ctx"))
 			}
 		}
+		if val, ok := md.Metadata[storage.TrashRetentionKey]; ok {
+			delete(md.Metadata, storage.TrashRetentionKey)
+			if err := fs.setTrashRetention(ctx, n, val); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
 	for k, v := range md.Metadata {
 		attrName := xattrs.MetadataPrefix + k
@@ -110,6 +117,7 @@ func (fs *Decomposedfs) SetArbitraryMetadata(ctx context.Context, ref *provider.
 
 	switch len(errs) {
 	case 0:
+		fs.notify(ctx, storage.ChangeModified, n.ID, nil)
 		return fs.tp.Propagate(ctx, n)
 	case 1:
 		// TODO Propagate if anything changed
@@ -173,6 +181,10 @@ func (fs *Decomposedfs) UnsetArbitraryMetadata(ctx context.Context, ref *provide
 					Msg("error getting user from ctx")
 				errs = append(errs, errors.Wrap(errtypes.UserRequired("userrequired"), "error getting user from ctx"))
 			}
+		case storage.TrashRetentionKey:
+			if err := fs.setTrashRetention(ctx, n, ""); err != nil {
+				errs = append(errs, err)
+			}
 		default:
 			if err = xattr.Remove(nodePath, xattrs.MetadataPrefix+k); err != nil {
 				// a non-existing attribute will return an error, which we can ignore
@@ -190,6 +202,7 @@ func (fs *Decomposedfs) UnsetArbitraryMetadata(ctx context.Context, ref *provide
 	}
 	switch len(errs) {
 	case 0:
+		fs.notify(ctx, storage.ChangeModified, n.ID, nil)
 		return fs.tp.Propagate(ctx, n)
 	case 1:
 		// TODO Propagate if anything changed
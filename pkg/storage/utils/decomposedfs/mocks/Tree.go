@@ -230,6 +230,20 @@ func (_m *Tree) ReadBlob(key string) (io.ReadCloser, error) {
 	return r0, r1
 }
 
+// ReferenceBlob provides a mock function with given fields: key
+func (_m *Tree) ReferenceBlob(key string) error {
+	ret := _m.Called(key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RestoreRecycleItemFunc provides a mock function with given fields: ctx, key
 func (_m *Tree) RestoreRecycleItemFunc(ctx context.Context, key string) (*node.Node, func() error, error) {
 	ret := _m.Called(ctx, key)
@@ -289,3 +303,17 @@ func (_m *Tree) WriteBlob(key string, reader io.Reader) error {
 
 	return r0
 }
+
+// WriteDeduplicatedBlob provides a mock function with given fields: key, reader
+func (_m *Tree) WriteDeduplicatedBlob(key string, reader io.Reader) error {
+	ret := _m.Called(key, reader)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, io.Reader) error); ok {
+		r0 = rf(key, reader)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
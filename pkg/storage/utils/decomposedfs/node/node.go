@@ -23,7 +23,6 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
-	"hash"
 	"io"
 	"io/fs"
 	"os"
@@ -44,8 +43,10 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/ace"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/cs3org/reva/pkg/storage/utils/grants"
 	"github.com/cs3org/reva/pkg/user"
 )
 
@@ -545,7 +546,21 @@ func (n *Node) AsResourceInfo(ctx context.Context, rp *provider.ResourcePermissi
 		readChecksumIntoResourceChecksum(ctx, nodePath, storageprovider.XSSHA1, ri)
 		readChecksumIntoOpaque(ctx, nodePath, storageprovider.XSMD5, ri)
 		readChecksumIntoOpaque(ctx, nodePath, storageprovider.XSAdler32, ri)
+		readChecksumIntoOpaque(ctx, nodePath, storageprovider.XSSHA256, ri)
 	}
+	// post-processing
+	if step := n.ProcessingStep(); step != "" {
+		if ri.Opaque == nil {
+			ri.Opaque = &types.Opaque{
+				Map: map[string]*types.OpaqueEntry{},
+			}
+		}
+		ri.Opaque.Map[storage.ProcessingOpaqueKey] = &types.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(step),
+		}
+	}
+
 	// quota
 	if _, ok := mdKeysMap[QuotaKey]; (nodeType == provider.ResourceType_RESOURCE_TYPE_CONTAINER) && returnAllKeys || ok {
 		var quotaPath string
@@ -671,6 +686,30 @@ func (n *Node) HasPropagation() (propagation bool) {
 	return false
 }
 
+// ProcessingStep returns the step of the post-processing of the node, empty
+// when it is not being processed
+func (n *Node) ProcessingStep() string {
+	if b, err := xattr.Get(n.lu.InternalPath(n.ID), xattrs.ProcessingAttr); err == nil {
+		return string(b)
+	}
+	return ""
+}
+
+// SetProcessingStep writes the step of the post-processing of the node to the
+// extended attributes, an empty step ends the processing
+func (n *Node) SetProcessingStep(step string) (err error) {
+	if step != "" {
+		return xattr.Set(n.lu.InternalPath(n.ID), xattrs.ProcessingAttr, []byte(step))
+	}
+	err = xattr.Remove(n.lu.InternalPath(n.ID), xattrs.ProcessingAttr)
+	if e, ok := err.(*xattr.Error); ok && (e.Err.Error() == "no data available" ||
+		// darwin
+		e.Err.Error() == "attribute not found") {
+		return nil
+	}
+	return err
+}
+
 // GetTMTime reads the tmtime from the extended attributes
 func (n *Node) GetTMTime() (tmTime time.Time, err error) {
 	var b []byte
@@ -699,9 +738,35 @@ func (n *Node) SetTreeSize(ts uint64) (err error) {
 	return xattr.Set(n.InternalPath(), xattrs.TreesizeAttr, []byte(strconv.FormatUint(ts, 10)))
 }
 
+// GetTreeFileCount reads the number of files in the tree from the extended attributes
+func (n *Node) GetTreeFileCount() (files uint64, err error) {
+	var b []byte
+	if b, err = xattr.Get(n.InternalPath(), xattrs.TreeFileCountAttr); err != nil {
+		return
+	}
+	return strconv.ParseUint(string(b), 10, 64)
+}
+
+// SetTreeFileCount writes the number of files in the tree to the extended attributes
+func (n *Node) SetTreeFileCount(files uint64) (err error) {
+	return xattr.Set(n.InternalPath(), xattrs.TreeFileCountAttr, []byte(strconv.FormatUint(files, 10)))
+}
+
+// UnsetTreeFileCount removes the tree file count attribute
+func (n *Node) UnsetTreeFileCount() (err error) {
+	if err = xattr.Remove(n.InternalPath(), xattrs.TreeFileCountAttr); err != nil {
+		if e, ok := err.(*xattr.Error); ok && (e.Err.Error() == "no data available" ||
+			// darwin
+			e.Err.Error() == "attribute not found") {
+			return nil
+		}
+	}
+	return err
+}
+
 // SetChecksum writes the checksum with the given checksum type to the extended attributes
-func (n *Node) SetChecksum(csType string, h hash.Hash) (err error) {
-	return xattr.Set(n.lu.InternalPath(n.ID), xattrs.ChecksumPrefix+csType, h.Sum(nil))
+func (n *Node) SetChecksum(csType string, sum []byte) (err error) {
+	return xattr.Set(n.lu.InternalPath(n.ID), xattrs.ChecksumPrefix+csType, sum)
 }
 
 // UnsetTempEtag removes the temporary etag attribute
@@ -718,21 +783,31 @@ func (n *Node) UnsetTempEtag() (err error) {
 
 // ReadUserPermissions will assemble the permissions for the current user on the given node without parent nodes
 func (n *Node) ReadUserPermissions(ctx context.Context, u *userpb.User) (ap *provider.ResourcePermissions, err error) {
+	ap, denied, err := n.readUserPermissions(ctx, u)
+	if denied {
+		return NoPermissions, err
+	}
+	return ap, err
+}
+
+// readUserPermissions assembles the permissions for the user on the node and
+// tells whether a deny grant for the user or one of its groups revokes them.
+func (n *Node) readUserPermissions(ctx context.Context, u *userpb.User) (ap *provider.ResourcePermissions, denied bool, err error) {
 	// check if the current user is the owner
 	o, err := n.Owner()
 	if err != nil {
 		// TODO check if a parent folder has the owner set?
 		appctx.GetLogger(ctx).Error().Err(err).Interface("node", n).Msg("could not determine owner, returning default permissions")
-		return NoPermissions, err
+		return NoPermissions, false, err
 	}
 	if o.OpaqueId == "" {
 		// this happens for root nodes in the storage. the extended attributes are set to emptystring to indicate: no owner
 		// TODO what if no owner is set but grants are present?
-		return NoOwnerPermissions, nil
+		return NoOwnerPermissions, false, nil
 	}
 	if isSameUserID(u.Id, o) {
 		appctx.GetLogger(ctx).Debug().Interface("node", n).Msg("user is owner, returning owner permissions")
-		return OwnerPermissions, nil
+		return OwnerPermissions, false, nil
 	}
 
 	ap = &provider.ResourcePermissions{}
@@ -750,7 +825,7 @@ func (n *Node) ReadUserPermissions(ctx context.Context, u *userpb.User) (ap *pro
 	var grantees []string
 	if grantees, err = n.ListGrantees(ctx); err != nil {
 		appctx.GetLogger(ctx).Error().Err(err).Interface("node", n).Msg("error listing grantees")
-		return nil, err
+		return nil, false, err
 	}
 
 	// instead of making n getxattr syscalls we are going to list the acls and filter them here
@@ -780,6 +855,11 @@ func (n *Node) ReadUserPermissions(ctx context.Context, u *userpb.User) (ap *pro
 
 		switch {
 		case err == nil:
+			// a deny grant wins over the permissions of all other grants
+			if grants.IsDeny(g.GetPermissions()) {
+				appctx.GetLogger(ctx).Debug().Interface("node", n).Str("grant", grantees[i]).Msg("access denied by grant")
+				return NoPermissions, true, nil
+			}
 			AddPermissions(ap, g.GetPermissions())
 		case isNoData(err):
 			err = nil
@@ -792,7 +872,7 @@ func (n *Node) ReadUserPermissions(ctx context.Context, u *userpb.User) (ap *pro
 	}
 
 	appctx.GetLogger(ctx).Debug().Interface("permissions", ap).Interface("node", n).Interface("user", u).Msg("returning aggregated permissions")
-	return ap, nil
+	return ap, false, nil
 }
 
 // ListGrantees lists the grantees of the current node
@@ -27,6 +27,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/cs3org/reva/pkg/storage/utils/grants"
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/pkg/errors"
 	"github.com/pkg/xattr"
@@ -119,7 +120,11 @@ func (p *Permissions) AssemblePermissions(ctx context.Context, n *Node) (ap *pro
 	// for all segments, starting at the leaf
 	for cn.ID != rn.ID {
 
-		if np, err := cn.ReadUserPermissions(ctx, u); err == nil {
+		if np, denied, err := cn.readUserPermissions(ctx, u); err == nil {
+			if denied {
+				// a deny grant on the node or one of its parents wins
+				return NoPermissions, nil
+			}
 			AddPermissions(ap, np)
 		} else {
 			appctx.GetLogger(ctx).Error().Err(err).Interface("node", cn).Msg("error reading permissions")
@@ -158,7 +163,8 @@ func AddPermissions(l *provider.ResourcePermissions, r *provider.ResourcePermiss
 	l.UpdateGrant = l.UpdateGrant || r.UpdateGrant
 }
 
-// HasPermission call check() for every node up to the root until check returns true
+// HasPermission call check() for every node up to the root until check returns true.
+// A deny grant for the user or one of its groups on any of the nodes wins.
 func (p *Permissions) HasPermission(ctx context.Context, n *Node, check func(*provider.ResourcePermissions) bool) (can bool, err error) {
 
 	var u *userv1beta1.User
@@ -183,6 +189,8 @@ func (p *Permissions) HasPermission(ctx context.Context, n *Node, check func(*pr
 	}
 
 	var g *provider.Grant
+	// all segments have to be checked for deny grants, even if a grant passed the check
+	allowed := false
 	// for all segments, starting at the leaf
 	for cn.ID != rn.ID {
 
@@ -215,8 +223,12 @@ func (p *Permissions) HasPermission(ctx context.Context, n *Node, check func(*pr
 			switch {
 			case err == nil:
 				appctx.GetLogger(ctx).Debug().Interface("node", cn).Str("grant", grantees[i]).Interface("permissions", g.GetPermissions()).Msg("checking permissions")
+				if grants.IsDeny(g.GetPermissions()) {
+					appctx.GetLogger(ctx).Debug().Interface("node", cn).Str("grant", grantees[i]).Msg("access denied by grant")
+					return false, nil
+				}
 				if check(g.GetPermissions()) {
-					return true, nil
+					allowed = true
 				}
 			case isNoData(err):
 				err = nil
@@ -232,6 +244,10 @@ func (p *Permissions) HasPermission(ctx context.Context, n *Node, check func(*pr
 		}
 	}
 
+	if allowed {
+		return true, nil
+	}
+
 	appctx.GetLogger(ctx).Debug().Interface("permissions", NoPermissions).Interface("node", n).Interface("user", u).Msg("no grant found, returning default permissions")
 	return false, nil
 }
@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/cs3org/reva/pkg/crypto"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 )
@@ -51,6 +52,24 @@ type Options struct {
 
 	// set an owner for the root node
 	Owner string `mapstructure:"owner"`
+
+	// TrashRetention is the number of days the items deleted inside a space are
+	// kept in its trash bin, unless the space sets its own retention. 0 keeps
+	// them until they are purged.
+	TrashRetention int `mapstructure:"trash_retention"`
+
+	// Checksums are the algorithms computed in a single pass on upload and
+	// stored with the files, defaults to sha1, md5 and adler32. sha256 is also supported.
+	Checksums []string `mapstructure:"checksums"`
+
+	// Postprocessing keeps the uploaded files out of the listings until the
+	// post-processing of the dataprovider finishes them.
+	Postprocessing bool `mapstructure:"postprocessing"`
+
+	// Deduplication stores identical uploads only once. The blobs are addressed
+	// by the sha256 of their content and deleted when the last file or revision
+	// referencing them is purged.
+	Deduplication bool `mapstructure:"deduplication"`
 }
 
 // New returns a new Options instance for the given configuration
@@ -73,6 +92,16 @@ func New(m map[string]interface{}) (*Options, error) {
 	// ensure share folder always starts with slash
 	o.ShareFolder = filepath.Join("/", o.ShareFolder)
 
+	if len(o.Checksums) == 0 {
+		o.Checksums = append([]string{}, crypto.DefaultChecksums...)
+	}
+	for i := range o.Checksums {
+		o.Checksums[i] = strings.ToLower(o.Checksums[i])
+		if !crypto.IsSupportedChecksum(o.Checksums[i]) {
+			return nil, errors.New("unsupported checksum algorithm: " + o.Checksums[i])
+		}
+	}
+
 	// c.DataDirectory should never end in / unless it is the root
 	o.Root = filepath.Clean(o.Root)
 
@@ -0,0 +1,78 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"path/filepath"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/pkg/errors"
+)
+
+// SetProcessing records the step of the post-processing of an uploaded file
+func (fs *Decomposedfs) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	n, err := fs.processingNode(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if step == "" {
+		return errtypes.BadRequest("Decomposedfs: the post-processing step must not be empty")
+	}
+	return n.SetProcessingStep(step)
+}
+
+// FinishProcessing ends the post-processing of an uploaded file, listing it in its parent
+func (fs *Decomposedfs) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	n, err := fs.processingNode(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := n.SetProcessingStep(""); err != nil {
+		return err
+	}
+	// the upload only shows up now
+	fs.notify(ctx, storage.ChangeCreated, n.ID, nil)
+	return fs.tp.Propagate(ctx, n)
+}
+
+func (fs *Decomposedfs) processingNode(ctx context.Context, ref *provider.Reference) (*node.Node, error) {
+	n, err := fs.lu.NodeFromResource(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "Decomposedfs: error resolving ref")
+	}
+	if !n.Exists {
+		return nil, errtypes.NotFound(filepath.Join(n.ParentID, n.Name))
+	}
+
+	// the post-processing changes the content the users see, like an upload
+	ok, err := fs.p.HasPermission(ctx, n, func(rp *provider.ResourcePermissions) bool {
+		return rp.InitiateFileUpload
+	})
+	switch {
+	case err != nil:
+		return nil, errtypes.InternalError(err.Error())
+	case !ok:
+		return nil, errtypes.PermissionDenied(filepath.Join(n.ParentID, n.Name))
+	}
+	return n, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
+)
+
+// SetQuota sets the quota of the root node, or of a home, the nodes the
+// quotas are read from. With the default user layout the homes are the nodes
+// right below the root. The storage provider only lets the quota admins call
+// it, so the permissions on the node are not checked.
+func (fs *Decomposedfs) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	n, err := fs.lu.NodeFromResource(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "Decomposedfs: error resolving ref")
+	}
+	if !n.Exists {
+		return errtypes.NotFound(filepath.Join(n.ParentID, n.Name))
+	}
+
+	root, err := fs.lu.RootNode(ctx)
+	if err != nil {
+		return err
+	}
+	if n.ID != root.ID && n.ParentID != root.ID {
+		return errtypes.BadRequest("Decomposedfs: quotas can only be set on the root or on the homes")
+	}
+	fi, err := os.Stat(n.InternalPath())
+	if err != nil {
+		return errors.Wrap(err, "Decomposedfs: error reading node")
+	}
+	if !fi.IsDir() {
+		return errtypes.BadRequest("Decomposedfs: quotas can only be set on folders")
+	}
+
+	v := node.QuotaUnlimited
+	if maxBytes > 0 {
+		v = strconv.FormatUint(maxBytes, 10)
+	}
+	if err := xattr.Set(n.InternalPath(), xattrs.QuotaAttr, []byte(v)); err != nil {
+		return errors.Wrap(err, "Decomposedfs: error setting quota")
+	}
+	return nil
+}
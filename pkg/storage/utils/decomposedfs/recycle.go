@@ -29,6 +29,7 @@ import (
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
 	"github.com/cs3org/reva/pkg/user"
@@ -66,6 +67,35 @@ func (fs *Decomposedfs) ListRecycle(ctx context.Context) (items []*provider.Recy
 		}
 	}
 
+	return fs.readTrash(ctx, trashRoot, func(nodePath, name string) bool {
+		// TODO filter results by permission ... on the original parent? or the trashed node?
+		// if it were on the original parent it would be possible to see files that were trashed before the current user got access
+		// so -> check the trash node itself
+		// hmm listing trash currently lists the current users trash or the 'root' trash. from ocs only the home storage is queried for trash items.
+		// for now we can only really check if the current user is the owner
+		attrBytes, err := xattr.Get(nodePath, xattrs.OwnerIDAttr)
+		if err != nil {
+			log.Error().Err(err).Str("trashRoot", trashRoot).Str("name", name).Msg("could not read owner, skipping")
+			return false
+		}
+		if fs.o.EnableHome {
+			u := user.ContextMustGetUser(ctx)
+			if u.Id.OpaqueId != string(attrBytes) {
+				log.Warn().Str("trashRoot", trashRoot).Str("name", name).Msg("trash item not owned by current user, skipping")
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// readTrash returns the items of the trash folder the keep func returns true
+// for, given the internal path of the deleted node and the name of the item.
+func (fs *Decomposedfs) readTrash(ctx context.Context, trashRoot string, keep func(nodePath, name string) bool) ([]*provider.RecycleItem, error) {
+	log := appctx.GetLogger(ctx)
+
+	items := make([]*provider.RecycleItem, 0)
+
 	f, err := os.Open(trashRoot)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -80,11 +110,12 @@ func (fs *Decomposedfs) ListRecycle(ctx context.Context) (items []*provider.Recy
 		return nil, err
 	}
 	for i := range names {
-		var trashnode string
-		trashnode, err = os.Readlink(filepath.Join(trashRoot, names[i]))
+		if ctx.Err() == context.DeadlineExceeded {
+			return items, ctx.Err()
+		}
+		trashnode, err := os.Readlink(filepath.Join(trashRoot, names[i]))
 		if err != nil {
 			log.Error().Err(err).Str("trashRoot", trashRoot).Str("name", names[i]).Msg("error reading trash link, skipping")
-			err = nil
 			continue
 		}
 		parts := strings.SplitN(filepath.Base(trashnode), ".T.", 2)
@@ -115,34 +146,18 @@ func (fs *Decomposedfs) ListRecycle(ctx context.Context) (items []*provider.Recy
 		}
 
 		// lookup origin path in extended attributes
-		var attrBytes []byte
-		if attrBytes, err = xattr.Get(nodePath, xattrs.TrashOriginAttr); err == nil {
+		if attrBytes, err := xattr.Get(nodePath, xattrs.TrashOriginAttr); err == nil {
 			item.Path = string(attrBytes)
 		} else {
 			log.Error().Err(err).Str("trashRoot", trashRoot).Str("name", names[i]).Str("link", trashnode).Msg("could not read origin path, skipping")
 			continue
 		}
-		// TODO filter results by permission ... on the original parent? or the trashed node?
-		// if it were on the original parent it would be possible to see files that were trashed before the current user got access
-		// so -> check the trash node itself
-		// hmm listing trash currently lists the current users trash or the 'root' trash. from ocs only the home storage is queried for trash items.
-		// for now we can only really check if the current user is the owner
-		if attrBytes, err = xattr.Get(nodePath, xattrs.OwnerIDAttr); err == nil {
-			if fs.o.EnableHome {
-				u := user.ContextMustGetUser(ctx)
-				if u.Id.OpaqueId != string(attrBytes) {
-					log.Warn().Str("trashRoot", trashRoot).Str("name", names[i]).Str("link", trashnode).Msg("trash item not owned by current user, skipping")
-					continue
-				}
-			}
-		} else {
-			log.Error().Err(err).Str("trashRoot", trashRoot).Str("name", names[i]).Str("link", trashnode).Msg("could not read owner, skipping")
-			continue
-		}
 
-		items = append(items, item)
+		if keep(nodePath, names[i]) {
+			items = append(items, item)
+		}
 	}
-	return
+	return items, nil
 }
 
 // RestoreRecycleItem restores the specified item
@@ -164,7 +179,11 @@ func (fs *Decomposedfs) RestoreRecycleItem(ctx context.Context, key, restorePath
 	}
 
 	// Run the restore func
-	return restoreFunc()
+	if err := restoreFunc(); err != nil {
+		return err
+	}
+	fs.notify(ctx, storage.ChangeCreated, rn.ID, nil)
+	return nil
 }
 
 // PurgeRecycleItem purges the specified item
@@ -29,8 +29,11 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
 	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
 )
 
 // Revision entries are stored inside the node folder and start with the same uuid as the current version.
@@ -124,13 +127,23 @@ func (fs *Decomposedfs) DownloadRevision(ctx context.Context, ref *provider.Refe
 
 	contentPath := fs.lu.InternalPath(revisionKey)
 
-	r, err := os.Open(contentPath)
-	if err != nil {
+	if _, err = os.Stat(contentPath); err != nil {
 		if os.IsNotExist(err) {
 			return nil, errtypes.NotFound(contentPath)
 		}
 		return nil, errors.Wrap(err, "Decomposedfs: error opening revision "+revisionKey)
 	}
+
+	// the revision node only carries the metadata, the content lives in the blobstore
+	blobID, err := xattr.Get(contentPath, xattrs.BlobIDAttr)
+	if err != nil {
+		return nil, errors.Wrap(err, "Decomposedfs: error reading blob id of revision "+revisionKey)
+	}
+
+	r, err := fs.tp.ReadBlob(string(blobID))
+	if err != nil {
+		return nil, errors.Wrap(err, "Decomposedfs: error opening revision "+revisionKey)
+	}
 	return r, nil
 }
 
@@ -197,7 +210,18 @@ func (fs *Decomposedfs) RestoreRevision(ctx context.Context, ref *provider.Refer
 			return
 		}
 
-		return fs.copyMD(revisionPath, nodePath)
+		if err = fs.copyMD(revisionPath, nodePath); err != nil {
+			return
+		}
+		// the restored node shares the blob with the revision
+		var blobID []byte
+		if blobID, err = xattr.Get(nodePath, xattrs.BlobIDAttr); err == nil {
+			if err = fs.tp.ReferenceBlob(string(blobID)); err != nil {
+				return
+			}
+		}
+		fs.notify(ctx, storage.ChangeModified, n.ID, nil)
+		return
 	}
 
 	log.Error().Err(err).Interface("ref", ref).Str("originalnode", kp[0]).Str("revisionKey", revisionKey).Msg("original node does not exist")
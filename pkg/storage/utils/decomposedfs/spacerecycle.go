@@ -0,0 +1,306 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
+)
+
+// The trash bin of a space holds the items deleted inside the tree below the
+// root of the space. The deleted nodes are kept in the trash of the owner of
+// their tree, which is also the owner of the space root, so the trash bin of a
+// space is the trash of its owner filtered by walking up from the parents of
+// the deleted nodes to the root of the space. Unlike the trash of a user it is
+// shared by everybody who can access the space, while only the managers of the
+// space, who can change its grants, are allowed to purge it.
+
+// ListSpaceRecycle lists the items deleted inside the space, purging those
+// deleted before the retention of the space.
+func (fs *Decomposedfs) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	log := appctx.GetLogger(ctx)
+
+	rn, err := fs.spaceRoot(ctx, root, func(rp *provider.ResourcePermissions) bool {
+		return rp.ListRecycle
+	})
+	if err != nil {
+		return nil, err
+	}
+	trashRoot, err := fs.spaceTrashRoot(rn)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := fs.readTrash(ctx, trashRoot, func(nodePath, name string) bool {
+		return fs.inSpace(nodePath, rn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	retention := fs.trashRetention(rn)
+	if retention == 0 {
+		return items, nil
+	}
+	expiry := uint64(time.Now().Add(-retention).Unix())
+	kept := items[:0]
+	for _, item := range items {
+		if item.DeletionTime == nil || item.DeletionTime.Seconds >= expiry {
+			kept = append(kept, item)
+			continue
+		}
+		_, purge, err := fs.tp.PurgeRecycleItemFunc(ctx, item.Key)
+		if err == nil {
+			err = purge()
+		}
+		if err != nil {
+			log.Error().Err(err).Str("key", item.Key).Msg("could not purge expired trash item, keeping it")
+			kept = append(kept, item)
+		}
+	}
+	return kept, nil
+}
+
+// RestoreSpaceRecycleItem restores an item deleted inside the space, to the
+// path relative to the root of the space or to its original parent.
+func (fs *Decomposedfs) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	rn, err := fs.spaceRoot(ctx, root, func(rp *provider.ResourcePermissions) bool {
+		return rp.ListRecycle
+	})
+	if err != nil {
+		return err
+	}
+	if err := fs.checkSpaceRecycleItem(rn, key); err != nil {
+		return err
+	}
+
+	dn, restoreFunc, err := fs.tp.RestoreRecycleItemToFunc(ctx, key, func(dn *node.Node, origin string) (*node.Node, error) {
+		if restorePath = strings.Trim(restorePath, "/"); restorePath != "" {
+			return fs.lu.WalkPath(ctx, rn, restorePath, nil)
+		}
+		p, err := node.ReadNode(ctx, fs.lu, dn.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if !p.Exists {
+			return nil, errtypes.NotFound("the original parent of " + key + " does not exist anymore")
+		}
+		return p.Child(ctx, dn.Name)
+	})
+	if err != nil {
+		return err
+	}
+
+	// check permissions of deleted node
+	ok, err := fs.p.HasPermission(ctx, dn, func(rp *provider.ResourcePermissions) bool {
+		return rp.RestoreRecycleItem
+	})
+	switch {
+	case err != nil:
+		return errtypes.InternalError(err.Error())
+	case !ok:
+		return errtypes.PermissionDenied(key)
+	}
+
+	if err := restoreFunc(); err != nil {
+		return err
+	}
+	fs.notify(ctx, storage.ChangeCreated, dn.ID, nil)
+	return nil
+}
+
+// PurgeSpaceRecycleItem purges an item deleted inside the space, only the
+// managers of the space are allowed to.
+func (fs *Decomposedfs) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	rn, err := fs.spaceRoot(ctx, root, isSpaceManager)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkSpaceRecycleItem(rn, key); err != nil {
+		return err
+	}
+
+	_, purgeFunc, err := fs.tp.PurgeRecycleItemFunc(ctx, key)
+	if err != nil {
+		return err
+	}
+	return purgeFunc()
+}
+
+// EmptySpaceRecycle purges all the items deleted inside the space, only the
+// managers of the space are allowed to.
+func (fs *Decomposedfs) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	rn, err := fs.spaceRoot(ctx, root, isSpaceManager)
+	if err != nil {
+		return err
+	}
+	trashRoot, err := fs.spaceTrashRoot(rn)
+	if err != nil {
+		return err
+	}
+	items, err := fs.readTrash(ctx, trashRoot, func(nodePath, name string) bool {
+		return fs.inSpace(nodePath, rn)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		_, purgeFunc, err := fs.tp.PurgeRecycleItemFunc(ctx, item.Key)
+		if err != nil {
+			return err
+		}
+		if err := purgeFunc(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isSpaceManager(rp *provider.ResourcePermissions) bool {
+	return rp.PurgeRecycle && rp.AddGrant
+}
+
+// spaceRoot returns the root node of the space, if the user passes the check.
+func (fs *Decomposedfs) spaceRoot(ctx context.Context, root *provider.ResourceId, check func(*provider.ResourcePermissions) bool) (*node.Node, error) {
+	rn, err := fs.lu.NodeFromID(ctx, root)
+	if err != nil {
+		return nil, errors.Wrap(err, "Decomposedfs: error reading space root")
+	}
+	if !rn.Exists {
+		return nil, errtypes.NotFound(root.OpaqueId)
+	}
+
+	ok, err := fs.p.HasPermission(ctx, rn, check)
+	switch {
+	case err != nil:
+		return nil, errtypes.InternalError(err.Error())
+	case !ok:
+		return nil, errtypes.PermissionDenied(rn.ID)
+	}
+	return rn, nil
+}
+
+// spaceTrashRoot returns the trash folder the items deleted inside the space are linked from.
+func (fs *Decomposedfs) spaceTrashRoot(rn *node.Node) (string, error) {
+	o, err := rn.Owner()
+	if err != nil {
+		return "", err
+	}
+	if o.OpaqueId == "" {
+		// same fallback as in Tree.Delete
+		return filepath.Join(fs.o.Root, "trash", "root"), nil
+	}
+	return filepath.Join(fs.o.Root, "trash", o.OpaqueId), nil
+}
+
+// checkSpaceRecycleItem returns a not found error unless the item with the
+// given key was deleted inside the space.
+func (fs *Decomposedfs) checkSpaceRecycleItem(rn *node.Node, key string) error {
+	trashRoot, err := fs.spaceTrashRoot(rn)
+	if err != nil {
+		return err
+	}
+	kp := strings.SplitN(key, ":", 2)
+	if len(kp) != 2 || kp[0] != filepath.Base(trashRoot) {
+		return errtypes.NotFound(key)
+	}
+	link, err := os.Readlink(filepath.Join(trashRoot, kp[1]))
+	if err != nil || !fs.inSpace(fs.lu.InternalPath(filepath.Base(link)), rn) {
+		return errtypes.NotFound(key)
+	}
+	return nil
+}
+
+// inSpace tells whether the deleted node was inside the tree below the space root.
+func (fs *Decomposedfs) inSpace(nodePath string, rn *node.Node) bool {
+	if rn.ID == "root" {
+		return true
+	}
+	p := nodePath
+	// guard against cycles in corrupted trees
+	for i := 0; i < 1000; i++ {
+		v, err := xattr.Get(p, xattrs.ParentidAttr)
+		if err != nil {
+			return false
+		}
+		switch id := string(v); id {
+		case rn.ID:
+			return true
+		case "", "root":
+			return false
+		default:
+			p = fs.lu.InternalPath(id)
+		}
+	}
+	return false
+}
+
+// trashRetention returns how long the items deleted inside the space are
+// kept, the configured retention unless the space sets its own.
+func (fs *Decomposedfs) trashRetention(rn *node.Node) time.Duration {
+	days := fs.o.TrashRetention
+	if v, err := xattr.Get(rn.InternalPath(), xattrs.TrashRetentionAttr); err == nil {
+		if d, err := strconv.Atoi(string(v)); err == nil && d >= 0 {
+			days = d
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// setTrashRetention sets the number of days the items deleted inside the
+// space rooted at the node are kept, only the managers of the space are
+// allowed to. An empty value resets it to the configured retention.
+func (fs *Decomposedfs) setTrashRetention(ctx context.Context, n *node.Node, v string) error {
+	ok, err := fs.p.HasPermission(ctx, n, isSpaceManager)
+	switch {
+	case err != nil:
+		return errtypes.InternalError(err.Error())
+	case !ok:
+		return errtypes.PermissionDenied(n.ID)
+	}
+
+	if v == "" {
+		err := xattr.Remove(n.InternalPath(), xattrs.TrashRetentionAttr)
+		// a non-existing attribute will return an error, which we can ignore
+		if e, ok := err.(*xattr.Error); ok && (e.Err.Error() == "no data available" ||
+			// darwin
+			e.Err.Error() == "attribute not found") {
+			return nil
+		}
+		return err
+	}
+	if d, err := strconv.Atoi(v); err != nil || d < 0 {
+		return errtypes.BadRequest("Decomposedfs: the trash retention must be a number of days")
+	}
+	return xattr.Set(n.InternalPath(), xattrs.TrashRetentionAttr, []byte(v))
+}
@@ -0,0 +1,210 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tree
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Deduplicated blobs are addressed by their content and may be referenced by
+// several nodes. Their references are counted in files below <root>/blobrefs,
+// blobs without such a file belong to a single node.
+// The counters are guarded by file locks below <root>/blobrefs/.locks, so the
+// storage providers sharing a root count the references consistently.
+
+// WriteDeduplicatedBlob stores the content addressed blob with the given key
+// unless it is already stored and adds a reference to it
+func (t *Tree) WriteDeduplicatedBlob(key string, reader io.Reader) error {
+	if key == "" {
+		return fmt.Errorf("could not write blob, empty key was given")
+	}
+
+	unlock, err := t.lockBlob(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	refs, err := t.readBlobRefs(key)
+	if err != nil {
+		return err
+	}
+	if refs == 0 {
+		if err := t.blobstore.Upload(key, reader); err != nil {
+			return err
+		}
+	}
+	return t.writeBlobRefs(key, refs+1)
+}
+
+// ReferenceBlob adds a reference to a deduplicated blob, e.g. when a revision
+// is restored. Blobs that are not deduplicated are left alone.
+func (t *Tree) ReferenceBlob(key string) error {
+	if key == "" {
+		return nil
+	}
+
+	unlock, err := t.lockBlob(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	refs, err := t.readBlobRefs(key)
+	if err != nil || refs == 0 {
+		return err
+	}
+	return t.writeBlobRefs(key, refs+1)
+}
+
+// releaseBlob drops a reference to the blob and deletes it from the blobstore
+// when it was the last one
+func (t *Tree) releaseBlob(key string) error {
+	unlock, err := t.lockBlob(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	refs, err := t.readBlobRefs(key)
+	if err != nil {
+		return err
+	}
+	if refs > 1 {
+		return t.writeBlobRefs(key, refs-1)
+	}
+
+	if err := t.blobstore.Delete(key); err != nil {
+		return err
+	}
+	if refs == 1 {
+		if err := os.Remove(t.blobRefsPath(key)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Decomposedfs: could not remove blob references of "+key)
+		}
+	}
+	return nil
+}
+
+// lockBlob serializes the reference counting of a blob with the other
+// goroutines and processes. The keys share a fixed set of lock files so that
+// they never have to be removed.
+func (t *Tree) lockBlob(key string) (unlock func(), err error) {
+	unlockKey := t.blobLocks.lock(key)
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	p := filepath.Join(t.root, "blobrefs", ".locks", fmt.Sprintf("%02x", h.Sum32()%256))
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		unlockKey()
+		return nil, errors.Wrap(err, "Decomposedfs: could not create blob locks dir")
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		unlockKey()
+		return nil, errors.Wrap(err, "Decomposedfs: could not open blob lock of "+key)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		unlockKey()
+		return nil, errors.Wrap(err, "Decomposedfs: could not lock blob "+key)
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+		unlockKey()
+	}, nil
+}
+
+func (t *Tree) blobRefsPath(key string) string {
+	return filepath.Join(t.root, "blobrefs", filepath.Clean(filepath.Join("/", key)))
+}
+
+func (t *Tree) readBlobRefs(key string) (uint64, error) {
+	b, err := ioutil.ReadFile(t.blobRefsPath(key))
+	switch {
+	case os.IsNotExist(err):
+		return 0, nil
+	case err != nil:
+		return 0, errors.Wrap(err, "Decomposedfs: could not read blob references of "+key)
+	}
+	refs, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "Decomposedfs: malformed blob references of "+key)
+	}
+	return refs, nil
+}
+
+func (t *Tree) writeBlobRefs(key string, refs uint64) error {
+	p := t.blobRefsPath(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not create blob references dir")
+	}
+	// write to a temporary file first so a crash never leaves a truncated counter
+	tmp := p + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatUint(refs, 10)), 0600); err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not write blob references of "+key)
+	}
+	return os.Rename(tmp, p)
+}
+
+// keyLocks serializes the reference counting of the same blob within the process
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	waiters int
+}
+
+func (l *keyLocks) lock(key string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*keyLock{}
+	}
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.waiters++
+	l.mu.Unlock()
+
+	kl.Lock()
+	return func() {
+		kl.Unlock()
+		l.mu.Lock()
+		kl.waiters--
+		if kl.waiters == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
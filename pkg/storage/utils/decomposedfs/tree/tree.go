@@ -69,6 +69,8 @@ type Tree struct {
 	root               string
 	treeSizeAccounting bool
 	treeTimeAccounting bool
+
+	blobLocks keyLocks
 }
 
 // PermissionCheckFunc defined a function used to check resource permissions
@@ -157,6 +159,16 @@ func (t *Tree) CreateDir(ctx context.Context, n *node.Node) (err error) {
 		return nil
 	}
 
+	if t.treeSizeAccounting {
+		// a new directory is empty, seed the attributes its parent sums up
+		if err = n.SetTreeSize(0); err != nil {
+			return
+		}
+		if err = n.SetTreeFileCount(0); err != nil {
+			return
+		}
+	}
+
 	// make child appear in listings
 	err = os.Symlink("../"+n.ID, filepath.Join(t.lookup.InternalPath(n.ParentID), n.Name))
 	if err != nil {
@@ -255,6 +267,10 @@ func (t *Tree) ListFolder(ctx context.Context, n *node.Node) ([]*node.Node, erro
 	}
 	nodes := []*node.Node{}
 	for i := range names {
+		// return the nodes read so far, the caller decides whether a partial result is acceptable
+		if ctx.Err() == context.DeadlineExceeded {
+			return nodes, ctx.Err()
+		}
 		link, err := os.Readlink(filepath.Join(dir, names[i]))
 		if err != nil {
 			// TODO log
@@ -346,19 +362,26 @@ func (t *Tree) Delete(ctx context.Context, n *node.Node) (err error) {
 
 // RestoreRecycleItemFunc returns a node and a function to restore it from the trash
 func (t *Tree) RestoreRecycleItemFunc(ctx context.Context, key, restorePath string) (*node.Node, func() error, error) {
+	return t.RestoreRecycleItemToFunc(ctx, key, func(rn *node.Node, origin string) (*node.Node, error) {
+		if restorePath == "" {
+			restorePath = origin
+		}
+		return t.lookup.NodeFromPath(ctx, restorePath)
+	})
+}
+
+// RestoreRecycleItemToFunc returns a node and a function to restore it from the trash
+// to the node returned by target, which is given the deleted node and its origin path
+func (t *Tree) RestoreRecycleItemToFunc(ctx context.Context, key string, target func(rn *node.Node, origin string) (*node.Node, error)) (*node.Node, func() error, error) {
 	rn, trashItem, deletedNodePath, origin, err := t.readRecycleItem(ctx, key)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if restorePath == "" {
-		restorePath = origin
-	}
-
 	fn := func() error {
 		// link to origin
 		var n *node.Node
-		n, err = t.lookup.NodeFromPath(ctx, restorePath)
+		n, err = target(rn, origin)
 		if err != nil {
 			return err
 		}
@@ -399,19 +422,12 @@ func (t *Tree) PurgeRecycleItemFunc(ctx context.Context, key string) (*node.Node
 	}
 
 	fn := func() error {
-		if err := os.RemoveAll(deletedNodePath); err != nil {
+		// delete the node with its revisions and descendants, releasing their blobs
+		if err := t.purgeNode(rn.ID, deletedNodePath); err != nil {
 			log.Error().Err(err).Str("deletedNodePath", deletedNodePath).Msg("error deleting trash node")
 			return err
 		}
 
-		// delete blob from blobstore
-		if rn.BlobID != "" {
-			if err = t.DeleteBlob(rn.BlobID); err != nil {
-				log.Error().Err(err).Str("trashItem", trashItem).Msg("error deleting trash item blob")
-				return err
-			}
-		}
-
 		// delete item link in trash
 		if err = os.Remove(trashItem); err != nil {
 			log.Error().Err(err).Str("trashItem", trashItem).Msg("error deleting trash item")
@@ -424,6 +440,64 @@ func (t *Tree) PurgeRecycleItemFunc(ctx context.Context, key string) (*node.Node
 	return rn, fn, nil
 }
 
+// purgeNode deletes the node with the given id stored at nodePath, its
+// revisions and, for folders, its descendants, releasing their blobs
+func (t *Tree) purgeNode(id, nodePath string) error {
+	if fi, err := os.Lstat(nodePath); err == nil && fi.IsDir() {
+		f, err := os.Open(nodePath)
+		if err != nil {
+			return err
+		}
+		names, err := f.Readdirnames(0)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			link, err := os.Readlink(filepath.Join(nodePath, name))
+			if err != nil {
+				continue
+			}
+			childID := filepath.Base(link)
+			if err := t.purgeNode(childID, t.lookup.InternalPath(childID)); err != nil {
+				return err
+			}
+		}
+	}
+
+	revisions, err := filepath.Glob(t.lookup.InternalPath(id) + ".REV.*")
+	if err != nil {
+		return err
+	}
+	for _, rev := range revisions {
+		if err := t.releaseNodeBlob(rev); err != nil {
+			return err
+		}
+		if err := os.Remove(rev); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := t.releaseNodeBlob(nodePath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(nodePath); err != nil {
+		return err
+	}
+	return nil
+}
+
+// releaseNodeBlob deletes the blob referenced by the node or revision stored
+// at the given path, deduplicated blobs are only deleted with their last reference
+func (t *Tree) releaseNodeBlob(nodePath string) error {
+	blobID, err := xattr.Get(nodePath, xattrs.BlobIDAttr)
+	if err != nil || len(blobID) == 0 {
+		// folders and nodes without content have no blob
+		return nil
+	}
+	return t.DeleteBlob(string(blobID))
+}
+
 // Propagate propagates changes to the root of the tree
 func (t *Tree) Propagate(ctx context.Context, n *node.Node) (err error) {
 	sublog := appctx.GetLogger(ctx).With().Interface("node", n).Logger()
@@ -505,8 +579,9 @@ func (t *Tree) Propagate(ctx context.Context, n *node.Node) (err error) {
 			// update the treesize if it differs from the current size
 			updateTreeSize := false
 
-			var treeSize, calculatedTreeSize uint64
-			calculatedTreeSize, err = calculateTreeSize(ctx, n.InternalPath())
+			var treeSize, calculatedTreeSize, calculatedFiles uint64
+			var filesKnown bool
+			calculatedTreeSize, calculatedFiles, filesKnown, err = calculateTreeSize(ctx, n.InternalPath())
 			if err != nil {
 				continue
 			}
@@ -538,6 +613,16 @@ func (t *Tree) Propagate(ctx context.Context, n *node.Node) (err error) {
 					sublog.Debug().Uint64("calculatedTreeSize", calculatedTreeSize).Msg("updated treesize of parent node")
 				}
 			}
+
+			// the file count is only kept while every child directory carries one,
+			// readers walk the tree when it is missing
+			if filesKnown {
+				if err = n.SetTreeFileCount(calculatedFiles); err != nil {
+					sublog.Error().Err(err).Uint64("calculatedFiles", calculatedFiles).Msg("could not update tree file count of parent node")
+				}
+			} else if err = n.UnsetTreeFileCount(); err != nil {
+				sublog.Error().Err(err).Msg("could not remove tree file count of parent node")
+			}
 		}
 	}
 	if err != nil {
@@ -547,53 +632,71 @@ func (t *Tree) Propagate(ctx context.Context, n *node.Node) (err error) {
 	return
 }
 
-func calculateTreeSize(ctx context.Context, nodePath string) (uint64, error) {
-	var size uint64
-
+// calculateTreeSize sums up the sizes and file counts of the children of the given directory.
+// filesKnown is false when a child directory does not carry a file count.
+func calculateTreeSize(ctx context.Context, nodePath string) (size uint64, files uint64, filesKnown bool, err error) {
 	f, err := os.Open(nodePath)
 	if err != nil {
 		appctx.GetLogger(ctx).Error().Err(err).Str("nodepath", nodePath).Msg("could not open dir")
-		return 0, err
+		return 0, 0, false, err
 	}
 	defer f.Close()
 
 	names, err := f.Readdirnames(0)
 	if err != nil {
 		appctx.GetLogger(ctx).Error().Err(err).Str("nodepath", nodePath).Msg("could not read dirnames")
-		return 0, err
+		return 0, 0, false, err
 	}
+	filesKnown = true
 	for i := range names {
 		cPath := filepath.Join(nodePath, names[i])
 		info, err := os.Stat(cPath)
 		if err != nil {
 			appctx.GetLogger(ctx).Error().Err(err).Str("childpath", cPath).Msg("could not stat child entry")
+			filesKnown = false
 			continue // continue after an error
 		}
 		if !info.IsDir() {
 			blobSize, err := node.ReadBlobSizeAttr(cPath)
 			if err != nil {
 				appctx.GetLogger(ctx).Error().Err(err).Str("childpath", cPath).Msg("could not read blobSize xattr")
+				filesKnown = false
 				continue // continue after an error
 			}
 			size += uint64(blobSize)
+			files++
 		} else {
 			// read from attr
 			var b []byte
 			// xattr.Get will follow the symlink
 			if b, err = xattr.Get(cPath, xattrs.TreesizeAttr); err != nil {
 				// TODO recursively descend and recalculate treesize
+				filesKnown = false
 				continue // continue after an error
 			}
 			csize, err := strconv.ParseUint(string(b), 10, 64)
 			if err != nil {
 				// TODO recursively descend and recalculate treesize
+				filesKnown = false
 				continue // continue after an error
 			}
 			size += csize
+
+			if filesKnown {
+				if b, err = xattr.Get(cPath, xattrs.TreeFileCountAttr); err != nil {
+					filesKnown = false
+					continue
+				}
+				cfiles, err := strconv.ParseUint(string(b), 10, 64)
+				if err != nil {
+					filesKnown = false
+					continue
+				}
+				files += cfiles
+			}
 		}
 	}
-	return size, err
-
+	return size, files, filesKnown, nil
 }
 
 // WriteBlob writes a blob to the blobstore
@@ -606,13 +709,14 @@ func (t *Tree) ReadBlob(key string) (io.ReadCloser, error) {
 	return t.blobstore.Download(key)
 }
 
-// DeleteBlob deletes a blob from the blobstore
+// DeleteBlob deletes a blob from the blobstore, deduplicated blobs are only
+// deleted when their last reference is gone
 func (t *Tree) DeleteBlob(key string) error {
 	if key == "" {
 		return fmt.Errorf("could not delete blob, empty key was given")
 	}
 
-	return t.blobstore.Delete(key)
+	return t.releaseBlob(key)
 }
 
 // TODO check if node exists?
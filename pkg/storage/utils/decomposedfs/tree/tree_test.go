@@ -21,6 +21,7 @@ package tree_test
 import (
 	"os"
 	"path"
+	"strings"
 
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
 	helpers "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/testhelpers"
@@ -274,6 +275,34 @@ var _ = Describe("Tree", func() {
 				Expect(size).To(Equal(uint64(101)))
 			})
 
+			It("counts the files", func() {
+				_, err := env.CreateTestFile("file1", "", 1, dir.ID)
+				Expect(err).ToNot(HaveOccurred())
+				file2, err := env.CreateTestFile("file2", "", 100, dir.ID)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = env.Tree.Propagate(env.Ctx, file2)
+				Expect(err).ToNot(HaveOccurred())
+				files, err := dir.GetTreeFileCount()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(files).To(Equal(uint64(2)))
+			})
+
+			It("drops the file count when a child directory has none", func() {
+				subdir, err := env.CreateTestDir("testdir/nocount")
+				Expect(err).ToNot(HaveOccurred())
+				err = xattr.Remove(subdir.InternalPath(), xattrs.TreeFileCountAttr)
+				Expect(err).ToNot(HaveOccurred())
+
+				file, err := env.CreateTestFile("file1", "", 1, dir.ID)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = env.Tree.Propagate(env.Ctx, file)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = dir.GetTreeFileCount()
+				Expect(err).To(HaveOccurred())
+			})
+
 			It("adds the size of child directories", func() {
 				subdir, err := env.CreateTestDir("testdir/200bytes")
 				Expect(err).ToNot(HaveOccurred())
@@ -319,4 +348,37 @@ var _ = Describe("Tree", func() {
 			})
 		})
 	})
+
+	Describe("deduplicated blobs", func() {
+		var key = "sha256-ea8fac7c65fb589b0d53560f5251f74f9e9b243478dcb6b3ea79b5e36449c8d9"
+
+		JustBeforeEach(func() {
+			env.Blobstore.On("Upload", key, mock.Anything).Return(nil)
+			env.Blobstore.On("Delete", key).Return(nil)
+
+			Expect(t.WriteDeduplicatedBlob(key, strings.NewReader("file"))).To(Succeed())
+			Expect(t.WriteDeduplicatedBlob(key, strings.NewReader("file"))).To(Succeed())
+		})
+
+		It("uploads identical content only once", func() {
+			env.Blobstore.AssertNumberOfCalls(GinkgoT(), "Upload", 1)
+		})
+
+		It("deletes the blob with its last reference", func() {
+			Expect(t.DeleteBlob(key)).To(Succeed())
+			env.Blobstore.AssertNotCalled(GinkgoT(), "Delete", key)
+
+			Expect(t.DeleteBlob(key)).To(Succeed())
+			env.Blobstore.AssertCalled(GinkgoT(), "Delete", key)
+			_, err := os.Stat(path.Join(env.Root, "blobrefs", key))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("counts the references added to restored revisions", func() {
+			Expect(t.ReferenceBlob(key)).To(Succeed())
+			Expect(t.DeleteBlob(key)).To(Succeed())
+			Expect(t.DeleteBlob(key)).To(Succeed())
+			env.Blobstore.AssertNotCalled(GinkgoT(), "Delete", key)
+		})
+	})
 })
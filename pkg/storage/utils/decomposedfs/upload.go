@@ -20,25 +20,21 @@ package decomposedfs
 
 import (
 	"context"
-	"crypto/md5"
-	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	"hash"
-	"hash/adler32"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/crypto"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/logger"
+	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
 	"github.com/cs3org/reva/pkg/user"
@@ -50,6 +46,9 @@ import (
 
 var defaultFilePerm = os.FileMode(0664)
 
+// dedupChecksum is the algorithm addressing the deduplicated blobs
+const dedupChecksum = "sha256"
+
 // Upload uploads data to the given resource
 // TODO Upload (and InitiateUpload) needs a way to receive the expected checksum.
 // Maybe in metadata as 'checksum' => 'sha1 aeosvp45w5xaeoe' = lowercase, space separated?
@@ -142,17 +141,19 @@ func (fs *Decomposedfs) InitiateUpload(ctx context.Context, ref *provider.Refere
 		if _, ok := metadata["sizedeferred"]; ok {
 			info.SizeIsDeferred = true
 		}
+		switch metadata["concat"] {
+		case "":
+		case "partial":
+			// partial uploads are only assembled into the node by ConcatUploads
+			info.IsPartial = true
+		default:
+			return nil, errtypes.BadRequest("unsupported upload concatenation: " + metadata["concat"])
+		}
 		if metadata["checksum"] != "" {
-			parts := strings.SplitN(metadata["checksum"], " ", 2)
-			if len(parts) != 2 {
-				return nil, errtypes.BadRequest("invalid checksum format. must be '[algorithm] [checksum]'")
-			}
-			switch parts[0] {
-			case "sha1", "md5", "adler32":
-				info.MetaData["checksum"] = metadata["checksum"]
-			default:
-				return nil, errtypes.BadRequest("unsupported checksum algorithm: " + parts[0])
+			if _, _, err := crypto.ParseChecksum(metadata["checksum"]); err != nil {
+				return nil, err
 			}
+			info.MetaData["checksum"] = metadata["checksum"]
 		}
 	}
 
@@ -285,7 +286,7 @@ func (fs *Decomposedfs) NewUpload(ctx context.Context, info tusd.FileInfo) (uplo
 		ctx:      ctx,
 	}
 
-	if !info.SizeIsDeferred && info.Size == 0 {
+	if !info.SizeIsDeferred && info.Size == 0 && !info.IsPartial {
 		log.Debug().Interface("info", info).Msg("Decomposedfs: finishing upload for empty file")
 		// no need to create info file and finish directly
 		err := u.FinishUpload(ctx)
@@ -357,6 +358,25 @@ func (fs *Decomposedfs) GetUpload(ctx context.Context, id string) (tusd.Upload,
 	}, nil
 }
 
+// GetUploadSession returns the resource and the expected size of the given upload
+func (fs *Decomposedfs) GetUploadSession(ctx context.Context, id string) (*storage.UploadSession, error) {
+	upload, err := fs.GetUpload(ctx, id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(id)
+		}
+		return nil, err
+	}
+	info := upload.(*fileUpload).info
+	return &storage.UploadSession{
+		Ref: &provider.Reference{
+			Spec: &provider.Reference_Path{Path: filepath.Join(info.MetaData["dir"], info.MetaData["filename"])},
+		},
+		Size:    info.Size,
+		Partial: info.IsPartial,
+	}, nil
+}
+
 type fileUpload struct {
 	// info stores the current information about the upload
 	info tusd.FileInfo
@@ -423,6 +443,11 @@ func (upload *fileUpload) writeInfo() error {
 // FinishUpload finishes an upload and moves the file to the internal destination
 func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 
+	// partial uploads are kept until they get concatenated
+	if upload.info.IsPartial {
+		return nil
+	}
+
 	// ensure cleanup
 	defer upload.discardChunk()
 
@@ -458,13 +483,26 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 		Str("targetPath", targetPath).
 		Logger()
 
-	// calculate the checksum of the written bytes
+	// calculate the configured checksums of the written bytes in a single pass
 	// they will all be written to the metadata later, so we cannot omit any of them
-	// TODO only calculate the checksum in sync that was requested to match, the rest could be async ... but the tests currently expect all to be present
+	// the algorithm of the sent checksum is computed as well, even if it is not configured
 	// TODO the hashes all implement BinaryMarshaler so we could try to persist the state for resumable upload. we would neet do keep track of the copied bytes ...
-	sha1h := sha1.New()
-	md5h := md5.New()
-	adler32h := adler32.New()
+	algos := append([]string{}, upload.fs.o.Checksums...)
+	if upload.fs.o.Deduplication {
+		algos = append(algos, dedupChecksum)
+	}
+	if upload.info.MetaData["checksum"] != "" {
+		algo, _, err := crypto.ParseChecksum(upload.info.MetaData["checksum"])
+		if err != nil {
+			return err
+		}
+		algos = append(algos, algo)
+	}
+	checksums, err := crypto.NewChecksums(algos...)
+	if err != nil {
+		return err
+	}
+	checksummed := false
 	{
 		f, err := os.Open(upload.binPath)
 		if err != nil {
@@ -473,40 +511,33 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 		}
 		defer f.Close()
 
-		r1 := io.TeeReader(f, sha1h)
-		r2 := io.TeeReader(r1, md5h)
-
-		if _, err := io.Copy(adler32h, r2); err != nil {
+		if _, err := io.Copy(checksums, f); err != nil {
 			sublog.Err(err).Msg("Decomposedfs: could not copy bytes for checksumming")
+		} else {
+			checksummed = true
 		}
 	}
 	// compare if they match the sent checksum
 	// TODO the tus checksum extension would do this on every chunk, but I currently don't see an easy way to pass in the requested checksum. for now we do it in FinishUpload which is also called for chunked uploads
 	if upload.info.MetaData["checksum"] != "" {
-		parts := strings.SplitN(upload.info.MetaData["checksum"], " ", 2)
-		if len(parts) != 2 {
-			return errtypes.BadRequest("invalid checksum format. must be '[algorithm] [checksum]'")
-		}
-		switch parts[0] {
-		case "sha1":
-			err = upload.checkHash(parts[1], sha1h)
-		case "md5":
-			err = upload.checkHash(parts[1], md5h)
-		case "adler32":
-			err = upload.checkHash(parts[1], adler32h)
-		default:
-			err = errtypes.BadRequest("unsupported checksum algorithm: " + parts[0])
-		}
-		if err != nil {
+		if err := checksums.Verify(upload.info.MetaData["checksum"]); err != nil {
+			upload.discardChunk()
 			return err
 		}
 	}
-	n.BlobID = upload.info.ID // This can be changed to a content hash in the future when reference counting for the blobs was added
+	n.BlobID = upload.info.ID
+	// identical content is stored only once when deduplicating, the blob references are counted by the tree
+	dedup := upload.fs.o.Deduplication && checksummed
+	if dedup {
+		n.BlobID = dedupChecksum + "-" + hex.EncodeToString(checksums.Sum(dedupChecksum))
+	}
 
 	// defer writing the checksums until the node is in place
 
 	// if target exists create new version
+	change := storage.ChangeCreated
 	if fi, err = os.Stat(targetPath); err == nil {
+		change = storage.ChangeModified
 		// versions are stored alongside the actual file, so a rename can be efficient and does not cross storage / partition boundaries
 		versionsPath := upload.fs.lu.InternalPath(n.ID + ".REV." + fi.ModTime().UTC().Format(time.RFC3339Nano))
 
@@ -525,7 +556,11 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 		return err
 	}
 	defer file.Close()
-	err = upload.fs.tp.WriteBlob(n.BlobID, file)
+	if dedup {
+		err = upload.fs.tp.WriteDeduplicatedBlob(n.BlobID, file)
+	} else {
+		err = upload.fs.tp.WriteBlob(n.BlobID, file)
+	}
 	if err != nil {
 		return errors.Wrap(err, "failed to upload file to blostore")
 	}
@@ -545,9 +580,9 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 	}
 
 	// now try write all checksums
-	tryWritingChecksum(&sublog, n, "sha1", sha1h)
-	tryWritingChecksum(&sublog, n, "md5", md5h)
-	tryWritingChecksum(&sublog, n, "adler32", adler32h)
+	for _, algo := range checksums.Algorithms() {
+		tryWritingChecksum(&sublog, n, algo, checksums.Sum(algo))
+	}
 
 	// who will become the owner?  the owner of the parent actually ... not the currently logged in user
 	err = n.WriteMetadata(&userpb.UserId{
@@ -558,6 +593,13 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 		return errors.Wrap(err, "Decomposedfs: could not write metadata")
 	}
 
+	// keep the file out of the listings until the post-processing finishes it
+	if upload.fs.o.Postprocessing {
+		if err = n.SetProcessingStep(storage.ProcessingPending); err != nil {
+			return errors.Wrap(err, "Decomposedfs: could not mark the upload for post-processing")
+		}
+	}
+
 	// link child name to parent if it is new
 	childNameLink := filepath.Join(upload.fs.lu.InternalPath(n.ParentID), n.Name)
 	var link string
@@ -597,21 +639,18 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 
 	n.Exists = true
 
+	// post-processed uploads are notified once they are finished
+	if !upload.fs.o.Postprocessing {
+		upload.fs.notify(upload.ctx, change, n.ID, nil)
+	}
 	return upload.fs.tp.Propagate(upload.ctx, n)
 }
 
-func (upload *fileUpload) checkHash(expected string, h hash.Hash) error {
-	if expected != hex.EncodeToString(h.Sum(nil)) {
-		upload.discardChunk()
-		return errtypes.ChecksumMismatch(fmt.Sprintf("invalid checksum: expected %s got %x", upload.info.MetaData["checksum"], h.Sum(nil)))
-	}
-	return nil
-}
-func tryWritingChecksum(log *zerolog.Logger, n *node.Node, algo string, h hash.Hash) {
-	if err := n.SetChecksum(algo, h); err != nil {
+func tryWritingChecksum(log *zerolog.Logger, n *node.Node, algo string, sum []byte) {
+	if err := n.SetChecksum(algo, sum); err != nil {
 		log.Err(err).
 			Str("csType", algo).
-			Bytes("hash", h.Sum(nil)).
+			Bytes("hash", sum).
 			Msg("Decomposedfs: could not write checksum")
 		// this is not critical, the bytes are there so we will continue
 	}
@@ -696,9 +735,10 @@ func (upload *fileUpload) ConcatUploads(ctx context.Context, uploads []tusd.Uplo
 		if err != nil {
 			return err
 		}
-		defer src.Close()
 
-		if _, err := io.Copy(file, src); err != nil {
+		_, err = io.Copy(file, src)
+		src.Close()
+		if err != nil {
 			return err
 		}
 	}
@@ -706,6 +746,117 @@ func (upload *fileUpload) ConcatUploads(ctx context.Context, uploads []tusd.Uplo
 	return
 }
 
+// ConcatUploads assembles finished partial uploads initiated for the same resource
+// into a final upload and finishes it. The final upload is attributed to the user
+// who initiated the partial uploads, as permissions were checked at that time.
+func (fs *Decomposedfs) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	if len(uploadIDs) == 0 {
+		return "", errtypes.BadRequest("Decomposedfs: no uploads to concatenate")
+	}
+
+	partials := make([]tusd.Upload, 0, len(uploadIDs))
+	var first tusd.FileInfo
+	var size int64
+	for i, id := range uploadIDs {
+		u, err := fs.GetUpload(ctx, id)
+		if err != nil {
+			return "", errtypes.NotFound(id)
+		}
+		info := u.(*fileUpload).info
+		if !info.IsPartial {
+			return "", errtypes.BadRequest("Decomposedfs: not a partial upload: " + id)
+		}
+		if info.SizeIsDeferred || info.Offset != info.Size {
+			return "", errtypes.BadRequest("Decomposedfs: partial upload not finished: " + id)
+		}
+		if i == 0 {
+			first = info
+		} else if !sameUploadTarget(first, info) {
+			return "", errtypes.BadRequest("Decomposedfs: partial uploads belong to different resources or users")
+		}
+		size += info.Size
+		partials = append(partials, u)
+	}
+
+	// use the context of the partial uploads, which carries their user
+	ctx = partials[0].(*fileUpload).ctx
+	log := appctx.GetLogger(ctx)
+
+	if _, err := checkQuota(ctx, fs, uint64(size)); err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	binPath, err := fs.getUploadPath(ctx, id)
+	if err != nil {
+		return "", errors.Wrap(err, "Decomposedfs: error resolving upload path")
+	}
+
+	info := tusd.FileInfo{
+		ID:             id,
+		Size:           size,
+		MetaData:       tusd.MetaData{},
+		IsFinal:        true,
+		PartialUploads: uploadIDs,
+		Storage:        map[string]string{},
+	}
+	for k, v := range first.MetaData {
+		// checksums sent for the partial uploads do not apply to the whole file
+		if k != "checksum" {
+			info.MetaData[k] = v
+		}
+	}
+	for k, v := range first.Storage {
+		info.Storage[k] = v
+	}
+	info.Storage["BinPath"] = binPath
+
+	file, err := os.OpenFile(binPath, os.O_CREATE|os.O_WRONLY, defaultFilePerm)
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+
+	final := &fileUpload{
+		info:     info,
+		binPath:  binPath,
+		infoPath: filepath.Join(fs.o.Root, "uploads", id+".info"),
+		fs:       fs,
+		ctx:      ctx,
+	}
+	if err := final.writeInfo(); err != nil {
+		final.discardChunk()
+		return "", err
+	}
+
+	if err := final.ConcatUploads(ctx, partials); err != nil {
+		final.discardChunk()
+		return "", errors.Wrap(err, "Decomposedfs: error concatenating uploads")
+	}
+	final.info.Offset = size
+
+	if err := final.FinishUpload(ctx); err != nil {
+		return "", err
+	}
+
+	for _, p := range partials {
+		if err := p.(*fileUpload).Terminate(ctx); err != nil {
+			log.Warn().Err(err).Str("uploadid", p.(*fileUpload).info.ID).Msg("Decomposedfs: could not remove partial upload")
+		}
+	}
+
+	return id, nil
+}
+
+func sameUploadTarget(a, b tusd.FileInfo) bool {
+	for _, k := range []string{"NodeId", "NodeParentId", "NodeName", "Idp", "UserId"} {
+		if a.Storage[k] != b.Storage[k] {
+			return false
+		}
+	}
+	return true
+}
+
 func checkQuota(ctx context.Context, fs *Decomposedfs, fileSize uint64) (quotaSufficient bool, err error) {
 	total, inUse, err := fs.GetQuota(ctx)
 	if err != nil {
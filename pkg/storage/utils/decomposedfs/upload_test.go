@@ -147,5 +147,57 @@ var _ = Describe("File uploads", func() {
 				bs.AssertCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
 			})
 		})
+
+		Describe("ConcatUploads", func() {
+			var (
+				partialIds []string
+			)
+
+			JustBeforeEach(func() {
+				partialIds = []string{}
+				for i := 0; i < 2; i++ {
+					uploadIds, err := fs.InitiateUpload(ctx, ref, 5, map[string]string{"concat": "partial"})
+					Expect(err).ToNot(HaveOccurred())
+					partialIds = append(partialIds, uploadIds["tus"])
+				}
+			})
+
+			It("assembles the finished partial uploads in order", func() {
+				bs.On("Upload", mock.AnythingOfType("string"), mock.AnythingOfType("*os.File")).
+					Return(nil).
+					Run(func(args mock.Arguments) {
+						data, err := ioutil.ReadAll(args.Get(1).(io.Reader))
+
+						Expect(err).ToNot(HaveOccurred())
+						Expect(data).To(Equal([]byte("0123456789")))
+					})
+
+				// upload the second chunk first, as parallel clients could
+				for i, chunk := range []string{"56789", "01234"} {
+					id := partialIds[1-i]
+					err := fs.Upload(ctx, &provider.Reference{Spec: &provider.Reference_Path{Path: id}}, ioutil.NopCloser(bytes.NewReader([]byte(chunk))))
+					Expect(err).ToNot(HaveOccurred())
+				}
+				bs.AssertNotCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
+
+				finalID, err := fs.(storage.UploadConcatenator).ConcatUploads(ctx, partialIds)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(finalID).ToNot(BeEmpty())
+				bs.AssertCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
+
+				// the partial uploads are gone once concatenated
+				_, err = fs.(storage.UploadConcatenator).ConcatUploads(ctx, partialIds)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("refuses unfinished partial uploads", func() {
+				err := fs.Upload(ctx, &provider.Reference{Spec: &provider.Reference_Path{Path: partialIds[0]}}, ioutil.NopCloser(bytes.NewReader([]byte("01234"))))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = fs.(storage.UploadConcatenator).ConcatUploads(ctx, partialIds)
+				Expect(err).To(HaveOccurred())
+				bs.AssertNotCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
+			})
+		})
 	})
 })
@@ -0,0 +1,98 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"path/filepath"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+)
+
+// Watch returns the changes made through this storage to the referenced
+// resource and below it until ctx is done.
+func (fs *Decomposedfs) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	n, err := fs.lu.NodeFromResource(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !n.Exists {
+		return nil, errtypes.NotFound(filepath.Join(n.ParentID, n.Name))
+	}
+
+	rp, err := fs.p.AssemblePermissions(ctx, n)
+	switch {
+	case err != nil:
+		return nil, errtypes.InternalError(err.Error())
+	case !rp.Stat:
+		return nil, errtypes.PermissionDenied(n.ID)
+	}
+	return fs.notifier.Watch(ctx, n.ID), nil
+}
+
+// ancestry returns the ids and names of the node and of its parents, which
+// tell the watchers whether a change concerns them.
+func (fs *Decomposedfs) ancestry(n *node.Node) storage.Ancestry {
+	a := storage.Ancestry{{ID: n.ID, Name: n.Name}}
+	for n.ParentID != "" {
+		p, err := n.Parent()
+		if err != nil {
+			// the root has no parent id nor name
+			if p != nil {
+				a = append(a, storage.Ancestor{ID: p.ID, Name: p.Name})
+			}
+			break
+		}
+		a = append(a, storage.Ancestor{ID: p.ID, Name: p.Name})
+		n = p
+	}
+	return a
+}
+
+// notify tells the watchers about a change to the node, which is read again
+// because the change may have renamed or moved it. from is the ancestry the
+// node had before a move or a deletion.
+func (fs *Decomposedfs) notify(ctx context.Context, changeType string, id string, from storage.Ancestry) {
+	if !fs.notifier.Watching() {
+		return
+	}
+	var to storage.Ancestry
+	if changeType == storage.ChangeDeleted {
+		to = from
+	} else {
+		n, err := node.ReadNode(ctx, fs.lu, id)
+		if err != nil || !n.Exists {
+			return
+		}
+		to = fs.ancestry(n)
+	}
+	fs.notifier.Notify(changeType, &provider.ResourceId{OpaqueId: id}, to, from)
+}
+
+// ancestryBefore returns the ancestry of a node about to be moved or deleted
+// when there are watchers to notify.
+func (fs *Decomposedfs) ancestryBefore(n *node.Node) storage.Ancestry {
+	if !fs.notifier.Watching() {
+		return nil
+	}
+	return fs.ancestry(n)
+}
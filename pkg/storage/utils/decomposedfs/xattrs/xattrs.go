@@ -67,9 +67,23 @@ const (
 	// stored as uint64, little endian
 	TreesizeAttr string = OcisPrefix + "treesize"
 
+	// the number of files in the tree below this node,
+	// propagated alongside the treesize and removed when
+	// a child directory does not carry it yet
+	// stored as a readable uint64
+	TreeFileCountAttr string = OcisPrefix + "treefilecount"
+
 	// the quota for the storage space / tree, regardless who accesses it
 	QuotaAttr string = OcisPrefix + "quota"
 
+	// the number of days the items deleted inside the space rooted at this
+	// node are kept in its trash bin, overriding the trash_retention option
+	TrashRetentionAttr string = OcisPrefix + "trash.retention"
+
+	// the step of the post-processing of an uploaded file, which is kept out
+	// of the listings of its parent as long as it is set
+	ProcessingAttr string = OcisPrefix + "processing"
+
 	UserAcePrefix  string = "u:"
 	GroupAcePrefix string = "g:"
 )
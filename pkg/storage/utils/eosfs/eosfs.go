@@ -782,6 +782,107 @@ func (fs *eosfs) GetQuota(ctx context.Context) (uint64, uint64, error) {
 	return qi.AvailableBytes, qi.UsedBytes, nil
 }
 
+// SetQuota sets the quota of the owner of the resource on the configured
+// quota node, keeping the default maximum number of files.
+func (fs *eosfs) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	if maxBytes == 0 {
+		return errtypes.NotSupported("eos: quotas can't be lifted")
+	}
+
+	u, err := getUser(ctx)
+	if err != nil {
+		return errors.Wrap(err, "eos: no user in ctx")
+	}
+
+	p, err := fs.resolve(ctx, u, ref)
+	if err != nil {
+		return errors.Wrap(err, "eos: error resolving reference")
+	}
+
+	rootUID, rootGID, err := fs.getRootUIDAndGID(ctx)
+	if err != nil {
+		return err
+	}
+
+	eosFileInfo, err := fs.c.GetFileInfoByPath(ctx, rootUID, rootGID, fs.wrap(ctx, p))
+	if err != nil {
+		return err
+	}
+
+	client, err := pool.GetGatewayServiceClient(fs.conf.GatewaySvc)
+	if err != nil {
+		return errors.Wrap(err, "eos: error getting gateway grpc client")
+	}
+	getUserResp, err := client.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{
+		Claim: "uid",
+		Value: strconv.FormatUint(eosFileInfo.UID, 10),
+	})
+	if err != nil {
+		return errors.Wrap(err, "eos: error getting owner")
+	}
+	if getUserResp.Status.Code != rpc.Code_CODE_OK {
+		return errors.New("eos: grpc get user failed")
+	}
+
+	err = fs.c.SetQuota(ctx, rootUID, rootGID, &eosclient.SetQuotaInfo{
+		Username:  getUserResp.User.Username,
+		MaxBytes:  maxBytes,
+		MaxFiles:  fs.conf.DefaultQuotaFiles,
+		QuotaNode: fs.conf.QuotaNode,
+	})
+	if err != nil {
+		return errors.Wrap(err, "eosfs: error setting quota")
+	}
+	return nil
+}
+
+// GetRecursiveSize uses the tree size and count that EOS keeps for every container,
+// or the quota node accounting if the reference points to the configured quota node.
+func (fs *eosfs) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	u, err := getUser(ctx)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "eos: no user in ctx")
+	}
+
+	p, err := fs.resolve(ctx, u, ref)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "eos: error resolving reference")
+	}
+
+	if fs.isShareFolder(ctx, p) {
+		return storage.WalkRecursiveSize(ctx, fs, ref)
+	}
+
+	fn := fs.wrap(ctx, p)
+
+	if fs.conf.QuotaNode != "" && path.Clean(fn) == path.Clean(fs.conf.QuotaNode) {
+		rootUID, rootGID, err := fs.getRootUIDAndGID(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		qi, err := fs.c.GetQuota(ctx, u.Username, rootUID, rootGID, fs.conf.QuotaNode)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "eos: error getting quota")
+		}
+		return qi.UsedBytes, qi.UsedInodes, nil
+	}
+
+	uid, gid, err := fs.getUserUIDAndGID(ctx, u)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	eosFileInfo, err := fs.c.GetFileInfoByPath(ctx, uid, gid, fn)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !eosFileInfo.IsDir {
+		return eosFileInfo.Size, 1, nil
+	}
+	return eosFileInfo.TreeSize, eosFileInfo.TreeCount, nil
+}
+
 func (fs *eosfs) getInternalHome(ctx context.Context) (string, error) {
 	if !fs.conf.EnableHome {
 		return "", errtypes.NotSupported("eos: get home not supported")
@@ -1102,6 +1203,10 @@ func (fs *eosfs) Move(ctx context.Context, oldRef, newRef *provider.Reference) e
 	return fs.c.Rename(ctx, uid, gid, oldFn, newFn)
 }
 
+func (fs *eosfs) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	return storage.StreamCopy(ctx, fs, src, dst)
+}
+
 func (fs *eosfs) moveShadow(ctx context.Context, oldPath, newPath string) error {
 	u, err := getUser(ctx)
 	if err != nil {
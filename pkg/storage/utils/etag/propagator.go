@@ -0,0 +1,143 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package etag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Propagator makes the changes of the resources show in the etags of their
+// ancestors, for the drivers deriving the etag of a directory from its mtime
+// on a file system only updating the mtime of the direct parent of a change.
+// The mtimes of the directories from the root down to the parents of the
+// changes are set to the time of the propagation and never go back, so that
+// a sync client comparing the etag of the root finds the deep changes.
+//
+// With a delay, the changes propagated within it are batched and every
+// directory they share, like the root, is touched once.
+type Propagator struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+	timer   *time.Timer
+}
+
+// NewPropagator returns a propagator touching the directories after the given
+// delay, at once when it is not positive.
+func NewPropagator(delay time.Duration) *Propagator {
+	return &Propagator{delay: delay, pending: map[string]struct{}{}}
+}
+
+// Propagate propagates the changes of the leaves, the paths of the changed
+// resources below the local directory root, root included.
+func (p *Propagator) Propagate(root string, leaves ...string) error {
+	dirs := map[string]struct{}{}
+	for _, leaf := range leaves {
+		if err := ancestors(root, leaf, dirs); err != nil {
+			return err
+		}
+	}
+	if p.delay <= 0 {
+		return touch(dirs, time.Now())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for d := range dirs {
+		p.pending[d] = struct{}{}
+	}
+	if p.timer == nil {
+		p.timer = time.AfterFunc(p.delay, func() {
+			if err := p.Flush(); err != nil {
+				log.Error().Err(err).Msg("etag: error propagating changes")
+			}
+		})
+	}
+	return nil
+}
+
+// Flush touches the directories of the pending propagations now.
+func (p *Propagator) Flush() error {
+	p.mu.Lock()
+	dirs := p.pending
+	p.pending = map[string]struct{}{}
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.mu.Unlock()
+	return touch(dirs, time.Now())
+}
+
+// ancestors adds the directories from root down to the parent of leaf.
+func ancestors(root, leaf string, dirs map[string]struct{}) error {
+	root, leaf = filepath.Clean(root), filepath.Clean(leaf)
+	if leaf == root {
+		dirs[root] = struct{}{}
+		return nil
+	}
+	prefix := root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	if !strings.HasPrefix(leaf, prefix) {
+		return errors.New("etag: path " + leaf + " outside root " + root)
+	}
+	for d := filepath.Dir(leaf); ; d = filepath.Dir(d) {
+		dirs[d] = struct{}{}
+		if d == root {
+			return nil
+		}
+	}
+}
+
+func touch(dirs map[string]struct{}, now time.Time) error {
+	var errs []string
+	for d := range dirs {
+		fi, err := os.Stat(d)
+		if err != nil {
+			// the directory may have been removed since
+			if !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		t := now
+		if !t.After(fi.ModTime()) {
+			// changes within the granularity of the clock, or after the mtime
+			// was set in the future, must still change the etag
+			t = fi.ModTime().Add(time.Microsecond)
+		}
+		if err := os.Chtimes(d, t, t); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("etag: error touching directories: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
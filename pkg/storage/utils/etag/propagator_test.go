@@ -0,0 +1,175 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package etag
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mtimes records the mtimes of the directories, which the drivers using the
+// propagator derive the etags of the directories from.
+func mtimes(t *testing.T, dirs ...string) map[string]time.Time {
+	m := map[string]time.Time{}
+	for _, d := range dirs {
+		fi, err := os.Stat(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m[d] = fi.ModTime()
+	}
+	return m
+}
+
+// syncChanged walks the tree like a sync client, descending only into the
+// directories whose etag changed, and returns the changed files it finds.
+func syncChanged(t *testing.T, dir string, before map[string]time.Time, files map[string]time.Time) []string {
+	if fi, err := os.Stat(dir); err != nil || fi.ModTime().Equal(before[dir]) {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed []string
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			changed = append(changed, syncChanged(t, p, before, files)...)
+			continue
+		}
+		if fi, err := os.Stat(p); err == nil && !fi.ModTime().Equal(files[p]) {
+			changed = append(changed, p)
+		}
+	}
+	return changed
+}
+
+func tree(t *testing.T) (root string, dirs []string, file string) {
+	root = t.TempDir()
+	dirs = []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "b"), filepath.Join(root, "a", "b", "c")}
+	if err := os.MkdirAll(dirs[len(dirs)-1], 0700); err != nil {
+		t.Fatal(err)
+	}
+	file = filepath.Join(dirs[len(dirs)-1], "file")
+	if err := ioutil.WriteFile(file, []byte("v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// settle the mtimes in the past, like an idle tree
+	past := time.Now().Add(-time.Hour)
+	for _, p := range append(dirs, file) {
+		if err := os.Chtimes(p, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, dirs, file
+}
+
+func TestPropagateDeepChange(t *testing.T) {
+	root, dirs, file := tree(t)
+	p := NewPropagator(0)
+
+	for i := 0; i < 3; i++ {
+		before := mtimes(t, dirs...)
+		files := mtimes(t, file)
+		// overwriting the file only changes the file, as on a local file system
+		if err := ioutil.WriteFile(file, []byte("v2"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now().Add(time.Duration(i) * time.Nanosecond)
+		if err := os.Chtimes(file, now, now); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Propagate(root, file); err != nil {
+			t.Fatal(err)
+		}
+		if changed := syncChanged(t, root, before, files); len(changed) != 1 || changed[0] != file {
+			t.Fatalf("change %d: sync found %v, want %s", i, changed, file)
+		}
+		after := mtimes(t, dirs...)
+		for _, d := range dirs {
+			if !after[d].After(before[d]) {
+				t.Errorf("change %d: mtime of %s went from %v to %v", i, d, before[d], after[d])
+			}
+		}
+	}
+}
+
+func TestPropagateNeverGoesBack(t *testing.T) {
+	root, dirs, file := tree(t)
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(dirs[1], future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPropagator(0).Propagate(root, file); err != nil {
+		t.Fatal(err)
+	}
+	if m := mtimes(t, dirs[1])[dirs[1]]; !m.After(future) {
+		t.Errorf("mtime of %s is %v, want after %v", dirs[1], m, future)
+	}
+}
+
+func TestPropagateBatched(t *testing.T) {
+	root, dirs, file := tree(t)
+	other := filepath.Join(dirs[1], "other")
+	if err := ioutil.WriteFile(other, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	before := mtimes(t, dirs...)
+
+	p := NewPropagator(time.Hour)
+	if err := p.Propagate(root, file); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Propagate(root, other); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.pending) != len(dirs) {
+		t.Errorf("%d directories pending, want %d", len(p.pending), len(dirs))
+	}
+	if m := mtimes(t, root)[root]; !m.Equal(before[root]) {
+		t.Errorf("root touched before the delay")
+	}
+
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	after := mtimes(t, dirs...)
+	for _, d := range dirs {
+		if !after[d].After(before[d]) {
+			t.Errorf("mtime of %s not propagated", d)
+		}
+	}
+	if len(p.pending) != 0 || p.timer != nil {
+		t.Errorf("propagations still pending after the flush")
+	}
+}
+
+func TestPropagateOutsideRoot(t *testing.T) {
+	root, dirs, _ := tree(t)
+	if err := NewPropagator(0).Propagate(dirs[2], filepath.Join(root, "a", "x")); err == nil {
+		t.Errorf("propagating outside of the root succeeded")
+	}
+	if err := NewPropagator(0).Propagate(root, root); err != nil {
+		t.Errorf("propagating the root: %v", err)
+	}
+}
@@ -24,11 +24,27 @@ import (
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/storage/utils/acl"
+	"github.com/golang/protobuf/proto"
 )
 
+// denyACLPerm is the EOS representation of a deny grant, it revokes the
+// permissions the grantee gets from other entries.
+const denyACLPerm = "!r!w!x!m!u!d"
+
+// IsDeny tells whether a set of ResourcePermissions denies the access to a resource.
+// A deny grant is a grant without any permission, it wins over the permissions
+// the grantee gets from other grants, e.g. of its groups.
+func IsDeny(set *provider.ResourcePermissions) bool {
+	return set != nil && proto.Equal(set, &provider.ResourcePermissions{})
+}
+
 // GetACLPerm generates a string representation of CS3APIs' ResourcePermissions
 // TODO(labkode): fine grained permission controls.
 func GetACLPerm(set *provider.ResourcePermissions) (string, error) {
+	if IsDeny(set) {
+		return denyACLPerm, nil
+	}
+
 	var b strings.Builder
 
 	if set.Stat || set.InitiateFileDownload {
@@ -35,11 +35,13 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/acl"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
+	"github.com/cs3org/reva/pkg/storage/utils/etag"
 	"github.com/cs3org/reva/pkg/storage/utils/grants"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
 	"github.com/cs3org/reva/pkg/user"
@@ -59,6 +61,7 @@ type Config struct {
 	Versions            string `mapstructure:"versions"`
 	Shadow              string `mapstructure:"shadow"`
 	References          string `mapstructure:"references"`
+	PropagationDelay    int    `mapstructure:"propagation_delay"`
 }
 
 func (c *Config) init() {
@@ -95,6 +98,7 @@ type localfs struct {
 	conf         *Config
 	db           *sql.DB
 	chunkHandler *chunking.ChunkHandler
+	propagator   *etag.Propagator
 }
 
 // NewLocalFS returns a storage.FS interface implementation that controls then
@@ -124,10 +128,14 @@ func NewLocalFS(c *Config) (storage.FS, error) {
 		conf:         c,
 		db:           db,
 		chunkHandler: chunking.NewChunkHandler(c.Uploads),
+		propagator:   etag.NewPropagator(time.Duration(c.PropagationDelay) * time.Millisecond),
 	}, nil
 }
 
 func (fs *localfs) Shutdown(ctx context.Context) error {
+	if err := fs.propagator.Flush(); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("localfs: error propagating the pending changes")
+	}
 	err := fs.db.Close()
 	if err != nil {
 		return errors.Wrap(err, "localfs: error closing db connection")
@@ -135,6 +143,17 @@ func (fs *localfs) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// Check tells whether the root and the metadata db can be used.
+func (fs *localfs) Check(ctx context.Context) error {
+	if _, err := os.Stat(fs.conf.Root); err != nil {
+		return errors.Wrap(err, "localfs: error accessing root")
+	}
+	if err := fs.db.PingContext(ctx); err != nil {
+		return errors.Wrap(err, "localfs: error pinging db")
+	}
+	return nil
+}
+
 func (fs *localfs) resolve(ctx context.Context, ref *provider.Reference) (string, error) {
 	if ref.GetPath() != "" {
 		return ref.GetPath(), nil
@@ -756,7 +775,7 @@ func (fs *localfs) CreateDir(ctx context.Context, fn string) error {
 		}
 		return errors.Wrap(err, "localfs: error creating dir "+fn)
 	}
-	return nil
+	return fs.propagate(ctx, fn)
 }
 
 func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
@@ -823,16 +842,21 @@ func (fs *localfs) Move(ctx context.Context, oldRef, newRef *provider.Reference)
 		return errors.Wrap(err, "localfs: error copying metadata")
 	}
 
-	if err := fs.propagate(ctx, newName); err != nil {
-		return err
-	}
-	if err := fs.propagate(ctx, path.Dir(oldName)); err != nil {
+	if err := fs.propagate(ctx, newName, path.Dir(oldName)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+func (fs *localfs) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	return storage.StreamCopy(ctx, fs, src, dst)
+}
+
+func (fs *localfs) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	return storage.WalkRecursiveSize(ctx, fs, ref)
+}
+
 func (fs *localfs) moveReferences(ctx context.Context, oldName, newName string) error {
 
 	if fs.isShareFolderRoot(ctx, oldName) || fs.isShareFolderRoot(ctx, newName) {
@@ -858,10 +882,7 @@ func (fs *localfs) moveReferences(ctx context.Context, oldName, newName string)
 		return errors.Wrap(err, "localfs: error copying metadata")
 	}
 
-	if err := fs.propagate(ctx, newName); err != nil {
-		return err
-	}
-	if err := fs.propagate(ctx, path.Dir(oldName)); err != nil {
+	if err := fs.propagate(ctx, newName, path.Dir(oldName)); err != nil {
 		return err
 	}
 
@@ -1238,32 +1259,25 @@ func (fs *localfs) RestoreRecycleItem(ctx context.Context, restoreKey, restorePa
 	return fs.propagate(ctx, localRestorePath)
 }
 
-func (fs *localfs) propagate(ctx context.Context, leafPath string) error {
-
-	var root string
-	if fs.isShareFolderChild(ctx, leafPath) || strings.HasSuffix(path.Clean(leafPath), fs.conf.ShareFolder) {
-		root = fs.wrapReferences(ctx, "/")
-	} else {
-		root = fs.wrap(ctx, "/")
-	}
-
-	if !strings.HasPrefix(leafPath, root) {
-		return errors.New("internal path: " + leafPath + " outside root: " + root)
-	}
-
-	fi, err := os.Stat(leafPath)
-	if err != nil {
-		return err
+// propagate changes the etags of the ancestors of the changed resources, the
+// mtime of the directories above the parent not being updated by the file
+// system.
+func (fs *localfs) propagate(ctx context.Context, leafPaths ...string) error {
+	leaves := map[string][]string{}
+	for _, leafPath := range leafPaths {
+		var root string
+		if fs.isShareFolderChild(ctx, leafPath) || strings.HasSuffix(path.Clean(leafPath), fs.conf.ShareFolder) {
+			root = fs.wrapReferences(ctx, "/")
+		} else {
+			root = fs.wrap(ctx, "/")
+		}
+		leaves[root] = append(leaves[root], leafPath)
 	}
 
-	parts := strings.Split(strings.TrimPrefix(leafPath, root), "/")
-	// root never ends in / so the split returns an empty first element, which we can skip
-	// we do not need to chmod the last element because it is the leaf path (< and not <= comparison)
-	for i := 1; i < len(parts); i++ {
-		if err := os.Chtimes(root, fi.ModTime(), fi.ModTime()); err != nil {
-			return err
+	for root, l := range leaves {
+		if err := fs.propagator.Propagate(root, l...); err != nil {
+			return errors.Wrap(err, "localfs: error propagating change")
 		}
-		root = path.Join(root, parts[i])
 	}
 	return nil
 }
@@ -33,7 +33,7 @@ import (
 )
 
 // calcEtag will create an etag based on the md5 of
-// - mtime,
+// - mtime, to the nanosecond so that the propagated changes show,
 // - inode (if available),
 // - device (if available) and
 // - size.
@@ -41,7 +41,7 @@ import (
 func calcEtag(ctx context.Context, fi os.FileInfo) string {
 	log := appctx.GetLogger(ctx)
 	h := md5.New()
-	err := binary.Write(h, binary.BigEndian, fi.ModTime().Unix())
+	err := binary.Write(h, binary.BigEndian, fi.ModTime().UnixNano())
 	if err != nil {
 		log.Error().Err(err).Msg("error writing mtime")
 	}
@@ -358,8 +358,7 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 
 	// TODO: set mtime if specified in metadata
 
-	// metadata propagation is left to the storage implementation
-	return err
+	return upload.fs.propagate(upload.ctx, np)
 }
 
 // To implement the termination extension as specified in https://tus.io/protocols/resumable-upload.html#termination
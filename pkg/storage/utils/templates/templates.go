@@ -33,6 +33,7 @@ import (
 
 	"github.com/Masterminds/sprig"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/pkg/errors"
 )
 
@@ -69,6 +70,31 @@ func WithUser(u *userpb.User, tpl string) string {
 	return b.String()
 }
 
+// SpaceData contains the template placeholders for a storage space.
+// For example {{.Name}}, {{.SpaceType}} or {{.Owner.Username}}
+type SpaceData struct {
+	*provider.StorageSpace
+	Owner *UserData
+}
+
+// WithSpace generates a layout based on storage space data.
+func WithSpace(s *provider.StorageSpace, tpl string) (string, error) {
+	tpl = clean(tpl)
+	t, err := template.New("tpl").Funcs(sprig.TxtFuncMap()).Parse(tpl)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing template: space_template:"+tpl)
+	}
+	sd := &SpaceData{StorageSpace: s}
+	if s.Owner != nil {
+		sd.Owner = newUserData(s.Owner)
+	}
+	b := bytes.Buffer{}
+	if err := t.Execute(&b, sd); err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("error executing template: space:%s tpl:%s", s.GetId().GetOpaqueId(), tpl))
+	}
+	return clean(b.String()), nil
+}
+
 func newUserData(u *userpb.User) *UserData {
 	usernameSplit := strings.Split(u.Username, "@")
 	if len(usernameSplit) == 1 {
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 )
 
 type testUnit struct {
@@ -101,6 +102,32 @@ func TestLayout(t *testing.T) {
 	}
 }
 
+func TestSpaceLayout(t *testing.T) {
+	space := &provider.StorageSpace{
+		Id:        &provider.StorageSpaceId{OpaqueId: "4c510ada"},
+		Name:      "Marketing",
+		SpaceType: "project",
+		Owner:     &userpb.User{Username: "michael@somewhere.com"},
+	}
+	for tpl, expected := range map[string]string{
+		"/projects/{{lower .Name}}":          "/projects/marketing",
+		"/{{.SpaceType}}s//{{.Id.OpaqueId}}": "/projects/4c510ada",
+		"/users/{{.Owner.Email.Local}}":      "/users/michael",
+	} {
+		got, err := WithSpace(space, tpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected != got {
+			t.Fatal("expected: " + expected + " got: " + got)
+		}
+	}
+
+	if _, err := WithSpace(space, "{{ .DoesNotExist }}"); err == nil {
+		t.Error("expected an error for an unknown placeholder")
+	}
+}
+
 func TestLayoutPanic(t *testing.T) {
 	assertPanic(t, testBadLayout)
 }
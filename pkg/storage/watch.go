@@ -0,0 +1,384 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// The types of the changes notified to the watchers.
+const (
+	ChangeCreated  = "created"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+	ChangeMoved    = "moved"
+	// ChangeOverflow tells that changes were dropped because the watcher did
+	// not keep up, the tree has to be walked again.
+	ChangeOverflow = "overflow"
+)
+
+// watchBuffer is the number of changes buffered for a watcher.
+const watchBuffer = 256
+
+// DefaultPollInterval is the interval the trees are walked at when none is given.
+const DefaultPollInterval = 30 * time.Second
+
+// ChangeEvent is a change made to a watched resource or below it.
+type ChangeEvent struct {
+	Type string
+	// Path is the path of the changed resource relative to the watched one,
+	// "." for the watched resource itself.
+	Path string
+	// OldPath is the relative path a moved resource comes from.
+	OldPath string
+	ID      *provider.ResourceId
+	Time    time.Time
+}
+
+// Watcher is implemented by the drivers notifying the changes made to their trees.
+type Watcher interface {
+	// Watch sends the changes made to the resource referenced by ref and below
+	// it on the returned channel, which is closed once ctx is done.
+	Watch(ctx context.Context, ref *provider.Reference) (<-chan ChangeEvent, error)
+}
+
+// Watch returns the changes made to the tree referenced by ref, notified by
+// the driver when it is a Watcher and found by walking the tree every interval
+// otherwise.
+func Watch(ctx context.Context, fs FS, ref *provider.Reference, interval time.Duration) (<-chan ChangeEvent, error) {
+	ch, err := WatchNotified(ctx, fs, ref)
+	if _, ok := err.(errtypes.IsNotSupported); !ok {
+		return ch, err
+	}
+	return PollChanges(ctx, fs, ref, interval)
+}
+
+// WatchNotified returns the changes notified by the Watcher of fs. Storage
+// wrappers implement Watcher by calling it with the storage they wrap, it
+// returns an errtypes.NotSupported error when the driver doesn't notify its
+// changes, for Watch to walk the tree of the wrapper instead.
+func WatchNotified(ctx context.Context, fs FS, ref *provider.Reference) (<-chan ChangeEvent, error) {
+	w, ok := fs.(Watcher)
+	if !ok {
+		return nil, errtypes.NotSupported("watch")
+	}
+	return w.Watch(ctx, ref)
+}
+
+// Ancestor is a resource on the way from a changed resource to the root of its storage.
+type Ancestor struct {
+	ID   string
+	Name string
+}
+
+// Ancestry are the ids and names of a resource and of its parents up to the
+// root of its storage, the resource first.
+type Ancestry []Ancestor
+
+// relative returns the path of the resource relative to its ancestor with the
+// given id, and false when it is not below it.
+func (a Ancestry) relative(id string) (string, bool) {
+	for i, n := range a {
+		if n.ID != id {
+			continue
+		}
+		names := make([]string, 0, i+1)
+		names = append(names, ".")
+		for j := i - 1; j >= 0; j-- {
+			names = append(names, a[j].Name)
+		}
+		return path.Join(names...), true
+	}
+	return "", false
+}
+
+type watcher struct {
+	id       string
+	ch       chan ChangeEvent
+	overflow bool
+}
+
+// send queues the event without blocking the driver, the watchers which do
+// not keep up get an overflow event once there is room again.
+func (w *watcher) send(ev ChangeEvent) {
+	if w.overflow {
+		select {
+		case w.ch <- ChangeEvent{Type: ChangeOverflow, Time: ev.Time}:
+			w.overflow = false
+		default:
+			return
+		}
+	}
+	select {
+	case w.ch <- ev:
+	default:
+		w.overflow = true
+	}
+}
+
+// Notifier fans the changes a driver makes out to the watchers of its trees,
+// the resources being identified by their ancestry so that the watchers learn
+// whether a change is below the resource they watch and at which path.
+type Notifier struct {
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+// Watching tells whether there are watchers, so that the drivers only work out
+// the ancestries of the changed resources when they are needed.
+func (n *Notifier) Watching() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.watchers) > 0
+}
+
+// Watch returns the changes made to the resource with the given id and below
+// it until ctx is done.
+func (n *Notifier) Watch(ctx context.Context, id string) <-chan ChangeEvent {
+	w := &watcher{id: id, ch: make(chan ChangeEvent, watchBuffer)}
+	n.mu.Lock()
+	if n.watchers == nil {
+		n.watchers = map[*watcher]struct{}{}
+	}
+	n.watchers[w] = struct{}{}
+	n.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		n.mu.Lock()
+		delete(n.watchers, w)
+		n.mu.Unlock()
+		close(w.ch)
+	}()
+	return w.ch
+}
+
+// Notify notifies the watchers of a change to the resource with the given
+// ancestry. The moves also give the ancestry the resource had before, the
+// watchers of only one of both getting a creation or a deletion.
+func (n *Notifier) Notify(changeType string, id *provider.ResourceId, to, from Ancestry) {
+	now := time.Now()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for w := range n.watchers {
+		p, in := to.relative(w.id)
+		var op string
+		var was bool
+		if changeType == ChangeMoved {
+			op, was = from.relative(w.id)
+		}
+
+		ev := ChangeEvent{Type: changeType, Path: p, ID: id, Time: now}
+		switch {
+		case changeType == ChangeMoved && in && was:
+			ev.OldPath = op
+		case changeType == ChangeMoved && in:
+			ev.Type = ChangeCreated
+		case changeType == ChangeMoved && was:
+			ev.Type, ev.Path = ChangeDeleted, op
+		case !in:
+			continue
+		}
+		w.send(ev)
+	}
+}
+
+type pollEntry struct {
+	id    *provider.ResourceId
+	etag  string
+	mtime uint64
+	dir   bool
+}
+
+// PollChanges finds the changes made to the tree referenced by ref by walking
+// it every interval, for the drivers which cannot notify them. The moves are
+// told apart from deletions by the ids of the resources.
+func PollChanges(ctx context.Context, fs FS, ref *provider.Reference, interval time.Duration) (<-chan ChangeEvent, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	md, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	// follow the resource when it is moved
+	if md.Id.GetOpaqueId() != "" {
+		ref = &provider.Reference{Spec: &provider.Reference_Id{Id: md.Id}}
+	}
+	prev := map[string]*pollEntry{}
+	if err := walkEntries(ctx, fs, md, ".", prev); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ChangeEvent, watchBuffer)
+	go func() {
+		defer close(ch)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			cur := map[string]*pollEntry{}
+			md, err := fs.GetMD(ctx, ref, nil)
+			if err == nil {
+				err = walkEntries(ctx, fs, md, ".", cur)
+			}
+			if _, ok := err.(errtypes.IsNotFound); ok {
+				cur = map[string]*pollEntry{}
+			} else if err != nil {
+				appctx.GetLogger(ctx).Debug().Err(err).Msg("storage: error walking the watched tree")
+				continue
+			}
+
+			for _, ev := range diffEntries(prev, cur, time.Now()) {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = cur
+			if len(cur) == 0 {
+				// the watched resource is gone
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func walkEntries(ctx context.Context, fs FS, md *provider.ResourceInfo, rel string, entries map[string]*pollEntry) error {
+	e := &pollEntry{
+		id:   md.Id,
+		etag: md.Etag,
+		dir:  md.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+	}
+	if md.Mtime != nil {
+		e.mtime = md.Mtime.Seconds*1e9 + uint64(md.Mtime.Nanos)
+	}
+	entries[rel] = e
+	if !e.dir {
+		return nil
+	}
+
+	children, err := fs.ListFolder(ctx, &provider.Reference{Spec: &provider.Reference_Id{Id: md.Id}}, nil)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := walkEntries(ctx, fs, child, path.Join(rel, path.Base(child.Path)), entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// below tells whether p is below the directory dir.
+func below(p, dir string) bool {
+	return dir == "." && p != "." || strings.HasPrefix(p, dir+"/")
+}
+
+// diffEntries returns the changes between two walks of a tree, sorted by path.
+// The resources below a deleted or moved folder are not reported.
+func diffEntries(prev, cur map[string]*pollEntry, now time.Time) []ChangeEvent {
+	var deleted, created []string
+	var events []ChangeEvent
+	for p, e := range prev {
+		c, ok := cur[p]
+		switch {
+		case !ok:
+			deleted = append(deleted, p)
+		case !e.dir && (c.etag != e.etag || c.mtime != e.mtime):
+			events = append(events, ChangeEvent{Type: ChangeModified, Path: p, ID: c.id, Time: now})
+		}
+	}
+	for p := range cur {
+		if _, ok := prev[p]; !ok {
+			created = append(created, p)
+		}
+	}
+	sort.Strings(deleted)
+	sort.Strings(created)
+
+	// the deleted resources are matched to the created ones by id
+	byID := map[string]string{}
+	for _, p := range deleted {
+		if id := prev[p].id; id.GetOpaqueId() != "" {
+			byID[id.StorageId+"!"+id.OpaqueId] = p
+		}
+	}
+	moved := map[string]string{} // new path to old path
+	gone := map[string]bool{}    // old paths of the moved resources
+	for _, p := range created {
+		id := cur[p].id
+		if id.GetOpaqueId() == "" {
+			continue
+		}
+		if op, ok := byID[id.StorageId+"!"+id.OpaqueId]; ok {
+			moved[p] = op
+			gone[op] = true
+		}
+	}
+
+	var removed []string // the folders deleted or moved away
+	inRemoved := func(p string) bool {
+		for _, r := range removed {
+			if below(p, r) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range deleted {
+		if inRemoved(p) {
+			continue
+		}
+		removed = append(removed, p)
+		if !gone[p] {
+			events = append(events, ChangeEvent{Type: ChangeDeleted, Path: p, ID: prev[p].id, Time: now})
+		}
+	}
+	for _, p := range created {
+		if op, ok := moved[p]; ok {
+			// the resources moved along with their folder keep their relative path
+			if parent, ok := moved[path.Dir(p)]; ok && path.Join(parent, path.Base(p)) == op {
+				continue
+			}
+			events = append(events, ChangeEvent{Type: ChangeMoved, Path: p, OldPath: op, ID: cur[p].id, Time: now})
+			continue
+		}
+		events = append(events, ChangeEvent{Type: ChangeCreated, Path: p, ID: cur[p].id, Time: now})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	return events
+}
@@ -0,0 +1,133 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func entry(id string, dir bool, etag string) *pollEntry {
+	return &pollEntry{id: &provider.ResourceId{StorageId: "s", OpaqueId: id}, dir: dir, etag: etag}
+}
+
+func summary(events []ChangeEvent) []string {
+	s := make([]string, 0, len(events))
+	for _, ev := range events {
+		line := ev.Type + " " + ev.Path
+		if ev.OldPath != "" {
+			line += " " + ev.OldPath
+		}
+		s = append(s, line)
+	}
+	return s
+}
+
+func TestDiffEntries(t *testing.T) {
+	prev := map[string]*pollEntry{
+		".":         entry("root", true, "1"),
+		"a.txt":     entry("a", false, "1"),
+		"b.txt":     entry("b", false, "1"),
+		"docs":      entry("docs", true, "1"),
+		"docs/c.md": entry("c", false, "1"),
+		"old":       entry("old", true, "1"),
+		"old/d.txt": entry("d", false, "1"),
+	}
+	cur := map[string]*pollEntry{
+		".":           entry("root", true, "2"),
+		"a.txt":       entry("a", false, "2"),
+		"new.txt":     entry("new", false, "1"),
+		"papers":      entry("docs", true, "1"),
+		"papers/c.md": entry("c", false, "1"),
+		"e.txt":       entry("b", false, "1"),
+	}
+	got := summary(diffEntries(prev, cur, time.Now()))
+	expected := []string{
+		"modified a.txt",
+		"moved e.txt b.txt",
+		"created new.txt",
+		"deleted old",
+		"moved papers docs",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	n := &Notifier{}
+	if n.Watching() {
+		t.Fatal("expected no watchers")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := n.Watch(ctx, "docs")
+	if !n.Watching() {
+		t.Fatal("expected a watcher")
+	}
+
+	docs := Ancestry{{ID: "docs", Name: "docs"}, {ID: "root"}}
+	fileIn := append(Ancestry{{ID: "f", Name: "f.txt"}}, docs...)
+	fileOut := Ancestry{{ID: "f", Name: "f.txt"}, {ID: "root"}}
+
+	n.Notify(ChangeCreated, nil, fileIn, nil)
+	n.Notify(ChangeCreated, nil, fileOut, nil)
+	n.Notify(ChangeMoved, nil, fileOut, fileIn)
+	n.Notify(ChangeMoved, nil, fileIn, fileOut)
+	n.Notify(ChangeModified, nil, docs, nil)
+
+	var events []ChangeEvent
+	for i := 0; i < 4; i++ {
+		events = append(events, <-ch)
+	}
+	expected := []string{"created f.txt", "deleted f.txt", "created f.txt", "modified ."}
+	if got := summary(events); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("expected the channel to be closed")
+	}
+}
+
+func TestNotifierOverflow(t *testing.T) {
+	n := &Notifier{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := n.Watch(ctx, "root")
+
+	a := Ancestry{{ID: "f", Name: "f.txt"}, {ID: "root"}}
+	for i := 0; i < watchBuffer+10; i++ {
+		n.Notify(ChangeModified, nil, a, nil)
+	}
+	for i := 0; i < watchBuffer; i++ {
+		<-ch
+	}
+	n.Notify(ChangeDeleted, nil, a, nil)
+	if ev := <-ch; ev.Type != ChangeOverflow {
+		t.Errorf("expected an overflow, got %v", ev)
+	}
+	if ev := <-ch; ev.Type != ChangeDeleted {
+		t.Errorf("expected the deletion, got %v", ev)
+	}
+}
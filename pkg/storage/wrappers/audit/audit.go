@@ -0,0 +1,277 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package audit provides a storage wrapper that logs every operation
+// modifying the wrapped storage.
+package audit
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("audit", New)
+}
+
+type config struct {
+	// Reads also logs the operations that do not modify the storage.
+	Reads bool `mapstructure:"reads"`
+}
+
+type audit struct {
+	storage.FS
+	reads bool
+}
+
+// New returns a storage wrapper that logs the operations executed on fs.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "audit: error decoding conf")
+	}
+	return &audit{FS: fs, reads: c.Reads}, nil
+}
+
+func (a *audit) log(ctx context.Context, op string, ref *provider.Reference, err error) {
+	log := appctx.GetLogger(ctx)
+	ev := log.Info()
+	if err != nil {
+		ev = log.Warn().Err(err)
+	}
+	if u, ok := ctxuser.ContextGetUser(ctx); ok {
+		ev = ev.Str("user", u.Username).Str("idp", u.Id.Idp)
+	}
+	ev.Str("op", op).Str("ref", ref.String()).Bool("success", err == nil).Msg("audit: storage operation")
+}
+
+func (a *audit) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	ri, err := a.FS.GetMD(ctx, ref, mdKeys)
+	if a.reads {
+		a.log(ctx, "stat", ref, err)
+	}
+	return ri, err
+}
+
+func (a *audit) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	ris, err := a.FS.ListFolder(ctx, ref, mdKeys)
+	if a.reads {
+		a.log(ctx, "list", ref, err)
+	}
+	return ris, err
+}
+
+// DownloadRange is not audited, like Download.
+func (a *audit) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	return storage.DownloadRange(ctx, a.FS, ref, offset, length)
+}
+
+func (a *audit) CreateDir(ctx context.Context, fn string) error {
+	err := a.FS.CreateDir(ctx, fn)
+	a.log(ctx, "mkdir", &provider.Reference{Spec: &provider.Reference_Path{Path: fn}}, err)
+	return err
+}
+
+func (a *audit) Delete(ctx context.Context, ref *provider.Reference) error {
+	err := a.FS.Delete(ctx, ref)
+	a.log(ctx, "delete", ref, err)
+	return err
+}
+
+func (a *audit) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	err := a.FS.Move(ctx, oldRef, newRef)
+	a.log(ctx, "move to "+newRef.String(), oldRef, err)
+	return err
+}
+
+func (a *audit) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	err := a.FS.Copy(ctx, src, dst)
+	a.log(ctx, "copy to "+dst.String(), src, err)
+	return err
+}
+
+func (a *audit) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	res, err := a.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+	a.log(ctx, "upload", ref, err)
+	return res, err
+}
+
+func (a *audit) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	err := a.FS.RestoreRevision(ctx, ref, key)
+	a.log(ctx, "restore revision "+key, ref, err)
+	return err
+}
+
+func (a *audit) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	err := a.FS.RestoreRecycleItem(ctx, key, restorePath)
+	a.log(ctx, "restore recycle item "+key, &provider.Reference{Spec: &provider.Reference_Path{Path: restorePath}}, err)
+	return err
+}
+
+func (a *audit) PurgeRecycleItem(ctx context.Context, key string) error {
+	err := a.FS.PurgeRecycleItem(ctx, key)
+	a.log(ctx, "purge recycle item "+key, nil, err)
+	return err
+}
+
+func (a *audit) EmptyRecycle(ctx context.Context) error {
+	err := a.FS.EmptyRecycle(ctx)
+	a.log(ctx, "empty recycle", nil, err)
+	return err
+}
+
+func (a *audit) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	err := a.FS.AddGrant(ctx, ref, g)
+	a.log(ctx, "add grant", ref, err)
+	return err
+}
+
+func (a *audit) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	err := a.FS.RemoveGrant(ctx, ref, g)
+	a.log(ctx, "remove grant", ref, err)
+	return err
+}
+
+func (a *audit) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	err := a.FS.UpdateGrant(ctx, ref, g)
+	a.log(ctx, "update grant", ref, err)
+	return err
+}
+
+func (a *audit) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	err := a.FS.SetArbitraryMetadata(ctx, ref, md)
+	a.log(ctx, "set metadata", ref, err)
+	return err
+}
+
+func (a *audit) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	err := a.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+	a.log(ctx, "unset metadata", ref, err)
+	return err
+}
+
+func (a *audit) UseIn(composer *tusd.StoreComposer) {
+	if storage.UseIn(a.FS, composer) == nil {
+		storage.WrapUploads(composer, storage.UploadHooks{
+			Finished: func(ctx context.Context, info tusd.FileInfo) {
+				a.log(ctx, "finish upload "+info.ID, storage.UploadReference(info), nil)
+			},
+		})
+	}
+}
+
+func (a *audit) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	id, err := storage.ConcatUploads(ctx, a.FS, uploadIDs)
+	a.log(ctx, "concat uploads "+strings.Join(uploadIDs, ","), nil, err)
+	return id, err
+}
+
+func (a *audit) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, a.FS, uploadID)
+}
+
+func (a *audit) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	err := storage.SetProcessing(ctx, a.FS, ref, step)
+	a.log(ctx, "set processing "+step, ref, err)
+	return err
+}
+
+func (a *audit) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	err := storage.FinishProcessing(ctx, a.FS, ref)
+	a.log(ctx, "finish processing", ref, err)
+	return err
+}
+
+func (a *audit) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	err := storage.SetQuota(ctx, a.FS, ref, maxBytes)
+	a.log(ctx, "set quota "+strconv.FormatUint(maxBytes, 10), ref, err)
+	return err
+}
+
+func (a *audit) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	err := storage.RenameHome(ctx, a.FS, oldUser, newUser)
+	a.log(ctx, "rename home of "+oldUser.Username+" to "+newUser.Username, nil, err)
+	return err
+}
+
+func (a *audit) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	items, err := storage.ListSpaceRecycle(ctx, a.FS, root)
+	if a.reads {
+		a.log(ctx, "list recycle", &provider.Reference{Spec: &provider.Reference_Id{Id: root}}, err)
+	}
+	return items, err
+}
+
+func (a *audit) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	err := storage.RestoreSpaceRecycleItem(ctx, a.FS, root, key, restorePath)
+	a.log(ctx, "restore recycle item "+key+" to "+restorePath, &provider.Reference{Spec: &provider.Reference_Id{Id: root}}, err)
+	return err
+}
+
+func (a *audit) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	err := storage.PurgeSpaceRecycleItem(ctx, a.FS, root, key)
+	a.log(ctx, "purge recycle item "+key, &provider.Reference{Spec: &provider.Reference_Id{Id: root}}, err)
+	return err
+}
+
+func (a *audit) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	err := storage.EmptySpaceRecycle(ctx, a.FS, root)
+	a.log(ctx, "empty recycle", &provider.Reference{Spec: &provider.Reference_Id{Id: root}}, err)
+	return err
+}
+
+// SetArbitraryMetadataBatch logs every change of the batch.
+func (a *audit) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	errs := storage.SetArbitraryMetadataBatch(ctx, a.FS, changes)
+	for i, c := range changes {
+		a.log(ctx, "set metadata", c.Reference(), errs[i])
+	}
+	return errs
+}
+
+func (a *audit) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	err := storage.PinResidency(ctx, a.FS, ref)
+	a.log(ctx, "pin residency", ref, err)
+	return err
+}
+
+func (a *audit) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	err := storage.UnpinResidency(ctx, a.FS, ref)
+	a.log(ctx, "unpin residency", ref, err)
+	return err
+}
+
+func (a *audit) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	ch, err := storage.WatchNotified(ctx, a.FS, ref)
+	if a.reads {
+		a.log(ctx, "watch", ref, err)
+	}
+	return ch, err
+}
@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/rs/zerolog"
+)
+
+type testFS struct {
+	storage.FS
+	err error
+}
+
+func (fs *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	return &provider.ResourceInfo{}, nil
+}
+
+func (fs *testFS) Delete(ctx context.Context, ref *provider.Reference) error {
+	return fs.err
+}
+
+// entries returns the log entries written to buf.
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var es []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		e := map[string]interface{}{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		es = append(es, e)
+	}
+	buf.Reset()
+	return es
+}
+
+func TestAudit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(buf)
+	ctx := appctx.WithLogger(context.Background(), &logger)
+	ctx = ctxuser.ContextSetUser(ctx, &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "1"}, Username: "einstein"})
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}}
+
+	driver := &testFS{}
+	fs, err := New(driver, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the reads are not logged by default
+	if _, err := fs.GetMD(ctx, ref, nil); err != nil {
+		t.Fatal(err)
+	}
+	if es := entries(t, buf); len(es) != 0 {
+		t.Errorf("unexpected entries for a read: %v", es)
+	}
+
+	if err := fs.Delete(ctx, ref); err != nil {
+		t.Fatal(err)
+	}
+	es := entries(t, buf)
+	if len(es) != 1 || es[0]["op"] != "delete" || es[0]["user"] != "einstein" || es[0]["success"] != true || es[0]["level"] != "info" {
+		t.Errorf("unexpected entries for a delete: %v", es)
+	}
+
+	// the errors of the driver are returned and logged
+	driver.err = errors.New("failed")
+	if err := fs.Delete(ctx, ref); err != driver.err {
+		t.Errorf("delete returned %v, want the error of the driver", err)
+	}
+	es = entries(t, buf)
+	if len(es) != 1 || es[0]["success"] != false || es[0]["level"] != "warn" || es[0]["error"] != "failed" {
+		t.Errorf("unexpected entries for a failed delete: %v", es)
+	}
+
+	fs, err = New(driver, map[string]interface{}{"reads": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.GetMD(ctx, ref, nil); err != nil {
+		t.Fatal(err)
+	}
+	if es := entries(t, buf); len(es) != 1 || es[0]["op"] != "stat" {
+		t.Errorf("unexpected entries for a logged read: %v", es)
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package cache provides a storage wrapper that keeps a local copy of the
+// content downloaded from the wrapped storage. Pinned files are fetched as
+// soon as they are pinned and are never evicted.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("cache", New)
+}
+
+const indexFile = "index.json"
+
+type config struct {
+	// Dir is the local directory the cached content is kept in.
+	Dir string `mapstructure:"dir"`
+	// MaxSize is the number of bytes of unpinned content kept in the cache.
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxFileSize is the size of the largest file cached on download.
+	// Pinned files are cached whatever their size.
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+	// PinQuota is the number of bytes every user can pin.
+	PinQuota int64 `mapstructure:"pin_quota"`
+	// AdminPinQuota is the number of bytes the admins can pin together, 0 for no limit.
+	AdminPinQuota int64 `mapstructure:"admin_pin_quota"`
+	// Admins are the usernames of the users allowed to set admin pins.
+	Admins []string `mapstructure:"admins"`
+}
+
+func (c *config) init() {
+	if c.Dir == "" {
+		c.Dir = "/var/tmp/reva/cache"
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = 10 * 1024 * 1024 * 1024
+	}
+	if c.MaxFileSize == 0 {
+		c.MaxFileSize = 100 * 1024 * 1024
+	}
+	if c.PinQuota == 0 {
+		c.PinQuota = 1024 * 1024 * 1024
+	}
+}
+
+// entry is a file known to the cache, either because its content is cached or
+// because it is pinned.
+type entry struct {
+	// Etag is the etag of the cached content, empty until it is fetched.
+	Etag  string    `json:"etag,omitempty"`
+	Size  int64     `json:"size"`
+	Atime time.Time `json:"atime"`
+	// Pinners are the ids of the users pinning the file.
+	Pinners []string `json:"pinners,omitempty"`
+	// Admin is the username of the admin pinning the file.
+	Admin string `json:"admin,omitempty"`
+}
+
+func (e *entry) pinned() bool {
+	return len(e.Pinners) > 0 || e.Admin != ""
+}
+
+type wrapper struct {
+	storage.FS
+	conf   *config
+	admins map[string]bool
+
+	sync.Mutex
+	entries map[string]*entry
+}
+
+// New returns a storage wrapper caching the content of the wrapped storage.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "cache: error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "cache: error creating cache dir")
+	}
+
+	w := &wrapper{
+		FS:      fs,
+		conf:    c,
+		admins:  map[string]bool{},
+		entries: map[string]*entry{},
+	}
+	for _, a := range c.Admins {
+		w.admins[a] = true
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// key returns the name of the cache file of a resource. The storage id is left
+// out because the wrapped driver may not know it.
+func key(id *provider.ResourceId) string {
+	sum := sha256.Sum256([]byte(id.GetOpaqueId()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (w *wrapper) path(key string) string {
+	return filepath.Join(w.conf.Dir, key)
+}
+
+// load reads the index of the cache, dropping the entries whose content is gone.
+func (w *wrapper) load() error {
+	data, err := ioutil.ReadFile(filepath.Join(w.conf.Dir, indexFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "cache: error reading index")
+	}
+	if err := json.Unmarshal(data, &w.entries); err != nil {
+		return errors.Wrap(err, "cache: error decoding index")
+	}
+	for k, e := range w.entries {
+		if _, err := os.Stat(w.path(k)); err != nil {
+			if !e.pinned() {
+				delete(w.entries, k)
+				continue
+			}
+			e.Etag = ""
+		}
+	}
+	return nil
+}
+
+// save writes the index of the cache. It must be called with the lock held.
+func (w *wrapper) save() error {
+	data, err := json.Marshal(w.entries)
+	if err != nil {
+		return errors.Wrap(err, "cache: error encoding index")
+	}
+	tmp := filepath.Join(w.conf.Dir, "."+indexFile)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "cache: error writing index")
+	}
+	return errors.Wrap(os.Rename(tmp, filepath.Join(w.conf.Dir, indexFile)), "cache: error writing index")
+}
+
+// evict removes the least recently used unpinned content until it fits in
+// MaxSize. It must be called with the lock held.
+func (w *wrapper) evict() {
+	var used int64
+	var keys []string
+	for k, e := range w.entries {
+		if e.Etag != "" && !e.pinned() {
+			used += e.Size
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return w.entries[keys[i]].Atime.Before(w.entries[keys[j]].Atime)
+	})
+	for _, k := range keys {
+		if used <= w.conf.MaxSize {
+			break
+		}
+		used -= w.entries[k].Size
+		delete(w.entries, k)
+		_ = os.Remove(w.path(k))
+	}
+}
+
+// drop removes a resource from the cache, releasing its pins.
+func (w *wrapper) drop(ctx context.Context, id *provider.ResourceId) {
+	k := key(id)
+	w.Lock()
+	defer w.Unlock()
+	if _, ok := w.entries[k]; !ok {
+		return
+	}
+	delete(w.entries, k)
+	_ = os.Remove(w.path(k))
+	if err := w.save(); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("cache: error saving index")
+	}
+}
+
+// commit moves a downloaded copy into the cache.
+func (w *wrapper) commit(ctx context.Context, k string, md *provider.ResourceInfo, tmp string) {
+	log := appctx.GetLogger(ctx)
+	w.Lock()
+	defer w.Unlock()
+
+	if err := os.Rename(tmp, w.path(k)); err != nil {
+		log.Error().Err(err).Msg("cache: error storing downloaded content")
+		_ = os.Remove(tmp)
+		return
+	}
+	e, ok := w.entries[k]
+	if !ok {
+		e = &entry{}
+		w.entries[k] = e
+	}
+	e.Etag = md.Etag
+	e.Size = int64(md.Size)
+	e.Atime = time.Now()
+	w.evict()
+	if err := w.save(); err != nil {
+		log.Error().Err(err).Msg("cache: error saving index")
+	}
+}
+
+func (w *wrapper) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	md, err := w.FS.GetMD(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		return w.FS.Download(ctx, ref)
+	}
+	k := key(md.Id)
+
+	// the cached copy is only served when the stat proves the user may download
+	// it, the wrapped storage checks the permissions otherwise
+	allowed := md.PermissionSet != nil && md.PermissionSet.InitiateFileDownload
+	w.Lock()
+	e, ok := w.entries[k]
+	if ok && allowed && e.Etag != "" && e.Etag == md.Etag {
+		if f, err := os.Open(w.path(k)); err == nil {
+			e.Atime = time.Now()
+			w.Unlock()
+			return f, nil
+		}
+	}
+	pinned := ok && e.pinned()
+	w.Unlock()
+
+	rc, err := w.FS.Download(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !pinned && int64(md.Size) > w.conf.MaxFileSize {
+		return rc, nil
+	}
+	tmp, err := ioutil.TempFile(w.conf.Dir, ".download-")
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("cache: error creating cache file")
+		return rc, nil
+	}
+	return &cachingReader{
+		ReadCloser: rc,
+		tmp:        tmp,
+		commit: func() {
+			w.commit(appctx.DetachContext(ctx), k, md, tmp.Name())
+		},
+	}, nil
+}
+
+// DownloadRange leaves the files the cache keeps to Download, which serves the
+// cached copy or fills it, and lets the driver read ranges of the bigger ones.
+func (w *wrapper) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	md, err := w.FS.GetMD(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	w.Lock()
+	_, cached := w.entries[key(md.Id)]
+	w.Unlock()
+	if cached || int64(md.Size) <= w.conf.MaxFileSize {
+		return nil, errtypes.NotSupported("cache: download range of a cached file")
+	}
+	return storage.DownloadRange(ctx, w.FS, ref, offset, length)
+}
+
+func (w *wrapper) Delete(ctx context.Context, ref *provider.Reference) error {
+	md, mdErr := w.FS.GetMD(ctx, ref, nil)
+	if err := w.FS.Delete(ctx, ref); err != nil {
+		return err
+	}
+	// the pins of a deleted file are released, they are not restored with it
+	if mdErr == nil && md.Type == provider.ResourceType_RESOURCE_TYPE_FILE {
+		w.drop(ctx, md.Id)
+	}
+	return nil
+}
+
+func (w *wrapper) UseIn(composer *tusd.StoreComposer) {
+	_ = storage.UseIn(w.FS, composer)
+}
+
+func (w *wrapper) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	return storage.ConcatUploads(ctx, w.FS, uploadIDs)
+}
+
+func (w *wrapper) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, w.FS, uploadID)
+}
+
+func (w *wrapper) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	return storage.SetProcessing(ctx, w.FS, ref, step)
+}
+
+func (w *wrapper) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	return storage.FinishProcessing(ctx, w.FS, ref)
+}
+
+func (w *wrapper) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	return storage.SetQuota(ctx, w.FS, ref, maxBytes)
+}
+
+func (w *wrapper) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return storage.RenameHome(ctx, w.FS, oldUser, newUser)
+}
+
+func (w *wrapper) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	return storage.ListSpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	return storage.RestoreSpaceRecycleItem(ctx, w.FS, root, key, restorePath)
+}
+
+func (w *wrapper) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return storage.PurgeSpaceRecycleItem(ctx, w.FS, root, key)
+}
+
+func (w *wrapper) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return storage.EmptySpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.PinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.UnpinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, w.FS, ref)
+}
+
+// cachingReader copies the content read from the storage to a cache file,
+// which is committed once the whole content has been read.
+type cachingReader struct {
+	io.ReadCloser
+	tmp    *os.File
+	werr   error
+	eof    bool
+	commit func()
+}
+
+func (r *cachingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 && r.werr == nil {
+		_, r.werr = r.tmp.Write(p[:n])
+	}
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+func (r *cachingReader) Close() error {
+	err := r.ReadCloser.Close()
+	if cerr := r.tmp.Close(); r.werr == nil {
+		r.werr = cerr
+	}
+	if r.eof && r.werr == nil && err == nil {
+		r.commit()
+	} else {
+		_ = os.Remove(r.tmp.Name())
+	}
+	return err
+}
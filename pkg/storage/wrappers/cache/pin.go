@@ -0,0 +1,318 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cache
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(l []string, s string) []string {
+	var res []string
+	for _, v := range l {
+		if v != s {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func idRef(id *provider.ResourceId) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Id{Id: id}}
+}
+
+// pinnedBytes returns the bytes pinned by the given user, or by the admins
+// when admin is set. It must be called with the lock held.
+func (w *wrapper) pinnedBytes(userID string, admin bool) int64 {
+	var used int64
+	for _, e := range w.entries {
+		if (admin && e.Admin != "") || (!admin && contains(e.Pinners, userID)) {
+			used += e.Size
+		}
+	}
+	return used
+}
+
+// getUser returns the user of the request, who must be an admin to change admin pins.
+func (w *wrapper) getUser(ctx context.Context, adminPin bool) (*userpb.User, error) {
+	u, ok := ctxuser.ContextGetUser(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("cache: pinning requires a user")
+	}
+	if adminPin && !w.admins[u.Username] {
+		return nil, errtypes.PermissionDenied("cache: admin pins can only be set by admins")
+	}
+	return u, nil
+}
+
+func (w *wrapper) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	md, err := w.FS.GetMD(ctx, ref, mdKeys)
+	if err != nil || !contains(mdKeys, storage.PinKey) {
+		return md, err
+	}
+	u, ok := ctxuser.ContextGetUser(ctx)
+	if !ok {
+		return md, nil
+	}
+
+	w.Lock()
+	used := w.pinnedBytes(u.Id.GetOpaqueId(), false)
+	w.Unlock()
+	if md.Opaque == nil {
+		md.Opaque = &typespb.Opaque{}
+	}
+	if md.Opaque.Map == nil {
+		md.Opaque.Map = map[string]*typespb.OpaqueEntry{}
+	}
+	md.Opaque.Map[storage.PinnedBytesKey] = &typespb.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(strconv.FormatInt(used, 10)),
+	}
+	md.Opaque.Map[storage.PinQuotaKey] = &typespb.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte(strconv.FormatInt(w.conf.PinQuota, 10)),
+	}
+	return md, nil
+}
+
+// SetArbitraryMetadata pins files when the metadata contains storage.PinKey or
+// storage.AdminPinKey. The pinned bytes are accounted against the pin quota of
+// the user, or the one of the admins, and the content is fetched into the cache.
+func (w *wrapper) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	_, userPin := md.GetMetadata()[storage.PinKey]
+	_, adminPin := md.GetMetadata()[storage.AdminPinKey]
+	if !userPin && !adminPin {
+		return w.FS.SetArbitraryMetadata(ctx, ref, md)
+	}
+	u, err := w.getUser(ctx, adminPin)
+	if err != nil {
+		return err
+	}
+	info, err := w.FS.GetMD(ctx, ref, []string{storage.PinKey})
+	if err != nil {
+		return err
+	}
+	if info.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		return errtypes.NotSupported("cache: only files can be pinned")
+	}
+	uid := u.Id.GetOpaqueId()
+	size := int64(info.Size)
+	k := key(info.Id)
+
+	// reserve the pins before storing them, so that concurrent pins can't
+	// exceed the quota
+	w.Lock()
+	e, ok := w.entries[k]
+	if !ok {
+		e = &entry{Size: size, Atime: time.Now()}
+	}
+	wasPinned := e.pinned()
+	userPin = userPin && !contains(e.Pinners, uid)
+	adminPin = adminPin && e.Admin == ""
+	if e.Etag == "" {
+		e.Size = size
+	}
+	if userPin && w.pinnedBytes(uid, false)+e.Size > w.conf.PinQuota {
+		w.Unlock()
+		return errtypes.InsufficientStorage("cache: pin quota exceeded")
+	}
+	if adminPin && w.conf.AdminPinQuota > 0 && w.pinnedBytes("", true)+e.Size > w.conf.AdminPinQuota {
+		w.Unlock()
+		return errtypes.InsufficientStorage("cache: admin pin quota exceeded")
+	}
+	if userPin {
+		e.Pinners = append(e.Pinners, uid)
+	}
+	if adminPin {
+		e.Admin = u.Username
+	}
+	w.entries[k] = e
+	w.Unlock()
+
+	err = w.storePins(ctx, ref, md, info, uid, u.Username, userPin, adminPin, wasPinned)
+	w.Lock()
+	defer w.Unlock()
+	if err != nil {
+		if userPin {
+			e.Pinners = remove(e.Pinners, uid)
+		}
+		if adminPin {
+			e.Admin = ""
+		}
+		if !e.pinned() && e.Etag == "" {
+			delete(w.entries, k)
+		}
+		return err
+	}
+	if err := w.save(); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("cache: error saving index")
+	}
+	if e.Etag != info.Etag {
+		go w.prefetch(appctx.DetachContext(ctx), info.Id)
+	}
+	return nil
+}
+
+// SetArbitraryMetadataBatch applies the batches changing pins one change at a
+// time, for the pins to be accounted and the pinned files fetched.
+func (w *wrapper) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	for _, c := range changes {
+		_, userPin := c.Set[storage.PinKey]
+		_, adminPin := c.Set[storage.AdminPinKey]
+		if userPin || adminPin || contains(c.Unset, storage.PinKey) || contains(c.Unset, storage.AdminPinKey) {
+			return storage.SetArbitraryMetadataEach(ctx, w, changes)
+		}
+	}
+	return storage.SetArbitraryMetadataBatch(ctx, w.FS, changes)
+}
+
+// storePins records the pins in the metadata of the resource.
+func (w *wrapper) storePins(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata, info *provider.ResourceInfo, uid, username string, userPin, adminPin, wasPinned bool) error {
+	if !wasPinned {
+		if err := storage.PinResidency(ctx, w.FS, idRef(info.Id)); err != nil {
+			if _, ok := err.(errtypes.IsNotSupported); !ok {
+				return err
+			}
+		}
+	}
+
+	stored := map[string]string{}
+	for k, v := range md.GetMetadata() {
+		if k != storage.PinKey && k != storage.AdminPinKey {
+			stored[k] = v
+		}
+	}
+	if userPin {
+		pinners := storage.ParsePinners(info.GetArbitraryMetadata().GetMetadata()[storage.PinKey])
+		if !contains(pinners, uid) {
+			pinners = append(pinners, uid)
+		}
+		stored[storage.PinKey] = storage.FormatPinners(pinners)
+	}
+	if adminPin {
+		stored[storage.AdminPinKey] = username
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+	return w.FS.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: stored})
+}
+
+// UnsetArbitraryMetadata releases the pins of the user, or the admin pin, when
+// the keys contain storage.PinKey or storage.AdminPinKey.
+func (w *wrapper) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	userPin := contains(keys, storage.PinKey)
+	adminPin := contains(keys, storage.AdminPinKey)
+	if !userPin && !adminPin {
+		return w.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+	}
+	u, err := w.getUser(ctx, adminPin)
+	if err != nil {
+		return err
+	}
+	info, err := w.FS.GetMD(ctx, ref, []string{storage.PinKey})
+	if err != nil {
+		return err
+	}
+	uid := u.Id.GetOpaqueId()
+
+	var unset []string
+	for _, k := range keys {
+		if k != storage.PinKey {
+			unset = append(unset, k)
+		}
+	}
+	if userPin {
+		pinners := remove(storage.ParsePinners(info.GetArbitraryMetadata().GetMetadata()[storage.PinKey]), uid)
+		if len(pinners) == 0 {
+			unset = append(unset, storage.PinKey)
+		} else if err := w.FS.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{
+			Metadata: map[string]string{storage.PinKey: storage.FormatPinners(pinners)},
+		}); err != nil {
+			return err
+		}
+	}
+	if len(unset) > 0 {
+		if err := w.FS.UnsetArbitraryMetadata(ctx, ref, unset); err != nil {
+			return err
+		}
+	}
+
+	k := key(info.Id)
+	w.Lock()
+	defer w.Unlock()
+	e, ok := w.entries[k]
+	if !ok {
+		return nil
+	}
+	if userPin {
+		e.Pinners = remove(e.Pinners, uid)
+	}
+	if adminPin {
+		e.Admin = ""
+	}
+	if !e.pinned() {
+		if err := storage.UnpinResidency(ctx, w.FS, idRef(info.Id)); err != nil {
+			if _, ok := err.(errtypes.IsNotSupported); !ok {
+				appctx.GetLogger(ctx).Error().Err(err).Msg("cache: error releasing residency pin")
+			}
+		}
+		if e.Etag == "" {
+			delete(w.entries, k)
+		}
+		w.evict()
+	}
+	if err := w.save(); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("cache: error saving index")
+	}
+	return nil
+}
+
+// prefetch downloads a pinned file into the cache.
+func (w *wrapper) prefetch(ctx context.Context, id *provider.ResourceId) {
+	rc, err := w.Download(ctx, idRef(id))
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("cache: error fetching pinned file")
+		return
+	}
+	defer rc.Close()
+	if _, err := io.Copy(ioutil.Discard, rc); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("cache: error fetching pinned file")
+	}
+}
@@ -0,0 +1,558 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package encryption provides a storage wrapper encrypting the content of the
+// files written through it and decrypting it when read back.
+//
+// Every file gets a random data key, stored in its arbitrary metadata wrapped
+// with the key of the space the file belongs to. The space keys are provided
+// by a pluggable key management service. The content is sealed with AES-256-GCM
+// in blocks of 64 KiB behind a short header holding the random nonce prefix of
+// the written version, so the revisions of a file share its data key and a
+// truncated or altered version fails to decrypt.
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/kms"
+	kmsregistry "github.com/cs3org/reva/pkg/kms/registry"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.RegisterWrapper("encryption", New)
+}
+
+const (
+	// KeyAttr is the arbitrary metadata key holding the wrapped data key of a file.
+	KeyAttr = "reva.encryption.key"
+	// KeyIDAttr is the arbitrary metadata key holding the id of the space key
+	// the data key is wrapped with.
+	KeyIDAttr = "reva.encryption.key_id"
+	// PlainAttr is the arbitrary metadata key holding the mtime of the last
+	// version of a file written before it got a data key.
+	PlainAttr = "reva.encryption.plain_mtime"
+)
+
+type config struct {
+	// KMS is the key management service providing the space keys.
+	KMS  string                            `mapstructure:"kms"`
+	KMSs map[string]map[string]interface{} `mapstructure:"kms_drivers"`
+}
+
+func (c *config) init() {
+	if c.KMS == "" {
+		c.KMS = "static"
+	}
+}
+
+// wrapper doesn't implement storage.Composable nor storage.UploadConcatenator,
+// tus would write the plaintext straight to the driver.
+type wrapper struct {
+	storage.FS
+	kms   kms.KMS
+	locks keyLocks
+}
+
+// New returns a storage wrapper encrypting the file content at rest.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "encryption: error decoding conf")
+	}
+	c.init()
+
+	f, ok := kmsregistry.NewFuncs[c.KMS]
+	if !ok {
+		return nil, errtypes.NotFound("encryption: kms driver not found: " + c.KMS)
+	}
+	k, err := f(c.KMSs[c.KMS])
+	if err != nil {
+		return nil, err
+	}
+	return &wrapper{FS: fs, kms: k}, nil
+}
+
+// target returns the reference of the file an upload writes to. Some drivers
+// receive the id of an upload initiated before instead of the file reference.
+func (w *wrapper) target(ctx context.Context, ref *provider.Reference) *provider.Reference {
+	if ref.GetPath() != "" {
+		if s, err := storage.GetUploadSession(ctx, w.FS, path.Base(ref.GetPath())); err == nil {
+			return s.Ref
+		}
+	}
+	return ref
+}
+
+// space returns the space of a file, identified by the owner of the file or
+// of its parent for new files.
+func (w *wrapper) space(ctx context.Context, ref *provider.Reference) (string, error) {
+	ri, err := w.FS.GetMD(ctx, ref, []string{KeyIDAttr})
+	if _, ok := err.(errtypes.IsNotFound); ok && ref.GetPath() != "" {
+		ri, err = w.FS.GetMD(ctx, &provider.Reference{Spec: &provider.Reference_Path{Path: path.Dir(ref.GetPath())}}, []string{KeyIDAttr})
+	}
+	if err == nil && ri.Owner.GetOpaqueId() != "" {
+		return ri.Owner.OpaqueId, nil
+	}
+	if u, ok := ctxuser.ContextGetUser(ctx); ok {
+		return u.Id.OpaqueId, nil
+	}
+	return "", errors.Wrap(err, "encryption: cannot determine the space of "+ref.String())
+}
+
+// fileKey returns the data key of a file, or nil if the file is not encrypted,
+// along with the metadata of the file.
+func (w *wrapper) fileKey(ctx context.Context, ref *provider.Reference) ([]byte, *provider.ResourceInfo, error) {
+	ri, err := w.FS.GetMD(ctx, ref, []string{KeyAttr, KeyIDAttr, PlainAttr})
+	if err != nil {
+		return nil, nil, err
+	}
+	md := ri.GetArbitraryMetadata().GetMetadata()
+	if md[KeyAttr] == "" {
+		return nil, ri, nil
+	}
+
+	spaceKey, err := w.kms.GetKeyByID(ctx, md[KeyIDAttr])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "encryption: error getting space key "+md[KeyIDAttr])
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(md[KeyAttr])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "encryption: invalid data key")
+	}
+	key, err := unwrapKey(spaceKey, wrapped)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, ri, nil
+}
+
+// newKey generates a data key for a file and returns it with the metadata
+// storing it.
+func (w *wrapper) newKey(ctx context.Context, ref *provider.Reference) ([]byte, map[string]string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	space, err := w.space(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	spaceKey, err := w.kms.GetKey(ctx, space)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "encryption: error getting key of space "+space)
+	}
+	wrapped, err := wrapKey(spaceKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, map[string]string{
+		KeyAttr:   base64.StdEncoding.EncodeToString(wrapped),
+		KeyIDAttr: spaceKey.ID,
+	}, nil
+}
+
+func wrapKey(spaceKey *kms.Key, key []byte) ([]byte, error) {
+	gcm, err := newAEAD(spaceKey.Material)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, key, []byte(spaceKey.ID)), nil
+}
+
+func unwrapKey(spaceKey *kms.Key, wrapped []byte) ([]byte, error) {
+	gcm, err := newAEAD(spaceKey.Material)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, errors.New("encryption: invalid data key")
+	}
+	key, err := gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], []byte(spaceKey.ID))
+	if err != nil {
+		return nil, errors.Wrap(err, "encryption: error unwrapping data key")
+	}
+	return key, nil
+}
+
+func formatMtime(ts *types.Timestamp) string {
+	return fmt.Sprintf("%d.%09d", ts.GetSeconds(), ts.GetNanos())
+}
+
+// isPlain tells whether the current version of a file was written before
+// the file got its data key.
+func isPlain(ri *provider.ResourceInfo) bool {
+	plain := ri.GetArbitraryMetadata().GetMetadata()[PlainAttr]
+	return plain != "" && plain == formatMtime(ri.Mtime)
+}
+
+// isPlainRevision tells whether a revision of a file was written before the
+// file got its data key.
+func isPlainRevision(ri *provider.ResourceInfo, rev *provider.FileVersion) bool {
+	plain := ri.GetArbitraryMetadata().GetMetadata()[PlainAttr]
+	if plain == "" {
+		return false
+	}
+	seconds, err := strconv.ParseUint(strings.SplitN(plain, ".", 2)[0], 10, 64)
+	return err == nil && rev.Mtime <= seconds
+}
+
+// stripMetadata hides the key attributes and reports the size of the plain
+// content. The checksums computed by the driver are the ones of the
+// encrypted content and are dropped.
+func stripMetadata(ri *provider.ResourceInfo) {
+	md := ri.GetArbitraryMetadata().GetMetadata()
+	if md == nil {
+		return
+	}
+	if _, ok := md[KeyAttr]; ok && ri.Type == provider.ResourceType_RESOURCE_TYPE_FILE && !isPlain(ri) {
+		ri.Size = plainSize(ri.Size)
+		ri.Checksum = nil
+	}
+	delete(md, KeyAttr)
+	delete(md, KeyIDAttr)
+	delete(md, PlainAttr)
+}
+
+// withKeyAttrs adds the key attributes to explicitly requested metadata keys.
+func withKeyAttrs(mdKeys []string) []string {
+	if len(mdKeys) == 0 {
+		return mdKeys
+	}
+	for _, k := range mdKeys {
+		if k == "*" {
+			return mdKeys
+		}
+	}
+	return append(mdKeys[:len(mdKeys):len(mdKeys)], KeyAttr, KeyIDAttr, PlainAttr)
+}
+
+func (w *wrapper) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	ri, err := w.FS.GetMD(ctx, ref, withKeyAttrs(mdKeys))
+	if err != nil {
+		return nil, err
+	}
+	stripMetadata(ri)
+	return ri, nil
+}
+
+func (w *wrapper) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	ris, err := w.FS.ListFolder(ctx, ref, withKeyAttrs(mdKeys))
+	if err != nil {
+		return nil, err
+	}
+	for _, ri := range ris {
+		stripMetadata(ri)
+	}
+	return ris, nil
+}
+
+// InitiateUpload only offers the uploads going through the wrapper. The
+// checksum sent by the client cannot be verified by the driver as it only sees
+// the encrypted content.
+func (w *wrapper) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	if _, ok := metadata["checksum"]; ok {
+		md := make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			md[k] = v
+		}
+		delete(md, "checksum")
+		metadata = md
+	}
+	if uploadLength > 0 {
+		uploadLength = int64(encryptedSize(uint64(uploadLength)))
+	}
+	res, err := w.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+	if err != nil {
+		return nil, err
+	}
+	// tus writes the chunks straight to the driver
+	delete(res, "tus")
+	return res, nil
+}
+
+// Upload encrypts the content with the data key of the file. The key of an
+// existing file is stored before its content, along with the mtime of its
+// plain version, while the key of a new file can only be stored once the
+// file exists. The creation of the keys is serialized per file so that
+// concurrent uploads agree on the key, within this process only: the uploads
+// to a file have to be routed to the same data provider.
+func (w *wrapper) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	target := w.target(ctx, ref)
+
+	key, ri, err := w.fileKey(ctx, target)
+	if _, ok := err.(errtypes.IsNotFound); err != nil && !ok {
+		return err
+	}
+	var md map[string]string
+	if key == nil {
+		p := target.GetPath()
+		if ri != nil && ri.Path != "" {
+			p = ri.Path
+		}
+		unlock := w.locks.lock(p)
+		defer unlock()
+
+		// another upload may have created the key meanwhile
+		key, ri, err = w.fileKey(ctx, target)
+		if _, ok := err.(errtypes.IsNotFound); err != nil && !ok {
+			return err
+		}
+	}
+	if key == nil {
+		if key, md, err = w.newKey(ctx, target); err != nil {
+			return err
+		}
+		if ri != nil {
+			md[PlainAttr] = formatMtime(ri.Mtime)
+			if err := w.FS.SetArbitraryMetadata(ctx, target, &provider.ArbitraryMetadata{Metadata: md}); err != nil {
+				return errors.Wrap(err, "encryption: error storing data key")
+			}
+			md = nil
+		}
+	}
+
+	content, err := encrypt(key, r)
+	if err != nil {
+		return err
+	}
+	if err := w.FS.Upload(ctx, ref, readCloser{content, r}); err != nil {
+		return err
+	}
+	if md != nil {
+		if err := w.FS.SetArbitraryMetadata(ctx, target, &provider.ArbitraryMetadata{Metadata: md}); err != nil {
+			return errors.Wrap(err, "encryption: error storing data key")
+		}
+	}
+	return nil
+}
+
+func (w *wrapper) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	key, ri, err := w.fileKey(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := w.FS.Download(ctx, ref)
+	if err != nil || key == nil {
+		return rc, err
+	}
+	return decrypt(key, rc, func() (bool, error) {
+		return isPlain(ri), nil
+	})
+}
+
+func (w *wrapper) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	key, ri, err := w.fileKey(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	revs, err := w.FS.ListRevisions(ctx, ref)
+	if err != nil || key == nil {
+		return revs, err
+	}
+	for _, r := range revs {
+		if !isPlainRevision(ri, r) {
+			r.Size = plainSize(r.Size)
+		}
+	}
+	return revs, nil
+}
+
+func (w *wrapper) DownloadRevision(ctx context.Context, ref *provider.Reference, revisionKey string) (io.ReadCloser, error) {
+	key, ri, err := w.fileKey(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := w.FS.DownloadRevision(ctx, ref, revisionKey)
+	if err != nil || key == nil {
+		return rc, err
+	}
+	return decrypt(key, rc, func() (bool, error) {
+		revs, err := w.FS.ListRevisions(ctx, ref)
+		if err != nil {
+			return false, err
+		}
+		for _, r := range revs {
+			if r.Key == revisionKey {
+				return isPlainRevision(ri, r), nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// Copy streams the content through the wrapper so that the copies get their
+// own data key.
+func (w *wrapper) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	return storage.StreamCopy(ctx, w, src, dst)
+}
+
+func isKeyAttr(k string) bool {
+	return k == KeyAttr || k == KeyIDAttr || k == PlainAttr
+}
+
+func (w *wrapper) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	for k := range md.GetMetadata() {
+		if isKeyAttr(k) {
+			return errtypes.PermissionDenied("encryption: " + k + " cannot be set")
+		}
+	}
+	return w.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (w *wrapper) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	for _, k := range keys {
+		if isKeyAttr(k) {
+			return errtypes.PermissionDenied("encryption: " + k + " cannot be unset")
+		}
+	}
+	return w.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+// SetArbitraryMetadataBatch refuses the changes to the key attributes and
+// applies the others with a single batch.
+func (w *wrapper) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	errs := make([]error, len(changes))
+	allowed := make([]*storage.MetadataChange, 0, len(changes))
+	indexes := make([]int, 0, len(changes))
+	for i, c := range changes {
+		for k := range c.Set {
+			if isKeyAttr(k) {
+				errs[i] = errtypes.PermissionDenied("encryption: " + k + " cannot be set")
+			}
+		}
+		for _, k := range c.Unset {
+			if isKeyAttr(k) {
+				errs[i] = errtypes.PermissionDenied("encryption: " + k + " cannot be unset")
+			}
+		}
+		if errs[i] == nil {
+			allowed = append(allowed, c)
+			indexes = append(indexes, i)
+		}
+	}
+	for i, err := range storage.SetArbitraryMetadataBatch(ctx, w.FS, allowed) {
+		errs[indexes[i]] = err
+	}
+	return errs
+}
+
+func (w *wrapper) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, w.FS, uploadID)
+}
+
+func (w *wrapper) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	return storage.SetProcessing(ctx, w.FS, ref, step)
+}
+
+func (w *wrapper) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	return storage.FinishProcessing(ctx, w.FS, ref)
+}
+
+func (w *wrapper) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	return storage.SetQuota(ctx, w.FS, ref, maxBytes)
+}
+
+func (w *wrapper) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return storage.RenameHome(ctx, w.FS, oldUser, newUser)
+}
+
+func (w *wrapper) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	return storage.ListSpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	return storage.RestoreSpaceRecycleItem(ctx, w.FS, root, key, restorePath)
+}
+
+func (w *wrapper) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return storage.PurgeSpaceRecycleItem(ctx, w.FS, root, key)
+}
+
+func (w *wrapper) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return storage.EmptySpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.PinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.UnpinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, w.FS, ref)
+}
+
+// keyLocks serializes the creation of the data key of the same file
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	waiters int
+}
+
+func (l *keyLocks) lock(key string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*keyLock{}
+	}
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.waiters++
+	l.mu.Unlock()
+
+	kl.Lock()
+	return func() {
+		kl.Unlock()
+		l.mu.Lock()
+		kl.waiters--
+		if kl.waiters == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	_ "github.com/cs3org/reva/pkg/kms/static"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// memFS keeps a single file and its revisions in memory.
+type memFS struct {
+	storage.FS
+	content   []byte
+	mtime     uint64
+	revisions []*provider.FileVersion
+	revs      map[string][]byte
+	md        map[string]string
+}
+
+func (m *memFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	if ref.GetPath() == "/" {
+		return &provider.ResourceInfo{
+			Type:  provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+			Owner: &userpb.UserId{OpaqueId: "einstein"},
+		}, nil
+	}
+	if m.content == nil {
+		return nil, errtypes.NotFound(ref.GetPath())
+	}
+	md := map[string]string{}
+	for k, v := range m.md {
+		md[k] = v
+	}
+	return &provider.ResourceInfo{
+		Type:              provider.ResourceType_RESOURCE_TYPE_FILE,
+		Path:              "/file",
+		Size:              uint64(len(m.content)),
+		Mtime:             &types.Timestamp{Seconds: m.mtime},
+		Checksum:          &provider.ResourceChecksum{Type: provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_ADLER32, Sum: "1"},
+		Owner:             &userpb.UserId{OpaqueId: "einstein"},
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: md},
+	}, nil
+}
+
+func (m *memFS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if m.content != nil {
+		key := strconv.Itoa(len(m.revisions))
+		m.revisions = append(m.revisions, &provider.FileVersion{Key: key, Size: uint64(len(m.content)), Mtime: m.mtime})
+		m.revs[key] = m.content
+	}
+	m.content = b
+	m.mtime++
+	return nil
+}
+
+func (m *memFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(m.content)), nil
+}
+
+func (m *memFS) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	revs := make([]*provider.FileVersion, 0, len(m.revisions))
+	for _, r := range m.revisions {
+		c := *r
+		revs = append(revs, &c)
+	}
+	return revs, nil
+}
+
+func (m *memFS) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(m.revs[key])), nil
+}
+
+func (m *memFS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if m.content == nil {
+		return errtypes.NotFound(ref.GetPath())
+	}
+	for k, v := range md.Metadata {
+		m.md[k] = v
+	}
+	return nil
+}
+
+func newFS(t *testing.T) (*memFS, storage.FS) {
+	mem := &memFS{md: map[string]string{}, revs: map[string][]byte{}, mtime: 1}
+	fs, err := New(mem, map[string]interface{}{
+		"kms_drivers": map[string]map[string]interface{}{
+			"static": {"master_key": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mem, fs
+}
+
+// reader returns a helper reading a whole download.
+func reader(t *testing.T) func(io.ReadCloser, error) string {
+	return func(rc io.ReadCloser, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		b, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}}
+	mem, fs := newFS(t)
+	read := reader(t)
+
+	versions := []string{
+		"first version",
+		"",
+		strings.Repeat("b", blockSize),
+		strings.Repeat("long version ", 3*blockSize/13),
+	}
+	for _, plain := range versions {
+		if err := fs.Upload(ctx, ref, ioutil.NopCloser(strings.NewReader(plain))); err != nil {
+			t.Fatal(err)
+		}
+		if plain != "" && bytes.Contains(mem.content, []byte(plain[:10])) {
+			t.Fatal("content stored in plain text")
+		}
+		if mem.md[KeyIDAttr] != "master/einstein" {
+			t.Fatalf("unexpected space key: %s", mem.md[KeyIDAttr])
+		}
+		if uint64(len(mem.content)) != encryptedSize(uint64(len(plain))) {
+			t.Fatalf("expected encrypted size %d, got %d", encryptedSize(uint64(len(plain))), len(mem.content))
+		}
+
+		ri, err := fs.GetMD(ctx, ref, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ri.Size != uint64(len(plain)) {
+			t.Fatalf("expected size %d, got %d", len(plain), ri.Size)
+		}
+		if ri.Checksum != nil {
+			t.Fatal("checksum of the encrypted content exposed")
+		}
+		if _, ok := ri.ArbitraryMetadata.Metadata[KeyAttr]; ok {
+			t.Fatal("data key exposed in metadata")
+		}
+
+		if b := read(fs.Download(ctx, ref)); b != plain {
+			t.Fatalf("expected %d bytes, got %d", len(plain), len(b))
+		}
+	}
+
+	revs, err := fs.ListRevisions(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range revs {
+		if r.Size != uint64(len(versions[i])) {
+			t.Fatalf("expected revision size %d, got %d", len(versions[i]), r.Size)
+		}
+		if b := read(fs.DownloadRevision(ctx, ref, r.Key)); b != versions[i] {
+			t.Fatalf("unexpected content of revision %s", r.Key)
+		}
+	}
+
+	if err := fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{KeyAttr: "x"}}); err == nil {
+		t.Fatal("expected the data key to be protected")
+	}
+}
+
+func TestPlainVersions(t *testing.T) {
+	ctx := context.Background()
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}}
+	mem, fs := newFS(t)
+	read := reader(t)
+
+	// written before the file was encrypted
+	if err := mem.Upload(ctx, ref, ioutil.NopCloser(strings.NewReader("plain"))); err != nil {
+		t.Fatal(err)
+	}
+	if b := read(fs.Download(ctx, ref)); b != "plain" {
+		t.Fatalf("expected the plain version, got %q", b)
+	}
+	if err := fs.Upload(ctx, ref, ioutil.NopCloser(strings.NewReader("secret"))); err != nil {
+		t.Fatal(err)
+	}
+	if b := read(fs.DownloadRevision(ctx, ref, "0")); b != "plain" {
+		t.Fatalf("expected the plain revision, got %q", b)
+	}
+	revs, _ := fs.ListRevisions(ctx, ref)
+	if revs[0].Size != uint64(len("plain")) {
+		t.Fatalf("unexpected size of the plain revision: %d", revs[0].Size)
+	}
+
+	// written behind the back of the wrapper
+	if err := mem.Upload(ctx, ref, ioutil.NopCloser(strings.NewReader("forged"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Download(ctx, ref); err == nil {
+		t.Fatal("expected the content without header to be refused")
+	}
+	if b := read(fs.DownloadRevision(ctx, ref, "1")); b != "secret" {
+		t.Fatalf("expected the encrypted revision, got %q", b)
+	}
+}
+
+func TestAlteredContent(t *testing.T) {
+	ctx := context.Background()
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}}
+	mem, fs := newFS(t)
+
+	plain := strings.Repeat("x", 2*blockSize+10)
+	if err := fs.Upload(ctx, ref, ioutil.NopCloser(strings.NewReader(plain))); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := mem.content
+
+	for name, content := range map[string][]byte{
+		"flipped":   append(append([]byte{}, encrypted[:100]...), append([]byte{encrypted[100] ^ 1}, encrypted[101:]...)...),
+		"truncated": encrypted[:headerSize+blockSize+tagSize],
+		"empty":     encrypted[:headerSize],
+	} {
+		mem.content = content
+		rc, err := fs.Download(ctx, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ioutil.ReadAll(rc); err == nil {
+			t.Fatalf("%s: expected the content to fail to decrypt", name)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// An encrypted version starts with the magic and a random nonce prefix,
+// followed by the blocks of the content sealed with AES-GCM. The nonce of a
+// block is made of the prefix, the index of the block and a flag set on the
+// last block only, so blocks cannot be reordered, dropped or truncated.
+const (
+	magic      = "RVE2"
+	prefixSize = 7
+	headerSize = len(magic) + prefixSize
+	blockSize  = 64 * 1024
+	tagSize    = 16
+)
+
+// encryptedSize returns the size of the encrypted version of size plain bytes.
+func encryptedSize(size uint64) uint64 {
+	blocks := (size + blockSize - 1) / blockSize
+	if blocks == 0 {
+		blocks = 1
+	}
+	return uint64(headerSize) + size + blocks*tagSize
+}
+
+// plainSize returns the size of the plain content of an encrypted version.
+func plainSize(size uint64) uint64 {
+	if size < uint64(headerSize+tagSize) {
+		return 0
+	}
+	size -= uint64(headerSize)
+	blocks, rest := size/(blockSize+tagSize), size%(blockSize+tagSize)
+	plain := blocks * blockSize
+	if rest > tagSize {
+		plain += rest - tagSize
+	}
+	return plain
+}
+
+func nonce(prefix []byte, index uint64, last bool) []byte {
+	n := make([]byte, prefixSize+5)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[prefixSize:], uint32(index))
+	if last {
+		n[prefixSize+4] = 1
+	}
+	return n
+}
+
+// blockReader applies seal or open to the consecutive blocks of src.
+type blockReader struct {
+	src     *bufio.Reader
+	prefix  []byte
+	block   []byte
+	out     []byte
+	pending []byte
+	index   uint64
+	done    bool
+	process func(dst, block, nonce []byte) ([]byte, error)
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if b.done {
+			return 0, io.EOF
+		}
+		if err := b.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *blockReader) next() error {
+	n, err := io.ReadFull(b.src, b.block)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		b.done = true
+	case err != nil:
+		return err
+	default:
+		if _, err := b.src.Peek(1); err == io.EOF {
+			b.done = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if b.index > math.MaxUint32 {
+		return errors.New("encryption: content too large")
+	}
+
+	out, err := b.process(b.out[:0], b.block[:n], nonce(b.prefix, b.index, b.done))
+	if err != nil {
+		return err
+	}
+	b.index++
+	b.out, b.pending = out, out
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(b)
+}
+
+// encrypt returns the encrypted version of the content read from r.
+func encrypt(key []byte, r io.Reader) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		return nil, err
+	}
+	b := &blockReader{
+		src:    bufio.NewReader(r),
+		prefix: header[len(magic):],
+		block:  make([]byte, blockSize),
+		out:    make([]byte, 0, blockSize+tagSize),
+		process: func(dst, block, nonce []byte) ([]byte, error) {
+			return aead.Seal(dst, nonce, block, nil), nil
+		},
+	}
+	return io.MultiReader(bytes.NewReader(header), b), nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// decrypt returns the plain content of a version of a file encrypted with key.
+// A version without the encryption header is returned as it is only if plain
+// reports it was written before the file got its data key.
+func decrypt(key []byte, rc io.ReadCloser, plain func() (bool, error)) (io.ReadCloser, error) {
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(rc, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		rc.Close()
+		return nil, err
+	}
+	if n < headerSize || string(header[:len(magic)]) != magic {
+		ok, err := plain()
+		if err != nil || !ok {
+			rc.Close()
+			if err == nil {
+				err = errors.New("encryption: content is not encrypted")
+			}
+			return nil, err
+		}
+		return readCloser{io.MultiReader(bytes.NewReader(header[:n]), rc), rc}, nil
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	b := &blockReader{
+		src:    bufio.NewReader(rc),
+		prefix: header[len(magic):],
+		block:  make([]byte, blockSize+tagSize),
+		out:    make([]byte, 0, blockSize),
+		process: func(dst, block, nonce []byte) ([]byte, error) {
+			out, err := aead.Open(dst, nonce, block, nil)
+			if err != nil {
+				return nil, errors.New("encryption: content altered or truncated")
+			}
+			return out, nil
+		},
+	}
+	return readCloser{b, rc}, nil
+}
@@ -0,0 +1,437 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package hidden provides a storage wrapper that hides the resources whose
+// name matches one of the configured patterns.
+package hidden
+
+import (
+	"context"
+	"io"
+	"path"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("hidden", New)
+}
+
+type config struct {
+	// Patterns are matched against the name of every resource with path.Match, eg. ".*" or "*.part"
+	Patterns []string `mapstructure:"patterns"`
+}
+
+type hidden struct {
+	storage.FS
+	patterns []string
+}
+
+// New returns a storage wrapper that hides resources from fs.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "hidden: error decoding conf")
+	}
+	for _, p := range c.Patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, errors.Wrap(err, "hidden: invalid pattern "+p)
+		}
+	}
+	return &hidden{FS: fs, patterns: c.Patterns}, nil
+}
+
+func (h *hidden) isHidden(p string) bool {
+	if p == "" {
+		return false
+	}
+	for dir := p; dir != "/" && dir != "." && dir != ""; dir = path.Dir(dir) {
+		for _, pattern := range h.patterns {
+			if ok, _ := path.Match(pattern, path.Base(dir)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check returns a not found error for path references pointing to a hidden resource.
+// Id based references are checked by GetMD and ListFolder on the resulting paths.
+func (h *hidden) check(ref *provider.Reference) error {
+	if h.isHidden(ref.GetPath()) {
+		return errtypes.NotFound(ref.GetPath())
+	}
+	return nil
+}
+
+func (h *hidden) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	ri, err := h.FS.GetMD(ctx, ref, mdKeys)
+	if err != nil {
+		return nil, err
+	}
+	if h.isHidden(ri.Path) {
+		return nil, errtypes.NotFound(ri.Path)
+	}
+	return ri, nil
+}
+
+func (h *hidden) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	ris, err := h.FS.ListFolder(ctx, ref, mdKeys)
+	if err != nil {
+		return nil, err
+	}
+	visible := ris[:0]
+	for _, ri := range ris {
+		if !h.isHidden(ri.Path) {
+			visible = append(visible, ri)
+		}
+	}
+	return visible, nil
+}
+
+func (h *hidden) CreateDir(ctx context.Context, fn string) error {
+	if h.isHidden(fn) {
+		return errtypes.PermissionDenied("hidden: cannot create " + fn)
+	}
+	return h.FS.CreateDir(ctx, fn)
+}
+
+func (h *hidden) Delete(ctx context.Context, ref *provider.Reference) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return h.FS.Delete(ctx, ref)
+}
+
+func (h *hidden) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	if err := h.check(oldRef); err != nil {
+		return err
+	}
+	if h.isHidden(newRef.GetPath()) {
+		return errtypes.PermissionDenied("hidden: cannot move to " + newRef.GetPath())
+	}
+	return h.FS.Move(ctx, oldRef, newRef)
+}
+
+func (h *hidden) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	if err := h.check(src); err != nil {
+		return err
+	}
+	if h.isHidden(dst.GetPath()) {
+		return errtypes.PermissionDenied("hidden: cannot copy to " + dst.GetPath())
+	}
+	return h.FS.Copy(ctx, src, dst)
+}
+
+func (h *hidden) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	if h.isHidden(ref.GetPath()) {
+		return nil, errtypes.PermissionDenied("hidden: cannot upload to " + ref.GetPath())
+	}
+	return h.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+// Upload is checked as well, the drivers without upload ids get the path of
+// the resource to upload to.
+func (h *hidden) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	if h.isHidden(ref.GetPath()) {
+		r.Close()
+		return errtypes.PermissionDenied("hidden: cannot upload to " + ref.GetPath())
+	}
+	return h.FS.Upload(ctx, ref, r)
+}
+
+func (h *hidden) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	return h.FS.Download(ctx, ref)
+}
+
+func (h *hidden) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	return storage.DownloadRange(ctx, h.FS, ref, offset, length)
+}
+
+func (h *hidden) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	return h.FS.ListRevisions(ctx, ref)
+}
+
+func (h *hidden) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return h.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (h *hidden) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return h.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+func (h *hidden) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	return h.FS.DownloadRevision(ctx, ref, key)
+}
+
+func (h *hidden) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return h.FS.RestoreRevision(ctx, ref, key)
+}
+
+func (h *hidden) ListRecycle(ctx context.Context) ([]*provider.RecycleItem, error) {
+	items, err := h.FS.ListRecycle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]*provider.RecycleItem, 0, len(items))
+	for _, item := range items {
+		if !h.isHidden(item.Path) {
+			visible = append(visible, item)
+		}
+	}
+	return visible, nil
+}
+
+// RestoreRecycleItem refuses to restore to a hidden path, and hides the items
+// deleted from one that are restored to their original location.
+func (h *hidden) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	if restorePath != "" {
+		if h.isHidden(restorePath) {
+			return errtypes.PermissionDenied("hidden: cannot restore to " + restorePath)
+		}
+		return h.FS.RestoreRecycleItem(ctx, key, restorePath)
+	}
+	items, err := h.FS.ListRecycle(ctx)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Key == key && h.isHidden(item.Path) {
+			return errtypes.NotFound(key)
+		}
+	}
+	return h.FS.RestoreRecycleItem(ctx, key, restorePath)
+}
+
+func (h *hidden) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return h.FS.AddGrant(ctx, ref, g)
+}
+
+func (h *hidden) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return h.FS.RemoveGrant(ctx, ref, g)
+}
+
+func (h *hidden) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return h.FS.UpdateGrant(ctx, ref, g)
+}
+
+func (h *hidden) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	return h.FS.ListGrants(ctx, ref)
+}
+
+func (h *hidden) UseIn(composer *tusd.StoreComposer) {
+	if storage.UseIn(h.FS, composer) == nil {
+		storage.WrapUploads(composer, storage.UploadHooks{
+			Write: func(ctx context.Context, info tusd.FileInfo) error {
+				if p := storage.UploadReference(info).GetPath(); h.isHidden(p) {
+					return errtypes.PermissionDenied("hidden: cannot upload to " + p)
+				}
+				return nil
+			},
+		})
+	}
+}
+
+func (h *hidden) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	return storage.ConcatUploads(ctx, h.FS, uploadIDs)
+}
+
+func (h *hidden) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, h.FS, uploadID)
+}
+
+func (h *hidden) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return storage.SetProcessing(ctx, h.FS, ref, step)
+}
+
+func (h *hidden) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return storage.FinishProcessing(ctx, h.FS, ref)
+}
+
+func (h *hidden) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return storage.SetQuota(ctx, h.FS, ref, maxBytes)
+}
+
+func (h *hidden) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return storage.RenameHome(ctx, h.FS, oldUser, newUser)
+}
+
+func (h *hidden) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	items, err := storage.ListSpaceRecycle(ctx, h.FS, root)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]*provider.RecycleItem, 0, len(items))
+	for _, item := range items {
+		if !h.isHidden(item.Path) {
+			visible = append(visible, item)
+		}
+	}
+	return visible, nil
+}
+
+// RestoreSpaceRecycleItem applies the policy of RestoreRecycleItem to the
+// trash bin of the space.
+func (h *hidden) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	if restorePath != "" {
+		if h.isHidden(restorePath) {
+			return errtypes.PermissionDenied("hidden: cannot restore to " + restorePath)
+		}
+		return storage.RestoreSpaceRecycleItem(ctx, h.FS, root, key, restorePath)
+	}
+	items, err := storage.ListSpaceRecycle(ctx, h.FS, root)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Key == key && h.isHidden(item.Path) {
+			return errtypes.NotFound(key)
+		}
+	}
+	return storage.RestoreSpaceRecycleItem(ctx, h.FS, root, key, restorePath)
+}
+
+func (h *hidden) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return storage.PurgeSpaceRecycleItem(ctx, h.FS, root, key)
+}
+
+func (h *hidden) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return storage.EmptySpaceRecycle(ctx, h.FS, root)
+}
+
+// SetArbitraryMetadataBatch refuses the changes to hidden resources and
+// applies the others with a single batch.
+func (h *hidden) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	errs := make([]error, len(changes))
+	visible := make([]*storage.MetadataChange, 0, len(changes))
+	indexes := make([]int, 0, len(changes))
+	for i, c := range changes {
+		if err := h.check(c.Reference()); err != nil {
+			errs[i] = err
+			continue
+		}
+		visible = append(visible, c)
+		indexes = append(indexes, i)
+	}
+	for i, err := range storage.SetArbitraryMetadataBatch(ctx, h.FS, visible) {
+		errs[indexes[i]] = err
+	}
+	return errs
+}
+
+func (h *hidden) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return storage.PinResidency(ctx, h.FS, ref)
+}
+
+func (h *hidden) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	if err := h.check(ref); err != nil {
+		return err
+	}
+	return storage.UnpinResidency(ctx, h.FS, ref)
+}
+
+// Watch drops the changes made to hidden resources, the moves from or to a
+// hidden path become creations or deletions.
+func (h *hidden) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	if err := h.check(ref); err != nil {
+		return nil, err
+	}
+	changes, err := storage.WatchNotified(ctx, h.FS, ref)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan storage.ChangeEvent, cap(changes))
+	go func() {
+		defer close(ch)
+		for ev := range changes {
+			hidden, wasHidden := h.isHidden(ev.Path), h.isHidden(ev.OldPath)
+			switch {
+			case hidden && (ev.Type != storage.ChangeMoved || wasHidden):
+				continue
+			case hidden:
+				ev.Type, ev.Path, ev.OldPath = storage.ChangeDeleted, ev.OldPath, ""
+			case wasHidden:
+				ev.Type, ev.OldPath = storage.ChangeCreated, ""
+			}
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package hidden
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// testFS answers with the given resources and records the calls it gets.
+type testFS struct {
+	storage.FS
+	infos []*provider.ResourceInfo
+	items []*provider.RecycleItem
+	calls []string
+}
+
+func (fs *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	fs.calls = append(fs.calls, "stat")
+	return fs.infos[0], nil
+}
+
+func (fs *testFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	fs.calls = append(fs.calls, "list")
+	return fs.infos, nil
+}
+
+func (fs *testFS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	fs.calls = append(fs.calls, "upload")
+	return nil
+}
+
+func (fs *testFS) ListRecycle(ctx context.Context) ([]*provider.RecycleItem, error) {
+	return fs.items, nil
+}
+
+func (fs *testFS) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	fs.calls = append(fs.calls, "restore "+key)
+	return nil
+}
+
+func (fs *testFS) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	fs.calls = append(fs.calls, "add grant")
+	return nil
+}
+
+func (fs *testFS) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	fs.calls = append(fs.calls, "list grants")
+	return nil, nil
+}
+
+func pathRef(p string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+}
+
+func newHidden(t *testing.T, fs storage.FS) storage.FS {
+	h, err := New(fs, map[string]interface{}{"patterns": []string{".*", "*.part"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestIsHidden(t *testing.T) {
+	h := newHidden(t, &testFS{}).(*hidden)
+	tests := map[string]bool{
+		"":                    false,
+		"/":                   false,
+		"/home/file.txt":      false,
+		"/home/.git":          true,
+		"/home/.git/config":   true,
+		"/home/upload.part":   true,
+		"/home/part/file.txt": false,
+	}
+	for p, want := range tests {
+		if got := h.isHidden(p); got != want {
+			t.Errorf("isHidden(%q) = %v, want %v", p, got, want)
+		}
+	}
+
+	if _, err := New(&testFS{}, map[string]interface{}{"patterns": []string{"["}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestHiddenResources(t *testing.T) {
+	ctx := context.Background()
+	fs := &testFS{infos: []*provider.ResourceInfo{{Path: "/home/.hidden"}, {Path: "/home/file.txt"}}}
+	h := newHidden(t, fs)
+
+	if _, err := h.GetMD(ctx, pathRef("/home/.hidden"), nil); !isNotFound(err) {
+		t.Errorf("stat of a hidden path: %v, want not found", err)
+	}
+	// id based references are checked on the path of the result
+	id := &provider.Reference{Spec: &provider.Reference_Id{Id: &provider.ResourceId{OpaqueId: "id"}}}
+	if _, err := h.GetMD(ctx, id, nil); !isNotFound(err) {
+		t.Errorf("stat of a hidden id: %v, want not found", err)
+	}
+
+	infos, err := h.ListFolder(ctx, pathRef("/home"), nil)
+	if err != nil || len(infos) != 1 || infos[0].Path != "/home/file.txt" {
+		t.Errorf("listing = %v, %v, want the visible file only", infos, err)
+	}
+
+	if err := h.Upload(ctx, pathRef("/home/file.part"), ioutil.NopCloser(strings.NewReader("x"))); !isPermissionDenied(err) {
+		t.Errorf("upload to a hidden path: %v, want permission denied", err)
+	}
+	if err := h.Upload(ctx, pathRef("upload-id"), ioutil.NopCloser(strings.NewReader("x"))); err != nil {
+		t.Errorf("upload with an upload id: %v", err)
+	}
+
+	if _, err := h.(storage.RangeDownloader).DownloadRange(ctx, pathRef("/home/.hidden"), 0, 1); !isNotFound(err) {
+		t.Errorf("range download of a hidden path: %v, want not found", err)
+	}
+	if _, err := h.(storage.RangeDownloader).DownloadRange(ctx, pathRef("/home/file.txt"), 0, 1); !isNotSupported(err) {
+		t.Errorf("range download from a driver without ranges: %v, want not supported", err)
+	}
+
+	if err := h.AddGrant(ctx, pathRef("/home/.hidden"), &provider.Grant{}); !isNotFound(err) {
+		t.Errorf("grant on a hidden path: %v, want not found", err)
+	}
+	if _, err := h.ListGrants(ctx, pathRef("/home/.hidden/sub")); !isNotFound(err) {
+		t.Errorf("listing the grants below a hidden path: %v, want not found", err)
+	}
+	if err := h.AddGrant(ctx, pathRef("/home/file.txt"), &provider.Grant{}); err != nil {
+		t.Errorf("grant on a visible path: %v", err)
+	}
+}
+
+func TestHiddenRecycle(t *testing.T) {
+	ctx := context.Background()
+	fs := &testFS{items: []*provider.RecycleItem{
+		{Key: "hidden", Path: "/home/.hidden"},
+		{Key: "visible", Path: "/home/file.txt"},
+	}}
+	h := newHidden(t, fs)
+
+	items, err := h.ListRecycle(ctx)
+	if err != nil || len(items) != 1 || items[0].Key != "visible" {
+		t.Errorf("recycle listing = %v, %v, want the visible item only", items, err)
+	}
+
+	if err := h.RestoreRecycleItem(ctx, "hidden", ""); !isNotFound(err) {
+		t.Errorf("restoring a hidden item: %v, want not found", err)
+	}
+	if err := h.RestoreRecycleItem(ctx, "visible", "/home/.restored"); !isPermissionDenied(err) {
+		t.Errorf("restoring to a hidden path: %v, want permission denied", err)
+	}
+	if err := h.RestoreRecycleItem(ctx, "visible", ""); err != nil {
+		t.Errorf("restoring a visible item: %v", err)
+	}
+	if len(fs.calls) != 1 || fs.calls[0] != "restore visible" {
+		t.Errorf("the driver got the calls %v, want the visible restore only", fs.calls)
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
+
+func isNotSupported(err error) bool {
+	_, ok := err.(errtypes.IsNotSupported)
+	return ok
+}
+
+func isPermissionDenied(err error) bool {
+	_, ok := err.(errtypes.IsPermissionDenied)
+	return ok
+}
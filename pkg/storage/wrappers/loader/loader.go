@@ -0,0 +1,34 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package loader
+
+import (
+	// Load core storage wrappers.
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/audit"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/cache"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/encryption"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/hidden"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/ratelimit"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/readonly"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/replica"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/search"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/slowlog"
+	_ "github.com/cs3org/reva/pkg/storage/wrappers/wopilocks"
+	// Add your own here
+)
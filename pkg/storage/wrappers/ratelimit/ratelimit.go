@@ -0,0 +1,255 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package ratelimit provides a storage wrapper that limits the number of
+// operations a single user can issue against the wrapped storage.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("ratelimit", New)
+}
+
+type config struct {
+	// Rate is the number of operations per second granted to every user.
+	Rate float64 `mapstructure:"rate"`
+	// Burst is the number of operations a user can issue at once.
+	Burst int `mapstructure:"burst"`
+}
+
+func (c *config) init() {
+	if c.Rate <= 0 {
+		c.Rate = 50
+	}
+	if c.Burst <= 0 {
+		c.Burst = int(c.Rate)
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type limiter struct {
+	storage.FS
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// New returns a storage wrapper that rate limits the operations per user.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "ratelimit: error decoding conf")
+	}
+	c.init()
+	return &limiter{
+		FS:      fs,
+		rate:    c.Rate,
+		burst:   float64(c.Burst),
+		buckets: map[string]*bucket{},
+	}, nil
+}
+
+// allow takes a token from the bucket of the user in the context.
+func (l *limiter) allow(ctx context.Context) error {
+	var key string
+	if u, ok := ctxuser.ContextGetUser(ctx); ok {
+		key = u.Id.Idp + "!" + u.Id.OpaqueId
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+		l.purge(now)
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return errtypes.TooManyRequests(fmt.Sprintf("ratelimit: more than %v operations per second", l.rate))
+	}
+	b.tokens--
+	return nil
+}
+
+// purge drops the buckets that have been refilled completely, they carry no state.
+func (l *limiter) purge(now time.Time) {
+	if len(l.buckets) < 1024 {
+		return
+	}
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+func (l *limiter) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	if err := l.allow(ctx); err != nil {
+		return nil, err
+	}
+	return l.FS.GetMD(ctx, ref, mdKeys)
+}
+
+func (l *limiter) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	if err := l.allow(ctx); err != nil {
+		return nil, err
+	}
+	return l.FS.ListFolder(ctx, ref, mdKeys)
+}
+
+func (l *limiter) CreateDir(ctx context.Context, fn string) error {
+	if err := l.allow(ctx); err != nil {
+		return err
+	}
+	return l.FS.CreateDir(ctx, fn)
+}
+
+func (l *limiter) Delete(ctx context.Context, ref *provider.Reference) error {
+	if err := l.allow(ctx); err != nil {
+		return err
+	}
+	return l.FS.Delete(ctx, ref)
+}
+
+func (l *limiter) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	if err := l.allow(ctx); err != nil {
+		return err
+	}
+	return l.FS.Move(ctx, oldRef, newRef)
+}
+
+func (l *limiter) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	if err := l.allow(ctx); err != nil {
+		return err
+	}
+	return l.FS.Copy(ctx, src, dst)
+}
+
+func (l *limiter) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	if err := l.allow(ctx); err != nil {
+		return nil, err
+	}
+	return l.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (l *limiter) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	if err := l.allow(ctx); err != nil {
+		return nil, err
+	}
+	return l.FS.Download(ctx, ref)
+}
+
+func (l *limiter) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	if err := l.allow(ctx); err != nil {
+		return nil, err
+	}
+	return storage.DownloadRange(ctx, l.FS, ref, offset, length)
+}
+
+// UseIn doesn't limit the tus requests, the uploads are counted once when
+// they are initiated.
+func (l *limiter) UseIn(composer *tusd.StoreComposer) {
+	_ = storage.UseIn(l.FS, composer)
+}
+
+func (l *limiter) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	return storage.ConcatUploads(ctx, l.FS, uploadIDs)
+}
+
+func (l *limiter) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, l.FS, uploadID)
+}
+
+func (l *limiter) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	return storage.SetProcessing(ctx, l.FS, ref, step)
+}
+
+func (l *limiter) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	return storage.FinishProcessing(ctx, l.FS, ref)
+}
+
+func (l *limiter) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	return storage.SetQuota(ctx, l.FS, ref, maxBytes)
+}
+
+func (l *limiter) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return storage.RenameHome(ctx, l.FS, oldUser, newUser)
+}
+
+func (l *limiter) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	return storage.ListSpaceRecycle(ctx, l.FS, root)
+}
+
+func (l *limiter) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	return storage.RestoreSpaceRecycleItem(ctx, l.FS, root, key, restorePath)
+}
+
+func (l *limiter) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return storage.PurgeSpaceRecycleItem(ctx, l.FS, root, key)
+}
+
+func (l *limiter) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return storage.EmptySpaceRecycle(ctx, l.FS, root)
+}
+
+func (l *limiter) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	return storage.SetArbitraryMetadataBatch(ctx, l.FS, changes)
+}
+
+func (l *limiter) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.PinResidency(ctx, l.FS, ref)
+}
+
+func (l *limiter) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.UnpinResidency(ctx, l.FS, ref)
+}
+
+func (l *limiter) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, l.FS, ref)
+}
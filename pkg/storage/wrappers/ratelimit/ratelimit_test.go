@@ -0,0 +1,92 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+type testFS struct {
+	storage.FS
+}
+
+func (fs *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	return &provider.ResourceInfo{}, nil
+}
+
+func userContext(name string) context.Context {
+	return ctxuser.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: name}})
+}
+
+func TestRateLimit(t *testing.T) {
+	fs, err := New(&testFS{}, map[string]interface{}{"rate": 10, "burst": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := fs.(*limiter)
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}}
+	einstein, marie := userContext("einstein"), userContext("marie")
+
+	for i := 0; i < 3; i++ {
+		if _, err := l.GetMD(einstein, ref, nil); err != nil {
+			t.Fatalf("operation %d within the burst failed: %v", i, err)
+		}
+	}
+	if _, err := l.GetMD(einstein, ref, nil); !isTooManyRequests(err) {
+		t.Errorf("operation beyond the burst: %v, want too many requests", err)
+	}
+	// the users have their own buckets
+	if _, err := l.GetMD(marie, ref, nil); err != nil {
+		t.Errorf("operation of another user failed: %v", err)
+	}
+
+	// the bucket is refilled at the rate
+	l.buckets["idp!einstein"].last = time.Now().Add(-200 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err := l.GetMD(einstein, ref, nil); err != nil {
+			t.Fatalf("operation %d after the refill failed: %v", i, err)
+		}
+	}
+	if _, err := l.GetMD(einstein, ref, nil); !isTooManyRequests(err) {
+		t.Errorf("operation beyond the refill: %v, want too many requests", err)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	fs, err := New(&testFS{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := fs.(*limiter); l.rate != 50 || l.burst != 50 {
+		t.Errorf("rate %v and burst %v, want 50 and 50", l.rate, l.burst)
+	}
+}
+
+func isTooManyRequests(err error) bool {
+	_, ok := err.(errtypes.IsTooManyRequests)
+	return ok
+}
@@ -0,0 +1,228 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package readonly provides a storage wrapper that rejects every operation
+// modifying the wrapped storage.
+package readonly
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/golang/protobuf/proto"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("readonly", New)
+}
+
+type readonly struct {
+	storage.FS
+}
+
+// New returns a storage wrapper that only allows read operations on fs.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	return &readonly{FS: fs}, nil
+}
+
+func denied(op string) error {
+	return errtypes.PermissionDenied("readonly: " + op + " is not allowed on a read-only storage")
+}
+
+func (r *readonly) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	ri, err := r.FS.GetMD(ctx, ref, mdKeys)
+	if err != nil {
+		return nil, err
+	}
+	removeWritePermissions(ri)
+	return ri, nil
+}
+
+func (r *readonly) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	ris, err := r.FS.ListFolder(ctx, ref, mdKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, ri := range ris {
+		removeWritePermissions(ri)
+	}
+	return ris, nil
+}
+
+func (r *readonly) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	return storage.DownloadRange(ctx, r.FS, ref, offset, length)
+}
+
+func (r *readonly) CreateHome(ctx context.Context) error {
+	return denied("create home")
+}
+
+func (r *readonly) CreateDir(ctx context.Context, fn string) error {
+	return denied("create dir")
+}
+
+func (r *readonly) Delete(ctx context.Context, ref *provider.Reference) error {
+	return denied("delete")
+}
+
+func (r *readonly) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	return denied("move")
+}
+
+func (r *readonly) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	return denied("copy")
+}
+
+func (r *readonly) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	return nil, denied("upload")
+}
+
+func (r *readonly) Upload(ctx context.Context, ref *provider.Reference, rc io.ReadCloser) error {
+	return denied("upload")
+}
+
+func (r *readonly) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	return denied("restore revision")
+}
+
+func (r *readonly) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	return denied("restore recycle item")
+}
+
+func (r *readonly) PurgeRecycleItem(ctx context.Context, key string) error {
+	return denied("purge recycle item")
+}
+
+func (r *readonly) EmptyRecycle(ctx context.Context) error {
+	return denied("empty recycle")
+}
+
+func (r *readonly) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return denied("add grant")
+}
+
+func (r *readonly) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return denied("remove grant")
+}
+
+func (r *readonly) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return denied("update grant")
+}
+
+func (r *readonly) CreateReference(ctx context.Context, path string, targetURI *url.URL) error {
+	return denied("create reference")
+}
+
+func (r *readonly) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	return denied("set arbitrary metadata")
+}
+
+func (r *readonly) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	return denied("unset arbitrary metadata")
+}
+
+func (r *readonly) UseIn(composer *tusd.StoreComposer) {
+	if storage.UseIn(r.FS, composer) == nil {
+		storage.WrapUploads(composer, storage.UploadHooks{
+			Write: func(ctx context.Context, info tusd.FileInfo) error {
+				return denied("upload")
+			},
+		})
+	}
+}
+
+func (r *readonly) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	return "", denied("upload")
+}
+
+func (r *readonly) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, r.FS, uploadID)
+}
+
+func (r *readonly) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	return denied("set processing")
+}
+
+func (r *readonly) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	return denied("finish processing")
+}
+
+func (r *readonly) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	return denied("set quota")
+}
+
+func (r *readonly) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return denied("rename home")
+}
+
+func (r *readonly) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	return storage.ListSpaceRecycle(ctx, r.FS, root)
+}
+
+func (r *readonly) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	return denied("restore recycle item")
+}
+
+func (r *readonly) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return denied("purge recycle item")
+}
+
+func (r *readonly) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return denied("empty recycle")
+}
+
+// PinResidency only keeps the content of the resource on the fast tier, which
+// doesn't modify it.
+func (r *readonly) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.PinResidency(ctx, r.FS, ref)
+}
+
+func (r *readonly) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.UnpinResidency(ctx, r.FS, ref)
+}
+
+func (r *readonly) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, r.FS, ref)
+}
+
+// removeWritePermissions lets clients know upfront that they can't modify the resource.
+func removeWritePermissions(ri *provider.ResourceInfo) {
+	if ri == nil || ri.PermissionSet == nil {
+		return
+	}
+	// the permission set may be shared between resources, never modify it in place
+	p := proto.Clone(ri.PermissionSet).(*provider.ResourcePermissions)
+	p.AddGrant = false
+	p.CreateContainer = false
+	p.Delete = false
+	p.InitiateFileUpload = false
+	p.Move = false
+	p.PurgeRecycle = false
+	p.RemoveGrant = false
+	p.RestoreFileVersion = false
+	p.RestoreRecycleItem = false
+	p.UpdateGrant = false
+	ri.PermissionSet = p
+}
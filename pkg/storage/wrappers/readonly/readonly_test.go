@@ -0,0 +1,113 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package readonly
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// testFS returns resources sharing the same permission set.
+type testFS struct {
+	storage.FS
+	perms *provider.ResourcePermissions
+}
+
+func (fs *testFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	return &provider.ResourceInfo{Path: ref.GetPath(), PermissionSet: fs.perms}, nil
+}
+
+func (fs *testFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	return []*provider.ResourceInfo{{Path: "/a", PermissionSet: fs.perms}, {Path: "/b"}}, nil
+}
+
+func TestReadonly(t *testing.T) {
+	ctx := context.Background()
+	perms := &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true, InitiateFileUpload: true, Delete: true, AddGrant: true}
+	r, err := New(&testFS{perms: perms}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/file"}}
+
+	info, err := r.GetMD(ctx, ref, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := info.PermissionSet
+	if !p.Stat || !p.InitiateFileDownload || p.InitiateFileUpload || p.Delete || p.AddGrant {
+		t.Errorf("unexpected permissions %+v", p)
+	}
+	// the permission set of the driver is left untouched
+	if !perms.InitiateFileUpload || !perms.Delete {
+		t.Errorf("the permission set of the driver was modified: %+v", perms)
+	}
+
+	infos, err := r.ListFolder(ctx, ref, nil)
+	if err != nil || len(infos) != 2 || infos[0].PermissionSet.Delete || infos[1].PermissionSet != nil {
+		t.Errorf("unexpected listing %v, %v", infos, err)
+	}
+
+	ops := map[string]func() error{
+		"create home": func() error { return r.CreateHome(ctx) },
+		"create dir":  func() error { return r.CreateDir(ctx, "/dir") },
+		"delete":      func() error { return r.Delete(ctx, ref) },
+		"move":        func() error { return r.Move(ctx, ref, ref) },
+		"copy":        func() error { return r.Copy(ctx, ref, ref) },
+		"initiate upload": func() error {
+			_, err := r.InitiateUpload(ctx, ref, 1, nil)
+			return err
+		},
+		"upload":           func() error { return r.Upload(ctx, ref, ioutil.NopCloser(strings.NewReader("x"))) },
+		"restore revision": func() error { return r.RestoreRevision(ctx, ref, "key") },
+		"restore recycle":  func() error { return r.RestoreRecycleItem(ctx, "key", "") },
+		"purge recycle":    func() error { return r.PurgeRecycleItem(ctx, "key") },
+		"empty recycle":    func() error { return r.EmptyRecycle(ctx) },
+		"add grant":        func() error { return r.AddGrant(ctx, ref, &provider.Grant{}) },
+		"remove grant":     func() error { return r.RemoveGrant(ctx, ref, &provider.Grant{}) },
+		"update grant":     func() error { return r.UpdateGrant(ctx, ref, &provider.Grant{}) },
+		"create reference": func() error { return r.CreateReference(ctx, "/ref", &url.URL{}) },
+		"set metadata":     func() error { return r.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{}) },
+		"unset metadata":   func() error { return r.UnsetArbitraryMetadata(ctx, ref, []string{"k"}) },
+		"set quota":        func() error { return storage.SetQuota(ctx, r, ref, 1) },
+		"empty space recycle": func() error {
+			return storage.EmptySpaceRecycle(ctx, r, &provider.ResourceId{OpaqueId: "root"})
+		},
+		"concat uploads": func() error {
+			_, err := storage.ConcatUploads(ctx, r, []string{"a", "b"})
+			return err
+		},
+		"batch metadata": func() error {
+			return storage.SetArbitraryMetadataBatch(ctx, r, []*storage.MetadataChange{{Path: "/file", Unset: []string{"k"}}})[0]
+		},
+	}
+	for name, op := range ops {
+		// the driver would panic if the call got through
+		if _, ok := op().(errtypes.IsPermissionDenied); !ok {
+			t.Errorf("%s: expected permission denied", name)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package replica
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// The operations replayed on the secondary storage.
+const (
+	opSync            = "sync"
+	opCreateHome      = "create_home"
+	opMove            = "move"
+	opDelete          = "delete"
+	opSetMetadata     = "set_metadata"
+	opUnsetMetadata   = "unset_metadata"
+	opCreateReference = "create_reference"
+)
+
+const entryFileExtension = ".json"
+
+// entry is a write to replicate. The content is not journaled, it is read from
+// the primary storage when the entry is replayed.
+type entry struct {
+	Seq      uint64            `json:"seq"`
+	Op       string            `json:"op"`
+	Path     string            `json:"path"`
+	Target   string            `json:"target,omitempty"`
+	Force    bool              `json:"force,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Keys     []string          `json:"keys,omitempty"`
+	// User is the JSON of the user the write was made by, the entry is
+	// replayed on their behalf.
+	User     json.RawMessage `json:"user,omitempty"`
+	Attempts int             `json:"attempts,omitempty"`
+}
+
+// journal keeps the entries in a folder, one file per entry named by its
+// sequence number, until they are replicated. It also remembers the users who
+// wrote, whose trees are walked by the reconciliation.
+type journal struct {
+	dir    string
+	mu     sync.Mutex
+	seq    uint64
+	notify chan struct{}
+}
+
+func openJournal(dir string) (*journal, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "users"), 0700); err != nil {
+		return nil, errors.Wrap(err, "replica: error creating journal")
+	}
+	j := &journal{dir: dir, notify: make(chan struct{}, 1)}
+	names, err := j.names()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		j.seq, _ = strconv.ParseUint(strings.TrimSuffix(names[len(names)-1], entryFileExtension), 10, 64)
+	}
+	return j, nil
+}
+
+func (j *journal) names() ([]string, error) {
+	infos, err := ioutil.ReadDir(j.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "replica: error reading journal")
+	}
+	names := []string{}
+	for _, fi := range infos {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), entryFileExtension) {
+			names = append(names, fi.Name())
+		}
+	}
+	// the names are zero padded, so they sort by sequence number
+	sort.Strings(names)
+	return names, nil
+}
+
+func (j *journal) entryPath(seq uint64) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%020d%s", seq, entryFileExtension))
+}
+
+// append adds the entry to the journal and wakes up the replication.
+func (j *journal) append(e *entry) error {
+	j.mu.Lock()
+	j.seq++
+	e.Seq = j.seq
+	j.mu.Unlock()
+
+	if err := j.write(e); err != nil {
+		return err
+	}
+	select {
+	case j.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (j *journal) write(e *entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeFile(j.entryPath(e.Seq), b)
+}
+
+// pending returns the entries to replicate in the order they were appended.
+func (j *journal) pending() ([]*entry, error) {
+	names, err := j.names()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*entry, 0, len(names))
+	for _, n := range names {
+		b, err := ioutil.ReadFile(filepath.Join(j.dir, n))
+		if err != nil {
+			return nil, errors.Wrap(err, "replica: error reading journal entry")
+		}
+		e := &entry{}
+		if err := json.Unmarshal(b, e); err != nil {
+			return nil, errors.Wrap(err, "replica: malformed journal entry "+n)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (j *journal) remove(e *entry) error {
+	if err := os.Remove(j.entryPath(e.Seq)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "replica: error removing journal entry")
+	}
+	return nil
+}
+
+// addUser remembers the user for the reconciliation.
+func (j *journal) addUser(u *userpb.User) error {
+	id := sha1.Sum([]byte(u.GetId().GetIdp() + "\x00" + u.GetId().GetOpaqueId()))
+	p := filepath.Join(j.dir, "users", hex.EncodeToString(id[:])+entryFileExtension)
+	if _, err := os.Stat(p); err == nil {
+		return nil
+	}
+	b, err := utils.MarshalProtoV1ToJSON(u)
+	if err != nil {
+		return err
+	}
+	return writeFile(p, b)
+}
+
+// users returns the JSON of the users that wrote to the storage.
+func (j *journal) users() ([]json.RawMessage, error) {
+	dir := filepath.Join(j.dir, "users")
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "replica: error reading journal users")
+	}
+	users := make([]json.RawMessage, 0, len(infos))
+	for _, fi := range infos {
+		if !strings.HasSuffix(fi.Name(), entryFileExtension) {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "replica: error reading journal user")
+		}
+		users = append(users, b)
+	}
+	return users, nil
+}
+
+// writeFile writes to a temporary file first, so that a crash never leaves a
+// truncated file behind.
+func writeFile(p string, b []byte) error {
+	tmp := p + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "replica: error writing journal")
+	}
+	return errors.Wrap(os.Rename(tmp, p), "replica: error writing journal")
+}
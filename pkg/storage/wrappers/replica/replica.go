@@ -0,0 +1,572 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package replica provides a storage wrapper that mirrors the writes made to
+// the wrapped storage to a secondary storage, giving a warm standby to the
+// sites whose backend doesn't replicate. The writes are journaled and
+// replayed asynchronously, the content being read from the primary storage
+// and verified by checksum after it was written to the secondary one. A
+// periodic reconciliation walks the trees and repairs what the journal
+// missed, e.g. the writes that could not be journaled.
+package replica
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"path"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("replica", New)
+}
+
+type config struct {
+	// Driver is the driver of the secondary storage the writes are mirrored to.
+	Driver  string                            `mapstructure:"driver"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// Journal is the folder the writes are kept in until they are replicated.
+	// Every wrapped storage needs its own.
+	Journal string `mapstructure:"journal"`
+	// RetryInterval is the number of seconds after which a failed replication is retried.
+	RetryInterval int `mapstructure:"retry_interval"`
+	// MaxAttempts is the number of times a write is replayed before it is left
+	// to the reconciliation.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// ReconcileInterval is the number of seconds between the reconciliations,
+	// negative disables them.
+	ReconcileInterval int `mapstructure:"reconcile_interval"`
+}
+
+func (c *config) init() {
+	if c.Journal == "" {
+		c.Journal = "/var/tmp/reva/replica"
+	}
+	if c.RetryInterval == 0 {
+		c.RetryInterval = 30
+	}
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 10
+	}
+	if c.ReconcileInterval == 0 {
+		c.ReconcileInterval = 3600
+	}
+}
+
+type wrapper struct {
+	storage.FS
+	secondary storage.FS
+	conf      *config
+	journal   *journal
+	log       zerolog.Logger
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// New returns a storage wrapper replicating the writes to a secondary storage.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "replica: error decoding conf")
+	}
+	c.init()
+
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("replica: driver not found: " + c.Driver)
+	}
+	secondary, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, errors.Wrap(err, "replica: error creating the secondary storage")
+	}
+	j, err := openJournal(c.Journal)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wrapper{
+		FS:        fs,
+		secondary: secondary,
+		conf:      c,
+		journal:   j,
+		log:       log.With().Str("pkg", "replica").Logger(),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func pathRef(p string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+}
+
+// path resolves the path of the reference in the wrapped storage, which is
+// the same in the secondary one.
+func (w *wrapper) path(ctx context.Context, ref *provider.Reference) (string, error) {
+	if p := ref.GetPath(); p != "" {
+		return path.Clean(p), nil
+	}
+	return w.FS.GetPathByID(ctx, ref.GetId())
+}
+
+// record journals a write that succeeded on the primary storage. A write that
+// can't be journaled is repaired by the next reconciliation.
+func (w *wrapper) record(ctx context.Context, e *entry) {
+	if u, ok := user.ContextGetUser(ctx); ok {
+		b, err := utils.MarshalProtoV1ToJSON(u)
+		if err == nil {
+			e.User = b
+			err = w.journal.addUser(u)
+		}
+		if err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Msg("replica: error journaling the user")
+		}
+	}
+	if err := w.journal.append(e); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("op", e.Op).Str("path", e.Path).Msg("replica: error journaling write, left to the reconciliation")
+	}
+}
+
+func (w *wrapper) recordRef(ctx context.Context, op string, ref *provider.Reference, e *entry) {
+	p, err := w.path(ctx, ref)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Interface("ref", ref).Msg("replica: error resolving path, left to the reconciliation")
+		return
+	}
+	e.Op = op
+	e.Path = p
+	w.record(ctx, e)
+}
+
+func (w *wrapper) CreateHome(ctx context.Context) error {
+	if err := w.FS.CreateHome(ctx); err != nil {
+		return err
+	}
+	w.record(ctx, &entry{Op: opCreateHome})
+	return nil
+}
+
+func (w *wrapper) CreateDir(ctx context.Context, fn string) error {
+	if err := w.FS.CreateDir(ctx, fn); err != nil {
+		return err
+	}
+	w.record(ctx, &entry{Op: opSync, Path: path.Clean(fn)})
+	return nil
+}
+
+func (w *wrapper) Delete(ctx context.Context, ref *provider.Reference) error {
+	// the path can't be looked up by id anymore once the resource is gone
+	p, perr := w.path(ctx, ref)
+	if err := w.FS.Delete(ctx, ref); err != nil {
+		return err
+	}
+	if perr != nil {
+		appctx.GetLogger(ctx).Error().Err(perr).Interface("ref", ref).Msg("replica: error resolving path, left to the reconciliation")
+		return nil
+	}
+	w.record(ctx, &entry{Op: opDelete, Path: p})
+	return nil
+}
+
+func (w *wrapper) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	oldPath, perr := w.path(ctx, oldRef)
+	if err := w.FS.Move(ctx, oldRef, newRef); err != nil {
+		return err
+	}
+	if perr != nil {
+		appctx.GetLogger(ctx).Error().Err(perr).Interface("ref", oldRef).Msg("replica: error resolving path, left to the reconciliation")
+		return nil
+	}
+	w.recordRef(ctx, opMove, newRef, &entry{Target: oldPath})
+	return nil
+}
+
+func (w *wrapper) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	if err := w.FS.Copy(ctx, src, dst); err != nil {
+		return err
+	}
+	w.recordRef(ctx, opSync, dst, &entry{})
+	return nil
+}
+
+// Upload journals the resource written to, some drivers receiving the id of
+// an upload initiated before instead of the file reference.
+func (w *wrapper) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	target := ref
+	if ref.GetPath() != "" {
+		if s, err := storage.GetUploadSession(ctx, w.FS, path.Base(ref.GetPath())); err == nil {
+			target = s.Ref
+		}
+	}
+	if err := w.FS.Upload(ctx, ref, r); err != nil {
+		return err
+	}
+	w.recordRef(ctx, opSync, target, &entry{Force: true})
+	return nil
+}
+
+// DownloadRange reads from the primary storage, like Download.
+func (w *wrapper) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	return storage.DownloadRange(ctx, w.FS, ref, offset, length)
+}
+
+func (w *wrapper) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	if err := w.FS.RestoreRevision(ctx, ref, key); err != nil {
+		return err
+	}
+	w.recordRef(ctx, opSync, ref, &entry{Force: true})
+	return nil
+}
+
+func (w *wrapper) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	if restorePath == "" {
+		// the item is restored to its original location
+		if items, err := w.FS.ListRecycle(ctx); err == nil {
+			for _, item := range items {
+				if item.Key == key {
+					restorePath = item.Path
+					break
+				}
+			}
+		}
+	}
+	if err := w.FS.RestoreRecycleItem(ctx, key, restorePath); err != nil {
+		return err
+	}
+	if restorePath == "" {
+		// the original location is unknown, the whole tree is compared
+		restorePath = "/"
+	}
+	w.record(ctx, &entry{Op: opSync, Path: path.Clean(restorePath)})
+	return nil
+}
+
+func (w *wrapper) CreateReference(ctx context.Context, p string, targetURI *url.URL) error {
+	if err := w.FS.CreateReference(ctx, p, targetURI); err != nil {
+		return err
+	}
+	w.record(ctx, &entry{Op: opCreateReference, Path: path.Clean(p), Target: targetURI.String()})
+	return nil
+}
+
+func (w *wrapper) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := w.FS.SetArbitraryMetadata(ctx, ref, md); err != nil {
+		return err
+	}
+	w.recordRef(ctx, opSetMetadata, ref, &entry{Metadata: md.GetMetadata()})
+	return nil
+}
+
+func (w *wrapper) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	if err := w.FS.UnsetArbitraryMetadata(ctx, ref, keys); err != nil {
+		return err
+	}
+	w.recordRef(ctx, opUnsetMetadata, ref, &entry{Keys: keys})
+	return nil
+}
+
+// RestoreSpaceRecycleItem journals the restored item like RestoreRecycleItem,
+// the restore path being relative to the root of the space.
+func (w *wrapper) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	var p string
+	if restorePath != "" {
+		if rp, err := w.FS.GetPathByID(ctx, root); err == nil {
+			p = path.Join(rp, restorePath)
+		}
+	} else if items, err := storage.ListSpaceRecycle(ctx, w.FS, root); err == nil {
+		for _, item := range items {
+			if item.Key == key {
+				p = item.Path
+				break
+			}
+		}
+	}
+	if err := storage.RestoreSpaceRecycleItem(ctx, w.FS, root, key, restorePath); err != nil {
+		return err
+	}
+	if p == "" {
+		// the location is unknown, the whole tree is compared
+		p = "/"
+	}
+	w.record(ctx, &entry{Op: opSync, Path: path.Clean(p)})
+	return nil
+}
+
+func (w *wrapper) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	errs := storage.SetArbitraryMetadataBatch(ctx, w.FS, changes)
+	for i, c := range changes {
+		if errs[i] != nil {
+			continue
+		}
+		if len(c.Set) > 0 {
+			w.recordRef(ctx, opSetMetadata, c.Reference(), &entry{Metadata: c.Set})
+		}
+		if len(c.Unset) > 0 {
+			w.recordRef(ctx, opUnsetMetadata, c.Reference(), &entry{Keys: c.Unset})
+		}
+	}
+	return errs
+}
+
+// UseIn journals the tus uploads once they are written to their resource.
+func (w *wrapper) UseIn(composer *tusd.StoreComposer) {
+	if storage.UseIn(w.FS, composer) == nil {
+		storage.WrapUploads(composer, storage.UploadHooks{
+			Finished: func(ctx context.Context, info tusd.FileInfo) {
+				w.recordRef(ctx, opSync, storage.UploadReference(info), &entry{Force: true})
+			},
+		})
+	}
+}
+
+// ConcatUploads journals the resource the partial uploads were initiated for.
+func (w *wrapper) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	var session *storage.UploadSession
+	if len(uploadIDs) > 0 {
+		session, _ = storage.GetUploadSession(ctx, w.FS, uploadIDs[0])
+	}
+	id, err := storage.ConcatUploads(ctx, w.FS, uploadIDs)
+	if err != nil {
+		return "", err
+	}
+	if session != nil {
+		w.recordRef(ctx, opSync, session.Ref, &entry{Force: true})
+	}
+	return id, nil
+}
+
+func (w *wrapper) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, w.FS, uploadID)
+}
+
+func (w *wrapper) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	return storage.SetProcessing(ctx, w.FS, ref, step)
+}
+
+func (w *wrapper) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	return storage.FinishProcessing(ctx, w.FS, ref)
+}
+
+func (w *wrapper) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	return storage.SetQuota(ctx, w.FS, ref, maxBytes)
+}
+
+func (w *wrapper) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return storage.RenameHome(ctx, w.FS, oldUser, newUser)
+}
+
+func (w *wrapper) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	return storage.ListSpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return storage.PurgeSpaceRecycleItem(ctx, w.FS, root, key)
+}
+
+func (w *wrapper) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return storage.EmptySpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.PinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.UnpinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, w.FS, ref)
+}
+
+func (w *wrapper) Shutdown(ctx context.Context) error {
+	close(w.done)
+	<-w.stopped
+	if err := w.secondary.Shutdown(ctx); err != nil {
+		w.log.Error().Err(err).Msg("replica: error shutting down the secondary storage")
+	}
+	return w.FS.Shutdown(ctx)
+}
+
+// run replays the journal until the wrapper is shut down.
+func (w *wrapper) run() {
+	defer close(w.stopped)
+
+	var reconcile <-chan time.Time
+	if w.conf.ReconcileInterval > 0 {
+		t := time.NewTicker(time.Duration(w.conf.ReconcileInterval) * time.Second)
+		defer t.Stop()
+		reconcile = t.C
+	}
+
+	for {
+		var retry <-chan time.Time
+		if !w.replicate() {
+			retry = time.After(time.Duration(w.conf.RetryInterval) * time.Second)
+		}
+		select {
+		case <-w.done:
+			return
+		case <-w.journal.notify:
+		case <-retry:
+		case <-reconcile:
+			w.reconcile()
+		}
+	}
+}
+
+// replicate replays the pending entries in order and reports whether all of
+// them were replicated. It stops at the first failing one, so that the later
+// writes aren't applied before it.
+func (w *wrapper) replicate() bool {
+	entries, err := w.journal.pending()
+	if err != nil {
+		w.log.Error().Err(err).Msg("replica: error reading journal")
+		return false
+	}
+	for _, e := range entries {
+		select {
+		case <-w.done:
+			return true
+		default:
+		}
+
+		if err := w.apply(e); err != nil {
+			e.Attempts++
+			if e.Attempts < w.conf.MaxAttempts {
+				w.log.Warn().Err(err).Str("op", e.Op).Str("path", e.Path).Int("attempts", e.Attempts).Msg("replica: error replicating, retrying later")
+				if err := w.journal.write(e); err != nil {
+					w.log.Error().Err(err).Msg("replica: error updating journal entry")
+				}
+				return false
+			}
+			w.log.Error().Err(err).Str("op", e.Op).Str("path", e.Path).Msg("replica: giving up replicating, left to the reconciliation")
+		}
+		if err := w.journal.remove(e); err != nil {
+			w.log.Error().Err(err).Msg("replica: error removing journal entry")
+			return false
+		}
+	}
+	return true
+}
+
+// reconcile journals the comparison of the trees of all the users who wrote
+// to the storage, unless writes are still pending.
+func (w *wrapper) reconcile() {
+	if entries, err := w.journal.pending(); err != nil || len(entries) > 0 {
+		return
+	}
+	users, err := w.journal.users()
+	if err != nil {
+		w.log.Error().Err(err).Msg("replica: error reading journal users")
+		return
+	}
+	if len(users) == 0 {
+		users = append(users, nil)
+	}
+	for _, u := range users {
+		if err := w.journal.append(&entry{Op: opSync, Path: "/", User: u}); err != nil {
+			w.log.Error().Err(err).Msg("replica: error journaling reconciliation")
+			return
+		}
+	}
+}
+
+// apply replays the entry on the secondary storage.
+func (w *wrapper) apply(e *entry) error {
+	ctx := appctx.WithLogger(context.Background(), &w.log)
+	if len(e.User) > 0 {
+		u := &userpb.User{}
+		if err := utils.UnmarshalJSONToProtoV1(e.User, u); err != nil {
+			return errors.Wrap(err, "replica: malformed journal user")
+		}
+		ctx = user.ContextSetUser(ctx, u)
+	}
+
+	switch e.Op {
+	case opSync:
+		return w.sync(ctx, e.Path, e.Force)
+	case opCreateHome:
+		err := w.secondary.CreateHome(ctx)
+		if _, ok := err.(errtypes.IsAlreadyExists); ok {
+			return nil
+		}
+		return err
+	case opDelete:
+		err := w.secondary.Delete(ctx, pathRef(e.Path))
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil
+		}
+		return err
+	case opMove:
+		err := w.secondary.Move(ctx, pathRef(e.Target), pathRef(e.Path))
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			// the source never made it to the secondary storage
+			return w.sync(ctx, e.Path, false)
+		}
+		return err
+	case opSetMetadata:
+		return w.withResource(ctx, e.Path, func() error {
+			return w.secondary.SetArbitraryMetadata(ctx, pathRef(e.Path), &provider.ArbitraryMetadata{Metadata: e.Metadata})
+		})
+	case opUnsetMetadata:
+		return w.withResource(ctx, e.Path, func() error {
+			return w.secondary.UnsetArbitraryMetadata(ctx, pathRef(e.Path), e.Keys)
+		})
+	case opCreateReference:
+		u, err := url.Parse(e.Target)
+		if err != nil {
+			return errors.Wrap(err, "replica: malformed reference target")
+		}
+		err = w.secondary.CreateReference(ctx, e.Path, u)
+		if _, ok := err.(errtypes.IsAlreadyExists); ok {
+			return nil
+		}
+		return err
+	}
+	return errtypes.NotSupported("replica: unknown journal operation " + e.Op)
+}
+
+// withResource calls f, replicating the resource first when it is missing on
+// the secondary storage.
+func (w *wrapper) withResource(ctx context.Context, p string, f func() error) error {
+	err := f()
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		if err := w.sync(ctx, p, false); err != nil {
+			return err
+		}
+		return f()
+	}
+	return err
+}
@@ -0,0 +1,267 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package replica
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/rs/zerolog"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// memFS keeps a tree in memory, the folders having a nil content.
+type memFS struct {
+	storage.FS
+	files   map[string][]byte
+	corrupt bool
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string][]byte{"/": nil}}
+}
+
+func (m *memFS) info(p string) *provider.ResourceInfo {
+	c := m.files[p]
+	if c == nil {
+		return &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Path: p}
+	}
+	return &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_FILE, Path: p, Size: uint64(len(c))}
+}
+
+func (m *memFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	if _, ok := m.files[ref.GetPath()]; !ok {
+		return nil, errtypes.NotFound(ref.GetPath())
+	}
+	return m.info(ref.GetPath()), nil
+}
+
+func (m *memFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	infos := []*provider.ResourceInfo{}
+	for p := range m.files {
+		if p != "/" && path.Dir(p) == ref.GetPath() {
+			infos = append(infos, m.info(p))
+		}
+	}
+	return infos, nil
+}
+
+func (m *memFS) CreateDir(ctx context.Context, fn string) error {
+	m.files[fn] = nil
+	return nil
+}
+
+func (m *memFS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	b, err := ioutil.ReadAll(r)
+	if m.corrupt {
+		b = append(b, '!')
+	}
+	m.files[ref.GetPath()] = b
+	return err
+}
+
+func (m *memFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(m.files[ref.GetPath()])), nil
+}
+
+func (m *memFS) Delete(ctx context.Context, ref *provider.Reference) error {
+	if _, ok := m.files[ref.GetPath()]; !ok {
+		return errtypes.NotFound(ref.GetPath())
+	}
+	for p := range m.files {
+		if p == ref.GetPath() || strings.HasPrefix(p, ref.GetPath()+"/") {
+			delete(m.files, p)
+		}
+	}
+	return nil
+}
+
+func (m *memFS) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	c, ok := m.files[oldRef.GetPath()]
+	if !ok {
+		return errtypes.NotFound(oldRef.GetPath())
+	}
+	delete(m.files, oldRef.GetPath())
+	m.files[newRef.GetPath()] = c
+	return nil
+}
+
+// UseIn serves the tus uploads, written to the tree once finished.
+func (m *memFS) UseIn(composer *tusd.StoreComposer) {
+	composer.UseCore(memStore{m})
+}
+
+type memStore struct {
+	fs *memFS
+}
+
+func (s memStore) NewUpload(ctx context.Context, info tusd.FileInfo) (tusd.Upload, error) {
+	return &memUpload{fs: s.fs, info: info}, nil
+}
+
+func (s memStore) GetUpload(ctx context.Context, id string) (tusd.Upload, error) {
+	return nil, tusd.ErrNotFound
+}
+
+type memUpload struct {
+	fs      *memFS
+	info    tusd.FileInfo
+	content []byte
+}
+
+func (u *memUpload) WriteChunk(ctx context.Context, offset int64, src io.Reader) (int64, error) {
+	b, err := ioutil.ReadAll(src)
+	u.content = append(u.content, b...)
+	return int64(len(b)), err
+}
+
+func (u *memUpload) GetInfo(ctx context.Context) (tusd.FileInfo, error) {
+	return u.info, nil
+}
+
+func (u *memUpload) GetReader(ctx context.Context) (io.Reader, error) {
+	return bytes.NewReader(u.content), nil
+}
+
+func (u *memUpload) FinishUpload(ctx context.Context) error {
+	u.fs.files[path.Join(u.info.MetaData["dir"], u.info.MetaData["filename"])] = u.content
+	return nil
+}
+
+func newTestWrapper(t *testing.T, primary, secondary *memFS) *wrapper {
+	j, err := openJournal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &config{MaxAttempts: 2}
+	c.init()
+	return &wrapper{FS: primary, secondary: secondary, conf: c, journal: j, log: zerolog.Nop(), done: make(chan struct{})}
+}
+
+func upload(t *testing.T, fs storage.FS, p, content string) {
+	if err := fs.Upload(context.Background(), pathRef(p), ioutil.NopCloser(strings.NewReader(content))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplicatesWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemFS(), newMemFS()
+	w := newTestWrapper(t, primary, secondary)
+
+	if err := w.CreateDir(ctx, "/dir"); err != nil {
+		t.Fatal(err)
+	}
+	upload(t, w, "/dir/a", "a")
+	upload(t, w, "/dir/b", "b")
+	if err := w.Move(ctx, pathRef("/dir/a"), pathRef("/dir/c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Delete(ctx, pathRef("/dir/b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := secondary.files["/dir"]; ok {
+		t.Fatal("writes must be replicated asynchronously")
+	}
+
+	if !w.replicate() {
+		t.Fatal("expected the journal to be replicated")
+	}
+	if string(secondary.files["/dir/c"]) != "a" || len(secondary.files) != 3 {
+		t.Errorf("unexpected secondary storage %v", secondary.files)
+	}
+	if entries, _ := w.journal.pending(); len(entries) != 0 {
+		t.Errorf("expected an empty journal, got %d entries", len(entries))
+	}
+}
+
+func TestReplicatesTusUploads(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemFS(), newMemFS()
+	w := newTestWrapper(t, primary, secondary)
+
+	composer := tusd.NewStoreComposer()
+	w.UseIn(composer)
+	up, err := composer.Core.NewUpload(ctx, tusd.FileInfo{MetaData: tusd.MetaData{"dir": "/", "filename": "t"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := up.WriteChunk(ctx, 0, strings.NewReader("tus")); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := w.journal.pending(); len(entries) != 0 {
+		t.Fatalf("expected the unfinished upload not to be journaled, got %v", entries)
+	}
+	if err := up.FinishUpload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !w.replicate() || string(secondary.files["/t"]) != "tus" {
+		t.Errorf("expected the tus upload to be replicated, got %v", secondary.files)
+	}
+}
+
+func TestVerifiesChecksums(t *testing.T) {
+	primary, secondary := newMemFS(), newMemFS()
+	secondary.corrupt = true
+	w := newTestWrapper(t, primary, secondary)
+
+	upload(t, w, "/a", "a")
+	if w.replicate() {
+		t.Fatal("expected the corrupted replica to fail")
+	}
+	entries, _ := w.journal.pending()
+	if len(entries) != 1 || entries[0].Attempts != 1 {
+		t.Fatalf("expected the entry to be retried, got %v", entries)
+	}
+
+	secondary.corrupt = false
+	if !w.replicate() || string(secondary.files["/a"]) != "a" {
+		t.Errorf("expected the retry to succeed, got %v", secondary.files)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	primary, secondary := newMemFS(), newMemFS()
+	w := newTestWrapper(t, primary, secondary)
+
+	// written behind the back of the wrapper
+	primary.files["/dir"] = nil
+	primary.files["/dir/a"] = []byte("a")
+	secondary.files["/stale"] = []byte("stale")
+
+	w.reconcile()
+	if !w.replicate() {
+		t.Fatal("expected the reconciliation to succeed")
+	}
+	if string(secondary.files["/dir/a"]) != "a" {
+		t.Errorf("expected the missing file to be replicated, got %v", secondary.files)
+	}
+	if _, ok := secondary.files["/stale"]; ok {
+		t.Error("expected the stale file to be removed")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package replica
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// sync makes the resource at the path on the secondary storage equal to the
+// one on the primary storage, descending into the folders. The files are
+// compared by checksum when both storages compute the same one and by size
+// otherwise, a forced file is copied anyway.
+func (w *wrapper) sync(ctx context.Context, p string, force bool) error {
+	ref := pathRef(p)
+	src, err := w.FS.GetMD(ctx, ref, nil)
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		return w.remove(ctx, p)
+	}
+	if err != nil {
+		return err
+	}
+
+	dst, err := w.secondary.GetMD(ctx, ref, nil)
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		dst = nil
+	} else if err != nil {
+		return err
+	}
+	if dst != nil && dst.Type != src.Type {
+		if err := w.remove(ctx, p); err != nil {
+			return err
+		}
+		dst = nil
+	}
+
+	if src.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		if !force && dst != nil && sameContent(src, dst) {
+			return nil
+		}
+		return w.copyFile(ctx, p)
+	}
+
+	if dst == nil {
+		if err := w.secondary.CreateDir(ctx, p); err != nil {
+			if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+				return err
+			}
+		}
+	}
+
+	children, err := w.FS.ListFolder(ctx, ref, nil)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(children))
+	for _, c := range children {
+		name := path.Base(c.Path)
+		names[name] = true
+		if err := w.sync(ctx, path.Join(p, name), false); err != nil {
+			return err
+		}
+	}
+
+	if dst == nil {
+		return nil
+	}
+	replicas, err := w.secondary.ListFolder(ctx, ref, nil)
+	if err != nil {
+		return err
+	}
+	for _, c := range replicas {
+		if name := path.Base(c.Path); !names[name] {
+			if err := w.remove(ctx, path.Join(p, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *wrapper) remove(ctx context.Context, p string) error {
+	err := w.secondary.Delete(ctx, pathRef(p))
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		return nil
+	}
+	return err
+}
+
+// copyFile copies the content of the file to the secondary storage and reads
+// it back to verify that it arrived unaltered.
+func (w *wrapper) copyFile(ctx context.Context, p string) error {
+	ref := pathRef(p)
+	rc, err := w.FS.Download(ctx, ref)
+	if err != nil {
+		return err
+	}
+	h := sha1.New()
+	if err := w.secondary.Upload(ctx, ref, ioutil.NopCloser(io.TeeReader(rc, h))); err != nil {
+		rc.Close()
+		return err
+	}
+	rc.Close()
+
+	replica, err := w.secondary.Download(ctx, ref)
+	if err != nil {
+		return err
+	}
+	defer replica.Close()
+	rh := sha1.New()
+	if _, err := io.Copy(rh, replica); err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), rh.Sum(nil)) {
+		return errtypes.ChecksumMismatch(fmt.Sprintf("replica: %s: expected sha1 %x got %x", p, h.Sum(nil), rh.Sum(nil)))
+	}
+	return nil
+}
+
+func sameContent(a, b *provider.ResourceInfo) bool {
+	if a.Size != b.Size {
+		return false
+	}
+	ac, bc := a.GetChecksum(), b.GetChecksum()
+	if ac.GetType() == provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_INVALID ||
+		ac.GetType() == provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_UNSET ||
+		ac.GetType() != bc.GetType() {
+		return true
+	}
+	return ac.GetSum() == bc.GetSum()
+}
@@ -0,0 +1,298 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package search provides a storage wrapper that feeds the changes made to
+// the wrapped storage to the search service.
+package search
+
+import (
+	"context"
+	"io"
+	"path"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("search", New)
+}
+
+type config struct {
+	// SearchSvc is the address of the search service.
+	SearchSvc string `mapstructure:"searchsvc"`
+	// StorageID is the mount id of the storage provider serving the wrapped driver.
+	StorageID string `mapstructure:"storage_id"`
+	// MaxContentSize is the size in bytes up to which the content of text files is indexed, negative disables it.
+	MaxContentSize int64 `mapstructure:"max_content_size"`
+}
+
+func (c *config) init() {
+	if c.SearchSvc == "" {
+		c.SearchSvc = "localhost:9999"
+	}
+	if c.MaxContentSize == 0 {
+		c.MaxContentSize = 1024 * 1024
+	}
+}
+
+type wrapper struct {
+	storage.FS
+	notifier *search.Notifier
+}
+
+// New returns a storage wrapper keeping the search index up to date.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "search: error decoding conf")
+	}
+	c.init()
+	return &wrapper{
+		FS:       fs,
+		notifier: search.NewNotifier(c.SearchSvc, c.StorageID, c.MaxContentSize),
+	}, nil
+}
+
+func pathRef(p string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: p}}
+}
+
+func hasTags(keys []string) bool {
+	for _, k := range keys {
+		if k == search.TagsKey {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *wrapper) CreateDir(ctx context.Context, fn string) error {
+	if err := w.FS.CreateDir(ctx, fn); err != nil {
+		return err
+	}
+	w.notifier.Updated(ctx, w.FS, pathRef(fn))
+	return nil
+}
+
+func (w *wrapper) Delete(ctx context.Context, ref *provider.Reference) error {
+	// the id can't be looked up anymore once the resource is gone. The children
+	// of a deleted folder stay in the index, they are dropped from the results
+	// because they can't be stated anymore.
+	info, err := w.FS.GetMD(ctx, ref, nil)
+	if err := w.FS.Delete(ctx, ref); err != nil {
+		return err
+	}
+	if err == nil && info.Id != nil {
+		w.notifier.Removed(ctx, info.Id)
+	}
+	return nil
+}
+
+func (w *wrapper) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	if err := w.FS.Move(ctx, oldRef, newRef); err != nil {
+		return err
+	}
+	w.notifier.Updated(ctx, w.FS, newRef)
+	return nil
+}
+
+func (w *wrapper) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	if err := w.FS.Copy(ctx, src, dst); err != nil {
+		return err
+	}
+	w.notifier.Updated(ctx, w.FS, dst)
+	return nil
+}
+
+func (w *wrapper) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	if err := w.FS.Upload(ctx, ref, r); err != nil {
+		return err
+	}
+	w.notifier.Updated(ctx, w.FS, ref)
+	return nil
+}
+
+func (w *wrapper) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	return storage.DownloadRange(ctx, w.FS, ref, offset, length)
+}
+
+func (w *wrapper) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	if err := w.FS.RestoreRevision(ctx, ref, key); err != nil {
+		return err
+	}
+	w.notifier.Updated(ctx, w.FS, ref)
+	return nil
+}
+
+func (w *wrapper) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	if restorePath == "" {
+		// the item is restored to its original location
+		if items, err := w.FS.ListRecycle(ctx); err == nil {
+			for _, item := range items {
+				if item.Key == key {
+					restorePath = item.Path
+					break
+				}
+			}
+		}
+	}
+	if err := w.FS.RestoreRecycleItem(ctx, key, restorePath); err != nil {
+		return err
+	}
+	if restorePath != "" {
+		w.notifier.Updated(ctx, w.FS, pathRef(path.Clean(restorePath)))
+	} else {
+		appctx.GetLogger(ctx).Warn().Str("key", key).Msg("search: restored recycle item not indexed, original location unknown")
+	}
+	return nil
+}
+
+func (w *wrapper) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := w.FS.SetArbitraryMetadata(ctx, ref, md); err != nil {
+		return err
+	}
+	if _, ok := md.GetMetadata()[search.TagsKey]; ok {
+		w.notifier.Updated(ctx, w.FS, ref)
+	}
+	return nil
+}
+
+func (w *wrapper) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	if err := w.FS.UnsetArbitraryMetadata(ctx, ref, keys); err != nil {
+		return err
+	}
+	if hasTags(keys) {
+		w.notifier.Updated(ctx, w.FS, ref)
+	}
+	return nil
+}
+
+func (w *wrapper) UseIn(composer *tusd.StoreComposer) {
+	if storage.UseIn(w.FS, composer) == nil {
+		storage.WrapUploads(composer, storage.UploadHooks{
+			Finished: func(ctx context.Context, info tusd.FileInfo) {
+				w.notifier.Updated(ctx, w.FS, storage.UploadReference(info))
+			},
+		})
+	}
+}
+
+// ConcatUploads indexes the resource the partial uploads were initiated for.
+func (w *wrapper) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	var session *storage.UploadSession
+	if len(uploadIDs) > 0 {
+		session, _ = storage.GetUploadSession(ctx, w.FS, uploadIDs[0])
+	}
+	id, err := storage.ConcatUploads(ctx, w.FS, uploadIDs)
+	if err != nil {
+		return "", err
+	}
+	if session != nil {
+		w.notifier.Updated(ctx, w.FS, session.Ref)
+	}
+	return id, nil
+}
+
+// RestoreSpaceRecycleItem indexes the restored item like RestoreRecycleItem,
+// the restore path being relative to the root of the space.
+func (w *wrapper) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	var p string
+	if restorePath != "" {
+		if rp, err := w.FS.GetPathByID(ctx, root); err == nil {
+			p = path.Join(rp, restorePath)
+		}
+	} else if items, err := storage.ListSpaceRecycle(ctx, w.FS, root); err == nil {
+		for _, item := range items {
+			if item.Key == key {
+				p = item.Path
+				break
+			}
+		}
+	}
+	if err := storage.RestoreSpaceRecycleItem(ctx, w.FS, root, key, restorePath); err != nil {
+		return err
+	}
+	if p != "" {
+		w.notifier.Updated(ctx, w.FS, pathRef(path.Clean(p)))
+	} else {
+		appctx.GetLogger(ctx).Warn().Str("key", key).Msg("search: restored recycle item not indexed, location unknown")
+	}
+	return nil
+}
+
+func (w *wrapper) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	errs := storage.SetArbitraryMetadataBatch(ctx, w.FS, changes)
+	for i, c := range changes {
+		if _, ok := c.Set[search.TagsKey]; errs[i] == nil && (ok || hasTags(c.Unset)) {
+			w.notifier.Updated(ctx, w.FS, c.Reference())
+		}
+	}
+	return errs
+}
+
+func (w *wrapper) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, w.FS, uploadID)
+}
+
+func (w *wrapper) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	return storage.SetProcessing(ctx, w.FS, ref, step)
+}
+
+func (w *wrapper) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	return storage.FinishProcessing(ctx, w.FS, ref)
+}
+
+func (w *wrapper) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	return storage.SetQuota(ctx, w.FS, ref, maxBytes)
+}
+
+func (w *wrapper) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return storage.RenameHome(ctx, w.FS, oldUser, newUser)
+}
+
+func (w *wrapper) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	return storage.ListSpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return storage.PurgeSpaceRecycleItem(ctx, w.FS, root, key)
+}
+
+func (w *wrapper) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return storage.EmptySpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wrapper) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.PinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.UnpinResidency(ctx, w.FS, ref)
+}
+
+func (w *wrapper) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, w.FS, ref)
+}
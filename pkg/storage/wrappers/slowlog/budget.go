@@ -0,0 +1,73 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package slowlog
+
+import (
+	"sync"
+	"time"
+)
+
+const slots = 60
+
+// budget counts the operations over a sliding window to compute the rate
+// the error budget of a latency objective is consumed at. A burn rate of 1
+// consumes exactly the budget over the window, higher rates exhaust it earlier.
+type budget struct {
+	slot      time.Duration
+	objective float64
+
+	mu    sync.Mutex
+	start [slots]time.Time
+	total [slots]int64
+	slow  [slots]int64
+}
+
+func newBudget(window time.Duration, objective float64) *budget {
+	slot := window / slots
+	if slot <= 0 {
+		slot = time.Second
+	}
+	return &budget{slot: slot, objective: objective}
+}
+
+// add records an operation completed at now and returns the current burn rate.
+func (b *budget) add(now time.Time, slow bool) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cur := now.Truncate(b.slot)
+	i := int(cur.UnixNano()/int64(b.slot)) % slots
+	if !b.start[i].Equal(cur) {
+		b.start[i], b.total[i], b.slow[i] = cur, 0, 0
+	}
+	b.total[i]++
+	if slow {
+		b.slow[i]++
+	}
+
+	var total, bad int64
+	oldest := cur.Add(-b.slot * (slots - 1))
+	for j := range b.start {
+		if !b.start[j].Before(oldest) {
+			total += b.total[j]
+			bad += b.slow[j]
+		}
+	}
+	return float64(bad) / float64(total) / (1 - b.objective)
+}
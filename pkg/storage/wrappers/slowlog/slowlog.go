@@ -0,0 +1,427 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package slowlog provides a storage wrapper that records the operations
+// exceeding a latency threshold and tracks the latency SLO of the wrapped storage.
+package slowlog
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	tusd "github.com/tus/tusd/pkg/handler"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func init() {
+	registry.RegisterWrapper("slowlog", New)
+}
+
+type config struct {
+	// Backend names the wrapped backend in the records, e.g. eos, sql or nextcloud.
+	Backend string `mapstructure:"backend"`
+	// Mount names the mount point of the wrapped storage in the records.
+	Mount string `mapstructure:"mount"`
+	// Threshold is the latency in milliseconds above which an operation is slow.
+	Threshold int `mapstructure:"threshold"`
+	// Thresholds overrides the threshold per operation, e.g. {"list": 5000}.
+	Thresholds map[string]int `mapstructure:"thresholds"`
+	// File is the slow-log file, the slow operations are logged with the
+	// request logger when empty.
+	File string `mapstructure:"file"`
+	// Objective is the fraction of operations expected to complete below the threshold.
+	Objective float64 `mapstructure:"objective"`
+	// Window is the period in seconds the SLO burn rate is computed over.
+	Window int `mapstructure:"window"`
+}
+
+func (c *config) init() {
+	if c.Backend == "" {
+		c.Backend = "unknown"
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 1000
+	}
+	if c.Objective <= 0 || c.Objective >= 1 {
+		c.Objective = 0.99
+	}
+	if c.Window <= 0 {
+		c.Window = 3600
+	}
+}
+
+var (
+	backendKey = tag.MustNewKey("backend")
+	mountKey   = tag.MustNewKey("mount")
+	opKey      = tag.MustNewKey("op")
+
+	latencyMeasure  = stats.Float64("reva_storage_operation_latency", "The latency of the storage operations", stats.UnitMilliseconds)
+	slowMeasure     = stats.Int64("reva_storage_slow_operations", "The number of storage operations exceeding the latency threshold", stats.UnitDimensionless)
+	burnRateMeasure = stats.Float64("reva_storage_slo_burn_rate", "The rate the latency error budget of a storage is consumed at", stats.UnitDimensionless)
+
+	registerViews sync.Once
+	viewsErr      error
+)
+
+func register() error {
+	registerViews.Do(func() {
+		viewsErr = view.Register(
+			&view.View{
+				Name:        latencyMeasure.Name(),
+				Description: latencyMeasure.Description(),
+				Measure:     latencyMeasure,
+				TagKeys:     []tag.Key{backendKey, mountKey, opKey},
+				Aggregation: view.Distribution(10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000),
+			},
+			&view.View{
+				Name:        slowMeasure.Name(),
+				Description: slowMeasure.Description(),
+				Measure:     slowMeasure,
+				TagKeys:     []tag.Key{backendKey, mountKey, opKey},
+				Aggregation: view.Count(),
+			},
+			&view.View{
+				Name:        burnRateMeasure.Name(),
+				Description: burnRateMeasure.Description(),
+				Measure:     burnRateMeasure,
+				TagKeys:     []tag.Key{backendKey, mountKey},
+				Aggregation: view.LastValue(),
+			},
+		)
+	})
+	return viewsErr
+}
+
+type slowlog struct {
+	storage.FS
+	conf *config
+	sink *zerolog.Logger
+	slo  *budget
+	tags []tag.Mutator
+}
+
+// New returns a storage wrapper that records the slow operations executed on fs.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "slowlog: error decoding conf")
+	}
+	c.init()
+
+	if err := register(); err != nil {
+		return nil, errors.Wrap(err, "slowlog: error registering views")
+	}
+
+	s := &slowlog{
+		FS:   fs,
+		conf: c,
+		slo:  newBudget(time.Duration(c.Window)*time.Second, c.Objective),
+		tags: []tag.Mutator{tag.Upsert(backendKey, c.Backend), tag.Upsert(mountKey, c.Mount)},
+	}
+	if c.File != "" {
+		f, err := os.OpenFile(c.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "slowlog: error opening slow-log file")
+		}
+		l := zerolog.New(f).With().Timestamp().Logger()
+		s.sink = &l
+	}
+	return s, nil
+}
+
+func (s *slowlog) threshold(op string) time.Duration {
+	if t, ok := s.conf.Thresholds[op]; ok && t > 0 {
+		return time.Duration(t) * time.Millisecond
+	}
+	return time.Duration(s.conf.Threshold) * time.Millisecond
+}
+
+// observe records the latency of an operation started at start,
+// logging it to the slow-log when it exceeds the threshold.
+func (s *slowlog) observe(ctx context.Context, op string, ref *provider.Reference, start time.Time) {
+	d := time.Since(start)
+	slow := d > s.threshold(op)
+	burnRate := s.slo.add(time.Now(), slow)
+
+	tags := append([]tag.Mutator{tag.Upsert(opKey, op)}, s.tags...)
+	ms := []stats.Measurement{latencyMeasure.M(float64(d) / float64(time.Millisecond))}
+	if slow {
+		ms = append(ms, slowMeasure.M(1))
+	}
+	_ = stats.RecordWithTags(context.Background(), tags, ms...)
+	_ = stats.RecordWithTags(context.Background(), s.tags, burnRateMeasure.M(burnRate))
+
+	if !slow {
+		return
+	}
+	sink := s.sink
+	if sink == nil {
+		sink = appctx.GetLogger(ctx)
+	}
+	ev := sink.Warn()
+	if u, ok := ctxuser.ContextGetUser(ctx); ok {
+		ev = ev.Str("user", u.Username)
+	}
+	ev.Str("backend", s.conf.Backend).Str("mount", s.conf.Mount).Str("op", op).Str("ref", ref.String()).
+		Dur("duration", d).Float64("burn_rate", burnRate).Msg("slowlog: slow storage operation")
+}
+
+func pathRef(fn string) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Path{Path: fn}}
+}
+
+func idRef(id *provider.ResourceId) *provider.Reference {
+	return &provider.Reference{Spec: &provider.Reference_Id{Id: id}}
+}
+
+func (s *slowlog) GetHome(ctx context.Context) (string, error) {
+	defer s.observe(ctx, "get_home", nil, time.Now())
+	return s.FS.GetHome(ctx)
+}
+
+func (s *slowlog) CreateHome(ctx context.Context) error {
+	defer s.observe(ctx, "create_home", nil, time.Now())
+	return s.FS.CreateHome(ctx)
+}
+
+func (s *slowlog) CreateDir(ctx context.Context, fn string) error {
+	defer s.observe(ctx, "mkdir", pathRef(fn), time.Now())
+	return s.FS.CreateDir(ctx, fn)
+}
+
+func (s *slowlog) Delete(ctx context.Context, ref *provider.Reference) error {
+	defer s.observe(ctx, "delete", ref, time.Now())
+	return s.FS.Delete(ctx, ref)
+}
+
+func (s *slowlog) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	defer s.observe(ctx, "move", oldRef, time.Now())
+	return s.FS.Move(ctx, oldRef, newRef)
+}
+
+func (s *slowlog) Copy(ctx context.Context, src, dst *provider.Reference) error {
+	defer s.observe(ctx, "copy", src, time.Now())
+	return s.FS.Copy(ctx, src, dst)
+}
+
+func (s *slowlog) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	defer s.observe(ctx, "stat", ref, time.Now())
+	return s.FS.GetMD(ctx, ref, mdKeys)
+}
+
+func (s *slowlog) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	defer s.observe(ctx, "list", ref, time.Now())
+	return s.FS.ListFolder(ctx, ref, mdKeys)
+}
+
+func (s *slowlog) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	defer s.observe(ctx, "initiate_upload", ref, time.Now())
+	return s.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+}
+
+func (s *slowlog) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	defer s.observe(ctx, "upload", ref, time.Now())
+	return s.FS.Upload(ctx, ref, r)
+}
+
+// Download only measures the time to first byte, the transfer is up to the client.
+func (s *slowlog) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	defer s.observe(ctx, "download", ref, time.Now())
+	return s.FS.Download(ctx, ref)
+}
+
+// DownloadRange only measures the time to first byte, like Download.
+func (s *slowlog) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	defer s.observe(ctx, "download_range", ref, time.Now())
+	return storage.DownloadRange(ctx, s.FS, ref, offset, length)
+}
+
+func (s *slowlog) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	defer s.observe(ctx, "list_revisions", ref, time.Now())
+	return s.FS.ListRevisions(ctx, ref)
+}
+
+func (s *slowlog) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	defer s.observe(ctx, "download_revision", ref, time.Now())
+	return s.FS.DownloadRevision(ctx, ref, key)
+}
+
+func (s *slowlog) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	defer s.observe(ctx, "restore_revision", ref, time.Now())
+	return s.FS.RestoreRevision(ctx, ref, key)
+}
+
+func (s *slowlog) ListRecycle(ctx context.Context) ([]*provider.RecycleItem, error) {
+	defer s.observe(ctx, "list_recycle", nil, time.Now())
+	return s.FS.ListRecycle(ctx)
+}
+
+func (s *slowlog) RestoreRecycleItem(ctx context.Context, key, restorePath string) error {
+	defer s.observe(ctx, "restore_recycle_item", pathRef(restorePath), time.Now())
+	return s.FS.RestoreRecycleItem(ctx, key, restorePath)
+}
+
+func (s *slowlog) PurgeRecycleItem(ctx context.Context, key string) error {
+	defer s.observe(ctx, "purge_recycle_item", nil, time.Now())
+	return s.FS.PurgeRecycleItem(ctx, key)
+}
+
+func (s *slowlog) EmptyRecycle(ctx context.Context) error {
+	defer s.observe(ctx, "empty_recycle", nil, time.Now())
+	return s.FS.EmptyRecycle(ctx)
+}
+
+func (s *slowlog) GetPathByID(ctx context.Context, id *provider.ResourceId) (string, error) {
+	defer s.observe(ctx, "get_path", &provider.Reference{Spec: &provider.Reference_Id{Id: id}}, time.Now())
+	return s.FS.GetPathByID(ctx, id)
+}
+
+func (s *slowlog) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	defer s.observe(ctx, "add_grant", ref, time.Now())
+	return s.FS.AddGrant(ctx, ref, g)
+}
+
+func (s *slowlog) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	defer s.observe(ctx, "remove_grant", ref, time.Now())
+	return s.FS.RemoveGrant(ctx, ref, g)
+}
+
+func (s *slowlog) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	defer s.observe(ctx, "update_grant", ref, time.Now())
+	return s.FS.UpdateGrant(ctx, ref, g)
+}
+
+func (s *slowlog) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	defer s.observe(ctx, "list_grants", ref, time.Now())
+	return s.FS.ListGrants(ctx, ref)
+}
+
+func (s *slowlog) GetQuota(ctx context.Context) (uint64, uint64, error) {
+	defer s.observe(ctx, "get_quota", nil, time.Now())
+	return s.FS.GetQuota(ctx)
+}
+
+func (s *slowlog) GetRecursiveSize(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	defer s.observe(ctx, "get_recursive_size", ref, time.Now())
+	return s.FS.GetRecursiveSize(ctx, ref)
+}
+
+func (s *slowlog) CreateReference(ctx context.Context, p string, targetURI *url.URL) error {
+	defer s.observe(ctx, "create_reference", pathRef(p), time.Now())
+	return s.FS.CreateReference(ctx, p, targetURI)
+}
+
+func (s *slowlog) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	defer s.observe(ctx, "set_metadata", ref, time.Now())
+	return s.FS.SetArbitraryMetadata(ctx, ref, md)
+}
+
+func (s *slowlog) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	defer s.observe(ctx, "unset_metadata", ref, time.Now())
+	return s.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+// UseIn doesn't observe the tus requests, their latency depends on the clients.
+func (s *slowlog) UseIn(composer *tusd.StoreComposer) {
+	_ = storage.UseIn(s.FS, composer)
+}
+
+func (s *slowlog) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	defer s.observe(ctx, "concat_uploads", nil, time.Now())
+	return storage.ConcatUploads(ctx, s.FS, uploadIDs)
+}
+
+func (s *slowlog) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	defer s.observe(ctx, "get_upload_session", nil, time.Now())
+	return storage.GetUploadSession(ctx, s.FS, uploadID)
+}
+
+func (s *slowlog) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	defer s.observe(ctx, "set_processing", ref, time.Now())
+	return storage.SetProcessing(ctx, s.FS, ref, step)
+}
+
+func (s *slowlog) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	defer s.observe(ctx, "finish_processing", ref, time.Now())
+	return storage.FinishProcessing(ctx, s.FS, ref)
+}
+
+func (s *slowlog) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	defer s.observe(ctx, "set_quota", ref, time.Now())
+	return storage.SetQuota(ctx, s.FS, ref, maxBytes)
+}
+
+func (s *slowlog) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	defer s.observe(ctx, "rename_home", nil, time.Now())
+	return storage.RenameHome(ctx, s.FS, oldUser, newUser)
+}
+
+func (s *slowlog) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	defer s.observe(ctx, "list_recycle", idRef(root), time.Now())
+	return storage.ListSpaceRecycle(ctx, s.FS, root)
+}
+
+func (s *slowlog) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	defer s.observe(ctx, "restore_recycle_item", pathRef(restorePath), time.Now())
+	return storage.RestoreSpaceRecycleItem(ctx, s.FS, root, key, restorePath)
+}
+
+func (s *slowlog) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	defer s.observe(ctx, "purge_recycle_item", idRef(root), time.Now())
+	return storage.PurgeSpaceRecycleItem(ctx, s.FS, root, key)
+}
+
+func (s *slowlog) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	defer s.observe(ctx, "empty_recycle", idRef(root), time.Now())
+	return storage.EmptySpaceRecycle(ctx, s.FS, root)
+}
+
+func (s *slowlog) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	defer s.observe(ctx, "set_metadata_batch", nil, time.Now())
+	return storage.SetArbitraryMetadataBatch(ctx, s.FS, changes)
+}
+
+func (s *slowlog) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	defer s.observe(ctx, "pin_residency", ref, time.Now())
+	return storage.PinResidency(ctx, s.FS, ref)
+}
+
+func (s *slowlog) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	defer s.observe(ctx, "unpin_residency", ref, time.Now())
+	return storage.UnpinResidency(ctx, s.FS, ref)
+}
+
+// Watch isn't observed, the changes are notified until the watch is cancelled.
+func (s *slowlog) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, s.FS, ref)
+}
@@ -0,0 +1,240 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package wopilocks provides a storage wrapper mirroring the WOPI locks the
+// wopiserver keeps in the arbitrary metadata of the files into the write
+// locks of the storage, which WebDAV enforces, so that a file edited in an
+// office app can't be changed through another protocol meanwhile.
+package wopilocks
+
+import (
+	"context"
+	"io"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/app/provider/wopi"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func init() {
+	registry.RegisterWrapper("wopilocks", New)
+}
+
+type config struct {
+	// LockKey is the arbitrary metadata key the wopiserver keeps the WOPI locks under.
+	LockKey string `mapstructure:"lock_key"`
+}
+
+type wopiLocks struct {
+	storage.FS
+	lockKey string
+	now     func() time.Time
+}
+
+// New returns a storage wrapper mirroring the WOPI locks of the files of fs.
+func New(fs storage.FS, m map[string]interface{}) (storage.FS, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "wopilocks: error decoding conf")
+	}
+	if c.LockKey == "" {
+		c.LockKey = wopi.LockKey
+	}
+	return &wopiLocks{FS: fs, lockKey: c.LockKey, now: time.Now}, nil
+}
+
+// currentLock returns the write lock of the resource, expired or not, nil if
+// it has none or it can't be read.
+func (w *wopiLocks) currentLock(ctx context.Context, ref *provider.Reference) (*storage.Lock, error) {
+	ri, err := w.FS.GetMD(ctx, ref, []string{storage.LockKey})
+	if err != nil {
+		return nil, err
+	}
+	v := ri.GetArbitraryMetadata().GetMetadata()[storage.LockKey]
+	if v == "" {
+		return nil, nil
+	}
+	l, err := storage.DecodeLock(v)
+	if err != nil {
+		return nil, nil
+	}
+	return l, nil
+}
+
+func (w *wopiLocks) DownloadRange(ctx context.Context, ref *provider.Reference, offset, length int64) (io.ReadCloser, error) {
+	return storage.DownloadRange(ctx, w.FS, ref, offset, length)
+}
+
+// SetArbitraryMetadata takes or refreshes the write lock mirroring the WOPI
+// lock set along with it. The WOPI lock is refused while the file is locked
+// through another protocol.
+func (w *wopiLocks) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	v, ok := md.GetMetadata()[w.lockKey]
+	if !ok {
+		return w.FS.SetArbitraryMetadata(ctx, ref, md)
+	}
+	if v == "" {
+		// an empty WOPI lock is an unlock
+		if err := w.unlock(ctx, ref); err != nil {
+			return err
+		}
+		rest := make(map[string]string, len(md.Metadata))
+		for k, v := range md.Metadata {
+			if k != w.lockKey {
+				rest[k] = v
+			}
+		}
+		if len(rest) == 0 {
+			return nil
+		}
+		return w.FS.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: rest})
+	}
+
+	current, err := w.currentLock(ctx, ref)
+	if err != nil {
+		return err
+	}
+	now := w.now()
+	if current != nil && !current.Expired(now) && !wopi.IsMirrorLock(current) {
+		return errtypes.PermissionDenied("wopilocks: the file is locked by " + current.Owner)
+	}
+	u, ok := user.ContextGetUser(ctx)
+	if !ok {
+		return errtypes.UserRequired("wopilocks: missing user in context")
+	}
+	lv, err := wopi.MirrorLock(current, u.Id, now).Encode()
+	if err != nil {
+		return errors.Wrap(err, "wopilocks: error encoding lock")
+	}
+
+	metadata := make(map[string]string, len(md.Metadata)+1)
+	for k, v := range md.Metadata {
+		metadata[k] = v
+	}
+	metadata[storage.LockKey] = lv
+	return w.FS.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: metadata})
+}
+
+// UnsetArbitraryMetadata releases the write lock mirroring the WOPI lock removed.
+func (w *wopiLocks) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	for _, k := range keys {
+		if k == w.lockKey {
+			return w.unlock(ctx, ref, keys...)
+		}
+	}
+	return w.FS.UnsetArbitraryMetadata(ctx, ref, keys)
+}
+
+// unlock removes the WOPI lock and the other given keys, and the write lock
+// when it mirrors the WOPI lock. A write lock taken through another protocol
+// is left alone.
+func (w *wopiLocks) unlock(ctx context.Context, ref *provider.Reference, keys ...string) error {
+	current, err := w.currentLock(ctx, ref)
+	if err != nil {
+		return err
+	}
+	unset := []string{w.lockKey}
+	for _, k := range keys {
+		if k != w.lockKey && k != storage.LockKey {
+			unset = append(unset, k)
+		}
+	}
+	if wopi.IsMirrorLock(current) {
+		unset = append(unset, storage.LockKey)
+	}
+	return w.FS.UnsetArbitraryMetadata(ctx, ref, unset)
+}
+
+// SetArbitraryMetadataBatch applies the batches setting or removing WOPI locks
+// one change at a time, for the write locks to be mirrored.
+func (w *wopiLocks) SetArbitraryMetadataBatch(ctx context.Context, changes []*storage.MetadataChange) []error {
+	for _, c := range changes {
+		_, set := c.Set[w.lockKey]
+		for _, k := range c.Unset {
+			set = set || k == w.lockKey
+		}
+		if set {
+			return storage.SetArbitraryMetadataEach(ctx, w, changes)
+		}
+	}
+	return storage.SetArbitraryMetadataBatch(ctx, w.FS, changes)
+}
+
+func (w *wopiLocks) UseIn(composer *tusd.StoreComposer) {
+	_ = storage.UseIn(w.FS, composer)
+}
+
+func (w *wopiLocks) ConcatUploads(ctx context.Context, uploadIDs []string) (string, error) {
+	return storage.ConcatUploads(ctx, w.FS, uploadIDs)
+}
+
+func (w *wopiLocks) GetUploadSession(ctx context.Context, uploadID string) (*storage.UploadSession, error) {
+	return storage.GetUploadSession(ctx, w.FS, uploadID)
+}
+
+func (w *wopiLocks) SetProcessing(ctx context.Context, ref *provider.Reference, step string) error {
+	return storage.SetProcessing(ctx, w.FS, ref, step)
+}
+
+func (w *wopiLocks) FinishProcessing(ctx context.Context, ref *provider.Reference) error {
+	return storage.FinishProcessing(ctx, w.FS, ref)
+}
+
+func (w *wopiLocks) SetQuota(ctx context.Context, ref *provider.Reference, maxBytes uint64) error {
+	return storage.SetQuota(ctx, w.FS, ref, maxBytes)
+}
+
+func (w *wopiLocks) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	return storage.RenameHome(ctx, w.FS, oldUser, newUser)
+}
+
+func (w *wopiLocks) ListSpaceRecycle(ctx context.Context, root *provider.ResourceId) ([]*provider.RecycleItem, error) {
+	return storage.ListSpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wopiLocks) RestoreSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key, restorePath string) error {
+	return storage.RestoreSpaceRecycleItem(ctx, w.FS, root, key, restorePath)
+}
+
+func (w *wopiLocks) PurgeSpaceRecycleItem(ctx context.Context, root *provider.ResourceId, key string) error {
+	return storage.PurgeSpaceRecycleItem(ctx, w.FS, root, key)
+}
+
+func (w *wopiLocks) EmptySpaceRecycle(ctx context.Context, root *provider.ResourceId) error {
+	return storage.EmptySpaceRecycle(ctx, w.FS, root)
+}
+
+func (w *wopiLocks) PinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.PinResidency(ctx, w.FS, ref)
+}
+
+func (w *wopiLocks) UnpinResidency(ctx context.Context, ref *provider.Reference) error {
+	return storage.UnpinResidency(ctx, w.FS, ref)
+}
+
+func (w *wopiLocks) Watch(ctx context.Context, ref *provider.Reference) (<-chan storage.ChangeEvent, error) {
+	return storage.WatchNotified(ctx, w.FS, ref)
+}
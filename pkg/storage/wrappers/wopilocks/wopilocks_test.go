@@ -0,0 +1,158 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wopilocks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/app/provider/wopi"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+// memFS keeps the metadata of a single file in memory.
+type memFS struct {
+	storage.FS
+	md map[string]string
+}
+
+func (m *memFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	md := map[string]string{}
+	for k, v := range m.md {
+		md[k] = v
+	}
+	return &provider.ResourceInfo{
+		Type:              provider.ResourceType_RESOURCE_TYPE_FILE,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: md},
+	}, nil
+}
+
+func (m *memFS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	for k, v := range md.Metadata {
+		m.md[k] = v
+	}
+	return nil
+}
+
+func (m *memFS) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	for _, k := range keys {
+		delete(m.md, k)
+	}
+	return nil
+}
+
+func (m *memFS) lock(t *testing.T) *storage.Lock {
+	v, ok := m.md[storage.LockKey]
+	if !ok {
+		return nil
+	}
+	l, err := storage.DecodeLock(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestWOPILocks(t *testing.T) {
+	now := time.Now()
+	mem := &memFS{md: map[string]string{}}
+	fs, err := New(mem, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.(*wopiLocks).now = func() time.Time { return now }
+	einstein := &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}
+	ctx := user.ContextSetUser(context.Background(), &userpb.User{Id: einstein})
+	ref := &provider.Reference{Spec: &provider.Reference_Path{Path: "/doc.odt"}}
+	setWOPILock := func(v string) error {
+		return fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{wopi.LockKey: v}})
+	}
+
+	// lock
+	if err := setWOPILock("lock-1"); err != nil {
+		t.Fatal(err)
+	}
+	l := mem.lock(t)
+	if !wopi.IsMirrorLock(l) || l.User.OpaqueId != "einstein" || !l.Expires.Equal(now.Add(wopi.LockDuration)) {
+		t.Fatalf("expected a lock mirroring the WOPI lock, got %+v", l)
+	}
+	if mem.md[wopi.LockKey] != "lock-1" {
+		t.Errorf("expected the WOPI lock to be kept, got %q", mem.md[wopi.LockKey])
+	}
+
+	// refresh
+	now = now.Add(10 * time.Minute)
+	if err := setWOPILock("lock-1"); err != nil {
+		t.Fatal(err)
+	}
+	if r := mem.lock(t); r.Token != l.Token || !r.Expires.Equal(now.Add(wopi.LockDuration)) {
+		t.Errorf("expected the refresh to extend lock %s, got %+v", l.Token, r)
+	}
+
+	// unlock
+	if err := fs.UnsetArbitraryMetadata(ctx, ref, []string{wopi.LockKey}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mem.md) != 0 {
+		t.Errorf("expected both locks to be released, got %v", mem.md)
+	}
+
+	// a WebDAV lock keeps the WOPI lock from being taken and outlives its unlock
+	dav := &storage.Lock{Token: "opaquelocktoken:dav", User: einstein, Owner: "litmus", Expires: now.Add(time.Hour)}
+	v, _ := dav.Encode()
+	mem.md[storage.LockKey] = v
+	if err := setWOPILock("lock-2"); err == nil {
+		t.Error("expected the WOPI lock to be refused")
+	}
+	if err := fs.UnsetArbitraryMetadata(ctx, ref, []string{wopi.LockKey}); err != nil {
+		t.Fatal(err)
+	}
+	if l := mem.lock(t); l == nil || l.Token != dav.Token {
+		t.Errorf("expected the WebDAV lock to be kept, got %+v", l)
+	}
+
+	// once the WebDAV lock expired the WOPI lock replaces it
+	now = now.Add(2 * time.Hour)
+	if err := setWOPILock("lock-3"); err != nil {
+		t.Fatal(err)
+	}
+	if l := mem.lock(t); !wopi.IsMirrorLock(l) || l.Token == dav.Token {
+		t.Errorf("expected a new lock mirroring the WOPI lock, got %+v", l)
+	}
+
+	// an empty WOPI lock unlocks as well
+	if err := setWOPILock(""); err != nil {
+		t.Fatal(err)
+	}
+	if len(mem.md) != 0 {
+		t.Errorf("expected both locks to be released, got %v", mem.md)
+	}
+
+	// other metadata is left to the storage
+	if err := fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"color": "red"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mem.md) != 1 || mem.md["color"] != "red" {
+		t.Errorf("expected only the color to be set, got %v", mem.md)
+	}
+}
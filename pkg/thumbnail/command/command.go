@@ -0,0 +1,172 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package command provides a thumbnail resizer running external tools, such
+// as pdftoppm for PDF documents or ffmpeg for videos.
+package command
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/thumbnail"
+	"github.com/cs3org/reva/pkg/thumbnail/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("command", New)
+}
+
+type config struct {
+	// Commands maps mime types, or mime type prefixes ending with a slash such as
+	// video/, to the command generating their thumbnails. The arguments are
+	// templates filled with the Input file and the Width and Height of the
+	// thumbnail, which the command writes to its standard output.
+	Commands map[string]string `mapstructure:"commands"`
+	// MimeType is the mime type of the thumbnails written by the commands.
+	MimeType string `mapstructure:"mime_type"`
+	// TmpDir is the directory the files are written to for the commands to read them.
+	TmpDir string `mapstructure:"tmp_dir"`
+	// Timeout is the time in seconds a command may run for.
+	Timeout int `mapstructure:"timeout"`
+}
+
+func (c *config) init() {
+	if c.Commands == nil {
+		c.Commands = map[string]string{
+			"application/pdf": "pdftoppm -png -singlefile -scale-to-x {{.Width}} -scale-to-y -1 {{.Input}}",
+			"video/":          "ffmpeg -loglevel error -i {{.Input}} -frames:v 1 -vf scale='min({{.Width}},iw)':'min({{.Height}},ih)':force_original_aspect_ratio=decrease -f image2pipe -vcodec png -",
+		}
+	}
+	if c.MimeType == "" {
+		c.MimeType = "image/png"
+	}
+	if c.TmpDir == "" {
+		c.TmpDir = os.TempDir()
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30
+	}
+}
+
+type args struct {
+	Input  string
+	Width  int
+	Height int
+}
+
+type resizer struct {
+	conf     *config
+	commands map[string][]*template.Template
+}
+
+// New returns a resizer running the configured commands.
+func New(m map[string]interface{}) (thumbnail.Resizer, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "command: error decoding conf")
+	}
+	c.init()
+
+	r := &resizer{conf: c, commands: map[string][]*template.Template{}}
+	for mime, cmd := range c.Commands {
+		// the command is split before the arguments are filled, so that
+		// file names with spaces can't add arguments
+		fields := strings.Fields(cmd)
+		if len(fields) == 0 {
+			return nil, errors.New("command: empty command for " + mime)
+		}
+		for _, f := range fields {
+			t, err := template.New(mime).Parse(f)
+			if err != nil {
+				return nil, errors.Wrap(err, "command: error parsing command for "+mime)
+			}
+			r.commands[mime] = append(r.commands[mime], t)
+		}
+	}
+	return r, nil
+}
+
+func (r *resizer) command(mimeType string) []*template.Template {
+	if cmd, ok := r.commands[mimeType]; ok {
+		return cmd
+	}
+	if i := strings.Index(mimeType, "/"); i >= 0 {
+		return r.commands[mimeType[:i+1]]
+	}
+	return nil
+}
+
+func (r *resizer) Supports(mimeType string) bool {
+	return r.command(mimeType) != nil
+}
+
+func (r *resizer) Thumbnail(ctx context.Context, in io.Reader, mimeType string, width, height int) ([]byte, string, error) {
+	cmd := r.command(mimeType)
+	if cmd == nil {
+		return nil, "", errtypes.NotSupported("command: no command for " + mimeType)
+	}
+
+	// the tools need to seek in their input, a file is safer than a pipe
+	f, err := ioutil.TempFile(r.conf.TmpDir, "thumbnail-")
+	if err != nil {
+		return nil, "", errors.Wrap(err, "command: error creating input file")
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, in)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, "", errors.Wrap(err, "command: error writing input file")
+	}
+
+	a := args{Input: f.Name(), Width: width, Height: height}
+	argv := make([]string, 0, len(cmd))
+	for _, t := range cmd {
+		b := &strings.Builder{}
+		if err := t.Execute(b, a); err != nil {
+			return nil, "", errors.Wrap(err, "command: error filling command")
+		}
+		argv = append(argv, b.String())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.conf.Timeout)*time.Second)
+	defer cancel()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	if err := c.Run(); err != nil {
+		return nil, "", errors.Wrapf(err, "command: error running %s: %s", argv[0], strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, "", errors.New("command: " + argv[0] + " wrote no thumbnail")
+	}
+	return stdout.Bytes(), r.conf.MimeType, nil
+}
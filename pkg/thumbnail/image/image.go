@@ -0,0 +1,170 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package image provides a thumbnail resizer for the image formats supported
+// by the standard library: JPEG, PNG and GIF.
+package image
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+
+	// register the GIF decoder
+	_ "image/gif"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/thumbnail"
+	"github.com/cs3org/reva/pkg/thumbnail/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("image", New)
+}
+
+var mimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type config struct {
+	// Quality is the JPEG quality of the thumbnails of JPEG images.
+	Quality int `mapstructure:"quality"`
+	// MaxPixels is the number of pixels of the largest image decoded.
+	MaxPixels int `mapstructure:"max_pixels"`
+}
+
+func (c *config) init() {
+	if c.Quality <= 0 || c.Quality > 100 {
+		c.Quality = 85
+	}
+	if c.MaxPixels <= 0 {
+		c.MaxPixels = 50 * 1000 * 1000
+	}
+}
+
+type resizer struct {
+	conf *config
+}
+
+// New returns a resizer for JPEG, PNG and GIF images.
+func New(m map[string]interface{}) (thumbnail.Resizer, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "image: error decoding conf")
+	}
+	c.init()
+	return &resizer{conf: c}, nil
+}
+
+func (r *resizer) Supports(mimeType string) bool {
+	return mimeTypes[mimeType]
+}
+
+func (r *resizer) Thumbnail(ctx context.Context, in io.Reader, mimeType string, width, height int) ([]byte, string, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "image: error reading image")
+	}
+
+	// check the size before decoding, so that small files can't claim huge images
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errtypes.BadRequest("image: unsupported image: " + err.Error())
+	}
+	if cfg.Width*cfg.Height > r.conf.MaxPixels {
+		return nil, "", errtypes.BadRequest("image: image too large")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errtypes.BadRequest("image: unsupported image: " + err.Error())
+	}
+
+	w, h := thumbnail.Fit(img.Bounds().Dx(), img.Bounds().Dy(), width, height)
+	thumb := Scale(img, w, h)
+
+	buf := &bytes.Buffer{}
+	if mimeType == "image/jpeg" {
+		err = jpeg.Encode(buf, thumb, &jpeg.Options{Quality: r.conf.Quality})
+	} else {
+		// keep the transparency of PNG and GIF images
+		mimeType = "image/png"
+		err = png.Encode(buf, thumb)
+	}
+	if err != nil {
+		return nil, "", errors.Wrap(err, "image: error encoding thumbnail")
+	}
+	return buf.Bytes(), mimeType, nil
+}
+
+// Scale returns the image scaled down to w x h pixels, every pixel of the
+// result being the average of the pixels of the source it covers.
+func Scale(src image.Image, w, h int) *image.NRGBA {
+	b := src.Bounds()
+	if b.Dx() == w && b.Dy() == h {
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := b.Min.Y + (y+1)*b.Dy()/h
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := b.Min.X + (x+1)*b.Dx()/w
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			// average in premultiplied alpha so that transparent pixels don't bleed their color
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					bl += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+			c := color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			}
+			dst.Set(x, y, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,108 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package image
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/thumbnail"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, maxW, maxH int
+		wantW, wantH     int
+	}{
+		{100, 50, 200, 200, 100, 50},
+		{400, 200, 100, 100, 100, 50},
+		{200, 400, 100, 100, 50, 100},
+		{1000, 1, 100, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		w, h := thumbnail.Fit(tt.w, tt.h, tt.maxW, tt.maxH)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("Fit(%d, %d, %d, %d) = %d, %d, want %d, %d", tt.w, tt.h, tt.maxW, tt.maxH, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	// left half black, right half white
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.NRGBA{A: 255}
+			if x >= 2 {
+				c = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	dst := Scale(src, 2, 1)
+	if got := dst.NRGBAAt(0, 0); got != (color.NRGBA{A: 255}) {
+		t.Errorf("left pixel = %v, want black", got)
+	}
+	if got := dst.NRGBAAt(1, 0); got != (color.NRGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("right pixel = %v, want white", got)
+	}
+
+	dst = Scale(src, 1, 1)
+	if got := dst.NRGBAAt(0, 0); got.R < 126 || got.R > 128 {
+		t.Errorf("averaged pixel = %v, want grey", got)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, 300, 150))); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Supports("image/png") || r.Supports("application/pdf") {
+		t.Fatal("unexpected supported mime types")
+	}
+	data, mime, err := r.Thumbnail(context.Background(), buf, "image/png", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != "image/png" {
+		t.Errorf("mime type = %s, want image/png", mime)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 100 || cfg.Height != 50 {
+		t.Errorf("thumbnail is %dx%d, want 100x50", cfg.Width, cfg.Height)
+	}
+
+	if _, _, err := r.Thumbnail(context.Background(), bytes.NewReader([]byte("not an image")), "image/png", 100, 100); err == nil {
+		t.Error("expected an error for invalid images")
+	}
+}
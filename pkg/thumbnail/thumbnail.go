@@ -0,0 +1,57 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package thumbnail provides the resizers generating the thumbnails of files.
+package thumbnail
+
+import (
+	"context"
+	"io"
+)
+
+// Resizer generates thumbnails.
+type Resizer interface {
+	// Supports returns whether the resizer can generate thumbnails of files of the given mime type.
+	Supports(mimeType string) bool
+	// Thumbnail returns a thumbnail fitting in width x height of the file read
+	// from r, along with the mime type of the thumbnail.
+	Thumbnail(ctx context.Context, r io.Reader, mimeType string, width, height int) ([]byte, string, error)
+}
+
+// Fit returns the size of an image of w x h pixels scaled down to fit in
+// maxW x maxH pixels, keeping its aspect ratio. Images are never scaled up.
+func Fit(w, h, maxW, maxH int) (int, int) {
+	if w <= maxW && h <= maxH {
+		return w, h
+	}
+	// compare maxW/w with maxH/h without losing precision
+	if maxW*h <= maxH*w {
+		h = h * maxW / w
+		w = maxW
+	} else {
+		w = w * maxH / h
+		h = maxH
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
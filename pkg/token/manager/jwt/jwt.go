@@ -20,20 +20,32 @@ package jwt
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"time"
 
 	auth "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	authscope "github.com/cs3org/reva/pkg/auth/scope"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/token"
 	"github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 )
 
-const defaultExpiration int64 = 86400 // 1 day
+const (
+	defaultExpiration         int64 = 86400 // 1 day
+	defaultMaxDelegationDepth       = 3
+)
 
 func init() {
 	registry.Register("jwt", New)
@@ -42,10 +54,21 @@ func init() {
 type config struct {
 	Secret  string `mapstructure:"secret"`
 	Expires int64  `mapstructure:"expires"`
+	// MaxDelegationDepth is the number of services that may act in a row for
+	// the user of a token.
+	MaxDelegationDepth int `mapstructure:"max_delegation_depth"`
+	// Delegators are the services allowed to act for the users, any is if
+	// empty.
+	Delegators []string `mapstructure:"delegators"`
+	// RevocationStore is the store of the revoked tokens, none if empty.
+	RevocationStore  string                            `mapstructure:"revocation_store"`
+	RevocationStores map[string]map[string]interface{} `mapstructure:"revocation_stores"`
 }
 
 type manager struct {
-	conf *config
+	conf       *config
+	delegators map[string]bool
+	revoked    revocation.Store
 }
 
 // claims are custom claims for the JWT token.
@@ -53,6 +76,9 @@ type claims struct {
 	jwt.StandardClaims
 	User  *user.User             `json:"user"`
 	Scope map[string]*auth.Scope `json:"scope"`
+	// Delegation is the chain of the services which minted the token for
+	// its user, the latest last.
+	Delegation []*token.Actor `json:"act,omitempty"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -74,6 +100,9 @@ func New(value map[string]interface{}) (token.Manager, error) {
 	if c.Expires == 0 {
 		c.Expires = defaultExpiration
 	}
+	if c.MaxDelegationDepth == 0 {
+		c.MaxDelegationDepth = defaultMaxDelegationDepth
+	}
 
 	c.Secret = sharedconf.GetJWTSecret(c.Secret)
 
@@ -81,21 +110,50 @@ func New(value map[string]interface{}) (token.Manager, error) {
 		return nil, errors.New("jwt: secret for signing payloads is not defined in config")
 	}
 
-	m := &manager{conf: c}
+	m := &manager{conf: c, delegators: map[string]bool{}}
+	for _, d := range c.Delegators {
+		m.delegators[d] = true
+	}
+	if c.RevocationStore != "" {
+		f, ok := revocationregistry.NewFuncs[c.RevocationStore]
+		if !ok {
+			return nil, fmt.Errorf("jwt: revocation store %s not found", c.RevocationStore)
+		}
+		if m.revoked, err = f(c.RevocationStores[c.RevocationStore]); err != nil {
+			return nil, errors.Wrap(err, "jwt: error creating the revocation store")
+		}
+	}
 	return m, nil
 }
 
 func (m *manager) MintToken(ctx context.Context, u *user.User, scope map[string]*auth.Scope) (string, error) {
+	// the token of the request the token is minted for, if any
+	caller := m.parse(ctx)
+
+	var delegation []*token.Actor
+	if caller != nil {
+		delegation = caller.Delegation
+	}
+	actor, impersonation := m.actor(ctx, u)
+	if actor != nil {
+		delegation = append(append([]*token.Actor{}, delegation...), actor)
+	}
+	if len(delegation) > m.conf.MaxDelegationDepth {
+		return "", errtypes.PermissionDenied(fmt.Sprintf("jwt: the delegation chain is longer than %d", m.conf.MaxDelegationDepth))
+	}
+
 	ttl := time.Duration(m.conf.Expires) * time.Second
 	claims := claims{
 		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
 			ExpiresAt: time.Now().Add(ttl).Unix(),
 			Issuer:    u.Id.Idp,
 			Audience:  "reva",
 			IssuedAt:  time.Now().Unix(),
 		},
-		User:  u,
-		Scope: scope,
+		User:       u,
+		Scope:      scope,
+		Delegation: delegation,
 	}
 
 	t := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claims)
@@ -105,6 +163,21 @@ func (m *manager) MintToken(ctx context.Context, u *user.User, scope map[string]
 		return "", errors.Wrapf(err, "error signing token with claims %+v", claims)
 	}
 
+	// every token is logged with its id, which is needed to revoke it
+	log := appctx.GetLogger(ctx)
+	elevation := caller != nil && elevates(caller.Scope, scope)
+	ev := log.Info()
+	if elevation {
+		ev = log.Warn()
+	}
+	ev.Str("jti", claims.Id).
+		Str("userid", u.Id.GetOpaqueId()).Str("idp", u.Id.GetIdp()).
+		Strs("scope", scopeKeys(scope)).
+		Time("expires", time.Unix(claims.ExpiresAt, 0)).
+		Interface("delegation", delegation).
+		Bool("impersonation", impersonation).Bool("elevation", elevation).
+		Msg("jwt: token minted")
+
 	return tkn, nil
 }
 
@@ -117,9 +190,116 @@ func (m *manager) DismantleToken(ctx context.Context, tkn string) (*user.User, m
 		return nil, nil, errors.Wrap(err, "error parsing token")
 	}
 
-	if claims, ok := token.Claims.(*claims); ok && token.Valid {
-		return claims.User, claims.Scope, nil
+	claims, ok := token.Claims.(*claims)
+	if !ok || !token.Valid {
+		return nil, nil, errtypes.InvalidCredentials("invalid token")
+	}
+	if err := m.verifyDelegation(claims.Delegation); err != nil {
+		return nil, nil, err
+	}
+	if m.revoked != nil && claims.Id != "" {
+		revoked, err := m.revoked.IsRevoked(ctx, claims.Id)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "jwt: error looking up the revoked tokens")
+		}
+		if revoked {
+			return nil, nil, errtypes.InvalidCredentials("token revoked")
+		}
+	}
+	return claims.User, claims.Scope, nil
+}
+
+// RevokeToken implements token.Revoker. The id is kept until the tokens
+// minted now expire, the revoked token being older.
+func (m *manager) RevokeToken(ctx context.Context, id string) error {
+	if m.revoked == nil {
+		return errtypes.NotSupported("jwt: no revocation store configured")
+	}
+	expires := time.Now().Add(time.Duration(m.conf.Expires) * time.Second)
+	if err := m.revoked.Revoke(ctx, id, expires); err != nil {
+		return errors.Wrap(err, "jwt: error revoking token")
+	}
+
+	log := appctx.GetLogger(ctx)
+	ev := log.Info().Str("jti", id)
+	if u, ok := ctxuser.ContextGetUser(ctx); ok {
+		ev = ev.Str("revoked_by", u.Username)
+	}
+	ev.Msg("jwt: token revoked")
+	return nil
+}
+
+// parse returns the claims of the token of the context, nil if it has none
+// or it is not a valid token of this manager.
+func (m *manager) parse(ctx context.Context) *claims {
+	tkn, ok := token.ContextGetToken(ctx)
+	if !ok || tkn == "" {
+		return nil
+	}
+	t, err := jwt.ParseWithClaims(tkn, &claims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(m.conf.Secret), nil
+	})
+	if err != nil {
+		return nil
+	}
+	if c, ok := t.Claims.(*claims); ok && t.Valid {
+		return c
 	}
+	return nil
+}
+
+// actor returns the link added to the delegation chain when a service set in
+// the context mints a token, or when a token is minted for another user than
+// the one of the context, which is then an impersonation.
+func (m *manager) actor(ctx context.Context, u *user.User) (*token.Actor, bool) {
+	service, _ := token.ContextGetService(ctx)
+	cu, ok := ctxuser.ContextGetUser(ctx)
+	impersonation := ok && cu.Id != nil && !utils.UserEqual(cu.Id, u.Id)
+	if service == "" && !impersonation {
+		return nil, false
+	}
+	a := &token.Actor{Service: service}
+	if impersonation {
+		a.User = cu.Id
+	}
+	return a, impersonation
+}
 
-	return nil, nil, errtypes.InvalidCredentials("invalid token")
+func (m *manager) verifyDelegation(delegation []*token.Actor) error {
+	if len(delegation) > m.conf.MaxDelegationDepth {
+		return errtypes.InvalidCredentials("jwt: delegation chain too long")
+	}
+	if len(m.delegators) == 0 {
+		return nil
+	}
+	for _, a := range delegation {
+		if !m.delegators[a.Service] {
+			return errtypes.InvalidCredentials(fmt.Sprintf("jwt: service %q may not act for the users", a.Service))
+		}
+	}
+	return nil
+}
+
+// elevates tells whether the scope grants more than the one of the caller:
+// unless the caller has the owner scope, a scope it has not or with another
+// role.
+func elevates(caller, scope map[string]*auth.Scope) bool {
+	if authscope.IsOwnerScope(caller) {
+		return false
+	}
+	for k, s := range scope {
+		if c, ok := caller[k]; !ok || c.GetRole() != s.GetRole() {
+			return true
+		}
+	}
+	return false
+}
+
+func scopeKeys(scope map[string]*auth.Scope) []string {
+	keys := make([]string, 0, len(scope))
+	for k := range scope {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,138 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package jwt
+
+import (
+	"context"
+	"testing"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token"
+	_ "github.com/cs3org/reva/pkg/token/revocation/memory"
+	ctxuser "github.com/cs3org/reva/pkg/user"
+)
+
+var (
+	einstein = &user.User{Id: &user.UserId{Idp: "http://idp", OpaqueId: "einstein"}, Username: "einstein"}
+	marie    = &user.User{Id: &user.UserId{Idp: "http://idp", OpaqueId: "marie"}, Username: "marie"}
+	richard  = &user.User{Id: &user.UserId{Idp: "http://idp", OpaqueId: "richard"}, Username: "richard"}
+)
+
+func newManager(t *testing.T, conf map[string]interface{}) *manager {
+	conf["secret"] = "secret"
+	m, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.(*manager)
+}
+
+func mint(t *testing.T, m *manager, ctx context.Context, u *user.User) string {
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tkn, err := m.MintToken(ctx, u, ownerScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tkn
+}
+
+// asUser returns the context of a request of the user with the token.
+func asUser(u *user.User, tkn string) context.Context {
+	return token.ContextSetToken(ctxuser.ContextSetUser(context.Background(), u), tkn)
+}
+
+func TestDelegation(t *testing.T) {
+	m := newManager(t, map[string]interface{}{})
+
+	tkn := mint(t, m, context.Background(), einstein)
+	if c := m.parse(token.ContextSetToken(context.Background(), tkn)); c == nil || len(c.Delegation) != 0 || c.Id == "" {
+		t.Fatalf("unexpected claims of a login token: %+v", c)
+	}
+
+	// the gateway acting for marie while serving a request of einstein
+	ctx := token.ContextSetService(asUser(einstein, tkn), "gateway")
+	delegated := mint(t, m, ctx, marie)
+	u, _, err := m.DismantleToken(context.Background(), delegated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "marie" {
+		t.Fatalf("user = %s, want marie", u.Username)
+	}
+	c := m.parse(token.ContextSetToken(context.Background(), delegated))
+	if len(c.Delegation) != 1 || c.Delegation[0].Service != "gateway" || c.Delegation[0].User.GetOpaqueId() != "einstein" {
+		t.Fatalf("unexpected delegation chain %+v", c.Delegation)
+	}
+
+	// the chain is extended by the services acting in turn
+	ctx = token.ContextSetService(asUser(marie, delegated), "usershareprovider")
+	c = m.parse(token.ContextSetToken(context.Background(), mint(t, m, ctx, richard)))
+	if len(c.Delegation) != 2 || c.Delegation[1].Service != "usershareprovider" || c.Delegation[1].User.GetOpaqueId() != "marie" {
+		t.Fatalf("unexpected delegation chain %+v", c.Delegation)
+	}
+}
+
+func TestDelegationVerified(t *testing.T) {
+	m := newManager(t, map[string]interface{}{"max_delegation_depth": 1, "delegators": []string{"gateway"}})
+
+	tkn := mint(t, m, token.ContextSetService(context.Background(), "gateway"), marie)
+	if _, _, err := m.DismantleToken(context.Background(), tkn); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.MintToken(token.ContextSetService(asUser(marie, tkn), "gateway"), einstein, nil); err == nil {
+		t.Fatal("minted a token with a delegation chain longer than the maximum")
+	}
+
+	// minted by a manager not restricting the delegators
+	other := newManager(t, map[string]interface{}{})
+	tkn = mint(t, other, token.ContextSetService(context.Background(), "notifications"), marie)
+	if _, _, err := m.DismantleToken(context.Background(), tkn); err == nil {
+		t.Fatal("accepted a token minted by a service not allowed to act for the users")
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	m := newManager(t, map[string]interface{}{"revocation_store": "memory"})
+
+	tkn := mint(t, m, context.Background(), einstein)
+	other := mint(t, m, context.Background(), einstein)
+	id := m.parse(token.ContextSetToken(context.Background(), tkn)).Id
+
+	if err := m.RevokeToken(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.DismantleToken(context.Background(), tkn); err == nil {
+		t.Fatal("accepted a revoked token")
+	} else if _, ok := err.(errtypes.IsInvalidCredentials); !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, _, err := m.DismantleToken(context.Background(), other); err != nil {
+		t.Fatalf("the other token was revoked as well: %v", err)
+	}
+
+	if err := newManager(t, map[string]interface{}{}).RevokeToken(context.Background(), id); err == nil {
+		t.Fatal("revoked a token without a revocation store")
+	}
+}
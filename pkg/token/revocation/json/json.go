@@ -0,0 +1,172 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package json provides a revocation store kept in a JSON file, which the
+// revad processes of a deployment share through the file system.
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File           string `mapstructure:"file" docs:"/var/tmp/reva/revoked-tokens.json;The file keeping the ids of the revoked tokens and their expiration."`
+	ReloadInterval int    `mapstructure:"reload_interval" docs:"5;The seconds after which the file is checked again for the tokens revoked by the other processes."`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/revoked-tokens.json"
+	}
+	if c.ReloadInterval <= 0 {
+		c.ReloadInterval = 5
+	}
+}
+
+// Store keeps the revoked tokens in a file, mapping their ids to the unix
+// time of their expiration.
+type Store struct {
+	file     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	revoked map[string]int64
+	modTime time.Time
+	checked time.Time
+}
+
+// New returns a new revocation store kept in a JSON file.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "json: error decoding conf")
+	}
+	c.init()
+	return NewStore(c.File, time.Duration(c.ReloadInterval)*time.Second)
+}
+
+// NewStore returns a store kept in the given file, which is read again when
+// it changed and the interval elapsed.
+func NewStore(file string, interval time.Duration) (*Store, error) {
+	s := &Store{file: file, interval: interval, revoked: map[string]int64{}}
+	if err := s.load(true); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Revoke implements revocation.Store.
+func (s *Store) Revoke(ctx context.Context, id string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// not to lose the tokens revoked in the meantime by the other processes
+	if err := s.load(true); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for k, exp := range s.revoked {
+		if exp < now {
+			delete(s.revoked, k)
+		}
+	}
+	s.revoked[id] = expires.Unix()
+	return s.save()
+}
+
+// IsRevoked implements revocation.Store.
+func (s *Store) IsRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(false); err != nil {
+		return false, err
+	}
+	_, ok := s.revoked[id]
+	return ok, nil
+}
+
+// load reads the file if it changed since it was last read, at most once per
+// interval unless forced.
+func (s *Store) load(force bool) error {
+	now := time.Now()
+	if !force && now.Sub(s.checked) < s.interval {
+		return nil
+	}
+	s.checked = now
+
+	info, err := os.Stat(s.file)
+	switch {
+	case os.IsNotExist(err):
+		s.revoked = map[string]int64{}
+		return nil
+	case err != nil:
+		return errors.Wrap(err, "json: error reading revoked tokens")
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		return errors.Wrap(err, "json: error reading revoked tokens")
+	}
+	revoked := map[string]int64{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &revoked); err != nil {
+			return errors.Wrap(err, "json: error decoding revoked tokens")
+		}
+	}
+	s.revoked, s.modTime = revoked, info.ModTime()
+	return nil
+}
+
+func (s *Store) save() error {
+	b, err := json.Marshal(s.revoked)
+	if err != nil {
+		return errors.Wrap(err, "json: error encoding revoked tokens")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), 0700); err != nil {
+		return errors.Wrap(err, "json: error creating the dir of the revoked tokens")
+	}
+	tmp := s.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return errors.Wrap(err, "json: error writing revoked tokens")
+	}
+	if err := os.Rename(tmp, s.file); err != nil {
+		return errors.Wrap(err, "json: error writing revoked tokens")
+	}
+	if info, err := os.Stat(s.file); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "revoked.json")
+
+	s, err := NewStore(file, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second process sharing the file
+	other, err := NewStore(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Revoke(ctx, "expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revoke(ctx, "jti", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := s.IsRevoked(ctx, "jti"); err != nil || !revoked {
+		t.Fatalf("IsRevoked = %v, %v, want true", revoked, err)
+	}
+	if revoked, _ := s.IsRevoked(ctx, "expired"); revoked {
+		t.Error("the expired token is still kept")
+	}
+	if revoked, err := other.IsRevoked(ctx, "jti"); err != nil || !revoked {
+		t.Fatalf("IsRevoked in the other process = %v, %v, want true", revoked, err)
+	}
+
+	// the revocation of the other process is kept as well
+	if err := other.Revoke(ctx, "jti2", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revoke(ctx, "jti3", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"jti", "jti2", "jti3"} {
+		if revoked, _ := other.IsRevoked(ctx, id); !revoked {
+			t.Errorf("%s is not revoked", id)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package memory provides a revocation store local to the process, for the
+// deployments running all the services in a single revad.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+// Store keeps the revoked tokens in memory.
+type Store struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+// New returns a new revocation store kept in memory.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	return NewStore(), nil
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{revoked: map[string]time.Time{}}
+}
+
+// Revoke implements revocation.Store.
+func (s *Store) Revoke(ctx context.Context, id string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, exp := range s.revoked {
+		if exp.Before(now) {
+			delete(s.revoked, k)
+		}
+	}
+	s.revoked[id] = expires
+	return nil
+}
+
+// IsRevoked implements revocation.Store.
+func (s *Store) IsRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.revoked[id]
+	return ok, nil
+}
@@ -0,0 +1,35 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package revocation defines the stores of the ids of the revoked tokens,
+// which the token managers look up when dismantling the tokens.
+package revocation
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps the ids of the revoked tokens.
+type Store interface {
+	// Revoke revokes the token with the given id. The store may forget it
+	// once the token has expired.
+	Revoke(ctx context.Context, id string, expires time.Time) error
+	// IsRevoked tells whether the token with the given id was revoked.
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
@@ -31,7 +31,10 @@ const TokenHeader = "x-access-token"
 
 type key int
 
-const tokenKey key = iota
+const (
+	tokenKey key = iota
+	serviceKey
+)
 
 // Manager is the interface to implement to sign and verify tokens
 type Manager interface {
@@ -39,6 +42,19 @@ type Manager interface {
 	DismantleToken(ctx context.Context, token string) (*user.User, map[string]*auth.Scope, error)
 }
 
+// Actor is a link of the delegation chain of a token: the service which
+// minted it for its user, while serving the request of User if set.
+type Actor struct {
+	Service string       `json:"service,omitempty"`
+	User    *user.UserId `json:"user,omitempty"`
+}
+
+// Revoker is implemented by the managers able to revoke the tokens they
+// minted by their ids, the jti claim of the JWT tokens.
+type Revoker interface {
+	RevokeToken(ctx context.Context, id string) error
+}
+
 // ContextGetToken returns the token if set in the given context.
 func ContextGetToken(ctx context.Context) (string, bool) {
 	u, ok := ctx.Value(tokenKey).(string)
@@ -58,3 +74,16 @@ func ContextMustGetToken(ctx context.Context) string {
 func ContextSetToken(ctx context.Context, t string) context.Context {
 	return context.WithValue(ctx, tokenKey, t)
 }
+
+// ContextSetService records that the tokens minted with the context are
+// minted by the named service acting for their users, which the managers
+// supporting delegation add to the delegation chain of the tokens.
+func ContextSetService(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, serviceKey, name)
+}
+
+// ContextGetService returns the service set by ContextSetService.
+func ContextGetService(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(serviceKey).(string)
+	return s, ok
+}
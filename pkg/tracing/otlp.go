@@ -0,0 +1,192 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// OTLPOptions are the options of the OTLP exporter.
+type OTLPOptions struct {
+	// Endpoint is the URL of the collector, e.g. http://localhost:4318, to
+	// which the spans are posted under /v1/traces unless it has a path.
+	Endpoint    string
+	ServiceName string
+	// Headers are added to the requests, e.g. to authenticate them.
+	Headers map[string]string
+	// OnError is called with the errors sending the spans.
+	OnError func(error)
+}
+
+// OTLPExporter sends the spans to an OpenTelemetry collector with the OTLP
+// exporter of the OpenTelemetry SDK, over HTTP. reva traces with OpenCensus,
+// so the spans are converted and handed to a batch span processor.
+type OTLPExporter struct {
+	processor sdktrace.SpanProcessor
+	resource  *resource.Resource
+}
+
+// NewOTLPExporter returns an exporter sending the spans in the background
+// every few seconds or as soon as a batch is full.
+func NewOTLPExporter(o OTLPOptions) (*OTLPExporter, error) {
+	endpoint := o.Endpoint
+	if endpoint == "" {
+		endpoint = "http://localhost:4318"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("tracing: invalid otlp endpoint %q", endpoint)
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithHeaders(o.Headers),
+	}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	if o.ServiceName == "" {
+		o.ServiceName = "revad"
+	}
+	if o.OnError != nil {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(o.OnError))
+	}
+
+	// the exporter only connects when sending the first spans
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &OTLPExporter{
+		processor: sdktrace.NewBatchSpanProcessor(exporter),
+		resource:  resource.NewSchemaless(attribute.String("service.name", o.ServiceName)),
+	}, nil
+}
+
+// ExportSpan implements trace.Exporter.
+func (e *OTLPExporter) ExportSpan(s *trace.SpanData) {
+	e.processor.OnEnd(e.span(s).Snapshot())
+}
+
+// Flush sends the spans exported so far.
+func (e *OTLPExporter) Flush() {
+	if err := e.processor.ForceFlush(context.Background()); err != nil {
+		otel.Handle(err)
+	}
+}
+
+// span converts an OpenCensus span. The exported spans are sampled, the
+// processor drops the other ones.
+func (e *OTLPExporter) span(s *trace.SpanData) tracetest.SpanStub {
+	sc := s.SpanContext
+	sc.TraceOptions |= 1
+	span := tracetest.SpanStub{
+		Name:                   s.Name,
+		SpanContext:            spanContext(sc),
+		SpanKind:               oteltrace.SpanKindInternal,
+		StartTime:              s.StartTime,
+		EndTime:                s.EndTime,
+		Attributes:             attributes(s.Attributes),
+		DroppedAttributes:      s.DroppedAttributeCount,
+		DroppedEvents:          s.DroppedAnnotationCount,
+		DroppedLinks:           s.DroppedLinkCount,
+		ChildSpanCount:         s.ChildSpanCount,
+		Resource:               e.resource,
+		InstrumentationLibrary: instrumentation.Library{Name: "github.com/cs3org/reva"},
+	}
+	if s.ParentSpanID != (trace.SpanID{}) {
+		span.Parent = spanContext(trace.SpanContext{TraceID: s.TraceID, SpanID: s.ParentSpanID, TraceOptions: s.TraceOptions})
+	}
+	switch s.SpanKind {
+	case trace.SpanKindServer:
+		span.SpanKind = oteltrace.SpanKindServer
+	case trace.SpanKindClient:
+		span.SpanKind = oteltrace.SpanKindClient
+	}
+	// the OpenCensus code 0 is OK, the other ones are the grpc error codes
+	if s.Code != 0 {
+		span.Status = sdktrace.Status{Code: codes.Error, Description: s.Message}
+	}
+	for _, a := range s.Annotations {
+		span.Events = append(span.Events, sdktrace.Event{
+			Name:       a.Message,
+			Attributes: attributes(a.Attributes),
+			Time:       a.Time,
+		})
+	}
+	for _, l := range s.Links {
+		span.Links = append(span.Links, sdktrace.Link{
+			SpanContext: spanContext(trace.SpanContext{TraceID: l.TraceID, SpanID: l.SpanID}),
+			Attributes:  attributes(l.Attributes),
+		})
+	}
+	return span
+}
+
+func spanContext(sc trace.SpanContext) oteltrace.SpanContext {
+	c := oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID(sc.TraceID),
+		SpanID:     oteltrace.SpanID(sc.SpanID),
+		TraceFlags: oteltrace.TraceFlags(sc.TraceOptions),
+	}
+	if sc.Tracestate != nil {
+		entries := []string{}
+		for _, entry := range sc.Tracestate.Entries() {
+			entries = append(entries, entry.Key+"="+entry.Value)
+		}
+		// the entries have been validated by OpenCensus
+		c.TraceState, _ = oteltrace.ParseTraceState(strings.Join(entries, ","))
+	}
+	return oteltrace.NewSpanContext(c)
+}
+
+func attributes(m map[string]interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			attrs = append(attrs, attribute.String(k, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(k, v))
+		case int64:
+			attrs = append(attrs, attribute.Int64(k, v))
+		case float64:
+			attrs = append(attrs, attribute.Float64(k, v))
+		default:
+			attrs = append(attrs, attribute.String(k, fmt.Sprint(v)))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,104 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package tracing holds what the reva services share to trace a request
+// across them: the propagation of the span context over HTTP and the
+// exporters of the spans.
+package tracing
+
+import (
+	"net/http"
+	"sync"
+
+	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+)
+
+// HTTPFormat propagates the span context in the W3C traceparent header,
+// understood by the OpenTelemetry instrumented services, as well as in the
+// B3 headers sent by the previous versions of reva.
+var HTTPFormat propagation.HTTPFormat = format{}
+
+type format struct{}
+
+var (
+	w3c     = &tracecontext.HTTPFormat{}
+	zipkin  = &b3.HTTPFormat{}
+	formats = []propagation.HTTPFormat{w3c, zipkin}
+)
+
+// SpanContextFromRequest implements propagation.HTTPFormat, preferring the
+// traceparent header over the B3 ones.
+func (format) SpanContextFromRequest(r *http.Request) (trace.SpanContext, bool) {
+	for _, f := range formats {
+		if sc, ok := f.SpanContextFromRequest(r); ok {
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+// SpanContextToRequest implements propagation.HTTPFormat.
+func (format) SpanContextToRequest(sc trace.SpanContext, r *http.Request) {
+	for _, f := range formats {
+		f.SpanContextToRequest(sc, r)
+	}
+}
+
+// Flusher is an exporter sending the spans in batches.
+type Flusher interface {
+	Flush()
+}
+
+var (
+	mu        sync.Mutex
+	exporters []trace.Exporter
+)
+
+// RegisterExporter registers the exporter of the spans, which Flush
+// flushes if it batches them.
+func RegisterExporter(e trace.Exporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	exporters = append(exporters, e)
+	trace.RegisterExporter(e)
+}
+
+// Flush sends the spans buffered by the registered exporters, it is called
+// before exiting not to lose the last ones.
+func Flush() {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, e := range exporters {
+		if f, ok := e.(Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// Sampler returns the sampler tracing the given fraction of the requests that
+// do not carry the sampling decision of a caller. 0, the default, traces all
+// of them.
+func Sampler(fraction float64) trace.Sampler {
+	if fraction <= 0 || fraction >= 1 {
+		return trace.AlwaysSample()
+	}
+	return trace.ProbabilitySampler(fraction)
+}
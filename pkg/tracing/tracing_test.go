@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tracing
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opencensus.io/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPFormat(t *testing.T) {
+	sc := trace.SpanContext{
+		TraceID:      trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:       trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceOptions: 1,
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	HTTPFormat.SpanContextToRequest(sc, r)
+	if got := r.Header.Get("traceparent"); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("traceparent = %q", got)
+	}
+	if got := r.Header.Get("X-B3-TraceId"); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("X-B3-TraceId = %q", got)
+	}
+
+	// an older reva only sends the B3 headers
+	r.Header.Del("traceparent")
+	got, ok := HTTPFormat.SpanContextFromRequest(r)
+	if !ok || got.TraceID != sc.TraceID || got.SpanID != sc.SpanID {
+		t.Errorf("span context from the B3 headers = %v, %v, want %v", got, ok, sc)
+	}
+
+	// the traceparent header wins over the B3 ones
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("X-B3-TraceId", "0000000000000000000000000000abcd")
+	r.Header.Set("X-B3-SpanId", "000000000000abcd")
+	got, ok = HTTPFormat.SpanContextFromRequest(r)
+	if !ok || got.TraceID != sc.TraceID || got.SpanID != sc.SpanID {
+		t.Errorf("span context = %v, %v, want %v", got, ok, sc)
+	}
+
+	if _, ok := HTTPFormat.SpanContextFromRequest(httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+		t.Error("span context found in a request without the headers")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- req
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var errs []error
+	e, err := NewOTLPExporter(OTLPOptions{
+		Endpoint:    srv.URL,
+		ServiceName: "gateway",
+		Headers:     map[string]string{"Authorization": "Bearer secret"},
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1600000000, 0)
+	e.ExportSpan(&trace.SpanData{
+		SpanContext: trace.SpanContext{
+			TraceID: trace.TraceID{1},
+			SpanID:  trace.SpanID{2},
+		},
+		ParentSpanID: trace.SpanID{3},
+		SpanKind:     trace.SpanKindServer,
+		Name:         "/cs3.gateway.v1beta1.GatewayAPI/InitiateFileUpload",
+		StartTime:    start,
+		EndTime:      start.Add(time.Second),
+		Attributes:   map[string]interface{}{"size": int64(42)},
+		Status:       trace.Status{Code: 5, Message: "not found"},
+	})
+	e.Flush()
+	mu.Lock()
+	failed := errs
+	mu.Unlock()
+	if len(failed) != 0 {
+		t.Fatalf("errors sending the spans: %v", failed)
+	}
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].InstrumentationLibrarySpans) != 1 || len(req.ResourceSpans[0].InstrumentationLibrarySpans[0].Spans) != 1 {
+		t.Fatalf("unexpected request %v", req)
+	}
+	if a := req.ResourceSpans[0].Resource.Attributes; len(a) != 1 || a[0].Key != "service.name" || a[0].Value.GetStringValue() != "gateway" {
+		t.Errorf("resource attributes = %v", a)
+	}
+	s := req.ResourceSpans[0].InstrumentationLibrarySpans[0].Spans[0]
+	if hex.EncodeToString(s.TraceId) != "01000000000000000000000000000000" || hex.EncodeToString(s.SpanId) != "0200000000000000" || hex.EncodeToString(s.ParentSpanId) != "0300000000000000" {
+		t.Errorf("ids = %x %x %x", s.TraceId, s.SpanId, s.ParentSpanId)
+	}
+	if s.Kind != tracepb.Span_SPAN_KIND_SERVER || s.StartTimeUnixNano != 1600000000000000000 || s.EndTimeUnixNano != 1600000001000000000 {
+		t.Errorf("span = %v", s)
+	}
+	if len(s.Attributes) != 1 || s.Attributes[0].Key != "size" || s.Attributes[0].Value.GetIntValue() != 42 {
+		t.Errorf("attributes = %v", s.Attributes)
+	}
+	if s.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || s.Status.GetMessage() != "not found" {
+		t.Errorf("status = %v", s.Status)
+	}
+
+	// nothing left to send
+	e.Flush()
+	select {
+	case req := <-requests:
+		t.Errorf("unexpected request %v", req)
+	default:
+	}
+}
+
+func TestSampler(t *testing.T) {
+	p := trace.SamplingParameters{TraceID: trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}
+	if !Sampler(0)(p).Sample {
+		t.Error("the default sampler does not trace all the requests")
+	}
+	if Sampler(0.5)(p).Sample {
+		t.Error("the trace above the fraction is sampled")
+	}
+}
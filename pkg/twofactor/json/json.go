@@ -0,0 +1,163 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package json stores the second factors of the users in a JSON file, which
+// is read on every access so that the services sharing it see the changes of
+// each other.
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/twofactor"
+	"github.com/cs3org/reva/pkg/twofactor/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+type config struct {
+	File string `mapstructure:"file"`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/twofactor.json"
+	}
+}
+
+type manager struct {
+	sync.Mutex
+	c *config
+}
+
+// New returns a second factor manager storing the enrollments in a JSON file.
+func New(m map[string]interface{}) (twofactor.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(filepath.Dir(c.File), 0700); err != nil {
+		return nil, errors.Wrapf(err, "error creating the directory of %s", c.File)
+	}
+	return &manager{c: c}, nil
+}
+
+func key(u *userpb.UserId) string {
+	return u.Idp + "!" + u.OpaqueId
+}
+
+// load reads the enrollments, keyed by user
+func (m *manager) load() (map[string]*twofactor.Enrollment, error) {
+	enrollments := map[string]*twofactor.Enrollment{}
+	data, err := ioutil.ReadFile(m.c.File)
+	if os.IsNotExist(err) {
+		return enrollments, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the file %s", m.c.File)
+	}
+	if len(data) == 0 {
+		return enrollments, nil
+	}
+	if err := json.Unmarshal(data, &enrollments); err != nil {
+		return nil, errors.Wrapf(err, "error parsing the file %s", m.c.File)
+	}
+	return enrollments, nil
+}
+
+func (m *manager) save(enrollments map[string]*twofactor.Enrollment) error {
+	data, err := json.Marshal(enrollments)
+	if err != nil {
+		return errors.Wrap(err, "error encoding json file")
+	}
+	// the secrets are only readable by reva
+	tmp := m.c.File + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrapf(err, "error writing to file %s", tmp)
+	}
+	return os.Rename(tmp, m.c.File)
+}
+
+func (m *manager) GetEnrollment(ctx context.Context, u *userpb.UserId) (*twofactor.Enrollment, error) {
+	m.Lock()
+	defer m.Unlock()
+	enrollments, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := enrollments[key(u)]
+	if !ok {
+		return nil, errtypes.NotFound("twofactor: no enrollment for " + u.OpaqueId)
+	}
+	return e, nil
+}
+
+func (m *manager) SetEnrollment(ctx context.Context, u *userpb.UserId, e *twofactor.Enrollment) error {
+	m.Lock()
+	defer m.Unlock()
+	enrollments, err := m.load()
+	if err != nil {
+		return err
+	}
+	enrollments[key(u)] = e
+	return m.save(enrollments)
+}
+
+func (m *manager) UpdateEnrollment(ctx context.Context, u *userpb.UserId, f func(e *twofactor.Enrollment) error) error {
+	m.Lock()
+	defer m.Unlock()
+	enrollments, err := m.load()
+	if err != nil {
+		return err
+	}
+	e, ok := enrollments[key(u)]
+	if !ok {
+		return errtypes.NotFound("twofactor: no enrollment for " + u.OpaqueId)
+	}
+	if err := f(e); err != nil {
+		return err
+	}
+	return m.save(enrollments)
+}
+
+func (m *manager) DeleteEnrollment(ctx context.Context, u *userpb.UserId) error {
+	m.Lock()
+	defer m.Unlock()
+	enrollments, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := enrollments[key(u)]; !ok {
+		return errtypes.NotFound("twofactor: no enrollment for " + u.OpaqueId)
+	}
+	delete(enrollments, key(u))
+	return m.save(enrollments)
+}
@@ -0,0 +1,131 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package twofactor
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	// the SHA1 test vectors of RFC 6238, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		c, err := Code(secret, time.Unix(tt.time, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != tt.code {
+			t.Errorf("Code at %d = %s, wanted %s", tt.time, c, tt.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	c, err := Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, ok := Validate(secret, c, now, 0)
+	if !ok {
+		t.Error("the current code must be valid")
+	}
+	if _, ok := Validate(secret, c, now.Add(period), 0); !ok {
+		t.Error("the code of the previous period must be valid")
+	}
+	if _, ok := Validate(secret, c, now.Add(3*period), 0); ok {
+		t.Error("old codes must be invalid")
+	}
+	if _, ok := Validate(secret, c, now, n); ok {
+		t.Error("a code accepted once must be invalid")
+	}
+	if _, ok := Validate(secret, "12345", now, 0); ok {
+		t.Error("malformed codes must be invalid")
+	}
+	if _, ok := Validate("not base32!", c, now, 0); ok {
+		t.Error("malformed secrets must be invalid")
+	}
+
+	uri := KeyURI("reva", "einstein", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/reva:einstein?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected key uri %s", uri)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	c, err := Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Enrollment{Secret: secret}
+	if !e.Check(c, now) {
+		t.Fatal("the current code must be accepted")
+	}
+	if e.Check(c, now) {
+		t.Error("a replayed code must be refused")
+	}
+
+	wrong := "000000"
+	if c == wrong {
+		wrong = "111111"
+	}
+	e = &Enrollment{Secret: secret}
+	for i := 0; i < MaxFailures; i++ {
+		if e.Check(wrong, now) {
+			t.Fatal("a wrong code must be refused")
+		}
+	}
+	if !e.Locked(now) || e.Check(c, now) {
+		t.Error("the enrollment must be locked after too many failures")
+	}
+	later := now.Add(Lockout)
+	if e.Locked(later) {
+		t.Error("the lockout must expire")
+	}
+	if c, err = Code(secret, later); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Check(c, later) {
+		t.Error("the code must be accepted after the lockout")
+	}
+}
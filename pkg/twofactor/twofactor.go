@@ -0,0 +1,92 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package twofactor defines the stores of the second factors of the users,
+// time-based one-time passwords (TOTP) generated by authenticator apps.
+package twofactor
+
+import (
+	"context"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+// Enrollment is the second factor of a user.
+type Enrollment struct {
+	// Secret is the base32 encoded TOTP secret.
+	Secret string `json:"secret"`
+	// Confirmed tells whether the user proved to generate the codes of the
+	// secret, the second factor is only required once confirmed.
+	Confirmed bool `json:"confirmed"`
+	// Counter is the TOTP counter of the last code accepted, the codes of
+	// the same or of earlier periods are refused.
+	Counter uint64 `json:"counter,omitempty"`
+	// Failures is the number of consecutive codes refused, and LockedUntil
+	// the unix time until which the codes are refused after MaxFailures.
+	Failures    int   `json:"failures,omitempty"`
+	LockedUntil int64 `json:"locked_until,omitempty"`
+}
+
+const (
+	// MaxFailures is the number of consecutive codes refused after which
+	// the codes of a user are refused for the Lockout, valid or not.
+	MaxFailures = 5
+	// Lockout is the time the codes are refused for after MaxFailures.
+	Lockout = 5 * time.Minute
+)
+
+// Locked tells whether the codes are refused at the given time after too
+// many failures.
+func (e *Enrollment) Locked(t time.Time) bool {
+	return t.Unix() < e.LockedUntil
+}
+
+// Check validates a code at the given time and records the outcome in the
+// enrollment, which has to be stored again.
+func (e *Enrollment) Check(code string, t time.Time) bool {
+	if e.Locked(t) {
+		return false
+	}
+	counter, ok := Validate(e.Secret, code, t, e.Counter)
+	if !ok {
+		e.Failures++
+		if e.Failures >= MaxFailures {
+			e.Failures, e.LockedUntil = 0, t.Add(Lockout).Unix()
+		}
+		return false
+	}
+	e.Counter, e.Failures, e.LockedUntil = counter, 0, 0
+	return true
+}
+
+// Manager stores the enrollments of the users.
+type Manager interface {
+	// GetEnrollment returns the enrollment of a user, errtypes.NotFound
+	// if the user didn't enroll.
+	GetEnrollment(ctx context.Context, u *userpb.UserId) (*Enrollment, error)
+	// SetEnrollment stores the enrollment of a user.
+	SetEnrollment(ctx context.Context, u *userpb.UserId, e *Enrollment) error
+	// UpdateEnrollment calls f with the enrollment of a user and stores it
+	// unless f fails, so that the outcome of a code is recorded before
+	// another one is checked. It returns errtypes.NotFound if the user
+	// didn't enroll.
+	UpdateEnrollment(ctx context.Context, u *userpb.UserId, f func(e *Enrollment) error) error
+	// DeleteEnrollment removes the enrollment of a user.
+	DeleteEnrollment(ctx context.Context, u *userpb.UserId) error
+}
@@ -0,0 +1,143 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package guest provides a user manager wrapping another user manager to
+// include the guest accounts in the users.
+package guest
+
+import (
+	"context"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/guest"
+	guestregistry "github.com/cs3org/reva/pkg/guest/manager/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+
+	// Load the guest manager drivers.
+	_ "github.com/cs3org/reva/pkg/guest/manager/loader"
+)
+
+func init() {
+	registry.Register("guest", New)
+}
+
+type config struct {
+	// Driver is the user manager providing the regular users.
+	Driver  string                            `mapstructure:"driver" docs:"json"`
+	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// GuestDriver is the guest manager providing the guest accounts.
+	GuestDriver  string                            `mapstructure:"guest_driver" docs:"json"`
+	GuestDrivers map[string]map[string]interface{} `mapstructure:"guest_drivers"`
+}
+
+func (c *config) init() {
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.GuestDriver == "" {
+		c.GuestDriver = "json"
+	}
+}
+
+type manager struct {
+	users  user.Manager
+	guests guest.Manager
+}
+
+// New returns a user manager looking the users up in the configured user
+// manager first and among the guest accounts afterwards.
+func New(m map[string]interface{}) (user.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "guest: error decoding conf")
+	}
+	c.init()
+	if c.Driver == "guest" {
+		return nil, errtypes.BadRequest("guest: the guest user manager can't wrap itself")
+	}
+
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("guest: user manager driver not found: " + c.Driver)
+	}
+	users, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+
+	gf, ok := guestregistry.NewFuncs[c.GuestDriver]
+	if !ok {
+		return nil, errtypes.NotFound("guest: guest manager driver not found: " + c.GuestDriver)
+	}
+	guests, err := gf(c.GuestDrivers[c.GuestDriver])
+	if err != nil {
+		return nil, err
+	}
+	return &manager{users: users, guests: guests}, nil
+}
+
+func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
+	if uid.GetType() == userpb.UserType_USER_TYPE_GUEST {
+		return m.guests.GetGuest(ctx, uid)
+	}
+	u, err := m.users.GetUser(ctx, uid)
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		if g, gerr := m.guests.GetGuest(ctx, uid); gerr == nil {
+			return g, nil
+		}
+	}
+	return u, err
+}
+
+func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
+	u, err := m.users.GetUserByClaim(ctx, claim, value)
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		if g, gerr := m.guests.GetGuestByClaim(ctx, claim, value); gerr == nil {
+			return g, nil
+		}
+	}
+	return u, err
+}
+
+func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+	if uid.GetType() == userpb.UserType_USER_TYPE_GUEST {
+		// guests are members of no group
+		return []string{}, nil
+	}
+	return m.users.GetUserGroups(ctx, uid)
+}
+
+func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User, error) {
+	users, err := m.users.FindUsers(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	// The guests are only found by their complete mail address, to not
+	// disclose them to everyone searching for users.
+	if guest.IsMail(query) {
+		if g, err := m.guests.GetGuestByClaim(ctx, "mail", strings.TrimSpace(query)); err == nil {
+			users = append(users, g)
+		}
+	}
+	return users, nil
+}
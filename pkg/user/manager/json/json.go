@@ -22,10 +22,14 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/golang/protobuf/proto"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 
@@ -38,7 +42,12 @@ func init() {
 }
 
 type manager struct {
+	file  string
+	mu    sync.RWMutex
 	users []*userpb.User
+	// mtime and size of the users file when it was last read
+	mtime time.Time
+	size  int64
 }
 
 type config struct {
@@ -69,24 +78,56 @@ func New(m map[string]interface{}) (user.Manager, error) {
 		return nil, err
 	}
 
-	f, err := ioutil.ReadFile(c.Users)
-	if err != nil {
+	mgr := &manager{file: c.Users}
+	if err := mgr.load(); err != nil {
 		return nil, err
 	}
+	return mgr, nil
+}
+
+// load reads the users file.
+func (m *manager) load() error {
+	info, err := os.Stat(m.file)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return err
+	}
 
 	users := []*userpb.User{}
+	if err := json.Unmarshal(f, &users); err != nil {
+		return err
+	}
+	m.users, m.mtime, m.size = users, info.ModTime(), info.Size()
+	return nil
+}
 
-	err = json.Unmarshal(f, &users)
+// refresh reloads the users file when it was changed by another process,
+// e.g. by the provisioning of new users in the auth providers.
+func (m *manager) refresh() {
+	info, err := os.Stat(m.file)
 	if err != nil {
-		return nil, err
+		return
+	}
+	m.mu.RLock()
+	changed := !info.ModTime().Equal(m.mtime) || info.Size() != m.size
+	m.mu.RUnlock()
+	if !changed {
+		return
 	}
 
-	return &manager{
-		users: users,
-	}, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// keep serving the users read last if the file is being replaced
+	_ = m.load()
 }
 
 func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
+	m.refresh()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for _, u := range m.users {
 		if (u.Id.GetOpaqueId() == uid.OpaqueId || u.Username == uid.OpaqueId) && (uid.Idp == "" || uid.Idp == u.Id.GetIdp()) {
 			return u, nil
@@ -96,6 +137,9 @@ func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User
 }
 
 func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
+	m.refresh()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	for _, u := range m.users {
 		if userClaim, err := extractClaim(u, claim); err == nil && value == userClaim {
 			return u, nil
@@ -130,6 +174,9 @@ func userContains(u *userpb.User, query string) bool {
 }
 
 func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User, error) {
+	m.refresh()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	users := []*userpb.User{}
 	for _, u := range m.users {
 		if userContains(u, query) {
@@ -146,3 +193,140 @@ func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]stri
 	}
 	return user.Groups, nil
 }
+
+// RenameUser changes the username of a user and writes the users file back.
+// Users whose opaque id is their username get the new username as opaque id.
+func (m *manager) RenameUser(ctx context.Context, uid *userpb.UserId, username string) (*userpb.User, error) {
+	m.refresh()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := -1
+	for j, u := range m.users {
+		if u.Username == username {
+			return nil, errtypes.AlreadyExists(username)
+		}
+		if u.Id.GetOpaqueId() == uid.OpaqueId && (uid.Idp == "" || uid.Idp == u.Id.GetIdp()) {
+			i = j
+		}
+	}
+	if i < 0 {
+		return nil, errtypes.NotFound(uid.OpaqueId)
+	}
+
+	old := m.users[i]
+	renamed := proto.Clone(old).(*userpb.User)
+	renamed.Username = username
+	if old.Id.GetOpaqueId() == old.Username {
+		renamed.Id = &userpb.UserId{Idp: old.Id.GetIdp(), OpaqueId: username}
+	}
+
+	users := make([]*userpb.User, len(m.users))
+	copy(users, m.users)
+	users[i] = renamed
+	if err := m.write(users); err != nil {
+		return nil, err
+	}
+	m.users = users
+	return renamed, nil
+}
+
+// CreateUser adds a user and writes the users file back.
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	if u.Id.GetOpaqueId() == "" || u.Username == "" {
+		return nil, errtypes.BadRequest("json: user needs an id and a username")
+	}
+	m.refresh()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.users {
+		if e.Username == u.Username {
+			return nil, errtypes.AlreadyExists(u.Username)
+		}
+		if e.Id.GetOpaqueId() == u.Id.OpaqueId && e.Id.GetIdp() == u.Id.Idp {
+			return nil, errtypes.AlreadyExists(u.Id.OpaqueId)
+		}
+	}
+
+	users := make([]*userpb.User, len(m.users), len(m.users)+1)
+	copy(users, m.users)
+	users = append(users, u)
+	if err := m.write(users); err != nil {
+		return nil, err
+	}
+	m.users = users
+	return u, nil
+}
+
+// UpdateUser replaces the attributes of a user and writes the users file back.
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	m.refresh()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := -1
+	for j, e := range m.users {
+		if e.Id.GetOpaqueId() == u.Id.GetOpaqueId() && (u.Id.GetIdp() == "" || u.Id.GetIdp() == e.Id.GetIdp()) {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return nil, errtypes.NotFound(u.Id.GetOpaqueId())
+	}
+
+	updated := proto.Clone(u).(*userpb.User)
+	updated.Id = m.users[i].Id
+	updated.Username = m.users[i].Username
+
+	users := make([]*userpb.User, len(m.users))
+	copy(users, m.users)
+	users[i] = updated
+	if err := m.write(users); err != nil {
+		return nil, err
+	}
+	m.users = users
+	return updated, nil
+}
+
+// DeleteUser removes a user and writes the users file back.
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	m.refresh()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make([]*userpb.User, 0, len(m.users))
+	for _, u := range m.users {
+		if u.Id.GetOpaqueId() != uid.OpaqueId || (uid.Idp != "" && uid.Idp != u.Id.GetIdp()) {
+			users = append(users, u)
+		}
+	}
+	if len(users) == len(m.users) {
+		return errtypes.NotFound(uid.OpaqueId)
+	}
+	if err := m.write(users); err != nil {
+		return err
+	}
+	m.users = users
+	return nil
+}
+
+// write replaces the users file, readers never see a partially written file.
+func (m *manager) write(users []*userpb.User) error {
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "json: error encoding users")
+	}
+	tmp := m.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "json: error writing users")
+	}
+	if err := os.Rename(tmp, m.file); err != nil {
+		return errors.Wrap(err, "json: error writing users")
+	}
+	if info, err := os.Stat(m.file); err == nil {
+		m.mtime, m.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
@@ -27,6 +27,7 @@ import (
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/user"
 )
 
 var ctx = context.Background()
@@ -135,3 +136,118 @@ func TestUserManager(t *testing.T) {
 		t.Fatalf("user differ: expected=%v got=%v", "einstein", resUser[0].Username)
 	}
 }
+
+func TestRenameUser(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "json_test")
+	if err != nil {
+		t.Fatalf("error while create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	userJSON := `[{"id":{"idp":"localhost","opaque_id":"einstein"},"username":"einstein"},{"id":{"idp":"localhost","opaque_id":"4c510ada-c86b-4815-8820-42cdf82c3d51"},"username":"marie"}]`
+	file := tempdir + "/users.json"
+	if err := ioutil.WriteFile(file, []byte(userJSON), 0600); err != nil {
+		t.Fatalf("error while writing temp file: %v", err)
+	}
+	manager, err := New(map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatalf("error while get manager: %v", err)
+	}
+	renamer := manager.(user.Renamer)
+
+	// the username is the opaque id, the id changes as well
+	renamed, err := renamer.RenameUser(ctx, &userpb.UserId{Idp: "localhost", OpaqueId: "einstein"}, "albert")
+	if err != nil {
+		t.Fatalf("error renaming user: %v", err)
+	}
+	if renamed.Username != "albert" || renamed.Id.OpaqueId != "albert" {
+		t.Fatalf("user not renamed: %v", renamed)
+	}
+
+	// the opaque id is independent of the username
+	renamed, err = renamer.RenameUser(ctx, &userpb.UserId{Idp: "localhost", OpaqueId: "4c510ada-c86b-4815-8820-42cdf82c3d51"}, "marie.curie")
+	if err != nil {
+		t.Fatalf("error renaming user: %v", err)
+	}
+	if renamed.Username != "marie.curie" || renamed.Id.OpaqueId != "4c510ada-c86b-4815-8820-42cdf82c3d51" {
+		t.Fatalf("user not renamed: %v", renamed)
+	}
+
+	// negative test for a taken username
+	_, err = renamer.RenameUser(ctx, renamed.Id, "albert")
+	if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got: %v", err)
+	}
+
+	// the renames are persisted
+	manager, err = New(map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatalf("error while get manager: %v", err)
+	}
+	if _, err := manager.GetUserByClaim(ctx, "username", "albert"); err != nil {
+		t.Fatalf("renamed user not found: %v", err)
+	}
+	if _, err := manager.GetUserByClaim(ctx, "username", "einstein"); err == nil {
+		t.Fatalf("old username still found")
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "json_test")
+	if err != nil {
+		t.Fatalf("error while create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	file := tempdir + "/users.json"
+	if err := ioutil.WriteFile(file, []byte(`[{"id":{"idp":"localhost","opaque_id":"einstein"},"username":"einstein"}]`), 0600); err != nil {
+		t.Fatalf("error while writing temp file: %v", err)
+	}
+	manager, err := New(map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatalf("error while get manager: %v", err)
+	}
+	// a second manager sharing the file, like a user provider in another process
+	reader, err := New(map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatalf("error while get manager: %v", err)
+	}
+	creator := manager.(user.Creator)
+
+	marie := &userpb.User{Id: &userpb.UserId{Idp: "localhost", OpaqueId: "marie"}, Username: "marie"}
+	if _, err := creator.CreateUser(ctx, marie); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	if _, err := reader.GetUser(ctx, marie.Id); err != nil {
+		t.Fatalf("created user not found by other manager: %v", err)
+	}
+
+	// negative test for a taken username
+	_, err = creator.CreateUser(ctx, &userpb.User{Id: &userpb.UserId{Idp: "localhost", OpaqueId: "other"}, Username: "einstein"})
+	if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got: %v", err)
+	}
+
+	update := &userpb.User{Id: marie.Id, Username: "ignored", DisplayName: "Marie Curie"}
+	user.SetDisabled(update, true)
+	updated, err := manager.(user.Updater).UpdateUser(ctx, update)
+	if err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	if updated.Username != "marie" || updated.DisplayName != "Marie Curie" {
+		t.Fatalf("unexpected updated user: %v", updated)
+	}
+	if u, err := reader.GetUser(ctx, marie.Id); err != nil || !user.IsDisabled(u) {
+		t.Fatalf("updated user not found by other manager: %v, %v", u, err)
+	}
+
+	if err := creator.DeleteUser(ctx, marie.Id); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	if _, err := manager.GetUser(ctx, marie.Id); err == nil {
+		t.Fatalf("deleted user still found")
+	}
+	if err := creator.DeleteUser(ctx, marie.Id); err == nil {
+		t.Fatalf("expected not found error deleting user twice")
+	}
+}
@@ -21,7 +21,6 @@ package ldap
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"fmt"
 	"strings"
 	"text/template"
@@ -31,8 +30,10 @@ import (
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/health"
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/user/manager/registry"
+	ldaputils "github.com/cs3org/reva/pkg/utils/ldap"
 	"github.com/go-ldap/ldap/v3"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -43,23 +44,32 @@ func init() {
 }
 
 type manager struct {
-	c           *config
-	userfilter  *template.Template
-	groupfilter *template.Template
+	c            *config
+	pool         *ldaputils.Pool
+	userfilter   *template.Template
+	groupfilter  *template.Template
+	parentfilter *template.Template
 }
 
 type config struct {
-	Hostname        string     `mapstructure:"hostname"`
-	Port            int        `mapstructure:"port"`
-	BaseDN          string     `mapstructure:"base_dn"`
-	UserFilter      string     `mapstructure:"userfilter"`
-	AttributeFilter string     `mapstructure:"attributefilter"`
-	FindFilter      string     `mapstructure:"findfilter"`
-	GroupFilter     string     `mapstructure:"groupfilter"`
-	BindUsername    string     `mapstructure:"bind_username"`
-	BindPassword    string     `mapstructure:"bind_password"`
-	Idp             string     `mapstructure:"idp"`
-	Schema          attributes `mapstructure:"schema"`
+	LDAP            ldaputils.Config `mapstructure:",squash"`
+	BaseDN          string           `mapstructure:"base_dn"`
+	UserFilter      string           `mapstructure:"userfilter"`
+	AttributeFilter string           `mapstructure:"attributefilter"`
+	FindFilter      string           `mapstructure:"findfilter"`
+	GroupFilter     string           `mapstructure:"groupfilter"`
+	Idp             string           `mapstructure:"idp"`
+	Schema          attributes       `mapstructure:"schema"`
+	// Opaque maps additional attributes of the users to their opaque
+	// entries, see ldaputils.OpaqueMapping.
+	Opaque ldaputils.OpaqueMapping `mapstructure:"opaque"`
+	// ParentFilter finds the groups a group is a direct member of, with %s
+	// standing for the name of the group, to add the groups the groups of a
+	// user are nested in to the ones of the user. Nested groups are not
+	// resolved if it is empty.
+	ParentFilter string `mapstructure:"parentfilter"`
+	// NestedGroupsDepth limits the levels of nested groups resolved.
+	NestedGroupsDepth int `mapstructure:"nested_groups_depth"`
 }
 
 type attributes struct {
@@ -92,12 +102,17 @@ var ldapDefaults = attributes{
 
 func parseConfig(m map[string]interface{}) (*config, error) {
 	c := config{
+		// the certificates of the servers were never verified
+		LDAP:   ldaputils.Config{Insecure: true},
 		Schema: ldapDefaults,
 	}
 	if err := mapstructure.Decode(m, &c); err != nil {
 		err = errors.Wrap(err, "error decoding conf")
 		return nil, err
 	}
+	if c.NestedGroupsDepth == 0 {
+		c.NestedGroupsDepth = 10
+	}
 
 	return &c, nil
 }
@@ -115,10 +130,13 @@ func New(m map[string]interface{}) (user.Manager, error) {
 		c.FindFilter = c.UserFilter
 	}
 	c.GroupFilter = strings.ReplaceAll(c.GroupFilter, "%s", "{{.OpaqueId}}")
+	c.ParentFilter = strings.ReplaceAll(c.ParentFilter, "%s", "{{.Name}}")
 
 	mgr := &manager{
-		c: c,
+		c:    c,
+		pool: ldaputils.NewPool(&c.LDAP),
 	}
+	health.Register("user/ldap", mgr.pool.Check)
 
 	mgr.userfilter, err = template.New("uf").Funcs(sprig.TxtFuncMap()).Parse(c.UserFilter)
 	if err != nil {
@@ -130,73 +148,84 @@ func New(m map[string]interface{}) (user.Manager, error) {
 		err := errors.Wrap(err, fmt.Sprintf("error parsing groupfilter tpl:%s", c.GroupFilter))
 		panic(err)
 	}
+	mgr.parentfilter, err = template.New("pf").Funcs(sprig.TxtFuncMap()).Parse(c.ParentFilter)
+	if err != nil {
+		err := errors.Wrap(err, fmt.Sprintf("error parsing parentfilter tpl:%s", c.ParentFilter))
+		panic(err)
+	}
 
 	return mgr, nil
 }
 
-func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
-	log := appctx.GetLogger(ctx)
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return nil, err
-	}
+func (m *manager) userAttributes() []string {
+	attrs := []string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber}
+	return append(attrs, m.c.Opaque.Attributes()...)
+}
 
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
+func (m *manager) search(filter string, attributes []string) (*ldap.SearchResult, error) {
+	return m.pool.Search(ldap.NewSearchRequest(
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getUserFilter(uid),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
+		filter,
+		attributes,
 		nil,
-	)
+	))
+}
 
-	sr, err := l.Search(searchRequest)
+// getUser returns the single user matched by the filter.
+func (m *manager) getUser(ctx context.Context, filter, notFound string) (*userpb.User, error) {
+	log := appctx.GetLogger(ctx)
+	sr, err := m.search(filter, m.userAttributes())
 	if err != nil {
 		return nil, err
 	}
 
 	if len(sr.Entries) != 1 {
-		return nil, errtypes.NotFound(uid.OpaqueId)
+		return nil, errtypes.NotFound(notFound)
 	}
 
 	log.Debug().Interface("entries", sr.Entries).Msg("entries")
 
+	return m.userFromEntry(ctx, sr.Entries[0])
+}
+
+func (m *manager) userFromEntry(ctx context.Context, entry *ldap.Entry) (*userpb.User, error) {
 	id := &userpb.UserId{
 		Idp:      m.c.Idp,
-		OpaqueId: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UID),
+		OpaqueId: entry.GetEqualFoldAttributeValue(m.c.Schema.UID),
 	}
 	groups, err := m.GetUserGroups(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	u := &userpb.User{
-		Id:          id,
-		Username:    sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.CN),
-		Groups:      groups,
-		Mail:        sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.Mail),
-		DisplayName: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-		Opaque: &types.Opaque{
-			Map: map[string]*types.OpaqueEntry{
-				"uid": {
-					Decoder: "plain",
-					Value:   []byte(sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)),
-				},
-				"gid": {
-					Decoder: "plain",
-					Value:   []byte(sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)),
-				},
+	opaque, err := m.c.Opaque.Apply(entry, &types.Opaque{
+		Map: map[string]*types.OpaqueEntry{
+			"uid": {
+				Decoder: "plain",
+				Value:   []byte(entry.GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)),
+			},
+			"gid": {
+				Decoder: "plain",
+				Value:   []byte(entry.GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)),
 			},
 		},
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return u, nil
+	return &userpb.User{
+		Id:          id,
+		Username:    entry.GetEqualFoldAttributeValue(m.c.Schema.CN),
+		Groups:      groups,
+		Mail:        entry.GetEqualFoldAttributeValue(m.c.Schema.Mail),
+		DisplayName: entry.GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
+		Opaque:      opaque,
+	}, nil
+}
+
+func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
+	return m.getUser(ctx, m.getUserFilter(uid), uid.OpaqueId)
 }
 
 func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
@@ -216,94 +245,11 @@ func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*use
 		return nil, errors.New("ldap: invalid field " + claim)
 	}
 
-	log := appctx.GetLogger(ctx)
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return nil, err
-	}
-
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
-		m.c.BaseDN,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getAttributeFilter(claim, value),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(sr.Entries) != 1 {
-		return nil, errtypes.NotFound(claim + ":" + value)
-	}
-
-	log.Debug().Interface("entries", sr.Entries).Msg("entries")
-
-	id := &userpb.UserId{
-		Idp:      m.c.Idp,
-		OpaqueId: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UID),
-	}
-	groups, err := m.GetUserGroups(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	u := &userpb.User{
-		Id:          id,
-		Username:    sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.CN),
-		Groups:      groups,
-		Mail:        sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.Mail),
-		DisplayName: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-		Opaque: &types.Opaque{
-			Map: map[string]*types.OpaqueEntry{
-				"uid": {
-					Decoder: "plain",
-					Value:   []byte(sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)),
-				},
-				"gid": {
-					Decoder: "plain",
-					Value:   []byte(sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)),
-				},
-			},
-		},
-	}
-
-	return u, nil
-
+	return m.getUser(ctx, m.getAttributeFilter(claim, value), claim+":"+value)
 }
 
 func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User, error) {
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return nil, err
-	}
-
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
-		m.c.BaseDN,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getFindFilter(query),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
+	sr, err := m.search(m.getFindFilter(query), m.userAttributes())
 	if err != nil {
 		return nil, err
 	}
@@ -311,33 +257,10 @@ func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User,
 	users := []*userpb.User{}
 
 	for _, entry := range sr.Entries {
-		id := &userpb.UserId{
-			Idp:      m.c.Idp,
-			OpaqueId: entry.GetEqualFoldAttributeValue(m.c.Schema.UID),
-		}
-		groups, err := m.GetUserGroups(ctx, id)
+		user, err := m.userFromEntry(ctx, entry)
 		if err != nil {
 			return nil, err
 		}
-		user := &userpb.User{
-			Id:          id,
-			Username:    entry.GetEqualFoldAttributeValue(m.c.Schema.CN),
-			Groups:      groups,
-			Mail:        entry.GetEqualFoldAttributeValue(m.c.Schema.Mail),
-			DisplayName: entry.GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-			Opaque: &types.Opaque{
-				Map: map[string]*types.OpaqueEntry{
-					"uid": {
-						Decoder: "plain",
-						Value:   []byte(entry.GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)),
-					},
-					"gid": {
-						Decoder: "plain",
-						Value:   []byte(entry.GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)),
-					},
-				},
-			},
-		}
 		users = append(users, user)
 	}
 
@@ -345,28 +268,7 @@ func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User,
 }
 
 func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
-	l, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", m.c.Hostname, m.c.Port), &tls.Config{InsecureSkipVerify: true})
-	if err != nil {
-		return []string{}, err
-	}
-	defer l.Close()
-
-	// First bind with a read only user
-	err = l.Bind(m.c.BindUsername, m.c.BindPassword)
-	if err != nil {
-		return []string{}, err
-	}
-
-	// Search for the given clientID
-	searchRequest := ldap.NewSearchRequest(
-		m.c.BaseDN,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		m.getGroupFilter(uid),
-		[]string{m.c.Schema.CN}, // TODO use DN to look up group id
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
+	sr, err := m.search(m.getGroupFilter(uid), []string{m.c.Schema.CN}) // TODO use DN to look up group id
 	if err != nil {
 		return []string{}, err
 	}
@@ -380,6 +282,39 @@ func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]stri
 		groups = append(groups, entry.GetEqualFoldAttributeValue(m.c.Schema.CN))
 	}
 
+	if m.c.ParentFilter == "" {
+		return groups, nil
+	}
+	return m.resolveNestedGroups(groups)
+}
+
+// resolveNestedGroups adds the groups the groups are nested in, level by
+// level, to the given groups.
+func (m *manager) resolveNestedGroups(groups []string) ([]string, error) {
+	seen := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		seen[g] = true
+	}
+
+	level := groups
+	for depth := 0; depth < m.c.NestedGroupsDepth && len(level) > 0; depth++ {
+		next := []string{}
+		for _, g := range level {
+			sr, err := m.search(m.getParentFilter(g), []string{m.c.Schema.CN})
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range sr.Entries {
+				parent := entry.GetEqualFoldAttributeValue(m.c.Schema.CN)
+				if !seen[parent] {
+					seen[parent] = true
+					next = append(next, parent)
+				}
+			}
+		}
+		groups = append(groups, next...)
+		level = next
+	}
 	return groups, nil
 }
 
@@ -409,3 +344,12 @@ func (m *manager) getGroupFilter(uid *userpb.UserId) string {
 	}
 	return b.String()
 }
+
+func (m *manager) getParentFilter(name string) string {
+	b := bytes.Buffer{}
+	if err := m.parentfilter.Execute(&b, struct{ Name string }{Name: ldap.EscapeFilter(name)}); err != nil {
+		err := errors.Wrap(err, fmt.Sprintf("error executing parent template: group: %s", name))
+		panic(err)
+	}
+	return b.String()
+}
@@ -21,7 +21,10 @@ package loader
 import (
 	// Load core user manager drivers.
 	_ "github.com/cs3org/reva/pkg/user/manager/demo"
+	_ "github.com/cs3org/reva/pkg/user/manager/guest"
 	_ "github.com/cs3org/reva/pkg/user/manager/json"
 	_ "github.com/cs3org/reva/pkg/user/manager/ldap"
+	_ "github.com/cs3org/reva/pkg/user/manager/plugin"
+	_ "github.com/cs3org/reva/pkg/user/manager/sql"
 	// Add your own here
 )
@@ -0,0 +1,210 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package plugin provides a user manager served by an external binary. The
+// binary creates its user manager with the configuration of the driver and
+// serves it with Serve:
+//
+//	func main() {
+//		plugin.Serve(mymanager.New)
+//	}
+package plugin
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	revaplugin "github.com/cs3org/reva/pkg/plugin"
+	pluginpb "github.com/cs3org/reva/pkg/plugin/proto"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/manager/registry"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const kind = "userprovider"
+
+func init() {
+	registry.Register("plugin", New)
+}
+
+type config struct {
+	// Path is the binary of the plugin.
+	Path string `mapstructure:"path"`
+	// Config is the configuration the plugin creates its user manager with.
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// New starts the plugin and returns the user manager it serves.
+func New(m map[string]interface{}) (user.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+
+	p, err := revaplugin.Load(kind, c.Path, &Plugin{})
+	if err != nil {
+		return nil, err
+	}
+	mgr := p.Raw.(*grpcClient)
+	if err := mgr.configure(c.Config); err != nil {
+		p.Kill()
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// Serve serves the user manager created by the given function from a plugin
+// binary.
+func Serve(f registry.NewFunc) {
+	revaplugin.Serve(kind, &Plugin{New: f})
+}
+
+// Plugin is the go-plugin of the user managers.
+type Plugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	// New creates the user manager on the plugin side.
+	New registry.NewFunc
+}
+
+// GRPCServer registers the server of the plugin binary.
+func (p *Plugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	pluginpb.RegisterUserProviderPluginServer(s, &grpcServer{new: p.New})
+	return nil
+}
+
+// GRPCClient returns the user manager calling the plugin binary.
+func (p *Plugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{client: pluginpb.NewUserProviderPluginClient(c)}, nil
+}
+
+type grpcClient struct {
+	client pluginpb.UserProviderPluginClient
+}
+
+func (c *grpcClient) configure(m map[string]interface{}) error {
+	config, err := revaplugin.EncodeConfig(m)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Configure(context.Background(), &pluginpb.ConfigureRequest{Config: config})
+	return err
+}
+
+func (c *grpcClient) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
+	res, err := c.client.GetUser(ctx, &pluginpb.GetUserRequest{UserId: uid})
+	if err != nil {
+		return nil, err
+	}
+	return res.User, nil
+}
+
+func (c *grpcClient) GetUserByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
+	res, err := c.client.GetUserByClaim(ctx, &pluginpb.GetUserByClaimRequest{Claim: claim, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	return res.User, nil
+}
+
+func (c *grpcClient) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+	res, err := c.client.GetUserGroups(ctx, &pluginpb.GetUserGroupsRequest{UserId: uid})
+	if err != nil {
+		return nil, err
+	}
+	return res.Groups, nil
+}
+
+func (c *grpcClient) FindUsers(ctx context.Context, query string) ([]*userpb.User, error) {
+	res, err := c.client.FindUsers(ctx, &pluginpb.FindUsersRequest{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	return res.Users, nil
+}
+
+type grpcServer struct {
+	new registry.NewFunc
+	mgr user.Manager
+}
+
+func (s *grpcServer) manager() (user.Manager, error) {
+	if s.mgr == nil {
+		return nil, errors.New("plugin: user manager not configured")
+	}
+	return s.mgr, nil
+}
+
+func (s *grpcServer) Configure(_ context.Context, req *pluginpb.ConfigureRequest) (*pluginpb.ConfigureResponse, error) {
+	mgr, err := s.new(revaplugin.DecodeConfig(req.Config))
+	if err != nil {
+		return nil, err
+	}
+	s.mgr = mgr
+	return &pluginpb.ConfigureResponse{}, nil
+}
+
+func (s *grpcServer) GetUser(ctx context.Context, req *pluginpb.GetUserRequest) (*pluginpb.GetUserResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	u, err := mgr.GetUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetUserResponse{User: u}, nil
+}
+
+func (s *grpcServer) GetUserByClaim(ctx context.Context, req *pluginpb.GetUserByClaimRequest) (*pluginpb.GetUserByClaimResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	u, err := mgr.GetUserByClaim(ctx, req.Claim, req.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetUserByClaimResponse{User: u}, nil
+}
+
+func (s *grpcServer) GetUserGroups(ctx context.Context, req *pluginpb.GetUserGroupsRequest) (*pluginpb.GetUserGroupsResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := mgr.GetUserGroups(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.GetUserGroupsResponse{Groups: groups}, nil
+}
+
+func (s *grpcServer) FindUsers(ctx context.Context, req *pluginpb.FindUsersRequest) (*pluginpb.FindUsersResponse, error) {
+	mgr, err := s.manager()
+	if err != nil {
+		return nil, err
+	}
+	users, err := mgr.FindUsers(ctx, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.FindUsersResponse{Users: users}, nil
+}
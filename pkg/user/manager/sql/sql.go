@@ -0,0 +1,246 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a user manager reading the users from a SQL
+// database, MySQL, PostgreSQL or SQLite, shared with the sql group manager.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/sqlidentity"
+	"github.com/cs3org/reva/pkg/sqlmigrate"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// columns are the columns a user is read from, in the order of scanUser.
+const columns = "idp, user_id, username, mail, display_name, uid_number, gid_number, disabled"
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	sqlidentity.Config `mapstructure:",squash"`
+	// SearchLimit is the maximum number of users returned by a search.
+	SearchLimit int `mapstructure:"search_limit"`
+}
+
+func (c *config) init() {
+	if c.SearchLimit == 0 {
+		c.SearchLimit = 100
+	}
+}
+
+type manager struct {
+	c       *config
+	dialect sqlmigrate.Dialect
+	db      *sql.DB
+}
+
+// New returns a user manager reading the users from a SQL database.
+func New(m map[string]interface{}) (user.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding conf")
+	}
+	c.init()
+
+	db, dialect, err := sqlidentity.Open(&c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &manager{c: c, dialect: dialect, db: db}, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(s scanner) (*userpb.User, error) {
+	u := &userpb.User{Id: &userpb.UserId{}}
+	var off bool
+	if err := s.Scan(&u.Id.Idp, &u.Id.OpaqueId, &u.Username, &u.Mail, &u.DisplayName, &u.UidNumber, &u.GidNumber, &off); err != nil {
+		return nil, err
+	}
+	user.SetDisabled(u, off)
+	return u, nil
+}
+
+// disabled returns the value of the disabled column of the user, stored as
+// a number as not all the databases have booleans.
+func disabled(u *userpb.User) int {
+	if user.IsDisabled(u) {
+		return 1
+	}
+	return 0
+}
+
+// getUser returns the user matched by the condition, with the groups it
+// is a member of.
+func (m *manager) getUser(ctx context.Context, cond, notFound string, args ...interface{}) (*userpb.User, error) {
+	query := m.dialect.Rebind("SELECT " + columns + " FROM users WHERE " + cond)
+	u, err := scanUser(m.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, errtypes.NotFound(notFound)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error reading user")
+	}
+
+	if u.Groups, err = m.getGroups(ctx, u.Id); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
+	if uid.Idp == "" {
+		return m.getUser(ctx, "user_id = ? OR username = ?", uid.OpaqueId, uid.OpaqueId, uid.OpaqueId)
+	}
+	return m.getUser(ctx, "idp = ? AND (user_id = ? OR username = ?)", uid.OpaqueId, uid.Idp, uid.OpaqueId, uid.OpaqueId)
+}
+
+func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
+	switch claim {
+	case "mail", "username":
+		return m.getUser(ctx, claim+" = ?", value, value)
+	case "userid":
+		return m.getUser(ctx, "user_id = ?", value, value)
+	case "uid", "gid":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errtypes.BadRequest("sql: invalid " + claim + " " + value)
+		}
+		return m.getUser(ctx, claim+"_number = ?", value, n)
+	}
+	return nil, errtypes.NotSupported("sql: invalid field " + claim)
+}
+
+func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User, error) {
+	cond, args := sqlidentity.Contains(query, "username", "mail", "display_name", "user_id")
+	q := m.dialect.Rebind("SELECT " + columns + " FROM users WHERE " + cond + " ORDER BY username LIMIT " + strconv.Itoa(m.c.SearchLimit))
+	rows, err := m.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error searching users")
+	}
+	defer rows.Close()
+
+	users := []*userpb.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "sql: error reading user")
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+	u, err := m.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return u.Groups, nil
+}
+
+// getGroups returns the names of the groups the user is a member of.
+func (m *manager) getGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+	query := m.dialect.Rebind(`SELECT g.group_name FROM group_members m
+		JOIN user_groups g ON g.idp = m.group_idp AND g.group_id = m.group_id
+		WHERE m.user_id = ? AND m.user_idp = ? ORDER BY g.group_name`)
+	rows, err := m.db.QueryContext(ctx, query, uid.OpaqueId, uid.Idp)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error reading the groups of the user")
+	}
+	defer rows.Close()
+
+	groups := []string{}
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			return nil, errors.Wrap(err, "sql: error reading the groups of the user")
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// CreateUser stores a new user, the groups it is a member of are ignored.
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	if u.Id.GetOpaqueId() == "" || u.Username == "" {
+		return nil, errtypes.BadRequest("sql: user needs an id and a username")
+	}
+	if _, err := m.GetUserByClaim(ctx, "username", u.Username); err == nil {
+		return nil, errtypes.AlreadyExists(u.Username)
+	}
+	if _, err := m.getUser(ctx, "idp = ? AND user_id = ?", u.Id.OpaqueId, u.Id.Idp, u.Id.OpaqueId); err == nil {
+		return nil, errtypes.AlreadyExists(u.Id.OpaqueId)
+	}
+
+	query := m.dialect.Rebind("INSERT INTO users (" + columns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if _, err := m.db.ExecContext(ctx, query, u.Id.Idp, u.Id.OpaqueId, u.Username, u.Mail, u.DisplayName, u.UidNumber, u.GidNumber, disabled(u)); err != nil {
+		return nil, errors.Wrap(err, "sql: error storing user")
+	}
+	return m.GetUser(ctx, u.Id)
+}
+
+// UpdateUser replaces the attributes of a user, its id and username are kept.
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	e, err := m.GetUser(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := m.dialect.Rebind("UPDATE users SET mail = ?, display_name = ?, uid_number = ?, gid_number = ?, disabled = ? WHERE idp = ? AND user_id = ?")
+	if _, err := m.db.ExecContext(ctx, query, u.Mail, u.DisplayName, u.UidNumber, u.GidNumber, disabled(u), e.Id.Idp, e.Id.OpaqueId); err != nil {
+		return nil, errors.Wrap(err, "sql: error updating user")
+	}
+	return m.GetUser(ctx, e.Id)
+}
+
+// DeleteUser removes a user and its group memberships.
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	u, err := m.GetUser(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error deleting user")
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := tx.ExecContext(ctx, m.dialect.Rebind("DELETE FROM group_members WHERE user_idp = ? AND user_id = ?"), u.Id.Idp, u.Id.OpaqueId); err != nil {
+		return errors.Wrap(err, "sql: error deleting the memberships of the user")
+	}
+	if _, err := tx.ExecContext(ctx, m.dialect.Rebind("DELETE FROM users WHERE idp = ? AND user_id = ?"), u.Id.Idp, u.Id.OpaqueId); err != nil {
+		return errors.Wrap(err, "sql: error deleting user")
+	}
+	return errors.Wrap(tx.Commit(), "sql: error deleting user")
+}
@@ -0,0 +1,135 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/user"
+)
+
+func TestUsers(t *testing.T) {
+	mgr, err := New(map[string]interface{}{
+		"db_driver": "sqlite3",
+		"db_dsn":    filepath.Join(t.TempDir(), "identity.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	ctx := context.Background()
+
+	users := []*userpb.User{
+		{Id: &userpb.UserId{Idp: "idp", OpaqueId: "4c510ada"}, Username: "einstein", Mail: "einstein@example.org", DisplayName: "Albert Einstein", UidNumber: 123, GidNumber: 987},
+		{Id: &userpb.UserId{Idp: "idp", OpaqueId: "f7fbf8c8"}, Username: "marie", Mail: "marie@example.org", DisplayName: "Marie Curie", UidNumber: 124, GidNumber: 987},
+		{Id: &userpb.UserId{Idp: "idp", OpaqueId: "932b4540"}, Username: "richard", Mail: "richard_100%@example.org", DisplayName: "Richard Feynman", UidNumber: 125, GidNumber: 987},
+	}
+	for _, u := range users {
+		if _, err := m.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.CreateUser(ctx, users[0]); err == nil {
+		t.Fatal("a user must not be created twice")
+	}
+	for _, s := range []string{
+		"INSERT INTO user_groups (idp, group_id, group_name, mail, display_name, gid_number) VALUES ('idp', 'g1', 'physics-lovers', '', 'Physics lovers', 101)",
+		"INSERT INTO user_groups (idp, group_id, group_name, mail, display_name, gid_number) VALUES ('idp', 'g2', 'sailing-lovers', '', 'Sailing lovers', 102)",
+		"INSERT INTO group_members (group_idp, group_id, user_idp, user_id) VALUES ('idp', 'g1', 'idp', '4c510ada'), ('idp', 'g2', 'idp', '4c510ada')",
+	} {
+		if _, err := m.db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := m.GetUser(ctx, &userpb.UserId{OpaqueId: "einstein"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Id.OpaqueId != "4c510ada" || u.DisplayName != "Albert Einstein" || len(u.Groups) != 2 || u.Groups[0] != "physics-lovers" {
+		t.Fatalf("unexpected user %v", u)
+	}
+	if _, err := m.GetUser(ctx, &userpb.UserId{Idp: "other", OpaqueId: "4c510ada"}); err == nil {
+		t.Fatal("the users of another idp must not be found")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for claim, value := range map[string]string{"mail": "marie@example.org", "username": "marie", "userid": "f7fbf8c8", "uid": "124"} {
+		u, err := m.GetUserByClaim(ctx, claim, value)
+		if err != nil {
+			t.Fatalf("claim %s: %v", claim, err)
+		}
+		if u.Username != "marie" {
+			t.Fatalf("claim %s: unexpected user %v", claim, u)
+		}
+	}
+	if _, err := m.GetUserByClaim(ctx, "shoe_size", "42"); err == nil {
+		t.Fatal("unknown claims must be refused")
+	}
+
+	for query, expected := range map[string]int{"lovers": 0, "CURIE": 1, "example.org": 3, "100%": 1, "_100": 1, "%": 1, "e": 3} {
+		found, err := m.FindUsers(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != expected {
+			t.Errorf("query %q: expected %d users, got %d", query, expected, len(found))
+		}
+	}
+
+	groups, err := m.GetUserGroups(ctx, users[1].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+
+	update := &userpb.User{Id: &userpb.UserId{OpaqueId: "f7fbf8c8"}, Username: "ignored", Mail: "curie@example.org", DisplayName: "Marie Curie", UidNumber: 124, GidNumber: 988}
+	user.SetDisabled(update, true)
+	u, err = m.UpdateUser(ctx, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "marie" || u.Mail != "curie@example.org" || u.GidNumber != 988 || !user.IsDisabled(u) {
+		t.Fatalf("unexpected updated user %v", u)
+	}
+	if _, err := m.UpdateUser(ctx, &userpb.User{Id: &userpb.UserId{OpaqueId: "unknown"}}); err == nil {
+		t.Fatal("unknown users must not be updated")
+	}
+
+	if err := m.DeleteUser(ctx, users[0].Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetUser(ctx, users[0].Id); err == nil {
+		t.Fatal("the user must be deleted")
+	}
+	var n int
+	if err := m.db.QueryRow("SELECT COUNT(*) FROM group_members").Scan(&n); err != nil || n != 0 {
+		t.Fatalf("the memberships of the user must be deleted: %d, %v", n, err)
+	}
+
+	var _ user.Creator = m
+	var _ user.Updater = m
+}
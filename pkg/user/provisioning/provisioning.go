@@ -0,0 +1,553 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package provisioning creates the users authenticated by an identity
+// provider in the local user manager on their first login, together with
+// their home and default storage spaces. It also creates, updates, disables
+// and deletes the users on behalf of the admins.
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/events"
+	eventsregistry "github.com/cs3org/reva/pkg/events/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/token"
+	tokenregistry "github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// uidProbes is how many uids following the derived one are tried when it is taken.
+const uidProbes = 100
+
+// Config holds the configuration of the provisioning.
+type Config struct {
+	Enabled             bool                              `mapstructure:"enabled" docs:"false;Whether to provision the users on their first login."`
+	Driver              string                            `mapstructure:"driver" docs:"json;The user manager the provisioned users are stored in, it has to support the creation of users."`
+	Drivers             map[string]map[string]interface{} `mapstructure:"drivers" docs:"url:pkg/user/manager/json/json.go;The configuration for the user managers."`
+	UsernameTemplate    string                            `mapstructure:"username_template" docs:"{{.preferred_username}};The template of the username, executed with the claims of the user."`
+	DisplayNameTemplate string                            `mapstructure:"display_name_template" docs:"{{.name}};The template of the display name, executed with the claims of the user."`
+	MailTemplate        string                            `mapstructure:"mail_template" docs:"{{.email}};The template of the mail address, executed with the claims of the user."`
+	GroupsClaim         string                            `mapstructure:"groups_claim" docs:"groups;The claim containing the groups of the user."`
+	UIDClaim            string                            `mapstructure:"uid_claim" docs:";The claim containing the UID of the user, when empty or missing the UID is derived from the id of the user."`
+	GIDClaim            string                            `mapstructure:"gid_claim" docs:";The claim containing the GID of the user, when empty or missing the GID is used."`
+	UIDMin              int64                             `mapstructure:"uid_min" docs:"100000;The lowest UID derived for the provisioned users."`
+	UIDMax              int64                             `mapstructure:"uid_max" docs:"1000000000;The UIDs derived for the provisioned users are lower than this."`
+	GID                 int64                             `mapstructure:"gid" docs:"0;The GID of the provisioned users, 0 uses the UID, i.e. a private group per user."`
+	CreateHome          bool                              `mapstructure:"create_home" docs:"true;Whether to create the home of the provisioned users."`
+	Spaces              []SpaceConfig                     `mapstructure:"spaces" docs:"nil;The storage spaces created for the provisioned users."`
+	GatewaySvc          string                            `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
+	TokenManager        string                            `mapstructure:"token_manager" docs:"jwt;The token manager minting the tokens the homes and spaces are created with."`
+	TokenManagers       map[string]map[string]interface{} `mapstructure:"token_managers" docs:"url:pkg/token/manager/jwt/jwt.go;The configuration for the token managers."`
+	EventStream         string                            `mapstructure:"event_stream" docs:"nil;The event stream the provisioned users are published to, none disables them."`
+	EventStreams        map[string]map[string]interface{} `mapstructure:"event_streams" docs:"url:pkg/events/nats/nats.go;The configuration for the event streams."`
+}
+
+// SpaceConfig describes a storage space created for the provisioned users.
+type SpaceConfig struct {
+	Type         string `mapstructure:"type" docs:"project;The type of the space."`
+	NameTemplate string `mapstructure:"name_template" docs:"{{.Username}};The template of the name of the space, executed with the provisioned user."`
+	Quota        uint64 `mapstructure:"quota" docs:"0;The quota of the space in bytes, 0 leaves it to the storage provider."`
+}
+
+func (c *Config) init() {
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.UsernameTemplate == "" {
+		c.UsernameTemplate = "{{.preferred_username}}"
+	}
+	if c.DisplayNameTemplate == "" {
+		c.DisplayNameTemplate = "{{.name}}"
+	}
+	if c.MailTemplate == "" {
+		c.MailTemplate = "{{.email}}"
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+	if c.UIDMin == 0 {
+		c.UIDMin = 100000
+	}
+	if c.UIDMax == 0 {
+		c.UIDMax = 1000000000
+	}
+	if c.TokenManager == "" {
+		c.TokenManager = "jwt"
+	}
+	for i := range c.Spaces {
+		if c.Spaces[i].Type == "" {
+			c.Spaces[i].Type = "project"
+		}
+		if c.Spaces[i].NameTemplate == "" {
+			c.Spaces[i].NameTemplate = "{{.Username}}"
+		}
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+// Provisioner creates the users on their first login.
+type Provisioner struct {
+	c        *Config
+	users    user.Manager
+	creator  user.Creator
+	updater  user.Updater
+	tokenmgr token.Manager
+	events   *events.Emitter
+
+	username    *template.Template
+	displayName *template.Template
+	mail        *template.Template
+	spaceNames  []*template.Template
+}
+
+// New returns a provisioner for the given configuration, or nil when the
+// provisioning is not enabled.
+func New(m map[string]interface{}) (*Provisioner, error) {
+	c := &Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "provisioning: error decoding conf")
+	}
+	if !c.Enabled {
+		return nil, nil
+	}
+	return newFromConfig(c, m)
+}
+
+// NewProvisioner returns a provisioner for the given configuration whether
+// the provisioning on the first login is enabled or not, for the services
+// managing the users explicitly.
+func NewProvisioner(m map[string]interface{}) (*Provisioner, error) {
+	c := &Config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "provisioning: error decoding conf")
+	}
+	return newFromConfig(c, m)
+}
+
+func newFromConfig(c *Config, m map[string]interface{}) (*Provisioner, error) {
+	// homes are created unless disabled explicitly
+	if _, ok := m["create_home"]; !ok {
+		c.CreateHome = true
+	}
+	c.init()
+	if c.UIDMax <= c.UIDMin {
+		return nil, fmt.Errorf("provisioning: uid_max %d must be greater than uid_min %d", c.UIDMax, c.UIDMin)
+	}
+
+	f, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("provisioning: user manager not found: " + c.Driver)
+	}
+	users, err := f(c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+	creator, ok := users.(user.Creator)
+	if !ok {
+		return nil, errtypes.NotSupported("provisioning: user manager does not support the creation of users: " + c.Driver)
+	}
+
+	// the updates are optional, not all the writable managers support them
+	updater, _ := users.(user.Updater)
+	p := &Provisioner{c: c, users: users, creator: creator, updater: updater}
+
+	if c.CreateHome || len(c.Spaces) > 0 {
+		tf, ok := tokenregistry.NewFuncs[c.TokenManager]
+		if !ok {
+			return nil, errtypes.NotFound("provisioning: token manager not found: " + c.TokenManager)
+		}
+		if p.tokenmgr, err = tf(c.TokenManagers[c.TokenManager]); err != nil {
+			return nil, errors.Wrap(err, "provisioning: error creating token manager")
+		}
+	}
+
+	if p.username, err = parseTemplate("username", c.UsernameTemplate); err != nil {
+		return nil, err
+	}
+	if p.displayName, err = parseTemplate("display_name", c.DisplayNameTemplate); err != nil {
+		return nil, err
+	}
+	if p.mail, err = parseTemplate("mail", c.MailTemplate); err != nil {
+		return nil, err
+	}
+	for _, s := range c.Spaces {
+		t, err := parseTemplate("space", s.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
+		p.spaceNames = append(p.spaceNames, t)
+	}
+
+	if p.events, err = eventsregistry.NewEmitter(c.EventStream, c.EventStreams); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	// a claim missing for a template is an error rather than an empty value
+	t, err := template.New(name).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "provisioning: error parsing %s template", name)
+	}
+	return t, nil
+}
+
+func execute(t *template.Template, data interface{}) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", errors.Wrapf(err, "provisioning: error executing %s template", t.Name())
+	}
+	return b.String(), nil
+}
+
+// Close releases the resources of the provisioner.
+func (p *Provisioner) Close() error {
+	return p.events.Close()
+}
+
+// Provision returns the user with the given id, creating it from the claims
+// of the identity provider if it does not exist yet. When a user is created
+// its home and spaces are created as well; if that fails the user is removed
+// again so that the next login retries the whole provisioning.
+func (p *Provisioner) Provision(ctx context.Context, id *userpb.UserId, claims map[string]interface{}) (*userpb.User, error) {
+	u, err := p.users.GetUser(ctx, id)
+	if err == nil {
+		return u, nil
+	}
+	if _, ok := err.(errtypes.IsNotFound); !ok {
+		return nil, errors.Wrap(err, "provisioning: error getting user")
+	}
+
+	u, err = p.newUser(ctx, id, claims)
+	if err != nil {
+		return nil, err
+	}
+	return p.Create(ctx, u)
+}
+
+// Create creates the user together with its home and spaces, deriving its
+// uid when it has none. If the storage can't be created the user is removed
+// again.
+func (p *Provisioner) Create(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	if u.Id.GetOpaqueId() == "" || u.Username == "" {
+		return nil, errtypes.BadRequest("provisioning: user needs an id and a username")
+	}
+	if err := p.setIDs(ctx, u); err != nil {
+		return nil, err
+	}
+	u, err := p.creator.CreateUser(ctx, u)
+	if err != nil {
+		return nil, errors.Wrap(err, "provisioning: error creating user")
+	}
+
+	log := appctx.GetLogger(ctx).With().Str("user", u.Username).Logger()
+	spaces, err := p.createStorage(ctx, u)
+	if err != nil {
+		if err := p.creator.DeleteUser(ctx, u.Id); err != nil {
+			log.Error().Err(err).Msg("provisioning: error removing user after failed provisioning")
+		}
+		return nil, err
+	}
+
+	log.Info().Int("spaces", len(spaces)).Msg("provisioning: user provisioned")
+	p.events.Emit(ctx, &events.UserProvisioned{
+		UserID:   u.Id,
+		Username: u.Username,
+		Home:     p.c.CreateHome,
+		Spaces:   spaces,
+	})
+	return u, nil
+}
+
+// Update replaces the attributes of a user, see user.Updater.
+func (p *Provisioner) Update(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	if p.updater == nil {
+		return nil, errtypes.NotSupported("provisioning: user manager does not support the update of users: " + p.c.Driver)
+	}
+	e, err := p.users.GetUser(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+	// the uid and gid are kept unless given
+	uid, gid := numbers(e)
+	if n, _ := numbers(u); n == 0 {
+		u.UidNumber = uid
+	}
+	if _, n := numbers(u); n == 0 {
+		u.GidNumber = gid
+	}
+	if err := p.setIDs(ctx, u); err != nil {
+		return nil, err
+	}
+	if u, err = p.updater.UpdateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	p.events.Emit(ctx, &events.UserUpdated{UserID: u.Id, Executant: executant(ctx)})
+	return u, nil
+}
+
+// SetDisabled disables a user, who can't log in anymore, or enables it again.
+func (p *Provisioner) SetDisabled(ctx context.Context, id *userpb.UserId, disabled bool) (*userpb.User, error) {
+	if p.updater == nil {
+		return nil, errtypes.NotSupported("provisioning: user manager does not support the update of users: " + p.c.Driver)
+	}
+	u, err := p.users.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user.SetDisabled(u, disabled)
+	if u, err = p.updater.UpdateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	p.events.Emit(ctx, &events.UserDisabled{UserID: u.Id, Executant: executant(ctx), Disabled: disabled})
+	return u, nil
+}
+
+// Delete removes a user. Its home and spaces are kept, they are removed with
+// the storage tools once their content was dealt with.
+func (p *Provisioner) Delete(ctx context.Context, id *userpb.UserId) error {
+	u, err := p.users.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := p.creator.DeleteUser(ctx, u.Id); err != nil {
+		return err
+	}
+	p.events.Emit(ctx, &events.UserDeleted{UserID: u.Id, Executant: executant(ctx)})
+	return nil
+}
+
+func executant(ctx context.Context) *userpb.UserId {
+	if u, ok := user.ContextGetUser(ctx); ok {
+		return u.Id
+	}
+	return nil
+}
+
+// setIDs fills in the uid and gid of the user, in its numbers and in its
+// opaque, when they are missing.
+func (p *Provisioner) setIDs(ctx context.Context, u *userpb.User) error {
+	uid, gid := numbers(u)
+	if uid == 0 {
+		var err error
+		if uid, err = p.deriveUID(ctx, u.Id); err != nil {
+			return err
+		}
+	}
+	if gid == 0 {
+		gid = p.c.GID
+		if gid == 0 {
+			gid = uid
+		}
+	}
+	u.UidNumber, u.GidNumber = uid, gid
+	if u.Opaque == nil {
+		u.Opaque = &types.Opaque{}
+	}
+	if u.Opaque.Map == nil {
+		u.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	u.Opaque.Map["uid"] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(strconv.FormatInt(uid, 10))}
+	u.Opaque.Map["gid"] = &types.OpaqueEntry{Decoder: "plain", Value: []byte(strconv.FormatInt(gid, 10))}
+	return nil
+}
+
+// numbers returns the uid and gid of the user, from its numbers or else from
+// its opaque, 0 when missing.
+func numbers(u *userpb.User) (int64, int64) {
+	uid, gid := u.UidNumber, u.GidNumber
+	if uid == 0 {
+		uid = opaqueNumber(u, "uid")
+	}
+	if gid == 0 {
+		gid = opaqueNumber(u, "gid")
+	}
+	return uid, gid
+}
+
+func opaqueNumber(u *userpb.User, key string) int64 {
+	e, ok := u.GetOpaque().GetMap()[key]
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(string(e.Value), 10, 64)
+	return n
+}
+
+// newUser builds the user from the claims.
+func (p *Provisioner) newUser(ctx context.Context, id *userpb.UserId, claims map[string]interface{}) (*userpb.User, error) {
+	u := &userpb.User{Id: id}
+	var err error
+	if u.Username, err = execute(p.username, claims); err != nil {
+		return nil, err
+	}
+	if u.Username == "" {
+		return nil, errtypes.BadRequest("provisioning: empty username")
+	}
+	if u.DisplayName, err = execute(p.displayName, claims); err != nil {
+		return nil, err
+	}
+	if u.Mail, err = execute(p.mail, claims); err != nil {
+		return nil, err
+	}
+	if v, ok := claims["email_verified"].(bool); ok {
+		u.MailVerified = v
+	}
+	if groups, ok := claims[p.c.GroupsClaim].([]interface{}); ok {
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				u.Groups = append(u.Groups, s)
+			}
+		}
+	}
+
+	uid, ok := numericClaim(claims, p.c.UIDClaim)
+	if !ok {
+		if uid, err = p.deriveUID(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	gid, ok := numericClaim(claims, p.c.GIDClaim)
+	if !ok {
+		gid = p.c.GID
+		if gid == 0 {
+			gid = uid
+		}
+	}
+	u.Opaque = &types.Opaque{Map: map[string]*types.OpaqueEntry{
+		"uid": {Decoder: "plain", Value: []byte(strconv.FormatInt(uid, 10))},
+		"gid": {Decoder: "plain", Value: []byte(strconv.FormatInt(gid, 10))},
+	}}
+	return u, nil
+}
+
+// numericClaim returns the value of a claim holding a number, as a JSON
+// number or as a string.
+func numericClaim(claims map[string]interface{}, claim string) (int64, bool) {
+	if claim == "" {
+		return 0, false
+	}
+	switch v := claims[claim].(type) {
+	case float64:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// deriveUID hashes the id of the user into the configured range, so that the
+// same user gets the same uid on every deployment sharing the configuration.
+// The next free uid is used when it is taken.
+func (p *Provisioner) deriveUID(ctx context.Context, id *userpb.UserId) (int64, error) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id.Idp + "/" + id.OpaqueId))
+	n := p.c.UIDMax - p.c.UIDMin
+	start := int64(h.Sum64() % uint64(n))
+
+	for i := int64(0); i < uidProbes && i < n; i++ {
+		uid := p.c.UIDMin + (start+i)%n
+		_, err := p.users.GetUserByClaim(ctx, "uid", strconv.FormatInt(uid, 10))
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return uid, nil
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "provisioning: error looking up uid")
+		}
+	}
+	return 0, errtypes.InternalError("provisioning: no free uid found")
+}
+
+// createStorage creates the home and the spaces of the user, as the user.
+func (p *Provisioner) createStorage(ctx context.Context, u *userpb.User) ([]*provider.StorageSpaceId, error) {
+	if !p.c.CreateHome && len(p.c.Spaces) == 0 {
+		return nil, nil
+	}
+
+	ownerScope, err := scope.GetOwnerScope()
+	if err != nil {
+		return nil, err
+	}
+	tkn, err := p.tokenmgr.MintToken(token.ContextSetService(ctx, "provisioning"), u, ownerScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "provisioning: error minting token")
+	}
+	ctx = token.ContextSetToken(ctx, tkn)
+	ctx = user.ContextSetUser(ctx, u)
+	ctx = metadata.AppendToOutgoingContext(ctx, token.TokenHeader, tkn)
+
+	client, err := pool.GetGatewayServiceClient(p.c.GatewaySvc)
+	if err != nil {
+		return nil, errors.Wrap(err, "provisioning: error getting gateway client")
+	}
+
+	if p.c.CreateHome {
+		res, err := client.CreateHome(ctx, &provider.CreateHomeRequest{})
+		if err != nil {
+			return nil, errors.Wrap(err, "provisioning: error calling CreateHome")
+		}
+		// the home may exist already from a previous, partial provisioning
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_ALREADY_EXISTS {
+			return nil, status.NewErrorFromCode(res.Status.Code, "provisioning")
+		}
+	}
+
+	spaces := make([]*provider.StorageSpaceId, 0, len(p.c.Spaces))
+	for i, s := range p.c.Spaces {
+		name, err := execute(p.spaceNames[i], u)
+		if err != nil {
+			return nil, err
+		}
+		req := &provider.CreateStorageSpaceRequest{Owner: u, Type: s.Type, Name: name}
+		if s.Quota > 0 {
+			req.Quota = &provider.Quota{QuotaMaxBytes: s.Quota}
+		}
+		res, err := client.CreateStorageSpace(ctx, req)
+		if err != nil {
+			return nil, errors.Wrap(err, "provisioning: error calling CreateStorageSpace")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return nil, status.NewErrorFromCode(res.Status.Code, "provisioning")
+		}
+		spaces = append(spaces, res.StorageSpace.GetId())
+	}
+	return spaces, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package provisioning
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/user"
+	_ "github.com/cs3org/reva/pkg/user/manager/json"
+)
+
+func newProvisioner(t *testing.T, conf map[string]interface{}) *Provisioner {
+	dir, err := ioutil.TempDir("", "provisioning_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	users := path.Join(dir, "users.json")
+	if err := ioutil.WriteFile(users, []byte(`[]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf["enabled"] = true
+	conf["create_home"] = false
+	conf["drivers"] = map[string]map[string]interface{}{"json": {"users": users}}
+	p, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProvision(t *testing.T) {
+	ctx := context.Background()
+	p := newProvisioner(t, map[string]interface{}{
+		"username_template": "{{.preferred_username | lower}}",
+		"gid":               100,
+	})
+
+	id := &userpb.UserId{Idp: "https://idp.example.org", OpaqueId: "f7fbf8c8-139b-4376-b307-cf0a8c2d0d9c"}
+	claims := map[string]interface{}{
+		"preferred_username": "Einstein",
+		"name":               "Albert Einstein",
+		"email":              "einstein@example.org",
+		"email_verified":     true,
+		"groups":             []interface{}{"physics-lovers", "sailing-lovers"},
+	}
+
+	u, err := p.Provision(ctx, id, claims)
+	if err != nil {
+		t.Fatalf("error provisioning user: %v", err)
+	}
+	if u.Username != "einstein" || u.DisplayName != "Albert Einstein" || u.Mail != "einstein@example.org" || !u.MailVerified {
+		t.Errorf("unexpected user: %v", u)
+	}
+	if len(u.Groups) != 2 {
+		t.Errorf("expected 2 groups, got %v", u.Groups)
+	}
+	uid := string(u.Opaque.Map["uid"].Value)
+	if gid := string(u.Opaque.Map["gid"].Value); gid != "100" {
+		t.Errorf("expected gid 100, got %s", gid)
+	}
+
+	// the second login finds the stored user
+	claims["name"] = "Changed"
+	again, err := p.Provision(ctx, id, claims)
+	if err != nil {
+		t.Fatalf("error provisioning user again: %v", err)
+	}
+	if again.DisplayName != "Albert Einstein" || string(again.Opaque.Map["uid"].Value) != uid {
+		t.Errorf("user was provisioned twice: %v", again)
+	}
+}
+
+func TestProvisionUIDs(t *testing.T) {
+	ctx := context.Background()
+	p := newProvisioner(t, map[string]interface{}{
+		"uid_claim": "uidNumber",
+		// a range of a single uid to force a collision
+		"uid_min": 5000,
+		"uid_max": 5001,
+	})
+	claims := map[string]interface{}{"preferred_username": "marie", "name": "Marie Curie", "email": "marie@example.org", "uidNumber": float64(4242)}
+
+	u, err := p.Provision(ctx, &userpb.UserId{Idp: "idp", OpaqueId: "marie"}, claims)
+	if err != nil {
+		t.Fatalf("error provisioning user: %v", err)
+	}
+	if uid := string(u.Opaque.Map["uid"].Value); uid != "4242" {
+		t.Errorf("expected the uid of the claim, got %s", uid)
+	}
+	if gid := string(u.Opaque.Map["gid"].Value); gid != "4242" {
+		t.Errorf("expected the uid as gid, got %s", gid)
+	}
+
+	delete(claims, "uidNumber")
+	claims["preferred_username"] = "richard"
+	u, err = p.Provision(ctx, &userpb.UserId{Idp: "idp", OpaqueId: "richard"}, claims)
+	if err != nil {
+		t.Fatalf("error provisioning user: %v", err)
+	}
+	if uid := string(u.Opaque.Map["uid"].Value); uid != "5000" {
+		t.Errorf("expected the derived uid 5000, got %s", uid)
+	}
+
+	claims["preferred_username"] = "lise"
+	if _, err := p.Provision(ctx, &userpb.UserId{Idp: "idp", OpaqueId: "lise"}, claims); err == nil {
+		t.Errorf("expected an error when the uid range is exhausted")
+	}
+
+	// claims missing for the templates are an error
+	if _, err := p.Provision(ctx, &userpb.UserId{Idp: "idp", OpaqueId: "anonymous"}, map[string]interface{}{}); err == nil {
+		t.Errorf("expected an error for missing claims")
+	}
+}
+
+func TestManageUsers(t *testing.T) {
+	ctx := context.Background()
+	p := newProvisioner(t, map[string]interface{}{"uid_min": 5000, "uid_max": 6000, "gid": 100})
+
+	if _, err := p.Create(ctx, &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "nouser"}}); err == nil {
+		t.Errorf("expected an error for a user without username")
+	}
+
+	u, err := p.Create(ctx, &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "marie"}, Username: "marie", DisplayName: "Marie Curie"})
+	if err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	uid := string(u.Opaque.Map["uid"].Value)
+	if u.UidNumber < 5000 || u.UidNumber >= 6000 || u.GidNumber != 100 || uid != strconv.FormatInt(u.UidNumber, 10) {
+		t.Errorf("unexpected ids of the created user: %v", u)
+	}
+
+	u, err = p.Update(ctx, &userpb.User{Id: u.Id, DisplayName: "Marie Skłodowska-Curie", Mail: "marie@example.org"})
+	if err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	if u.Username != "marie" || u.DisplayName != "Marie Skłodowska-Curie" || string(u.Opaque.Map["uid"].Value) != uid {
+		t.Errorf("unexpected updated user: %v", u)
+	}
+
+	if u, err = p.SetDisabled(ctx, u.Id, true); err != nil || !user.IsDisabled(u) {
+		t.Fatalf("error disabling user: %v, %v", u, err)
+	}
+	if u, err = p.SetDisabled(ctx, u.Id, false); err != nil || user.IsDisabled(u) {
+		t.Fatalf("error enabling user: %v, %v", u, err)
+	}
+
+	if err := p.Delete(ctx, u.Id); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	if _, err := p.users.GetUser(ctx, u.Id); err == nil {
+		t.Errorf("expected the user to be deleted")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package rename implements the change of the username of a user, remapping
+// the references the other managers hold to the user.
+package rename
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/cache"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Managers holds the managers affected by a rename. Only Users is mandatory.
+type Managers struct {
+	Users        user.Manager
+	Storages     []storage.FS
+	Shares       share.Manager
+	PublicShares publicshare.Manager
+	// Cache is the stat cache shared by the gateways.
+	Cache cache.Cache
+	// CachePaths are the templates of the paths of the homes as seen by the
+	// gateways, e.g. /home/{{.Username}}. They are dropped from Cache.
+	CachePaths []string
+}
+
+// step undoes a completed part of a rename.
+type step struct {
+	name string
+	undo func(ctx context.Context) error
+}
+
+// Rename changes the username of the user with the given id. The homes of the
+// user are moved and, when the id of the user changes as well, the shares and
+// public shares are rewritten. If any of this fails the completed parts are
+// undone, so the user either is renamed everywhere or nowhere.
+func Rename(ctx context.Context, m Managers, uid *userpb.UserId, username string) (*userpb.User, error) {
+	log := appctx.GetLogger(ctx)
+
+	renamer, ok := m.Users.(user.Renamer)
+	if !ok {
+		return nil, errtypes.NotSupported("rename: the user manager does not support renames")
+	}
+	shareRenamer, ok := m.Shares.(share.UserRenamer)
+	if m.Shares != nil && !ok {
+		return nil, errtypes.NotSupported("rename: the share manager does not support renames")
+	}
+	publicShareRenamer, ok := m.PublicShares.(publicshare.UserRenamer)
+	if m.PublicShares != nil && !ok {
+		return nil, errtypes.NotSupported("rename: the public share manager does not support renames")
+	}
+
+	old, err := m.Users.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if old.Username == username {
+		return old, nil
+	}
+
+	renamed, err := renamer.RenameUser(ctx, old.Id, username)
+	if err != nil {
+		return nil, errors.Wrap(err, "rename: error renaming user")
+	}
+	done := []step{{"user", func(ctx context.Context) error {
+		_, err := renamer.RenameUser(ctx, renamed.Id, old.Username)
+		return err
+	}}}
+
+	fail := func(err error) (*userpb.User, error) {
+		return nil, undo(ctx, done, err)
+	}
+
+	for i, fs := range m.Storages {
+		fs := fs
+		err := storage.RenameHome(ctx, fs, old, renamed)
+		if _, ok := err.(errtypes.IsNotSupported); ok {
+			log.Debug().Int("storage", i).Msg("rename: storage does not derive homes from usernames, skipping")
+			continue
+		}
+		if err != nil {
+			return fail(errors.Wrapf(err, "rename: error moving home on storage %d", i))
+		}
+		done = append(done, step{fmt.Sprintf("home on storage %d", i), func(ctx context.Context) error {
+			return storage.RenameHome(ctx, fs, renamed, old)
+		}})
+	}
+
+	if !utils.UserEqual(old.Id, renamed.Id) {
+		if shareRenamer != nil {
+			if err := shareRenamer.RenameUser(ctx, old.Id, renamed.Id); err != nil {
+				return fail(errors.Wrap(err, "rename: error rewriting shares"))
+			}
+			done = append(done, step{"shares", func(ctx context.Context) error {
+				return shareRenamer.RenameUser(ctx, renamed.Id, old.Id)
+			}})
+		}
+		if publicShareRenamer != nil {
+			if err := publicShareRenamer.RenameUser(ctx, old.Id, renamed.Id); err != nil {
+				return fail(errors.Wrap(err, "rename: error rewriting public shares"))
+			}
+			done = append(done, step{"public shares", func(ctx context.Context) error {
+				return publicShareRenamer.RenameUser(ctx, renamed.Id, old.Id)
+			}})
+		}
+	}
+
+	// cached entries expire on their own, failing to drop them is not fatal
+	if m.Cache != nil && len(m.CachePaths) > 0 {
+		paths := make([]string, 0, 2*len(m.CachePaths))
+		for _, p := range m.CachePaths {
+			paths = append(paths, templates.WithUser(old, p), templates.WithUser(renamed, p))
+		}
+		if err := m.Cache.InvalidateTree(paths...); err != nil {
+			log.Warn().Err(err).Strs("paths", paths).Msg("rename: error invalidating stat cache")
+		}
+	}
+
+	return renamed, nil
+}
+
+// undo reverts the completed steps in reverse order. The steps that could not
+// be undone are reported in the returned error so they can be fixed manually.
+func undo(ctx context.Context, done []step, cause error) error {
+	var failed []string
+	for i := len(done) - 1; i >= 0; i-- {
+		if err := done[i].undo(ctx); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("step", done[i].name).Msg("rename: error undoing step")
+			failed = append(failed, done[i].name)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Wrapf(cause, "rename: could not undo %s", strings.Join(failed, ", "))
+	}
+	return cause
+}
@@ -0,0 +1,155 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package rename_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/share"
+	sharejson "github.com/cs3org/reva/pkg/share/manager/json"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/user"
+	userjson "github.com/cs3org/reva/pkg/user/manager/json"
+	"github.com/cs3org/reva/pkg/user/rename"
+)
+
+var (
+	einstein = &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein"}
+	marie    = &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie"}
+)
+
+// storageFS records the renamed homes, failing if err is set.
+type storageFS struct {
+	storage.FS
+	err   error
+	homes []string
+}
+
+func (s *storageFS) RenameHome(ctx context.Context, oldUser, newUser *userpb.User) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.homes = append(s.homes, oldUser.Username+"->"+newUser.Username)
+	return nil
+}
+
+func newManagers(t *testing.T) rename.Managers {
+	dir, err := ioutil.TempDir("", "rename_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	users := `[{"id":{"idp":"cernbox.cern.ch","opaque_id":"einstein"},"username":"einstein"},{"id":{"idp":"cernbox.cern.ch","opaque_id":"marie"},"username":"marie"}]`
+	if err := ioutil.WriteFile(path.Join(dir, "users.json"), []byte(users), 0600); err != nil {
+		t.Fatal(err)
+	}
+	um, err := userjson.New(map[string]interface{}{"users": path.Join(dir, "users.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm, err := sharejson.New(map[string]interface{}{"file": path.Join(dir, "shares.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shareID := &collaboration.ShareId{OpaqueId: "1"}
+	err = sm.(share.Loader).Load(context.Background(), []*collaboration.Share{{
+		Id:          shareID,
+		ResourceId:  &provider.ResourceId{StorageId: "storage", OpaqueId: "file"},
+		Permissions: &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true}},
+		Grantee:     &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: einstein}},
+		Owner:       marie,
+		Creator:     marie,
+	}}, []*share.ReceivedShareState{{UserID: einstein, ShareID: shareID, State: collaboration.ShareState_SHARE_STATE_ACCEPTED}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rename.Managers{Users: um, Shares: sm, Storages: []storage.FS{&storageFS{}}}
+}
+
+func TestRename(t *testing.T) {
+	ctx := context.Background()
+	m := newManagers(t)
+
+	renamed, err := rename.Rename(ctx, m, einstein, "albert")
+	if err != nil {
+		t.Fatalf("error renaming user: %v", err)
+	}
+	if renamed.Username != "albert" || renamed.Id.OpaqueId != "albert" {
+		t.Fatalf("user not renamed: %v", renamed)
+	}
+
+	if homes := m.Storages[0].(*storageFS).homes; len(homes) != 1 || homes[0] != "einstein->albert" {
+		t.Fatalf("home not moved: %v", homes)
+	}
+
+	shares, states, err := m.Shares.(share.Dumper).Dump(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := shares[0].Grantee.GetUserId(); g.OpaqueId != "albert" {
+		t.Fatalf("grantee not rewritten: %v", g)
+	}
+	if len(states) != 1 || states[0].UserID.OpaqueId != "albert" {
+		t.Fatalf("received share state not rewritten: %v", states)
+	}
+}
+
+func TestRenameUndo(t *testing.T) {
+	ctx := context.Background()
+	m := newManagers(t)
+	m.Storages = append(m.Storages, &storageFS{err: errors.New("storage is down")})
+
+	if _, err := rename.Rename(ctx, m, einstein, "albert"); err == nil {
+		t.Fatal("expected rename to fail")
+	}
+
+	if u, err := m.Users.GetUser(ctx, einstein); err != nil || u.Username != "einstein" {
+		t.Fatalf("user rename not undone: %v %v", u, err)
+	}
+	if homes := m.Storages[0].(*storageFS).homes; len(homes) != 2 || homes[1] != "albert->einstein" {
+		t.Fatalf("home move not undone: %v", homes)
+	}
+	shares, _, err := m.Shares.(share.Dumper).Dump(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := shares[0].Grantee.GetUserId(); g.OpaqueId != "einstein" {
+		t.Fatalf("shares modified: %v", g)
+	}
+}
+
+func TestRenameNotSupported(t *testing.T) {
+	m := newManagers(t)
+	m.Users = struct{ user.Manager }{m.Users}
+
+	if _, err := rename.Rename(context.Background(), m, einstein, "albert"); err == nil {
+		t.Fatal("expected rename to fail without a renamer")
+	}
+}
@@ -22,8 +22,13 @@ import (
 	"context"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 )
 
+// DisabledOpaqueKey is the opaque key flagging the users who were disabled
+// and can't log in anymore.
+const DisabledOpaqueKey = "disabled"
+
 type key int
 
 const (
@@ -69,3 +74,48 @@ type Manager interface {
 	GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error)
 	FindUsers(ctx context.Context, query string) ([]*userpb.User, error)
 }
+
+// Renamer is implemented by the managers that are able to change the username
+// of a user. Deployments using the username as the opaque id of the user get a
+// new id as well, callers have to remap every reference to the returned user.
+type Renamer interface {
+	RenameUser(ctx context.Context, uid *userpb.UserId, username string) (*userpb.User, error)
+}
+
+// Creator is implemented by the managers that are able to store new users,
+// as needed to provision the users on their first login.
+type Creator interface {
+	CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error)
+	DeleteUser(ctx context.Context, uid *userpb.UserId) error
+}
+
+// Updater is implemented by the managers that are able to change the
+// attributes of a user.
+type Updater interface {
+	// UpdateUser replaces the attributes of the user with the id of u with
+	// the ones of u. The username is kept, it is changed by a rename.
+	UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error)
+}
+
+// IsDisabled tells whether the user was disabled.
+func IsDisabled(u *userpb.User) bool {
+	e, ok := u.GetOpaque().GetMap()[DisabledOpaqueKey]
+	return ok && string(e.Value) == "true"
+}
+
+// SetDisabled flags the user as disabled, or removes the flag.
+func SetDisabled(u *userpb.User, disabled bool) {
+	if !disabled {
+		if u.Opaque != nil {
+			delete(u.Opaque.Map, DisabledOpaqueKey)
+		}
+		return
+	}
+	if u.Opaque == nil {
+		u.Opaque = &types.Opaque{}
+	}
+	if u.Opaque.Map == nil {
+		u.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	u.Opaque.Map[DisabledOpaqueKey] = &types.OpaqueEntry{Decoder: "plain", Value: []byte("true")}
+}
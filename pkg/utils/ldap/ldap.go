@@ -0,0 +1,265 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package ldap holds what the LDAP backed managers share: a pool of bound
+// connections spread over several servers, the paged searches, and the
+// mapping of attributes to opaque entries.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Config holds the connection options of the LDAP backed managers.
+type Config struct {
+	Hostname string `mapstructure:"hostname"`
+	Port     int    `mapstructure:"port"`
+	// URIs are the servers to connect to, in the order they are failed over,
+	// e.g. ldaps://ldap1.example.org:636. Hostname and port stand for a single
+	// ldaps server if empty.
+	URIs         []string `mapstructure:"uris"`
+	BindUsername string   `mapstructure:"bind_username"`
+	BindPassword string   `mapstructure:"bind_password"`
+	// Insecure skips the verification of the certificates of the servers.
+	Insecure bool `mapstructure:"insecure"`
+	// PoolSize is the number of idle connections kept open.
+	PoolSize int `mapstructure:"pool_size"`
+	// PageSize is the number of entries the searches are paged by, so that
+	// the directories limiting the size of the results can be listed. A
+	// negative size disables the paging.
+	PageSize int `mapstructure:"page_size"`
+	// Timeout is the time in seconds after which connecting or searching fails.
+	Timeout int `mapstructure:"timeout"`
+}
+
+func (c *Config) init() {
+	if len(c.URIs) == 0 {
+		c.URIs = []string{fmt.Sprintf("ldaps://%s:%d", c.Hostname, c.Port)}
+	}
+	if c.PoolSize == 0 {
+		c.PoolSize = 4
+	}
+	if c.PageSize == 0 {
+		c.PageSize = 500
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10
+	}
+}
+
+// Pool hands out connections bound with the credentials of the configuration.
+type Pool struct {
+	c    *Config
+	tls  *tls.Config
+	idle chan *goldap.Conn
+
+	mu sync.Mutex
+	// current is the server connected to last, the next connections are
+	// opened to it as long as it is reachable
+	current int
+}
+
+// NewPool returns a pool of connections to the servers of the configuration,
+// which are opened on demand.
+func NewPool(c *Config) *Pool {
+	c.init()
+	return &Pool{
+		c:    c,
+		tls:  &tls.Config{InsecureSkipVerify: c.Insecure},
+		idle: make(chan *goldap.Conn, c.PoolSize),
+	}
+}
+
+// dial connects to the first reachable server, starting with the current one.
+func (p *Pool) dial() (*goldap.Conn, error) {
+	p.mu.Lock()
+	start := p.current
+	p.mu.Unlock()
+
+	timeout := time.Duration(p.c.Timeout) * time.Second
+	var err error
+	for i := range p.c.URIs {
+		n := (start + i) % len(p.c.URIs)
+		var l *goldap.Conn
+		l, err = goldap.DialURL(p.c.URIs[n], goldap.DialWithTLSConfig(p.tls), goldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+		if err != nil {
+			log.Warn().Err(err).Str("uri", p.c.URIs[n]).Msg("ldap: error connecting to server")
+			continue
+		}
+		l.SetTimeout(timeout)
+		// bind with a read only user
+		if err = l.Bind(p.c.BindUsername, p.c.BindPassword); err != nil {
+			l.Close()
+			log.Warn().Err(err).Str("uri", p.c.URIs[n]).Msg("ldap: error binding to server")
+			continue
+		}
+
+		p.mu.Lock()
+		p.current = n
+		p.mu.Unlock()
+		return l, nil
+	}
+	return nil, errors.Wrap(err, "ldap: no server available")
+}
+
+func (p *Pool) get() (*goldap.Conn, error) {
+	for {
+		select {
+		case l := <-p.idle:
+			if l.IsClosing() {
+				l.Close()
+				continue
+			}
+			return l, nil
+		default:
+			return p.dial()
+		}
+	}
+}
+
+func (p *Pool) put(l *goldap.Conn) {
+	select {
+	case p.idle <- l:
+	default:
+		l.Close()
+	}
+}
+
+// Search runs the search on a pooled connection, by pages unless disabled. A
+// search failing on a broken connection is retried once on a new one, opened
+// to the next server if the previous one is down.
+func (p *Pool) Search(req *goldap.SearchRequest) (*goldap.SearchResult, error) {
+	l, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+
+	sr, err := p.search(l, req)
+	if goldap.IsErrorWithCode(err, goldap.ErrorNetwork) {
+		l.Close()
+		if l, err = p.dial(); err != nil {
+			return nil, err
+		}
+		sr, err = p.search(l, req)
+	}
+	if goldap.IsErrorWithCode(err, goldap.ErrorNetwork) {
+		l.Close()
+		return nil, err
+	}
+	p.put(l)
+	return sr, err
+}
+
+func (p *Pool) search(l *goldap.Conn, req *goldap.SearchRequest) (*goldap.SearchResult, error) {
+	if p.c.PageSize < 0 {
+		return l.Search(req)
+	}
+	// the paging control is added to the request and tracks the pages
+	r := *req
+	r.Controls = append([]goldap.Control(nil), req.Controls...)
+	return l.SearchWithPaging(&r, uint32(p.c.PageSize))
+}
+
+// Check opens and binds a new connection, to tell whether a server can still
+// be used with the credentials of the configuration.
+func (p *Pool) Check(ctx context.Context) error {
+	l, err := p.dial()
+	if err != nil {
+		return err
+	}
+	l.Close()
+	return nil
+}
+
+// Close closes the idle connections.
+func (p *Pool) Close() {
+	for {
+		select {
+		case l := <-p.idle:
+			l.Close()
+		default:
+			return
+		}
+	}
+}
+
+// OpaqueMapping maps the keys of opaque entries to the attributes they are
+// read from. The first value of the attribute is stored in plain, or all the
+// values of a multi-valued attribute as a JSON list when it is suffixed with
+// [], e.g. `memberOf[]`.
+type OpaqueMapping map[string]string
+
+func (o OpaqueMapping) attribute(key string) (string, bool) {
+	attr := o[key]
+	if strings.HasSuffix(attr, "[]") {
+		return strings.TrimSuffix(attr, "[]"), true
+	}
+	return attr, false
+}
+
+// Attributes returns the attributes to request for the mapping.
+func (o OpaqueMapping) Attributes() []string {
+	attrs := make([]string, 0, len(o))
+	for k := range o {
+		attr, _ := o.attribute(k)
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// Apply adds the mapped attributes of the entry to the opaque, which is
+// created if nil. The attributes missing in the entry are skipped.
+func (o OpaqueMapping) Apply(e *goldap.Entry, opaque *types.Opaque) (*types.Opaque, error) {
+	for k := range o {
+		attr, multi := o.attribute(k)
+		values := e.GetEqualFoldAttributeValues(attr)
+		if len(values) == 0 {
+			continue
+		}
+
+		entry := &types.OpaqueEntry{Decoder: "plain", Value: []byte(values[0])}
+		if multi {
+			v, err := json.Marshal(values)
+			if err != nil {
+				return nil, errors.Wrapf(err, "ldap: error encoding attribute %s", attr)
+			}
+			entry = &types.OpaqueEntry{Decoder: "json", Value: v}
+		}
+
+		if opaque == nil {
+			opaque = &types.Opaque{}
+		}
+		if opaque.Map == nil {
+			opaque.Map = map[string]*types.OpaqueEntry{}
+		}
+		opaque.Map[k] = entry
+	}
+	return opaque, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ldap
+
+import (
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+func TestOpaqueMapping(t *testing.T) {
+	o := OpaqueMapping{
+		"department": "department",
+		"member_of":  "memberOf[]",
+		"missing":    "employeeType",
+	}
+
+	attrs := o.Attributes()
+	sort.Strings(attrs)
+	if len(attrs) != 3 || attrs[0] != "department" || attrs[1] != "employeeType" || attrs[2] != "memberOf" {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+
+	e := goldap.NewEntry("cn=einstein,ou=users,dc=example,dc=org", map[string][]string{
+		"Department": {"physics", "ignored"},
+		"memberOf":   {"cn=physics-lovers", "cn=sailing-lovers"},
+	})
+	opaque, err := o.Apply(e, &types.Opaque{Map: map[string]*types.OpaqueEntry{"uid": {Decoder: "plain", Value: []byte("123")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := opaque.Map["department"]; v.Decoder != "plain" || string(v.Value) != "physics" {
+		t.Errorf("unexpected department %v", v)
+	}
+	if v := opaque.Map["member_of"]; v.Decoder != "json" || string(v.Value) != `["cn=physics-lovers","cn=sailing-lovers"]` {
+		t.Errorf("unexpected member_of %v", v)
+	}
+	if _, ok := opaque.Map["missing"]; ok {
+		t.Error("missing attributes must be skipped")
+	}
+	if string(opaque.Map["uid"].Value) != "123" {
+		t.Error("the existing entries must be kept")
+	}
+
+	if opaque, err := (OpaqueMapping{}).Apply(e, nil); err != nil || opaque != nil {
+		t.Errorf("an empty mapping must not create the opaque, got %v, %v", opaque, err)
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	// a server refusing the connections and one closing them right away
+	refused, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refusedAddr := refused.Addr().String()
+	refused.Close()
+
+	closing, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closing.Close()
+	accepted := make(chan struct{}, 8)
+	go func() {
+		for {
+			c, err := closing.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+			accepted <- struct{}{}
+		}
+	}()
+
+	p := NewPool(&Config{
+		URIs:         []string{"ldap://" + refusedAddr, "ldap://" + closing.Addr().String()},
+		BindUsername: "cn=reva,dc=example,dc=org",
+		BindPassword: "secret",
+		Timeout:      1,
+	})
+	if p.c.PageSize != 500 || p.c.PoolSize != 4 {
+		t.Fatalf("unexpected defaults %+v", p.c)
+	}
+	if _, err := p.Search(goldap.NewSearchRequest("dc=example,dc=org", goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false, "(cn=*)", nil, nil)); err == nil {
+		t.Fatal("the search must fail without servers")
+	}
+	select {
+	case <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("the second server must be tried when the first one is down")
+	}
+}